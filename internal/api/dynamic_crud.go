@@ -77,6 +77,8 @@ func (d *dynamicConfMgr) Remove(id string) {
 type Dynamic struct {
 	onUpdate func(ctx context.Context, id string, conf []byte) error
 	onDelete func(ctx context.Context, id string) error
+	onPause  func(ctx context.Context, id string, paused bool) error
+	onStats  func(id string) (any, bool)
 
 	// configs is a map of the latest sanitised configs from our CRUD clients.
 	configs      map[string][]byte
@@ -116,6 +118,21 @@ func (d *Dynamic) OnDelete(onDelete func(ctx context.Context, id string) error)
 	d.onDelete = onDelete
 }
 
+// OnPause registers a func to handle requests to pause or resume a dynamic
+// component. An error should be returned if the component does not exist.
+// Pause and resume requests are rejected when no func is registered.
+func (d *Dynamic) OnPause(onPause func(ctx context.Context, id string, paused bool) error) {
+	d.onPause = onPause
+}
+
+// OnStats registers a func that provides the stats of a dynamic component,
+// which must be JSON serialisable, and false if the component does not exist.
+// When registered the stats of each component are also included in list
+// requests.
+func (d *Dynamic) OnStats(onStats func(id string) (any, bool)) {
+	d.onStats = onStats
+}
+
 // Stopped should be called whenever an active dynamic component has closed,
 // whether by naturally winding down or from a request.
 func (d *Dynamic) Stopped(id string) {
@@ -160,6 +177,7 @@ func (d *Dynamic) HandleList(w http.ResponseWriter, r *http.Request) {
 		Uptime    string `json:"uptime"`
 		Config    any    `json:"config"`
 		ConfigRaw string `json:"config_raw"`
+		Stats     any    `json:"stats,omitempty"`
 	}
 	uptimes := map[string]confInfo{}
 
@@ -191,6 +209,15 @@ func (d *Dynamic) HandleList(w http.ResponseWriter, r *http.Request) {
 	}
 	d.configsMut.Unlock()
 
+	if d.onStats != nil {
+		for k, info := range uptimes {
+			if stats, exists := d.onStats(k); exists {
+				info.Stats = stats
+				uptimes[k] = info
+			}
+		}
+	}
+
 	var resBytes []byte
 	if resBytes, httpErr = json.Marshal(uptimes); httpErr == nil {
 		_, _ = w.Write(resBytes)
@@ -223,6 +250,60 @@ func (d *Dynamic) HandleUptime(w http.ResponseWriter, r *http.Request) {
 	_, _ = w.Write([]byte(uptimeStr))
 }
 
+// HandleStats is an http.HandleFunc for returning the stats of a dynamic
+// component as a JSON object.
+func (d *Dynamic) HandleStats(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	if d.onStats == nil {
+		http.Error(w, "Stats are not supported by this component", http.StatusNotImplemented)
+		return
+	}
+
+	stats, exists := d.onStats(id)
+	if !exists {
+		http.Error(w, fmt.Sprintf("Dynamic component '%v' is unknown", id), http.StatusNotFound)
+		return
+	}
+
+	resBytes, err := json.Marshal(stats)
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusBadGateway)
+		return
+	}
+	_, _ = w.Write(resBytes)
+}
+
+// HandlePause is an http.HandleFunc for pausing a dynamic component.
+func (d *Dynamic) HandlePause(w http.ResponseWriter, r *http.Request) {
+	d.handleSetPaused(w, r, true)
+}
+
+// HandleResume is an http.HandleFunc for resuming a paused dynamic component.
+func (d *Dynamic) HandleResume(w http.ResponseWriter, r *http.Request) {
+	d.handleSetPaused(w, r, false)
+}
+
+func (d *Dynamic) handleSetPaused(w http.ResponseWriter, r *http.Request, paused bool) {
+	if r.Body != nil {
+		defer r.Body.Close()
+	}
+	if r.Method != "POST" {
+		http.Error(w, "Method not supported", http.StatusBadRequest)
+		return
+	}
+
+	id := mux.Vars(r)["id"]
+	if d.onPause == nil {
+		http.Error(w, "Pausing is not supported by this component", http.StatusNotImplemented)
+		return
+	}
+	if err := d.onPause(r.Context(), id, paused); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to update component '%v': %v", id, err), http.StatusBadRequest)
+		return
+	}
+}
+
 func (d *Dynamic) handleGETInput(w http.ResponseWriter, r *http.Request) error {
 	id := mux.Vars(r)["id"]
 
@@ -244,3 +244,72 @@ test: second sanitised
 
 	assert.Equal(t, `{"foo":{"uptime":"stopped","config":{"test":"second sanitised"},"config_raw":"\ntest: second sanitised\n"}}`, response.Body.String())
 }
+
+func TestDynamicPauseAndStats(t *testing.T) {
+	dAPI := NewDynamic()
+
+	r := mux.NewRouter()
+	r.HandleFunc("/inputs", dAPI.HandleList)
+	r.HandleFunc("/input/{id}/stats", dAPI.HandleStats)
+	r.HandleFunc("/input/{id}/pause", dAPI.HandlePause)
+	r.HandleFunc("/input/{id}/resume", dAPI.HandleResume)
+
+	request, _ := http.NewRequest("POST", "/input/foo/pause", http.NoBody)
+	response := httptest.NewRecorder()
+	r.ServeHTTP(response, request)
+	assert.Equal(t, http.StatusNotImplemented, response.Code)
+
+	paused := map[string]bool{}
+	dAPI.OnPause(func(ctx context.Context, id string, p bool) error {
+		if id != "foo" {
+			return errors.New("nope")
+		}
+		paused[id] = p
+		return nil
+	})
+	dAPI.OnStats(func(id string) (any, bool) {
+		if id != "foo" {
+			return nil, false
+		}
+		return map[string]any{"paused": paused[id]}, true
+	})
+	dAPI.Started("foo", []byte("bar"))
+
+	request, _ = http.NewRequest("POST", "/input/foo/pause", http.NoBody)
+	response = httptest.NewRecorder()
+	r.ServeHTTP(response, request)
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.True(t, paused["foo"])
+
+	request, _ = http.NewRequest("GET", "/input/foo/stats", http.NoBody)
+	response = httptest.NewRecorder()
+	r.ServeHTTP(response, request)
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.Equal(t, `{"paused":true}`, response.Body.String())
+
+	request, _ = http.NewRequest("GET", "/inputs", http.NoBody)
+	response = httptest.NewRecorder()
+	r.ServeHTTP(response, request)
+	assert.Contains(t, response.Body.String(), `"stats":{"paused":true}`)
+
+	request, _ = http.NewRequest("POST", "/input/foo/resume", http.NoBody)
+	response = httptest.NewRecorder()
+	r.ServeHTTP(response, request)
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.False(t, paused["foo"])
+
+	request, _ = http.NewRequest("GET", "/input/bar/resume", http.NoBody)
+	response = httptest.NewRecorder()
+	r.ServeHTTP(response, request)
+	assert.Equal(t, http.StatusBadRequest, response.Code)
+
+	request, _ = http.NewRequest("POST", "/input/bar/resume", http.NoBody)
+	response = httptest.NewRecorder()
+	r.ServeHTTP(response, request)
+	assert.Equal(t, http.StatusBadRequest, response.Code)
+
+	request, _ = http.NewRequest("GET", "/input/bar/stats", http.NoBody)
+	response = httptest.NewRecorder()
+	r.ServeHTTP(response, request)
+	assert.Equal(t, http.StatusNotFound, response.Code)
+}
@@ -6,21 +6,23 @@ import (
 
 // Config contains configuration parameters for a batch policy.
 type Config struct {
-	ByteSize   int                `json:"byte_size" yaml:"byte_size"`
-	Count      int                `json:"count" yaml:"count"`
-	Check      string             `json:"check" yaml:"check"`
-	Period     string             `json:"period" yaml:"period"`
-	Processors []processor.Config `json:"processors" yaml:"processors"`
+	ByteSize       int                `json:"byte_size" yaml:"byte_size"`
+	StrictByteSize bool               `json:"strict_byte_size" yaml:"strict_byte_size"`
+	Count          int                `json:"count" yaml:"count"`
+	Check          string             `json:"check" yaml:"check"`
+	Period         string             `json:"period" yaml:"period"`
+	Processors     []processor.Config `json:"processors" yaml:"processors"`
 }
 
 // NewConfig creates a default PolicyConfig.
 func NewConfig() Config {
 	return Config{
-		ByteSize:   0,
-		Count:      0,
-		Check:      "",
-		Period:     "",
-		Processors: []processor.Config{},
+		ByteSize:       0,
+		StrictByteSize: false,
+		Count:          0,
+		Check:          "",
+		Period:         "",
+		Processors:     []processor.Config{},
 	}
 }
 
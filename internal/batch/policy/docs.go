@@ -34,6 +34,10 @@ Allows you to configure a [batching policy](/docs/configuration/batching).`,
 				"byte_size",
 				"An amount of bytes at which the batch should be flushed. If `0` disables size based batching.",
 			).HasDefault(0),
+			docs.FieldBool(
+				"strict_byte_size",
+				"Whether `byte_size` is a ceiling that batches should not exceed. When enabled, a pending batch is flushed before adding messages that would take it beyond `byte_size` rather than after, and therefore batches only exceed `byte_size` when a single message, or a batch of messages received together, already does. This is useful for outputs with hard payload limits, where it can be combined with a `split` processor with `max_part_size` for messages that are too large on their own.",
+			).HasDefault(false).Advanced().AtVersion("4.28.0"),
 			docs.FieldString(
 				"period",
 				"A period in which an incomplete batch should be flushed regardless of its size.",
@@ -21,13 +21,14 @@ import (
 type Batcher struct {
 	log log.Modular
 
-	byteSize  int
-	count     int
-	period    time.Duration
-	check     *mapping.Executor
-	procs     []iprocessor.V1
-	sizeTally int
-	parts     []*message.Part
+	byteSize       int
+	strictByteSize bool
+	count          int
+	period         time.Duration
+	check          *mapping.Executor
+	procs          []iprocessor.V1
+	sizeTally      int
+	parts          []*message.Part
 
 	triggered bool
 	lastBatch time.Time
@@ -73,11 +74,12 @@ func New(conf batchconfig.Config, mgr bundle.NewManagement) (*Batcher, error) {
 	return &Batcher{
 		log: mgr.Logger(),
 
-		byteSize: conf.ByteSize,
-		count:    conf.Count,
-		period:   period,
-		check:    check,
-		procs:    procs,
+		byteSize:       conf.ByteSize,
+		strictByteSize: conf.StrictByteSize,
+		count:          conf.Count,
+		period:         period,
+		check:          check,
+		procs:          procs,
 
 		lastBatch: time.Now(),
 
@@ -126,6 +128,25 @@ func (p *Batcher) Add(part *message.Part) bool {
 	return p.triggered || (p.period > 0 && time.Since(p.lastBatch) > p.period)
 }
 
+// FlushBefore returns true if the policy has a strict byte size and adding the
+// parts of a batch would take the pending batch beyond it, in which case the
+// pending batch should be flushed before the parts are added.
+func (p *Batcher) FlushBefore(msg message.Batch) bool {
+	if !p.strictByteSize || p.byteSize <= 0 || len(p.parts) == 0 {
+		return false
+	}
+	size := p.sizeTally
+	for _, part := range msg {
+		size += len(part.AsBytes())
+	}
+	if size <= p.byteSize {
+		return false
+	}
+	p.mSizeBatch.Incr(1)
+	p.log.Trace("Batching based on strict byte_size")
+	return true
+}
+
 // Flush clears all messages stored by this batch policy. Returns nil if the
 // policy is currently empty.
 func (p *Batcher) Flush(ctx context.Context) message.Batch {
@@ -161,6 +161,44 @@ func TestPolicySize(t *testing.T) {
 	}
 }
 
+func TestPolicyStrictSize(t *testing.T) {
+	conf := batchconfig.NewConfig()
+	conf.ByteSize = 10
+	conf.StrictByteSize = true
+
+	pol, err := policy.New(conf, mock.NewManager())
+	require.NoError(t, err)
+
+	tCtx, done := context.WithTimeout(context.Background(), time.Second*30)
+	t.Cleanup(func() {
+		require.NoError(t, pol.Close(tCtx))
+		done()
+	})
+
+	// An empty policy never needs flushing, even for oversized batches.
+	assert.False(t, pol.FlushBefore(message.QuickBatch([][]byte{[]byte("foo bar baz")})))
+
+	assert.False(t, pol.FlushBefore(message.QuickBatch([][]byte{[]byte("foo")})))
+	assert.False(t, pol.Add(message.NewPart([]byte("foo"))))
+
+	assert.False(t, pol.FlushBefore(message.QuickBatch([][]byte{[]byte("bar"), []byte("baz")})))
+	assert.False(t, pol.Add(message.NewPart([]byte("bar"))))
+	assert.False(t, pol.Add(message.NewPart([]byte("baz"))))
+
+	assert.True(t, pol.FlushBefore(message.QuickBatch([][]byte{[]byte("qux")})))
+
+	msg := pol.Flush(tCtx)
+	assert.Equal(t, [][]byte{[]byte("foo"), []byte("bar"), []byte("baz")}, message.GetAllBytes(msg))
+
+	// Without strict byte sizes batches are flushed after exceeding the size.
+	conf.StrictByteSize = false
+	pol, err = policy.New(conf, mock.NewManager())
+	require.NoError(t, err)
+
+	require.False(t, pol.Add(message.NewPart([]byte("foo bar"))))
+	assert.False(t, pol.FlushBefore(message.QuickBatch([][]byte{[]byte("baz qux")})))
+}
+
 func TestPolicyCheck(t *testing.T) {
 	conf := batchconfig.NewConfig()
 	conf.Check = `content() == "bar"`
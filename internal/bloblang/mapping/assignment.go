@@ -3,7 +3,6 @@ package mapping
 import (
 	"errors"
 	"fmt"
-	"strconv"
 	"strings"
 
 	"github.com/Jeffail/gabs/v2"
@@ -26,6 +25,10 @@ type AssignmentContext struct {
 	Vars  map[string]any
 	Meta  metaMsg
 	Value *any
+
+	// When set the value is assigned to with copy-on-write semantics,
+	// otherwise it is modified in place.
+	cow *cowState
 }
 
 // Assignment represents a way of assigning a queried value to something within
@@ -166,51 +169,41 @@ func findTheNonObject(gObj *gabs.Container, allowArray bool, paths ...string) (c
 // Apply a value to the target JSON path.
 func (j *JSONAssignment) Apply(val any, ctx AssignmentContext) error {
 	_, deleted := val.(value.Delete)
-	if !deleted {
+	if !deleted && ctx.cow == nil {
 		val = value.IClone(val)
 	}
 	if len(j.path) == 0 {
+		ctx.cow.replaceRoot()
 		*ctx.Value = val
 		return nil
 	}
 	if _, isNothing := (*ctx.Value).(value.Nothing); isNothing || *ctx.Value == nil {
-		*ctx.Value = map[string]any{}
+		*ctx.Value = ctx.cow.newMap()
 	}
 
-	gObj := gabs.Wrap(*ctx.Value)
 	if deleted {
-		if len(j.path) > 0 {
-			_ = gObj.Delete(j.path...)
-		}
-	} else {
-		_, err := gObj.Set(val, j.path...)
-		if err != nil && err.Error() == "unable to append new array index at root of path" {
-			if s, ok := (*ctx.Value).([]any); ok {
-				newPath := make([]string, len(j.path))
-				copy(newPath, j.path)
-				newPath[0] = strconv.Itoa(len(s))
-				gObj = gabs.Wrap(append(s, map[string]any{}))
-				_, err = gObj.Set(val, newPath...)
-			}
-		}
-		if err != nil {
-			if errors.Is(err, gabs.ErrPathCollision) {
-				culprit, typeStr := findTheNonObject(gObj, false, j.path...)
-				if culprit == "" {
-					return fmt.Errorf(
-						"unable to set target path %v as the value of the root was a non-object type (%v)",
-						query.SliceToDotPath(j.path...), typeStr,
-					)
-				}
+		*ctx.Value, _ = ctx.cow.delete(*ctx.Value, j.path, 0)
+		return nil
+	}
+
+	newValue, err := ctx.cow.set(*ctx.Value, val, j.path, 0)
+	if err != nil {
+		if errors.Is(err, gabs.ErrPathCollision) {
+			culprit, typeStr := findTheNonObject(gabs.Wrap(*ctx.Value), false, j.path...)
+			if culprit == "" {
 				return fmt.Errorf(
-					"unable to set target path %v as the value of %v was a non-object type (%v)",
-					query.SliceToDotPath(j.path...), culprit, typeStr,
+					"unable to set target path %v as the value of the root was a non-object type (%v)",
+					query.SliceToDotPath(j.path...), typeStr,
 				)
 			}
-			return fmt.Errorf("unable to set target path %v: %w", query.SliceToDotPath(j.path...), err)
+			return fmt.Errorf(
+				"unable to set target path %v as the value of %v was a non-object type (%v)",
+				query.SliceToDotPath(j.path...), culprit, typeStr,
+			)
 		}
+		return fmt.Errorf("unable to set target path %v: %w", query.SliceToDotPath(j.path...), err)
 	}
-	*ctx.Value = gObj.Data()
+	*ctx.Value = newValue
 	return nil
 }
 
@@ -0,0 +1,249 @@
+package mapping
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"unsafe"
+
+	"github.com/Jeffail/gabs/v2"
+)
+
+// cowState tracks the structured values created by the assignments of a single
+// mapping execution in order to implement copy-on-write semantics. Rather than
+// cloning assigned values, and the document being assigned onto, assignments
+// copy only the objects and arrays along the path being assigned and otherwise
+// share subtrees with their origins.
+//
+// Objects and arrays that were copied (or created) by an assignment are owned
+// by the execution and can be modified in place by subsequent assignments, as
+// long as they can't have been referenced elsewhere since, which means
+// ownership must be dropped whenever the document being assigned to is
+// queried.
+//
+// A nil cowState indicates that the document being assigned to is mutable and
+// can be modified in place, which requires assigned values to be cloned.
+type cowState struct {
+	// When nil objects and arrays are never owned and are always copied.
+	owned        map[unsafe.Pointer]struct{}
+	rootReplaced bool
+}
+
+func newCowState(allowOwnership bool) *cowState {
+	c := &cowState{}
+	if allowOwnership {
+		c.owned = map[unsafe.Pointer]struct{}{}
+	}
+	return c
+}
+
+// Holding the pointers within the owned map also prevents the owned values
+// from being garbage collected and their addresses being recycled.
+func cowPointer(v any) unsafe.Pointer {
+	switch t := v.(type) {
+	case map[string]any:
+		return reflect.ValueOf(t).UnsafePointer()
+	case []any:
+		if cap(t) == 0 {
+			return nil
+		}
+		return unsafe.Pointer(unsafe.SliceData(t))
+	}
+	return nil
+}
+
+func (c *cowState) own(v any) {
+	if c == nil || c.owned == nil {
+		return
+	}
+	if p := cowPointer(v); p != nil {
+		c.owned[p] = struct{}{}
+	}
+}
+
+func (c *cowState) isMutable(v any) bool {
+	if c == nil {
+		return true
+	}
+	if c.owned == nil {
+		return false
+	}
+	_, exists := c.owned[cowPointer(v)]
+	return exists
+}
+
+// disown drops ownership of all values, which is necessary once the document
+// being assigned to has been exposed to queries.
+func (c *cowState) disown() {
+	if c == nil || c.owned == nil {
+		return
+	}
+	clear(c.owned)
+}
+
+func (c *cowState) replaceRoot() {
+	if c != nil {
+		c.rootReplaced = true
+	}
+}
+
+func (c *cowState) newMap() map[string]any {
+	m := map[string]any{}
+	c.own(m)
+	return m
+}
+
+func (c *cowState) mutableMap(m map[string]any) map[string]any {
+	if c.isMutable(m) {
+		return m
+	}
+	newM := make(map[string]any, len(m)+1)
+	for k, v := range m {
+		newM[k] = v
+	}
+	c.own(newM)
+	return newM
+}
+
+func (c *cowState) mutableSlice(s []any) []any {
+	if c.isMutable(s) {
+		return s
+	}
+	newS := make([]any, len(s), len(s)+1)
+	copy(newS, s)
+	c.own(newS)
+	return newS
+}
+
+func (c *cowState) appendSlice(s []any, v any) []any {
+	if c.isMutable(s) {
+		newS := append(s, v)
+		c.own(newS)
+		return newS
+	}
+	newS := make([]any, len(s), len(s)+1)
+	copy(newS, s)
+	newS = append(newS, v)
+	c.own(newS)
+	return newS
+}
+
+func (c *cowState) removeIndex(s []any, index int) []any {
+	if c.isMutable(s) {
+		return append(s[:index], s[index+1:]...)
+	}
+	newS := make([]any, 0, len(s))
+	newS = append(newS, s[:index]...)
+	newS = append(newS, s[index+1:]...)
+	c.own(newS)
+	return newS
+}
+
+// set returns the target with the value set at the path, where the path is
+// resolved from the segment of the given depth. Objects are created for
+// intermediate path segments that do not exist and a segment of `-` appends
+// to an array.
+func (c *cowState) set(target, val any, path []string, depth int) (any, error) {
+	if depth == len(path) {
+		return val, nil
+	}
+
+	seg, isLast := path[depth], depth == len(path)-1
+	switch t := target.(type) {
+	case map[string]any:
+		var child any
+		if !isLast {
+			if child = t[seg]; child == nil {
+				child = c.newMap()
+			}
+		}
+		newChild, err := c.set(child, val, path, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		m := c.mutableMap(t)
+		m[seg] = newChild
+		return m, nil
+	case []any:
+		if seg == "-" {
+			var child any
+			if !isLast {
+				child = c.newMap()
+			}
+			newChild, err := c.set(child, val, path, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			return c.appendSlice(t, newChild), nil
+		}
+		index, err := strconv.Atoi(seg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve path segment '%v': found array but segment value '%v' could not be parsed into array index: %v", depth, seg, err)
+		}
+		if index < 0 {
+			return nil, fmt.Errorf("failed to resolve path segment '%v': found array but index '%v' is invalid", depth, seg)
+		}
+		if len(t) <= index {
+			return nil, fmt.Errorf("failed to resolve path segment '%v': found array but index '%v' exceeded target array size of '%v'", depth, seg, len(t))
+		}
+		var child any
+		if !isLast {
+			if child = t[index]; child == nil {
+				return nil, fmt.Errorf("failed to resolve path segment '%v': field '%v' was not found", depth, seg)
+			}
+		}
+		newChild, err := c.set(child, val, path, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		s := c.mutableSlice(t)
+		s[index] = newChild
+		return s, nil
+	}
+	return nil, gabs.ErrPathCollision
+}
+
+// delete returns the target with the value at the path removed, and whether
+// the target was modified. Paths that do not exist are ignored, as are array
+// indexes at the root of the path.
+func (c *cowState) delete(target any, path []string, depth int) (any, bool) {
+	seg, isLast := path[depth], depth == len(path)-1
+	switch t := target.(type) {
+	case map[string]any:
+		child, exists := t[seg]
+		if !exists {
+			return target, false
+		}
+		if isLast {
+			m := c.mutableMap(t)
+			delete(m, seg)
+			return m, true
+		}
+		newChild, changed := c.delete(child, path, depth+1)
+		if !changed {
+			return target, false
+		}
+		m := c.mutableMap(t)
+		m[seg] = newChild
+		return m, true
+	case []any:
+		index, err := strconv.Atoi(seg)
+		if err != nil || index < 0 || index >= len(t) {
+			return target, false
+		}
+		if isLast {
+			if depth == 0 {
+				return target, false
+			}
+			return c.removeIndex(t, index), true
+		}
+		newChild, changed := c.delete(t[index], path, depth+1)
+		if !changed {
+			return target, false
+		}
+		s := c.mutableSlice(t)
+		s[index] = newChild
+		return s, true
+	}
+	return target, false
+}
@@ -0,0 +1,69 @@
+package mapping
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCowOwnership(t *testing.T) {
+	newInput := func() map[string]any {
+		return map[string]any{
+			"a": map[string]any{"b": "old"},
+			"c": []any{"d"},
+		}
+	}
+	mapPtr := func(v any) uintptr {
+		return reflect.ValueOf(v).Pointer()
+	}
+
+	input := newInput()
+	c := newCowState(true)
+
+	first, err := c.set(input, "new", []string{"a", "b"}, 0)
+	require.NoError(t, err)
+	assert.NotEqual(t, mapPtr(input), mapPtr(first))
+	assert.Equal(t, mapPtr(input["c"]), mapPtr(first.(map[string]any)["c"]))
+
+	// Values copied by the previous assignment are modified in place.
+	second, err := c.set(first, "new", []string{"a", "e"}, 0)
+	require.NoError(t, err)
+	assert.Equal(t, mapPtr(first), mapPtr(second))
+	assert.Equal(t, mapPtr(first.(map[string]any)["a"]), mapPtr(second.(map[string]any)["a"]))
+
+	// Once disowned values are copied again.
+	c.disown()
+	third, err := c.set(second, "new", []string{"c", "-"}, 0)
+	require.NoError(t, err)
+	assert.NotEqual(t, mapPtr(second), mapPtr(third))
+
+	fourth, changed := c.delete(third, []string{"a", "b"}, 0)
+	assert.True(t, changed)
+	assert.Equal(t, mapPtr(third), mapPtr(fourth))
+
+	_, changed = c.delete(fourth, []string{"nope", "b"}, 0)
+	assert.False(t, changed)
+
+	assert.Equal(t, newInput(), input)
+	assert.Equal(t, map[string]any{
+		"a": map[string]any{"e": "new"},
+		"c": []any{"d", "new"},
+	}, fourth)
+}
+
+func TestCowWithoutOwnership(t *testing.T) {
+	input := map[string]any{"a": map[string]any{"b": "old"}}
+	c := newCowState(false)
+
+	first, err := c.set(input, "new", []string{"a", "b"}, 0)
+	require.NoError(t, err)
+
+	second, err := c.set(first, "new", []string{"a", "c"}, 0)
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]any{"a": map[string]any{"b": "old"}}, input)
+	assert.Equal(t, map[string]any{"a": map[string]any{"b": "new"}}, first)
+	assert.Equal(t, map[string]any{"a": map[string]any{"b": "new", "c": "new"}}, second)
+}
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"sync"
 
 	"github.com/benthosdev/benthos/v4/internal/bloblang/query"
 	"github.com/benthosdev/benthos/v4/internal/message"
@@ -46,6 +47,9 @@ type Executor struct {
 	statements []Statement
 
 	maxMapStacks int
+
+	queriesRootOnce sync.Once
+	queriesRoot     bool
 }
 
 const defaultMaxMapStacks = 5000
@@ -71,6 +75,22 @@ func (e *Executor) SetMaxMapRecursion(m int) {
 	e.maxMapStacks = m
 }
 
+// queriesNewValue returns whether any statement of the mapping queries the
+// document being created (with `root`), in which case values assigned to the
+// document might be referenced elsewhere and can't be modified in place.
+func (e *Executor) queriesNewValue() bool {
+	e.queriesRootOnce.Do(func() {
+		_, paths := e.QueryTargets(query.TargetsContext{Maps: e.maps})
+		for _, p := range paths {
+			if p.Type == query.TargetRoot {
+				e.queriesRoot = true
+				return
+			}
+		}
+	})
+	return e.queriesRoot
+}
+
 // Annotation returns a string annotation that describes the mapping executor.
 func (e *Executor) Annotation() string {
 	return e.annotation
@@ -126,7 +146,7 @@ func (e *Executor) mapPart(appendTo *message.Part, index int, reference Message)
 	var valuePtr *any
 	var parseErr error
 
-	lazyValue := func() *any {
+	parseValue := func() *any {
 		if valuePtr == nil && parseErr == nil {
 			if jObj, err := reference.Get(index).AsStructured(); err == nil {
 				valuePtr = &jObj
@@ -140,16 +160,35 @@ func (e *Executor) mapPart(appendTo *message.Part, index int, reference Message)
 		}
 		return valuePtr
 	}
+	lazyValue := parseValue
 
 	var newPart *message.Part
 	var newValue any = value.Nothing(nil)
 
+	// Assignments share unchanged subtrees with the original document rather
+	// than modifying it, and therefore the result is read-only.
+	cow := newCowState(!e.queriesNewValue())
+
 	if appendTo == nil {
 		newPart = reference.Get(index).ShallowCopy()
 	} else {
 		newPart = appendTo
-		if appendObj, err := appendTo.AsStructuredMut(); err == nil {
+		if appendObj, err := appendTo.AsStructured(); err == nil {
 			newValue = appendObj
+			if reference.Get(index) == appendTo {
+				// When mapping onto the referenced message itself, `this`
+				// reflects the assignments made to the document until the
+				// root is replaced, after which it is the original document
+				// unless it was already referenced.
+				lazyValue = func() *any {
+					if !cow.rootReplaced {
+						cow.disown()
+						thisValue := newValue
+						valuePtr = &thisValue
+					}
+					return parseValue()
+				}
+			}
 		}
 	}
 
@@ -168,6 +207,7 @@ func (e *Executor) mapPart(appendTo *message.Part, index int, reference Message)
 				Vars:  vars,
 				Meta:  newPart,
 				Value: &newValue,
+				cow:   cow,
 			},
 		)
 		if err != nil {
@@ -200,7 +240,7 @@ func (e *Executor) mapPart(appendTo *message.Part, index int, reference Message)
 		case []byte:
 			newPart.SetBytes(t)
 		default:
-			newPart.SetStructuredMut(newValue)
+			newPart.SetStructured(newValue)
 		}
 	}
 	return newPart, nil
@@ -269,3 +269,137 @@ func TestMappingParallelExecution(t *testing.T) {
 		})
 	}
 }
+
+func TestMappingCopyOnWrite(t *testing.T) {
+	tests := map[string]struct {
+		mapping  string
+		mutation bool
+		output   any
+	}{
+		"mapping nested field": {
+			mapping: `root = this
+root.a.b = "new"`,
+			output: map[string]any{
+				"a": map[string]any{"b": "new", "c": "old"},
+				"d": map[string]any{"e": "old"},
+				"f": []any{"g", map[string]any{"h": "old"}},
+			},
+		},
+		"mutation nested fields": {
+			mapping: `root.a.b = "new"
+root.f.1.h = this.a.b
+root.f."-" = "appended"
+root.d.e = deleted()`,
+			mutation: true,
+			output: map[string]any{
+				"a": map[string]any{"b": "new", "c": "old"},
+				"d": map[string]any{},
+				"f": []any{"g", map[string]any{"h": "new"}, "appended"},
+			},
+		},
+		"mutation delete array index": {
+			mapping:  `root.f.0 = deleted()`,
+			mutation: true,
+			output: map[string]any{
+				"a": map[string]any{"b": "old", "c": "old"},
+				"d": map[string]any{"e": "old"},
+				"f": []any{map[string]any{"h": "old"}},
+			},
+		},
+		"mutation root replaced": {
+			mapping: `root.a.b = "new"
+root = this.a
+root.c = "also new"
+root.seen = this.a.b
+root.nested = this.d`,
+			mutation: true,
+			output: map[string]any{
+				"b":      "new",
+				"c":      "also new",
+				"seen":   "new",
+				"nested": map[string]any{"e": "old"},
+			},
+		},
+		"mutation root replaced before this": {
+			mapping: `root = {}
+root.id = this.a.b
+root.nested = this.d`,
+			mutation: true,
+			output: map[string]any{
+				"id":     "old",
+				"nested": map[string]any{"e": "old"},
+			},
+		},
+		"mapping assigned values are not aliased": {
+			mapping: `root.x = this.a
+root.y = root.x
+root.x.b = "new"
+root.y.c = "also new"`,
+			output: map[string]any{
+				"x": map[string]any{"b": "new", "c": "old"},
+				"y": map[string]any{"b": "old", "c": "also new"},
+			},
+		},
+		"mutation assigned values are not aliased": {
+			mapping: `root.x.a.b = "old"
+root.y = this.x
+root.x.a.b = "new"`,
+			mutation: true,
+			output: map[string]any{
+				"a": map[string]any{"b": "old", "c": "old"},
+				"d": map[string]any{"e": "old"},
+				"f": []any{"g", map[string]any{"h": "old"}},
+				"x": map[string]any{"a": map[string]any{"b": "new"}},
+				"y": map[string]any{"a": map[string]any{"b": "old"}},
+			},
+		},
+	}
+
+	newInput := func() any {
+		return map[string]any{
+			"a": map[string]any{"b": "old", "c": "old"},
+			"d": map[string]any{"e": "old"},
+			"f": []any{"g", map[string]any{"h": "old"}},
+		}
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			m, err := GlobalEnvironment().NewMapping(test.mapping)
+			require.NoError(t, err)
+
+			input := newInput()
+			inPart := message.NewPart(nil)
+			inPart.SetStructured(input)
+
+			var resPart *message.Part
+			if test.mutation {
+				part := inPart.ShallowCopy()
+				resPart, err = m.MapOnto(part, 0, message.Batch{part})
+			} else {
+				resPart, err = m.MapPart(0, message.Batch{inPart})
+			}
+			require.NoError(t, err)
+
+			res, err := resPart.AsStructured()
+			require.NoError(t, err)
+			assert.Equal(t, test.output, res)
+
+			// The original document must remain unchanged.
+			assert.Equal(t, newInput(), input)
+
+			// Mutating the result must not modify the original document.
+			resMut, err := resPart.AsStructuredMut()
+			require.NoError(t, err)
+			if obj, ok := resMut.(map[string]any); ok {
+				for _, v := range obj {
+					if child, ok := v.(map[string]any); ok {
+						child["mutated"] = true
+					}
+				}
+			}
+			assert.Equal(t, newInput(), input)
+		})
+	}
+}
@@ -1,7 +1,9 @@
 package parser
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"strconv"
 	"testing"
 	"time"
@@ -350,6 +352,38 @@ bar""")`,
 				{err: errors.New("test error")},
 			},
 		},
+		"error class function": {
+			input:  `[error_class(), error_class().from(1), error_class().from(2), error_class().from(3)]`,
+			output: `[null,"permanent","transient","validation"]`,
+			messages: []easyMsg{
+				{},
+				{err: errors.New("test error")},
+				{err: fmt.Errorf("test error: %w", context.DeadlineExceeded)},
+				{err: message.NewProcessorError(message.ErrorWithClass(errors.New("test error"), message.ErrorClassValidation), "foo", "mapping", nil)},
+			},
+		},
+		"error source functions": {
+			input:  `[error_source_label(), error_source_name(), error_source_path()]`,
+			output: `["foo","mapping","root.pipeline.processors.0"]`,
+			messages: []easyMsg{
+				{err: message.NewProcessorError(errors.New("test error"), "foo", "mapping", []string{"pipeline", "processors", "0"})},
+			},
+		},
+		"error source functions unknown": {
+			input:  `[error_source_label(), error_source_name(), error_source_path()]`,
+			output: `[null,null,null]`,
+			messages: []easyMsg{
+				{err: errors.New("test error")},
+			},
+		},
+		"error chain function": {
+			input:  `[error_chain(), error_chain().from(1)]`,
+			output: `[["failed: test error","test error"],null]`,
+			messages: []easyMsg{
+				{err: message.NewProcessorError(fmt.Errorf("failed: %w", errors.New("test error")), "", "mapping", nil)},
+				{},
+			},
+		},
 		"errored function else": {
 			input:  `if errored() { "failed" } else { "succeeded" }`,
 			output: `succeeded`,
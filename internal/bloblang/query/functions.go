@@ -14,6 +14,7 @@ import (
 	gonanoid "github.com/matoous/go-nanoid/v2"
 	"github.com/segmentio/ksuid"
 
+	"github.com/benthosdev/benthos/v4/internal/message"
 	"github.com/benthosdev/benthos/v4/internal/tracing"
 	"github.com/benthosdev/benthos/v4/internal/value"
 )
@@ -365,6 +366,109 @@ var _ = registerSimpleFunction(
 	},
 )
 
+var _ = registerSimpleFunction(
+	NewFunctionSpec(
+		FunctionCategoryMessage, "error_class",
+		"If an error has occurred during the processing of a message this function returns the class of the error, otherwise `null`. The class is one of `transient`, where retrying the failed operation might succeed such as after a timeout or a lost connection, `validation`, where the contents of the message are invalid such as when they cannot be parsed or fail a schema, or `permanent` for all other errors. For more information about error handling patterns read [here][error_handling].",
+		NewExampleSpec("",
+			`root.doc.retry = error_class() == "transient"`,
+		),
+	).Beta(),
+	func(ctx FunctionContext) (any, error) {
+		err := ctx.MsgBatch.Get(ctx.Index).ErrorGet()
+		if err == nil {
+			return nil, nil
+		}
+		class, ok := message.ErrorClassOf(err)
+		if !ok {
+			class = message.ErrorClassPermanent
+		}
+		return string(class), nil
+	},
+)
+
+func processorErrorOf(ctx FunctionContext) *message.ProcessorError {
+	var pErr *message.ProcessorError
+	if errors.As(ctx.MsgBatch.Get(ctx.Index).ErrorGet(), &pErr) {
+		return pErr
+	}
+	return nil
+}
+
+var _ = registerSimpleFunction(
+	NewFunctionSpec(
+		FunctionCategoryMessage, "error_source_label",
+		"Returns the label of the processor that caused the error of a message, or `null` if the message has no error or the processor is not labelled. For more information about error handling patterns read [here][error_handling].",
+		NewExampleSpec("",
+			`root.doc.failed_at = error_source_label()`,
+		),
+	).Beta(),
+	func(ctx FunctionContext) (any, error) {
+		if pErr := processorErrorOf(ctx); pErr != nil && pErr.Label != "" {
+			return pErr.Label, nil
+		}
+		return nil, nil
+	},
+)
+
+var _ = registerSimpleFunction(
+	NewFunctionSpec(
+		FunctionCategoryMessage, "error_source_name",
+		"Returns the type of the processor that caused the error of a message, such as `http`, or `null` if the message has no error or the processor is unknown. For more information about error handling patterns read [here][error_handling].",
+		NewExampleSpec("",
+			`root.doc.failed_by = error_source_name()`,
+		),
+	).Beta(),
+	func(ctx FunctionContext) (any, error) {
+		if pErr := processorErrorOf(ctx); pErr != nil && pErr.Name != "" {
+			return pErr.Name, nil
+		}
+		return nil, nil
+	},
+)
+
+var _ = registerSimpleFunction(
+	NewFunctionSpec(
+		FunctionCategoryMessage, "error_source_path",
+		"Returns the path of the processor that caused the error of a message within the config, such as `root.pipeline.processors.0`, or `null` if the message has no error or the processor is unknown. For more information about error handling patterns read [here][error_handling].",
+		NewExampleSpec("",
+			`root.doc.failed_at = error_source_path()`,
+		),
+	).Beta(),
+	func(ctx FunctionContext) (any, error) {
+		if pErr := processorErrorOf(ctx); pErr != nil && len(pErr.Path) > 0 {
+			return "root." + SliceToDotPath(pErr.Path...), nil
+		}
+		return nil, nil
+	},
+)
+
+var _ = registerSimpleFunction(
+	NewFunctionSpec(
+		FunctionCategoryMessage, "error_chain",
+		"If an error has occurred during the processing of a message this function returns an array of the causes of the error as strings, starting with the error itself and followed by each of the errors it wraps, otherwise `null`. For more information about error handling patterns read [here][error_handling].",
+		NewExampleSpec("",
+			`root.doc.root_cause = error_chain().index(-1)`,
+		),
+	).Beta(),
+	func(ctx FunctionContext) (any, error) {
+		err := ctx.MsgBatch.Get(ctx.Index).ErrorGet()
+		if err == nil {
+			return nil, nil
+		}
+		var chain []any
+		for ; err != nil; err = errors.Unwrap(err) {
+			// Errors that wrap others without adding context are skipped.
+			errStr := err.Error()
+			if len(chain) > 0 && chain[len(chain)-1] == errStr {
+				continue
+			}
+			chain = append(chain, errStr)
+		}
+		return chain, nil
+	},
+)
+
 //------------------------------------------------------------------------------
 
 var _ = registerFunction(
@@ -32,16 +32,23 @@ type metricsCache struct {
 	mDelError   metrics.StatCounter
 	mDelSuccess metrics.StatCounter
 	mDelLatency metrics.StatTimer
+
+	mItems metrics.StatGauge
+	mBytes metrics.StatGauge
 }
 
+// The interval at which the size of caches that implement Sizer is measured.
+var cacheSizeInterval = time.Second * 5
+
 // MetricsForCache wraps a cache with a struct that adds standard metrics over
-// each method.
+// each method. When the cache implements Sizer its size is also measured
+// periodically.
 func MetricsForCache(c V1, stats metrics.Type) V1 {
 	cacheSuccess := stats.GetCounterVec("cache_success", "operation")
 	cacheError := stats.GetCounterVec("cache_error", "operation")
 	cacheLatency := stats.GetTimerVec("cache_latency_ns", "operation")
 
-	return &metricsCache{
+	m := &metricsCache{
 		c: c, sig: shutdown.NewSignaller(),
 
 		mGetNotFound: stats.GetCounterVec("cache_not_found", "operation").With("get"),
@@ -62,6 +69,34 @@ func MetricsForCache(c V1, stats metrics.Type) V1 {
 		mDelSuccess: cacheSuccess.With("delete"),
 		mDelLatency: cacheLatency.With("delete"),
 	}
+
+	if sizer, ok := c.(Sizer); ok {
+		m.mItems = stats.GetGauge("cache_items")
+		m.mBytes = stats.GetGauge("cache_size_bytes")
+		go m.measureSizeLoop(sizer)
+	} else {
+		m.sig.TriggerHasStopped()
+	}
+	return m
+}
+
+func (a *metricsCache) measureSizeLoop(sizer Sizer) {
+	defer a.sig.TriggerHasStopped()
+
+	ticker := time.NewTicker(cacheSizeInterval)
+	defer ticker.Stop()
+
+	for {
+		items, bytes := sizer.Size()
+		a.mItems.Set(items)
+		a.mBytes.Set(bytes)
+
+		select {
+		case <-ticker.C:
+		case <-a.sig.HardStopChan():
+			return
+		}
+	}
 }
 
 func (a *metricsCache) Get(ctx context.Context, key string) ([]byte, error) {
@@ -80,6 +115,25 @@ func (a *metricsCache) Get(ctx context.Context, key string) ([]byte, error) {
 	return b, err
 }
 
+func (a *metricsCache) GetMulti(ctx context.Context, keys []string) (map[string][]byte, error) {
+	started := time.Now()
+	values, err := a.c.GetMulti(ctx, keys)
+	a.mGetLatency.Timing(int64(time.Since(started)))
+	var keyErrs KeyErrors
+	if err != nil && !errors.As(err, &keyErrs) {
+		a.mGetError.Incr(int64(len(keys)))
+	} else {
+		a.mGetSuccess.Incr(int64(len(values)))
+		if len(keyErrs) > 0 {
+			a.mGetError.Incr(int64(len(keyErrs)))
+		}
+		if notFound := len(keys) - len(values) - len(keyErrs); notFound > 0 {
+			a.mGetNotFound.Incr(int64(notFound))
+		}
+	}
+	return values, err
+}
+
 func (a *metricsCache) Set(ctx context.Context, key string, value []byte, ttl *time.Duration) error {
 	started := time.Now()
 	err := a.c.Set(ctx, key, value, ttl)
@@ -133,5 +187,11 @@ func (a *metricsCache) Delete(ctx context.Context, key string) error {
 }
 
 func (a *metricsCache) Close(ctx context.Context) error {
+	a.sig.TriggerHardStop()
+	select {
+	case <-a.sig.HasStoppedChan():
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 	return a.c.Close(ctx)
 }
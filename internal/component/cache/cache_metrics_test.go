@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/benthosdev/benthos/v4/internal/component"
 	"github.com/benthosdev/benthos/v4/internal/component/metrics"
@@ -33,6 +34,19 @@ func (c *closableCache) Get(ctx context.Context, key string) ([]byte, error) {
 	return i.b, nil
 }
 
+func (c *closableCache) GetMulti(ctx context.Context, keys []string) (map[string][]byte, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	values := map[string][]byte{}
+	for _, k := range keys {
+		if i, ok := c.m[k]; ok {
+			values[k] = i.b
+		}
+	}
+	return values, nil
+}
+
 func (c *closableCache) Set(ctx context.Context, key string, value []byte, ttl *time.Duration) error {
 	if c.err != nil {
 		return c.err
@@ -110,6 +124,22 @@ func TestCacheAirGapGet(t *testing.T) {
 	assert.EqualError(t, err, "key does not exist")
 }
 
+func TestCacheAirGapGetMulti(t *testing.T) {
+	ctx := context.Background()
+	rl := &closableCache{
+		m: map[string]testCacheItem{
+			"foo": {
+				b: []byte("bar"),
+			},
+		},
+	}
+	agrl := MetricsForCache(rl, metrics.Noop())
+
+	values, err := agrl.GetMulti(ctx, []string{"foo", "not exist"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]byte{"foo": []byte("bar")}, values)
+}
+
 func TestCacheAirGapSet(t *testing.T) {
 	ctx := context.Background()
 	rl := &closableCache{
@@ -253,3 +283,36 @@ func TestCacheAirGapDelete(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, map[string]testCacheItem{}, rl.m)
 }
+
+type sizedCache struct {
+	closableCache
+}
+
+func (c *sizedCache) Size() (items, bytes int64) {
+	for k, v := range c.m {
+		items++
+		bytes += int64(len(k) + len(v.b))
+	}
+	return
+}
+
+func TestCacheMetricsSize(t *testing.T) {
+	rl := &sizedCache{
+		closableCache: closableCache{
+			m: map[string]testCacheItem{
+				"foo": {b: []byte("bar")},
+				"baz": {b: []byte("buzz")},
+			},
+		},
+	}
+	stats := metrics.NewLocal()
+	agrl := MetricsForCache(rl, stats)
+
+	assert.Eventually(t, func() bool {
+		counters := stats.GetCounters()
+		return counters["cache_items"] == 2 && counters["cache_size_bytes"] == 13
+	}, time.Second, time.Millisecond*10)
+
+	require.NoError(t, agrl.Close(context.Background()))
+	assert.True(t, rl.closed)
+}
@@ -2,6 +2,9 @@ package cache
 
 import (
 	"context"
+	"fmt"
+	"sort"
+	"strings"
 	"time"
 )
 
@@ -17,6 +20,12 @@ type V1 interface {
 	// error if the key does not exist or if the command fails.
 	Get(ctx context.Context, key string) ([]byte, error)
 
+	// GetMulti attempts to locate and return the cached values of multiple
+	// keys, where keys that do not exist are omitted from the result. Returns
+	// an error if the command fails, or KeyErrors along with the values of the
+	// remaining keys if only the lookups of individual keys failed.
+	GetMulti(ctx context.Context, keys []string) (map[string][]byte, error)
+
 	// Set attempts to set the value of a key, returns an error if the command
 	// fails.
 	Set(ctx context.Context, key string, value []byte, ttl *time.Duration) error
@@ -38,3 +47,30 @@ type V1 interface {
 	// is cancelled.
 	Close(ctx context.Context) error
 }
+
+// Sizer is an optional interface implemented by caches that hold items in
+// memory, which reports approximately how many items are held and how many
+// bytes their keys and values occupy.
+type Sizer interface {
+	Size() (items, bytes int64)
+}
+
+// KeyErrors is returned by GetMulti when the lookups of individual keys failed
+// but the values of the remaining keys were obtained, and maps each failed key
+// to its error.
+type KeyErrors map[string]error
+
+// Error returns the errors of all failed keys.
+func (k KeyErrors) Error() string {
+	keys := make([]string, 0, len(k))
+	for key := range k {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	msgs := make([]string, 0, len(keys))
+	for _, key := range keys {
+		msgs = append(msgs, fmt.Sprintf("key '%v': %v", key, k[key]))
+	}
+	return strings.Join(msgs, ", ")
+}
@@ -4,6 +4,8 @@ import (
 	"errors"
 	"fmt"
 	"time"
+
+	"github.com/benthosdev/benthos/v4/internal/message"
 )
 
 // ErrNotUnwrapped is returned in cases where a component was meant to be
@@ -34,18 +36,18 @@ func ErrInvalidType(typeStr, tried string) error {
 
 // Errors used throughout the codebase.
 var (
-	ErrTimeout    = errors.New("action timed out")
+	ErrTimeout    = message.ErrorWithClass(errors.New("action timed out"), message.ErrorClassTransient)
 	ErrTypeClosed = errors.New("type was closed")
 
-	ErrNotConnected = errors.New("not connected to target source or sink")
+	ErrNotConnected = message.ErrorWithClass(errors.New("not connected to target source or sink"), message.ErrorClassTransient)
 
 	// ErrAlreadyStarted is returned when an input or output type gets started a
 	// second time.
 	ErrAlreadyStarted = errors.New("type has already been started")
 
-	ErrNoAck = errors.New("failed to receive acknowledgement")
+	ErrNoAck = message.ErrorWithClass(errors.New("failed to receive acknowledgement"), message.ErrorClassTransient)
 
-	ErrFailedSend = errors.New("message failed to reach a target destination")
+	ErrFailedSend = message.ErrorWithClass(errors.New("message failed to reach a target destination"), message.ErrorClassTransient)
 )
 
 // ErrBackOff is an error returned that allows for a back off duration to be specified
@@ -59,6 +61,12 @@ func (e *ErrBackOff) Error() string {
 	return e.Err.Error()
 }
 
+// ErrorClass returns the class of the error, which is transient as the
+// errored call is expected to be retried.
+func (e *ErrBackOff) ErrorClass() message.ErrorClass {
+	return message.ErrorClassTransient
+}
+
 //------------------------------------------------------------------------------
 
 // Manager errors.
@@ -130,6 +130,9 @@ func (m *Impl) loop() {
 				return
 			}
 
+			if m.batcher.FlushBefore(tran.Payload) {
+				flushBatchFn()
+			}
 			trackedTran := transaction.NewTracked(tran.Payload, tran.Ack)
 			_ = trackedTran.Message().Iter(func(i int, p *message.Part) error {
 				if m.batcher.Add(p) {
@@ -101,6 +101,16 @@ func (c *combinedGauge) DecrFloat64(count float64) {
 	c.c2.DecrFloat64(count)
 }
 
+type combinedHistogram struct {
+	c1 StatHistogram
+	c2 StatHistogram
+}
+
+func (c *combinedHistogram) Observe(value float64) {
+	c.c1.Observe(value)
+	c.c2.Observe(value)
+}
+
 //------------------------------------------------------------------------------
 
 type combinedCounterVec struct {
@@ -139,6 +149,18 @@ func (c *combinedGaugeVec) With(labelValues ...string) StatGauge {
 	}
 }
 
+type combinedHistogramVec struct {
+	c1 StatHistogramVec
+	c2 StatHistogramVec
+}
+
+func (c *combinedHistogramVec) With(labelValues ...string) StatHistogram {
+	return &combinedHistogram{
+		c1: c.c1.With(labelValues...),
+		c2: c.c2.With(labelValues...),
+	}
+}
+
 //------------------------------------------------------------------------------
 
 func (c *combinedWrapper) GetCounter(path string) StatCounter {
@@ -183,6 +205,20 @@ func (c *combinedWrapper) GetGaugeVec(path string, n ...string) StatGaugeVec {
 	}
 }
 
+func (c *combinedWrapper) GetHistogramVec(path string, buckets []float64, n ...string) StatHistogramVec {
+	return &combinedHistogramVec{
+		c1: GetHistogramVec(c.t1, path, buckets, n...),
+		c2: GetHistogramVec(c.t2, path, buckets, n...),
+	}
+}
+
+func (c *combinedWrapper) GetSummaryVec(path string, objectives map[float64]float64, n ...string) StatHistogramVec {
+	return &combinedHistogramVec{
+		c1: GetSummaryVec(c.t1, path, objectives, n...),
+		c2: GetSummaryVec(c.t2, path, objectives, n...),
+	}
+}
+
 func (c *combinedWrapper) HandlerFunc() http.HandlerFunc {
 	if h := c.t1.HandlerFunc(); h != nil {
 		return h
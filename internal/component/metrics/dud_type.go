@@ -26,6 +26,9 @@ func (d DudStat) IncrFloat64(count float64) {}
 // DecrFloat64 does nothing
 func (d DudStat) DecrFloat64(count float64) {}
 
+// Observe does nothing
+func (d DudStat) Observe(value float64) {}
+
 //------------------------------------------------------------------------------
 
 var _ Type = DudType{}
@@ -0,0 +1,39 @@
+package metrics
+
+// timerHistogram records the observations of a histogram as timings, for
+// aggregators that do not support histograms.
+type timerHistogram struct {
+	t StatTimer
+}
+
+func (t timerHistogram) Observe(value float64) {
+	t.t.Timing(int64(value))
+}
+
+func timerHistogramVec(tv StatTimerVec) StatHistogramVec {
+	return FakeHistogramVec(func(labelValues ...string) StatHistogram {
+		return timerHistogram{t: tv.With(labelValues...)}
+	})
+}
+
+// GetHistogramVec returns an editable histogram stat for a given path with
+// labels from a metrics aggregator. When the aggregator does not implement
+// HistogramType the observations are recorded as timings, where values are
+// truncated to integers.
+func GetHistogramVec(t Type, path string, buckets []float64, labelNames ...string) StatHistogramVec {
+	if ht, ok := t.(HistogramType); ok {
+		return ht.GetHistogramVec(path, buckets, labelNames...)
+	}
+	return timerHistogramVec(t.GetTimerVec(path, labelNames...))
+}
+
+// GetSummaryVec returns an editable summary stat for a given path with labels
+// from a metrics aggregator. When the aggregator does not implement
+// HistogramType the observations are recorded as timings, where values are
+// truncated to integers.
+func GetSummaryVec(t Type, path string, objectives map[float64]float64, labelNames ...string) StatHistogramVec {
+	if ht, ok := t.(HistogramType); ok {
+		return ht.GetSummaryVec(path, objectives, labelNames...)
+	}
+	return timerHistogramVec(t.GetTimerVec(path, labelNames...))
+}
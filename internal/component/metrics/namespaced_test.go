@@ -257,3 +257,23 @@ root = this.replace_all("bar","baz")`, log.Noop())
 	assert.Contains(t, body, "\ngaugetwo{extra1=\"extravalue1\",extra2=\"extravalue2\",label2=\"value3\",static1=\"sbaz1\"} 12")
 	assert.Contains(t, body, "\ntimertwo_sum{extra1=\"extravalue1\",extra2=\"extravalue2\",label3=\"value4\",label4=\"value5\",static1=\"sbaz1\"} 1.3e-08")
 }
+
+func TestNamespacedHistograms(t *testing.T) {
+	prom, handler := getTestProm(t)
+
+	nm := metrics.NewNamespaced(prom).WithLabels("component", "foo")
+
+	hist := nm.GetHistogramVec("histone", []float64{1, 10}, "label1")
+	hist.With("value1").Observe(5)
+	hist.With("value1").Observe(50)
+
+	sum := nm.GetSummaryVec("sumone", nil)
+	sum.With().Observe(2)
+
+	body := getPage(t, handler)
+
+	assert.Contains(t, body, "\nhistone_bucket{component=\"foo\",label1=\"value1\",le=\"1\"} 0")
+	assert.Contains(t, body, "\nhistone_bucket{component=\"foo\",label1=\"value1\",le=\"10\"} 1")
+	assert.Contains(t, body, "\nhistone_count{component=\"foo\",label1=\"value1\"} 2")
+	assert.Contains(t, body, "\nsumone_count{component=\"foo\"} 1")
+}
@@ -44,6 +44,14 @@ type StatGauge interface {
 	DecrFloat64(count float64)
 }
 
+// StatHistogram is a representation of a single histogram or summary metric
+// stat, where the distribution of observed values is tracked. Interactions with
+// this stat are thread safe.
+type StatHistogram interface {
+	// Observe adds a single observation to the distribution.
+	Observe(value float64)
+}
+
 //------------------------------------------------------------------------------
 
 // StatCounterVec creates StatCounters with dynamic labels.
@@ -64,6 +72,31 @@ type StatGaugeVec interface {
 	With(labelValues ...string) StatGauge
 }
 
+// StatHistogramVec creates StatHistograms with dynamic labels.
+type StatHistogramVec interface {
+	// With returns a StatHistogram with a set of label values.
+	With(labelValues ...string) StatHistogram
+}
+
+//------------------------------------------------------------------------------
+
+// HistogramType is an optional interface implemented by metrics aggregators
+// that support histograms with custom buckets and summaries with custom
+// quantile objectives. Use GetHistogramVec and GetSummaryVec in order to obtain
+// these stats from any Type.
+type HistogramType interface {
+	// GetHistogramVec returns an editable histogram stat for a given path with
+	// labels, where observations are counted within buckets of the provided
+	// upper bounds. When no buckets are provided the defaults of the
+	// implementation are used.
+	GetHistogramVec(path string, buckets []float64, labelNames ...string) StatHistogramVec
+
+	// GetSummaryVec returns an editable summary stat for a given path with
+	// labels, where quantiles are calculated for the provided objectives, a map
+	// of quantiles to their absolute errors.
+	GetSummaryVec(path string, objectives map[float64]float64, labelNames ...string) StatHistogramVec
+}
+
 //------------------------------------------------------------------------------
 
 // Type is an interface for metrics aggregation.
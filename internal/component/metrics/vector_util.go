@@ -48,3 +48,20 @@ func FakeGaugeVec(f func(...string) StatGauge) StatGaugeVec {
 		f: f,
 	}
 }
+
+//------------------------------------------------------------------------------
+
+type fHistogramVec struct {
+	f func(...string) StatHistogram
+}
+
+func (f *fHistogramVec) With(labels ...string) StatHistogram {
+	return f.f(labels...)
+}
+
+// FakeHistogramVec returns a histogram vec implementation that ignores labels.
+func FakeHistogramVec(f func(...string) StatHistogram) StatHistogramVec {
+	return &fHistogramVec{
+		f: f,
+	}
+}
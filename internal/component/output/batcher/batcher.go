@@ -82,6 +82,44 @@ func (m *Impl) loop() {
 	}
 
 	var pendingTrans []*transaction.Tracked
+
+	// Flushes the pending batch and sends it to the child output, returns false
+	// if the output is shutting down.
+	flushBatchFn := func() bool {
+		sendMsg := m.batcher.Flush(closeNowCtx)
+		if sendMsg == nil {
+			return true
+		}
+
+		resChan := make(chan error)
+		select {
+		case m.messagesOut <- message.NewTransaction(sendMsg, resChan):
+		case <-m.shutSig.SoftStopChan():
+			return false
+		}
+
+		go func(rChan chan error, upstreamTrans []*transaction.Tracked) {
+			select {
+			case <-m.shutSig.SoftStopChan():
+				return
+			case res, open := <-rChan:
+				if !open {
+					return
+				}
+				closeLeisureCtx, done := m.shutSig.SoftStopCtx(context.Background())
+				for _, t := range upstreamTrans {
+					if err := t.Ack(closeLeisureCtx, res); err != nil {
+						done()
+						return
+					}
+				}
+				done()
+			}
+		}(resChan, pendingTrans)
+		pendingTrans = nil
+		return true
+	}
+
 	for !m.shutSig.IsSoftStopSignalled() {
 		if nextTimedBatchChan == nil {
 			if tNext := m.batcher.UntilNext(); tNext > 0 {
@@ -105,6 +143,9 @@ func (m *Impl) loop() {
 					}
 				}
 			} else {
+				if m.batcher.FlushBefore(tran.Payload) && !flushBatchFn() {
+					return
+				}
 				trackedTran := transaction.NewTracked(tran.Payload, tran.Ack)
 				_ = trackedTran.Message().Iter(func(i int, p *message.Part) error {
 					if m.batcher.Add(p) {
@@ -125,37 +166,9 @@ func (m *Impl) loop() {
 			continue
 		}
 
-		sendMsg := m.batcher.Flush(closeNowCtx)
-		if sendMsg == nil {
-			continue
-		}
-
-		resChan := make(chan error)
-		select {
-		case m.messagesOut <- message.NewTransaction(sendMsg, resChan):
-		case <-m.shutSig.SoftStopChan():
+		if !flushBatchFn() {
 			return
 		}
-
-		go func(rChan chan error, upstreamTrans []*transaction.Tracked) {
-			select {
-			case <-m.shutSig.SoftStopChan():
-				return
-			case res, open := <-rChan:
-				if !open {
-					return
-				}
-				closeLeisureCtx, done := m.shutSig.SoftStopCtx(context.Background())
-				for _, t := range upstreamTrans {
-					if err := t.Ack(closeLeisureCtx, res); err != nil {
-						done()
-						return
-					}
-				}
-				done()
-			}
-		}(resChan, pendingTrans)
-		pendingTrans = nil
 	}
 }
 
@@ -60,6 +60,7 @@ type v2ToV1Processor struct {
 	typeStr string
 	p       AutoObserved
 	mgr     component.Observability
+	source  errSource
 
 	mReceived      metrics.StatCounter
 	mBatchReceived metrics.StatCounter
@@ -74,6 +75,7 @@ type v2ToV1Processor struct {
 func NewAutoObservedProcessor(typeStr string, p AutoObserved, mgr component.Observability) V1 {
 	return &v2ToV1Processor{
 		typeStr: typeStr, p: p, mgr: mgr,
+		source: newErrSource(typeStr, mgr),
 
 		mReceived:      mgr.Metrics().GetCounter("processor_received"),
 		mBatchReceived: mgr.Metrics().GetCounter("processor_batch_received"),
@@ -98,7 +100,7 @@ func (a *v2ToV1Processor) ProcessBatch(ctx context.Context, msg message.Batch) (
 		if err != nil {
 			a.mError.Incr(1)
 			a.mgr.Logger().Debug("Processor failed: %v", err)
-			MarkErr(part, span, err)
+			MarkErr(part, span, a.source.wrap(err))
 			nextParts = append(nextParts, part)
 		}
 
@@ -138,9 +140,10 @@ func TestBatchProcContext(ctx context.Context, spans []*tracing.Span, parts []*m
 // BatchProcContext provides methods for triggering observability updates and
 // accessing processor specific spans.
 type BatchProcContext struct {
-	ctx   context.Context
-	spans []*tracing.Span
-	parts []*message.Part
+	ctx    context.Context
+	spans  []*tracing.Span
+	parts  []*message.Part
+	source errSource
 
 	mError metrics.StatCounter
 	logger log.Modular
@@ -181,7 +184,7 @@ func (b *BatchProcContext) OnError(err error, index int, p *message.Part) {
 	if p == nil && len(b.parts) > index && index >= 0 {
 		p = b.parts[index]
 	}
-	MarkErr(p, span, err)
+	MarkErr(p, span, b.source.wrap(err))
 }
 
 // Implements types.Processor.
@@ -189,6 +192,7 @@ type v2BatchedToV1Processor struct {
 	typeStr string
 	p       AutoObservedBatched
 	mgr     component.Observability
+	source  errSource
 
 	mReceived      metrics.StatCounter
 	mBatchReceived metrics.StatCounter
@@ -203,6 +207,7 @@ type v2BatchedToV1Processor struct {
 func NewAutoObservedBatchedProcessor(typeStr string, p AutoObservedBatched, mgr component.Observability) V1 {
 	return &v2BatchedToV1Processor{
 		typeStr: typeStr, p: p, mgr: mgr,
+		source: newErrSource(typeStr, mgr),
 
 		mReceived:      mgr.Metrics().GetCounter("processor_received"),
 		mBatchReceived: mgr.Metrics().GetCounter("processor_batch_received"),
@@ -224,6 +229,7 @@ func (a *v2BatchedToV1Processor) ProcessBatch(ctx context.Context, msg message.B
 		ctx:    ctx,
 		spans:  spans,
 		parts:  msg,
+		source: a.source,
 		mError: a.mError,
 		logger: a.mgr.Logger(),
 	}, msg)
@@ -231,7 +237,7 @@ func (a *v2BatchedToV1Processor) ProcessBatch(ctx context.Context, msg message.B
 		a.mError.Incr(int64(msg.Len()))
 		a.mgr.Logger().Debug("Processor failed: %v", err)
 		_ = msg.Iter(func(i int, p *message.Part) error {
-			MarkErr(p, spans[i], err)
+			MarkErr(p, spans[i], a.source.wrap(err))
 			return nil
 		})
 		outputBatches = append(outputBatches, msg)
@@ -3,6 +3,7 @@ package processor
 import (
 	"context"
 	"errors"
+	"fmt"
 	"sync"
 	"testing"
 	"time"
@@ -257,3 +258,73 @@ func TestBatchProcessorAirGapIndividualErrors(t *testing.T) {
 	assert.NoError(t, msgs[0][1].ErrorGet())
 	assert.EqualError(t, msgs[0][2].ErrorGet(), "invalid character 'a' looking for beginning of value")
 }
+
+type labelledObservability struct {
+	component.Observability
+}
+
+func (labelledObservability) Label() string {
+	return "foo_label"
+}
+
+func (labelledObservability) Path() []string {
+	return []string{"pipeline", "processors", "0"}
+}
+
+func TestProcessorAirGapErrorSource(t *testing.T) {
+	tCtx := context.Background()
+	obs := labelledObservability{component.NoopObservability()}
+
+	agrp := NewAutoObservedProcessor("foo", &fnProcessor{
+		fn: func(c context.Context, m *message.Part) ([]*message.Part, error) {
+			return nil, component.ErrTimeout
+		},
+	}, obs)
+
+	msgs, err := agrp.ProcessBatch(tCtx, message.QuickBatch([][]byte{[]byte("hello")}))
+	require.NoError(t, err)
+	require.Len(t, msgs, 1)
+
+	var pErr *message.ProcessorError
+	require.ErrorAs(t, msgs[0].Get(0).ErrorGet(), &pErr)
+	assert.ErrorIs(t, pErr, component.ErrTimeout)
+	assert.Equal(t, message.ErrorClassTransient, pErr.Class)
+	assert.Equal(t, "foo_label", pErr.Label)
+	assert.Equal(t, "foo", pErr.Name)
+	assert.Equal(t, []string{"pipeline", "processors", "0"}, pErr.Path)
+
+	bagrp := NewAutoObservedBatchedProcessor("bar", &fnBatchProcessor{
+		fn: func(c *BatchProcContext, msgs message.Batch) ([]message.Batch, error) {
+			for i, m := range msgs {
+				if _, err := m.AsStructured(); err != nil {
+					c.OnError(err, i, nil)
+				}
+			}
+			return []message.Batch{msgs}, nil
+		},
+	}, obs)
+
+	msgs, err = bagrp.ProcessBatch(tCtx, message.QuickBatch([][]byte{
+		[]byte("not a structured doc"),
+		[]byte(`{"foo":"bar"}`),
+	}))
+	require.NoError(t, err)
+	require.Len(t, msgs, 1)
+
+	require.ErrorAs(t, msgs[0].Get(0).ErrorGet(), &pErr)
+	assert.Equal(t, message.ErrorClassValidation, pErr.Class)
+	assert.Equal(t, "bar", pErr.Name)
+	assert.NoError(t, msgs[0].Get(1).ErrorGet())
+
+	// Errors retain the processor they originated from.
+	agrp = NewAutoObservedProcessor("baz", &fnProcessor{
+		fn: func(c context.Context, m *message.Part) ([]*message.Part, error) {
+			return nil, fmt.Errorf("wrapped: %w", m.ErrorGet())
+		},
+	}, obs)
+
+	msgs, err = agrp.ProcessBatch(tCtx, msgs[0])
+	require.NoError(t, err)
+	require.ErrorAs(t, msgs[0].Get(0).ErrorGet(), &pErr)
+	assert.Equal(t, "bar", pErr.Name)
+}
@@ -1,6 +1,7 @@
 package processor
 
 import (
+	"github.com/benthosdev/benthos/v4/internal/component"
 	"github.com/benthosdev/benthos/v4/internal/message"
 	"github.com/benthosdev/benthos/v4/internal/tracing"
 )
@@ -26,3 +27,30 @@ func MarkErr(part *message.Part, span *tracing.Span, err error) {
 		)
 	}
 }
+
+// errSource describes a processor that errors attached to messages originate
+// from.
+type errSource struct {
+	label string
+	name  string
+	path  []string
+}
+
+func newErrSource(name string, mgr component.Observability) errSource {
+	s := errSource{name: name}
+	if l, ok := mgr.(interface{ Label() string }); ok {
+		s.label = l.Label()
+	}
+	if p, ok := mgr.(interface{ Path() []string }); ok {
+		s.path = p.Path()
+	}
+	return s
+}
+
+// wrap an error with the details of the processor and the class of the error.
+func (s errSource) wrap(err error) error {
+	if err == nil {
+		return nil
+	}
+	return message.NewProcessorError(err, s.label, s.name, s.path)
+}
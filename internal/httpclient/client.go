@@ -29,12 +29,13 @@ type Client struct {
 	clientCancel func()
 
 	// Request execution and retry logic
-	rateLimit     string
-	numRetries    int
-	retryThrottle *throttle.Type
-	backoffOn     map[int]struct{}
-	dropOn        map[int]struct{}
-	successOn     map[int]struct{}
+	rateLimit        string
+	rateLimitMaxWait time.Duration
+	numRetries       int
+	retryThrottle    *throttle.Type
+	backoffOn        map[int]struct{}
+	dropOn           map[int]struct{}
+	successOn        map[int]struct{}
 
 	// Response extraction
 	metaExtractFilter *service.MetadataFilter
@@ -129,6 +130,7 @@ func NewClientFromOldConfig(conf OldConfig, mgr *service.Resources, opts ...Requ
 		if !h.mgr.HasRateLimit(h.rateLimit) {
 			return nil, fmt.Errorf("rate limit resource '%v' was not found", h.rateLimit)
 		}
+		h.rateLimitMaxWait = conf.RateLimitMaxWait
 	}
 
 	h.numRetries = conf.NumRetries
@@ -165,9 +167,18 @@ func (h *Client) incrCode(code int) {
 	h.codesMut.Unlock()
 }
 
-func (h *Client) waitForAccess(ctx context.Context) bool {
+var errRateLimitMaxWait = errors.New("exceeded maximum wait for rate limit access")
+
+// waitForAccess blocks until the rate limit grants access, returning an error
+// if the context is cancelled or access would not be granted within the
+// configured max wait.
+func (h *Client) waitForAccess(ctx context.Context) error {
 	if h.rateLimit == "" {
-		return true
+		return nil
+	}
+	var deadline time.Time
+	if h.rateLimitMaxWait > 0 {
+		deadline = time.Now().Add(h.rateLimitMaxWait)
 	}
 	for {
 		var period time.Duration
@@ -181,15 +192,16 @@ func (h *Client) waitForAccess(ctx context.Context) bool {
 			h.log.Errorf("Rate limit error: %v\n", err)
 			period = time.Second
 		}
-
-		if period > 0 {
-			select {
-			case <-time.After(period):
-			case <-ctx.Done():
-				return false
-			}
-		} else {
-			return true
+		if period <= 0 {
+			return nil
+		}
+		if !deadline.IsZero() && time.Now().Add(period).After(deadline) {
+			return errRateLimitMaxWait
+		}
+		select {
+		case <-time.After(period):
+		case <-ctx.Done():
+			return ctx.Err()
 		}
 	}
 }
@@ -335,11 +347,8 @@ func (h *Client) SendToResponse(ctx context.Context, sendMsg service.MessageBatc
 		}
 	}()
 
-	if !h.waitForAccess(ctx) {
-		if ctx.Err() != nil {
-			return nil, ctx.Err()
-		}
-		return nil, errTimedOut
+	if err = h.waitForAccess(ctx); err != nil {
+		return nil, err
 	}
 
 	rateLimited := false
@@ -382,11 +391,8 @@ func (h *Client) SendToResponse(ctx context.Context, sendMsg service.MessageBatc
 				return nil, errTimedOut
 			}
 		}
-		if !h.waitForAccess(ctx) {
-			if ctx.Err() != nil {
-				return nil, ctx.Err()
-			}
-			return nil, errTimedOut
+		if err = h.waitForAccess(ctx); err != nil {
+			return nil, err
 		}
 		rateLimited = false
 
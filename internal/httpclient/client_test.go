@@ -58,6 +58,41 @@ retries: 3
 	assert.Equal(t, uint32(4), atomic.LoadUint32(&reqCount))
 }
 
+func TestHTTPClientRateLimitMaxWait(t *testing.T) {
+	var reqCount uint32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddUint32(&reqCount, 1)
+		_, _ = w.Write([]byte("OK"))
+	}))
+	defer ts.Close()
+
+	var accessCount uint32
+	mgr := service.MockResources(service.MockResourcesOptAddRateLimit("foo", func(ctx context.Context) (time.Duration, error) {
+		// Every other access is delayed by longer than the max wait.
+		if atomic.AddUint32(&accessCount, 1)%2 == 0 {
+			return time.Hour, nil
+		}
+		return 0, nil
+	}))
+
+	conf := clientConfig(t, `
+url: %v
+rate_limit: foo
+rate_limit_max_wait: 100ms
+`, ts.URL+"/testpost")
+
+	h, err := NewClientFromOldConfig(conf, mgr)
+	require.NoError(t, err)
+	defer h.Close(context.Background())
+
+	_, err = h.Send(context.Background(), service.MessageBatch{service.NewMessage([]byte("test"))})
+	require.NoError(t, err)
+
+	_, err = h.Send(context.Background(), service.MessageBatch{service.NewMessage([]byte("test"))})
+	require.ErrorIs(t, err, errRateLimitMaxWait)
+	assert.Equal(t, uint32(1), atomic.LoadUint32(&reqCount))
+}
+
 func TestHTTPClientBadRequest(t *testing.T) {
 	conf := clientConfig(t, `
 url: htp://notvalid:1111
@@ -17,6 +17,7 @@ const (
 	hcFieldMetadata            = "metadata"
 	hcFieldExtractHeaders      = "extract_headers"
 	hcFieldRateLimit           = "rate_limit"
+	hcFieldRateLimitMaxWait    = "rate_limit_max_wait"
 	hcFieldTimeout             = "timeout"
 	hcFieldRetryPeriod         = "retry_period"
 	hcFieldMaxRetryBackoff     = "max_retry_backoff"
@@ -57,6 +58,7 @@ func ConfigField(defaultVerb string, forOutput bool, extraChildren ...*service.C
 			Version("4.12.0"),
 	}
 	innerFields = append(innerFields, AuthFieldSpecsExpanded()...)
+	innerFields = append(innerFields, signingFieldSpec())
 
 	extractHeadersDesc := "Specify which response headers should be added to resulting messages as metadata. Header keys are lowercased before matching, so ensure that your patterns target lowercased versions of the header keys that you expect."
 	if forOutput {
@@ -70,6 +72,11 @@ func ConfigField(defaultVerb string, forOutput bool, extraChildren ...*service.C
 		service.NewStringField(hcFieldRateLimit).
 			Description("An optional [rate limit](/docs/components/rate_limits/about) to throttle requests by.").
 			Optional(),
+		service.NewDurationField(hcFieldRateLimitMaxWait).
+			Description("The maximum period to wait for access to the `rate_limit` before a request is abandoned with an error, allowing messages to be handled with standard [error handling patterns](/docs/configuration/error_handling) rather than blocking indefinitely. A request is abandoned as soon as the rate limit indicates that access will not be granted within the remaining period. If `0s` requests wait indefinitely.").
+			Advanced().
+			Default("0s").
+			Version("4.28.0"),
 		service.NewDurationField(hcFieldTimeout).
 			Description("A static timeout to apply to requests.").
 			Default("5s"),
@@ -128,6 +135,9 @@ func ConfigFromParsed(pConf *service.ParsedConfig) (conf OldConfig, err error) {
 		return
 	}
 	conf.RateLimit, _ = pConf.FieldString(hcFieldRateLimit)
+	if conf.RateLimitMaxWait, err = pConf.FieldDuration(hcFieldRateLimitMaxWait); err != nil {
+		return
+	}
 	if conf.Timeout, err = pConf.FieldDuration(hcFieldTimeout); err != nil {
 		return
 	}
@@ -160,6 +170,9 @@ func ConfigFromParsed(pConf *service.ParsedConfig) (conf OldConfig, err error) {
 	if conf.clientCtor, err = oauth2ClientCtorFromParsed(pConf); err != nil {
 		return
 	}
+	if conf.signer, err = requestSignerFromParsed(pConf); err != nil {
+		return
+	}
 	return
 }
 
@@ -171,6 +184,7 @@ type OldConfig struct {
 	Metadata            *service.MetadataFilter
 	ExtractMetadata     *service.MetadataFilter
 	RateLimit           string
+	RateLimitMaxWait    time.Duration
 	Timeout             time.Duration
 	Retry               time.Duration
 	MaxBackoff          time.Duration
@@ -184,4 +198,5 @@ type OldConfig struct {
 	ProxyURL            string
 	authSigner          func(f fs.FS, req *http.Request) error
 	clientCtor          func(context.Context, *http.Client) *http.Client
+	signer              *requestSigner
 }
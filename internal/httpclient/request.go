@@ -33,6 +33,7 @@ type RequestCreator struct {
 
 	fs        fs.FS
 	reqSigner func(f fs.FS, req *http.Request) error
+	hmacSign  *requestSigner
 
 	url              *service.InterpolatedString
 	host             *service.InterpolatedString
@@ -52,6 +53,7 @@ func RequestCreatorFromOldConfig(conf OldConfig, mgr *service.Resources, opts ..
 		fs:               mgr.FS(),
 		url:              conf.URL,
 		reqSigner:        conf.authSigner,
+		hmacSign:         conf.signer,
 		verb:             conf.Verb,
 		headers:          conf.Headers,
 		metaInsertFilter: conf.Metadata,
@@ -254,6 +256,11 @@ func (r *RequestCreator) Create(refBatch service.MessageBatch) (req *http.Reques
 		req.Header.Add("Content-Type", overrideContentType)
 	}
 
-	err = r.reqSigner(r.fs, req)
+	if err = r.reqSigner(r.fs, req); err != nil {
+		return
+	}
+	if r.hmacSign != nil {
+		err = r.hmacSign.Sign(req)
+	}
 	return
 }
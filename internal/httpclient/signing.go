@@ -0,0 +1,263 @@
+package httpclient
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	hcFieldSigning = "signing"
+
+	sFieldEnabled           = "enabled"
+	sFieldAlgorithm         = "algorithm"
+	sFieldSecret            = "secret"
+	sFieldSecretEncoding    = "secret_encoding"
+	sFieldComponents        = "components"
+	sFieldSeparator         = "separator"
+	sFieldTimestampHeader   = "timestamp_header"
+	sFieldTimestampFormat   = "timestamp_format"
+	sFieldSignatureHeader   = "signature_header"
+	sFieldSignaturePrefix   = "signature_prefix"
+	sFieldSignatureEncoding = "signature_encoding"
+)
+
+func signingFieldSpec() *service.ConfigField {
+	return service.NewObjectField(hcFieldSigning,
+		service.NewBoolField(sFieldEnabled).
+			Description("Whether to sign requests with an HMAC signature.").
+			Default(false),
+		service.NewStringEnumField(sFieldAlgorithm, "hmac_sha256", "hmac_sha512", "hmac_sha1").
+			Description("The HMAC algorithm to sign requests with.").
+			Default("hmac_sha256"),
+		service.NewStringField(sFieldSecret).
+			Description("The secret key to sign requests with.").
+			Default("").
+			Secret(),
+		service.NewStringEnumField(sFieldSecretEncoding, "none", "base64", "hex").
+			Description("The encoding of the secret key, which is decoded before signing.").
+			Default("none"),
+		service.NewStringListField(sFieldComponents).
+			Description("The components of a request that are joined with the separator in order to form the canonical payload to sign. Valid components are `method`, `host`, `path`, `query`, `body`, `body_sha256`, `timestamp`, and `header:<name>` for the value of a header.").
+			Example([]string{"method", "path", "timestamp", "body_sha256"}).
+			Example([]string{"timestamp", "body"}).
+			Default([]any{"method", "path", "timestamp", "body_sha256"}),
+		service.NewStringField(sFieldSeparator).
+			Description("A separator to join the components of the canonical payload with.").
+			Default("\n"),
+		service.NewStringField(sFieldTimestampHeader).
+			Description("An optional header to set to the timestamp of the request, which is the same timestamp used by the `timestamp` component.").
+			Example("X-Timestamp").
+			Default(""),
+		service.NewStringEnumField(sFieldTimestampFormat, "unix", "unix_ms", "rfc3339").
+			Description("The format of the request timestamp.").
+			Default("unix"),
+		service.NewStringField(sFieldSignatureHeader).
+			Description("The header to set to the signature.").
+			Default("X-Signature"),
+		service.NewStringField(sFieldSignaturePrefix).
+			Description("An optional prefix to add to the signature within the header.").
+			Example("sha256=").
+			Default(""),
+		service.NewStringEnumField(sFieldSignatureEncoding, "hex", "base64").
+			Description("The encoding of the signature.").
+			Default("hex"),
+	).
+		Description("Allows you to sign requests with an HMAC signature calculated over a canonical payload made up of components of the request. Requests are signed again each time they are retried, and so the timestamp of each attempt is current.").
+		Version("4.28.0").
+		Advanced()
+}
+
+type requestSigner struct {
+	hashFn            func() hash.Hash
+	secret            []byte
+	components        []string
+	separator         string
+	timestampHeader   string
+	timestampFormat   string
+	signatureHeader   string
+	signaturePrefix   string
+	signatureEncoding string
+
+	nowFn func() time.Time
+}
+
+func requestSignerFromParsed(pConf *service.ParsedConfig) (*requestSigner, error) {
+	sConf := pConf.Namespace(hcFieldSigning)
+	if enabled, err := sConf.FieldBool(sFieldEnabled); err != nil || !enabled {
+		return nil, err
+	}
+
+	s := &requestSigner{nowFn: time.Now}
+
+	algorithm, err := sConf.FieldString(sFieldAlgorithm)
+	if err != nil {
+		return nil, err
+	}
+	switch algorithm {
+	case "hmac_sha256":
+		s.hashFn = sha256.New
+	case "hmac_sha512":
+		s.hashFn = sha512.New
+	case "hmac_sha1":
+		s.hashFn = sha1.New
+	default:
+		return nil, fmt.Errorf("unrecognised signing algorithm: %v", algorithm)
+	}
+
+	secretStr, err := sConf.FieldString(sFieldSecret)
+	if err != nil {
+		return nil, err
+	}
+	secretEncoding, err := sConf.FieldString(sFieldSecretEncoding)
+	if err != nil {
+		return nil, err
+	}
+	switch secretEncoding {
+	case "base64":
+		if s.secret, err = base64.StdEncoding.DecodeString(secretStr); err != nil {
+			return nil, fmt.Errorf("failed to decode signing secret: %w", err)
+		}
+	case "hex":
+		if s.secret, err = hex.DecodeString(secretStr); err != nil {
+			return nil, fmt.Errorf("failed to decode signing secret: %w", err)
+		}
+	default:
+		s.secret = []byte(secretStr)
+	}
+	if len(s.secret) == 0 {
+		return nil, errors.New("a signing secret must be specified")
+	}
+
+	if s.components, err = sConf.FieldStringList(sFieldComponents); err != nil {
+		return nil, err
+	}
+	if len(s.components) == 0 {
+		return nil, errors.New("at least one signing component must be specified")
+	}
+	for _, c := range s.components {
+		switch c {
+		case "method", "host", "path", "query", "body", "body_sha256", "timestamp":
+		default:
+			if name, isHeader := strings.CutPrefix(c, "header:"); !isHeader || name == "" {
+				return nil, fmt.Errorf("unrecognised signing component: %v", c)
+			}
+		}
+	}
+
+	if s.separator, err = sConf.FieldString(sFieldSeparator); err != nil {
+		return nil, err
+	}
+	if s.timestampHeader, err = sConf.FieldString(sFieldTimestampHeader); err != nil {
+		return nil, err
+	}
+	if s.timestampFormat, err = sConf.FieldString(sFieldTimestampFormat); err != nil {
+		return nil, err
+	}
+	if s.signatureHeader, err = sConf.FieldString(sFieldSignatureHeader); err != nil {
+		return nil, err
+	}
+	if s.signatureHeader == "" {
+		return nil, errors.New("a signature header must be specified")
+	}
+	if s.signaturePrefix, err = sConf.FieldString(sFieldSignaturePrefix); err != nil {
+		return nil, err
+	}
+	if s.signatureEncoding, err = sConf.FieldString(sFieldSignatureEncoding); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *requestSigner) timestamp() string {
+	now := s.nowFn()
+	switch s.timestampFormat {
+	case "unix_ms":
+		return strconv.FormatInt(now.UnixMilli(), 10)
+	case "rfc3339":
+		return now.UTC().Format(time.RFC3339)
+	}
+	return strconv.FormatInt(now.Unix(), 10)
+}
+
+func requestBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	if req.GetBody == nil {
+		return nil, errors.New("unable to read request body for signing")
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+	return io.ReadAll(body)
+}
+
+// Sign calculates the signature of a request and adds it as a header, along
+// with the timestamp header when configured.
+func (s *requestSigner) Sign(req *http.Request) error {
+	ts := s.timestamp()
+	if s.timestampHeader != "" {
+		req.Header.Set(s.timestampHeader, ts)
+	}
+
+	mac := hmac.New(s.hashFn, s.secret)
+	for i, c := range s.components {
+		if i > 0 {
+			_, _ = io.WriteString(mac, s.separator)
+		}
+		switch c {
+		case "method":
+			_, _ = io.WriteString(mac, req.Method)
+		case "host":
+			host := req.Host
+			if host == "" {
+				host = req.URL.Host
+			}
+			_, _ = io.WriteString(mac, host)
+		case "path":
+			_, _ = io.WriteString(mac, req.URL.EscapedPath())
+		case "query":
+			_, _ = io.WriteString(mac, req.URL.RawQuery)
+		case "body", "body_sha256":
+			body, err := requestBody(req)
+			if err != nil {
+				return err
+			}
+			if c == "body" {
+				_, _ = mac.Write(body)
+			} else {
+				bodyHash := sha256.Sum256(body)
+				_, _ = io.WriteString(mac, hex.EncodeToString(bodyHash[:]))
+			}
+		case "timestamp":
+			_, _ = io.WriteString(mac, ts)
+		default:
+			_, _ = io.WriteString(mac, req.Header.Get(strings.TrimPrefix(c, "header:")))
+		}
+	}
+
+	var sig string
+	if s.signatureEncoding == "base64" {
+		sig = base64.StdEncoding.EncodeToString(mac.Sum(nil))
+	} else {
+		sig = hex.EncodeToString(mac.Sum(nil))
+	}
+	req.Header.Set(s.signatureHeader, s.signaturePrefix+sig)
+	return nil
+}
@@ -0,0 +1,125 @@
+package httpclient
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func testSigningRequestCreator(t *testing.T, conf string) *RequestCreator {
+	t.Helper()
+
+	spec := service.NewConfigSpec().Field(ConfigField("POST", true))
+	parsed, err := spec.ParseYAML(conf, nil)
+	require.NoError(t, err)
+
+	oldConf, err := ConfigFromParsed(parsed)
+	require.NoError(t, err)
+	require.NotNil(t, oldConf.signer)
+	oldConf.signer.nowFn = func() time.Time {
+		return time.Unix(1700000000, 0)
+	}
+
+	reqCreator, err := RequestCreatorFromOldConfig(oldConf, service.MockResources())
+	require.NoError(t, err)
+	return reqCreator
+}
+
+func TestSigningDefaults(t *testing.T) {
+	reqCreator := testSigningRequestCreator(t, `
+url: http://example.com/foo/bar?baz=buz
+signing:
+  enabled: true
+  secret: shh
+  timestamp_header: X-Timestamp
+`)
+
+	req, err := reqCreator.Create(service.MessageBatch{
+		service.NewMessage([]byte(`{"hello":"world"}`)),
+	})
+	require.NoError(t, err)
+
+	bodyHash := sha256.Sum256([]byte(`{"hello":"world"}`))
+	mac := hmac.New(sha256.New, []byte("shh"))
+	_, _ = io.WriteString(mac, "POST\n/foo/bar\n1700000000\n"+hex.EncodeToString(bodyHash[:]))
+
+	assert.Equal(t, "1700000000", req.Header.Get("X-Timestamp"))
+	assert.Equal(t, hex.EncodeToString(mac.Sum(nil)), req.Header.Get("X-Signature"))
+
+	// The body must remain intact after signing.
+	body, err := io.ReadAll(req.Body)
+	require.NoError(t, err)
+	assert.Equal(t, `{"hello":"world"}`, string(body))
+}
+
+func TestSigningCustomPayload(t *testing.T) {
+	secret := []byte("very secret")
+	reqCreator := testSigningRequestCreator(t, `
+url: http://example.com/foo?baz=buz
+headers:
+  X-Account: acme
+signing:
+  enabled: true
+  algorithm: hmac_sha512
+  secret: `+base64.StdEncoding.EncodeToString(secret)+`
+  secret_encoding: base64
+  components: [ timestamp, method, query, header:X-Account, body ]
+  separator: "|"
+  timestamp_format: rfc3339
+  signature_header: Authorization
+  signature_prefix: "HMAC "
+  signature_encoding: base64
+`)
+
+	req, err := reqCreator.Create(service.MessageBatch{
+		service.NewMessage([]byte(`hello world`)),
+	})
+	require.NoError(t, err)
+
+	mac := hmac.New(sha512.New, secret)
+	_, _ = io.WriteString(mac, "2023-11-14T22:13:20Z|POST|baz=buz|acme|hello world")
+
+	assert.Equal(t, "HMAC "+base64.StdEncoding.EncodeToString(mac.Sum(nil)), req.Header.Get("Authorization"))
+	assert.Empty(t, req.Header.Get("X-Signature"))
+}
+
+func TestSigningConfigErrors(t *testing.T) {
+	spec := service.NewConfigSpec().Field(ConfigField("POST", true))
+	for _, conf := range []string{
+		`
+url: http://example.com
+signing:
+  enabled: true
+`,
+		`
+url: http://example.com
+signing:
+  enabled: true
+  secret: shh
+  components: [ method, nope ]
+`,
+		`
+url: http://example.com
+signing:
+  enabled: true
+  secret: not hex
+  secret_encoding: hex
+`,
+	} {
+		parsed, err := spec.ParseYAML(conf, nil)
+		require.NoError(t, err)
+
+		_, err = ConfigFromParsed(parsed)
+		require.Error(t, err, conf)
+	}
+}
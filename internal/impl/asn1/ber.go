@@ -0,0 +1,164 @@
+package asn1
+
+import (
+	"errors"
+	"fmt"
+)
+
+const (
+	classUniversal = iota
+	classApplication
+	classContext
+	classPrivate
+)
+
+var classNames = [...]string{"universal", "application", "context", "private"}
+
+// maxDepth limits the nesting of constructed values in order to protect
+// against maliciously deep payloads.
+const maxDepth = 64
+
+var errTruncated = errors.New("value is truncated")
+
+// tagKey identifies the tag of an encoded value.
+type tagKey struct {
+	class  int
+	number int
+}
+
+func (k tagKey) String() string {
+	if k.class == classContext {
+		return fmt.Sprintf("[%v]", k.number)
+	}
+	return fmt.Sprintf("[%v %v]", classNames[k.class], k.number)
+}
+
+// tlv is a BER encoded value, where the content of values with an indefinite
+// length excludes the end-of-contents marker.
+type tlv struct {
+	tagKey
+	constructed bool
+	content     []byte
+}
+
+// readTLV reads a BER (or DER) encoded value from the start of b, returning
+// the remaining bytes.
+func readTLV(b []byte) (tlv, []byte, error) {
+	return readTLVDepth(b, 0)
+}
+
+func readTLVDepth(b []byte, depth int) (t tlv, rest []byte, err error) {
+	if depth > maxDepth {
+		return t, nil, fmt.Errorf("values are nested beyond the maximum depth of %v", maxDepth)
+	}
+	if len(b) < 2 {
+		return t, nil, errTruncated
+	}
+
+	t.class = int(b[0] >> 6)
+	t.constructed = b[0]&0x20 != 0
+	t.number = int(b[0] & 0x1f)
+	i := 1
+	if t.number == 0x1f {
+		t.number = 0
+		for {
+			if i >= len(b) {
+				return t, nil, errTruncated
+			}
+			if t.number > 1<<24 {
+				return t, nil, errors.New("tag number is too large")
+			}
+			c := b[i]
+			i++
+			t.number = t.number<<7 | int(c&0x7f)
+			if c&0x80 == 0 {
+				break
+			}
+		}
+	}
+
+	if i >= len(b) {
+		return t, nil, errTruncated
+	}
+	l := int(b[i])
+	i++
+	switch {
+	case l == 0x80:
+		if !t.constructed {
+			return t, nil, errors.New("primitive values cannot have an indefinite length")
+		}
+		start := i
+		for {
+			if len(b)-i >= 2 && b[i] == 0 && b[i+1] == 0 {
+				t.content = b[start:i]
+				return t, b[i+2:], nil
+			}
+			_, r, err := readTLVDepth(b[i:], depth+1)
+			if err != nil {
+				return t, nil, err
+			}
+			i = len(b) - len(r)
+		}
+	case l > 0x80:
+		n := l & 0x7f
+		if n > 4 {
+			return t, nil, fmt.Errorf("length of %v bytes is not supported", n)
+		}
+		if len(b)-i < n {
+			return t, nil, errTruncated
+		}
+		l = 0
+		for _, c := range b[i : i+n] {
+			l = l<<8 | int(c)
+		}
+		i += n
+	}
+	if len(b)-i < l {
+		return t, nil, errTruncated
+	}
+	t.content = b[i : i+l]
+	return t, b[i+l:], nil
+}
+
+// children parses the contents of a constructed value.
+func (t tlv) children() ([]tlv, error) {
+	if !t.constructed {
+		return nil, fmt.Errorf("expected a constructed value for %v", t.tagKey)
+	}
+	var values []tlv
+	b := t.content
+	for len(b) > 0 {
+		c, rest, err := readTLV(b)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, c)
+		b = rest
+	}
+	return values, nil
+}
+
+// primitiveContent returns the contents of a value that may be in the BER
+// constructed form, where the contents of each segment are concatenated, as is
+// permitted for string types.
+func (t tlv) primitiveContent(depth int) ([]byte, error) {
+	if depth > maxDepth {
+		return nil, fmt.Errorf("values are nested beyond the maximum depth of %v", maxDepth)
+	}
+	if !t.constructed {
+		return t.content, nil
+	}
+	children, err := t.children()
+	if err != nil {
+		return nil, err
+	}
+	var b []byte
+	for _, c := range children {
+		cb, err := c.primitiveContent(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		b = append(b, cb...)
+	}
+	return b, nil
+}
@@ -0,0 +1,455 @@
+package asn1
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf16"
+)
+
+// decoder converts BER encoded values into structured values, either against
+// the types of compiled modules or generically when no type is known.
+type decoder struct {
+	mods          *compiledModules
+	bytesEncoding string
+}
+
+func (d *decoder) encodeBytes(b []byte) string {
+	if d.bytesEncoding == "base64" {
+		return base64.StdEncoding.EncodeToString(b)
+	}
+	return hex.EncodeToString(b)
+}
+
+func errNesting() error {
+	return fmt.Errorf("values are nested beyond the maximum depth of %v", maxDepth)
+}
+
+// decode converts a value of a type, where tagChecked indicates that the tag
+// of the value has already been matched against an implicit tag.
+func (d *decoder) decode(v tlv, t *asnType, tagChecked bool, depth int) (any, error) {
+	if depth > maxDepth {
+		return nil, errNesting()
+	}
+
+	if len(t.tags) > 0 {
+		tg := t.tags[0]
+		if !tagChecked && v.tagKey != tg.tagKey {
+			return nil, fmt.Errorf("expected tag %v, got %v", tg.tagKey, v.tagKey)
+		}
+		rest := t.untagged()
+		if tg.implicit && !d.mods.isExplicitOnly(rest) {
+			return d.decode(v, rest, true, depth+1)
+		}
+		children, err := v.children()
+		if err != nil {
+			return nil, err
+		}
+		if len(children) != 1 {
+			return nil, fmt.Errorf("explicitly tagged value %v must contain exactly one value, found %v", v.tagKey, len(children))
+		}
+		return d.decode(children[0], rest, false, depth+1)
+	}
+
+	switch t.kind {
+	case kindRef:
+		return d.decode(v, t.resolved, tagChecked, depth+1)
+	case kindAny:
+		return d.generic(v, depth+1)
+	case kindChoice:
+		return d.decodeChoice(v, t, depth)
+	}
+
+	if exp := (tagKey{class: classUniversal, number: universalTags[t.kind]}); !tagChecked && v.tagKey != exp {
+		return nil, fmt.Errorf("expected %v %v, got %v", t.kind, exp, v.tagKey)
+	}
+
+	switch t.kind {
+	case kindSequence:
+		return d.decodeSequence(v, t, depth)
+	case kindSet:
+		return d.decodeSet(v, t, depth)
+	case kindSequenceOf, kindSetOf:
+		children, err := v.children()
+		if err != nil {
+			return nil, err
+		}
+		arr := make([]any, 0, len(children))
+		for i, c := range children {
+			e, err := d.decode(c, t.elem, false, depth+1)
+			if err != nil {
+				return nil, fmt.Errorf("[%v]: %w", i, err)
+			}
+			arr = append(arr, e)
+		}
+		return arr, nil
+	}
+	return d.primitive(v, t.kind, t.named)
+}
+
+func matchesTag(outer []tagKey, k tagKey) bool {
+	if outer == nil {
+		return true
+	}
+	for _, o := range outer {
+		if o == k {
+			return true
+		}
+	}
+	return false
+}
+
+func (d *decoder) decodeChoice(v tlv, t *asnType, depth int) (any, error) {
+	for _, c := range t.components {
+		if !matchesTag(c.outer, v.tagKey) {
+			continue
+		}
+		val, err := d.decode(v, c.typ, false, depth+1)
+		if err != nil {
+			return nil, fmt.Errorf("%v: %w", c.name, err)
+		}
+		return map[string]any{c.name: val}, nil
+	}
+	if t.extensible {
+		val, err := d.generic(v, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]any{v.tagKey.String(): val}, nil
+	}
+	return nil, fmt.Errorf("no alternative of CHOICE matches tag %v", v.tagKey)
+}
+
+func (d *decoder) decodeSequence(v tlv, t *asnType, depth int) (any, error) {
+	children, err := v.children()
+	if err != nil {
+		return nil, err
+	}
+	obj := make(map[string]any, len(t.components))
+	i := 0
+	for _, c := range t.components {
+		if i < len(children) && matchesTag(c.outer, children[i].tagKey) {
+			val, err := d.decode(children[i], c.typ, false, depth+1)
+			if err != nil {
+				return nil, fmt.Errorf("%v: %w", c.name, err)
+			}
+			obj[c.name] = val
+			i++
+			continue
+		}
+		if !c.optional {
+			return nil, fmt.Errorf("missing component %v", c.name)
+		}
+	}
+	if i < len(children) && !t.extensible {
+		return nil, fmt.Errorf("unexpected value with tag %v in SEQUENCE", children[i].tagKey)
+	}
+	return obj, nil
+}
+
+func (d *decoder) decodeSet(v tlv, t *asnType, depth int) (any, error) {
+	children, err := v.children()
+	if err != nil {
+		return nil, err
+	}
+	obj := make(map[string]any, len(t.components))
+	for _, child := range children {
+		var comp *component
+		for _, c := range t.components {
+			if _, exists := obj[c.name]; !exists && matchesTag(c.outer, child.tagKey) {
+				comp = c
+				break
+			}
+		}
+		if comp == nil {
+			if t.extensible {
+				continue
+			}
+			return nil, fmt.Errorf("unexpected value with tag %v in SET", child.tagKey)
+		}
+		val, err := d.decode(child, comp.typ, false, depth+1)
+		if err != nil {
+			return nil, fmt.Errorf("%v: %w", comp.name, err)
+		}
+		obj[comp.name] = val
+	}
+	for _, c := range t.components {
+		if _, exists := obj[c.name]; !exists && !c.optional {
+			return nil, fmt.Errorf("missing component %v", c.name)
+		}
+	}
+	return obj, nil
+}
+
+//------------------------------------------------------------------------------
+
+// genericKinds maps universal tag numbers to the kinds used to decode them
+// when their type is unknown.
+var genericKinds = map[int]string{}
+
+func init() {
+	for kind, n := range universalTags {
+		switch kind {
+		case kindSequenceOf, kindSetOf, "T61String", "ISO646String":
+			continue
+		}
+		genericKinds[n] = kind
+	}
+}
+
+// generic decodes a value without a type, where universal types are decoded
+// as their natural values, constructed universal values as arrays and other
+// values as objects containing their tag and contents.
+func (d *decoder) generic(v tlv, depth int) (any, error) {
+	if depth > maxDepth {
+		return nil, errNesting()
+	}
+
+	if v.class == classUniversal {
+		if v.constructed && (v.number == 16 || v.number == 17) {
+			return d.genericChildren(v, depth)
+		}
+		if kind, exists := genericKinds[v.number]; exists && kind != kindSequence && kind != kindSet {
+			return d.primitive(v, kind, nil)
+		}
+	}
+
+	obj := map[string]any{
+		"class": classNames[v.class],
+		"tag":   int64(v.number),
+	}
+	if v.constructed {
+		children, err := d.genericChildren(v, depth)
+		if err != nil {
+			return nil, err
+		}
+		obj["value"] = children
+	} else {
+		obj["value"] = d.encodeBytes(v.content)
+	}
+	return obj, nil
+}
+
+func (d *decoder) genericChildren(v tlv, depth int) ([]any, error) {
+	children, err := v.children()
+	if err != nil {
+		return nil, err
+	}
+	arr := make([]any, 0, len(children))
+	for _, c := range children {
+		e, err := d.generic(c, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		arr = append(arr, e)
+	}
+	return arr, nil
+}
+
+//------------------------------------------------------------------------------
+
+// primitive decodes the contents of a value of a builtin type.
+func (d *decoder) primitive(v tlv, kind string, named map[int64]string) (any, error) {
+	b, err := v.primitiveContent(0)
+	if err != nil {
+		return nil, err
+	}
+
+	switch kind {
+	case "BOOLEAN":
+		if len(b) != 1 {
+			return nil, errors.New("BOOLEAN values must be a single byte")
+		}
+		return b[0] != 0, nil
+	case "INTEGER", "ENUMERATED":
+		if len(b) == 0 {
+			return nil, fmt.Errorf("%v values must not be empty", kind)
+		}
+		n := new(big.Int).SetBytes(b)
+		if b[0]&0x80 != 0 {
+			n.Sub(n, new(big.Int).Lsh(big.NewInt(1), uint(len(b)*8)))
+		}
+		if !n.IsInt64() {
+			return n.String(), nil
+		}
+		if name, exists := named[n.Int64()]; exists && kind == "ENUMERATED" {
+			return name, nil
+		}
+		return n.Int64(), nil
+	case "NULL":
+		return nil, nil
+	case "OBJECT IDENTIFIER", "RELATIVE-OID":
+		return decodeOID(b, kind == "OBJECT IDENTIFIER")
+	case "REAL":
+		return decodeReal(b)
+	case "BIT STRING":
+		return decodeBitString(b, named)
+	case "OCTET STRING":
+		return d.encodeBytes(b), nil
+	case "BMPString":
+		if len(b)%2 != 0 {
+			return nil, errors.New("BMPString values must have an even length")
+		}
+		units := make([]uint16, len(b)/2)
+		for i := range units {
+			units[i] = binary.BigEndian.Uint16(b[i*2:])
+		}
+		return string(utf16.Decode(units)), nil
+	case "UniversalString":
+		if len(b)%4 != 0 {
+			return nil, errors.New("UniversalString values must have a length that is a multiple of four")
+		}
+		var sb strings.Builder
+		for i := 0; i < len(b); i += 4 {
+			sb.WriteRune(rune(binary.BigEndian.Uint32(b[i:])))
+		}
+		return sb.String(), nil
+	case "UTCTime":
+		return decodeTime(string(b), "060102150405Z0700", "0601021504Z0700"), nil
+	case "GeneralizedTime":
+		return decodeTime(string(b),
+			"20060102150405Z0700", "20060102150405",
+			"200601021504Z0700", "200601021504",
+			"2006010215Z0700", "2006010215",
+		), nil
+	}
+	return string(b), nil
+}
+
+func decodeOID(b []byte, absolute bool) (string, error) {
+	var arcs []string
+	var n uint64
+	for i, c := range b {
+		if n > math.MaxUint64>>7 {
+			return "", errors.New("object identifier arc is too large")
+		}
+		n = n<<7 | uint64(c&0x7f)
+		if c&0x80 != 0 {
+			if i == len(b)-1 {
+				return "", errors.New("object identifier is truncated")
+			}
+			continue
+		}
+		if absolute && len(arcs) == 0 {
+			switch {
+			case n < 40:
+				arcs = append(arcs, "0", strconv.FormatUint(n, 10))
+			case n < 80:
+				arcs = append(arcs, "1", strconv.FormatUint(n-40, 10))
+			default:
+				arcs = append(arcs, "2", strconv.FormatUint(n-80, 10))
+			}
+		} else {
+			arcs = append(arcs, strconv.FormatUint(n, 10))
+		}
+		n = 0
+	}
+	if len(arcs) == 0 {
+		return "", errors.New("object identifier must not be empty")
+	}
+	return strings.Join(arcs, "."), nil
+}
+
+// decodeReal decodes a REAL value in either the binary or decimal encoding,
+// special values that cannot be represented in JSON are returned as strings.
+func decodeReal(b []byte) (any, error) {
+	if len(b) == 0 {
+		return 0.0, nil
+	}
+
+	switch {
+	case b[0]&0x80 != 0:
+		base := [...]int{1, 3, 4, 0}[(b[0]>>4)&0x03]
+		if base == 0 {
+			return nil, errors.New("invalid REAL base")
+		}
+		scale := int((b[0] >> 2) & 0x03)
+		expLen, i := int(b[0]&0x03)+1, 1
+		if expLen == 4 {
+			if len(b) < 2 {
+				return nil, errors.New("REAL value is truncated")
+			}
+			expLen, i = int(b[1]), 2
+		}
+		if expLen > 4 || len(b) < i+expLen {
+			return nil, errors.New("invalid REAL exponent")
+		}
+		exp := int(int8(b[i]))
+		for _, c := range b[i+1 : i+expLen] {
+			exp = exp<<8 | int(c)
+		}
+		mantissa, _ := new(big.Float).SetInt(new(big.Int).SetBytes(b[i+expLen:])).Float64()
+		f := math.Ldexp(mantissa, scale+exp*base)
+		if b[0]&0x40 != 0 {
+			f = -f
+		}
+		return f, nil
+	case b[0]&0x40 != 0:
+		switch b[0] {
+		case 0x40:
+			return "Infinity", nil
+		case 0x41:
+			return "-Infinity", nil
+		case 0x42:
+			return "NaN", nil
+		case 0x43:
+			return math.Copysign(0, -1), nil
+		}
+		return nil, errors.New("invalid REAL special value")
+	}
+	f, err := strconv.ParseFloat(strings.ReplaceAll(strings.TrimSpace(string(b[1:])), ",", "."), 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid REAL value: %w", err)
+	}
+	return f, nil
+}
+
+// decodeBitString returns the names of the bits that are set when bits are
+// named, and otherwise a string of ones and zeros.
+func decodeBitString(b []byte, named map[int64]string) (any, error) {
+	if len(b) == 0 || b[0] > 7 || (len(b) == 1 && b[0] != 0) {
+		return nil, errors.New("invalid BIT STRING")
+	}
+	bitLen := (len(b)-1)*8 - int(b[0])
+	bit := func(i int) bool {
+		return b[1+i/8]&(0x80>>(i%8)) != 0
+	}
+
+	if named != nil {
+		names := []any{}
+		for i := 0; i < bitLen; i++ {
+			if name, exists := named[int64(i)]; exists && bit(i) {
+				names = append(names, name)
+			}
+		}
+		return names, nil
+	}
+
+	var sb strings.Builder
+	for i := 0; i < bitLen; i++ {
+		if bit(i) {
+			sb.WriteByte('1')
+		} else {
+			sb.WriteByte('0')
+		}
+	}
+	return sb.String(), nil
+}
+
+// decodeTime converts a time value into an RFC 3339 timestamp, or returns it
+// unchanged when it cannot be parsed.
+func decodeTime(s string, layouts ...string) string {
+	for _, l := range layouts {
+		if t, err := time.Parse(l, s); err == nil {
+			return t.Format(time.RFC3339Nano)
+		}
+	}
+	return s
+}
@@ -0,0 +1,833 @@
+package asn1
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Kinds of types that aren't identified by their universal tag.
+const (
+	kindRef        = "reference"
+	kindChoice     = "CHOICE"
+	kindAny        = "ANY"
+	kindSequence   = "SEQUENCE"
+	kindSet        = "SET"
+	kindSequenceOf = "SEQUENCE OF"
+	kindSetOf      = "SET OF"
+)
+
+// universalTags maps the builtin types to their universal tag numbers.
+var universalTags = map[string]int{
+	"BOOLEAN":           1,
+	"INTEGER":           2,
+	"BIT STRING":        3,
+	"OCTET STRING":      4,
+	"NULL":              5,
+	"OBJECT IDENTIFIER": 6,
+	"ObjectDescriptor":  7,
+	"REAL":              9,
+	"ENUMERATED":        10,
+	"UTF8String":        12,
+	"RELATIVE-OID":      13,
+	kindSequence:        16,
+	kindSequenceOf:      16,
+	kindSet:             17,
+	kindSetOf:           17,
+	"NumericString":     18,
+	"PrintableString":   19,
+	"TeletexString":     20,
+	"T61String":         20,
+	"VideotexString":    21,
+	"IA5String":         22,
+	"UTCTime":           23,
+	"GeneralizedTime":   24,
+	"GraphicString":     25,
+	"VisibleString":     26,
+	"ISO646String":      26,
+	"GeneralString":     27,
+	"UniversalString":   28,
+	"BMPString":         30,
+}
+
+// tag is a tag applied to a type, where implicit tags replace the tag of the
+// type and explicit tags enclose it.
+type tag struct {
+	tagKey
+	implicit bool
+}
+
+// asnType is a type of an ASN.1 module.
+type asnType struct {
+	kind string
+	tags []tag
+
+	// Set for references.
+	module   *module
+	refMod   string
+	ref      string
+	resolved *asnType
+
+	// Set for SEQUENCE, SET and CHOICE types.
+	components []*component
+	extensible bool
+
+	// Set for SEQUENCE OF and SET OF types.
+	elem *asnType
+
+	// Named numbers of INTEGER and ENUMERATED types, or named bits of BIT
+	// STRING types.
+	named map[int64]string
+}
+
+// untagged returns a copy of the type without its outermost tag.
+func (t *asnType) untagged() *asnType {
+	c := *t
+	c.tags = t.tags[1:]
+	return &c
+}
+
+type component struct {
+	name     string
+	typ      *asnType
+	optional bool
+
+	// The tags that an encoding of the component may begin with, where nil
+	// indicates any tag. Set once references have been resolved.
+	outer []tagKey
+}
+
+type module struct {
+	name      string
+	tagging   string
+	types     map[string]*asnType
+	typeOrder []string
+}
+
+//------------------------------------------------------------------------------
+
+// compiledModules is a set of parsed modules with their references resolved.
+type compiledModules struct {
+	modules map[string]*module
+}
+
+// compileModules parses a list of ASN.1 sources and resolves the references of
+// the types within them.
+func compileModules(sources map[string]string) (*compiledModules, error) {
+	c := &compiledModules{modules: map[string]*module{}}
+	for name, src := range sources {
+		mods, err := parseModules(src)
+		if err != nil {
+			return nil, fmt.Errorf("%v: %w", name, err)
+		}
+		for _, m := range mods {
+			if _, exists := c.modules[m.name]; exists {
+				return nil, fmt.Errorf("%v: module %v is defined more than once", name, m.name)
+			}
+			c.modules[m.name] = m
+		}
+	}
+
+	for _, m := range c.modules {
+		for _, name := range m.typeOrder {
+			if err := c.resolve(m.types[name], 0); err != nil {
+				return nil, fmt.Errorf("%v.%v: %w", m.name, name, err)
+			}
+		}
+	}
+	for _, m := range c.modules {
+		for _, name := range m.typeOrder {
+			if err := c.prepare(m.types[name], 0); err != nil {
+				return nil, fmt.Errorf("%v.%v: %w", m.name, name, err)
+			}
+		}
+	}
+	return c, nil
+}
+
+// lookup finds a type by name, which may be qualified by the name of its
+// module. Unqualified names are looked up within the module provided first,
+// and otherwise must be unique amongst all modules.
+func (c *compiledModules) lookup(from *module, modName, name string) (*asnType, error) {
+	if modName != "" {
+		m, exists := c.modules[modName]
+		if !exists {
+			return nil, fmt.Errorf("unknown module %v", modName)
+		}
+		t, exists := m.types[name]
+		if !exists {
+			return nil, fmt.Errorf("unknown type %v.%v", modName, name)
+		}
+		return t, nil
+	}
+	if from != nil {
+		if t, exists := from.types[name]; exists {
+			return t, nil
+		}
+	}
+
+	var found *asnType
+	for _, m := range c.modules {
+		if t, exists := m.types[name]; exists {
+			if found != nil {
+				return nil, fmt.Errorf("type %v is ambiguous, qualify it with the module name", name)
+			}
+			found = t
+		}
+	}
+	if found == nil {
+		return nil, fmt.Errorf("unknown type %v", name)
+	}
+	return found, nil
+}
+
+func (c *compiledModules) resolve(t *asnType, depth int) error {
+	if depth > maxDepth {
+		return errors.New("type definitions are nested too deeply")
+	}
+	switch t.kind {
+	case kindRef:
+		if t.resolved != nil {
+			return nil
+		}
+		target, err := c.lookup(t.module, t.refMod, t.ref)
+		if err != nil {
+			return err
+		}
+		t.resolved = target
+	case kindSequence, kindSet, kindChoice:
+		for _, comp := range t.components {
+			if err := c.resolve(comp.typ, depth+1); err != nil {
+				return fmt.Errorf("%v: %w", comp.name, err)
+			}
+		}
+	case kindSequenceOf, kindSetOf:
+		return c.resolve(t.elem, depth+1)
+	}
+	return nil
+}
+
+// prepare determines the outer tags of the components of a type and checks
+// that references aren't circular.
+func (c *compiledModules) prepare(t *asnType, depth int) error {
+	if depth > maxDepth {
+		return errors.New("type definitions are nested too deeply")
+	}
+	if _, err := c.outerTags(t, 0); err != nil {
+		return err
+	}
+	switch t.kind {
+	case kindSequence, kindSet, kindChoice:
+		for _, comp := range t.components {
+			var err error
+			if comp.outer, err = c.outerTags(comp.typ, 0); err != nil {
+				return fmt.Errorf("%v: %w", comp.name, err)
+			}
+			if err := c.prepare(comp.typ, depth+1); err != nil {
+				return fmt.Errorf("%v: %w", comp.name, err)
+			}
+		}
+	case kindSequenceOf, kindSetOf:
+		return c.prepare(t.elem, depth+1)
+	}
+	return nil
+}
+
+// outerTags returns the tags that an encoding of a type may begin with, or nil
+// when the type is untagged and may begin with any tag.
+func (c *compiledModules) outerTags(t *asnType, depth int) ([]tagKey, error) {
+	if depth > maxDepth {
+		return nil, errors.New("type definitions are circular")
+	}
+	if len(t.tags) > 0 {
+		return []tagKey{t.tags[0].tagKey}, nil
+	}
+	switch t.kind {
+	case kindRef:
+		return c.outerTags(t.resolved, depth+1)
+	case kindAny:
+		return nil, nil
+	case kindChoice:
+		var tags []tagKey
+		for _, comp := range t.components {
+			ct, err := c.outerTags(comp.typ, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			if ct == nil {
+				return nil, nil
+			}
+			tags = append(tags, ct...)
+		}
+		return tags, nil
+	}
+	return []tagKey{{class: classUniversal, number: universalTags[t.kind]}}, nil
+}
+
+// isExplicitOnly returns whether a tag applied to a type must be explicit,
+// which is the case for untagged CHOICE and ANY types.
+func (c *compiledModules) isExplicitOnly(t *asnType) bool {
+	for i := 0; i < maxDepth; i++ {
+		if len(t.tags) > 0 {
+			return false
+		}
+		switch t.kind {
+		case kindChoice, kindAny:
+			return true
+		case kindRef:
+			t = t.resolved
+		default:
+			return false
+		}
+	}
+	return false
+}
+
+//------------------------------------------------------------------------------
+
+type token struct {
+	value string
+	line  int
+}
+
+func lexASN1(src string) ([]token, error) {
+	var tokens []token
+	line := 1
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == '\n':
+			line++
+			i++
+		case c == ' ' || c == '\t' || c == '\r' || c == '\f':
+			i++
+		case strings.HasPrefix(src[i:], "--"):
+			// Comments end at the end of the line or the next "--".
+			i += 2
+			for i < len(src) && src[i] != '\n' && !strings.HasPrefix(src[i:], "--") {
+				i++
+			}
+			if strings.HasPrefix(src[i:], "--") {
+				i += 2
+			}
+		case strings.HasPrefix(src[i:], "/*"):
+			end := strings.Index(src[i+2:], "*/")
+			if end == -1 {
+				return nil, fmt.Errorf("line %v: unterminated comment", line)
+			}
+			line += strings.Count(src[i:i+2+end], "\n")
+			i += end + 4
+		case c == '"' || c == '\'':
+			end := strings.IndexByte(src[i+1:], c)
+			if end == -1 {
+				return nil, fmt.Errorf("line %v: unterminated string", line)
+			}
+			j := i + end + 2
+			// Binary and hexadecimal strings are suffixed with B or H.
+			if c == '\'' && j < len(src) && (src[j] == 'B' || src[j] == 'H') {
+				j++
+			}
+			tokens = append(tokens, token{value: src[i:j], line: line})
+			line += strings.Count(src[i:j], "\n")
+			i = j
+		case isIdentStart(c) || c >= '0' && c <= '9':
+			j := i + 1
+			for j < len(src) && (isIdentStart(src[j]) || src[j] >= '0' && src[j] <= '9' ||
+				(src[j] == '-' && j+1 < len(src) && src[j+1] != '-')) {
+				j++
+			}
+			tokens = append(tokens, token{value: src[i:j], line: line})
+			i = j
+		default:
+			j := i + 1
+			for _, op := range []string{"::=", "...", "..", "[[", "]]"} {
+				if strings.HasPrefix(src[i:], op) {
+					j = i + len(op)
+					break
+				}
+			}
+			tokens = append(tokens, token{value: src[i:j], line: line})
+			i = j
+		}
+	}
+	return tokens, nil
+}
+
+func isIdentStart(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+func isTypeReference(s string) bool {
+	return s != "" && unicode.IsUpper(rune(s[0]))
+}
+
+func isIdentifier(s string) bool {
+	return s != "" && unicode.IsLower(rune(s[0]))
+}
+
+//------------------------------------------------------------------------------
+
+type parser struct {
+	tokens []token
+	pos    int
+	mod    *module
+}
+
+func (p *parser) peek(n int) string {
+	if p.pos+n >= len(p.tokens) {
+		return ""
+	}
+	return p.tokens[p.pos+n].value
+}
+
+func (p *parser) next() string {
+	v := p.peek(0)
+	p.pos++
+	return v
+}
+
+func (p *parser) accept(values ...string) bool {
+	for _, v := range values {
+		if p.peek(0) == v {
+			p.pos++
+			return true
+		}
+	}
+	return false
+}
+
+func (p *parser) errorf(format string, args ...any) error {
+	line := 0
+	if p.pos < len(p.tokens) {
+		line = p.tokens[p.pos].line
+	} else if len(p.tokens) > 0 {
+		line = p.tokens[len(p.tokens)-1].line
+	}
+	return fmt.Errorf("line %v: %v", line, fmt.Sprintf(format, args...))
+}
+
+func (p *parser) expect(v string) error {
+	if got := p.next(); got != v {
+		p.pos--
+		return p.errorf("expected %q, got %q", v, got)
+	}
+	return nil
+}
+
+// skipBalanced skips a bracketed group beginning at the current token.
+func (p *parser) skipBalanced(open, closing string) error {
+	depth := 0
+	for p.pos < len(p.tokens) {
+		switch p.next() {
+		case open:
+			depth++
+		case closing:
+			if depth--; depth == 0 {
+				return nil
+			}
+		}
+	}
+	return p.errorf("unterminated %q", open)
+}
+
+// parseModules parses the module definitions of an ASN.1 source.
+func parseModules(src string) ([]*module, error) {
+	tokens, err := lexASN1(src)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+	var modules []*module
+	for p.pos < len(p.tokens) {
+		m, err := p.parseModule()
+		if err != nil {
+			return nil, err
+		}
+		modules = append(modules, m)
+	}
+	if len(modules) == 0 {
+		return nil, errors.New("no module definitions found")
+	}
+	return modules, nil
+}
+
+func (p *parser) parseModule() (*module, error) {
+	m := &module{tagging: "EXPLICIT", types: map[string]*asnType{}}
+	p.mod = m
+	if m.name = p.next(); !isTypeReference(m.name) {
+		p.pos--
+		return nil, p.errorf("expected a module name, got %q", m.name)
+	}
+	if p.peek(0) == "{" {
+		if err := p.skipBalanced("{", "}"); err != nil {
+			return nil, err
+		}
+	}
+	if err := p.expect("DEFINITIONS"); err != nil {
+		return nil, err
+	}
+	for p.peek(0) != "::=" && p.pos < len(p.tokens) {
+		switch t := p.next(); t {
+		case "EXPLICIT", "IMPLICIT", "AUTOMATIC":
+			m.tagging = t
+		case "TAGS", "EXTENSIBILITY", "IMPLIED":
+		default:
+			p.pos--
+			return nil, p.errorf("unexpected %q in module header", t)
+		}
+	}
+	if err := p.expect("::="); err != nil {
+		return nil, err
+	}
+	if err := p.expect("BEGIN"); err != nil {
+		return nil, err
+	}
+
+	// Exports and imports are skipped as references are resolved across all
+	// modules.
+	for _, section := range []string{"EXPORTS", "IMPORTS"} {
+		if p.accept(section) {
+			for p.peek(0) != ";" {
+				if p.pos >= len(p.tokens) {
+					return nil, p.errorf("unterminated %v", section)
+				}
+				p.next()
+			}
+			p.next()
+		}
+	}
+
+	for !p.accept("END") {
+		if p.pos >= len(p.tokens) {
+			return nil, p.errorf("expected END of module %v", m.name)
+		}
+		if err := p.parseAssignment(); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+func (p *parser) parseAssignment() error {
+	name := p.next()
+	switch {
+	case isTypeReference(name) && p.peek(0) == "::=":
+		p.next()
+		t, err := p.parseType()
+		if err != nil {
+			return err
+		}
+		if _, exists := p.mod.types[name]; exists {
+			return p.errorf("type %v is defined more than once", name)
+		}
+		p.mod.types[name] = t
+		p.mod.typeOrder = append(p.mod.typeOrder, name)
+		return nil
+	case isIdentifier(name):
+		// Value assignments aren't needed in order to decode values and so
+		// are skipped.
+		if _, err := p.parseType(); err != nil {
+			return err
+		}
+		if err := p.expect("::="); err != nil {
+			return err
+		}
+		return p.skipValue()
+	case isTypeReference(name) && p.peek(0) == "{":
+		p.pos--
+		return p.errorf("parameterized type %v is not supported", name)
+	}
+	p.pos--
+	return p.errorf("unsupported assignment %q", name)
+}
+
+// skipValue skips a value, which is either a bracketed group or a single
+// token that may be qualified or fractional.
+func (p *parser) skipValue() error {
+	if p.peek(0) == "{" {
+		return p.skipBalanced("{", "}")
+	}
+	p.accept("-")
+	p.next()
+	if p.accept(".") {
+		p.next()
+	}
+	return nil
+}
+
+func (p *parser) parseType() (*asnType, error) {
+	var tags []tag
+	for p.accept("[") {
+		tg := tag{tagKey: tagKey{class: classContext}, implicit: p.mod.tagging != "EXPLICIT"}
+		switch p.peek(0) {
+		case "UNIVERSAL":
+			tg.class = classUniversal
+			p.next()
+		case "APPLICATION":
+			tg.class = classApplication
+			p.next()
+		case "PRIVATE":
+			tg.class = classPrivate
+			p.next()
+		}
+		n, err := strconv.Atoi(p.next())
+		if err != nil {
+			p.pos--
+			return nil, p.errorf("expected a tag number, got %q", p.peek(0))
+		}
+		tg.number = n
+		if err := p.expect("]"); err != nil {
+			return nil, err
+		}
+		if p.accept("IMPLICIT") {
+			tg.implicit = true
+		} else if p.accept("EXPLICIT") {
+			tg.implicit = false
+		}
+		tags = append(tags, tg)
+	}
+
+	t, err := p.parseBuiltinType()
+	if err != nil {
+		return nil, err
+	}
+	t.tags = tags
+
+	// Constraints are not enforced.
+	for p.peek(0) == "(" {
+		if err := p.skipBalanced("(", ")"); err != nil {
+			return nil, err
+		}
+	}
+	return t, nil
+}
+
+func (p *parser) parseBuiltinType() (*asnType, error) {
+	name := p.next()
+	t := &asnType{kind: name}
+	switch name {
+	case "BOOLEAN", "NULL", "REAL", "ObjectDescriptor", "UTCTime", "GeneralizedTime", "RELATIVE-OID",
+		"UTF8String", "NumericString", "PrintableString", "TeletexString", "T61String", "VideotexString",
+		"IA5String", "GraphicString", "VisibleString", "ISO646String", "GeneralString", "UniversalString", "BMPString":
+	case "INTEGER", "ENUMERATED":
+		if p.peek(0) == "{" {
+			var err error
+			if t.named, err = p.parseNamedNumbers(name == "ENUMERATED"); err != nil {
+				return nil, err
+			}
+		}
+	case "BIT", "OCTET":
+		if err := p.expect("STRING"); err != nil {
+			return nil, err
+		}
+		t.kind = name + " STRING"
+		if name == "BIT" && p.peek(0) == "{" {
+			var err error
+			if t.named, err = p.parseNamedNumbers(false); err != nil {
+				return nil, err
+			}
+		}
+	case "OBJECT":
+		if err := p.expect("IDENTIFIER"); err != nil {
+			return nil, err
+		}
+		t.kind = "OBJECT IDENTIFIER"
+	case "ANY":
+		if p.accept("DEFINED") {
+			if err := p.expect("BY"); err != nil {
+				return nil, err
+			}
+			p.next()
+		}
+	case kindChoice:
+		var err error
+		if t.components, t.extensible, err = p.parseComponents(); err != nil {
+			return nil, err
+		}
+		p.autoTag(t)
+	case kindSequence, kindSet:
+		if p.peek(0) == "{" {
+			var err error
+			if t.components, t.extensible, err = p.parseComponents(); err != nil {
+				return nil, err
+			}
+			p.autoTag(t)
+			break
+		}
+		if p.accept("SIZE") && p.peek(0) == "(" {
+			if err := p.skipBalanced("(", ")"); err != nil {
+				return nil, err
+			}
+		}
+		for p.peek(0) == "(" {
+			if err := p.skipBalanced("(", ")"); err != nil {
+				return nil, err
+			}
+		}
+		if err := p.expect("OF"); err != nil {
+			return nil, err
+		}
+		if isIdentifier(p.peek(0)) {
+			p.next()
+		}
+		t.kind = name + " OF"
+		var err error
+		if t.elem, err = p.parseType(); err != nil {
+			return nil, err
+		}
+	default:
+		if !isTypeReference(name) {
+			p.pos--
+			return nil, p.errorf("expected a type, got %q", name)
+		}
+		t.kind, t.module, t.ref = kindRef, p.mod, name
+		if p.peek(0) == "." && isTypeReference(p.peek(1)) {
+			p.next()
+			t.refMod, t.ref = name, p.next()
+		}
+	}
+	return t, nil
+}
+
+// parseNamedNumbers parses a list of named numbers or bits, where the values
+// of enumerations may be omitted.
+func (p *parser) parseNamedNumbers(enumerated bool) (map[int64]string, error) {
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	named := map[int64]string{}
+	used := map[int64]bool{}
+	var unnumbered []string
+	for !p.accept("}") {
+		if p.accept(",", "...") {
+			continue
+		}
+		if p.peek(0) == "!" {
+			p.next()
+			p.next()
+			continue
+		}
+		name := p.next()
+		if !isIdentifier(name) {
+			p.pos--
+			return nil, p.errorf("expected a named number, got %q", name)
+		}
+		if !p.accept("(") {
+			if !enumerated {
+				p.pos--
+				return nil, p.errorf("expected a value for %v", name)
+			}
+			unnumbered = append(unnumbered, name)
+			continue
+		}
+		neg := p.accept("-")
+		n, err := strconv.ParseInt(p.next(), 10, 64)
+		if err != nil {
+			p.pos--
+			return nil, p.errorf("named number %v must have a numeric value", name)
+		}
+		if neg {
+			n = -n
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+		named[n] = name
+		used[n] = true
+	}
+
+	// Enumerations without values are numbered from zero, skipping the values
+	// that have been assigned.
+	var n int64
+	for _, name := range unnumbered {
+		for used[n] {
+			n++
+		}
+		named[n] = name
+		used[n] = true
+	}
+	return named, nil
+}
+
+func (p *parser) parseComponents() ([]*component, bool, error) {
+	if err := p.expect("{"); err != nil {
+		return nil, false, err
+	}
+	var comps []*component
+	extensible := false
+	for !p.accept("}") {
+		if p.pos >= len(p.tokens) {
+			return nil, false, p.errorf("unterminated component list")
+		}
+		switch {
+		case p.accept(","), p.accept("[["), p.accept("]]"):
+			continue
+		case p.accept("..."):
+			extensible = true
+			if p.accept("!") {
+				if err := p.skipValue(); err != nil {
+					return nil, false, err
+				}
+			}
+			continue
+		case p.peek(0) == "COMPONENTS":
+			return nil, false, p.errorf("COMPONENTS OF is not supported")
+		}
+
+		// Extension addition groups may be prefixed with a version number.
+		if _, err := strconv.Atoi(p.peek(0)); err == nil && p.peek(1) == ":" {
+			p.next()
+			p.next()
+			continue
+		}
+
+		c := &component{name: p.next()}
+		if !isIdentifier(c.name) {
+			p.pos--
+			return nil, false, p.errorf("expected a component name, got %q", c.name)
+		}
+		var err error
+		if c.typ, err = p.parseType(); err != nil {
+			return nil, false, err
+		}
+		if p.accept("OPTIONAL") {
+			c.optional = true
+		} else if p.accept("DEFAULT") {
+			c.optional = true
+			for p.peek(0) != "," && p.peek(0) != "}" && p.peek(0) != "]]" {
+				if p.pos >= len(p.tokens) {
+					return nil, false, p.errorf("unterminated component list")
+				}
+				if p.peek(0) == "{" {
+					if err := p.skipBalanced("{", "}"); err != nil {
+						return nil, false, err
+					}
+					continue
+				}
+				p.next()
+			}
+		}
+		comps = append(comps, c)
+	}
+	return comps, extensible, nil
+}
+
+// autoTag assigns context specific tags to the components of a type in order
+// when the module uses automatic tagging and none of the components are
+// tagged.
+func (p *parser) autoTag(t *asnType) {
+	if p.mod.tagging != "AUTOMATIC" {
+		return
+	}
+	for _, c := range t.components {
+		if len(c.typ.tags) > 0 {
+			return
+		}
+	}
+	for i, c := range t.components {
+		c.typ.tags = []tag{{tagKey: tagKey{class: classContext, number: i}, implicit: true}}
+	}
+}
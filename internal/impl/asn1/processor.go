@@ -0,0 +1,227 @@
+package asn1
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	apFieldModule        = "module"
+	apFieldModulePaths   = "module_paths"
+	apFieldType          = "type"
+	apFieldSplitValues   = "split_values"
+	apFieldBytesEncoding = "bytes_encoding"
+)
+
+func asn1ProcSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Categories("Parsing").
+		Version("4.28.0").
+		Summary("Decodes BER and DER encoded ASN.1 values into structured messages, optionally against the types of ASN.1 modules.").
+		Description(`
+ASN.1 modules provided with the fields `+"`module`"+` and `+"`module_paths`"+` are compiled when the processor is created, and messages are decoded as the type named by the field `+"`type`"+`. Without any modules messages are decoded generically.
+
+Values of SEQUENCE and SET types are decoded as objects keyed by the names of their components, with absent optional components omitted. Values of CHOICE types are decoded as an object containing a single key, the name of the alternative present. Values of SEQUENCE OF and SET OF types are decoded as arrays.
+
+Other types are decoded as follows:
+
+- `+"`INTEGER`"+`: A number, or a string when it exceeds 64 bits.
+- `+"`ENUMERATED`"+`: The name of the value when known, otherwise a number.
+- `+"`BIT STRING`"+`: An array of the names of the bits that are set when bits are named, otherwise a string of ones and zeros.
+- `+"`OCTET STRING`"+`: A string encoded according to `+"`bytes_encoding`"+`.
+- `+"`OBJECT IDENTIFIER`"+`: A string in dot notation.
+- `+"`UTCTime`"+` and `+"`GeneralizedTime`"+`: An RFC 3339 timestamp.
+- `+"`ANY`"+` and values of unknown types: Decoded generically, where universal types are decoded as their natural values, and other values as an object containing the `+"`class`"+` and `+"`tag`"+` number of the value and its contents as `+"`value`"+`.
+
+Modules may use explicit, implicit or automatic tagging, and constraints are parsed but not enforced. Value assignments are ignored, and parameterized types, information object classes and `+"`COMPONENTS OF`"+` are not supported. Types are resolved by name across all modules, and so imports are not required, although types with names that are defined by more than one module must be qualified with their module name, e.g. `+"`MyModule.MyType`"+`.
+
+When `+"`split_values`"+` is `+"`true`"+` messages are treated as a series of concatenated values, such as a file of call detail records, and a message is created for each value with the metadata field `+"`asn1_index`"+` set to its zero-based position. Padding bytes (`+"`0x00`"+` and `+"`0xFF`"+`) between values are skipped. Values that cannot be decoded against the type are flagged as having failed and retain their raw contents, allowing you to use [standard processor error handling patterns](/docs/configuration/error_handling).`).
+		Fields(
+			service.NewStringField(apFieldModule).
+				Description("An ASN.1 module definition to compile.").
+				Optional().
+				Example(`Records DEFINITIONS IMPLICIT TAGS ::= BEGIN
+  CallRecord ::= [APPLICATION 1] SEQUENCE {
+    callingNumber [0] OCTET STRING,
+    duration      [1] INTEGER,
+    startTime     [2] GeneralizedTime OPTIONAL
+  }
+END`),
+			service.NewStringListField(apFieldModulePaths).
+				Description("A list of paths to files containing ASN.1 module definitions to compile.").
+				Default([]any{}).
+				Example([]any{"./schemas/records.asn"}),
+			service.NewStringField(apFieldType).
+				Description("The type to decode messages as, which is required when modules are provided.").
+				Optional().
+				Example("CallRecord").
+				Example("Records.CallRecord"),
+			service.NewBoolField(apFieldSplitValues).
+				Description("Whether to decode a series of concatenated values from each message into a message per value.").
+				Default(false),
+			service.NewStringEnumField(apFieldBytesEncoding, "hex", "base64").
+				Description("The encoding of the contents of OCTET STRING values and values of unknown types.").
+				Default("hex"),
+		).
+		Example("Call Detail Records", "Here we decode files of call detail records from S3 into a message per record.", `
+input:
+  aws_s3:
+    bucket: cdr-exports
+    prefix: cdrs/
+
+pipeline:
+  processors:
+    - asn1:
+        module_paths: [ ./schemas/records.asn ]
+        type: CallRecord
+        split_values: true
+`).
+		Example("Certificate Extensions", "Here we decode a DER encoded X.509 extension generically, which is useful for exploring the structure of values without a module.", `
+pipeline:
+  processors:
+    - branch:
+        request_map: 'root = this.extension.decode("base64")'
+        processors:
+          - asn1: {}
+        result_map: 'root.extension_decoded = this'
+`)
+}
+
+func init() {
+	err := service.RegisterProcessor(
+		"asn1", asn1ProcSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Processor, error) {
+			return newASN1ProcFromConfig(conf, mgr)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type asn1Proc struct {
+	dec         *decoder
+	typ         *asnType
+	splitValues bool
+}
+
+func newASN1ProcFromConfig(conf *service.ParsedConfig, mgr *service.Resources) (*asn1Proc, error) {
+	sources := map[string]string{}
+	if conf.Contains(apFieldModule) {
+		src, err := conf.FieldString(apFieldModule)
+		if err != nil {
+			return nil, err
+		}
+		sources[apFieldModule] = src
+	}
+
+	paths, err := conf.FieldStringList(apFieldModulePaths)
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range paths {
+		b, err := service.ReadFile(mgr.FS(), path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read module file %v: %w", path, err)
+		}
+		sources[path] = string(b)
+	}
+
+	p := &asn1Proc{dec: &decoder{}}
+	if p.dec.bytesEncoding, err = conf.FieldString(apFieldBytesEncoding); err != nil {
+		return nil, err
+	}
+	if p.splitValues, err = conf.FieldBool(apFieldSplitValues); err != nil {
+		return nil, err
+	}
+
+	if len(sources) == 0 {
+		if conf.Contains(apFieldType) {
+			return nil, errors.New("a type cannot be specified without a module")
+		}
+		return p, nil
+	}
+
+	if p.dec.mods, err = compileModules(sources); err != nil {
+		return nil, fmt.Errorf("failed to compile modules: %w", err)
+	}
+	if !conf.Contains(apFieldType) {
+		return nil, errors.New("a type must be specified when modules are provided")
+	}
+	typeName, err := conf.FieldString(apFieldType)
+	if err != nil {
+		return nil, err
+	}
+	modName, name := "", typeName
+	if i := strings.LastIndexByte(typeName, '.'); i != -1 {
+		modName, name = typeName[:i], typeName[i+1:]
+	}
+	if p.typ, err = p.dec.mods.lookup(nil, modName, name); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (p *asn1Proc) decode(v tlv) (any, error) {
+	if p.typ == nil {
+		return p.dec.generic(v, 0)
+	}
+	return p.dec.decode(v, p.typ, false, 0)
+}
+
+func (p *asn1Proc) Process(ctx context.Context, msg *service.Message) (service.MessageBatch, error) {
+	raw, err := msg.AsBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	if !p.splitValues {
+		v, rest, err := readTLV(raw)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read value: %w", err)
+		}
+		if len(rest) > 0 {
+			return nil, fmt.Errorf("unexpected %v bytes following value", len(rest))
+		}
+		obj, err := p.decode(v)
+		if err != nil {
+			return nil, err
+		}
+		msg.SetStructuredMut(obj)
+		return service.MessageBatch{msg}, nil
+	}
+
+	var batch service.MessageBatch
+	for i := 0; ; i++ {
+		for len(raw) > 0 && (raw[0] == 0x00 || raw[0] == 0xff) {
+			raw = raw[1:]
+		}
+		if len(raw) == 0 {
+			break
+		}
+
+		v, rest, err := readTLV(raw)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read value %v: %w", i, err)
+		}
+
+		vMsg := msg.Copy()
+		vMsg.MetaSetMut("asn1_index", int64(i))
+		if obj, err := p.decode(v); err != nil {
+			vMsg.SetBytes(raw[:len(raw)-len(rest)])
+			vMsg.SetError(fmt.Errorf("value %v: %w", i, err))
+		} else {
+			vMsg.SetStructuredMut(obj)
+		}
+		batch = append(batch, vMsg)
+		raw = rest
+	}
+	return batch, nil
+}
+
+func (p *asn1Proc) Close(ctx context.Context) error {
+	return nil
+}
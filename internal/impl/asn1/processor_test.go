@@ -0,0 +1,226 @@
+package asn1
+
+import (
+	"context"
+	stdasn1 "encoding/asn1"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const testModule = `
+Records DEFINITIONS IMPLICIT TAGS ::= BEGIN
+  IMPORTS Other FROM OtherModule;
+
+  -- A call record -- CallId ::= INTEGER
+  CallRecord ::= [APPLICATION 1] SEQUENCE {
+    callingNumber [0] OCTET STRING,
+    duration      [1] INTEGER (0..MAX),
+    startTime     [2] GeneralizedTime OPTIONAL,
+    result        [3] CallResult,
+    party         [4] Party,
+    flags         [5] BIT STRING { forwarded(0), roamed(2) } OPTIONAL,
+    labels        [6] SEQUENCE SIZE(1..5) OF IA5String OPTIONAL,
+    ...,
+    [[ 2: extra [7] BOOLEAN OPTIONAL ]]
+  }
+
+  CallResult ::= ENUMERATED { answered(0), busy(1), noAnswer(2), ... }
+
+  Party ::= CHOICE {
+    msisdn [0] NumericString,
+    imsi   [1] OCTET STRING
+  }
+
+  maxLabels INTEGER ::= 5
+END
+
+Messages DEFINITIONS AUTOMATIC TAGS ::= BEGIN
+  Message ::= SEQUENCE {
+    id    INTEGER,
+    body  CHOICE { text UTF8String, number INTEGER },
+    oid   OBJECT IDENTIFIER DEFAULT { 1 2 },
+    items SET OF Records.Party
+  }
+END
+`
+
+func testASN1Proc(t *testing.T, confStr string) *asn1Proc {
+	t.Helper()
+
+	conf, err := asn1ProcSpec().ParseYAML(confStr, nil)
+	require.NoError(t, err)
+
+	proc, err := newASN1ProcFromConfig(conf, service.MockResources())
+	require.NoError(t, err)
+	return proc
+}
+
+func testModuleConf(typeName string) string {
+	return "type: " + typeName + "\nmodule: |" + strings.ReplaceAll(testModule, "\n", "\n  ")
+}
+
+func mustHex(t *testing.T, s string) []byte {
+	t.Helper()
+
+	b, err := hex.DecodeString(s)
+	require.NoError(t, err)
+	return b
+}
+
+// A CallRecord in DER.
+const testCallRecord = "613a80034412348102012c820f32303234303130323033303430355a830101a40980073535353132333485" +
+	"0205a0a6061601611601628701ff880100"
+
+func TestASN1ImplicitTags(t *testing.T) {
+	proc := testASN1Proc(t, testModuleConf("CallRecord"))
+
+	res, err := proc.Process(context.Background(), service.NewMessage(mustHex(t, testCallRecord)))
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+
+	v, err := res[0].AsStructured()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{
+		"callingNumber": "441234",
+		"duration":      int64(300),
+		"startTime":     "2024-01-02T03:04:05Z",
+		"result":        "busy",
+		"party":         map[string]any{"msisdn": "5551234"},
+		"flags":         []any{"forwarded", "roamed"},
+		"labels":        []any{"a", "b"},
+		"extra":         true,
+	}, v)
+
+	_, err = proc.Process(context.Background(), service.NewMessage(mustHex(t, "6103810101")))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "missing component callingNumber")
+}
+
+func TestASN1AutomaticTags(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "records.asn"), []byte(testModule), 0o644))
+
+	proc := testASN1Proc(t, `
+module_paths: [ `+filepath.Join(dir, "records.asn")+` ]
+type: Messages.Message
+bytes_encoding: base64
+`)
+
+	res, err := proc.Process(context.Background(), service.NewMessage(mustHex(t, "300e800107a1048002686ca3038101ab")))
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+
+	v, err := res[0].AsStructured()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{
+		"id":    int64(7),
+		"body":  map[string]any{"text": "hl"},
+		"items": []any{map[string]any{"imsi": "qw=="}},
+	}, v)
+}
+
+func TestASN1SplitValues(t *testing.T) {
+	proc := testASN1Proc(t, testModuleConf("CallRecord")+"\nsplit_values: true")
+
+	// The second record has an indefinite length and the third lacks a
+	// mandatory component.
+	input := mustHex(t, testCallRecord+"0000"+
+		"61808001448101ff830102a480810101000000000000"+
+		"ffff6103810101")
+
+	res, err := proc.Process(context.Background(), service.NewMessage(input))
+	require.NoError(t, err)
+	require.Len(t, res, 3)
+
+	v, err := res[1].AsStructured()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{
+		"callingNumber": "44",
+		"duration":      int64(-1),
+		"result":        "noAnswer",
+		"party":         map[string]any{"imsi": "01"},
+	}, v)
+
+	index, _ := res[2].MetaGetMut("asn1_index")
+	assert.Equal(t, int64(2), index)
+	require.Error(t, res[2].GetError())
+	b, err := res[2].AsBytes()
+	require.NoError(t, err)
+	assert.Equal(t, "6103810101", hex.EncodeToString(b))
+
+	_, err = proc.Process(context.Background(), service.NewMessage(mustHex(t, "300501")))
+	require.Error(t, err)
+}
+
+func TestASN1Generic(t *testing.T) {
+	proc := testASN1Proc(t, `{}`)
+
+	der, err := stdasn1.Marshal(struct {
+		N    int
+		S    string
+		O    stdasn1.ObjectIdentifier
+		T    time.Time
+		B    []byte
+		F    bool
+		Bits stdasn1.BitString
+		X    int    `asn1:"tag:3,explicit"`
+		U    string `asn1:"utf8"`
+	}{
+		-129, "hello", stdasn1.ObjectIdentifier{1, 2, 840, 113549},
+		time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), []byte{1, 2}, true,
+		stdasn1.BitString{Bytes: []byte{0xa0}, BitLength: 3}, 9, "é",
+	})
+	require.NoError(t, err)
+
+	res, err := proc.Process(context.Background(), service.NewMessage(der))
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+
+	v, err := res[0].AsStructured()
+	require.NoError(t, err)
+	assert.Equal(t, []any{
+		int64(-129), "hello", "1.2.840.113549", "2024-01-02T03:04:05Z", "0102", true, "101",
+		map[string]any{"class": "context", "tag": int64(3), "value": []any{int64(9)}},
+		"é",
+	}, v)
+
+	_, err = proc.Process(context.Background(), service.NewMessage(append(der, 0x00)))
+	require.Error(t, err)
+}
+
+func TestASN1ConfigErrors(t *testing.T) {
+	for _, confStr := range []string{
+		`type: CallRecord`,
+		testModuleConf("Missing"),
+		`
+type: A
+module: |
+  M DEFINITIONS ::= BEGIN A ::= SEQUENCE { a Unknown } END
+`,
+		`
+type: A
+module: |
+  M DEFINITIONS ::= BEGIN A{T} ::= SEQUENCE { a T } END
+`,
+		`
+type: A
+module: |
+  M DEFINITIONS ::= BEGIN A ::= B B ::= A END
+`,
+	} {
+		conf, err := asn1ProcSpec().ParseYAML(confStr, nil)
+		require.NoError(t, err, confStr)
+
+		_, err = newASN1ProcFromConfig(conf, service.MockResources())
+		require.Error(t, err, confStr)
+	}
+}
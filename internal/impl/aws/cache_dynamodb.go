@@ -128,6 +128,7 @@ func newDynamodbCacheFromConfig(conf *service.ParsedConfig) (*dynamodbCache, err
 
 type dynamoDBAPIV2 interface {
 	PutItem(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error)
+	BatchGetItem(ctx context.Context, params *dynamodb.BatchGetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.BatchGetItemOutput, error)
 	BatchWriteItem(ctx context.Context, params *dynamodb.BatchWriteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.BatchWriteItemOutput, error)
 	DescribeTable(ctx context.Context, params *dynamodb.DescribeTableInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DescribeTableOutput, error)
 	GetItem(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error)
@@ -234,6 +235,80 @@ func (d *dynamodbCache) get(ctx context.Context, key string) ([]byte, error) {
 	return val.Value, nil
 }
 
+// The maximum number of items that can be read with a single BatchGetItem
+// request.
+const dynamoDBMaxBatchGetItems = 100
+
+func (d *dynamodbCache) GetMulti(ctx context.Context, keys ...string) (map[string][]byte, error) {
+	boff := d.boffPool.Get().(backoff.BackOff)
+	defer func() {
+		boff.Reset()
+		d.boffPool.Put(boff)
+	}()
+
+	// Keys of a BatchGetItem request must be unique.
+	getKeys := make([]map[string]types.AttributeValue, 0, len(keys))
+	seen := make(map[string]struct{}, len(keys))
+	for _, key := range keys {
+		if _, exists := seen[key]; exists {
+			continue
+		}
+		seen[key] = struct{}{}
+		getKeys = append(getKeys, map[string]types.AttributeValue{
+			d.hashKey: &types.AttributeValueMemberS{Value: key},
+		})
+	}
+
+	values := make(map[string][]byte, len(keys))
+	var err error
+	for len(getKeys) > 0 {
+		chunk := getKeys
+		if len(chunk) > dynamoDBMaxBatchGetItems {
+			chunk = chunk[:dynamoDBMaxBatchGetItems]
+		}
+		getKeys = getKeys[len(chunk):]
+
+		var batchResult *dynamodb.BatchGetItemOutput
+		batchResult, err = d.client.BatchGetItem(ctx, &dynamodb.BatchGetItemInput{
+			RequestItems: map[string]types.KeysAndAttributes{
+				d.table: {
+					Keys:           chunk,
+					ConsistentRead: aws.Bool(d.consistentRead),
+				},
+			},
+		})
+		if err == nil {
+			for _, item := range batchResult.Responses[d.table] {
+				key, ok := item[d.hashKey].(*types.AttributeValueMemberS)
+				if !ok {
+					continue
+				}
+				if val, ok := item[d.dataKey].(*types.AttributeValueMemberB); ok {
+					values[key.Value] = val.Value
+				}
+			}
+			if unproc := batchResult.UnprocessedKeys[d.table].Keys; len(unproc) > 0 {
+				getKeys = append(getKeys, unproc...)
+				err = fmt.Errorf("failed to get %v items", len(unproc))
+			}
+		} else {
+			getKeys = append(getKeys, chunk...)
+		}
+		if err != nil {
+			wait := boff.NextBackOff()
+			if wait == backoff.Stop {
+				return nil, err
+			}
+			select {
+			case <-time.After(wait):
+			case <-ctx.Done():
+				return nil, err
+			}
+		}
+	}
+	return values, nil
+}
+
 func (d *dynamodbCache) Set(ctx context.Context, key string, value []byte, ttl *time.Duration) error {
 	boff := d.boffPool.Get().(backoff.BackOff)
 	defer func() {
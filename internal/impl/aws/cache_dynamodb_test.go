@@ -1,10 +1,15 @@
 package aws
 
 import (
+	"context"
+	"fmt"
 	"sync"
 	"testing"
 	"time"
 
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/cenkalti/backoff/v4"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -88,3 +93,72 @@ ttl_key: buz
 		})
 	}
 }
+
+type mockDynamoDBBatchGet struct {
+	dynamoDBAPIV2
+
+	items    map[string][]byte
+	requests [][]string
+}
+
+func (m *mockDynamoDBBatchGet) BatchGetItem(ctx context.Context, params *dynamodb.BatchGetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.BatchGetItemOutput, error) {
+	var keys []string
+	for _, k := range params.RequestItems["foo"].Keys {
+		keys = append(keys, k["id"].(*types.AttributeValueMemberS).Value)
+	}
+	m.requests = append(m.requests, keys)
+
+	out := &dynamodb.BatchGetItemOutput{
+		Responses: map[string][]map[string]types.AttributeValue{},
+	}
+
+	// The last key of the first request is left unprocessed.
+	if len(m.requests) == 1 {
+		last := params.RequestItems["foo"].Keys[len(keys)-1]
+		keys = keys[:len(keys)-1]
+		out.UnprocessedKeys = map[string]types.KeysAndAttributes{
+			"foo": {Keys: []map[string]types.AttributeValue{last}},
+		}
+	}
+
+	for _, k := range keys {
+		if v, exists := m.items[k]; exists {
+			out.Responses["foo"] = append(out.Responses["foo"], map[string]types.AttributeValue{
+				"id":   &types.AttributeValueMemberS{Value: k},
+				"data": &types.AttributeValueMemberB{Value: v},
+			})
+		}
+	}
+	return out, nil
+}
+
+func TestDynamoDBCacheGetMulti(t *testing.T) {
+	client := &mockDynamoDBBatchGet{items: map[string][]byte{}}
+
+	var keys []string
+	expected := map[string][]byte{}
+	for i := 0; i < 150; i++ {
+		key := fmt.Sprintf("key%v", i)
+		keys = append(keys, key)
+		if i%2 == 0 {
+			client.items[key] = []byte(fmt.Sprintf("value%v", i))
+			expected[key] = []byte(fmt.Sprintf("value%v", i))
+		}
+	}
+	keys = append(keys, "key0")
+
+	boff := backoff.NewExponentialBackOff()
+	boff.InitialInterval = time.Millisecond
+	boff.MaxInterval = time.Millisecond
+
+	c := newDynamodbCache(client, "foo", "id", "data", false, nil, nil, boff)
+
+	values, err := c.GetMulti(context.Background(), keys...)
+	require.NoError(t, err)
+	assert.Equal(t, expected, values)
+
+	require.Len(t, client.requests, 2)
+	assert.Len(t, client.requests[0], 100)
+	assert.Len(t, client.requests[1], 51)
+	assert.Equal(t, "key99", client.requests[1][50])
+}
@@ -0,0 +1,343 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/eventbridge"
+	"github.com/aws/aws-sdk-go-v2/service/eventbridge/types"
+	"github.com/cenkalti/backoff/v4"
+
+	"github.com/benthosdev/benthos/v4/internal/impl/aws/config"
+	"github.com/benthosdev/benthos/v4/internal/impl/pure"
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	// EventBridge Output Fields
+	eboFieldEventBus   = "event_bus"
+	eboFieldSource     = "source"
+	eboFieldDetailType = "detail_type"
+	eboFieldResources  = "resources"
+	eboFieldBatching   = "batching"
+
+	// The limits of a single PutEvents request, see
+	// https://docs.aws.amazon.com/eventbridge/latest/userguide/eb-putevent-size.html
+	eventBridgeMaxEntriesCount = 10
+	eventBridgeMaxRequestSize  = 256 * 1024
+)
+
+type eboConfig struct {
+	EventBus   string
+	Source     *service.InterpolatedString
+	DetailType *service.InterpolatedString
+	Resources  []*service.InterpolatedString
+
+	aconf       aws.Config
+	backoffCtor func() backoff.BackOff
+}
+
+func eboConfigFromParsed(pConf *service.ParsedConfig) (conf eboConfig, err error) {
+	if conf.EventBus, err = pConf.FieldString(eboFieldEventBus); err != nil {
+		return
+	}
+	if conf.Source, err = pConf.FieldInterpolatedString(eboFieldSource); err != nil {
+		return
+	}
+	if conf.DetailType, err = pConf.FieldInterpolatedString(eboFieldDetailType); err != nil {
+		return
+	}
+	if conf.Resources, err = pConf.FieldInterpolatedStringList(eboFieldResources); err != nil {
+		return
+	}
+	if conf.aconf, err = GetSession(context.TODO(), pConf); err != nil {
+		return
+	}
+	if conf.backoffCtor, err = pure.CommonRetryBackOffCtorFromParsed(pConf); err != nil {
+		return
+	}
+	return
+}
+
+func eboOutputSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Version("4.28.0").
+		Categories("Services", "AWS").
+		Summary(`Sends messages as events to an EventBridge event bus.`).
+		Description(`
+The contents of each message are sent as the `+"`Detail`"+` of an event and must therefore be a JSON object. The fields `+"`source`, `detail_type` and `resources`"+` can be set dynamically using [function interpolations](/docs/configuration/interpolation#bloblang-queries), which are resolved individually for each message of a batch.
+
+Batches are sent with as few PutEvents requests as possible, where each request contains at most 10 entries and 256KiB of data. Messages that are invalid or exceed the 256KiB entry limit on their own are rejected without being sent.
+
+Entries that are rejected by EventBridge due to throttling or internal failures are retried according to the `+"`backoff`"+` fields, and only those entries are sent again. Entries rejected for any other reason, or that are still failing once the retries are exhausted, are reported as individual message failures.
+
+### Credentials
+
+By default Benthos will use a shared credentials file when connecting to AWS services. It's also possible to set them explicitly at the component level, allowing you to transfer data across accounts. You can find out more [in this document](/docs/guides/cloud/aws).`+service.OutputPerformanceDocs(true, true)).
+		Fields(
+			service.NewStringField(eboFieldEventBus).
+				Description("The name or ARN of the event bus to send events to.").
+				Examples("default", "arn:aws:events:us-east-1:111122223333:event-bus/my-bus").
+				Default("default"),
+			service.NewInterpolatedStringField(eboFieldSource).
+				Description("The source of each event.").
+				Example("com.example.orders"),
+			service.NewInterpolatedStringField(eboFieldDetailType).
+				Description("The detail type of each event, which together with the source identifies the fields and values expected within the event detail.").
+				Example(`${! meta("event_type") }`),
+			service.NewInterpolatedStringListField(eboFieldResources).
+				Description("A list of ARNs of AWS resources that each event primarily concerns. Resources that resolve to an empty string are omitted.").
+				Default([]any{}).
+				Advanced(),
+			service.NewOutputMaxInFlightField().
+				Description("The maximum number of parallel message batches to have in flight at any given time."),
+			service.NewBatchPolicyField(eboFieldBatching),
+		).
+		Fields(config.SessionFields()...).
+		Fields(pure.CommonRetryBackOffFields(0, "1s", "5s", "30s")...)
+}
+
+func init() {
+	err := service.RegisterBatchOutput("aws_eventbridge", eboOutputSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (out service.BatchOutput, batchPolicy service.BatchPolicy, maxInFlight int, err error) {
+			if maxInFlight, err = conf.FieldMaxInFlight(); err != nil {
+				return
+			}
+			if batchPolicy, err = conf.FieldBatchPolicy(eboFieldBatching); err != nil {
+				return
+			}
+			var wConf eboConfig
+			if wConf, err = eboConfigFromParsed(conf); err != nil {
+				return
+			}
+			out, err = newEventBridgeWriter(wConf, mgr)
+			return
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type eventBridgeAPI interface {
+	PutEvents(ctx context.Context, params *eventbridge.PutEventsInput, optFns ...func(*eventbridge.Options)) (*eventbridge.PutEventsOutput, error)
+}
+
+type eventBridgeWriter struct {
+	conf        eboConfig
+	eventbridge eventBridgeAPI
+
+	closer    sync.Once
+	closeChan chan struct{}
+
+	log *service.Logger
+}
+
+func newEventBridgeWriter(conf eboConfig, mgr *service.Resources) (*eventBridgeWriter, error) {
+	return &eventBridgeWriter{
+		conf:      conf,
+		log:       mgr.Logger(),
+		closeChan: make(chan struct{}),
+	}, nil
+}
+
+func (a *eventBridgeWriter) Connect(ctx context.Context) error {
+	if a.eventbridge != nil {
+		return nil
+	}
+
+	a.eventbridge = eventbridge.NewFromConfig(a.conf.aconf)
+	return nil
+}
+
+// eventBridgeEntrySize calculates the size of an entry the way EventBridge
+// does when enforcing the 256KiB limit of PutEvents requests.
+func eventBridgeEntrySize(entry types.PutEventsRequestEntry) int {
+	size := 0
+	if entry.Time != nil {
+		size += 14
+	}
+	size += len(aws.ToString(entry.Source))
+	size += len(aws.ToString(entry.DetailType))
+	size += len(aws.ToString(entry.Detail))
+	for _, r := range entry.Resources {
+		size += len(r)
+	}
+	return size
+}
+
+func (a *eventBridgeWriter) toEntry(batch service.MessageBatch, i int) (types.PutEventsRequestEntry, error) {
+	source, err := batch.TryInterpolatedString(i, a.conf.Source)
+	if err != nil {
+		return types.PutEventsRequestEntry{}, fmt.Errorf("source interpolation error: %w", err)
+	}
+	detailType, err := batch.TryInterpolatedString(i, a.conf.DetailType)
+	if err != nil {
+		return types.PutEventsRequestEntry{}, fmt.Errorf("detail type interpolation error: %w", err)
+	}
+
+	var resources []string
+	for _, r := range a.conf.Resources {
+		resource, err := batch.TryInterpolatedString(i, r)
+		if err != nil {
+			return types.PutEventsRequestEntry{}, fmt.Errorf("resource interpolation error: %w", err)
+		}
+		if resource != "" {
+			resources = append(resources, resource)
+		}
+	}
+
+	structured, err := batch[i].AsStructured()
+	if err != nil {
+		return types.PutEventsRequestEntry{}, fmt.Errorf("event detail must be a JSON object: %w", err)
+	}
+	if _, isObj := structured.(map[string]any); !isObj {
+		return types.PutEventsRequestEntry{}, fmt.Errorf("event detail must be a JSON object, got %T", structured)
+	}
+	detail, err := batch[i].AsBytes()
+	if err != nil {
+		return types.PutEventsRequestEntry{}, err
+	}
+
+	entry := types.PutEventsRequestEntry{
+		Source:     aws.String(source),
+		DetailType: aws.String(detailType),
+		Detail:     aws.String(string(detail)),
+		Resources:  resources,
+	}
+	if a.conf.EventBus != "" {
+		entry.EventBusName = aws.String(a.conf.EventBus)
+	}
+	if size := eventBridgeEntrySize(entry); size > eventBridgeMaxRequestSize {
+		return types.PutEventsRequestEntry{}, fmt.Errorf("event size of %v bytes exceeds the maximum EventBridge entry size of 256KiB", size)
+	}
+	return entry, nil
+}
+
+// isRetryableEventBridgeError returns true if an entry rejected with the given
+// error code might succeed if it were sent again.
+func isRetryableEventBridgeError(code string) bool {
+	return strings.Contains(code, "Throttl") || strings.HasPrefix(code, "Internal") || code == "ServiceUnavailable"
+}
+
+func (a *eventBridgeWriter) WriteBatch(ctx context.Context, batch service.MessageBatch) error {
+	if a.eventbridge == nil {
+		return service.ErrNotConnected
+	}
+
+	var batchErr *service.BatchError
+	failMessage := func(i int, err error) {
+		if batchErr == nil {
+			batchErr = service.NewBatchError(batch, err)
+		}
+		batchErr.Failed(i, err)
+	}
+
+	entries := make([]types.PutEventsRequestEntry, len(batch))
+	pending := make([]int, 0, len(batch))
+	for i := range batch {
+		entry, err := a.toEntry(batch, i)
+		if err != nil {
+			a.log.With("error", err).Error("Failed to prepare event")
+			failMessage(i, err)
+			continue
+		}
+		entries[i] = entry
+		pending = append(pending, i)
+	}
+
+	backOff := a.conf.backoffCtor()
+	for len(pending) > 0 {
+		var retries []int
+		var lastErr error
+
+		for len(pending) > 0 {
+			// Take as many entries as fit within the limits of one request
+			n, size := 0, 0
+			for n < len(pending) && n < eventBridgeMaxEntriesCount {
+				entrySize := eventBridgeEntrySize(entries[pending[n]])
+				if n > 0 && size+entrySize > eventBridgeMaxRequestSize {
+					break
+				}
+				size += entrySize
+				n++
+			}
+
+			var chunk []int
+			chunk, pending = pending[:n], pending[n:]
+
+			input := &eventbridge.PutEventsInput{
+				Entries: make([]types.PutEventsRequestEntry, len(chunk)),
+			}
+			for j, i := range chunk {
+				input.Entries[j] = entries[i]
+			}
+
+			output, err := a.eventbridge.PutEvents(ctx, input)
+			if err != nil {
+				a.log.Warnf("EventBridge error: %v\n", err)
+				lastErr = err
+				retries = append(retries, chunk...)
+				continue
+			}
+			if output.FailedEntryCount == 0 {
+				continue
+			}
+
+			for j, res := range output.Entries {
+				if j >= len(chunk) || res.ErrorCode == nil {
+					continue
+				}
+				code := *res.ErrorCode
+				err := fmt.Errorf("event failed with code [%s] %s", code, aws.ToString(res.ErrorMessage))
+				if isRetryableEventBridgeError(code) {
+					lastErr = err
+					retries = append(retries, chunk[j])
+					continue
+				}
+				a.log.Errorf("EventBridge event error: %v\n", err)
+				failMessage(chunk[j], err)
+			}
+		}
+
+		if len(retries) == 0 {
+			break
+		}
+
+		wait := backOff.NextBackOff()
+		if wait == backoff.Stop {
+			err := fmt.Errorf("%v events failed to be delivered within backoff policy: %w", len(retries), lastErr)
+			for _, i := range retries {
+				failMessage(i, err)
+			}
+			break
+		}
+
+		a.log.Warnf("Scheduling retry of rejected events (%d)\n", len(retries))
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-a.closeChan:
+			return errors.New("output closed while retrying rejected events")
+		}
+		pending = retries
+	}
+
+	if batchErr != nil {
+		return batchErr
+	}
+	return nil
+}
+
+func (a *eventBridgeWriter) Close(context.Context) error {
+	a.closer.Do(func() {
+		close(a.closeChan)
+	})
+	return nil
+}
@@ -0,0 +1,246 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/eventbridge"
+	"github.com/aws/aws-sdk-go-v2/service/eventbridge/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+type mockEventBridge struct {
+	fn func(input *eventbridge.PutEventsInput) (*eventbridge.PutEventsOutput, error)
+}
+
+func (m *mockEventBridge) PutEvents(ctx context.Context, input *eventbridge.PutEventsInput, optFns ...func(*eventbridge.Options)) (*eventbridge.PutEventsOutput, error) {
+	return m.fn(input)
+}
+
+func testEBWriter(t *testing.T, conf string) *eventBridgeWriter {
+	t.Helper()
+
+	pConf, err := eboOutputSpec().ParseYAML(conf, nil)
+	require.NoError(t, err)
+
+	eConf, err := eboConfigFromParsed(pConf)
+	require.NoError(t, err)
+
+	w, err := newEventBridgeWriter(eConf, service.MockResources())
+	require.NoError(t, err)
+
+	return w
+}
+
+func failedEBIndexes(t *testing.T, batch service.MessageBatch, err error) []int {
+	t.Helper()
+
+	var bErr *service.BatchError
+	require.True(t, errors.As(err, &bErr), err)
+
+	index := batch.Index()
+	var failed []int
+	bErr.WalkMessagesIndexedBy(index, func(i int, _ *service.Message, err error) bool {
+		if err != nil {
+			failed = append(failed, i)
+		}
+		return true
+	})
+	sort.Ints(failed)
+	return failed
+}
+
+func TestEventBridgeWriteEntries(t *testing.T) {
+	w := testEBWriter(t, `
+event_bus: my-bus
+source: com.example.${! meta("service") }
+detail_type: ${! json("type") }
+resources:
+  - arn:aws:s3:::${! meta("bucket") }
+`)
+
+	var inputs []*eventbridge.PutEventsInput
+	w.eventbridge = &mockEventBridge{
+		fn: func(input *eventbridge.PutEventsInput) (*eventbridge.PutEventsOutput, error) {
+			inputs = append(inputs, input)
+			return &eventbridge.PutEventsOutput{}, nil
+		},
+	}
+
+	msg := service.NewMessage([]byte(`{"type":"OrderPlaced","id":1}`))
+	msg.MetaSetMut("service", "orders")
+	msg.MetaSetMut("bucket", "foo")
+
+	require.NoError(t, w.WriteBatch(context.Background(), service.MessageBatch{msg}))
+
+	require.Len(t, inputs, 1)
+	require.Len(t, inputs[0].Entries, 1)
+
+	entry := inputs[0].Entries[0]
+	assert.Equal(t, "my-bus", aws.ToString(entry.EventBusName))
+	assert.Equal(t, "com.example.orders", aws.ToString(entry.Source))
+	assert.Equal(t, "OrderPlaced", aws.ToString(entry.DetailType))
+	assert.Equal(t, `{"type":"OrderPlaced","id":1}`, aws.ToString(entry.Detail))
+	assert.Equal(t, []string{"arn:aws:s3:::foo"}, entry.Resources)
+}
+
+func TestEventBridgeWriteChunks(t *testing.T) {
+	w := testEBWriter(t, `
+source: foo
+detail_type: bar
+`)
+
+	var batchLengths []int
+	w.eventbridge = &mockEventBridge{
+		fn: func(input *eventbridge.PutEventsInput) (*eventbridge.PutEventsOutput, error) {
+			size := 0
+			for _, e := range input.Entries {
+				size += eventBridgeEntrySize(e)
+			}
+			assert.LessOrEqual(t, size, eventBridgeMaxRequestSize)
+			batchLengths = append(batchLengths, len(input.Entries))
+			return &eventbridge.PutEventsOutput{}, nil
+		},
+	}
+
+	var batch service.MessageBatch
+	for i := 0; i < 25; i++ {
+		batch = append(batch, service.NewMessage([]byte(`{"id":"small"}`)))
+	}
+	require.NoError(t, w.WriteBatch(context.Background(), batch))
+	assert.Equal(t, []int{10, 10, 5}, batchLengths)
+
+	// Three messages of ~100KiB cannot share a single request
+	batchLengths = nil
+	large := []byte(`{"data":"` + strings.Repeat("x", 100*1024) + `"}`)
+	batch = service.MessageBatch{
+		service.NewMessage(large),
+		service.NewMessage(large),
+		service.NewMessage(large),
+	}
+	require.NoError(t, w.WriteBatch(context.Background(), batch))
+	assert.Equal(t, []int{2, 1}, batchLengths)
+}
+
+func TestEventBridgeWriteInvalidEntries(t *testing.T) {
+	w := testEBWriter(t, `
+source: foo
+detail_type: bar
+`)
+
+	var sent []string
+	w.eventbridge = &mockEventBridge{
+		fn: func(input *eventbridge.PutEventsInput) (*eventbridge.PutEventsOutput, error) {
+			for _, e := range input.Entries {
+				sent = append(sent, aws.ToString(e.Detail))
+			}
+			return &eventbridge.PutEventsOutput{}, nil
+		},
+	}
+
+	batch := service.MessageBatch{
+		service.NewMessage([]byte(`{"id":1}`)),
+		service.NewMessage([]byte(`not json`)),
+		service.NewMessage([]byte(`{"data":"` + strings.Repeat("x", 300*1024) + `"}`)),
+		service.NewMessage([]byte(`[1,2,3]`)),
+		service.NewMessage([]byte(`{"id":2}`)),
+	}
+
+	err := w.WriteBatch(context.Background(), batch)
+	require.Error(t, err)
+	assert.Equal(t, []int{1, 2, 3}, failedEBIndexes(t, batch, err))
+	assert.Equal(t, []string{`{"id":1}`, `{"id":2}`}, sent)
+}
+
+func TestEventBridgeWritePartialFailures(t *testing.T) {
+	w := testEBWriter(t, `
+source: foo
+detail_type: bar
+backoff:
+  initial_interval: 1ms
+  max_interval: 1ms
+`)
+
+	var calls [][]string
+	w.eventbridge = &mockEventBridge{
+		fn: func(input *eventbridge.PutEventsInput) (*eventbridge.PutEventsOutput, error) {
+			var details []string
+			output := &eventbridge.PutEventsOutput{}
+			for _, e := range input.Entries {
+				detail := aws.ToString(e.Detail)
+				details = append(details, detail)
+
+				res := types.PutEventsResultEntry{EventId: aws.String("id")}
+				switch {
+				case detail == `{"id":"throttled"}` && len(calls) == 0:
+					res = types.PutEventsResultEntry{
+						ErrorCode:    aws.String("ThrottlingException"),
+						ErrorMessage: aws.String("slow down"),
+					}
+				case detail == `{"id":"malformed"}`:
+					res = types.PutEventsResultEntry{
+						ErrorCode:    aws.String("MalformedDetail"),
+						ErrorMessage: aws.String("bad detail"),
+					}
+				}
+				if res.ErrorCode != nil {
+					output.FailedEntryCount++
+				}
+				output.Entries = append(output.Entries, res)
+			}
+			calls = append(calls, details)
+			return output, nil
+		},
+	}
+
+	batch := service.MessageBatch{
+		service.NewMessage([]byte(`{"id":"ok"}`)),
+		service.NewMessage([]byte(`{"id":"throttled"}`)),
+		service.NewMessage([]byte(`{"id":"malformed"}`)),
+	}
+
+	err := w.WriteBatch(context.Background(), batch)
+	require.Error(t, err)
+	assert.Equal(t, []int{2}, failedEBIndexes(t, batch, err))
+	assert.Equal(t, [][]string{
+		{`{"id":"ok"}`, `{"id":"throttled"}`, `{"id":"malformed"}`},
+		{`{"id":"throttled"}`},
+	}, calls)
+}
+
+func TestEventBridgeWriteRetriesExhausted(t *testing.T) {
+	w := testEBWriter(t, `
+source: foo
+detail_type: bar
+backoff:
+  initial_interval: 1ms
+  max_interval: 1ms
+  max_elapsed_time: 1ms
+`)
+
+	var calls int
+	w.eventbridge = &mockEventBridge{
+		fn: func(input *eventbridge.PutEventsInput) (*eventbridge.PutEventsOutput, error) {
+			calls++
+			return nil, errors.New("service unavailable")
+		},
+	}
+
+	batch := service.MessageBatch{
+		service.NewMessage([]byte(`{"id":1}`)),
+		service.NewMessage([]byte(`{"id":2}`)),
+	}
+
+	err := w.WriteBatch(context.Background(), batch)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "service unavailable")
+	assert.Equal(t, []int{0, 1}, failedEBIndexes(t, batch, err))
+	assert.GreaterOrEqual(t, calls, 1)
+}
@@ -1,6 +1,7 @@
 package aws
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"time"
@@ -17,12 +18,18 @@ import (
 
 const (
 	// Kinesis Firehose Output Fields
-	kfoFieldStream   = "stream"
-	kfoFieldBatching = "batching"
+	kfoFieldStream        = "stream"
+	kfoFieldAppendNewline = "append_newline"
+	kfoFieldBatching      = "batching"
+
+	// The limits of a single PutRecordBatch request
+	firehoseMaxRecordsCount = 500
+	firehoseMaxRequestSize  = 4 * mebibyte
 )
 
 type kfoConfig struct {
-	Stream string
+	Stream        string
+	AppendNewline bool
 
 	aconf       aws.Config
 	backoffCtor func() backoff.BackOff
@@ -32,6 +39,9 @@ func kfoConfigFromParsed(pConf *service.ParsedConfig) (conf kfoConfig, err error
 	if conf.Stream, err = pConf.FieldString(kfoFieldStream); err != nil {
 		return
 	}
+	if conf.AppendNewline, err = pConf.FieldBool(kfoFieldAppendNewline); err != nil {
+		return
+	}
 	if conf.aconf, err = GetSession(context.TODO(), pConf); err != nil {
 		return
 	}
@@ -48,6 +58,10 @@ func kfoOutputSpec() *service.ConfigSpec {
 		Categories("Services", "AWS").
 		Summary(`Sends messages to a Kinesis Firehose delivery stream.`).
 		Description(`
+Batches are sent with as few PutRecordBatch requests as possible, where each request contains at most 500 records and 4 MiB of data. Records that are rejected due to throttling are retried according to the `+"`backoff`"+` fields, and only those records are sent again.
+
+Firehose concatenates records when delivering them to destinations such as S3, and therefore when the contents of messages do not end with a delimiter the field `+"`append_newline`"+` can be used in order to produce newline delimited objects.
+
 ### Credentials
 
 By default Benthos will use a shared credentials file when connecting to AWS services. It's also possible to set them explicitly at the component level, allowing you to transfer data across accounts. You can find out more [in this document](/docs/guides/cloud/aws).
@@ -61,6 +75,10 @@ This output benefits from sending messages as a batch for improved performance.
 		Fields(
 			service.NewStringField(kfoFieldStream).
 				Description("The stream to publish messages to."),
+			service.NewBoolField(kfoFieldAppendNewline).
+				Description("Whether to append a newline to the contents of each record that does not already end with one, which is useful for delivering newline delimited records to destinations such as S3.").
+				Version("4.28.0").
+				Default(false),
 			service.NewOutputMaxInFlightField(),
 			service.NewBatchPolicyField(kfoFieldBatching),
 		).
@@ -122,6 +140,11 @@ func (a *kinesisFirehoseWriter) toRecords(batch service.MessageBatch) ([]types.R
 		if entry.Data, err = p.AsBytes(); err != nil {
 			return nil, err
 		}
+		if a.conf.AppendNewline && !bytes.HasSuffix(entry.Data, []byte("\n")) {
+			data := make([]byte, len(entry.Data), len(entry.Data)+1)
+			copy(data, entry.Data)
+			entry.Data = append(data, '\n')
+		}
 
 		if len(entry.Data) > mebibyte {
 			err = fmt.Errorf("batch message %d exceeds the maximum Kinesis Firehose payload limit of 1 MiB", i)
@@ -135,6 +158,27 @@ func (a *kinesisFirehoseWriter) toRecords(batch service.MessageBatch) ([]types.R
 	return entries, nil
 }
 
+// fillRecordBatch moves records from the front of remaining onto the end of
+// current until either the record count or the request size limit of a
+// PutRecordBatch request would be exceeded. At least one record is always moved
+// when current is empty.
+func fillRecordBatch(current, remaining []types.Record) ([]types.Record, []types.Record) {
+	size := 0
+	for _, r := range current {
+		size += len(r.Data)
+	}
+	n := 0
+	for n < len(remaining) && len(current)+n < firehoseMaxRecordsCount {
+		recordSize := len(remaining[n].Data)
+		if len(current)+n > 0 && size+recordSize > firehoseMaxRequestSize {
+			break
+		}
+		size += recordSize
+		n++
+	}
+	return append(current, remaining[:n]...), remaining[n:]
+}
+
 //------------------------------------------------------------------------------
 
 // Connect creates a new Kinesis Firehose client and ensures that the target
@@ -154,7 +198,7 @@ func (a *kinesisFirehoseWriter) Connect(ctx context.Context) error {
 }
 
 // WriteBatch attempts to write message contents to a target Kinesis
-// Firehose delivery stream in batches of 500. If throttling is detected, failed
+// Firehose delivery stream in batches of up to 500 records and 4 MiB. If throttling is detected, failed
 // messages are retried according to the configurable backoff settings.
 func (a *kinesisFirehoseWriter) WriteBatch(ctx context.Context, batch service.MessageBatch) error {
 	if a.firehose == nil {
@@ -169,16 +213,11 @@ func (a *kinesisFirehoseWriter) WriteBatch(ctx context.Context, batch service.Me
 	}
 
 	input := &firehose.PutRecordBatchInput{
-		Records:            records,
 		DeliveryStreamName: aws.String(a.conf.Stream),
 	}
 
-	// trim input record length to max kinesis firehose batch size
-	if len(records) > kinesisMaxRecordsCount {
-		input.Records, records = records[:kinesisMaxRecordsCount], records[kinesisMaxRecordsCount:]
-	} else {
-		records = nil
-	}
+	// trim input records to the limits of a kinesis firehose batch
+	input.Records, records = fillRecordBatch(nil, records)
 
 	var failed []types.Record
 	for len(input.Records) > 0 {
@@ -222,13 +261,7 @@ func (a *kinesisFirehoseWriter) WriteBatch(ctx context.Context, batch service.Me
 		}
 
 		// add remaining records to batch
-		if n := len(records); n > 0 && l < kinesisMaxRecordsCount {
-			if remaining := kinesisMaxRecordsCount - l; remaining < n {
-				input.Records, records = append(input.Records, records[:remaining]...), records[remaining:]
-			} else {
-				input.Records, records = append(input.Records, records...), nil
-			}
-		}
+		input.Records, records = fillRecordBatch(input.Records, records)
 	}
 	return err
 }
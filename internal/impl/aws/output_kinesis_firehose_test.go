@@ -1,6 +1,7 @@
 package aws
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
@@ -282,3 +283,59 @@ func TestKinesisFirehoseWriteBackoffMaxRetriesExceeded(t *testing.T) {
 		t.Errorf("Expected kinesis firehose PutRecordBatch to have call count %d, got %d", exp, calls)
 	}
 }
+
+func TestKinesisFirehoseWriteChunkBySize(t *testing.T) {
+	var batchLengths []int
+
+	k := testKFO(t,
+		&mockKinesisFirehose{
+			fn: func(input *firehose.PutRecordBatchInput) (*firehose.PutRecordBatchOutput, error) {
+				size := 0
+				for _, r := range input.Records {
+					size += len(r.Data)
+				}
+				if size > firehoseMaxRequestSize {
+					return nil, fmt.Errorf("request size %d exceeds limit", size)
+				}
+				batchLengths = append(batchLengths, len(input.Records))
+				return &firehose.PutRecordBatchOutput{}, nil
+			},
+		},
+	)
+
+	var msg service.MessageBatch
+	for i := 0; i < 10; i++ {
+		msg = append(msg, service.NewMessage(bytes.Repeat([]byte("x"), 900*1024)))
+	}
+
+	require.NoError(t, k.WriteBatch(context.Background(), msg))
+	require.Equal(t, []int{4, 4, 2}, batchLengths)
+}
+
+func TestKinesisFirehoseWriteAppendNewline(t *testing.T) {
+	var data []string
+
+	k := testKFO(t,
+		&mockKinesisFirehose{
+			fn: func(input *firehose.PutRecordBatchInput) (*firehose.PutRecordBatchOutput, error) {
+				for _, r := range input.Records {
+					data = append(data, string(r.Data))
+				}
+				return &firehose.PutRecordBatchOutput{}, nil
+			},
+		},
+	)
+	k.conf.AppendNewline = true
+
+	msg := service.MessageBatch{
+		service.NewMessage([]byte(`{"id":1}`)),
+		service.NewMessage([]byte("{\"id\":2}\n")),
+	}
+
+	require.NoError(t, k.WriteBatch(context.Background(), msg))
+	require.Equal(t, []string{"{\"id\":1}\n", "{\"id\":2}\n"}, data)
+
+	b, err := msg[0].AsBytes()
+	require.NoError(t, err)
+	require.Equal(t, `{"id":1}`, string(b))
+}
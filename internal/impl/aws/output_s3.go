@@ -37,6 +37,7 @@ const (
 	s3oFieldKMSKeyID                = "kms_key_id"
 	s3oFieldServerSideEncryption    = "server_side_encryption"
 	s3oFieldBatching                = "batching"
+	s3oFieldRolling                 = "rolling"
 )
 
 type s3TagPair struct {
@@ -61,6 +62,7 @@ type s3oConfig struct {
 	KMSKeyID                string
 	ServerSideEncryption    string
 	UsePathStyle            bool
+	Rolling                 s3oRollingConfig
 
 	aconf aws.Config
 }
@@ -124,6 +126,9 @@ func s3oConfigFromParsed(pConf *service.ParsedConfig) (conf s3oConfig, err error
 	if conf.ServerSideEncryption, err = pConf.FieldString(s3oFieldServerSideEncryption); err != nil {
 		return
 	}
+	if conf.Rolling, err = s3oRollingConfigFromParsed(pConf.Namespace(s3oFieldRolling)); err != nil {
+		return
+	}
 	if conf.aconf, err = GetSession(context.TODO(), pConf); err != nil {
 		return
 	}
@@ -194,6 +199,30 @@ output:
       processors:
         - archive:
             format: json_array
+`+"```"+`
+
+### Rolling Files
+
+Setting `+"`rolling.enabled`"+` to `+"`true`"+` accumulates messages into large files rather than uploading an object per message. Each message is added to the open file of its `+"`rolling.partition`"+`, which is usually a Hive-style prefix such as `+"`dt=2024-05-01/hour=13`"+`, followed by `+"`rolling.separator`"+`. A file is rolled, making it visible as an object, once it reaches `+"`rolling.max_size`"+` bytes or once `+"`rolling.max_age`"+` has passed since it was opened.
+
+Files are written with multipart uploads, where parts are uploaded as the file grows, and the object only becomes visible once the upload is completed, so readers never observe partially written files. The key of each object is the partition followed by a unique file name ending with `+"`rolling.file_suffix`"+`, and the `+"`path`"+` field is ignored. The remaining attributes of an object, such as its content type, tags and metadata, are resolved from the message that opened the file.
+
+Messages are only acknowledged once the file they were added to has been rolled, and so messages of a file that fails to upload are all retried. In order for files to accumulate many batches the `+"`max_in_flight`"+` field should be set high enough to cover the number of batches expected within `+"`rolling.max_age`"+`:
+
+`+"```yaml"+`
+output:
+  aws_s3:
+    bucket: TODO
+    max_in_flight: 256
+    rolling:
+      enabled: true
+      partition: events/dt=${! timestamp_unix().ts_format("2006-01-02", "UTC") }/hour=${! timestamp_unix().ts_format("15", "UTC") }
+      file_suffix: .jsonl
+      max_size: 128MiB
+      max_age: 5m
+    batching:
+      count: 1000
+      period: 1s
 `+"```"+``+service.OutputPerformanceDocs(true, false)).
 		Fields(
 			service.NewStringField(s3oFieldBucket).
@@ -261,6 +290,7 @@ output:
 				Advanced().
 				Default("5s"),
 			service.NewBatchPolicyField(s3oFieldBatching),
+			s3oRollingField(),
 		).
 		Fields(config.SessionFields()...)
 }
@@ -278,6 +308,10 @@ func init() {
 			if wConf, err = s3oConfigFromParsed(conf); err != nil {
 				return
 			}
+			if wConf.Rolling.Enabled {
+				out, err = newS3RollingWriter(wConf, mgr)
+				return
+			}
 			out, err = newAmazonS3Writer(wConf, mgr)
 			return
 		})
@@ -321,113 +355,125 @@ func (a *amazonS3Writer) WriteBatch(wctx context.Context, msg service.MessageBat
 	defer cancel()
 
 	return msg.WalkWithBatchedErrors(func(i int, m *service.Message) error {
-		metadata := map[string]string{}
-		_ = a.conf.Metadata.WalkMut(m, func(k string, v any) error {
-			metadata[k] = bloblang.ValueToString(v)
-			return nil
-		})
-
-		var contentEncoding *string
-		ce, err := msg.TryInterpolatedString(i, a.conf.ContentEncoding)
+		uploadInput, err := a.conf.putObjectInput(msg, i)
 		if err != nil {
-			return fmt.Errorf("content encoding interpolation: %w", err)
-		}
-		if ce != "" {
-			contentEncoding = aws.String(ce)
-		}
-		var cacheControl *string
-		if ce, err = msg.TryInterpolatedString(i, a.conf.CacheControl); err != nil {
-			return fmt.Errorf("cache control interpolation: %w", err)
-		}
-		if ce != "" {
-			cacheControl = aws.String(ce)
-		}
-		var contentDisposition *string
-		if ce, err = msg.TryInterpolatedString(i, a.conf.ContentDisposition); err != nil {
-			return fmt.Errorf("content disposition interpolation: %w", err)
-		}
-		if ce != "" {
-			contentDisposition = aws.String(ce)
-		}
-		var contentLanguage *string
-		if ce, err = msg.TryInterpolatedString(i, a.conf.ContentLanguage); err != nil {
-			return fmt.Errorf("content language interpolation: %w", err)
-		}
-		if ce != "" {
-			contentLanguage = aws.String(ce)
-		}
-		var websiteRedirectLocation *string
-		if ce, err = msg.TryInterpolatedString(i, a.conf.WebsiteRedirectLocation); err != nil {
-			return fmt.Errorf("website redirect location interpolation: %w", err)
-		}
-		if ce != "" {
-			websiteRedirectLocation = aws.String(ce)
+			return err
 		}
 
-		key, err := msg.TryInterpolatedString(i, a.conf.Path)
+		mBytes, err := m.AsBytes()
 		if err != nil {
-			return fmt.Errorf("key interpolation: %w", err)
+			return err
 		}
+		uploadInput.Body = bytes.NewReader(mBytes)
 
-		contentType, err := msg.TryInterpolatedString(i, a.conf.ContentType)
-		if err != nil {
-			return fmt.Errorf("content type interpolation: %w", err)
+		if _, err := a.uploader.Upload(ctx, uploadInput); err != nil {
+			return err
 		}
+		return nil
+	})
+}
 
-		storageClass, err := msg.TryInterpolatedString(i, a.conf.StorageClass)
-		if err != nil {
-			return fmt.Errorf("storage class interpolation: %w", err)
-		}
+// putObjectInput resolves the key and attributes of an object from a message
+// of a batch, leaving the body of the object unset.
+func (conf *s3oConfig) putObjectInput(msg service.MessageBatch, i int) (*s3.PutObjectInput, error) {
+	m := msg[i]
 
-		mBytes, err := m.AsBytes()
-		if err != nil {
-			return err
-		}
+	metadata := map[string]string{}
+	_ = conf.Metadata.WalkMut(m, func(k string, v any) error {
+		metadata[k] = bloblang.ValueToString(v)
+		return nil
+	})
 
-		uploadInput := &s3.PutObjectInput{
-			Bucket:                  &a.conf.Bucket,
-			Key:                     aws.String(key),
-			Body:                    bytes.NewReader(mBytes),
-			ContentType:             aws.String(contentType),
-			ContentEncoding:         contentEncoding,
-			CacheControl:            cacheControl,
-			ContentDisposition:      contentDisposition,
-			ContentLanguage:         contentLanguage,
-			WebsiteRedirectLocation: websiteRedirectLocation,
-			StorageClass:            types.StorageClass(storageClass),
-			Metadata:                metadata,
-		}
+	var contentEncoding *string
+	ce, err := msg.TryInterpolatedString(i, conf.ContentEncoding)
+	if err != nil {
+		return nil, fmt.Errorf("content encoding interpolation: %w", err)
+	}
+	if ce != "" {
+		contentEncoding = aws.String(ce)
+	}
+	var cacheControl *string
+	if ce, err = msg.TryInterpolatedString(i, conf.CacheControl); err != nil {
+		return nil, fmt.Errorf("cache control interpolation: %w", err)
+	}
+	if ce != "" {
+		cacheControl = aws.String(ce)
+	}
+	var contentDisposition *string
+	if ce, err = msg.TryInterpolatedString(i, conf.ContentDisposition); err != nil {
+		return nil, fmt.Errorf("content disposition interpolation: %w", err)
+	}
+	if ce != "" {
+		contentDisposition = aws.String(ce)
+	}
+	var contentLanguage *string
+	if ce, err = msg.TryInterpolatedString(i, conf.ContentLanguage); err != nil {
+		return nil, fmt.Errorf("content language interpolation: %w", err)
+	}
+	if ce != "" {
+		contentLanguage = aws.String(ce)
+	}
+	var websiteRedirectLocation *string
+	if ce, err = msg.TryInterpolatedString(i, conf.WebsiteRedirectLocation); err != nil {
+		return nil, fmt.Errorf("website redirect location interpolation: %w", err)
+	}
+	if ce != "" {
+		websiteRedirectLocation = aws.String(ce)
+	}
 
-		// Prepare tags, escaping keys and values to ensure they're valid query string parameters.
-		if len(a.conf.Tags) > 0 {
-			tags := make([]string, len(a.conf.Tags))
-			for j, pair := range a.conf.Tags {
-				tagStr, err := msg.TryInterpolatedString(i, pair.value)
-				if err != nil {
-					return fmt.Errorf("tag %v interpolation: %w", pair.key, err)
-				}
-				tags[j] = url.QueryEscape(pair.key) + "=" + url.QueryEscape(tagStr)
-			}
-			uploadInput.Tagging = aws.String(strings.Join(tags, "&"))
-		}
+	key, err := msg.TryInterpolatedString(i, conf.Path)
+	if err != nil {
+		return nil, fmt.Errorf("key interpolation: %w", err)
+	}
 
-		if a.conf.KMSKeyID != "" {
-			uploadInput.ServerSideEncryption = types.ServerSideEncryptionAwsKms
-			uploadInput.SSEKMSKeyId = &a.conf.KMSKeyID
-		}
+	contentType, err := msg.TryInterpolatedString(i, conf.ContentType)
+	if err != nil {
+		return nil, fmt.Errorf("content type interpolation: %w", err)
+	}
 
-		// NOTE: This overrides the ServerSideEncryption set above. We need this to preserve
-		// backwards compatibility, where it is allowed to only set kms_key_id in the config and
-		// the ServerSideEncryption value of "aws:kms" is implied.
-		if a.conf.ServerSideEncryption != "" {
-			uploadInput.ServerSideEncryption = types.ServerSideEncryption(a.conf.ServerSideEncryption)
-		}
+	storageClass, err := msg.TryInterpolatedString(i, conf.StorageClass)
+	if err != nil {
+		return nil, fmt.Errorf("storage class interpolation: %w", err)
+	}
 
-		if _, err := a.uploader.Upload(ctx, uploadInput); err != nil {
-			return err
+	uploadInput := &s3.PutObjectInput{
+		Bucket:                  &conf.Bucket,
+		Key:                     aws.String(key),
+		ContentType:             aws.String(contentType),
+		ContentEncoding:         contentEncoding,
+		CacheControl:            cacheControl,
+		ContentDisposition:      contentDisposition,
+		ContentLanguage:         contentLanguage,
+		WebsiteRedirectLocation: websiteRedirectLocation,
+		StorageClass:            types.StorageClass(storageClass),
+		Metadata:                metadata,
+	}
+
+	// Prepare tags, escaping keys and values to ensure they're valid query string parameters.
+	if len(conf.Tags) > 0 {
+		tags := make([]string, len(conf.Tags))
+		for j, pair := range conf.Tags {
+			tagStr, err := msg.TryInterpolatedString(i, pair.value)
+			if err != nil {
+				return nil, fmt.Errorf("tag %v interpolation: %w", pair.key, err)
+			}
+			tags[j] = url.QueryEscape(pair.key) + "=" + url.QueryEscape(tagStr)
 		}
-		return nil
-	})
+		uploadInput.Tagging = aws.String(strings.Join(tags, "&"))
+	}
+
+	if conf.KMSKeyID != "" {
+		uploadInput.ServerSideEncryption = types.ServerSideEncryptionAwsKms
+		uploadInput.SSEKMSKeyId = &conf.KMSKeyID
+	}
+
+	// NOTE: This overrides the ServerSideEncryption set above. We need this to preserve
+	// backwards compatibility, where it is allowed to only set kms_key_id in the config and
+	// the ServerSideEncryption value of "aws:kms" is implied.
+	if conf.ServerSideEncryption != "" {
+		uploadInput.ServerSideEncryption = types.ServerSideEncryption(conf.ServerSideEncryption)
+	}
+	return uploadInput, nil
 }
 
 func (a *amazonS3Writer) Close(context.Context) error {
@@ -0,0 +1,458 @@
+package aws
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/shutdown"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/dustin/go-humanize"
+	"github.com/gofrs/uuid"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	// S3 Output Rolling Fields
+	s3orFieldEnabled    = "enabled"
+	s3orFieldPartition  = "partition"
+	s3orFieldFileSuffix = "file_suffix"
+	s3orFieldSeparator  = "separator"
+	s3orFieldMaxSize    = "max_size"
+	s3orFieldMaxAge     = "max_age"
+	s3orFieldPartSize   = "part_size"
+
+	// The minimum size of all but the last part of a multipart upload.
+	s3MinPartSize = 5 * 1024 * 1024
+)
+
+type s3oRollingConfig struct {
+	Enabled    bool
+	Partition  *service.InterpolatedString
+	FileSuffix string
+	Separator  []byte
+	MaxSize    int64
+	MaxAge     time.Duration
+	PartSize   int64
+}
+
+func s3oRollingField() *service.ConfigField {
+	return service.NewObjectField(s3oFieldRolling,
+		service.NewBoolField(s3orFieldEnabled).
+			Description("Whether to accumulate messages into rolling files rather than uploading an object per message.").
+			Default(false),
+		service.NewInterpolatedStringField(s3orFieldPartition).
+			Description("The partition of each message, which is used as the prefix of the key of the file it is added to.").
+			Example(`dt=${! timestamp_unix().ts_format("2006-01-02", "UTC") }/hour=${! timestamp_unix().ts_format("15", "UTC") }`).
+			Example(`${! meta("kafka_topic") }/dt=${! this.created_at.ts_parse("2006-01-02T15:04:05Z07:00").ts_format("2006-01-02", "UTC") }`).
+			Default(""),
+		service.NewStringField(s3orFieldFileSuffix).
+			Description("A suffix to add to the name of each file.").
+			Example(".jsonl").
+			Default(""),
+		service.NewStringField(s3orFieldSeparator).
+			Description("A separator added after each message of a file.").
+			Default("\n"),
+		service.NewStringField(s3orFieldMaxSize).
+			Description("The size in bytes at which a file is rolled.").
+			Example("64MiB").
+			Example("1GB").
+			Default("128MiB"),
+		service.NewDurationField(s3orFieldMaxAge).
+			Description("The maximum period of time after a file is opened before it is rolled.").
+			Default("5m"),
+		service.NewStringField(s3orFieldPartSize).
+			Description("The size of the parts uploaded as a file grows, which must be at least 5MiB.").
+			Default("16MiB").
+			Advanced(),
+	).
+		Description("Accumulate messages into partitioned files that are rolled once they reach a size or age. Check out the [rolling files](#rolling-files) section for more details.").
+		Version("4.28.0")
+}
+
+func s3oRollingConfigFromParsed(pConf *service.ParsedConfig) (conf s3oRollingConfig, err error) {
+	if conf.Enabled, err = pConf.FieldBool(s3orFieldEnabled); err != nil {
+		return
+	}
+	if conf.Partition, err = pConf.FieldInterpolatedString(s3orFieldPartition); err != nil {
+		return
+	}
+	if conf.FileSuffix, err = pConf.FieldString(s3orFieldFileSuffix); err != nil {
+		return
+	}
+	var sep string
+	if sep, err = pConf.FieldString(s3orFieldSeparator); err != nil {
+		return
+	}
+	conf.Separator = []byte(sep)
+
+	var sizeStr string
+	var size uint64
+	if sizeStr, err = pConf.FieldString(s3orFieldMaxSize); err != nil {
+		return
+	}
+	if size, err = humanize.ParseBytes(sizeStr); err != nil {
+		err = fmt.Errorf("failed to parse max_size: %w", err)
+		return
+	}
+	conf.MaxSize = int64(size)
+
+	if sizeStr, err = pConf.FieldString(s3orFieldPartSize); err != nil {
+		return
+	}
+	if size, err = humanize.ParseBytes(sizeStr); err != nil {
+		err = fmt.Errorf("failed to parse part_size: %w", err)
+		return
+	}
+	if size < s3MinPartSize {
+		err = fmt.Errorf("part_size must be at least 5MiB, got %v", sizeStr)
+		return
+	}
+	conf.PartSize = int64(size)
+
+	if conf.MaxAge, err = pConf.FieldDuration(s3orFieldMaxAge); err != nil {
+		return
+	}
+	if conf.Enabled && (conf.MaxSize <= 0 || conf.MaxAge <= 0) {
+		err = errors.New("max_size and max_age must both be greater than zero")
+		return
+	}
+	return
+}
+
+//------------------------------------------------------------------------------
+
+type s3RollingAPI interface {
+	PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error)
+	CreateMultipartUpload(ctx context.Context, params *s3.CreateMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CreateMultipartUploadOutput, error)
+	UploadPart(ctx context.Context, params *s3.UploadPartInput, optFns ...func(*s3.Options)) (*s3.UploadPartOutput, error)
+	CompleteMultipartUpload(ctx context.Context, params *s3.CompleteMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CompleteMultipartUploadOutput, error)
+	AbortMultipartUpload(ctx context.Context, params *s3.AbortMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.AbortMultipartUploadOutput, error)
+}
+
+// s3RollingFile is a file that accumulates messages until it is rolled. Bytes
+// are buffered until enough have accumulated for a part of a multipart upload,
+// and files rolled before uploading any parts are written with a single put.
+type s3RollingFile struct {
+	attrs    *s3.PutObjectInput
+	opened   time.Time
+	size     int64
+	buf      bytes.Buffer
+	uploadID *string
+	parts    []types.CompletedPart
+
+	// Closed once the file has been rolled, with err set when it failed.
+	done chan struct{}
+	err  error
+}
+
+type s3RollingWriter struct {
+	conf s3oConfig
+	log  *service.Logger
+
+	nowFn         func() time.Time
+	checkInterval time.Duration
+
+	mut    sync.Mutex
+	client s3RollingAPI
+	files  map[string]*s3RollingFile
+
+	shutSig *shutdown.Signaller
+}
+
+func newS3RollingWriter(conf s3oConfig, mgr *service.Resources) (*s3RollingWriter, error) {
+	r := &s3RollingWriter{
+		conf:    conf,
+		log:     mgr.Logger(),
+		nowFn:   time.Now,
+		files:   map[string]*s3RollingFile{},
+		shutSig: shutdown.NewSignaller(),
+	}
+
+	// Check the age of files often enough for them to be rolled close to
+	// their maximum age.
+	r.checkInterval = conf.Rolling.MaxAge / 10
+	if r.checkInterval > time.Second {
+		r.checkInterval = time.Second
+	}
+	return r, nil
+}
+
+func (r *s3RollingWriter) Connect(ctx context.Context) error {
+	r.mut.Lock()
+	defer r.mut.Unlock()
+
+	if r.client != nil {
+		return nil
+	}
+	r.startLocked(s3.NewFromConfig(r.conf.aconf, func(o *s3.Options) {
+		o.UsePathStyle = r.conf.UsePathStyle
+	}))
+	return nil
+}
+
+func (r *s3RollingWriter) startLocked(client s3RollingAPI) {
+	r.client = client
+	go r.loop()
+}
+
+// loop rolls files that have reached their maximum age, and rolls all files
+// once the output is closing.
+func (r *s3RollingWriter) loop() {
+	defer r.shutSig.TriggerHasStopped()
+
+	ticker := time.NewTicker(r.checkInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			r.mut.Lock()
+			now := r.nowFn()
+			for partition, f := range r.files {
+				if now.Sub(f.opened) >= r.conf.Rolling.MaxAge {
+					r.rollLocked(partition, f)
+				}
+			}
+			r.mut.Unlock()
+		case <-r.shutSig.SoftStopChan():
+			r.mut.Lock()
+			for partition, f := range r.files {
+				r.rollLocked(partition, f)
+			}
+			r.client = nil
+			r.mut.Unlock()
+			return
+		}
+	}
+}
+
+func (r *s3RollingWriter) fileKey(partition string) string {
+	name := strconv.FormatInt(r.nowFn().UnixNano(), 10) + "-" + uuid.Must(uuid.NewV4()).String() + r.conf.Rolling.FileSuffix
+	if partition == "" {
+		return name
+	}
+	return path.Join(partition, name)
+}
+
+func (r *s3RollingWriter) WriteBatch(ctx context.Context, batch service.MessageBatch) error {
+	var partitions []string
+	byPartition := map[string][]int{}
+	for i := range batch {
+		partition, err := batch.TryInterpolatedString(i, r.conf.Rolling.Partition)
+		if err != nil {
+			return fmt.Errorf("partition interpolation: %w", err)
+		}
+		if _, exists := byPartition[partition]; !exists {
+			partitions = append(partitions, partition)
+		}
+		byPartition[partition] = append(byPartition[partition], i)
+	}
+
+	r.mut.Lock()
+	if r.client == nil {
+		r.mut.Unlock()
+		return service.ErrNotConnected
+	}
+
+	files := make([]*s3RollingFile, 0, len(partitions))
+	for _, partition := range partitions {
+		f, err := r.appendLocked(partition, batch, byPartition[partition])
+		if err != nil {
+			r.mut.Unlock()
+			return err
+		}
+		files = append(files, f)
+	}
+	r.mut.Unlock()
+
+	// Messages are only delivered once the files they were added to have been
+	// rolled.
+	for _, f := range files {
+		select {
+		case <-f.done:
+			if f.err != nil {
+				return f.err
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+func (r *s3RollingWriter) appendLocked(partition string, batch service.MessageBatch, indexes []int) (*s3RollingFile, error) {
+	f, exists := r.files[partition]
+	if !exists {
+		attrs, err := r.conf.putObjectInput(batch, indexes[0])
+		if err != nil {
+			return nil, err
+		}
+		attrs.Key = aws.String(r.fileKey(partition))
+		f = &s3RollingFile{
+			attrs:  attrs,
+			opened: r.nowFn(),
+			done:   make(chan struct{}),
+		}
+		r.files[partition] = f
+	}
+
+	for _, i := range indexes {
+		mBytes, err := batch[i].AsBytes()
+		if err != nil {
+			return nil, err
+		}
+		f.buf.Write(mBytes)
+		f.buf.Write(r.conf.Rolling.Separator)
+		f.size += int64(len(mBytes) + len(r.conf.Rolling.Separator))
+	}
+
+	if int64(f.buf.Len()) >= r.conf.Rolling.PartSize {
+		if err := r.uploadPartLocked(f); err != nil {
+			r.failLocked(partition, f, err)
+			return nil, err
+		}
+	}
+	if f.size >= r.conf.Rolling.MaxSize {
+		r.rollLocked(partition, f)
+	}
+	return f, nil
+}
+
+func (r *s3RollingWriter) uploadPartLocked(f *s3RollingFile) error {
+	ctx, cancel := context.WithTimeout(context.Background(), r.conf.Timeout)
+	defer cancel()
+
+	if f.uploadID == nil {
+		res, err := r.client.CreateMultipartUpload(ctx, &s3.CreateMultipartUploadInput{
+			Bucket:                  f.attrs.Bucket,
+			Key:                     f.attrs.Key,
+			ContentType:             f.attrs.ContentType,
+			ContentEncoding:         f.attrs.ContentEncoding,
+			CacheControl:            f.attrs.CacheControl,
+			ContentDisposition:      f.attrs.ContentDisposition,
+			ContentLanguage:         f.attrs.ContentLanguage,
+			WebsiteRedirectLocation: f.attrs.WebsiteRedirectLocation,
+			StorageClass:            f.attrs.StorageClass,
+			Metadata:                f.attrs.Metadata,
+			Tagging:                 f.attrs.Tagging,
+			ServerSideEncryption:    f.attrs.ServerSideEncryption,
+			SSEKMSKeyId:             f.attrs.SSEKMSKeyId,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create multipart upload: %w", err)
+		}
+		f.uploadID = res.UploadId
+	}
+
+	partNumber := int32(len(f.parts) + 1)
+	res, err := r.client.UploadPart(ctx, &s3.UploadPartInput{
+		Bucket:     f.attrs.Bucket,
+		Key:        f.attrs.Key,
+		UploadId:   f.uploadID,
+		PartNumber: aws.Int32(partNumber),
+		Body:       bytes.NewReader(f.buf.Bytes()),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to upload part %v: %w", partNumber, err)
+	}
+	f.parts = append(f.parts, types.CompletedPart{
+		ETag:       res.ETag,
+		PartNumber: aws.Int32(partNumber),
+	})
+	f.buf.Reset()
+	return nil
+}
+
+// rollLocked completes the upload of a file, making it visible as an object,
+// and releases the messages waiting on it.
+func (r *s3RollingWriter) rollLocked(partition string, f *s3RollingFile) {
+	delete(r.files, partition)
+
+	if f.uploadID == nil {
+		ctx, cancel := context.WithTimeout(context.Background(), r.conf.Timeout)
+		defer cancel()
+
+		input := *f.attrs
+		input.Body = bytes.NewReader(f.buf.Bytes())
+		if _, err := r.client.PutObject(ctx, &input); err != nil {
+			f.err = fmt.Errorf("failed to upload file: %w", err)
+		}
+		close(f.done)
+		return
+	}
+
+	if f.buf.Len() > 0 {
+		if err := r.uploadPartLocked(f); err != nil {
+			r.failLocked(partition, f, err)
+			return
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), r.conf.Timeout)
+	defer cancel()
+
+	sort.Slice(f.parts, func(i, j int) bool {
+		return *f.parts[i].PartNumber < *f.parts[j].PartNumber
+	})
+	if _, err := r.client.CompleteMultipartUpload(ctx, &s3.CompleteMultipartUploadInput{
+		Bucket:          f.attrs.Bucket,
+		Key:             f.attrs.Key,
+		UploadId:        f.uploadID,
+		MultipartUpload: &types.CompletedMultipartUpload{Parts: f.parts},
+	}); err != nil {
+		r.failLocked(partition, f, fmt.Errorf("failed to complete multipart upload: %w", err))
+		return
+	}
+	close(f.done)
+}
+
+// failLocked abandons a file, aborting any multipart upload so that no object
+// becomes visible, and fails the messages waiting on it.
+func (r *s3RollingWriter) failLocked(partition string, f *s3RollingFile, err error) {
+	if r.files[partition] == f {
+		delete(r.files, partition)
+	}
+	if f.uploadID != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), r.conf.Timeout)
+		defer cancel()
+
+		if _, aErr := r.client.AbortMultipartUpload(ctx, &s3.AbortMultipartUploadInput{
+			Bucket:   f.attrs.Bucket,
+			Key:      f.attrs.Key,
+			UploadId: f.uploadID,
+		}); aErr != nil {
+			r.log.Errorf("Failed to abort multipart upload of %v: %v", *f.attrs.Key, aErr)
+		}
+	}
+	f.err = err
+	close(f.done)
+}
+
+func (r *s3RollingWriter) Close(ctx context.Context) error {
+	r.mut.Lock()
+	connected := r.client != nil
+	r.mut.Unlock()
+	if !connected {
+		return nil
+	}
+
+	// Files are rolled when closing so that the messages waiting on them are
+	// delivered.
+	r.shutSig.TriggerSoftStop()
+	select {
+	case <-r.shutSig.HasStoppedChan():
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return nil
+}
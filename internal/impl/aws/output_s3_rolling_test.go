@@ -0,0 +1,264 @@
+package aws
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+type mockS3Rolling struct {
+	mut     sync.Mutex
+	objects map[string]string
+	parts   map[string][]string
+	aborted []string
+	partErr error
+}
+
+func newMockS3Rolling() *mockS3Rolling {
+	return &mockS3Rolling{
+		objects: map[string]string{},
+		parts:   map[string][]string{},
+	}
+}
+
+func (m *mockS3Rolling) PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+
+	b, err := io.ReadAll(params.Body)
+	if err != nil {
+		return nil, err
+	}
+	m.objects[*params.Key] = string(b)
+	return &s3.PutObjectOutput{}, nil
+}
+
+func (m *mockS3Rolling) CreateMultipartUpload(ctx context.Context, params *s3.CreateMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CreateMultipartUploadOutput, error) {
+	return &s3.CreateMultipartUploadOutput{UploadId: aws.String("upload-" + *params.Key)}, nil
+}
+
+func (m *mockS3Rolling) UploadPart(ctx context.Context, params *s3.UploadPartInput, optFns ...func(*s3.Options)) (*s3.UploadPartOutput, error) {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+
+	if m.partErr != nil {
+		return nil, m.partErr
+	}
+	b, err := io.ReadAll(params.Body)
+	if err != nil {
+		return nil, err
+	}
+	m.parts[*params.UploadId] = append(m.parts[*params.UploadId], string(b))
+	return &s3.UploadPartOutput{ETag: aws.String("etag")}, nil
+}
+
+func (m *mockS3Rolling) CompleteMultipartUpload(ctx context.Context, params *s3.CompleteMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CompleteMultipartUploadOutput, error) {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+
+	parts := m.parts[*params.UploadId]
+	if len(parts) != len(params.MultipartUpload.Parts) {
+		return nil, errors.New("mismatched parts")
+	}
+	m.objects[*params.Key] = strings.Join(parts, "")
+	return &s3.CompleteMultipartUploadOutput{}, nil
+}
+
+func (m *mockS3Rolling) AbortMultipartUpload(ctx context.Context, params *s3.AbortMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.AbortMultipartUploadOutput, error) {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+
+	m.aborted = append(m.aborted, *params.Key)
+	return &s3.AbortMultipartUploadOutput{}, nil
+}
+
+func (m *mockS3Rolling) getObjects() map[string]string {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+
+	objs := map[string]string{}
+	for k, v := range m.objects {
+		objs[k] = v
+	}
+	return objs
+}
+
+func testS3RollingWriter(t *testing.T, yamlConf string) (*s3RollingWriter, *mockS3Rolling) {
+	t.Helper()
+
+	pConf, err := s3oOutputSpec().ParseYAML(yamlConf, nil)
+	require.NoError(t, err)
+
+	conf, err := s3oConfigFromParsed(pConf)
+	require.NoError(t, err)
+	require.True(t, conf.Rolling.Enabled)
+
+	w, err := newS3RollingWriter(conf, service.MockResources())
+	require.NoError(t, err)
+
+	m := newMockS3Rolling()
+	w.mut.Lock()
+	w.startLocked(m)
+	w.mut.Unlock()
+
+	t.Cleanup(func() {
+		_ = w.Close(context.Background())
+	})
+	return w, m
+}
+
+func TestS3RollingBySize(t *testing.T) {
+	w, m := testS3RollingWriter(t, `
+bucket: foo
+region: us-east-1
+rolling:
+  enabled: true
+  partition: dt=${! meta("dt") }
+  file_suffix: .jsonl
+  max_size: 10B
+`)
+
+	msg := func(dt, content string) *service.Message {
+		m := service.NewMessage([]byte(content))
+		m.MetaSetMut("dt", dt)
+		return m
+	}
+
+	require.NoError(t, w.WriteBatch(context.Background(), service.MessageBatch{
+		msg("2024-01-01", `{"a":1}`),
+		msg("2024-01-02", `{"b":1}`),
+		msg("2024-01-01", `{"a":2}`),
+		msg("2024-01-02", `{"b":2}`),
+	}))
+
+	objs := m.getObjects()
+	require.Len(t, objs, 2)
+
+	contents := map[string]string{}
+	for k, v := range objs {
+		assert.True(t, strings.HasSuffix(k, ".jsonl"), k)
+		contents[k[:strings.Index(k, "/")]] = v
+	}
+	assert.Equal(t, map[string]string{
+		"dt=2024-01-01": "{\"a\":1}\n{\"a\":2}\n",
+		"dt=2024-01-02": "{\"b\":1}\n{\"b\":2}\n",
+	}, contents)
+}
+
+func TestS3RollingByAge(t *testing.T) {
+	w, m := testS3RollingWriter(t, `
+bucket: foo
+region: us-east-1
+rolling:
+  enabled: true
+  separator: ","
+  max_age: 50ms
+`)
+
+	var wg sync.WaitGroup
+	for _, content := range []string{"a", "b", "c"} {
+		content := content
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, w.WriteBatch(context.Background(), service.MessageBatch{
+				service.NewMessage([]byte(content)),
+			}))
+		}()
+	}
+	wg.Wait()
+
+	var total string
+	for _, v := range m.getObjects() {
+		total += v
+	}
+	assert.Len(t, total, 6)
+	for _, content := range []string{"a,", "b,", "c,"} {
+		assert.Contains(t, total, content)
+	}
+}
+
+func TestS3RollingMultipart(t *testing.T) {
+	w, m := testS3RollingWriter(t, `
+bucket: foo
+region: us-east-1
+rolling:
+  enabled: true
+  separator: ""
+  max_size: 1GB
+  max_age: 1h
+  part_size: 5MiB
+`)
+
+	chunk := bytes.Repeat([]byte("x"), 3*1024*1024)
+
+	errs := make(chan error, 3)
+	for i := 0; i < 3; i++ {
+		go func() {
+			errs <- w.WriteBatch(context.Background(), service.MessageBatch{
+				service.NewMessage(chunk),
+			})
+		}()
+	}
+
+	require.Eventually(t, func() bool {
+		w.mut.Lock()
+		defer w.mut.Unlock()
+		f, exists := w.files[""]
+		return exists && f.size == int64(3*len(chunk))
+	}, time.Second, time.Millisecond)
+
+	// Parts have been uploaded but nothing is visible until the file is
+	// rolled, which happens on close.
+	m.mut.Lock()
+	assert.Len(t, m.parts, 1)
+	m.mut.Unlock()
+	assert.Empty(t, m.getObjects())
+	require.NoError(t, w.Close(context.Background()))
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, <-errs)
+	}
+
+	objs := m.getObjects()
+	require.Len(t, objs, 1)
+	for _, v := range objs {
+		assert.Len(t, v, 3*len(chunk))
+	}
+	for _, parts := range m.parts {
+		require.Len(t, parts, 2)
+		assert.Len(t, parts[0], 2*len(chunk))
+		assert.Len(t, parts[1], len(chunk))
+	}
+}
+
+func TestS3RollingPartFailure(t *testing.T) {
+	w, m := testS3RollingWriter(t, `
+bucket: foo
+region: us-east-1
+rolling:
+  enabled: true
+  part_size: 5MiB
+`)
+	m.partErr = errors.New("nope")
+
+	err := w.WriteBatch(context.Background(), service.MessageBatch{
+		service.NewMessage(bytes.Repeat([]byte("x"), 6*1024*1024)),
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "nope")
+	assert.Len(t, m.aborted, 1)
+	assert.Empty(t, m.getObjects())
+}
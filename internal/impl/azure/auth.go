@@ -11,6 +11,8 @@ import (
 	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
 	"github.com/Azure/azure-sdk-for-go/sdk/data/aztables"
 	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azdatalake"
+	dlservice "github.com/Azure/azure-sdk-for-go/sdk/storage/azdatalake/service"
 	"github.com/Azure/azure-sdk-for-go/sdk/storage/azqueue"
 )
 
@@ -272,3 +274,60 @@ func getTablesServiceClient(account, accessKey, connectionString, storageSASToke
 	}
 	return client, err
 }
+
+//------------------------------------------------------------------------------
+
+func dataLakeServiceClientFromParsed(pConf *service.ParsedConfig) (*dlservice.Client, error) {
+	connectionString, err := pConf.FieldString(bscFieldStorageConnectionString)
+	if err != nil {
+		return nil, err
+	}
+	storageAccount, err := pConf.FieldString(bscFieldStorageAccount)
+	if err != nil {
+		return nil, err
+	}
+	storageAccessKey, err := pConf.FieldString(bscFieldStorageAccessKey)
+	if err != nil {
+		return nil, err
+	}
+	storageSASToken, err := pConf.FieldString(bscFieldStorageSASToken)
+	if err != nil {
+		return nil, err
+	}
+	if storageAccount == "" && connectionString == "" {
+		return nil, errors.New("invalid azure storage account credentials")
+	}
+	return getDataLakeServiceClient(storageAccount, storageAccessKey, connectionString, storageSASToken)
+}
+
+const (
+	dfsEndpointExp = "https://%s.dfs.core.windows.net"
+)
+
+func getDataLakeServiceClient(account, accessKey, connectionString, storageSASToken string) (*dlservice.Client, error) {
+	var client *dlservice.Client
+	var err error
+	if connectionString != "" {
+		connStr := parseStorageConnectionString(connectionString, account)
+		client, err = dlservice.NewClientFromConnectionString(connStr, nil)
+	} else if accessKey != "" {
+		cred, credErr := azdatalake.NewSharedKeyCredential(account, accessKey)
+		if credErr != nil {
+			return nil, fmt.Errorf("error creating shared key credential: %w", credErr)
+		}
+		client, err = dlservice.NewClientWithSharedKeyCredential(fmt.Sprintf(dfsEndpointExp, account), cred, nil)
+	} else if storageSASToken != "" {
+		serviceURL := fmt.Sprintf("%s/?%s", fmt.Sprintf(dfsEndpointExp, account), strings.TrimPrefix(storageSASToken, "?"))
+		client, err = dlservice.NewClientWithNoCredential(serviceURL, nil)
+	} else {
+		cred, credErr := azidentity.NewDefaultAzureCredential(nil)
+		if credErr != nil {
+			return nil, fmt.Errorf("error getting default Azure credentials: %v", credErr)
+		}
+		client, err = dlservice.NewClient(fmt.Sprintf(dfsEndpointExp, account), cred, nil)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid azure storage account credentials: %w", err)
+	}
+	return client, nil
+}
@@ -0,0 +1,387 @@
+package azure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/messaging/azservicebus"
+
+	"github.com/benthosdev/benthos/v4/internal/component"
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	// Service Bus Input Fields
+	sbiFieldSubscription       = "subscription"
+	sbiFieldDeadLetter         = "dead_letter"
+	sbiFieldSessions           = "sessions"
+	sbiFieldSessionsEnabled    = "enabled"
+	sbiFieldSessionsSessionID  = "session_id"
+	sbiFieldSessionsIdleTimout = "idle_timeout"
+	sbiFieldMaxMessages        = "max_messages"
+)
+
+type sbiConfig struct {
+	client             *azservicebus.Client
+	Queue              string
+	Topic              string
+	Subscription       string
+	DeadLetter         bool
+	SessionsEnabled    bool
+	SessionID          string
+	SessionIdleTimeout time.Duration
+	MaxMessages        int
+}
+
+func sbiConfigFromParsed(pConf *service.ParsedConfig) (conf sbiConfig, err error) {
+	if conf.client, err = serviceBusClientFromParsed(pConf); err != nil {
+		return
+	}
+	if conf.Queue, err = pConf.FieldString(sbFieldQueue); err != nil {
+		return
+	}
+	if conf.Topic, err = pConf.FieldString(sbFieldTopic); err != nil {
+		return
+	}
+	if conf.Subscription, err = pConf.FieldString(sbiFieldSubscription); err != nil {
+		return
+	}
+	if conf.DeadLetter, err = pConf.FieldBool(sbiFieldDeadLetter); err != nil {
+		return
+	}
+	sConf := pConf.Namespace(sbiFieldSessions)
+	if conf.SessionsEnabled, err = sConf.FieldBool(sbiFieldSessionsEnabled); err != nil {
+		return
+	}
+	if conf.SessionID, err = sConf.FieldString(sbiFieldSessionsSessionID); err != nil {
+		return
+	}
+	if conf.SessionIdleTimeout, err = sConf.FieldDuration(sbiFieldSessionsIdleTimout); err != nil {
+		return
+	}
+	if conf.MaxMessages, err = pConf.FieldInt(sbiFieldMaxMessages); err != nil {
+		return
+	}
+
+	if (conf.Queue == "") == (conf.Topic == "") {
+		err = errors.New("exactly one of queue or topic must be set")
+		return
+	}
+	if conf.Topic != "" && conf.Subscription == "" {
+		err = errors.New("a subscription must be set when consuming from a topic")
+		return
+	}
+	if conf.SessionsEnabled && conf.DeadLetter {
+		err = errors.New("sessions cannot be enabled when consuming from the dead-letter sub-queue")
+		return
+	}
+	if conf.MaxMessages < 1 {
+		err = errors.New("max_messages must be greater than zero")
+		return
+	}
+	return
+}
+
+func sbiSpec() *service.ConfigSpec {
+	return serviceBusComponentSpec().
+		Beta().
+		Version("4.28.0").
+		Summary(`Consumes messages from an Azure Service Bus queue or topic subscription.`).
+		Description(`
+Messages are received in peek-lock mode. A message is completed once it has been processed successfully, and is abandoned otherwise, which makes it available for redelivery until its maximum delivery count is reached and Service Bus moves it to the dead-letter sub-queue. Messages of that sub-queue can be consumed by setting `+"`dead_letter`"+` to `+"`true`"+`.
+
+### Sessions
+
+When `+"`sessions.enabled`"+` is `+"`true`"+` messages are consumed from one session at a time, which is either the session specified with `+"`sessions.session_id`"+` or the next available session. Messages of a session are delivered strictly in order, and a batch is only received once the previous batch has been acknowledged. When no messages arrive within `+"`sessions.idle_timeout`"+` the session is released and the next available session is accepted. Multiple sessions can be consumed in parallel by running several instances of this input, for example within a `+"[`broker`](/docs/components/inputs/broker)"+`.
+
+### Metadata
+
+This input adds the following metadata fields to each message:
+
+`+"```text"+`
+- service_bus_message_id
+- service_bus_sequence_number
+- service_bus_enqueued_time
+- service_bus_delivery_count
+- service_bus_session_id
+- service_bus_subject
+- service_bus_correlation_id
+- service_bus_content_type
+- service_bus_partition_key
+- service_bus_reply_to
+- service_bus_to
+- service_bus_dead_letter_reason
+- service_bus_dead_letter_description
+- service_bus_dead_letter_source
+- All application properties
+`+"```"+`
+
+Fields that are not set on a message are omitted. You can access these metadata fields using [function interpolation](/docs/configuration/interpolation#bloblang-queries).
+`+sbAuthDocs).
+		Fields(
+			service.NewStringField(sbFieldQueue).
+				Description("The queue to consume from. Either this field or `"+sbFieldTopic+"` must be set.").
+				Default(""),
+			service.NewStringField(sbFieldTopic).
+				Description("The topic to consume from, which requires a `"+sbiFieldSubscription+"` to be set.").
+				Default(""),
+			service.NewStringField(sbiFieldSubscription).
+				Description("The subscription of the topic to consume from.").
+				Default(""),
+			service.NewBoolField(sbiFieldDeadLetter).
+				Description("Whether to consume from the dead-letter sub-queue of the queue or subscription.").
+				Default(false),
+			service.NewObjectField(sbiFieldSessions,
+				service.NewBoolField(sbiFieldSessionsEnabled).
+					Description("Whether to consume from a session-enabled queue or subscription.").
+					Default(false),
+				service.NewStringField(sbiFieldSessionsSessionID).
+					Description("An optional session to consume from. When empty the next available session is accepted.").
+					Default(""),
+				service.NewDurationField(sbiFieldSessionsIdleTimout).
+					Description("The period of time after which a session without new messages is released in order to accept the next available session. This field is ignored when a `session_id` is set.").
+					Default("10s"),
+			).
+				Description("Consume from session-enabled entities in order to process related messages in order."),
+			service.NewIntField(sbiFieldMaxMessages).
+				Description("The maximum number of messages to receive as a single batch.").
+				Default(10).
+				Advanced(),
+		).
+		LintRule(`root = if (this.queue.or("") == "") == (this.topic.or("") == "") { [ "exactly one of queue or topic must be set" ] }`).
+		Example("Ordered Sessions", "Consume messages from a session-enabled queue using a managed identity, processing the messages of each session in order.", `
+input:
+  azure_service_bus:
+    namespace: example.servicebus.windows.net
+    queue: orders
+    sessions:
+      enabled: true
+`)
+}
+
+func init() {
+	err := service.RegisterBatchInput("azure_service_bus", sbiSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.BatchInput, error) {
+			pConf, err := sbiConfigFromParsed(conf)
+			if err != nil {
+				return nil, err
+			}
+			return newServiceBusReader(pConf, mgr)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+// serviceBusReceiver is implemented by both *azservicebus.Receiver and
+// *azservicebus.SessionReceiver.
+type serviceBusReceiver interface {
+	ReceiveMessages(ctx context.Context, maxMessages int, options *azservicebus.ReceiveMessagesOptions) ([]*azservicebus.ReceivedMessage, error)
+	CompleteMessage(ctx context.Context, message *azservicebus.ReceivedMessage, options *azservicebus.CompleteMessageOptions) error
+	AbandonMessage(ctx context.Context, message *azservicebus.ReceivedMessage, options *azservicebus.AbandonMessageOptions) error
+	Close(ctx context.Context) error
+}
+
+type serviceBusReader struct {
+	conf sbiConfig
+	log  *service.Logger
+
+	// inOrder is held from the moment a batch is received within a session
+	// until that batch is acknowledged.
+	inOrder chan struct{}
+
+	mut      sync.Mutex
+	receiver serviceBusReceiver
+}
+
+func newServiceBusReader(conf sbiConfig, mgr *service.Resources) (*serviceBusReader, error) {
+	return &serviceBusReader{
+		conf:    conf,
+		log:     mgr.Logger(),
+		inOrder: make(chan struct{}, 1),
+	}, nil
+}
+
+func (s *serviceBusReader) receiverOptions() *azservicebus.ReceiverOptions {
+	opts := &azservicebus.ReceiverOptions{
+		ReceiveMode: azservicebus.ReceiveModePeekLock,
+	}
+	if s.conf.DeadLetter {
+		opts.SubQueue = azservicebus.SubQueueDeadLetter
+	}
+	return opts
+}
+
+func (s *serviceBusReader) Connect(ctx context.Context) error {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	if s.receiver != nil || s.conf.SessionsEnabled {
+		// Sessions are accepted lazily when reading.
+		return nil
+	}
+
+	var err error
+	if s.conf.Queue != "" {
+		s.receiver, err = s.conf.client.NewReceiverForQueue(s.conf.Queue, s.receiverOptions())
+	} else {
+		s.receiver, err = s.conf.client.NewReceiverForSubscription(s.conf.Topic, s.conf.Subscription, s.receiverOptions())
+	}
+	return err
+}
+
+// acceptSession blocks until a session is available or the context is
+// cancelled.
+func (s *serviceBusReader) acceptSession(ctx context.Context) (*azservicebus.SessionReceiver, error) {
+	opts := &azservicebus.SessionReceiverOptions{
+		ReceiveMode: azservicebus.ReceiveModePeekLock,
+	}
+	for {
+		var recv *azservicebus.SessionReceiver
+		var err error
+		switch {
+		case s.conf.SessionID != "" && s.conf.Queue != "":
+			recv, err = s.conf.client.AcceptSessionForQueue(ctx, s.conf.Queue, s.conf.SessionID, opts)
+		case s.conf.SessionID != "":
+			recv, err = s.conf.client.AcceptSessionForSubscription(ctx, s.conf.Topic, s.conf.Subscription, s.conf.SessionID, opts)
+		case s.conf.Queue != "":
+			recv, err = s.conf.client.AcceptNextSessionForQueue(ctx, s.conf.Queue, opts)
+		default:
+			recv, err = s.conf.client.AcceptNextSessionForSubscription(ctx, s.conf.Topic, s.conf.Subscription, opts)
+		}
+		if err == nil {
+			s.log.Debugf("Accepted service bus session %v", recv.SessionID())
+			return recv, nil
+		}
+
+		// No sessions became available before the service timed out.
+		var sbErr *azservicebus.Error
+		if errors.As(err, &sbErr) && sbErr.Code == azservicebus.CodeTimeout && ctx.Err() == nil {
+			continue
+		}
+		return nil, err
+	}
+}
+
+func (s *serviceBusReader) ReadBatch(ctx context.Context) (service.MessageBatch, service.AckFunc, error) {
+	if s.conf.SessionsEnabled {
+		select {
+		case s.inOrder <- struct{}{}:
+		case <-ctx.Done():
+			return nil, nil, ctx.Err()
+		}
+	}
+
+	batch, ackFn, err := s.readBatch(ctx)
+	if err != nil && s.conf.SessionsEnabled {
+		<-s.inOrder
+	}
+	return batch, ackFn, err
+}
+
+func (s *serviceBusReader) readBatch(ctx context.Context) (service.MessageBatch, service.AckFunc, error) {
+	s.mut.Lock()
+	recv := s.receiver
+	s.mut.Unlock()
+
+	if recv == nil {
+		if !s.conf.SessionsEnabled {
+			return nil, nil, service.ErrNotConnected
+		}
+		sessionRecv, err := s.acceptSession(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+		recv = sessionRecv
+
+		s.mut.Lock()
+		s.receiver = recv
+		s.mut.Unlock()
+	}
+
+	receiveCtx := ctx
+	idleSession := s.conf.SessionsEnabled && s.conf.SessionID == ""
+	if idleSession {
+		var done func()
+		receiveCtx, done = context.WithTimeout(ctx, s.conf.SessionIdleTimeout)
+		defer done()
+	}
+
+	msgs, err := recv.ReceiveMessages(receiveCtx, s.conf.MaxMessages, nil)
+	if err != nil {
+		if idleSession && ctx.Err() == nil && errors.Is(err, context.DeadlineExceeded) {
+			// The session is idle, release it so that the next available
+			// session can be accepted.
+			s.releaseReceiver(ctx, recv)
+			return nil, nil, component.ErrTimeout
+		}
+		var sbErr *azservicebus.Error
+		if errors.As(err, &sbErr) && (sbErr.Code == azservicebus.CodeLockLost || sbErr.Code == azservicebus.CodeConnectionLost) {
+			s.releaseReceiver(ctx, recv)
+			if s.conf.SessionsEnabled {
+				return nil, nil, err
+			}
+			return nil, nil, service.ErrNotConnected
+		}
+		return nil, nil, err
+	}
+	if len(msgs) == 0 {
+		return nil, nil, component.ErrTimeout
+	}
+
+	batch := make(service.MessageBatch, len(msgs))
+	for i, m := range msgs {
+		batch[i] = receivedMessageToPart(m)
+	}
+
+	return batch, func(ctx context.Context, res error) error {
+		if s.conf.SessionsEnabled {
+			defer func() {
+				<-s.inOrder
+			}()
+		}
+		for _, m := range msgs {
+			var err error
+			if res == nil {
+				err = recv.CompleteMessage(ctx, m, nil)
+			} else {
+				err = recv.AbandonMessage(ctx, m, nil)
+			}
+			if err != nil {
+				return fmt.Errorf("failed to settle service bus message: %w", err)
+			}
+		}
+		return nil
+	}, nil
+}
+
+func (s *serviceBusReader) releaseReceiver(ctx context.Context, recv serviceBusReceiver) {
+	s.mut.Lock()
+	if s.receiver == recv {
+		s.receiver = nil
+	}
+	s.mut.Unlock()
+
+	if err := recv.Close(ctx); err != nil {
+		s.log.Debugf("Failed to close service bus receiver: %v", err)
+	}
+}
+
+func (s *serviceBusReader) Close(ctx context.Context) error {
+	s.mut.Lock()
+	recv := s.receiver
+	s.receiver = nil
+	s.mut.Unlock()
+
+	var err error
+	if recv != nil {
+		err = recv.Close(ctx)
+	}
+	if cErr := s.conf.client.Close(ctx); err == nil {
+		err = cErr
+	}
+	return err
+}
@@ -0,0 +1,569 @@
+package azure
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/streaming"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azdatalake/datalakeerror"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azdatalake/file"
+	dlservice "github.com/Azure/azure-sdk-for-go/sdk/storage/azdatalake/service"
+	"github.com/Jeffail/shutdown"
+	"github.com/dustin/go-humanize"
+	"github.com/gofrs/uuid"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	// Data Lake Output Fields
+	dloFieldFilesystem  = "filesystem"
+	dloFieldPath        = "path"
+	dloFieldPermissions = "permissions"
+	dloFieldUmask       = "umask"
+	dloFieldACL         = "acl"
+	dloFieldRolling     = "rolling"
+	dloFieldBatching    = "batching"
+
+	// Data Lake Output Rolling Fields
+	dlorFieldEnabled    = "enabled"
+	dlorFieldPartition  = "partition"
+	dlorFieldFileSuffix = "file_suffix"
+	dlorFieldSeparator  = "separator"
+	dlorFieldMaxSize    = "max_size"
+	dlorFieldMaxAge     = "max_age"
+)
+
+type dloRollingConfig struct {
+	Enabled    bool
+	Partition  *service.InterpolatedString
+	FileSuffix string
+	Separator  []byte
+	MaxSize    int64
+	MaxAge     time.Duration
+}
+
+type dloConfig struct {
+	Filesystem  *service.InterpolatedString
+	Path        *service.InterpolatedString
+	Permissions string
+	Umask       string
+	ACL         string
+	Rolling     dloRollingConfig
+}
+
+func dloConfigFromParsed(pConf *service.ParsedConfig) (conf dloConfig, err error) {
+	if conf.Filesystem, err = pConf.FieldInterpolatedString(dloFieldFilesystem); err != nil {
+		return
+	}
+	if conf.Path, err = pConf.FieldInterpolatedString(dloFieldPath); err != nil {
+		return
+	}
+	if conf.Permissions, err = pConf.FieldString(dloFieldPermissions); err != nil {
+		return
+	}
+	if conf.Umask, err = pConf.FieldString(dloFieldUmask); err != nil {
+		return
+	}
+	if conf.ACL, err = pConf.FieldString(dloFieldACL); err != nil {
+		return
+	}
+
+	rConf := pConf.Namespace(dloFieldRolling)
+	if conf.Rolling.Enabled, err = rConf.FieldBool(dlorFieldEnabled); err != nil {
+		return
+	}
+	if conf.Rolling.Partition, err = rConf.FieldInterpolatedString(dlorFieldPartition); err != nil {
+		return
+	}
+	if conf.Rolling.FileSuffix, err = rConf.FieldString(dlorFieldFileSuffix); err != nil {
+		return
+	}
+	var sep string
+	if sep, err = rConf.FieldString(dlorFieldSeparator); err != nil {
+		return
+	}
+	conf.Rolling.Separator = []byte(sep)
+
+	var sizeStr string
+	if sizeStr, err = rConf.FieldString(dlorFieldMaxSize); err != nil {
+		return
+	}
+	var size uint64
+	if size, err = humanize.ParseBytes(sizeStr); err != nil {
+		err = fmt.Errorf("failed to parse max_size: %w", err)
+		return
+	}
+	conf.Rolling.MaxSize = int64(size)
+	if conf.Rolling.MaxAge, err = rConf.FieldDuration(dlorFieldMaxAge); err != nil {
+		return
+	}
+	if conf.Rolling.Enabled && (conf.Rolling.MaxSize <= 0 || conf.Rolling.MaxAge <= 0) {
+		err = errors.New("rolling max_size and max_age must both be greater than zero")
+		return
+	}
+	return
+}
+
+func dloSpec() *service.ConfigSpec {
+	return azureComponentSpec(true).
+		Beta().
+		Version("4.28.0").
+		Summary(`Writes messages as files to an Azure Data Lake Storage Gen2 filesystem.`).
+		Description(`
+Files are written with the Data Lake Storage (DFS) API, which supports hierarchical namespaces. Directories within the path of a file are created automatically, and files can be created with POSIX permissions and access control lists.
+
+Supports multiple authentication methods but only one of the following is required:
+- `+"`storage_connection_string`"+`
+- `+"`storage_account` and `storage_access_key`"+`
+- `+"`storage_account` and `storage_sas_token`"+`
+- `+"`storage_account` to access via [DefaultAzureCredential](https://pkg.go.dev/github.com/Azure/azure-sdk-for-go/sdk/azidentity#DefaultAzureCredential)"+`
+
+If multiple are set then the `+"`storage_connection_string`"+` is given priority.
+
+### Rolling Files
+
+By default each message is written as a file at the `+"`path`"+` of the message. Setting `+"`rolling.enabled`"+` to `+"`true`"+` instead accumulates messages into large files. Each message is appended to the open file of its `+"`rolling.partition`"+`, which is usually a Hive-style directory such as `+"`dt=2024-05-01/hour=13`"+`, followed by `+"`rolling.separator`"+`. A file is rolled once it reaches `+"`rolling.max_size`"+` bytes or once `+"`rolling.max_age`"+` has passed since it was opened.
+
+Data is appended to a file as messages arrive, and only committed with a flush once the file is rolled. Open files are written with a name beginning with a dot, which is ignored by most query engines, and are renamed to their final name once committed. The final name of each file is unique and ends with `+"`rolling.file_suffix`"+`, and the `+"`path`"+` field is ignored.
+
+Messages are only acknowledged once the file they were appended to has been rolled, and so the messages of a file that fails to be written are all retried. In order for files to accumulate many batches the `+"`max_in_flight`"+` field should be set high enough to cover the number of batches expected within `+"`rolling.max_age`"+`.`+service.OutputPerformanceDocs(true, true)).
+		Fields(
+			service.NewInterpolatedStringField(dloFieldFilesystem).
+				Description("The filesystem to write files to, which is created if it does not exist.").
+				Example(`landing`).
+				Example(`logs-${!timestamp_unix().ts_format("2006")}`),
+			service.NewInterpolatedStringField(dloFieldPath).
+				Description("The path of each file to write, including any directories.").
+				Example(`${!meta("kafka_topic")}/${!count("files")}-${!timestamp_unix_nano()}.json`).
+				Example(`raw/dt=${!timestamp_unix().ts_format("2006-01-02")}/${!uuid_v4()}.json`).
+				Default(`${!count("files")}-${!timestamp_unix_nano()}.txt`),
+			service.NewStringField(dloFieldPermissions).
+				Description("Optional POSIX permissions to set on created files, in symbolic or octal notation.").
+				Example("0640").
+				Example("rw-r-----").
+				Default("").
+				Advanced(),
+			service.NewStringField(dloFieldUmask).
+				Description("An optional umask to restrict the permissions of created files.").
+				Example("0027").
+				Default("").
+				Advanced(),
+			service.NewStringField(dloFieldACL).
+				Description("An optional access control list to set on created files, as a comma separated list of access control entries.").
+				Example("user::rw-,group::r--,other::---,user:00000000-0000-0000-0000-000000000000:r--").
+				Default("").
+				Advanced(),
+			service.NewObjectField(dloFieldRolling,
+				service.NewBoolField(dlorFieldEnabled).
+					Description("Whether to accumulate messages into rolling files rather than writing a file per message.").
+					Default(false),
+				service.NewInterpolatedStringField(dlorFieldPartition).
+					Description("The partition of each message, which is used as the directory of the file it is appended to.").
+					Example(`events/dt=${! timestamp_unix().ts_format("2006-01-02", "UTC") }/hour=${! timestamp_unix().ts_format("15", "UTC") }`).
+					Default(""),
+				service.NewStringField(dlorFieldFileSuffix).
+					Description("A suffix to add to the name of each file.").
+					Example(".jsonl").
+					Default(""),
+				service.NewStringField(dlorFieldSeparator).
+					Description("A separator added after each message of a file.").
+					Default("\n"),
+				service.NewStringField(dlorFieldMaxSize).
+					Description("The size in bytes at which a file is rolled.").
+					Example("256MiB").
+					Default("128MiB"),
+				service.NewDurationField(dlorFieldMaxAge).
+					Description("The maximum period of time after a file is opened before it is rolled.").
+					Default("5m"),
+			).
+				Description("Accumulate messages into partitioned files that are rolled once they reach a size or age. Check out the [rolling files](#rolling-files) section for more details."),
+			service.NewOutputMaxInFlightField(),
+			service.NewBatchPolicyField(dloFieldBatching),
+		).
+		Example("Landing Zone", "Land events as hourly partitioned JSON lines files.", `
+output:
+  azure_adls_gen2:
+    storage_account: mylake
+    filesystem: landing
+    max_in_flight: 128
+    rolling:
+      enabled: true
+      partition: events/dt=${! timestamp_unix().ts_format("2006-01-02", "UTC") }/hour=${! timestamp_unix().ts_format("15", "UTC") }
+      file_suffix: .jsonl
+      max_size: 256MiB
+      max_age: 10m
+    batching:
+      count: 1000
+      period: 1s
+`)
+}
+
+func init() {
+	err := service.RegisterBatchOutput("azure_adls_gen2", dloSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (out service.BatchOutput, batchPolicy service.BatchPolicy, maxInFlight int, err error) {
+			if maxInFlight, err = conf.FieldMaxInFlight(); err != nil {
+				return
+			}
+			if batchPolicy, err = conf.FieldBatchPolicy(dloFieldBatching); err != nil {
+				return
+			}
+			var dConf dloConfig
+			if dConf, err = dloConfigFromParsed(conf); err != nil {
+				return
+			}
+			var client *dlservice.Client
+			if client, err = dataLakeServiceClientFromParsed(conf); err != nil {
+				return
+			}
+			out = newDataLakeWriter(dConf, &dataLakeClient{client: client, conf: dConf}, mgr)
+			return
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// dataLakeFiles is the subset of file operations used for writing files.
+type dataLakeFiles interface {
+	create(ctx context.Context, filesystem, filePath string) error
+	appendData(ctx context.Context, filesystem, filePath string, offset int64, data []byte) error
+	flush(ctx context.Context, filesystem, filePath string, offset int64) error
+	rename(ctx context.Context, filesystem, fromPath, toPath string) error
+	delete(ctx context.Context, filesystem, filePath string) error
+}
+
+type dataLakeClient struct {
+	client *dlservice.Client
+	conf   dloConfig
+}
+
+func optionalString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+func (d *dataLakeClient) fileClient(filesystem, filePath string) *file.Client {
+	return d.client.NewFileSystemClient(filesystem).NewFileClient(filePath)
+}
+
+func (d *dataLakeClient) create(ctx context.Context, filesystem, filePath string) error {
+	opts := &file.CreateOptions{
+		Permissions: optionalString(d.conf.Permissions),
+		Umask:       optionalString(d.conf.Umask),
+		ACL:         optionalString(d.conf.ACL),
+	}
+	_, err := d.fileClient(filesystem, filePath).Create(ctx, opts)
+	if err != nil && datalakeerror.HasCode(err, datalakeerror.FileSystemNotFound) {
+		if _, err = d.client.NewFileSystemClient(filesystem).Create(ctx, nil); err != nil && !datalakeerror.HasCode(err, datalakeerror.FileSystemAlreadyExists) {
+			return fmt.Errorf("failed to create filesystem: %w", err)
+		}
+		_, err = d.fileClient(filesystem, filePath).Create(ctx, opts)
+	}
+	return err
+}
+
+func (d *dataLakeClient) appendData(ctx context.Context, filesystem, filePath string, offset int64, data []byte) error {
+	_, err := d.fileClient(filesystem, filePath).AppendData(ctx, offset, streaming.NopCloser(bytes.NewReader(data)), nil)
+	return err
+}
+
+func (d *dataLakeClient) flush(ctx context.Context, filesystem, filePath string, offset int64) error {
+	_, err := d.fileClient(filesystem, filePath).FlushData(ctx, offset, &file.FlushDataOptions{
+		Close: to.Ptr(true),
+	})
+	return err
+}
+
+func (d *dataLakeClient) rename(ctx context.Context, filesystem, fromPath, toPath string) error {
+	_, err := d.fileClient(filesystem, fromPath).Rename(ctx, toPath, nil)
+	return err
+}
+
+func (d *dataLakeClient) delete(ctx context.Context, filesystem, filePath string) error {
+	_, err := d.fileClient(filesystem, filePath).Delete(ctx, nil)
+	return err
+}
+
+//------------------------------------------------------------------------------
+
+// dataLakeRollingFile is an open file that messages are appended to until it
+// is rolled.
+type dataLakeRollingFile struct {
+	filesystem string
+	tmpPath    string
+	finalPath  string
+	opened     time.Time
+	size       int64
+
+	// Closed once the file has been rolled, with err set when it failed.
+	done chan struct{}
+	err  error
+}
+
+type dataLakeFileKey struct {
+	filesystem string
+	partition  string
+}
+
+type dataLakeWriter struct {
+	conf  dloConfig
+	files dataLakeFiles
+	log   *service.Logger
+
+	nowFn         func() time.Time
+	checkInterval time.Duration
+
+	rollMut   sync.Mutex
+	rolling   map[dataLakeFileKey]*dataLakeRollingFile
+	connected bool
+	shutSig   *shutdown.Signaller
+}
+
+func newDataLakeWriter(conf dloConfig, files dataLakeFiles, mgr *service.Resources) *dataLakeWriter {
+	d := &dataLakeWriter{
+		conf:    conf,
+		files:   files,
+		log:     mgr.Logger(),
+		nowFn:   time.Now,
+		rolling: map[dataLakeFileKey]*dataLakeRollingFile{},
+		shutSig: shutdown.NewSignaller(),
+	}
+
+	d.checkInterval = conf.Rolling.MaxAge / 10
+	if d.checkInterval > time.Second || d.checkInterval <= 0 {
+		d.checkInterval = time.Second
+	}
+	return d
+}
+
+func (d *dataLakeWriter) Connect(ctx context.Context) error {
+	d.rollMut.Lock()
+	defer d.rollMut.Unlock()
+
+	if d.connected {
+		return nil
+	}
+	d.connected = true
+	if d.conf.Rolling.Enabled {
+		go d.rollLoop()
+	}
+	return nil
+}
+
+func (d *dataLakeWriter) WriteBatch(ctx context.Context, batch service.MessageBatch) error {
+	if d.conf.Rolling.Enabled {
+		return d.writeRolling(ctx, batch)
+	}
+	return batch.WalkWithBatchedErrors(func(i int, m *service.Message) error {
+		filesystem, err := batch.TryInterpolatedString(i, d.conf.Filesystem)
+		if err != nil {
+			return fmt.Errorf("filesystem interpolation error: %w", err)
+		}
+		filePath, err := batch.TryInterpolatedString(i, d.conf.Path)
+		if err != nil {
+			return fmt.Errorf("path interpolation error: %w", err)
+		}
+		mBytes, err := m.AsBytes()
+		if err != nil {
+			return err
+		}
+
+		if err := d.files.create(ctx, filesystem, filePath); err != nil {
+			return fmt.Errorf("failed to create file: %w", err)
+		}
+		if len(mBytes) > 0 {
+			if err := d.files.appendData(ctx, filesystem, filePath, 0, mBytes); err != nil {
+				return fmt.Errorf("failed to append data: %w", err)
+			}
+		}
+		if err := d.files.flush(ctx, filesystem, filePath, int64(len(mBytes))); err != nil {
+			return fmt.Errorf("failed to flush data: %w", err)
+		}
+		return nil
+	})
+}
+
+func (d *dataLakeWriter) rollLoop() {
+	defer d.shutSig.TriggerHasStopped()
+
+	ticker := time.NewTicker(d.checkInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			d.rollMut.Lock()
+			now := d.nowFn()
+			for k, f := range d.rolling {
+				if now.Sub(f.opened) >= d.conf.Rolling.MaxAge {
+					d.rollLocked(k, f)
+				}
+			}
+			d.rollMut.Unlock()
+		case <-d.shutSig.SoftStopChan():
+			d.rollMut.Lock()
+			for k, f := range d.rolling {
+				d.rollLocked(k, f)
+			}
+			d.connected = false
+			d.rollMut.Unlock()
+			return
+		}
+	}
+}
+
+func (d *dataLakeWriter) writeRolling(ctx context.Context, batch service.MessageBatch) error {
+	var keys []dataLakeFileKey
+	byKey := map[dataLakeFileKey][]int{}
+	for i := range batch {
+		var k dataLakeFileKey
+		var err error
+		if k.filesystem, err = batch.TryInterpolatedString(i, d.conf.Filesystem); err != nil {
+			return fmt.Errorf("filesystem interpolation error: %w", err)
+		}
+		if k.partition, err = batch.TryInterpolatedString(i, d.conf.Rolling.Partition); err != nil {
+			return fmt.Errorf("partition interpolation error: %w", err)
+		}
+		if _, exists := byKey[k]; !exists {
+			keys = append(keys, k)
+		}
+		byKey[k] = append(byKey[k], i)
+	}
+
+	d.rollMut.Lock()
+	if !d.connected {
+		d.rollMut.Unlock()
+		return service.ErrNotConnected
+	}
+
+	files := make([]*dataLakeRollingFile, 0, len(keys))
+	for _, k := range keys {
+		f, err := d.appendLocked(ctx, k, batch, byKey[k])
+		if err != nil {
+			d.rollMut.Unlock()
+			return err
+		}
+		files = append(files, f)
+	}
+	d.rollMut.Unlock()
+
+	// Messages are only delivered once the files they were appended to have
+	// been rolled.
+	for _, f := range files {
+		select {
+		case <-f.done:
+			if f.err != nil {
+				return f.err
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+func (d *dataLakeWriter) appendLocked(ctx context.Context, k dataLakeFileKey, batch service.MessageBatch, indexes []int) (*dataLakeRollingFile, error) {
+	f, exists := d.rolling[k]
+	if !exists {
+		name := strconv.FormatInt(d.nowFn().UnixNano(), 10) + "-" + uuid.Must(uuid.NewV4()).String() + d.conf.Rolling.FileSuffix
+		f = &dataLakeRollingFile{
+			filesystem: k.filesystem,
+			tmpPath:    path.Join(k.partition, "."+name+".tmp"),
+			finalPath:  path.Join(k.partition, name),
+			opened:     d.nowFn(),
+			done:       make(chan struct{}),
+		}
+		if err := d.files.create(ctx, f.filesystem, f.tmpPath); err != nil {
+			return nil, fmt.Errorf("failed to create file: %w", err)
+		}
+		d.rolling[k] = f
+	}
+
+	var buf bytes.Buffer
+	for _, i := range indexes {
+		mBytes, err := batch[i].AsBytes()
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(mBytes)
+		buf.Write(d.conf.Rolling.Separator)
+	}
+
+	if buf.Len() > 0 {
+		if err := d.files.appendData(ctx, f.filesystem, f.tmpPath, f.size, buf.Bytes()); err != nil {
+			err = fmt.Errorf("failed to append data: %w", err)
+			d.failLocked(k, f, err)
+			return nil, err
+		}
+		f.size += int64(buf.Len())
+	}
+
+	if f.size >= d.conf.Rolling.MaxSize {
+		d.rollLocked(k, f)
+	}
+	return f, nil
+}
+
+// rollLocked commits the data of a file and renames it to its final path,
+// releasing the messages waiting on it.
+func (d *dataLakeWriter) rollLocked(k dataLakeFileKey, f *dataLakeRollingFile) {
+	ctx := context.Background()
+	if err := d.files.flush(ctx, f.filesystem, f.tmpPath, f.size); err != nil {
+		d.failLocked(k, f, fmt.Errorf("failed to flush data: %w", err))
+		return
+	}
+	if err := d.files.rename(ctx, f.filesystem, f.tmpPath, f.finalPath); err != nil {
+		d.failLocked(k, f, fmt.Errorf("failed to rename file: %w", err))
+		return
+	}
+	delete(d.rolling, k)
+	close(f.done)
+}
+
+// failLocked abandons a file, deleting its temporary file, and fails the
+// messages waiting on it.
+func (d *dataLakeWriter) failLocked(k dataLakeFileKey, f *dataLakeRollingFile, err error) {
+	if d.rolling[k] == f {
+		delete(d.rolling, k)
+	}
+	if dErr := d.files.delete(context.Background(), f.filesystem, f.tmpPath); dErr != nil {
+		d.log.Errorf("Failed to delete abandoned file %v: %v", f.tmpPath, dErr)
+	}
+	f.err = err
+	close(f.done)
+}
+
+func (d *dataLakeWriter) Close(ctx context.Context) error {
+	if !d.conf.Rolling.Enabled {
+		return nil
+	}
+
+	d.rollMut.Lock()
+	connected := d.connected
+	d.rollMut.Unlock()
+	if !connected {
+		return nil
+	}
+
+	// Files are rolled when closing so that the messages waiting on them are
+	// delivered.
+	d.shutSig.TriggerSoftStop()
+	select {
+	case <-d.shutSig.HasStoppedChan():
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return nil
+}
@@ -0,0 +1,241 @@
+package azure
+
+import (
+	"context"
+	"errors"
+	"path"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+type mockDataLakeFile struct {
+	data    []byte
+	flushed bool
+}
+
+type mockDataLake struct {
+	mut       sync.Mutex
+	files     map[string]*mockDataLakeFile
+	appendErr error
+}
+
+func newMockDataLake() *mockDataLake {
+	return &mockDataLake{files: map[string]*mockDataLakeFile{}}
+}
+
+func (m *mockDataLake) create(ctx context.Context, filesystem, filePath string) error {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+
+	m.files[path.Join(filesystem, filePath)] = &mockDataLakeFile{}
+	return nil
+}
+
+func (m *mockDataLake) appendData(ctx context.Context, filesystem, filePath string, offset int64, data []byte) error {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+
+	if m.appendErr != nil {
+		return m.appendErr
+	}
+	f, exists := m.files[path.Join(filesystem, filePath)]
+	if !exists {
+		return errors.New("file does not exist")
+	}
+	if int64(len(f.data)) != offset {
+		return errors.New("invalid offset")
+	}
+	f.data = append(f.data, data...)
+	return nil
+}
+
+func (m *mockDataLake) flush(ctx context.Context, filesystem, filePath string, offset int64) error {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+
+	f, exists := m.files[path.Join(filesystem, filePath)]
+	if !exists {
+		return errors.New("file does not exist")
+	}
+	if int64(len(f.data)) != offset {
+		return errors.New("invalid flush position")
+	}
+	f.flushed = true
+	return nil
+}
+
+func (m *mockDataLake) rename(ctx context.Context, filesystem, fromPath, toPath string) error {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+
+	f, exists := m.files[path.Join(filesystem, fromPath)]
+	if !exists {
+		return errors.New("file does not exist")
+	}
+	delete(m.files, path.Join(filesystem, fromPath))
+	m.files[path.Join(filesystem, toPath)] = f
+	return nil
+}
+
+func (m *mockDataLake) delete(ctx context.Context, filesystem, filePath string) error {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+
+	delete(m.files, path.Join(filesystem, filePath))
+	return nil
+}
+
+func (m *mockDataLake) flushedFiles() map[string]string {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+
+	files := map[string]string{}
+	for k, f := range m.files {
+		if f.flushed {
+			files[k] = string(f.data)
+		}
+	}
+	return files
+}
+
+func testDataLakeWriter(t *testing.T, yamlConf string) (*dataLakeWriter, *mockDataLake) {
+	t.Helper()
+
+	pConf, err := dloSpec().ParseYAML(yamlConf, nil)
+	require.NoError(t, err)
+
+	conf, err := dloConfigFromParsed(pConf)
+	require.NoError(t, err)
+
+	m := newMockDataLake()
+	w := newDataLakeWriter(conf, m, service.MockResources())
+	require.NoError(t, w.Connect(context.Background()))
+
+	t.Cleanup(func() {
+		_ = w.Close(context.Background())
+	})
+	return w, m
+}
+
+func TestDataLakeFilePerMessage(t *testing.T) {
+	w, m := testDataLakeWriter(t, `
+storage_account: foo
+filesystem: ${! meta("fs") }
+path: dir/${! json("id") }.json
+`)
+
+	msg := func(fs, content string) *service.Message {
+		m := service.NewMessage([]byte(content))
+		m.MetaSetMut("fs", fs)
+		return m
+	}
+
+	require.NoError(t, w.WriteBatch(context.Background(), service.MessageBatch{
+		msg("a", `{"id":"1"}`),
+		msg("b", `{"id":"2"}`),
+	}))
+
+	assert.Equal(t, map[string]string{
+		"a/dir/1.json": `{"id":"1"}`,
+		"b/dir/2.json": `{"id":"2"}`,
+	}, m.flushedFiles())
+}
+
+func TestDataLakeRollingBySize(t *testing.T) {
+	w, m := testDataLakeWriter(t, `
+storage_account: foo
+filesystem: landing
+rolling:
+  enabled: true
+  partition: dt=${! meta("dt") }
+  file_suffix: .jsonl
+  max_size: 10B
+`)
+
+	msg := func(dt, content string) *service.Message {
+		m := service.NewMessage([]byte(content))
+		m.MetaSetMut("dt", dt)
+		return m
+	}
+
+	require.NoError(t, w.WriteBatch(context.Background(), service.MessageBatch{
+		msg("2024-01-01", `{"a":1}`),
+		msg("2024-01-02", `{"b":1}`),
+		msg("2024-01-01", `{"a":2}`),
+		msg("2024-01-02", `{"b":2}`),
+	}))
+
+	files := m.flushedFiles()
+	require.Len(t, files, 2)
+
+	contents := map[string]string{}
+	for k, v := range files {
+		assert.True(t, strings.HasSuffix(k, ".jsonl"), k)
+		dir, name := path.Split(k)
+		assert.False(t, strings.HasPrefix(name, "."), k)
+		contents[dir] = v
+	}
+	assert.Equal(t, map[string]string{
+		"landing/dt=2024-01-01/": "{\"a\":1}\n{\"a\":2}\n",
+		"landing/dt=2024-01-02/": "{\"b\":1}\n{\"b\":2}\n",
+	}, contents)
+}
+
+func TestDataLakeRollingByAge(t *testing.T) {
+	w, m := testDataLakeWriter(t, `
+storage_account: foo
+filesystem: landing
+rolling:
+  enabled: true
+  separator: ","
+  max_age: 50ms
+`)
+
+	var wg sync.WaitGroup
+	for _, content := range []string{"a", "b", "c"} {
+		content := content
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, w.WriteBatch(context.Background(), service.MessageBatch{
+				service.NewMessage([]byte(content)),
+			}))
+		}()
+	}
+	wg.Wait()
+
+	var total string
+	for _, v := range m.flushedFiles() {
+		total += v
+	}
+	assert.Len(t, total, 6)
+	for _, content := range []string{"a,", "b,", "c,"} {
+		assert.Contains(t, total, content)
+	}
+}
+
+func TestDataLakeRollingAppendFailure(t *testing.T) {
+	w, m := testDataLakeWriter(t, `
+storage_account: foo
+filesystem: landing
+rolling:
+  enabled: true
+`)
+	m.appendErr = errors.New("nope")
+
+	err := w.WriteBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte("hello world")),
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "nope")
+
+	m.mut.Lock()
+	assert.Empty(t, m.files)
+	m.mut.Unlock()
+}
@@ -0,0 +1,392 @@
+package azure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/messaging/azservicebus"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	// Service Bus Output Fields
+	sboFieldSessionID            = "session_id"
+	sboFieldMessageID            = "message_id"
+	sboFieldCorrelationID        = "correlation_id"
+	sboFieldSubject              = "subject"
+	sboFieldContentType          = "content_type"
+	sboFieldPartitionKey         = "partition_key"
+	sboFieldTTL                  = "ttl"
+	sboFieldScheduledEnqueueTime = "scheduled_enqueue_time"
+	sboFieldMetadata             = "metadata"
+	sboFieldBatching             = "batching"
+)
+
+type sboConfig struct {
+	client               *azservicebus.Client
+	Queue                string
+	Topic                string
+	SessionID            *service.InterpolatedString
+	MessageID            *service.InterpolatedString
+	CorrelationID        *service.InterpolatedString
+	Subject              *service.InterpolatedString
+	ContentType          *service.InterpolatedString
+	PartitionKey         *service.InterpolatedString
+	TTL                  *service.InterpolatedString
+	ScheduledEnqueueTime *service.InterpolatedString
+	Metadata             *service.MetadataExcludeFilter
+}
+
+func sboConfigFromParsed(pConf *service.ParsedConfig) (conf sboConfig, err error) {
+	if conf.Queue, err = pConf.FieldString(sbFieldQueue); err != nil {
+		return
+	}
+	if conf.Topic, err = pConf.FieldString(sbFieldTopic); err != nil {
+		return
+	}
+	if (conf.Queue == "") == (conf.Topic == "") {
+		err = errors.New("exactly one of queue or topic must be set")
+		return
+	}
+	for _, f := range []struct {
+		name   string
+		target **service.InterpolatedString
+	}{
+		{sboFieldSessionID, &conf.SessionID},
+		{sboFieldMessageID, &conf.MessageID},
+		{sboFieldCorrelationID, &conf.CorrelationID},
+		{sboFieldSubject, &conf.Subject},
+		{sboFieldContentType, &conf.ContentType},
+		{sboFieldPartitionKey, &conf.PartitionKey},
+		{sboFieldTTL, &conf.TTL},
+		{sboFieldScheduledEnqueueTime, &conf.ScheduledEnqueueTime},
+	} {
+		if !pConf.Contains(f.name) {
+			continue
+		}
+		if *f.target, err = pConf.FieldInterpolatedString(f.name); err != nil {
+			return
+		}
+	}
+	if conf.Metadata, err = pConf.FieldMetadataExcludeFilter(sboFieldMetadata); err != nil {
+		return
+	}
+	if conf.client, err = serviceBusClientFromParsed(pConf); err != nil {
+		return
+	}
+	return
+}
+
+func sboSpec() *service.ConfigSpec {
+	return serviceBusComponentSpec().
+		Beta().
+		Version("4.28.0").
+		Summary(`Sends messages to an Azure Service Bus queue or topic.`).
+		Description(`
+Metadata values are sent as application properties of each message, and the fields of the message such as `+"`session_id`"+`, `+"`subject`"+` and `+"`correlation_id`"+` can be set dynamically using [function interpolations](/docs/configuration/interpolation#bloblang-queries), which are resolved individually for each message of a batch.
+
+### Sessions
+
+Setting a `+"`session_id`"+` is required when sending to session-enabled queues and topics, and messages that share a session are delivered to consumers in the order that they were sent.
+
+### Scheduled Messages
+
+When `+"`scheduled_enqueue_time`"+` resolves to a non-empty timestamp the message is scheduled and only becomes visible to consumers at that time. Timestamps can be either RFC3339 strings or unix timestamps in seconds.
+
+### Batching
+
+Messages of a batch are sent with as few requests as possible, where each request contains messages of the same session and partition key up to the maximum batch size permitted by the Service Bus. Scheduled messages are sent individually.
+`+sbAuthDocs+service.OutputPerformanceDocs(true, true)).
+		Fields(
+			service.NewStringField(sbFieldQueue).
+				Description("The queue to send messages to. Either this field or `"+sbFieldTopic+"` must be set.").
+				Default(""),
+			service.NewStringField(sbFieldTopic).
+				Description("The topic to send messages to.").
+				Default(""),
+			service.NewInterpolatedStringField(sboFieldSessionID).
+				Description("An optional session to send each message to.").
+				Example(`${! json("customer_id") }`).
+				Optional(),
+			service.NewInterpolatedStringField(sboFieldMessageID).
+				Description("An optional ID to set for each message, which is used for duplicate detection.").
+				Optional().
+				Advanced(),
+			service.NewInterpolatedStringField(sboFieldCorrelationID).
+				Description("An optional correlation ID to set for each message.").
+				Optional().
+				Advanced(),
+			service.NewInterpolatedStringField(sboFieldSubject).
+				Description("An optional subject to set for each message.").
+				Optional().
+				Advanced(),
+			service.NewInterpolatedStringField(sboFieldContentType).
+				Description("An optional content type to set for each message.").
+				Example("application/json").
+				Optional().
+				Advanced(),
+			service.NewInterpolatedStringField(sboFieldPartitionKey).
+				Description("An optional partition key to set for each message of a partitioned entity. When a session ID is also set both must have the same value.").
+				Optional().
+				Advanced(),
+			service.NewInterpolatedStringField(sboFieldTTL).
+				Description("An optional time to live of each message as a duration string.").
+				Example("60s").Example("24h").
+				Optional().
+				Advanced(),
+			service.NewInterpolatedStringField(sboFieldScheduledEnqueueTime).
+				Description("An optional time at which each message becomes visible to consumers, as either an RFC3339 string or a unix timestamp in seconds. Messages for which this resolves to an empty string are sent immediately.").
+				Example(`${! now().ts_add_iso8601("PT1H") }`).
+				Example(`${! meta("deliver_at") }`).
+				Optional().
+				Advanced(),
+			service.NewMetadataExcludeFilterField(sboFieldMetadata).
+				Description("Specify criteria for which metadata values are sent as application properties."),
+			service.NewOutputMaxInFlightField().
+				Description("The maximum number of parallel message batches to have in flight at any given time."),
+			service.NewBatchPolicyField(sboFieldBatching),
+		).
+		LintRule(`root = if (this.queue.or("") == "") == (this.topic.or("") == "") { [ "exactly one of queue or topic must be set" ] }`)
+}
+
+func init() {
+	err := service.RegisterBatchOutput("azure_service_bus", sboSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (out service.BatchOutput, batcher service.BatchPolicy, mif int, err error) {
+			var pConf sboConfig
+			if pConf, err = sboConfigFromParsed(conf); err != nil {
+				return
+			}
+			if batcher, err = conf.FieldBatchPolicy(sboFieldBatching); err != nil {
+				return
+			}
+			if mif, err = conf.FieldMaxInFlight(); err != nil {
+				return
+			}
+			out, err = newServiceBusWriter(pConf, mgr.Logger())
+			return
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type serviceBusWriter struct {
+	conf sboConfig
+	log  *service.Logger
+
+	mut    sync.Mutex
+	sender *azservicebus.Sender
+}
+
+func newServiceBusWriter(conf sboConfig, log *service.Logger) (*serviceBusWriter, error) {
+	return &serviceBusWriter{
+		conf: conf,
+		log:  log,
+	}, nil
+}
+
+func (s *serviceBusWriter) Connect(ctx context.Context) error {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	if s.sender != nil {
+		return nil
+	}
+
+	entity := s.conf.Queue
+	if entity == "" {
+		entity = s.conf.Topic
+	}
+
+	var err error
+	s.sender, err = s.conf.client.NewSender(entity, nil)
+	return err
+}
+
+// sbOutgoingMessage is a message to send along with the time at which it
+// should be enqueued, which is zero for messages that are sent immediately.
+type sbOutgoingMessage struct {
+	msg         *azservicebus.Message
+	scheduledAt time.Time
+}
+
+func parseScheduledEnqueueTime(s string) (time.Time, error) {
+	if unix, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return time.Unix(unix, 0), nil
+	}
+	return time.Parse(time.RFC3339Nano, s)
+}
+
+func (s *serviceBusWriter) toMessage(batch service.MessageBatch, i int) (sbOutgoingMessage, error) {
+	body, err := batch[i].AsBytes()
+	if err != nil {
+		return sbOutgoingMessage{}, err
+	}
+	msg := &azservicebus.Message{Body: body}
+
+	for _, f := range []struct {
+		name   string
+		value  *service.InterpolatedString
+		target **string
+	}{
+		{sboFieldSessionID, s.conf.SessionID, &msg.SessionID},
+		{sboFieldMessageID, s.conf.MessageID, &msg.MessageID},
+		{sboFieldCorrelationID, s.conf.CorrelationID, &msg.CorrelationID},
+		{sboFieldSubject, s.conf.Subject, &msg.Subject},
+		{sboFieldContentType, s.conf.ContentType, &msg.ContentType},
+		{sboFieldPartitionKey, s.conf.PartitionKey, &msg.PartitionKey},
+	} {
+		if f.value == nil {
+			continue
+		}
+		v, err := batch.TryInterpolatedString(i, f.value)
+		if err != nil {
+			return sbOutgoingMessage{}, fmt.Errorf("%v interpolation error: %w", f.name, err)
+		}
+		if v != "" {
+			*f.target = &v
+		}
+	}
+
+	if s.conf.TTL != nil {
+		ttlStr, err := batch.TryInterpolatedString(i, s.conf.TTL)
+		if err != nil {
+			return sbOutgoingMessage{}, fmt.Errorf("ttl interpolation error: %w", err)
+		}
+		if ttlStr != "" {
+			ttl, err := time.ParseDuration(ttlStr)
+			if err != nil {
+				return sbOutgoingMessage{}, fmt.Errorf("failed to parse ttl: %w", err)
+			}
+			msg.TimeToLive = &ttl
+		}
+	}
+
+	var scheduledAt time.Time
+	if s.conf.ScheduledEnqueueTime != nil {
+		tStr, err := batch.TryInterpolatedString(i, s.conf.ScheduledEnqueueTime)
+		if err != nil {
+			return sbOutgoingMessage{}, fmt.Errorf("scheduled enqueue time interpolation error: %w", err)
+		}
+		if tStr != "" {
+			if scheduledAt, err = parseScheduledEnqueueTime(tStr); err != nil {
+				return sbOutgoingMessage{}, fmt.Errorf("failed to parse scheduled enqueue time: %w", err)
+			}
+		}
+	}
+
+	_ = s.conf.Metadata.WalkMut(batch[i], func(k string, v any) error {
+		if msg.ApplicationProperties == nil {
+			msg.ApplicationProperties = map[string]any{}
+		}
+		msg.ApplicationProperties[k] = v
+		return nil
+	})
+
+	return sbOutgoingMessage{msg: msg, scheduledAt: scheduledAt}, nil
+}
+
+// sameServiceBusGroup returns true if two messages can be sent within the same
+// batch, which requires them to share a session and partition key.
+func sameServiceBusGroup(a, b *azservicebus.Message) bool {
+	eq := func(l, r *string) bool {
+		if l == nil || r == nil {
+			return l == r
+		}
+		return *l == *r
+	}
+	return eq(a.SessionID, b.SessionID) && eq(a.PartitionKey, b.PartitionKey)
+}
+
+func (s *serviceBusWriter) WriteBatch(ctx context.Context, batch service.MessageBatch) error {
+	s.mut.Lock()
+	sender := s.sender
+	s.mut.Unlock()
+
+	if sender == nil {
+		return service.ErrNotConnected
+	}
+
+	msgs := make([]sbOutgoingMessage, len(batch))
+	for i := range batch {
+		var err error
+		if msgs[i], err = s.toMessage(batch, i); err != nil {
+			return err
+		}
+	}
+
+	var pending *azservicebus.MessageBatch
+	var pendingHead *azservicebus.Message
+	flush := func() error {
+		if pending == nil || pending.NumMessages() == 0 {
+			return nil
+		}
+		err := sender.SendMessageBatch(ctx, pending, nil)
+		pending, pendingHead = nil, nil
+		return err
+	}
+
+	for i, m := range msgs {
+		if !m.scheduledAt.IsZero() {
+			if err := flush(); err != nil {
+				return err
+			}
+			if _, err := sender.ScheduleMessages(ctx, []*azservicebus.Message{m.msg}, m.scheduledAt, nil); err != nil {
+				return fmt.Errorf("failed to schedule message: %w", err)
+			}
+			continue
+		}
+
+		if pending != nil && !sameServiceBusGroup(pendingHead, m.msg) {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+
+		for {
+			if pending == nil {
+				var err error
+				if pending, err = sender.NewMessageBatch(ctx, nil); err != nil {
+					return err
+				}
+				pendingHead = m.msg
+			}
+
+			err := pending.AddMessage(m.msg, nil)
+			if err == nil {
+				break
+			}
+			if !errors.Is(err, azservicebus.ErrMessageTooLarge) {
+				return err
+			}
+			if pending.NumMessages() == 0 {
+				return fmt.Errorf("message %v exceeds the maximum size of a service bus message", i)
+			}
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+	return flush()
+}
+
+func (s *serviceBusWriter) Close(ctx context.Context) error {
+	s.mut.Lock()
+	sender := s.sender
+	s.sender = nil
+	s.mut.Unlock()
+
+	var err error
+	if sender != nil {
+		err = sender.Close(ctx)
+	}
+	if cErr := s.conf.client.Close(ctx); err == nil {
+		err = cErr
+	}
+	return err
+}
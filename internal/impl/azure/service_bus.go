@@ -0,0 +1,131 @@
+package azure
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	"github.com/Azure/azure-sdk-for-go/sdk/messaging/azservicebus"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	// Common fields for service bus components
+	sbFieldConnectionString        = "connection_string"
+	sbFieldNamespace               = "namespace"
+	sbFieldManagedIdentityClientID = "managed_identity_client_id"
+	sbFieldQueue                   = "queue"
+	sbFieldTopic                   = "topic"
+)
+
+const sbAuthDocs = `
+### Authentication
+
+Either a ` + "`connection_string`" + ` or the fully qualified ` + "`namespace`" + ` of the Service Bus must be provided. When only a namespace is set the credentials are obtained with the [default Azure credential chain](https://learn.microsoft.com/en-us/azure/developer/go/azure-sdk-authentication), which covers environment variables, workload identity and managed identity. A specific user-assigned managed identity can be selected with the field ` + "`managed_identity_client_id`" + `.`
+
+func serviceBusComponentSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Categories("Services", "Azure").
+		Fields(
+			service.NewStringField(sbFieldConnectionString).
+				Description("A Service Bus connection string. This field is required if `"+sbFieldNamespace+"` is not set.").
+				Example("Endpoint=sb://example.servicebus.windows.net/;SharedAccessKeyName=RootManageSharedAccessKey;SharedAccessKey=...").
+				Secret().
+				Default(""),
+			service.NewStringField(sbFieldNamespace).
+				Description("The fully qualified namespace of the Service Bus, used to authenticate with Azure credentials. This field is ignored if `"+sbFieldConnectionString+"` is set.").
+				Example("example.servicebus.windows.net").
+				Default(""),
+			service.NewStringField(sbFieldManagedIdentityClientID).
+				Description("The client ID of a user-assigned managed identity to authenticate with. When empty the default Azure credential chain is used. This field is ignored if `"+sbFieldConnectionString+"` is set.").
+				Default("").
+				Advanced(),
+		).
+		LintRule(`root = if this.connection_string.or("") == "" && this.namespace.or("") == "" { [ "either connection_string or namespace must be set" ] }`)
+}
+
+func serviceBusClientFromParsed(pConf *service.ParsedConfig) (*azservicebus.Client, error) {
+	connectionString, err := pConf.FieldString(sbFieldConnectionString)
+	if err != nil {
+		return nil, err
+	}
+	namespace, err := pConf.FieldString(sbFieldNamespace)
+	if err != nil {
+		return nil, err
+	}
+	clientID, err := pConf.FieldString(sbFieldManagedIdentityClientID)
+	if err != nil {
+		return nil, err
+	}
+	return getServiceBusClient(connectionString, namespace, clientID)
+}
+
+func getServiceBusClient(connectionString, namespace, managedIdentityClientID string) (*azservicebus.Client, error) {
+	if connectionString != "" {
+		client, err := azservicebus.NewClientFromConnectionString(connectionString, nil)
+		if err != nil {
+			return nil, fmt.Errorf("invalid service bus connection string: %w", err)
+		}
+		return client, nil
+	}
+	if namespace == "" {
+		return nil, errors.New("either a service bus connection string or namespace must be provided")
+	}
+
+	var cred azcore.TokenCredential
+	var err error
+	if managedIdentityClientID != "" {
+		cred, err = azidentity.NewManagedIdentityCredential(&azidentity.ManagedIdentityCredentialOptions{
+			ID: azidentity.ClientID(managedIdentityClientID),
+		})
+	} else {
+		cred, err = azidentity.NewDefaultAzureCredential(nil)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error getting azure credentials: %w", err)
+	}
+
+	namespace = strings.TrimSuffix(strings.TrimPrefix(namespace, "sb://"), "/")
+	return azservicebus.NewClient(namespace, cred, nil)
+}
+
+// receivedMessageToPart converts a message consumed from Service Bus into a
+// Benthos message, adding its properties as metadata.
+func receivedMessageToPart(m *azservicebus.ReceivedMessage) *service.Message {
+	part := service.NewMessage(m.Body)
+
+	part.MetaSetMut("service_bus_message_id", m.MessageID)
+	part.MetaSetMut("service_bus_delivery_count", int64(m.DeliveryCount))
+	if m.SequenceNumber != nil {
+		part.MetaSetMut("service_bus_sequence_number", *m.SequenceNumber)
+	}
+	if m.EnqueuedTime != nil {
+		part.MetaSetMut("service_bus_enqueued_time", m.EnqueuedTime.Format(time.RFC3339Nano))
+	}
+
+	for k, v := range map[string]*string{
+		"service_bus_session_id":              m.SessionID,
+		"service_bus_subject":                 m.Subject,
+		"service_bus_correlation_id":          m.CorrelationID,
+		"service_bus_content_type":            m.ContentType,
+		"service_bus_partition_key":           m.PartitionKey,
+		"service_bus_reply_to":                m.ReplyTo,
+		"service_bus_to":                      m.To,
+		"service_bus_dead_letter_reason":      m.DeadLetterReason,
+		"service_bus_dead_letter_description": m.DeadLetterErrorDescription,
+		"service_bus_dead_letter_source":      m.DeadLetterSource,
+	} {
+		if v != nil {
+			part.MetaSetMut(k, *v)
+		}
+	}
+
+	for k, v := range m.ApplicationProperties {
+		part.MetaSetMut(k, v)
+	}
+	return part
+}
@@ -0,0 +1,142 @@
+package azure
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/messaging/azservicebus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const testServiceBusConnectionString = "Endpoint=sb://example.servicebus.windows.net/;SharedAccessKeyName=RootManageSharedAccessKey;SharedAccessKey=c2VjcmV0"
+
+func TestServiceBusOutputToMessage(t *testing.T) {
+	pConf, err := sboSpec().ParseYAML(`
+connection_string: `+testServiceBusConnectionString+`
+queue: foo
+session_id: ${! json("customer") }
+subject: ${! meta("kind") }
+ttl: 1h
+scheduled_enqueue_time: ${! meta("at") }
+metadata:
+  exclude_prefixes: [ "at" ]
+`, nil)
+	require.NoError(t, err)
+
+	conf, err := sboConfigFromParsed(pConf)
+	require.NoError(t, err)
+
+	w, err := newServiceBusWriter(conf, service.MockResources().Logger())
+	require.NoError(t, err)
+
+	immediate := service.NewMessage([]byte(`{"customer":"a"}`))
+	immediate.MetaSetMut("kind", "order")
+
+	scheduled := service.NewMessage([]byte(`{"customer":"b"}`))
+	scheduled.MetaSetMut("at", "1700000000")
+
+	batch := service.MessageBatch{immediate, scheduled}
+
+	m, err := w.toMessage(batch, 0)
+	require.NoError(t, err)
+	assert.Equal(t, `{"customer":"a"}`, string(m.msg.Body))
+	assert.Equal(t, "a", *m.msg.SessionID)
+	assert.Equal(t, "order", *m.msg.Subject)
+	assert.Equal(t, time.Hour, *m.msg.TimeToLive)
+	assert.Equal(t, map[string]any{"kind": "order"}, m.msg.ApplicationProperties)
+	assert.True(t, m.scheduledAt.IsZero())
+
+	m, err = w.toMessage(batch, 1)
+	require.NoError(t, err)
+	assert.Equal(t, "b", *m.msg.SessionID)
+	assert.Nil(t, m.msg.Subject)
+	assert.Nil(t, m.msg.ApplicationProperties)
+	assert.Equal(t, time.Unix(1700000000, 0), m.scheduledAt)
+}
+
+func TestServiceBusConfigValidation(t *testing.T) {
+	for _, test := range []struct {
+		name        string
+		conf        string
+		errContains string
+	}{
+		{
+			name:        "no entity",
+			conf:        `queue: ""`,
+			errContains: "exactly one of queue or topic",
+		},
+		{
+			name:        "topic without subscription",
+			conf:        `topic: foo`,
+			errContains: "subscription must be set",
+		},
+		{
+			name: "sessions from dead letter queue",
+			conf: `
+queue: foo
+dead_letter: true
+sessions:
+  enabled: true
+`,
+			errContains: "dead-letter",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			pConf, err := sbiSpec().ParseYAML("connection_string: "+testServiceBusConnectionString+"\n"+test.conf, nil)
+			require.NoError(t, err)
+
+			_, err = sbiConfigFromParsed(pConf)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), test.errContains)
+		})
+	}
+}
+
+func TestServiceBusReceivedMessageToPart(t *testing.T) {
+	seq := int64(42)
+	enqueued := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	session := "foo"
+	reason := "MaxDeliveryCountExceeded"
+
+	part := receivedMessageToPart(&azservicebus.ReceivedMessage{
+		Body:                  []byte("hello"),
+		MessageID:             "abc",
+		DeliveryCount:         3,
+		SequenceNumber:        &seq,
+		EnqueuedTime:          &enqueued,
+		SessionID:             &session,
+		DeadLetterReason:      &reason,
+		ApplicationProperties: map[string]any{"tenant": "bar"},
+	})
+
+	b, err := part.AsBytes()
+	require.NoError(t, err)
+	assert.Equal(t, "hello", string(b))
+
+	meta := map[string]any{}
+	require.NoError(t, part.MetaWalkMut(func(k string, v any) error {
+		meta[k] = v
+		return nil
+	}))
+	assert.Equal(t, map[string]any{
+		"service_bus_message_id":         "abc",
+		"service_bus_delivery_count":     int64(3),
+		"service_bus_sequence_number":    int64(42),
+		"service_bus_enqueued_time":      "2024-01-02T03:04:05Z",
+		"service_bus_session_id":         "foo",
+		"service_bus_dead_letter_reason": "MaxDeliveryCountExceeded",
+		"tenant":                         "bar",
+	}, meta)
+}
+
+func TestServiceBusSameGroup(t *testing.T) {
+	a, b := "a", "b"
+	assert.True(t, sameServiceBusGroup(&azservicebus.Message{}, &azservicebus.Message{}))
+	assert.True(t, sameServiceBusGroup(&azservicebus.Message{SessionID: &a}, &azservicebus.Message{SessionID: &a}))
+	assert.False(t, sameServiceBusGroup(&azservicebus.Message{SessionID: &a}, &azservicebus.Message{SessionID: &b}))
+	assert.False(t, sameServiceBusGroup(&azservicebus.Message{SessionID: &a}, &azservicebus.Message{}))
+	assert.False(t, sameServiceBusGroup(&azservicebus.Message{PartitionKey: &a}, &azservicebus.Message{PartitionKey: &b}))
+}
@@ -3,9 +3,12 @@ package cassandra
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"math"
 	"math/rand"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -21,6 +24,11 @@ const (
 	coFieldConsistency = "consistency"
 	coFieldLoggedBatch = "logged_batch"
 	coFieldBatching    = "batching"
+
+	coFieldGroupByPartition = "group_by_partition"
+	coFieldMaxPreparedStmts = "max_prepared_statements"
+	coFieldTTL              = "ttl"
+	coFieldTimestamp        = "timestamp"
 )
 
 func outputSpec() *service.ConfigSpec {
@@ -30,7 +38,17 @@ func outputSpec() *service.ConfigSpec {
 		Description(`
 Query arguments can be set using a bloblang array for the fields using the `+"`args_mapping`"+` field.
 
-When populating timestamp columns the value must either be a string in ISO 8601 format (2006-01-02T15:04:05Z07:00), or an integer representing unix time in seconds.`+service.OutputPerformanceDocs(true, true)).
+When populating timestamp columns the value must either be a string in ISO 8601 format (2006-01-02T15:04:05Z07:00), or an integer representing unix time in seconds.
+
+Queries are prepared once and the prepared statements are cached and reused across batches, the size of this cache can be set with `+"`max_prepared_statements`"+`.
+
+### Batch Failures
+
+When a message fails, either due to its arguments failing to map or the statement it belongs to failing to execute, only that message and the messages written within the same statement are marked as failed. This allows failed messages to be routed or retried individually with patterns such as a [`+"`fallback`"+` output](/docs/components/outputs/fallback) or [`+"`switch`"+` output](/docs/components/outputs/switch) rather than failing the whole batch.
+
+### Partition Grouping
+
+Setting `+"`group_by_partition`"+` to `+"`true`"+` splits each batch into a batch statement per partition key, and routes each statement directly to a replica of its partition. Batches that span partitions are expensive for the coordinating node, and so this can greatly improve write throughput. Logged batches remain atomic only within each partition.`+service.OutputPerformanceDocs(true, true)).
 		Example(
 			"Basic Inserts",
 			"If we were to create a table with some basic columns with `CREATE TABLE foo.bar (id int primary key, content text, created_at timestamp);`, and were processing JSON documents of the form `{\"id\":\"342354354\",\"content\":\"hello world\",\"timestamp\":1605219406}` using logged batches, we could populate our table with the following config:",
@@ -64,6 +82,24 @@ output:
     batching:
       count: 500
       period: 1s
+`,
+		).
+		Example(
+			"Expiring Partitioned Writes",
+			"The following example writes events grouped into batch statements per partition, where each event expires after a TTL taken from its metadata and is written with the timestamp of the event.",
+			`
+output:
+  cassandra:
+    addresses:
+      - localhost:9042
+    query: 'INSERT INTO foospace.events (device_id, id, content) VALUES (?, ?, ?)'
+    args_mapping: 'root = [ this.device_id, this.id, this.content ]'
+    group_by_partition: true
+    ttl: ${! meta("ttl").or("24h") }
+    timestamp: ${! this.created_at.ts_unix_micro() }
+    batching:
+      count: 500
+      period: 1s
 `,
 		).
 		Fields(clientFields()...).
@@ -83,6 +119,30 @@ output:
 				Description("If enabled the driver will perform a logged batch. Disabling this prompts unlogged batches to be used instead, which are less efficient but necessary for alternative storages that do not support logged batches.").
 				Advanced().
 				Default(true),
+			service.NewBoolField(coFieldGroupByPartition).
+				Description("Whether to split batches into a batch statement per partition key, with each statement routed to a replica of its partition. Check out the [partition grouping](#partition-grouping) section for more details.").
+				Advanced().
+				Version("4.28.0").
+				Default(false),
+			service.NewIntField(coFieldMaxPreparedStmts).
+				Description("The maximum number of prepared statements to cache.").
+				Advanced().
+				Version("4.28.0").
+				Default(1000),
+			service.NewInterpolatedStringField(coFieldTTL).
+				Description("An optional time to live to write each message with, either as a duration string or as an integer number of seconds. This is only supported with INSERT queries that do not already contain a `USING` clause, to which it is added.").
+				Example("24h").
+				Example(`${! meta("ttl_seconds") }`).
+				Advanced().
+				Version("4.28.0").
+				Optional(),
+			service.NewInterpolatedStringField(coFieldTimestamp).
+				Description("An optional timestamp to write each message with, either as an integer number of microseconds since the unix epoch or as a string in RFC 3339 format. This is only supported with INSERT queries that do not already contain a `USING` clause, to which it is added.").
+				Example(`${! timestamp_unix_micro() }`).
+				Example(`${! this.updated_at }`).
+				Advanced().
+				Version("4.28.0").
+				Optional(),
 			service.NewOutputMaxInFlightField(),
 			service.NewBatchPolicyField(coFieldBatching),
 		)
@@ -115,6 +175,11 @@ type cassandraWriter struct {
 	batchType   gocql.BatchType
 	consistency gocql.Consistency
 
+	groupByPartition bool
+	maxPreparedStmts int
+	ttl              *service.InterpolatedString
+	timestamp        *service.InterpolatedString
+
 	session  *gocql.Session
 	connLock sync.RWMutex
 }
@@ -151,9 +216,53 @@ func newCassandraWriter(conf *service.ParsedConfig, mgr *service.Resources) (c *
 		return nil, fmt.Errorf("parsing consistency: %w", err)
 	}
 
+	if c.groupByPartition, err = conf.FieldBool(coFieldGroupByPartition); err != nil {
+		return
+	}
+	if c.maxPreparedStmts, err = conf.FieldInt(coFieldMaxPreparedStmts); err != nil {
+		return
+	}
+	if conf.Contains(coFieldTTL) {
+		if c.ttl, err = conf.FieldInterpolatedString(coFieldTTL); err != nil {
+			return
+		}
+	}
+	if conf.Contains(coFieldTimestamp) {
+		if c.timestamp, err = conf.FieldInterpolatedString(coFieldTimestamp); err != nil {
+			return
+		}
+	}
+	if c.query, err = queryWithUsing(c.query, c.ttl != nil, c.timestamp != nil); err != nil {
+		return nil, err
+	}
 	return
 }
 
+// queryWithUsing adds a USING clause to an INSERT query for binding a TTL
+// and/or timestamp to each statement.
+func queryWithUsing(query string, ttl, timestamp bool) (string, error) {
+	if !ttl && !timestamp {
+		return query, nil
+	}
+
+	upperQuery := strings.ToUpper(strings.TrimSpace(query))
+	if !strings.HasPrefix(upperQuery, "INSERT") {
+		return "", errors.New("the ttl and timestamp fields are only supported with INSERT queries")
+	}
+	if strings.Contains(upperQuery, " USING ") {
+		return "", errors.New("the ttl and timestamp fields cannot be used with queries that already contain a USING clause")
+	}
+
+	var clauses []string
+	if ttl {
+		clauses = append(clauses, "TTL ?")
+	}
+	if timestamp {
+		clauses = append(clauses, "TIMESTAMP ?")
+	}
+	return strings.TrimSuffix(strings.TrimSpace(query), ";") + " USING " + strings.Join(clauses, " AND "), nil
+}
+
 func (c *cassandraWriter) Connect(ctx context.Context) error {
 	c.connLock.Lock()
 	defer c.connLock.Unlock()
@@ -166,6 +275,10 @@ func (c *cassandraWriter) Connect(ctx context.Context) error {
 		return err
 	}
 	conn.Consistency = c.consistency
+	conn.MaxPreparedStmts = c.maxPreparedStmts
+	if c.groupByPartition {
+		conn.PoolConfig.HostSelectionPolicy = gocql.TokenAwareHostPolicy(gocql.RoundRobinHostPolicy())
+	}
 
 	session, err := conn.CreateSession()
 	if err != nil {
@@ -181,36 +294,156 @@ func (c *cassandraWriter) WriteBatch(ctx context.Context, batch service.MessageB
 	session := c.session
 	c.connLock.RUnlock()
 
-	if c.session == nil {
+	if session == nil {
 		return service.ErrNotConnected
 	}
 
-	if len(batch) == 1 {
-		return c.writeRow(session, batch)
+	var routingKeyFn func(values []any) ([]byte, error)
+	if c.groupByPartition {
+		routingKeyFn = func(values []any) ([]byte, error) {
+			return session.Query(c.query, values...).WithContext(ctx).GetRoutingKey()
+		}
 	}
-	return c.writeBatch(session, batch)
-}
 
-func (c *cassandraWriter) writeRow(session *gocql.Session, b service.MessageBatch) error {
-	values, err := c.mapArgs(b, 0)
-	if err != nil {
-		return fmt.Errorf("parsing args: %w", err)
+	groups, batchErr := c.groupStatements(batch, routingKeyFn)
+	for _, g := range groups {
+		var err error
+		if len(g) == 1 {
+			err = session.Query(c.query, g[0].values...).WithContext(ctx).Exec()
+		} else {
+			b := session.NewBatch(c.batchType).WithContext(ctx)
+			for _, stmt := range g {
+				b.Query(c.query, stmt.values...)
+			}
+			err = session.ExecuteBatch(b)
+		}
+		if err != nil {
+			if batchErr == nil {
+				batchErr = service.NewBatchError(batch, err)
+			}
+			for _, stmt := range g {
+				batchErr = batchErr.Failed(stmt.index, err)
+			}
+		}
+	}
+	if batchErr != nil {
+		return batchErr
 	}
-	return session.Query(c.query, values...).Exec()
+	return nil
 }
 
-func (c *cassandraWriter) writeBatch(session *gocql.Session, b service.MessageBatch) error {
-	batch := session.NewBatch(c.batchType)
+type cassandraStatement struct {
+	index  int
+	values []any
+}
+
+// groupStatements maps the messages of a batch into statements, grouping them
+// by routing key when a function is provided. Messages that fail to map are
+// marked as failed within the returned batch error.
+func (c *cassandraWriter) groupStatements(batch service.MessageBatch, routingKeyFn func(values []any) ([]byte, error)) ([][]cassandraStatement, *service.BatchError) {
+	var batchErr *service.BatchError
+	fail := func(i int, err error) {
+		if batchErr == nil {
+			batchErr = service.NewBatchError(batch, err)
+		}
+		batchErr = batchErr.Failed(i, err)
+	}
+
+	var groups [][]cassandraStatement
+	groupIndexes := map[string]int{}
+	for i := range batch {
+		values, err := c.mapArgs(batch, i)
+		if err != nil {
+			fail(i, fmt.Errorf("parsing args: %w", err))
+			continue
+		}
+		if values, err = c.usingArgs(batch, i, values); err != nil {
+			fail(i, err)
+			continue
+		}
+
+		stmt := cassandraStatement{index: i, values: values}
+		if routingKeyFn == nil {
+			if len(groups) == 0 {
+				groups = append(groups, nil)
+			}
+			groups[0] = append(groups[0], stmt)
+			continue
+		}
 
-	for i := range b {
-		values, err := c.mapArgs(b, i)
+		routingKey, err := routingKeyFn(values)
 		if err != nil {
-			return fmt.Errorf("parsing args for part: %d: %w", i, err)
+			fail(i, fmt.Errorf("obtaining routing key: %w", err))
+			continue
+		}
+		gIndex, exists := groupIndexes[string(routingKey)]
+		if !exists {
+			gIndex = len(groups)
+			groupIndexes[string(routingKey)] = gIndex
+			groups = append(groups, nil)
 		}
-		batch.Query(c.query, values...)
+		groups[gIndex] = append(groups[gIndex], stmt)
 	}
+	return groups, batchErr
+}
 
-	return session.ExecuteBatch(batch)
+// usingArgs appends the TTL and timestamp arguments of a message when they
+// are configured.
+func (c *cassandraWriter) usingArgs(batch service.MessageBatch, i int, values []any) ([]any, error) {
+	if c.ttl != nil {
+		ttlStr, err := batch.TryInterpolatedString(i, c.ttl)
+		if err != nil {
+			return nil, fmt.Errorf("ttl interpolation error: %w", err)
+		}
+		ttl, err := parseTTL(ttlStr)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, ttl)
+	}
+	if c.timestamp != nil {
+		tsStr, err := batch.TryInterpolatedString(i, c.timestamp)
+		if err != nil {
+			return nil, fmt.Errorf("timestamp interpolation error: %w", err)
+		}
+		ts, err := parseWriteTimestamp(tsStr)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, ts)
+	}
+	return values, nil
+}
+
+func parseTTL(s string) (int, error) {
+	if s == "" {
+		return 0, nil
+	}
+	if secs, err := strconv.Atoi(s); err == nil {
+		if secs < 0 {
+			return 0, fmt.Errorf("ttl must not be negative: %v", s)
+		}
+		return secs, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse ttl: %w", err)
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("ttl must not be negative: %v", s)
+	}
+	return int(d.Seconds()), nil
+}
+
+func parseWriteTimestamp(s string) (int64, error) {
+	if micros, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return micros, nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse timestamp: %w", err)
+	}
+	return t.UnixMicro(), nil
 }
 
 func (c *cassandraWriter) mapArgs(b service.MessageBatch, index int) ([]any, error) {
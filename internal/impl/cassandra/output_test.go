@@ -0,0 +1,134 @@
+package cassandra
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func testCassandraWriter(t *testing.T, conf string) *cassandraWriter {
+	t.Helper()
+
+	pConf, err := outputSpec().ParseYAML(conf, nil)
+	require.NoError(t, err)
+
+	w, err := newCassandraWriter(pConf, service.MockResources())
+	require.NoError(t, err)
+	return w
+}
+
+func TestCassandraQueryWithUsing(t *testing.T) {
+	w := testCassandraWriter(t, `
+addresses: [ localhost:9042 ]
+query: 'INSERT INTO foo.bar (id, content) VALUES (?, ?);'
+ttl: ${! meta("ttl") }
+timestamp: ${! meta("ts") }
+`)
+	assert.Equal(t, "INSERT INTO foo.bar (id, content) VALUES (?, ?) USING TTL ? AND TIMESTAMP ?", w.query)
+
+	w = testCassandraWriter(t, `
+addresses: [ localhost:9042 ]
+query: 'INSERT INTO foo.bar JSON ?'
+ttl: 1h
+`)
+	assert.Equal(t, "INSERT INTO foo.bar JSON ? USING TTL ?", w.query)
+
+	for _, query := range []string{
+		"UPDATE foo.bar SET content = ? WHERE id = ?",
+		"INSERT INTO foo.bar (id) VALUES (?) USING TTL 60",
+	} {
+		pConf, err := outputSpec().ParseYAML(`
+addresses: [ localhost:9042 ]
+query: '`+query+`'
+ttl: 1h
+`, nil)
+		require.NoError(t, err)
+
+		_, err = newCassandraWriter(pConf, service.MockResources())
+		require.Error(t, err, query)
+	}
+}
+
+func TestCassandraGroupStatements(t *testing.T) {
+	w := testCassandraWriter(t, `
+addresses: [ localhost:9042 ]
+query: 'INSERT INTO foo.bar (device, content) VALUES (?, ?)'
+args_mapping: 'root = [ this.device, this.content ]'
+group_by_partition: true
+ttl: ${! meta("ttl") }
+timestamp: ${! meta("ts") }
+`)
+
+	msg := func(content, ttl, ts string) *service.Message {
+		m := service.NewMessage([]byte(content))
+		m.MetaSetMut("ttl", ttl)
+		m.MetaSetMut("ts", ts)
+		return m
+	}
+
+	batch := service.MessageBatch{
+		msg(`{"device":"a","content":"foo"}`, "1h", "1700000000000000"),
+		msg(`{"device":"b","content":"bar"}`, "60", "2024-01-01T00:00:00Z"),
+		msg(`not json`, "", "1"),
+		msg(`{"device":"a","content":"baz"}`, "", "1"),
+		msg(`{"device":"b","content":"buz"}`, "nope", "1"),
+		msg(`{"device":"c","content":"qux"}`, "", "1"),
+	}
+
+	idx := batch.Index()
+	groups, batchErr := w.groupStatements(batch, func(values []any) ([]byte, error) {
+		device := values[0].(genericValue).v.(string)
+		if device == "c" {
+			return nil, errors.New("no routing")
+		}
+		return []byte(device), nil
+	})
+
+	require.NotNil(t, batchErr)
+	var failed []int
+	batchErr.WalkMessagesIndexedBy(idx, func(i int, _ *service.Message, err error) bool {
+		if err != nil {
+			failed = append(failed, i)
+		}
+		return true
+	})
+	assert.Equal(t, []int{2, 4, 5}, failed)
+
+	require.Len(t, groups, 2)
+
+	require.Len(t, groups[0], 2)
+	assert.Equal(t, 0, groups[0][0].index)
+	assert.Equal(t, []any{genericValue{v: "a"}, genericValue{v: "foo"}, 3600, int64(1700000000000000)}, groups[0][0].values)
+	assert.Equal(t, 3, groups[0][1].index)
+	assert.Equal(t, []any{genericValue{v: "a"}, genericValue{v: "baz"}, 0, int64(1)}, groups[0][1].values)
+
+	require.Len(t, groups[1], 1)
+	assert.Equal(t, 1, groups[1][0].index)
+	assert.Equal(t, []any{genericValue{v: "b"}, genericValue{v: "bar"}, 60, int64(1704067200000000)}, groups[1][0].values)
+}
+
+func TestCassandraGroupStatementsUngrouped(t *testing.T) {
+	w := testCassandraWriter(t, `
+addresses: [ localhost:9042 ]
+query: 'INSERT INTO foo.bar (device) VALUES (?)'
+args_mapping: 'root = [ this.device ]'
+`)
+
+	batch := service.MessageBatch{
+		service.NewMessage([]byte(`{"device":"a"}`)),
+		service.NewMessage([]byte(`{"device":"b"}`)),
+		service.NewMessage([]byte(`{"device":"c"}`)),
+	}
+
+	groups, batchErr := w.groupStatements(batch, nil)
+	require.Nil(t, batchErr)
+	require.Len(t, groups, 1)
+	require.Len(t, groups[0], 3)
+	for i, stmt := range groups[0] {
+		assert.Equal(t, i, stmt.index)
+	}
+}
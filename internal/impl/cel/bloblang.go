@@ -0,0 +1,55 @@
+package cel
+
+import (
+	"github.com/google/cel-go/cel"
+
+	"github.com/benthosdev/benthos/v4/public/bloblang"
+)
+
+func init() {
+	if err := bloblang.RegisterMethodV2("cel",
+		bloblang.NewPluginSpec().
+			Category("Object & Array Manipulation").
+			Version("4.28.0").
+			Description("Executes a [Common Expression Language](https://github.com/google/cel-spec) expression against a value, which is available to the expression as the variable `this`, and returns the result.").
+			Example("", `root.allowed = this.cel("this.roles.exists(r, r == 'admin') || this.age >= 18")`, [2]string{
+				`{"roles":["user"],"age":21}`,
+				`{"allowed":true}`,
+			}, [2]string{
+				`{"roles":["admin"],"age":16}`,
+				`{"allowed":true}`,
+			}, [2]string{
+				`{"roles":[],"age":16}`,
+				`{"allowed":false}`,
+			}).
+			Example("Expressions can construct new values.", `root = this.cel("this.items.filter(i, i.quantity > 0).map(i, i.sku)")`, [2]string{
+				`{"items":[{"sku":"a","quantity":2},{"sku":"b","quantity":0}]}`,
+				`["a"]`,
+			}).
+			Param(bloblang.NewStringParam("expression").Description("The CEL expression to execute.")),
+		func(args *bloblang.ParsedParams) (bloblang.Method, error) {
+			expr, err := args.GetString("expression")
+			if err != nil {
+				return nil, err
+			}
+			env, err := newEnv(cel.Variable("this", cel.DynType))
+			if err != nil {
+				return nil, err
+			}
+			prg, err := compile(env, expr)
+			if err != nil {
+				return nil, err
+			}
+			return func(v any) (any, error) {
+				res, _, err := prg.Eval(map[string]any{
+					"this": sanitise(v),
+				})
+				if err != nil {
+					return nil, err
+				}
+				return toNative(res)
+			}, nil
+		}); err != nil {
+		panic(err)
+	}
+}
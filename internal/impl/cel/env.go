@@ -0,0 +1,114 @@
+package cel
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+	"github.com/google/cel-go/common/types/traits"
+	"github.com/google/cel-go/ext"
+)
+
+// newEnv creates a CEL environment with the standard extensions available to
+// all programs, and the provided variables declared as dynamic values.
+func newEnv(vars ...cel.EnvOption) (*cel.Env, error) {
+	opts := []cel.EnvOption{
+		// Numbers parsed from JSON documents might be integers or doubles.
+		cel.CrossTypeNumericComparisons(true),
+		ext.Strings(),
+		ext.Encoders(),
+		ext.Math(),
+		ext.Sets(),
+	}
+	opts = append(opts, vars...)
+	return cel.NewEnv(opts...)
+}
+
+// compile parses, checks and plans a CEL expression.
+func compile(env *cel.Env, expr string) (cel.Program, error) {
+	ast, iss := env.Compile(expr)
+	if err := iss.Err(); err != nil {
+		return nil, fmt.Errorf("failed to compile expression: %w", err)
+	}
+	prg, err := env.Program(ast, cel.EvalOptions(cel.OptOptimize))
+	if err != nil {
+		return nil, fmt.Errorf("failed to plan expression: %w", err)
+	}
+	return prg, nil
+}
+
+// sanitise converts values parsed from JSON documents into types that can be
+// adapted into CEL values, which does not support json.Number.
+func sanitise(v any) any {
+	switch t := v.(type) {
+	case json.Number:
+		if i, err := t.Int64(); err == nil {
+			return i
+		}
+		f, _ := t.Float64()
+		return f
+	case map[string]any:
+		m := make(map[string]any, len(t))
+		for k, e := range t {
+			m[k] = sanitise(e)
+		}
+		return m
+	case []any:
+		s := make([]any, len(t))
+		for i, e := range t {
+			s[i] = sanitise(e)
+		}
+		return s
+	}
+	return v
+}
+
+// toNative converts the result of a CEL program into a value that can be
+// stored within a message.
+func toNative(v ref.Val) (any, error) {
+	if types.IsError(v) {
+		if err, ok := v.Value().(error); ok {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%v", v)
+	}
+	switch t := v.(type) {
+	case types.Null:
+		return nil, nil
+	case types.Bytes:
+		return []byte(t), nil
+	case traits.Mapper:
+		m := map[string]any{}
+		for it := t.Iterator(); it.HasNext() == types.True; {
+			k := it.Next()
+			kv, err := toNative(k)
+			if err != nil {
+				return nil, err
+			}
+			key, ok := kv.(string)
+			if !ok {
+				key = fmt.Sprintf("%v", kv)
+			}
+			if m[key], err = toNative(t.Get(k)); err != nil {
+				return nil, err
+			}
+		}
+		return m, nil
+	case traits.Lister:
+		var s []any
+		for it := t.Iterator(); it.HasNext() == types.True; {
+			e, err := toNative(it.Next())
+			if err != nil {
+				return nil, err
+			}
+			s = append(s, e)
+		}
+		if s == nil {
+			s = []any{}
+		}
+		return s, nil
+	}
+	return v.Value(), nil
+}
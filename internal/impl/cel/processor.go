@@ -0,0 +1,171 @@
+package cel
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	cpFieldExpression = "expression"
+	cpFieldFile       = "file"
+)
+
+func celProcessorConfig() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Categories("Mapping").
+		Version("4.28.0").
+		Summary("Executes a [Common Expression Language](https://github.com/google/cel-spec) program for each message, replacing the contents of the message with the result.").
+		Description(`
+The following variables are available to programs:
+
+- `+"`this`"+`: The contents of the message parsed as JSON.
+- `+"`content`"+`: The raw contents of the message as bytes.
+- `+"`meta`"+`: A map of the metadata of the message.
+
+When the result of a program is a string or bytes value it becomes the raw contents of the message, otherwise the message is set to the structured result. If a program fails to evaluate the message is left unchanged and flagged as having failed, allowing you to use [standard processor error handling patterns](/docs/configuration/error_handling).
+
+Programs have access to the standard CEL functions and macros, as well as the strings, encoders, math and sets extensions of the [cel-go](https://github.com/google/cel-go) library, which this processor is implemented with. CEL expressions can also be executed within [Bloblang](/docs/guides/bloblang/about) mappings with the `+"[`cel` method](/docs/guides/bloblang/methods#cel)"+`, which is useful for filtering or routing messages with policies written in CEL.`).
+		Field(service.NewStringField(cpFieldExpression).
+			Description("An inline CEL program to execute. One of `"+cpFieldExpression+"` or `"+cpFieldFile+"` must be defined.").
+			Example(`this.amount > 100.0 && meta.region in ["eu", "us"]`).
+			Optional()).
+		Field(service.NewStringField(cpFieldFile).
+			Description("A file containing a CEL program to execute. One of `"+cpFieldExpression+"` or `"+cpFieldFile+"` must be defined.").
+			Optional()).
+		LintRule(fmt.Sprintf(`
+let exprLen = (this.%v | "").length()
+let fileLen = (this.%v | "").length()
+root = if $exprLen == 0 && $fileLen == 0 {
+  "either the expression or file field must be specified"
+} else if $exprLen > 0 && $fileLen > 0 {
+  "cannot specify both the expression and file fields"
+}`, cpFieldExpression, cpFieldFile)).
+		Example(
+			"Evaluate a policy",
+			"Policies written in CEL can be evaluated against messages, here we add the result of a policy to each message with a [`branch` processor](/docs/components/processors/branch).",
+			`
+pipeline:
+  processors:
+    - branch:
+        processors:
+          - cel:
+              expression: |
+                this.user.roles.exists(r, r == "admin") || this.resource.owner == this.user.id
+        result_map: root.allowed = this
+`,
+		).
+		Example(
+			"Transform a document",
+			"Programs can construct new documents from the fields of a message and its metadata.",
+			`
+pipeline:
+  processors:
+    - cel:
+        expression: |
+          {
+            "id": this.id,
+            "topic": meta.kafka_topic,
+            "skus": this.items.filter(i, i.quantity > 0).map(i, i.sku)
+          }
+`,
+		)
+}
+
+func init() {
+	err := service.RegisterProcessor(
+		"cel", celProcessorConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Processor, error) {
+			return newCELProcessorFromConfig(conf, mgr)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type celProcessor struct {
+	prg cel.Program
+}
+
+func newCELProcessorFromConfig(conf *service.ParsedConfig, mgr *service.Resources) (*celProcessor, error) {
+	expr, _ := conf.FieldString(cpFieldExpression)
+	file, _ := conf.FieldString(cpFieldFile)
+	if file == "" && expr == "" {
+		return nil, fmt.Errorf("either a `%v` or `%v` must be specified", cpFieldExpression, cpFieldFile)
+	}
+
+	if file != "" {
+		exprBytes, err := service.ReadFile(mgr.FS(), file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open target file: %w", err)
+		}
+		expr = string(exprBytes)
+	}
+
+	env, err := newEnv(
+		cel.Variable("this", cel.DynType),
+		cel.Variable("content", cel.BytesType),
+		cel.Variable("meta", cel.MapType(cel.StringType, cel.DynType)),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	prg, err := compile(env, expr)
+	if err != nil {
+		return nil, err
+	}
+	return &celProcessor{prg: prg}, nil
+}
+
+func (c *celProcessor) Process(ctx context.Context, msg *service.Message) (service.MessageBatch, error) {
+	content, err := msg.AsBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	meta := map[string]any{}
+	_ = msg.MetaWalkMut(func(k string, v any) error {
+		meta[k] = sanitise(v)
+		return nil
+	})
+
+	res, _, err := c.prg.ContextEval(ctx, map[string]any{
+		// Messages are only parsed when the program references them.
+		"this": func() ref.Val {
+			v, err := msg.AsStructured()
+			if err != nil {
+				return types.NewErr("failed to parse message as JSON: %v", err)
+			}
+			return types.DefaultTypeAdapter.NativeToValue(sanitise(v))
+		},
+		"content": content,
+		"meta":    meta,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	v, err := toNative(res)
+	if err != nil {
+		return nil, err
+	}
+	switch t := v.(type) {
+	case string:
+		msg.SetBytes([]byte(t))
+	case []byte:
+		msg.SetBytes(t)
+	default:
+		msg.SetStructuredMut(t)
+	}
+	return service.MessageBatch{msg}, nil
+}
+
+func (c *celProcessor) Close(ctx context.Context) error {
+	return nil
+}
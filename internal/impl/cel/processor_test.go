@@ -0,0 +1,130 @@
+package cel
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/bloblang"
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func TestProcessorStructured(t *testing.T) {
+	conf, err := celProcessorConfig().ParseYAML(`
+expression: |
+  {
+    "id": this.id,
+    "topic": meta.topic,
+    "skus": this.items.filter(i, i.quantity > 0).map(i, i.sku),
+    "large": this.items.exists(i, i.price > 10.5)
+  }
+`, nil)
+	require.NoError(t, err)
+
+	proc, err := newCELProcessorFromConfig(conf, service.MockResources())
+	require.NoError(t, err)
+
+	msg := service.NewMessage([]byte(`{"id":"foo","items":[{"sku":"a","quantity":2,"price":5},{"sku":"b","quantity":0,"price":20.5}]}`))
+	msg.MetaSetMut("topic", "orders")
+
+	res, err := proc.Process(context.Background(), msg)
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+
+	resBytes, err := res[0].AsBytes()
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"id":"foo","topic":"orders","skus":["a"],"large":true}`, string(resBytes))
+}
+
+func TestProcessorRawResults(t *testing.T) {
+	tests := []struct {
+		expression string
+		input      string
+		output     string
+	}{
+		{expression: `string(content).upperAscii()`, input: `hello world`, output: `HELLO WORLD`},
+		{expression: `content + b"!"`, input: `hello world`, output: `hello world!`},
+		{expression: `this.value * 2`, input: `{"value":21}`, output: `42`},
+		{expression: `this.value > 20`, input: `{"value":21}`, output: `true`},
+		{expression: `has(this.nope)`, input: `{"value":21}`, output: `false`},
+	}
+
+	for _, test := range tests {
+		conf, err := celProcessorConfig().ParseYAML(`expression: '`+test.expression+`'`, nil)
+		require.NoError(t, err, test.expression)
+
+		proc, err := newCELProcessorFromConfig(conf, service.MockResources())
+		require.NoError(t, err, test.expression)
+
+		res, err := proc.Process(context.Background(), service.NewMessage([]byte(test.input)))
+		require.NoError(t, err, test.expression)
+		require.Len(t, res, 1)
+
+		resBytes, err := res[0].AsBytes()
+		require.NoError(t, err)
+		assert.Equal(t, test.output, string(resBytes), test.expression)
+	}
+}
+
+func TestProcessorFile(t *testing.T) {
+	tmpDir := t.TempDir()
+	file := filepath.Join(tmpDir, "policy.cel")
+	require.NoError(t, os.WriteFile(file, []byte(`this.age >= 18`), 0o644))
+
+	conf, err := celProcessorConfig().ParseYAML(`file: `+file, nil)
+	require.NoError(t, err)
+
+	proc, err := newCELProcessorFromConfig(conf, service.MockResources())
+	require.NoError(t, err)
+
+	res, err := proc.Process(context.Background(), service.NewMessage([]byte(`{"age":17}`)))
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+
+	resBytes, err := res[0].AsBytes()
+	require.NoError(t, err)
+	assert.Equal(t, "false", string(resBytes))
+}
+
+func TestProcessorErrors(t *testing.T) {
+	conf, err := celProcessorConfig().ParseYAML(`expression: 'this.value +'`, nil)
+	require.NoError(t, err)
+
+	_, err = newCELProcessorFromConfig(conf, service.MockResources())
+	require.Error(t, err)
+
+	conf, err = celProcessorConfig().ParseYAML(`expression: 'this.value.size()'`, nil)
+	require.NoError(t, err)
+
+	proc, err := newCELProcessorFromConfig(conf, service.MockResources())
+	require.NoError(t, err)
+
+	_, err = proc.Process(context.Background(), service.NewMessage([]byte(`{"other":"foo"}`)))
+	require.Error(t, err)
+
+	_, err = proc.Process(context.Background(), service.NewMessage([]byte(`not json`)))
+	require.Error(t, err)
+}
+
+func TestBloblangMethod(t *testing.T) {
+	exec, err := bloblang.Parse(`root.allowed = this.cel("this.roles.exists(r, r == 'admin') || this.age >= 18")`)
+	require.NoError(t, err)
+
+	res, err := exec.Query(map[string]any{
+		"roles": []any{"user"},
+		"age":   16,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"allowed": false}, res)
+
+	res, err = exec.Query(map[string]any{
+		"roles": []any{"admin"},
+		"age":   16,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"allowed": true}, res)
+}
@@ -0,0 +1,151 @@
+package clickhouse
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/benthosdev/benthos/v4/internal/value"
+)
+
+// columnConverter converts a value from a structured message into a value
+// accepted by the driver for a given column type.
+type columnConverter func(v any) (any, error)
+
+func passthroughConverter(v any) (any, error) {
+	return v, nil
+}
+
+func timeFromValue(v any) (time.Time, error) {
+	if s, ok := v.(string); ok {
+		if t, err := time.Parse(time.DateOnly, s); err == nil {
+			return t, nil
+		}
+		if t, err := time.Parse(time.DateTime, s); err == nil {
+			return t, nil
+		}
+	}
+	return value.IGetTimestamp(v)
+}
+
+// unwrapType removes a type wrapper such as `Nullable(...)` from a column type,
+// returning the inner type and whether the wrapper was present.
+func unwrapType(chType, wrapper string) (string, bool) {
+	if strings.HasPrefix(chType, wrapper+"(") && strings.HasSuffix(chType, ")") {
+		return chType[len(wrapper)+1 : len(chType)-1], true
+	}
+	return chType, false
+}
+
+// converterFor returns a converter for values of a column type. Types that
+// are not recognised are passed to the driver as they are.
+func converterFor(chType string) columnConverter {
+	chType = strings.TrimSpace(chType)
+	if inner, ok := unwrapType(chType, "LowCardinality"); ok {
+		return converterFor(inner)
+	}
+	if inner, ok := unwrapType(chType, "Nullable"); ok {
+		innerFn := converterFor(inner)
+		return func(v any) (any, error) {
+			if v == nil {
+				return nil, nil
+			}
+			return innerFn(v)
+		}
+	}
+	if inner, ok := unwrapType(chType, "Array"); ok {
+		innerFn := converterFor(inner)
+		return func(v any) (any, error) {
+			arr, ok := v.([]any)
+			if !ok {
+				return nil, fmt.Errorf("expected an array, got %T", v)
+			}
+			res := make([]any, len(arr))
+			for i, e := range arr {
+				var err error
+				if res[i], err = innerFn(e); err != nil {
+					return nil, fmt.Errorf("index %v: %w", i, err)
+				}
+			}
+			return res, nil
+		}
+	}
+
+	baseType, _, _ := strings.Cut(chType, "(")
+	switch baseType {
+	case "String", "FixedString":
+		return func(v any) (any, error) {
+			return value.IToString(v), nil
+		}
+	case "Int8":
+		return func(v any) (any, error) { return value.IToInt8(v) }
+	case "Int16":
+		return func(v any) (any, error) { return value.IToInt16(v) }
+	case "Int32":
+		return func(v any) (any, error) { return value.IToInt32(v) }
+	case "Int64":
+		return func(v any) (any, error) { return value.IToInt(v) }
+	case "UInt8":
+		return func(v any) (any, error) { return value.IToUint8(v) }
+	case "UInt16":
+		return func(v any) (any, error) { return value.IToUint16(v) }
+	case "UInt32":
+		return func(v any) (any, error) { return value.IToUint32(v) }
+	case "UInt64":
+		return func(v any) (any, error) { return value.IToUint(v) }
+	case "Float32":
+		return func(v any) (any, error) { return value.IToFloat32(v) }
+	case "Float64":
+		return func(v any) (any, error) { return value.IToFloat64(v) }
+	case "Bool":
+		return func(v any) (any, error) { return value.IToBool(v) }
+	case "UUID":
+		return func(v any) (any, error) {
+			return value.IToString(v), nil
+		}
+	case "Date", "Date32", "DateTime", "DateTime64":
+		return func(v any) (any, error) {
+			return timeFromValue(v)
+		}
+	}
+	return passthroughConverter
+}
+
+// tableColumn is a column of the target table that is populated from the
+// message field of the same name.
+type tableColumn struct {
+	name     string
+	chType   string
+	nullable bool
+	convert  columnConverter
+}
+
+func newTableColumn(name, chType string) tableColumn {
+	inner, _ := unwrapType(strings.TrimSpace(chType), "LowCardinality")
+	_, nullable := unwrapType(inner, "Nullable")
+	return tableColumn{
+		name:     name,
+		chType:   chType,
+		nullable: nullable,
+		convert:  converterFor(chType),
+	}
+}
+
+func (c tableColumn) valueFrom(obj map[string]any) (any, error) {
+	v, exists := obj[c.name]
+	if !exists || v == nil {
+		if c.nullable {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("column %v is missing a value", c.name)
+	}
+	res, err := c.convert(v)
+	if err != nil {
+		return nil, fmt.Errorf("column %v (%v): %w", c.name, c.chType, err)
+	}
+	return res, nil
+}
+
+func quoteIdentifier(s string) string {
+	return "`" + strings.ReplaceAll(strings.ReplaceAll(s, `\`, `\\`), "`", "\\`") + "`"
+}
@@ -0,0 +1,60 @@
+package clickhouse
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestColumnConversion(t *testing.T) {
+	tests := []struct {
+		chType string
+		input  any
+		output any
+		errMsg string
+	}{
+		{chType: "String", input: 5.0, output: "5"},
+		{chType: "LowCardinality(String)", input: "foo", output: "foo"},
+		{chType: "Int8", input: 5.0, output: int8(5)},
+		{chType: "Int8", input: 500.0, errMsg: "column foo (Int8)"},
+		{chType: "Int64", input: "10", output: int64(10)},
+		{chType: "UInt32", input: 7.0, output: uint32(7)},
+		{chType: "Float32", input: 1.5, output: float32(1.5)},
+		{chType: "Bool", input: true, output: true},
+		{chType: "Nullable(Int32)", input: 3.0, output: int32(3)},
+		{chType: "Array(UInt8)", input: []any{1.0, 2.0}, output: []any{uint8(1), uint8(2)}},
+		{chType: "Array(UInt8)", input: "nope", errMsg: "expected an array"},
+		{chType: "Date", input: "2024-03-05", output: time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC)},
+		{chType: "DateTime64(3, 'UTC')", input: "2024-03-05T10:00:00Z", output: time.Date(2024, 3, 5, 10, 0, 0, 0, time.UTC)},
+		{chType: "Decimal(10, 2)", input: "1.25", output: "1.25"},
+	}
+
+	for _, test := range tests {
+		c := newTableColumn("foo", test.chType)
+		v, err := c.valueFrom(map[string]any{"foo": test.input})
+		if test.errMsg != "" {
+			require.Error(t, err, test.chType)
+			assert.Contains(t, err.Error(), test.errMsg, test.chType)
+			continue
+		}
+		require.NoError(t, err, test.chType)
+		assert.Equal(t, test.output, v, test.chType)
+	}
+}
+
+func TestColumnMissingValues(t *testing.T) {
+	v, err := newTableColumn("foo", "LowCardinality(Nullable(String))").valueFrom(map[string]any{})
+	require.NoError(t, err)
+	assert.Nil(t, v)
+
+	_, err = newTableColumn("foo", "String").valueFrom(map[string]any{"foo": nil})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "column foo is missing a value")
+}
+
+func TestQuoteIdentifier(t *testing.T) {
+	assert.Equal(t, "`foo`", quoteIdentifier("foo"))
+	assert.Equal(t, "`fo\\`o`", quoteIdentifier("fo`o"))
+}
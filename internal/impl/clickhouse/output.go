@@ -0,0 +1,359 @@
+package clickhouse
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/ClickHouse/clickhouse-go/v2"
+	"github.com/ClickHouse/clickhouse-go/v2/lib/driver"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	choFieldAddresses       = "addresses"
+	choFieldDatabase        = "database"
+	choFieldUsername        = "username"
+	choFieldPassword        = "password"
+	choFieldTable           = "table"
+	choFieldColumns         = "columns"
+	choFieldCompression     = "compression"
+	choFieldAsyncInsert     = "async_insert"
+	choFieldAsyncInsertOn   = "enabled"
+	choFieldAsyncInsertWait = "wait"
+	choFieldSettings        = "settings"
+	choFieldDialTimeout     = "dial_timeout"
+	choFieldTLS             = "tls"
+	choFieldBatching        = "batching"
+)
+
+func outputSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Services").
+		Version("4.28.0").
+		Summary("Inserts messages into a [ClickHouse](https://clickhouse.com/) table using the native protocol.").
+		Description(`
+Each batch of messages is inserted as a single native protocol insert, where rows are encoded into columnar blocks before being sent. This is considerably faster than inserting via the `+"`sql_insert`"+` output and so batching should be configured in order to make the most of it.
+
+### Column Mapping
+
+The columns of the table are read when connecting, and messages must be objects where each column is populated from the field of the same name, other fields are ignored. Values are converted to the type of their column, where integers, floats, booleans, strings, UUIDs, dates and timestamps (as RFC 3339 strings or unix timestamps), arrays and nullable columns are supported. Values of other column types are passed to the driver unchanged.
+
+Columns that are `+"`MATERIALIZED`, `ALIAS` or `EPHEMERAL`"+` are never inserted, and the field `+"`columns`"+` can be used in order to insert only a subset of columns, allowing the remaining columns to be populated by their defaults.
+
+### Async Inserts
+
+When `+"`async_insert.enabled`"+` is set the inserts are performed with the ClickHouse [asynchronous insert](https://clickhouse.com/docs/en/optimize/asynchronous-inserts) settings, allowing the server to buffer and combine inserts from many writers. Unless `+"`async_insert.wait`"+` is disabled the insert is only acknowledged once the data has been flushed by the server.`+service.OutputPerformanceDocs(true, true)).
+		Fields(
+			service.NewStringListField(choFieldAddresses).
+				Description("A list of native protocol addresses to connect to. Connections are balanced across the addresses.").
+				Example([]string{"localhost:9000"}),
+			service.NewStringField(choFieldDatabase).
+				Description("The database of the table.").
+				Default("default"),
+			service.NewStringField(choFieldUsername).
+				Description("The username to authenticate with.").
+				Default("default"),
+			service.NewStringField(choFieldPassword).
+				Description("The password to authenticate with.").
+				Default("").
+				Secret(),
+			service.NewStringField(choFieldTable).
+				Description("The table to insert to.").
+				Example("events"),
+			service.NewStringListField(choFieldColumns).
+				Description("An optional list of columns to insert. When empty all columns of the table that can be inserted are used.").
+				Default([]any{}).
+				Example([]string{"id", "name", "created_at"}),
+			service.NewStringEnumField(choFieldCompression, "none", "lz4", "zstd").
+				Description("The compression to use for blocks sent to the server.").
+				Default("lz4").
+				Advanced(),
+			service.NewObjectField(choFieldAsyncInsert,
+				service.NewBoolField(choFieldAsyncInsertOn).
+					Description("Whether to perform asynchronous inserts.").
+					Default(false),
+				service.NewBoolField(choFieldAsyncInsertWait).
+					Description("Whether to wait for asynchronous inserts to be flushed by the server before acknowledging messages. Disabling this may result in data loss.").
+					Default(true),
+			).
+				Description("Configures asynchronous inserts, where the server buffers inserted data before writing it."),
+			service.NewStringMapField(choFieldSettings).
+				Description("A map of [settings](https://clickhouse.com/docs/en/operations/settings/settings) to apply to inserts.").
+				Default(map[string]any{}).
+				Example(map[string]any{"insert_quorum": "2"}).
+				Advanced(),
+			service.NewDurationField(choFieldDialTimeout).
+				Description("The maximum period to wait when establishing a connection.").
+				Default("10s").
+				Advanced(),
+			service.NewTLSToggledField(choFieldTLS),
+			service.NewOutputMaxInFlightField(),
+			service.NewBatchPolicyField(choFieldBatching),
+		).
+		Example("Async Inserts", "Insert events into a table using asynchronous inserts, which allows many writers to insert small batches efficiently:", `
+output:
+  clickhouse:
+    addresses: [ localhost:9000 ]
+    table: events
+    async_insert:
+      enabled: true
+    batching:
+      count: 1000
+      period: 1s
+`)
+}
+
+func init() {
+	err := service.RegisterBatchOutput("clickhouse", outputSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (out service.BatchOutput, batchPolicy service.BatchPolicy, maxInFlight int, err error) {
+			if maxInFlight, err = conf.FieldMaxInFlight(); err != nil {
+				return
+			}
+			if batchPolicy, err = conf.FieldBatchPolicy(choFieldBatching); err != nil {
+				return
+			}
+			out, err = newOutputFromParsed(conf, mgr)
+			return
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type output struct {
+	log *service.Logger
+
+	opts     *clickhouse.Options
+	table    string
+	columns  []string
+	settings clickhouse.Settings
+
+	mut         sync.RWMutex
+	conn        driver.Conn
+	insertQuery string
+	tableCols   []tableColumn
+}
+
+func newOutputFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*output, error) {
+	o := &output{
+		log:      mgr.Logger(),
+		opts:     &clickhouse.Options{},
+		settings: clickhouse.Settings{},
+	}
+
+	var err error
+	if o.opts.Addr, err = conf.FieldStringList(choFieldAddresses); err != nil {
+		return nil, err
+	}
+	if len(o.opts.Addr) == 0 {
+		return nil, errors.New("at least one address must be specified")
+	}
+	if o.opts.Auth.Database, err = conf.FieldString(choFieldDatabase); err != nil {
+		return nil, err
+	}
+	if o.opts.Auth.Username, err = conf.FieldString(choFieldUsername); err != nil {
+		return nil, err
+	}
+	if o.opts.Auth.Password, err = conf.FieldString(choFieldPassword); err != nil {
+		return nil, err
+	}
+	if o.table, err = conf.FieldString(choFieldTable); err != nil {
+		return nil, err
+	}
+	if o.columns, err = conf.FieldStringList(choFieldColumns); err != nil {
+		return nil, err
+	}
+
+	compressStr, err := conf.FieldString(choFieldCompression)
+	if err != nil {
+		return nil, err
+	}
+	switch compressStr {
+	case "none":
+	case "lz4":
+		o.opts.Compression = &clickhouse.Compression{Method: clickhouse.CompressionLZ4}
+	case "zstd":
+		o.opts.Compression = &clickhouse.Compression{Method: clickhouse.CompressionZSTD}
+	default:
+		return nil, fmt.Errorf("compression type %v not recognised", compressStr)
+	}
+
+	if o.opts.DialTimeout, err = conf.FieldDuration(choFieldDialTimeout); err != nil {
+		return nil, err
+	}
+	tlsConf, tlsEnabled, err := conf.FieldTLSToggled(choFieldTLS)
+	if err != nil {
+		return nil, err
+	}
+	if tlsEnabled {
+		o.opts.TLS = tlsConf
+	}
+
+	settings, err := conf.FieldStringMap(choFieldSettings)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range settings {
+		o.settings[k] = v
+	}
+
+	aConf := conf.Namespace(choFieldAsyncInsert)
+	asyncEnabled, err := aConf.FieldBool(choFieldAsyncInsertOn)
+	if err != nil {
+		return nil, err
+	}
+	if asyncEnabled {
+		asyncWait, err := aConf.FieldBool(choFieldAsyncInsertWait)
+		if err != nil {
+			return nil, err
+		}
+		o.settings["async_insert"] = 1
+		o.settings["wait_for_async_insert"] = 0
+		if asyncWait {
+			o.settings["wait_for_async_insert"] = 1
+		}
+	}
+	return o, nil
+}
+
+// loadColumns reads the columns of the table that can be inserted.
+func (o *output) loadColumns(ctx context.Context, conn driver.Conn) ([]tableColumn, error) {
+	rows, err := conn.Query(ctx, "SELECT name, type, default_kind FROM system.columns WHERE database = ? AND table = ? ORDER BY position", o.opts.Auth.Database, o.table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	byName := map[string]tableColumn{}
+	var all []tableColumn
+	for rows.Next() {
+		var name, chType, defaultKind string
+		if err := rows.Scan(&name, &chType, &defaultKind); err != nil {
+			return nil, err
+		}
+		switch defaultKind {
+		case "MATERIALIZED", "ALIAS", "EPHEMERAL":
+			continue
+		}
+		c := newTableColumn(name, chType)
+		byName[name] = c
+		all = append(all, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(all) == 0 {
+		return nil, fmt.Errorf("table %v.%v does not exist or has no insertable columns", o.opts.Auth.Database, o.table)
+	}
+
+	if len(o.columns) == 0 {
+		return all, nil
+	}
+	cols := make([]tableColumn, 0, len(o.columns))
+	for _, name := range o.columns {
+		c, exists := byName[name]
+		if !exists {
+			return nil, fmt.Errorf("column %v does not exist or cannot be inserted", name)
+		}
+		cols = append(cols, c)
+	}
+	return cols, nil
+}
+
+func (o *output) Connect(ctx context.Context) error {
+	o.mut.Lock()
+	defer o.mut.Unlock()
+
+	if o.conn != nil {
+		return nil
+	}
+
+	conn, err := clickhouse.Open(o.opts)
+	if err != nil {
+		return err
+	}
+	if err := conn.Ping(ctx); err != nil {
+		_ = conn.Close()
+		return err
+	}
+
+	cols, err := o.loadColumns(ctx, conn)
+	if err != nil {
+		_ = conn.Close()
+		return fmt.Errorf("failed to read table columns: %w", err)
+	}
+
+	quoted := make([]string, len(cols))
+	for i, c := range cols {
+		quoted[i] = quoteIdentifier(c.name)
+	}
+	o.insertQuery = fmt.Sprintf("INSERT INTO %v.%v (%v)", quoteIdentifier(o.opts.Auth.Database), quoteIdentifier(o.table), strings.Join(quoted, ", "))
+	o.tableCols = cols
+	o.conn = conn
+	return nil
+}
+
+func (o *output) WriteBatch(ctx context.Context, batch service.MessageBatch) error {
+	o.mut.RLock()
+	conn, query, cols := o.conn, o.insertQuery, o.tableCols
+	o.mut.RUnlock()
+
+	if conn == nil {
+		return service.ErrNotConnected
+	}
+
+	rows := make([][]any, len(batch))
+	for i, msg := range batch {
+		structured, err := msg.AsStructured()
+		if err != nil {
+			return fmt.Errorf("message %v: %w", i, err)
+		}
+		obj, ok := structured.(map[string]any)
+		if !ok {
+			return fmt.Errorf("message %v: expected an object, got %T", i, structured)
+		}
+
+		row := make([]any, len(cols))
+		for j, c := range cols {
+			if row[j], err = c.valueFrom(obj); err != nil {
+				return fmt.Errorf("message %v: %w", i, err)
+			}
+		}
+		rows[i] = row
+	}
+
+	if len(o.settings) > 0 {
+		ctx = clickhouse.Context(ctx, clickhouse.WithSettings(o.settings))
+	}
+
+	insert, err := conn.PrepareBatch(ctx, query)
+	if err != nil {
+		return err
+	}
+	for _, row := range rows {
+		if err := insert.Append(row...); err != nil {
+			_ = insert.Abort()
+			return err
+		}
+	}
+	return insert.Send()
+}
+
+func (o *output) Close(ctx context.Context) error {
+	o.mut.Lock()
+	defer o.mut.Unlock()
+
+	if o.conn == nil {
+		return nil
+	}
+	err := o.conn.Close()
+	o.conn = nil
+	return err
+}
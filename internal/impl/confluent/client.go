@@ -73,7 +73,7 @@ type SchemaReference struct {
 func (c *schemaRegistryClient) GetSchemaByID(ctx context.Context, id int) (resPayload SchemaInfo, err error) {
 	var resCode int
 	var resBody []byte
-	if resCode, resBody, err = c.doRequest(ctx, "GET", fmt.Sprintf("/schemas/ids/%v", id)); err != nil {
+	if resCode, resBody, err = c.doRequest(ctx, "GET", fmt.Sprintf("/schemas/ids/%v", id), nil); err != nil {
 		err = fmt.Errorf("request failed for schema '%v': %v", id, err)
 		c.mgr.Logger().Errorf(err.Error())
 		return
@@ -108,7 +108,7 @@ func (c *schemaRegistryClient) GetSchemaBySubjectAndVersion(ctx context.Context,
 
 	var resCode int
 	var resBody []byte
-	if resCode, resBody, err = c.doRequest(ctx, "GET", path); err != nil {
+	if resCode, resBody, err = c.doRequest(ctx, "GET", path, nil); err != nil {
 		err = fmt.Errorf("request failed for schema subject '%v': %v", subject, err)
 		c.mgr.Logger().Errorf(err.Error())
 		return
@@ -133,6 +133,43 @@ func (c *schemaRegistryClient) GetSchemaBySubjectAndVersion(ctx context.Context,
 	return
 }
 
+// CreateSchema registers a schema under a subject, returning the ID of the
+// schema, which is the ID of an existing version when the schema has been
+// registered before.
+func (c *schemaRegistryClient) CreateSchema(ctx context.Context, subject, schemaType, schema string) (id int, err error) {
+	var reqBody []byte
+	if reqBody, err = json.Marshal(map[string]string{
+		"schemaType": schemaType,
+		"schema":     schema,
+	}); err != nil {
+		return
+	}
+
+	var resCode int
+	var resBody []byte
+	path := fmt.Sprintf("/subjects/%s/versions", url.PathEscape(subject))
+	if resCode, resBody, err = c.doRequest(ctx, "POST", path, reqBody); err != nil {
+		err = fmt.Errorf("request failed to register schema subject '%v': %v", subject, err)
+		c.mgr.Logger().Errorf(err.Error())
+		return
+	}
+
+	if resCode == http.StatusNotFound {
+		err = fmt.Errorf("schema subject '%v' could not be registered", subject)
+		c.mgr.Logger().Errorf(err.Error())
+		return
+	}
+
+	var resPayload struct {
+		ID int `json:"id"`
+	}
+	if err = json.Unmarshal(resBody, &resPayload); err != nil {
+		c.mgr.Logger().Errorf("failed to parse response for registering schema subject '%v': %v", subject, err)
+		return
+	}
+	return resPayload.ID, nil
+}
+
 type RefWalkFn func(ctx context.Context, name string, info SchemaInfo) error
 
 // For each reference provided the schema info is obtained and the provided
@@ -168,22 +205,36 @@ func (c *schemaRegistryClient) walkReferencesTracked(ctx context.Context, seen m
 	return nil
 }
 
-func (c *schemaRegistryClient) doRequest(ctx context.Context, verb, reqPath string) (resCode int, resBody []byte, err error) {
+func (c *schemaRegistryClient) doRequest(ctx context.Context, verb, reqPath string, reqBody []byte) (resCode int, resBody []byte, err error) {
 	reqURL := *c.schemaRegistryBaseURL
 	if reqURL.Path, err = url.JoinPath(reqURL.Path, reqPath); err != nil {
 		return
 	}
 
-	var req *http.Request
-	if req, err = http.NewRequestWithContext(ctx, verb, reqURL.String(), http.NoBody); err != nil {
-		return
-	}
-	req.Header.Add("Accept", "application/vnd.schemaregistry.v1+json")
-	if err = c.requestSigner(c.mgr.FS(), req); err != nil {
+	newReq := func() (req *http.Request, err error) {
+		var body io.Reader = http.NoBody
+		if reqBody != nil {
+			body = bytes.NewReader(reqBody)
+		}
+		if req, err = http.NewRequestWithContext(ctx, verb, reqURL.String(), body); err != nil {
+			return
+		}
+		req.Header.Add("Accept", "application/vnd.schemaregistry.v1+json")
+		if reqBody != nil {
+			req.Header.Add("Content-Type", "application/vnd.schemaregistry.v1+json")
+		}
+		err = c.requestSigner(c.mgr.FS(), req)
 		return
 	}
 
 	for i := 0; i < 3; i++ {
+		// Requests are created for each attempt as bodies can only be read
+		// once.
+		var req *http.Request
+		if req, err = newReq(); err != nil {
+			return
+		}
+
 		var res *http.Response
 		if res, err = c.client.Do(req); err != nil {
 			c.mgr.Logger().Errorf("request failed: %v", err)
@@ -0,0 +1,159 @@
+package confluent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	oeFieldEnabled       = "enabled"
+	oeFieldURL           = "url"
+	oeFieldSubject       = "subject"
+	oeFieldVersion       = "version"
+	oeFieldRefreshPeriod = "refresh_period"
+	oeFieldAvroRawJSON   = "avro_raw_json"
+	oeFieldTLS           = "tls"
+)
+
+// OutputEncodingField returns a config field for encoding messages with the
+// schemas of a schema registry, which is intended for outputs that write to
+// topics with schemas registered under the topic name strategy.
+func OutputEncodingField(name string) *service.ConfigField {
+	fields := []*service.ConfigField{
+		service.NewBoolField(oeFieldEnabled).
+			Description("Whether to encode messages with schemas from a schema registry.").
+			Default(false),
+		service.NewStringField(oeFieldURL).
+			Description("The base URL of the schema registry service.").
+			Example("http://localhost:8081").
+			Default(""),
+		service.NewInterpolatedStringField(oeFieldSubject).
+			Description("An optional subject to obtain the schema of each message from. When empty the subject is the topic of the message with the suffix `-value`, following the topic name strategy.").
+			Example(`${! meta("schema_subject") }`).
+			Default(""),
+		service.NewStringField(oeFieldVersion).
+			Description("The version of the subject schema to encode messages with, which is either `latest` or a specific version number.").
+			Example("3").
+			Default("latest"),
+		service.NewStringField(oeFieldRefreshPeriod).
+			Description("The period after which the schema of a subject is refreshed by polling the schema registry service.").
+			Default("10m"),
+		service.NewBoolField(oeFieldAvroRawJSON).
+			Description("Whether messages encoded in Avro format should be parsed as normal JSON rather than Avro JSON, with the same behaviour as the `avro_raw_json` field of the `schema_registry_encode` processor.").
+			Default(false),
+	}
+	fields = append(fields, service.NewHTTPRequestAuthSignerFields()...)
+	fields = append(fields, service.NewTLSField(oeFieldTLS))
+
+	return service.NewObjectField(name, fields...).
+		Description("Encode structured messages with Avro, Protobuf or JSON schemas obtained from a [Confluent Schema Registry service](https://docs.confluent.io/platform/current/schema-registry/index.html), prefixing the encoded messages with the magic byte and schema ID expected by registry aware consumers. Messages are encoded in the same way as the [`schema_registry_encode` processor](/docs/components/processors/schema_registry_encode), and a message that fails to encode fails the write.").
+		Advanced().
+		Version("4.28.0")
+}
+
+// OutputEncoder encodes messages with the schemas of a schema registry.
+type OutputEncoder struct {
+	enc     *schemaRegistryEncoder
+	subject *service.InterpolatedString
+}
+
+// OutputEncoderFromParsed creates an encoder from a parsed config of the field
+// returned by OutputEncodingField, or returns nil when encoding is disabled.
+func OutputEncoderFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*OutputEncoder, error) {
+	enabled, err := conf.FieldBool(oeFieldEnabled)
+	if err != nil || !enabled {
+		return nil, err
+	}
+
+	urlStr, err := conf.FieldString(oeFieldURL)
+	if err != nil {
+		return nil, err
+	}
+	if urlStr == "" {
+		return nil, errors.New("a schema registry url must be provided when encoding is enabled")
+	}
+
+	var version *int
+	versionStr, err := conf.FieldString(oeFieldVersion)
+	if err != nil {
+		return nil, err
+	}
+	if versionStr != "latest" {
+		v, err := strconv.Atoi(versionStr)
+		if err != nil || v <= 0 {
+			return nil, fmt.Errorf("schema version must be either latest or a positive integer, got %v", versionStr)
+		}
+		version = &v
+	}
+
+	o := &OutputEncoder{}
+	if subjectStr, _ := conf.FieldString(oeFieldSubject); subjectStr != "" {
+		if o.subject, err = conf.FieldInterpolatedString(oeFieldSubject); err != nil {
+			return nil, err
+		}
+	}
+
+	avroRawJSON, err := conf.FieldBool(oeFieldAvroRawJSON)
+	if err != nil {
+		return nil, err
+	}
+	refreshPeriodStr, err := conf.FieldString(oeFieldRefreshPeriod)
+	if err != nil {
+		return nil, err
+	}
+	refreshPeriod, err := time.ParseDuration(refreshPeriodStr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse refresh period: %v", err)
+	}
+	refreshTicker := refreshPeriod / 10
+	if refreshTicker < time.Second {
+		refreshTicker = time.Second
+	}
+	authSigner, err := conf.HTTPRequestAuthSignerFromParsed()
+	if err != nil {
+		return nil, err
+	}
+	tlsConf, err := conf.FieldTLS(oeFieldTLS)
+	if err != nil {
+		return nil, err
+	}
+
+	if o.enc, err = newSchemaRegistryEncoder(urlStr, authSigner, tlsConf, nil, avroConfig{rawJSON: avroRawJSON}, refreshPeriod, refreshTicker, mgr); err != nil {
+		return nil, err
+	}
+
+	// Schemas are only ever requested whilst holding the request mutex.
+	o.enc.requestMut.Lock()
+	o.enc.version = version
+	o.enc.requestMut.Unlock()
+	return o, nil
+}
+
+// Encode returns the contents of a message encoded with the schema of its
+// subject, which is derived from the topic of the message unless a subject
+// has been configured. The message itself is not modified.
+func (o *OutputEncoder) Encode(msg *service.Message, topic string) ([]byte, error) {
+	subject := topic + "-value"
+	if o.subject != nil {
+		var err error
+		if subject, err = o.subject.TryString(msg); err != nil {
+			return nil, fmt.Errorf("subject interpolation error: %w", err)
+		}
+	}
+
+	encoded := msg.Copy()
+	if err := o.enc.encodeMessage(encoded, subject); err != nil {
+		return nil, fmt.Errorf("failed to encode message with schema subject %v: %w", subject, err)
+	}
+	return encoded.AsBytes()
+}
+
+// Close stops the encoder from refreshing schemas.
+func (o *OutputEncoder) Close(ctx context.Context) error {
+	return o.enc.Close(ctx)
+}
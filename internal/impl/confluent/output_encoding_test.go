@@ -0,0 +1,113 @@
+package confluent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func TestOutputEncoder(t *testing.T) {
+	schemaPayload, err := json.Marshal(struct {
+		Schema string `json:"schema"`
+		ID     int    `json:"id"`
+	}{
+		Schema: testSchema,
+		ID:     3,
+	})
+	require.NoError(t, err)
+
+	var requested []string
+	urlStr := runSchemaRegistryServer(t, func(path string) ([]byte, error) {
+		requested = append(requested, path)
+		switch path {
+		case "/subjects/foo-value/versions/latest", "/subjects/bar/versions/2":
+			return schemaPayload, nil
+		}
+		return nil, errors.New("nope")
+	})
+
+	spec := service.NewConfigSpec().Field(OutputEncodingField("schema_registry"))
+
+	parseEncoder := func(t *testing.T, conf string) *OutputEncoder {
+		t.Helper()
+
+		pConf, err := spec.ParseYAML(conf, nil)
+		require.NoError(t, err)
+
+		enc, err := OutputEncoderFromParsed(pConf.Namespace("schema_registry"), service.MockResources())
+		require.NoError(t, err)
+		if enc != nil {
+			t.Cleanup(func() {
+				_ = enc.Close(context.Background())
+			})
+		}
+		return enc
+	}
+
+	assert.Nil(t, parseEncoder(t, `{}`))
+
+	input := `{"Name":"foo","MaybeHobby":null}`
+	expected := "\x00\x00\x00\x00\x03\x06foo\x00\x00"
+
+	enc := parseEncoder(t, `
+schema_registry:
+  enabled: true
+  url: `+urlStr+`
+`)
+	msg := service.NewMessage([]byte(input))
+	out, err := enc.Encode(msg, "foo")
+	require.NoError(t, err)
+	assert.Equal(t, expected, string(out))
+
+	// The original message is not modified.
+	mBytes, err := msg.AsBytes()
+	require.NoError(t, err)
+	assert.Equal(t, input, string(mBytes))
+
+	enc = parseEncoder(t, `
+schema_registry:
+  enabled: true
+  url: `+urlStr+`
+  subject: ${! meta("subject") }
+  version: 2
+`)
+	msg = service.NewMessage([]byte(input))
+	msg.MetaSetMut("subject", "bar")
+	out, err = enc.Encode(msg, "foo")
+	require.NoError(t, err)
+	assert.Equal(t, expected, string(out))
+
+	_, err = enc.Encode(service.NewMessage([]byte(`{"Name":5}`)), "foo")
+	require.Error(t, err)
+
+	assert.Equal(t, []string{"/subjects/foo-value/versions/latest", "/subjects/bar/versions/2"}, requested)
+}
+
+func TestOutputEncoderConfigErrors(t *testing.T) {
+	spec := service.NewConfigSpec().Field(OutputEncodingField("schema_registry"))
+
+	for _, conf := range []string{
+		`
+schema_registry:
+  enabled: true
+`,
+		`
+schema_registry:
+  enabled: true
+  url: http://localhost:8081
+  version: nope
+`,
+	} {
+		pConf, err := spec.ParseYAML(conf, nil)
+		require.NoError(t, err)
+
+		_, err = OutputEncoderFromParsed(pConf.Namespace("schema_registry"), service.MockResources())
+		require.Error(t, err, conf)
+	}
+}
@@ -0,0 +1,212 @@
+package confluent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	sipFieldFormat         = "format"
+	sipFieldSubject        = "subject"
+	sipFieldAvroRecordName = "avro_record_name"
+	sipFieldRegistry       = "schema_registry"
+	sipFieldRegistryURL    = "url"
+	sipFieldRegistryTLS    = "tls"
+)
+
+func schemaInferProcSpec() *service.ConfigSpec {
+	registryFields := []*service.ConfigField{
+		service.NewURLField(sipFieldRegistryURL).Description("The base URL of the schema registry service."),
+	}
+	registryFields = append(registryFields, service.NewHTTPRequestAuthSignerFields()...)
+	registryFields = append(registryFields, service.NewTLSField(sipFieldRegistryTLS))
+
+	return service.NewConfigSpec().
+		Beta().
+		Version("4.28.0").
+		Categories("Parsing", "Integration").
+		Summary("Infers a schema from the structure of messages, optionally registering each evolution of the schema with a Confluent Schema Registry service.").
+		Description(`
+Each structured message observed is merged into the schema inferred for its `+"`subject`"+`, where the schema is widened in order to accept every message observed so far. Fields are required until a message is observed without them, numbers are integers until a message is observed with a fractional number, and values of differing types result in a union of types. The contents of messages are not modified.
+
+The following metadata fields are added to each message:
+
+- `+"`inferred_schema`"+`: The schema of the subject after observing the message, either a JSON Schema or an Avro schema depending on the `+"`format`"+`.
+- `+"`inferred_schema_version`"+`: A number incremented each time the schema of the subject is widened, starting at one.
+- `+"`inferred_schema_widened`"+`: Whether the message widened the schema of the subject, which can be used to route messages that would otherwise be rejected by typed sinks.
+- `+"`inferred_schema_id`"+`: The ID of the schema within the schema registry, when configured.
+
+### Schema Registry
+
+When a `+"`schema_registry`"+` is configured each version of the schema is registered under the subject, and therefore the subject must be a valid subject name. When registration fails, such as when the registry rejects a schema that is incompatible with previous versions of the subject, the message is flagged as having failed and registration is attempted again with the next message, allowing you to use [standard processor error handling patterns](/docs/configuration/error_handling).
+
+Schemas are inferred from the messages observed by each instance of this processor only and are not loaded from the registry, and so instances of Benthos processing messages of the same subject may infer and register differing schemas.`).
+		Fields(
+			service.NewStringEnumField(sipFieldFormat, "json_schema", "avro").
+				Description("The format of the inferred schema.").
+				Default("json_schema"),
+			service.NewInterpolatedStringField(sipFieldSubject).
+				Description("An interpolated string identifying the schema that a message belongs to, where a schema is inferred for each subject separately.").
+				Default("default").
+				Example(`${! meta("kafka_topic") }-value`),
+			service.NewStringField(sipFieldAvroRecordName).
+				Description("The name of the root record of Avro schemas, where nested records are named by appending the names of their fields.").
+				Default("Record").
+				Advanced(),
+			service.NewObjectField(sipFieldRegistry, registryFields...).
+				Description("An optional schema registry to register inferred schemas with.").
+				Optional(),
+		).
+		Example("Landing Untyped JSON", "Here we infer an Avro schema from JSON documents consumed from a topic and register it, encoding the documents with the registered schema so that they can be landed into typed sinks.", `
+pipeline:
+  processors:
+    - schema_infer:
+        format: avro
+        subject: ${! meta("kafka_topic") }-value
+        schema_registry:
+          url: http://localhost:8081
+    - schema_registry_encode:
+        url: http://localhost:8081
+        subject: ${! meta("kafka_topic") }-value
+        avro_raw_json: true
+        refresh_period: 10s
+`)
+}
+
+func init() {
+	err := service.RegisterProcessor(
+		"schema_infer", schemaInferProcSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Processor, error) {
+			return newSchemaInferProcFromConfig(conf, mgr)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type schemaInferProc struct {
+	format     string
+	subject    *service.InterpolatedString
+	recordName string
+	client     *schemaRegistryClient
+
+	schemas map[string]*inferredSchema
+	mut     sync.Mutex
+}
+
+func newSchemaInferProcFromConfig(conf *service.ParsedConfig, mgr *service.Resources) (*schemaInferProc, error) {
+	p := &schemaInferProc{
+		schemas: map[string]*inferredSchema{},
+	}
+
+	var err error
+	if p.format, err = conf.FieldString(sipFieldFormat); err != nil {
+		return nil, err
+	}
+	if p.subject, err = conf.FieldInterpolatedString(sipFieldSubject); err != nil {
+		return nil, err
+	}
+	if p.recordName, err = conf.FieldString(sipFieldAvroRecordName); err != nil {
+		return nil, err
+	}
+	if p.recordName == "" || avroName(p.recordName) != p.recordName {
+		return nil, fmt.Errorf("avro record name %q is not a valid name", p.recordName)
+	}
+
+	if conf.Contains(sipFieldRegistry) {
+		rConf := conf.Namespace(sipFieldRegistry)
+		urlStr, err := rConf.FieldString(sipFieldRegistryURL)
+		if err != nil {
+			return nil, err
+		}
+		authSigner, err := rConf.HTTPRequestAuthSignerFromParsed()
+		if err != nil {
+			return nil, err
+		}
+		tlsConf, err := rConf.FieldTLS(sipFieldRegistryTLS)
+		if err != nil {
+			return nil, err
+		}
+		if p.client, err = newSchemaRegistryClient(urlStr, authSigner, tlsConf, mgr); err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+func (p *schemaInferProc) render(s *inferredSchema) (string, error) {
+	var doc any
+	if p.format == "avro" {
+		doc = s.root.avroSchema(p.recordName, false)
+	} else {
+		jDoc := s.root.jsonSchema()
+		jDoc["$schema"] = "http://json-schema.org/draft-07/schema#"
+		doc = jDoc
+	}
+	b, err := json.Marshal(doc)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+func (p *schemaInferProc) Process(ctx context.Context, msg *service.Message) (service.MessageBatch, error) {
+	subject, err := p.subject.TryString(msg)
+	if err != nil {
+		return nil, fmt.Errorf("subject interpolation error: %w", err)
+	}
+
+	v, err := msg.AsStructured()
+	if err != nil {
+		return nil, err
+	}
+
+	p.mut.Lock()
+	defer p.mut.Unlock()
+
+	s, exists := p.schemas[subject]
+	if !exists {
+		s = &inferredSchema{}
+		p.schemas[subject] = s
+	}
+
+	widened := s.root.merge(v)
+	if widened {
+		if s.schema, err = p.render(s); err != nil {
+			return nil, err
+		}
+		s.version++
+	}
+
+	msg.MetaSetMut("inferred_schema", s.schema)
+	msg.MetaSetMut("inferred_schema_version", int64(s.version))
+	msg.MetaSetMut("inferred_schema_widened", widened)
+
+	if p.client != nil {
+		if s.registeredVersion != s.version {
+			if subject == "" {
+				return nil, errors.New("schemas cannot be registered with an empty subject")
+			}
+			schemaType := "JSON"
+			if p.format == "avro" {
+				schemaType = "AVRO"
+			}
+			id, err := p.client.CreateSchema(ctx, subject, schemaType, s.schema)
+			if err != nil {
+				return nil, err
+			}
+			s.registeredVersion, s.registeredID = s.version, id
+		}
+		msg.MetaSetMut("inferred_schema_id", int64(s.registeredID))
+	}
+	return service.MessageBatch{msg}, nil
+}
+
+func (p *schemaInferProc) Close(ctx context.Context) error {
+	return nil
+}
@@ -0,0 +1,192 @@
+package confluent
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func testSchemaInferProc(t *testing.T, confStr string) *schemaInferProc {
+	t.Helper()
+
+	conf, err := schemaInferProcSpec().ParseYAML(confStr, nil)
+	require.NoError(t, err)
+
+	proc, err := newSchemaInferProcFromConfig(conf, service.MockResources())
+	require.NoError(t, err)
+	return proc
+}
+
+type inferResult struct {
+	schema  string
+	version int64
+	widened bool
+}
+
+func inferSchema(t *testing.T, proc *schemaInferProc, doc string) inferResult {
+	t.Helper()
+
+	res, err := proc.Process(context.Background(), service.NewMessage([]byte(doc)))
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+
+	var r inferResult
+	v, _ := res[0].MetaGetMut("inferred_schema")
+	r.schema, _ = v.(string)
+	v, _ = res[0].MetaGetMut("inferred_schema_version")
+	r.version, _ = v.(int64)
+	v, _ = res[0].MetaGetMut("inferred_schema_widened")
+	r.widened, _ = v.(bool)
+	return r
+}
+
+func TestSchemaInferJSONSchema(t *testing.T) {
+	proc := testSchemaInferProc(t, `{}`)
+
+	r := inferSchema(t, proc, `{"id":1,"name":"foo","tags":["a"]}`)
+	assert.True(t, r.widened)
+	assert.Equal(t, int64(1), r.version)
+	assert.JSONEq(t, `{
+  "$schema": "http://json-schema.org/draft-07/schema#",
+  "type": "object",
+  "properties": {
+    "id": {"type": "integer"},
+    "name": {"type": "string"},
+    "tags": {"type": "array", "items": {"type": "string"}}
+  },
+  "required": ["id", "name", "tags"]
+}`, r.schema)
+
+	r = inferSchema(t, proc, `{"id":2,"name":"bar","tags":[]}`)
+	assert.False(t, r.widened)
+	assert.Equal(t, int64(1), r.version)
+
+	r = inferSchema(t, proc, `{"id":2.5,"name":null,"extra":{"a":true}}`)
+	assert.True(t, r.widened)
+	assert.Equal(t, int64(2), r.version)
+	assert.JSONEq(t, `{
+  "$schema": "http://json-schema.org/draft-07/schema#",
+  "type": "object",
+  "properties": {
+    "id": {"type": "number"},
+    "name": {"type": ["null", "string"]},
+    "tags": {"type": "array", "items": {"type": "string"}},
+    "extra": {"type": "object", "properties": {"a": {"type": "boolean"}}, "required": ["a"]}
+  },
+  "required": ["id", "name"]
+}`, r.schema)
+
+	// Integers are accepted by a number type.
+	r = inferSchema(t, proc, `{"id":3,"name":"baz"}`)
+	assert.False(t, r.widened)
+}
+
+func TestSchemaInferAvro(t *testing.T) {
+	proc := testSchemaInferProc(t, `
+format: avro
+subject: ${! meta("topic") }
+`)
+
+	r := inferSchema(t, proc, `{"id":1,"user name":"foo","address":{"city":"x"}}`)
+	assert.JSONEq(t, `{
+  "type": "record",
+  "name": "Record",
+  "fields": [
+    {"name": "address", "type": {"type": "record", "name": "Record_address", "fields": [
+      {"name": "city", "type": "string"}
+    ]}},
+    {"name": "id", "type": "long"},
+    {"name": "user_name", "type": "string"}
+  ]
+}`, r.schema)
+
+	r = inferSchema(t, proc, `{"id":1,"user name":3,"items":[1.5]}`)
+	assert.True(t, r.widened)
+	assert.JSONEq(t, `{
+  "type": "record",
+  "name": "Record",
+  "fields": [
+    {"name": "address", "type": ["null", {"type": "record", "name": "Record_address", "fields": [
+      {"name": "city", "type": "string"}
+    ]}], "default": null},
+    {"name": "id", "type": "long"},
+    {"name": "user_name", "type": ["long", "string"]},
+    {"name": "items", "type": ["null", {"type": "array", "items": "double"}], "default": null}
+  ]
+}`, r.schema)
+
+	// Subjects are inferred separately.
+	msg := service.NewMessage([]byte(`{"other":true}`))
+	msg.MetaSetMut("topic", "other")
+	res, err := proc.Process(context.Background(), msg)
+	require.NoError(t, err)
+	v, _ := res[0].MetaGetMut("inferred_schema_version")
+	assert.Equal(t, int64(1), v)
+
+	_, err = proc.Process(context.Background(), service.NewMessage([]byte(`not structured`)))
+	require.Error(t, err)
+}
+
+func TestSchemaInferRegistry(t *testing.T) {
+	var registered []map[string]string
+	reject := false
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" || r.URL.Path != "/subjects/foo-value/versions" {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		if reject {
+			http.Error(w, "incompatible schema", http.StatusConflict)
+			return
+		}
+		b, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		var body map[string]string
+		if err := json.Unmarshal(b, &body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		registered = append(registered, body)
+		_, _ = w.Write([]byte(`{"id":` + strconv.Itoa(len(registered)) + `}`))
+	}))
+	t.Cleanup(ts.Close)
+
+	proc := testSchemaInferProc(t, `
+subject: foo-value
+schema_registry:
+  url: `+ts.URL+`
+`)
+
+	for _, doc := range []string{`{"a":1}`, `{"a":2}`} {
+		res, err := proc.Process(context.Background(), service.NewMessage([]byte(doc)))
+		require.NoError(t, err)
+		id, _ := res[0].MetaGetMut("inferred_schema_id")
+		assert.Equal(t, int64(1), id)
+	}
+	require.Len(t, registered, 1)
+	assert.Equal(t, "JSON", registered[0]["schemaType"])
+
+	reject = true
+	_, err := proc.Process(context.Background(), service.NewMessage([]byte(`{"a":"x"}`)))
+	require.Error(t, err)
+
+	// Registration is attempted again with the next message.
+	reject = false
+	res, err := proc.Process(context.Background(), service.NewMessage([]byte(`{"a":"y"}`)))
+	require.NoError(t, err)
+	id, _ := res[0].MetaGetMut("inferred_schema_id")
+	assert.Equal(t, int64(2), id)
+	require.Len(t, registered, 2)
+}
@@ -41,6 +41,10 @@ For example, the union schema ` + "`[\"null\",\"string\",\"Foo\"]`, where `Foo`"
 - a ` + "`Foo` instance as `{\"Foo\": {...}}`, where `{...}` indicates the JSON encoding of a `Foo`" + ` instance.
 
 However, it is possible to instead create documents in [standard/raw JSON format](https://pkg.go.dev/github.com/linkedin/goavro/v2#NewCodecForStandardJSONFull) by setting the field ` + "[`avro_raw_json`](#avro_raw_json) to `true`" + `.
+### Avro Logical Types
+
+By default values of Avro logical types are represented in the form of their underlying types, e.g. a number of milliseconds since the unix epoch for ` + "`timestamp-millis`" + `. Setting ` + "[`avro_logical_types`](#avro_logical_types) to `true`" + ` represents them with readable values such as RFC 3339 timestamps and decimal strings instead.
+
 ### Protobuf Format
 
 This processor decodes protobuf messages to JSON documents, you can read more about JSON mapping of protobuf messages here: https://developers.google.com/protocol-buffers/docs/proto3#json
@@ -48,6 +52,14 @@ This processor decodes protobuf messages to JSON documents, you can read more ab
 		Field(service.NewBoolField("avro_raw_json").
 			Description("Whether Avro messages should be decoded into normal JSON (\"json that meets the expectations of regular internet json\") rather than [Avro JSON](https://avro.apache.org/docs/current/specification/_print/#json-encoding). If `true` the schema returned from the subject should be decoded as [standard json](https://pkg.go.dev/github.com/linkedin/goavro/v2#NewCodecForStandardJSONFull) instead of as [avro json](https://pkg.go.dev/github.com/linkedin/goavro/v2#NewCodec). There is a [comment in goavro](https://github.com/linkedin/goavro/blob/5ec5a5ee7ec82e16e6e2b438d610e1cab2588393/union.go#L224-L249), the [underlining library used for avro serialization](https://github.com/linkedin/goavro), that explains in more detail the difference between the standard json and avro json.").
 			Advanced().Default(false)).
+		Field(avroLogicalTypesField()).
+		Field(avroUnionNamingField()).
+		Field(service.NewStringField("schema_metadata").
+			Description("An optional metadata key to store the schema each message was decoded with. For Avro schemas this is the reader schema with all references expanded inline, which allows the schema to be parsed by downstream components without access to the schema registry.").
+			Example("avro_schema").
+			Advanced().
+			Default("").
+			Version("4.28.0")).
 		Field(service.NewURLField("url").Description("The base URL of the schema registry service."))
 
 	for _, f := range service.NewHTTPRequestAuthSignerFields() {
@@ -71,8 +83,9 @@ func init() {
 //------------------------------------------------------------------------------
 
 type schemaRegistryDecoder struct {
-	avroRawJSON bool
-	client      *schemaRegistryClient
+	avro           avroConfig
+	schemaMetadata string
+	client         *schemaRegistryClient
 
 	schemas    map[int]*cachedSchemaDecoder
 	cacheMut   sync.RWMutex
@@ -96,26 +109,35 @@ func newSchemaRegistryDecoderFromConfig(conf *service.ParsedConfig, mgr *service
 	if err != nil {
 		return nil, err
 	}
-	avroRawJSON, err := conf.FieldBool("avro_raw_json")
+	avro, err := avroConfigFromParsed(conf)
+	if err != nil {
+		return nil, err
+	}
+	schemaMetadata, err := conf.FieldString("schema_metadata")
+	if err != nil {
+		return nil, err
+	}
+	s, err := newSchemaRegistryDecoder(urlStr, authSigner, tlsConf, avro, mgr)
 	if err != nil {
 		return nil, err
 	}
-	return newSchemaRegistryDecoder(urlStr, authSigner, tlsConf, avroRawJSON, mgr)
+	s.schemaMetadata = schemaMetadata
+	return s, nil
 }
 
 func newSchemaRegistryDecoder(
 	urlStr string,
 	reqSigner func(f fs.FS, req *http.Request) error,
 	tlsConf *tls.Config,
-	avroRawJSON bool,
+	avro avroConfig,
 	mgr *service.Resources,
 ) (*schemaRegistryDecoder, error) {
 	s := &schemaRegistryDecoder{
-		avroRawJSON: avroRawJSON,
-		schemas:     map[int]*cachedSchemaDecoder{},
-		shutSig:     shutdown.NewSignaller(),
-		logger:      mgr.Logger(),
-		mgr:         mgr,
+		avro:    avro,
+		schemas: map[int]*cachedSchemaDecoder{},
+		shutSig: shutdown.NewSignaller(),
+		logger:  mgr.Logger(),
+		mgr:     mgr,
 	}
 	var err error
 	if s.client, err = newSchemaRegistryClient(urlStr, reqSigner, tlsConf, mgr); err != nil {
@@ -176,6 +198,16 @@ func (s *schemaRegistryDecoder) Close(ctx context.Context) error {
 
 type schemaDecoder func(m *service.Message) error
 
+func withSchemaMetadata(decoder schemaDecoder, key, schema string) schemaDecoder {
+	return func(m *service.Message) error {
+		if err := decoder(m); err != nil {
+			return err
+		}
+		m.MetaSetMut(key, schema)
+		return nil
+	}
+}
+
 type cachedSchemaDecoder struct {
 	lastUsedUnixSeconds int64
 	decoder             schemaDecoder
@@ -260,6 +292,12 @@ func (s *schemaRegistryDecoder) getDecoder(id int) (schemaDecoder, error) {
 	case "PROTOBUF":
 		decoder, err = s.getProtobufDecoder(ctx, resPayload)
 	case "", "AVRO":
+		// Expand references up front so that the reader schema can be added
+		// to metadata.
+		if resPayload.Schema, err = resolveAvroReferences(ctx, s.client, resPayload); err != nil {
+			return nil, err
+		}
+		resPayload.References = nil
 		decoder, err = s.getAvroDecoder(ctx, resPayload)
 	case "JSON":
 		decoder, err = s.getJSONDecoder(ctx, resPayload)
@@ -269,6 +307,9 @@ func (s *schemaRegistryDecoder) getDecoder(id int) (schemaDecoder, error) {
 	if err != nil {
 		return nil, err
 	}
+	if s.schemaMetadata != "" {
+		decoder = withSchemaMetadata(decoder, s.schemaMetadata, resPayload.Schema)
+	}
 
 	s.cacheMut.Lock()
 	s.schemas[id] = &cachedSchemaDecoder{
@@ -45,9 +45,13 @@ For example, the union schema ` + "`[\"null\",\"string\",\"Foo\"]`, where `Foo`"
 
 However, it is possible to instead consume documents in [standard/raw JSON format](https://pkg.go.dev/github.com/linkedin/goavro/v2#NewCodecForStandardJSONFull) by setting the field ` + "[`avro_raw_json`](#avro_raw_json) to `true`" + `.
 
-#### Known Issues
+### Avro Logical Types
 
-Important! There is an outstanding issue in the [avro serializing library](https://github.com/linkedin/goavro) that benthos uses which means it [doesn't encode logical types correctly](https://github.com/linkedin/goavro/issues/252). It's still possible to encode logical types that are in-line with the spec if ` + "`avro_raw_json` is set to true" + `, though now of course non-logical types will not be in-line with the spec.
+By default values of Avro logical types are expected in the form of their underlying types, e.g. a number of milliseconds since the unix epoch for ` + "`timestamp-millis`" + `. Setting ` + "[`avro_logical_types`](#avro_logical_types) to `true`" + ` allows documents to instead provide readable values such as RFC 3339 timestamps and decimal strings.
+
+### Avro Schema References
+
+Avro schemas may reference named types defined within other subjects, in which case the first usage of each referenced type within the schema is replaced with its definition before parsing, including references made by referenced schemas.
 
 ### Protobuf Format
 
@@ -70,7 +74,9 @@ We will be considering alternative approaches in future so please [get in touch]
 			Example("1h")).
 		Field(service.NewBoolField("avro_raw_json").
 			Description("Whether messages encoded in Avro format should be parsed as normal JSON (\"json that meets the expectations of regular internet json\") rather than [Avro JSON](https://avro.apache.org/docs/current/specification/_print/#json-encoding). If `true` the schema returned from the subject should be parsed as [standard json](https://pkg.go.dev/github.com/linkedin/goavro/v2#NewCodecForStandardJSONFull) instead of as [avro json](https://pkg.go.dev/github.com/linkedin/goavro/v2#NewCodec). There is a [comment in goavro](https://github.com/linkedin/goavro/blob/5ec5a5ee7ec82e16e6e2b438d610e1cab2588393/union.go#L224-L249), the [underlining library used for avro serialization](https://github.com/linkedin/goavro), that explains in more detail the difference between standard json and avro json.").
-			Advanced().Default(false).Version("3.59.0"))
+			Advanced().Default(false).Version("3.59.0")).
+		Field(avroLogicalTypesField()).
+		Field(avroUnionNamingField())
 
 	for _, f := range service.NewHTTPRequestAuthSignerFields() {
 		spec = spec.Field(f.Version("4.7.0"))
@@ -95,9 +101,12 @@ func init() {
 type schemaRegistryEncoder struct {
 	client             *schemaRegistryClient
 	subject            *service.InterpolatedString
-	avroRawJSON        bool
+	avro               avroConfig
 	schemaRefreshAfter time.Duration
 
+	// An optional version of subjects to encode with rather than the latest.
+	version *int
+
 	schemas    map[string]cachedSchemaEncoder
 	cacheMut   sync.RWMutex
 	requestMut sync.Mutex
@@ -117,7 +126,7 @@ func newSchemaRegistryEncoderFromConfig(conf *service.ParsedConfig, mgr *service
 	if err != nil {
 		return nil, err
 	}
-	avroRawJSON, err := conf.FieldBool("avro_raw_json")
+	avro, err := avroConfigFromParsed(conf)
 	if err != nil {
 		return nil, err
 	}
@@ -141,7 +150,7 @@ func newSchemaRegistryEncoderFromConfig(conf *service.ParsedConfig, mgr *service
 	if err != nil {
 		return nil, err
 	}
-	return newSchemaRegistryEncoder(urlStr, authSigner, tlsConf, subject, avroRawJSON, refreshPeriod, refreshTicker, mgr)
+	return newSchemaRegistryEncoder(urlStr, authSigner, tlsConf, subject, avro, refreshPeriod, refreshTicker, mgr)
 }
 
 func newSchemaRegistryEncoder(
@@ -149,13 +158,13 @@ func newSchemaRegistryEncoder(
 	reqSigner func(f fs.FS, req *http.Request) error,
 	tlsConf *tls.Config,
 	subject *service.InterpolatedString,
-	avroRawJSON bool,
+	avro avroConfig,
 	schemaRefreshAfter, schemaRefreshTicker time.Duration,
 	mgr *service.Resources,
 ) (*schemaRegistryEncoder, error) {
 	s := &schemaRegistryEncoder{
 		subject:            subject,
-		avroRawJSON:        avroRawJSON,
+		avro:               avro,
 		schemaRefreshAfter: schemaRefreshAfter,
 		schemas:            map[string]cachedSchemaEncoder{},
 		shutSig:            shutdown.NewSignaller(),
@@ -191,30 +200,35 @@ func (s *schemaRegistryEncoder) ProcessBatch(ctx context.Context, batch service.
 			continue
 		}
 
-		encoder, id, err := s.getEncoder(subject)
-		if err != nil {
+		if err := s.encodeMessage(msg, subject); err != nil {
 			msg.SetError(err)
-			continue
 		}
+	}
+	return []service.MessageBatch{batch}, nil
+}
 
-		if err := encoder(msg); err != nil {
-			msg.SetError(err)
-			continue
-		}
+// encodeMessage encodes a message with the schema of a subject, and prefixes
+// the result with the magic byte and ID of the schema.
+func (s *schemaRegistryEncoder) encodeMessage(msg *service.Message, subject string) error {
+	encoder, id, err := s.getEncoder(subject)
+	if err != nil {
+		return err
+	}
 
-		rawBytes, err := msg.AsBytes()
-		if err != nil {
-			msg.SetError(errors.New("unable to reference encoded message as bytes"))
-			continue
-		}
+	if err := encoder(msg); err != nil {
+		return err
+	}
 
-		if rawBytes, err = insertID(id, rawBytes); err != nil {
-			msg.SetError(err)
-			continue
-		}
-		msg.SetBytes(rawBytes)
+	rawBytes, err := msg.AsBytes()
+	if err != nil {
+		return errors.New("unable to reference encoded message as bytes")
 	}
-	return []service.MessageBatch{batch}, nil
+
+	if rawBytes, err = insertID(id, rawBytes); err != nil {
+		return err
+	}
+	msg.SetBytes(rawBytes)
+	return nil
 }
 
 func (s *schemaRegistryEncoder) Close(ctx context.Context) error {
@@ -303,7 +317,7 @@ func (s *schemaRegistryEncoder) getLatestEncoder(subject string) (schemaEncoder,
 	ctx, done := context.WithTimeout(context.Background(), time.Second*5)
 	defer done()
 
-	resPayload, err := s.client.GetSchemaBySubjectAndVersion(ctx, subject, nil)
+	resPayload, err := s.client.GetSchemaBySubjectAndVersion(ctx, subject, s.version)
 	if err != nil {
 		return nil, 0, err
 	}
@@ -0,0 +1,282 @@
+package confluent
+
+import (
+	"encoding/json"
+	"math"
+	"sort"
+	"strings"
+)
+
+type inferredKind uint8
+
+const (
+	kindNull inferredKind = 1 << iota
+	kindBoolean
+	kindInteger
+	kindNumber
+	kindString
+	kindObject
+	kindArray
+)
+
+// inferredType describes the values observed at a location of a document,
+// which can be of several kinds.
+type inferredType struct {
+	kinds inferredKind
+
+	// The fields of observed objects in order of appearance, along with the
+	// number of objects observed and the number of objects each field was
+	// present in, which determines whether a field is required.
+	fields      map[string]*inferredField
+	fieldOrder  []string
+	objectCount int
+
+	// The type of the items of observed arrays, nil until an item is observed.
+	items *inferredType
+}
+
+type inferredField struct {
+	typ   *inferredType
+	count int
+}
+
+func valueKind(v any) inferredKind {
+	switch t := v.(type) {
+	case nil:
+		return kindNull
+	case bool:
+		return kindBoolean
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return kindInteger
+	case float32:
+		if float64(t) == math.Trunc(float64(t)) {
+			return kindInteger
+		}
+		return kindNumber
+	case float64:
+		if t == math.Trunc(t) && !math.IsInf(t, 0) {
+			return kindInteger
+		}
+		return kindNumber
+	case json.Number:
+		if _, err := t.Int64(); err == nil {
+			return kindInteger
+		}
+		return kindNumber
+	case map[string]any:
+		return kindObject
+	case []any:
+		return kindArray
+	}
+	// Strings, byte arrays, timestamps and anything else are strings.
+	return kindString
+}
+
+// merge widens the type to include a value, returning true if the type was
+// widened.
+func (t *inferredType) merge(v any) bool {
+	widened := false
+
+	kind := valueKind(v)
+	if kind == kindInteger && t.kinds&kindNumber != 0 {
+		kind = kindNumber
+	}
+	if t.kinds&kind == 0 {
+		t.kinds |= kind
+		widened = true
+	}
+
+	switch kind {
+	case kindObject:
+		obj, _ := v.(map[string]any)
+		if t.fields == nil {
+			t.fields = map[string]*inferredField{}
+		}
+
+		// Fields of the value not observed before, which are added in a
+		// deterministic order.
+		var newFields []string
+		for k := range obj {
+			if _, exists := t.fields[k]; !exists {
+				newFields = append(newFields, k)
+			}
+		}
+		sort.Strings(newFields)
+		for _, k := range newFields {
+			t.fields[k] = &inferredField{typ: &inferredType{}}
+			t.fieldOrder = append(t.fieldOrder, k)
+		}
+
+		for _, k := range t.fieldOrder {
+			f := t.fields[k]
+			fv, exists := obj[k]
+			if !exists {
+				if f.count == t.objectCount {
+					// A required field becomes optional.
+					widened = true
+				}
+				continue
+			}
+			if f.typ.merge(fv) {
+				widened = true
+			}
+			f.count++
+		}
+		if t.objectCount > 0 && len(newFields) > 0 {
+			widened = true
+		}
+		t.objectCount++
+	case kindArray:
+		arr, _ := v.([]any)
+		for _, item := range arr {
+			if t.items == nil {
+				t.items = &inferredType{}
+			}
+			if t.items.merge(item) {
+				widened = true
+			}
+		}
+	}
+	return widened
+}
+
+func (t *inferredType) required(k string) bool {
+	return t.fields[k].count == t.objectCount
+}
+
+//------------------------------------------------------------------------------
+
+// jsonSchema renders the type as a JSON Schema document.
+func (t *inferredType) jsonSchema() map[string]any {
+	var types []any
+	if t.kinds&kindNull != 0 {
+		types = append(types, "null")
+	}
+	if t.kinds&kindBoolean != 0 {
+		types = append(types, "boolean")
+	}
+	if t.kinds&kindNumber != 0 {
+		types = append(types, "number")
+	} else if t.kinds&kindInteger != 0 {
+		types = append(types, "integer")
+	}
+	if t.kinds&kindString != 0 {
+		types = append(types, "string")
+	}
+	if t.kinds&kindObject != 0 {
+		types = append(types, "object")
+	}
+	if t.kinds&kindArray != 0 {
+		types = append(types, "array")
+	}
+
+	s := map[string]any{}
+	if len(types) == 1 {
+		s["type"] = types[0]
+	} else if len(types) > 1 {
+		s["type"] = types
+	}
+
+	if t.kinds&kindObject != 0 {
+		props := map[string]any{}
+		var required []any
+		for _, k := range t.fieldOrder {
+			props[k] = t.fields[k].typ.jsonSchema()
+			if t.required(k) {
+				required = append(required, k)
+			}
+		}
+		s["properties"] = props
+		if len(required) > 0 {
+			s["required"] = required
+		}
+	}
+	if t.kinds&kindArray != 0 && t.items != nil {
+		s["items"] = t.items.jsonSchema()
+	}
+	return s
+}
+
+//------------------------------------------------------------------------------
+
+func avroName(s string) string {
+	var b strings.Builder
+	for i, r := range s {
+		if r == '_' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (i > 0 && r >= '0' && r <= '9') {
+			b.WriteRune(r)
+		} else {
+			b.WriteRune('_')
+		}
+	}
+	if b.Len() == 0 {
+		return "_"
+	}
+	return b.String()
+}
+
+// avroSchema renders the type as an Avro schema, where records are named after
+// the path of their field from the root record.
+func (t *inferredType) avroSchema(name string, nullable bool) any {
+	var union []any
+	if nullable || t.kinds&kindNull != 0 || t.kinds == 0 {
+		union = append(union, "null")
+	}
+	if t.kinds&kindBoolean != 0 {
+		union = append(union, "boolean")
+	}
+	if t.kinds&kindNumber != 0 {
+		union = append(union, "double")
+	} else if t.kinds&kindInteger != 0 {
+		union = append(union, "long")
+	}
+	if t.kinds&kindString != 0 {
+		union = append(union, "string")
+	}
+	if t.kinds&kindObject != 0 {
+		fields := make([]any, 0, len(t.fieldOrder))
+		for _, k := range t.fieldOrder {
+			optional := !t.required(k)
+			f := map[string]any{
+				"name": avroName(k),
+				"type": t.fields[k].typ.avroSchema(name+"_"+avroName(k), optional),
+			}
+			if optional {
+				f["default"] = nil
+			}
+			fields = append(fields, f)
+		}
+		union = append(union, map[string]any{
+			"type":   "record",
+			"name":   name,
+			"fields": fields,
+		})
+	}
+	if t.kinds&kindArray != 0 {
+		var items any = "null"
+		if t.items != nil {
+			items = t.items.avroSchema(name+"_item", false)
+		}
+		union = append(union, map[string]any{
+			"type":  "array",
+			"items": items,
+		})
+	}
+
+	if len(union) == 1 {
+		return union[0]
+	}
+	return union
+}
+
+//------------------------------------------------------------------------------
+
+// inferredSchema is the schema inferred for a subject.
+type inferredSchema struct {
+	root    inferredType
+	version int
+	schema  string
+
+	// The version and ID of the schema last registered.
+	registeredVersion int
+	registeredID      int
+}
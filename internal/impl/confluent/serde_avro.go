@@ -1,15 +1,54 @@
 package confluent
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
+	"strings"
 
 	"github.com/linkedin/goavro/v2"
 
 	"github.com/benthosdev/benthos/v4/public/service"
 )
 
+const (
+	avroFieldRawJSON      = "avro_raw_json"
+	avroFieldLogicalTypes = "avro_logical_types"
+	avroFieldUnionNaming  = "avro_union_naming"
+)
+
+func avroLogicalTypesField() *service.ConfigField {
+	return service.NewBoolField(avroFieldLogicalTypes).
+		Description("Whether Avro logical types should be represented in documents by readable values. When `true` the `timestamp` and `local-timestamp` types are RFC 3339 timestamp strings, the `date` type is a `YYYY-MM-DD` string, the `time` types are `hh:mm:ss.sss` strings, the `decimal` type is a decimal number string of the scale within the schema, and the `uuid` type is a canonical UUID string, including when backed by a `fixed` type. When `false` logical types are represented by their underlying Avro types.").
+		Advanced().
+		Default(false).
+		Version("4.28.0")
+}
+
+func avroUnionNamingField() *service.ConfigField {
+	return service.NewStringAnnotatedEnumField(avroFieldUnionNaming, map[string]string{
+		avroUnionNamingDefault: "Union branches of named types are named by their full name, primitive types by their type name, and logical types recognised by the Avro library by their type name suffixed with the logical type, e.g. `long.timestamp-millis`.",
+		avroUnionNamingType:    "Union branches are named as described by the Avro specification, where named types are named by their full name and all other types by their type name, ignoring logical types.",
+		avroUnionNamingShort:   "Union branches are named as with `type_name`, except named types are named by their name without a namespace.",
+	}).
+		Description("The names given to union branches within Avro JSON documents. Documents being encoded may use any of these naming conventions when this field is set to anything other than `default`. This field has no effect when `avro_raw_json` is `true`.").
+		Advanced().
+		Default(avroUnionNamingDefault).
+		Version("4.28.0")
+}
+
+func avroConfigFromParsed(conf *service.ParsedConfig) (c avroConfig, err error) {
+	if c.rawJSON, err = conf.FieldBool(avroFieldRawJSON); err != nil {
+		return
+	}
+	if c.logicalTypes, err = conf.FieldBool(avroFieldLogicalTypes); err != nil {
+		return
+	}
+	c.unionNaming, err = conf.FieldString(avroFieldUnionNaming)
+	return
+}
+
 func resolveAvroReferences(ctx context.Context, client *schemaRegistryClient, info SchemaInfo) (string, error) {
 	if len(info.References) == 0 {
 		return info.Schema, nil
@@ -20,46 +59,122 @@ func resolveAvroReferences(ctx context.Context, client *schemaRegistryClient, in
 		refsMap[name] = info.Schema
 		return nil
 	}); err != nil {
-		return "", nil
+		return "", err
 	}
 
-	schemaDry := []string{}
-	if err := json.Unmarshal([]byte(info.Schema), &schemaDry); err != nil {
-		return "", fmt.Errorf("failed to parse root schema as enum: %w", err)
+	var root any
+	if err := json.Unmarshal([]byte(info.Schema), &root); err != nil {
+		return "", fmt.Errorf("failed to parse root schema: %w", err)
 	}
 
-	schemaHydrated := make([]json.RawMessage, len(schemaDry))
-	for i, name := range schemaDry {
-		def, exists := refsMap[name]
-		if !exists {
-			return "", fmt.Errorf("referenced type '%v' was not found in references", name)
-		}
-		schemaHydrated[i] = []byte(def)
+	h := avroRefHydrator{refs: refsMap, defined: map[string]struct{}{}}
+	hydrated, err := h.hydrate(root, "")
+	if err != nil {
+		return "", err
 	}
 
-	schemaHydratedBytes, err := json.Marshal(schemaHydrated)
+	schemaHydratedBytes, err := json.Marshal(hydrated)
 	if err != nil {
 		return "", fmt.Errorf("failed to marshal hydrated schema: %w", err)
 	}
-
 	return string(schemaHydratedBytes), nil
 }
 
+// avroRefHydrator replaces the first usage of each referenced named type
+// within a schema with its definition, as the Avro codec requires all named
+// types to be defined inline before they are used by name.
+type avroRefHydrator struct {
+	refs    map[string]string
+	defined map[string]struct{}
+}
+
+func (h *avroRefHydrator) hydrate(v any, ns string) (any, error) {
+	switch t := v.(type) {
+	case string:
+		candidates := []string{t}
+		if ns != "" && !strings.Contains(t, ".") {
+			candidates = []string{ns + "." + t, t}
+		}
+		for _, name := range candidates {
+			if _, exists := h.defined[name]; exists {
+				return t, nil
+			}
+			def, exists := h.refs[name]
+			if !exists {
+				continue
+			}
+			var refRoot any
+			if err := json.Unmarshal([]byte(def), &refRoot); err != nil {
+				return nil, fmt.Errorf("failed to parse referenced schema '%v': %w", name, err)
+			}
+			h.defined[name] = struct{}{}
+			return h.hydrate(refRoot, "")
+		}
+		return t, nil
+	case []any:
+		for i, e := range t {
+			var err error
+			if t[i], err = h.hydrate(e, ns); err != nil {
+				return nil, err
+			}
+		}
+		return t, nil
+	case map[string]any:
+		childNS := ns
+		if kind, _ := t["type"].(string); kind == "record" || kind == "error" || kind == "enum" || kind == "fixed" {
+			var fullName string
+			fullName, childNS = avroFullName(t, ns)
+			h.defined[fullName] = struct{}{}
+		}
+		for _, k := range []string{"type", "items", "values"} {
+			if e, exists := t[k]; exists {
+				var err error
+				if t[k], err = h.hydrate(e, childNS); err != nil {
+					return nil, err
+				}
+			}
+		}
+		if fields, ok := t["fields"].([]any); ok {
+			for _, f := range fields {
+				fm, ok := f.(map[string]any)
+				if !ok {
+					continue
+				}
+				var err error
+				if fm["type"], err = h.hydrate(fm["type"], childNS); err != nil {
+					return nil, err
+				}
+			}
+		}
+		return t, nil
+	}
+	return v, nil
+}
+
+func newAvroCodec(schema string, conf avroConfig) (codec *goavro.Codec, schemaType *avroType, err error) {
+	if conf.rawJSON {
+		codec, err = goavro.NewCodecForStandardJSONFull(schema)
+	} else {
+		codec, err = goavro.NewCodec(schema)
+	}
+	if err != nil {
+		return
+	}
+	if conf.transforms() {
+		schemaType, err = parseAvroSchema(schema)
+	}
+	return
+}
+
 func (s *schemaRegistryEncoder) getAvroEncoder(ctx context.Context, info SchemaInfo) (schemaEncoder, error) {
 	schema, err := resolveAvroReferences(ctx, s.client, info)
 	if err != nil {
 		return nil, err
 	}
 
-	var codec *goavro.Codec
-	if s.avroRawJSON {
-		if codec, err = goavro.NewCodecForStandardJSONFull(schema); err != nil {
-			return nil, err
-		}
-	} else {
-		if codec, err = goavro.NewCodec(schema); err != nil {
-			return nil, err
-		}
+	codec, schemaType, err := newAvroCodec(schema, s.avro)
+	if err != nil {
+		return nil, err
 	}
 
 	return func(m *service.Message) error {
@@ -68,6 +183,22 @@ func (s *schemaRegistryEncoder) getAvroEncoder(ctx context.Context, info SchemaI
 			return err
 		}
 
+		if schemaType != nil {
+			dec := json.NewDecoder(bytes.NewReader(b))
+			dec.UseNumber()
+
+			var doc any
+			if err := dec.Decode(&doc); err != nil {
+				return err
+			}
+			if doc, err = s.avro.toTextual(schemaType, doc); err != nil {
+				return err
+			}
+			if b, err = json.Marshal(doc); err != nil {
+				return err
+			}
+		}
+
 		datum, _, err := codec.NativeFromTextual(b)
 		if err != nil {
 			return err
@@ -89,12 +220,7 @@ func (s *schemaRegistryDecoder) getAvroDecoder(ctx context.Context, info SchemaI
 		return nil, err
 	}
 
-	var codec *goavro.Codec
-	if s.avroRawJSON {
-		codec, err = goavro.NewCodecForStandardJSONFull(schema)
-	} else {
-		codec, err = goavro.NewCodec(schema)
-	}
+	codec, schemaType, err := newAvroCodec(schema, s.avro)
 	if err != nil {
 		return nil, err
 	}
@@ -110,7 +236,12 @@ func (s *schemaRegistryDecoder) getAvroDecoder(ctx context.Context, info SchemaI
 			return err
 		}
 
-		jb, err := codec.TextualFromNative(nil, native)
+		var jb []byte
+		if schemaType != nil {
+			jb, err = s.avro.appendNativeJSON(nil, schemaType, native)
+		} else {
+			jb, err = codec.TextualFromNative(nil, native)
+		}
 		if err != nil {
 			return err
 		}
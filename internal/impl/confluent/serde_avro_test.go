@@ -3,6 +3,7 @@ package confluent
 import (
 	"context"
 	"encoding/json"
+	"math/big"
 	"testing"
 	"time"
 
@@ -90,10 +91,10 @@ func TestAvroReferences(t *testing.T) {
 	for _, test := range tests {
 		test := test
 		t.Run(test.name, func(t *testing.T) {
-			encoder, err := newSchemaRegistryEncoder(urlStr, noopReqSign, nil, subj, true, time.Minute*10, time.Minute, service.MockResources())
+			encoder, err := newSchemaRegistryEncoder(urlStr, noopReqSign, nil, subj, avroConfig{rawJSON: true}, time.Minute*10, time.Minute, service.MockResources())
 			require.NoError(t, err)
 
-			decoder, err := newSchemaRegistryDecoder(urlStr, noopReqSign, nil, true, service.MockResources())
+			decoder, err := newSchemaRegistryDecoder(urlStr, noopReqSign, nil, avroConfig{rawJSON: true}, service.MockResources())
 			require.NoError(t, err)
 
 			t.Cleanup(func() {
@@ -141,3 +142,272 @@ func TestAvroReferences(t *testing.T) {
 		})
 	}
 }
+
+func testAvroRoundTrip(t *testing.T, schema string, refs []any, routes map[string]any, conf avroConfig, input, output string) *service.Message {
+	t.Helper()
+
+	tCtx, done := context.WithTimeout(context.Background(), time.Second*10)
+	defer done()
+
+	urlStr := runSchemaRegistryServer(t, func(path string) ([]byte, error) {
+		switch path {
+		case "/subjects/root/versions/latest", "/schemas/ids/1":
+			return mustJBytes(t, map[string]any{
+				"id": 1, "version": 1, "schemaType": "AVRO",
+				"schema":     schema,
+				"references": refs,
+			}), nil
+		}
+		if v, exists := routes[path]; exists {
+			return mustJBytes(t, v), nil
+		}
+		return nil, nil
+	})
+
+	subj, err := service.NewInterpolatedString("root")
+	require.NoError(t, err)
+
+	encoder, err := newSchemaRegistryEncoder(urlStr, noopReqSign, nil, subj, conf, time.Minute*10, time.Minute, service.MockResources())
+	require.NoError(t, err)
+
+	decoder, err := newSchemaRegistryDecoder(urlStr, noopReqSign, nil, conf, service.MockResources())
+	require.NoError(t, err)
+	decoder.schemaMetadata = "avro_schema"
+
+	t.Cleanup(func() {
+		_ = encoder.Close(tCtx)
+		_ = decoder.Close(tCtx)
+	})
+
+	encodedMsgs, err := encoder.ProcessBatch(tCtx, service.MessageBatch{service.NewMessage([]byte(input))})
+	require.NoError(t, err)
+	require.Len(t, encodedMsgs, 1)
+	require.Len(t, encodedMsgs[0], 1)
+	require.NoError(t, encodedMsgs[0][0].GetError())
+
+	decodedMsgs, err := decoder.Process(tCtx, encodedMsgs[0][0])
+	require.NoError(t, err)
+	require.Len(t, decodedMsgs, 1)
+
+	b, err := decodedMsgs[0].AsBytes()
+	require.NoError(t, err)
+	assert.JSONEq(t, output, string(b))
+	return decodedMsgs[0]
+}
+
+func TestAvroNestedReferences(t *testing.T) {
+	rootSchema := `{
+	"type": "record",
+	"name": "Order",
+	"namespace": "com.example",
+	"fields": [
+		{ "name": "customer", "type": "com.example.Customer" },
+		{ "name": "previous_customer", "type": ["null", "Customer"] }
+	]
+}`
+
+	customerSchema := `{
+	"type": "record",
+	"name": "Customer",
+	"namespace": "com.example",
+	"fields": [
+		{ "name": "name", "type": "string" },
+		{ "name": "address", "type": "com.example.Address" }
+	]
+}`
+
+	addressSchema := `{
+	"type": "record",
+	"name": "Address",
+	"namespace": "com.example",
+	"fields": [
+		{ "name": "street", "type": "string" }
+	]
+}`
+
+	routes := map[string]any{
+		"/subjects/customer/versions/1": map[string]any{
+			"id": 2, "version": 1, "schemaType": "AVRO",
+			"schema": customerSchema,
+			"references": []any{
+				map[string]any{"name": "com.example.Address", "subject": "address", "version": 1},
+			},
+		},
+		"/subjects/address/versions/1": map[string]any{
+			"id": 3, "version": 1, "schemaType": "AVRO",
+			"schema": addressSchema,
+		},
+	}
+	refs := []any{
+		map[string]any{"name": "com.example.Customer", "subject": "customer", "version": 1},
+	}
+
+	msg := testAvroRoundTrip(t, rootSchema, refs, routes, avroConfig{rawJSON: true},
+		`{"customer":{"name":"foo","address":{"street":"bar"}},"previous_customer":{"name":"baz","address":{"street":"buz"}}}`,
+		`{"customer":{"name":"foo","address":{"street":"bar"}},"previous_customer":{"name":"baz","address":{"street":"buz"}}}`,
+	)
+
+	readerSchema, exists := msg.MetaGet("avro_schema")
+	require.True(t, exists)
+	assert.Contains(t, readerSchema, `"name":"Address"`)
+	assert.Contains(t, readerSchema, `"name":"Customer"`)
+	assert.Contains(t, readerSchema, `["null","Customer"]`)
+}
+
+func TestAvroLogicalTypes(t *testing.T) {
+	schema := `{
+	"type": "record",
+	"name": "Payment",
+	"namespace": "com.example",
+	"fields": [
+		{ "name": "id", "type": { "type": "string", "logicalType": "uuid" } },
+		{ "name": "raw_id", "type": { "type": "fixed", "name": "RawID", "size": 16, "logicalType": "uuid" } },
+		{ "name": "amount", "type": { "type": "bytes", "logicalType": "decimal", "precision": 10, "scale": 2 } },
+		{ "name": "fixed_amount", "type": { "type": "fixed", "name": "Amount", "size": 8, "logicalType": "decimal", "precision": 18, "scale": 3 } },
+		{ "name": "created_at", "type": { "type": "long", "logicalType": "timestamp-micros" } },
+		{ "name": "day", "type": { "type": "int", "logicalType": "date" } },
+		{ "name": "at", "type": { "type": "int", "logicalType": "time-millis" } },
+		{ "name": "updated_at", "type": ["null", { "type": "long", "logicalType": "timestamp-millis" }] }
+	]
+}`
+
+	tests := []struct {
+		name   string
+		conf   avroConfig
+		input  string
+		output string
+	}{
+		{
+			name: "avro json with type names",
+			conf: avroConfig{logicalTypes: true, unionNaming: avroUnionNamingType},
+			input: `{
+	"id": "0B2BB2B6-4B4E-4C5E-9F4E-3C0C6A3D9A7B",
+	"raw_id": "0b2bb2b6-4b4e-4c5e-9f4e-3c0c6a3d9a7b",
+	"amount": "123.45",
+	"fixed_amount": -12.5,
+	"created_at": "2024-03-01T12:30:45.123456Z",
+	"day": "2024-03-01",
+	"at": "12:30:45.5",
+	"updated_at": { "long": "2024-03-01T12:30:45.123Z" }
+}`,
+			output: `{
+	"id": "0b2bb2b6-4b4e-4c5e-9f4e-3c0c6a3d9a7b",
+	"raw_id": "0b2bb2b6-4b4e-4c5e-9f4e-3c0c6a3d9a7b",
+	"amount": "123.45",
+	"fixed_amount": "-12.500",
+	"created_at": "2024-03-01T12:30:45.123456Z",
+	"day": "2024-03-01",
+	"at": "12:30:45.5",
+	"updated_at": { "long": "2024-03-01T12:30:45.123Z" }
+}`,
+		},
+		{
+			name: "avro json with default names",
+			conf: avroConfig{logicalTypes: true},
+			input: `{
+	"id": "0b2bb2b6-4b4e-4c5e-9f4e-3c0c6a3d9a7b",
+	"raw_id": "0b2bb2b6-4b4e-4c5e-9f4e-3c0c6a3d9a7b",
+	"amount": "0.01",
+	"fixed_amount": "1",
+	"created_at": 1709296245123456,
+	"day": 19783,
+	"at": 45045500,
+	"updated_at": { "long.timestamp-millis": "2024-03-01T12:30:45.123Z" }
+}`,
+			output: `{
+	"id": "0b2bb2b6-4b4e-4c5e-9f4e-3c0c6a3d9a7b",
+	"raw_id": "0b2bb2b6-4b4e-4c5e-9f4e-3c0c6a3d9a7b",
+	"amount": "0.01",
+	"fixed_amount": "1.000",
+	"created_at": "2024-03-01T12:30:45.123456Z",
+	"day": "2024-03-01",
+	"at": "12:30:45.5",
+	"updated_at": { "long.timestamp-millis": "2024-03-01T12:30:45.123Z" }
+}`,
+		},
+		{
+			name: "raw json",
+			conf: avroConfig{rawJSON: true, logicalTypes: true},
+			input: `{
+	"id": "0b2bb2b6-4b4e-4c5e-9f4e-3c0c6a3d9a7b",
+	"raw_id": "0b2bb2b6-4b4e-4c5e-9f4e-3c0c6a3d9a7b",
+	"amount": "-99.99",
+	"fixed_amount": "123456.789",
+	"created_at": "1970-01-01T00:00:00Z",
+	"day": "1969-12-31",
+	"at": "00:00:00",
+	"updated_at": null
+}`,
+			output: `{
+	"id": "0b2bb2b6-4b4e-4c5e-9f4e-3c0c6a3d9a7b",
+	"raw_id": "0b2bb2b6-4b4e-4c5e-9f4e-3c0c6a3d9a7b",
+	"amount": "-99.99",
+	"fixed_amount": "123456.789",
+	"created_at": "1970-01-01T00:00:00Z",
+	"day": "1969-12-31",
+	"at": "00:00:00",
+	"updated_at": null
+}`,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			testAvroRoundTrip(t, schema, nil, nil, test.conf, test.input, test.output)
+		})
+	}
+}
+
+func TestAvroUnionNaming(t *testing.T) {
+	schema := `{
+	"type": "record",
+	"name": "Owner",
+	"namespace": "com.example",
+	"fields": [
+		{ "name": "data", "type": "bytes" },
+		{ "name": "pet", "type": ["null", { "type": "record", "name": "Dog", "namespace": "com.animals", "fields": [{ "name": "name", "type": "string" }] }] },
+		{ "name": "nickname", "type": ["null", "string"] }
+	]
+}`
+
+	testAvroRoundTrip(t, schema, nil, nil, avroConfig{unionNaming: avroUnionNamingShort},
+		`{"data":"ÿ\u0001a","pet":{"com.animals.Dog":{"name":"rex"}},"nickname":{"string":"r"}}`,
+		`{"data":"ÿ\u0001a","pet":{"Dog":{"name":"rex"}},"nickname":{"string":"r"}}`,
+	)
+
+	testAvroRoundTrip(t, schema, nil, nil, avroConfig{unionNaming: avroUnionNamingType},
+		`{"data":"","pet":{"Dog":{"name":"rex"}},"nickname":null}`,
+		`{"data":"","pet":{"com.animals.Dog":{"name":"rex"}},"nickname":null}`,
+	)
+}
+
+func TestAvroDecimalBytes(t *testing.T) {
+	for _, test := range []struct {
+		value string
+		scale int
+		size  int
+		bytes []byte
+	}{
+		{value: "0", scale: 0, bytes: []byte{0x00}},
+		{value: "1.27", scale: 2, bytes: []byte{0x7f}},
+		{value: "1.28", scale: 2, bytes: []byte{0x00, 0x80}},
+		{value: "-1.28", scale: 2, bytes: []byte{0xff, 0x80}},
+		{value: "-0.01", scale: 2, size: 4, bytes: []byte{0xff, 0xff, 0xff, 0xff}},
+		{value: "2.55", scale: 2, size: 4, bytes: []byte{0x00, 0x00, 0x00, 0xff}},
+	} {
+		b, err := avroDecimalToBytes(test.value, test.scale, test.size)
+		require.NoError(t, err, test.value)
+		assert.Equal(t, test.bytes, b, test.value)
+
+		r, ok := new(big.Rat).SetString(test.value)
+		require.True(t, ok)
+		assert.Equal(t, r.FloatString(test.scale), avroDecimalFromBytes(b, test.scale), test.value)
+	}
+
+	_, err := avroDecimalToBytes("1.234", 2, 0)
+	require.Error(t, err)
+
+	_, err = avroDecimalToBytes("1000", 0, 1)
+	require.Error(t, err)
+}
@@ -0,0 +1,796 @@
+package confluent
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+const (
+	avroUnionNamingDefault = "default"
+	avroUnionNamingType    = "type_name"
+	avroUnionNamingShort   = "short_name"
+)
+
+// avroConfig describes how JSON documents are mapped to and from Avro data.
+type avroConfig struct {
+	rawJSON      bool
+	logicalTypes bool
+	unionNaming  string
+}
+
+// transforms returns true when documents must be transformed in order to be
+// mapped to and from the JSON format understood by goavro.
+func (c avroConfig) transforms() bool {
+	if c.logicalTypes {
+		return true
+	}
+	return !c.rawJSON && c.unionNaming != "" && c.unionNaming != avroUnionNamingDefault
+}
+
+//------------------------------------------------------------------------------
+
+// avroType is a parsed Avro schema, which is used for walking documents in
+// order to map logical types and union branch names.
+type avroType struct {
+	kind     string
+	logical  string
+	scale    int
+	size     int
+	fullName string
+	name     string
+	fields   []avroField
+	items    *avroType
+	branches []*avroType
+}
+
+type avroField struct {
+	name string
+	t    *avroType
+}
+
+var avroPrimitives = map[string]struct{}{
+	"null": {}, "boolean": {}, "int": {}, "long": {}, "float": {}, "double": {}, "bytes": {}, "string": {},
+}
+
+// Logical types that goavro recognises, which changes the name of union
+// branches of these types.
+var goavroLogicalTypes = map[string]struct{}{
+	"long.timestamp-millis": {},
+	"long.timestamp-micros": {},
+	"int.time-millis":       {},
+	"long.time-micros":      {},
+	"int.date":              {},
+	"bytes.decimal":         {},
+}
+
+// goavroName returns the name of a union branch of this type as expected by
+// goavro.
+func (t *avroType) goavroName() string {
+	if t.fullName != "" {
+		return t.fullName
+	}
+	if t.logical != "" {
+		if _, exists := goavroLogicalTypes[t.kind+"."+t.logical]; exists {
+			return t.kind + "." + t.logical
+		}
+	}
+	return t.kind
+}
+
+// unionName returns the name of a union branch of this type for a naming
+// option.
+func (t *avroType) unionName(naming string) string {
+	switch naming {
+	case avroUnionNamingType:
+		if t.fullName != "" {
+			return t.fullName
+		}
+		return t.kind
+	case avroUnionNamingShort:
+		if t.name != "" {
+			return t.name
+		}
+		return t.kind
+	}
+	return t.goavroName()
+}
+
+func parseAvroSchema(schema string) (*avroType, error) {
+	var root any
+	if err := json.Unmarshal([]byte(schema), &root); err != nil {
+		return nil, fmt.Errorf("failed to parse schema: %w", err)
+	}
+	p := avroSchemaParser{named: map[string]*avroType{}}
+	return p.parse(root, "")
+}
+
+type avroSchemaParser struct {
+	named map[string]*avroType
+}
+
+// avroFullName returns the full name and namespace of a named type.
+func avroFullName(m map[string]any, enclosingNamespace string) (fullName, namespace string) {
+	name, _ := m["name"].(string)
+	if i := strings.LastIndexByte(name, '.'); i >= 0 {
+		return name, name[:i]
+	}
+	namespace = enclosingNamespace
+	if ns, exists := m["namespace"].(string); exists {
+		namespace = ns
+	}
+	if namespace == "" {
+		return name, ""
+	}
+	return namespace + "." + name, namespace
+}
+
+func (p *avroSchemaParser) parse(v any, ns string) (*avroType, error) {
+	switch s := v.(type) {
+	case string:
+		if _, exists := avroPrimitives[s]; exists {
+			return &avroType{kind: s}, nil
+		}
+		if t, exists := p.named[s]; exists {
+			return t, nil
+		}
+		if t, exists := p.named[ns+"."+s]; exists && ns != "" {
+			return t, nil
+		}
+		return nil, fmt.Errorf("unknown type name: %v", s)
+	case []any:
+		t := &avroType{kind: "union"}
+		for _, b := range s {
+			bt, err := p.parse(b, ns)
+			if err != nil {
+				return nil, err
+			}
+			t.branches = append(t.branches, bt)
+		}
+		return t, nil
+	case map[string]any:
+		return p.parseMap(s, ns)
+	}
+	return nil, fmt.Errorf("unexpected schema type: %T", v)
+}
+
+func (p *avroSchemaParser) parseMap(m map[string]any, ns string) (*avroType, error) {
+	kind, isStr := m["type"].(string)
+	if !isStr {
+		return p.parse(m["type"], ns)
+	}
+
+	t := &avroType{kind: kind}
+	t.logical, _ = m["logicalType"].(string)
+	if scale, exists := m["scale"].(float64); exists {
+		t.scale = int(scale)
+	}
+
+	switch kind {
+	case "record", "error", "enum", "fixed":
+		var fieldsNS string
+		t.fullName, fieldsNS = avroFullName(m, ns)
+		t.name = t.fullName[strings.LastIndexByte(t.fullName, '.')+1:]
+		if kind == "error" {
+			t.kind = "record"
+		}
+		p.named[t.fullName] = t
+
+		if size, exists := m["size"].(float64); exists {
+			t.size = int(size)
+		}
+		fields, _ := m["fields"].([]any)
+		for _, f := range fields {
+			fm, ok := f.(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("record %v field was unexpected type: %T", t.fullName, f)
+			}
+			fName, _ := fm["name"].(string)
+			ft, err := p.parse(fm["type"], fieldsNS)
+			if err != nil {
+				return nil, fmt.Errorf("record %v field %v: %w", t.fullName, fName, err)
+			}
+			t.fields = append(t.fields, avroField{name: fName, t: ft})
+		}
+	case "array":
+		items, err := p.parse(m["items"], ns)
+		if err != nil {
+			return nil, err
+		}
+		t.items = items
+	case "map":
+		values, err := p.parse(m["values"], ns)
+		if err != nil {
+			return nil, err
+		}
+		t.items = values
+	default:
+		if _, exists := avroPrimitives[kind]; exists {
+			return t, nil
+		}
+		// A reference to a named type, where any logical type annotation is
+		// ignored.
+		return p.parse(kind, ns)
+	}
+	return t, nil
+}
+
+//------------------------------------------------------------------------------
+
+const (
+	avroLocalTimestampLayout = "2006-01-02T15:04:05.999999999"
+	avroDateLayout           = "2006-01-02"
+	avroTimeLayout           = "15:04:05.999999999"
+)
+
+// avroJSONBytes is marshalled into JSON as a string where each byte is a code
+// point, with all bytes outside of printable ASCII escaped, as the Avro codec
+// reads unescaped characters of bytes strings as raw UTF-8 bytes.
+type avroJSONBytes []byte
+
+func (b avroJSONBytes) MarshalJSON() ([]byte, error) {
+	const hex = "0123456789abcdef"
+
+	buf := make([]byte, 0, len(b)+2)
+	buf = append(buf, '"')
+	for _, c := range b {
+		switch {
+		case c == '"' || c == '\\':
+			buf = append(buf, '\\', c)
+		case c < 0x20 || c >= 0x7f:
+			buf = append(buf, '\\', 'u', '0', '0', hex[c>>4], hex[c&0xf])
+		default:
+			buf = append(buf, c)
+		}
+	}
+	return append(buf, '"'), nil
+}
+
+func avroStringToBytes(s string) ([]byte, error) {
+	b := make([]byte, 0, len(s))
+	for _, r := range s {
+		if r > math.MaxUint8 {
+			return nil, fmt.Errorf("bytes string contains a code point beyond 255: %q", r)
+		}
+		b = append(b, byte(r))
+	}
+	return b, nil
+}
+
+// avroDecimalFromBytes converts the two's complement unscaled bytes of a
+// decimal into a decimal string.
+func avroDecimalFromBytes(b []byte, scale int) string {
+	n := new(big.Int).SetBytes(b)
+	if len(b) > 0 && b[0]&0x80 != 0 {
+		n.Sub(n, new(big.Int).Lsh(big.NewInt(1), uint(len(b)*8)))
+	}
+	denom := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(scale)), nil)
+	return new(big.Rat).SetFrac(n, denom).FloatString(scale)
+}
+
+// avroDecimalToBytes converts a decimal value into its two's complement
+// unscaled bytes, padded to a size when non-zero.
+func avroDecimalToBytes(v any, scale, size int) ([]byte, error) {
+	var str string
+	switch d := v.(type) {
+	case string:
+		str = d
+	case json.Number:
+		str = d.String()
+	default:
+		return nil, fmt.Errorf("expected decimal value as a string or number, got %T", v)
+	}
+
+	r, ok := new(big.Rat).SetString(str)
+	if !ok {
+		return nil, fmt.Errorf("failed to parse decimal value: %v", str)
+	}
+	r.Mul(r, new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(scale)), nil)))
+	if !r.IsInt() {
+		return nil, fmt.Errorf("decimal value %v exceeds the scale %v of the schema", str, scale)
+	}
+	n := r.Num()
+
+	byteLen := n.BitLen()/8 + 1
+	if size > 0 {
+		if byteLen > size {
+			return nil, fmt.Errorf("decimal value %v exceeds the fixed size %v of the schema", str, size)
+		}
+		byteLen = size
+	}
+
+	// Obtain the two's complement of negative numbers by offsetting them by
+	// the maximum value of the target length.
+	if n.Sign() < 0 {
+		n = new(big.Int).Add(n, new(big.Int).Lsh(big.NewInt(1), uint(byteLen*8)))
+	}
+	b := n.Bytes()
+	out := make([]byte, byteLen)
+	copy(out[byteLen-len(b):], b)
+	return out, nil
+}
+
+func avroInt64(v any) (int64, error) {
+	switch n := v.(type) {
+	case int32:
+		return int64(n), nil
+	case int64:
+		return n, nil
+	case int:
+		return int64(n), nil
+	case json.Number:
+		return n.Int64()
+	case float64:
+		return int64(n), nil
+	}
+	return 0, fmt.Errorf("expected an integer value, got %T", v)
+}
+
+//------------------------------------------------------------------------------
+
+// appendNativeJSON appends the JSON representation of a value decoded by
+// goavro, mapping logical types to readable values and naming union branches
+// according to the config.
+func (c avroConfig) appendNativeJSON(buf []byte, t *avroType, v any) ([]byte, error) {
+	if t.kind == "union" {
+		return c.appendUnionJSON(buf, t, v)
+	}
+	if v == nil {
+		return append(buf, "null"...), nil
+	}
+
+	if c.logicalTypes && t.logical != "" {
+		if s, handled, err := c.logicalToString(t, v); err != nil {
+			return nil, err
+		} else if handled {
+			return appendJSONValue(buf, s)
+		}
+	}
+
+	switch t.kind {
+	case "bytes", "fixed":
+		if r, isRat := v.(*big.Rat); isRat {
+			// Decimal logical types without the logical types mapping
+			// enabled.
+			size := 0
+			if t.kind == "fixed" {
+				size = t.size
+			}
+			b, err := avroDecimalToBytes(r.FloatString(t.scale), t.scale, size)
+			if err != nil {
+				return nil, err
+			}
+			return appendJSONValue(buf, avroJSONBytes(b))
+		}
+		b, ok := v.([]byte)
+		if !ok {
+			return nil, fmt.Errorf("expected bytes value, got %T", v)
+		}
+		return appendJSONValue(buf, avroJSONBytes(b))
+	case "float", "double":
+		f, ok := v.(float64)
+		if f32, is32 := v.(float32); is32 {
+			f, ok = float64(f32), true
+		}
+		if !ok {
+			return nil, fmt.Errorf("expected float value, got %T", v)
+		}
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			return nil, fmt.Errorf("float value %v cannot be represented in JSON", f)
+		}
+		bitSize := 64
+		if t.kind == "float" {
+			bitSize = 32
+		}
+		return strconv.AppendFloat(buf, f, 'g', -1, bitSize), nil
+	case "int", "long":
+		if d, isDur := v.(time.Duration); isDur {
+			// Time logical types without the logical types mapping enabled.
+			div := int64(time.Millisecond)
+			if t.logical == "time-micros" {
+				div = int64(time.Microsecond)
+			}
+			return strconv.AppendInt(buf, int64(d)/div, 10), nil
+		}
+		if ts, isTime := v.(time.Time); isTime {
+			return c.appendTimeNumber(buf, t, ts), nil
+		}
+		return appendJSONValue(buf, v)
+	case "array":
+		arr, ok := v.([]any)
+		if !ok {
+			return nil, fmt.Errorf("expected array value, got %T", v)
+		}
+		buf = append(buf, '[')
+		for i, e := range arr {
+			if i > 0 {
+				buf = append(buf, ',')
+			}
+			var err error
+			if buf, err = c.appendNativeJSON(buf, t.items, e); err != nil {
+				return nil, err
+			}
+		}
+		return append(buf, ']'), nil
+	case "map":
+		m, ok := v.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("expected map value, got %T", v)
+		}
+		keys := make([]string, 0, len(m))
+		for k := range m {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		buf = append(buf, '{')
+		for i, k := range keys {
+			if i > 0 {
+				buf = append(buf, ',')
+			}
+			var err error
+			if buf, err = appendJSONValue(buf, k); err != nil {
+				return nil, err
+			}
+			buf = append(buf, ':')
+			if buf, err = c.appendNativeJSON(buf, t.items, m[k]); err != nil {
+				return nil, err
+			}
+		}
+		return append(buf, '}'), nil
+	case "record":
+		m, ok := v.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("expected record value, got %T", v)
+		}
+		buf = append(buf, '{')
+		for i, f := range t.fields {
+			if i > 0 {
+				buf = append(buf, ',')
+			}
+			var err error
+			if buf, err = appendJSONValue(buf, f.name); err != nil {
+				return nil, err
+			}
+			buf = append(buf, ':')
+			if buf, err = c.appendNativeJSON(buf, f.t, m[f.name]); err != nil {
+				return nil, fmt.Errorf("field %v: %w", f.name, err)
+			}
+		}
+		return append(buf, '}'), nil
+	}
+	return appendJSONValue(buf, v)
+}
+
+func (c avroConfig) appendUnionJSON(buf []byte, t *avroType, v any) ([]byte, error) {
+	if v == nil {
+		return append(buf, "null"...), nil
+	}
+	m, ok := v.(map[string]any)
+	if !ok || len(m) != 1 {
+		return nil, fmt.Errorf("expected union value, got %T", v)
+	}
+	for k, bv := range m {
+		for _, b := range t.branches {
+			if b.goavroName() != k {
+				continue
+			}
+			if c.rawJSON {
+				return c.appendNativeJSON(buf, b, bv)
+			}
+			var err error
+			buf = append(buf, '{')
+			if buf, err = appendJSONValue(buf, b.unionName(c.unionNaming)); err != nil {
+				return nil, err
+			}
+			buf = append(buf, ':')
+			if buf, err = c.appendNativeJSON(buf, b, bv); err != nil {
+				return nil, err
+			}
+			return append(buf, '}'), nil
+		}
+		return nil, fmt.Errorf("union branch %v not found in schema", k)
+	}
+	return nil, errors.New("empty union value")
+}
+
+func (c avroConfig) appendTimeNumber(buf []byte, t *avroType, ts time.Time) []byte {
+	switch t.logical {
+	case "date":
+		return strconv.AppendInt(buf, ts.Unix()/86400, 10)
+	case "timestamp-micros":
+		return strconv.AppendInt(buf, ts.UnixMicro(), 10)
+	}
+	return strconv.AppendInt(buf, ts.UnixMilli(), 10)
+}
+
+// logicalToString returns the readable form of a logical type value decoded
+// by goavro, or false if the logical type is not recognised.
+func (c avroConfig) logicalToString(t *avroType, v any) (string, bool, error) {
+	switch t.logical {
+	case "timestamp-millis", "timestamp-micros", "timestamp-nanos":
+		if ts, isTime := v.(time.Time); isTime {
+			return ts.UTC().Format(time.RFC3339Nano), true, nil
+		}
+		n, err := avroInt64(v)
+		if err != nil {
+			return "", false, err
+		}
+		return avroUnixTime(t.logical, n).Format(time.RFC3339Nano), true, nil
+	case "local-timestamp-millis", "local-timestamp-micros", "local-timestamp-nanos":
+		n, err := avroInt64(v)
+		if err != nil {
+			return "", false, err
+		}
+		return avroUnixTime(t.logical, n).Format(avroLocalTimestampLayout), true, nil
+	case "date":
+		if ts, isTime := v.(time.Time); isTime {
+			return ts.UTC().Format(avroDateLayout), true, nil
+		}
+		n, err := avroInt64(v)
+		if err != nil {
+			return "", false, err
+		}
+		return time.Unix(n*86400, 0).UTC().Format(avroDateLayout), true, nil
+	case "time-millis", "time-micros":
+		d, isDur := v.(time.Duration)
+		if !isDur {
+			n, err := avroInt64(v)
+			if err != nil {
+				return "", false, err
+			}
+			if d = time.Duration(n) * time.Millisecond; t.logical == "time-micros" {
+				d = time.Duration(n) * time.Microsecond
+			}
+		}
+		return time.Time{}.Add(d).Format(avroTimeLayout), true, nil
+	case "decimal":
+		switch d := v.(type) {
+		case *big.Rat:
+			return d.FloatString(t.scale), true, nil
+		case []byte:
+			return avroDecimalFromBytes(d, t.scale), true, nil
+		}
+		return "", false, fmt.Errorf("expected decimal value, got %T", v)
+	case "uuid":
+		if b, isBytes := v.([]byte); isBytes {
+			u, err := uuid.FromBytes(b)
+			if err != nil {
+				return "", false, err
+			}
+			return u.String(), true, nil
+		}
+	}
+	return "", false, nil
+}
+
+func avroUnixTime(logical string, n int64) time.Time {
+	switch {
+	case strings.HasSuffix(logical, "-micros"):
+		return time.UnixMicro(n).UTC()
+	case strings.HasSuffix(logical, "-nanos"):
+		return time.Unix(0, n).UTC()
+	}
+	return time.UnixMilli(n).UTC()
+}
+
+func appendJSONValue(buf []byte, v any) ([]byte, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return append(buf, b...), nil
+}
+
+//------------------------------------------------------------------------------
+
+// toTextual maps a JSON document parsed with numbers preserved into the form
+// expected by goavro, converting readable logical type values and union
+// branch names according to the config.
+func (c avroConfig) toTextual(t *avroType, v any) (any, error) {
+	if v == nil {
+		return nil, nil
+	}
+
+	switch t.kind {
+	case "union":
+		return c.unionToTextual(t, v)
+	case "record":
+		m, ok := v.(map[string]any)
+		if !ok {
+			return v, nil
+		}
+		for _, f := range t.fields {
+			fv, exists := m[f.name]
+			if !exists {
+				continue
+			}
+			var err error
+			if m[f.name], err = c.toTextual(f.t, fv); err != nil {
+				return nil, fmt.Errorf("field %v: %w", f.name, err)
+			}
+		}
+		return m, nil
+	case "array":
+		arr, ok := v.([]any)
+		if !ok {
+			return v, nil
+		}
+		for i, e := range arr {
+			var err error
+			if arr[i], err = c.toTextual(t.items, e); err != nil {
+				return nil, err
+			}
+		}
+		return arr, nil
+	case "map":
+		m, ok := v.(map[string]any)
+		if !ok {
+			return v, nil
+		}
+		for k, e := range m {
+			var err error
+			if m[k], err = c.toTextual(t.items, e); err != nil {
+				return nil, err
+			}
+		}
+		return m, nil
+	}
+
+	if c.logicalTypes && t.logical != "" {
+		if tv, err := c.logicalFromReadable(t, v); err != nil || tv != nil {
+			return tv, err
+		}
+	}
+	if s, isStr := v.(string); isStr && (t.kind == "bytes" || t.kind == "fixed") {
+		// Each code point of a bytes string is a byte.
+		b, err := avroStringToBytes(s)
+		if err != nil {
+			return nil, err
+		}
+		return avroJSONBytes(b), nil
+	}
+	return v, nil
+}
+
+func (c avroConfig) unionToTextual(t *avroType, v any) (any, error) {
+	if c.rawJSON {
+		// Without union branch names we attempt the first branch that accepts
+		// the value.
+		for _, b := range t.branches {
+			if b.kind == "null" || !avroValueMatchesKind(b, v) {
+				continue
+			}
+			if tv, err := c.toTextual(b, v); err == nil {
+				return tv, nil
+			}
+		}
+		return v, nil
+	}
+
+	m, ok := v.(map[string]any)
+	if !ok || len(m) != 1 {
+		return v, nil
+	}
+	for k, bv := range m {
+		for _, b := range t.branches {
+			if k != b.goavroName() && k != b.unionName(avroUnionNamingType) && k != b.unionName(avroUnionNamingShort) {
+				continue
+			}
+			tv, err := c.toTextual(b, bv)
+			if err != nil {
+				return nil, err
+			}
+			return map[string]any{b.goavroName(): tv}, nil
+		}
+	}
+	return v, nil
+}
+
+func avroValueMatchesKind(t *avroType, v any) bool {
+	switch v.(type) {
+	case bool:
+		return t.kind == "boolean"
+	case json.Number:
+		switch t.kind {
+		case "int", "long", "float", "double":
+			return true
+		case "bytes", "fixed":
+			return t.logical == "decimal"
+		}
+	case string:
+		switch t.kind {
+		case "string", "bytes", "fixed", "enum":
+			return true
+		case "int", "long":
+			return t.logical != ""
+		}
+	case []any:
+		return t.kind == "array"
+	case map[string]any:
+		return t.kind == "record" || t.kind == "map"
+	}
+	return false
+}
+
+// logicalFromReadable converts a readable logical type value into the form
+// expected by goavro, values that are already in that form are unchanged, and
+// nil is returned for logical types that are not recognised.
+func (c avroConfig) logicalFromReadable(t *avroType, v any) (any, error) {
+	s, isStr := v.(string)
+
+	switch t.logical {
+	case "timestamp-millis", "timestamp-micros", "timestamp-nanos",
+		"local-timestamp-millis", "local-timestamp-micros", "local-timestamp-nanos":
+		if !isStr {
+			return v, nil
+		}
+		layout := time.RFC3339Nano
+		if strings.HasPrefix(t.logical, "local-") {
+			layout = avroLocalTimestampLayout
+		}
+		ts, err := time.Parse(layout, s)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %v value: %w", t.logical, err)
+		}
+		switch {
+		case strings.HasSuffix(t.logical, "-micros"):
+			return json.Number(strconv.FormatInt(ts.UnixMicro(), 10)), nil
+		case strings.HasSuffix(t.logical, "-nanos"):
+			return json.Number(strconv.FormatInt(ts.UnixNano(), 10)), nil
+		}
+		return json.Number(strconv.FormatInt(ts.UnixMilli(), 10)), nil
+	case "date":
+		if !isStr {
+			return v, nil
+		}
+		ts, err := time.Parse(avroDateLayout, s)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse date value: %w", err)
+		}
+		return json.Number(strconv.FormatInt(ts.Unix()/86400, 10)), nil
+	case "time-millis", "time-micros":
+		if !isStr {
+			return v, nil
+		}
+		ts, err := time.Parse(avroTimeLayout, s)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %v value: %w", t.logical, err)
+		}
+		d := ts.Sub(time.Date(ts.Year(), ts.Month(), ts.Day(), 0, 0, 0, 0, time.UTC))
+		if t.logical == "time-micros" {
+			return json.Number(strconv.FormatInt(d.Microseconds(), 10)), nil
+		}
+		return json.Number(strconv.FormatInt(d.Milliseconds(), 10)), nil
+	case "decimal":
+		size := 0
+		if t.kind == "fixed" {
+			size = t.size
+		}
+		b, err := avroDecimalToBytes(v, t.scale, size)
+		if err != nil {
+			return nil, err
+		}
+		return avroJSONBytes(b), nil
+	case "uuid":
+		if !isStr {
+			return v, nil
+		}
+		u, err := uuid.FromString(s)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse uuid value: %w", err)
+		}
+		if t.kind == "fixed" {
+			return avroJSONBytes(u.Bytes()), nil
+		}
+		return u.String(), nil
+	}
+	return nil, nil
+}
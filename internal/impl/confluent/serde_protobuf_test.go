@@ -79,10 +79,10 @@ message bar {
 	for _, test := range tests {
 		test := test
 		t.Run(test.name, func(t *testing.T) {
-			encoder, err := newSchemaRegistryEncoder(urlStr, noopReqSign, nil, subj, true, time.Minute*10, time.Minute, service.MockResources())
+			encoder, err := newSchemaRegistryEncoder(urlStr, noopReqSign, nil, subj, avroConfig{rawJSON: true}, time.Minute*10, time.Minute, service.MockResources())
 			require.NoError(t, err)
 
-			decoder, err := newSchemaRegistryDecoder(urlStr, noopReqSign, nil, true, service.MockResources())
+			decoder, err := newSchemaRegistryDecoder(urlStr, noopReqSign, nil, avroConfig{rawJSON: true}, service.MockResources())
 			require.NoError(t, err)
 
 			t.Cleanup(func() {
@@ -209,10 +209,10 @@ message bar {
 	for _, test := range tests {
 		test := test
 		t.Run(test.name, func(t *testing.T) {
-			encoder, err := newSchemaRegistryEncoder(urlStr, noopReqSign, nil, subj, true, time.Minute*10, time.Minute, service.MockResources())
+			encoder, err := newSchemaRegistryEncoder(urlStr, noopReqSign, nil, subj, avroConfig{rawJSON: true}, time.Minute*10, time.Minute, service.MockResources())
 			require.NoError(t, err)
 
-			decoder, err := newSchemaRegistryDecoder(urlStr, noopReqSign, nil, true, service.MockResources())
+			decoder, err := newSchemaRegistryDecoder(urlStr, noopReqSign, nil, avroConfig{rawJSON: true}, service.MockResources())
 			require.NoError(t, err)
 
 			t.Cleanup(func() {
@@ -268,7 +268,7 @@ func runEncoderAgainstInputsMultiple(t testing.TB, urlStr, subject string, input
 	subj, err := service.NewInterpolatedString(subject)
 	require.NoError(t, err)
 
-	encoder, err := newSchemaRegistryEncoder(urlStr, noopReqSign, nil, subj, true, time.Minute*10, time.Minute, service.MockResources())
+	encoder, err := newSchemaRegistryEncoder(urlStr, noopReqSign, nil, subj, avroConfig{rawJSON: true}, time.Minute*10, time.Minute, service.MockResources())
 	require.NoError(t, err)
 	t.Cleanup(func() {
 		_ = encoder.Close(tCtx)
@@ -0,0 +1,319 @@
+package crypto
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+)
+
+// fpeCipher is a format-preserving cipher operating on strings of numerals,
+// each of which is within the range [0, radix).
+type fpeCipher interface {
+	encrypt(x []int) ([]int, error)
+	decrypt(x []int) ([]int, error)
+}
+
+// fpeMinLen returns the minimum length of numeral strings for a radix, which
+// NIST SP 800-38G requires to have at least a million possible values.
+func fpeMinLen(radix int) int {
+	return int(math.Ceil(6 / math.Log10(float64(radix))))
+}
+
+func fpeNum(x []int, radix int) *big.Int {
+	n, r := new(big.Int), big.NewInt(int64(radix))
+	for _, d := range x {
+		n.Mul(n, r)
+		n.Add(n, big.NewInt(int64(d)))
+	}
+	return n
+}
+
+func fpeStr(n *big.Int, radix, m int) []int {
+	x := make([]int, m)
+	n, r, d := new(big.Int).Set(n), big.NewInt(int64(radix)), new(big.Int)
+	for i := m - 1; i >= 0; i-- {
+		n.DivMod(n, r, d)
+		x[i] = int(d.Int64())
+	}
+	return x
+}
+
+func fpeRev(x []int) []int {
+	r := make([]int, len(x))
+	for i, d := range x {
+		r[len(x)-1-i] = d
+	}
+	return r
+}
+
+func fpeRevBytes(b []byte) []byte {
+	r := make([]byte, len(b))
+	for i, c := range b {
+		r[len(b)-1-i] = c
+	}
+	return r
+}
+
+// fpeBytes writes the big-endian representation of n into a slice of length
+// l, truncating the most significant bytes when it doesn't fit.
+func fpeBytes(n *big.Int, l int) []byte {
+	b := n.Bytes()
+	if len(b) >= l {
+		return b[len(b)-l:]
+	}
+	out := make([]byte, l)
+	copy(out[l-len(b):], b)
+	return out
+}
+
+func fpeCheckNumerals(x []int, radix, minLen, maxLen int) error {
+	if len(x) < minLen {
+		return fmt.Errorf("value must contain at least %v characters of the alphabet, got %v", minLen, len(x))
+	}
+	if maxLen > 0 && len(x) > maxLen {
+		return fmt.Errorf("value must contain at most %v characters of the alphabet, got %v", maxLen, len(x))
+	}
+	for _, d := range x {
+		if d < 0 || d >= radix {
+			return fmt.Errorf("numeral %v is outside of radix %v", d, radix)
+		}
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+// ff1 implements the FF1 mode of NIST SP 800-38G.
+type ff1 struct {
+	block  cipher.Block
+	radix  int
+	tweak  []byte
+	minLen int
+}
+
+func newFF1(key, tweak []byte, radix int) (*ff1, error) {
+	if radix < 2 || radix > 1<<16 {
+		return nil, fmt.Errorf("radix must be between 2 and 65536, got %v", radix)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return &ff1{
+		block:  block,
+		radix:  radix,
+		tweak:  tweak,
+		minLen: fpeMinLen(radix),
+	}, nil
+}
+
+// prf computes the CBC-MAC of the input with a zero IV, the input must be a
+// multiple of the block size.
+func (f *ff1) prf(in []byte) []byte {
+	y := make([]byte, aes.BlockSize)
+	for i := 0; i < len(in); i += aes.BlockSize {
+		for j := 0; j < aes.BlockSize; j++ {
+			y[j] ^= in[i+j]
+		}
+		f.block.Encrypt(y, y)
+	}
+	return y
+}
+
+func (f *ff1) round(p []byte, i, b, d int, x []int) *big.Int {
+	t := len(f.tweak)
+	pad := ((-t-b-1)%16 + 16) % 16
+
+	q := make([]byte, 0, t+pad+1+b)
+	q = append(q, f.tweak...)
+	q = append(q, make([]byte, pad)...)
+	q = append(q, byte(i))
+	q = append(q, fpeBytes(fpeNum(x, f.radix), b)...)
+
+	r := f.prf(append(append([]byte{}, p...), q...))
+
+	s := make([]byte, 0, d+aes.BlockSize)
+	s = append(s, r...)
+	for j := 1; len(s) < d; j++ {
+		blk := make([]byte, aes.BlockSize)
+		copy(blk[aes.BlockSize-4:], []byte{byte(j >> 24), byte(j >> 16), byte(j >> 8), byte(j)})
+		for k := range blk {
+			blk[k] ^= r[k]
+		}
+		f.block.Encrypt(blk, blk)
+		s = append(s, blk...)
+	}
+	return new(big.Int).SetBytes(s[:d])
+}
+
+func (f *ff1) params(n int) (u, v, b, d int, p []byte) {
+	u = n / 2
+	v = n - u
+	b = int(math.Ceil(math.Ceil(float64(v)*math.Log2(float64(f.radix))) / 8))
+	d = 4*((b+3)/4) + 4
+
+	t := len(f.tweak)
+	p = []byte{
+		1, 2, 1,
+		byte(f.radix >> 16), byte(f.radix >> 8), byte(f.radix),
+		10, byte(u),
+		byte(n >> 24), byte(n >> 16), byte(n >> 8), byte(n),
+		byte(t >> 24), byte(t >> 16), byte(t >> 8), byte(t),
+	}
+	return
+}
+
+func (f *ff1) encrypt(x []int) ([]int, error) {
+	if err := fpeCheckNumerals(x, f.radix, f.minLen, 0); err != nil {
+		return nil, err
+	}
+
+	n := len(x)
+	u, v, b, d, p := f.params(n)
+	a, bx := x[:u], x[u:]
+
+	radix := big.NewInt(int64(f.radix))
+	for i := 0; i < 10; i++ {
+		m := u
+		if i%2 == 1 {
+			m = v
+		}
+		y := f.round(p, i, b, d, bx)
+		c := new(big.Int).Add(fpeNum(a, f.radix), y)
+		c.Mod(c, new(big.Int).Exp(radix, big.NewInt(int64(m)), nil))
+		a, bx = bx, fpeStr(c, f.radix, m)
+	}
+	return append(append([]int{}, a...), bx...), nil
+}
+
+func (f *ff1) decrypt(x []int) ([]int, error) {
+	if err := fpeCheckNumerals(x, f.radix, f.minLen, 0); err != nil {
+		return nil, err
+	}
+
+	n := len(x)
+	u, v, b, d, p := f.params(n)
+	a, bx := x[:u], x[u:]
+
+	radix := big.NewInt(int64(f.radix))
+	for i := 9; i >= 0; i-- {
+		m := u
+		if i%2 == 1 {
+			m = v
+		}
+		y := f.round(p, i, b, d, a)
+		c := new(big.Int).Sub(fpeNum(bx, f.radix), y)
+		c.Mod(c, new(big.Int).Exp(radix, big.NewInt(int64(m)), nil))
+		a, bx = fpeStr(c, f.radix, m), a
+	}
+	return append(append([]int{}, a...), bx...), nil
+}
+
+//------------------------------------------------------------------------------
+
+// ff3 implements the FF3 mode of NIST SP 800-38G, where the tweak is already
+// split into its left and right halves. The FF3-1 mode is constructed with
+// newFF31.
+type ff3 struct {
+	block          cipher.Block
+	radix          int
+	tweakL, tweakR []byte
+	minLen, maxLen int
+}
+
+func newFF3(key []byte, tweakL, tweakR []byte, radix int) (*ff3, error) {
+	if radix < 2 || radix > 1<<16 {
+		return nil, fmt.Errorf("radix must be between 2 and 65536, got %v", radix)
+	}
+	block, err := aes.NewCipher(fpeRevBytes(key))
+	if err != nil {
+		return nil, err
+	}
+	return &ff3{
+		block:  block,
+		radix:  radix,
+		tweakL: tweakL,
+		tweakR: tweakR,
+		minLen: fpeMinLen(radix),
+		maxLen: 2 * int(math.Floor(96/math.Log2(float64(radix)))),
+	}, nil
+}
+
+// newFF31 creates an FF3-1 cipher from a 56 bit tweak.
+func newFF31(key, tweak []byte, radix int) (*ff3, error) {
+	if len(tweak) != 7 {
+		return nil, errors.New("ff3-1 tweaks must be exactly 7 bytes")
+	}
+	tweakL := []byte{tweak[0], tweak[1], tweak[2], tweak[3] & 0xf0}
+	tweakR := []byte{tweak[4], tweak[5], tweak[6], tweak[3] << 4}
+	return newFF3(key, tweakL, tweakR, radix)
+}
+
+func (f *ff3) round(i int, x []int) *big.Int {
+	w := f.tweakR
+	if i%2 == 1 {
+		w = f.tweakL
+	}
+
+	p := make([]byte, 16)
+	copy(p, w)
+	p[3] ^= byte(i)
+	copy(p[4:], fpeBytes(fpeNum(fpeRev(x), f.radix), 12))
+
+	s := fpeRevBytes(p)
+	f.block.Encrypt(s, s)
+	return new(big.Int).SetBytes(fpeRevBytes(s))
+}
+
+func (f *ff3) split(n int) (u, v int) {
+	u = (n + 1) / 2
+	v = n - u
+	return
+}
+
+func (f *ff3) encrypt(x []int) ([]int, error) {
+	if err := fpeCheckNumerals(x, f.radix, f.minLen, f.maxLen); err != nil {
+		return nil, err
+	}
+
+	u, v := f.split(len(x))
+	a, b := x[:u], x[u:]
+
+	radix := big.NewInt(int64(f.radix))
+	for i := 0; i < 8; i++ {
+		m := u
+		if i%2 == 1 {
+			m = v
+		}
+		y := f.round(i, b)
+		c := new(big.Int).Add(fpeNum(fpeRev(a), f.radix), y)
+		c.Mod(c, new(big.Int).Exp(radix, big.NewInt(int64(m)), nil))
+		a, b = b, fpeRev(fpeStr(c, f.radix, m))
+	}
+	return append(append([]int{}, a...), b...), nil
+}
+
+func (f *ff3) decrypt(x []int) ([]int, error) {
+	if err := fpeCheckNumerals(x, f.radix, f.minLen, f.maxLen); err != nil {
+		return nil, err
+	}
+
+	u, v := f.split(len(x))
+	a, b := x[:u], x[u:]
+
+	radix := big.NewInt(int64(f.radix))
+	for i := 7; i >= 0; i-- {
+		m := u
+		if i%2 == 1 {
+			m = v
+		}
+		y := f.round(i, a)
+		c := new(big.Int).Sub(fpeNum(fpeRev(b), f.radix), y)
+		c.Mod(c, new(big.Int).Exp(radix, big.NewInt(int64(m)), nil))
+		a, b = fpeRev(fpeStr(c, f.radix, m)), a
+	}
+	return append(append([]int{}, a...), b...), nil
+}
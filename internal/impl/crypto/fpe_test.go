@@ -0,0 +1,139 @@
+package crypto
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const fpeTestAlphabet = "0123456789abcdefghijklmnopqrstuvwxyz"
+
+func fpeTestNumerals(s string) []int {
+	x := make([]int, 0, len(s))
+	for _, c := range s {
+		x = append(x, strings.IndexRune(fpeTestAlphabet, c))
+	}
+	return x
+}
+
+func fpeTestString(x []int) string {
+	var b strings.Builder
+	for _, d := range x {
+		b.WriteByte(fpeTestAlphabet[d])
+	}
+	return b.String()
+}
+
+func fpeTestHex(t testing.TB, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	require.NoError(t, err)
+	return b
+}
+
+func TestFF1Vectors(t *testing.T) {
+	// Samples from NIST SP 800-38G.
+	for _, test := range []struct {
+		key, tweak string
+		radix      int
+		plain      string
+		cipher     string
+	}{
+		{
+			key:    "2B7E151628AED2A6ABF7158809CF4F3C",
+			radix:  10,
+			plain:  "0123456789",
+			cipher: "2433477484",
+		},
+		{
+			key:    "2B7E151628AED2A6ABF7158809CF4F3C",
+			tweak:  "39383736353433323130",
+			radix:  10,
+			plain:  "0123456789",
+			cipher: "6124200773",
+		},
+		{
+			key:    "2B7E151628AED2A6ABF7158809CF4F3C",
+			tweak:  "3737373770717273373737",
+			radix:  36,
+			plain:  "0123456789abcdefghi",
+			cipher: "a9tv40mll9kdu509eum",
+		},
+		{
+			key:    "2B7E151628AED2A6ABF7158809CF4F3CEF4359D8D580AA4F7F036D6F04FC6A94",
+			radix:  10,
+			plain:  "0123456789",
+			cipher: "6657667009",
+		},
+	} {
+		f, err := newFF1(fpeTestHex(t, test.key), fpeTestHex(t, test.tweak), test.radix)
+		require.NoError(t, err)
+
+		c, err := f.encrypt(fpeTestNumerals(test.plain))
+		require.NoError(t, err)
+		assert.Equal(t, test.cipher, fpeTestString(c))
+
+		p, err := f.decrypt(c)
+		require.NoError(t, err)
+		assert.Equal(t, test.plain, fpeTestString(p))
+	}
+}
+
+func TestFF3Vectors(t *testing.T) {
+	// Samples of FF3 from NIST SP 800-38G, with 64 bit tweaks.
+	for _, test := range []struct {
+		key, tweak string
+		plain      string
+		cipher     string
+	}{
+		{
+			key:    "EF4359D8D580AA4F7F036D6F04FC6A94",
+			tweak:  "D8E7920AFA330A73",
+			plain:  "890121234567890000",
+			cipher: "750918814058654607",
+		},
+		{
+			key:    "EF4359D8D580AA4F7F036D6F04FC6A94",
+			tweak:  "9A768A92F60E12D8",
+			plain:  "890121234567890000",
+			cipher: "018989839189395384",
+		},
+	} {
+		tweak := fpeTestHex(t, test.tweak)
+		f, err := newFF3(fpeTestHex(t, test.key), tweak[:4], tweak[4:], 10)
+		require.NoError(t, err)
+
+		c, err := f.encrypt(fpeTestNumerals(test.plain))
+		require.NoError(t, err)
+		assert.Equal(t, test.cipher, fpeTestString(c))
+
+		p, err := f.decrypt(c)
+		require.NoError(t, err)
+		assert.Equal(t, test.plain, fpeTestString(p))
+	}
+}
+
+func TestFF31(t *testing.T) {
+	f, err := newFF31(fpeTestHex(t, "EF4359D8D580AA4F7F036D6F04FC6A94"), fpeTestHex(t, "D8E7920AFA330A"), 10)
+	require.NoError(t, err)
+
+	c, err := f.encrypt(fpeTestNumerals("890121234567890000"))
+	require.NoError(t, err)
+	assert.Equal(t, "477064185124354662", fpeTestString(c))
+
+	p, err := f.decrypt(c)
+	require.NoError(t, err)
+	assert.Equal(t, "890121234567890000", fpeTestString(p))
+
+	_, err = f.encrypt(fpeTestNumerals("12345"))
+	require.Error(t, err)
+
+	_, err = f.encrypt(fpeTestNumerals(strings.Repeat("1", 57)))
+	require.Error(t, err)
+
+	_, err = newFF31(fpeTestHex(t, "EF4359D8D580AA4F7F036D6F04FC6A94"), fpeTestHex(t, "D8E7920AFA330A73"), 10)
+	require.Error(t, err)
+}
@@ -0,0 +1,407 @@
+package crypto
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/Jeffail/gabs/v2"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	fpeFieldOperation = "operation"
+	fpeFieldAlgorithm = "algorithm"
+	fpeFieldKey       = "key"
+	fpeFieldTweak     = "tweak"
+	fpeFieldAlphabet  = "alphabet"
+	fpeFieldFields    = "fields"
+	fpeFieldCache     = "cache"
+
+	fpeOpEncrypt    = "encrypt"
+	fpeOpDecrypt    = "decrypt"
+	fpeOpTokenise   = "tokenise"
+	fpeOpDetokenise = "detokenise"
+
+	fpeAlgoFF1  = "ff1"
+	fpeAlgoFF31 = "ff3-1"
+
+	fpeCacheTokenPrefix = "fpe_token:"
+	fpeCacheValuePrefix = "fpe_value:"
+)
+
+func fpeProcSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Categories("Utility").
+		Version("4.28.0").
+		Summary("Protects values such as card numbers and social security numbers with format-preserving encryption or reversible tokenisation, where protected values keep the length and character set of the original.").
+		Description(`
+By default the entire contents of each message are protected, and when `+"`fields`"+` are specified the string values at those paths of the message, parsed as JSON, are protected instead.
+
+Only the characters of a value that belong to the `+"`alphabet`"+` are protected, and all other characters, such as the separators of `+"`4111-1111-1111-1111`"+` or `+"`123-45-6789`"+`, are kept in place. Protected values therefore remain valid against the schemas of downstream systems.
+
+### Encryption
+
+The operations `+"`encrypt`"+` and `+"`decrypt`"+` use the FF1 or FF3-1 modes of [NIST SP 800-38G](https://csrc.nist.gov/pubs/sp/800/38/g/r1/ipd) with an AES key, and are deterministic: equal values encrypted with the same key and tweak produce equal results. The number of alphabet characters within each value must be enough for a million possible values, which is six characters for the default alphabet of digits, and FF3-1 additionally limits values to 56 digits.
+
+### Tokenisation
+
+The operation `+"`tokenise`"+` replaces each value with a random token of the same format, and stores the original value within a `+"[`cache` resource](/docs/components/caches/about)"+` under the key `+"`fpe_token:<token>`"+`, from which the operation `+"`detokenise`"+` restores it. Tokens are not derived from the values they replace, and therefore cannot be reversed without access to the cache, which serves as a token vault.
+
+Equal values are given equal tokens by also storing the token of each value under a key derived from a SHA-256 hash of the value, prefixed with `+"`fpe_value:`"+`. When a `+"`key`"+` is set the hash is computed as an HMAC, which prevents the values of tokens from being found by hashing guesses, and is strongly recommended for values with few possibilities such as social security numbers. The cache must not expire keys for tokens to remain reversible.`).
+		Field(service.NewStringEnumField(fpeFieldOperation, fpeOpEncrypt, fpeOpDecrypt, fpeOpTokenise, fpeOpDetokenise).
+			Description("The operation to perform on values.")).
+		Field(service.NewStringEnumField(fpeFieldAlgorithm, fpeAlgoFF1, fpeAlgoFF31).
+			Description("The format-preserving encryption mode to use for the operations `encrypt` and `decrypt`.").
+			Default(fpeAlgoFF1)).
+		Field(service.NewStringField(fpeFieldKey).
+			Description("A hex encoded AES key of 16, 24 or 32 bytes. Required for the operations `encrypt` and `decrypt`, and optionally used for hashing values for the operation `tokenise`.").
+			Secret().
+			Optional()).
+		Field(service.NewStringField(fpeFieldTweak).
+			Description("A hex encoded tweak for the operations `encrypt` and `decrypt`. FF1 accepts tweaks of any length, and FF3-1 requires tweaks of exactly 7 bytes.").
+			Example("d8e7920afa330a").
+			Advanced().
+			Default("")).
+		Field(service.NewStringField(fpeFieldAlphabet).
+			Description("The characters of values that are protected, in order, where all other characters are kept in place.").
+			Example("0123456789abcdefghijklmnopqrstuvwxyz").
+			Default("0123456789")).
+		Field(service.NewStringListField(fpeFieldFields).
+			Description("A list of [dot paths](/docs/configuration/field_paths) of string values within messages parsed as JSON to protect. When empty the entire contents of each message are protected.").
+			Example([]string{"card.number", "customer.ssn"}).
+			Default([]any{})).
+		Field(service.NewStringField(fpeFieldCache).
+			Description("The [`cache` resource](/docs/components/caches/about) used as a token vault, required for the operations `tokenise` and `detokenise`.").
+			Optional()).
+		Example(
+			"Encrypt card numbers",
+			"Here we encrypt the card numbers within documents with FF1, keeping their separators, and decrypt them again further down the pipeline.",
+			`
+pipeline:
+  processors:
+    - fpe:
+        operation: encrypt
+        key: ${FPE_KEY}
+        fields: [ payment.card_number ]
+
+    # Processing of protected documents...
+
+    - fpe:
+        operation: decrypt
+        key: ${FPE_KEY}
+        fields: [ payment.card_number ]
+`,
+		).
+		Example(
+			"Tokenise social security numbers",
+			"Here we replace social security numbers with tokens of the same format, and keep the original values within a Redis cache.",
+			`
+pipeline:
+  processors:
+    - fpe:
+        operation: tokenise
+        key: ${FPE_HASH_KEY}
+        fields: [ customer.ssn ]
+        cache: vault
+
+cache_resources:
+  - label: vault
+    redis:
+      url: tcp://localhost:6379
+`,
+		)
+}
+
+func init() {
+	err := service.RegisterProcessor(
+		"fpe", fpeProcSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Processor, error) {
+			return newFPEProcFromConfig(conf, mgr)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type fpeProc struct {
+	mgr *service.Resources
+
+	operation string
+	cipher    fpeCipher
+	hashKey   []byte
+	alphabet  []rune
+	indexes   map[rune]int
+	fields    []string
+	cache     string
+}
+
+func newFPEProcFromConfig(conf *service.ParsedConfig, mgr *service.Resources) (*fpeProc, error) {
+	p := &fpeProc{mgr: mgr}
+
+	var err error
+	if p.operation, err = conf.FieldString(fpeFieldOperation); err != nil {
+		return nil, err
+	}
+
+	alphabetStr, err := conf.FieldString(fpeFieldAlphabet)
+	if err != nil {
+		return nil, err
+	}
+	p.alphabet = []rune(alphabetStr)
+	if len(p.alphabet) < 2 {
+		return nil, errors.New("alphabet must contain at least two characters")
+	}
+	p.indexes = make(map[rune]int, len(p.alphabet))
+	for i, c := range p.alphabet {
+		if _, exists := p.indexes[c]; exists {
+			return nil, fmt.Errorf("alphabet contains the character %q more than once", c)
+		}
+		p.indexes[c] = i
+	}
+
+	if p.fields, err = conf.FieldStringList(fpeFieldFields); err != nil {
+		return nil, err
+	}
+
+	var key []byte
+	if conf.Contains(fpeFieldKey) {
+		keyStr, err := conf.FieldString(fpeFieldKey)
+		if err != nil {
+			return nil, err
+		}
+		if key, err = hex.DecodeString(keyStr); err != nil {
+			return nil, fmt.Errorf("failed to decode key: %w", err)
+		}
+	}
+
+	switch p.operation {
+	case fpeOpEncrypt, fpeOpDecrypt:
+		if len(key) == 0 {
+			return nil, fmt.Errorf("a key is required for the operation %v", p.operation)
+		}
+
+		tweakStr, err := conf.FieldString(fpeFieldTweak)
+		if err != nil {
+			return nil, err
+		}
+		tweak, err := hex.DecodeString(tweakStr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode tweak: %w", err)
+		}
+
+		algorithm, err := conf.FieldString(fpeFieldAlgorithm)
+		if err != nil {
+			return nil, err
+		}
+		if algorithm == fpeAlgoFF31 {
+			p.cipher, err = newFF31(key, tweak, len(p.alphabet))
+		} else {
+			p.cipher, err = newFF1(key, tweak, len(p.alphabet))
+		}
+		if err != nil {
+			return nil, err
+		}
+	case fpeOpTokenise, fpeOpDetokenise:
+		p.hashKey = key
+		if !conf.Contains(fpeFieldCache) {
+			return nil, fmt.Errorf("a cache is required for the operation %v", p.operation)
+		}
+		if p.cache, err = conf.FieldString(fpeFieldCache); err != nil {
+			return nil, err
+		}
+		if !mgr.HasCache(p.cache) {
+			return nil, fmt.Errorf("cache named %v not found", p.cache)
+		}
+	}
+	return p, nil
+}
+
+// numerals returns the alphabet indexes of the characters of a value that
+// belong to the alphabet.
+func (p *fpeProc) numerals(v []rune) []int {
+	x := make([]int, 0, len(v))
+	for _, c := range v {
+		if i, exists := p.indexes[c]; exists {
+			x = append(x, i)
+		}
+	}
+	return x
+}
+
+// replaceNumerals returns a value where the characters that belong to the
+// alphabet are replaced by the characters of a string of numerals.
+func (p *fpeProc) replaceNumerals(v []rune, x []int) string {
+	out := make([]rune, len(v))
+	var j int
+	for i, c := range v {
+		if _, exists := p.indexes[c]; exists {
+			c = p.alphabet[x[j]]
+			j++
+		}
+		out[i] = c
+	}
+	return string(out)
+}
+
+func (p *fpeProc) randomToken(v []rune) (string, error) {
+	x := make([]int, len(p.numerals(v)))
+	radix := big.NewInt(int64(len(p.alphabet)))
+	for i := range x {
+		n, err := rand.Int(rand.Reader, radix)
+		if err != nil {
+			return "", err
+		}
+		x[i] = int(n.Int64())
+	}
+	return p.replaceNumerals(v, x), nil
+}
+
+func (p *fpeProc) valueKey(v string) string {
+	var sum []byte
+	if len(p.hashKey) > 0 {
+		h := hmac.New(sha256.New, p.hashKey)
+		_, _ = h.Write([]byte(v))
+		sum = h.Sum(nil)
+	} else {
+		s := sha256.Sum256([]byte(v))
+		sum = s[:]
+	}
+	return fpeCacheValuePrefix + hex.EncodeToString(sum)
+}
+
+func (p *fpeProc) tokenise(ctx context.Context, v string) (token string, err error) {
+	valueKey := p.valueKey(v)
+
+	var existing []byte
+	if cerr := p.mgr.AccessCache(ctx, p.cache, func(c service.Cache) {
+		existing, err = c.Get(ctx, valueKey)
+	}); cerr != nil {
+		return "", cerr
+	}
+	if err == nil {
+		return string(existing), nil
+	}
+	if !errors.Is(err, service.ErrKeyNotFound) {
+		return "", err
+	}
+
+	runes := []rune(v)
+	for attempts := 0; ; attempts++ {
+		if attempts >= 10 {
+			return "", errors.New("failed to generate a unique token, the alphabet or value may be too short")
+		}
+		if token, err = p.randomToken(runes); err != nil {
+			return "", err
+		}
+		if token == v {
+			continue
+		}
+		if cerr := p.mgr.AccessCache(ctx, p.cache, func(c service.Cache) {
+			err = c.Add(ctx, fpeCacheTokenPrefix+token, []byte(v), nil)
+		}); cerr != nil {
+			return "", cerr
+		}
+		if errors.Is(err, service.ErrKeyAlreadyExists) {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		break
+	}
+
+	if cerr := p.mgr.AccessCache(ctx, p.cache, func(c service.Cache) {
+		err = c.Set(ctx, valueKey, []byte(token), nil)
+	}); cerr != nil {
+		return "", cerr
+	}
+	return token, err
+}
+
+func (p *fpeProc) detokenise(ctx context.Context, token string) (v string, err error) {
+	var original []byte
+	if cerr := p.mgr.AccessCache(ctx, p.cache, func(c service.Cache) {
+		original, err = c.Get(ctx, fpeCacheTokenPrefix+token)
+	}); cerr != nil {
+		return "", cerr
+	}
+	if errors.Is(err, service.ErrKeyNotFound) {
+		return "", errors.New("token not found")
+	}
+	return string(original), err
+}
+
+func (p *fpeProc) transform(ctx context.Context, v string) (string, error) {
+	switch p.operation {
+	case fpeOpTokenise:
+		return p.tokenise(ctx, v)
+	case fpeOpDetokenise:
+		return p.detokenise(ctx, v)
+	}
+
+	runes := []rune(v)
+	x := p.numerals(runes)
+
+	var err error
+	if p.operation == fpeOpEncrypt {
+		x, err = p.cipher.encrypt(x)
+	} else {
+		x, err = p.cipher.decrypt(x)
+	}
+	if err != nil {
+		return "", err
+	}
+	return p.replaceNumerals(runes, x), nil
+}
+
+func (p *fpeProc) Process(ctx context.Context, msg *service.Message) (service.MessageBatch, error) {
+	if len(p.fields) == 0 {
+		b, err := msg.AsBytes()
+		if err != nil {
+			return nil, err
+		}
+		res, err := p.transform(ctx, string(b))
+		if err != nil {
+			return nil, err
+		}
+		msg.SetBytes([]byte(res))
+		return service.MessageBatch{msg}, nil
+	}
+
+	v, err := msg.AsStructuredMut()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse message as JSON: %w", err)
+	}
+
+	doc := gabs.Wrap(v)
+	for _, path := range p.fields {
+		target := doc.Path(path)
+		if target.Data() == nil {
+			continue
+		}
+		str, ok := target.Data().(string)
+		if !ok {
+			return nil, fmt.Errorf("field %v: expected string value, got %T", path, target.Data())
+		}
+		res, err := p.transform(ctx, str)
+		if err != nil {
+			return nil, fmt.Errorf("field %v: %w", path, err)
+		}
+		if _, err := doc.SetP(res, path); err != nil {
+			return nil, fmt.Errorf("field %v: %w", path, err)
+		}
+	}
+	msg.SetStructuredMut(doc.Data())
+	return service.MessageBatch{msg}, nil
+}
+
+func (p *fpeProc) Close(ctx context.Context) error {
+	return nil
+}
@@ -0,0 +1,140 @@
+package crypto
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func fpeTestProc(t testing.TB, mgr *service.Resources, confStr string) *fpeProc {
+	t.Helper()
+
+	conf, err := fpeProcSpec().ParseYAML(confStr, nil)
+	require.NoError(t, err)
+
+	proc, err := newFPEProcFromConfig(conf, mgr)
+	require.NoError(t, err)
+	return proc
+}
+
+func TestFPEProcessorEncryptFields(t *testing.T) {
+	for _, algo := range []string{"ff1", "ff3-1"} {
+		algo := algo
+		t.Run(algo, func(t *testing.T) {
+			confStr := `
+algorithm: ` + algo + `
+key: 2B7E151628AED2A6ABF7158809CF4F3C
+tweak: 39383736353433
+fields: [ card, customer.ssn, missing ]
+`
+			enc := fpeTestProc(t, service.MockResources(), "operation: encrypt"+confStr)
+			dec := fpeTestProc(t, service.MockResources(), "operation: decrypt"+confStr)
+
+			input := `{"card":"4111-1111-1111-1111","customer":{"ssn":"123-45-6789","name":"foo"}}`
+			res, err := enc.Process(context.Background(), service.NewMessage([]byte(input)))
+			require.NoError(t, err)
+			require.Len(t, res, 1)
+
+			v, err := res[0].AsStructured()
+			require.NoError(t, err)
+			doc := v.(map[string]any)
+			assert.Regexp(t, `^\d{4}-\d{4}-\d{4}-\d{4}$`, doc["card"])
+			assert.NotEqual(t, "4111-1111-1111-1111", doc["card"])
+			assert.Regexp(t, `^\d{3}-\d{2}-\d{4}$`, doc["customer"].(map[string]any)["ssn"])
+			assert.Equal(t, "foo", doc["customer"].(map[string]any)["name"])
+
+			res, err = dec.Process(context.Background(), res[0])
+			require.NoError(t, err)
+			require.Len(t, res, 1)
+
+			b, err := res[0].AsBytes()
+			require.NoError(t, err)
+			assert.JSONEq(t, input, string(b))
+		})
+	}
+}
+
+func TestFPEProcessorEncryptContent(t *testing.T) {
+	proc := fpeTestProc(t, service.MockResources(), `
+operation: encrypt
+key: 2B7E151628AED2A6ABF7158809CF4F3C
+alphabet: 0123456789abcdefghijklmnopqrstuvwxyz
+`)
+
+	res, err := proc.Process(context.Background(), service.NewMessage([]byte("0123456789abcdefghi")))
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+
+	b, err := res[0].AsBytes()
+	require.NoError(t, err)
+	assert.Regexp(t, `^[0-9a-z]{19}$`, string(b))
+
+	_, err = proc.Process(context.Background(), service.NewMessage([]byte("abc")))
+	require.Error(t, err)
+}
+
+func TestFPEProcessorTokenise(t *testing.T) {
+	mgr := service.MockResources(service.MockResourcesOptAddCache("vault"))
+
+	tok := fpeTestProc(t, mgr, `
+operation: tokenise
+key: 2B7E151628AED2A6ABF7158809CF4F3C
+fields: [ ssn ]
+cache: vault
+`)
+	detok := fpeTestProc(t, mgr, `
+operation: detokenise
+fields: [ ssn ]
+cache: vault
+`)
+
+	var tokens []string
+	for _, ssn := range []string{"123-45-6789", "123-45-6789", "987-65-4321"} {
+		res, err := tok.Process(context.Background(), service.NewMessage([]byte(`{"ssn":"`+ssn+`"}`)))
+		require.NoError(t, err)
+		require.Len(t, res, 1)
+
+		v, err := res[0].AsStructured()
+		require.NoError(t, err)
+		token := v.(map[string]any)["ssn"].(string)
+		assert.Regexp(t, `^\d{3}-\d{2}-\d{4}$`, token)
+		assert.NotEqual(t, ssn, token)
+		tokens = append(tokens, token)
+
+		res, err = detok.Process(context.Background(), res[0])
+		require.NoError(t, err)
+		require.Len(t, res, 1)
+
+		b, err := res[0].AsBytes()
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"ssn":"`+ssn+`"}`, string(b))
+	}
+	assert.Equal(t, tokens[0], tokens[1])
+	assert.NotEqual(t, tokens[0], tokens[2])
+
+	_, err := detok.Process(context.Background(), service.NewMessage([]byte(`{"ssn":"000-00-0000"}`)))
+	require.Error(t, err)
+}
+
+func TestFPEProcessorConfigErrors(t *testing.T) {
+	for _, confStr := range []string{
+		`operation: encrypt`,
+		`{ operation: encrypt, key: nothex }`,
+		`{ operation: encrypt, key: 2B7E15 }`,
+		`{ operation: encrypt, key: 2B7E151628AED2A6ABF7158809CF4F3C, algorithm: ff3-1 }`,
+		`{ operation: encrypt, key: 2B7E151628AED2A6ABF7158809CF4F3C, alphabet: "0" }`,
+		`{ operation: encrypt, key: 2B7E151628AED2A6ABF7158809CF4F3C, alphabet: "001" }`,
+		`operation: tokenise`,
+		`{ operation: tokenise, cache: nope }`,
+	} {
+		conf, err := fpeProcSpec().ParseYAML(confStr, nil)
+		require.NoError(t, err, confStr)
+
+		_, err = newFPEProcFromConfig(conf, service.MockResources())
+		require.Error(t, err, confStr)
+	}
+}
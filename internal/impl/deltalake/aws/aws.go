@@ -0,0 +1,121 @@
+package aws
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+
+	baws "github.com/benthosdev/benthos/v4/internal/impl/aws"
+	"github.com/benthosdev/benthos/v4/internal/impl/deltalake"
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func init() {
+	deltalake.AWSOptFn = func(conf *service.ParsedConfig, opts *deltalake.ClientOptions) error {
+		if enabled, _ := conf.FieldBool(deltalake.DLOFieldAWSEnabled); !enabled {
+			return nil
+		}
+
+		sess, err := baws.GetSession(context.TODO(), conf)
+		if err != nil {
+			return err
+		}
+
+		forcePathStyle, err := conf.FieldBool(deltalake.DLOFieldAWSForcePathStyle)
+		if err != nil {
+			return err
+		}
+
+		storage := &s3Storage{
+			client: s3.NewFromConfig(sess, func(o *s3.Options) {
+				o.UsePathStyle = forcePathStyle
+			}),
+		}
+		for _, scheme := range []string{"s3", "s3a", "s3n"} {
+			opts.Storage[scheme] = storage
+		}
+		return nil
+	}
+}
+
+type s3Storage struct {
+	client *s3.Client
+}
+
+func bucketAndKey(location string) (bucket, key string, err error) {
+	u, err := url.Parse(location)
+	if err != nil {
+		return "", "", err
+	}
+	if u.Host == "" {
+		return "", "", fmt.Errorf("location '%v' is missing a bucket", location)
+	}
+	return u.Host, strings.TrimPrefix(u.Path, "/"), nil
+}
+
+func (s *s3Storage) Put(ctx context.Context, location string, data []byte) error {
+	bucket, key, err := bucketAndKey(location)
+	if err != nil {
+		return err
+	}
+	_, err = s.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket: &bucket,
+		Key:    &key,
+		Body:   bytes.NewReader(data),
+	})
+	return err
+}
+
+// PutIfAbsent writes an object with a conditional write that fails when an
+// object already exists at the location.
+func (s *s3Storage) PutIfAbsent(ctx context.Context, location string, data []byte) error {
+	bucket, key, err := bucketAndKey(location)
+	if err != nil {
+		return err
+	}
+	_, err = s.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:      &bucket,
+		Key:         &key,
+		Body:        bytes.NewReader(data),
+		IfNoneMatch: aws.String("*"),
+	})
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		switch apiErr.ErrorCode() {
+		case "PreconditionFailed", "ConditionalRequestConflict":
+			// A conflict means that another write to the same key is in
+			// progress, which is treated as the object already existing.
+			return deltalake.ErrObjectExists
+		}
+	}
+	return err
+}
+
+func (s *s3Storage) Get(ctx context.Context, location string) ([]byte, error) {
+	bucket, key, err := bucketAndKey(location)
+	if err != nil {
+		return nil, err
+	}
+	obj, err := s.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: &bucket,
+		Key:    &key,
+	})
+	if err != nil {
+		var noSuchKey *types.NoSuchKey
+		if errors.As(err, &noSuchKey) {
+			return nil, deltalake.ErrObjectNotFound
+		}
+		return nil, err
+	}
+	defer obj.Body.Close()
+	return io.ReadAll(obj.Body)
+}
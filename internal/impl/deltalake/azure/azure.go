@@ -0,0 +1,182 @@
+package azure
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/bloberror"
+
+	"github.com/benthosdev/benthos/v4/internal/impl/deltalake"
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func init() {
+	deltalake.AzureOptFn = func(conf *service.ParsedConfig, opts *deltalake.ClientOptions) error {
+		if enabled, _ := conf.FieldBool(deltalake.DLOFieldAzureEnabled); !enabled {
+			return nil
+		}
+
+		s := &blobStorage{clients: map[string]*azblob.Client{}}
+
+		var err error
+		if s.account, err = conf.FieldString(deltalake.DLOFieldAzureStorageAccount); err != nil {
+			return err
+		}
+		if s.accessKey, err = conf.FieldString(deltalake.DLOFieldAzureStorageAccessKey); err != nil {
+			return err
+		}
+		if s.sasToken, err = conf.FieldString(deltalake.DLOFieldAzureStorageSASToken); err != nil {
+			return err
+		}
+		if s.connectionString, err = conf.FieldString(deltalake.DLOFieldAzureStorageConnectionString); err != nil {
+			return err
+		}
+
+		for _, scheme := range []string{"abfs", "abfss", "wasb", "wasbs", "az"} {
+			opts.Storage[scheme] = s
+		}
+		return nil
+	}
+}
+
+// blobStorage accesses tables using the blob API, which is also supported by
+// ADLS Gen2 accounts. Clients are created lazily as the storage account may be
+// taken from the location of a table.
+type blobStorage struct {
+	account          string
+	accessKey        string
+	sasToken         string
+	connectionString string
+
+	mut     sync.Mutex
+	clients map[string]*azblob.Client
+}
+
+type blobLocation struct {
+	account   string
+	container string
+	name      string
+}
+
+// parseLocation extracts the blob from locations of the form
+// abfss://<container>@<account>.dfs.core.windows.net/<path> and
+// az://<container>/<path>.
+func (s *blobStorage) parseLocation(location string) (loc blobLocation, err error) {
+	u, err := url.Parse(location)
+	if err != nil {
+		return loc, err
+	}
+	loc.name = strings.TrimPrefix(u.Path, "/")
+	if u.User != nil {
+		loc.container = u.User.Username()
+		loc.account, _, _ = strings.Cut(u.Hostname(), ".")
+	} else {
+		loc.container = u.Host
+	}
+	if s.account != "" {
+		loc.account = s.account
+	}
+	if loc.container == "" {
+		return loc, fmt.Errorf("location '%v' is missing a container", location)
+	}
+	if loc.account == "" && s.connectionString == "" {
+		return loc, fmt.Errorf("location '%v' is missing a storage account", location)
+	}
+	return loc, nil
+}
+
+func (s *blobStorage) client(account string) (*azblob.Client, error) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	if c, exists := s.clients[account]; exists {
+		return c, nil
+	}
+
+	serviceURL := fmt.Sprintf("https://%s.blob.core.windows.net/", account)
+
+	var client *azblob.Client
+	var err error
+	switch {
+	case s.connectionString != "":
+		client, err = azblob.NewClientFromConnectionString(s.connectionString, nil)
+	case s.accessKey != "":
+		var cred *azblob.SharedKeyCredential
+		if cred, err = azblob.NewSharedKeyCredential(account, s.accessKey); err == nil {
+			client, err = azblob.NewClientWithSharedKeyCredential(serviceURL, cred, nil)
+		}
+	case s.sasToken != "":
+		client, err = azblob.NewClientWithNoCredential(serviceURL+"?"+strings.TrimPrefix(s.sasToken, "?"), nil)
+	default:
+		var cred *azidentity.DefaultAzureCredential
+		if cred, err = azidentity.NewDefaultAzureCredential(nil); err == nil {
+			client, err = azblob.NewClient(serviceURL, cred, nil)
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create storage client: %w", err)
+	}
+	s.clients[account] = client
+	return client, nil
+}
+
+func (s *blobStorage) upload(ctx context.Context, location string, data []byte, opts *azblob.UploadBufferOptions) error {
+	loc, err := s.parseLocation(location)
+	if err != nil {
+		return err
+	}
+	client, err := s.client(loc.account)
+	if err != nil {
+		return err
+	}
+	_, err = client.UploadBuffer(ctx, loc.container, loc.name, data, opts)
+	return err
+}
+
+func (s *blobStorage) Put(ctx context.Context, location string, data []byte) error {
+	return s.upload(ctx, location, data, nil)
+}
+
+func (s *blobStorage) PutIfAbsent(ctx context.Context, location string, data []byte) error {
+	err := s.upload(ctx, location, data, &azblob.UploadBufferOptions{
+		AccessConditions: &blob.AccessConditions{
+			ModifiedAccessConditions: &blob.ModifiedAccessConditions{
+				IfNoneMatch: to.Ptr(azcore.ETagAny),
+			},
+		},
+	})
+	if bloberror.HasCode(err, bloberror.BlobAlreadyExists, bloberror.ConditionNotMet) {
+		return deltalake.ErrObjectExists
+	}
+	return err
+}
+
+func (s *blobStorage) Get(ctx context.Context, location string) ([]byte, error) {
+	loc, err := s.parseLocation(location)
+	if err != nil {
+		return nil, err
+	}
+	client, err := s.client(loc.account)
+	if err != nil {
+		return nil, err
+	}
+	res, err := client.DownloadStream(ctx, loc.container, loc.name, nil)
+	if err != nil {
+		if bloberror.HasCode(err, bloberror.BlobNotFound) {
+			return nil, deltalake.ErrObjectNotFound
+		}
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	return io.ReadAll(res.Body)
+}
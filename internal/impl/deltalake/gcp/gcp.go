@@ -0,0 +1,93 @@
+package gcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"cloud.google.com/go/storage"
+	"google.golang.org/api/googleapi"
+
+	"github.com/benthosdev/benthos/v4/internal/impl/deltalake"
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func init() {
+	deltalake.GCPOptFn = func(conf *service.ParsedConfig, opts *deltalake.ClientOptions) error {
+		if enabled, _ := conf.FieldBool(deltalake.DLOFieldGCPEnabled); !enabled {
+			return nil
+		}
+
+		client, err := storage.NewClient(context.Background())
+		if err != nil {
+			return err
+		}
+		opts.Storage["gs"] = &gcsStorage{client: client}
+		return nil
+	}
+}
+
+type gcsStorage struct {
+	client *storage.Client
+}
+
+func (s *gcsStorage) object(location string) (*storage.ObjectHandle, error) {
+	u, err := url.Parse(location)
+	if err != nil {
+		return nil, err
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("location '%v' is missing a bucket", location)
+	}
+	return s.client.Bucket(u.Host).Object(strings.TrimPrefix(u.Path, "/")), nil
+}
+
+func write(ctx context.Context, obj *storage.ObjectHandle, data []byte) error {
+	w := obj.NewWriter(ctx)
+	if _, err := w.Write(data); err != nil {
+		_ = w.Close()
+		return err
+	}
+	return w.Close()
+}
+
+func (s *gcsStorage) Put(ctx context.Context, location string, data []byte) error {
+	obj, err := s.object(location)
+	if err != nil {
+		return err
+	}
+	return write(ctx, obj, data)
+}
+
+func (s *gcsStorage) PutIfAbsent(ctx context.Context, location string, data []byte) error {
+	obj, err := s.object(location)
+	if err != nil {
+		return err
+	}
+	err = write(ctx, obj.If(storage.Conditions{DoesNotExist: true}), data)
+	var apiErr *googleapi.Error
+	if errors.As(err, &apiErr) && apiErr.Code == http.StatusPreconditionFailed {
+		return deltalake.ErrObjectExists
+	}
+	return err
+}
+
+func (s *gcsStorage) Get(ctx context.Context, location string) ([]byte, error) {
+	obj, err := s.object(location)
+	if err != nil {
+		return nil, err
+	}
+	r, err := obj.NewReader(ctx)
+	if err != nil {
+		if errors.Is(err, storage.ErrObjectNotExist) {
+			return nil, deltalake.ErrObjectNotFound
+		}
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
@@ -0,0 +1,313 @@
+package deltalake
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+// protocolAction is the protocol action of a Delta transaction log.
+type protocolAction struct {
+	MinReaderVersion int      `json:"minReaderVersion"`
+	MinWriterVersion int      `json:"minWriterVersion"`
+	ReaderFeatures   []string `json:"readerFeatures,omitempty"`
+	WriterFeatures   []string `json:"writerFeatures,omitempty"`
+}
+
+type formatSpec struct {
+	Provider string            `json:"provider"`
+	Options  map[string]string `json:"options"`
+}
+
+// metadataAction is the metaData action of a Delta transaction log.
+type metadataAction struct {
+	ID               string            `json:"id"`
+	Name             string            `json:"name,omitempty"`
+	Description      string            `json:"description,omitempty"`
+	Format           formatSpec        `json:"format"`
+	SchemaString     string            `json:"schemaString"`
+	PartitionColumns []string          `json:"partitionColumns"`
+	Configuration    map[string]string `json:"configuration"`
+	CreatedTime      *int64            `json:"createdTime,omitempty"`
+}
+
+type addAction struct {
+	Path             string             `json:"path"`
+	PartitionValues  map[string]*string `json:"partitionValues"`
+	Size             int64              `json:"size"`
+	ModificationTime int64              `json:"modificationTime"`
+	DataChange       bool               `json:"dataChange"`
+	Stats            string             `json:"stats,omitempty"`
+}
+
+type commitInfoAction struct {
+	Timestamp           int64          `json:"timestamp"`
+	Operation           string         `json:"operation"`
+	OperationParameters map[string]any `json:"operationParameters"`
+	IsBlindAppend       bool           `json:"isBlindAppend"`
+	EngineInfo          string         `json:"engineInfo"`
+}
+
+type logAction struct {
+	Protocol   *protocolAction   `json:"protocol,omitempty"`
+	MetaData   *metadataAction   `json:"metaData,omitempty"`
+	Add        *addAction        `json:"add,omitempty"`
+	CommitInfo *commitInfoAction `json:"commitInfo,omitempty"`
+}
+
+func encodeCommit(actions []logAction) ([]byte, error) {
+	var buf bytes.Buffer
+	for _, a := range actions {
+		b, err := json.Marshal(a)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(b)
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes(), nil
+}
+
+//------------------------------------------------------------------------------
+
+// tableState is the protocol and metadata of a table at a given version, which
+// is all that is needed in order to append to it.
+type tableState struct {
+	version  int64
+	protocol *protocolAction
+	metadata *metadataAction
+}
+
+func logPath(tablePath string, version int64) string {
+	return fmt.Sprintf("%v/_delta_log/%020d.json", tablePath, version)
+}
+
+// applyCommit updates the state with the protocol and metadata actions of a
+// commit, returning whether either changed.
+func (s *tableState) applyCommit(data []byte) (changed bool, err error) {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(nil, 64*1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		var action struct {
+			Protocol *protocolAction `json:"protocol"`
+			MetaData *metadataAction `json:"metaData"`
+		}
+		if err := json.Unmarshal(line, &action); err != nil {
+			return false, err
+		}
+		if action.Protocol != nil {
+			s.protocol = action.Protocol
+			changed = true
+		}
+		if action.MetaData != nil {
+			s.metadata = action.MetaData
+			changed = true
+		}
+	}
+	return changed, scanner.Err()
+}
+
+// applyCheckpoint updates the state with the protocol and metadata actions of
+// a single part parquet checkpoint.
+func (s *tableState) applyCheckpoint(data []byte) error {
+	f, err := parquet.OpenFile(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return err
+	}
+
+	rdr := parquet.NewGenericReader[any](f)
+	defer rdr.Close()
+
+	rows := make([]any, 100)
+	for {
+		n, readErr := rdr.Read(rows)
+		for _, row := range rows[:n] {
+			obj, _ := row.(map[string]any)
+			if p, ok := obj["protocol"].(map[string]any); ok && p != nil {
+				if s.protocol, err = checkpointValueAs[protocolAction](p); err != nil {
+					return err
+				}
+			}
+			if m, ok := obj["metaData"].(map[string]any); ok && m != nil {
+				if s.metadata, err = checkpointValueAs[metadataAction](normaliseCheckpointMaps(m).(map[string]any)); err != nil {
+					return err
+				}
+			}
+		}
+		if readErr != nil {
+			if errors.Is(readErr, io.EOF) {
+				return nil
+			}
+			return readErr
+		}
+		if n == 0 {
+			return nil
+		}
+	}
+}
+
+// normaliseCheckpointMaps converts parquet map columns, which may be decoded as
+// lists of key/value pairs, into objects.
+func normaliseCheckpointMaps(v any) any {
+	switch t := v.(type) {
+	case map[string]any:
+		for k, child := range t {
+			t[k] = normaliseCheckpointMaps(child)
+		}
+		return t
+	case []any:
+		obj := map[string]any{}
+		for _, e := range t {
+			kv, ok := e.(map[string]any)
+			if !ok || len(kv) != 2 {
+				return t
+			}
+			key, ok := kv["key"].(string)
+			if !ok {
+				return t
+			}
+			obj[key] = kv["value"]
+		}
+		if len(obj) == 0 {
+			return t
+		}
+		return obj
+	}
+	return v
+}
+
+func checkpointValueAs[T any](v map[string]any) (*T, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var t T
+	if err := json.Unmarshal(b, &t); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// loadTableState reads the latest state of a table from its transaction log,
+// starting from the last checkpoint when there is one. Returns a nil state
+// when the table does not exist.
+func loadTableState(ctx context.Context, storage ObjectStorage, tablePath string) (*tableState, error) {
+	state := &tableState{version: -1}
+
+	lastCheckpoint, err := storage.Get(ctx, tablePath+"/_delta_log/_last_checkpoint")
+	if err != nil && !errors.Is(err, ErrObjectNotFound) {
+		return nil, err
+	}
+	if err == nil {
+		var cp struct {
+			Version int64 `json:"version"`
+			Parts   *int  `json:"parts"`
+		}
+		if err := json.Unmarshal(lastCheckpoint, &cp); err != nil {
+			return nil, fmt.Errorf("failed to parse last checkpoint: %w", err)
+		}
+		if cp.Parts != nil && *cp.Parts > 1 {
+			return nil, errors.New("multi-part checkpoints are not supported")
+		}
+
+		cpData, err := storage.Get(ctx, fmt.Sprintf("%v/_delta_log/%020d.checkpoint.parquet", tablePath, cp.Version))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read checkpoint: %w", err)
+		}
+		if err := state.applyCheckpoint(cpData); err != nil {
+			return nil, fmt.Errorf("failed to decode checkpoint: %w", err)
+		}
+		state.version = cp.Version
+	}
+
+	if _, err := state.catchUp(ctx, storage, tablePath); err != nil {
+		return nil, err
+	}
+	if state.version < 0 {
+		return nil, nil
+	}
+	if state.protocol == nil || state.metadata == nil {
+		return nil, errors.New("table log is missing protocol or metadata actions")
+	}
+	return state, nil
+}
+
+// catchUp applies any commits that follow the current version of the state,
+// returning whether the protocol or metadata changed.
+func (s *tableState) catchUp(ctx context.Context, storage ObjectStorage, tablePath string) (changed bool, err error) {
+	for {
+		data, err := storage.Get(ctx, logPath(tablePath, s.version+1))
+		if errors.Is(err, ErrObjectNotFound) {
+			return changed, nil
+		}
+		if err != nil {
+			return changed, err
+		}
+
+		commitChanged, err := s.applyCommit(data)
+		if err != nil {
+			return changed, fmt.Errorf("failed to parse commit %v: %w", s.version+1, err)
+		}
+		changed = changed || commitChanged
+		s.version++
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// Writer features that either never affect blind appends or whose usage we
+// check for explicitly.
+var supportedWriterFeatures = []string{
+	"appendOnly", "invariants", "checkConstraints", "changeDataFeed",
+	"generatedColumns", "columnMapping", "identityColumns", "timestampNtz",
+	"deletionVectors", "domainMetadata", "vacuumProtocolCheck",
+}
+
+// checkWritable returns an error if the table uses protocol features that this
+// writer cannot honour.
+func (s *tableState) checkWritable(schema *structType) error {
+	if s.protocol.MinWriterVersion > 7 {
+		return fmt.Errorf("table writer version %v is not supported", s.protocol.MinWriterVersion)
+	}
+	for _, f := range s.protocol.WriterFeatures {
+		if !slices.Contains(supportedWriterFeatures, f) {
+			return fmt.Errorf("table writer feature %v is not supported", f)
+		}
+	}
+
+	for k := range s.metadata.Configuration {
+		if strings.HasPrefix(k, "delta.constraints.") {
+			return errors.New("tables with check constraints are not supported")
+		}
+	}
+	if mode := s.metadata.Configuration["delta.columnMapping.mode"]; mode != "" && mode != "none" {
+		return fmt.Errorf("column mapping mode %v is not supported", mode)
+	}
+
+	for _, f := range schema.Fields {
+		for k := range f.Metadata {
+			switch {
+			case k == "delta.invariants":
+				return fmt.Errorf("column %v: invariants are not supported", f.Name)
+			case k == "delta.generationExpression":
+				return fmt.Errorf("column %v: generated columns are not supported", f.Name)
+			case strings.HasPrefix(k, "delta.identity."):
+				return fmt.Errorf("column %v: identity columns are not supported", f.Name)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package deltalake
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func TestTableStateApplyCommits(t *testing.T) {
+	ctx := context.Background()
+	storage := &localStorage{fs: service.MockResources().FS()}
+	tablePath := "file://" + t.TempDir()
+
+	state, err := loadTableState(ctx, storage, tablePath)
+	require.NoError(t, err)
+	assert.Nil(t, state)
+
+	require.NoError(t, storage.PutIfAbsent(ctx, logPath(tablePath, 0), []byte(`{"commitInfo":{"timestamp":1,"operation":"CREATE TABLE"}}
+{"protocol":{"minReaderVersion":1,"minWriterVersion":2}}
+{"metaData":{"id":"a","format":{"provider":"parquet","options":{}},"schemaString":"{\"type\":\"struct\",\"fields\":[{\"name\":\"id\",\"type\":\"long\",\"nullable\":true,\"metadata\":{}}]}","partitionColumns":[],"configuration":{}}}
+`)))
+	require.NoError(t, storage.PutIfAbsent(ctx, logPath(tablePath, 1), []byte(`{"add":{"path":"a.parquet","partitionValues":{},"size":1,"modificationTime":1,"dataChange":true}}
+`)))
+
+	state, err = loadTableState(ctx, storage, tablePath)
+	require.NoError(t, err)
+	require.NotNil(t, state)
+	assert.Equal(t, int64(1), state.version)
+	assert.Equal(t, 2, state.protocol.MinWriterVersion)
+	assert.Equal(t, "a", state.metadata.ID)
+
+	require.ErrorIs(t, storage.PutIfAbsent(ctx, logPath(tablePath, 1), []byte(`{}`)), ErrObjectExists)
+
+	require.NoError(t, storage.PutIfAbsent(ctx, logPath(tablePath, 2), []byte(`{"metaData":{"id":"b","format":{"provider":"parquet","options":{}},"schemaString":"{}","partitionColumns":[],"configuration":{}}}
+`)))
+	changed, err := state.catchUp(ctx, storage, tablePath)
+	require.NoError(t, err)
+	assert.True(t, changed)
+	assert.Equal(t, int64(2), state.version)
+	assert.Equal(t, "b", state.metadata.ID)
+}
+
+func TestTableStateCheckWritable(t *testing.T) {
+	tests := []struct {
+		name     string
+		protocol protocolAction
+		config   map[string]string
+		schema   string
+		errMsg   string
+	}{
+		{
+			name:     "plain table",
+			protocol: protocolAction{MinReaderVersion: 1, MinWriterVersion: 2},
+			schema:   `{"type":"struct","fields":[{"name":"id","type":"long","nullable":true,"metadata":{}}]}`,
+		},
+		{
+			name:     "unknown writer feature",
+			protocol: protocolAction{MinReaderVersion: 3, MinWriterVersion: 7, WriterFeatures: []string{"rowTracking"}},
+			schema:   `{"type":"struct","fields":[]}`,
+			errMsg:   "writer feature rowTracking is not supported",
+		},
+		{
+			name:     "check constraints",
+			protocol: protocolAction{MinReaderVersion: 1, MinWriterVersion: 3},
+			config:   map[string]string{"delta.constraints.positive": "id > 0"},
+			schema:   `{"type":"struct","fields":[]}`,
+			errMsg:   "check constraints",
+		},
+		{
+			name:     "column mapping",
+			protocol: protocolAction{MinReaderVersion: 2, MinWriterVersion: 5},
+			config:   map[string]string{"delta.columnMapping.mode": "name"},
+			schema:   `{"type":"struct","fields":[]}`,
+			errMsg:   "column mapping",
+		},
+		{
+			name:     "generated column",
+			protocol: protocolAction{MinReaderVersion: 1, MinWriterVersion: 4},
+			schema:   `{"type":"struct","fields":[{"name":"id","type":"long","nullable":true,"metadata":{"delta.generationExpression":"1"}}]}`,
+			errMsg:   "generated columns",
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			state := &tableState{
+				protocol: &test.protocol,
+				metadata: &metadataAction{Configuration: test.config, SchemaString: test.schema},
+			}
+			schema, _, err := parseSchema(test.schema)
+			require.NoError(t, err)
+
+			err = state.checkWritable(schema)
+			if test.errMsg == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), test.errMsg)
+		})
+	}
+}
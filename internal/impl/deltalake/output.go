@@ -0,0 +1,701 @@
+package deltalake
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/parquet-go/parquet-go"
+	"github.com/parquet-go/parquet-go/compress"
+
+	"github.com/benthosdev/benthos/v4/internal/impl/aws/config"
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	dloFieldPath             = "path"
+	dloFieldPartitionColumns = "partition_columns"
+	dloFieldSchema           = "schema"
+	dloFieldSchemaName       = "name"
+	dloFieldSchemaType       = "type"
+	dloFieldSchemaNullable   = "nullable"
+	dloFieldCompression      = "compression"
+	dloFieldTargetFileSize   = "target_file_size"
+	dloFieldCommitRetries    = "commit_retries"
+	dloFieldBatching         = "batching"
+	dloFieldAWS              = "aws"
+	dloFieldAzure            = "azure"
+	dloFieldGCP              = "gcp"
+
+	// DLOFieldAWSEnabled enables S3 storage.
+	DLOFieldAWSEnabled = "enabled"
+	// DLOFieldAWSForcePathStyle forces path style S3 URLs.
+	DLOFieldAWSForcePathStyle = "force_path_style_urls"
+
+	// DLOFieldAzureEnabled enables Azure storage.
+	DLOFieldAzureEnabled = "enabled"
+	// DLOFieldAzureStorageAccount is the storage account to access.
+	DLOFieldAzureStorageAccount = "storage_account"
+	// DLOFieldAzureStorageAccessKey is the storage account access key.
+	DLOFieldAzureStorageAccessKey = "storage_access_key"
+	// DLOFieldAzureStorageSASToken is the storage account SAS token.
+	DLOFieldAzureStorageSASToken = "storage_sas_token"
+	// DLOFieldAzureStorageConnectionString is a storage connection string.
+	DLOFieldAzureStorageConnectionString = "storage_connection_string"
+
+	// DLOFieldGCPEnabled enables Google Cloud Storage.
+	DLOFieldGCPEnabled = "enabled"
+)
+
+// The value written for null partition values within data file paths.
+const hiveDefaultPartition = "__HIVE_DEFAULT_PARTITION__"
+
+func notImportedAWSOptFn(conf *service.ParsedConfig, opts *ClientOptions) error {
+	if enabled, _ := conf.FieldBool(DLOFieldAWSEnabled); !enabled {
+		return nil
+	}
+	return errors.New("unable to configure AWS storage as this binary does not import components/aws")
+}
+
+func notImportedAzureOptFn(conf *service.ParsedConfig, opts *ClientOptions) error {
+	if enabled, _ := conf.FieldBool(DLOFieldAzureEnabled); !enabled {
+		return nil
+	}
+	return errors.New("unable to configure Azure storage as this binary does not import components/azure")
+}
+
+func notImportedGCPOptFn(conf *service.ParsedConfig, opts *ClientOptions) error {
+	if enabled, _ := conf.FieldBool(DLOFieldGCPEnabled); !enabled {
+		return nil
+	}
+	return errors.New("unable to configure GCP storage as this binary does not import components/gcp")
+}
+
+// AWSOptFn is populated with the child `aws` package when imported.
+var AWSOptFn = notImportedAWSOptFn
+
+// AzureOptFn is populated with the child `azure` package when imported.
+var AzureOptFn = notImportedAzureOptFn
+
+// GCPOptFn is populated with the child `gcp` package when imported.
+var GCPOptFn = notImportedGCPOptFn
+
+// AWSField represents the aws block within a delta_lake output. This is
+// exported in order to make unit testing easier within the aws subpackage.
+func AWSField() *service.ConfigField {
+	return service.NewObjectField(dloFieldAWS,
+		append([]*service.ConfigField{
+			service.NewBoolField(DLOFieldAWSEnabled).
+				Description("Whether to enable writing tables to S3 (paths with the `s3`, `s3a` or `s3n` scheme).").
+				Default(false),
+			service.NewBoolField(DLOFieldAWSForcePathStyle).
+				Description("Forces the client API to use path style URLs, which helps when connecting to custom endpoints.").
+				Default(false),
+		}, config.SessionFields()...)...).
+		Description("Enables and customises connectivity to Amazon Web Services.").
+		Advanced()
+}
+
+// AzureField represents the azure block within a delta_lake output.
+func AzureField() *service.ConfigField {
+	return service.NewObjectField(dloFieldAzure,
+		service.NewBoolField(DLOFieldAzureEnabled).
+			Description("Whether to enable writing tables to Azure Blob Storage and ADLS Gen2 (paths with the `abfs`, `abfss`, `wasb`, `wasbs` or `az` scheme).").
+			Default(false),
+		service.NewStringField(DLOFieldAzureStorageAccount).
+			Description("The storage account to access. When empty the account is taken from the host of the table path. This field is ignored if `"+DLOFieldAzureStorageConnectionString+"` is set.").
+			Default(""),
+		service.NewStringField(DLOFieldAzureStorageAccessKey).
+			Description("The storage account access key. This field is ignored if `"+DLOFieldAzureStorageConnectionString+"` is set.").
+			Default("").
+			Secret(),
+		service.NewStringField(DLOFieldAzureStorageSASToken).
+			Description("The storage account SAS token. This field is ignored if `"+DLOFieldAzureStorageConnectionString+"` or `"+DLOFieldAzureStorageAccessKey+"` are set.").
+			Default("").
+			Secret(),
+		service.NewStringField(DLOFieldAzureStorageConnectionString).
+			Description("A storage account connection string. When no credentials are set the default Azure credential chain is used.").
+			Default("").
+			Secret(),
+	).
+		Description("Enables and customises connectivity to Microsoft Azure.").
+		Advanced()
+}
+
+// GCPField represents the gcp block within a delta_lake output.
+func GCPField() *service.ConfigField {
+	return service.NewObjectField(dloFieldGCP,
+		service.NewBoolField(DLOFieldGCPEnabled).
+			Description("Whether to enable writing tables to Google Cloud Storage (paths with the `gs` scheme) using application default credentials.").
+			Default(false),
+	).
+		Description("Enables and customises connectivity to Google Cloud Platform.").
+		Advanced()
+}
+
+func outputSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Services").
+		Version("4.28.0").
+		Summary("Appends messages as Parquet data files to a [Delta Lake](https://delta.io/) table.").
+		Description(`
+Each batch of messages is written as one or more Parquet data files, which are then committed to the table as a single append within the transaction log of the table. Batching should therefore be configured in order to avoid committing a large number of small files.
+
+### Schema Mapping
+
+Messages must be objects, and each top level column of the table schema is populated from the message field of the same name, other fields are ignored. Columns of type `+"`string`, `long`, `integer`, `short`, `byte`, `float`, `double`, `boolean`, `binary`, `date`, `timestamp` and `timestamp_ntz`"+` are supported, and dates and timestamps can be provided either as RFC 3339 strings or unix timestamps.
+
+When the table does not yet exist it is created using the `+"`schema` and `partition_columns`"+` fields, otherwise these fields are ignored and the schema of the existing table is used.
+
+### Partitioning
+
+Rows are written to a separate data file for each combination of partition column values, following the Hive style directory layout used by other Delta Lake writers.
+
+### File Sizes
+
+Data files are closed and a new file started once they reach roughly `+"`target_file_size`"+` bytes. Data files can never be larger than the batch they were written from, and therefore the batching policy should also be tuned in order to produce larger files.
+
+### Storage
+
+Tables on the local filesystem are supported by default. Tables on S3, Azure Blob Storage (including ADLS Gen2) and Google Cloud Storage are supported when the respective `+"`aws`, `azure` or `gcp`"+` block is enabled.
+
+Commits are written with a conditional write that fails if another writer has already committed the same version, in which case the new commits are read and the commit is retried at the next version, up to `+"`commit_retries`"+` times. On S3 this requires a bucket that supports conditional writes with the `+"`If-None-Match`"+` header, which is the case for Amazon S3 itself but not necessarily for S3 compatible services.`+service.OutputPerformanceDocs(true, true)).
+		Fields(
+			service.NewStringField(dloFieldPath).
+				Description("The location of the table.").
+				Example("file:///var/lib/tables/events").
+				Example("s3://my-bucket/tables/events").
+				Example("abfss://my-container@myaccount.dfs.core.windows.net/tables/events").
+				Example("gs://my-bucket/tables/events"),
+			service.NewStringListField(dloFieldPartitionColumns).
+				Description("The columns to partition a newly created table by.").
+				Default([]any{}),
+			service.NewObjectListField(dloFieldSchema,
+				service.NewStringField(dloFieldSchemaName).
+					Description("The name of the column."),
+				service.NewStringEnumField(dloFieldSchemaType, supportedTypes...).
+					Description("The type of the column."),
+				service.NewBoolField(dloFieldSchemaNullable).
+					Description("Whether the column may contain null values.").
+					Default(true),
+			).
+				Description("The schema of a newly created table. When empty the table must already exist.").
+				Default([]any{}),
+			service.NewStringEnumField(dloFieldCompression, "uncompressed", "snappy", "gzip", "zstd").
+				Description("The compression to use for data files.").
+				Default("snappy").
+				Advanced(),
+			service.NewIntField(dloFieldTargetFileSize).
+				Description("The size in bytes at which a data file is closed and a new file started.").
+				Default(128*1024*1024).
+				Advanced(),
+			service.NewIntField(dloFieldCommitRetries).
+				Description("The maximum number of times to retry a commit that failed due to a concurrent commit to the table.").
+				Default(10).
+				Advanced(),
+			service.NewOutputMaxInFlightField().Default(1),
+			service.NewBatchPolicyField(dloFieldBatching),
+			AWSField(),
+			AzureField(),
+			GCPField(),
+		).
+		Example("Writing to S3", "Append batches of events to a table partitioned by date, creating the table if it does not exist:", `
+output:
+  delta_lake:
+    path: s3://my-bucket/tables/events
+    partition_columns: [ date ]
+    schema:
+      - name: id
+        type: long
+        nullable: false
+      - name: message
+        type: string
+      - name: date
+        type: date
+    aws:
+      enabled: true
+    batching:
+      count: 10000
+      period: 60s
+`)
+}
+
+func init() {
+	err := service.RegisterBatchOutput("delta_lake", outputSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (out service.BatchOutput, batchPolicy service.BatchPolicy, maxInFlight int, err error) {
+			if maxInFlight, err = conf.FieldMaxInFlight(); err != nil {
+				return
+			}
+			if batchPolicy, err = conf.FieldBatchPolicy(dloFieldBatching); err != nil {
+				return
+			}
+			out, err = newOutputFromParsed(conf, mgr)
+			return
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type output struct {
+	log *service.Logger
+
+	path             string
+	storage          ObjectStorage
+	createSchema     []column
+	partitionColumns []string
+	compression      compress.Codec
+	targetFileSize   int
+	commitRetries    int
+
+	// The number of rows written between checks of the size of a data file.
+	rowsPerCheck int
+
+	mut   sync.Mutex
+	state *tableState
+}
+
+func newOutputFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*output, error) {
+	o := &output{
+		log:          mgr.Logger(),
+		rowsPerCheck: 10000,
+	}
+
+	var err error
+	if o.path, err = conf.FieldString(dloFieldPath); err != nil {
+		return nil, err
+	}
+	o.path = strings.TrimSuffix(o.path, "/")
+	if o.path == "" {
+		return nil, errors.New("a table path must be specified")
+	}
+
+	if o.partitionColumns, err = conf.FieldStringList(dloFieldPartitionColumns); err != nil {
+		return nil, err
+	}
+
+	schemaConfs, err := conf.FieldObjectList(dloFieldSchema)
+	if err != nil {
+		return nil, err
+	}
+	for _, sConf := range schemaConfs {
+		var c column
+		if c.name, err = sConf.FieldString(dloFieldSchemaName); err != nil {
+			return nil, err
+		}
+		if c.typeName, err = sConf.FieldString(dloFieldSchemaType); err != nil {
+			return nil, err
+		}
+		if c.nullable, err = sConf.FieldBool(dloFieldSchemaNullable); err != nil {
+			return nil, err
+		}
+		o.createSchema = append(o.createSchema, c)
+	}
+	for _, p := range o.partitionColumns {
+		if !slices.ContainsFunc(o.createSchema, func(c column) bool { return c.name == p }) {
+			return nil, fmt.Errorf("partition column %v is not in the schema", p)
+		}
+	}
+
+	compressStr, err := conf.FieldString(dloFieldCompression)
+	if err != nil {
+		return nil, err
+	}
+	switch compressStr {
+	case "uncompressed":
+		o.compression = &parquet.Uncompressed
+	case "snappy":
+		o.compression = &parquet.Snappy
+	case "gzip":
+		o.compression = &parquet.Gzip
+	case "zstd":
+		o.compression = &parquet.Zstd
+	default:
+		return nil, fmt.Errorf("compression type %v not recognised", compressStr)
+	}
+
+	if o.targetFileSize, err = conf.FieldInt(dloFieldTargetFileSize); err != nil {
+		return nil, err
+	}
+	if o.commitRetries, err = conf.FieldInt(dloFieldCommitRetries); err != nil {
+		return nil, err
+	}
+
+	opts := ClientOptions{
+		Storage: map[string]ObjectStorage{
+			"file": &localStorage{fs: mgr.FS()},
+		},
+	}
+	if err = AWSOptFn(conf.Namespace(dloFieldAWS), &opts); err != nil {
+		return nil, err
+	}
+	if err = AzureOptFn(conf.Namespace(dloFieldAzure), &opts); err != nil {
+		return nil, err
+	}
+	if err = GCPOptFn(conf.Namespace(dloFieldGCP), &opts); err != nil {
+		return nil, err
+	}
+	if o.storage, err = opts.storageFor(o.path); err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+func (o *output) Connect(ctx context.Context) error {
+	o.mut.Lock()
+	defer o.mut.Unlock()
+
+	if o.state != nil {
+		return nil
+	}
+
+	state, err := loadTableState(ctx, o.storage, o.path)
+	if err != nil {
+		return fmt.Errorf("failed to load table: %w", err)
+	}
+	if state == nil {
+		if state, err = o.createTable(ctx); err != nil {
+			return err
+		}
+	}
+	if _, _, err := o.checkState(state); err != nil {
+		return err
+	}
+
+	o.state = state
+	return nil
+}
+
+// createTable commits the first version of a table using the configured
+// schema. If another writer creates the table first then its state is loaded
+// instead.
+func (o *output) createTable(ctx context.Context) (*tableState, error) {
+	if len(o.createSchema) == 0 {
+		return nil, errors.New("the table does not exist and no schema has been configured in order to create it")
+	}
+
+	schemaString, err := schemaFromConfig(o.createSchema)
+	if err != nil {
+		return nil, err
+	}
+	tableID, err := uuid.NewV4()
+	if err != nil {
+		return nil, err
+	}
+
+	protocol := &protocolAction{MinReaderVersion: 1, MinWriterVersion: 2}
+	if slices.ContainsFunc(o.createSchema, func(c column) bool { return c.typeName == "timestamp_ntz" }) {
+		protocol = &protocolAction{
+			MinReaderVersion: 3,
+			MinWriterVersion: 7,
+			ReaderFeatures:   []string{"timestampNtz"},
+			WriterFeatures:   []string{"timestampNtz"},
+		}
+	}
+
+	now := time.Now().UnixMilli()
+	metadata := &metadataAction{
+		ID:               tableID.String(),
+		Format:           formatSpec{Provider: "parquet", Options: map[string]string{}},
+		SchemaString:     schemaString,
+		PartitionColumns: append([]string{}, o.partitionColumns...),
+		Configuration:    map[string]string{},
+		CreatedTime:      &now,
+	}
+
+	commit, err := encodeCommit([]logAction{
+		{CommitInfo: &commitInfoAction{
+			Timestamp:           now,
+			Operation:           "CREATE TABLE",
+			OperationParameters: map[string]any{},
+			IsBlindAppend:       true,
+			EngineInfo:          "Benthos",
+		}},
+		{Protocol: protocol},
+		{MetaData: metadata},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = o.storage.PutIfAbsent(ctx, logPath(o.path, 0), commit)
+	if errors.Is(err, ErrObjectExists) {
+		o.log.Debug("Table was created by another writer, loading it")
+		state, err := loadTableState(ctx, o.storage, o.path)
+		if err == nil && state == nil {
+			err = errors.New("table log is missing")
+		}
+		return state, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create table: %w", err)
+	}
+	o.log.Infof("Created table at %v", o.path)
+	return &tableState{version: 0, protocol: protocol, metadata: metadata}, nil
+}
+
+// checkState validates that the table can be written to, returning the data
+// and partition columns of its schema.
+func (o *output) checkState(state *tableState) (dataCols, partCols []column, err error) {
+	schema, cols, err := parseSchema(state.metadata.SchemaString)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := state.checkWritable(schema); err != nil {
+		return nil, nil, err
+	}
+	if state.metadata.Format.Provider != "" && state.metadata.Format.Provider != "parquet" {
+		return nil, nil, fmt.Errorf("table format %v is not supported", state.metadata.Format.Provider)
+	}
+
+	for _, p := range state.metadata.PartitionColumns {
+		i := slices.IndexFunc(cols, func(c column) bool { return c.name == p })
+		if i < 0 {
+			return nil, nil, fmt.Errorf("partition column %v is not in the table schema", p)
+		}
+		partCols = append(partCols, cols[i])
+	}
+	for _, c := range cols {
+		if !slices.Contains(state.metadata.PartitionColumns, c.name) {
+			dataCols = append(dataCols, c)
+		}
+	}
+	return dataCols, partCols, nil
+}
+
+// escapePartitionValue escapes characters of a partition value that are not
+// permitted within a Hive style directory name.
+func escapePartitionValue(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if r < 0x20 || r == 0x7f || strings.ContainsRune("\"#%'*/:=?\\{[]^", r) {
+			fmt.Fprintf(&b, "%%%02X", r)
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+type partitionedRows struct {
+	dir    string
+	values map[string]*string
+	rows   []any
+}
+
+func (o *output) writeParquet(schema *parquet.Schema, dataCols []column, rows []any, fn func(data []byte, stats *fileStats) error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("encoding panic: %v", r)
+		}
+	}()
+
+	for len(rows) > 0 {
+		var buf bytes.Buffer
+		stats := newFileStats(dataCols)
+		// Row groups are written to the buffer directly rather than through a
+		// write buffer so that the size of the file is known after each flush.
+		pWtr := parquet.NewGenericWriter[any](&buf, schema, parquet.Compression(o.compression), parquet.WriteBufferSize(0))
+		for len(rows) > 0 && buf.Len() < o.targetFileSize {
+			chunk := rows[:min(len(rows), o.rowsPerCheck)]
+			rows = rows[len(chunk):]
+			if _, err = pWtr.Write(chunk); err != nil {
+				return
+			}
+			if err = pWtr.Flush(); err != nil {
+				return
+			}
+			for _, r := range chunk {
+				stats.add(r.(map[string]any))
+			}
+		}
+		if err = pWtr.Close(); err != nil {
+			return
+		}
+		if err = fn(buf.Bytes(), stats); err != nil {
+			return
+		}
+	}
+	return nil
+}
+
+func (o *output) WriteBatch(ctx context.Context, batch service.MessageBatch) error {
+	o.mut.Lock()
+	defer o.mut.Unlock()
+
+	if o.state == nil {
+		return service.ErrNotConnected
+	}
+
+	dataCols, partCols, err := o.checkState(o.state)
+	if err != nil {
+		return err
+	}
+
+	// Convert messages into rows grouped by their partition.
+	var partitions []*partitionedRows
+	partitionsByDir := map[string]*partitionedRows{}
+	for i, msg := range batch {
+		structured, err := msg.AsStructured()
+		if err != nil {
+			return fmt.Errorf("message %v: %w", i, err)
+		}
+		obj, ok := structured.(map[string]any)
+		if !ok {
+			return fmt.Errorf("message %v: expected an object, got %T", i, structured)
+		}
+
+		row := make(map[string]any, len(dataCols))
+		for _, c := range dataCols {
+			if row[c.name], err = c.convert(obj[c.name]); err != nil {
+				return fmt.Errorf("message %v: %w", i, err)
+			}
+		}
+
+		values := make(map[string]*string, len(partCols))
+		dirs := make([]string, 0, len(partCols))
+		for _, c := range partCols {
+			v, err := c.convert(obj[c.name])
+			if err != nil {
+				return fmt.Errorf("message %v: %w", i, err)
+			}
+			pv := c.partitionValue(v)
+			values[c.name] = pv
+			if pv == nil {
+				dirs = append(dirs, escapePartitionValue(c.name)+"="+hiveDefaultPartition)
+			} else {
+				dirs = append(dirs, escapePartitionValue(c.name)+"="+escapePartitionValue(*pv))
+			}
+		}
+
+		dir := strings.Join(dirs, "/")
+		pr, exists := partitionsByDir[dir]
+		if !exists {
+			pr = &partitionedRows{dir: dir, values: values}
+			partitionsByDir[dir] = pr
+			partitions = append(partitions, pr)
+		}
+		pr.rows = append(pr.rows, row)
+	}
+
+	pSchema := parquetSchemaFromColumns(dataCols)
+
+	var actions []logAction
+	var addedRecords int64
+	for _, pr := range partitions {
+		err := o.writeParquet(pSchema, dataCols, pr.rows, func(data []byte, stats *fileStats) error {
+			fileID, err := uuid.NewV4()
+			if err != nil {
+				return err
+			}
+			relPath := "part-00000-" + fileID.String() + "-c000" + o.compressionExt() + ".parquet"
+			if pr.dir != "" {
+				relPath = pr.dir + "/" + relPath
+			}
+			if err := o.storage.Put(ctx, o.path+"/"+relPath, data); err != nil {
+				return fmt.Errorf("failed to write data file: %w", err)
+			}
+
+			statsJSON, err := stats.json()
+			if err != nil {
+				return err
+			}
+			actions = append(actions, logAction{Add: &addAction{
+				Path:             (&url.URL{Path: relPath}).EscapedPath(),
+				PartitionValues:  pr.values,
+				Size:             int64(len(data)),
+				ModificationTime: time.Now().UnixMilli(),
+				DataChange:       true,
+				Stats:            statsJSON,
+			}})
+			addedRecords += stats.numRecords
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	partitionBy, err := json.Marshal(o.state.metadata.PartitionColumns)
+	if err != nil {
+		return err
+	}
+	commit, err := encodeCommit(append([]logAction{{CommitInfo: &commitInfoAction{
+		Timestamp: time.Now().UnixMilli(),
+		Operation: "WRITE",
+		OperationParameters: map[string]any{
+			"mode":        "Append",
+			"partitionBy": string(partitionBy),
+		},
+		IsBlindAppend: true,
+		EngineInfo:    "Benthos",
+	}}}, actions...))
+	if err != nil {
+		return err
+	}
+
+	for attempt := 0; ; attempt++ {
+		version := o.state.version + 1
+		err := o.storage.PutIfAbsent(ctx, logPath(o.path, version), commit)
+		if err == nil {
+			o.state.version = version
+			o.log.Debugf("Committed %v records to table version %v", addedRecords, version)
+			return nil
+		}
+		if !errors.Is(err, ErrObjectExists) {
+			return fmt.Errorf("failed to commit: %w", err)
+		}
+		if attempt >= o.commitRetries {
+			return fmt.Errorf("failed to commit after %v attempts due to concurrent commits", attempt+1)
+		}
+
+		o.log.Debugf("Version %v was committed concurrently, retrying", version)
+		prevSchema := o.state.metadata.SchemaString
+		prevPartitionColumns := o.state.metadata.PartitionColumns
+		changed, err := o.state.catchUp(ctx, o.storage, o.path)
+		if err != nil {
+			o.state = nil
+			return fmt.Errorf("failed to read concurrent commits: %w", err)
+		}
+		if changed {
+			_, _, err := o.checkState(o.state)
+			if err == nil && (o.state.metadata.SchemaString != prevSchema || !slices.Equal(o.state.metadata.PartitionColumns, prevPartitionColumns)) {
+				err = errors.New("the table schema was changed by a concurrent commit")
+			}
+			if err != nil {
+				o.state = nil
+				return err
+			}
+		}
+	}
+}
+
+func (o *output) compressionExt() string {
+	switch o.compression {
+	case &parquet.Snappy:
+		return ".snappy"
+	case &parquet.Gzip:
+		return ".gz"
+	case &parquet.Zstd:
+		return ".zstd"
+	}
+	return ""
+}
+
+func (o *output) Close(ctx context.Context) error {
+	o.mut.Lock()
+	o.state = nil
+	o.mut.Unlock()
+	return nil
+}
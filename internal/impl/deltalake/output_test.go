@@ -0,0 +1,187 @@
+package deltalake
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func readCommit(t *testing.T, tableDir string, version int64) []map[string]any {
+	t.Helper()
+
+	data, err := os.ReadFile(localPath(logPath(tableDir, version)))
+	require.NoError(t, err)
+
+	var actions []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		var action map[string]any
+		require.NoError(t, json.Unmarshal([]byte(line), &action))
+		actions = append(actions, action)
+	}
+	return actions
+}
+
+func TestDeltaLakeOutputCreatesAndAppends(t *testing.T) {
+	ctx, done := context.WithTimeout(context.Background(), time.Minute)
+	defer done()
+
+	tableDir := t.TempDir()
+	conf, err := outputSpec().ParseYAML(fmt.Sprintf(`
+path: file://%v
+partition_columns: [ category ]
+schema:
+  - name: id
+    type: long
+    nullable: false
+  - name: category
+    type: string
+  - name: ts
+    type: timestamp
+`, tableDir), nil)
+	require.NoError(t, err)
+
+	out, err := newOutputFromParsed(conf, service.MockResources())
+	require.NoError(t, err)
+	require.NoError(t, out.Connect(ctx))
+
+	require.NoError(t, out.WriteBatch(ctx, service.MessageBatch{
+		service.NewMessage([]byte(`{"id":1,"category":"a","ts":"2024-03-05T10:00:00Z"}`)),
+		service.NewMessage([]byte(`{"id":2,"category":"b/c"}`)),
+		service.NewMessage([]byte(`{"id":3,"category":"a","ts":"2024-03-05T11:00:00Z"}`)),
+		service.NewMessage([]byte(`{"id":4}`)),
+	}))
+
+	// Another writer commits the next version, which should be skipped over.
+	require.NoError(t, out.storage.PutIfAbsent(ctx, logPath("file://"+tableDir, 2), []byte(`{"commitInfo":{"timestamp":1,"operation":"WRITE"}}
+`)))
+	require.NoError(t, out.WriteBatch(ctx, service.MessageBatch{
+		service.NewMessage([]byte(`{"id":5,"category":"a"}`)),
+	}))
+
+	err = out.WriteBatch(ctx, service.MessageBatch{
+		service.NewMessage([]byte(`{"category":"a"}`)),
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "column id is not nullable")
+
+	require.NoError(t, out.Close(ctx))
+
+	create := readCommit(t, tableDir, 0)
+	require.Len(t, create, 3)
+	assert.Equal(t, []any{"category"}, create[2]["metaData"].(map[string]any)["partitionColumns"])
+
+	first := readCommit(t, tableDir, 1)
+	require.Len(t, first, 4)
+	assert.Equal(t, "WRITE", first[0]["commitInfo"].(map[string]any)["operation"])
+
+	adds := map[any]map[string]any{}
+	for _, a := range first[1:] {
+		add := a["add"].(map[string]any)
+		adds[add["partitionValues"].(map[string]any)["category"]] = add
+	}
+	require.Contains(t, adds, "a")
+	require.Contains(t, adds, "b/c")
+	require.Contains(t, adds, nil)
+
+	assert.True(t, strings.HasPrefix(adds["b/c"]["path"].(string), "category=b%252Fc/"), adds["b/c"]["path"])
+	assert.True(t, strings.HasPrefix(adds[nil]["path"].(string), "category=__HIVE_DEFAULT_PARTITION__/"), adds[nil]["path"])
+
+	var stats map[string]any
+	require.NoError(t, json.Unmarshal([]byte(adds["a"]["stats"].(string)), &stats))
+	assert.Equal(t, float64(2), stats["numRecords"])
+	assert.Equal(t, map[string]any{"id": float64(1), "ts": "2024-03-05T10:00:00.000Z"}, stats["minValues"])
+	assert.Equal(t, map[string]any{"id": float64(3), "ts": "2024-03-05T11:00:00.000Z"}, stats["maxValues"])
+
+	files, err := filepath.Glob(filepath.Join(tableDir, "category=a", "*.parquet"))
+	require.NoError(t, err)
+	require.Len(t, files, 2)
+
+	second := readCommit(t, tableDir, 3)
+	require.Len(t, second, 2)
+
+	dataBytes, err := os.ReadFile(filepath.Join(tableDir, "category=a", filepath.Base(adds["a"]["path"].(string))))
+	require.NoError(t, err)
+
+	rdr := parquet.NewGenericReader[any](bytes.NewReader(dataBytes))
+	rows := make([]any, 10)
+	n, _ := rdr.Read(rows)
+	require.Equal(t, 2, n)
+	assert.Equal(t, int64(1), rows[0].(map[string]any)["id"])
+	assert.NotContains(t, rows[0].(map[string]any), "category")
+}
+
+func TestDeltaLakeOutputTargetFileSize(t *testing.T) {
+	ctx, done := context.WithTimeout(context.Background(), time.Minute)
+	defer done()
+
+	tableDir := t.TempDir()
+	conf, err := outputSpec().ParseYAML(fmt.Sprintf(`
+path: %v
+target_file_size: 1
+schema:
+  - name: id
+    type: long
+`, tableDir), nil)
+	require.NoError(t, err)
+
+	out, err := newOutputFromParsed(conf, service.MockResources())
+	require.NoError(t, err)
+	out.rowsPerCheck = 2
+	require.NoError(t, out.Connect(ctx))
+
+	var batch service.MessageBatch
+	for i := 0; i < 5; i++ {
+		batch = append(batch, service.NewMessage([]byte(fmt.Sprintf(`{"id":%v}`, i))))
+	}
+	require.NoError(t, out.WriteBatch(ctx, batch))
+
+	commit := readCommit(t, tableDir, 1)
+	require.Len(t, commit, 4)
+}
+
+func TestDeltaLakeOutputMissingTable(t *testing.T) {
+	conf, err := outputSpec().ParseYAML(fmt.Sprintf(`
+path: %v
+`, t.TempDir()), nil)
+	require.NoError(t, err)
+
+	out, err := newOutputFromParsed(conf, service.MockResources())
+	require.NoError(t, err)
+
+	err = out.Connect(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "no schema has been configured")
+}
+
+func TestDeltaLakeOutputCloudNotImported(t *testing.T) {
+	for _, block := range []string{"aws", "azure", "gcp"} {
+		conf, err := outputSpec().ParseYAML(fmt.Sprintf(`
+path: /tmp/foo
+%v:
+  enabled: true
+`, block), nil)
+		require.NoError(t, err)
+
+		_, err = newOutputFromParsed(conf, service.MockResources())
+		require.Error(t, err, block)
+		assert.Contains(t, err.Error(), "does not import components/"+block)
+	}
+}
+
+func TestEscapePartitionValue(t *testing.T) {
+	assert.Equal(t, "2024-03-05 10%3A00%3A00", escapePartitionValue("2024-03-05 10:00:00"))
+	assert.Equal(t, "a%2Fb%3Dc", escapePartitionValue("a/b=c"))
+	assert.Equal(t, "plain", escapePartitionValue("plain"))
+}
@@ -0,0 +1,307 @@
+package deltalake
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+
+	"github.com/benthosdev/benthos/v4/internal/value"
+)
+
+type structField struct {
+	Name     string          `json:"name"`
+	Type     json.RawMessage `json:"type"`
+	Nullable bool            `json:"nullable"`
+	Metadata map[string]any  `json:"metadata"`
+}
+
+type structType struct {
+	Type   string        `json:"type"`
+	Fields []structField `json:"fields"`
+}
+
+var supportedTypes = []string{
+	"string", "long", "integer", "short", "byte", "float", "double",
+	"boolean", "binary", "date", "timestamp", "timestamp_ntz",
+}
+
+// column is a top level column of a table schema along with the means to
+// convert message values into values of its type.
+type column struct {
+	name     string
+	typeName string
+	nullable bool
+}
+
+func parseSchema(schemaString string) (*structType, []column, error) {
+	var schema structType
+	if err := json.Unmarshal([]byte(schemaString), &schema); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse table schema: %w", err)
+	}
+
+	cols := make([]column, 0, len(schema.Fields))
+	for _, f := range schema.Fields {
+		var typeName string
+		if err := json.Unmarshal(f.Type, &typeName); err != nil {
+			return nil, nil, fmt.Errorf("column %v: nested types are not supported", f.Name)
+		}
+		supported := false
+		for _, t := range supportedTypes {
+			if t == typeName {
+				supported = true
+				break
+			}
+		}
+		if !supported {
+			return nil, nil, fmt.Errorf("column %v: type %v is not supported", f.Name, typeName)
+		}
+		cols = append(cols, column{name: f.Name, typeName: typeName, nullable: f.Nullable})
+	}
+	return &schema, cols, nil
+}
+
+func (c column) parquetNode() parquet.Node {
+	var n parquet.Node
+	switch c.typeName {
+	case "string":
+		n = parquet.String()
+	case "long":
+		n = parquet.Int(64)
+	case "integer":
+		n = parquet.Int(32)
+	case "short":
+		n = parquet.Int(16)
+	case "byte":
+		n = parquet.Int(8)
+	case "float":
+		n = parquet.Leaf(parquet.FloatType)
+	case "double":
+		n = parquet.Leaf(parquet.DoubleType)
+	case "boolean":
+		n = parquet.Leaf(parquet.BooleanType)
+	case "binary":
+		n = parquet.Leaf(parquet.ByteArrayType)
+	case "date":
+		n = parquet.Date()
+	case "timestamp", "timestamp_ntz":
+		n = parquet.Timestamp(parquet.Microsecond)
+	}
+	if c.nullable {
+		n = parquet.Optional(n)
+	}
+	return n
+}
+
+func parquetSchemaFromColumns(cols []column) *parquet.Schema {
+	group := parquet.Group{}
+	for _, c := range cols {
+		group[c.name] = c.parquetNode()
+	}
+	return parquet.NewSchema("spark_schema", group)
+}
+
+func floorDiv(a, b int64) int64 {
+	q := a / b
+	if a%b != 0 && a < 0 {
+		q--
+	}
+	return q
+}
+
+func timeFromValue(v any) (time.Time, error) {
+	if s, ok := v.(string); ok {
+		if t, err := time.Parse("2006-01-02", s); err == nil {
+			return t, nil
+		}
+	}
+	return value.IGetTimestamp(v)
+}
+
+// convert a message value into the value written to parquet for this column.
+// Dates are represented as days since the epoch and timestamps as microseconds
+// since the epoch.
+func (c column) convert(v any) (any, error) {
+	if v == nil {
+		if !c.nullable {
+			return nil, fmt.Errorf("column %v is not nullable but the value is missing", c.name)
+		}
+		return nil, nil
+	}
+
+	var res any
+	var err error
+	switch c.typeName {
+	case "string":
+		res = value.IToString(v)
+	case "long":
+		res, err = value.IToInt(v)
+	case "integer":
+		res, err = value.IToInt32(v)
+	case "short":
+		var i int16
+		if i, err = value.IToInt16(v); err == nil {
+			res = int32(i)
+		}
+	case "byte":
+		var i int8
+		if i, err = value.IToInt8(v); err == nil {
+			res = int32(i)
+		}
+	case "float":
+		res, err = value.IToFloat32(v)
+	case "double":
+		res, err = value.IToFloat64(v)
+	case "boolean":
+		res, err = value.IToBool(v)
+	case "binary":
+		res = value.IToBytes(v)
+	case "date":
+		var t time.Time
+		if t, err = timeFromValue(v); err == nil {
+			res = int32(floorDiv(t.Unix(), 86400))
+		}
+	case "timestamp", "timestamp_ntz":
+		var t time.Time
+		if t, err = timeFromValue(v); err == nil {
+			res = t.UnixMicro()
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("column %v: %w", c.name, err)
+	}
+	return res, nil
+}
+
+// partitionValue serialises a converted value into the string form used for
+// partition values within the transaction log.
+func (c column) partitionValue(v any) *string {
+	if v == nil {
+		return nil
+	}
+	var s string
+	switch c.typeName {
+	case "date":
+		s = time.Unix(int64(v.(int32))*86400, 0).UTC().Format("2006-01-02")
+	case "timestamp", "timestamp_ntz":
+		s = time.UnixMicro(v.(int64)).UTC().Format("2006-01-02 15:04:05.999999")
+	case "binary":
+		s = string(v.([]byte))
+	default:
+		s = value.IToString(v)
+	}
+	return &s
+}
+
+// statsValue returns the JSON representation of a converted value for use as
+// a min or max statistic, or nil if the type does not support statistics.
+func (c column) statsValue(v any) any {
+	switch c.typeName {
+	case "long", "integer", "short", "byte", "float", "double":
+		return v
+	case "date":
+		return time.Unix(int64(v.(int32))*86400, 0).UTC().Format("2006-01-02")
+	case "timestamp":
+		return time.UnixMicro(v.(int64)).UTC().Format("2006-01-02T15:04:05.000Z07:00")
+	case "timestamp_ntz":
+		return time.UnixMicro(v.(int64)).UTC().Format("2006-01-02T15:04:05.000")
+	}
+	return nil
+}
+
+func (c column) less(a, b any) bool {
+	switch c.typeName {
+	case "long", "timestamp", "timestamp_ntz":
+		return a.(int64) < b.(int64)
+	case "integer", "short", "byte", "date":
+		return a.(int32) < b.(int32)
+	case "float":
+		return a.(float32) < b.(float32)
+	case "double":
+		return a.(float64) < b.(float64)
+	}
+	return false
+}
+
+// fileStats accumulates the statistics of the rows of a data file.
+type fileStats struct {
+	cols       []column
+	numRecords int64
+	minValues  map[string]any
+	maxValues  map[string]any
+	nullCount  map[string]int64
+}
+
+func newFileStats(cols []column) *fileStats {
+	return &fileStats{
+		cols:      cols,
+		minValues: map[string]any{},
+		maxValues: map[string]any{},
+		nullCount: map[string]int64{},
+	}
+}
+
+func (s *fileStats) add(row map[string]any) {
+	s.numRecords++
+	for _, c := range s.cols {
+		v := row[c.name]
+		if v == nil {
+			s.nullCount[c.name]++
+			continue
+		}
+		if c.statsValue(v) == nil {
+			continue
+		}
+		if f, ok := v.(float32); ok && f != f {
+			continue
+		}
+		if f, ok := v.(float64); ok && f != f {
+			continue
+		}
+		if minV, exists := s.minValues[c.name]; !exists || c.less(v, minV) {
+			s.minValues[c.name] = v
+		}
+		if maxV, exists := s.maxValues[c.name]; !exists || c.less(maxV, v) {
+			s.maxValues[c.name] = v
+		}
+	}
+}
+
+func (s *fileStats) json() (string, error) {
+	minValues := map[string]any{}
+	maxValues := map[string]any{}
+	nullCount := map[string]int64{}
+	for _, c := range s.cols {
+		nullCount[c.name] = s.nullCount[c.name]
+		if v, exists := s.minValues[c.name]; exists {
+			minValues[c.name] = c.statsValue(v)
+		}
+		if v, exists := s.maxValues[c.name]; exists {
+			maxValues[c.name] = c.statsValue(v)
+		}
+	}
+	b, err := json.Marshal(map[string]any{
+		"numRecords": s.numRecords,
+		"minValues":  minValues,
+		"maxValues":  maxValues,
+		"nullCount":  nullCount,
+	})
+	return string(b), err
+}
+
+// schemaFromConfig builds a table schema string from a list of columns.
+func schemaFromConfig(cols []column) (string, error) {
+	schema := structType{Type: "struct"}
+	for _, c := range cols {
+		schema.Fields = append(schema.Fields, structField{
+			Name:     c.name,
+			Type:     json.RawMessage(strconv.Quote(c.typeName)),
+			Nullable: c.nullable,
+			Metadata: map[string]any{},
+		})
+	}
+	b, err := json.Marshal(schema)
+	return string(b), err
+}
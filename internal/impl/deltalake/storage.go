@@ -0,0 +1,117 @@
+package deltalake
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+var (
+	// ErrObjectExists is returned by ObjectStorage.PutIfAbsent when an object
+	// already exists at the target location.
+	ErrObjectExists = errors.New("object already exists")
+
+	// ErrObjectNotFound is returned by ObjectStorage.Get when an object does
+	// not exist at the target location.
+	ErrObjectNotFound = errors.New("object not found")
+)
+
+// ObjectStorage provides access to the files of a table, which are addressed
+// by their full location URL (e.g. s3://bucket/tables/foo/_delta_log/x.json).
+type ObjectStorage interface {
+	// Put writes an object, overwriting any existing object.
+	Put(ctx context.Context, location string, data []byte) error
+
+	// PutIfAbsent writes an object only if it does not already exist, and
+	// returns ErrObjectExists otherwise. Transaction log commits rely on this
+	// being atomic in order to detect concurrent writers.
+	PutIfAbsent(ctx context.Context, location string, data []byte) error
+
+	// Get reads an object, returning ErrObjectNotFound if it does not exist.
+	Get(ctx context.Context, location string) ([]byte, error)
+}
+
+// ClientOptions are the options used to access the storage of a table, which
+// can be customised by child packages.
+type ClientOptions struct {
+	// Storage maps location URL schemes to the storage implementation used
+	// for them.
+	Storage map[string]ObjectStorage
+}
+
+func (o *ClientOptions) storageFor(location string) (ObjectStorage, error) {
+	scheme := "file"
+	if u, err := url.Parse(location); err == nil && len(u.Scheme) > 1 {
+		scheme = u.Scheme
+	}
+	s, exists := o.Storage[scheme]
+	if !exists {
+		return nil, fmt.Errorf("storage scheme '%v' is not supported, the relevant components package may need to be imported", scheme)
+	}
+	return s, nil
+}
+
+//------------------------------------------------------------------------------
+
+// localStorage writes table files to the local filesystem.
+type localStorage struct {
+	fs *service.FS
+}
+
+func localPath(location string) string {
+	return strings.TrimPrefix(strings.TrimPrefix(location, "file://"), "file:")
+}
+
+func (l *localStorage) write(location string, data []byte, flag int) error {
+	path := localPath(location)
+	if err := l.fs.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	f, err := l.fs.OpenFile(path, flag, 0o644)
+	if err != nil {
+		if errors.Is(err, fs.ErrExist) {
+			return ErrObjectExists
+		}
+		return err
+	}
+
+	w, ok := f.(io.Writer)
+	if !ok {
+		_ = f.Close()
+		return errors.New("failed to open a writable file")
+	}
+	if _, err := w.Write(data); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func (l *localStorage) Put(ctx context.Context, location string, data []byte) error {
+	return l.write(location, data, os.O_CREATE|os.O_WRONLY|os.O_TRUNC)
+}
+
+func (l *localStorage) PutIfAbsent(ctx context.Context, location string, data []byte) error {
+	return l.write(location, data, os.O_CREATE|os.O_WRONLY|os.O_EXCL)
+}
+
+func (l *localStorage) Get(ctx context.Context, location string) ([]byte, error) {
+	f, err := l.fs.Open(localPath(location))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, ErrObjectNotFound
+		}
+		return nil, err
+	}
+	defer f.Close()
+	return io.ReadAll(f)
+}
@@ -0,0 +1,331 @@
+package dgraph
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/dgraph-io/badger/v4"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	bcFieldDirectory       = "directory"
+	bcFieldDefaultTTL      = "default_ttl"
+	bcFieldSyncWrites      = "sync_writes"
+	bcFieldCompaction      = "compaction"
+	bcFieldNumCompactors   = "num_compactors"
+	bcFieldGCInterval      = "gc_interval"
+	bcFieldGCDiscardRatio  = "gc_discard_ratio"
+	bcFieldValueLogMaxSize = "value_log_file_size"
+)
+
+func badgerCacheConfig() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Version("4.28.0").
+		Summary(`Stores key/value pairs in an embedded [Badger](https://github.com/dgraph-io/badger) database on the local filesystem, which persists them across restarts.`).
+		Description(`
+This cache is durable without requiring an external service, which makes it appropriate for holding the state of deduplication between restarts of a single instance of Benthos. The directory of the database can only be opened by one process at a time, and therefore this cache cannot be shared between instances.
+
+The `+"`add`"+` operator is atomic, and items can be given a TTL after which they are no longer returned. Expired and deleted items are removed from disk by compaction, and the space occupied by them within the value log is reclaimed by garbage collection, which runs periodically according to the `+"`compaction`"+` fields.`).
+		Fields(
+			service.NewStringField(bcFieldDirectory).
+				Description("The directory within which to store the database, which is created if it does not exist.").
+				Example("./dedupe_state"),
+			service.NewDurationField(bcFieldDefaultTTL).
+				Description("A default TTL to set for items, calculated from the moment the item is cached. Set to an empty string or zero duration to disable TTLs.").
+				Default("").
+				Example("5m").
+				Example("24h"),
+			service.NewBoolField(bcFieldSyncWrites).
+				Description("Whether to sync writes to disk before they are acknowledged, which prevents items from being lost when the machine fails at the cost of write performance. Items are not lost when Benthos itself fails regardless of this field.").
+				Default(false).
+				Advanced(),
+			service.NewObjectField(bcFieldCompaction,
+				service.NewIntField(bcFieldNumCompactors).
+					Description("The number of compaction workers to run concurrently, which must be at least two.").
+					Default(4),
+				service.NewDurationField(bcFieldGCInterval).
+					Description("The period of time between runs of value log garbage collection. Set to an empty string or zero duration to disable garbage collection.").
+					Default("5m"),
+				service.NewFloatField(bcFieldGCDiscardRatio).
+					Description("The ratio of a value log file that must be occupied by expired or deleted items in order for the file to be rewritten by garbage collection.").
+					Default(0.5),
+				service.NewIntField(bcFieldValueLogMaxSize).
+					Description("The maximum size in bytes of each value log file, smaller files can be garbage collected sooner.").
+					Default(1<<28),
+			).
+				Description("Options for the compaction and garbage collection of the database.").
+				Advanced(),
+		).
+		Example(
+			"Persistent Deduplication",
+			"Here we deduplicate documents by their ID over a period of a day, where the IDs that were seen survive restarts.",
+			`
+pipeline:
+  processors:
+    - dedupe:
+        cache: seen
+        key: ${! json("id") }
+
+cache_resources:
+  - label: seen
+    badger:
+      directory: ./dedupe_state
+      default_ttl: 24h
+`)
+}
+
+func init() {
+	err := service.RegisterCache(
+		"badger", badgerCacheConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Cache, error) {
+			return newBadgerCacheFromConfig(conf, mgr.Logger())
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+func newBadgerCacheFromConfig(conf *service.ParsedConfig, log *service.Logger) (*badgerCache, error) {
+	directory, err := conf.FieldString(bcFieldDirectory)
+	if err != nil {
+		return nil, err
+	}
+
+	var defaultTTL time.Duration
+	if testStr, _ := conf.FieldString(bcFieldDefaultTTL); testStr != "" {
+		if defaultTTL, err = conf.FieldDuration(bcFieldDefaultTTL); err != nil {
+			return nil, err
+		}
+	}
+
+	syncWrites, err := conf.FieldBool(bcFieldSyncWrites)
+	if err != nil {
+		return nil, err
+	}
+
+	compConf := conf.Namespace(bcFieldCompaction)
+	numCompactors, err := compConf.FieldInt(bcFieldNumCompactors)
+	if err != nil {
+		return nil, err
+	}
+	var gcInterval time.Duration
+	if testStr, _ := compConf.FieldString(bcFieldGCInterval); testStr != "" {
+		if gcInterval, err = compConf.FieldDuration(bcFieldGCInterval); err != nil {
+			return nil, err
+		}
+	}
+	gcDiscardRatio, err := compConf.FieldFloat(bcFieldGCDiscardRatio)
+	if err != nil {
+		return nil, err
+	}
+	valueLogFileSize, err := compConf.FieldInt(bcFieldValueLogMaxSize)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := badger.DefaultOptions(directory).
+		WithSyncWrites(syncWrites).
+		WithNumCompactors(numCompactors).
+		WithValueLogFileSize(int64(valueLogFileSize)).
+		WithLogger(badgerLogger{log: log})
+
+	return newBadgerCache(opts, defaultTTL, gcInterval, gcDiscardRatio, log)
+}
+
+//------------------------------------------------------------------------------
+
+// badgerLogger adapts a service logger to the logging interface of Badger.
+type badgerLogger struct {
+	log *service.Logger
+}
+
+func (l badgerLogger) Errorf(format string, v ...any) {
+	l.log.Errorf(format, v...)
+}
+
+func (l badgerLogger) Warningf(format string, v ...any) {
+	l.log.Warnf(format, v...)
+}
+
+func (l badgerLogger) Infof(format string, v ...any) {
+	l.log.Debugf(format, v...)
+}
+
+func (l badgerLogger) Debugf(format string, v ...any) {
+	l.log.Tracef(format, v...)
+}
+
+//------------------------------------------------------------------------------
+
+type badgerCache struct {
+	db         *badger.DB
+	defaultTTL time.Duration
+	log        *service.Logger
+
+	gcDone    chan struct{}
+	closeChan chan struct{}
+	closeOnce sync.Once
+}
+
+func newBadgerCache(opts badger.Options, defaultTTL, gcInterval time.Duration, gcDiscardRatio float64, log *service.Logger) (*badgerCache, error) {
+	db, err := badger.Open(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	b := &badgerCache{
+		db:         db,
+		defaultTTL: defaultTTL,
+		log:        log,
+		gcDone:     make(chan struct{}),
+		closeChan:  make(chan struct{}),
+	}
+	if gcInterval > 0 {
+		go b.gcLoop(gcInterval, gcDiscardRatio)
+	} else {
+		close(b.gcDone)
+	}
+	return b, nil
+}
+
+func (b *badgerCache) gcLoop(interval time.Duration, discardRatio float64) {
+	defer close(b.gcDone)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-b.closeChan:
+			return
+		}
+
+		// Each run rewrites at most one file, and so we keep running until
+		// there is nothing left to rewrite.
+		for {
+			err := b.db.RunValueLogGC(discardRatio)
+			if err == nil {
+				continue
+			}
+			if !errors.Is(err, badger.ErrNoRewrite) && !errors.Is(err, badger.ErrRejected) {
+				b.log.Errorf("Failed to garbage collect value log: %v", err)
+			}
+			break
+		}
+	}
+}
+
+func (b *badgerCache) entry(key string, value []byte, ttl *time.Duration) *badger.Entry {
+	e := badger.NewEntry([]byte(key), value)
+	t := b.defaultTTL
+	if ttl != nil {
+		t = *ttl
+	}
+	if t > 0 {
+		e = e.WithTTL(t)
+	}
+	return e
+}
+
+func (b *badgerCache) Get(ctx context.Context, key string) (value []byte, err error) {
+	err = b.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get([]byte(key))
+		if err != nil {
+			return err
+		}
+		value, err = item.ValueCopy(nil)
+		return err
+	})
+	if errors.Is(err, badger.ErrKeyNotFound) {
+		err = service.ErrKeyNotFound
+	}
+	return
+}
+
+func (b *badgerCache) Set(ctx context.Context, key string, value []byte, ttl *time.Duration) error {
+	return b.db.Update(func(txn *badger.Txn) error {
+		return txn.SetEntry(b.entry(key, value, ttl))
+	})
+}
+
+func (b *badgerCache) GetMulti(ctx context.Context, keys ...string) (map[string][]byte, error) {
+	values := make(map[string][]byte, len(keys))
+	err := b.db.View(func(txn *badger.Txn) error {
+		for _, k := range keys {
+			item, err := txn.Get([]byte(k))
+			if errors.Is(err, badger.ErrKeyNotFound) {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			if values[k], err = item.ValueCopy(nil); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+func (b *badgerCache) SetMulti(ctx context.Context, keyValues ...service.CacheItem) error {
+	wb := b.db.NewWriteBatch()
+	defer wb.Cancel()
+
+	for _, item := range keyValues {
+		if err := wb.SetEntry(b.entry(item.Key, item.Value, item.TTL)); err != nil {
+			return err
+		}
+	}
+	return wb.Flush()
+}
+
+func (b *badgerCache) Add(ctx context.Context, key string, value []byte, ttl *time.Duration) error {
+	for {
+		err := b.db.Update(func(txn *badger.Txn) error {
+			_, err := txn.Get([]byte(key))
+			if err == nil {
+				return service.ErrKeyAlreadyExists
+			}
+			if !errors.Is(err, badger.ErrKeyNotFound) {
+				return err
+			}
+			return txn.SetEntry(b.entry(key, value, ttl))
+		})
+		// A conflict means that the key was written concurrently, in which
+		// case we try again in order to observe it.
+		if !errors.Is(err, badger.ErrConflict) {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+	}
+}
+
+func (b *badgerCache) Delete(ctx context.Context, key string) error {
+	return b.db.Update(func(txn *badger.Txn) error {
+		return txn.Delete([]byte(key))
+	})
+}
+
+func (b *badgerCache) Close(ctx context.Context) error {
+	var err error
+	b.closeOnce.Do(func() {
+		close(b.closeChan)
+		<-b.gcDone
+		err = b.db.Close()
+	})
+	return err
+}
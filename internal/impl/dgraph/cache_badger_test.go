@@ -0,0 +1,140 @@
+package dgraph
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func testBadgerCache(t *testing.T, dir, extraConf string) *badgerCache {
+	t.Helper()
+
+	conf, err := badgerCacheConfig().ParseYAML(fmt.Sprintf(`
+directory: %v
+%v
+`, dir, extraConf), nil)
+	require.NoError(t, err)
+
+	c, err := newBadgerCacheFromConfig(conf, nil)
+	require.NoError(t, err)
+	return c
+}
+
+func TestBadgerCache(t *testing.T) {
+	c := testBadgerCache(t, t.TempDir(), "")
+	t.Cleanup(func() {
+		_ = c.Close(context.Background())
+	})
+
+	ctx := context.Background()
+
+	_, err := c.Get(ctx, "foo")
+	assert.Equal(t, service.ErrKeyNotFound, err)
+
+	require.NoError(t, c.Set(ctx, "foo", []byte("1"), nil))
+
+	res, err := c.Get(ctx, "foo")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("1"), res)
+
+	assert.Equal(t, service.ErrKeyAlreadyExists, c.Add(ctx, "foo", []byte("2"), nil))
+	require.NoError(t, c.Add(ctx, "bar", []byte("3"), nil))
+
+	require.NoError(t, c.SetMulti(ctx,
+		service.CacheItem{Key: "baz", Value: []byte("4")},
+		service.CacheItem{Key: "buz", Value: []byte("5")},
+	))
+
+	values, err := c.GetMulti(ctx, "foo", "bar", "baz", "buz", "nope")
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]byte{
+		"foo": []byte("1"),
+		"bar": []byte("3"),
+		"baz": []byte("4"),
+		"buz": []byte("5"),
+	}, values)
+
+	require.NoError(t, c.Delete(ctx, "foo"))
+
+	_, err = c.Get(ctx, "foo")
+	assert.Equal(t, service.ErrKeyNotFound, err)
+}
+
+func TestBadgerCacheTTL(t *testing.T) {
+	c := testBadgerCache(t, t.TempDir(), `default_ttl: 1s`)
+	t.Cleanup(func() {
+		_ = c.Close(context.Background())
+	})
+
+	ctx := context.Background()
+
+	ttl := time.Hour
+	require.NoError(t, c.Set(ctx, "foo", []byte("1"), nil))
+	require.NoError(t, c.Set(ctx, "bar", []byte("2"), &ttl))
+
+	// Badger expires items at a granularity of seconds.
+	require.Eventually(t, func() bool {
+		_, err := c.Get(ctx, "foo")
+		return err == service.ErrKeyNotFound
+	}, time.Second*5, time.Millisecond*50)
+
+	res, err := c.Get(ctx, "bar")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("2"), res)
+
+	require.NoError(t, c.Add(ctx, "foo", []byte("3"), &ttl))
+}
+
+func TestBadgerCacheConcurrentAdd(t *testing.T) {
+	c := testBadgerCache(t, t.TempDir(), "")
+	t.Cleanup(func() {
+		_ = c.Close(context.Background())
+	})
+
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	var mut sync.Mutex
+	var added int
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := c.Add(ctx, "foo", []byte("1"), nil)
+			if err == nil {
+				mut.Lock()
+				added++
+				mut.Unlock()
+				return
+			}
+			assert.Equal(t, service.ErrKeyAlreadyExists, err)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, 1, added)
+}
+
+func TestBadgerCachePersists(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	c := testBadgerCache(t, dir, "")
+	require.NoError(t, c.Set(ctx, "foo", []byte("1"), nil))
+	require.NoError(t, c.Close(ctx))
+
+	c = testBadgerCache(t, dir, "")
+	t.Cleanup(func() {
+		_ = c.Close(context.Background())
+	})
+
+	res, err := c.Get(ctx, "foo")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("1"), res)
+}
@@ -0,0 +1,376 @@
+package dns
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Jeffail/gabs/v2"
+	lru "github.com/hashicorp/golang-lru/v2"
+	"golang.org/x/net/dns/dnsmessage"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	dpFieldLookups          = "lookups"
+	dpFieldLookupField      = "field"
+	dpFieldLookupTarget     = "target"
+	dpFieldLookupType       = "type"
+	dpFieldResolver         = "resolver"
+	dpFieldTLS              = "tls"
+	dpFieldTimeout          = "timeout"
+	dpFieldMessageTimeout   = "message_timeout"
+	dpFieldOnNXDomain       = "on_nxdomain"
+	dpFieldCache            = "cache"
+	dpFieldCacheSize        = "size"
+	dpFieldCacheMinTTL      = "min_ttl"
+	dpFieldCacheMaxTTL      = "max_ttl"
+	dpFieldCacheNegativeTTL = "negative_ttl"
+
+	dpOnNXDomainSkip  = "skip"
+	dpOnNXDomainNull  = "null"
+	dpOnNXDomainError = "error"
+)
+
+func dnsProcSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Version("4.28.0").
+		Categories("Integration").
+		Summary("Performs DNS lookups of the values of fields and adds the resulting records to messages.").
+		Description(`
+Messages are parsed as JSON, and for each of the `+"`lookups`"+` the string within `+"`field`"+` is looked up and the resulting records are added as an array to `+"`target`"+`. The records of each type are represented as follows:
+
+- `+"`A`"+` and `+"`AAAA`"+`: IP addresses such as `+"`\"192.0.2.1\"`"+`.
+- `+"`PTR`"+`: The host names of the IP address within `+"`field`"+`, such as `+"`\"mail.example.com\"`"+`.
+- `+"`TXT`"+`: The text of each record, where the strings of a record are concatenated.
+- `+"`MX`"+`: Objects such as `+"`{\"host\":\"mx1.example.com\",\"preference\":10}`"+`.
+
+Lookups of fields that do not exist within a message are skipped, and messages where a field is not a string are flagged as having failed.
+
+### Resolvers
+
+By default lookups are performed with the resolver of the operating system. Alternatively, the field `+"`resolver`"+` can be set to the URL of a DNS server, where the scheme of the URL determines the protocol used:
+
+- `+"`udp://8.8.8.8`"+`: Plain DNS over UDP, falling back to TCP for truncated responses.
+- `+"`tcp://8.8.8.8`"+`: Plain DNS over TCP.
+- `+"`tls://1.1.1.1`"+`: DNS over TLS (DoT), which uses port 853 by default.
+- `+"`https://cloudflare-dns.com/dns-query`"+`: DNS over HTTPS (DoH).
+
+### Caching
+
+The results of lookups are cached for the TTL of their records, bounded by `+"`cache.min_ttl`"+` and `+"`cache.max_ttl`"+`. Since the system resolver does not expose TTLs, results obtained from it are cached for `+"`cache.max_ttl`"+`. Lookups of names that do not exist (NXDOMAIN) or that have no records of the requested type are cached for `+"`cache.negative_ttl`"+`, or for the negative TTL of the zone when it is lower.
+
+### Timeouts
+
+Each lookup is given the period `+"`timeout`"+` to complete, and all lookups of a message must complete within `+"`message_timeout`"+`. Lookups that fail or time out flag the message as having failed, leaving it unchanged, allowing you to use [standard processor error handling patterns](/docs/configuration/error_handling).`).
+		Fields(
+			service.NewObjectListField(dpFieldLookups,
+				service.NewStringField(dpFieldLookupField).
+					Description("The [dot path](/docs/configuration/field_paths) of the name or IP address to look up."),
+				service.NewStringField(dpFieldLookupTarget).
+					Description("The [dot path](/docs/configuration/field_paths) of the field to add the resulting records to."),
+				service.NewStringEnumField(dpFieldLookupType, "A", "AAAA", "PTR", "TXT", "MX").
+					Description("The type of records to look up.").
+					Default("A"),
+			).
+				Description("The lookups to perform on each message.").
+				Example([]any{
+					map[string]any{"field": "source.ip", "target": "source.hostnames", "type": "PTR"},
+					map[string]any{"field": "destination.domain", "target": "destination.ips", "type": "A"},
+				}),
+			service.NewStringField(dpFieldResolver).
+				Description("The URL of a DNS server to perform lookups with, where the system resolver is used when empty.").
+				Examples("udp://8.8.8.8:53", "tls://1.1.1.1", "https://cloudflare-dns.com/dns-query").
+				Default(""),
+			service.NewTLSField(dpFieldTLS).
+				Description("Custom TLS settings for resolvers using the `tls` or `https` schemes.").
+				Advanced(),
+			service.NewDurationField(dpFieldTimeout).
+				Description("The maximum period to wait for each lookup.").
+				Default("2s"),
+			service.NewDurationField(dpFieldMessageTimeout).
+				Description("The maximum period to wait for all lookups of a message.").
+				Default("10s"),
+			service.NewStringAnnotatedEnumField(dpFieldOnNXDomain, map[string]string{
+				dpOnNXDomainSkip:  "Leave the target field unset.",
+				dpOnNXDomainNull:  "Set the target field to `null`.",
+				dpOnNXDomainError: "Flag the message as having failed.",
+			}).
+				Description("How to handle lookups of names that do not exist (NXDOMAIN). Names that exist but have no records of the requested type always result in an empty array.").
+				Default(dpOnNXDomainSkip),
+			service.NewObjectField(dpFieldCache,
+				service.NewIntField(dpFieldCacheSize).
+					Description("The maximum number of lookup results to cache. Set to zero to disable the cache.").
+					Default(10000),
+				service.NewDurationField(dpFieldCacheMinTTL).
+					Description("The minimum period to cache results for, overriding lower TTLs.").
+					Default("0s"),
+				service.NewDurationField(dpFieldCacheMaxTTL).
+					Description("The maximum period to cache results for, overriding higher TTLs.").
+					Default("1h"),
+				service.NewDurationField(dpFieldCacheNegativeTTL).
+					Description("The maximum period to cache lookups of names that do not exist or have no records for.").
+					Default("5m"),
+			).
+				Description("Configuration of the cache of lookup results.").
+				Advanced(),
+		).
+		Example("Security Log Enrichment", "Here we resolve the host names of the source addresses of firewall logs, and the addresses of the domains that clients queried, with a DNS over HTTPS resolver.", `
+pipeline:
+  processors:
+    - dns:
+        resolver: https://cloudflare-dns.com/dns-query
+        lookups:
+          - field: source.ip
+            target: source.hostnames
+            type: PTR
+          - field: dns.question.name
+            target: dns.resolved_ips
+            type: A
+`)
+}
+
+func init() {
+	err := service.RegisterProcessor(
+		"dns", dnsProcSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Processor, error) {
+			return newDNSProcFromConfig(conf, mgr)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type dnsLookup struct {
+	field   string
+	target  string
+	typeStr string
+	qType   dnsmessage.Type
+}
+
+type cacheKey struct {
+	name  string
+	qType dnsmessage.Type
+}
+
+type cacheEntry struct {
+	res     lookupResult
+	expires time.Time
+}
+
+type dnsProc struct {
+	log *service.Logger
+
+	lookups        []dnsLookup
+	resolver       resolver
+	timeout        time.Duration
+	messageTimeout time.Duration
+	onNXDomain     string
+
+	cache       *lru.Cache[cacheKey, cacheEntry]
+	minTTL      time.Duration
+	maxTTL      time.Duration
+	negativeTTL time.Duration
+
+	now func() time.Time
+}
+
+func newDNSProcFromConfig(conf *service.ParsedConfig, mgr *service.Resources) (*dnsProc, error) {
+	d := &dnsProc{
+		log: mgr.Logger(),
+		now: time.Now,
+	}
+
+	lookupConfs, err := conf.FieldObjectList(dpFieldLookups)
+	if err != nil {
+		return nil, err
+	}
+	if len(lookupConfs) == 0 {
+		return nil, errors.New("at least one lookup must be specified")
+	}
+	for i, lConf := range lookupConfs {
+		var l dnsLookup
+		if l.field, err = lConf.FieldString(dpFieldLookupField); err != nil {
+			return nil, err
+		}
+		if l.target, err = lConf.FieldString(dpFieldLookupTarget); err != nil {
+			return nil, err
+		}
+		if l.typeStr, err = lConf.FieldString(dpFieldLookupType); err != nil {
+			return nil, err
+		}
+		var exists bool
+		if l.qType, exists = lookupTypes[l.typeStr]; !exists {
+			return nil, fmt.Errorf("lookup %v: unsupported record type: %v", i, l.typeStr)
+		}
+		d.lookups = append(d.lookups, l)
+	}
+
+	resolverURL, err := conf.FieldString(dpFieldResolver)
+	if err != nil {
+		return nil, err
+	}
+	tlsConf, err := conf.FieldTLS(dpFieldTLS)
+	if err != nil {
+		return nil, err
+	}
+	if d.resolver, err = newResolver(resolverURL, tlsConf); err != nil {
+		return nil, err
+	}
+
+	if d.timeout, err = conf.FieldDuration(dpFieldTimeout); err != nil {
+		return nil, err
+	}
+	if d.messageTimeout, err = conf.FieldDuration(dpFieldMessageTimeout); err != nil {
+		return nil, err
+	}
+	if d.onNXDomain, err = conf.FieldString(dpFieldOnNXDomain); err != nil {
+		return nil, err
+	}
+
+	cConf := conf.Namespace(dpFieldCache)
+	cacheSize, err := cConf.FieldInt(dpFieldCacheSize)
+	if err != nil {
+		return nil, err
+	}
+	if cacheSize < 0 {
+		return nil, errors.New("cache size must not be negative")
+	}
+	if cacheSize > 0 {
+		if d.cache, err = lru.New[cacheKey, cacheEntry](cacheSize); err != nil {
+			return nil, err
+		}
+	}
+	if d.minTTL, err = cConf.FieldDuration(dpFieldCacheMinTTL); err != nil {
+		return nil, err
+	}
+	if d.maxTTL, err = cConf.FieldDuration(dpFieldCacheMaxTTL); err != nil {
+		return nil, err
+	}
+	if d.negativeTTL, err = cConf.FieldDuration(dpFieldCacheNegativeTTL); err != nil {
+		return nil, err
+	}
+	if d.minTTL > d.maxTTL {
+		return nil, errors.New("cache min_ttl must not be greater than max_ttl")
+	}
+	return d, nil
+}
+
+// cacheTTL returns the period to cache a lookup result for.
+func (d *dnsProc) cacheTTL(res lookupResult) time.Duration {
+	if res.nxdomain || len(res.values) == 0 {
+		if res.ttl < 0 || res.ttl > d.negativeTTL {
+			return d.negativeTTL
+		}
+		return res.ttl
+	}
+	if res.ttl < 0 {
+		return d.maxTTL
+	}
+	return min(max(res.ttl, d.minTTL), d.maxTTL)
+}
+
+func (d *dnsProc) resolve(ctx context.Context, name string, qType dnsmessage.Type) (lookupResult, error) {
+	key := cacheKey{name: strings.ToLower(name), qType: qType}
+	if d.cache != nil {
+		if e, exists := d.cache.Get(key); exists {
+			if d.now().Before(e.expires) {
+				return e.res, nil
+			}
+			d.cache.Remove(key)
+		}
+	}
+
+	if d.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, d.timeout)
+		defer cancel()
+	}
+	res, err := d.resolver.lookup(ctx, name, qType)
+	if err != nil {
+		return lookupResult{}, err
+	}
+
+	if d.cache != nil {
+		if ttl := d.cacheTTL(res); ttl > 0 {
+			d.cache.Add(key, cacheEntry{res: res, expires: d.now().Add(ttl)})
+		}
+	}
+	return res, nil
+}
+
+// copyValues returns a copy of cached records that is safe to mutate.
+func copyValues(values []any) []any {
+	c := make([]any, 0, len(values))
+	for _, v := range values {
+		if obj, ok := v.(map[string]any); ok {
+			objCopy := make(map[string]any, len(obj))
+			for k, ov := range obj {
+				objCopy[k] = ov
+			}
+			v = objCopy
+		}
+		c = append(c, v)
+	}
+	return c
+}
+
+func (d *dnsProc) Process(ctx context.Context, msg *service.Message) (service.MessageBatch, error) {
+	v, err := msg.AsStructuredMut()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse message as JSON: %w", err)
+	}
+	doc := gabs.Wrap(v)
+
+	if d.messageTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, d.messageTimeout)
+		defer cancel()
+	}
+
+	for _, l := range d.lookups {
+		nameV := doc.Path(l.field).Data()
+		if nameV == nil {
+			continue
+		}
+		name, ok := nameV.(string)
+		if !ok {
+			return nil, fmt.Errorf("field %v: expected string value, got %T", l.field, nameV)
+		}
+		if name = strings.TrimSpace(name); name == "" {
+			continue
+		}
+
+		res, err := d.resolve(ctx, name, l.qType)
+		if err != nil {
+			d.log.Debugf("Failed to look up %v records of %v: %v", l.typeStr, name, err)
+			return nil, fmt.Errorf("failed to look up %v records of %v: %w", l.typeStr, name, err)
+		}
+
+		var value any = copyValues(res.values)
+		if res.nxdomain {
+			switch d.onNXDomain {
+			case dpOnNXDomainSkip:
+				continue
+			case dpOnNXDomainError:
+				return nil, fmt.Errorf("failed to look up %v records of %v: name does not exist", l.typeStr, name)
+			default:
+				value = nil
+			}
+		}
+		if _, err := doc.SetP(value, l.target); err != nil {
+			return nil, fmt.Errorf("field %v: %w", l.target, err)
+		}
+	}
+
+	msg.SetStructuredMut(doc.Data())
+	return service.MessageBatch{msg}, nil
+}
+
+func (d *dnsProc) Close(ctx context.Context) error {
+	return nil
+}
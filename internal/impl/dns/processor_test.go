@@ -0,0 +1,312 @@
+package dns
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/dns/dnsmessage"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+// testZone answers queries for a fixed set of records, and counts the queries
+// that it receives.
+type testZone struct {
+	queries atomic.Int64
+}
+
+func (z *testZone) answer(query []byte) []byte {
+	z.queries.Add(1)
+
+	var q dnsmessage.Message
+	if err := q.Unpack(query); err != nil || len(q.Questions) != 1 {
+		return nil
+	}
+	question := q.Questions[0]
+
+	res := dnsmessage.Message{
+		Header: dnsmessage.Header{
+			ID:                 q.Header.ID,
+			Response:           true,
+			RecursionAvailable: true,
+		},
+		Questions: q.Questions,
+	}
+	rh := dnsmessage.ResourceHeader{Name: question.Name, Type: question.Type, Class: dnsmessage.ClassINET, TTL: 300}
+
+	switch question.Name.String() + question.Type.String() {
+	case "example.com.TypeA":
+		res.Answers = []dnsmessage.Resource{
+			{Header: rh, Body: &dnsmessage.AResource{A: [4]byte{192, 0, 2, 1}}},
+			{Header: rh, Body: &dnsmessage.AResource{A: [4]byte{192, 0, 2, 2}}},
+		}
+	case "example.com.TypeMX":
+		res.Answers = []dnsmessage.Resource{
+			{Header: rh, Body: &dnsmessage.MXResource{Pref: 10, MX: dnsmessage.MustNewName("mx1.example.com.")}},
+		}
+	case "example.com.TypeTXT":
+		res.Answers = []dnsmessage.Resource{
+			{Header: rh, Body: &dnsmessage.TXTResource{TXT: []string{"v=spf1 ", "-all"}}},
+		}
+	case "1.2.0.192.in-addr.arpa.TypePTR":
+		res.Answers = []dnsmessage.Resource{
+			{Header: rh, Body: &dnsmessage.PTRResource{PTR: dnsmessage.MustNewName("host.example.com.")}},
+		}
+	case "broken.example.com.TypeA":
+		res.Header.RCode = dnsmessage.RCodeServerFailure
+	case "example.com.TypeAAAA":
+	default:
+		res.Header.RCode = dnsmessage.RCodeNameError
+		res.Authorities = []dnsmessage.Resource{{
+			Header: dnsmessage.ResourceHeader{Name: dnsmessage.MustNewName("example.com."), Type: dnsmessage.TypeSOA, Class: dnsmessage.ClassINET, TTL: 60},
+			Body: &dnsmessage.SOAResource{
+				NS:     dnsmessage.MustNewName("ns.example.com."),
+				MBox:   dnsmessage.MustNewName("admin.example.com."),
+				MinTTL: 30,
+			},
+		}}
+	}
+
+	b, _ := res.Pack()
+	return b
+}
+
+func (z *testZone) serveUDP(t *testing.T) string {
+	t.Helper()
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = conn.Close()
+	})
+
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			if res := z.answer(buf[:n]); res != nil {
+				_, _ = conn.WriteTo(res, addr)
+			}
+		}
+	}()
+	return "udp://" + conn.LocalAddr().String()
+}
+
+func (z *testZone) serveHTTPS(t *testing.T) string {
+	t.Helper()
+
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Type") != "application/dns-message" {
+			http.Error(w, "bad content type", http.StatusUnsupportedMediaType)
+			return
+		}
+		query, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/dns-message")
+		_, _ = io.Copy(w, bytes.NewReader(z.answer(query)))
+	}))
+	t.Cleanup(ts.Close)
+	return ts.URL + "/dns-query"
+}
+
+func testDNSProc(t *testing.T, confStr string) *dnsProc {
+	t.Helper()
+
+	conf, err := dnsProcSpec().ParseYAML(confStr, nil)
+	require.NoError(t, err)
+
+	proc, err := newDNSProcFromConfig(conf, service.MockResources())
+	require.NoError(t, err)
+	return proc
+}
+
+func TestReverseAddr(t *testing.T) {
+	name, err := reverseAddr("192.0.2.1")
+	require.NoError(t, err)
+	assert.Equal(t, "1.2.0.192.in-addr.arpa.", name)
+
+	name, err = reverseAddr("2001:db8::1")
+	require.NoError(t, err)
+	assert.Equal(t, "1.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.8.b.d.0.1.0.0.2.ip6.arpa.", name)
+
+	_, err = reverseAddr("example.com")
+	require.Error(t, err)
+}
+
+func TestDNSProcessorLookups(t *testing.T) {
+	for _, transport := range []string{"udp", "https"} {
+		transport := transport
+		t.Run(transport, func(t *testing.T) {
+			var zone testZone
+			url := zone.serveUDP(t)
+			if transport == "https" {
+				url = zone.serveHTTPS(t)
+			}
+
+			proc := testDNSProc(t, `
+resolver: `+url+`
+tls:
+  skip_cert_verify: true
+lookups:
+  - field: domain
+    target: ips
+  - field: domain
+    target: mx
+    type: MX
+  - field: domain
+    target: txt
+    type: TXT
+  - field: domain
+    target: ipv6
+    type: AAAA
+  - field: ip
+    target: hostnames
+    type: PTR
+  - field: missing
+    target: nope
+`)
+
+			res, err := proc.Process(context.Background(), service.NewMessage([]byte(`{"domain":"example.com","ip":"192.0.2.1"}`)))
+			require.NoError(t, err)
+			require.Len(t, res, 1)
+
+			b, err := res[0].AsBytes()
+			require.NoError(t, err)
+			assert.JSONEq(t, `{
+  "domain": "example.com",
+  "ip": "192.0.2.1",
+  "ips": ["192.0.2.1", "192.0.2.2"],
+  "mx": [{"host": "mx1.example.com", "preference": 10}],
+  "txt": ["v=spf1 -all"],
+  "ipv6": [],
+  "hostnames": ["host.example.com"]
+}`, string(b))
+			assert.Equal(t, int64(5), zone.queries.Load())
+		})
+	}
+}
+
+func TestDNSProcessorCache(t *testing.T) {
+	var zone testZone
+	proc := testDNSProc(t, `
+resolver: `+zone.serveUDP(t)+`
+lookups:
+  - field: domain
+    target: ips
+cache:
+  max_ttl: 2m
+`)
+
+	now := time.Now()
+	proc.now = func() time.Time { return now }
+
+	process := func(domain string) {
+		t.Helper()
+		_, err := proc.Process(context.Background(), service.NewMessage([]byte(`{"domain":"`+domain+`"}`)))
+		require.NoError(t, err)
+	}
+
+	process("example.com")
+	process("EXAMPLE.com")
+	assert.Equal(t, int64(1), zone.queries.Load())
+
+	// The record TTL of five minutes is capped to two minutes.
+	now = now.Add(time.Minute)
+	process("example.com")
+	assert.Equal(t, int64(1), zone.queries.Load())
+
+	now = now.Add(2 * time.Minute)
+	process("example.com")
+	assert.Equal(t, int64(2), zone.queries.Load())
+
+	// Lookups of names that do not exist are cached for the negative TTL of
+	// the zone.
+	process("missing.example.com")
+	process("missing.example.com")
+	assert.Equal(t, int64(3), zone.queries.Load())
+
+	now = now.Add(31 * time.Second)
+	process("missing.example.com")
+	assert.Equal(t, int64(4), zone.queries.Load())
+}
+
+func TestDNSProcessorNXDomain(t *testing.T) {
+	var zone testZone
+	url := zone.serveUDP(t)
+
+	for _, test := range []struct {
+		mode   string
+		output string
+		errStr string
+	}{
+		{mode: "skip", output: `{"domain":"missing.example.com"}`},
+		{mode: "null", output: `{"domain":"missing.example.com","ips":null}`},
+		{mode: "error", errStr: "name does not exist"},
+	} {
+		proc := testDNSProc(t, `
+resolver: `+url+`
+on_nxdomain: `+test.mode+`
+lookups:
+  - field: domain
+    target: ips
+`)
+
+		res, err := proc.Process(context.Background(), service.NewMessage([]byte(`{"domain":"missing.example.com"}`)))
+		if test.errStr != "" {
+			assert.ErrorContains(t, err, test.errStr, test.mode)
+			continue
+		}
+		require.NoError(t, err, test.mode)
+
+		b, err := res[0].AsBytes()
+		require.NoError(t, err)
+		assert.JSONEq(t, test.output, string(b), test.mode)
+	}
+}
+
+func TestDNSProcessorErrors(t *testing.T) {
+	var zone testZone
+	proc := testDNSProc(t, `
+resolver: `+zone.serveUDP(t)+`
+lookups:
+  - field: domain
+    target: ips
+`)
+
+	_, err := proc.Process(context.Background(), service.NewMessage([]byte(`{"domain":"broken.example.com"}`)))
+	assert.ErrorContains(t, err, "ServerFailure")
+
+	_, err = proc.Process(context.Background(), service.NewMessage([]byte(`{"domain":5}`)))
+	assert.ErrorContains(t, err, "expected string value")
+
+	// A server that never responds results in a timeout.
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = conn.Close()
+	})
+
+	proc = testDNSProc(t, `
+resolver: udp://`+conn.LocalAddr().String()+`
+timeout: 50ms
+lookups:
+  - field: domain
+    target: ips
+`)
+
+	start := time.Now()
+	_, err = proc.Process(context.Background(), service.NewMessage([]byte(`{"domain":"example.com"}`)))
+	require.Error(t, err)
+	assert.Less(t, time.Since(start), time.Second)
+}
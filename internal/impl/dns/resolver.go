@@ -0,0 +1,380 @@
+package dns
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// lookupResult is the result of a lookup, where values are the records of the
+// requested type and ttl is the lowest TTL of the records, or negative when
+// the TTL is unknown.
+type lookupResult struct {
+	values   []any
+	ttl      time.Duration
+	nxdomain bool
+}
+
+// resolver performs lookups of a given record type, where names of PTR lookups
+// are IP addresses.
+type resolver interface {
+	lookup(ctx context.Context, name string, qType dnsmessage.Type) (lookupResult, error)
+}
+
+var lookupTypes = map[string]dnsmessage.Type{
+	"A":    dnsmessage.TypeA,
+	"AAAA": dnsmessage.TypeAAAA,
+	"PTR":  dnsmessage.TypePTR,
+	"TXT":  dnsmessage.TypeTXT,
+	"MX":   dnsmessage.TypeMX,
+}
+
+// newResolver returns the resolver of a URL, or the system resolver when the
+// URL is empty.
+func newResolver(urlStr string, tlsConf *tls.Config) (resolver, error) {
+	if urlStr == "" {
+		return &systemResolver{r: net.DefaultResolver}, nil
+	}
+
+	u, err := url.Parse(urlStr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse resolver url: %w", err)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("resolver url %v does not contain a host", urlStr)
+	}
+
+	w := &wireResolver{scheme: u.Scheme, addr: u.Host}
+	switch u.Scheme {
+	case "udp", "tcp":
+		if u.Port() == "" {
+			w.addr = net.JoinHostPort(u.Hostname(), "53")
+		}
+	case "tls":
+		if u.Port() == "" {
+			w.addr = net.JoinHostPort(u.Hostname(), "853")
+		}
+		w.tlsConf = tlsConf.Clone()
+		if w.tlsConf == nil {
+			w.tlsConf = &tls.Config{}
+		}
+		if w.tlsConf.ServerName == "" {
+			w.tlsConf.ServerName = u.Hostname()
+		}
+	case "https":
+		w.url = u.String()
+		w.client = &http.Client{Transport: &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: tlsConf,
+		}}
+	default:
+		return nil, fmt.Errorf("unsupported resolver scheme: %v", u.Scheme)
+	}
+	return w, nil
+}
+
+//------------------------------------------------------------------------------
+
+// systemResolver performs lookups with the resolver of the operating system,
+// which does not expose the TTLs of records.
+type systemResolver struct {
+	r *net.Resolver
+}
+
+func (s *systemResolver) lookup(ctx context.Context, name string, qType dnsmessage.Type) (res lookupResult, err error) {
+	res.ttl = -1
+	switch qType {
+	case dnsmessage.TypeA, dnsmessage.TypeAAAA:
+		network := "ip4"
+		if qType == dnsmessage.TypeAAAA {
+			network = "ip6"
+		}
+		var ips []net.IP
+		if ips, err = s.r.LookupIP(ctx, network, name); err == nil {
+			for _, ip := range ips {
+				res.values = append(res.values, ip.String())
+			}
+		}
+	case dnsmessage.TypePTR:
+		var names []string
+		if names, err = s.r.LookupAddr(ctx, name); err == nil {
+			for _, n := range names {
+				res.values = append(res.values, strings.TrimSuffix(n, "."))
+			}
+		}
+	case dnsmessage.TypeTXT:
+		var txts []string
+		if txts, err = s.r.LookupTXT(ctx, name); err == nil {
+			for _, t := range txts {
+				res.values = append(res.values, t)
+			}
+		}
+	case dnsmessage.TypeMX:
+		var mxs []*net.MX
+		if mxs, err = s.r.LookupMX(ctx, name); err == nil {
+			for _, mx := range mxs {
+				res.values = append(res.values, map[string]any{
+					"host":       strings.TrimSuffix(mx.Host, "."),
+					"preference": int64(mx.Pref),
+				})
+			}
+		}
+	default:
+		return res, fmt.Errorf("unsupported record type: %v", qType)
+	}
+
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+		return lookupResult{ttl: -1, nxdomain: true}, nil
+	}
+	return res, err
+}
+
+//------------------------------------------------------------------------------
+
+// wireResolver performs lookups by exchanging DNS messages with a server over
+// UDP, TCP, TLS (DoT) or HTTPS (DoH).
+type wireResolver struct {
+	scheme  string
+	addr    string
+	url     string
+	tlsConf *tls.Config
+	client  *http.Client
+}
+
+func (w *wireResolver) lookup(ctx context.Context, name string, qType dnsmessage.Type) (lookupResult, error) {
+	if qType == dnsmessage.TypePTR {
+		var err error
+		if name, err = reverseAddr(name); err != nil {
+			return lookupResult{}, err
+		}
+	}
+	if !strings.HasSuffix(name, ".") {
+		name += "."
+	}
+	qName, err := dnsmessage.NewName(name)
+	if err != nil {
+		return lookupResult{}, fmt.Errorf("invalid name %q: %w", name, err)
+	}
+
+	query := dnsmessage.Message{
+		Header: dnsmessage.Header{RecursionDesired: true},
+		Questions: []dnsmessage.Question{
+			{Name: qName, Type: qType, Class: dnsmessage.ClassINET},
+		},
+	}
+	// DoH queries use an ID of zero in order to be cache friendly.
+	if w.scheme != "https" {
+		query.Header.ID = uint16(rand.Uint32())
+	}
+	packed, err := query.Pack()
+	if err != nil {
+		return lookupResult{}, err
+	}
+
+	var resBytes []byte
+	switch w.scheme {
+	case "udp":
+		if resBytes, err = w.exchangeUDP(ctx, packed); err == nil && isTruncated(resBytes) {
+			resBytes, err = w.exchangeStream(ctx, packed)
+		}
+	case "https":
+		resBytes, err = w.exchangeHTTPS(ctx, packed)
+	default:
+		resBytes, err = w.exchangeStream(ctx, packed)
+	}
+	if err != nil {
+		return lookupResult{}, err
+	}
+
+	var res dnsmessage.Message
+	if err := res.Unpack(resBytes); err != nil {
+		return lookupResult{}, fmt.Errorf("failed to parse response: %w", err)
+	}
+	if res.Header.ID != query.Header.ID {
+		return lookupResult{}, errors.New("response id does not match query")
+	}
+	return parseAnswers(&res, qType)
+}
+
+func isTruncated(msg []byte) bool {
+	return len(msg) > 2 && msg[2]&0x02 != 0
+}
+
+func (w *wireResolver) exchangeUDP(ctx context.Context, query []byte) ([]byte, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "udp", w.addr)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	if _, err := conn.Write(query); err != nil {
+		return nil, err
+	}
+	buf := make([]byte, 65535)
+	n, err := conn.Read(buf)
+	if err != nil {
+		return nil, err
+	}
+	return buf[:n], nil
+}
+
+// exchangeStream exchanges a message over TCP or TLS, where messages are
+// prefixed with their length.
+func (w *wireResolver) exchangeStream(ctx context.Context, query []byte) ([]byte, error) {
+	var conn net.Conn
+	var err error
+	if w.scheme == "tls" {
+		d := tls.Dialer{Config: w.tlsConf}
+		conn, err = d.DialContext(ctx, "tcp", w.addr)
+	} else {
+		var d net.Dialer
+		conn, err = d.DialContext(ctx, "tcp", w.addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	framed := make([]byte, 2, 2+len(query))
+	binary.BigEndian.PutUint16(framed, uint16(len(query)))
+	if _, err := conn.Write(append(framed, query...)); err != nil {
+		return nil, err
+	}
+
+	var length [2]byte
+	if _, err := io.ReadFull(conn, length[:]); err != nil {
+		return nil, err
+	}
+	res := make([]byte, binary.BigEndian.Uint16(length[:]))
+	if _, err := io.ReadFull(conn, res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (w *wireResolver) exchangeHTTPS(ctx context.Context, query []byte) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(query))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/dns-message")
+	req.Header.Set("Accept", "application/dns-message")
+
+	res, err := w.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(res.Body, 65535))
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status code %v: %s", res.StatusCode, bytes.TrimSpace(body))
+	}
+	return body, nil
+}
+
+// parseAnswers extracts the records of a given type from a response, along
+// with their lowest TTL.
+func parseAnswers(msg *dnsmessage.Message, qType dnsmessage.Type) (lookupResult, error) {
+	switch msg.Header.RCode {
+	case dnsmessage.RCodeSuccess:
+	case dnsmessage.RCodeNameError:
+		return lookupResult{ttl: negativeTTL(msg), nxdomain: true}, nil
+	default:
+		return lookupResult{}, fmt.Errorf("server responded with %v", strings.TrimPrefix(msg.Header.RCode.String(), "RCode"))
+	}
+
+	res := lookupResult{ttl: -1}
+	for _, a := range msg.Answers {
+		if a.Header.Type != qType {
+			continue
+		}
+		switch body := a.Body.(type) {
+		case *dnsmessage.AResource:
+			res.values = append(res.values, net.IP(body.A[:]).String())
+		case *dnsmessage.AAAAResource:
+			res.values = append(res.values, net.IP(body.AAAA[:]).String())
+		case *dnsmessage.PTRResource:
+			res.values = append(res.values, strings.TrimSuffix(body.PTR.String(), "."))
+		case *dnsmessage.TXTResource:
+			res.values = append(res.values, strings.Join(body.TXT, ""))
+		case *dnsmessage.MXResource:
+			res.values = append(res.values, map[string]any{
+				"host":       strings.TrimSuffix(body.MX.String(), "."),
+				"preference": int64(body.Pref),
+			})
+		default:
+			continue
+		}
+		if ttl := time.Duration(a.Header.TTL) * time.Second; res.ttl < 0 || ttl < res.ttl {
+			res.ttl = ttl
+		}
+	}
+	if len(res.values) == 0 {
+		res.ttl = negativeTTL(msg)
+	}
+	return res, nil
+}
+
+// negativeTTL returns the period for which the absence of records may be
+// cached, which is given by the SOA record of the authority section.
+func negativeTTL(msg *dnsmessage.Message) time.Duration {
+	for _, a := range msg.Authorities {
+		if soa, ok := a.Body.(*dnsmessage.SOAResource); ok {
+			return time.Duration(min(a.Header.TTL, soa.MinTTL)) * time.Second
+		}
+	}
+	return -1
+}
+
+// reverseAddr returns the name used for reverse lookups of an IP address.
+func reverseAddr(addr string) (string, error) {
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return "", fmt.Errorf("invalid IP address: %q", addr)
+	}
+
+	var b strings.Builder
+	if ip4 := ip.To4(); ip4 != nil {
+		for i := len(ip4) - 1; i >= 0; i-- {
+			b.WriteString(strconv.Itoa(int(ip4[i])))
+			b.WriteByte('.')
+		}
+		b.WriteString("in-addr.arpa.")
+		return b.String(), nil
+	}
+
+	const hexDigits = "0123456789abcdef"
+	for i := len(ip) - 1; i >= 0; i-- {
+		b.WriteByte(hexDigits[ip[i]&0xf])
+		b.WriteByte('.')
+		b.WriteByte(hexDigits[ip[i]>>4])
+		b.WriteByte('.')
+	}
+	b.WriteString("ip6.arpa.")
+	return b.String(), nil
+}
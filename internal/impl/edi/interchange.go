@@ -0,0 +1,420 @@
+package edi
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// The acknowledgment statuses of interchanges, groups and transaction sets.
+const (
+	statusAccepted           = "accepted"
+	statusAcceptedWithErrors = "accepted_with_errors"
+	statusPartiallyAccepted  = "partially_accepted"
+	statusRejected           = "rejected"
+)
+
+// schema describes a transaction set, providing names for the elements of
+// segments and the segments that are mandatory.
+type schema struct {
+	segments map[string][]string
+	required []string
+}
+
+type transaction struct {
+	txType        string
+	controlNumber string
+	header        segment
+	trailer       *segment
+	segments      []segment
+
+	errors   []string
+	rejected bool
+}
+
+type group struct {
+	id            string
+	controlNumber string
+	header        *segment
+	trailer       *segment
+	transactions  []*transaction
+
+	errors   []string
+	rejected bool
+}
+
+type interchange struct {
+	dialect       dialect
+	seps          separators
+	header        segment
+	trailer       *segment
+	groups        []*group
+	controlNumber string
+	sender        string
+	receiver      string
+
+	errors []string
+}
+
+// parseInterchange parses an X12 or EDIFACT interchange, where standard is
+// either x12, edifact or auto. Violations of the envelope structure and
+// control totals are recorded against the interchange, group or transaction
+// set in which they occur so that acknowledgments can be generated, whereas
+// data that isn't recognisable as an interchange results in an error.
+func parseInterchange(data []byte, standard string, overrides separators, schemas map[string]*schema) (*interchange, error) {
+	data = bytes.TrimLeft(data, " \t\r\n")
+	if standard == "auto" {
+		switch {
+		case bytes.HasPrefix(data, []byte("ISA")):
+			standard = "x12"
+		case bytes.HasPrefix(data, []byte("UNA")), bytes.HasPrefix(data, []byte("UNB")):
+			standard = "edifact"
+		default:
+			return nil, errors.New("unable to detect standard, interchanges must begin with an ISA, UNA or UNB segment")
+		}
+	}
+
+	ic := &interchange{}
+	var err error
+	switch standard {
+	case "x12":
+		ic.dialect = x12Dialect
+		ic.seps, err = detectX12Separators(data)
+	case "edifact":
+		ic.dialect = edifactDialect
+		ic.seps, data, err = detectEDIFACTSeparators(data)
+	default:
+		err = fmt.Errorf("unrecognised standard: %v", standard)
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, o := range []struct {
+		override byte
+		target   *byte
+	}{
+		{overrides.segment, &ic.seps.segment},
+		{overrides.element, &ic.seps.element},
+		{overrides.component, &ic.seps.component},
+		{overrides.repetition, &ic.seps.repetition},
+		{overrides.release, &ic.seps.release},
+	} {
+		if o.override != 0 {
+			*o.target = o.override
+		}
+	}
+
+	segments := tokenise(data, ic.seps)
+	if len(segments) == 0 || segments[0].id != ic.dialect.interchangeHeader {
+		return nil, fmt.Errorf("interchange must begin with the %v segment", ic.dialect.interchangeHeader)
+	}
+
+	d, s := ic.dialect, ic.seps
+	ic.header = segments[0]
+	ic.controlNumber = ic.header.value(s, d.interchangeControl, 0)
+	ic.sender = ic.header.value(s, d.sender, 1)
+	ic.receiver = ic.header.value(s, d.receiver, 1)
+	if d.name == "x12" {
+		// The ISA segment isn't split into components.
+		ic.sender = ic.header.value(s, d.sender, 0)
+		ic.receiver = ic.header.value(s, d.receiver, 0)
+	}
+
+	var currentGroup *group
+	var currentTx *transaction
+	implicitGroups := false
+
+	closeTx := func() {
+		if currentTx != nil && currentTx.trailer == nil {
+			currentTx.rejected = true
+			currentTx.errors = append(currentTx.errors, fmt.Sprintf("missing %v trailer", d.transactionTrailer))
+		}
+		currentTx = nil
+	}
+	closeGroup := func() {
+		closeTx()
+		if currentGroup != nil && currentGroup.header != nil && currentGroup.trailer == nil {
+			currentGroup.rejected = true
+			currentGroup.errors = append(currentGroup.errors, fmt.Sprintf("missing %v trailer", d.groupTrailer))
+		}
+		currentGroup = nil
+	}
+
+	for i := 1; i < len(segments); i++ {
+		seg := segments[i]
+		if ic.trailer != nil {
+			ic.errors = append(ic.errors, fmt.Sprintf("segment %v (%v) follows the %v trailer", i+1, seg.id, d.interchangeTrailer))
+			continue
+		}
+
+		switch seg.id {
+		case d.groupHeader:
+			closeGroup()
+			header := seg
+			currentGroup = &group{
+				id:            seg.value(s, d.groupID, 0),
+				controlNumber: seg.value(s, d.groupControl, 0),
+				header:        &header,
+			}
+			ic.groups = append(ic.groups, currentGroup)
+
+		case d.groupTrailer:
+			closeTx()
+			if currentGroup == nil || currentGroup.header == nil {
+				ic.errors = append(ic.errors, fmt.Sprintf("segment %v (%v) without a %v header", i+1, seg.id, d.groupHeader))
+				continue
+			}
+			trailer := seg
+			currentGroup.trailer = &trailer
+			checkTrailer(&currentGroup.errors, &currentGroup.rejected, seg, s, len(currentGroup.transactions), currentGroup.controlNumber, "transaction set")
+			currentGroup = nil
+
+		case d.transactionHeader:
+			closeTx()
+			if currentGroup == nil {
+				// EDIFACT messages aren't required to be within groups,
+				// whereas X12 transaction sets are.
+				if d.name == "x12" {
+					ic.errors = append(ic.errors, fmt.Sprintf("segment %v (%v) outside of a functional group", i+1, seg.id))
+				}
+				currentGroup = &group{}
+				ic.groups = append(ic.groups, currentGroup)
+				implicitGroups = true
+			}
+			currentTx = &transaction{
+				txType:        seg.value(s, d.transactionType, 1),
+				controlNumber: seg.value(s, d.transactionControl, 0),
+				header:        seg,
+			}
+			currentGroup.transactions = append(currentGroup.transactions, currentTx)
+
+		case d.transactionTrailer:
+			if currentTx == nil {
+				ic.errors = append(ic.errors, fmt.Sprintf("segment %v (%v) without a %v header", i+1, seg.id, d.transactionHeader))
+				continue
+			}
+			trailer := seg
+			currentTx.trailer = &trailer
+			// Segment counts include the header and trailer.
+			checkTrailer(&currentTx.errors, &currentTx.rejected, seg, s, len(currentTx.segments)+2, currentTx.controlNumber, "segment")
+			validateSchema(currentTx, schemas[currentTx.txType])
+			currentTx = nil
+
+		case d.interchangeTrailer:
+			closeGroup()
+			trailer := seg
+			ic.trailer = &trailer
+
+			// When groups aren't used the count is of messages instead.
+			count, counted := len(ic.groups), "group"
+			if implicitGroups && d.name == "edifact" {
+				count, counted = 0, "message"
+				for _, g := range ic.groups {
+					count += len(g.transactions)
+				}
+			}
+			var rejected bool
+			checkTrailer(&ic.errors, &rejected, seg, s, count, ic.controlNumber, counted)
+
+		default:
+			if currentTx == nil {
+				ic.errors = append(ic.errors, fmt.Sprintf("segment %v (%v) outside of a transaction set", i+1, seg.id))
+				continue
+			}
+			currentTx.segments = append(currentTx.segments, seg)
+		}
+	}
+	closeGroup()
+	if ic.trailer == nil {
+		ic.errors = append(ic.errors, fmt.Sprintf("missing %v trailer", d.interchangeTrailer))
+	}
+	return ic, nil
+}
+
+func checkTrailer(errs *[]string, rejected *bool, trailer segment, s separators, count int, controlNumber, counted string) {
+	if n, err := strconv.Atoi(trailer.value(s, 1, 0)); err != nil || n != count {
+		*rejected = true
+		*errs = append(*errs, fmt.Sprintf("%v count mismatch: trailer declares %q, found %v", counted, trailer.value(s, 1, 0), count))
+	}
+	if c := trailer.value(s, 2, 0); c != controlNumber {
+		*rejected = true
+		*errs = append(*errs, fmt.Sprintf("control number mismatch: trailer declares %q, header declares %q", c, controlNumber))
+	}
+}
+
+func validateSchema(tx *transaction, sch *schema) {
+	if sch == nil {
+		return
+	}
+	present := map[string]bool{}
+	for _, seg := range tx.segments {
+		present[seg.id] = true
+		if _, known := sch.segments[seg.id]; !known && len(sch.segments) > 0 {
+			tx.errors = append(tx.errors, fmt.Sprintf("unrecognised segment %v", seg.id))
+		}
+	}
+	for _, id := range sch.required {
+		if !present[id] {
+			tx.rejected = true
+			tx.errors = append(tx.errors, fmt.Sprintf("missing mandatory segment %v", id))
+		}
+	}
+}
+
+//------------------------------------------------------------------------------
+
+func (tx *transaction) status() string {
+	switch {
+	case tx.rejected:
+		return statusRejected
+	case len(tx.errors) > 0:
+		return statusAcceptedWithErrors
+	}
+	return statusAccepted
+}
+
+func (g *group) status() string {
+	if g.rejected {
+		return statusRejected
+	}
+	return aggregateStatus(len(g.errors) > 0, len(g.transactions), func(i int) string {
+		return g.transactions[i].status()
+	})
+}
+
+func (ic *interchange) status() string {
+	if len(ic.errors) > 0 {
+		return statusRejected
+	}
+	return aggregateStatus(false, len(ic.groups), func(i int) string {
+		return ic.groups[i].status()
+	})
+}
+
+func aggregateStatus(hasErrors bool, n int, childStatus func(i int) string) string {
+	counts := map[string]int{}
+	for i := 0; i < n; i++ {
+		counts[childStatus(i)]++
+	}
+	switch {
+	case n > 0 && counts[statusRejected] == n:
+		return statusRejected
+	case counts[statusRejected] > 0 || counts[statusPartiallyAccepted] > 0:
+		return statusPartiallyAccepted
+	case hasErrors || counts[statusAcceptedWithErrors] > 0:
+		return statusAcceptedWithErrors
+	}
+	return statusAccepted
+}
+
+func stringsToAny(strs []string) []any {
+	res := make([]any, len(strs))
+	for i, s := range strs {
+		res[i] = s
+	}
+	return res
+}
+
+// acknowledgment summarises the outcome of validating an interchange in a
+// structure resembling the functional acknowledgments of each standard (X12
+// 997/999 and EDIFACT CONTRL).
+func (ic *interchange) acknowledgment() map[string]any {
+	groups := make([]any, 0, len(ic.groups))
+	for _, g := range ic.groups {
+		txs := make([]any, 0, len(g.transactions))
+		accepted := 0
+		for _, tx := range g.transactions {
+			status := tx.status()
+			if status != statusRejected {
+				accepted++
+			}
+			txs = append(txs, map[string]any{
+				"type":           tx.txType,
+				"control_number": tx.controlNumber,
+				"status":         status,
+				"errors":         stringsToAny(tx.errors),
+			})
+		}
+		groups = append(groups, map[string]any{
+			"functional_id":     g.id,
+			"control_number":    g.controlNumber,
+			"status":            g.status(),
+			"transaction_count": int64(len(g.transactions)),
+			"accepted_count":    int64(accepted),
+			"errors":            stringsToAny(g.errors),
+			"transactions":      txs,
+		})
+	}
+	return map[string]any{
+		"standard":       ic.dialect.name,
+		"control_number": ic.controlNumber,
+		"sender":         ic.sender,
+		"receiver":       ic.receiver,
+		"status":         ic.status(),
+		"errors":         stringsToAny(ic.errors),
+		"groups":         groups,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// headerObj returns the interchange header, where the elements of the X12 ISA
+// segment aren't split as it declares the separators themselves.
+func (ic *interchange) headerObj() map[string]any {
+	return ic.header.structured(ic.seps, nil, ic.dialect.name == "x12")
+}
+
+func (ic *interchange) transactionObj(tx *transaction, schemas map[string]*schema) map[string]any {
+	var names map[string][]string
+	if sch, exists := schemas[tx.txType]; exists {
+		names = sch.segments
+	}
+	segments := make([]any, 0, len(tx.segments))
+	for _, seg := range tx.segments {
+		segments = append(segments, seg.structured(ic.seps, names[seg.id], false))
+	}
+	obj := map[string]any{
+		"type":           tx.txType,
+		"control_number": tx.controlNumber,
+		"header":         tx.header.structured(ic.seps, nil, false),
+		"segments":       segments,
+	}
+	if tx.trailer != nil {
+		obj["trailer"] = tx.trailer.structured(ic.seps, nil, false)
+	}
+	return obj
+}
+
+// document converts the entire interchange into a structured document.
+func (ic *interchange) document(schemas map[string]*schema) map[string]any {
+	groups := make([]any, 0, len(ic.groups))
+	for _, g := range ic.groups {
+		txs := make([]any, 0, len(g.transactions))
+		for _, tx := range g.transactions {
+			txs = append(txs, ic.transactionObj(tx, schemas))
+		}
+		gObj := map[string]any{
+			"functional_id":  g.id,
+			"control_number": g.controlNumber,
+			"transactions":   txs,
+		}
+		if g.header != nil {
+			gObj["header"] = g.header.structured(ic.seps, nil, false)
+		}
+		if g.trailer != nil {
+			gObj["trailer"] = g.trailer.structured(ic.seps, nil, false)
+		}
+		groups = append(groups, gObj)
+	}
+
+	doc := map[string]any{
+		"standard": ic.dialect.name,
+		"header":   ic.headerObj(),
+		"groups":   groups,
+	}
+	if ic.trailer != nil {
+		doc["trailer"] = ic.trailer.structured(ic.seps, nil, false)
+	}
+	return doc
+}
@@ -0,0 +1,275 @@
+package edi
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	epFieldStandard          = "standard"
+	epFieldSeparators        = "separators"
+	epFieldSepSegment        = "segment"
+	epFieldSepElement        = "element"
+	epFieldSepComponent      = "component"
+	epFieldSepRepetition     = "repetition"
+	epFieldSepRelease        = "release"
+	epFieldSchemas           = "schemas"
+	epFieldSchemaSegments    = "segments"
+	epFieldSchemaRequired    = "required_segments"
+	epFieldSplitTransactions = "split_transactions"
+)
+
+func ediProcSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Categories("Parsing").
+		Version("4.28.0").
+		Summary("Parses X12 and EDIFACT interchanges into structured documents and validates their envelopes.").
+		Description(`
+Each message is parsed as an interchange and replaced with a document containing the interchange header and trailer, and the functional groups within it. Each group contains its transaction sets (messages in EDIFACT terms), and each transaction set contains its type, control number and segments. EDIFACT messages that aren't enclosed in a UNG group are placed in a group without a header.
+
+Segments are objects with the segment identifier under the key `+"`segment`"+` and the non-empty elements of the segment keyed by the segment identifier followed by their two-digit position, e.g. `+"`BEG03`"+`. Composite elements are objects of one-based component positions to values, and repeated elements are arrays. The elements of the X12 ISA segment are never split, as it declares the separators themselves.
+
+Separators are detected from the ISA segment of X12 interchanges and the UNA segment of EDIFACT interchanges, falling back to the EDIFACT defaults, and can be overridden with the `+"`separators`"+` field.
+
+### Schemas
+
+Schemas can be provided for each transaction set type, e.g. `+"`850`"+` or `+"`ORDERS`"+`, giving names to the elements of segments and listing the segments that are mandatory. Segments of a transaction set with a schema that aren't recognised by it are reported as errors, and transaction sets that are missing mandatory segments are rejected.
+
+### Acknowledgments
+
+The control numbers and counts declared by the trailer of each transaction set, group and interchange are checked, and the result is added to each message as the metadata field `+"`edi_ack`"+`, which resembles the functional acknowledgments of each standard (X12 997/999 and EDIFACT CONTRL):
+
+`+"```json"+`
+{
+  "standard": "x12",
+  "control_number": "000000905",
+  "sender": "SENDER",
+  "receiver": "RECEIVER",
+  "status": "accepted",
+  "errors": [],
+  "groups": [
+    {
+      "functional_id": "PO",
+      "control_number": "1",
+      "status": "accepted",
+      "transaction_count": 1,
+      "accepted_count": 1,
+      "errors": [],
+      "transactions": [
+        { "type": "850", "control_number": "0001", "status": "accepted", "errors": [] }
+      ]
+    }
+  ]
+}
+`+"```"+`
+
+Statuses are one of `+"`accepted`"+`, `+"`accepted_with_errors`"+`, `+"`partially_accepted`"+` or `+"`rejected`"+`. The overall status is also added as the metadata field `+"`edi_ack_status`"+`, along with the fields `+"`edi_standard`"+`, `+"`edi_sender`"+`, `+"`edi_receiver`"+` and `+"`edi_control_number`"+`. This data can be used to generate acknowledgments with a [`+"`mapping`"+` processor](/docs/components/processors/mapping).
+
+When `+"`split_transactions`"+` is `+"`true`"+` a message is created for each transaction set instead, containing the interchange header under `+"`interchange`"+`, the group header under `+"`group`"+` and the transaction set under `+"`transaction`"+`. These messages have the additional metadata fields `+"`edi_group_control_number`"+`, `+"`edi_transaction_type`"+`, `+"`edi_transaction_control_number`"+` and `+"`edi_transaction_status`"+`.
+
+Data that cannot be parsed as an interchange is flagged as having failed, allowing you to use [standard processor error handling patterns](/docs/configuration/error_handling).`).
+		Fields(
+			service.NewStringEnumField(epFieldStandard, "auto", "x12", "edifact").
+				Description("The standard of interchanges, where `auto` detects the standard from the first segment.").
+				Default("auto"),
+			service.NewObjectField(epFieldSeparators,
+				service.NewStringField(epFieldSepSegment).
+					Description("The segment terminator.").
+					Optional(),
+				service.NewStringField(epFieldSepElement).
+					Description("The element separator.").
+					Optional(),
+				service.NewStringField(epFieldSepComponent).
+					Description("The component (sub-element) separator.").
+					Optional(),
+				service.NewStringField(epFieldSepRepetition).
+					Description("The repetition separator.").
+					Optional(),
+				service.NewStringField(epFieldSepRelease).
+					Description("The release (escape) character, which is only used by EDIFACT.").
+					Optional(),
+			).
+				Description("Single character separators that override those detected from interchanges.").
+				Advanced(),
+			service.NewObjectMapField(epFieldSchemas,
+				service.NewAnyMapField(epFieldSchemaSegments).
+					Description("A map of segment identifiers to the names of their elements in order. Empty names are keyed by position.").
+					Default(map[string]any{}),
+				service.NewStringListField(epFieldSchemaRequired).
+					Description("Segments that must be present within transaction sets of this type.").
+					Default([]any{}),
+			).
+				Description("Optional schemas keyed by transaction set type.").
+				Default(map[string]any{}).
+				Example(map[string]any{
+					"850": map[string]any{
+						"segments": map[string]any{
+							"BEG": []any{"purpose_code", "type_code", "po_number", "release_number", "date"},
+							"PO1": []any{"line_number", "quantity", "unit", "unit_price"},
+						},
+						"required_segments": []any{"BEG", "PO1"},
+					},
+				}),
+			service.NewBoolField(epFieldSplitTransactions).
+				Description("Whether to create a message for each transaction set rather than a single message for the interchange.").
+				Default(false),
+		).
+		Example("Purchase Orders", "Here we split X12 interchanges into a message per purchase order and route those that are rejected to a separate topic.", `
+pipeline:
+  processors:
+    - edi:
+        split_transactions: true
+        schemas:
+          "850":
+            segments:
+              BEG: [ purpose_code, type_code, po_number, "", date ]
+            required_segments: [ BEG ]
+
+output:
+  switch:
+    cases:
+      - check: '@edi_transaction_status == "rejected"'
+        output:
+          kafka_franz:
+            seed_brokers: [ localhost:9092 ]
+            topic: edi_rejected
+      - output:
+          kafka_franz:
+            seed_brokers: [ localhost:9092 ]
+            topic: purchase_orders
+`)
+}
+
+func init() {
+	err := service.RegisterProcessor(
+		"edi", ediProcSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Processor, error) {
+			return newEDIProcFromConfig(conf)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type ediProc struct {
+	standard          string
+	overrides         separators
+	schemas           map[string]*schema
+	splitTransactions bool
+}
+
+func newEDIProcFromConfig(conf *service.ParsedConfig) (*ediProc, error) {
+	p := &ediProc{schemas: map[string]*schema{}}
+
+	var err error
+	if p.standard, err = conf.FieldString(epFieldStandard); err != nil {
+		return nil, err
+	}
+
+	sepConf := conf.Namespace(epFieldSeparators)
+	for _, s := range []struct {
+		field  string
+		target *byte
+	}{
+		{epFieldSepSegment, &p.overrides.segment},
+		{epFieldSepElement, &p.overrides.element},
+		{epFieldSepComponent, &p.overrides.component},
+		{epFieldSepRepetition, &p.overrides.repetition},
+		{epFieldSepRelease, &p.overrides.release},
+	} {
+		if !sepConf.Contains(s.field) {
+			continue
+		}
+		v, err := sepConf.FieldString(s.field)
+		if err != nil {
+			return nil, err
+		}
+		if len(v) != 1 {
+			return nil, fmt.Errorf("separator %v must be a single character, got %q", s.field, v)
+		}
+		*s.target = v[0]
+	}
+
+	schemaConfs, err := conf.FieldObjectMap(epFieldSchemas)
+	if err != nil {
+		return nil, err
+	}
+	for txType, sConf := range schemaConfs {
+		sch := &schema{segments: map[string][]string{}}
+		segConfs, err := sConf.FieldAnyMap(epFieldSchemaSegments)
+		if err != nil {
+			return nil, err
+		}
+		for id, segConf := range segConfs {
+			if sch.segments[id], err = segConf.FieldStringList(); err != nil {
+				return nil, fmt.Errorf("schema %v segment %v: %w", txType, id, err)
+			}
+		}
+		if sch.required, err = sConf.FieldStringList(epFieldSchemaRequired); err != nil {
+			return nil, err
+		}
+		p.schemas[txType] = sch
+	}
+
+	if p.splitTransactions, err = conf.FieldBool(epFieldSplitTransactions); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (p *ediProc) Process(ctx context.Context, msg *service.Message) (service.MessageBatch, error) {
+	raw, err := msg.AsBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	ic, err := parseInterchange(raw, p.standard, p.overrides, p.schemas)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse interchange: %w", err)
+	}
+
+	status := ic.status()
+	setMeta := func(m *service.Message) {
+		m.MetaSetMut("edi_standard", ic.dialect.name)
+		m.MetaSetMut("edi_sender", ic.sender)
+		m.MetaSetMut("edi_receiver", ic.receiver)
+		m.MetaSetMut("edi_control_number", ic.controlNumber)
+		m.MetaSetMut("edi_ack_status", status)
+		m.MetaSetMut("edi_ack", ic.acknowledgment())
+	}
+
+	if !p.splitTransactions {
+		msg.SetStructuredMut(ic.document(p.schemas))
+		setMeta(msg)
+		return service.MessageBatch{msg}, nil
+	}
+
+	var batch service.MessageBatch
+	for _, g := range ic.groups {
+		var groupHeader any
+		if g.header != nil {
+			groupHeader = g.header.structured(ic.seps, nil, false)
+		}
+		for _, tx := range g.transactions {
+			txMsg := msg.Copy()
+			txMsg.SetStructuredMut(map[string]any{
+				"interchange": ic.headerObj(),
+				"group":       groupHeader,
+				"transaction": ic.transactionObj(tx, p.schemas),
+			})
+			setMeta(txMsg)
+			txMsg.MetaSetMut("edi_group_control_number", g.controlNumber)
+			txMsg.MetaSetMut("edi_transaction_type", tx.txType)
+			txMsg.MetaSetMut("edi_transaction_control_number", tx.controlNumber)
+			txMsg.MetaSetMut("edi_transaction_status", tx.status())
+			batch = append(batch, txMsg)
+		}
+	}
+	return batch, nil
+}
+
+func (p *ediProc) Close(ctx context.Context) error {
+	return nil
+}
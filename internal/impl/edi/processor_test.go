@@ -0,0 +1,177 @@
+package edi
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const testX12Interchange = "ISA*00*          *00*          *ZZ*SENDER         *ZZ*RECEIVER       *240102*1200*^*00501*000000905*0*P*>~\n" +
+	"GS*PO*SENDER*RECEIVER*20240102*1200*1*X*005010~\n" +
+	"ST*850*0001~\n" +
+	"BEG*00*SA*PO123**20240102~\n" +
+	"PO1*1*10*EA*9.99**VP*SKU>1^SKU>2~\n" +
+	"SE*4*0001~\n" +
+	"ST*850*0002~\n" +
+	"PO1*1*10*EA~\n" +
+	"SE*5*0002~\n" +
+	"GE*2*1~\n" +
+	"IEA*1*000000905~\n"
+
+const testEDIFACTInterchange = "UNA:+.? '" +
+	"UNB+UNOC:3+SENDER:14+RECEIVER:14+240102:1200+REF1'" +
+	"UNH+1+ORDERS:D:96A:UN'" +
+	"BGM+220+PO?+123+9'" +
+	"DTM+137:20240102:102'" +
+	"UNT+4+1'" +
+	"UNZ+1+REF1'"
+
+func testEDIProc(t *testing.T, confStr string) *ediProc {
+	t.Helper()
+
+	conf, err := ediProcSpec().ParseYAML(confStr, nil)
+	require.NoError(t, err)
+
+	proc, err := newEDIProcFromConfig(conf)
+	require.NoError(t, err)
+	return proc
+}
+
+func TestEDIX12(t *testing.T) {
+	proc := testEDIProc(t, `
+schemas:
+  "850":
+    segments:
+      BEG: [ purpose, type, po_number, "", date ]
+      PO1: []
+    required_segments: [ BEG ]
+`)
+
+	res, err := proc.Process(context.Background(), service.NewMessage([]byte(testX12Interchange)))
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+
+	v, err := res[0].AsStructured()
+	require.NoError(t, err)
+
+	doc := v.(map[string]any)
+	assert.Equal(t, "x12", doc["standard"])
+	assert.Equal(t, "SENDER         ", doc["header"].(map[string]any)["ISA06"])
+
+	groups := doc["groups"].([]any)
+	require.Len(t, groups, 1)
+	txs := groups[0].(map[string]any)["transactions"].([]any)
+	require.Len(t, txs, 2)
+
+	tx := txs[0].(map[string]any)
+	assert.Equal(t, "850", tx["type"])
+	assert.Equal(t, []any{
+		map[string]any{"segment": "BEG", "purpose": "00", "type": "SA", "po_number": "PO123", "date": "20240102"},
+		map[string]any{
+			"segment": "PO1", "PO101": "1", "PO102": "10", "PO103": "EA", "PO104": "9.99", "PO106": "VP",
+			"PO107": []any{
+				map[string]any{"1": "SKU", "2": "1"},
+				map[string]any{"1": "SKU", "2": "2"},
+			},
+		},
+	}, tx["segments"])
+
+	for k, exp := range map[string]any{
+		"edi_standard":       "x12",
+		"edi_sender":         "SENDER",
+		"edi_receiver":       "RECEIVER",
+		"edi_control_number": "000000905",
+		"edi_ack_status":     "partially_accepted",
+	} {
+		act, _ := res[0].MetaGetMut(k)
+		assert.Equal(t, exp, act, k)
+	}
+
+	ack, _ := res[0].MetaGetMut("edi_ack")
+	ackGroup := ack.(map[string]any)["groups"].([]any)[0].(map[string]any)
+	assert.Equal(t, "partially_accepted", ackGroup["status"])
+	assert.Equal(t, int64(1), ackGroup["accepted_count"])
+	assert.Equal(t, map[string]any{
+		"type":           "850",
+		"control_number": "0002",
+		"status":         "rejected",
+		"errors": []any{
+			`segment count mismatch: trailer declares "5", found 3`,
+			"missing mandatory segment BEG",
+		},
+	}, ackGroup["transactions"].([]any)[1])
+}
+
+func TestEDIFACT(t *testing.T) {
+	proc := testEDIProc(t, `
+standard: edifact
+split_transactions: true
+`)
+
+	res, err := proc.Process(context.Background(), service.NewMessage([]byte(testEDIFACTInterchange)))
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+
+	v, err := res[0].AsStructured()
+	require.NoError(t, err)
+
+	doc := v.(map[string]any)
+	assert.Equal(t, map[string]any{"1": "SENDER", "2": "14"}, doc["interchange"].(map[string]any)["UNB02"])
+	assert.Nil(t, doc["group"])
+	assert.Equal(t, []any{
+		map[string]any{"segment": "BGM", "BGM01": "220", "BGM02": "PO+123", "BGM03": "9"},
+		map[string]any{"segment": "DTM", "DTM01": map[string]any{"1": "137", "2": "20240102", "3": "102"}},
+	}, doc["transaction"].(map[string]any)["segments"])
+
+	for k, exp := range map[string]any{
+		"edi_standard":                   "edifact",
+		"edi_sender":                     "SENDER",
+		"edi_control_number":             "REF1",
+		"edi_ack_status":                 "accepted",
+		"edi_transaction_type":           "ORDERS",
+		"edi_transaction_control_number": "1",
+		"edi_transaction_status":         "accepted",
+	} {
+		act, _ := res[0].MetaGetMut(k)
+		assert.Equal(t, exp, act, k)
+	}
+}
+
+func TestEDIEnvelopeErrors(t *testing.T) {
+	proc := testEDIProc(t, `separators: { segment: "\n" }`)
+
+	res, err := proc.Process(context.Background(), service.NewMessage([]byte("UNB+UNOC:3+A+B+240102:1200+REF1\n"+
+		"FOO+1\n"+
+		"UNH+1+ORDERS:D:96A:UN\n"+
+		"BGM+220\n"+
+		"UNZ+2+REF2\n")))
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+
+	status, _ := res[0].MetaGetMut("edi_ack_status")
+	assert.Equal(t, "rejected", status)
+
+	ack, _ := res[0].MetaGetMut("edi_ack")
+	assert.Equal(t, []any{
+		"segment 2 (FOO) outside of a transaction set",
+		`message count mismatch: trailer declares "2", found 1`,
+		`control number mismatch: trailer declares "REF2", header declares "REF1"`,
+	}, ack.(map[string]any)["errors"])
+	tx := ack.(map[string]any)["groups"].([]any)[0].(map[string]any)["transactions"].([]any)[0].(map[string]any)
+	assert.Equal(t, []any{"missing UNT trailer"}, tx["errors"])
+
+	_, err = proc.Process(context.Background(), service.NewMessage([]byte("not an interchange")))
+	require.Error(t, err)
+}
+
+func TestEDIConfigErrors(t *testing.T) {
+	conf, err := ediProcSpec().ParseYAML(`separators: { element: "++" }`, nil)
+	require.NoError(t, err)
+
+	_, err = newEDIProcFromConfig(conf)
+	require.Error(t, err)
+}
@@ -0,0 +1,262 @@
+package edi
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// separators are the delimiters of an interchange, where a zero value
+// indicates that a delimiter isn't used.
+type separators struct {
+	segment    byte
+	element    byte
+	component  byte
+	repetition byte
+	release    byte
+}
+
+// dialect describes the envelope segments of a standard. The trailer of each
+// envelope contains the number of enclosed items as its first element and the
+// control number of the envelope as its second.
+type dialect struct {
+	name string
+
+	interchangeHeader, interchangeTrailer string
+	groupHeader, groupTrailer             string
+	transactionHeader, transactionTrailer string
+
+	// Element positions within the header segments.
+	interchangeControl int
+	sender, receiver   int
+	groupID            int
+	groupControl       int
+	transactionType    int
+	transactionControl int
+}
+
+var (
+	x12Dialect = dialect{
+		name:               "x12",
+		interchangeHeader:  "ISA",
+		interchangeTrailer: "IEA",
+		groupHeader:        "GS",
+		groupTrailer:       "GE",
+		transactionHeader:  "ST",
+		transactionTrailer: "SE",
+		interchangeControl: 13,
+		sender:             6,
+		receiver:           8,
+		groupID:            1,
+		groupControl:       6,
+		transactionType:    1,
+		transactionControl: 2,
+	}
+	edifactDialect = dialect{
+		name:               "edifact",
+		interchangeHeader:  "UNB",
+		interchangeTrailer: "UNZ",
+		groupHeader:        "UNG",
+		groupTrailer:       "UNE",
+		transactionHeader:  "UNH",
+		transactionTrailer: "UNT",
+		interchangeControl: 5,
+		sender:             2,
+		receiver:           3,
+		groupID:            1,
+		groupControl:       5,
+		transactionType:    2,
+		transactionControl: 1,
+	}
+)
+
+// detectX12Separators reads the separators of an X12 interchange from its
+// ISA segment, which has a fixed number of elements followed by the component
+// separator and the segment terminator.
+func detectX12Separators(data []byte) (separators, error) {
+	if len(data) < 4 || !bytes.HasPrefix(data, []byte("ISA")) {
+		return separators{}, errors.New("interchange must begin with the ISA segment")
+	}
+	s := separators{element: data[3]}
+
+	idx, count := 3, 1
+	for count < 16 {
+		i := bytes.IndexByte(data[idx+1:], s.element)
+		if i == -1 {
+			return separators{}, errors.New("ISA segment is truncated")
+		}
+		idx += i + 1
+		count++
+	}
+	if len(data) < idx+3 {
+		return separators{}, errors.New("ISA segment is truncated")
+	}
+	s.component, s.segment = data[idx+1], data[idx+2]
+
+	// Versions 00402 onwards declare a repetition separator in ISA11, which
+	// was previously a standards identifier (usually U).
+	isa := strings.Split(string(data[:idx]), string(s.element))
+	if rep := isa[11]; len(rep) == 1 && !isAlphanumeric(rep[0]) {
+		s.repetition = rep[0]
+	}
+	return s, nil
+}
+
+func isAlphanumeric(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
+
+// detectEDIFACTSeparators reads the separators of an EDIFACT interchange from
+// its UNA service string advice, or returns the default separators when it is
+// absent. Repetition separators are only used when declared by a UNA segment.
+// The remaining data following the UNA segment is returned.
+func detectEDIFACTSeparators(data []byte) (separators, []byte, error) {
+	s := separators{segment: '\'', element: '+', component: ':', release: '?'}
+	if !bytes.HasPrefix(data, []byte("UNA")) {
+		if !bytes.HasPrefix(data, []byte("UNB")) {
+			return separators{}, nil, errors.New("interchange must begin with the UNA or UNB segment")
+		}
+		return s, data, nil
+	}
+	if len(data) < 9 {
+		return separators{}, nil, errors.New("UNA segment is truncated")
+	}
+	s.component, s.element, s.release, s.repetition, s.segment = data[3], data[4], data[6], data[7], data[8]
+	if s.release == ' ' {
+		s.release = 0
+	}
+	if s.repetition == ' ' {
+		s.repetition = 0
+	}
+	return s, data[9:], nil
+}
+
+// splitRaw splits a string by a separator, ignoring separators that are
+// preceded by the release character. Release characters are retained.
+func splitRaw(s string, sep, release byte) []string {
+	if sep == 0 {
+		return []string{s}
+	}
+	if release == 0 {
+		return strings.Split(s, string(sep))
+	}
+
+	var parts []string
+	start := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case release:
+			i++
+		case sep:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+func unescape(s string, release byte) string {
+	if release == 0 || strings.IndexByte(s, release) == -1 {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == release && i+1 < len(s) {
+			i++
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// segment is a tokenised segment where elements retain any release
+// characters, the first element is the segment identifier.
+type segment struct {
+	id       string
+	elements []string
+}
+
+func tokenise(data []byte, s separators) []segment {
+	var segments []segment
+	for _, raw := range splitRaw(string(data), s.segment, s.release) {
+		raw = strings.Trim(raw, "\r\n")
+		if raw == "" {
+			continue
+		}
+		elements := splitRaw(raw, s.element, s.release)
+		segments = append(segments, segment{
+			id:       unescape(elements[0], s.release),
+			elements: elements,
+		})
+	}
+	return segments
+}
+
+// value returns the unescaped value of a one-based element position, or of a
+// component of that element when component is greater than zero.
+func (g segment) value(s separators, element, component int) string {
+	if element >= len(g.elements) {
+		return ""
+	}
+	v := g.elements[element]
+	if component > 0 {
+		comps := splitRaw(v, s.component, s.release)
+		if component > len(comps) {
+			return ""
+		}
+		v = comps[component-1]
+	}
+	return strings.TrimSpace(unescape(v, s.release))
+}
+
+// structured converts a segment into an object of element keys to values,
+// where composite elements are objects of one-based component positions and
+// repeated elements are arrays. Elements are keyed by the names provided, and
+// otherwise by the segment identifier followed by their two-digit position.
+// When raw is true elements are neither split nor trimmed, which is necessary
+// for the X12 ISA segment.
+func (g segment) structured(s separators, names []string, raw bool) map[string]any {
+	obj := map[string]any{"segment": g.id}
+	for i := 1; i < len(g.elements); i++ {
+		e := g.elements[i]
+		if e == "" {
+			continue
+		}
+		key := fmt.Sprintf("%v%02d", g.id, i)
+		if i-1 < len(names) && names[i-1] != "" {
+			key = names[i-1]
+		}
+		if raw {
+			obj[key] = e
+			continue
+		}
+
+		reps := splitRaw(e, s.repetition, s.release)
+		values := make([]any, 0, len(reps))
+		for _, r := range reps {
+			values = append(values, compositeValue(r, s))
+		}
+		if len(values) == 1 {
+			obj[key] = values[0]
+		} else {
+			obj[key] = values
+		}
+	}
+	return obj
+}
+
+func compositeValue(e string, s separators) any {
+	comps := splitRaw(e, s.component, s.release)
+	if len(comps) == 1 {
+		return unescape(e, s.release)
+	}
+	obj := map[string]any{}
+	for i, c := range comps {
+		if c != "" {
+			obj[strconv.Itoa(i+1)] = unescape(c, s.release)
+		}
+	}
+	return obj
+}
@@ -3,6 +3,7 @@ package elasticsearch
 import (
 	"context"
 	"crypto/tls"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
@@ -24,6 +25,10 @@ const (
 	esoFieldID              = "id"
 	esoFieldAction          = "action"
 	esoFieldIndex           = "index"
+	esoFieldDataStream      = "data_stream"
+	esoFieldTemplate        = "index_template"
+	esoFieldTemplateName    = "name"
+	esoFieldTemplateBody    = "body"
 	esoFieldPipeline        = "pipeline"
 	esoFieldRouting         = "routing"
 	esoFieldType            = "type"
@@ -45,6 +50,10 @@ type esoConfig struct {
 	clientOpts  []elastic.ClientOptionFunc
 	backoffCtor func() backoff.BackOff
 
+	dataStream   bool
+	templateName string
+	templateBody string
+
 	actionStr   *service.InterpolatedString
 	idStr       *service.InterpolatedString
 	indexStr    *service.InterpolatedString
@@ -151,6 +160,27 @@ func esoConfigFromParsed(pConf *service.ParsedConfig) (conf esoConfig, err error
 	if conf.typeStr, err = pConf.FieldInterpolatedString(esoFieldType); err != nil {
 		return
 	}
+
+	if conf.dataStream, err = pConf.FieldBool(esoFieldDataStream); err != nil {
+		return
+	}
+
+	if pConf.Contains(esoFieldTemplate) {
+		tConf := pConf.Namespace(esoFieldTemplate)
+		if conf.templateName, err = tConf.FieldString(esoFieldTemplateName); err != nil {
+			return
+		}
+		var body any
+		if body, err = tConf.FieldAny(esoFieldTemplateBody); err != nil {
+			return
+		}
+		var bodyBytes []byte
+		if bodyBytes, err = json.Marshal(body); err != nil {
+			err = fmt.Errorf("failed to marshal index template body: %w", err)
+			return
+		}
+		conf.templateBody = string(bodyBytes)
+	}
 	return
 }
 
@@ -188,7 +218,19 @@ func OutputSpec() *service.ConfigSpec {
 		Categories("Services").
 		Summary(`Publishes messages into an Elasticsearch index. If the index does not exist then it is created with a dynamic mapping.`).
 		Description(`
-Both the `+"`id` and `index`"+` fields can be dynamically set using function interpolations described [here](/docs/configuration/interpolation#bloblang-queries). When sending batched messages these interpolations are performed per message part.
+Both the `+"`id` and `index`"+` fields can be dynamically set using function interpolations described [here](/docs/configuration/interpolation#bloblang-queries). When sending batched messages these interpolations are performed per message part. The same applies to the `+"`pipeline`"+` field, which allows each document to be routed through a different ingest pipeline.
+
+### Data Streams
+
+When `+"`data_stream`"+` is set to `+"`true`"+` the `+"`index`"+` field names a data stream and every document is written with a `+"`create`"+` operation, which is the only operation data streams accept, and the `+"`action`"+` field is ignored. Documents written to a data stream must contain a `+"`@timestamp`"+` field.
+
+### Index Templates and Rollover
+
+The `+"`index_template`"+` field can be used to install a composable index template when the output connects, which is useful for ensuring that data streams or rollover aliases are created with the right mappings and lifecycle settings. When the target of this output is managed by an ILM policy rollover happens transparently, and bulk items rejected with a 429 status while the cluster is busy are retried along with server errors.
+
+### Delivery Errors
+
+Documents that are rejected by Elasticsearch with a non-retryable status (such as a mapping error) are reported individually, so that only the rejected messages of a batch are nacked and they can be routed elsewhere with a `+"[`fallback`](/docs/components/outputs/fallback)"+` or `+"[`reject_errored`](/docs/components/outputs/reject_errored)"+` output. Documents that fail with a retryable status are retried according to the `+"`backoff`"+` settings before being reported as failed.
 
 ### AWS
 
@@ -199,12 +241,16 @@ It's possible to enable AWS connectivity with this output using the `+"`aws`"+`
 				Example([]string{"http://localhost:9200"}),
 			service.NewInterpolatedStringField(esoFieldIndex).
 				Description("The index to place messages."),
+			service.NewBoolField(esoFieldDataStream).
+				Description("Whether the `index` field refers to a data stream, in which case all documents are written with a `create` operation.").
+				Version("4.28.0").
+				Default(false),
 			service.NewInterpolatedStringField(esoFieldAction).
 				Description("The action to take on the document. This field must resolve to one of the following action types: `create`, `index`, `update`, `upsert` or `delete`.").
 				Default("index").
 				Advanced(),
 			service.NewInterpolatedStringField(esoFieldPipeline).
-				Description("An optional pipeline id to preprocess incoming documents.").
+				Description("An optional pipeline id to preprocess incoming documents. This field is resolved for each document and applies to the `index` and `create` actions.").
 				Advanced().
 				Default(""),
 			service.NewInterpolatedStringField(esoFieldID).
@@ -247,6 +293,24 @@ It's possible to enable AWS connectivity with this output using the `+"`aws`"+`
 			).Description("Allows you to specify basic authentication.").
 				Advanced().
 				Optional(),
+			service.NewObjectField(esoFieldTemplate,
+				service.NewStringField(esoFieldTemplateName).
+					Description("The name of the index template."),
+				service.NewAnyField(esoFieldTemplateBody).
+					Description("The body of the composable index template, which is put when the output connects and replaces any existing template of the same name.").
+					Example(map[string]any{
+						"index_patterns": []any{"logs-benthos-*"},
+						"data_stream":    map[string]any{},
+						"template": map[string]any{
+							"settings": map[string]any{
+								"index.lifecycle.name": "logs",
+							},
+						},
+					}),
+			).Description("An optional composable index template to install when connecting, which can be used to configure the mappings, data stream and lifecycle policy of the indexes written to.").
+				Version("4.28.0").
+				Advanced().
+				Optional(),
 			service.NewBatchPolicyField(esoFieldBatching),
 			AWSField(),
 			service.NewBoolField(esoFieldGzipCompression).
@@ -305,11 +369,23 @@ func (e *Output) Connect(ctx context.Context) error {
 		return err
 	}
 
+	if e.conf.templateName != "" {
+		if _, err := client.IndexPutIndexTemplate(e.conf.templateName).
+			BodyString(e.conf.templateBody).
+			Do(ctx); err != nil {
+			client.Stop()
+			return fmt.Errorf("failed to put index template '%v': %w", e.conf.templateName, err)
+		}
+	}
+
 	e.client = client
 	return nil
 }
 
 func shouldRetry(s int) bool {
+	if s == http.StatusTooManyRequests {
+		return true
+	}
 	if s >= 500 && s <= 599 {
 		return true
 	}
@@ -331,60 +407,53 @@ func (e *Output) WriteBatch(ctx context.Context, msg service.MessageBatch) error
 		return service.ErrNotConnected
 	}
 
-	boff := e.conf.backoffCtor()
-
-	requests := make([]*pendingBulkIndex, len(msg))
-
-	for i := 0; i < len(msg); i++ {
-		jObj, ierr := msg[i].AsStructured()
-		if ierr != nil {
-			e.log.Errorf("Failed to marshal message into JSON document: %v\n", ierr)
-			return fmt.Errorf("failed to marshal message into JSON document: %w", ierr)
+	var batchErr *service.BatchError
+	failed := func(i int, err error) {
+		if batchErr == nil {
+			batchErr = service.NewBatchError(msg, err)
 		}
-
-		pbi := &pendingBulkIndex{Doc: jObj}
-		if pbi.Action, ierr = msg.TryInterpolatedString(i, e.conf.actionStr); ierr != nil {
-			return fmt.Errorf("action interpolation error: %w", ierr)
-		}
-		if pbi.Index, ierr = msg.TryInterpolatedString(i, e.conf.indexStr); ierr != nil {
-			return fmt.Errorf("index interpolation error: %w", ierr)
-		}
-		if pbi.Pipeline, ierr = msg.TryInterpolatedString(i, e.conf.pipelineStr); ierr != nil {
-			return fmt.Errorf("pipeline interpolation error: %w", ierr)
-		}
-		if pbi.Routing, ierr = msg.TryInterpolatedString(i, e.conf.routingStr); ierr != nil {
-			return fmt.Errorf("routing interpolation error: %w", ierr)
-		}
-		if pbi.Type, ierr = msg.TryInterpolatedString(i, e.conf.typeStr); ierr != nil {
-			return fmt.Errorf("type interpolation error: %w", ierr)
-		}
-		if pbi.ID, ierr = msg.TryInterpolatedString(i, e.conf.idStr); ierr != nil {
-			return fmt.Errorf("id interpolation error: %w", ierr)
-		}
-		requests[i] = pbi
+		batchErr.Failed(i, err)
 	}
 
-	b := e.client.Bulk()
-	for _, v := range requests {
-		bulkReq, err := e.buildBulkableRequest(v)
+	// Messages that cannot be converted into a bulk request are failed
+	// individually, and the remaining messages are still attempted.
+	bulkReqs := make([]elastic.BulkableRequest, len(msg))
+	pending := make([]int, 0, len(msg))
+	for i := 0; i < len(msg); i++ {
+		pbi, err := e.pendingBulkIndexFrom(msg, i)
+		if err == nil {
+			bulkReqs[i], err = e.buildBulkableRequest(pbi)
+		}
 		if err != nil {
-			return err
+			e.log.Errorf("Failed to prepare message for Elasticsearch: %v\n", err)
+			failed(i, err)
+			continue
 		}
-		b.Add(bulkReq)
+		pending = append(pending, i)
 	}
 
+	boff := e.conf.backoffCtor()
+
 	lastErrReason := "no reason given"
-	for b.NumberOfActions() != 0 {
+	for len(pending) > 0 {
+		b := e.client.Bulk()
+		for _, i := range pending {
+			b.Add(bulkReqs[i])
+		}
+
 		result, err := b.Do(ctx)
 		if err != nil {
 			return err
 		}
 		if !result.Errors {
-			return nil
+			break
 		}
 
-		var newRequests []*pendingBulkIndex
-		for i, resp := range result.Items {
+		var retries []int
+		for j, resp := range result.Items {
+			// IMPORTANT: j exactly matches the index of our pending requests
+			// and so we can map each result back to its source message.
+			i := pending[j]
 			for _, item := range resp {
 				if item.Status >= 200 && item.Status <= 299 {
 					continue
@@ -393,31 +462,31 @@ func (e *Output) WriteBatch(ctx context.Context, msg service.MessageBatch) error
 				reason := "no reason given"
 				if item.Error != nil {
 					reason = item.Error.Reason
-					lastErrReason = fmt.Sprintf("status [%v]: %v", item.Status, reason)
+					if item.Error.Type != "" {
+						reason = fmt.Sprintf("%v: %v", item.Error.Type, reason)
+					}
 				}
+				lastErrReason = fmt.Sprintf("status [%v]: %v", item.Status, reason)
 
 				e.log.Errorf("Elasticsearch message '%v' rejected with status [%v]: %v\n", item.Id, item.Status, reason)
 				if !shouldRetry(item.Status) {
-					return fmt.Errorf("failed to send message '%v': %v", item.Id, reason)
-				}
-
-				// IMPORTANT: i exactly matches the index of our source requests
-				// and when we re-run our bulk request with errored requests
-				// that must remain true.
-				sourceReq := requests[i]
-				bulkReq, err := e.buildBulkableRequest(sourceReq)
-				if err != nil {
-					return err
+					failed(i, fmt.Errorf("failed to send message '%v': %v", item.Id, reason))
+					continue
 				}
-				b.Add(bulkReq)
-				newRequests = append(newRequests, sourceReq)
+				retries = append(retries, i)
 			}
 		}
-		requests = newRequests
+		if pending = retries; len(pending) == 0 {
+			break
+		}
 
 		wait := boff.NextBackOff()
 		if wait == backoff.Stop {
-			return fmt.Errorf("retries exhausted for messages, aborting with last error reported as: %v", lastErrReason)
+			err := fmt.Errorf("retries exhausted for messages, aborting with last error reported as: %v", lastErrReason)
+			for _, i := range pending {
+				failed(i, err)
+			}
+			break
 		}
 		select {
 		case <-time.After(wait):
@@ -426,9 +495,42 @@ func (e *Output) WriteBatch(ctx context.Context, msg service.MessageBatch) error
 		}
 	}
 
+	if batchErr != nil {
+		return batchErr
+	}
 	return nil
 }
 
+func (e *Output) pendingBulkIndexFrom(msg service.MessageBatch, i int) (*pendingBulkIndex, error) {
+	jObj, err := msg[i].AsStructured()
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal message into JSON document: %w", err)
+	}
+
+	pbi := &pendingBulkIndex{Doc: jObj}
+	if e.conf.dataStream {
+		pbi.Action = "create"
+	} else if pbi.Action, err = msg.TryInterpolatedString(i, e.conf.actionStr); err != nil {
+		return nil, fmt.Errorf("action interpolation error: %w", err)
+	}
+	if pbi.Index, err = msg.TryInterpolatedString(i, e.conf.indexStr); err != nil {
+		return nil, fmt.Errorf("index interpolation error: %w", err)
+	}
+	if pbi.Pipeline, err = msg.TryInterpolatedString(i, e.conf.pipelineStr); err != nil {
+		return nil, fmt.Errorf("pipeline interpolation error: %w", err)
+	}
+	if pbi.Routing, err = msg.TryInterpolatedString(i, e.conf.routingStr); err != nil {
+		return nil, fmt.Errorf("routing interpolation error: %w", err)
+	}
+	if pbi.Type, err = msg.TryInterpolatedString(i, e.conf.typeStr); err != nil {
+		return nil, fmt.Errorf("type interpolation error: %w", err)
+	}
+	if pbi.ID, err = msg.TryInterpolatedString(i, e.conf.idStr); err != nil {
+		return nil, fmt.Errorf("id interpolation error: %w", err)
+	}
+	return pbi, nil
+}
+
 func (e *Output) Close(context.Context) error {
 	return nil
 }
@@ -0,0 +1,188 @@
+package elasticsearch_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/internal/impl/elasticsearch"
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+type fakeBulkServer struct {
+	mut       sync.Mutex
+	actions   []map[string]any
+	template  string
+	responder func(doc map[string]any) (status int, errType string)
+}
+
+func (f *fakeBulkServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mut.Lock()
+	defer f.mut.Unlock()
+
+	if strings.HasPrefix(r.URL.Path, "/_index_template/") {
+		f.template = strings.TrimPrefix(r.URL.Path, "/_index_template/")
+		_, _ = w.Write([]byte(`{"acknowledged":true}`))
+		return
+	}
+
+	if r.URL.Path != "/_bulk" {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+
+	var items []map[string]any
+	var hasErrors bool
+
+	scanner := bufio.NewScanner(r.Body)
+	for scanner.Scan() {
+		var action map[string]any
+		if err := json.Unmarshal(scanner.Bytes(), &action); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if !scanner.Scan() {
+			break
+		}
+		var doc map[string]any
+		_ = json.Unmarshal(scanner.Bytes(), &doc)
+		f.actions = append(f.actions, action)
+
+		status, errType := f.responder(doc)
+		for op := range action {
+			res := map[string]any{"status": status}
+			if errType != "" {
+				hasErrors = true
+				res["error"] = map[string]any{"type": errType, "reason": "nope"}
+			}
+			items = append(items, map[string]any{op: res})
+		}
+	}
+
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"took":   1,
+		"errors": hasErrors,
+		"items":  items,
+	})
+}
+
+func outputFromConfStr(t *testing.T, confStr string, args ...any) *elasticsearch.Output {
+	t.Helper()
+
+	pConf, err := elasticsearch.OutputSpec().ParseYAML(fmt.Sprintf(confStr, args...), nil)
+	require.NoError(t, err)
+
+	o, err := elasticsearch.OutputFromParsed(pConf, service.MockResources())
+	require.NoError(t, err)
+
+	return o
+}
+
+func TestElasticsearchPerItemErrors(t *testing.T) {
+	server := &fakeBulkServer{
+		responder: func(doc map[string]any) (int, string) {
+			if doc["bad"] == true {
+				return 400, "mapper_parsing_exception"
+			}
+			return 201, ""
+		},
+	}
+	ts := httptest.NewServer(server)
+	t.Cleanup(ts.Close)
+
+	o := outputFromConfStr(t, `
+urls: [ %v ]
+index: foo
+id: ${! json("id") }
+sniff: false
+healthcheck: false
+`, ts.URL)
+
+	ctx := context.Background()
+	require.NoError(t, o.Connect(ctx))
+
+	batch := service.MessageBatch{
+		service.NewMessage([]byte(`{"id":"a"}`)),
+		service.NewMessage([]byte(`{"id":"b","bad":true}`)),
+		service.NewMessage([]byte(`not json`)),
+		service.NewMessage([]byte(`{"id":"d"}`)),
+	}
+
+	index := batch.Index()
+	err := o.WriteBatch(ctx, batch)
+	require.Error(t, err)
+
+	var bErr *service.BatchError
+	require.True(t, errors.As(err, &bErr))
+
+	var failed []int
+	assert.Equal(t, 2, bErr.IndexedErrors())
+	bErr.WalkMessagesIndexedBy(index, func(i int, _ *service.Message, err error) bool {
+		if err != nil {
+			failed = append(failed, i)
+		}
+		return true
+	})
+	sort.Ints(failed)
+	assert.Equal(t, []int{1, 2}, failed)
+
+	server.mut.Lock()
+	assert.Len(t, server.actions, 3)
+	server.mut.Unlock()
+
+	require.NoError(t, o.Close(ctx))
+}
+
+func TestElasticsearchDataStream(t *testing.T) {
+	server := &fakeBulkServer{
+		responder: func(doc map[string]any) (int, string) {
+			return 201, ""
+		},
+	}
+	ts := httptest.NewServer(server)
+	t.Cleanup(ts.Close)
+
+	o := outputFromConfStr(t, `
+urls: [ %v ]
+index: logs-benthos-default
+action: index
+data_stream: true
+sniff: false
+healthcheck: false
+index_template:
+  name: logs-benthos
+  body:
+    index_patterns: [ logs-benthos-* ]
+    data_stream: {}
+`, ts.URL)
+
+	ctx := context.Background()
+	require.NoError(t, o.Connect(ctx))
+
+	require.NoError(t, o.WriteBatch(ctx, service.MessageBatch{
+		service.NewMessage([]byte(`{"@timestamp":"2024-01-01T00:00:00Z","message":"foo"}`)),
+		service.NewMessage([]byte(`{"@timestamp":"2024-01-01T00:00:01Z","message":"bar"}`)),
+	}))
+
+	server.mut.Lock()
+	defer server.mut.Unlock()
+
+	assert.Equal(t, "logs-benthos", server.template)
+	require.Len(t, server.actions, 2)
+	for _, a := range server.actions {
+		create, ok := a["create"].(map[string]any)
+		require.True(t, ok, a)
+		assert.Equal(t, "logs-benthos-default", create["_index"])
+	}
+}
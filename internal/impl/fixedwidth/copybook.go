@@ -0,0 +1,430 @@
+package fixedwidth
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// copybookItem is a data description entry of a copybook.
+type copybookItem struct {
+	level     int
+	name      string
+	pic       string
+	usage     string
+	occurs    int
+	redefines string
+	signSep   bool
+	children  []*copybookItem
+}
+
+// parseCopybook converts a COBOL copybook into a list of fields. Only data
+// description entries are supported, where level 88 condition names and level
+// 66 renames are ignored. When a copybook contains a single level 01 record
+// its subordinate items become the top level fields, and otherwise each level
+// 01 record is a field starting at the beginning of the record, allowing
+// multiple layouts to be described.
+func parseCopybook(src string) ([]*field, error) {
+	var roots []*copybookItem
+	var stack []*copybookItem
+	for _, stmt := range copybookStatements(src) {
+		item, err := parseCopybookEntry(stmt)
+		if err != nil {
+			return nil, fmt.Errorf("entry %q: %w", strings.Join(stmt, " "), err)
+		}
+		if item == nil {
+			continue
+		}
+		for len(stack) > 0 && stack[len(stack)-1].level >= item.level {
+			stack = stack[:len(stack)-1]
+		}
+		if len(stack) == 0 {
+			roots = append(roots, item)
+		} else {
+			parent := stack[len(stack)-1]
+			parent.children = append(parent.children, item)
+		}
+		stack = append(stack, item)
+	}
+	if len(roots) == 0 {
+		return nil, errors.New("copybook contains no data description entries")
+	}
+
+	if len(roots) == 1 && len(roots[0].children) > 0 {
+		fields, _, err := layoutItems(roots[0].children, "")
+		return fields, err
+	}
+
+	var fields []*field
+	for _, r := range roots {
+		f, _, err := layoutItems([]*copybookItem{r}, "")
+		if err != nil {
+			return nil, err
+		}
+		fields = append(fields, f...)
+	}
+	return fields, nil
+}
+
+// layoutItems calculates the offsets and lengths of a list of sibling items,
+// returning the fields and their total size. Fillers occupy space but are not
+// returned.
+func layoutItems(items []*copybookItem, usage string) ([]*field, int, error) {
+	var fields []*field
+	offsets := map[string]int{}
+	offset, size := 0, 0
+	for _, item := range items {
+		itemUsage := usage
+		if item.usage != "" {
+			itemUsage = item.usage
+		}
+
+		f := &field{name: item.name, occurs: item.occurs, trim: true, signed: true}
+		if len(item.children) > 0 {
+			if item.pic != "" {
+				return nil, 0, fmt.Errorf("group item %v cannot have a picture clause", item.name)
+			}
+			var err error
+			if f.children, f.length, err = layoutItems(item.children, itemUsage); err != nil {
+				return nil, 0, err
+			}
+			f.typ = typeGroup
+		} else if err := item.elementary(f, itemUsage); err != nil {
+			return nil, 0, fmt.Errorf("item %v: %w", item.name, err)
+		}
+
+		f.offset = offset
+		if item.redefines != "" {
+			o, exists := offsets[item.redefines]
+			if !exists {
+				return nil, 0, fmt.Errorf("item %v redefines unknown item %v", item.name, item.redefines)
+			}
+			f.offset = o
+		} else {
+			offset += f.size()
+		}
+		offsets[item.name] = f.offset
+		if end := f.offset + f.size(); end > size {
+			size = end
+		}
+		if item.name != "FILLER" {
+			fields = append(fields, f)
+		}
+	}
+	return fields, size, nil
+}
+
+// elementary sets the type and length of a field from the picture clause and
+// usage of an elementary item.
+func (c *copybookItem) elementary(f *field, usage string) error {
+	if c.pic == "" {
+		return errors.New("elementary items require a picture clause")
+	}
+	p, err := parsePicture(c.pic)
+	if err != nil {
+		return err
+	}
+
+	if !p.numeric {
+		if usage != "" && usage != "display" {
+			return fmt.Errorf("%v usage requires a numeric picture", usage)
+		}
+		f.typ, f.length = typeString, p.length
+		return nil
+	}
+
+	f.scale, f.signed = p.scale, p.signed
+	switch usage {
+	case "", "display":
+		f.typ, f.length = typeInteger, p.length
+		if f.scale > 0 {
+			f.typ = typeDecimal
+		}
+		if c.signSep {
+			f.length++
+		}
+	case "packed":
+		f.typ, f.length = typePacked, p.digits/2+1
+	case "binary":
+		f.typ = typeBinary
+		switch {
+		case p.digits <= 4:
+			f.length = 2
+		case p.digits <= 9:
+			f.length = 4
+		case p.digits <= 18:
+			f.length = 8
+		default:
+			return errors.New("binary items cannot exceed 18 digits")
+		}
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+type picture struct {
+	numeric bool
+	signed  bool
+	digits  int
+	scale   int
+	length  int
+}
+
+// parsePicture parses a picture clause such as X(10), S9(5)V99 or ZZ9.99.
+// Numeric edited pictures are treated as alphanumeric as they are intended for
+// display.
+func parsePicture(pic string) (picture, error) {
+	var p picture
+	upper := strings.ToUpper(pic)
+	afterPoint, edited, alpha := false, false, false
+	for i := 0; i < len(upper); i++ {
+		c := upper[i]
+		count := 1
+		if i+1 < len(upper) && upper[i+1] == '(' {
+			end := strings.IndexByte(upper[i:], ')')
+			if end == -1 {
+				return p, fmt.Errorf("invalid picture: %v", pic)
+			}
+			n, err := strconv.Atoi(upper[i+2 : i+end])
+			if err != nil || n < 1 {
+				return p, fmt.Errorf("invalid picture: %v", pic)
+			}
+			count = n
+			i += end
+		}
+
+		switch c {
+		case 'S':
+			p.signed = true
+		case 'V':
+			afterPoint = true
+		case '9':
+			p.digits += count
+			p.length += count
+			if afterPoint {
+				p.scale += count
+			}
+		case 'X', 'A':
+			alpha = true
+			p.length += count
+		case 'Z', '*', '$', ',', '.', '-', '+', 'B', '0', '/', 'C', 'R', 'D':
+			edited = true
+			p.length += count
+		case 'P':
+			return p, errors.New("scaling positions (P) are not supported")
+		default:
+			return p, fmt.Errorf("invalid picture: %v", pic)
+		}
+	}
+	if p.length == 0 {
+		return p, fmt.Errorf("invalid picture: %v", pic)
+	}
+	p.numeric = !alpha && !edited
+	return p, nil
+}
+
+//------------------------------------------------------------------------------
+
+// copybookStatements removes the sequence and indicator areas and comments of
+// a copybook and splits it into statements of tokens, where each statement
+// is terminated by a period.
+func copybookStatements(src string) [][]string {
+	lines := strings.Split(strings.ReplaceAll(src, "\r", ""), "\n")
+	fixed := true
+	for _, line := range lines {
+		if strings.TrimSpace(line) != "" && !isFixedFormat(line) {
+			fixed = false
+		}
+	}
+
+	var code strings.Builder
+	for _, line := range lines {
+		if fixed && len(line) > 6 {
+			if line[6] == '*' || line[6] == '/' {
+				continue
+			}
+			line = line[7:]
+			if len(line) > 65 {
+				line = line[:65]
+			}
+		}
+		if i := strings.Index(line, "*>"); i != -1 {
+			line = line[:i]
+		}
+		if strings.HasPrefix(strings.TrimSpace(line), "*") {
+			continue
+		}
+		code.WriteString(line)
+		code.WriteByte(' ')
+	}
+
+	var statements [][]string
+	var tokens []string
+	var token strings.Builder
+	flush := func() {
+		if token.Len() > 0 {
+			tokens = append(tokens, token.String())
+			token.Reset()
+		}
+	}
+
+	s := code.String()
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			token.WriteByte(c)
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+			token.WriteByte(c)
+		case c == ' ' || c == '\t':
+			flush()
+		case c == '.' && (i+1 == len(s) || s[i+1] == ' ' || s[i+1] == '\t'):
+			flush()
+			if len(tokens) > 0 {
+				statements = append(statements, tokens)
+				tokens = nil
+			}
+		default:
+			token.WriteByte(c)
+		}
+	}
+	flush()
+	if len(tokens) > 0 {
+		statements = append(statements, tokens)
+	}
+	return statements
+}
+
+// isFixedFormat returns whether a line has a sequence number area (columns 1
+// to 6) that is either blank or numeric, followed by an indicator area (column
+// 7).
+func isFixedFormat(line string) bool {
+	if len(line) < 7 {
+		return false
+	}
+	if seq := line[:6]; strings.TrimSpace(seq) != "" && strings.Trim(seq, "0123456789") != "" {
+		return false
+	}
+	switch line[6] {
+	case ' ', '*', '/', '-':
+		return true
+	}
+	return false
+}
+
+// parseCopybookEntry parses the tokens of a data description entry, returning
+// nil for entries that do not describe data.
+func parseCopybookEntry(tokens []string) (*copybookItem, error) {
+	level, err := strconv.Atoi(tokens[0])
+	if err != nil {
+		return nil, fmt.Errorf("invalid level number: %v", tokens[0])
+	}
+	if level == 66 || level == 88 {
+		return nil, nil
+	}
+	if level == 77 {
+		level = 1
+	}
+	if level < 1 || level > 49 {
+		return nil, fmt.Errorf("invalid level number: %v", level)
+	}
+
+	item := &copybookItem{level: level, name: "FILLER"}
+	rest := tokens[1:]
+	if len(rest) > 0 && !isCopybookKeyword(rest[0]) {
+		item.name, rest = rest[0], rest[1:]
+	}
+
+	next := func() string {
+		if len(rest) == 0 {
+			return ""
+		}
+		t := rest[0]
+		rest = rest[1:]
+		return t
+	}
+	optional := func(words ...string) {
+		for len(rest) > 0 {
+			matched := false
+			for _, w := range words {
+				if strings.EqualFold(rest[0], w) {
+					matched = true
+				}
+			}
+			if !matched {
+				return
+			}
+			rest = rest[1:]
+		}
+	}
+
+	for len(rest) > 0 {
+		switch t := strings.ToUpper(next()); t {
+		case "PIC", "PICTURE":
+			optional("IS")
+			if item.pic = next(); item.pic == "" {
+				return nil, errors.New("missing picture string")
+			}
+		case "USAGE":
+			optional("IS")
+		case "DISPLAY":
+			item.usage = "display"
+		case "COMP-3", "COMPUTATIONAL-3", "PACKED-DECIMAL":
+			item.usage = "packed"
+		case "COMP", "COMP-4", "COMP-5", "COMPUTATIONAL", "COMPUTATIONAL-4", "COMPUTATIONAL-5", "BINARY":
+			item.usage = "binary"
+		case "OCCURS":
+			n, err := strconv.Atoi(next())
+			if err != nil || n < 1 {
+				return nil, errors.New("invalid occurs clause")
+			}
+			item.occurs = n
+			if len(rest) > 0 && strings.EqualFold(rest[0], "TO") {
+				return nil, errors.New("variable length tables (OCCURS DEPENDING ON) are not supported")
+			}
+			optional("TIMES")
+		case "INDEXED":
+			optional("BY")
+			next()
+		case "REDEFINES":
+			if item.redefines = next(); item.redefines == "" {
+				return nil, errors.New("missing redefined item")
+			}
+		case "SIGN":
+			optional("IS", "LEADING", "TRAILING")
+			if len(rest) > 0 && strings.EqualFold(rest[0], "SEPARATE") {
+				item.signSep = true
+				optional("SEPARATE", "CHARACTER")
+			}
+		case "LEADING", "TRAILING":
+			if len(rest) > 0 && strings.EqualFold(rest[0], "SEPARATE") {
+				item.signSep = true
+				optional("SEPARATE", "CHARACTER")
+			}
+		case "VALUE", "VALUES":
+			optional("IS", "ARE")
+			next()
+		case "JUST", "JUSTIFIED", "SYNC", "SYNCHRONIZED", "BLANK", "WHEN", "ZERO", "ZEROS", "ZEROES",
+			"RIGHT", "LEFT", "GLOBAL", "EXTERNAL":
+		default:
+			return nil, fmt.Errorf("unsupported clause: %v", t)
+		}
+	}
+	return item, nil
+}
+
+func isCopybookKeyword(t string) bool {
+	switch strings.ToUpper(t) {
+	case "PIC", "PICTURE", "USAGE", "DISPLAY", "COMP", "COMP-3", "COMP-4", "COMP-5", "BINARY",
+		"PACKED-DECIMAL", "OCCURS", "REDEFINES", "VALUE", "SIGN":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,296 @@
+package fixedwidth
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	fwpFieldFields       = "fields"
+	fwpFieldName         = "name"
+	fwpFieldOffset       = "offset"
+	fwpFieldLength       = "length"
+	fwpFieldType         = "type"
+	fwpFieldScale        = "scale"
+	fwpFieldSigned       = "signed"
+	fwpFieldTrim         = "trim"
+	fwpFieldCopybook     = "copybook"
+	fwpFieldEncoding     = "encoding"
+	fwpFieldRecordLength = "record_length"
+)
+
+func fixedWidthProcSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Categories("Parsing").
+		Version("4.28.0").
+		Summary("Parses fixed-width records, such as those of mainframe flat files, into structured messages.").
+		Description(`
+Each message is split into records, either by line or into chunks of `+"`record_length`"+` bytes, and a message is created for each record containing an object with a value for each field. Each resulting message has the metadata field `+"`fixed_width_index`"+` set to the zero-based position of the record within the original message.
+
+The layout of records is described either with a list of `+"`fields`"+` or with a COBOL `+"`copybook`"+`. Records shorter than the layout are padded with spaces, allowing lines with trailing whitespace removed to be parsed.
+
+### Field Types
+
+- `+"`string`"+`: Text decoded with the configured `+"`encoding`"+`.
+- `+"`integer`"+`: A zoned (display) number, which may have a leading or trailing sign or a sign overpunched on its final digit.
+- `+"`decimal`"+`: A zoned number with `+"`scale`"+` implied decimal places, or an explicit decimal point.
+- `+"`packed`"+`: A packed decimal (COMP-3) with `+"`scale`"+` implied decimal places.
+- `+"`binary`"+`: A big-endian binary integer (COMP) of up to eight bytes with `+"`scale`"+` implied decimal places.
+
+Numbers with decimal places are parsed as floating point numbers, and otherwise as integers. Numeric fields that consist entirely of spaces or low values (null bytes) are parsed as `+"`null`"+`, except for binary fields.
+
+### Copybooks
+
+Copybooks may be in either fixed or free format. Elementary items are mapped to field types from their picture clause and usage, group items become nested objects and items with an `+"`OCCURS`"+` clause become arrays. Items that redefine another item are parsed from the same position, `+"`FILLER`"+` items are skipped and level 88 condition names are ignored. Variable length tables (`+"`OCCURS DEPENDING ON`"+`) are not supported.
+
+When a copybook contains a single level 01 record its subordinate items become the fields of each record, otherwise each level 01 record is parsed from the start of each record and added under its own name.
+
+### EBCDIC
+
+Mainframe data sets are usually EBCDIC encoded, which can be decoded by setting the `+"`encoding`"+` to one of the supported code pages. Only text fields are decoded, as packed and binary fields are parsed from the raw bytes of the record. EBCDIC records are typically fixed length without line breaks and so a `+"`record_length`"+` should be set, otherwise lines are split by the EBCDIC NL and LF characters.
+
+Records that cannot be parsed are flagged as having failed and retain their raw contents, allowing you to use [standard processor error handling patterns](/docs/configuration/error_handling).`).
+		Fields(
+			service.NewObjectListField(fwpFieldFields,
+				service.NewStringField(fwpFieldName).
+					Description("The key of the field within the resulting object."),
+				service.NewIntField(fwpFieldOffset).
+					Description("The zero-based byte offset of the field within the record. When omitted the field follows the previous field.").
+					Optional(),
+				service.NewIntField(fwpFieldLength).
+					Description("The length of the field in bytes."),
+				service.NewStringEnumField(fwpFieldType, typeString, typeInteger, typeDecimal, typePacked, typeBinary).
+					Description("The type of the field.").
+					Default(typeString),
+				service.NewIntField(fwpFieldScale).
+					Description("The number of implied decimal places of `decimal`, `packed` and `binary` fields.").
+					Default(0),
+				service.NewBoolField(fwpFieldSigned).
+					Description("Whether `binary` fields are two's complement signed integers.").
+					Default(true).
+					Advanced(),
+				service.NewBoolField(fwpFieldTrim).
+					Description("Whether to trim spaces and null bytes from `string` fields.").
+					Default(true).
+					Advanced(),
+			).
+				Description("The fields of each record, which is required unless a `copybook` is provided.").
+				Optional(),
+			service.NewStringField(fwpFieldCopybook).
+				Description("A COBOL copybook describing the layout of each record.").
+				Optional().
+				Example(`       01  CUSTOMER-RECORD.
+           05  CUST-ID           PIC 9(6).
+           05  CUST-NAME         PIC X(20).
+           05  CUST-BALANCE      PIC S9(7)V99 COMP-3.
+           05  CUST-PHONE        PIC X(10) OCCURS 2 TIMES.`),
+			service.NewStringEnumField(fwpFieldEncoding, "utf-8", "iso-8859-1", "cp037", "cp1047", "cp1140").
+				Description("The character encoding of text within records, where `cp037`, `cp1047` and `cp1140` are EBCDIC code pages.").
+				Default("utf-8"),
+			service.NewIntField(fwpFieldRecordLength).
+				Description("The length in bytes of each record within a message. When zero messages are split into records by line.").
+				Default(0),
+		).
+		Example("Mainframe Extract", "Here we parse fixed length EBCDIC records described by a copybook.", `
+pipeline:
+  processors:
+    - fixed_width:
+        encoding: cp037
+        record_length: 42
+        copybook: |
+          01  CUSTOMER-RECORD.
+              05  CUST-ID           PIC 9(6).
+              05  CUST-NAME         PIC X(20).
+              05  CUST-BALANCE      PIC S9(7)V99 COMP-3.
+              05  CUST-PHONE        PIC X(5) OCCURS 2 TIMES.
+              05  FILLER            PIC X(1).
+`).
+		Example("Flat File", "Here we parse lines of a fixed-width text file.", `
+pipeline:
+  processors:
+    - fixed_width:
+        fields:
+          - { name: id, length: 6, type: integer }
+          - { name: name, length: 20 }
+          - { name: amount, length: 10, type: decimal, scale: 2 }
+          - { name: status, offset: 40, length: 1 }
+`)
+}
+
+func init() {
+	err := service.RegisterProcessor(
+		"fixed_width", fixedWidthProcSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Processor, error) {
+			return newFixedWidthProcFromConfig(conf)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type fixedWidthProc struct {
+	fields       []*field
+	minLength    int
+	enc          encoding
+	recordLength int
+}
+
+func newFixedWidthProcFromConfig(conf *service.ParsedConfig) (*fixedWidthProc, error) {
+	p := &fixedWidthProc{}
+
+	// An unset fields list is present within the config as an empty list.
+	var fConfs []*service.ParsedConfig
+	if conf.Contains(fwpFieldFields) {
+		var err error
+		if fConfs, err = conf.FieldObjectList(fwpFieldFields); err != nil {
+			return nil, err
+		}
+	}
+
+	var err error
+	switch {
+	case conf.Contains(fwpFieldCopybook) && len(fConfs) > 0:
+		return nil, fmt.Errorf("cannot combine fields %v and %v", fwpFieldFields, fwpFieldCopybook)
+	case conf.Contains(fwpFieldCopybook):
+		src, err := conf.FieldString(fwpFieldCopybook)
+		if err != nil {
+			return nil, err
+		}
+		if p.fields, err = parseCopybook(src); err != nil {
+			return nil, fmt.Errorf("failed to parse copybook: %w", err)
+		}
+	case len(fConfs) > 0:
+		if p.fields, err = fieldsFromConfig(fConfs); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("either field %v or %v must be specified", fwpFieldFields, fwpFieldCopybook)
+	}
+	p.minLength = recordLength(p.fields)
+
+	encName, err := conf.FieldString(fwpFieldEncoding)
+	if err != nil {
+		return nil, err
+	}
+	p.enc = encodings[encName]
+
+	if p.recordLength, err = conf.FieldInt(fwpFieldRecordLength); err != nil {
+		return nil, err
+	}
+	if p.recordLength < 0 {
+		return nil, errors.New("record length must not be negative")
+	}
+	return p, nil
+}
+
+func fieldsFromConfig(fConfs []*service.ParsedConfig) ([]*field, error) {
+	var err error
+	var fields []*field
+	offset := 0
+	for i, fConf := range fConfs {
+		f := &field{}
+		if f.name, err = fConf.FieldString(fwpFieldName); err != nil {
+			return nil, err
+		}
+		if fConf.Contains(fwpFieldOffset) {
+			if offset, err = fConf.FieldInt(fwpFieldOffset); err != nil {
+				return nil, err
+			}
+		}
+		f.offset = offset
+		if f.length, err = fConf.FieldInt(fwpFieldLength); err != nil {
+			return nil, err
+		}
+		if f.typ, err = fConf.FieldString(fwpFieldType); err != nil {
+			return nil, err
+		}
+		if f.scale, err = fConf.FieldInt(fwpFieldScale); err != nil {
+			return nil, err
+		}
+		if f.signed, err = fConf.FieldBool(fwpFieldSigned); err != nil {
+			return nil, err
+		}
+		if f.trim, err = fConf.FieldBool(fwpFieldTrim); err != nil {
+			return nil, err
+		}
+
+		switch {
+		case f.offset < 0 || f.length < 1:
+			err = errors.New("offset must not be negative and length must be positive")
+		case f.typ == typeBinary && f.length > 8:
+			err = errors.New("binary fields cannot exceed 8 bytes")
+		case f.scale < 0 || (f.scale > 0 && (f.typ == typeString || f.typ == typeInteger)):
+			err = fmt.Errorf("scale is not supported by %v fields", f.typ)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("field %v (%v): %w", i, f.name, err)
+		}
+		offset += f.length
+		fields = append(fields, f)
+	}
+	return fields, nil
+}
+
+// records splits a message into records by length or by line.
+func (p *fixedWidthProc) records(data []byte) [][]byte {
+	var records [][]byte
+	if p.recordLength > 0 {
+		for len(data) > 0 {
+			n := p.recordLength
+			if n > len(data) {
+				n = len(data)
+			}
+			records = append(records, data[:n])
+			data = data[n:]
+		}
+		return records
+	}
+
+	start := 0
+	for i := 0; i <= len(data); i++ {
+		if i < len(data) && !p.enc.isLineBreak(data[i]) {
+			continue
+		}
+		if line := bytes.TrimRight(data[start:i], "\r"); len(line) > 0 {
+			records = append(records, line)
+		}
+		start = i + 1
+	}
+	return records
+}
+
+func (p *fixedWidthProc) Process(ctx context.Context, msg *service.Message) (service.MessageBatch, error) {
+	raw, err := msg.AsBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	var batch service.MessageBatch
+	for i, rec := range p.records(raw) {
+		recMsg := msg.Copy()
+		recMsg.MetaSetMut("fixed_width_index", int64(i))
+
+		padded := rec
+		if len(rec) < p.minLength {
+			padded = append(append([]byte{}, rec...), bytes.Repeat([]byte{p.enc.space}, p.minLength-len(rec))...)
+		}
+
+		obj, err := p.enc.decodeFields(padded, p.fields, 0)
+		if err != nil {
+			recMsg.SetBytes(rec)
+			recMsg.SetError(fmt.Errorf("record %v: %w", i, err))
+		} else {
+			recMsg.SetStructuredMut(obj)
+		}
+		batch = append(batch, recMsg)
+	}
+	return batch, nil
+}
+
+func (p *fixedWidthProc) Close(ctx context.Context) error {
+	return nil
+}
@@ -0,0 +1,140 @@
+package fixedwidth
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/text/encoding/charmap"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func testFixedWidthProc(t *testing.T, confStr string) *fixedWidthProc {
+	t.Helper()
+
+	conf, err := fixedWidthProcSpec().ParseYAML(confStr, nil)
+	require.NoError(t, err)
+
+	proc, err := newFixedWidthProcFromConfig(conf)
+	require.NoError(t, err)
+	return proc
+}
+
+func TestFixedWidthFields(t *testing.T) {
+	proc := testFixedWidthProc(t, `
+fields:
+  - { name: id, length: 4, type: integer }
+  - { name: name, length: 8 }
+  - { name: amount, length: 7, type: decimal, scale: 2 }
+  - { name: status, offset: 20, length: 1 }
+`)
+
+	res, err := proc.Process(context.Background(), service.NewMessage([]byte(
+		"0001John    001234{ A\r\n"+
+			"0002Jane    000050}\n"+
+			"\n"+
+			"000xBad     0000000 X\n",
+	)))
+	require.NoError(t, err)
+	require.Len(t, res, 3)
+
+	v, err := res[0].AsStructured()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{
+		"id":     int64(1),
+		"name":   "John",
+		"amount": 123.4,
+		"status": "A",
+	}, v)
+
+	v, err = res[1].AsStructured()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{
+		"id":     int64(2),
+		"name":   "Jane",
+		"amount": -5.0,
+		"status": "",
+	}, v)
+
+	index, _ := res[1].MetaGetMut("fixed_width_index")
+	assert.Equal(t, int64(1), index)
+
+	require.Error(t, res[2].GetError())
+	assert.Contains(t, res[2].GetError().Error(), `record 2: field id at offset 0: invalid number: "000x"`)
+	b, err := res[2].AsBytes()
+	require.NoError(t, err)
+	assert.Equal(t, "000xBad     0000000 X", string(b))
+}
+
+func TestFixedWidthCopybookEBCDIC(t *testing.T) {
+	proc := testFixedWidthProc(t, `
+encoding: cp037
+record_length: 22
+copybook: |
+  000100 01  ACCOUNT-RECORD.
+  000200*    A COMMENT LINE
+  000300     05  ACCT-ID          PIC X(4).
+  000400     05  ACCT-BALANCE     PIC S9(5)V99 COMP-3.
+  000500     05  ACCT-FLAGS       PIC 9(4) COMP.
+  000600     05  ACCT-HISTORY     OCCURS 2 TIMES.
+  000700         10  HIST-CODE    PIC X(2).
+  000800         10  HIST-AMOUNT  PIC S9(3) COMP-3.
+  000900     05  ACCT-TYPE        PIC X.
+  001000         88  ACCT-ACTIVE  VALUE 'A'.
+  001100     05  ACCT-TYPE-NUM    REDEFINES ACCT-TYPE PIC 9.
+  001200     05  FILLER           PIC X(3).
+`)
+
+	enc := func(s string) []byte {
+		b, err := charmap.CodePage037.NewEncoder().Bytes([]byte(s))
+		require.NoError(t, err)
+		return b
+	}
+
+	var rec []byte
+	rec = append(rec, enc("AB12")...)
+	rec = append(rec, 0x01, 0x23, 0x45, 0x6D)
+	rec = append(rec, 0x00, 0x2A)
+	rec = append(rec, enc("XY")...)
+	rec = append(rec, 0x12, 0x3C)
+	rec = append(rec, enc("ZZ")...)
+	rec = append(rec, 0x40, 0x40)
+	rec = append(rec, enc("7   ")...)
+	require.Len(t, rec, 22)
+
+	res, err := proc.Process(context.Background(), service.NewMessage(append(rec, rec...)))
+	require.NoError(t, err)
+	require.Len(t, res, 2)
+
+	v, err := res[1].AsStructured()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{
+		"ACCT-ID":      "AB12",
+		"ACCT-BALANCE": -1234.56,
+		"ACCT-FLAGS":   int64(42),
+		"ACCT-HISTORY": []any{
+			map[string]any{"HIST-CODE": "XY", "HIST-AMOUNT": int64(123)},
+			map[string]any{"HIST-CODE": "ZZ", "HIST-AMOUNT": nil},
+		},
+		"ACCT-TYPE":     "7",
+		"ACCT-TYPE-NUM": int64(7),
+	}, v)
+}
+
+func TestFixedWidthConfigErrors(t *testing.T) {
+	for _, confStr := range []string{
+		`record_length: 10`,
+		`copybook: "01 FOO PIC Q(3)."`,
+		`copybook: "01 FOO OCCURS 1 TO 5 TIMES DEPENDING ON BAR PIC X."`,
+		`fields: [ { name: foo, length: 4, type: integer, scale: 2 } ]`,
+		`fields: [ { name: foo, length: 10, type: binary } ]`,
+	} {
+		conf, err := fixedWidthProcSpec().ParseYAML(confStr, nil)
+		require.NoError(t, err, confStr)
+
+		_, err = newFixedWidthProcFromConfig(conf)
+		require.Error(t, err, confStr)
+	}
+}
@@ -0,0 +1,317 @@
+package fixedwidth
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"golang.org/x/text/encoding/charmap"
+)
+
+const (
+	typeString  = "string"
+	typeInteger = "integer"
+	typeDecimal = "decimal"
+	typePacked  = "packed"
+	typeBinary  = "binary"
+	typeGroup   = "group"
+)
+
+// field describes a column of a record. The offset of a field is relative to
+// the field that encloses it, or the start of the record for top level fields.
+type field struct {
+	name   string
+	offset int
+	length int // The length of a single occurrence.
+	typ    string
+	scale  int
+	signed bool
+	trim   bool
+	occurs int
+
+	// Only set for group fields.
+	children []*field
+}
+
+// size returns the number of bytes occupied by all occurrences of a field.
+func (f *field) size() int {
+	if f.occurs > 0 {
+		return f.length * f.occurs
+	}
+	return f.length
+}
+
+// recordLength returns the minimum length of a record containing all fields.
+func recordLength(fields []*field) int {
+	n := 0
+	for _, f := range fields {
+		if end := f.offset + f.size(); end > n {
+			n = end
+		}
+	}
+	return n
+}
+
+//------------------------------------------------------------------------------
+
+// encoding describes the character set of records.
+type encoding struct {
+	cm     *charmap.Charmap // Nil for UTF-8
+	space  byte
+	ebcdic bool
+}
+
+var encodings = map[string]encoding{
+	"utf-8":      {space: ' '},
+	"iso-8859-1": {cm: charmap.ISO8859_1, space: ' '},
+	"cp037":      {cm: charmap.CodePage037, space: 0x40, ebcdic: true},
+	"cp1047":     {cm: charmap.CodePage1047, space: 0x40, ebcdic: true},
+	"cp1140":     {cm: charmap.CodePage1140, space: 0x40, ebcdic: true},
+}
+
+func (e encoding) text(b []byte) string {
+	if e.cm == nil {
+		return string(b)
+	}
+	var sb strings.Builder
+	sb.Grow(len(b))
+	for _, c := range b {
+		sb.WriteRune(e.cm.DecodeByte(c))
+	}
+	return sb.String()
+}
+
+// isLineBreak returns whether a byte terminates a line of text, EBCDIC text
+// may use either the NL or LF control characters.
+func (e encoding) isLineBreak(c byte) bool {
+	if e.ebcdic {
+		return c == 0x15 || c == 0x25
+	}
+	return c == '\n'
+}
+
+// isBlank returns whether a field consists entirely of spaces or low values
+// (null bytes).
+func (e encoding) isBlank(b []byte) bool {
+	for _, c := range b {
+		if c != e.space && c != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+//------------------------------------------------------------------------------
+
+// decodeFields converts a record into an object containing a value for each
+// field, where base is the offset of the enclosing field.
+func (e encoding) decodeFields(rec []byte, fields []*field, base int) (map[string]any, error) {
+	obj := make(map[string]any, len(fields))
+	for _, f := range fields {
+		start := base + f.offset
+		if f.occurs == 0 {
+			v, err := e.decodeField(rec, f, start)
+			if err != nil {
+				return nil, err
+			}
+			obj[f.name] = v
+			continue
+		}
+
+		arr := make([]any, f.occurs)
+		for i := range arr {
+			v, err := e.decodeField(rec, f, start+i*f.length)
+			if err != nil {
+				return nil, err
+			}
+			arr[i] = v
+		}
+		obj[f.name] = arr
+	}
+	return obj, nil
+}
+
+func (e encoding) decodeField(rec []byte, f *field, start int) (any, error) {
+	if f.typ == typeGroup {
+		return e.decodeFields(rec, f.children, start)
+	}
+	v, err := e.decodeValue(rec[start:start+f.length], f)
+	if err != nil {
+		return nil, fmt.Errorf("field %v at offset %v: %w", f.name, start, err)
+	}
+	return v, nil
+}
+
+func (e encoding) decodeValue(b []byte, f *field) (any, error) {
+	switch f.typ {
+	case typeString:
+		s := e.text(b)
+		if f.trim {
+			s = strings.Trim(s, " \x00")
+		}
+		return s, nil
+	case typeInteger, typeDecimal:
+		if e.isBlank(b) {
+			return nil, nil
+		}
+		digits, scale, neg, err := parseDisplay(strings.TrimSpace(e.text(b)), f.scale)
+		if err != nil {
+			return nil, err
+		}
+		if f.typ == typeInteger && scale > 0 {
+			return nil, fmt.Errorf("expected an integer, got %q", e.text(b))
+		}
+		return numberFromDigits(digits, scale, neg, f.typ == typeDecimal)
+	case typePacked:
+		if e.isBlank(b) {
+			return nil, nil
+		}
+		digits, neg, err := unpackDecimal(b)
+		if err != nil {
+			return nil, err
+		}
+		return numberFromDigits(digits, f.scale, neg, f.scale > 0)
+	case typeBinary:
+		return decodeBinary(b, f.scale, f.signed)
+	}
+	return nil, fmt.Errorf("unsupported field type: %v", f.typ)
+}
+
+//------------------------------------------------------------------------------
+
+// overpunch maps the final character of a signed zoned decimal to its digit
+// and whether the value is negative.
+func overpunch(c byte) (digit byte, neg, ok bool) {
+	switch {
+	case c == '{':
+		return '0', false, true
+	case c >= 'A' && c <= 'I':
+		return '1' + (c - 'A'), false, true
+	case c == '}':
+		return '0', true, true
+	case c >= 'J' && c <= 'R':
+		return '1' + (c - 'J'), true, true
+	}
+	return 0, false, false
+}
+
+// parseDisplay parses a zoned (display) decimal, which may have a leading or
+// trailing sign, a sign overpunched on its final digit or an explicit decimal
+// point, which overrides the implied scale. The digits of the number are
+// returned along with its scale.
+func parseDisplay(s string, scale int) (digits string, resScale int, neg bool, err error) {
+	orig := s
+	switch {
+	case strings.HasPrefix(s, "-"), strings.HasPrefix(s, "+"):
+		neg, s = s[0] == '-', strings.TrimSpace(s[1:])
+	case strings.HasSuffix(s, "-"), strings.HasSuffix(s, "+"):
+		neg, s = s[len(s)-1] == '-', strings.TrimSpace(s[:len(s)-1])
+	case s != "":
+		if d, n, ok := overpunch(s[len(s)-1]); ok {
+			neg, s = n, s[:len(s)-1]+string(d)
+		}
+	}
+
+	if i := strings.IndexByte(s, '.'); i != -1 {
+		scale = len(s) - i - 1
+		s = s[:i] + s[i+1:]
+	}
+	if s == "" {
+		return "", 0, false, fmt.Errorf("invalid number: %q", orig)
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return "", 0, false, fmt.Errorf("invalid number: %q", orig)
+		}
+	}
+	return s, scale, neg, nil
+}
+
+// unpackDecimal decodes a packed decimal (COMP-3), where each byte contains
+// two digits and the final half byte contains the sign.
+func unpackDecimal(b []byte) (digits string, neg bool, err error) {
+	var sb strings.Builder
+	for i, c := range b {
+		hi, lo := c>>4, c&0x0f
+		if hi > 9 {
+			return "", false, fmt.Errorf("invalid packed decimal: %X", b)
+		}
+		sb.WriteByte('0' + hi)
+		if i < len(b)-1 {
+			if lo > 9 {
+				return "", false, fmt.Errorf("invalid packed decimal: %X", b)
+			}
+			sb.WriteByte('0' + lo)
+			continue
+		}
+		switch lo {
+		case 0x0c, 0x0a, 0x0e, 0x0f:
+		case 0x0d, 0x0b:
+			neg = true
+		default:
+			return "", false, fmt.Errorf("invalid packed decimal sign: %X", b)
+		}
+	}
+	return sb.String(), neg, nil
+}
+
+// decodeBinary decodes a big-endian binary integer (COMP) of up to 8 bytes.
+func decodeBinary(b []byte, scale int, signed bool) (any, error) {
+	if len(b) == 0 || len(b) > 8 {
+		return nil, fmt.Errorf("binary fields must be between 1 and 8 bytes, got %v", len(b))
+	}
+	var buf [8]byte
+	if signed && b[0]&0x80 != 0 {
+		for i := range buf {
+			buf[i] = 0xff
+		}
+	}
+	copy(buf[8-len(b):], b)
+	u := binary.BigEndian.Uint64(buf[:])
+
+	var digits string
+	var neg bool
+	if signed {
+		digits = strconv.FormatInt(int64(u), 10)
+		if neg = digits[0] == '-'; neg {
+			digits = digits[1:]
+		}
+	} else {
+		digits = strconv.FormatUint(u, 10)
+	}
+	return numberFromDigits(digits, scale, neg, scale > 0)
+}
+
+var errOutOfRange = errors.New("value is out of range")
+
+// numberFromDigits converts a string of digits with an implied scale into a
+// float64 when asFloat is true, and otherwise an int64.
+func numberFromDigits(digits string, scale int, neg, asFloat bool) (any, error) {
+	if !asFloat {
+		if neg {
+			digits = "-" + digits
+		}
+		i, err := strconv.ParseInt(digits, 10, 64)
+		if err != nil {
+			return nil, errOutOfRange
+		}
+		return i, nil
+	}
+
+	if scale > 0 {
+		if len(digits) <= scale {
+			digits = strings.Repeat("0", scale-len(digits)+1) + digits
+		}
+		digits = digits[:len(digits)-scale] + "." + digits[len(digits)-scale:]
+	}
+	if neg {
+		digits = "-" + digits
+	}
+	f, err := strconv.ParseFloat(digits, 64)
+	if err != nil {
+		return nil, errOutOfRange
+	}
+	return f, nil
+}
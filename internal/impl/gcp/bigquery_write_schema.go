@@ -0,0 +1,223 @@
+package gcp
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+	"cloud.google.com/go/bigquery/storage/managedwriter/adapt"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+
+	"github.com/benthosdev/benthos/v4/internal/value"
+)
+
+// bqRowEncoder serialises structured messages into protobuf rows matching the
+// schema of a table, as required by the Storage Write API.
+type bqRowEncoder struct {
+	schema     bigquery.Schema
+	descriptor *descriptorpb.DescriptorProto
+	msgDesc    protoreflect.MessageDescriptor
+}
+
+// bqFieldProtoType returns the protobuf type used to send values of a column.
+// Where the API accepts multiple representations the string form is used as it
+// is the most natural for structured messages.
+func bqFieldProtoType(t bigquery.FieldType) (descriptorpb.FieldDescriptorProto_Type, error) {
+	switch t {
+	case bigquery.StringFieldType, bigquery.NumericFieldType, bigquery.BigNumericFieldType,
+		bigquery.DateTimeFieldType, bigquery.TimeFieldType, bigquery.GeographyFieldType,
+		bigquery.JSONFieldType, bigquery.IntervalFieldType:
+		return descriptorpb.FieldDescriptorProto_TYPE_STRING, nil
+	case bigquery.BytesFieldType:
+		return descriptorpb.FieldDescriptorProto_TYPE_BYTES, nil
+	case bigquery.IntegerFieldType, bigquery.TimestampFieldType:
+		return descriptorpb.FieldDescriptorProto_TYPE_INT64, nil
+	case bigquery.DateFieldType:
+		return descriptorpb.FieldDescriptorProto_TYPE_INT32, nil
+	case bigquery.FloatFieldType:
+		return descriptorpb.FieldDescriptorProto_TYPE_DOUBLE, nil
+	case bigquery.BooleanFieldType:
+		return descriptorpb.FieldDescriptorProto_TYPE_BOOL, nil
+	case bigquery.RecordFieldType:
+		return descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, nil
+	}
+	return 0, fmt.Errorf("column type %v is not supported", t)
+}
+
+// bqSchemaToDescriptor builds a message descriptor for a table schema, where
+// record columns are nested messages named after the index of their column.
+func bqSchemaToDescriptor(fullName string, schema bigquery.Schema) (*descriptorpb.DescriptorProto, error) {
+	name := fullName[strings.LastIndex(fullName, ".")+1:]
+	dp := &descriptorpb.DescriptorProto{Name: proto.String(name)}
+	for i, f := range schema {
+		fType, err := bqFieldProtoType(f.Type)
+		if err != nil {
+			return nil, fmt.Errorf("column %v: %w", f.Name, err)
+		}
+
+		label := descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL
+		if f.Repeated {
+			label = descriptorpb.FieldDescriptorProto_LABEL_REPEATED
+		} else if f.Required {
+			label = descriptorpb.FieldDescriptorProto_LABEL_REQUIRED
+		}
+
+		fdp := &descriptorpb.FieldDescriptorProto{
+			Name:   proto.String(f.Name),
+			Number: proto.Int32(int32(i + 1)),
+			Label:  label.Enum(),
+			Type:   fType.Enum(),
+		}
+		if fType == descriptorpb.FieldDescriptorProto_TYPE_MESSAGE {
+			nestedName := fmt.Sprintf("%v.%v_%v", fullName, name, i+1)
+			nested, err := bqSchemaToDescriptor(nestedName, f.Schema)
+			if err != nil {
+				return nil, fmt.Errorf("column %v: %w", f.Name, err)
+			}
+			dp.NestedType = append(dp.NestedType, nested)
+			fdp.TypeName = proto.String("." + nestedName)
+		}
+		dp.Field = append(dp.Field, fdp)
+	}
+	return dp, nil
+}
+
+func newBQRowEncoder(schema bigquery.Schema) (*bqRowEncoder, error) {
+	dp, err := bqSchemaToDescriptor("Row", schema)
+	if err != nil {
+		return nil, err
+	}
+
+	fd, err := protodesc.NewFile(&descriptorpb.FileDescriptorProto{
+		Name:        proto.String("row.proto"),
+		Syntax:      proto.String("proto2"),
+		MessageType: []*descriptorpb.DescriptorProto{dp},
+	}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build row descriptor: %w", err)
+	}
+
+	msgDesc := fd.Messages().Get(0)
+	normalised, err := adapt.NormalizeDescriptor(msgDesc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to normalise row descriptor: %w", err)
+	}
+
+	return &bqRowEncoder{
+		schema:     schema,
+		descriptor: normalised,
+		msgDesc:    msgDesc,
+	}, nil
+}
+
+// encode serialises a structured message into a protobuf row.
+func (e *bqRowEncoder) encode(v any) ([]byte, error) {
+	obj, ok := v.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("expected an object, got %T", v)
+	}
+	msg := dynamicpb.NewMessage(e.msgDesc)
+	if err := bqSetFields(msg, e.schema, obj); err != nil {
+		return nil, err
+	}
+	return proto.Marshal(msg)
+}
+
+func bqSetFields(msg protoreflect.Message, schema bigquery.Schema, obj map[string]any) error {
+	fields := msg.Descriptor().Fields()
+	for i, f := range schema {
+		fd := fields.ByNumber(protoreflect.FieldNumber(i + 1))
+
+		v := obj[f.Name]
+		if v == nil {
+			if f.Required {
+				return fmt.Errorf("column %v is required", f.Name)
+			}
+			continue
+		}
+
+		if f.Repeated {
+			arr, ok := v.([]any)
+			if !ok {
+				return fmt.Errorf("column %v: expected an array, got %T", f.Name, v)
+			}
+			list := msg.Mutable(fd).List()
+			for j, e := range arr {
+				pv, err := bqProtoValue(list.NewElement, f, e)
+				if err != nil {
+					return fmt.Errorf("column %v index %v: %w", f.Name, j, err)
+				}
+				list.Append(pv)
+			}
+			continue
+		}
+
+		pv, err := bqProtoValue(func() protoreflect.Value { return msg.NewField(fd) }, f, v)
+		if err != nil {
+			return fmt.Errorf("column %v: %w", f.Name, err)
+		}
+		msg.Set(fd, pv)
+	}
+	return nil
+}
+
+func bqProtoValue(newValue func() protoreflect.Value, f *bigquery.FieldSchema, v any) (protoreflect.Value, error) {
+	switch f.Type {
+	case bigquery.RecordFieldType:
+		obj, ok := v.(map[string]any)
+		if !ok {
+			return protoreflect.Value{}, fmt.Errorf("expected an object, got %T", v)
+		}
+		nested := newValue()
+		if err := bqSetFields(nested.Message(), f.Schema, obj); err != nil {
+			return protoreflect.Value{}, err
+		}
+		return nested, nil
+	case bigquery.JSONFieldType:
+		if s, ok := v.(string); ok {
+			return protoreflect.ValueOfString(s), nil
+		}
+		b, err := json.Marshal(v)
+		if err != nil {
+			return protoreflect.Value{}, err
+		}
+		return protoreflect.ValueOfString(string(b)), nil
+	case bigquery.BytesFieldType:
+		return protoreflect.ValueOfBytes(value.IToBytes(v)), nil
+	case bigquery.IntegerFieldType:
+		i, err := value.IToInt(v)
+		return protoreflect.ValueOfInt64(i), err
+	case bigquery.FloatFieldType:
+		fl, err := value.IToFloat64(v)
+		return protoreflect.ValueOfFloat64(fl), err
+	case bigquery.BooleanFieldType:
+		b, err := value.IToBool(v)
+		return protoreflect.ValueOfBool(b), err
+	case bigquery.TimestampFieldType:
+		t, err := value.IGetTimestamp(v)
+		return protoreflect.ValueOfInt64(t.UnixMicro()), err
+	case bigquery.DateFieldType:
+		var t time.Time
+		var err error
+		if s, ok := v.(string); ok {
+			t, err = time.Parse(time.DateOnly, s)
+		} else {
+			t, err = value.IGetTimestamp(v)
+		}
+		if err != nil {
+			return protoreflect.Value{}, err
+		}
+		days := t.Unix() / 86400
+		if t.Unix()%86400 < 0 {
+			days--
+		}
+		return protoreflect.ValueOfInt32(int32(days)), nil
+	}
+	return protoreflect.ValueOfString(value.IToString(v)), nil
+}
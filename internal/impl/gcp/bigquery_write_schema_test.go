@@ -0,0 +1,86 @@
+package gcp
+
+import (
+	"testing"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+func TestBigQueryRowEncoder(t *testing.T) {
+	enc, err := newBQRowEncoder(bigquery.Schema{
+		{Name: "id", Type: bigquery.IntegerFieldType, Required: true},
+		{Name: "name", Type: bigquery.StringFieldType},
+		{Name: "ts", Type: bigquery.TimestampFieldType},
+		{Name: "day", Type: bigquery.DateFieldType},
+		{Name: "tags", Type: bigquery.StringFieldType, Repeated: true},
+		{Name: "attrs", Type: bigquery.JSONFieldType},
+		{Name: "user", Type: bigquery.RecordFieldType, Schema: bigquery.Schema{
+			{Name: "email", Type: bigquery.StringFieldType},
+			{Name: "score", Type: bigquery.FloatFieldType},
+		}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "Row", enc.descriptor.GetName())
+
+	data, err := enc.encode(map[string]any{
+		"id":    5.0,
+		"ts":    "2024-03-05T10:00:00Z",
+		"day":   "1969-12-31",
+		"tags":  []any{"a", "b"},
+		"attrs": map[string]any{"foo": "bar"},
+		"user":  map[string]any{"email": "foo@example.com", "score": 1.5},
+		"other": "ignored",
+	})
+	require.NoError(t, err)
+
+	msg := dynamicpb.NewMessage(enc.msgDesc)
+	require.NoError(t, proto.Unmarshal(data, msg))
+
+	fields := enc.msgDesc.Fields()
+	get := func(name string) protoreflect.Value {
+		return msg.Get(fields.ByName(protoreflect.Name(name)))
+	}
+	assert.Equal(t, int64(5), get("id").Int())
+	assert.False(t, msg.Has(fields.ByName("name")))
+	assert.Equal(t, time.Date(2024, 3, 5, 10, 0, 0, 0, time.UTC).UnixMicro(), get("ts").Int())
+	assert.Equal(t, int64(-1), get("day").Int())
+	assert.Equal(t, 2, get("tags").List().Len())
+	assert.Equal(t, `{"foo":"bar"}`, get("attrs").String())
+
+	user := get("user").Message()
+	assert.Equal(t, "foo@example.com", user.Get(user.Descriptor().Fields().ByName("email")).String())
+	assert.Equal(t, 1.5, user.Get(user.Descriptor().Fields().ByName("score")).Float())
+
+	_, err = enc.encode(map[string]any{"name": "foo"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "column id is required")
+
+	_, err = enc.encode([]any{})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "expected an object")
+}
+
+func TestBigQueryRowEncoderUnsupported(t *testing.T) {
+	_, err := newBQRowEncoder(bigquery.Schema{
+		{Name: "r", Type: bigquery.RangeFieldType},
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "column r")
+}
+
+func TestBigQueryChunkRows(t *testing.T) {
+	big := make([]byte, bqwaMaxRequestBytes/2+1)
+	chunks := chunkRows([][]byte{big, big, []byte("a"), big})
+	require.Len(t, chunks, 3)
+	assert.Len(t, chunks[0], 1)
+	assert.Len(t, chunks[1], 2)
+	assert.Len(t, chunks[2], 1)
+
+	assert.Empty(t, chunkRows(nil))
+}
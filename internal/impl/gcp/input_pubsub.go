@@ -3,8 +3,10 @@ package gcp
 import (
 	"context"
 	"errors"
+	"fmt"
 	"strings"
 	"sync"
+	"time"
 
 	"cloud.google.com/go/pubsub"
 	"google.golang.org/api/option"
@@ -20,9 +22,14 @@ const (
 	pbiFieldMaxOutstandingMessages = "max_outstanding_messages"
 	pbiFieldMaxOutstandingBytes    = "max_outstanding_bytes"
 	pbiFieldSync                   = "sync"
+	pbiFieldExactlyOnce            = "exactly_once"
+	pbiFieldMaxExtension           = "max_extension"
+	pbiFieldMinExtensionPeriod     = "min_extension_period"
+	pbiFieldMaxExtensionPeriod     = "max_extension_period"
 	pbiFieldCreateSub              = "create_subscription"
 	pbiFieldCreateSubEnabled       = "enabled"
 	pbiFieldCreateSubTopicID       = "topic"
+	pbiFieldCreateSubExactlyOnce   = "enable_exactly_once_delivery"
 )
 
 type pbiConfig struct {
@@ -32,8 +39,13 @@ type pbiConfig struct {
 	MaxOutstandingMessages int
 	MaxOutstandingBytes    int
 	Sync                   bool
+	ExactlyOnce            bool
+	MaxExtension           time.Duration
+	MinExtensionPeriod     time.Duration
+	MaxExtensionPeriod     time.Duration
 	CreateEnabled          bool
 	CreateTopicID          string
+	CreateExactlyOnce      bool
 }
 
 func pbiConfigFromParsed(pConf *service.ParsedConfig) (conf pbiConfig, err error) {
@@ -55,6 +67,18 @@ func pbiConfigFromParsed(pConf *service.ParsedConfig) (conf pbiConfig, err error
 	if conf.Sync, err = pConf.FieldBool(pbiFieldSync); err != nil {
 		return
 	}
+	if conf.ExactlyOnce, err = pConf.FieldBool(pbiFieldExactlyOnce); err != nil {
+		return
+	}
+	if conf.MaxExtension, err = pConf.FieldDuration(pbiFieldMaxExtension); err != nil {
+		return
+	}
+	if conf.MinExtensionPeriod, err = pConf.FieldDuration(pbiFieldMinExtensionPeriod); err != nil {
+		return
+	}
+	if conf.MaxExtensionPeriod, err = pConf.FieldDuration(pbiFieldMaxExtensionPeriod); err != nil {
+		return
+	}
 	if pConf.Contains(pbiFieldCreateSub) {
 		createConf := pConf.Namespace(pbiFieldCreateSub)
 		if conf.CreateEnabled, err = createConf.FieldBool(pbiFieldCreateSubEnabled); err != nil {
@@ -63,6 +87,9 @@ func pbiConfigFromParsed(pConf *service.ParsedConfig) (conf pbiConfig, err error
 		if conf.CreateTopicID, err = createConf.FieldString(pbiFieldCreateSubTopicID); err != nil {
 			return
 		}
+		if conf.CreateExactlyOnce, err = createConf.FieldBool(pbiFieldCreateSubExactlyOnce); err != nil {
+			return
+		}
 	}
 	return
 }
@@ -80,12 +107,20 @@ For information on how to set up credentials check out [this guide](https://clou
 This input adds the following metadata fields to each message:
 
 `+"``` text"+`
+- gcp_pubsub_message_id - The ID of the message assigned by the server.
 - gcp_pubsub_publish_time_unix - The time at which the message was published to the topic.
 - gcp_pubsub_delivery_attempt - When dead lettering is enabled, this is set to the number of times PubSub has attempted to deliver a message.
+- gcp_pubsub_ordering_key - When message ordering is enabled, this is set to the ordering key of the message.
 - All message attributes
 `+"```"+`
 
 You can access these metadata fields using [function interpolation](/docs/configuration/interpolation#bloblang-queries).
+
+### Exactly-Once Delivery
+
+When consuming from a subscription with [exactly-once delivery](https://cloud.google.com/pubsub/docs/exactly-once-delivery) enabled the field `+"`"+pbiFieldExactlyOnce+"`"+` should be set to `+"`true`"+`. Messages are only acknowledged once they have been delivered by the output, and the acknowledgement is then confirmed with the server. Acknowledgements that cannot be confirmed, such as when the ack deadline of a message has already expired, are reported as errors and the message will be redelivered.
+
+The ack deadline of messages is automatically extended whilst they are in flight, for up to the duration of `+"`"+pbiFieldMaxExtension+"`"+`. When consuming from exactly-once subscriptions this duration should be larger than the longest time it takes for a message to be delivered, otherwise messages will be redelivered whilst still in flight.
 `).
 		Fields(
 			service.NewStringField(pbiFieldProjectID).
@@ -100,6 +135,25 @@ You can access these metadata fields using [function interpolation](/docs/config
 			service.NewBoolField(pbiFieldSync).
 				Description("Enable synchronous pull mode.").
 				Default(false),
+			service.NewBoolField(pbiFieldExactlyOnce).
+				Description("Whether acknowledgements should be confirmed with the server before being considered successful, which is required in order to benefit from subscriptions with exactly-once delivery enabled.").
+				Version("4.28.0").
+				Default(false),
+			service.NewDurationField(pbiFieldMaxExtension).
+				Description("The maximum period for which the ack deadline of a message is automatically extended whilst it is in flight.").
+				Version("4.28.0").
+				Default("60m").
+				Advanced(),
+			service.NewDurationField(pbiFieldMinExtensionPeriod).
+				Description("The minimum duration of a single ack deadline extension, must be between 10s and 600s when set. A value of zero uses the client library defaults, which are 60s for exactly-once subscriptions.").
+				Version("4.28.0").
+				Default("0s").
+				Advanced(),
+			service.NewDurationField(pbiFieldMaxExtensionPeriod).
+				Description("The maximum duration of a single ack deadline extension, must be between 10s and 600s when set. A value of zero uses the client library defaults.").
+				Version("4.28.0").
+				Default("0s").
+				Advanced(),
 			service.NewIntField(pbiFieldMaxOutstandingMessages).
 				Description("The maximum number of outstanding pending messages to be consumed at a given time.").
 				Default(1000), // pubsub.DefaultReceiveSettings.MaxOutstandingMessages)
@@ -112,6 +166,10 @@ You can access these metadata fields using [function interpolation](/docs/config
 				service.NewStringField(pbiFieldCreateSubTopicID).
 					Description("Defines the topic that the subscription should be vinculated to.").
 					Default(""),
+				service.NewBoolField(pbiFieldCreateSubExactlyOnce).
+					Description("Whether the created subscription should have exactly-once delivery enabled.").
+					Version("4.28.0").
+					Default(false),
 			).
 				Description("Allows you to configure the input subscription and creates if it doesn't exist.").
 				Advanced(),
@@ -150,7 +208,10 @@ func createSubscription(conf pbiConfig, client *pubsub.Client, log *service.Logg
 	}
 
 	log.Infof("Creating subscription '%v' on topic '%v'\n", conf.SubscriptionID, conf.CreateTopicID)
-	_, err = client.CreateSubscription(context.Background(), conf.SubscriptionID, pubsub.SubscriptionConfig{Topic: client.Topic(conf.CreateTopicID)})
+	_, err = client.CreateSubscription(context.Background(), conf.SubscriptionID, pubsub.SubscriptionConfig{
+		Topic:                     client.Topic(conf.CreateTopicID),
+		EnableExactlyOnceDelivery: conf.CreateExactlyOnce,
+	})
 	if err != nil {
 		log.Errorf("Error creating subscription %v", err)
 	}
@@ -205,6 +266,9 @@ func (c *gcpPubSubReader) Connect(ignored context.Context) error {
 	sub.ReceiveSettings.MaxOutstandingMessages = c.conf.MaxOutstandingMessages
 	sub.ReceiveSettings.MaxOutstandingBytes = c.conf.MaxOutstandingBytes
 	sub.ReceiveSettings.Synchronous = c.conf.Sync
+	sub.ReceiveSettings.MaxExtension = c.conf.MaxExtension
+	sub.ReceiveSettings.MinExtensionPeriod = c.conf.MinExtensionPeriod
+	sub.ReceiveSettings.MaxExtensionPeriod = c.conf.MaxExtensionPeriod
 
 	subCtx, cancel := context.WithCancel(context.Background())
 	msgsChan := make(chan *pubsub.Message, 1)
@@ -259,11 +323,30 @@ func (c *gcpPubSubReader) Read(ctx context.Context) (*service.Message, service.A
 	for k, v := range gmsg.Attributes {
 		part.MetaSetMut(k, v)
 	}
+	part.MetaSetMut("gcp_pubsub_message_id", gmsg.ID)
 	part.MetaSetMut("gcp_pubsub_publish_time_unix", gmsg.PublishTime.Unix())
 
 	if gmsg.DeliveryAttempt != nil {
 		part.MetaSetMut("gcp_pubsub_delivery_attempt", *gmsg.DeliveryAttempt)
 	}
+	if gmsg.OrderingKey != "" {
+		part.MetaSetMut("gcp_pubsub_ordering_key", gmsg.OrderingKey)
+	}
+
+	if c.conf.ExactlyOnce {
+		return part, func(ctx context.Context, res error) error {
+			var ackRes *pubsub.AckResult
+			if res != nil {
+				ackRes = gmsg.NackWithResult()
+			} else {
+				ackRes = gmsg.AckWithResult()
+			}
+			if _, err := ackRes.Get(ctx); err != nil {
+				return fmt.Errorf("failed to confirm acknowledgement of message %v: %w", gmsg.ID, err)
+			}
+			return nil
+		}, nil
+	}
 
 	return part, func(ctx context.Context, res error) error {
 		if res != nil {
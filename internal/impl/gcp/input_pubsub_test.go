@@ -0,0 +1,49 @@
+package gcp
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPubSubInputConfigExactlyOnce(t *testing.T) {
+	pConf, err := pbiSpec().ParseYAML(`
+project: foo
+subscription: bar
+exactly_once: true
+max_extension: 10m
+min_extension_period: 30s
+create_subscription:
+  enabled: true
+  topic: baz
+  enable_exactly_once_delivery: true
+`, nil)
+	require.NoError(t, err)
+
+	conf, err := pbiConfigFromParsed(pConf)
+	require.NoError(t, err)
+
+	assert.True(t, conf.ExactlyOnce)
+	assert.Equal(t, 10*time.Minute, conf.MaxExtension)
+	assert.Equal(t, 30*time.Second, conf.MinExtensionPeriod)
+	assert.Equal(t, time.Duration(0), conf.MaxExtensionPeriod)
+	assert.True(t, conf.CreateEnabled)
+	assert.True(t, conf.CreateExactlyOnce)
+}
+
+func TestPubSubInputConfigDefaults(t *testing.T) {
+	pConf, err := pbiSpec().ParseYAML(`
+project: foo
+subscription: bar
+`, nil)
+	require.NoError(t, err)
+
+	conf, err := pbiConfigFromParsed(pConf)
+	require.NoError(t, err)
+
+	assert.False(t, conf.ExactlyOnce)
+	assert.Equal(t, 60*time.Minute, conf.MaxExtension)
+	assert.False(t, conf.CreateExactlyOnce)
+}
@@ -0,0 +1,391 @@
+package gcp
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"sync"
+
+	"cloud.google.com/go/bigquery"
+	"cloud.google.com/go/bigquery/storage/apiv1/storagepb"
+	"cloud.google.com/go/bigquery/storage/managedwriter"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	bqwaFieldProject    = "project"
+	bqwaFieldDataset    = "dataset"
+	bqwaFieldTable      = "table"
+	bqwaFieldStreamType = "stream_type"
+	bqwaFieldBatching   = "batching"
+
+	// The API rejects append requests larger than 10MB, and so we keep well
+	// below that.
+	bqwaMaxRequestBytes = 9 * 1024 * 1024
+)
+
+func bigQueryWriteAPIOutputConfig() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("GCP", "Services").
+		Version("4.28.0").
+		Summary("Writes messages to a BigQuery table using the Storage Write API.").
+		Description(`
+Messages are serialised as protobuf rows according to the schema of the table, which is read when connecting, and are streamed directly into the table. This avoids the load jobs used by the `+"`gcp_bigquery`"+` output, making rows available within seconds and without load job quotas.
+
+### Schema Mapping
+
+Messages must be objects, and each column of the table is populated from the field of the same name, other fields are ignored. Record columns are populated from nested objects and repeated columns from arrays. Timestamps and dates can be provided either as RFC 3339 strings or unix timestamps, and `+"`NUMERIC`, `BIGNUMERIC`, `DATETIME`, `TIME`, `GEOGRAPHY` and `JSON`"+` columns are sent as strings.
+
+### Stream Types
+
+- `+"`committed`"+`: Rows are written to an application created stream and are available as soon as each append succeeds. Each append is made at an explicit offset, and when an append is retried after an unknown outcome an offset that already exists is treated as a success, preventing duplicate rows for the lifetime of the stream.
+- `+"`pending`"+`: Each batch is written to its own stream that is committed atomically once all rows of the batch have been appended, so that either all or none of the rows of a batch become visible.
+- `+"`default`"+`: Rows are written to the default stream of the table, which provides at-least-once delivery with the highest throughput.`).
+		Fields(
+			service.NewStringField(bqwaFieldProject).
+				Description("The project ID of the dataset to insert data to. If not set, it will be inferred from the credentials or read from the GOOGLE_CLOUD_PROJECT environment variable.").
+				Default(""),
+			service.NewStringField(bqwaFieldDataset).
+				Description("The BigQuery Dataset ID."),
+			service.NewStringField(bqwaFieldTable).
+				Description("The table to insert messages to."),
+			service.NewStringEnumField(bqwaFieldStreamType, "committed", "pending", "default").
+				Description("The type of write stream to use.").
+				Default("committed"),
+			service.NewOutputMaxInFlightField().Default(1),
+			service.NewBatchPolicyField(bqwaFieldBatching),
+		).
+		Example("Atomic Batches", "Write each batch of messages atomically to a table:", `
+output:
+  gcp_bigquery_write_api:
+    project: my-project
+    dataset: analytics
+    table: events
+    stream_type: pending
+    batching:
+      count: 5000
+      period: 10s
+`)
+}
+
+func init() {
+	err := service.RegisterBatchOutput("gcp_bigquery_write_api", bigQueryWriteAPIOutputConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (out service.BatchOutput, batchPolicy service.BatchPolicy, maxInFlight int, err error) {
+			if batchPolicy, err = conf.FieldBatchPolicy(bqwaFieldBatching); err != nil {
+				return
+			}
+			if maxInFlight, err = conf.FieldMaxInFlight(); err != nil {
+				return
+			}
+			out, err = newBigQueryWriteAPIOutputFromParsed(conf, mgr)
+			return
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// bqwaUnknownAppend is an append to a committed stream that failed without a
+// known outcome, and may therefore have been written.
+type bqwaUnknownAppend struct {
+	fingerprint [32]byte
+	rows        int64
+}
+
+type bigQueryWriteAPIOutput struct {
+	log *service.Logger
+
+	projectID  string
+	datasetID  string
+	tableID    string
+	streamType managedwriter.StreamType
+
+	connMut     sync.Mutex
+	bqClient    *bigquery.Client
+	writeClient *managedwriter.Client
+	encoder     *bqRowEncoder
+	tableParent string
+
+	// The long lived stream of committed and default stream types.
+	stream  *managedwriter.ManagedStream
+	offset  int64
+	unknown *bqwaUnknownAppend
+}
+
+func newBigQueryWriteAPIOutputFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*bigQueryWriteAPIOutput, error) {
+	o := &bigQueryWriteAPIOutput{log: mgr.Logger()}
+
+	var err error
+	if o.projectID, err = conf.FieldString(bqwaFieldProject); err != nil {
+		return nil, err
+	}
+	if o.projectID == "" {
+		o.projectID = bigquery.DetectProjectID
+	}
+	if o.datasetID, err = conf.FieldString(bqwaFieldDataset); err != nil {
+		return nil, err
+	}
+	if o.tableID, err = conf.FieldString(bqwaFieldTable); err != nil {
+		return nil, err
+	}
+
+	streamType, err := conf.FieldString(bqwaFieldStreamType)
+	if err != nil {
+		return nil, err
+	}
+	switch streamType {
+	case "committed":
+		o.streamType = managedwriter.CommittedStream
+	case "pending":
+		o.streamType = managedwriter.PendingStream
+	case "default":
+		o.streamType = managedwriter.DefaultStream
+	default:
+		return nil, fmt.Errorf("stream type %v not recognised", streamType)
+	}
+	return o, nil
+}
+
+func (o *bigQueryWriteAPIOutput) newStream(ctx context.Context) (*managedwriter.ManagedStream, error) {
+	return o.writeClient.NewManagedStream(ctx,
+		managedwriter.WithDestinationTable(o.tableParent),
+		managedwriter.WithType(o.streamType),
+		managedwriter.WithSchemaDescriptor(o.encoder.descriptor),
+	)
+}
+
+func (o *bigQueryWriteAPIOutput) Connect(ctx context.Context) error {
+	o.connMut.Lock()
+	defer o.connMut.Unlock()
+
+	if o.writeClient != nil {
+		return nil
+	}
+
+	bqClient, err := bigquery.NewClient(context.Background(), o.projectID)
+	if err != nil {
+		return fmt.Errorf("error creating big query client: %w", err)
+	}
+
+	md, err := bqClient.Dataset(o.datasetID).Table(o.tableID).Metadata(ctx)
+	if err != nil {
+		_ = bqClient.Close()
+		return fmt.Errorf("failed to read table metadata: %w", err)
+	}
+	encoder, err := newBQRowEncoder(md.Schema)
+	if err != nil {
+		_ = bqClient.Close()
+		return err
+	}
+
+	writeClient, err := managedwriter.NewClient(context.Background(), bqClient.Project())
+	if err != nil {
+		_ = bqClient.Close()
+		return fmt.Errorf("error creating storage write client: %w", err)
+	}
+
+	o.bqClient = bqClient
+	o.writeClient = writeClient
+	o.encoder = encoder
+	o.tableParent = managedwriter.TableParentFromParts(bqClient.Project(), o.datasetID, o.tableID)
+
+	if o.streamType != managedwriter.PendingStream {
+		if o.stream, err = o.newStream(ctx); err != nil {
+			o.closeClients()
+			return fmt.Errorf("failed to create write stream: %w", err)
+		}
+		o.offset = 0
+		o.unknown = nil
+	}
+	return nil
+}
+
+// chunkRows splits rows into chunks that fit within a single append request.
+func chunkRows(rows [][]byte) (chunks [][][]byte) {
+	var size int
+	start := 0
+	for i, r := range rows {
+		if size+len(r) > bqwaMaxRequestBytes && i > start {
+			chunks = append(chunks, rows[start:i])
+			start, size = i, 0
+		}
+		size += len(r)
+	}
+	if start < len(rows) {
+		chunks = append(chunks, rows[start:])
+	}
+	return
+}
+
+func fingerprintRows(rows [][]byte) (fp [32]byte) {
+	h := sha256.New()
+	for _, r := range rows {
+		_, _ = h.Write(r)
+		_, _ = h.Write([]byte{0})
+	}
+	copy(fp[:], h.Sum(nil))
+	return
+}
+
+func appendAndWait(ctx context.Context, stream *managedwriter.ManagedStream, rows [][]byte, opts ...managedwriter.AppendOption) error {
+	res, err := stream.AppendRows(ctx, rows, opts...)
+	if err != nil {
+		return err
+	}
+	_, err = res.GetResult(ctx)
+	return err
+}
+
+// appendCommitted appends rows to the committed stream at the next offset.
+// When a previous append had an unknown outcome and the offset already exists
+// then that append was written, and so either these rows are a retry of it or
+// they belong after it.
+func (o *bigQueryWriteAPIOutput) appendCommitted(ctx context.Context, rows [][]byte) error {
+	fp := fingerprintRows(rows)
+	for {
+		err := appendAndWait(ctx, o.stream, rows, managedwriter.WithOffset(o.offset))
+		if err == nil {
+			o.offset += int64(len(rows))
+			o.unknown = nil
+			return nil
+		}
+
+		if status.Code(err) == codes.AlreadyExists && o.unknown != nil {
+			if o.unknown.fingerprint == fp {
+				o.log.Debugf("Rows at offset %v were already written", o.offset)
+				o.offset += int64(len(rows))
+				o.unknown = nil
+				return nil
+			}
+			o.offset += o.unknown.rows
+			o.unknown = nil
+			continue
+		}
+
+		if status.Code(err) == codes.AlreadyExists || status.Code(err) == codes.OutOfRange {
+			// The stream is not in the state we expect, and so we start a
+			// new one.
+			_ = o.stream.Close()
+			o.stream = nil
+			return fmt.Errorf("%w: unexpected stream offset: %v", service.ErrNotConnected, err)
+		}
+
+		o.unknown = &bqwaUnknownAppend{fingerprint: fp, rows: int64(len(rows))}
+		return err
+	}
+}
+
+// writePending writes rows to a new pending stream and commits it.
+func (o *bigQueryWriteAPIOutput) writePending(ctx context.Context, chunks [][][]byte) error {
+	stream, err := o.newStream(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to create write stream: %w", err)
+	}
+	defer stream.Close()
+
+	var offset int64
+	for _, rows := range chunks {
+		if err := appendAndWait(ctx, stream, rows, managedwriter.WithOffset(offset)); err != nil {
+			return err
+		}
+		offset += int64(len(rows))
+	}
+
+	if _, err := stream.Finalize(ctx); err != nil {
+		return fmt.Errorf("failed to finalise stream: %w", err)
+	}
+
+	res, err := o.writeClient.BatchCommitWriteStreams(ctx, &storagepb.BatchCommitWriteStreamsRequest{
+		Parent:       o.tableParent,
+		WriteStreams: []string{stream.StreamName()},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to commit stream: %w", err)
+	}
+	if errs := res.GetStreamErrors(); len(errs) > 0 {
+		return fmt.Errorf("failed to commit stream: %v", errs[0].GetErrorMessage())
+	}
+	return nil
+}
+
+func (o *bigQueryWriteAPIOutput) WriteBatch(ctx context.Context, batch service.MessageBatch) error {
+	o.connMut.Lock()
+	defer o.connMut.Unlock()
+
+	if o.writeClient == nil {
+		return service.ErrNotConnected
+	}
+
+	rows := make([][]byte, len(batch))
+	for i, msg := range batch {
+		structured, err := msg.AsStructured()
+		if err != nil {
+			return fmt.Errorf("message %v: %w", i, err)
+		}
+		if rows[i], err = o.encoder.encode(structured); err != nil {
+			return fmt.Errorf("message %v: %w", i, err)
+		}
+	}
+	chunks := chunkRows(rows)
+
+	if o.streamType == managedwriter.PendingStream {
+		return o.writePending(ctx, chunks)
+	}
+
+	if o.stream == nil {
+		var err error
+		if o.stream, err = o.newStream(ctx); err != nil {
+			return fmt.Errorf("failed to create write stream: %w", err)
+		}
+		o.offset = 0
+		o.unknown = nil
+	}
+	for _, c := range chunks {
+		var err error
+		if o.streamType == managedwriter.DefaultStream {
+			err = appendAndWait(ctx, o.stream, c)
+		} else {
+			err = o.appendCommitted(ctx, c)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (o *bigQueryWriteAPIOutput) closeClients() {
+	if o.stream != nil {
+		if err := o.stream.Close(); err != nil {
+			o.log.Debugf("Failed to close write stream: %v", err)
+		}
+		o.stream = nil
+	}
+	if o.writeClient != nil {
+		_ = o.writeClient.Close()
+		o.writeClient = nil
+	}
+	if o.bqClient != nil {
+		_ = o.bqClient.Close()
+		o.bqClient = nil
+	}
+}
+
+func (o *bigQueryWriteAPIOutput) Close(ctx context.Context) error {
+	o.connMut.Lock()
+	defer o.connMut.Unlock()
+
+	if o.stream != nil && o.streamType == managedwriter.CommittedStream {
+		if _, err := o.stream.Finalize(ctx); err != nil {
+			o.log.Debugf("Failed to finalise write stream: %v", err)
+		}
+	}
+	o.closeClients()
+	return nil
+}
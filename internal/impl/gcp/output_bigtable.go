@@ -0,0 +1,381 @@
+package gcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"cloud.google.com/go/bigtable"
+	"google.golang.org/api/option"
+
+	"github.com/benthosdev/benthos/v4/internal/value"
+	"github.com/benthosdev/benthos/v4/public/bloblang"
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	// Bigtable Output Fields
+	btoFieldProject    = "project"
+	btoFieldInstance   = "instance"
+	btoFieldTable      = "table"
+	btoFieldAppProfile = "app_profile"
+	btoFieldEndpoint   = "endpoint"
+	btoFieldRowKey     = "row_key"
+	btoFieldFamily     = "family"
+	btoFieldColumns    = "columns_mapping"
+	btoFieldTimestamp  = "timestamp_mapping"
+	btoFieldBatching   = "batching"
+)
+
+func newBigtableOutputConfig() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Services", "GCP").
+		Version("4.28.0").
+		Summary("Writes messages as rows to a Google Cloud Bigtable table.").
+		Description(`
+Each message is written as a row identified by the `+"`row_key`"+`, with cells resulting from the `+"`columns_mapping`"+`. When a `+"`family`"+` is set the mapping must result in an object of column qualifiers to values, otherwise it must result in an object of column families, each containing an object of column qualifiers to values. Cells with a null value are skipped.
+
+String values are written as they are, whereas all other values are written as their JSON representation.
+
+Messages of a batch are written with a single MutateRows request, and when only some rows fail to be written only the messages of those rows are reattempted.
+
+### Timestamps
+
+By default the timestamp of each cell is assigned by Bigtable, which means replaying a message creates new versions of its cells. When a `+"`timestamp_mapping`"+` is set cells are written with the resulting timestamp instead, truncated to milliseconds, and therefore rewriting the same message overwrites the same cell versions, making replays idempotent.
+
+For information on how to set up credentials check out [this guide](https://cloud.google.com/docs/authentication/production).`+service.OutputPerformanceDocs(true, true)).
+		Fields(
+			service.NewStringField(btoFieldProject).
+				Description("The project ID of the Bigtable instance."),
+			service.NewStringField(btoFieldInstance).
+				Description("The Bigtable instance to write to."),
+			service.NewStringField(btoFieldTable).
+				Description("The table to write rows to."),
+			service.NewStringField(btoFieldAppProfile).
+				Description("An optional app profile to route requests with.").
+				Default("").
+				Advanced(),
+			service.NewStringField(btoFieldEndpoint).
+				Description("An optional endpoint to override the default Bigtable data API endpoint.").
+				Default("").
+				Advanced(),
+			service.NewInterpolatedStringField(btoFieldRowKey).
+				Description("The key of the row to write each message to.").
+				Example(`${! json("user_id") }#${! json("event_id") }`),
+			service.NewStringField(btoFieldFamily).
+				Description("An optional column family that all columns resulting from the `"+btoFieldColumns+"` are written to.").
+				Optional(),
+			service.NewBloblangField(btoFieldColumns).
+				Description("A [Bloblang mapping](/docs/guides/bloblang/about) that results in the columns of each row. When a `"+btoFieldFamily+"` is set the result must be an object of column qualifiers to values, otherwise an object of column families to objects of column qualifiers to values.").
+				Example(`root.stats.count = this.count
+root.stats.total = this.total
+root.info.name = this.name`).
+				Default("root = this"),
+			service.NewBloblangField(btoFieldTimestamp).
+				Description("An optional [Bloblang mapping](/docs/guides/bloblang/about) that results in the timestamp of the cells of each row, either as a timestamp, a string in RFC 3339 format or a unix timestamp. When omitted the timestamp is assigned by Bigtable.").
+				Example(`root = this.event_time`).
+				Optional(),
+			service.NewOutputMaxInFlightField(),
+			service.NewBatchPolicyField(btoFieldBatching),
+		).
+		Example("Events by User", "Write events to the family `events` of a row for each user and event, where the event time is used as the cell timestamp so that events can be replayed safely.", `
+output:
+  gcp_bigtable:
+    project: my-project
+    instance: my-instance
+    table: user_events
+    row_key: ${! json("user_id") }#${! json("event_id") }
+    family: events
+    columns_mapping: |
+      root.type = this.type
+      root.payload = this.payload
+    timestamp_mapping: root = this.created_at
+`)
+}
+
+func init() {
+	err := service.RegisterBatchOutput("gcp_bigtable", newBigtableOutputConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (out service.BatchOutput, batchPolicy service.BatchPolicy, maxInFlight int, err error) {
+			if maxInFlight, err = conf.FieldMaxInFlight(); err != nil {
+				return
+			}
+			if batchPolicy, err = conf.FieldBatchPolicy(btoFieldBatching); err != nil {
+				return
+			}
+			out, err = newBigtableOutput(conf, mgr)
+			return
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type bigtableTable interface {
+	ApplyBulk(ctx context.Context, rowKeys []string, muts []*bigtable.Mutation, opts ...bigtable.ApplyOption) ([]error, error)
+}
+
+// bigtableCell is a single value to set within a row.
+type bigtableCell struct {
+	family    string
+	qualifier string
+	value     []byte
+	timestamp bigtable.Timestamp
+}
+
+type bigtableOutput struct {
+	project    string
+	instance   string
+	tableName  string
+	appProfile string
+	clientOpts []option.ClientOption
+
+	rowKey    *service.InterpolatedString
+	family    string
+	columns   *bloblang.Executor
+	timestamp *bloblang.Executor
+
+	log *service.Logger
+
+	connMut sync.Mutex
+	client  *bigtable.Client
+	table   bigtableTable
+}
+
+func newBigtableOutput(conf *service.ParsedConfig, mgr *service.Resources) (*bigtableOutput, error) {
+	b := &bigtableOutput{log: mgr.Logger()}
+
+	var err error
+	if b.project, err = conf.FieldString(btoFieldProject); err != nil {
+		return nil, err
+	}
+	if b.instance, err = conf.FieldString(btoFieldInstance); err != nil {
+		return nil, err
+	}
+	if b.tableName, err = conf.FieldString(btoFieldTable); err != nil {
+		return nil, err
+	}
+	if b.appProfile, err = conf.FieldString(btoFieldAppProfile); err != nil {
+		return nil, err
+	}
+
+	var endpoint string
+	if endpoint, err = conf.FieldString(btoFieldEndpoint); err != nil {
+		return nil, err
+	}
+	if endpoint != "" {
+		b.clientOpts = append(b.clientOpts, option.WithEndpoint(endpoint))
+	}
+
+	if b.rowKey, err = conf.FieldInterpolatedString(btoFieldRowKey); err != nil {
+		return nil, err
+	}
+	if conf.Contains(btoFieldFamily) {
+		if b.family, err = conf.FieldString(btoFieldFamily); err != nil {
+			return nil, err
+		}
+	}
+	if b.columns, err = conf.FieldBloblang(btoFieldColumns); err != nil {
+		return nil, err
+	}
+	if conf.Contains(btoFieldTimestamp) {
+		if b.timestamp, err = conf.FieldBloblang(btoFieldTimestamp); err != nil {
+			return nil, err
+		}
+	}
+	return b, nil
+}
+
+func (b *bigtableOutput) Connect(ctx context.Context) error {
+	b.connMut.Lock()
+	defer b.connMut.Unlock()
+
+	if b.table != nil {
+		return nil
+	}
+
+	client, err := bigtable.NewClientWithConfig(context.Background(), b.project, b.instance, bigtable.ClientConfig{
+		AppProfile: b.appProfile,
+	}, b.clientOpts...)
+	if err != nil {
+		return fmt.Errorf("failed to create bigtable client: %w", err)
+	}
+
+	b.client = client
+	b.table = client.Open(b.tableName)
+	return nil
+}
+
+func bigtableCellValue(v any) ([]byte, error) {
+	switch t := v.(type) {
+	case []byte:
+		return t, nil
+	case string:
+		return []byte(t), nil
+	}
+	return json.Marshal(v)
+}
+
+// rowCells resolves the row key and cells of a message.
+func (b *bigtableOutput) rowCells(batch service.MessageBatch, i int) (string, []bigtableCell, error) {
+	rowKey, err := batch.TryInterpolatedString(i, b.rowKey)
+	if err != nil {
+		return "", nil, fmt.Errorf("row key interpolation error: %w", err)
+	}
+	if rowKey == "" {
+		return "", nil, errors.New("row key must not be empty")
+	}
+
+	ts := bigtable.ServerTime
+	if b.timestamp != nil {
+		tsMsg, err := batch.BloblangQuery(i, b.timestamp)
+		if err != nil {
+			return "", nil, fmt.Errorf("timestamp mapping failed: %w", err)
+		}
+		var tsV any
+		if tsMsg != nil {
+			if tsV, err = tsMsg.AsStructured(); err != nil {
+				if tsBytes, _ := tsMsg.AsBytes(); len(tsBytes) > 0 {
+					tsV = string(tsBytes)
+					err = nil
+				}
+			}
+			if err != nil {
+				return "", nil, fmt.Errorf("timestamp mapping failed: %w", err)
+			}
+		}
+		if tsV != nil {
+			t, err := value.IGetTimestamp(tsV)
+			if err != nil {
+				return "", nil, fmt.Errorf("timestamp mapping failed: %w", err)
+			}
+			ts = bigtable.Time(t).TruncateToMilliseconds()
+		}
+	}
+
+	colsMsg, err := batch.BloblangQuery(i, b.columns)
+	if err != nil {
+		return "", nil, fmt.Errorf("columns mapping failed: %w", err)
+	}
+	var colsV any
+	if colsMsg != nil {
+		if colsV, err = colsMsg.AsStructured(); err != nil {
+			return "", nil, fmt.Errorf("columns mapping failed: %w", err)
+		}
+	}
+	colsObj, ok := colsV.(map[string]any)
+	if !ok {
+		return "", nil, fmt.Errorf("expected columns mapping to result in an object, got %T", colsV)
+	}
+
+	families := colsObj
+	if b.family != "" {
+		families = map[string]any{b.family: colsObj}
+	}
+
+	var cells []bigtableCell
+	for family, qualifiersV := range families {
+		qualifiers, ok := qualifiersV.(map[string]any)
+		if !ok {
+			return "", nil, fmt.Errorf("expected column family %v to be an object, got %T", family, qualifiersV)
+		}
+		for qualifier, v := range qualifiers {
+			if v == nil {
+				continue
+			}
+			cellValue, err := bigtableCellValue(v)
+			if err != nil {
+				return "", nil, fmt.Errorf("column %v:%v: %w", family, qualifier, err)
+			}
+			cells = append(cells, bigtableCell{
+				family:    family,
+				qualifier: qualifier,
+				value:     cellValue,
+				timestamp: ts,
+			})
+		}
+	}
+	if len(cells) == 0 {
+		return "", nil, errors.New("row must have at least one column")
+	}
+
+	sort.Slice(cells, func(i, j int) bool {
+		if cells[i].family == cells[j].family {
+			return cells[i].qualifier < cells[j].qualifier
+		}
+		return cells[i].family < cells[j].family
+	})
+	return rowKey, cells, nil
+}
+
+func (b *bigtableOutput) WriteBatch(ctx context.Context, batch service.MessageBatch) error {
+	b.connMut.Lock()
+	table := b.table
+	b.connMut.Unlock()
+
+	if table == nil {
+		return service.ErrNotConnected
+	}
+
+	var batchErr *service.BatchError
+	batchErrFailed := func(i int, err error) {
+		if batchErr == nil {
+			batchErr = service.NewBatchError(batch, err)
+		}
+		batchErr.Failed(i, err)
+	}
+
+	indexes := make([]int, 0, len(batch))
+	rowKeys := make([]string, 0, len(batch))
+	muts := make([]*bigtable.Mutation, 0, len(batch))
+	for i := range batch {
+		rowKey, cells, err := b.rowCells(batch, i)
+		if err != nil {
+			b.log.Errorf("Failed to prepare bigtable row: %v", err)
+			batchErrFailed(i, err)
+			continue
+		}
+
+		mut := bigtable.NewMutation()
+		for _, c := range cells {
+			mut.Set(c.family, c.qualifier, c.timestamp, c.value)
+		}
+
+		indexes = append(indexes, i)
+		rowKeys = append(rowKeys, rowKey)
+		muts = append(muts, mut)
+	}
+
+	if len(rowKeys) > 0 {
+		rowErrs, err := table.ApplyBulk(ctx, rowKeys, muts)
+		if err != nil {
+			return fmt.Errorf("failed to write rows: %w", err)
+		}
+		for j, rowErr := range rowErrs {
+			if rowErr != nil && j < len(indexes) {
+				batchErrFailed(indexes[j], fmt.Errorf("failed to write row %v: %w", rowKeys[j], rowErr))
+			}
+		}
+	}
+
+	if batchErr != nil {
+		return batchErr
+	}
+	return nil
+}
+
+func (b *bigtableOutput) Close(ctx context.Context) error {
+	b.connMut.Lock()
+	defer b.connMut.Unlock()
+
+	var err error
+	if b.client != nil {
+		err = b.client.Close()
+		b.client = nil
+	}
+	b.table = nil
+	return err
+}
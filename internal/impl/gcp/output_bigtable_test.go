@@ -0,0 +1,152 @@
+package gcp
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/bigtable"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+type mockBigtableTable struct {
+	fn func(rowKeys []string, muts []*bigtable.Mutation) ([]error, error)
+}
+
+func (m *mockBigtableTable) ApplyBulk(ctx context.Context, rowKeys []string, muts []*bigtable.Mutation, opts ...bigtable.ApplyOption) ([]error, error) {
+	return m.fn(rowKeys, muts)
+}
+
+func testBigtableOutput(t *testing.T, conf string) *bigtableOutput {
+	t.Helper()
+
+	pConf, err := newBigtableOutputConfig().ParseYAML(conf, nil)
+	require.NoError(t, err)
+
+	out, err := newBigtableOutput(pConf, service.MockResources())
+	require.NoError(t, err)
+	return out
+}
+
+func TestBigtableRowCells(t *testing.T) {
+	out := testBigtableOutput(t, `
+project: foo
+instance: bar
+table: baz
+row_key: ${! json("id") }
+columns_mapping: |
+  root.info.name = this.name
+  root.info.missing = null
+  root.stats.count = this.count
+  root.stats.tags = this.tags
+timestamp_mapping: root = this.ts
+`)
+
+	batch := service.MessageBatch{
+		service.NewMessage([]byte(`{"id":"a","name":"alice","count":3,"tags":["x"],"ts":"2024-01-02T03:04:05.123456Z"}`)),
+	}
+
+	rowKey, cells, err := out.rowCells(batch, 0)
+	require.NoError(t, err)
+	assert.Equal(t, "a", rowKey)
+
+	ts := bigtable.Time(time.Date(2024, 1, 2, 3, 4, 5, 123000000, time.UTC))
+	assert.Equal(t, []bigtableCell{
+		{family: "info", qualifier: "name", value: []byte("alice"), timestamp: ts},
+		{family: "stats", qualifier: "count", value: []byte("3"), timestamp: ts},
+		{family: "stats", qualifier: "tags", value: []byte(`["x"]`), timestamp: ts},
+	}, cells)
+}
+
+func TestBigtableRowCellsSingleFamily(t *testing.T) {
+	out := testBigtableOutput(t, `
+project: foo
+instance: bar
+table: baz
+row_key: ${! json("id") }
+family: cf
+`)
+
+	batch := service.MessageBatch{
+		service.NewMessage([]byte(`{"id":"a","name":"alice"}`)),
+		service.NewMessage([]byte(`not json`)),
+		service.NewMessage([]byte(`["not","an","object"]`)),
+	}
+
+	rowKey, cells, err := out.rowCells(batch, 0)
+	require.NoError(t, err)
+	assert.Equal(t, "a", rowKey)
+	assert.Equal(t, []bigtableCell{
+		{family: "cf", qualifier: "id", value: []byte("a"), timestamp: bigtable.ServerTime},
+		{family: "cf", qualifier: "name", value: []byte("alice"), timestamp: bigtable.ServerTime},
+	}, cells)
+
+	_, _, err = out.rowCells(batch, 1)
+	require.Error(t, err)
+
+	_, _, err = out.rowCells(batch, 2)
+	require.Error(t, err)
+}
+
+func TestBigtableWriteBatchRowErrors(t *testing.T) {
+	out := testBigtableOutput(t, `
+project: foo
+instance: bar
+table: baz
+row_key: ${! json("id") }
+family: cf
+`)
+
+	var writtenKeys []string
+	out.table = &mockBigtableTable{
+		fn: func(rowKeys []string, muts []*bigtable.Mutation) ([]error, error) {
+			writtenKeys = append(writtenKeys, rowKeys...)
+			require.Len(t, muts, len(rowKeys))
+
+			errs := make([]error, len(rowKeys))
+			for i, k := range rowKeys {
+				if k == "c" {
+					errs[i] = errors.New("nope")
+				}
+			}
+			return errs, nil
+		},
+	}
+
+	batch := service.MessageBatch{
+		service.NewMessage([]byte(`{"id":"a","v":1}`)),
+		service.NewMessage([]byte(`not json`)),
+		service.NewMessage([]byte(`{"id":"c","v":3}`)),
+		service.NewMessage([]byte(`{"id":"d","v":4}`)),
+	}
+
+	idx := batch.Index()
+	err := out.WriteBatch(context.Background(), batch)
+	require.Error(t, err)
+	assert.Equal(t, []string{"a", "c", "d"}, writtenKeys)
+
+	var bErr *service.BatchError
+	require.True(t, errors.As(err, &bErr))
+
+	var failed []int
+	bErr.WalkMessagesIndexedBy(idx, func(i int, _ *service.Message, err error) bool {
+		if err != nil {
+			failed = append(failed, i)
+		}
+		return true
+	})
+	sort.Ints(failed)
+	assert.Equal(t, []int{1, 2}, failed)
+
+	// A successful write returns no error.
+	writtenKeys = nil
+	require.NoError(t, out.WriteBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte(`{"id":"a","v":1}`)),
+	}))
+	assert.Equal(t, []string{"a"}, writtenKeys)
+}
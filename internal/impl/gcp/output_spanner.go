@@ -0,0 +1,487 @@
+package gcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"cloud.google.com/go/spanner"
+	"google.golang.org/api/option"
+
+	"github.com/benthosdev/benthos/v4/public/bloblang"
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	// Spanner Output Fields
+	spoFieldProject              = "project"
+	spoFieldInstance             = "instance"
+	spoFieldDatabase             = "database"
+	spoFieldEndpoint             = "endpoint"
+	spoFieldMode                 = "mode"
+	spoFieldTable                = "table"
+	spoFieldOperation            = "operation"
+	spoFieldColumns              = "columns_mapping"
+	spoFieldPrimaryKey           = "primary_key"
+	spoFieldStatement            = "statement"
+	spoFieldArgs                 = "args_mapping"
+	spoFieldSessionPool          = "session_pool"
+	spoFieldSessionPoolMinOpened = "min_opened"
+	spoFieldSessionPoolMaxOpened = "max_opened"
+	spoFieldSessionPoolMaxIdle   = "max_idle"
+	spoFieldBatching             = "batching"
+
+	spannerModeMutation = "mutation"
+	spannerModeDML      = "dml"
+)
+
+func newSpannerOutputConfig() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Services", "GCP").
+		Version("4.28.0").
+		Summary("Writes messages to a Google Cloud Spanner database with mutations or DML statements.").
+		Description(`
+Each batch of messages is written within a single commit, and therefore either all messages of a batch are written or none are. Spanner limits the number of mutations of a commit, where each column of a row counts as one mutation, and so the batching policy of this output should be configured to stay within [those limits](https://cloud.google.com/spanner/quotas#limits-for-creating-reading-updating-and-deleting-data).
+
+### Mutations
+
+In the default `+"`mutation`"+` mode each message is converted into a mutation of the `+"`table`"+` with the columns resulting from the `+"`columns_mapping`"+`. The `+"`operation`"+` of each mutation can be set dynamically, which allows change streams to be applied by mapping the type of each change to an operation. Delete operations identify the row to delete by the values of the `+"`primary_key`"+` columns.
+
+### DML
+
+In the `+"`dml`"+` mode each message is converted into an execution of the parameterised `+"`statement`"+` with the parameters resulting from the `+"`args_mapping`"+`, and the statements of a batch are executed with a single batch update within a read-write transaction.
+
+### Types
+
+Values are converted to Spanner types as follows: integers are written as INT64, other numbers as FLOAT64, objects and arrays as JSON, and timestamps as TIMESTAMP. Use a mapping in order to convert values to other types, for example `+"`this.created_at.ts_parse(\"2006-01-02\")`"+` for timestamp columns.
+
+For information on how to set up credentials check out [this guide](https://cloud.google.com/docs/authentication/production).`+service.OutputPerformanceDocs(true, true)).
+		Fields(
+			service.NewStringField(spoFieldProject).
+				Description("The project ID of the Spanner instance."),
+			service.NewStringField(spoFieldInstance).
+				Description("The Spanner instance of the database."),
+			service.NewStringField(spoFieldDatabase).
+				Description("The database to write to."),
+			service.NewStringField(spoFieldEndpoint).
+				Description("An optional endpoint to override the default Spanner API endpoint.").
+				Default("").
+				Advanced(),
+			service.NewStringEnumField(spoFieldMode, spannerModeMutation, spannerModeDML).
+				Description("Whether to write messages as mutations or by executing DML statements.").
+				Default(spannerModeMutation),
+			service.NewStringField(spoFieldTable).
+				Description("The table to write mutations to. Required in `mutation` mode.").
+				Default(""),
+			service.NewInterpolatedStringField(spoFieldOperation).
+				Description("The operation of each mutation, which must resolve to one of `insert`, `update`, `insert_or_update`, `replace` or `delete`.").
+				Example(`${! meta("operation") }`).
+				Default("insert_or_update"),
+			service.NewBloblangField(spoFieldColumns).
+				Description("A [Bloblang mapping](/docs/guides/bloblang/about) that results in an object of the columns of each mutation.").
+				Example(`root.id = this.id
+root.name = this.user.name
+root.updated_at = now().ts_parse("2006-01-02T15:04:05Z07:00")`).
+				Default("root = this"),
+			service.NewStringListField(spoFieldPrimaryKey).
+				Description("The primary key columns of the table in order, used to identify the rows of delete operations.").
+				Example([]string{"id"}).
+				Default([]any{}),
+			service.NewStringField(spoFieldStatement).
+				Description("The DML statement to execute for each message in `dml` mode, with parameters in the form `@name`.").
+				Example("UPDATE Accounts SET balance = balance + @amount WHERE id = @id").
+				Default(""),
+			service.NewBloblangField(spoFieldArgs).
+				Description("A [Bloblang mapping](/docs/guides/bloblang/about) that results in an object of the parameters of each statement in `dml` mode.").
+				Example(`root.id = this.account_id
+root.amount = this.amount`).
+				Optional(),
+			service.NewObjectField(spoFieldSessionPool,
+				service.NewIntField(spoFieldSessionPoolMinOpened).
+					Description("The minimum number of sessions to keep open.").
+					Default(int(spanner.DefaultSessionPoolConfig.MinOpened)),
+				service.NewIntField(spoFieldSessionPoolMaxOpened).
+					Description("The maximum number of sessions that can be opened.").
+					Default(int(spanner.DefaultSessionPoolConfig.MaxOpened)),
+				service.NewIntField(spoFieldSessionPoolMaxIdle).
+					Description("The maximum number of idle sessions to keep open.").
+					Default(int(spanner.DefaultSessionPoolConfig.MaxIdle)),
+			).
+				Description("Tune the pool of sessions used by the client.").
+				Advanced(),
+			service.NewOutputMaxInFlightField(),
+			service.NewBatchPolicyField(spoFieldBatching),
+		).
+		LintRule(`root = if this.mode.or("mutation") == "mutation" && this.table.or("") == "" {
+  [ "a table must be set in mutation mode" ]
+} else if this.mode.or("mutation") == "dml" && this.statement.or("") == "" {
+  [ "a statement must be set in dml mode" ]
+}`).
+		Example("Apply Change Events", "Apply change events to a table, where each event contains an operation and the row that changed.", `
+output:
+  gcp_spanner:
+    project: my-project
+    instance: my-instance
+    database: my-database
+    table: Users
+    primary_key: [ UserId ]
+    operation: ${! json("op") }
+    columns_mapping: root = this.row
+    batching:
+      count: 100
+      period: 1s
+`).
+		Example("DML Statements", "Increment balances by executing a DML statement for each message.", `
+output:
+  gcp_spanner:
+    project: my-project
+    instance: my-instance
+    database: my-database
+    mode: dml
+    statement: UPDATE Accounts SET Balance = Balance + @amount WHERE AccountId = @id
+    args_mapping: |
+      root.id = this.account_id
+      root.amount = this.amount
+`)
+}
+
+func init() {
+	err := service.RegisterBatchOutput("gcp_spanner", newSpannerOutputConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (out service.BatchOutput, batchPolicy service.BatchPolicy, maxInFlight int, err error) {
+			if maxInFlight, err = conf.FieldMaxInFlight(); err != nil {
+				return
+			}
+			if batchPolicy, err = conf.FieldBatchPolicy(spoFieldBatching); err != nil {
+				return
+			}
+			out, err = newSpannerOutput(conf, mgr)
+			return
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+// spannerDB abstracts the operations performed against a Spanner database.
+type spannerDB interface {
+	Apply(ctx context.Context, muts []*spanner.Mutation) error
+	BatchUpdate(ctx context.Context, stmts []spanner.Statement) error
+	Close()
+}
+
+type spannerClientDB struct {
+	c *spanner.Client
+}
+
+func (s *spannerClientDB) Apply(ctx context.Context, muts []*spanner.Mutation) error {
+	_, err := s.c.Apply(ctx, muts)
+	return err
+}
+
+func (s *spannerClientDB) BatchUpdate(ctx context.Context, stmts []spanner.Statement) error {
+	_, err := s.c.ReadWriteTransaction(ctx, func(ctx context.Context, txn *spanner.ReadWriteTransaction) error {
+		_, err := txn.BatchUpdate(ctx, stmts)
+		return err
+	})
+	return err
+}
+
+func (s *spannerClientDB) Close() {
+	s.c.Close()
+}
+
+// spannerRow is a row mutation resolved from a message.
+type spannerRow struct {
+	operation string
+	columns   map[string]any
+}
+
+type spannerOutput struct {
+	database   string
+	clientOpts []option.ClientOption
+	poolConf   spanner.SessionPoolConfig
+
+	mode       string
+	table      string
+	operation  *service.InterpolatedString
+	columns    *bloblang.Executor
+	primaryKey []string
+	statement  string
+	args       *bloblang.Executor
+
+	log *service.Logger
+
+	connMut sync.Mutex
+	db      spannerDB
+}
+
+func newSpannerOutput(conf *service.ParsedConfig, mgr *service.Resources) (*spannerOutput, error) {
+	s := &spannerOutput{log: mgr.Logger()}
+
+	project, err := conf.FieldString(spoFieldProject)
+	if err != nil {
+		return nil, err
+	}
+	instance, err := conf.FieldString(spoFieldInstance)
+	if err != nil {
+		return nil, err
+	}
+	database, err := conf.FieldString(spoFieldDatabase)
+	if err != nil {
+		return nil, err
+	}
+	s.database = fmt.Sprintf("projects/%v/instances/%v/databases/%v", project, instance, database)
+
+	endpoint, err := conf.FieldString(spoFieldEndpoint)
+	if err != nil {
+		return nil, err
+	}
+	if endpoint != "" {
+		s.clientOpts = append(s.clientOpts, option.WithEndpoint(endpoint))
+	}
+
+	s.poolConf = spanner.DefaultSessionPoolConfig
+	poolConf := conf.Namespace(spoFieldSessionPool)
+	for _, f := range []struct {
+		name   string
+		target *uint64
+	}{
+		{spoFieldSessionPoolMinOpened, &s.poolConf.MinOpened},
+		{spoFieldSessionPoolMaxOpened, &s.poolConf.MaxOpened},
+		{spoFieldSessionPoolMaxIdle, &s.poolConf.MaxIdle},
+	} {
+		v, err := poolConf.FieldInt(f.name)
+		if err != nil {
+			return nil, err
+		}
+		if v < 0 {
+			return nil, fmt.Errorf("field %v.%v must not be negative", spoFieldSessionPool, f.name)
+		}
+		*f.target = uint64(v)
+	}
+
+	if s.mode, err = conf.FieldString(spoFieldMode); err != nil {
+		return nil, err
+	}
+	if s.table, err = conf.FieldString(spoFieldTable); err != nil {
+		return nil, err
+	}
+	if s.operation, err = conf.FieldInterpolatedString(spoFieldOperation); err != nil {
+		return nil, err
+	}
+	if s.columns, err = conf.FieldBloblang(spoFieldColumns); err != nil {
+		return nil, err
+	}
+	if s.primaryKey, err = conf.FieldStringList(spoFieldPrimaryKey); err != nil {
+		return nil, err
+	}
+	if s.statement, err = conf.FieldString(spoFieldStatement); err != nil {
+		return nil, err
+	}
+	if conf.Contains(spoFieldArgs) {
+		if s.args, err = conf.FieldBloblang(spoFieldArgs); err != nil {
+			return nil, err
+		}
+	}
+
+	switch s.mode {
+	case spannerModeMutation:
+		if s.table == "" {
+			return nil, errors.New("a table must be set in mutation mode")
+		}
+	case spannerModeDML:
+		if s.statement == "" {
+			return nil, errors.New("a statement must be set in dml mode")
+		}
+	default:
+		return nil, fmt.Errorf("unrecognised mode: %v", s.mode)
+	}
+	return s, nil
+}
+
+func (s *spannerOutput) Connect(ctx context.Context) error {
+	s.connMut.Lock()
+	defer s.connMut.Unlock()
+
+	if s.db != nil {
+		return nil
+	}
+
+	client, err := spanner.NewClientWithConfig(context.Background(), s.database, spanner.ClientConfig{
+		SessionPoolConfig: s.poolConf,
+	}, s.clientOpts...)
+	if err != nil {
+		return fmt.Errorf("failed to create spanner client: %w", err)
+	}
+
+	s.db = &spannerClientDB{c: client}
+	return nil
+}
+
+// spannerValue converts a value resulting from a mapping into a type supported
+// by the Spanner client.
+func spannerValue(v any) (any, error) {
+	switch t := v.(type) {
+	case json.Number:
+		if i, err := t.Int64(); err == nil {
+			return i, nil
+		}
+		return t.Float64()
+	case int:
+		return int64(t), nil
+	case int32:
+		return int64(t), nil
+	case uint32:
+		return int64(t), nil
+	case uint64:
+		return int64(t), nil
+	case float32:
+		return float64(t), nil
+	case map[string]any, []any:
+		return spanner.NullJSON{Value: t, Valid: true}, nil
+	}
+	return v, nil
+}
+
+func spannerValues(obj map[string]any) (map[string]any, error) {
+	values := make(map[string]any, len(obj))
+	for k, v := range obj {
+		sv, err := spannerValue(v)
+		if err != nil {
+			return nil, fmt.Errorf("column %v: %w", k, err)
+		}
+		values[k] = sv
+	}
+	return values, nil
+}
+
+func (s *spannerOutput) resolveRow(batch service.MessageBatch, i int) (spannerRow, error) {
+	operation, err := batch.TryInterpolatedString(i, s.operation)
+	if err != nil {
+		return spannerRow{}, fmt.Errorf("operation interpolation error: %w", err)
+	}
+
+	colsMsg, err := batch.BloblangQuery(i, s.columns)
+	if err != nil {
+		return spannerRow{}, fmt.Errorf("columns mapping failed: %w", err)
+	}
+	var colsV any
+	if colsMsg != nil {
+		if colsV, err = colsMsg.AsStructured(); err != nil {
+			return spannerRow{}, fmt.Errorf("columns mapping failed: %w", err)
+		}
+	}
+	colsObj, ok := colsV.(map[string]any)
+	if !ok {
+		return spannerRow{}, fmt.Errorf("expected columns mapping to result in an object, got %T", colsV)
+	}
+
+	columns, err := spannerValues(colsObj)
+	if err != nil {
+		return spannerRow{}, err
+	}
+	return spannerRow{operation: operation, columns: columns}, nil
+}
+
+func (s *spannerOutput) rowMutation(row spannerRow) (*spanner.Mutation, error) {
+	switch row.operation {
+	case "insert":
+		return spanner.InsertMap(s.table, row.columns), nil
+	case "update":
+		return spanner.UpdateMap(s.table, row.columns), nil
+	case "insert_or_update":
+		return spanner.InsertOrUpdateMap(s.table, row.columns), nil
+	case "replace":
+		return spanner.ReplaceMap(s.table, row.columns), nil
+	case "delete":
+		if len(s.primaryKey) == 0 {
+			return nil, errors.New("delete operations require the primary_key field to be set")
+		}
+		key := make(spanner.Key, len(s.primaryKey))
+		for i, col := range s.primaryKey {
+			v, exists := row.columns[col]
+			if !exists {
+				return nil, fmt.Errorf("primary key column %v missing from row", col)
+			}
+			key[i] = v
+		}
+		return spanner.Delete(s.table, key), nil
+	}
+	return nil, fmt.Errorf("unsupported operation: %v", row.operation)
+}
+
+func (s *spannerOutput) resolveStatement(batch service.MessageBatch, i int) (spanner.Statement, error) {
+	stmt := spanner.NewStatement(s.statement)
+	if s.args == nil {
+		return stmt, nil
+	}
+
+	argsMsg, err := batch.BloblangQuery(i, s.args)
+	if err != nil {
+		return stmt, fmt.Errorf("args mapping failed: %w", err)
+	}
+	var argsV any
+	if argsMsg != nil {
+		if argsV, err = argsMsg.AsStructured(); err != nil {
+			return stmt, fmt.Errorf("args mapping failed: %w", err)
+		}
+	}
+	argsObj, ok := argsV.(map[string]any)
+	if !ok {
+		return stmt, fmt.Errorf("expected args mapping to result in an object, got %T", argsV)
+	}
+
+	for k, v := range argsObj {
+		if stmt.Params[k], err = spannerValue(v); err != nil {
+			return stmt, fmt.Errorf("parameter %v: %w", k, err)
+		}
+	}
+	return stmt, nil
+}
+
+func (s *spannerOutput) WriteBatch(ctx context.Context, batch service.MessageBatch) error {
+	s.connMut.Lock()
+	db := s.db
+	s.connMut.Unlock()
+
+	if db == nil {
+		return service.ErrNotConnected
+	}
+
+	if s.mode == spannerModeDML {
+		stmts := make([]spanner.Statement, len(batch))
+		for i := range batch {
+			var err error
+			if stmts[i], err = s.resolveStatement(batch, i); err != nil {
+				return fmt.Errorf("message %v: %w", i, err)
+			}
+		}
+		return db.BatchUpdate(ctx, stmts)
+	}
+
+	muts := make([]*spanner.Mutation, len(batch))
+	for i := range batch {
+		row, err := s.resolveRow(batch, i)
+		if err != nil {
+			return fmt.Errorf("message %v: %w", i, err)
+		}
+		if muts[i], err = s.rowMutation(row); err != nil {
+			return fmt.Errorf("message %v: %w", i, err)
+		}
+	}
+	return db.Apply(ctx, muts)
+}
+
+func (s *spannerOutput) Close(ctx context.Context) error {
+	s.connMut.Lock()
+	defer s.connMut.Unlock()
+
+	if s.db != nil {
+		s.db.Close()
+		s.db = nil
+	}
+	return nil
+}
@@ -0,0 +1,166 @@
+package gcp
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"cloud.google.com/go/spanner"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+type mockSpannerDB struct {
+	muts  [][]*spanner.Mutation
+	stmts [][]spanner.Statement
+	err   error
+}
+
+func (m *mockSpannerDB) Apply(ctx context.Context, muts []*spanner.Mutation) error {
+	m.muts = append(m.muts, muts)
+	return m.err
+}
+
+func (m *mockSpannerDB) BatchUpdate(ctx context.Context, stmts []spanner.Statement) error {
+	m.stmts = append(m.stmts, stmts)
+	return m.err
+}
+
+func (m *mockSpannerDB) Close() {}
+
+func testSpannerOutput(t *testing.T, conf string) (*spannerOutput, *mockSpannerDB) {
+	t.Helper()
+
+	pConf, err := newSpannerOutputConfig().ParseYAML(conf, nil)
+	require.NoError(t, err)
+
+	out, err := newSpannerOutput(pConf, service.MockResources())
+	require.NoError(t, err)
+
+	db := &mockSpannerDB{}
+	out.db = db
+	return out, db
+}
+
+func TestSpannerResolveRow(t *testing.T) {
+	out, _ := testSpannerOutput(t, `
+project: foo
+instance: bar
+database: baz
+table: Users
+operation: ${! json("op") }
+columns_mapping: root = this.row
+`)
+
+	batch := service.MessageBatch{
+		service.NewMessage([]byte(`{"op":"update","row":{"id":1,"score":1.5,"name":"alice","tags":["a"],"attrs":{"b":true}}}`)),
+	}
+
+	row, err := out.resolveRow(batch, 0)
+	require.NoError(t, err)
+	assert.Equal(t, "update", row.operation)
+	assert.Equal(t, map[string]any{
+		"id":    int64(1),
+		"score": 1.5,
+		"name":  "alice",
+		"tags":  spanner.NullJSON{Value: []any{"a"}, Valid: true},
+		"attrs": spanner.NullJSON{Value: map[string]any{"b": true}, Valid: true},
+	}, row.columns)
+}
+
+func TestSpannerMutations(t *testing.T) {
+	out, db := testSpannerOutput(t, `
+project: foo
+instance: bar
+database: baz
+table: Users
+primary_key: [ id ]
+operation: ${! json("op") }
+columns_mapping: root = this.row
+`)
+
+	for _, op := range []string{"insert", "update", "insert_or_update", "replace", "delete"} {
+		_, err := out.rowMutation(spannerRow{operation: op, columns: map[string]any{"id": int64(1)}})
+		require.NoError(t, err, op)
+	}
+
+	_, err := out.rowMutation(spannerRow{operation: "upsert", columns: map[string]any{"id": int64(1)}})
+	require.Error(t, err)
+
+	_, err = out.rowMutation(spannerRow{operation: "delete", columns: map[string]any{"name": "foo"}})
+	require.Error(t, err)
+
+	require.NoError(t, out.WriteBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte(`{"op":"insert","row":{"id":1}}`)),
+		service.NewMessage([]byte(`{"op":"delete","row":{"id":2}}`)),
+	}))
+	require.Len(t, db.muts, 1)
+	assert.Len(t, db.muts[0], 2)
+
+	// A batch is written with a single commit, so an invalid message fails the
+	// entire batch.
+	require.Error(t, out.WriteBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte(`{"op":"insert","row":{"id":3}}`)),
+		service.NewMessage([]byte(`{"op":"nope","row":{"id":4}}`)),
+	}))
+	assert.Len(t, db.muts, 1)
+
+	db.err = errors.New("aborted")
+	require.Error(t, out.WriteBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte(`{"op":"insert","row":{"id":5}}`)),
+	}))
+}
+
+func TestSpannerDML(t *testing.T) {
+	out, db := testSpannerOutput(t, `
+project: foo
+instance: bar
+database: baz
+mode: dml
+statement: UPDATE Accounts SET Balance = Balance + @amount WHERE AccountId = @id
+args_mapping: |
+  root.id = this.account
+  root.amount = this.amount
+`)
+
+	require.NoError(t, out.WriteBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte(`{"account":"a","amount":10}`)),
+		service.NewMessage([]byte(`{"account":"b","amount":-2.5}`)),
+	}))
+
+	require.Len(t, db.stmts, 1)
+	assert.Equal(t, []spanner.Statement{
+		{
+			SQL:    "UPDATE Accounts SET Balance = Balance + @amount WHERE AccountId = @id",
+			Params: map[string]any{"id": "a", "amount": int64(10)},
+		},
+		{
+			SQL:    "UPDATE Accounts SET Balance = Balance + @amount WHERE AccountId = @id",
+			Params: map[string]any{"id": "b", "amount": -2.5},
+		},
+	}, db.stmts[0])
+}
+
+func TestSpannerConfigErrors(t *testing.T) {
+	for _, conf := range []string{
+		`
+project: foo
+instance: bar
+database: baz
+`,
+		`
+project: foo
+instance: bar
+database: baz
+mode: dml
+`,
+	} {
+		pConf, err := newSpannerOutputConfig().ParseYAML(conf, nil)
+		require.NoError(t, err)
+
+		_, err = newSpannerOutput(pConf, service.MockResources())
+		require.Error(t, err)
+	}
+}
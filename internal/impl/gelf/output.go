@@ -0,0 +1,568 @@
+package gelf
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/benthosdev/benthos/v4/internal/value"
+	"github.com/benthosdev/benthos/v4/public/bloblang"
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	goFieldAddress      = "address"
+	goFieldHost         = "host"
+	goFieldShortMessage = "short_message"
+	goFieldFullMessage  = "full_message"
+	goFieldLevel        = "level"
+	goFieldTimestamp    = "timestamp"
+	goFieldFields       = "fields_mapping"
+	goFieldCompression  = "compression"
+	goFieldChunkSize    = "chunk_size"
+	goFieldTimeout      = "timeout"
+	goFieldTLS          = "tls"
+	goFieldBatching     = "batching"
+)
+
+func gelfOutputSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Services").
+		Version("4.28.0").
+		Summary("Sends log messages to Graylog, or any other service accepting the Graylog Extended Log Format (GELF).").
+		Description(`
+The transport is chosen by the scheme of the `+"`address`"+`:
+
+- `+"`udp://`"+` sends each message as a datagram, splitting messages larger than `+"`chunk_size`"+` into GELF chunks.
+- `+"`tcp://`"+` sends uncompressed messages over a persistent connection, each terminated with a null byte.
+- `+"`http://` and `https://`"+` send each message with a POST request.
+
+### Additional Fields
+
+The `+"`fields_mapping`"+` is a [Bloblang mapping](/docs/guides/bloblang/about) that returns an object of additional fields to add to each message. Field names are prefixed with an underscore, nested objects are flattened with their keys joined by underscores, and values that are not strings or numbers are converted to strings. Characters that are not permitted in GELF field names are replaced with underscores, and the field `+"`id`"+` is reserved.
+
+Messages that fail to be converted to GELF are rejected individually and can be routed elsewhere with a `+"[`fallback`](/docs/components/outputs/fallback)"+` output.`+service.OutputPerformanceDocs(true, true)).
+		Fields(
+			service.NewStringField(goFieldAddress).
+				Description("The address to send messages to, where the scheme determines the transport.").
+				Example("udp://localhost:12201").
+				Example("tcp://graylog:12201").
+				Example("http://graylog:12201/gelf"),
+			service.NewInterpolatedStringField(goFieldHost).
+				Description("The host that produced each message. When empty the hostname of the machine running Benthos is used.").
+				Example(`${! @kubernetes_pod_name }`).
+				Default(""),
+			service.NewInterpolatedStringField(goFieldShortMessage).
+				Description("A short descriptive message of each message.").
+				Default("${! content() }"),
+			service.NewInterpolatedStringField(goFieldFullMessage).
+				Description("An optional long message of each message, such as a backtrace. Omitted when empty.").
+				Default(""),
+			service.NewInterpolatedStringField(goFieldLevel).
+				Description("The syslog severity level of each message, either as a number from 0 to 7 or as a name such as `error` or `info`.").
+				Example(`${! this.level }`).
+				Default("info"),
+			service.NewBloblangField(goFieldTimestamp).
+				Description("An optional [Bloblang mapping](/docs/guides/bloblang/about) that returns the timestamp of each message, either as a timestamp, a string in RFC 3339 format or a unix timestamp. When omitted the current time is used.").
+				Example(`root = this.ts`).
+				Optional(),
+			service.NewBloblangField(goFieldFields).
+				Description("An optional [Bloblang mapping](/docs/guides/bloblang/about) that returns an object of additional fields. Check out the [additional fields](#additional-fields) section for more details.").
+				Example(`root = this.without("msg", "level", "ts")`).
+				Example(`root.service = @service
+root.trace_id = @trace_id`).
+				Optional(),
+			service.NewStringEnumField(goFieldCompression, "gzip", "zlib", "none").
+				Description("The compression to apply to messages sent over UDP or HTTP. Messages sent over TCP are never compressed.").
+				Advanced().
+				Default("gzip"),
+			service.NewIntField(goFieldChunkSize).
+				Description("The maximum size in bytes of each UDP datagram, messages exceeding this size are split into chunks. A message can be split into at most 128 chunks.").
+				Advanced().
+				Default(1420),
+			service.NewDurationField(goFieldTimeout).
+				Description("The maximum period to wait for messages to be sent.").
+				Advanced().
+				Default("5s"),
+			service.NewTLSToggledField(goFieldTLS),
+			service.NewOutputMaxInFlightField(),
+			service.NewBatchPolicyField(goFieldBatching),
+		).
+		Example("Structured Logs", "Send structured JSON logs to Graylog over TCP, with the remaining fields of each log added as additional fields.", `
+output:
+  gelf:
+    address: tcp://graylog:12201
+    short_message: ${! this.msg }
+    level: ${! this.level.or("info") }
+    timestamp: root = this.ts
+    fields_mapping: root = this.without("msg", "level", "ts")
+`)
+}
+
+func init() {
+	err := service.RegisterBatchOutput("gelf", gelfOutputSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (out service.BatchOutput, batchPolicy service.BatchPolicy, maxInFlight int, err error) {
+			if maxInFlight, err = conf.FieldMaxInFlight(); err != nil {
+				return
+			}
+			if batchPolicy, err = conf.FieldBatchPolicy(goFieldBatching); err != nil {
+				return
+			}
+			out, err = newGELFWriterFromParsed(conf, mgr)
+			return
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+const (
+	gelfChunkHeaderLen = 12
+	gelfMaxChunks      = 128
+)
+
+var gelfChunkMagic = []byte{0x1e, 0x0f}
+
+type gelfWriter struct {
+	log *service.Logger
+
+	scheme      string
+	address     string
+	host        *service.InterpolatedString
+	shortMsg    *service.InterpolatedString
+	fullMsg     *service.InterpolatedString
+	level       *service.InterpolatedString
+	timestamp   *bloblang.Executor
+	fields      *bloblang.Executor
+	compression string
+	chunkSize   int
+	timeout     time.Duration
+	tlsConf     *tls.Config
+	hostname    string
+
+	client *http.Client
+
+	connMut sync.Mutex
+	conn    net.Conn
+}
+
+func newGELFWriterFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*gelfWriter, error) {
+	g := &gelfWriter{
+		log: mgr.Logger(),
+	}
+
+	addressStr, err := conf.FieldString(goFieldAddress)
+	if err != nil {
+		return nil, err
+	}
+	u, err := url.Parse(addressStr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse address: %w", err)
+	}
+	switch g.scheme = u.Scheme; g.scheme {
+	case "udp", "tcp":
+		g.address = u.Host
+	case "http", "https":
+		g.address = u.String()
+	default:
+		return nil, fmt.Errorf("address scheme must be udp, tcp, http or https, got: %q", u.Scheme)
+	}
+
+	if g.host, err = conf.FieldInterpolatedString(goFieldHost); err != nil {
+		return nil, err
+	}
+	if g.shortMsg, err = conf.FieldInterpolatedString(goFieldShortMessage); err != nil {
+		return nil, err
+	}
+	if g.fullMsg, err = conf.FieldInterpolatedString(goFieldFullMessage); err != nil {
+		return nil, err
+	}
+	if g.level, err = conf.FieldInterpolatedString(goFieldLevel); err != nil {
+		return nil, err
+	}
+	if conf.Contains(goFieldTimestamp) {
+		if g.timestamp, err = conf.FieldBloblang(goFieldTimestamp); err != nil {
+			return nil, err
+		}
+	}
+	if conf.Contains(goFieldFields) {
+		if g.fields, err = conf.FieldBloblang(goFieldFields); err != nil {
+			return nil, err
+		}
+	}
+	if g.compression, err = conf.FieldString(goFieldCompression); err != nil {
+		return nil, err
+	}
+	if g.chunkSize, err = conf.FieldInt(goFieldChunkSize); err != nil {
+		return nil, err
+	}
+	if g.chunkSize <= gelfChunkHeaderLen {
+		return nil, fmt.Errorf("chunk_size must be greater than %v", gelfChunkHeaderLen)
+	}
+	if g.timeout, err = conf.FieldDuration(goFieldTimeout); err != nil {
+		return nil, err
+	}
+
+	var tlsEnabled bool
+	if g.tlsConf, tlsEnabled, err = conf.FieldTLSToggled(goFieldTLS); err != nil {
+		return nil, err
+	}
+	if !tlsEnabled {
+		g.tlsConf = nil
+	}
+
+	if g.scheme == "http" || g.scheme == "https" {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		if g.tlsConf != nil {
+			transport.TLSClientConfig = g.tlsConf
+		}
+		g.client = &http.Client{Transport: transport, Timeout: g.timeout}
+	}
+
+	if g.hostname, err = os.Hostname(); err != nil {
+		g.hostname = "localhost"
+	}
+	return g, nil
+}
+
+func (g *gelfWriter) Connect(ctx context.Context) error {
+	if g.client != nil {
+		return nil
+	}
+
+	g.connMut.Lock()
+	defer g.connMut.Unlock()
+	if g.conn != nil {
+		return nil
+	}
+
+	dialer := &net.Dialer{Timeout: g.timeout}
+	var err error
+	if g.scheme == "tcp" && g.tlsConf != nil {
+		g.conn, err = (&tls.Dialer{NetDialer: dialer, Config: g.tlsConf}).DialContext(ctx, "tcp", g.address)
+	} else {
+		g.conn, err = dialer.DialContext(ctx, g.scheme, g.address)
+	}
+	return err
+}
+
+//------------------------------------------------------------------------------
+
+var gelfLevels = map[string]int{
+	"emergency": 0, "emerg": 0,
+	"alert":    1,
+	"critical": 2, "crit": 2,
+	"error": 3, "err": 3,
+	"warning": 4, "warn": 4,
+	"notice": 5,
+	"info":   6, "informational": 6,
+	"debug": 7,
+}
+
+func parseLevel(s string) (int, error) {
+	if l, err := strconv.Atoi(s); err == nil {
+		if l < 0 || l > 7 {
+			return 0, fmt.Errorf("level %v is outside of the range 0 to 7", l)
+		}
+		return l, nil
+	}
+	if l, exists := gelfLevels[strings.ToLower(s)]; exists {
+		return l, nil
+	}
+	return 0, fmt.Errorf("unrecognised level: %q", s)
+}
+
+var invalidFieldChars = regexp.MustCompile(`[^\w.\-]`)
+
+// addFields flattens a structured value into GELF additional fields.
+func addFields(obj map[string]any, prefix string, v any) error {
+	switch t := v.(type) {
+	case map[string]any:
+		for k, child := range t {
+			key := invalidFieldChars.ReplaceAllString(k, "_")
+			if prefix != "_" {
+				key = prefix + "_" + key
+			} else {
+				key = prefix + key
+			}
+			if err := addFields(obj, key, child); err != nil {
+				return err
+			}
+		}
+		return nil
+	case nil:
+		return nil
+	}
+
+	if prefix == "_id" {
+		return errors.New("additional field id is reserved")
+	}
+	switch t := v.(type) {
+	case string:
+		obj[prefix] = t
+	case json.Number:
+		obj[prefix] = t
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		obj[prefix] = t
+	default:
+		obj[prefix] = value.IToString(t)
+	}
+	return nil
+}
+
+func (g *gelfWriter) messageFrom(batch service.MessageBatch, i int) ([]byte, error) {
+	obj := map[string]any{
+		"version": "1.1",
+	}
+
+	if g.fields != nil {
+		fieldsMsg, err := batch.BloblangQuery(i, g.fields)
+		if err != nil {
+			return nil, fmt.Errorf("fields mapping error: %w", err)
+		}
+		if fieldsMsg != nil {
+			fieldsV, err := fieldsMsg.AsStructured()
+			if err != nil {
+				return nil, fmt.Errorf("fields mapping error: %w", err)
+			}
+			if _, isObj := fieldsV.(map[string]any); !isObj {
+				return nil, fmt.Errorf("fields mapping must return an object, got: %T", fieldsV)
+			}
+			if err := addFields(obj, "_", fieldsV); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	host, err := batch.TryInterpolatedString(i, g.host)
+	if err != nil {
+		return nil, fmt.Errorf("host interpolation error: %w", err)
+	}
+	if host == "" {
+		host = g.hostname
+	}
+	obj["host"] = host
+
+	shortMsg, err := batch.TryInterpolatedString(i, g.shortMsg)
+	if err != nil {
+		return nil, fmt.Errorf("short message interpolation error: %w", err)
+	}
+	if shortMsg == "" {
+		return nil, errors.New("short message must not be empty")
+	}
+	obj["short_message"] = shortMsg
+
+	fullMsg, err := batch.TryInterpolatedString(i, g.fullMsg)
+	if err != nil {
+		return nil, fmt.Errorf("full message interpolation error: %w", err)
+	}
+	if fullMsg != "" {
+		obj["full_message"] = fullMsg
+	}
+
+	levelStr, err := batch.TryInterpolatedString(i, g.level)
+	if err != nil {
+		return nil, fmt.Errorf("level interpolation error: %w", err)
+	}
+	if obj["level"], err = parseLevel(levelStr); err != nil {
+		return nil, err
+	}
+
+	ts := time.Now()
+	if g.timestamp != nil {
+		tsMsg, err := batch.BloblangQuery(i, g.timestamp)
+		if err != nil {
+			return nil, fmt.Errorf("timestamp mapping error: %w", err)
+		}
+		if tsMsg != nil {
+			tsV, err := tsMsg.AsStructured()
+			if err != nil {
+				return nil, fmt.Errorf("timestamp mapping error: %w", err)
+			}
+			if ts, err = value.IGetTimestamp(tsV); err != nil {
+				return nil, fmt.Errorf("timestamp mapping error: %w", err)
+			}
+		}
+	}
+	obj["timestamp"] = json.Number(strconv.FormatFloat(float64(ts.UnixMicro())/1e6, 'f', 6, 64))
+
+	return json.Marshal(obj)
+}
+
+func (g *gelfWriter) compress(b []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	var w io.WriteCloser
+	switch g.compression {
+	case "gzip":
+		w = gzip.NewWriter(&buf)
+	case "zlib":
+		w = zlib.NewWriter(&buf)
+	default:
+		return b, nil
+	}
+	if _, err := w.Write(b); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// chunks splits a UDP payload into GELF chunks when it exceeds the chunk size.
+func (g *gelfWriter) chunks(b []byte) ([][]byte, error) {
+	if len(b) <= g.chunkSize {
+		return [][]byte{b}, nil
+	}
+
+	dataSize := g.chunkSize - gelfChunkHeaderLen
+	count := (len(b) + dataSize - 1) / dataSize
+	if count > gelfMaxChunks {
+		return nil, fmt.Errorf("message of %v bytes exceeds the maximum of %v chunks", len(b), gelfMaxChunks)
+	}
+
+	msgID := make([]byte, 8)
+	if _, err := rand.Read(msgID); err != nil {
+		return nil, err
+	}
+
+	chunks := make([][]byte, 0, count)
+	for i := 0; i < count; i++ {
+		end := (i + 1) * dataSize
+		if end > len(b) {
+			end = len(b)
+		}
+		chunk := make([]byte, 0, gelfChunkHeaderLen+end-i*dataSize)
+		chunk = append(chunk, gelfChunkMagic...)
+		chunk = append(chunk, msgID...)
+		chunk = append(chunk, byte(i), byte(count))
+		chunk = append(chunk, b[i*dataSize:end]...)
+		chunks = append(chunks, chunk)
+	}
+	return chunks, nil
+}
+
+func (g *gelfWriter) send(ctx context.Context, b []byte) error {
+	switch g.scheme {
+	case "http", "https":
+		return g.sendHTTP(ctx, b)
+	case "udp":
+		cb, err := g.compress(b)
+		if err != nil {
+			return err
+		}
+		chunks, err := g.chunks(cb)
+		if err != nil {
+			return err
+		}
+		return g.write(chunks...)
+	}
+	return g.write(append(b, 0))
+}
+
+func (g *gelfWriter) write(datagrams ...[]byte) error {
+	g.connMut.Lock()
+	defer g.connMut.Unlock()
+
+	if g.conn == nil {
+		return service.ErrNotConnected
+	}
+	for _, d := range datagrams {
+		_ = g.conn.SetWriteDeadline(time.Now().Add(g.timeout))
+		if _, err := g.conn.Write(d); err != nil {
+			// Writes to a broken connection are not recoverable, and so we
+			// reconnect.
+			_ = g.conn.Close()
+			g.conn = nil
+			return service.ErrNotConnected
+		}
+	}
+	return nil
+}
+
+func (g *gelfWriter) sendHTTP(ctx context.Context, b []byte) error {
+	body, err := g.compress(b)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, g.address, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	switch g.compression {
+	case "gzip":
+		req.Header.Set("Content-Encoding", "gzip")
+	case "zlib":
+		req.Header.Set("Content-Encoding", "deflate")
+	}
+
+	res, err := g.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		resBody, _ := io.ReadAll(io.LimitReader(res.Body, 512))
+		return fmt.Errorf("request failed with status %v: %s", res.StatusCode, resBody)
+	}
+	return nil
+}
+
+func (g *gelfWriter) WriteBatch(ctx context.Context, batch service.MessageBatch) error {
+	var batchErr *service.BatchError
+	for i := range batch {
+		b, err := g.messageFrom(batch, i)
+		if err == nil {
+			err = g.send(ctx, b)
+		}
+		if errors.Is(err, service.ErrNotConnected) {
+			return err
+		}
+		if err != nil {
+			if batchErr == nil {
+				batchErr = service.NewBatchError(batch, err)
+			}
+			batchErr.Failed(i, err)
+		}
+	}
+	if batchErr != nil {
+		return batchErr
+	}
+	return nil
+}
+
+func (g *gelfWriter) Close(ctx context.Context) error {
+	g.connMut.Lock()
+	defer g.connMut.Unlock()
+
+	if g.conn != nil {
+		err := g.conn.Close()
+		g.conn = nil
+		return err
+	}
+	if g.client != nil {
+		g.client.CloseIdleConnections()
+	}
+	return nil
+}
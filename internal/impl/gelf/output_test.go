@@ -0,0 +1,245 @@
+package gelf
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func testGELFWriter(t *testing.T, conf string) *gelfWriter {
+	t.Helper()
+
+	pConf, err := gelfOutputSpec().ParseYAML(conf, nil)
+	require.NoError(t, err)
+
+	w, err := newGELFWriterFromParsed(pConf, service.MockResources())
+	require.NoError(t, err)
+
+	require.NoError(t, w.Connect(context.Background()))
+	t.Cleanup(func() {
+		_ = w.Close(context.Background())
+	})
+	return w
+}
+
+func TestGELFMessageFields(t *testing.T) {
+	w := testGELFWriter(t, `
+address: http://localhost:12201/gelf
+host: ${! @host }
+short_message: ${! this.msg }
+level: ${! this.level }
+timestamp: root = this.ts
+fields_mapping: root = this.without("msg", "level", "ts")
+`)
+
+	msg := service.NewMessage([]byte(`{"msg":"hello world","level":"warn","ts":1700000000.5,"user":{"id":5,"first name":"foo"},"ok":true,"tags":["a","b"]}`))
+	msg.MetaSetMut("host", "foo.example.com")
+
+	b, err := w.messageFrom(service.MessageBatch{msg}, 0)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+  "version": "1.1",
+  "host": "foo.example.com",
+  "short_message": "hello world",
+  "level": 4,
+  "timestamp": 1700000000.5,
+  "_user_id": 5,
+  "_user_first_name": "foo",
+  "_ok": "true",
+  "_tags": "[\"a\",\"b\"]"
+}`, string(b))
+
+	for _, content := range []string{
+		`{"msg":"","level":"info"}`,
+		`{"msg":"foo","level":"nope"}`,
+		`{"msg":"foo","level":"9"}`,
+		`{"msg":"foo","level":"info","id":"reserved"}`,
+	} {
+		_, err := w.messageFrom(service.MessageBatch{service.NewMessage([]byte(content))}, 0)
+		require.Error(t, err, content)
+	}
+}
+
+func TestGELFUDPChunking(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = pc.Close()
+	})
+
+	w := testGELFWriter(t, `
+address: udp://`+pc.LocalAddr().String()+`
+compression: none
+chunk_size: 100
+`)
+
+	content := strings.Repeat("x", 300)
+	require.NoError(t, w.WriteBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte(content)),
+	}))
+
+	var payload []byte
+	var msgID []byte
+	buf := make([]byte, 1024)
+	for i := 0; ; i++ {
+		require.NoError(t, pc.SetReadDeadline(time.Now().Add(time.Second)))
+		n, _, err := pc.ReadFrom(buf)
+		require.NoError(t, err)
+		require.LessOrEqual(t, n, 100)
+
+		chunk := buf[:n]
+		assert.Equal(t, gelfChunkMagic, chunk[:2])
+		if msgID == nil {
+			msgID = append([]byte(nil), chunk[2:10]...)
+		}
+		assert.Equal(t, msgID, chunk[2:10])
+		assert.Equal(t, byte(i), chunk[10])
+
+		payload = append(payload, chunk[12:]...)
+		if int(chunk[11]) == i+1 {
+			break
+		}
+	}
+
+	var obj map[string]any
+	require.NoError(t, json.Unmarshal(payload, &obj))
+	assert.Equal(t, content, obj["short_message"])
+}
+
+func TestGELFUDPCompressed(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = pc.Close()
+	})
+
+	w := testGELFWriter(t, `
+address: udp://`+pc.LocalAddr().String()+`
+`)
+
+	require.NoError(t, w.WriteBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte("hello world")),
+	}))
+
+	buf := make([]byte, 1024)
+	require.NoError(t, pc.SetReadDeadline(time.Now().Add(time.Second)))
+	n, _, err := pc.ReadFrom(buf)
+	require.NoError(t, err)
+
+	zr, err := gzip.NewReader(bytes.NewReader(buf[:n]))
+	require.NoError(t, err)
+	payload, err := io.ReadAll(zr)
+	require.NoError(t, err)
+
+	var obj map[string]any
+	require.NoError(t, json.Unmarshal(payload, &obj))
+	assert.Equal(t, "hello world", obj["short_message"])
+	assert.Equal(t, float64(6), obj["level"])
+}
+
+func TestGELFTCP(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = ln.Close()
+	})
+
+	received := make(chan string, 10)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		r := bufio.NewReader(conn)
+		for {
+			frame, err := r.ReadString(0)
+			if err != nil {
+				return
+			}
+			received <- strings.TrimSuffix(frame, "\x00")
+		}
+	}()
+
+	w := testGELFWriter(t, `
+address: tcp://`+ln.Addr().String()+`
+`)
+
+	require.NoError(t, w.WriteBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte("foo")),
+		service.NewMessage([]byte("bar")),
+	}))
+
+	for _, exp := range []string{"foo", "bar"} {
+		select {
+		case frame := <-received:
+			var obj map[string]any
+			require.NoError(t, json.Unmarshal([]byte(frame), &obj))
+			assert.Equal(t, exp, obj["short_message"])
+		case <-time.After(time.Second):
+			t.Fatal("timed out waiting for frame")
+		}
+	}
+}
+
+func TestGELFHTTP(t *testing.T) {
+	var bodies []string
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/gelf", r.URL.Path)
+		assert.Equal(t, "gzip", r.Header.Get("Content-Encoding"))
+
+		zr, err := gzip.NewReader(r.Body)
+		require.NoError(t, err)
+		b, err := io.ReadAll(zr)
+		require.NoError(t, err)
+
+		if strings.Contains(string(b), "reject") {
+			http.Error(rw, "nope", http.StatusBadRequest)
+			return
+		}
+		bodies = append(bodies, string(b))
+		rw.WriteHeader(http.StatusAccepted)
+	}))
+	t.Cleanup(srv.Close)
+
+	w := testGELFWriter(t, `
+address: `+srv.URL+`/gelf
+`)
+
+	batch := service.MessageBatch{
+		service.NewMessage([]byte("foo")),
+		service.NewMessage([]byte("reject")),
+		service.NewMessage([]byte("")),
+		service.NewMessage([]byte("bar")),
+	}
+	idx := batch.Index()
+	err := w.WriteBatch(context.Background(), batch)
+	require.Error(t, err)
+
+	var failed []int
+	var bErr *service.BatchError
+	require.ErrorAs(t, err, &bErr)
+	bErr.WalkMessagesIndexedBy(idx, func(i int, _ *service.Message, err error) bool {
+		if err != nil {
+			failed = append(failed, i)
+		}
+		return true
+	})
+	assert.Equal(t, []int{1, 2}, failed)
+	require.Len(t, bodies, 2)
+}
@@ -0,0 +1,379 @@
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"time"
+
+	"github.com/benthosdev/benthos/v4/public/bloblang"
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	gqlFieldURL                  = "url"
+	gqlFieldQuery                = "query"
+	gqlFieldOperationName        = "operation_name"
+	gqlFieldVariables            = "variables"
+	gqlFieldHeaders              = "headers"
+	gqlFieldPersistedQuery       = "persisted_query"
+	gqlFieldPersistedQueryEnable = "enabled"
+	gqlFieldPersistedQueryHash   = "sha256_hash"
+	gqlFieldPersistedQueryOnMiss = "send_query_on_miss"
+	gqlFieldTimeout              = "timeout"
+	gqlFieldTLS                  = "tls"
+)
+
+// clientFields returns the fields shared by the graphql processor and output.
+func clientFields() []*service.ConfigField {
+	return append([]*service.ConfigField{
+		service.NewStringField(gqlFieldURL).
+			Description("The URL of the GraphQL endpoint.").
+			Example("https://api.example.com/graphql"),
+		service.NewStringField(gqlFieldQuery).
+			Description("The GraphQL document containing the query or mutation to execute.").
+			Example(`mutation CreateUser($name: String!, $email: String!) {
+  createUser(name: $name, email: $email) { id }
+}`).
+			Default(""),
+		service.NewStringField(gqlFieldOperationName).
+			Description("The name of the operation to execute, which is required when the document contains multiple operations.").
+			Optional(),
+		service.NewBloblangField(gqlFieldVariables).
+			Description("An optional [Bloblang mapping](/docs/guides/bloblang/about) that results in an object of variables for the operation.").
+			Example(`root.name = this.user.name
+root.email = this.user.email`).
+			Optional(),
+		service.NewInterpolatedStringMapField(gqlFieldHeaders).
+			Description("A map of headers to add to each request.").
+			Example(map[string]any{
+				"Authorization": "Bearer ${API_TOKEN}",
+			}).
+			Default(map[string]any{}),
+		service.NewObjectField(gqlFieldPersistedQuery,
+			service.NewBoolField(gqlFieldPersistedQueryEnable).
+				Description("Whether to send automatic persisted queries.").
+				Default(false),
+			service.NewStringField(gqlFieldPersistedQueryHash).
+				Description("An optional SHA-256 hash of a query persisted by the server, when empty the hash is calculated from the `query`.").
+				Default(""),
+			service.NewBoolField(gqlFieldPersistedQueryOnMiss).
+				Description("Whether to send the full query when the server reports that the persisted query was not found, allowing it to be registered.").
+				Default(true),
+		).
+			Description("Configures sending [persisted queries](https://www.apollographql.com/docs/apollo-server/performance/apq/) by their hash rather than the full document.").
+			Advanced(),
+		service.NewDurationField(gqlFieldTimeout).
+			Description("The maximum period to wait for a request to complete.").
+			Advanced().
+			Default("30s"),
+		service.NewTLSToggledField(gqlFieldTLS),
+	}, service.NewHTTPRequestAuthSignerFields()...)
+}
+
+const errorMetadataDocs = `
+### Errors
+
+When a response contains errors they are added to the metadata field ` + "`graphql_errors`" + ` as an array of objects, each containing the fields ` + "`message`, `path`, `locations` and `extensions`" + ` as returned by the server. The ` + "`extensions.code`" + ` of each error, where present, is also added to the metadata field ` + "`graphql_error_codes`" + ` as an array of strings.`
+
+type gqlError struct {
+	Message    string         `json:"message"`
+	Path       []any          `json:"path,omitempty"`
+	Locations  []any          `json:"locations,omitempty"`
+	Extensions map[string]any `json:"extensions,omitempty"`
+}
+
+func (e gqlError) code() string {
+	code, _ := e.Extensions["code"].(string)
+	return code
+}
+
+type gqlResponse struct {
+	Data   json.RawMessage `json:"data"`
+	Errors []gqlError      `json:"errors"`
+}
+
+// hasData returns true when the response contains a non-null data field.
+func (r *gqlResponse) hasData() bool {
+	return len(r.Data) > 0 && !bytes.Equal(r.Data, []byte("null"))
+}
+
+func (r *gqlResponse) errorsErr() error {
+	if len(r.Errors) == 0 {
+		return nil
+	}
+	msg := r.Errors[0].Message
+	if len(r.Errors) > 1 {
+		msg = fmt.Sprintf("%v (and %v other errors)", msg, len(r.Errors)-1)
+	}
+	return fmt.Errorf("graphql errors: %v", msg)
+}
+
+// setErrorMetadata adds the errors of a response, if any, to the metadata of a
+// message.
+func (r *gqlResponse) setErrorMetadata(msg *service.Message) {
+	if len(r.Errors) == 0 {
+		return
+	}
+	errs := make([]any, 0, len(r.Errors))
+	codes := make([]any, 0, len(r.Errors))
+	for _, e := range r.Errors {
+		obj := map[string]any{"message": e.Message}
+		if e.Path != nil {
+			obj["path"] = e.Path
+		}
+		if e.Locations != nil {
+			obj["locations"] = e.Locations
+		}
+		if e.Extensions != nil {
+			obj["extensions"] = e.Extensions
+		}
+		errs = append(errs, obj)
+		if c := e.code(); c != "" {
+			codes = append(codes, c)
+		}
+	}
+	msg.MetaSetMut("graphql_errors", errs)
+	if len(codes) > 0 {
+		msg.MetaSetMut("graphql_error_codes", codes)
+	}
+}
+
+type gqlStatusError struct {
+	status int
+	body   string
+}
+
+func (e *gqlStatusError) Error() string {
+	return fmt.Sprintf("request failed with status %v: %v", e.status, e.body)
+}
+
+type gqlClient struct {
+	mgr *service.Resources
+
+	url           string
+	query         string
+	operationName string
+	variables     *bloblang.Executor
+	headers       map[string]*service.InterpolatedString
+
+	persisted       bool
+	persistedHash   string
+	persistedOnMiss bool
+
+	authSigner func(f fs.FS, req *http.Request) error
+	client     *http.Client
+}
+
+func newClientFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*gqlClient, error) {
+	c := &gqlClient{mgr: mgr}
+
+	var err error
+	if c.url, err = conf.FieldString(gqlFieldURL); err != nil {
+		return nil, err
+	}
+	if c.query, err = conf.FieldString(gqlFieldQuery); err != nil {
+		return nil, err
+	}
+	if conf.Contains(gqlFieldOperationName) {
+		if c.operationName, err = conf.FieldString(gqlFieldOperationName); err != nil {
+			return nil, err
+		}
+	}
+	if conf.Contains(gqlFieldVariables) {
+		if c.variables, err = conf.FieldBloblang(gqlFieldVariables); err != nil {
+			return nil, err
+		}
+	}
+	if c.headers, err = conf.FieldInterpolatedStringMap(gqlFieldHeaders); err != nil {
+		return nil, err
+	}
+
+	pqConf := conf.Namespace(gqlFieldPersistedQuery)
+	if c.persisted, err = pqConf.FieldBool(gqlFieldPersistedQueryEnable); err != nil {
+		return nil, err
+	}
+	if c.persistedHash, err = pqConf.FieldString(gqlFieldPersistedQueryHash); err != nil {
+		return nil, err
+	}
+	if c.persistedHash == "" {
+		sum := sha256.Sum256([]byte(c.query))
+		c.persistedHash = hex.EncodeToString(sum[:])
+	}
+	if c.persistedOnMiss, err = pqConf.FieldBool(gqlFieldPersistedQueryOnMiss); err != nil {
+		return nil, err
+	}
+	if c.persisted && c.persistedOnMiss && c.query == "" {
+		return nil, errors.New("a query must be specified in order to send it when a persisted query is not found")
+	}
+	if !c.persisted && c.query == "" {
+		return nil, errors.New("a query must be specified")
+	}
+
+	var timeout time.Duration
+	if timeout, err = conf.FieldDuration(gqlFieldTimeout); err != nil {
+		return nil, err
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	var tlsConf *tls.Config
+	var tlsEnabled bool
+	if tlsConf, tlsEnabled, err = conf.FieldTLSToggled(gqlFieldTLS); err != nil {
+		return nil, err
+	}
+	if tlsEnabled {
+		transport.TLSClientConfig = tlsConf
+	}
+	c.client = &http.Client{Transport: transport, Timeout: timeout}
+
+	if c.authSigner, err = conf.HTTPRequestAuthSignerFromParsed(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+type gqlRequest struct {
+	Query         string         `json:"query,omitempty"`
+	OperationName string         `json:"operationName,omitempty"`
+	Variables     map[string]any `json:"variables,omitempty"`
+	Extensions    map[string]any `json:"extensions,omitempty"`
+}
+
+// execute resolves the variables and headers of a request from the message at
+// index i of a batch and executes it.
+func (c *gqlClient) execute(ctx context.Context, batch service.MessageBatch, i int) (*gqlResponse, error) {
+	req, headers, err := c.prepare(batch, i)
+	if err != nil {
+		return nil, err
+	}
+	return c.send(ctx, headers, req)
+}
+
+// prepare resolves the variables and headers of a request from the message at
+// index i of a batch.
+func (c *gqlClient) prepare(batch service.MessageBatch, i int) (req gqlRequest, headers http.Header, err error) {
+	req = gqlRequest{
+		Query:         c.query,
+		OperationName: c.operationName,
+	}
+	if c.variables != nil {
+		vMsg, err := batch.BloblangQuery(i, c.variables)
+		if err != nil {
+			return req, nil, fmt.Errorf("variables mapping failed: %w", err)
+		}
+		var v any
+		if vMsg != nil {
+			if v, err = vMsg.AsStructured(); err != nil {
+				return req, nil, fmt.Errorf("variables mapping failed: %w", err)
+			}
+		}
+		if v != nil {
+			obj, ok := v.(map[string]any)
+			if !ok {
+				return req, nil, fmt.Errorf("expected variables mapping to result in an object, got %T", v)
+			}
+			req.Variables = obj
+		}
+	}
+
+	headers = make(http.Header, len(c.headers))
+	for k, v := range c.headers {
+		str, err := batch.TryInterpolatedString(i, v)
+		if err != nil {
+			return req, nil, fmt.Errorf("header %v interpolation error: %w", k, err)
+		}
+		headers.Set(k, str)
+	}
+	return req, headers, nil
+}
+
+// send executes a prepared request, sending it as a persisted query when
+// configured to do so.
+func (c *gqlClient) send(ctx context.Context, headers http.Header, req gqlRequest) (*gqlResponse, error) {
+	if !c.persisted {
+		return c.do(ctx, headers, req)
+	}
+
+	req.Query = ""
+	req.Extensions = map[string]any{
+		"persistedQuery": map[string]any{
+			"version":    1,
+			"sha256Hash": c.persistedHash,
+		},
+	}
+	res, err := c.do(ctx, headers, req)
+	if err != nil || !c.persistedOnMiss || !isPersistedQueryNotFound(res) {
+		return res, err
+	}
+
+	req.Query = c.query
+	return c.do(ctx, headers, req)
+}
+
+func isPersistedQueryNotFound(res *gqlResponse) bool {
+	for _, e := range res.Errors {
+		if e.code() == "PERSISTED_QUERY_NOT_FOUND" || e.Message == "PersistedQueryNotFound" {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *gqlClient) do(ctx context.Context, headers http.Header, gReq gqlRequest) (*gqlResponse, error) {
+	body, err := json.Marshal(gReq)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range headers {
+		req.Header[k] = v
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if req.Header.Get("Accept") == "" {
+		req.Header.Set("Accept", "application/graphql-response+json, application/json")
+	}
+	if err := c.authSigner(c.mgr.FS(), req); err != nil {
+		return nil, err
+	}
+
+	res, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	resBody, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	// Servers following the GraphQL over HTTP specification may respond to
+	// requests that fail validation with a 4XX status and a valid response
+	// body, in which case the errors of the body are more useful.
+	var gRes gqlResponse
+	if jErr := json.Unmarshal(resBody, &gRes); jErr == nil && res.StatusCode < 500 && (gRes.hasData() || len(gRes.Errors) > 0) {
+		return &gRes, nil
+	}
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		if len(resBody) > 1024 {
+			resBody = resBody[:1024]
+		}
+		return nil, &gqlStatusError{status: res.StatusCode, body: string(bytes.TrimSpace(resBody))}
+	}
+	return nil, errors.New("response did not contain data or errors")
+}
+
+func (c *gqlClient) close() {
+	c.client.CloseIdleConnections()
+}
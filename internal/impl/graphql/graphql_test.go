@@ -0,0 +1,196 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+type fakeGraphQL struct {
+	mut         sync.Mutex
+	requests    []map[string]any
+	persisted   map[string]string
+	unavailable int
+}
+
+func (f *fakeGraphQL) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mut.Lock()
+	defer f.mut.Unlock()
+
+	if f.unavailable > 0 {
+		f.unavailable--
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+		return
+	}
+
+	var req map[string]any
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	req["authorization"] = r.Header.Get("Authorization")
+	f.requests = append(f.requests, req)
+
+	query, _ := req["query"].(string)
+	if ext, ok := req["extensions"].(map[string]any); ok {
+		hash := ext["persistedQuery"].(map[string]any)["sha256Hash"].(string)
+		if query != "" {
+			f.persisted[hash] = query
+		} else if query = f.persisted[hash]; query == "" {
+			_, _ = w.Write([]byte(`{"errors":[{"message":"PersistedQueryNotFound","extensions":{"code":"PERSISTED_QUERY_NOT_FOUND"}}]}`))
+			return
+		}
+	}
+
+	vars, _ := req["variables"].(map[string]any)
+	w.Header().Set("Content-Type", "application/json")
+	switch vars["id"] {
+	case "missing":
+		_, _ = w.Write([]byte(`{"data":null,"errors":[{"message":"user not found","path":["user"],"extensions":{"code":"NOT_FOUND"}}]}`))
+	case "partial":
+		_, _ = w.Write([]byte(`{"data":{"user":{"name":"bob","email":null}},"errors":[{"message":"email hidden","path":["user","email"],"extensions":{"code":"FORBIDDEN"}}]}`))
+	case "invalid":
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"errors":[{"message":"Variable \"$id\" got invalid value"}]}`))
+	default:
+		_, _ = w.Write([]byte(`{"data":{"user":{"name":"alice","email":"alice@example.com"}}}`))
+	}
+}
+
+func startFakeGraphQL(t *testing.T) (*fakeGraphQL, string) {
+	t.Helper()
+
+	f := &fakeGraphQL{persisted: map[string]string{}}
+	ts := httptest.NewServer(f)
+	t.Cleanup(ts.Close)
+	return f, ts.URL
+}
+
+func TestGraphQLProcessor(t *testing.T) {
+	_, url := startFakeGraphQL(t)
+
+	pConf, err := processorSpec().ParseYAML(`
+url: `+url+`
+query: 'query User($id: ID!) { user(id: $id) { name email } }'
+variables: 'root.id = this.id'
+`, nil)
+	require.NoError(t, err)
+
+	proc, err := newProcessorFromParsed(pConf, service.MockResources())
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = proc.Close(context.Background())
+	})
+
+	res, err := proc.Process(context.Background(), service.NewMessage([]byte(`{"id":"1"}`)))
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+	b, err := res[0].AsBytes()
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"user":{"name":"alice","email":"alice@example.com"}}`, string(b))
+	_, exists := res[0].MetaGetMut("graphql_errors")
+	assert.False(t, exists)
+
+	res, err = proc.Process(context.Background(), service.NewMessage([]byte(`{"id":"partial"}`)))
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+	b, err = res[0].AsBytes()
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"user":{"name":"bob","email":null}}`, string(b))
+	errs, _ := res[0].MetaGetMut("graphql_errors")
+	assert.Equal(t, []any{
+		map[string]any{
+			"message":    "email hidden",
+			"path":       []any{"user", "email"},
+			"extensions": map[string]any{"code": "FORBIDDEN"},
+		},
+	}, errs)
+	codes, _ := res[0].MetaGetMut("graphql_error_codes")
+	assert.Equal(t, []any{"FORBIDDEN"}, codes)
+
+	msg := service.NewMessage([]byte(`{"id":"missing"}`))
+	_, err = proc.Process(context.Background(), msg)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "user not found")
+	codes, _ = msg.MetaGetMut("graphql_error_codes")
+	assert.Equal(t, []any{"NOT_FOUND"}, codes)
+
+	_, err = proc.Process(context.Background(), service.NewMessage([]byte(`{"id":"invalid"}`)))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "got invalid value")
+}
+
+func TestGraphQLPersistedQuery(t *testing.T) {
+	f, url := startFakeGraphQL(t)
+
+	pConf, err := processorSpec().ParseYAML(`
+url: `+url+`
+query: 'query User($id: ID!) { user(id: $id) { name } }'
+variables: 'root.id = this.id'
+persisted_query:
+  enabled: true
+`, nil)
+	require.NoError(t, err)
+
+	proc, err := newProcessorFromParsed(pConf, service.MockResources())
+	require.NoError(t, err)
+
+	for i := 0; i < 2; i++ {
+		_, err = proc.Process(context.Background(), service.NewMessage([]byte(`{"id":"1"}`)))
+		require.NoError(t, err)
+	}
+
+	f.mut.Lock()
+	defer f.mut.Unlock()
+
+	require.Len(t, f.requests, 3)
+	_, hasQuery := f.requests[0]["query"]
+	assert.False(t, hasQuery)
+	assert.Equal(t, "query User($id: ID!) { user(id: $id) { name } }", f.requests[1]["query"])
+	_, hasQuery = f.requests[2]["query"]
+	assert.False(t, hasQuery)
+}
+
+func TestGraphQLOutput(t *testing.T) {
+	f, url := startFakeGraphQL(t)
+	f.unavailable = 1
+
+	pConf, err := outputSpec().ParseYAML(`
+url: `+url+`
+query: 'mutation Update($id: ID!) { update(id: $id) { id } }'
+variables: 'root.id = this.id'
+headers:
+  Authorization: Bearer foo
+backoff:
+  initial_interval: 1ms
+`, nil)
+	require.NoError(t, err)
+
+	out, err := newOutputFromParsed(pConf, service.MockResources())
+	require.NoError(t, err)
+	require.NoError(t, out.Connect(context.Background()))
+
+	require.NoError(t, out.Write(context.Background(), service.NewMessage([]byte(`{"id":"1"}`))))
+
+	msg := service.NewMessage([]byte(`{"id":"missing"}`))
+	err = out.Write(context.Background(), msg)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "user not found")
+
+	require.Error(t, out.Write(context.Background(), service.NewMessage([]byte(`not json`))))
+
+	f.mut.Lock()
+	defer f.mut.Unlock()
+
+	require.Len(t, f.requests, 2)
+	assert.Equal(t, "Bearer foo", f.requests[0]["authorization"])
+	assert.Equal(t, map[string]any{"id": "1"}, f.requests[0]["variables"])
+}
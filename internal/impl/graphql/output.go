@@ -0,0 +1,121 @@
+package graphql
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+
+	"github.com/benthosdev/benthos/v4/internal/impl/pure"
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func outputSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Network").
+		Version("4.28.0").
+		Summary("Executes a GraphQL mutation (or query) for each message.").
+		Description(`
+The variables of the operation can be populated from each message with the `+"`variables`"+` mapping. A message is considered delivered once a response is received without errors. Requests that fail due to connection errors or with a 5XX status code are retried according to the `+"`backoff`"+` fields, whereas responses containing errors fail the message immediately.
+`+errorMetadataDocs+`
+
+Since outputs do not pass messages on, the error metadata is only observable when failed messages are routed elsewhere, for example with a `+"[`fallback`](/docs/components/outputs/fallback)"+` output.`+service.OutputPerformanceDocs(true, false)).
+		Fields(clientFields()...).
+		Fields(
+			service.NewOutputMaxInFlightField(),
+		).
+		Fields(pure.CommonRetryBackOffFields(3, "500ms", "10s", "1m")...).
+		Example("Create Records", "Create a user for each message with a mutation.", `
+output:
+  graphql:
+    url: https://api.example.com/graphql
+    headers:
+      Authorization: Bearer ${API_TOKEN}
+    query: |
+      mutation CreateUser($name: String!, $email: String!) {
+        createUser(name: $name, email: $email) { id }
+      }
+    variables: |
+      root.name = this.name
+      root.email = this.email
+`)
+}
+
+func init() {
+	err := service.RegisterOutput("graphql", outputSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (out service.Output, maxInFlight int, err error) {
+			if maxInFlight, err = conf.FieldMaxInFlight(); err != nil {
+				return
+			}
+			out, err = newOutputFromParsed(conf, mgr)
+			return
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type graphqlOutput struct {
+	log         *service.Logger
+	client      *gqlClient
+	backoffCtor func() backoff.BackOff
+}
+
+func newOutputFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*graphqlOutput, error) {
+	client, err := newClientFromParsed(conf, mgr)
+	if err != nil {
+		return nil, err
+	}
+	o := &graphqlOutput{
+		log:    mgr.Logger(),
+		client: client,
+	}
+	if o.backoffCtor, err = pure.CommonRetryBackOffCtorFromParsed(conf); err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+func (o *graphqlOutput) Connect(ctx context.Context) error {
+	return nil
+}
+
+func (o *graphqlOutput) Write(ctx context.Context, msg *service.Message) error {
+	req, headers, err := o.client.prepare(service.MessageBatch{msg}, 0)
+	if err != nil {
+		return err
+	}
+
+	boff := o.backoffCtor()
+	for {
+		res, err := o.client.send(ctx, headers, req)
+		if err == nil {
+			res.setErrorMetadata(msg)
+			return res.errorsErr()
+		}
+
+		var sErr *gqlStatusError
+		if errors.As(err, &sErr) && sErr.status < 500 {
+			return err
+		}
+
+		wait := boff.NextBackOff()
+		if wait == backoff.Stop {
+			return err
+		}
+		o.log.Warnf("Retrying GraphQL request in %v: %v", wait, err)
+
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (o *graphqlOutput) Close(ctx context.Context) error {
+	o.client.close()
+	return nil
+}
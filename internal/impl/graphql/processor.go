@@ -0,0 +1,76 @@
+package graphql
+
+import (
+	"context"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func processorSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Integration").
+		Version("4.28.0").
+		Summary("Executes a GraphQL query or mutation for each message and replaces its contents with the resulting data.").
+		Description(`
+The variables of the operation can be populated from each message with the `+"`variables`"+` mapping. When a response contains data the contents of the message are replaced with the `+"`data`"+` field of the response, even when the response also contains errors. When a response contains errors and no data the message is flagged as having failed, and can be handled with [error handling patterns](/docs/configuration/error_handling).
+`+errorMetadataDocs).
+		Fields(clientFields()...).
+		Example("Enrich with a Query", "Fetch the details of a user referenced by each message and merge them into the message.", `
+pipeline:
+  processors:
+    - branch:
+        request_map: 'root.id = this.user_id'
+        processors:
+          - graphql:
+              url: https://api.example.com/graphql
+              query: |
+                query User($id: ID!) {
+                  user(id: $id) { name email }
+                }
+              variables: 'root.id = this.id'
+        result_map: 'root.user = this.user'
+`)
+}
+
+func init() {
+	err := service.RegisterProcessor("graphql", processorSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Processor, error) {
+			return newProcessorFromParsed(conf, mgr)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type graphqlProcessor struct {
+	client *gqlClient
+}
+
+func newProcessorFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*graphqlProcessor, error) {
+	client, err := newClientFromParsed(conf, mgr)
+	if err != nil {
+		return nil, err
+	}
+	return &graphqlProcessor{client: client}, nil
+}
+
+func (p *graphqlProcessor) Process(ctx context.Context, msg *service.Message) (service.MessageBatch, error) {
+	res, err := p.client.execute(ctx, service.MessageBatch{msg}, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	res.setErrorMetadata(msg)
+	if !res.hasData() {
+		return nil, res.errorsErr()
+	}
+
+	msg.SetBytes(res.Data)
+	return service.MessageBatch{msg}, nil
+}
+
+func (p *graphqlProcessor) Close(ctx context.Context) error {
+	p.client.close()
+	return nil
+}
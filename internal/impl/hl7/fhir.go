@@ -0,0 +1,780 @@
+package hl7
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid"
+
+	"github.com/benthosdev/benthos/v4/internal/value"
+)
+
+// The code systems referenced by resources, and their HL7v2 equivalents where
+// these differ.
+const (
+	fhirSystemEventType     = "http://terminology.hl7.org/CodeSystem/v2-0003"
+	fhirSystemActCode       = "http://terminology.hl7.org/CodeSystem/v3-ActCode"
+	fhirSystemParticipation = "http://terminology.hl7.org/CodeSystem/v3-ParticipationType"
+	fhirSystemLOINC         = "http://loinc.org"
+)
+
+var (
+	hl7GenderToFHIR = map[string]string{"M": "male", "F": "female", "O": "other", "A": "other", "U": "unknown", "N": "unknown"}
+	fhirGenderToHL7 = map[string]string{"male": "M", "female": "F", "other": "O", "unknown": "U"}
+
+	hl7ClassToFHIR = map[string]string{"I": "IMP", "O": "AMB", "E": "EMER", "P": "PRENC"}
+	fhirClassToHL7 = map[string]string{"IMP": "I", "AMB": "O", "EMER": "E", "PRENC": "P"}
+
+	hl7ObsStatusToFHIR = map[string]string{"F": "final", "C": "corrected", "P": "preliminary", "R": "preliminary", "X": "cancelled", "W": "entered-in-error"}
+	fhirObsStatusToHL7 = map[string]string{"final": "F", "corrected": "C", "amended": "C", "preliminary": "P", "cancelled": "X", "entered-in-error": "W"}
+)
+
+//------------------------------------------------------------------------------
+
+// hl7Reps returns the repetitions of a field value.
+func hl7Reps(v any) []any {
+	switch t := v.(type) {
+	case nil:
+		return nil
+	case []any:
+		return t
+	}
+	return []any{v}
+}
+
+// hl7Value returns the string at a position of a (non-repeating) value, where
+// the remaining subcomponents of a position are ignored.
+func hl7Value(v any, positions ...int) string {
+	for _, pos := range positions {
+		switch t := v.(type) {
+		case map[string]any:
+			v = t[strconv.Itoa(pos)]
+		case string:
+			if pos != 1 {
+				return ""
+			}
+		default:
+			return ""
+		}
+	}
+	for {
+		obj, isObj := v.(map[string]any)
+		if !isObj {
+			break
+		}
+		v = obj["1"]
+	}
+	s, _ := v.(string)
+	return s
+}
+
+// hl7Field returns the string at a position of the first repetition of a
+// field.
+func hl7Field(seg map[string]any, field int, positions ...int) string {
+	reps := hl7Reps(seg[strconv.Itoa(field)])
+	if len(reps) == 0 {
+		return ""
+	}
+	return hl7Value(reps[0], positions...)
+}
+
+// hl7Components creates a composite value from components, omitting empty
+// components, and returns nil when all components are empty.
+func hl7Components(components ...string) any {
+	obj := map[string]any{}
+	for i, c := range components {
+		if c != "" {
+			obj[strconv.Itoa(i+1)] = c
+		}
+	}
+	switch len(obj) {
+	case 0:
+		return nil
+	case 1:
+		if v, exists := obj["1"]; exists {
+			return v
+		}
+	}
+	return obj
+}
+
+func setIfNotEmpty(obj map[string]any, key string, v any) {
+	switch t := v.(type) {
+	case nil:
+		return
+	case string:
+		if t == "" {
+			return
+		}
+	case []any:
+		if len(t) == 0 {
+			return
+		}
+	case map[string]any:
+		if len(t) == 0 {
+			return
+		}
+	}
+	obj[key] = v
+}
+
+//------------------------------------------------------------------------------
+
+var (
+	hl7DateTimeRegexp  = regexp.MustCompile(`^(\d{4})(\d{2})?(\d{2})?(\d{2})?(\d{2})?(\d{2})?(\.\d{1,4})?([+-]\d{4})?$`)
+	fhirDateTimeRegexp = regexp.MustCompile(`^(\d{4})(?:-(\d{2})(?:-(\d{2})(?:T(\d{2}):(\d{2})(?::(\d{2})(\.\d+)?)?(Z|[+-]\d{2}:\d{2})?)?)?)?$`)
+)
+
+// hl7ToFHIRDateTime converts an HL7v2 timestamp into a FHIR dateTime, or a
+// FHIR date when dateOnly is true. Timestamps with a time but without an
+// offset are assumed to be UTC.
+func hl7ToFHIRDateTime(s string, dateOnly bool) (string, error) {
+	m := hl7DateTimeRegexp.FindStringSubmatch(s)
+	if m == nil {
+		return "", fmt.Errorf("invalid timestamp: %q", s)
+	}
+
+	res := m[1]
+	for _, part := range m[2:4] {
+		if part == "" {
+			return res, nil
+		}
+		res += "-" + part
+	}
+	if dateOnly || m[4] == "" {
+		return res, nil
+	}
+
+	minutes, seconds := m[5], m[6]
+	if minutes == "" {
+		minutes = "00"
+	}
+	if seconds == "" {
+		seconds = "00"
+	}
+	res += "T" + m[4] + ":" + minutes + ":" + seconds + m[7]
+	if offset := m[8]; offset == "" || offset == "+0000" {
+		res += "Z"
+	} else {
+		res += offset[:3] + ":" + offset[3:]
+	}
+	return res, nil
+}
+
+// fhirToHL7DateTime converts a FHIR date, dateTime or instant into an HL7v2
+// timestamp.
+func fhirToHL7DateTime(s string) (string, error) {
+	m := fhirDateTimeRegexp.FindStringSubmatch(s)
+	if m == nil {
+		return "", fmt.Errorf("invalid date time: %q", s)
+	}
+	res := strings.Join(m[1:8], "")
+	switch offset := m[8]; offset {
+	case "":
+	case "Z":
+		res += "+0000"
+	default:
+		res += strings.ReplaceAll(offset, ":", "")
+	}
+	return res, nil
+}
+
+//------------------------------------------------------------------------------
+
+// fhirMapper converts between parsed HL7v2 segments and FHIR R4 resources,
+// recording errors against individual segments or resources rather than
+// failing the entire conversion.
+type fhirMapper struct {
+	errs    []string
+	context string
+}
+
+func (m *fhirMapper) errorf(format string, args ...any) {
+	m.errs = append(m.errs, m.context+": "+fmt.Sprintf(format, args...))
+}
+
+func (m *fhirMapper) dateTime(obj map[string]any, key, hl7 string, dateOnly bool) {
+	if hl7 == "" {
+		return
+	}
+	v, err := hl7ToFHIRDateTime(hl7, dateOnly)
+	if err != nil {
+		m.errorf("%v: %v", key, err)
+		return
+	}
+	obj[key] = v
+}
+
+func (m *fhirMapper) hl7DateTime(seg map[string]any, field int, fhir any) {
+	s, _ := fhir.(string)
+	if s == "" {
+		return
+	}
+	v, err := fhirToHL7DateTime(s)
+	if err != nil {
+		m.errorf("field %v: %v", field, err)
+		return
+	}
+	seg[strconv.Itoa(field)] = v
+}
+
+// toFHIR converts the segments of an HL7v2 message into a FHIR message
+// bundle. The MSH, PID, PV1 and OBX segments are mapped and all other
+// segments are ignored.
+func (m *fhirMapper) toFHIR(raw string, segments []map[string]any) map[string]any {
+	// Resource URLs are derived from the contents of the message so that
+	// converting the same message twice results in identical bundles.
+	namespace := uuid.NewV5(uuid.NamespaceOID, raw)
+	fullURL := func(i int) string {
+		return "urn:uuid:" + uuid.NewV5(namespace, strconv.Itoa(i)).String()
+	}
+
+	var entries []any
+	var patientRef, encounterRef map[string]any
+	addEntry := func(i int, resource map[string]any) map[string]any {
+		url := fullURL(i)
+		entries = append(entries, map[string]any{
+			"fullUrl":  url,
+			"resource": resource,
+		})
+		return map[string]any{"reference": url}
+	}
+
+	bundle := map[string]any{
+		"resourceType": "Bundle",
+		"type":         "message",
+	}
+	for i, seg := range segments {
+		id, _ := seg[segmentKey].(string)
+		m.context = fmt.Sprintf("segment %v (%v)", i+1, id)
+
+		switch id {
+		case "MSH":
+			if i != 0 {
+				continue
+			}
+			m.dateTime(bundle, "timestamp", hl7Field(seg, 7), false)
+			addEntry(i, m.messageHeader(seg))
+		case "PID":
+			patientRef = addEntry(i, m.patient(seg))
+		case "PV1":
+			encounter := m.encounter(seg)
+			setIfNotEmpty(encounter, "subject", patientRef)
+			encounterRef = addEntry(i, encounter)
+		case "OBX":
+			observation := m.observation(seg)
+			setIfNotEmpty(observation, "subject", patientRef)
+			setIfNotEmpty(observation, "encounter", encounterRef)
+			addEntry(i, observation)
+		}
+	}
+	bundle["entry"] = entries
+	return bundle
+}
+
+func (m *fhirMapper) messageHeader(seg map[string]any) map[string]any {
+	res := map[string]any{
+		"resourceType": "MessageHeader",
+		"eventCoding": map[string]any{
+			"system": fhirSystemEventType,
+			"code":   hl7Field(seg, 9, 2),
+		},
+	}
+	setIfNotEmpty(res, "id", hl7Field(seg, 10))
+	if app := hl7Field(seg, 3); app != "" {
+		res["source"] = map[string]any{"name": app, "endpoint": app}
+	}
+	if app := hl7Field(seg, 5); app != "" {
+		res["destination"] = []any{map[string]any{"name": app, "endpoint": app}}
+	}
+	return res
+}
+
+func (m *fhirMapper) patient(seg map[string]any) map[string]any {
+	res := map[string]any{"resourceType": "Patient"}
+
+	var identifiers []any
+	for _, rep := range hl7Reps(seg["3"]) {
+		ident := map[string]any{}
+		setIfNotEmpty(ident, "value", hl7Value(rep, 1))
+		setIfNotEmpty(ident, "system", hl7Value(rep, 4))
+		if code := hl7Value(rep, 5); code != "" {
+			ident["type"] = map[string]any{"coding": []any{map[string]any{"code": code}}}
+		}
+		if len(ident) > 0 {
+			identifiers = append(identifiers, ident)
+		}
+	}
+	setIfNotEmpty(res, "identifier", identifiers)
+
+	var names []any
+	for _, rep := range hl7Reps(seg["5"]) {
+		name := map[string]any{}
+		setIfNotEmpty(name, "family", hl7Value(rep, 1))
+		var given []any
+		for _, pos := range []int{2, 3} {
+			if g := hl7Value(rep, pos); g != "" {
+				given = append(given, g)
+			}
+		}
+		setIfNotEmpty(name, "given", given)
+		if s := hl7Value(rep, 4); s != "" {
+			name["suffix"] = []any{s}
+		}
+		if p := hl7Value(rep, 5); p != "" {
+			name["prefix"] = []any{p}
+		}
+		if len(name) > 0 {
+			names = append(names, name)
+		}
+	}
+	setIfNotEmpty(res, "name", names)
+
+	m.dateTime(res, "birthDate", hl7Field(seg, 7), true)
+	if g := hl7Field(seg, 8); g != "" {
+		if gender, exists := hl7GenderToFHIR[g]; exists {
+			res["gender"] = gender
+		} else {
+			m.errorf("unrecognised administrative sex: %q", g)
+		}
+	}
+
+	var addresses []any
+	for _, rep := range hl7Reps(seg["11"]) {
+		addr := map[string]any{}
+		var lines []any
+		for _, pos := range []int{1, 2} {
+			if l := hl7Value(rep, pos); l != "" {
+				lines = append(lines, l)
+			}
+		}
+		setIfNotEmpty(addr, "line", lines)
+		setIfNotEmpty(addr, "city", hl7Value(rep, 3))
+		setIfNotEmpty(addr, "state", hl7Value(rep, 4))
+		setIfNotEmpty(addr, "postalCode", hl7Value(rep, 5))
+		setIfNotEmpty(addr, "country", hl7Value(rep, 6))
+		if len(addr) > 0 {
+			addresses = append(addresses, addr)
+		}
+	}
+	setIfNotEmpty(res, "address", addresses)
+
+	var telecoms []any
+	for _, f := range []struct {
+		field, use string
+	}{{"13", "home"}, {"14", "work"}} {
+		for _, rep := range hl7Reps(seg[f.field]) {
+			telecom := map[string]any{"use": f.use}
+			if hl7Value(rep, 3) == "Internet" {
+				telecom["system"] = "email"
+				setIfNotEmpty(telecom, "value", hl7Value(rep, 4))
+			} else {
+				telecom["system"] = "phone"
+				setIfNotEmpty(telecom, "value", hl7Value(rep, 1))
+			}
+			if _, exists := telecom["value"]; exists {
+				telecoms = append(telecoms, telecom)
+			}
+		}
+	}
+	setIfNotEmpty(res, "telecom", telecoms)
+
+	if code := hl7Field(seg, 16); code != "" {
+		res["maritalStatus"] = map[string]any{"coding": []any{map[string]any{"code": code}}}
+	}
+	m.dateTime(res, "deceasedDateTime", hl7Field(seg, 29), false)
+	switch hl7Field(seg, 30) {
+	case "Y":
+		if _, exists := res["deceasedDateTime"]; !exists {
+			res["deceasedBoolean"] = true
+		}
+	case "N":
+		res["deceasedBoolean"] = false
+	}
+	return res
+}
+
+func (m *fhirMapper) encounter(seg map[string]any) map[string]any {
+	res := map[string]any{
+		"resourceType": "Encounter",
+		"status":       "in-progress",
+	}
+	if class := hl7Field(seg, 2); class != "" {
+		code, exists := hl7ClassToFHIR[class]
+		if !exists {
+			code = class
+		}
+		res["class"] = map[string]any{"system": fhirSystemActCode, "code": code}
+	}
+	if loc := hl7Field(seg, 3, 1); loc != "" {
+		res["location"] = []any{map[string]any{"location": map[string]any{"display": loc}}}
+	}
+	if id := hl7Field(seg, 7, 1); id != "" {
+		individual := map[string]any{"identifier": map[string]any{"value": id}}
+		var display []string
+		for _, pos := range []int{3, 2} {
+			if n := hl7Field(seg, 7, pos); n != "" {
+				display = append(display, n)
+			}
+		}
+		setIfNotEmpty(individual, "display", strings.Join(display, " "))
+		res["participant"] = []any{map[string]any{
+			"type": []any{map[string]any{"coding": []any{map[string]any{
+				"system": fhirSystemParticipation,
+				"code":   "ATND",
+			}}}},
+			"individual": individual,
+		}}
+	}
+	if visit := hl7Field(seg, 19); visit != "" {
+		res["identifier"] = []any{map[string]any{"value": visit}}
+	}
+
+	period := map[string]any{}
+	m.dateTime(period, "start", hl7Field(seg, 44), false)
+	m.dateTime(period, "end", hl7Field(seg, 45), false)
+	if _, exists := period["end"]; exists {
+		res["status"] = "finished"
+	}
+	setIfNotEmpty(res, "period", period)
+	return res
+}
+
+func (m *fhirMapper) observation(seg map[string]any) map[string]any {
+	res := map[string]any{
+		"resourceType": "Observation",
+		"status":       "final",
+	}
+	if s := hl7Field(seg, 11); s != "" {
+		if status, exists := hl7ObsStatusToFHIR[s]; exists {
+			res["status"] = status
+		} else {
+			m.errorf("unrecognised observation result status: %q", s)
+		}
+	}
+
+	coding := map[string]any{}
+	setIfNotEmpty(coding, "code", hl7Field(seg, 3, 1))
+	setIfNotEmpty(coding, "display", hl7Field(seg, 3, 2))
+	switch system := hl7Field(seg, 3, 3); system {
+	case "LN":
+		coding["system"] = fhirSystemLOINC
+	default:
+		setIfNotEmpty(coding, "system", system)
+	}
+	res["code"] = map[string]any{"coding": []any{coding}}
+
+	if v := hl7Field(seg, 5); v != "" {
+		switch vType := hl7Field(seg, 2); vType {
+		case "NM":
+			f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+			if err != nil {
+				m.errorf("invalid numeric value: %q", v)
+				break
+			}
+			quantity := map[string]any{"value": f}
+			setIfNotEmpty(quantity, "unit", hl7Field(seg, 6, 1))
+			res["valueQuantity"] = quantity
+		case "CE", "CWE":
+			valueCoding := map[string]any{"code": v}
+			setIfNotEmpty(valueCoding, "display", hl7Field(seg, 5, 2))
+			res["valueCodeableConcept"] = map[string]any{"coding": []any{valueCoding}}
+		case "DT", "DTM", "TS":
+			m.dateTime(res, "valueDateTime", v, vType == "DT")
+		default:
+			res["valueString"] = v
+		}
+	}
+
+	if r := hl7Field(seg, 7); r != "" {
+		res["referenceRange"] = []any{map[string]any{"text": r}}
+	}
+	if code := hl7Field(seg, 8); code != "" {
+		res["interpretation"] = []any{map[string]any{"coding": []any{map[string]any{"code": code}}}}
+	}
+	m.dateTime(res, "effectiveDateTime", hl7Field(seg, 14), false)
+	return res
+}
+
+//------------------------------------------------------------------------------
+
+// fhirObj returns the value of a path within a FHIR resource, where numeric
+// path segments index arrays.
+func fhirObj(v any, path ...string) any {
+	for _, p := range path {
+		switch t := v.(type) {
+		case map[string]any:
+			v = t[p]
+		case []any:
+			i, err := strconv.Atoi(p)
+			if err != nil || i >= len(t) {
+				return nil
+			}
+			v = t[i]
+		default:
+			return nil
+		}
+	}
+	return v
+}
+
+func fhirStr(v any, path ...string) string {
+	s, _ := fhirObj(v, path...).(string)
+	return s
+}
+
+func fhirArr(v any, path ...string) []any {
+	a, _ := fhirObj(v, path...).([]any)
+	return a
+}
+
+// fromFHIR converts a FHIR bundle, or a single resource, into the segments of
+// an HL7v2 message. Patient, Encounter and Observation resources are mapped,
+// and MessageHeader resources provide the values of the MSH segment.
+func (m *fhirMapper) fromFHIR(root map[string]any, messageType, version string) []any {
+	var resources []map[string]any
+	if root["resourceType"] == "Bundle" {
+		for i, e := range fhirArr(root, "entry") {
+			r, ok := fhirObj(e, "resource").(map[string]any)
+			if !ok {
+				m.errs = append(m.errs, fmt.Sprintf("entry %v: missing resource", i+1))
+				continue
+			}
+			resources = append(resources, r)
+		}
+	} else {
+		resources = append(resources, root)
+	}
+
+	var header map[string]any
+	for _, r := range resources {
+		if r["resourceType"] == "MessageHeader" {
+			header = r
+			break
+		}
+	}
+
+	msh := map[string]any{
+		segmentKey: "MSH",
+		"1":        string(defaultDelimiters.field),
+		"2":        defaultDelimiters.encodingChars(),
+		"9":        hl7Components(strings.Split(messageType, string(defaultDelimiters.comp))...),
+		"11":       "P",
+		"12":       version,
+	}
+	setIfNotEmpty(msh, "3", fhirStr(header, "source", "name"))
+	setIfNotEmpty(msh, "5", fhirStr(header, "destination", "0", "name"))
+
+	controlID := fhirStr(header, "id")
+	if controlID == "" {
+		controlID = fhirStr(root, "id")
+	}
+	if controlID == "" {
+		controlID = uuid.Must(uuid.NewV4()).String()
+	}
+	msh["10"] = controlID
+
+	m.context = "bundle"
+	if ts := fhirStr(root, "timestamp"); ts != "" {
+		m.hl7DateTime(msh, 7, ts)
+	} else {
+		msh["7"] = time.Now().UTC().Format("20060102150405") + "+0000"
+	}
+
+	segments := []any{msh}
+	setID := 0
+	for i, r := range resources {
+		rType, _ := r["resourceType"].(string)
+		m.context = fmt.Sprintf("resource %v (%v)", i+1, rType)
+		switch rType {
+		case "MessageHeader":
+		case "Patient":
+			segments = append(segments, m.pid(r))
+		case "Encounter":
+			segments = append(segments, m.pv1(r))
+		case "Observation":
+			setID++
+			segments = append(segments, m.obx(r, setID))
+		default:
+			m.errorf("unsupported resource type")
+		}
+	}
+	return segments
+}
+
+func codingCode(v any) string {
+	return fhirStr(v, "coding", "0", "code")
+}
+
+func (m *fhirMapper) pid(r map[string]any) map[string]any {
+	seg := map[string]any{segmentKey: "PID", "1": "1"}
+
+	var identifiers []any
+	for _, ident := range fhirArr(r, "identifier") {
+		if v := hl7Components(fhirStr(ident, "value"), "", "", fhirStr(ident, "system"), codingCode(fhirObj(ident, "type"))); v != nil {
+			identifiers = append(identifiers, v)
+		}
+	}
+	setRepetitions(seg, "3", identifiers)
+
+	var names []any
+	for _, name := range fhirArr(r, "name") {
+		given := fhirArr(name, "given")
+		var middle []string
+		for _, g := range given[min(1, len(given)):] {
+			if s, ok := g.(string); ok {
+				middle = append(middle, s)
+			}
+		}
+		v := hl7Components(
+			fhirStr(name, "family"),
+			fhirStr(given, "0"),
+			strings.Join(middle, " "),
+			fhirStr(name, "suffix", "0"),
+			fhirStr(name, "prefix", "0"),
+		)
+		if v != nil {
+			names = append(names, v)
+		}
+	}
+	setRepetitions(seg, "5", names)
+
+	m.hl7DateTime(seg, 7, r["birthDate"])
+	if g := fhirStr(r, "gender"); g != "" {
+		if gender, exists := fhirGenderToHL7[g]; exists {
+			seg["8"] = gender
+		} else {
+			m.errorf("unrecognised gender: %q", g)
+		}
+	}
+
+	var addresses []any
+	for _, addr := range fhirArr(r, "address") {
+		v := hl7Components(
+			fhirStr(addr, "line", "0"),
+			fhirStr(addr, "line", "1"),
+			fhirStr(addr, "city"),
+			fhirStr(addr, "state"),
+			fhirStr(addr, "postalCode"),
+			fhirStr(addr, "country"),
+		)
+		if v != nil {
+			addresses = append(addresses, v)
+		}
+	}
+	setRepetitions(seg, "11", addresses)
+
+	var home, work []any
+	for _, telecom := range fhirArr(r, "telecom") {
+		value := fhirStr(telecom, "value")
+		if value == "" {
+			continue
+		}
+		var v any
+		switch fhirStr(telecom, "system") {
+		case "email":
+			v = hl7Components("", "NET", "Internet", value)
+		case "phone", "":
+			v = value
+		default:
+			continue
+		}
+		if fhirStr(telecom, "use") == "work" {
+			work = append(work, v)
+		} else {
+			home = append(home, v)
+		}
+	}
+	setRepetitions(seg, "13", home)
+	setRepetitions(seg, "14", work)
+
+	setIfNotEmpty(seg, "16", codingCode(r["maritalStatus"]))
+	m.hl7DateTime(seg, 29, r["deceasedDateTime"])
+	if _, exists := r["deceasedDateTime"]; exists {
+		seg["30"] = "Y"
+	} else if deceased, ok := r["deceasedBoolean"].(bool); ok {
+		seg["30"] = map[bool]string{true: "Y", false: "N"}[deceased]
+	}
+	return seg
+}
+
+func (m *fhirMapper) pv1(r map[string]any) map[string]any {
+	seg := map[string]any{segmentKey: "PV1", "1": "1"}
+
+	if code := fhirStr(r, "class", "code"); code != "" {
+		if class, exists := fhirClassToHL7[code]; exists {
+			seg["2"] = class
+		} else {
+			seg["2"] = code
+		}
+	}
+	setIfNotEmpty(seg, "3", fhirStr(r, "location", "0", "location", "display"))
+	for _, p := range fhirArr(r, "participant") {
+		if codingCode(fhirObj(p, "type", "0")) != "ATND" {
+			continue
+		}
+		setIfNotEmpty(seg, "7", fhirStr(p, "individual", "identifier", "value"))
+		break
+	}
+	setIfNotEmpty(seg, "19", fhirStr(r, "identifier", "0", "value"))
+	m.hl7DateTime(seg, 44, fhirObj(r, "period", "start"))
+	m.hl7DateTime(seg, 45, fhirObj(r, "period", "end"))
+	return seg
+}
+
+func (m *fhirMapper) obx(r map[string]any, setID int) map[string]any {
+	seg := map[string]any{segmentKey: "OBX", "1": strconv.Itoa(setID)}
+
+	coding := fhirObj(r, "code", "coding", "0")
+	system := fhirStr(coding, "system")
+	if system == fhirSystemLOINC {
+		system = "LN"
+	}
+	setIfNotEmpty(seg, "3", hl7Components(fhirStr(coding, "code"), fhirStr(coding, "display"), system))
+
+	if q, exists := r["valueQuantity"]; exists {
+		seg["2"] = "NM"
+		if v := fhirObj(q, "value"); v != nil {
+			if f, err := value.IGetNumber(v); err == nil {
+				seg["5"] = strconv.FormatFloat(f, 'f', -1, 64)
+			} else {
+				m.errorf("valueQuantity: %v", err)
+			}
+		}
+		setIfNotEmpty(seg, "6", fhirStr(q, "unit"))
+	} else if c, exists := r["valueCodeableConcept"]; exists {
+		seg["2"] = "CWE"
+		setIfNotEmpty(seg, "5", hl7Components(codingCode(c), fhirStr(c, "coding", "0", "display")))
+	} else if dt, exists := r["valueDateTime"]; exists {
+		seg["2"] = "DTM"
+		m.hl7DateTime(seg, 5, dt)
+	} else if s := fhirStr(r, "valueString"); s != "" {
+		seg["2"] = "ST"
+		seg["5"] = s
+	}
+
+	setIfNotEmpty(seg, "7", fhirStr(r, "referenceRange", "0", "text"))
+	setIfNotEmpty(seg, "8", codingCode(fhirObj(r, "interpretation", "0")))
+	if s := fhirStr(r, "status"); s != "" {
+		if status, exists := fhirObsStatusToHL7[s]; exists {
+			seg["11"] = status
+		} else {
+			m.errorf("unrecognised status: %q", s)
+		}
+	}
+	m.hl7DateTime(seg, 14, r["effectiveDateTime"])
+	return seg
+}
+
+func setRepetitions(seg map[string]any, field string, reps []any) {
+	switch len(reps) {
+	case 0:
+	case 1:
+		seg[field] = reps[0]
+	default:
+		seg[field] = reps
+	}
+}
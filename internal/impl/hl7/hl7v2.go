@@ -0,0 +1,373 @@
+package hl7
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/benthosdev/benthos/v4/internal/value"
+)
+
+// segmentKey is the key of a parsed segment that holds the segment identifier,
+// all other keys are the one-based positions of fields.
+const segmentKey = "segment"
+
+var segmentIDRegexp = regexp.MustCompile(`^[A-Z][A-Z0-9]{2}$`)
+
+type delimiters struct {
+	field, comp, rep, esc, sub byte
+}
+
+var defaultDelimiters = delimiters{field: '|', comp: '^', rep: '~', esc: '\\', sub: '&'}
+
+func (d delimiters) encodingChars() string {
+	var b strings.Builder
+	for _, c := range []byte{d.comp, d.rep, d.esc, d.sub} {
+		if c == 0 {
+			break
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
+
+// delimitersFromMSH reads the delimiters declared by the first two fields of
+// an MSH segment.
+func delimitersFromMSH(field, encChars string) (delimiters, error) {
+	if len(field) != 1 {
+		return delimiters{}, fmt.Errorf("invalid field separator: %q", field)
+	}
+	d := delimiters{field: field[0]}
+	for i, c := range []*byte{&d.comp, &d.rep, &d.esc, &d.sub} {
+		if i < len(encChars) {
+			*c = encChars[i]
+		}
+	}
+	if d.comp == 0 {
+		return delimiters{}, fmt.Errorf("invalid encoding characters: %q", encChars)
+	}
+	return d, nil
+}
+
+// parseHL7v2 parses an HL7v2 message into a slice of segments, where each
+// segment is an object of field positions to values. Segments that cannot be
+// parsed are omitted and reported as errors, whereas a message without a
+// valid MSH header results in an error.
+func parseHL7v2(raw string) (segments []map[string]any, segErrs []string, err error) {
+	lines := strings.FieldsFunc(raw, func(r rune) bool {
+		return r == '\r' || r == '\n'
+	})
+	for len(lines) > 0 && strings.TrimSpace(lines[0]) == "" {
+		lines = lines[1:]
+	}
+	if len(lines) == 0 || !strings.HasPrefix(lines[0], "MSH") || len(lines[0]) < 5 {
+		return nil, nil, errors.New("message must begin with an MSH segment")
+	}
+
+	header := lines[0]
+	encChars := header[4:]
+	if i := strings.IndexByte(encChars, header[3]); i != -1 {
+		encChars = encChars[:i]
+	}
+	d, err := delimitersFromMSH(header[3:4], encChars)
+	if err != nil {
+		return nil, nil, fmt.Errorf("segment 1 (MSH): %w", err)
+	}
+
+	for i, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		fields := strings.Split(line, string(d.field))
+		if !segmentIDRegexp.MatchString(fields[0]) {
+			segErrs = append(segErrs, fmt.Sprintf("segment %v: invalid segment identifier: %q", i+1, fields[0]))
+			continue
+		}
+
+		seg := map[string]any{segmentKey: fields[0]}
+		start, offset := 1, 0
+		if fields[0] == "MSH" {
+			// The field separator is the first field of MSH segments and the
+			// encoding characters are the second, which isn't parsed.
+			seg["1"] = string(d.field)
+			if len(fields) > 1 {
+				seg["2"] = fields[1]
+			}
+			start, offset = 2, 1
+		}
+		for j := start; j < len(fields); j++ {
+			if v, ok := d.parseField(fields[j]); ok {
+				seg[strconv.Itoa(j+offset)] = v
+			}
+		}
+		segments = append(segments, seg)
+	}
+	return segments, segErrs, nil
+}
+
+// parseField returns the structured value of a field, where repetitions are
+// arrays, and components and subcomponents are objects of one-based positions
+// to values. Fields containing a single value are strings, and fields with the
+// HL7 null value ("") are nil. False is returned for empty fields.
+func (d delimiters) parseField(s string) (any, bool) {
+	if s == "" {
+		return nil, false
+	}
+	if s == `""` {
+		return nil, true
+	}
+	reps := d.split(s, d.rep)
+	if len(reps) == 1 {
+		return d.parseComposite(reps[0], d.comp, true), true
+	}
+	values := make([]any, len(reps))
+	for i, r := range reps {
+		values[i] = d.parseComposite(r, d.comp, true)
+	}
+	return values, true
+}
+
+func (d delimiters) parseComposite(s string, sep byte, descend bool) any {
+	parts := d.split(s, sep)
+	if len(parts) == 1 {
+		if !descend {
+			return d.unescape(s)
+		}
+		// Subcomponents of a field without components are nested within the
+		// first component so that they aren't confused with components.
+		v := d.parseComposite(s, d.sub, false)
+		if _, isObj := v.(map[string]any); isObj {
+			return map[string]any{"1": v}
+		}
+		return v
+	}
+	obj := map[string]any{}
+	for i, p := range parts {
+		if p == "" {
+			continue
+		}
+		if descend {
+			obj[strconv.Itoa(i+1)] = d.parseComposite(p, d.sub, false)
+		} else {
+			obj[strconv.Itoa(i+1)] = d.unescape(p)
+		}
+	}
+	if len(obj) == 0 {
+		return ""
+	}
+	return obj
+}
+
+func (d delimiters) split(s string, sep byte) []string {
+	if sep == 0 {
+		return []string{s}
+	}
+	return strings.Split(s, string(sep))
+}
+
+// unescape replaces the escape sequences of a value. Formatting sequences are
+// removed and unrecognised sequences are left as they are.
+func (d delimiters) unescape(s string) string {
+	if d.esc == 0 || strings.IndexByte(s, d.esc) == -1 {
+		return s
+	}
+
+	var b strings.Builder
+	for {
+		start := strings.IndexByte(s, d.esc)
+		if start == -1 {
+			break
+		}
+		end := strings.IndexByte(s[start+1:], d.esc)
+		if end == -1 {
+			break
+		}
+		end += start + 1
+
+		b.WriteString(s[:start])
+		switch seq := s[start+1 : end]; {
+		case seq == "F":
+			b.WriteByte(d.field)
+		case seq == "S":
+			b.WriteByte(d.comp)
+		case seq == "T":
+			b.WriteByte(d.sub)
+		case seq == "R":
+			b.WriteByte(d.rep)
+		case seq == "E":
+			b.WriteByte(d.esc)
+		case seq == ".br":
+			b.WriteByte('\n')
+		case seq == "H", seq == "N":
+		case strings.HasPrefix(seq, "X"):
+			if decoded, err := hex.DecodeString(seq[1:]); err == nil {
+				b.Write(decoded)
+			} else {
+				b.WriteString(s[start : end+1])
+			}
+		default:
+			b.WriteString(s[start : end+1])
+		}
+		s = s[end+1:]
+	}
+	b.WriteString(s)
+	return b.String()
+}
+
+func (d delimiters) escape(s string) string {
+	if d.esc == 0 {
+		return s
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case d.esc:
+			b.WriteString(string(d.esc) + "E" + string(d.esc))
+		case d.field:
+			b.WriteString(string(d.esc) + "F" + string(d.esc))
+		case d.comp:
+			b.WriteString(string(d.esc) + "S" + string(d.esc))
+		case d.sub:
+			b.WriteString(string(d.esc) + "T" + string(d.esc))
+		case d.rep:
+			b.WriteString(string(d.esc) + "R" + string(d.esc))
+		case '\n':
+			b.WriteString(string(d.esc) + ".br" + string(d.esc))
+		case '\r':
+			b.WriteString(string(d.esc) + "X0D" + string(d.esc))
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// encodeHL7v2 serialises segments in the structure produced by parseHL7v2,
+// using the delimiters declared by the leading MSH segment.
+func encodeHL7v2(segments []any) ([]byte, error) {
+	if len(segments) == 0 {
+		return nil, errors.New("message must begin with an MSH segment")
+	}
+
+	d := defaultDelimiters
+	var b strings.Builder
+	for i, s := range segments {
+		seg, ok := s.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("segment %v: expected object, got %T", i+1, s)
+		}
+		id, _ := seg[segmentKey].(string)
+		if !segmentIDRegexp.MatchString(id) {
+			return nil, fmt.Errorf("segment %v: invalid segment identifier: %q", i+1, id)
+		}
+		if i == 0 && id != "MSH" {
+			return nil, errors.New("message must begin with an MSH segment")
+		}
+
+		positions, err := fieldPositions(seg)
+		if err != nil {
+			return nil, fmt.Errorf("segment %v (%v): %w", i+1, id, err)
+		}
+
+		b.WriteString(id)
+		first := 1
+		if id == "MSH" {
+			if i == 0 {
+				field, encChars := string(defaultDelimiters.field), defaultDelimiters.encodingChars()
+				if v, exists := seg["1"]; exists {
+					field = value.IToString(v)
+				}
+				if v, exists := seg["2"]; exists {
+					encChars = value.IToString(v)
+				}
+				if d, err = delimitersFromMSH(field, encChars); err != nil {
+					return nil, fmt.Errorf("segment 1 (MSH): %w", err)
+				}
+			}
+			b.WriteByte(d.field)
+			b.WriteString(d.encodingChars())
+			first = 3
+		}
+
+		last := 0
+		if len(positions) > 0 {
+			last = positions[len(positions)-1]
+		}
+		for pos := first; pos <= last; pos++ {
+			b.WriteByte(d.field)
+			if v, exists := seg[strconv.Itoa(pos)]; exists {
+				b.WriteString(d.encodeField(v))
+			}
+		}
+		b.WriteByte('\r')
+	}
+	return []byte(b.String()), nil
+}
+
+func fieldPositions(seg map[string]any) ([]int, error) {
+	positions := make([]int, 0, len(seg))
+	for k := range seg {
+		if k == segmentKey {
+			continue
+		}
+		pos, err := strconv.Atoi(k)
+		if err != nil || pos < 1 {
+			return nil, fmt.Errorf("invalid field position: %q", k)
+		}
+		positions = append(positions, pos)
+	}
+	sort.Ints(positions)
+	return positions, nil
+}
+
+func (d delimiters) encodeField(v any) string {
+	switch t := v.(type) {
+	case nil:
+		return `""`
+	case []any:
+		reps := make([]string, len(t))
+		for i, r := range t {
+			reps[i] = d.encodeComposite(r, d.comp, true)
+		}
+		return strings.Join(reps, string(d.rep))
+	}
+	return d.encodeComposite(v, d.comp, true)
+}
+
+func (d delimiters) encodeComposite(v any, sep byte, descend bool) string {
+	obj, ok := v.(map[string]any)
+	if !ok {
+		return d.escape(value.IToString(v))
+	}
+
+	parts := map[int]string{}
+	last := 0
+	for k, p := range obj {
+		pos, err := strconv.Atoi(k)
+		if err != nil || pos < 1 {
+			continue
+		}
+		if descend {
+			parts[pos] = d.encodeComposite(p, d.sub, false)
+		} else {
+			parts[pos] = d.escape(value.IToString(p))
+		}
+		if pos > last {
+			last = pos
+		}
+	}
+
+	var b strings.Builder
+	for pos := 1; pos <= last; pos++ {
+		if pos > 1 {
+			b.WriteByte(sep)
+		}
+		b.WriteString(parts[pos])
+	}
+	return b.String()
+}
@@ -0,0 +1,267 @@
+package hl7
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	hpFieldOperator      = "operator"
+	hpFieldStrict        = "strict"
+	hpFieldGroupSegments = "group_segments"
+	hpFieldMessageType   = "message_type"
+	hpFieldVersion       = "version"
+)
+
+func hl7v2ProcSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Categories("Parsing").
+		Version("4.28.0").
+		Summary("Converts HL7v2 messages to and from structured JSON, and maps them to and from FHIR R4 resources.").
+		Description(`
+## Operators
+
+### `+"`to_json`"+`
+
+Parses a pipe-delimited HL7v2 message into an array of segments, where each segment is an object containing the segment identifier under the key `+"`segment`"+` and the non-empty fields of the segment keyed by their one-based position. Fields that contain components are objects of component positions to values, subcomponents are nested in the same way, and repeated fields are arrays. Escape sequences are decoded and fields containing the HL7 null value `+"`\"\"`"+` are set to `+"`null`"+`. The delimiters are read from the MSH segment of each message.
+
+For example, the segment `+"`PID|1||12345^^^HOSP^MR||Doe^John`"+` becomes:
+
+`+"```json"+`
+{"segment":"PID","1":"1","3":{"1":"12345","4":"HOSP","5":"MR"},"5":{"1":"Doe","2":"John"}}
+`+"```"+`
+
+Which allows fields to be addressed by their position, e.g. `+"`this.1.5.1`"+` refers to PID-5.1 when PID is the second segment. When `+"`group_segments`"+` is `+"`true`"+` the result is instead an object of segment identifiers to arrays of segments, e.g. `+"`this.PID.0.5.1`"+`.
+
+### `+"`from_json`"+`
+
+Serialises an array of segments in the structure produced by `+"`to_json`"+` into an HL7v2 message, using the delimiters declared by the leading MSH segment. Values are escaped as required.
+
+### `+"`to_fhir`"+`
+
+Parses an HL7v2 message and converts it into a FHIR R4 message bundle, where the MSH segment becomes a MessageHeader, PID segments become Patient resources, PV1 segments become Encounter resources and OBX segments become Observation resources that reference the patient and encounter. Other segments are ignored. Timestamps without an offset are assumed to be UTC.
+
+### `+"`from_fhir`"+`
+
+Converts a FHIR R4 bundle, or a single resource, into an HL7v2 message with the type set by `+"`message_type`"+`. The MSH segment is populated from a MessageHeader resource when present, and Patient, Encounter and Observation resources are converted into PID, PV1 and OBX segments respectively.
+
+## Errors
+
+Segments that cannot be parsed and fields or resources that cannot be mapped are skipped, and a description of each problem is added to the metadata field `+"`hl7v2_errors`"+` as an array of strings, e.g. `+"`segment 3 (PID): birthDate: invalid timestamp: \"19800\"`"+`. When `+"`strict`"+` is `+"`true`"+` these problems instead cause the message to fail. Messages that cannot be parsed at all, such as those without an MSH segment, always fail, allowing you to use [standard processor error handling patterns](/docs/configuration/error_handling).
+
+When parsing messages the metadata fields `+"`hl7v2_message_type`"+` and `+"`hl7v2_control_id`"+` are set from MSH-9 and MSH-10.`).
+		Fields(
+			service.NewStringEnumField(hpFieldOperator, "to_json", "from_json", "to_fhir", "from_fhir").
+				Description("The [operation](#operators) to apply to messages."),
+			service.NewBoolField(hpFieldStrict).
+				Description("Whether to fail messages containing segments, fields or resources that cannot be converted rather than skipping them.").
+				Default(false),
+			service.NewBoolField(hpFieldGroupSegments).
+				Description("When the operator is `to_json`, whether to group segments by their identifier rather than emitting them in order.").
+				Default(false).
+				Advanced(),
+			service.NewStringField(hpFieldMessageType).
+				Description("When the operator is `from_fhir`, the message type (MSH-9) of the resulting messages.").
+				Default("ADT^A08").
+				Advanced(),
+			service.NewStringField(hpFieldVersion).
+				Description("When the operator is `from_fhir`, the version (MSH-12) of the resulting messages.").
+				Default("2.5").
+				Advanced(),
+		).
+		Example("ADT Feed to FHIR", "Here we consume an HL7v2 feed over MLLP framed TCP, convert each message into a FHIR bundle and submit it to a FHIR server.", `
+input:
+  socket_server:
+    network: tcp
+    address: 0.0.0.0:2575
+    scanner:
+      lines:
+        custom_delimiter: "\x1c\r"
+
+pipeline:
+  processors:
+    - mapping: 'root = content().string().trim_prefix("\u000b")'
+    - hl7v2:
+        operator: to_fhir
+
+output:
+  http_client:
+    url: https://fhir.example.com/fhir
+    verb: POST
+    headers:
+      Content-Type: application/fhir+json
+`).
+		Example("Field Addressing", "Here we parse messages and route them by their message type and the patient's assigning authority.", `
+pipeline:
+  processors:
+    - hl7v2:
+        operator: to_json
+        group_segments: true
+    - mapping: |
+        root.type = @hl7v2_message_type
+        root.mrn = this.PID.0.3.1
+        root.authority = this.PID.0.3.4
+`)
+}
+
+func init() {
+	err := service.RegisterProcessor(
+		"hl7v2", hl7v2ProcSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Processor, error) {
+			return newHL7v2ProcFromConfig(conf)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type hl7v2Proc struct {
+	operator      string
+	strict        bool
+	groupSegments bool
+	messageType   string
+	version       string
+}
+
+func newHL7v2ProcFromConfig(conf *service.ParsedConfig) (*hl7v2Proc, error) {
+	p := &hl7v2Proc{}
+
+	var err error
+	if p.operator, err = conf.FieldString(hpFieldOperator); err != nil {
+		return nil, err
+	}
+	if p.strict, err = conf.FieldBool(hpFieldStrict); err != nil {
+		return nil, err
+	}
+	if p.groupSegments, err = conf.FieldBool(hpFieldGroupSegments); err != nil {
+		return nil, err
+	}
+	if p.messageType, err = conf.FieldString(hpFieldMessageType); err != nil {
+		return nil, err
+	}
+	if p.messageType == "" {
+		return nil, errors.New("message_type must not be empty")
+	}
+	if p.version, err = conf.FieldString(hpFieldVersion); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (p *hl7v2Proc) Process(ctx context.Context, msg *service.Message) (service.MessageBatch, error) {
+	var errs []string
+	var err error
+	switch p.operator {
+	case "to_json", "to_fhir":
+		errs, err = p.parse(msg)
+	case "from_json":
+		err = p.fromJSON(msg)
+	case "from_fhir":
+		errs, err = p.fromFHIR(msg)
+	default:
+		err = fmt.Errorf("operator not recognised: %v", p.operator)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if len(errs) > 0 {
+		if p.strict {
+			return nil, errors.New(strings.Join(errs, "; "))
+		}
+		errVals := make([]any, len(errs))
+		for i, e := range errs {
+			errVals[i] = e
+		}
+		msg.MetaSetMut("hl7v2_errors", errVals)
+	}
+	return service.MessageBatch{msg}, nil
+}
+
+func (p *hl7v2Proc) parse(msg *service.Message) ([]string, error) {
+	raw, err := msg.AsBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	segments, errs, err := parseHL7v2(string(raw))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse HL7v2 message: %w", err)
+	}
+	if reps := hl7Reps(segments[0]["9"]); len(reps) > 0 {
+		msg.MetaSetMut("hl7v2_message_type", defaultDelimiters.encodeComposite(reps[0], defaultDelimiters.comp, true))
+	}
+	if controlID := hl7Field(segments[0], 10); controlID != "" {
+		msg.MetaSetMut("hl7v2_control_id", controlID)
+	}
+
+	if p.operator == "to_fhir" {
+		m := &fhirMapper{errs: errs}
+		msg.SetStructuredMut(m.toFHIR(string(raw), segments))
+		return m.errs, nil
+	}
+
+	if p.groupSegments {
+		grouped := map[string]any{}
+		for _, seg := range segments {
+			id := seg[segmentKey].(string)
+			existing, _ := grouped[id].([]any)
+			grouped[id] = append(existing, seg)
+		}
+		msg.SetStructuredMut(grouped)
+	} else {
+		segs := make([]any, len(segments))
+		for i, seg := range segments {
+			segs[i] = seg
+		}
+		msg.SetStructuredMut(segs)
+	}
+	return errs, nil
+}
+
+func (p *hl7v2Proc) fromJSON(msg *service.Message) error {
+	v, err := msg.AsStructured()
+	if err != nil {
+		return err
+	}
+	segments, ok := v.([]any)
+	if !ok {
+		return fmt.Errorf("expected an array of segments, got %T", v)
+	}
+	raw, err := encodeHL7v2(segments)
+	if err != nil {
+		return fmt.Errorf("failed to serialise HL7v2 message: %w", err)
+	}
+	msg.SetBytes(raw)
+	return nil
+}
+
+func (p *hl7v2Proc) fromFHIR(msg *service.Message) ([]string, error) {
+	v, err := msg.AsStructured()
+	if err != nil {
+		return nil, err
+	}
+	root, ok := v.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("expected a FHIR resource, got %T", v)
+	}
+	if _, ok := root["resourceType"].(string); !ok {
+		return nil, errors.New("expected a FHIR resource, resourceType is missing")
+	}
+
+	m := &fhirMapper{}
+	raw, err := encodeHL7v2(m.fromFHIR(root, p.messageType, p.version))
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialise HL7v2 message: %w", err)
+	}
+	msg.SetBytes(raw)
+	return m.errs, nil
+}
+
+func (p *hl7v2Proc) Close(ctx context.Context) error {
+	return nil
+}
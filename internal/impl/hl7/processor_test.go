@@ -0,0 +1,230 @@
+package hl7
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+var testHL7Message = strings.Join([]string{
+	`MSH|^~\&|LAB|HOSP|EHR|HOSP|20240102030405||ORU^R01|MSG0001|P|2.5`,
+	`PID|1||12345^^^HOSP^MR~999^^^SSA^SS||Doe&van^John^Q||19800102|F|||1 Main St^^Springfield^IL^62701||555-1234`,
+	`PV1|1|I|ICU^101||||D123^Smith^Jane`,
+	`OBX|1|NM|718-7^Hemoglobin^LN||13.5|g/dL|12-16|N|||F|||202401020300`,
+	`OBX|2|ST|NOTE^Note||Line one\.br\with \F\ pipe||||||P`,
+	`bad|segment`,
+	`NTE|1||""`,
+}, "\r") + "\r"
+
+func testHL7v2Proc(t *testing.T, confStr string) *hl7v2Proc {
+	t.Helper()
+
+	conf, err := hl7v2ProcSpec().ParseYAML(confStr, nil)
+	require.NoError(t, err)
+
+	proc, err := newHL7v2ProcFromConfig(conf)
+	require.NoError(t, err)
+	return proc
+}
+
+func TestHL7v2ToJSON(t *testing.T) {
+	proc := testHL7v2Proc(t, `operator: to_json`)
+
+	res, err := proc.Process(context.Background(), service.NewMessage([]byte(testHL7Message)))
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+
+	v, err := res[0].AsStructured()
+	require.NoError(t, err)
+
+	segments := v.([]any)
+	require.Len(t, segments, 6)
+	assert.Equal(t, map[string]any{
+		"segment": "PID",
+		"1":       "1",
+		"3": []any{
+			map[string]any{"1": "12345", "4": "HOSP", "5": "MR"},
+			map[string]any{"1": "999", "4": "SSA", "5": "SS"},
+		},
+		"5": map[string]any{
+			"1": map[string]any{"1": "Doe", "2": "van"},
+			"2": "John",
+			"3": "Q",
+		},
+		"7":  "19800102",
+		"8":  "F",
+		"11": map[string]any{"1": "1 Main St", "3": "Springfield", "4": "IL", "5": "62701"},
+		"13": "555-1234",
+	}, segments[1])
+	assert.Equal(t, "Line one\nwith | pipe", segments[4].(map[string]any)["5"])
+	assert.Equal(t, map[string]any{"segment": "NTE", "1": "1", "3": nil}, segments[5])
+
+	msgType, _ := res[0].MetaGetMut("hl7v2_message_type")
+	assert.Equal(t, "ORU^R01", msgType)
+	controlID, _ := res[0].MetaGetMut("hl7v2_control_id")
+	assert.Equal(t, "MSG0001", controlID)
+	errs, _ := res[0].MetaGetMut("hl7v2_errors")
+	assert.Equal(t, []any{`segment 6: invalid segment identifier: "bad"`}, errs)
+}
+
+func TestHL7v2GroupSegments(t *testing.T) {
+	proc := testHL7v2Proc(t, `
+operator: to_json
+group_segments: true
+`)
+
+	res, err := proc.Process(context.Background(), service.NewMessage([]byte(testHL7Message)))
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+
+	v, err := res[0].AsStructured()
+	require.NoError(t, err)
+
+	grouped := v.(map[string]any)
+	assert.Len(t, grouped["OBX"], 2)
+	assert.Equal(t, "Doe", grouped["PID"].([]any)[0].(map[string]any)["5"].(map[string]any)["1"].(map[string]any)["1"])
+}
+
+func TestHL7v2RoundTrip(t *testing.T) {
+	toJSON := testHL7v2Proc(t, `operator: to_json`)
+	fromJSON := testHL7v2Proc(t, `operator: from_json`)
+
+	input := strings.ReplaceAll(testHL7Message, "bad|segment\r", "")
+
+	res, err := toJSON.Process(context.Background(), service.NewMessage([]byte(input)))
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+
+	b, err := res[0].AsBytes()
+	require.NoError(t, err)
+
+	res, err = fromJSON.Process(context.Background(), service.NewMessage(b))
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+
+	b, err = res[0].AsBytes()
+	require.NoError(t, err)
+	assert.Equal(t, input, string(b))
+
+	_, err = fromJSON.Process(context.Background(), service.NewMessage([]byte(`[{"segment":"PID","1":"1"}]`)))
+	require.Error(t, err)
+}
+
+func TestHL7v2ToFHIR(t *testing.T) {
+	proc := testHL7v2Proc(t, `operator: to_fhir`)
+
+	res, err := proc.Process(context.Background(), service.NewMessage([]byte(testHL7Message)))
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+
+	v, err := res[0].AsStructured()
+	require.NoError(t, err)
+
+	bundle := v.(map[string]any)
+	assert.Equal(t, "Bundle", bundle["resourceType"])
+	assert.Equal(t, "2024-01-02T03:04:05Z", bundle["timestamp"])
+
+	entries := bundle["entry"].([]any)
+	require.Len(t, entries, 5)
+
+	resource := func(i int) map[string]any {
+		return entries[i].(map[string]any)["resource"].(map[string]any)
+	}
+	fullURL := func(i int) string {
+		return entries[i].(map[string]any)["fullUrl"].(string)
+	}
+
+	assert.Equal(t, "MessageHeader", resource(0)["resourceType"])
+	assert.Equal(t, "MSG0001", resource(0)["id"])
+
+	patient := resource(1)
+	assert.Equal(t, "female", patient["gender"])
+	assert.Equal(t, "1980-01-02", patient["birthDate"])
+	assert.Equal(t, []any{map[string]any{
+		"family": "Doe",
+		"given":  []any{"John", "Q"},
+	}}, patient["name"])
+
+	encounter := resource(2)
+	assert.Equal(t, "IMP", encounter["class"].(map[string]any)["code"])
+	assert.Equal(t, map[string]any{"reference": fullURL(1)}, encounter["subject"])
+
+	observation := resource(3)
+	assert.Equal(t, map[string]any{"value": 13.5, "unit": "g/dL"}, observation["valueQuantity"])
+	assert.Equal(t, "2024-01-02T03:00:00Z", observation["effectiveDateTime"])
+	assert.Equal(t, map[string]any{"reference": fullURL(2)}, observation["encounter"])
+	assert.Equal(t, "preliminary", resource(4)["status"])
+
+	// Converting the same message again results in the same bundle.
+	res2, err := proc.Process(context.Background(), service.NewMessage([]byte(testHL7Message)))
+	require.NoError(t, err)
+	v2, err := res2[0].AsStructured()
+	require.NoError(t, err)
+	assert.Equal(t, v, v2)
+}
+
+func TestHL7v2Strict(t *testing.T) {
+	proc := testHL7v2Proc(t, `
+operator: to_fhir
+strict: true
+`)
+
+	_, err := proc.Process(context.Background(), service.NewMessage([]byte(testHL7Message)))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `segment 6: invalid segment identifier: "bad"`)
+
+	_, err = proc.Process(context.Background(), service.NewMessage([]byte("MSH|^~\\&|LAB\rPID|1||||||1980x")))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `segment 2 (PID): birthDate: invalid timestamp: "1980x"`)
+
+	_, err = proc.Process(context.Background(), service.NewMessage([]byte("PID|1")))
+	require.Error(t, err)
+}
+
+func TestHL7v2FromFHIR(t *testing.T) {
+	proc := testHL7v2Proc(t, `operator: from_fhir`)
+
+	res, err := proc.Process(context.Background(), service.NewMessage([]byte(`{
+  "resourceType": "Bundle",
+  "id": "bundle-1",
+  "timestamp": "2024-01-02T03:04:05+01:00",
+  "entry": [
+    {"resource": {
+      "resourceType": "Patient",
+      "identifier": [{"value": "12345", "system": "HOSP"}],
+      "name": [{"family": "Doe", "given": ["John", "Q", "R"]}],
+      "gender": "male",
+      "birthDate": "1980-01-02",
+      "telecom": [
+        {"system": "phone", "value": "555-1234", "use": "work"},
+        {"system": "email", "value": "john@example.com"}
+      ]
+    }},
+    {"resource": {
+      "resourceType": "Observation",
+      "status": "final",
+      "code": {"coding": [{"system": "http://loinc.org", "code": "718-7"}]},
+      "valueQuantity": {"value": 13.5, "unit": "g/dL"}
+    }},
+    {"resource": {"resourceType": "Condition"}}
+  ]
+}`)))
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+
+	b, err := res[0].AsBytes()
+	require.NoError(t, err)
+	assert.Equal(t, strings.Join([]string{
+		`MSH|^~\&|||||20240102030405+0100||ADT^A08|bundle-1|P|2.5`,
+		`PID|1||12345^^^HOSP||Doe^John^Q R||19800102|M|||||^NET^Internet^john@example.com|555-1234`,
+		`OBX|1|NM|718-7^^LN||13.5|g/dL|||||F`,
+	}, "\r")+"\r", string(b))
+
+	errs, _ := res[0].MetaGetMut("hl7v2_errors")
+	assert.Equal(t, []any{"resource 3 (Condition): unsupported resource type"}, errs)
+}
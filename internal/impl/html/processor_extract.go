@@ -0,0 +1,333 @@
+package html
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Jeffail/gabs/v2"
+	"github.com/PuerkitoBio/goquery"
+	"github.com/andybalholm/cascadia"
+	"golang.org/x/net/html/charset"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	hepFieldField         = "field"
+	hepFieldFields        = "fields"
+	hepFieldContentType   = "content_type"
+	hepFieldCharset       = "charset"
+	hepFieldFieldSelector = "selector"
+	hepFieldFieldExtract  = "extract"
+	hepFieldFieldAttr     = "attribute"
+	hepFieldFieldAll      = "all"
+	hepFieldFieldTrim     = "trim"
+)
+
+func htmlExtractProcSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Categories("Parsing").
+		Version("4.28.0").
+		Summary("Extracts text, attributes and fragments from HTML documents with CSS selectors into a structured object.").
+		Description(`
+Each entry of `+"`fields`"+` describes a value to extract from the document, where the key of the entry is the [dot path](/docs/configuration/field_paths) of the resulting value, and `+"`selector`"+` is a [CSS selector](https://developer.mozilla.org/en-US/docs/Web/CSS/CSS_Selectors) identifying the elements to extract from. By default only the first matching element is extracted, and when `+"`all`"+` is set to `+"`true`"+` the values of all matching elements are extracted as an array. Fields where no element matches the selector are omitted unless `+"`all`"+` is set, in which case they are an empty array.
+
+The value extracted from an element is determined by `+"`extract`"+`:
+
+- `+"`text`"+`: The combined text contents of the element and its descendants.
+- `+"`html`"+`: The inner HTML of the element.
+- `+"`outer_html`"+`: The HTML of the element, including the element itself.
+- `+"`attribute`"+`: The value of the attribute named by `+"`attribute`"+`, elements without the attribute are skipped.
+
+When `+"`field`"+` is empty the entire message is parsed as an HTML document and replaced with an object containing the extracted values. Otherwise, the message is parsed as JSON, the HTML document is read from the string at `+"`field`"+`, and the extracted values are added to the message.
+
+### Character Encoding
+
+Documents are converted to UTF-8 before they are parsed. The encoding of a document is detected from a byte order mark, the charset parameter of `+"`content_type`"+`, or the `+"`<meta>`"+` tags of the document, in that order, and when none of these are present the document is assumed to be UTF-8 when valid, or otherwise windows-1252. The detected encoding can be overridden with `+"`charset`"+`.`).
+		Fields(
+			service.NewStringField(hepFieldField).
+				Description("An optional [dot path](/docs/configuration/field_paths) of a string field within JSON messages containing the HTML document. When empty the entire message is treated as the HTML document.").
+				Example("email.body_html").
+				Default(""),
+			service.NewObjectMapField(hepFieldFields,
+				service.NewStringField(hepFieldFieldSelector).
+					Description("A CSS selector identifying the elements to extract from.").
+					Example("h1.title").
+					Example("a[href]"),
+				service.NewStringEnumField(hepFieldFieldExtract, "text", "html", "outer_html", "attribute").
+					Description("The value to extract from matched elements.").
+					Default("text"),
+				service.NewStringField(hepFieldFieldAttr).
+					Description("The name of the attribute to extract when `extract` is set to `attribute`.").
+					Example("href").
+					Default(""),
+				service.NewBoolField(hepFieldFieldAll).
+					Description("Whether to extract the values of all matching elements as an array rather than only the first.").
+					Default(false),
+				service.NewBoolField(hepFieldFieldTrim).
+					Description("Whether to remove leading and trailing whitespace from extracted values.").
+					Default(true),
+			).
+				Description("A map of [dot paths](/docs/configuration/field_paths) to the values to extract into them."),
+			service.NewInterpolatedStringField(hepFieldContentType).
+				Description("An optional content type of documents, the charset parameter of which is used in order to detect their encoding.").
+				Example(`${! meta("Content-Type") }`).
+				Advanced().
+				Optional(),
+			service.NewStringField(hepFieldCharset).
+				Description("An optional character encoding to decode documents with, overriding the detected encoding.").
+				Example("iso-8859-1").
+				Advanced().
+				Optional(),
+		).
+		Example("Web Scraping", "Here we extract the title, links and the first paragraph of pages fetched by an `http_client` input.", `
+pipeline:
+  processors:
+    - html_extract:
+        fields:
+          title:
+            selector: head > title
+          links:
+            selector: a[href]
+            extract: attribute
+            attribute: href
+            all: true
+          summary:
+            selector: article p
+        content_type: ${! meta("Content-Type") }
+`).
+		Example("Email Bodies", "Here we extract the links and plain text from the HTML bodies of emails within JSON documents, keeping the rest of the document intact.", `
+pipeline:
+  processors:
+    - html_extract:
+        field: body_html
+        fields:
+          body_links:
+            selector: a[href]
+            extract: attribute
+            attribute: href
+            all: true
+          body_text:
+            selector: body
+`)
+}
+
+func init() {
+	err := service.RegisterProcessor(
+		"html_extract", htmlExtractProcSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Processor, error) {
+			return newHTMLExtractProcFromConfig(conf)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type htmlExtractField struct {
+	path     string
+	selector cascadia.Selector
+	extract  string
+	attr     string
+	all      bool
+	trim     bool
+}
+
+type htmlExtractProc struct {
+	field       string
+	fields      []htmlExtractField
+	contentType *service.InterpolatedString
+	charset     string
+}
+
+func newHTMLExtractProcFromConfig(conf *service.ParsedConfig) (*htmlExtractProc, error) {
+	h := &htmlExtractProc{}
+
+	var err error
+	if h.field, err = conf.FieldString(hepFieldField); err != nil {
+		return nil, err
+	}
+
+	fieldConfs, err := conf.FieldObjectMap(hepFieldFields)
+	if err != nil {
+		return nil, err
+	}
+	if len(fieldConfs) == 0 {
+		return nil, errors.New("at least one field must be specified")
+	}
+	for path, fConf := range fieldConfs {
+		f := htmlExtractField{path: path}
+
+		selector, err := fConf.FieldString(hepFieldFieldSelector)
+		if err != nil {
+			return nil, err
+		}
+		if f.selector, err = cascadia.Compile(selector); err != nil {
+			return nil, fmt.Errorf("field %v: failed to parse selector: %w", path, err)
+		}
+		if f.extract, err = fConf.FieldString(hepFieldFieldExtract); err != nil {
+			return nil, err
+		}
+		if f.attr, err = fConf.FieldString(hepFieldFieldAttr); err != nil {
+			return nil, err
+		}
+		if f.all, err = fConf.FieldBool(hepFieldFieldAll); err != nil {
+			return nil, err
+		}
+		if f.trim, err = fConf.FieldBool(hepFieldFieldTrim); err != nil {
+			return nil, err
+		}
+		if f.extract == "attribute" && f.attr == "" {
+			return nil, fmt.Errorf("field %v: an `%v` must be specified when extracting attributes", path, hepFieldFieldAttr)
+		}
+		h.fields = append(h.fields, f)
+	}
+	sort.Slice(h.fields, func(i, j int) bool {
+		return h.fields[i].path < h.fields[j].path
+	})
+
+	if conf.Contains(hepFieldContentType) {
+		if h.contentType, err = conf.FieldInterpolatedString(hepFieldContentType); err != nil {
+			return nil, err
+		}
+	}
+	if conf.Contains(hepFieldCharset) {
+		if h.charset, err = conf.FieldString(hepFieldCharset); err != nil {
+			return nil, err
+		}
+		if enc, _ := charset.Lookup(h.charset); enc == nil {
+			return nil, fmt.Errorf("unrecognised charset: %v", h.charset)
+		}
+	}
+	return h, nil
+}
+
+func (f *htmlExtractField) value(s *goquery.Selection) (string, bool, error) {
+	var v string
+	switch f.extract {
+	case "text":
+		v = s.Text()
+	case "html":
+		var err error
+		if v, err = s.Html(); err != nil {
+			return "", false, err
+		}
+	case "outer_html":
+		var err error
+		if v, err = goquery.OuterHtml(s); err != nil {
+			return "", false, err
+		}
+	case "attribute":
+		var exists bool
+		if v, exists = s.Attr(f.attr); !exists {
+			return "", false, nil
+		}
+	}
+	if f.trim {
+		v = strings.TrimSpace(v)
+	}
+	return v, true, nil
+}
+
+func (f *htmlExtractField) extractFrom(doc *goquery.Document) (any, bool, error) {
+	matches := doc.FindMatcher(f.selector)
+	if !f.all {
+		for i := range matches.Nodes {
+			v, ok, err := f.value(matches.Eq(i))
+			if err != nil || ok {
+				return v, ok, err
+			}
+		}
+		return nil, false, nil
+	}
+
+	values := []any{}
+	for i := range matches.Nodes {
+		v, ok, err := f.value(matches.Eq(i))
+		if err != nil {
+			return nil, false, err
+		}
+		if ok {
+			values = append(values, v)
+		}
+	}
+	return values, true, nil
+}
+
+func (h *htmlExtractProc) parseDocument(msg *service.Message, raw []byte) (*goquery.Document, error) {
+	label := h.charset
+	if label == "" {
+		var contentType string
+		if h.contentType != nil {
+			var err error
+			if contentType, err = h.contentType.TryString(msg); err != nil {
+				return nil, fmt.Errorf("content type interpolation error: %w", err)
+			}
+		}
+		_, label, _ = charset.DetermineEncoding(raw, contentType)
+	}
+
+	r, err := charset.NewReaderLabel(label, bytes.NewReader(raw))
+	if err != nil {
+		return nil, err
+	}
+	return goquery.NewDocumentFromReader(r)
+}
+
+func (h *htmlExtractProc) Process(ctx context.Context, msg *service.Message) (service.MessageBatch, error) {
+	var raw []byte
+	var root *gabs.Container
+
+	if h.field == "" {
+		var err error
+		if raw, err = msg.AsBytes(); err != nil {
+			return nil, err
+		}
+		root = gabs.New()
+	} else {
+		v, err := msg.AsStructuredMut()
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse message as JSON: %w", err)
+		}
+		root = gabs.Wrap(v)
+
+		switch t := root.Path(h.field).Data().(type) {
+		case string:
+			raw = []byte(t)
+		case []byte:
+			raw = t
+		case nil:
+			return nil, fmt.Errorf("field %v: not found", h.field)
+		default:
+			return nil, fmt.Errorf("field %v: expected string value, got %T", h.field, t)
+		}
+	}
+
+	doc, err := h.parseDocument(msg, raw)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse HTML document: %w", err)
+	}
+
+	for _, f := range h.fields {
+		v, ok, err := f.extractFrom(doc)
+		if err != nil {
+			return nil, fmt.Errorf("field %v: %w", f.path, err)
+		}
+		if !ok {
+			continue
+		}
+		if _, err := root.SetP(v, f.path); err != nil {
+			return nil, fmt.Errorf("field %v: %w", f.path, err)
+		}
+	}
+
+	msg.SetStructuredMut(root.Data())
+	return service.MessageBatch{msg}, nil
+}
+
+func (h *htmlExtractProc) Close(ctx context.Context) error {
+	return nil
+}
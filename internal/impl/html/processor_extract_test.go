@@ -0,0 +1,160 @@
+package html
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const testHTMLDoc = `<html>
+<head><meta charset="iso-8859-1"><title> Caf` + "\xe9" + ` Menu </title></head>
+<body>
+  <article><p>First <b>para</b></p><p>Second</p></article>
+  <a href="/a">A</a><a>none</a><a href="/b">B</a>
+</body>
+</html>`
+
+func testHTMLExtractProc(t *testing.T, confStr string) *htmlExtractProc {
+	t.Helper()
+
+	conf, err := htmlExtractProcSpec().ParseYAML(confStr, nil)
+	require.NoError(t, err)
+
+	proc, err := newHTMLExtractProcFromConfig(conf)
+	require.NoError(t, err)
+	return proc
+}
+
+func TestHTMLExtractDocument(t *testing.T) {
+	proc := testHTMLExtractProc(t, `
+fields:
+  title:
+    selector: head > title
+  links:
+    selector: a[href]
+    extract: attribute
+    attribute: href
+    all: true
+  article.first:
+    selector: article p
+    extract: html
+  article.first_outer:
+    selector: article p
+    extract: outer_html
+  article.paragraphs:
+    selector: article p
+    all: true
+  missing:
+    selector: table
+  missing_all:
+    selector: table
+    all: true
+`)
+
+	res, err := proc.Process(context.Background(), service.NewMessage([]byte(testHTMLDoc)))
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+
+	v, err := res[0].AsStructured()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{
+		"title": "Café Menu",
+		"links": []any{"/a", "/b"},
+		"article": map[string]any{
+			"first":       "First <b>para</b>",
+			"first_outer": "<p>First <b>para</b></p>",
+			"paragraphs":  []any{"First para", "Second"},
+		},
+		"missing_all": []any{},
+	}, v)
+}
+
+func TestHTMLExtractField(t *testing.T) {
+	proc := testHTMLExtractProc(t, `
+field: email.body
+fields:
+  email.text:
+    selector: p
+    trim: false
+`)
+
+	msg := service.NewMessage([]byte(`{"email":{"subject":"hello","body":"<p> hi <i>there</i> </p>"}}`))
+	res, err := proc.Process(context.Background(), msg)
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+
+	b, err := res[0].AsBytes()
+	require.NoError(t, err)
+	assert.Equal(t, `{"email":{"body":"<p> hi <i>there</i> </p>","subject":"hello","text":" hi there "}}`, string(b))
+
+	_, err = proc.Process(context.Background(), service.NewMessage([]byte(`{"email":{"body":5}}`)))
+	require.Error(t, err)
+}
+
+func TestHTMLExtractCharset(t *testing.T) {
+	doc := []byte("<p>caf\xe9</p>")
+
+	for _, test := range []struct {
+		name   string
+		conf   string
+		meta   map[string]string
+		output string
+	}{
+		{
+			name:   "detected",
+			conf:   "",
+			output: `{"text":"café"}`,
+		},
+		{
+			name:   "content type",
+			conf:   `content_type: ${! meta("Content-Type") }`,
+			meta:   map[string]string{"Content-Type": "text/html; charset=iso-8859-15"},
+			output: `{"text":"café"}`,
+		},
+		{
+			name:   "override",
+			conf:   "charset: utf-8",
+			output: `{"text":"caf` + "�" + `"}`,
+		},
+	} {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			proc := testHTMLExtractProc(t, `
+fields:
+  text:
+    selector: p
+`+test.conf)
+
+			msg := service.NewMessage(doc)
+			for k, v := range test.meta {
+				msg.MetaSetMut(k, v)
+			}
+			res, err := proc.Process(context.Background(), msg)
+			require.NoError(t, err)
+			require.Len(t, res, 1)
+
+			b, err := res[0].AsBytes()
+			require.NoError(t, err)
+			assert.Equal(t, test.output, string(b))
+		})
+	}
+}
+
+func TestHTMLExtractConfigErrors(t *testing.T) {
+	for _, confStr := range []string{
+		`fields: {}`,
+		`fields: { foo: { selector: 'a[href' } }`,
+		`fields: { foo: { selector: a, extract: attribute } }`,
+		`{ fields: { foo: { selector: a } }, charset: nope }`,
+	} {
+		conf, err := htmlExtractProcSpec().ParseYAML(confStr, nil)
+		require.NoError(t, err, confStr)
+
+		_, err = newHTMLExtractProcFromConfig(conf)
+		require.Error(t, err, confStr)
+	}
+}
@@ -0,0 +1,114 @@
+package aws
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+
+	baws "github.com/benthosdev/benthos/v4/internal/impl/aws"
+	"github.com/benthosdev/benthos/v4/internal/impl/iceberg"
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func init() {
+	iceberg.AWSOptFn = func(conf *service.ParsedConfig, opts *iceberg.ClientOptions) error {
+		if enabled, _ := conf.FieldBool(iceberg.ICOFieldAWSEnabled); !enabled {
+			return nil
+		}
+
+		sess, err := baws.GetSession(context.TODO(), conf)
+		if err != nil {
+			return err
+		}
+
+		forcePathStyle, err := conf.FieldBool(iceberg.ICOFieldAWSForcePathStyle)
+		if err != nil {
+			return err
+		}
+
+		storage := &s3Storage{
+			client: s3.NewFromConfig(sess, func(o *s3.Options) {
+				o.UsePathStyle = forcePathStyle
+			}),
+		}
+		for _, scheme := range []string{"s3", "s3a", "s3n"} {
+			opts.Storage[scheme] = storage
+		}
+
+		if sign, _ := conf.FieldBool(iceberg.ICOFieldAWSSignCatalog); sign {
+			signingName, err := conf.FieldString(iceberg.ICOFieldAWSSigningName)
+			if err != nil {
+				return err
+			}
+			opts.RequestSigner = newRequestSigner(sess, signingName)
+		}
+		return nil
+	}
+}
+
+func newRequestSigner(sess aws.Config, signingName string) func(req *http.Request, body []byte) error {
+	signer := v4.NewSigner()
+	return func(req *http.Request, body []byte) error {
+		creds, err := sess.Credentials.Retrieve(req.Context())
+		if err != nil {
+			return err
+		}
+		hash := sha256.Sum256(body)
+		return signer.SignHTTP(req.Context(), creds, req, hex.EncodeToString(hash[:]), signingName, sess.Region, time.Now())
+	}
+}
+
+type s3Storage struct {
+	client *s3.Client
+}
+
+func bucketAndKey(location string) (bucket, key string, err error) {
+	u, err := url.Parse(location)
+	if err != nil {
+		return "", "", err
+	}
+	if u.Host == "" {
+		return "", "", fmt.Errorf("location '%v' is missing a bucket", location)
+	}
+	return u.Host, strings.TrimPrefix(u.Path, "/"), nil
+}
+
+func (s *s3Storage) Put(ctx context.Context, location string, data []byte) error {
+	bucket, key, err := bucketAndKey(location)
+	if err != nil {
+		return err
+	}
+	_, err = s.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket: &bucket,
+		Key:    &key,
+		Body:   bytes.NewReader(data),
+	})
+	return err
+}
+
+func (s *s3Storage) Get(ctx context.Context, location string) ([]byte, error) {
+	bucket, key, err := bucketAndKey(location)
+	if err != nil {
+		return nil, err
+	}
+	obj, err := s.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: &bucket,
+		Key:    &key,
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer obj.Body.Close()
+	return io.ReadAll(obj.Body)
+}
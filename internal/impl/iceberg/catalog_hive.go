@@ -0,0 +1,587 @@
+package iceberg
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"os/user"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+const (
+	hiveDefaultPort = "9083"
+
+	// Field IDs of the Hive metastore Table struct.
+	hiveTableFieldParameters int16 = 9
+
+	hiveLockTypeExclusive int32 = 3
+	hiveLockLevelTable    int32 = 2
+	hiveLockAcquired      int32 = 1
+	hiveLockWaiting       int32 = 2
+
+	hiveLockPollInterval = 50 * time.Millisecond
+
+	// The number of previous metadata files tracked in the metadata log,
+	// matching the default of write.metadata.previous-versions-max.
+	hiveMaxMetadataLog = 100
+)
+
+// hiveError is an exception returned by the metastore for a call, as opposed
+// to a failure of the connection.
+type hiveError struct {
+	method  string
+	message string
+}
+
+func (h *hiveError) Error() string {
+	return fmt.Sprintf("%v: %v", h.method, h.message)
+}
+
+// hiveCatalog loads and commits tables registered in a Hive metastore, which
+// only tracks the location of the current metadata file of each table. Commits
+// therefore write a new metadata file and swap the location of the table while
+// holding an exclusive lock on it, in the same way as the Iceberg Hive catalog.
+type hiveCatalog struct {
+	address  string
+	tlsConf  *tls.Config
+	timeout  time.Duration
+	opts     *ClientOptions
+	user     string
+	hostname string
+
+	mut   sync.Mutex
+	conn  net.Conn
+	w     *thriftWriter
+	r     *thriftReader
+	seqID int32
+}
+
+func newHiveCatalog(uri string, timeout time.Duration, tlsConf *tls.Config, opts *ClientOptions) (*hiveCatalog, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "thrift" {
+		return nil, fmt.Errorf("hive catalog uri must have the thrift scheme, got: %v", uri)
+	}
+
+	address := u.Host
+	if u.Port() == "" {
+		address = net.JoinHostPort(u.Hostname(), hiveDefaultPort)
+	}
+
+	h := &hiveCatalog{
+		address: address,
+		tlsConf: tlsConf,
+		timeout: timeout,
+		opts:    opts,
+		user:    "benthos",
+	}
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		h.user = u.Username
+	}
+	h.hostname, _ = os.Hostname()
+	return h, nil
+}
+
+func (h *hiveCatalog) dial(ctx context.Context) error {
+	dialer := &net.Dialer{Timeout: h.timeout}
+
+	var conn net.Conn
+	var err error
+	if h.tlsConf != nil {
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: h.tlsConf}).DialContext(ctx, "tcp", h.address)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", h.address)
+	}
+	if err != nil {
+		return err
+	}
+
+	h.conn = conn
+	h.w = &thriftWriter{w: bufio.NewWriter(conn)}
+	h.r = &thriftReader{r: bufio.NewReader(conn)}
+	return nil
+}
+
+func (h *hiveCatalog) closeConn() {
+	if h.conn != nil {
+		_ = h.conn.Close()
+		h.conn = nil
+	}
+}
+
+func (h *hiveCatalog) connect(ctx context.Context) error {
+	h.mut.Lock()
+	defer h.mut.Unlock()
+
+	h.closeConn()
+	return h.dial(ctx)
+}
+
+// call invokes a metastore method and returns the value of its result, which
+// is nil for methods without one.
+func (h *hiveCatalog) call(ctx context.Context, method string, args thriftStructValue) (any, error) {
+	h.mut.Lock()
+	defer h.mut.Unlock()
+
+	if h.conn == nil {
+		if err := h.dial(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	res, err := h.roundTrip(ctx, method, args)
+	if err != nil {
+		var hErr *hiveError
+		if !errors.As(err, &hErr) {
+			// The state of the connection is unknown and so a new one is
+			// established for the next call.
+			h.closeConn()
+		}
+	}
+	return res, err
+}
+
+func (h *hiveCatalog) roundTrip(ctx context.Context, method string, args thriftStructValue) (any, error) {
+	deadline := time.Now().Add(h.timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	if err := h.conn.SetDeadline(deadline); err != nil {
+		return nil, err
+	}
+
+	h.seqID++
+	h.w.writeMessageBegin(method, thriftMessageCall, h.seqID)
+	if err := h.w.writeStruct(args); err != nil {
+		return nil, err
+	}
+	if err := h.w.w.Flush(); err != nil {
+		return nil, err
+	}
+
+	name, typ, seqID, err := h.r.readMessageBegin()
+	if err != nil {
+		return nil, err
+	}
+	if name != method || seqID != h.seqID {
+		return nil, fmt.Errorf("unexpected response %v (%v) to call %v (%v)", name, seqID, method, h.seqID)
+	}
+
+	res, err := h.r.readStruct()
+	if err != nil {
+		return nil, err
+	}
+	if typ == thriftMessageException {
+		return nil, &hiveError{method: method, message: res.str(1)}
+	}
+	if typ != thriftMessageReply {
+		return nil, fmt.Errorf("unexpected message type %v", typ)
+	}
+
+	for _, f := range res {
+		if f.id == 0 {
+			return f.value, nil
+		}
+		// Any other field is an exception declared by the method, all of
+		// which have a message as their first field.
+		exc, _ := f.value.(thriftStructValue)
+		return nil, &hiveError{method: method, message: exc.str(1)}
+	}
+	return nil, nil
+}
+
+func hiveDatabase(namespace []string) (string, error) {
+	if len(namespace) != 1 {
+		return "", fmt.Errorf("hive catalogs require a single level namespace, got: %v", strings.Join(namespace, "."))
+	}
+	return namespace[0], nil
+}
+
+func hiveTableParameters(tbl thriftStructValue) map[string]string {
+	f, _ := tbl.field(hiveTableFieldParameters)
+	if m, ok := f.value.(*thriftMapValue); ok {
+		return m.stringMap()
+	}
+	return map[string]string{}
+}
+
+func (h *hiveCatalog) getTable(ctx context.Context, db, table string) (thriftStructValue, error) {
+	res, err := h.call(ctx, "get_table", thriftStructValue{
+		{id: 1, typ: thriftString, value: db},
+		{id: 2, typ: thriftString, value: table},
+	})
+	if err != nil {
+		return nil, err
+	}
+	tbl, ok := res.(thriftStructValue)
+	if !ok {
+		return nil, fmt.Errorf("unexpected get_table result: %T", res)
+	}
+	return tbl, nil
+}
+
+func (h *hiveCatalog) alterTable(ctx context.Context, db, table string, tbl thriftStructValue) error {
+	_, err := h.call(ctx, "alter_table_with_environment_context", thriftStructValue{
+		{id: 1, typ: thriftString, value: db},
+		{id: 2, typ: thriftString, value: table},
+		{id: 3, typ: thriftStruct, value: tbl},
+		{id: 4, typ: thriftStruct, value: thriftStructValue{
+			{id: 1, typ: thriftMap, value: thriftStringMap(map[string]string{
+				"DO_NOT_UPDATE_STATS": "true",
+			})},
+		}},
+	})
+	return err
+}
+
+func hiveLockIDRequest(lockID int64) thriftStructValue {
+	return thriftStructValue{
+		{id: 1, typ: thriftStruct, value: thriftStructValue{
+			{id: 1, typ: thriftI64, value: lockID},
+		}},
+	}
+}
+
+// lock acquires an exclusive lock on a table, waiting for it to be released by
+// other writers for up to the timeout.
+func (h *hiveCatalog) lock(ctx context.Context, db, table string) (int64, error) {
+	res, err := h.call(ctx, "lock", thriftStructValue{
+		{id: 1, typ: thriftStruct, value: thriftStructValue{
+			{id: 1, typ: thriftList, value: &thriftListValue{
+				elemType: thriftStruct,
+				elems: []any{thriftStructValue{
+					{id: 1, typ: thriftI32, value: hiveLockTypeExclusive},
+					{id: 2, typ: thriftI32, value: hiveLockLevelTable},
+					{id: 3, typ: thriftString, value: db},
+					{id: 4, typ: thriftString, value: table},
+				}},
+			}},
+			{id: 3, typ: thriftString, value: h.user},
+			{id: 4, typ: thriftString, value: h.hostname},
+			{id: 5, typ: thriftString, value: "benthos"},
+		}},
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	lockRes, _ := res.(thriftStructValue)
+	lockID := lockRes.i64(1)
+
+	waitUntil := time.Now().Add(h.timeout)
+	for state := lockRes.i32(2); state != hiveLockAcquired; state = lockRes.i32(2) {
+		if state != hiveLockWaiting {
+			h.unlock(lockID)
+			return 0, fmt.Errorf("failed to acquire table lock, state: %v", state)
+		}
+		if time.Now().After(waitUntil) {
+			h.unlock(lockID)
+			return 0, fmt.Errorf("%w: timed out waiting for table lock", errCommitConflict)
+		}
+
+		select {
+		case <-time.After(hiveLockPollInterval):
+		case <-ctx.Done():
+			h.unlock(lockID)
+			return 0, ctx.Err()
+		}
+
+		if res, err = h.call(ctx, "check_lock", hiveLockIDRequest(lockID)); err != nil {
+			h.unlock(lockID)
+			return 0, err
+		}
+		lockRes, _ = res.(thriftStructValue)
+	}
+	return lockID, nil
+}
+
+func (h *hiveCatalog) unlock(lockID int64) {
+	// Locks are released even when the context of a commit is cancelled, and
+	// otherwise time out within the metastore.
+	_, _ = h.call(context.Background(), "unlock", hiveLockIDRequest(lockID))
+}
+
+// readMetadata reads a metadata file both as the subset of fields used by the
+// output and as a raw document, which is modified and written back by commits
+// without losing any fields.
+func (h *hiveCatalog) readMetadata(ctx context.Context, location string) (map[string]any, *tableMetadata, error) {
+	storage, err := h.opts.storageFor(location)
+	if err != nil {
+		return nil, nil, err
+	}
+	data, err := storage.Get(ctx, location)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read metadata file: %w", err)
+	}
+	if bytes.HasPrefix(data, []byte{0x1f, 0x8b}) {
+		gr, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, nil, err
+		}
+		if data, err = io.ReadAll(gr); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	var raw map[string]any
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&raw); err != nil {
+		return nil, nil, fmt.Errorf("failed to decode metadata file: %w", err)
+	}
+
+	var meta tableMetadata
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return nil, nil, fmt.Errorf("failed to decode metadata file: %w", err)
+	}
+	return raw, &meta, nil
+}
+
+func (h *hiveCatalog) loadTable(ctx context.Context, namespace []string, table string) (*loadTableResponse, error) {
+	db, err := hiveDatabase(namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	tbl, err := h.getTable(ctx, db, table)
+	if err != nil {
+		return nil, err
+	}
+	params := hiveTableParameters(tbl)
+	if !strings.EqualFold(params["table_type"], "iceberg") {
+		return nil, fmt.Errorf("table %v.%v is not an Iceberg table", db, table)
+	}
+
+	location := params["metadata_location"]
+	if location == "" {
+		return nil, fmt.Errorf("table %v.%v has no metadata location", db, table)
+	}
+
+	_, meta, err := h.readMetadata(ctx, location)
+	if err != nil {
+		return nil, err
+	}
+	return &loadTableResponse{MetadataLocation: location, Metadata: *meta}, nil
+}
+
+// hiveCommit is the subset of table requirements and updates made by the
+// output, which are applied by the Hive catalog itself.
+type hiveCommit struct {
+	Requirements []struct {
+		Type       string `json:"type"`
+		UUID       string `json:"uuid"`
+		Ref        string `json:"ref"`
+		SnapshotID *int64 `json:"snapshot-id"`
+	} `json:"requirements"`
+	Updates []struct {
+		Action     string          `json:"action"`
+		Snapshot   json.RawMessage `json:"snapshot"`
+		RefName    string          `json:"ref-name"`
+		Type       string          `json:"type"`
+		SnapshotID *int64          `json:"snapshot-id"`
+	} `json:"updates"`
+}
+
+func rawRefSnapshotID(raw map[string]any, ref string) *int64 {
+	var id any
+	if refs, ok := raw["refs"].(map[string]any); ok {
+		if r, ok := refs[ref].(map[string]any); ok {
+			id = r["snapshot-id"]
+		}
+	} else if ref == "main" {
+		id = raw["current-snapshot-id"]
+	}
+	n, ok := id.(json.Number)
+	if !ok {
+		return nil
+	}
+	i, err := n.Int64()
+	if err != nil || i == -1 {
+		return nil
+	}
+	return &i
+}
+
+func rawInt64(raw map[string]any, key string) int64 {
+	n, _ := raw[key].(json.Number)
+	i, _ := n.Int64()
+	return i
+}
+
+func appendRaw(raw map[string]any, key string, v any) {
+	l, _ := raw[key].([]any)
+	raw[key] = append(l, v)
+}
+
+// applyCommit checks the requirements of a commit against the current
+// metadata of a table and applies its updates to it.
+func applyCommit(raw map[string]any, meta *tableMetadata, commit *hiveCommit) error {
+	for _, r := range commit.Requirements {
+		switch r.Type {
+		case "assert-table-uuid":
+			if meta.TableUUID != r.UUID {
+				return fmt.Errorf("%w: table uuid has changed", errCommitConflict)
+			}
+		case "assert-ref-snapshot-id":
+			current := rawRefSnapshotID(raw, r.Ref)
+			if (current == nil) != (r.SnapshotID == nil) || (current != nil && *current != *r.SnapshotID) {
+				return fmt.Errorf("%w: branch %v has changed", errCommitConflict, r.Ref)
+			}
+		default:
+			return fmt.Errorf("requirement %v is not supported", r.Type)
+		}
+	}
+
+	for _, u := range commit.Updates {
+		switch u.Action {
+		case "add-snapshot":
+			var snap snapshot
+			if err := json.Unmarshal(u.Snapshot, &snap); err != nil {
+				return err
+			}
+			var rawSnap map[string]any
+			dec := json.NewDecoder(bytes.NewReader(u.Snapshot))
+			dec.UseNumber()
+			if err := dec.Decode(&rawSnap); err != nil {
+				return err
+			}
+			appendRaw(raw, "snapshots", rawSnap)
+			raw["last-sequence-number"] = snap.SequenceNumber
+			raw["last-updated-ms"] = snap.TimestampMs
+		case "set-snapshot-ref":
+			if u.SnapshotID == nil {
+				return errors.New("set-snapshot-ref requires a snapshot id")
+			}
+			refs, _ := raw["refs"].(map[string]any)
+			if refs == nil {
+				refs = map[string]any{}
+				raw["refs"] = refs
+			}
+			refs[u.RefName] = map[string]any{"snapshot-id": *u.SnapshotID, "type": u.Type}
+			if u.RefName == "main" {
+				raw["current-snapshot-id"] = *u.SnapshotID
+				appendRaw(raw, "snapshot-log", map[string]any{
+					"snapshot-id":  *u.SnapshotID,
+					"timestamp-ms": raw["last-updated-ms"],
+				})
+			}
+		default:
+			return fmt.Errorf("update %v is not supported", u.Action)
+		}
+	}
+	return nil
+}
+
+// nextMetadataLocation returns the location of the metadata file that follows
+// another, named in the same way as by Iceberg (e.g. 00002-<uuid>.metadata.json).
+func nextMetadataLocation(tableLocation, current string) (string, error) {
+	version := -1
+	if prefix, _, found := strings.Cut(path.Base(current), "-"); found {
+		if v, err := strconv.Atoi(prefix); err == nil {
+			version = v
+		}
+	}
+
+	id, err := uuid.NewV4()
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%v/metadata/%05d-%v.metadata.json", strings.TrimSuffix(tableLocation, "/"), version+1, id), nil
+}
+
+func (h *hiveCatalog) commitTable(ctx context.Context, namespace []string, table string, reqBody commitTableRequest) (*loadTableResponse, error) {
+	db, err := hiveDatabase(namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	reqBytes, err := json.Marshal(reqBody)
+	if err != nil {
+		return nil, err
+	}
+	var commit hiveCommit
+	if err := json.Unmarshal(reqBytes, &commit); err != nil {
+		return nil, err
+	}
+
+	lockID, err := h.lock(ctx, db, table)
+	if err != nil {
+		return nil, err
+	}
+	defer h.unlock(lockID)
+
+	// The table is read again while holding the lock, as it may have been
+	// modified since it was loaded.
+	tbl, err := h.getTable(ctx, db, table)
+	if err != nil {
+		return nil, err
+	}
+	params := hiveTableParameters(tbl)
+	baseLocation := params["metadata_location"]
+
+	raw, meta, err := h.readMetadata(ctx, baseLocation)
+	if err != nil {
+		return nil, err
+	}
+
+	previousUpdated := raw["last-updated-ms"]
+	if err := applyCommit(raw, meta, &commit); err != nil {
+		return nil, err
+	}
+
+	metadataLog, _ := raw["metadata-log"].([]any)
+	metadataLog = append(metadataLog, map[string]any{
+		"metadata-file": baseLocation,
+		"timestamp-ms":  previousUpdated,
+	})
+	if len(metadataLog) > hiveMaxMetadataLog {
+		metadataLog = metadataLog[len(metadataLog)-hiveMaxMetadataLog:]
+	}
+	raw["metadata-log"] = metadataLog
+
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return nil, err
+	}
+	var newMeta tableMetadata
+	if err := json.Unmarshal(data, &newMeta); err != nil {
+		return nil, err
+	}
+
+	newLocation, err := nextMetadataLocation(meta.Location, baseLocation)
+	if err != nil {
+		return nil, err
+	}
+	storage, err := h.opts.storageFor(newLocation)
+	if err != nil {
+		return nil, err
+	}
+	if err := storage.Put(ctx, newLocation, data); err != nil {
+		return nil, fmt.Errorf("failed to write metadata file: %w", err)
+	}
+
+	params["previous_metadata_location"] = baseLocation
+	params["metadata_location"] = newLocation
+	tbl = tbl.set(hiveTableFieldParameters, thriftMap, thriftStringMap(params))
+	if err := h.alterTable(ctx, db, table, tbl); err != nil {
+		return nil, err
+	}
+	return &loadTableResponse{MetadataLocation: newLocation, Metadata: newMeta}, nil
+}
@@ -0,0 +1,298 @@
+package iceberg
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type memoryStorage struct {
+	mut   sync.Mutex
+	files map[string][]byte
+}
+
+func (m *memoryStorage) Put(ctx context.Context, location string, data []byte) error {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+	m.files[location] = data
+	return nil
+}
+
+func (m *memoryStorage) Get(ctx context.Context, location string) ([]byte, error) {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+	data, exists := m.files[location]
+	if !exists {
+		return nil, errors.New("not found")
+	}
+	return data, nil
+}
+
+// fakeMetastore is a minimal Hive metastore serving a single table.
+type fakeMetastore struct {
+	mut       sync.Mutex
+	table     thriftStructValue
+	lockWaits int
+	locked    bool
+	unlocks   int
+}
+
+func newFakeMetastore(t *testing.T, metadataLocation string) (*fakeMetastore, string) {
+	t.Helper()
+
+	m := &fakeMetastore{
+		table: thriftStructValue{
+			{id: 1, typ: thriftString, value: "events"},
+			{id: 2, typ: thriftString, value: "analytics"},
+			{id: 4, typ: thriftI32, value: int32(1700000000)},
+			{id: 9, typ: thriftMap, value: thriftStringMap(map[string]string{
+				"table_type":        "ICEBERG",
+				"metadata_location": metadataLocation,
+			})},
+			{id: 12, typ: thriftString, value: "EXTERNAL_TABLE"},
+		},
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go m.serve(conn)
+		}
+	}()
+	return m, ln.Addr().String()
+}
+
+func (m *fakeMetastore) serve(conn net.Conn) {
+	defer conn.Close()
+
+	r := &thriftReader{r: bufio.NewReader(conn)}
+	w := &thriftWriter{w: bufio.NewWriter(conn)}
+	for {
+		name, _, seqID, err := r.readMessageBegin()
+		if err != nil {
+			return
+		}
+		args, err := r.readStruct()
+		if err != nil {
+			return
+		}
+
+		res := m.handle(name, args)
+		w.writeMessageBegin(name, thriftMessageReply, seqID)
+		if err := w.writeStruct(res); err != nil {
+			return
+		}
+		if err := w.w.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+func (m *fakeMetastore) lockResponse() thriftStructValue {
+	state := hiveLockAcquired
+	if m.lockWaits > 0 {
+		m.lockWaits--
+		state = hiveLockWaiting
+	} else {
+		m.locked = true
+	}
+	return thriftStructValue{{id: 0, typ: thriftStruct, value: thriftStructValue{
+		{id: 1, typ: thriftI64, value: int64(7)},
+		{id: 2, typ: thriftI32, value: state},
+	}}}
+}
+
+func (m *fakeMetastore) handle(name string, args thriftStructValue) thriftStructValue {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+
+	switch name {
+	case "get_table":
+		if args.str(1) != "analytics" || args.str(2) != "events" {
+			return thriftStructValue{{id: 2, typ: thriftStruct, value: thriftStructValue{
+				{id: 1, typ: thriftString, value: "table not found"},
+			}}}
+		}
+		return thriftStructValue{{id: 0, typ: thriftStruct, value: m.table}}
+	case "lock", "check_lock":
+		return m.lockResponse()
+	case "unlock":
+		m.locked = false
+		m.unlocks++
+		return thriftStructValue{}
+	case "alter_table_with_environment_context":
+		if !m.locked {
+			return thriftStructValue{{id: 2, typ: thriftStruct, value: thriftStructValue{
+				{id: 1, typ: thriftString, value: "table is not locked"},
+			}}}
+		}
+		f, _ := args.field(3)
+		m.table = f.value.(thriftStructValue)
+		return thriftStructValue{}
+	}
+	return thriftStructValue{{id: 1, typ: thriftStruct, value: thriftStructValue{
+		{id: 1, typ: thriftString, value: "unknown method " + name},
+	}}}
+}
+
+func (m *fakeMetastore) parameters() map[string]string {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+	return hiveTableParameters(m.table)
+}
+
+const testHiveMetadata = `{
+  "format-version": 2,
+  "table-uuid": "9c12d441-03fe-4693-9a96-a0705ddf69c1",
+  "location": "s3://bucket/analytics/events",
+  "last-sequence-number": 0,
+  "last-updated-ms": 1700000000000,
+  "last-column-id": 2,
+  "current-schema-id": 0,
+  "schemas": [{
+    "schema-id": 0,
+    "type": "struct",
+    "fields": [
+      {"id": 1, "name": "id", "required": true, "type": "long"},
+      {"id": 2, "name": "category", "required": false, "type": "string"}
+    ]
+  }],
+  "default-spec-id": 0,
+  "partition-specs": [{"spec-id": 0, "fields": []}],
+  "properties": {"owner": "someone"},
+  "current-snapshot-id": -1,
+  "snapshots": []
+}`
+
+func testHiveCommit(meta *tableMetadata, snapshotID int64) commitTableRequest {
+	var parentID *int64
+	if parent := meta.currentSnapshot(); parent != nil {
+		parentID = &parent.SnapshotID
+	}
+	return commitTableRequest{
+		Requirements: []any{
+			map[string]any{"type": "assert-table-uuid", "uuid": meta.TableUUID},
+			map[string]any{"type": "assert-ref-snapshot-id", "ref": "main", "snapshot-id": parentID},
+		},
+		Updates: []any{
+			map[string]any{"action": "add-snapshot", "snapshot": snapshot{
+				SnapshotID:       snapshotID,
+				ParentSnapshotID: parentID,
+				SequenceNumber:   meta.LastSequenceNumber + 1,
+				TimestampMs:      time.Now().UnixMilli(),
+				ManifestList:     "s3://bucket/analytics/events/metadata/snap.avro",
+			}},
+			map[string]any{"action": "set-snapshot-ref", "ref-name": "main", "type": "branch", "snapshot-id": snapshotID},
+		},
+	}
+}
+
+func TestHiveCatalogCommits(t *testing.T) {
+	ctx, done := context.WithTimeout(context.Background(), time.Minute)
+	defer done()
+
+	initialLocation := "s3://bucket/analytics/events/metadata/00000-a.metadata.json"
+	storage := &memoryStorage{files: map[string][]byte{
+		initialLocation: []byte(testHiveMetadata),
+	}}
+	metastore, addr := newFakeMetastore(t, initialLocation)
+
+	c, err := newHiveCatalog("thrift://"+addr, time.Second*10, nil, &ClientOptions{
+		Storage: map[string]ObjectStorage{"s3": storage},
+	})
+	require.NoError(t, err)
+	require.NoError(t, c.connect(ctx))
+
+	namespace := []string{"analytics"}
+	loaded, err := c.loadTable(ctx, namespace, "events")
+	require.NoError(t, err)
+	assert.Equal(t, initialLocation, loaded.MetadataLocation)
+	assert.Nil(t, loaded.Metadata.currentSnapshot())
+
+	res, err := c.commitTable(ctx, namespace, "events", testHiveCommit(&loaded.Metadata, 100))
+	require.NoError(t, err)
+	require.NotNil(t, res.Metadata.currentSnapshot())
+	assert.Equal(t, int64(100), res.Metadata.currentSnapshot().SnapshotID)
+	assert.True(t, strings.HasPrefix(res.MetadataLocation, "s3://bucket/analytics/events/metadata/00001-"), res.MetadataLocation)
+
+	// A commit based on stale metadata must conflict.
+	_, err = c.commitTable(ctx, namespace, "events", testHiveCommit(&loaded.Metadata, 200))
+	require.ErrorIs(t, err, errCommitConflict)
+
+	// The second commit waits for the lock of the table.
+	metastore.mut.Lock()
+	metastore.lockWaits = 2
+	metastore.mut.Unlock()
+
+	res, err = c.commitTable(ctx, namespace, "events", testHiveCommit(&res.Metadata, 300))
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(res.MetadataLocation, "s3://bucket/analytics/events/metadata/00002-"), res.MetadataLocation)
+
+	params := metastore.parameters()
+	assert.Equal(t, res.MetadataLocation, params["metadata_location"])
+	assert.True(t, strings.HasPrefix(params["previous_metadata_location"], "s3://bucket/analytics/events/metadata/00001-"))
+	assert.Equal(t, "ICEBERG", params["table_type"])
+
+	metastore.mut.Lock()
+	assert.Equal(t, 3, metastore.unlocks)
+	assert.Equal(t, "EXTERNAL_TABLE", metastore.table.str(12))
+	assert.Equal(t, int32(1700000000), metastore.table.i32(4))
+	metastore.mut.Unlock()
+
+	loaded, err = c.loadTable(ctx, namespace, "events")
+	require.NoError(t, err)
+	require.Len(t, loaded.Metadata.Snapshots, 2)
+	assert.Equal(t, int64(2), loaded.Metadata.LastSequenceNumber)
+	assert.Equal(t, int64(100), *loaded.Metadata.currentSnapshot().ParentSnapshotID)
+
+	var raw map[string]any
+	require.NoError(t, json.Unmarshal(storage.files[loaded.MetadataLocation], &raw))
+	assert.Equal(t, map[string]any{"owner": "someone"}, raw["properties"])
+	assert.Equal(t, map[string]any{
+		"main": map[string]any{"snapshot-id": float64(300), "type": "branch"},
+	}, raw["refs"])
+	assert.Len(t, raw["snapshot-log"], 2)
+	require.Len(t, raw["metadata-log"], 2)
+	assert.Equal(t, initialLocation, raw["metadata-log"].([]any)[0].(map[string]any)["metadata-file"])
+}
+
+func TestHiveCatalogErrors(t *testing.T) {
+	ctx, done := context.WithTimeout(context.Background(), time.Minute)
+	defer done()
+
+	_, addr := newFakeMetastore(t, "s3://bucket/analytics/events/metadata/00000-a.metadata.json")
+
+	_, err := newHiveCatalog("http://"+addr, time.Second, nil, &ClientOptions{})
+	require.Error(t, err)
+
+	c, err := newHiveCatalog("thrift://"+addr, time.Second, nil, &ClientOptions{})
+	require.NoError(t, err)
+
+	_, err = c.loadTable(ctx, []string{"analytics"}, "nope")
+	require.EqualError(t, err, "get_table: table not found")
+
+	_, err = c.loadTable(ctx, []string{"prod", "analytics"}, "events")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "single level namespace")
+
+	// The connection remains usable after an exception.
+	_, err = c.loadTable(ctx, []string{"analytics"}, "events")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "storage scheme 's3' is not supported")
+}
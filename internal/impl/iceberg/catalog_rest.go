@@ -0,0 +1,234 @@
+package iceberg
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+var errCommitConflict = errors.New("commit conflict")
+
+// restCatalog is a client for the Iceberg REST catalog API.
+type restCatalog struct {
+	uri        string
+	warehouse  string
+	credential string
+	scope      string
+	headers    map[string]string
+	client     *http.Client
+	signer     func(req *http.Request, body []byte) error
+
+	prefix string
+
+	tokenMut sync.Mutex
+	token    string
+}
+
+type restErrorResponse struct {
+	Error struct {
+		Message string `json:"message"`
+		Type    string `json:"type"`
+		Code    int    `json:"code"`
+	} `json:"error"`
+}
+
+type loadTableResponse struct {
+	MetadataLocation string            `json:"metadata-location"`
+	Metadata         tableMetadata     `json:"metadata"`
+	Config           map[string]string `json:"config"`
+}
+
+type tableIdentifier struct {
+	Namespace []string `json:"namespace"`
+	Name      string   `json:"name"`
+}
+
+type commitTableRequest struct {
+	Identifier   tableIdentifier `json:"identifier"`
+	Requirements []any           `json:"requirements"`
+	Updates      []any           `json:"updates"`
+}
+
+// connect fetches the catalog configuration and, when client credentials are
+// configured, an access token.
+func (c *restCatalog) connect(ctx context.Context) error {
+	if c.credential != "" {
+		if err := c.refreshToken(ctx); err != nil {
+			return err
+		}
+	}
+
+	query := url.Values{}
+	if c.warehouse != "" {
+		query.Set("warehouse", c.warehouse)
+	}
+
+	var conf struct {
+		Defaults  map[string]string `json:"defaults"`
+		Overrides map[string]string `json:"overrides"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/v1/config?"+query.Encode(), nil, &conf); err != nil {
+		return fmt.Errorf("failed to obtain catalog config: %w", err)
+	}
+
+	c.prefix = conf.Defaults["prefix"]
+	if p, exists := conf.Overrides["prefix"]; exists {
+		c.prefix = p
+	}
+	return nil
+}
+
+func (c *restCatalog) refreshToken(ctx context.Context) error {
+	clientID, clientSecret, _ := strings.Cut(c.credential, ":")
+
+	form := url.Values{}
+	form.Set("grant_type", "client_credentials")
+	form.Set("client_id", clientID)
+	form.Set("client_secret", clientSecret)
+	form.Set("scope", c.scope)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.uri+"/v1/oauth/tokens", strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	res, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to obtain an access token: %v", res.Status)
+	}
+
+	var tokenRes struct {
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&tokenRes); err != nil {
+		return err
+	}
+
+	c.tokenMut.Lock()
+	c.token = tokenRes.AccessToken
+	c.tokenMut.Unlock()
+	return nil
+}
+
+func (c *restCatalog) newRequest(ctx context.Context, method, path string, body []byte) (*http.Request, error) {
+	var bodyReader io.Reader
+	if body != nil {
+		bodyReader = bytes.NewReader(body)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.uri+path, bodyReader)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for k, v := range c.headers {
+		req.Header.Set(k, v)
+	}
+
+	c.tokenMut.Lock()
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	c.tokenMut.Unlock()
+
+	if c.signer != nil {
+		if err := c.signer(req, body); err != nil {
+			return nil, err
+		}
+	}
+	return req, nil
+}
+
+func (c *restCatalog) do(ctx context.Context, method, path string, reqBody, resBody any) error {
+	var body []byte
+	if reqBody != nil {
+		var err error
+		if body, err = json.Marshal(reqBody); err != nil {
+			return err
+		}
+	}
+
+	for attempt := 0; ; attempt++ {
+		req, err := c.newRequest(ctx, method, path, body)
+		if err != nil {
+			return err
+		}
+
+		res, err := c.client.Do(req)
+		if err != nil {
+			return err
+		}
+
+		resBytes, err := io.ReadAll(res.Body)
+		res.Body.Close()
+		if err != nil {
+			return err
+		}
+
+		if res.StatusCode == http.StatusUnauthorized && c.credential != "" && attempt == 0 {
+			// The token may have expired, obtain a new one and try again.
+			if err := c.refreshToken(ctx); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if res.StatusCode < 200 || res.StatusCode > 299 {
+			var errRes restErrorResponse
+			_ = json.Unmarshal(resBytes, &errRes)
+
+			msg := errRes.Error.Message
+			if msg == "" {
+				msg = string(resBytes)
+			}
+			if res.StatusCode == http.StatusConflict {
+				return fmt.Errorf("%w: %v", errCommitConflict, msg)
+			}
+			return fmt.Errorf("%v %v: %v: %v", method, path, res.Status, msg)
+		}
+
+		if resBody != nil && len(resBytes) > 0 {
+			return json.Unmarshal(resBytes, resBody)
+		}
+		return nil
+	}
+}
+
+func (c *restCatalog) tablePath(namespace []string, table string) string {
+	path := "/v1/"
+	if c.prefix != "" {
+		path += url.PathEscape(c.prefix) + "/"
+	}
+	return path + "namespaces/" + url.PathEscape(strings.Join(namespace, "\x1f")) + "/tables/" + url.PathEscape(table)
+}
+
+func (c *restCatalog) loadTable(ctx context.Context, namespace []string, table string) (*loadTableResponse, error) {
+	var res loadTableResponse
+	if err := c.do(ctx, http.MethodGet, c.tablePath(namespace, table), nil, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+func (c *restCatalog) commitTable(ctx context.Context, namespace []string, table string, reqBody commitTableRequest) (*loadTableResponse, error) {
+	var res loadTableResponse
+	if err := c.do(ctx, http.MethodPost, c.tablePath(namespace, table), reqBody, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
@@ -0,0 +1,323 @@
+package iceberg
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/linkedin/goavro/v2"
+)
+
+// dataFile describes a parquet data file that has been written to storage.
+type dataFile struct {
+	path            string
+	partition       []any
+	recordCount     int64
+	fileSizeInBytes int64
+}
+
+// manifestFile describes a manifest that is referenced by a manifest list.
+type manifestFile struct {
+	path               string
+	length             int64
+	partitionSpecID    int32
+	content            int32
+	sequenceNumber     int64
+	minSequenceNumber  int64
+	addedSnapshotID    int64
+	addedFilesCount    int32
+	existingFilesCount int32
+	deletedFilesCount  int32
+	addedRowsCount     int64
+	existingRowsCount  int64
+	deletedRowsCount   int64
+
+	// Partition field summaries in their decoded Avro form, these are carried
+	// over verbatim from existing manifest lists.
+	partitions any
+}
+
+func optionalAvroType(t string) []any {
+	return []any{"null", t}
+}
+
+func manifestEntrySchema(p *partitioner) (string, error) {
+	partitionFields := make([]any, len(p.spec.Fields))
+	for i, pf := range p.spec.Fields {
+		partitionFields[i] = map[string]any{
+			"name":     pf.Name,
+			"type":     optionalAvroType(p.avroType(i)),
+			"default":  nil,
+			"field-id": pf.FieldID,
+		}
+	}
+
+	schema := map[string]any{
+		"type": "record",
+		"name": "manifest_entry",
+		"fields": []any{
+			map[string]any{"name": "status", "type": "int", "field-id": 0},
+			map[string]any{"name": "snapshot_id", "type": optionalAvroType("long"), "default": nil, "field-id": 1},
+			map[string]any{"name": "sequence_number", "type": optionalAvroType("long"), "default": nil, "field-id": 3},
+			map[string]any{"name": "file_sequence_number", "type": optionalAvroType("long"), "default": nil, "field-id": 4},
+			map[string]any{
+				"name":     "data_file",
+				"field-id": 2,
+				"type": map[string]any{
+					"type": "record",
+					"name": "r2",
+					"fields": []any{
+						map[string]any{"name": "content", "type": "int", "field-id": 134},
+						map[string]any{"name": "file_path", "type": "string", "field-id": 100},
+						map[string]any{"name": "file_format", "type": "string", "field-id": 101},
+						map[string]any{
+							"name":     "partition",
+							"field-id": 102,
+							"type": map[string]any{
+								"type":   "record",
+								"name":   "r102",
+								"fields": partitionFields,
+							},
+						},
+						map[string]any{"name": "record_count", "type": "long", "field-id": 103},
+						map[string]any{"name": "file_size_in_bytes", "type": "long", "field-id": 104},
+					},
+				},
+			},
+		},
+	}
+
+	b, err := json.Marshal(schema)
+	return string(b), err
+}
+
+const manifestListSchema = `{
+  "type": "record",
+  "name": "manifest_file",
+  "fields": [
+    {"name": "manifest_path", "type": "string", "field-id": 500},
+    {"name": "manifest_length", "type": "long", "field-id": 501},
+    {"name": "partition_spec_id", "type": "int", "field-id": 502},
+    {"name": "content", "type": "int", "field-id": 517},
+    {"name": "sequence_number", "type": "long", "field-id": 515},
+    {"name": "min_sequence_number", "type": "long", "field-id": 516},
+    {"name": "added_snapshot_id", "type": "long", "field-id": 503},
+    {"name": "added_files_count", "type": "int", "field-id": 504},
+    {"name": "existing_files_count", "type": "int", "field-id": 505},
+    {"name": "deleted_files_count", "type": "int", "field-id": 506},
+    {"name": "added_rows_count", "type": "long", "field-id": 512},
+    {"name": "existing_rows_count", "type": "long", "field-id": 513},
+    {"name": "deleted_rows_count", "type": "long", "field-id": 514},
+    {
+      "name": "partitions",
+      "type": ["null", {
+        "type": "array",
+        "element-id": 508,
+        "items": {
+          "type": "record",
+          "name": "r508",
+          "fields": [
+            {"name": "contains_null", "type": "boolean", "field-id": 509},
+            {"name": "contains_nan", "type": ["null", "boolean"], "default": null, "field-id": 518},
+            {"name": "lower_bound", "type": ["null", "bytes"], "default": null, "field-id": 510},
+            {"name": "upper_bound", "type": ["null", "bytes"], "default": null, "field-id": 511}
+          ]
+        }
+      }],
+      "default": null,
+      "field-id": 507
+    }
+  ]
+}`
+
+// writeManifest encodes a manifest of newly added data files as an Avro object
+// container file. Sequence numbers are left unset so that they are inherited
+// from the manifest list entry, which means the manifest remains valid when a
+// commit is retried with a different sequence number.
+func writeManifest(schemaJSON []byte, p *partitioner, snapshotID int64, files []dataFile) ([]byte, error) {
+	avroSchema, err := manifestEntrySchema(p)
+	if err != nil {
+		return nil, err
+	}
+
+	specJSON, err := json.Marshal(p.spec.Fields)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	w, err := goavro.NewOCFWriter(goavro.OCFConfig{
+		W:      &buf,
+		Schema: avroSchema,
+		MetaData: map[string][]byte{
+			"schema":            schemaJSON,
+			"partition-spec":    specJSON,
+			"partition-spec-id": []byte(strconv.Itoa(p.spec.SpecID)),
+			"format-version":    []byte("2"),
+			"content":           []byte("data"),
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	records := make([]any, len(files))
+	for i, f := range files {
+		partition := map[string]any{}
+		for j, pf := range p.spec.Fields {
+			if v := f.partition[j]; v != nil {
+				partition[pf.Name] = goavro.Union(p.avroType(j), v)
+			} else {
+				partition[pf.Name] = nil
+			}
+		}
+		records[i] = map[string]any{
+			"status":               int32(1),
+			"snapshot_id":          goavro.Union("long", snapshotID),
+			"sequence_number":      nil,
+			"file_sequence_number": nil,
+			"data_file": map[string]any{
+				"content":            int32(0),
+				"file_path":          f.path,
+				"file_format":        "PARQUET",
+				"partition":          partition,
+				"record_count":       f.recordCount,
+				"file_size_in_bytes": f.fileSizeInBytes,
+			},
+		}
+	}
+	if err := w.Append(records); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func avroField[T any](record map[string]any, name string) (v T) {
+	raw, exists := record[name]
+	if !exists || raw == nil {
+		return
+	}
+	if u, ok := raw.(map[string]any); ok && len(u) == 1 {
+		// Unwrap union values.
+		for _, uv := range u {
+			raw = uv
+		}
+	}
+	v, _ = raw.(T)
+	return
+}
+
+// readManifestList decodes the entries of an existing manifest list.
+func readManifestList(data []byte) ([]manifestFile, error) {
+	r, err := goavro.NewOCFReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	var files []manifestFile
+	for r.Scan() {
+		v, err := r.Read()
+		if err != nil {
+			return nil, err
+		}
+		record, ok := v.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("unexpected manifest list entry type: %T", v)
+		}
+		files = append(files, manifestFile{
+			path:               avroField[string](record, "manifest_path"),
+			length:             avroField[int64](record, "manifest_length"),
+			partitionSpecID:    avroField[int32](record, "partition_spec_id"),
+			content:            avroField[int32](record, "content"),
+			sequenceNumber:     avroField[int64](record, "sequence_number"),
+			minSequenceNumber:  avroField[int64](record, "min_sequence_number"),
+			addedSnapshotID:    avroField[int64](record, "added_snapshot_id"),
+			addedFilesCount:    avroField[int32](record, "added_files_count") + avroField[int32](record, "added_data_files_count"),
+			existingFilesCount: avroField[int32](record, "existing_files_count") + avroField[int32](record, "existing_data_files_count"),
+			deletedFilesCount:  avroField[int32](record, "deleted_files_count") + avroField[int32](record, "deleted_data_files_count"),
+			addedRowsCount:     avroField[int64](record, "added_rows_count"),
+			existingRowsCount:  avroField[int64](record, "existing_rows_count"),
+			deletedRowsCount:   avroField[int64](record, "deleted_rows_count"),
+			partitions:         normalisePartitionSummaries(avroField[[]any](record, "partitions")),
+		})
+	}
+	return files, r.Err()
+}
+
+// normalisePartitionSummaries converts decoded partition field summaries into
+// a form that can be encoded with our manifest list schema.
+func normalisePartitionSummaries(summaries []any) any {
+	if summaries == nil {
+		return nil
+	}
+	res := make([]any, 0, len(summaries))
+	for _, s := range summaries {
+		record, ok := s.(map[string]any)
+		if !ok {
+			return nil
+		}
+		summary := map[string]any{
+			"contains_null": avroField[bool](record, "contains_null"),
+			"contains_nan":  nil,
+			"lower_bound":   nil,
+			"upper_bound":   nil,
+		}
+		for _, k := range []string{"lower_bound", "upper_bound"} {
+			if b := avroField[[]byte](record, k); b != nil {
+				summary[k] = goavro.Union("bytes", b)
+			}
+		}
+		if nan, exists := record["contains_nan"]; exists && nan != nil {
+			summary["contains_nan"] = goavro.Union("boolean", avroField[bool](record, "contains_nan"))
+		}
+		res = append(res, summary)
+	}
+	return goavro.Union("array", res)
+}
+
+func writeManifestList(snapshotID int64, parentID *int64, sequenceNumber int64, files []manifestFile) ([]byte, error) {
+	parent := "null"
+	if parentID != nil {
+		parent = strconv.FormatInt(*parentID, 10)
+	}
+
+	var buf bytes.Buffer
+	w, err := goavro.NewOCFWriter(goavro.OCFConfig{
+		W:      &buf,
+		Schema: manifestListSchema,
+		MetaData: map[string][]byte{
+			"snapshot-id":        []byte(strconv.FormatInt(snapshotID, 10)),
+			"parent-snapshot-id": []byte(parent),
+			"sequence-number":    []byte(strconv.FormatInt(sequenceNumber, 10)),
+			"format-version":     []byte("2"),
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	records := make([]any, len(files))
+	for i, f := range files {
+		records[i] = map[string]any{
+			"manifest_path":        f.path,
+			"manifest_length":      f.length,
+			"partition_spec_id":    f.partitionSpecID,
+			"content":              f.content,
+			"sequence_number":      f.sequenceNumber,
+			"min_sequence_number":  f.minSequenceNumber,
+			"added_snapshot_id":    f.addedSnapshotID,
+			"added_files_count":    f.addedFilesCount,
+			"existing_files_count": f.existingFilesCount,
+			"deleted_files_count":  f.deletedFilesCount,
+			"added_rows_count":     f.addedRowsCount,
+			"existing_rows_count":  f.existingRowsCount,
+			"deleted_rows_count":   f.deletedRowsCount,
+			"partitions":           f.partitions,
+		}
+	}
+	if err := w.Append(records); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,357 @@
+package iceberg
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+
+	"github.com/benthosdev/benthos/v4/internal/value"
+)
+
+// tableMetadata is the subset of the Iceberg table metadata that this output
+// needs in order to write data files and commit snapshots.
+type tableMetadata struct {
+	FormatVersion      int             `json:"format-version"`
+	TableUUID          string          `json:"table-uuid"`
+	Location           string          `json:"location"`
+	LastSequenceNumber int64           `json:"last-sequence-number"`
+	CurrentSchemaID    int             `json:"current-schema-id"`
+	Schemas            []tableSchema   `json:"schemas"`
+	DefaultSpecID      int             `json:"default-spec-id"`
+	PartitionSpecs     []partitionSpec `json:"partition-specs"`
+	CurrentSnapshotID  *int64          `json:"current-snapshot-id"`
+	Snapshots          []snapshot      `json:"snapshots"`
+}
+
+type tableSchema struct {
+	SchemaID int           `json:"schema-id"`
+	Type     string        `json:"type"`
+	Fields   []schemaField `json:"fields"`
+}
+
+type schemaField struct {
+	ID       int             `json:"id"`
+	Name     string          `json:"name"`
+	Required bool            `json:"required"`
+	Type     json.RawMessage `json:"type"`
+	Doc      string          `json:"doc,omitempty"`
+}
+
+type partitionSpec struct {
+	SpecID int              `json:"spec-id"`
+	Fields []partitionField `json:"fields"`
+}
+
+type partitionField struct {
+	SourceID  int    `json:"source-id"`
+	FieldID   int    `json:"field-id"`
+	Name      string `json:"name"`
+	Transform string `json:"transform"`
+}
+
+type snapshot struct {
+	SnapshotID       int64             `json:"snapshot-id"`
+	ParentSnapshotID *int64            `json:"parent-snapshot-id,omitempty"`
+	SequenceNumber   int64             `json:"sequence-number"`
+	TimestampMs      int64             `json:"timestamp-ms"`
+	ManifestList     string            `json:"manifest-list"`
+	Summary          map[string]string `json:"summary"`
+	SchemaID         *int              `json:"schema-id,omitempty"`
+}
+
+func (m *tableMetadata) currentSchema() (*tableSchema, error) {
+	for i := range m.Schemas {
+		if m.Schemas[i].SchemaID == m.CurrentSchemaID {
+			return &m.Schemas[i], nil
+		}
+	}
+	return nil, fmt.Errorf("current schema %v not found in table metadata", m.CurrentSchemaID)
+}
+
+func (m *tableMetadata) defaultSpec() (*partitionSpec, error) {
+	for i := range m.PartitionSpecs {
+		if m.PartitionSpecs[i].SpecID == m.DefaultSpecID {
+			return &m.PartitionSpecs[i], nil
+		}
+	}
+	return nil, fmt.Errorf("default partition spec %v not found in table metadata", m.DefaultSpecID)
+}
+
+func (m *tableMetadata) currentSnapshot() *snapshot {
+	if m.CurrentSnapshotID == nil {
+		return nil
+	}
+	for i := range m.Snapshots {
+		if m.Snapshots[i].SnapshotID == *m.CurrentSnapshotID {
+			return &m.Snapshots[i]
+		}
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+// column is a top level column of a table schema along with the means to
+// convert message values into values of its type.
+type column struct {
+	field    schemaField
+	typeName string
+}
+
+func columnsFromSchema(s *tableSchema) ([]column, error) {
+	cols := make([]column, 0, len(s.Fields))
+	for _, f := range s.Fields {
+		var typeName string
+		if err := json.Unmarshal(f.Type, &typeName); err != nil {
+			return nil, fmt.Errorf("column %v: nested types are not supported", f.Name)
+		}
+		switch typeName {
+		case "boolean", "int", "long", "float", "double", "string", "binary", "date", "time", "timestamp", "timestamptz":
+		default:
+			return nil, fmt.Errorf("column %v: type %v is not supported", f.Name, typeName)
+		}
+		cols = append(cols, column{field: f, typeName: typeName})
+	}
+	return cols, nil
+}
+
+func (c column) parquetNode() parquet.Node {
+	var n parquet.Node
+	switch c.typeName {
+	case "boolean":
+		n = parquet.Leaf(parquet.BooleanType)
+	case "int":
+		n = parquet.Int(32)
+	case "long":
+		n = parquet.Int(64)
+	case "float":
+		n = parquet.Leaf(parquet.FloatType)
+	case "double":
+		n = parquet.Leaf(parquet.DoubleType)
+	case "string":
+		n = parquet.String()
+	case "binary":
+		n = parquet.Leaf(parquet.ByteArrayType)
+	case "date":
+		n = parquet.Date()
+	case "time":
+		n = parquet.Time(parquet.Microsecond)
+	case "timestamp", "timestamptz":
+		n = parquet.Timestamp(parquet.Microsecond)
+	}
+	if !c.field.Required {
+		n = parquet.Optional(n)
+	}
+	return parquet.FieldID(n, c.field.ID)
+}
+
+func parquetSchemaFromColumns(cols []column) *parquet.Schema {
+	group := parquet.Group{}
+	for _, c := range cols {
+		group[c.field.Name] = c.parquetNode()
+	}
+	return parquet.NewSchema("table", group)
+}
+
+func floorDiv(a, b int64) int64 {
+	q := a / b
+	if a%b != 0 && a < 0 {
+		q--
+	}
+	return q
+}
+
+func timeFromValue(v any) (time.Time, error) {
+	if s, ok := v.(string); ok {
+		if t, err := time.Parse("2006-01-02", s); err == nil {
+			return t, nil
+		}
+	}
+	return value.IGetTimestamp(v)
+}
+
+// convert a message value into the value written to parquet for this column.
+// Dates are represented as days since the epoch, times as microseconds since
+// midnight and timestamps as microseconds since the epoch.
+func (c column) convert(v any) (any, error) {
+	if v == nil {
+		if c.field.Required {
+			return nil, fmt.Errorf("column %v is required but the value is missing", c.field.Name)
+		}
+		return nil, nil
+	}
+
+	var res any
+	var err error
+	switch c.typeName {
+	case "boolean":
+		res, err = value.IToBool(v)
+	case "int":
+		res, err = value.IToInt32(v)
+	case "long":
+		res, err = value.IToInt(v)
+	case "float":
+		res, err = value.IToFloat32(v)
+	case "double":
+		res, err = value.IToFloat64(v)
+	case "string":
+		res = value.IToString(v)
+	case "binary":
+		res = value.IToBytes(v)
+	case "date":
+		var t time.Time
+		if t, err = timeFromValue(v); err == nil {
+			res = int32(floorDiv(t.Unix(), 86400))
+		}
+	case "time":
+		res, err = value.IToInt(v)
+	case "timestamp", "timestamptz":
+		var t time.Time
+		if t, err = timeFromValue(v); err == nil {
+			res = t.UnixMicro()
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("column %v: %w", c.field.Name, err)
+	}
+	return res, nil
+}
+
+//------------------------------------------------------------------------------
+
+// partitioner computes the partition values of rows for a partition spec.
+type partitioner struct {
+	spec    *partitionSpec
+	sources []column
+}
+
+func newPartitioner(spec *partitionSpec, cols []column) (*partitioner, error) {
+	p := &partitioner{spec: spec}
+	for _, pf := range spec.Fields {
+		var source *column
+		for i := range cols {
+			if cols[i].field.ID == pf.SourceID {
+				source = &cols[i]
+				break
+			}
+		}
+		if source == nil {
+			return nil, fmt.Errorf("partition field %v: source column %v not found in schema", pf.Name, pf.SourceID)
+		}
+
+		switch pf.Transform {
+		case "identity", "void":
+		case "year", "month", "day", "hour":
+			switch source.typeName {
+			case "date", "timestamp", "timestamptz":
+			default:
+				return nil, fmt.Errorf("partition field %v: transform %v cannot be applied to type %v", pf.Name, pf.Transform, source.typeName)
+			}
+			if pf.Transform == "hour" && source.typeName == "date" {
+				return nil, fmt.Errorf("partition field %v: transform hour cannot be applied to type date", pf.Name)
+			}
+		default:
+			return nil, fmt.Errorf("partition field %v: transform %v is not supported", pf.Name, pf.Transform)
+		}
+		p.sources = append(p.sources, *source)
+	}
+	return p, nil
+}
+
+// avroType returns the Avro type of a partition field value.
+func (p *partitioner) avroType(i int) string {
+	switch p.spec.Fields[i].Transform {
+	case "year", "month", "day", "hour":
+		return "int"
+	}
+	switch p.sources[i].typeName {
+	case "boolean":
+		return "boolean"
+	case "int", "date":
+		return "int"
+	case "long", "time", "timestamp", "timestamptz":
+		return "long"
+	case "float":
+		return "float"
+	case "double":
+		return "double"
+	case "binary":
+		return "bytes"
+	}
+	return "string"
+}
+
+func epochMicrosToTime(source column, v any) time.Time {
+	if source.typeName == "date" {
+		return time.Unix(int64(v.(int32))*86400, 0).UTC()
+	}
+	return time.UnixMicro(v.(int64)).UTC()
+}
+
+// values returns the partition values of a row that has already been
+// converted into parquet column values.
+func (p *partitioner) values(row map[string]any) []any {
+	values := make([]any, len(p.spec.Fields))
+	for i, pf := range p.spec.Fields {
+		v := row[p.sources[i].field.Name]
+		if v == nil || pf.Transform == "void" {
+			continue
+		}
+		switch pf.Transform {
+		case "identity":
+			values[i] = v
+		case "year":
+			t := epochMicrosToTime(p.sources[i], v)
+			values[i] = int32(t.Year() - 1970)
+		case "month":
+			t := epochMicrosToTime(p.sources[i], v)
+			values[i] = int32((t.Year()-1970)*12 + int(t.Month()) - 1)
+		case "day":
+			t := epochMicrosToTime(p.sources[i], v)
+			values[i] = int32(floorDiv(t.Unix(), 86400))
+		case "hour":
+			t := epochMicrosToTime(p.sources[i], v)
+			values[i] = int32(floorDiv(t.Unix(), 3600))
+		}
+	}
+	return values
+}
+
+// path returns the relative directory path of a data file for a set of
+// partition values, following the conventions of the Java implementation.
+func (p *partitioner) path(values []any) string {
+	segments := make([]string, len(values))
+	for i, v := range values {
+		pf := p.spec.Fields[i]
+		var str string
+		switch {
+		case v == nil:
+			str = "null"
+		case pf.Transform == "year":
+			str = strconv.Itoa(1970 + int(v.(int32)))
+		case pf.Transform == "month":
+			m := int(v.(int32))
+			str = fmt.Sprintf("%04d-%02d", 1970+m/12, m%12+1)
+		case pf.Transform == "day":
+			str = time.Unix(int64(v.(int32))*86400, 0).UTC().Format("2006-01-02")
+		case pf.Transform == "hour":
+			str = time.Unix(int64(v.(int32))*3600, 0).UTC().Format("2006-01-02-15")
+		case p.sources[i].typeName == "date":
+			str = time.Unix(int64(v.(int32))*86400, 0).UTC().Format("2006-01-02")
+		case p.sources[i].typeName == "timestamp" || p.sources[i].typeName == "timestamptz":
+			str = time.UnixMicro(v.(int64)).UTC().Format("2006-01-02T15:04:05.999999")
+		default:
+			str = value.IToString(v)
+		}
+		segments[i] = url.QueryEscape(pf.Name) + "=" + url.QueryEscape(str)
+	}
+	return strings.Join(segments, "/")
+}
+
+var errUnsupportedFormatVersion = errors.New("only Iceberg format version 2 tables are supported")
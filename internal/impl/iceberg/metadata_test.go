@@ -0,0 +1,125 @@
+package iceberg
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testColumns(t *testing.T) []column {
+	t.Helper()
+
+	var schema tableSchema
+	require.NoError(t, json.Unmarshal([]byte(`{
+  "schema-id": 0,
+  "type": "struct",
+  "fields": [
+    {"id": 1, "name": "id", "required": true, "type": "long"},
+    {"id": 2, "name": "name", "required": false, "type": "string"},
+    {"id": 3, "name": "ts", "required": false, "type": "timestamptz"},
+    {"id": 4, "name": "day", "required": false, "type": "date"}
+  ]
+}`), &schema))
+
+	cols, err := columnsFromSchema(&schema)
+	require.NoError(t, err)
+	return cols
+}
+
+func TestColumnsFromSchemaUnsupported(t *testing.T) {
+	var schema tableSchema
+	require.NoError(t, json.Unmarshal([]byte(`{
+  "schema-id": 0,
+  "type": "struct",
+  "fields": [
+    {"id": 1, "name": "tags", "required": false, "type": {"type": "list", "element-id": 2, "element": "string", "element-required": false}}
+  ]
+}`), &schema))
+
+	_, err := columnsFromSchema(&schema)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "nested types are not supported")
+}
+
+func TestColumnConvert(t *testing.T) {
+	cols := testColumns(t)
+
+	v, err := cols[0].convert(5.0)
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), v)
+
+	_, err = cols[0].convert(nil)
+	require.Error(t, err)
+
+	v, err = cols[1].convert(nil)
+	require.NoError(t, err)
+	assert.Nil(t, v)
+
+	v, err = cols[2].convert("2024-03-05T10:00:00Z")
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 3, 5, 10, 0, 0, 0, time.UTC).UnixMicro(), v)
+
+	v, err = cols[3].convert("1969-12-31")
+	require.NoError(t, err)
+	assert.Equal(t, int32(-1), v)
+}
+
+func TestPartitioner(t *testing.T) {
+	cols := testColumns(t)
+
+	p, err := newPartitioner(&partitionSpec{
+		SpecID: 0,
+		Fields: []partitionField{
+			{SourceID: 3, FieldID: 1000, Name: "ts_year", Transform: "year"},
+			{SourceID: 3, FieldID: 1001, Name: "ts_month", Transform: "month"},
+			{SourceID: 3, FieldID: 1002, Name: "ts_day", Transform: "day"},
+			{SourceID: 3, FieldID: 1003, Name: "ts_hour", Transform: "hour"},
+			{SourceID: 2, FieldID: 1004, Name: "name", Transform: "identity"},
+			{SourceID: 4, FieldID: 1005, Name: "day", Transform: "identity"},
+		},
+	}, cols)
+	require.NoError(t, err)
+
+	ts := time.Date(2024, 3, 5, 10, 30, 0, 0, time.UTC)
+	values := p.values(map[string]any{
+		"ts":   ts.UnixMicro(),
+		"name": "foo bar",
+		"day":  int32(19787),
+	})
+	assert.Equal(t, []any{
+		int32(54),
+		int32(54*12 + 2),
+		int32(19787),
+		int32(ts.Unix() / 3600),
+		"foo bar",
+		int32(19787),
+	}, values)
+	assert.Equal(t, "ts_year=2024/ts_month=2024-03/ts_day=2024-03-05/ts_hour=2024-03-05-10/name=foo+bar/day=2024-03-05", p.path(values))
+
+	values = p.values(map[string]any{})
+	assert.Equal(t, []any{nil, nil, nil, nil, nil, nil}, values)
+	assert.Equal(t, "ts_year=null/ts_month=null/ts_day=null/ts_hour=null/name=null/day=null", p.path(values))
+
+	assert.Equal(t, "int", p.avroType(0))
+	assert.Equal(t, "string", p.avroType(4))
+	assert.Equal(t, "int", p.avroType(5))
+}
+
+func TestPartitionerUnsupported(t *testing.T) {
+	cols := testColumns(t)
+
+	_, err := newPartitioner(&partitionSpec{
+		Fields: []partitionField{{SourceID: 1, FieldID: 1000, Name: "id_bucket", Transform: "bucket[16]"}},
+	}, cols)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "not supported")
+
+	_, err = newPartitioner(&partitionSpec{
+		Fields: []partitionField{{SourceID: 2, FieldID: 1000, Name: "name_day", Transform: "day"}},
+	}, cols)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "cannot be applied")
+}
@@ -0,0 +1,641 @@
+package iceberg
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/parquet-go/parquet-go"
+	"github.com/parquet-go/parquet-go/compress"
+
+	"github.com/benthosdev/benthos/v4/internal/impl/aws/config"
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	icoFieldCatalog           = "catalog"
+	icoFieldCatalogType       = "type"
+	icoFieldCatalogURI        = "uri"
+	icoFieldCatalogWarehouse  = "warehouse"
+	icoFieldCatalogToken      = "token"
+	icoFieldCatalogCredential = "credential"
+	icoFieldCatalogScope      = "scope"
+	icoFieldCatalogHeaders    = "headers"
+	icoFieldNamespace         = "namespace"
+	icoFieldTable             = "table"
+	icoFieldCompression       = "compression"
+	icoFieldCommitRetries     = "commit_retries"
+	icoFieldTimeout           = "timeout"
+	icoFieldTLS               = "tls"
+	icoFieldBatching          = "batching"
+	icoFieldAWS               = "aws"
+
+	// ICOFieldAWSEnabled enables AWS storage and signing.
+	ICOFieldAWSEnabled = "enabled"
+	// ICOFieldAWSSignCatalog enables SigV4 signing of catalog requests.
+	ICOFieldAWSSignCatalog = "sign_catalog_requests"
+	// ICOFieldAWSSigningName is the service name used when signing.
+	ICOFieldAWSSigningName = "signing_name"
+	// ICOFieldAWSForcePathStyle forces path style S3 URLs.
+	ICOFieldAWSForcePathStyle = "force_path_style_urls"
+)
+
+func notImportedAWSOptFn(conf *service.ParsedConfig, opts *ClientOptions) error {
+	if enabled, _ := conf.FieldBool(ICOFieldAWSEnabled); !enabled {
+		return nil
+	}
+	return errors.New("unable to configure AWS storage as this binary does not import components/aws")
+}
+
+// AWSOptFn is populated with the child `aws` package when imported.
+var AWSOptFn = notImportedAWSOptFn
+
+// AWSField represents the aws block within an iceberg output. This is exported
+// in order to make unit testing easier within the aws subpackage.
+func AWSField() *service.ConfigField {
+	return service.NewObjectField(icoFieldAWS,
+		append([]*service.ConfigField{
+			service.NewBoolField(ICOFieldAWSEnabled).
+				Description("Whether to enable writing table files to S3 (locations with the `s3`, `s3a` or `s3n` scheme).").
+				Default(false),
+			service.NewBoolField(ICOFieldAWSSignCatalog).
+				Description("Whether to sign catalog requests with AWS Signature Version 4, which is required for the AWS Glue Iceberg REST endpoint and S3 Tables.").
+				Default(false),
+			service.NewStringField(ICOFieldAWSSigningName).
+				Description("The service name to use when signing catalog requests.").
+				Example("glue").
+				Example("s3tables").
+				Default("glue"),
+			service.NewBoolField(ICOFieldAWSForcePathStyle).
+				Description("Forces the client API to use path style URLs, which helps when connecting to custom endpoints.").
+				Default(false),
+		}, config.SessionFields()...)...).
+		Description("Enables and customises connectivity to Amazon Web Services.").
+		Advanced()
+}
+
+func outputSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Services").
+		Version("4.28.0").
+		Summary("Writes messages as Parquet data files to an [Apache Iceberg](https://iceberg.apache.org/) table and commits them via a REST catalog or Hive metastore.").
+		Description(`
+Each batch of messages is written as one or more Parquet data files (one per partition), which are then committed to the table as a single append snapshot. Batching should therefore be configured in order to avoid committing a large number of small files.
+
+### Catalogs
+
+Tables are loaded and committed using the [Iceberg REST catalog API](https://iceberg.apache.org/concepts/catalog/#decoupling-using-the-rest-catalog). This includes catalogs such as Apache Polaris, Nessie, Unity Catalog and Tabular, and the AWS Glue Data Catalog via its Iceberg REST endpoint `+"(`https://glue.<region>.amazonaws.com/iceberg`)"+` when requests are signed using the `+"`aws`"+` fields.
+
+When the `+"`catalog.type`"+` is `+"`hive`"+` tables are loaded and committed using the Thrift API of a Hive metastore in the same way as the Iceberg Hive catalog, where the metadata files of a table are read from and written to its location directly and the `+"`metadata_location`"+` property of the table is swapped while holding an exclusive lock on it. The namespace is then the name of the database, and connections can be secured with TLS but SASL and Kerberos authentication are not supported.
+
+### Schema Mapping
+
+The table must already exist. Messages must be objects, and each top level column of the current table schema is populated from the message field of the same name, other fields are ignored. Columns of type `+"`boolean`, `int`, `long`, `float`, `double`, `string`, `binary`, `date`, `time`, `timestamp` and `timestamptz`"+` are supported, and dates and timestamps can be provided either as RFC 3339 strings or unix timestamps.
+
+### Partitioning
+
+Data files are partitioned according to the default partition spec of the table, and the `+"`identity`, `year`, `month`, `day`, `hour` and `void`"+` transforms are supported.
+
+### Storage
+
+Table files are written relative to the location of the table. Local filesystem locations are supported by default, and S3 locations are supported when the `+"`aws.enabled`"+` field is set.
+
+### Concurrent Commits
+
+When a commit fails because the table was modified concurrently the table is reloaded and the commit is retried on top of the latest snapshot, up to `+"`commit_retries`"+` times.`+service.OutputPerformanceDocs(true, true)).
+		Fields(
+			service.NewObjectField(icoFieldCatalog,
+				service.NewStringAnnotatedEnumField(icoFieldCatalogType, map[string]string{
+					"rest": "An Iceberg REST catalog.",
+					"hive": "A Hive metastore, connected to using its Thrift API.",
+				}).
+					Description("The type of catalog.").
+					Default("rest"),
+				service.NewURLField(icoFieldCatalogURI).
+					Description("The URI of the catalog, which is the base URI of a REST catalog or a `thrift://<host>:<port>` address of a Hive metastore.").
+					Example("http://localhost:8181").
+					Example("https://glue.us-east-1.amazonaws.com/iceberg").
+					Example("thrift://localhost:9083"),
+				service.NewStringField(icoFieldCatalogWarehouse).
+					Description("The warehouse to request from a REST catalog.").
+					Default(""),
+				service.NewStringField(icoFieldCatalogToken).
+					Description("An optional bearer token to authenticate with a REST catalog.").
+					Default("").
+					Secret(),
+				service.NewStringField(icoFieldCatalogCredential).
+					Description("Optional client credentials in the form `<client_id>:<client_secret>`, which are exchanged for an access token using the OAuth2 endpoint of a REST catalog.").
+					Default("").
+					Secret(),
+				service.NewStringField(icoFieldCatalogScope).
+					Description("The scope to request when exchanging client credentials for an access token.").
+					Default("catalog").
+					Advanced(),
+				service.NewStringMapField(icoFieldCatalogHeaders).
+					Description("A map of headers to add to REST catalog requests.").
+					Default(map[string]any{}).
+					Advanced(),
+			).Description("The catalog to load and commit the table with."),
+			service.NewStringField(icoFieldNamespace).
+				Description("The namespace of the table, multiple levels are separated with a dot.").
+				Example("analytics").
+				Example("prod.analytics"),
+			service.NewStringField(icoFieldTable).
+				Description("The name of the table to write to."),
+			service.NewStringEnumField(icoFieldCompression, "uncompressed", "snappy", "gzip", "zstd").
+				Description("The compression to use for data files.").
+				Default("zstd").
+				Advanced(),
+			service.NewIntField(icoFieldCommitRetries).
+				Description("The maximum number of times to retry a commit that failed due to a concurrent modification of the table.").
+				Default(5).
+				Advanced(),
+			service.NewDurationField(icoFieldTimeout).
+				Description("The maximum period to wait for catalog requests to complete.").
+				Default("30s").
+				Advanced(),
+			service.NewTLSToggledField(icoFieldTLS),
+			service.NewOutputMaxInFlightField().Default(1),
+			service.NewBatchPolicyField(icoFieldBatching),
+			AWSField(),
+		).
+		Example("Writing to a REST Catalog", "Write batches of events to an Iceberg table, committing a snapshot roughly every minute:", `
+output:
+  iceberg:
+    catalog:
+      uri: http://localhost:8181
+    namespace: analytics
+    table: events
+    batching:
+      count: 10000
+      period: 60s
+`).
+		Example("Writing to a Hive Metastore", "Write batches of events to an Iceberg table registered in a Hive metastore, with table files stored in S3:", `
+output:
+  iceberg:
+    catalog:
+      type: hive
+      uri: thrift://localhost:9083
+    namespace: analytics
+    table: events
+    aws:
+      enabled: true
+      region: us-east-1
+    batching:
+      count: 10000
+      period: 60s
+`)
+}
+
+func init() {
+	err := service.RegisterBatchOutput("iceberg", outputSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (out service.BatchOutput, batchPolicy service.BatchPolicy, maxInFlight int, err error) {
+			if maxInFlight, err = conf.FieldMaxInFlight(); err != nil {
+				return
+			}
+			if batchPolicy, err = conf.FieldBatchPolicy(icoFieldBatching); err != nil {
+				return
+			}
+			out, err = newOutputFromParsed(conf, mgr)
+			return
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// catalog loads tables and commits changes to them, where commits fail with
+// errCommitConflict when a requirement of the change is no longer met.
+type catalog interface {
+	connect(ctx context.Context) error
+	loadTable(ctx context.Context, namespace []string, table string) (*loadTableResponse, error)
+	commitTable(ctx context.Context, namespace []string, table string, reqBody commitTableRequest) (*loadTableResponse, error)
+}
+
+type output struct {
+	log *service.Logger
+
+	catalog       catalog
+	opts          ClientOptions
+	namespace     []string
+	table         string
+	compression   compress.Codec
+	commitRetries int
+
+	mut    sync.Mutex
+	loaded *loadTableResponse
+}
+
+func newOutputFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*output, error) {
+	o := &output{
+		log: mgr.Logger(),
+		opts: ClientOptions{
+			Storage: map[string]ObjectStorage{
+				"file": &localStorage{fs: mgr.FS()},
+			},
+		},
+	}
+
+	namespace, err := conf.FieldString(icoFieldNamespace)
+	if err != nil {
+		return nil, err
+	}
+	if namespace == "" {
+		return nil, errors.New("a namespace must be specified")
+	}
+	o.namespace = strings.Split(namespace, ".")
+
+	if o.table, err = conf.FieldString(icoFieldTable); err != nil {
+		return nil, err
+	}
+
+	compressStr, err := conf.FieldString(icoFieldCompression)
+	if err != nil {
+		return nil, err
+	}
+	switch compressStr {
+	case "uncompressed":
+		o.compression = &parquet.Uncompressed
+	case "snappy":
+		o.compression = &parquet.Snappy
+	case "gzip":
+		o.compression = &parquet.Gzip
+	case "zstd":
+		o.compression = &parquet.Zstd
+	default:
+		return nil, fmt.Errorf("compression type %v not recognised", compressStr)
+	}
+
+	if o.commitRetries, err = conf.FieldInt(icoFieldCommitRetries); err != nil {
+		return nil, err
+	}
+
+	if err = AWSOptFn(conf.Namespace(icoFieldAWS), &o.opts); err != nil {
+		return nil, err
+	}
+
+	cConf := conf.Namespace(icoFieldCatalog)
+	catalogType, err := cConf.FieldString(icoFieldCatalogType)
+	if err != nil {
+		return nil, err
+	}
+	uri, err := cConf.FieldString(icoFieldCatalogURI)
+	if err != nil {
+		return nil, err
+	}
+	timeout, err := conf.FieldDuration(icoFieldTimeout)
+	if err != nil {
+		return nil, err
+	}
+	tlsConf, tlsEnabled, err := conf.FieldTLSToggled(icoFieldTLS)
+	if err != nil {
+		return nil, err
+	}
+	if !tlsEnabled {
+		tlsConf = nil
+	}
+
+	switch catalogType {
+	case "rest":
+		if o.catalog, err = restCatalogFromParsed(cConf, uri, timeout, tlsConf, o.opts.RequestSigner); err != nil {
+			return nil, err
+		}
+	case "hive":
+		if _, err = hiveDatabase(o.namespace); err != nil {
+			return nil, err
+		}
+		if o.catalog, err = newHiveCatalog(uri, timeout, tlsConf, &o.opts); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("catalog type %v not recognised", catalogType)
+	}
+	return o, nil
+}
+
+func restCatalogFromParsed(conf *service.ParsedConfig, uri string, timeout time.Duration, tlsConf *tls.Config, signer func(req *http.Request, body []byte) error) (*restCatalog, error) {
+	c := &restCatalog{
+		uri:    strings.TrimSuffix(uri, "/"),
+		client: &http.Client{Timeout: timeout},
+		signer: signer,
+	}
+	if tlsConf != nil {
+		c.client.Transport = &http.Transport{
+			TLSClientConfig: tlsConf,
+		}
+	}
+
+	var err error
+	if c.warehouse, err = conf.FieldString(icoFieldCatalogWarehouse); err != nil {
+		return nil, err
+	}
+	if c.token, err = conf.FieldString(icoFieldCatalogToken); err != nil {
+		return nil, err
+	}
+	if c.credential, err = conf.FieldString(icoFieldCatalogCredential); err != nil {
+		return nil, err
+	}
+	if c.scope, err = conf.FieldString(icoFieldCatalogScope); err != nil {
+		return nil, err
+	}
+	if c.headers, err = conf.FieldStringMap(icoFieldCatalogHeaders); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (o *output) Connect(ctx context.Context) error {
+	o.mut.Lock()
+	defer o.mut.Unlock()
+
+	if o.loaded != nil {
+		return nil
+	}
+
+	if err := o.catalog.connect(ctx); err != nil {
+		return err
+	}
+
+	loaded, err := o.catalog.loadTable(ctx, o.namespace, o.table)
+	if err != nil {
+		return fmt.Errorf("failed to load table: %w", err)
+	}
+	if loaded.Metadata.FormatVersion != 2 {
+		return errUnsupportedFormatVersion
+	}
+	if _, err := o.opts.storageFor(loaded.Metadata.Location); err != nil {
+		return err
+	}
+
+	o.loaded = loaded
+	return nil
+}
+
+type partitionedFile struct {
+	values []any
+	rows   []any
+}
+
+func writeParquet(schema *parquet.Schema, codec compress.Codec, rows []any) (data []byte, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("encoding panic: %v", r)
+		}
+	}()
+
+	var buf bytes.Buffer
+	pWtr := parquet.NewGenericWriter[any](&buf, schema, parquet.Compression(codec))
+	if _, err = pWtr.Write(rows); err != nil {
+		return
+	}
+	if err = pWtr.Close(); err != nil {
+		return
+	}
+	return buf.Bytes(), nil
+}
+
+func (o *output) WriteBatch(ctx context.Context, batch service.MessageBatch) error {
+	o.mut.Lock()
+	defer o.mut.Unlock()
+
+	if o.loaded == nil {
+		return service.ErrNotConnected
+	}
+	meta := &o.loaded.Metadata
+
+	storage, err := o.opts.storageFor(meta.Location)
+	if err != nil {
+		return err
+	}
+
+	tSchema, err := meta.currentSchema()
+	if err != nil {
+		return err
+	}
+	cols, err := columnsFromSchema(tSchema)
+	if err != nil {
+		return err
+	}
+	spec, err := meta.defaultSpec()
+	if err != nil {
+		return err
+	}
+	part, err := newPartitioner(spec, cols)
+	if err != nil {
+		return err
+	}
+
+	// Convert messages into rows grouped by their partition.
+	var partitions []*partitionedFile
+	partitionsByPath := map[string]*partitionedFile{}
+	for i, msg := range batch {
+		structured, err := msg.AsStructured()
+		if err != nil {
+			return fmt.Errorf("message %v: %w", i, err)
+		}
+		obj, ok := structured.(map[string]any)
+		if !ok {
+			return fmt.Errorf("message %v: expected an object, got %T", i, structured)
+		}
+
+		row := make(map[string]any, len(cols))
+		for _, c := range cols {
+			if row[c.field.Name], err = c.convert(obj[c.field.Name]); err != nil {
+				return fmt.Errorf("message %v: %w", i, err)
+			}
+		}
+
+		values := part.values(row)
+		path := part.path(values)
+		pf, exists := partitionsByPath[path]
+		if !exists {
+			pf = &partitionedFile{values: values}
+			partitionsByPath[path] = pf
+			partitions = append(partitions, pf)
+		}
+		pf.rows = append(pf.rows, row)
+	}
+
+	pSchema := parquetSchemaFromColumns(cols)
+	location := strings.TrimSuffix(meta.Location, "/")
+
+	var files []dataFile
+	var addedRecords, addedSize int64
+	for _, pf := range partitions {
+		data, err := writeParquet(pSchema, o.compression, pf.rows)
+		if err != nil {
+			return err
+		}
+
+		fileID, err := uuid.NewV4()
+		if err != nil {
+			return err
+		}
+		filePath := location + "/data/"
+		if p := part.path(pf.values); p != "" {
+			filePath += p + "/"
+		}
+		filePath += fileID.String() + ".parquet"
+
+		if err := storage.Put(ctx, filePath, data); err != nil {
+			return fmt.Errorf("failed to write data file: %w", err)
+		}
+
+		files = append(files, dataFile{
+			path:            filePath,
+			partition:       pf.values,
+			recordCount:     int64(len(pf.rows)),
+			fileSizeInBytes: int64(len(data)),
+		})
+		addedRecords += int64(len(pf.rows))
+		addedSize += int64(len(data))
+	}
+
+	schemaJSON, err := json.Marshal(tSchema)
+	if err != nil {
+		return err
+	}
+
+	snapshotID := rand.Int63()
+	manifestID, err := uuid.NewV4()
+	if err != nil {
+		return err
+	}
+
+	manifestBytes, err := writeManifest(schemaJSON, part, snapshotID, files)
+	if err != nil {
+		return fmt.Errorf("failed to encode manifest: %w", err)
+	}
+	manifestPath := location + "/metadata/" + manifestID.String() + "-m0.avro"
+	if err := storage.Put(ctx, manifestPath, manifestBytes); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+
+	added := manifestFile{
+		path:            manifestPath,
+		length:          int64(len(manifestBytes)),
+		partitionSpecID: int32(spec.SpecID),
+		addedSnapshotID: snapshotID,
+		addedFilesCount: int32(len(files)),
+		addedRowsCount:  addedRecords,
+	}
+	summary := map[string]string{
+		"operation":        "append",
+		"added-data-files": strconv.Itoa(len(files)),
+		"added-records":    strconv.FormatInt(addedRecords, 10),
+		"added-files-size": strconv.FormatInt(addedSize, 10),
+	}
+
+	for attempt := 0; ; attempt++ {
+		err = o.commit(ctx, storage, snapshotID, tSchema.SchemaID, added, summary)
+		if err == nil || !errors.Is(err, errCommitConflict) || attempt >= o.commitRetries {
+			return err
+		}
+
+		o.log.Debugf("Retrying commit after conflict: %v", err)
+		loaded, err := o.catalog.loadTable(ctx, o.namespace, o.table)
+		if err != nil {
+			return fmt.Errorf("failed to reload table: %w", err)
+		}
+		o.loaded = loaded
+	}
+}
+
+// commit attempts to add a snapshot containing the existing manifests of the
+// current snapshot plus a newly added manifest.
+func (o *output) commit(ctx context.Context, storage ObjectStorage, snapshotID int64, schemaID int, added manifestFile, summary map[string]string) error {
+	meta := &o.loaded.Metadata
+	sequenceNumber := meta.LastSequenceNumber + 1
+
+	added.sequenceNumber = sequenceNumber
+	added.minSequenceNumber = sequenceNumber
+	manifests := []manifestFile{added}
+
+	parent := meta.currentSnapshot()
+	var parentID *int64
+	if parent != nil {
+		parentID = &parent.SnapshotID
+
+		listBytes, err := storage.Get(ctx, parent.ManifestList)
+		if err != nil {
+			return fmt.Errorf("failed to read manifest list: %w", err)
+		}
+		existing, err := readManifestList(listBytes)
+		if err != nil {
+			return fmt.Errorf("failed to decode manifest list: %w", err)
+		}
+		manifests = append(manifests, existing...)
+	}
+
+	listBytes, err := writeManifestList(snapshotID, parentID, sequenceNumber, manifests)
+	if err != nil {
+		return fmt.Errorf("failed to encode manifest list: %w", err)
+	}
+
+	listID, err := uuid.NewV4()
+	if err != nil {
+		return err
+	}
+	listPath := fmt.Sprintf("%v/metadata/snap-%v-%v-%v.avro", strings.TrimSuffix(meta.Location, "/"), snapshotID, sequenceNumber, listID.String())
+	if err := storage.Put(ctx, listPath, listBytes); err != nil {
+		return fmt.Errorf("failed to write manifest list: %w", err)
+	}
+
+	snap := snapshot{
+		SnapshotID:       snapshotID,
+		ParentSnapshotID: parentID,
+		SequenceNumber:   sequenceNumber,
+		TimestampMs:      time.Now().UnixMilli(),
+		ManifestList:     listPath,
+		Summary:          summary,
+		SchemaID:         &schemaID,
+	}
+
+	res, err := o.catalog.commitTable(ctx, o.namespace, o.table, commitTableRequest{
+		Identifier: tableIdentifier{
+			Namespace: o.namespace,
+			Name:      o.table,
+		},
+		Requirements: []any{
+			map[string]any{"type": "assert-table-uuid", "uuid": meta.TableUUID},
+			map[string]any{"type": "assert-ref-snapshot-id", "ref": "main", "snapshot-id": parentID},
+		},
+		Updates: []any{
+			map[string]any{"action": "add-snapshot", "snapshot": snap},
+			map[string]any{"action": "set-snapshot-ref", "ref-name": "main", "type": "branch", "snapshot-id": snapshotID},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	if res.Metadata.FormatVersion == 0 {
+		// Some catalogs do not return the updated metadata.
+		if res, err = o.catalog.loadTable(ctx, o.namespace, o.table); err != nil {
+			o.loaded = nil
+			return fmt.Errorf("failed to reload table after commit: %w", err)
+		}
+	}
+	o.loaded = res
+	return nil
+}
+
+func (o *output) Close(ctx context.Context) error {
+	o.mut.Lock()
+	o.loaded = nil
+	o.mut.Unlock()
+	return nil
+}
@@ -0,0 +1,256 @@
+package iceberg
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+// fakeCatalog is a minimal in-memory REST catalog serving a single table.
+type fakeCatalog struct {
+	mut       sync.Mutex
+	meta      map[string]any
+	conflicts int
+	commits   int
+}
+
+func newFakeCatalog(t *testing.T, location string) (*fakeCatalog, *httptest.Server) {
+	t.Helper()
+
+	c := &fakeCatalog{}
+	dec := json.NewDecoder(strings.NewReader(fmt.Sprintf(`{
+  "format-version": 2,
+  "table-uuid": "9c12d441-03fe-4693-9a96-a0705ddf69c1",
+  "location": %q,
+  "last-sequence-number": 0,
+  "current-schema-id": 0,
+  "schemas": [{
+    "schema-id": 0,
+    "type": "struct",
+    "fields": [
+      {"id": 1, "name": "id", "required": true, "type": "long"},
+      {"id": 2, "name": "category", "required": false, "type": "string"}
+    ]
+  }],
+  "default-spec-id": 0,
+  "partition-specs": [{
+    "spec-id": 0,
+    "fields": [{"source-id": 2, "field-id": 1000, "name": "category", "transform": "identity"}]
+  }],
+  "snapshots": []
+}`, location)))
+	dec.UseNumber()
+	require.NoError(t, dec.Decode(&c.meta))
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/config", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"defaults":{},"overrides":{"prefix":"main"}}`))
+	})
+	mux.HandleFunc("/v1/main/namespaces/analytics/tables/events", func(w http.ResponseWriter, r *http.Request) {
+		c.mut.Lock()
+		defer c.mut.Unlock()
+
+		if r.Method == http.MethodPost {
+			if c.conflicts > 0 {
+				c.conflicts--
+				w.WriteHeader(http.StatusConflict)
+				_, _ = w.Write([]byte(`{"error":{"message":"branch main has changed","type":"CommitFailedException","code":409}}`))
+				return
+			}
+
+			var req struct {
+				Requirements []map[string]any `json:"requirements"`
+				Updates      []map[string]any `json:"updates"`
+			}
+			reqDec := json.NewDecoder(r.Body)
+			reqDec.UseNumber()
+			if err := reqDec.Decode(&req); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			for _, u := range req.Updates {
+				switch u["action"] {
+				case "add-snapshot":
+					snap := u["snapshot"].(map[string]any)
+					c.meta["snapshots"] = append(c.meta["snapshots"].([]any), snap)
+					c.meta["last-sequence-number"] = snap["sequence-number"]
+				case "set-snapshot-ref":
+					c.meta["current-snapshot-id"] = u["snapshot-id"]
+				}
+			}
+			c.commits++
+		}
+
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"metadata-location": "",
+			"metadata":          c.meta,
+		})
+	})
+	return c, httptest.NewServer(mux)
+}
+
+func TestIcebergOutputCommits(t *testing.T) {
+	ctx, done := context.WithTimeout(context.Background(), time.Minute)
+	defer done()
+
+	tableDir := t.TempDir()
+	catalog, server := newFakeCatalog(t, "file://"+tableDir)
+	t.Cleanup(server.Close)
+
+	conf, err := outputSpec().ParseYAML(fmt.Sprintf(`
+catalog:
+  uri: %v
+namespace: analytics
+table: events
+`, server.URL), nil)
+	require.NoError(t, err)
+
+	out, err := newOutputFromParsed(conf, service.MockResources())
+	require.NoError(t, err)
+	require.NoError(t, out.Connect(ctx))
+
+	require.NoError(t, out.WriteBatch(ctx, service.MessageBatch{
+		service.NewMessage([]byte(`{"id":1,"category":"a"}`)),
+		service.NewMessage([]byte(`{"id":2,"category":"b"}`)),
+		service.NewMessage([]byte(`{"id":3,"category":"a"}`)),
+	}))
+
+	// The second commit conflicts once and should be retried.
+	catalog.mut.Lock()
+	catalog.conflicts = 1
+	catalog.mut.Unlock()
+	require.NoError(t, out.WriteBatch(ctx, service.MessageBatch{
+		service.NewMessage([]byte(`{"id":4,"category":"c","ignored":true}`)),
+	}))
+
+	err = out.WriteBatch(ctx, service.MessageBatch{
+		service.NewMessage([]byte(`{"category":"c"}`)),
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "column id is required")
+
+	require.NoError(t, out.Close(ctx))
+
+	catalog.mut.Lock()
+	assert.Equal(t, 2, catalog.commits)
+	catalog.mut.Unlock()
+
+	loaded, err := out.catalog.loadTable(ctx, out.namespace, out.table)
+	require.NoError(t, err)
+	require.Len(t, loaded.Metadata.Snapshots, 2)
+	assert.Equal(t, int64(2), loaded.Metadata.LastSequenceNumber)
+
+	current := loaded.Metadata.currentSnapshot()
+	require.NotNil(t, current)
+	assert.Equal(t, loaded.Metadata.Snapshots[0].SnapshotID, *current.ParentSnapshotID)
+	assert.Equal(t, "1", current.Summary["added-records"])
+
+	listBytes, err := os.ReadFile(localPath(current.ManifestList))
+	require.NoError(t, err)
+
+	manifests, err := readManifestList(listBytes)
+	require.NoError(t, err)
+	require.Len(t, manifests, 2)
+	assert.Equal(t, int64(2), manifests[0].sequenceNumber)
+	assert.Equal(t, int64(1), manifests[0].addedRowsCount)
+	assert.Equal(t, int64(1), manifests[1].sequenceNumber)
+	assert.Equal(t, int32(2), manifests[1].addedFilesCount)
+	assert.Equal(t, int64(3), manifests[1].addedRowsCount)
+
+	dataFiles, err := filepath.Glob(filepath.Join(tableDir, "data", "category=a", "*.parquet"))
+	require.NoError(t, err)
+	require.Len(t, dataFiles, 1)
+
+	dataBytes, err := os.ReadFile(dataFiles[0])
+	require.NoError(t, err)
+
+	rdr := parquet.NewGenericReader[any](bytes.NewReader(dataBytes))
+	rows := make([]any, 10)
+	n, _ := rdr.Read(rows)
+	require.Equal(t, 2, n)
+	assert.Equal(t, map[string]any{"id": int64(1), "category": "a"}, rows[0])
+	assert.Equal(t, map[string]any{"id": int64(3), "category": "a"}, rows[1])
+}
+
+func TestIcebergOutputRejectsV1Tables(t *testing.T) {
+	ctx, done := context.WithTimeout(context.Background(), time.Minute)
+	defer done()
+
+	catalog, server := newFakeCatalog(t, "file://"+t.TempDir())
+	t.Cleanup(server.Close)
+	catalog.mut.Lock()
+	catalog.meta["format-version"] = 1
+	catalog.mut.Unlock()
+
+	conf, err := outputSpec().ParseYAML(fmt.Sprintf(`
+catalog:
+  uri: %v
+namespace: analytics
+table: events
+`, server.URL), nil)
+	require.NoError(t, err)
+
+	out, err := newOutputFromParsed(conf, service.MockResources())
+	require.NoError(t, err)
+	require.ErrorIs(t, out.Connect(ctx), errUnsupportedFormatVersion)
+}
+
+func TestIcebergOutputAWSNotImported(t *testing.T) {
+	conf, err := outputSpec().ParseYAML(`
+catalog:
+  uri: http://localhost:8181
+namespace: analytics
+table: events
+aws:
+  enabled: true
+`, nil)
+	require.NoError(t, err)
+
+	_, err = newOutputFromParsed(conf, service.MockResources())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "does not import components/aws")
+}
+
+func TestIcebergOutputHiveConfig(t *testing.T) {
+	conf, err := outputSpec().ParseYAML(`
+catalog:
+  type: hive
+  uri: thrift://localhost:9083
+namespace: prod.analytics
+table: events
+`, nil)
+	require.NoError(t, err)
+
+	_, err = newOutputFromParsed(conf, service.MockResources())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "single level namespace")
+
+	conf, err = outputSpec().ParseYAML(`
+catalog:
+  type: hive
+  uri: thrift://localhost
+namespace: analytics
+table: events
+`, nil)
+	require.NoError(t, err)
+
+	out, err := newOutputFromParsed(conf, service.MockResources())
+	require.NoError(t, err)
+	require.IsType(t, &hiveCatalog{}, out.catalog)
+	assert.Equal(t, "localhost:9083", out.catalog.(*hiveCatalog).address)
+}
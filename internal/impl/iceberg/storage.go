@@ -0,0 +1,89 @@
+package iceberg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+// ObjectStorage provides access to the files of a table, which are addressed
+// by their full location URL (e.g. s3://bucket/warehouse/foo/data/bar.parquet).
+type ObjectStorage interface {
+	Put(ctx context.Context, location string, data []byte) error
+	Get(ctx context.Context, location string) ([]byte, error)
+}
+
+// ClientOptions are the options used to connect to a catalog and the storage
+// of its tables, which can be customised by child packages.
+type ClientOptions struct {
+	// Storage maps location URL schemes to the storage implementation used
+	// for them.
+	Storage map[string]ObjectStorage
+
+	// RequestSigner, when set, is called with each catalog request and its
+	// body before it is sent.
+	RequestSigner func(req *http.Request, body []byte) error
+}
+
+func (o *ClientOptions) storageFor(location string) (ObjectStorage, error) {
+	scheme := "file"
+	if u, err := url.Parse(location); err == nil && u.Scheme != "" {
+		scheme = u.Scheme
+	}
+	s, exists := o.Storage[scheme]
+	if !exists {
+		return nil, fmt.Errorf("storage scheme '%v' is not supported", scheme)
+	}
+	return s, nil
+}
+
+//------------------------------------------------------------------------------
+
+// localStorage writes table files to the local filesystem.
+type localStorage struct {
+	fs *service.FS
+}
+
+func localPath(location string) string {
+	return strings.TrimPrefix(strings.TrimPrefix(location, "file://"), "file:")
+}
+
+func (l *localStorage) Put(ctx context.Context, location string, data []byte) error {
+	path := localPath(location)
+	if err := l.fs.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	f, err := l.fs.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+
+	w, ok := f.(io.Writer)
+	if !ok {
+		_ = f.Close()
+		return errors.New("failed to open a writable file")
+	}
+	if _, err := w.Write(data); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func (l *localStorage) Get(ctx context.Context, location string) ([]byte, error) {
+	f, err := l.fs.Open(localPath(location))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return io.ReadAll(f)
+}
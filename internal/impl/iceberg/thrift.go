@@ -0,0 +1,417 @@
+package iceberg
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+// A minimal implementation of the Thrift binary protocol, which is sufficient
+// for the few Hive metastore calls that the Hive catalog makes. Structs are
+// decoded generically so that values such as tables can be written back
+// without losing fields.
+
+const (
+	thriftStop   byte = 0
+	thriftBool   byte = 2
+	thriftByte   byte = 3
+	thriftDouble byte = 4
+	thriftI16    byte = 6
+	thriftI32    byte = 8
+	thriftI64    byte = 10
+	thriftString byte = 11
+	thriftStruct byte = 12
+	thriftMap    byte = 13
+	thriftSet    byte = 14
+	thriftList   byte = 15
+)
+
+const (
+	thriftMessageCall      byte = 1
+	thriftMessageReply     byte = 2
+	thriftMessageException byte = 3
+
+	thriftVersion1    uint32 = 0x80010000
+	thriftVersionMask uint32 = 0xffff0000
+)
+
+// The maximum size of strings and containers accepted from a peer.
+const thriftMaxLength = 64 * 1024 * 1024
+
+type thriftField struct {
+	id    int16
+	typ   byte
+	value any
+}
+
+// thriftStructValue is a decoded struct with its fields in the order they were
+// read.
+type thriftStructValue []thriftField
+
+func (s thriftStructValue) field(id int16) (thriftField, bool) {
+	for _, f := range s {
+		if f.id == id {
+			return f, true
+		}
+	}
+	return thriftField{}, false
+}
+
+func (s thriftStructValue) str(id int16) string {
+	f, _ := s.field(id)
+	v, _ := f.value.(string)
+	return v
+}
+
+func (s thriftStructValue) i32(id int16) int32 {
+	f, _ := s.field(id)
+	v, _ := f.value.(int32)
+	return v
+}
+
+func (s thriftStructValue) i64(id int16) int64 {
+	f, _ := s.field(id)
+	v, _ := f.value.(int64)
+	return v
+}
+
+// set replaces the value of a field, or adds the field when it isn't present.
+func (s thriftStructValue) set(id int16, typ byte, value any) thriftStructValue {
+	for i, f := range s {
+		if f.id == id {
+			s[i] = thriftField{id: id, typ: typ, value: value}
+			return s
+		}
+	}
+	return append(s, thriftField{id: id, typ: typ, value: value})
+}
+
+type thriftMapValue struct {
+	keyType   byte
+	valueType byte
+	keys      []any
+	values    []any
+}
+
+// stringMap returns the entries of a map<string,string>.
+func (m *thriftMapValue) stringMap() map[string]string {
+	res := make(map[string]string, len(m.keys))
+	for i, k := range m.keys {
+		ks, _ := k.(string)
+		vs, _ := m.values[i].(string)
+		res[ks] = vs
+	}
+	return res
+}
+
+func thriftStringMap(m map[string]string) *thriftMapValue {
+	tm := &thriftMapValue{keyType: thriftString, valueType: thriftString}
+	for k, v := range m {
+		tm.keys = append(tm.keys, k)
+		tm.values = append(tm.values, v)
+	}
+	return tm
+}
+
+type thriftListValue struct {
+	elemType byte
+	elems    []any
+}
+
+//------------------------------------------------------------------------------
+
+type thriftWriter struct {
+	w *bufio.Writer
+}
+
+func (t *thriftWriter) writeMessageBegin(name string, typ byte, seqID int32) {
+	t.writeI32(int32(thriftVersion1 | uint32(typ)))
+	t.writeString(name)
+	t.writeI32(seqID)
+}
+
+func (t *thriftWriter) writeByte(b byte) {
+	_ = t.w.WriteByte(b)
+}
+
+func (t *thriftWriter) writeI16(v int16) {
+	_, _ = t.w.Write(binary.BigEndian.AppendUint16(nil, uint16(v)))
+}
+
+func (t *thriftWriter) writeI32(v int32) {
+	_, _ = t.w.Write(binary.BigEndian.AppendUint32(nil, uint32(v)))
+}
+
+func (t *thriftWriter) writeI64(v int64) {
+	_, _ = t.w.Write(binary.BigEndian.AppendUint64(nil, uint64(v)))
+}
+
+func (t *thriftWriter) writeString(s string) {
+	t.writeI32(int32(len(s)))
+	_, _ = t.w.WriteString(s)
+}
+
+func (t *thriftWriter) writeStruct(s thriftStructValue) error {
+	for _, f := range s {
+		t.writeByte(f.typ)
+		t.writeI16(f.id)
+		if err := t.writeValue(f.typ, f.value); err != nil {
+			return fmt.Errorf("field %v: %w", f.id, err)
+		}
+	}
+	t.writeByte(thriftStop)
+	return nil
+}
+
+func (t *thriftWriter) writeValue(typ byte, v any) error {
+	var ok bool
+	switch typ {
+	case thriftBool:
+		var b bool
+		if b, ok = v.(bool); ok {
+			if b {
+				t.writeByte(1)
+			} else {
+				t.writeByte(0)
+			}
+		}
+	case thriftByte:
+		var b int8
+		if b, ok = v.(int8); ok {
+			t.writeByte(byte(b))
+		}
+	case thriftDouble:
+		var f float64
+		if f, ok = v.(float64); ok {
+			t.writeI64(int64(math.Float64bits(f)))
+		}
+	case thriftI16:
+		var i int16
+		if i, ok = v.(int16); ok {
+			t.writeI16(i)
+		}
+	case thriftI32:
+		var i int32
+		if i, ok = v.(int32); ok {
+			t.writeI32(i)
+		}
+	case thriftI64:
+		var i int64
+		if i, ok = v.(int64); ok {
+			t.writeI64(i)
+		}
+	case thriftString:
+		var s string
+		if s, ok = v.(string); ok {
+			t.writeString(s)
+		}
+	case thriftStruct:
+		var s thriftStructValue
+		if s, ok = v.(thriftStructValue); ok {
+			return t.writeStruct(s)
+		}
+	case thriftMap:
+		var m *thriftMapValue
+		if m, ok = v.(*thriftMapValue); ok {
+			t.writeByte(m.keyType)
+			t.writeByte(m.valueType)
+			t.writeI32(int32(len(m.keys)))
+			for i, k := range m.keys {
+				if err := t.writeValue(m.keyType, k); err != nil {
+					return err
+				}
+				if err := t.writeValue(m.valueType, m.values[i]); err != nil {
+					return err
+				}
+			}
+		}
+	case thriftSet, thriftList:
+		var l *thriftListValue
+		if l, ok = v.(*thriftListValue); ok {
+			t.writeByte(l.elemType)
+			t.writeI32(int32(len(l.elems)))
+			for _, e := range l.elems {
+				if err := t.writeValue(l.elemType, e); err != nil {
+					return err
+				}
+			}
+		}
+	default:
+		return fmt.Errorf("unsupported thrift type %v", typ)
+	}
+	if !ok {
+		return fmt.Errorf("unexpected value %T for thrift type %v", v, typ)
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+type thriftReader struct {
+	r *bufio.Reader
+}
+
+func (t *thriftReader) readMessageBegin() (name string, typ byte, seqID int32, err error) {
+	var header int32
+	if header, err = t.readI32(); err != nil {
+		return
+	}
+	if uint32(header)&thriftVersionMask != thriftVersion1 {
+		err = errors.New("unexpected thrift message version")
+		return
+	}
+	typ = byte(uint32(header) & 0xff)
+	if name, err = t.readString(); err != nil {
+		return
+	}
+	seqID, err = t.readI32()
+	return
+}
+
+func (t *thriftReader) readByte() (byte, error) {
+	return t.r.ReadByte()
+}
+
+func (t *thriftReader) readN(n int) ([]byte, error) {
+	b := make([]byte, n)
+	_, err := io.ReadFull(t.r, b)
+	return b, err
+}
+
+func (t *thriftReader) readI16() (int16, error) {
+	b, err := t.readN(2)
+	if err != nil {
+		return 0, err
+	}
+	return int16(binary.BigEndian.Uint16(b)), nil
+}
+
+func (t *thriftReader) readI32() (int32, error) {
+	b, err := t.readN(4)
+	if err != nil {
+		return 0, err
+	}
+	return int32(binary.BigEndian.Uint32(b)), nil
+}
+
+func (t *thriftReader) readI64() (int64, error) {
+	b, err := t.readN(8)
+	if err != nil {
+		return 0, err
+	}
+	return int64(binary.BigEndian.Uint64(b)), nil
+}
+
+func (t *thriftReader) readLength() (int, error) {
+	l, err := t.readI32()
+	if err != nil {
+		return 0, err
+	}
+	if l < 0 || l > thriftMaxLength {
+		return 0, fmt.Errorf("invalid thrift length %v", l)
+	}
+	return int(l), nil
+}
+
+func (t *thriftReader) readString() (string, error) {
+	l, err := t.readLength()
+	if err != nil {
+		return "", err
+	}
+	b, err := t.readN(l)
+	return string(b), err
+}
+
+func (t *thriftReader) readStruct() (thriftStructValue, error) {
+	var s thriftStructValue
+	for {
+		typ, err := t.readByte()
+		if err != nil {
+			return nil, err
+		}
+		if typ == thriftStop {
+			return s, nil
+		}
+		id, err := t.readI16()
+		if err != nil {
+			return nil, err
+		}
+		v, err := t.readValue(typ)
+		if err != nil {
+			return nil, err
+		}
+		s = append(s, thriftField{id: id, typ: typ, value: v})
+	}
+}
+
+func (t *thriftReader) readValue(typ byte) (any, error) {
+	switch typ {
+	case thriftBool:
+		b, err := t.readByte()
+		return b != 0, err
+	case thriftByte:
+		b, err := t.readByte()
+		return int8(b), err
+	case thriftDouble:
+		i, err := t.readI64()
+		return math.Float64frombits(uint64(i)), err
+	case thriftI16:
+		return t.readI16()
+	case thriftI32:
+		return t.readI32()
+	case thriftI64:
+		return t.readI64()
+	case thriftString:
+		return t.readString()
+	case thriftStruct:
+		return t.readStruct()
+	case thriftMap:
+		m := &thriftMapValue{}
+		var err error
+		if m.keyType, err = t.readByte(); err != nil {
+			return nil, err
+		}
+		if m.valueType, err = t.readByte(); err != nil {
+			return nil, err
+		}
+		n, err := t.readLength()
+		if err != nil {
+			return nil, err
+		}
+		for i := 0; i < n; i++ {
+			k, err := t.readValue(m.keyType)
+			if err != nil {
+				return nil, err
+			}
+			v, err := t.readValue(m.valueType)
+			if err != nil {
+				return nil, err
+			}
+			m.keys = append(m.keys, k)
+			m.values = append(m.values, v)
+		}
+		return m, nil
+	case thriftSet, thriftList:
+		l := &thriftListValue{}
+		var err error
+		if l.elemType, err = t.readByte(); err != nil {
+			return nil, err
+		}
+		n, err := t.readLength()
+		if err != nil {
+			return nil, err
+		}
+		for i := 0; i < n; i++ {
+			e, err := t.readValue(l.elemType)
+			if err != nil {
+				return nil, err
+			}
+			l.elems = append(l.elems, e)
+		}
+		return l, nil
+	}
+	return nil, fmt.Errorf("unsupported thrift type %v", typ)
+}
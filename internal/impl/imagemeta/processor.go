@@ -0,0 +1,428 @@
+package imagemeta
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif" // Register the GIF decoder.
+	"image/jpeg"
+	"image/png"
+	"math"
+	"strings"
+	"unicode"
+
+	"github.com/rwcarlsen/goexif/exif"
+	"github.com/rwcarlsen/goexif/tiff"
+	_ "golang.org/x/image/bmp" // Register the BMP decoder.
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/tiff" // Register the TIFF decoder.
+	_ "golang.org/x/image/webp" // Register the WebP decoder.
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	impFieldExif                = "exif"
+	impFieldXMP                 = "xmp"
+	impFieldThumbnail           = "thumbnail"
+	impFieldThumbnailEnabled    = "enabled"
+	impFieldThumbnailMaxWidth   = "max_width"
+	impFieldThumbnailMaxHeight  = "max_height"
+	impFieldThumbnailFormat     = "format"
+	impFieldThumbnailQuality    = "quality"
+	impFieldThumbnailAutoOrient = "auto_orient"
+)
+
+func imageMetadataProcSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Categories("Parsing").
+		Version("4.28.0").
+		Summary("Extracts the format, dimensions, EXIF and XMP metadata of images, and optionally generates thumbnails.").
+		Description(`
+Each message is parsed as an image, and replaced with an object describing it:
+
+`+"```json"+`
+{
+  "format": "jpeg",
+  "width": 4032,
+  "height": 3024,
+  "exif": {
+    "Make": "Apple",
+    "Model": "iPhone 12",
+    "Orientation": 6,
+    "DateTimeOriginal": "2023:06:01 12:30:45",
+    "FNumber": 1.6
+  },
+  "location": { "latitude": 51.5007, "longitude": -0.1246 },
+  "xmp": {
+    "dc:title": "Westminster",
+    "dc:subject": [ "london", "travel" ]
+  }
+}
+`+"```"+`
+
+Supported image formats are JPEG, PNG, GIF, WebP, BMP and TIFF. EXIF metadata is extracted from JPEG and TIFF images, where tag values are converted to strings, numbers or arrays of numbers and tags with undefined binary values are omitted. When the EXIF metadata includes GPS coordinates they're added as decimal degrees to the field `+"`location`"+`. XMP metadata is extracted from images of any format, where properties are keyed by their prefixed names, arrays are converted into lists of strings and language alternatives are reduced to their default value.
+
+### Thumbnails
+
+When the field `+"`thumbnail.enabled`"+` is `+"`true`"+` a thumbnail of each image is added as a message following the metadata, with the metadata fields `+"`image_thumbnail_format`"+`, `+"`image_thumbnail_width`"+` and `+"`image_thumbnail_height`"+`. Thumbnails are scaled down to fit within the configured dimensions while preserving their aspect ratio, images that are already smaller than this are not scaled up.
+
+Messages that cannot be parsed as an image are flagged as having failed, allowing you to use [standard processor error handling patterns](/docs/configuration/error_handling).`).
+		Fields(
+			service.NewBoolField(impFieldExif).
+				Description("Whether to extract EXIF metadata.").
+				Default(true),
+			service.NewBoolField(impFieldXMP).
+				Description("Whether to extract XMP metadata.").
+				Default(true),
+			service.NewObjectField(impFieldThumbnail,
+				service.NewBoolField(impFieldThumbnailEnabled).
+					Description("Whether to generate thumbnails.").
+					Default(false),
+				service.NewIntField(impFieldThumbnailMaxWidth).
+					Description("The maximum width of thumbnails in pixels.").
+					Default(256),
+				service.NewIntField(impFieldThumbnailMaxHeight).
+					Description("The maximum height of thumbnails in pixels.").
+					Default(256),
+				service.NewStringEnumField(impFieldThumbnailFormat, "jpeg", "png").
+					Description("The format to encode thumbnails with.").
+					Default("jpeg"),
+				service.NewIntField(impFieldThumbnailQuality).
+					Description("The quality of JPEG thumbnails, from 1 to 100.").
+					Advanced().
+					Default(80),
+				service.NewBoolField(impFieldThumbnailAutoOrient).
+					Description("Whether to rotate and flip thumbnails according to the EXIF orientation of images.").
+					Advanced().
+					Default(true),
+			).
+				Description("An optional thumbnail to generate for each image.").
+				Optional(),
+		).
+		Example("Media Ingestion", "Here we extract the metadata of uploaded images and generate thumbnails, writing each to a different bucket by switching on the metadata of thumbnails.", `
+input:
+  aws_s3:
+    bucket: uploads
+
+pipeline:
+  processors:
+    - image_metadata:
+        thumbnail:
+          enabled: true
+          max_width: 320
+          max_height: 320
+
+output:
+  switch:
+    cases:
+      - check: '@image_thumbnail_format != null'
+        output:
+          aws_s3:
+            bucket: thumbnails
+            path: ${! @s3_key }.jpg
+      - output:
+          aws_s3:
+            bucket: image-metadata
+            path: ${! @s3_key }.json
+`)
+}
+
+func init() {
+	err := service.RegisterProcessor(
+		"image_metadata", imageMetadataProcSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Processor, error) {
+			return newImageMetadataProcFromConfig(conf)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type thumbnailConfig struct {
+	maxWidth   int
+	maxHeight  int
+	format     string
+	quality    int
+	autoOrient bool
+}
+
+type imageMetadataProc struct {
+	exif      bool
+	xmp       bool
+	thumbnail *thumbnailConfig
+}
+
+func newImageMetadataProcFromConfig(conf *service.ParsedConfig) (*imageMetadataProc, error) {
+	p := &imageMetadataProc{}
+
+	var err error
+	if p.exif, err = conf.FieldBool(impFieldExif); err != nil {
+		return nil, err
+	}
+	if p.xmp, err = conf.FieldBool(impFieldXMP); err != nil {
+		return nil, err
+	}
+
+	tConf := conf.Namespace(impFieldThumbnail)
+	enabled, err := tConf.FieldBool(impFieldThumbnailEnabled)
+	if err != nil {
+		return nil, err
+	}
+	if enabled {
+		t := &thumbnailConfig{}
+		if t.maxWidth, err = tConf.FieldInt(impFieldThumbnailMaxWidth); err != nil {
+			return nil, err
+		}
+		if t.maxHeight, err = tConf.FieldInt(impFieldThumbnailMaxHeight); err != nil {
+			return nil, err
+		}
+		if t.maxWidth <= 0 || t.maxHeight <= 0 {
+			return nil, errors.New("thumbnail dimensions must be greater than zero")
+		}
+		if t.format, err = tConf.FieldString(impFieldThumbnailFormat); err != nil {
+			return nil, err
+		}
+		if t.quality, err = tConf.FieldInt(impFieldThumbnailQuality); err != nil {
+			return nil, err
+		}
+		if t.quality < 1 || t.quality > 100 {
+			return nil, fmt.Errorf("thumbnail quality must be between 1 and 100, got %v", t.quality)
+		}
+		if t.autoOrient, err = tConf.FieldBool(impFieldThumbnailAutoOrient); err != nil {
+			return nil, err
+		}
+		p.thumbnail = t
+	}
+	return p, nil
+}
+
+type exifWalker map[string]any
+
+func (w exifWalker) Walk(name exif.FieldName, tag *tiff.Tag) error {
+	switch name {
+	case exif.ExifIFDPointer, exif.GPSInfoIFDPointer, exif.InteroperabilityIFDPointer,
+		exif.MakerNote, exif.ThumbJPEGInterchangeFormat, exif.ThumbJPEGInterchangeFormatLength:
+		return nil
+	}
+	if v := exifTagValue(tag); v != nil {
+		w[string(name)] = v
+	}
+	return nil
+}
+
+func exifTagValue(tag *tiff.Tag) any {
+	count := int(tag.Count)
+
+	var values []any
+	switch tag.Format() {
+	case tiff.StringVal:
+		s, err := tag.StringVal()
+		if err != nil {
+			return nil
+		}
+		return strings.TrimSpace(s)
+	case tiff.UndefVal:
+		// Undefined values are usually binary, but are sometimes used for
+		// versions and character codes.
+		s := strings.TrimRight(string(tag.Val), "\x00")
+		if s == "" || strings.IndexFunc(s, func(r rune) bool {
+			return r > unicode.MaxASCII || !unicode.IsPrint(r)
+		}) != -1 {
+			return nil
+		}
+		return strings.TrimSpace(s)
+	case tiff.IntVal:
+		for i := 0; i < count; i++ {
+			v, err := tag.Int64(i)
+			if err != nil {
+				return nil
+			}
+			values = append(values, v)
+		}
+	case tiff.RatVal:
+		for i := 0; i < count; i++ {
+			num, den, err := tag.Rat2(i)
+			if err != nil || den == 0 {
+				return nil
+			}
+			values = append(values, float64(num)/float64(den))
+		}
+	case tiff.FloatVal:
+		for i := 0; i < count; i++ {
+			v, err := tag.Float(i)
+			if err != nil {
+				return nil
+			}
+			values = append(values, v)
+		}
+	}
+
+	switch len(values) {
+	case 0:
+		return nil
+	case 1:
+		return values[0]
+	}
+	return values
+}
+
+func (p *imageMetadataProc) Process(ctx context.Context, msg *service.Message) (service.MessageBatch, error) {
+	raw, err := msg.AsBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(raw))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image: %w", err)
+	}
+
+	result := map[string]any{
+		"format": format,
+		"width":  int64(cfg.Width),
+		"height": int64(cfg.Height),
+	}
+
+	orientation := 1
+	if p.exif || (p.thumbnail != nil && p.thumbnail.autoOrient) {
+		if x, err := exif.Decode(bytes.NewReader(raw)); x != nil && (err == nil || !exif.IsCriticalError(err)) {
+			if tag, err := x.Get(exif.Orientation); err == nil {
+				if o, err := tag.Int(0); err == nil && o >= 1 && o <= 8 {
+					orientation = o
+				}
+			}
+			if p.exif {
+				tags := exifWalker{}
+				_ = x.Walk(tags)
+				if len(tags) > 0 {
+					result["exif"] = map[string]any(tags)
+				}
+				if lat, long, err := x.LatLong(); err == nil && !math.IsNaN(lat) && !math.IsNaN(long) {
+					result["location"] = map[string]any{
+						"latitude":  lat,
+						"longitude": long,
+					}
+				}
+			}
+		}
+	}
+
+	if p.xmp {
+		if packet := findXMPPacket(raw); packet != nil {
+			props, err := parseXMP(packet)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse XMP metadata: %w", err)
+			}
+			if len(props) > 0 {
+				result["xmp"] = props
+			}
+		}
+	}
+
+	metaMsg := msg.Copy()
+	metaMsg.SetStructuredMut(result)
+	batch := service.MessageBatch{metaMsg}
+
+	if p.thumbnail != nil {
+		thumbMsg, err := p.thumbnail.generate(msg, raw, orientation)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate thumbnail: %w", err)
+		}
+		batch = append(batch, thumbMsg)
+	}
+	return batch, nil
+}
+
+func (p *imageMetadataProc) Close(ctx context.Context) error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+func (t *thumbnailConfig) generate(msg *service.Message, raw []byte, orientation int) (*service.Message, error) {
+	src, _, err := image.Decode(bytes.NewReader(raw))
+	if err != nil {
+		return nil, err
+	}
+
+	if !t.autoOrient {
+		orientation = 1
+	}
+
+	// Orientations 5 to 8 transpose the image, and therefore the maximum
+	// dimensions are swapped before scaling.
+	maxWidth, maxHeight := t.maxWidth, t.maxHeight
+	if orientation >= 5 {
+		maxWidth, maxHeight = maxHeight, maxWidth
+	}
+
+	bounds := src.Bounds()
+	scale := math.Min(1, math.Min(float64(maxWidth)/float64(bounds.Dx()), float64(maxHeight)/float64(bounds.Dy())))
+	width := int(math.Max(1, math.Round(float64(bounds.Dx())*scale)))
+	height := int(math.Max(1, math.Round(float64(bounds.Dy())*scale)))
+
+	scaled := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.CatmullRom.Scale(scaled, scaled.Bounds(), src, bounds, draw.Src, nil)
+
+	thumb := orient(scaled, orientation)
+
+	var buf bytes.Buffer
+	switch t.format {
+	case "png":
+		err = png.Encode(&buf, thumb)
+	default:
+		err = jpeg.Encode(&buf, thumb, &jpeg.Options{Quality: t.quality})
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	thumbMsg := msg.Copy()
+	thumbMsg.SetBytes(buf.Bytes())
+	thumbMsg.MetaSetMut("image_thumbnail_format", t.format)
+	thumbMsg.MetaSetMut("image_thumbnail_width", int64(thumb.Bounds().Dx()))
+	thumbMsg.MetaSetMut("image_thumbnail_height", int64(thumb.Bounds().Dy()))
+	return thumbMsg, nil
+}
+
+// orient applies an EXIF orientation to an image so that it's displayed
+// upright.
+func orient(src *image.RGBA, orientation int) *image.RGBA {
+	if orientation <= 1 || orientation > 8 {
+		return src
+	}
+
+	w, h := src.Bounds().Dx(), src.Bounds().Dy()
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var dx, dy int
+			switch orientation {
+			case 2:
+				dx, dy = w-1-x, y
+			case 3:
+				dx, dy = w-1-x, h-1-y
+			case 4:
+				dx, dy = x, h-1-y
+			case 5:
+				dx, dy = y, x
+			case 6:
+				dx, dy = h-1-y, x
+			case 7:
+				dx, dy = h-1-y, w-1-x
+			case 8:
+				dx, dy = y, w-1-x
+			}
+			dst.SetRGBA(dx, dy, src.RGBAAt(x, y))
+		}
+	}
+	return dst
+}
@@ -0,0 +1,340 @@
+package imagemeta
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+type testIFDEntry struct {
+	tag   uint16
+	typ   uint16
+	count uint32
+	data  []byte
+}
+
+// testTIFF encodes little endian TIFF data containing IFD0 and a GPS IFD.
+func testTIFF(ifd0, gps []testIFDEntry) []byte {
+	le := binary.LittleEndian
+
+	ifdSize := func(entries []testIFDEntry) int {
+		n := 2 + len(entries)*12 + 4
+		for _, e := range entries {
+			if len(e.data) > 4 {
+				n += len(e.data)
+			}
+		}
+		return n
+	}
+
+	if gps != nil {
+		// The GPS IFD follows IFD0, which includes the pointer entry.
+		ptr := make([]byte, 4)
+		le.PutUint32(ptr, uint32(8+ifdSize(ifd0)+12))
+		ifd0 = append(ifd0, testIFDEntry{tag: 0x8825, typ: 4, count: 1, data: ptr})
+	}
+
+	writeIFD := func(buf *bytes.Buffer, entries []testIFDEntry) {
+		start := buf.Len()
+		extra := start + 2 + len(entries)*12 + 4
+
+		var values bytes.Buffer
+		_ = binary.Write(buf, le, uint16(len(entries)))
+		for _, e := range entries {
+			_ = binary.Write(buf, le, e.tag)
+			_ = binary.Write(buf, le, e.typ)
+			_ = binary.Write(buf, le, e.count)
+			if len(e.data) > 4 {
+				_ = binary.Write(buf, le, uint32(extra+values.Len()))
+				values.Write(e.data)
+			} else {
+				inline := make([]byte, 4)
+				copy(inline, e.data)
+				buf.Write(inline)
+			}
+		}
+		_ = binary.Write(buf, le, uint32(0))
+		buf.Write(values.Bytes())
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("II*\x00")
+	_ = binary.Write(&buf, le, uint32(8))
+	writeIFD(&buf, ifd0)
+	if gps != nil {
+		writeIFD(&buf, gps)
+	}
+	return buf.Bytes()
+}
+
+func testASCII(s string) testIFDEntry {
+	return testIFDEntry{typ: 2, count: uint32(len(s) + 1), data: append([]byte(s), 0)}
+}
+
+func testRationals(vals ...[2]uint32) testIFDEntry {
+	var buf bytes.Buffer
+	for _, v := range vals {
+		_ = binary.Write(&buf, binary.LittleEndian, v[0])
+		_ = binary.Write(&buf, binary.LittleEndian, v[1])
+	}
+	return testIFDEntry{typ: 5, count: uint32(len(vals)), data: buf.Bytes()}
+}
+
+func withTag(e testIFDEntry, tag uint16) testIFDEntry {
+	e.tag = tag
+	return e
+}
+
+const testXMPPacket = `<x:xmpmeta xmlns:x="adobe:ns:meta/">
+ <rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#">
+  <rdf:Description rdf:about="" xmlns:dc="http://purl.org/dc/elements/1.1/" xmlns:xmp="http://ns.adobe.com/xap/1.0/" xmp:Rating="4">
+   <dc:title><rdf:Alt><rdf:li xml:lang="fr">Pont</rdf:li><rdf:li xml:lang="x-default">Bridge</rdf:li></rdf:Alt></dc:title>
+   <dc:subject><rdf:Bag><rdf:li>london</rdf:li><rdf:li>travel</rdf:li></rdf:Bag></dc:subject>
+   <dc:format>image/jpeg</dc:format>
+  </rdf:Description>
+ </rdf:RDF>
+</x:xmpmeta>`
+
+// testImage creates a 40x20 JPEG, left half red and right half blue, with EXIF
+// and XMP segments.
+func testImage(t testing.TB) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, 40, 20))
+	for y := 0; y < 20; y++ {
+		for x := 0; x < 40; x++ {
+			c := color.RGBA{R: 0xff, A: 0xff}
+			if x >= 20 {
+				c = color.RGBA{B: 0xff, A: 0xff}
+			}
+			img.SetRGBA(x, y, c)
+		}
+	}
+	var jpg bytes.Buffer
+	require.NoError(t, jpeg.Encode(&jpg, img, &jpeg.Options{Quality: 100}))
+
+	orientation := make([]byte, 2)
+	binary.LittleEndian.PutUint16(orientation, 6)
+
+	tiffData := testTIFF([]testIFDEntry{
+		withTag(testASCII("Acme"), 0x010F),
+		withTag(testASCII("Camera 3000"), 0x0110),
+		{tag: 0x0112, typ: 3, count: 1, data: orientation},
+	}, []testIFDEntry{
+		withTag(testASCII("N"), 0x0001),
+		withTag(testRationals([2]uint32{51, 1}, [2]uint32{30, 1}, [2]uint32{0, 1}), 0x0002),
+		withTag(testASCII("W"), 0x0003),
+		withTag(testRationals([2]uint32{0, 1}, [2]uint32{7, 1}, [2]uint32{30, 1}), 0x0004),
+	})
+
+	segment := func(payload []byte) []byte {
+		seg := []byte{0xFF, 0xE1, 0, 0}
+		binary.BigEndian.PutUint16(seg[2:], uint16(len(payload)+2))
+		return append(seg, payload...)
+	}
+
+	var out bytes.Buffer
+	out.Write(jpg.Bytes()[:2])
+	out.Write(segment(append([]byte("Exif\x00\x00"), tiffData...)))
+	out.Write(segment(append([]byte("http://ns.adobe.com/xap/1.0/\x00"), testXMPPacket...)))
+	out.Write(jpg.Bytes()[2:])
+	return out.Bytes()
+}
+
+func TestImageMetadataExtract(t *testing.T) {
+	conf, err := imageMetadataProcSpec().ParseYAML(``, nil)
+	require.NoError(t, err)
+
+	proc, err := newImageMetadataProcFromConfig(conf)
+	require.NoError(t, err)
+
+	res, err := proc.Process(context.Background(), service.NewMessage(testImage(t)))
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+
+	v, err := res[0].AsStructured()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{
+		"format": "jpeg",
+		"width":  int64(40),
+		"height": int64(20),
+		"exif": map[string]any{
+			"Make":            "Acme",
+			"Model":           "Camera 3000",
+			"Orientation":     int64(6),
+			"GPSLatitudeRef":  "N",
+			"GPSLatitude":     []any{51.0, 30.0, 0.0},
+			"GPSLongitudeRef": "W",
+			"GPSLongitude":    []any{0.0, 7.0, 30.0},
+		},
+		"location": map[string]any{
+			"latitude":  51.5,
+			"longitude": -0.125,
+		},
+		"xmp": map[string]any{
+			"dc:format":  "image/jpeg",
+			"dc:subject": []any{"london", "travel"},
+			"dc:title":   "Bridge",
+			"xmp:Rating": "4",
+		},
+	}, v)
+}
+
+func TestImageMetadataDisabled(t *testing.T) {
+	conf, err := imageMetadataProcSpec().ParseYAML(`
+exif: false
+xmp: false
+`, nil)
+	require.NoError(t, err)
+
+	proc, err := newImageMetadataProcFromConfig(conf)
+	require.NoError(t, err)
+
+	res, err := proc.Process(context.Background(), service.NewMessage(testImage(t)))
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+
+	b, err := res[0].AsBytes()
+	require.NoError(t, err)
+	assert.Equal(t, `{"format":"jpeg","height":20,"width":40}`, string(b))
+}
+
+func TestImageMetadataPNG(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, image.NewGray(image.Rect(0, 0, 3, 7))))
+
+	conf, err := imageMetadataProcSpec().ParseYAML(``, nil)
+	require.NoError(t, err)
+
+	proc, err := newImageMetadataProcFromConfig(conf)
+	require.NoError(t, err)
+
+	res, err := proc.Process(context.Background(), service.NewMessage(buf.Bytes()))
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+
+	b, err := res[0].AsBytes()
+	require.NoError(t, err)
+	assert.Equal(t, `{"format":"png","height":7,"width":3}`, string(b))
+
+	_, err = proc.Process(context.Background(), service.NewMessage([]byte(`not an image`)))
+	require.Error(t, err)
+}
+
+func TestImageMetadataThumbnail(t *testing.T) {
+	for _, test := range []struct {
+		name          string
+		conf          string
+		width, height int64
+		topLeft       color.RGBA
+	}{
+		{
+			name: "auto orient",
+			conf: `
+thumbnail:
+  enabled: true
+  max_width: 10
+  max_height: 10
+  format: png
+`,
+			width:   5,
+			height:  10,
+			topLeft: color.RGBA{R: 0xff, A: 0xff},
+		},
+		{
+			name: "no orient",
+			conf: `
+thumbnail:
+  enabled: true
+  max_width: 10
+  max_height: 10
+  format: png
+  auto_orient: false
+`,
+			width:   10,
+			height:  5,
+			topLeft: color.RGBA{R: 0xff, A: 0xff},
+		},
+		{
+			name: "no upscale",
+			conf: `
+thumbnail:
+  enabled: true
+  max_width: 100
+  max_height: 100
+  format: png
+  auto_orient: false
+`,
+			width:   40,
+			height:  20,
+			topLeft: color.RGBA{R: 0xff, A: 0xff},
+		},
+	} {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			conf, err := imageMetadataProcSpec().ParseYAML(test.conf, nil)
+			require.NoError(t, err)
+
+			proc, err := newImageMetadataProcFromConfig(conf)
+			require.NoError(t, err)
+
+			res, err := proc.Process(context.Background(), service.NewMessage(testImage(t)))
+			require.NoError(t, err)
+			require.Len(t, res, 2)
+
+			b, err := res[1].AsBytes()
+			require.NoError(t, err)
+
+			img, err := png.Decode(bytes.NewReader(b))
+			require.NoError(t, err)
+			assert.Equal(t, int(test.width), img.Bounds().Dx())
+			assert.Equal(t, int(test.height), img.Bounds().Dy())
+
+			r, g, bl, _ := img.At(0, 0).RGBA()
+			assert.Greater(t, r>>8, uint32(0xe0))
+			assert.Less(t, g>>8, uint32(0x20))
+			assert.Less(t, bl>>8, uint32(0x20))
+
+			width, _ := res[1].MetaGetMut("image_thumbnail_width")
+			assert.Equal(t, test.width, width)
+			height, _ := res[1].MetaGetMut("image_thumbnail_height")
+			assert.Equal(t, test.height, height)
+			format, _ := res[1].MetaGet("image_thumbnail_format")
+			assert.Equal(t, "png", format)
+		})
+	}
+}
+
+func TestImageMetadataConfigErrors(t *testing.T) {
+	for _, confStr := range []string{
+		`thumbnail: { enabled: true, max_width: 0 }`,
+		`thumbnail: { enabled: true, quality: 101 }`,
+	} {
+		conf, err := imageMetadataProcSpec().ParseYAML(confStr, nil)
+		require.NoError(t, err, confStr)
+
+		_, err = newImageMetadataProcFromConfig(conf)
+		require.Error(t, err, confStr)
+	}
+}
+
+func TestParseXMPIgnoresStructures(t *testing.T) {
+	props, err := parseXMP([]byte(`<x:xmpmeta xmlns:x="adobe:ns:meta/">
+ <rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#">
+  <rdf:Description xmlns:foo="http://example.com/foo/">
+   <foo:nested rdf:parseType="Resource"><foo:inner>value</foo:inner></foo:nested>
+   <foo:simple>hello</foo:simple>
+  </rdf:Description>
+ </rdf:RDF>
+</x:xmpmeta>`))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"foo:simple": "hello"}, props)
+}
@@ -0,0 +1,195 @@
+package imagemeta
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"strings"
+)
+
+const xmpRDFNamespace = "http://www.w3.org/1999/02/22-rdf-syntax-ns#"
+
+var (
+	xmpPacketStart = []byte("<x:xmpmeta")
+	xmpPacketEnd   = []byte("</x:xmpmeta>")
+)
+
+// findXMPPacket returns the first XMP packet found within an image, which works
+// for all common image formats as packets are stored uncompressed.
+func findXMPPacket(data []byte) []byte {
+	start := bytes.Index(data, xmpPacketStart)
+	if start == -1 {
+		return nil
+	}
+	end := bytes.Index(data[start:], xmpPacketEnd)
+	if end == -1 {
+		return nil
+	}
+	return data[start : start+end+len(xmpPacketEnd)]
+}
+
+type xmpParser struct {
+	dec      *xml.Decoder
+	prefixes map[string]string
+}
+
+// parseXMP flattens the properties of an XMP packet into a map keyed by the
+// prefixed property names, e.g. dc:creator. Simple properties are strings,
+// ordered and unordered arrays are lists of strings, and language
+// alternatives are the default value. Structured properties are ignored.
+func parseXMP(packet []byte) (map[string]any, error) {
+	p := &xmpParser{
+		dec:      xml.NewDecoder(bytes.NewReader(packet)),
+		prefixes: map[string]string{},
+	}
+
+	props := map[string]any{}
+	for {
+		tok, err := p.dec.Token()
+		if errors.Is(err, io.EOF) {
+			return props, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		start, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		p.addPrefixes(start)
+		if start.Name.Space != xmpRDFNamespace || start.Name.Local != "Description" {
+			continue
+		}
+		if err := p.parseDescription(start, props); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (p *xmpParser) addPrefixes(start xml.StartElement) {
+	for _, attr := range start.Attr {
+		if attr.Name.Space == "xmlns" {
+			p.prefixes[attr.Value] = attr.Name.Local
+		}
+	}
+}
+
+func (p *xmpParser) name(n xml.Name) string {
+	if prefix, exists := p.prefixes[n.Space]; exists {
+		return prefix + ":" + n.Local
+	}
+	if n.Space == "" {
+		return n.Local
+	}
+	return n.Space + ":" + n.Local
+}
+
+func (p *xmpParser) parseDescription(start xml.StartElement, props map[string]any) error {
+	for _, attr := range start.Attr {
+		if attr.Name.Space == "xmlns" || attr.Name.Space == xmpRDFNamespace || attr.Name.Space == "" {
+			continue
+		}
+		props[p.name(attr.Name)] = attr.Value
+	}
+
+	for {
+		tok, err := p.dec.Token()
+		if err != nil {
+			return err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			p.addPrefixes(t)
+			v, err := p.parseValue()
+			if err != nil {
+				return err
+			}
+			if v != nil {
+				props[p.name(t.Name)] = v
+			}
+		case xml.EndElement:
+			return nil
+		}
+	}
+}
+
+// parseValue parses the value of a property element, consuming tokens up to
+// and including the end of the element.
+func (p *xmpParser) parseValue() (any, error) {
+	var text strings.Builder
+	var value any
+	for {
+		tok, err := p.dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.CharData:
+			text.Write(t)
+		case xml.StartElement:
+			p.addPrefixes(t)
+			if t.Name.Space == xmpRDFNamespace && (t.Name.Local == "Seq" || t.Name.Local == "Bag" || t.Name.Local == "Alt") {
+				if value, err = p.parseArray(t.Name.Local == "Alt"); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			if err := p.dec.Skip(); err != nil {
+				return nil, err
+			}
+		case xml.EndElement:
+			if value != nil {
+				return value, nil
+			}
+			if s := strings.TrimSpace(text.String()); s != "" {
+				return s, nil
+			}
+			return nil, nil
+		}
+	}
+}
+
+func (p *xmpParser) parseArray(alt bool) (any, error) {
+	items := []any{}
+	var defaultItem any
+	for {
+		tok, err := p.dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			p.addPrefixes(t)
+			isDefault := false
+			for _, attr := range t.Attr {
+				if attr.Name.Local == "lang" && attr.Value == "x-default" {
+					isDefault = true
+				}
+			}
+			v, err := p.parseValue()
+			if err != nil {
+				return nil, err
+			}
+			if v == nil {
+				continue
+			}
+			if isDefault {
+				defaultItem = v
+			}
+			items = append(items, v)
+		case xml.EndElement:
+			if !alt {
+				return items, nil
+			}
+			if defaultItem != nil {
+				return defaultItem, nil
+			}
+			if len(items) > 0 {
+				return items[0], nil
+			}
+			return nil, nil
+		}
+	}
+}
@@ -0,0 +1,536 @@
+package influxdb
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+	client "github.com/influxdata/influxdb1-client/v2"
+
+	"github.com/benthosdev/benthos/v4/internal/impl/pure"
+	"github.com/benthosdev/benthos/v4/internal/value"
+	"github.com/benthosdev/benthos/v4/public/bloblang"
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	ioFieldURL         = "url"
+	ioFieldAPIVersion  = "api_version"
+	ioFieldToken       = "token"
+	ioFieldOrg         = "org"
+	ioFieldBucket      = "bucket"
+	ioFieldMeasurement = "measurement"
+	ioFieldTags        = "tags"
+	ioFieldFields      = "fields_mapping"
+	ioFieldTimestamp   = "timestamp_mapping"
+	ioFieldPrecision   = "precision"
+	ioFieldGzip        = "gzip"
+	ioFieldTimeout     = "timeout"
+	ioFieldTLS         = "tls"
+	ioFieldBatching    = "batching"
+)
+
+func outputSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Services").
+		Version("4.28.0").
+		Summary("Writes points to InfluxDB 2.x or 3.x using line protocol.").
+		Description(`
+Each message is converted into a point with a measurement and tags resolved with interpolation functions, and fields resulting from the `+"`fields_mapping`"+`. Messages of a batch are encoded as line protocol and written with a single request for each distinct org and bucket.
+
+### Field Types
+
+Field values are written according to their type. Strings and booleans are written as such, numbers parsed from JSON documents are written as floats, and integer values created by the mapping (for example with the `+"[`int64`](/docs/guides/bloblang/methods#int64) or [`uint64`](/docs/guides/bloblang/methods#uint64)"+` methods) are written as integers and unsigned integers respectively. Fields with null values are omitted.
+
+### API Versions
+
+With `+"`api_version`"+` set to `+"`v2`"+` points are written to the `+"`/api/v2/write`"+` endpoint, which is supported by InfluxDB 2.x, InfluxDB Cloud and, for compatibility, InfluxDB 3.x. With `+"`v3`"+` points are written to the `+"`/api/v3/write_lp`"+` endpoint of InfluxDB 3.x, in which case the `+"`bucket`"+` is used as the database and the `+"`org`"+` is ignored.
+
+### Retries
+
+Requests that fail with a 429 or 5XX status code are retried according to the `+"`backoff`"+` fields, honouring any `+"`Retry-After`"+` header returned. Any other failed status code fails the batch immediately.`+service.OutputPerformanceDocs(true, true)).
+		Fields(
+			service.NewStringField(ioFieldURL).
+				Description("The base URL of the InfluxDB server.").
+				Example("http://localhost:8086"),
+			service.NewStringEnumField(ioFieldAPIVersion, "v2", "v3").
+				Description("The version of the write API to use.").
+				Default("v2"),
+			service.NewStringField(ioFieldToken).
+				Description("An API token to authenticate with.").
+				Secret().
+				Default(""),
+			service.NewInterpolatedStringField(ioFieldOrg).
+				Description("The organization to write to, this is ignored for the v3 API.").
+				Default(""),
+			service.NewInterpolatedStringField(ioFieldBucket).
+				Description("The bucket (or database) to write to."),
+			service.NewInterpolatedStringField(ioFieldMeasurement).
+				Description("The measurement of each point.").
+				Example(`${! @kafka_topic }`).
+				Example("cpu"),
+			service.NewInterpolatedStringMapField(ioFieldTags).
+				Description("A map of tags to add to each point, tags that resolve to an empty string are omitted.").
+				Example(map[string]any{
+					"host":   `${! this.host }`,
+					"region": "eu-west-1",
+				}).
+				Default(map[string]any{}),
+			service.NewBloblangField(ioFieldFields).
+				Description("A [Bloblang mapping](/docs/guides/bloblang/about) that results in an object of the fields of each point.").
+				Example(`root.usage_user = this.usage.user
+root.usage_system = this.usage.system
+root.cores = this.cores.int64()`).
+				Default("root = this"),
+			service.NewBloblangField(ioFieldTimestamp).
+				Description("An optional [Bloblang mapping](/docs/guides/bloblang/about) that results in the timestamp of each point, either as a timestamp, a string in RFC 3339 format or a unix timestamp. When omitted the timestamp is assigned by InfluxDB.").
+				Example(`root = this.ts`).
+				Optional(),
+			service.NewStringEnumField(ioFieldPrecision, "ns", "us", "ms", "s").
+				Description("The precision of timestamps written.").
+				Advanced().
+				Default("ns"),
+			service.NewBoolField(ioFieldGzip).
+				Description("Whether to compress requests with gzip.").
+				Advanced().
+				Default(false),
+			service.NewDurationField(ioFieldTimeout).
+				Description("The maximum period to wait for a request to complete.").
+				Advanced().
+				Default("10s"),
+			service.NewTLSToggledField(ioFieldTLS),
+			service.NewOutputMaxInFlightField(),
+			service.NewBatchPolicyField(ioFieldBatching),
+		).
+		Fields(pure.CommonRetryBackOffFields(0, "500ms", "30s", "5m")...).
+		Example("CPU Metrics", "Write CPU usage metrics with a tag per host.", `
+output:
+  influxdb:
+    url: http://localhost:8086
+    token: ${INFLUX_TOKEN}
+    org: acme
+    bucket: metrics
+    measurement: cpu
+    tags:
+      host: ${! this.host }
+    fields_mapping: |
+      root.usage = this.usage
+      root.cores = this.cores.int64()
+    timestamp_mapping: root = this.ts
+    batching:
+      count: 5000
+      period: 1s
+`)
+}
+
+func init() {
+	err := service.RegisterBatchOutput("influxdb", outputSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (out service.BatchOutput, batchPolicy service.BatchPolicy, maxInFlight int, err error) {
+			if maxInFlight, err = conf.FieldMaxInFlight(); err != nil {
+				return
+			}
+			if batchPolicy, err = conf.FieldBatchPolicy(ioFieldBatching); err != nil {
+				return
+			}
+			out, err = newOutputWriter(conf, mgr)
+			return
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type outputWriter struct {
+	log *service.Logger
+
+	baseURL     string
+	v3          bool
+	token       string
+	org         *service.InterpolatedString
+	bucket      *service.InterpolatedString
+	measurement *service.InterpolatedString
+	tags        map[string]*service.InterpolatedString
+	fields      *bloblang.Executor
+	timestamp   *bloblang.Executor
+	precision   string
+	gzip        bool
+
+	backoffCtor func() backoff.BackOff
+	client      *http.Client
+}
+
+func newOutputWriter(conf *service.ParsedConfig, mgr *service.Resources) (*outputWriter, error) {
+	w := &outputWriter{
+		log: mgr.Logger(),
+	}
+
+	var err error
+	if w.baseURL, err = conf.FieldString(ioFieldURL); err != nil {
+		return nil, err
+	}
+	w.baseURL = strings.TrimSuffix(w.baseURL, "/")
+
+	var apiVersion string
+	if apiVersion, err = conf.FieldString(ioFieldAPIVersion); err != nil {
+		return nil, err
+	}
+	w.v3 = apiVersion == "v3"
+
+	if w.token, err = conf.FieldString(ioFieldToken); err != nil {
+		return nil, err
+	}
+	if w.org, err = conf.FieldInterpolatedString(ioFieldOrg); err != nil {
+		return nil, err
+	}
+	if w.bucket, err = conf.FieldInterpolatedString(ioFieldBucket); err != nil {
+		return nil, err
+	}
+	if w.measurement, err = conf.FieldInterpolatedString(ioFieldMeasurement); err != nil {
+		return nil, err
+	}
+	if w.tags, err = conf.FieldInterpolatedStringMap(ioFieldTags); err != nil {
+		return nil, err
+	}
+	if w.fields, err = conf.FieldBloblang(ioFieldFields); err != nil {
+		return nil, err
+	}
+	if conf.Contains(ioFieldTimestamp) {
+		if w.timestamp, err = conf.FieldBloblang(ioFieldTimestamp); err != nil {
+			return nil, err
+		}
+	}
+	if w.precision, err = conf.FieldString(ioFieldPrecision); err != nil {
+		return nil, err
+	}
+	if w.gzip, err = conf.FieldBool(ioFieldGzip); err != nil {
+		return nil, err
+	}
+
+	var timeout time.Duration
+	if timeout, err = conf.FieldDuration(ioFieldTimeout); err != nil {
+		return nil, err
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	var tlsConf *tls.Config
+	var tlsEnabled bool
+	if tlsConf, tlsEnabled, err = conf.FieldTLSToggled(ioFieldTLS); err != nil {
+		return nil, err
+	}
+	if tlsEnabled {
+		transport.TLSClientConfig = tlsConf
+	}
+	w.client = &http.Client{Transport: transport, Timeout: timeout}
+
+	if w.backoffCtor, err = pure.CommonRetryBackOffCtorFromParsed(conf); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *outputWriter) Connect(ctx context.Context) error {
+	return nil
+}
+
+// linePrecision maps a configured precision onto that expected when encoding
+// points with the influxdb1 client.
+func (w *outputWriter) linePrecision() string {
+	switch w.precision {
+	case "us":
+		return "u"
+	case "ns":
+		return "n"
+	}
+	return w.precision
+}
+
+func (w *outputWriter) writeURL(org, bucket string) string {
+	query := url.Values{}
+	if w.v3 {
+		query.Set("db", bucket)
+		query.Set("precision", map[string]string{
+			"ns": "nanosecond",
+			"us": "microsecond",
+			"ms": "millisecond",
+			"s":  "second",
+		}[w.precision])
+		return w.baseURL + "/api/v3/write_lp?" + query.Encode()
+	}
+	if org != "" {
+		query.Set("org", org)
+	}
+	query.Set("bucket", bucket)
+	query.Set("precision", w.precision)
+	return w.baseURL + "/api/v2/write?" + query.Encode()
+}
+
+func fieldValue(v any) (any, error) {
+	switch t := v.(type) {
+	case json.Number:
+		return t.Float64()
+	case float32:
+		return float64(t), nil
+	case float64, int64, uint64, bool, string:
+		return t, nil
+	case int:
+		return int64(t), nil
+	case int32:
+		return int64(t), nil
+	case uint32:
+		return uint64(t), nil
+	case []byte:
+		return string(t), nil
+	}
+	return nil, fmt.Errorf("unsupported field value type %T", v)
+}
+
+func (w *outputWriter) lineFrom(batch service.MessageBatch, i int) ([]byte, error) {
+	measurement, err := batch.TryInterpolatedString(i, w.measurement)
+	if err != nil {
+		return nil, fmt.Errorf("measurement interpolation error: %w", err)
+	}
+	if measurement == "" {
+		return nil, errors.New("measurement must not be empty")
+	}
+
+	tags := make(map[string]string, len(w.tags))
+	for k, istr := range w.tags {
+		v, err := batch.TryInterpolatedString(i, istr)
+		if err != nil {
+			return nil, fmt.Errorf("tag %v interpolation error: %w", k, err)
+		}
+		if v != "" {
+			tags[k] = v
+		}
+	}
+
+	fieldsMsg, err := batch.BloblangQuery(i, w.fields)
+	if err != nil {
+		return nil, fmt.Errorf("fields mapping failed: %w", err)
+	}
+	var fieldsV any
+	if fieldsMsg != nil {
+		if fieldsV, err = fieldsMsg.AsStructured(); err != nil {
+			return nil, fmt.Errorf("fields mapping failed: %w", err)
+		}
+	}
+	fieldsObj, ok := fieldsV.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("expected fields mapping to result in an object, got %T", fieldsV)
+	}
+	fields := make(map[string]any, len(fieldsObj))
+	for k, v := range fieldsObj {
+		if v == nil {
+			continue
+		}
+		if fields[k], err = fieldValue(v); err != nil {
+			return nil, fmt.Errorf("field %v: %w", k, err)
+		}
+	}
+	if len(fields) == 0 {
+		return nil, errors.New("point must have at least one field")
+	}
+
+	var ts []time.Time
+	if w.timestamp != nil {
+		tsMsg, err := batch.BloblangQuery(i, w.timestamp)
+		if err != nil {
+			return nil, fmt.Errorf("timestamp mapping failed: %w", err)
+		}
+		var tsV any
+		if tsMsg != nil {
+			if tsV, err = tsMsg.AsStructured(); err != nil {
+				if tsBytes, _ := tsMsg.AsBytes(); len(tsBytes) > 0 {
+					tsV = string(tsBytes)
+					err = nil
+				}
+			}
+			if err != nil {
+				return nil, fmt.Errorf("timestamp mapping failed: %w", err)
+			}
+		}
+		if tsV != nil {
+			t, err := value.IGetTimestamp(tsV)
+			if err != nil {
+				return nil, fmt.Errorf("timestamp mapping failed: %w", err)
+			}
+			ts = append(ts, t)
+		}
+	}
+
+	pt, err := client.NewPoint(measurement, tags, fields, ts...)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(pt.PrecisionString(w.linePrecision())), nil
+}
+
+type influxWrite struct {
+	url   string
+	body  bytes.Buffer
+	index []int
+}
+
+func (w *outputWriter) WriteBatch(ctx context.Context, batch service.MessageBatch) error {
+	var batchErr *service.BatchError
+	failed := func(i int, err error) {
+		if batchErr == nil {
+			batchErr = service.NewBatchError(batch, err)
+		}
+		batchErr.Failed(i, err)
+	}
+
+	var writes []*influxWrite
+	writesByURL := map[string]*influxWrite{}
+	for i := range batch {
+		org, err := batch.TryInterpolatedString(i, w.org)
+		if err != nil {
+			failed(i, fmt.Errorf("org interpolation error: %w", err))
+			continue
+		}
+		bucket, err := batch.TryInterpolatedString(i, w.bucket)
+		if err != nil {
+			failed(i, fmt.Errorf("bucket interpolation error: %w", err))
+			continue
+		}
+		line, err := w.lineFrom(batch, i)
+		if err != nil {
+			failed(i, err)
+			continue
+		}
+
+		u := w.writeURL(org, bucket)
+		wr, exists := writesByURL[u]
+		if !exists {
+			wr = &influxWrite{url: u}
+			writesByURL[u] = wr
+			writes = append(writes, wr)
+		}
+		wr.body.Write(line)
+		wr.body.WriteByte('\n')
+		wr.index = append(wr.index, i)
+	}
+
+	for _, wr := range writes {
+		if err := w.write(ctx, wr.url, wr.body.Bytes()); err != nil {
+			if len(writes) == 1 && batchErr == nil {
+				return err
+			}
+			for _, i := range wr.index {
+				failed(i, err)
+			}
+		}
+	}
+
+	if batchErr != nil {
+		return batchErr
+	}
+	return nil
+}
+
+type writeStatusError struct {
+	status     int
+	body       []byte
+	retryAfter time.Duration
+}
+
+func (e *writeStatusError) Error() string {
+	return fmt.Sprintf("write request failed with status %v: %s", e.status, bytes.TrimSpace(e.body))
+}
+
+func (e *writeStatusError) retryable() bool {
+	return e.status == http.StatusTooManyRequests || e.status >= 500
+}
+
+func (w *outputWriter) write(ctx context.Context, reqURL string, body []byte) error {
+	contentEncoding := ""
+	if w.gzip {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		if _, err := zw.Write(body); err != nil {
+			return err
+		}
+		if err := zw.Close(); err != nil {
+			return err
+		}
+		body, contentEncoding = buf.Bytes(), "gzip"
+	}
+
+	boff := w.backoffCtor()
+	for {
+		err := w.writeOnce(ctx, reqURL, contentEncoding, body)
+		if err == nil {
+			return nil
+		}
+
+		var sErr *writeStatusError
+		if errors.As(err, &sErr) && !sErr.retryable() {
+			return err
+		}
+
+		wait := boff.NextBackOff()
+		if wait == backoff.Stop {
+			return err
+		}
+		if sErr != nil && sErr.retryAfter > wait {
+			wait = sErr.retryAfter
+		}
+		w.log.Warnf("Retrying write request in %v: %v", wait, err)
+
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (w *outputWriter) writeOnce(ctx context.Context, reqURL, contentEncoding string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, reqURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	if contentEncoding != "" {
+		req.Header.Set("Content-Encoding", contentEncoding)
+	}
+	if w.token != "" {
+		req.Header.Set("Authorization", "Token "+w.token)
+	}
+
+	res, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode >= 200 && res.StatusCode <= 299 {
+		_, _ = io.Copy(io.Discard, res.Body)
+		return nil
+	}
+
+	resBody, _ := io.ReadAll(io.LimitReader(res.Body, 4096))
+	sErr := &writeStatusError{status: res.StatusCode, body: resBody}
+	if secs, err := strconv.Atoi(res.Header.Get("Retry-After")); err == nil && secs > 0 {
+		sErr.retryAfter = time.Duration(secs) * time.Second
+	}
+	return sErr
+}
+
+func (w *outputWriter) Close(ctx context.Context) error {
+	w.client.CloseIdleConnections()
+	return nil
+}
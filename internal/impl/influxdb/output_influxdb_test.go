@@ -0,0 +1,222 @@
+package influxdb
+
+import (
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+type fakeInflux struct {
+	mut      sync.Mutex
+	throttle int
+	paths    []string
+	bodies   []string
+	auth     []string
+}
+
+func (f *fakeInflux) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mut.Lock()
+	defer f.mut.Unlock()
+
+	if f.throttle > 0 {
+		f.throttle--
+		w.Header().Set("Retry-After", "0")
+		http.Error(w, "slow down", http.StatusTooManyRequests)
+		return
+	}
+
+	var body io.Reader = r.Body
+	if r.Header.Get("Content-Encoding") == "gzip" {
+		zr, err := gzip.NewReader(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		body = zr
+	}
+	b, err := io.ReadAll(body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	f.paths = append(f.paths, r.URL.Path+"?"+r.URL.RawQuery)
+	f.bodies = append(f.bodies, string(b))
+	f.auth = append(f.auth, r.Header.Get("Authorization"))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func testInfluxWriter(t *testing.T, confStr string) *outputWriter {
+	t.Helper()
+
+	pConf, err := outputSpec().ParseYAML(confStr, nil)
+	require.NoError(t, err)
+
+	w, err := newOutputWriter(pConf, service.MockResources())
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = w.Close(context.Background())
+	})
+	return w
+}
+
+func TestInfluxOutputV2(t *testing.T) {
+	fake := &fakeInflux{throttle: 1}
+	ts := httptest.NewServer(fake)
+	t.Cleanup(ts.Close)
+
+	w := testInfluxWriter(t, `
+url: `+ts.URL+`
+token: footoken
+org: acme
+bucket: ${! @bucket }
+measurement: cpu
+tags:
+  host: ${! this.host }
+  region: ${! this.region.or("") }
+fields_mapping: |
+  root.usage = this.usage
+  root.cores = this.cores.int64()
+  root.label = this.label
+timestamp_mapping: root = this.ts
+gzip: true
+backoff:
+  initial_interval: 1ms
+`)
+
+	var batch service.MessageBatch
+	for _, v := range []struct {
+		bucket, body string
+	}{
+		{"a", `{"host":"h1","region":"eu","usage":0.5,"cores":4,"label":"foo","ts":1}`},
+		{"b", `{"host":"h2","usage":1,"cores":2,"label":"bar","ts":2}`},
+		{"a", `{"host":"h3","usage":2.5,"cores":8,"label":"baz","ts":3}`},
+	} {
+		m := service.NewMessage([]byte(v.body))
+		m.MetaSetMut("bucket", v.bucket)
+		batch = append(batch, m)
+	}
+
+	require.NoError(t, w.WriteBatch(context.Background(), batch))
+
+	fake.mut.Lock()
+	defer fake.mut.Unlock()
+
+	assert.Equal(t, []string{
+		"/api/v2/write?bucket=a&org=acme&precision=ns",
+		"/api/v2/write?bucket=b&org=acme&precision=ns",
+	}, fake.paths)
+	assert.Equal(t, []string{"Token footoken", "Token footoken"}, fake.auth)
+	assert.Equal(t, []string{
+		`cpu,host=h1,region=eu cores=4i,label="foo",usage=0.5 1000000000` + "\n" +
+			`cpu,host=h3 cores=8i,label="baz",usage=2.5 3000000000` + "\n",
+		`cpu,host=h2 cores=2i,label="bar",usage=1 2000000000` + "\n",
+	}, fake.bodies)
+}
+
+func TestInfluxOutputV3(t *testing.T) {
+	fake := &fakeInflux{}
+	ts := httptest.NewServer(fake)
+	t.Cleanup(ts.Close)
+
+	w := testInfluxWriter(t, `
+url: `+ts.URL+`/
+api_version: v3
+bucket: metrics
+measurement: ${! @name }
+precision: s
+fields_mapping: root.value = this.value.uint64()
+timestamp_mapping: root = this.ts
+`)
+
+	m := service.NewMessage([]byte(`{"value":10,"ts":"2024-01-02T03:04:05Z"}`))
+	m.MetaSetMut("name", "temp")
+	require.NoError(t, w.WriteBatch(context.Background(), service.MessageBatch{m}))
+
+	fake.mut.Lock()
+	defer fake.mut.Unlock()
+
+	assert.Equal(t, []string{"/api/v3/write_lp?db=metrics&precision=second"}, fake.paths)
+	assert.Equal(t, []string{""}, fake.auth)
+	assert.Equal(t, []string{"temp value=10u 1704164645\n"}, fake.bodies)
+}
+
+func TestInfluxOutputInvalidPoints(t *testing.T) {
+	fake := &fakeInflux{}
+	ts := httptest.NewServer(fake)
+	t.Cleanup(ts.Close)
+
+	w := testInfluxWriter(t, `
+url: `+ts.URL+`
+bucket: metrics
+measurement: ${! this.name.or("") }
+`)
+
+	var batch service.MessageBatch
+	for _, s := range []string{
+		`{"name":"ok","value":1}`,
+		`{"value":1}`,
+		`{"name":"nested","value":{"a":1}}`,
+		`"foo"`,
+		`{"name":"ok","value":2}`,
+	} {
+		batch = append(batch, service.NewMessage([]byte(s)))
+	}
+
+	index := batch.Index()
+	err := w.WriteBatch(context.Background(), batch)
+	require.Error(t, err)
+
+	var bErr *service.BatchError
+	require.True(t, errors.As(err, &bErr))
+
+	var failed []int
+	bErr.WalkMessagesIndexedBy(index, func(i int, _ *service.Message, err error) bool {
+		if err != nil {
+			failed = append(failed, i)
+		}
+		return true
+	})
+	sort.Ints(failed)
+	assert.Equal(t, []int{1, 2, 3}, failed)
+
+	fake.mut.Lock()
+	defer fake.mut.Unlock()
+
+	require.Len(t, fake.bodies, 1)
+	assert.Equal(t, 2, strings.Count(fake.bodies[0], "\n"))
+}
+
+func TestInfluxOutputNonRetryableStatus(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "partial write: field type conflict", http.StatusBadRequest)
+	}))
+	t.Cleanup(ts.Close)
+
+	w := testInfluxWriter(t, `
+url: `+ts.URL+`
+bucket: metrics
+measurement: cpu
+`)
+
+	start := time.Now()
+	err := w.WriteBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte(`{"value":1}`)),
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "field type conflict")
+	assert.Less(t, time.Since(start), time.Second)
+}
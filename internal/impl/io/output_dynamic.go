@@ -48,7 +48,19 @@ Stops and removes an output.
 
 ### GET `+"`/outputs/{id}/uptime`"+`
 
-Returns the uptime of an output as a duration string (of the form "72h3m0.5s").`).
+Returns the uptime of an output as a duration string (of the form "72h3m0.5s").
+
+### GET `+"`/outputs/{id}/stats`"+`
+
+Returns a JSON object detailing the delivery stats of an output, including whether it is paused, the number of messages sent and failed, and the time and error of the most recent failure. These stats are also included in the response of `+"`GET /outputs`"+`.
+
+### POST `+"`/outputs/{id}/pause`"+`
+
+Pauses an output. Messages are not delivered to paused outputs whilst other outputs continue to receive them, and when all outputs are paused the output waits until one is resumed. Updating the configuration of a paused output keeps it paused.
+
+### POST `+"`/outputs/{id}/resume`"+`
+
+Resumes a paused output, which receives messages from that point onwards.`).
 		Fields(
 			service.NewOutputMapField(doFieldOutputs).
 				Description("A map of outputs to statically create.").
@@ -172,6 +184,28 @@ func newDynamicOutputFromParsed(conf *service.ParsedConfig, res *service.Resourc
 		return err
 	})
 
+	dynAPI.OnPause(func(ctx context.Context, id string, paused bool) error {
+		return fanOut.SetPaused(id, paused)
+	})
+	dynAPI.OnStats(func(id string) (any, bool) {
+		return fanOut.Stats(id)
+	})
+
+	mgr.RegisterEndpoint(
+		path.Join(prefix, "/outputs/{id}/stats"),
+		`Returns the delivery stats of a specific output.`,
+		dynAPI.HandleStats,
+	)
+	mgr.RegisterEndpoint(
+		path.Join(prefix, "/outputs/{id}/pause"),
+		`Pauses the delivery of messages to a specific output.`,
+		dynAPI.HandlePause,
+	)
+	mgr.RegisterEndpoint(
+		path.Join(prefix, "/outputs/{id}/resume"),
+		`Resumes the delivery of messages to a specific output.`,
+		dynAPI.HandleResume,
+	)
 	mgr.RegisterEndpoint(
 		path.Join(prefix, "/outputs/{id}/uptime"),
 		`Returns the uptime of a specific output as a duration string.`,
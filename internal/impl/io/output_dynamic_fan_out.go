@@ -31,6 +31,52 @@ type outputWithTSChan struct {
 	output output.Streamed
 	ctx    context.Context
 	done   func()
+	stats  *dynamicOutputStats
+}
+
+// dynamicOutputStats tracks the delivery of messages to a dynamic output.
+type dynamicOutputStats struct {
+	sent   atomic.Int64
+	failed atomic.Int64
+
+	mut         sync.Mutex
+	lastSentAt  time.Time
+	lastError   string
+	lastErrorAt time.Time
+}
+
+func (s *dynamicOutputStats) record(batchSize int, err error) {
+	now := time.Now()
+	if err != nil {
+		s.failed.Add(int64(batchSize))
+	} else {
+		s.sent.Add(int64(batchSize))
+	}
+
+	s.mut.Lock()
+	if err != nil {
+		s.lastError, s.lastErrorAt = err.Error(), now
+	} else {
+		s.lastSentAt = now
+	}
+	s.mut.Unlock()
+}
+
+// DynamicOutputStats is a snapshot of the delivery stats of a dynamic output.
+type DynamicOutputStats struct {
+	Paused      bool   `json:"paused"`
+	Sent        int64  `json:"sent"`
+	Failed      int64  `json:"failed"`
+	LastSentAt  string `json:"last_sent_at,omitempty"`
+	LastError   string `json:"last_error,omitempty"`
+	LastErrorAt string `json:"last_error_at,omitempty"`
+}
+
+func formatStatsTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339Nano)
 }
 
 type dynamicFanOutOutputBroker struct {
@@ -45,6 +91,13 @@ type dynamicFanOutOutputBroker struct {
 	newOutputChan chan wrappedOutput
 	outputs       map[string]outputWithTSChan
 
+	// Pause states and stats are tracked separately from outputs so that they
+	// can be accessed without waiting on the outputs lock, which is held for
+	// the duration of each fan out.
+	stateMut sync.Mutex
+	paused   map[string]struct{}
+	stats    map[string]*dynamicOutputStats
+
 	shutSig *shutdown.Signaller
 }
 
@@ -59,6 +112,8 @@ func newDynamicFanOutOutputBroker(
 		transactions:  nil,
 		newOutputChan: make(chan wrappedOutput),
 		outputs:       make(map[string]outputWithTSChan, len(outputs)),
+		paused:        map[string]struct{}{},
+		stats:         map[string]*dynamicOutputStats{},
 		shutSig:       shutdown.NewSignaller(),
 		onAdd:         onAdd,
 		onRemove:      onRemove,
@@ -106,6 +161,54 @@ func (d *dynamicFanOutOutputBroker) SetOutput(ctx context.Context, ident string,
 	return component.ErrTimeout
 }
 
+// SetPaused pauses or resumes an output. Messages are not delivered to paused
+// outputs, and when all outputs are paused the broker waits until an output
+// is resumed.
+func (d *dynamicFanOutOutputBroker) SetPaused(ident string, paused bool) error {
+	d.stateMut.Lock()
+	defer d.stateMut.Unlock()
+
+	if _, exists := d.stats[ident]; !exists {
+		return fmt.Errorf("output '%v' does not exist", ident)
+	}
+	if paused {
+		d.paused[ident] = struct{}{}
+	} else {
+		delete(d.paused, ident)
+	}
+	return nil
+}
+
+// Stats returns a snapshot of the delivery stats of an output, and false if
+// the output does not exist.
+func (d *dynamicFanOutOutputBroker) Stats(ident string) (DynamicOutputStats, bool) {
+	d.stateMut.Lock()
+	s, exists := d.stats[ident]
+	_, paused := d.paused[ident]
+	d.stateMut.Unlock()
+	if !exists {
+		return DynamicOutputStats{}, false
+	}
+
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	return DynamicOutputStats{
+		Paused:      paused,
+		Sent:        s.sent.Load(),
+		Failed:      s.failed.Load(),
+		LastSentAt:  formatStatsTime(s.lastSentAt),
+		LastError:   s.lastError,
+		LastErrorAt: formatStatsTime(s.lastErrorAt),
+	}, true
+}
+
+func (d *dynamicFanOutOutputBroker) isPaused(ident string) bool {
+	d.stateMut.Lock()
+	_, paused := d.paused[ident]
+	d.stateMut.Unlock()
+	return paused
+}
+
 func (d *dynamicFanOutOutputBroker) Consume(transactions <-chan message.Transaction) error {
 	if d.transactions != nil {
 		return component.ErrAlreadyStarted
@@ -131,8 +234,13 @@ func (d *dynamicFanOutOutputBroker) addOutput(ident string, output output.Stream
 		return err
 	}
 	ow.ctx, ow.done = context.WithCancel(context.Background())
+	ow.stats = &dynamicOutputStats{}
 
 	d.outputs[ident] = ow
+
+	d.stateMut.Lock()
+	d.stats[ident] = ow.stats
+	d.stateMut.Unlock()
 	return nil
 }
 
@@ -149,6 +257,10 @@ func (d *dynamicFanOutOutputBroker) removeOutput(ctx context.Context, ident stri
 	close(ow.tsChan)
 	delete(d.outputs, ident)
 
+	d.stateMut.Lock()
+	delete(d.stats, ident)
+	d.stateMut.Unlock()
+
 	return err
 }
 
@@ -212,6 +324,9 @@ func (d *dynamicFanOutOutputBroker) loop() {
 
 					// Next, attempt to create a new output (if specified).
 					if wrappedOutput.Output == nil {
+						d.stateMut.Lock()
+						delete(d.paused, wrappedOutput.Name)
+						d.stateMut.Unlock()
 						wrappedOutput.ResChan <- nil
 					} else {
 						err := d.addOutput(wrappedOutput.Name, wrappedOutput.Output)
@@ -242,7 +357,8 @@ func (d *dynamicFanOutOutputBroker) loop() {
 		}
 
 		d.outputsMut.RLock()
-		for len(d.outputs) == 0 {
+		active := d.activeOutputs()
+		for len(active) == 0 {
 			// Assuming this isn't a common enough occurrence that it
 			// won't be busy enough to require a sync.Cond, looping with
 			// a sleep is fine for now.
@@ -253,15 +369,18 @@ func (d *dynamicFanOutOutputBroker) loop() {
 				return
 			}
 			d.outputsMut.RLock()
+			active = d.activeOutputs()
 		}
 
 		_ = atomic.AddInt64(&ackPending, 1)
-		pendingResponses := int64(len(d.outputs))
+		pendingResponses := int64(len(active))
 
 	outputsLoop:
-		for _, output := range d.outputs {
+		for _, output := range active {
+			stats := output.stats
 			select {
 			case output.tsChan <- message.NewTransactionFunc(ts.Payload.ShallowCopy(), func(ctx context.Context, err error) error {
+				stats.record(len(ts.Payload), err)
 				if atomic.AddInt64(&pendingResponses, -1) == 0 || err != nil {
 					atomic.StoreInt64(&pendingResponses, 0)
 					ackErr := ts.Ack(ctx, err)
@@ -282,6 +401,18 @@ func (d *dynamicFanOutOutputBroker) loop() {
 	}
 }
 
+// activeOutputs returns the outputs that are not paused, the outputs lock must
+// be held by the caller.
+func (d *dynamicFanOutOutputBroker) activeOutputs() []outputWithTSChan {
+	active := make([]outputWithTSChan, 0, len(d.outputs))
+	for k, ow := range d.outputs {
+		if !d.isPaused(k) {
+			active = append(active, ow)
+		}
+	}
+	return active
+}
+
 func (d *dynamicFanOutOutputBroker) Connected() bool {
 	d.outputsMut.RLock()
 	defer d.outputsMut.RUnlock()
@@ -435,3 +435,93 @@ func TestDynamicFanOutShutDownFromSend(t *testing.T) {
 		t.Error("Timed out waiting for msg rcv")
 	}
 }
+
+func TestDynamicFanOutPauseAndStats(t *testing.T) {
+	tCtx, done := context.WithTimeout(context.Background(), time.Second*5)
+	defer done()
+
+	fooOutput, barOutput := &mock.OutputChanneled{}, &mock.OutputChanneled{}
+	outputs := map[string]output.Streamed{
+		"foo": fooOutput,
+		"bar": barOutput,
+	}
+	readChan := make(chan message.Transaction)
+	resChan := make(chan error, 1)
+
+	oTM, err := newDynamicFanOutOutputBroker(outputs, log.Noop(), nil, nil)
+	require.NoError(t, err)
+	require.NoError(t, oTM.Consume(readChan))
+
+	require.Error(t, oTM.SetPaused("baz", true))
+	require.NoError(t, oTM.SetPaused("bar", true))
+
+	sendAndAck := func(content string, ackErrs map[*mock.OutputChanneled]error) error {
+		t.Helper()
+		select {
+		case readChan <- message.NewTransaction(message.QuickBatch([][]byte{[]byte(content)}), resChan):
+		case <-time.After(time.Second):
+			t.Fatal("Timed out waiting for broker send")
+		}
+		wg := sync.WaitGroup{}
+		for out, ackErr := range ackErrs {
+			wg.Add(1)
+			go func(out *mock.OutputChanneled, ackErr error) {
+				defer wg.Done()
+				select {
+				case ts := <-out.TChan:
+					assert.Equal(t, content, string(ts.Payload.Get(0).AsBytes()))
+					assert.NoError(t, ts.Ack(tCtx, ackErr))
+				case <-time.After(time.Second):
+					t.Error("Timed out waiting for broker propagate")
+				}
+			}(out, ackErr)
+		}
+		wg.Wait()
+		select {
+		case res := <-resChan:
+			return res
+		case <-time.After(time.Second):
+			t.Fatal("Timed out responding to broker")
+		}
+		return nil
+	}
+
+	require.NoError(t, sendAndAck("first", map[*mock.OutputChanneled]error{fooOutput: nil}))
+	select {
+	case <-barOutput.TChan:
+		t.Fatal("Paused output received a message")
+	default:
+	}
+
+	require.NoError(t, oTM.SetPaused("bar", false))
+	require.Error(t, sendAndAck("second", map[*mock.OutputChanneled]error{
+		fooOutput: nil,
+		barOutput: errors.New("nope"),
+	}))
+
+	fooStats, exists := oTM.Stats("foo")
+	require.True(t, exists)
+	assert.False(t, fooStats.Paused)
+	assert.Equal(t, int64(2), fooStats.Sent)
+	assert.Equal(t, int64(0), fooStats.Failed)
+	assert.NotEmpty(t, fooStats.LastSentAt)
+	assert.Empty(t, fooStats.LastError)
+
+	barStats, exists := oTM.Stats("bar")
+	require.True(t, exists)
+	assert.Equal(t, int64(0), barStats.Sent)
+	assert.Equal(t, int64(1), barStats.Failed)
+	assert.Equal(t, "nope", barStats.LastError)
+	assert.NotEmpty(t, barStats.LastErrorAt)
+
+	require.NoError(t, oTM.SetPaused("foo", true))
+	fooStats, _ = oTM.Stats("foo")
+	assert.True(t, fooStats.Paused)
+
+	require.NoError(t, oTM.SetOutput(tCtx, "foo", nil))
+	_, exists = oTM.Stats("foo")
+	assert.False(t, exists)
+
+	oTM.TriggerCloseNow()
+	require.NoError(t, oTM.WaitForClose(tCtx))
+}
@@ -15,7 +15,7 @@ func httpProcSpec() *service.ConfigSpec {
 		Categories("Integration").
 		Summary("Performs an HTTP request using a message batch as the request body, and replaces the original message parts with the body of the response.").
 		Description(`
-The `+"`rate_limit`"+` field can be used to specify a rate limit [resource](/docs/components/rate_limits/about) to cap the rate of requests across all parallel components service wide.
+The `+"`rate_limit`"+` field can be used to specify a rate limit [resource](/docs/components/rate_limits/about) to cap the rate of requests across all parallel components service wide. Requests wait for access to the rate limit, and by setting `+"`rate_limit_max_wait`"+` requests that cannot be granted access in time are abandoned and their messages flagged as having failed instead of blocking the pipeline.
 
 The URL and header values of this type can be dynamically set using function interpolations described [here](/docs/configuration/interpolation#bloblang-queries).
 
@@ -12,18 +12,23 @@ import (
 	"strings"
 	"sync"
 	"syscall"
+	"time"
 
 	"github.com/dop251/goja"
 	"github.com/dop251/goja_nodejs/console"
 	"github.com/dop251/goja_nodejs/require"
+	"github.com/evanw/esbuild/pkg/api"
 
 	"github.com/benthosdev/benthos/v4/public/service"
 )
 
 const (
-	codeField    = "code"
-	fileField    = "file"
-	includeField = "global_folders"
+	codeField             = "code"
+	fileField             = "file"
+	includeField          = "global_folders"
+	librariesField        = "libraries"
+	esModulesField        = "es_modules"
+	eventLoopTimeoutField = "event_loop_timeout"
 )
 
 func javascriptProcessorConfig() *service.ConfigSpec {
@@ -56,6 +61,16 @@ In order to optimise code execution JS runtimes are created on demand (in order
 
 Although technically possible, it is recommended that you do not rely on the global state for maintaining state across invocations as the pooling nature of the runtimes will prevent deterministic behaviour. We aim to support deterministic strategies for mutating global state in the future.
 
+## Modules
+
+Modules can be imported with `+"`require`"+` from paths relative to the program, or from the directories listed in the field `+"`global_folders`"+`. When the field `+"`es_modules`"+` is enabled the program and the modules it imports may instead use ES module `+"`import`"+` and `+"`export`"+` statements, which are transpiled into their CommonJS equivalents with [esbuild](https://esbuild.github.io/) when they are loaded. The program itself cannot export values, and because it is executed for each message any top level `+"`import`"+` statements are evaluated only once per runtime, as modules are cached.
+
+## Async
+
+Programs can use `+"`async`"+` functions, promises and the timer functions `+"`setTimeout`"+`, `+"`clearTimeout`"+`, `+"`setInterval`"+` and `+"`clearInterval`"+`. Once a program has been executed for a message any pending timers are run until none remain, and if the program evaluates to a promise, such as the result of calling an async function, the processor waits for that promise to settle and the message fails if it is rejected. Pending timers are abandoned once the promise has settled.
+
+The event loop of each message is bounded by the field `+"`event_loop_timeout`"+`, and a message fails if its timers and promises have not completed within that duration.
+
 ## Functions
 `+description.String()+`
 `).
@@ -68,6 +83,20 @@ Although technically possible, it is recommended that you do not rely on the glo
 		Field(service.NewStringListField(includeField).
 			Description("List of folders that will be used to load modules from if the requested JS module is not found elsewhere.").
 			Default([]string{})).
+		Field(service.NewStringListField(librariesField).
+			Description("A list of JavaScript files that are executed when each runtime is created, before any messages are processed. Libraries can define global functions and values that are then available to the program.").
+			Example([]string{"./lib/helpers.js"}).
+			Version("4.28.0").
+			Default([]string{})).
+		Field(service.NewBoolField(esModulesField).
+			Description("Whether the program, libraries and imported modules are ES modules, allowing the use of `import` and `export` statements.").
+			Version("4.28.0").
+			Default(false)).
+		Field(service.NewDurationField(eventLoopTimeoutField).
+			Description("The maximum period to wait for the timers and promise of a program to complete after it has been executed for a message.").
+			Version("4.28.0").
+			Advanced().
+			Default("10s")).
 		LintRule(fmt.Sprintf(`
 let codeLen = (this.%v | "").length()
 let fileLen = (this.%v | "").length()
@@ -84,6 +113,23 @@ pipeline:
   processors:
     - javascript:
         code: 'benthos.v0_msg_set_string(benthos.v0_msg_as_string() + "hello world");'
+`,
+		).
+		Example(
+			`Async functions`,
+			`In this example we use an async function to wait for a timer and a module imported with ES module syntax. The processor waits for the promise returned by the async function to settle before moving on to the next message.`,
+			`
+pipeline:
+  processors:
+    - javascript:
+        es_modules: true
+        global_folders: [ ./js ]
+        code: |
+          import { enrich } from "enrich.js";
+          (async () => {
+            await new Promise((resolve) => setTimeout(resolve, 10));
+            benthos.v0_msg_set_structured(await enrich(benthos.v0_msg_as_structured()));
+          })();
 `,
 		).
 		Example(
@@ -118,13 +164,38 @@ func init() {
 //------------------------------------------------------------------------------
 
 type javascriptProcessor struct {
-	program         *goja.Program
-	requireRegistry *require.Registry
-	logger          *service.Logger
-	vmPool          sync.Pool
+	program          *goja.Program
+	libraries        []*goja.Program
+	requireRegistry  *require.Registry
+	eventLoopTimeout time.Duration
+	logger           *service.Logger
+	vmPool           sync.Pool
 }
 
-func sourceLoader(serviceFS *service.FS) require.SourceLoader {
+// transpileModule converts an ES module into a CommonJS module that can be
+// executed by the runtime.
+func transpileModule(filename, code string) (string, error) {
+	res := api.Transform(code, api.TransformOptions{
+		Sourcefile: filename,
+		Loader:     api.LoaderJS,
+		Format:     api.FormatCommonJS,
+		Target:     api.ESNext,
+	})
+	if len(res.Errors) > 0 {
+		errs := make([]string, 0, len(res.Errors))
+		for _, e := range res.Errors {
+			if e.Location != nil {
+				errs = append(errs, fmt.Sprintf("%v:%v:%v: %v", e.Location.File, e.Location.Line, e.Location.Column, e.Text))
+			} else {
+				errs = append(errs, e.Text)
+			}
+		}
+		return "", fmt.Errorf("failed to transpile module: %v", strings.Join(errs, ", "))
+	}
+	return string(res.Code), nil
+}
+
+func sourceLoader(serviceFS *service.FS, esModules bool) require.SourceLoader {
 	// Copy of `require.DefaultSourceLoader`: https://github.com/dop251/goja_nodejs/blob/e84d9a924c5ca9e541575e643b7efbca5705862f/require/module.go#L116-L141
 	// with some slight adjustments because we need to use the Benthos manager filesystem for opening and reading files.
 	return func(filename string) ([]byte, error) {
@@ -152,7 +223,15 @@ func sourceLoader(serviceFS *service.FS) require.SourceLoader {
 			return nil, err
 		}
 
-		return io.ReadAll(f)
+		b, err := io.ReadAll(f)
+		if err != nil || !esModules {
+			return b, err
+		}
+		code, err := transpileModule(filename, string(b))
+		if err != nil {
+			return nil, err
+		}
+		return []byte(code), nil
 	}
 }
 
@@ -174,11 +253,47 @@ func newJavascriptProcessorFromConfig(conf *service.ParsedConfig, mgr *service.R
 		code = string(codeBytes)
 	}
 
-	program, err := goja.Compile(filename, code, false)
+	esModules, err := conf.FieldBool(esModulesField)
+	if err != nil {
+		return nil, err
+	}
+	compile := func(filename, code string) (*goja.Program, error) {
+		if esModules {
+			var err error
+			if code, err = transpileModule(filename, code); err != nil {
+				return nil, err
+			}
+		}
+		return goja.Compile(filename, code, false)
+	}
+
+	program, err := compile(filename, code)
 	if err != nil {
 		return nil, fmt.Errorf("failed to compile javascript code: %v", err)
 	}
 
+	libraryPaths, err := conf.FieldStringList(librariesField)
+	if err != nil {
+		return nil, err
+	}
+	var libraries []*goja.Program
+	for _, path := range libraryPaths {
+		libBytes, err := service.ReadFile(mgr.FS(), path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open library %v: %w", path, err)
+		}
+		lib, err := compile(path, string(libBytes))
+		if err != nil {
+			return nil, fmt.Errorf("failed to compile library %v: %v", path, err)
+		}
+		libraries = append(libraries, lib)
+	}
+
+	eventLoopTimeout, err := conf.FieldDuration(eventLoopTimeoutField)
+	if err != nil {
+		return nil, err
+	}
+
 	logger := mgr.Logger()
 	registryGlobalFolders, err := conf.FieldStringList(includeField)
 	if err != nil {
@@ -186,15 +301,17 @@ func newJavascriptProcessorFromConfig(conf *service.ParsedConfig, mgr *service.R
 	}
 	requireRegistry := require.NewRegistry(
 		require.WithGlobalFolders(registryGlobalFolders...),
-		require.WithLoader(sourceLoader(mgr.FS())),
+		require.WithLoader(sourceLoader(mgr.FS(), esModules)),
 	)
 	requireRegistry.RegisterNativeModule("console", console.RequireWithPrinter(&Logger{logger}))
 
 	return &javascriptProcessor{
-		program:         program,
-		requireRegistry: requireRegistry,
-		logger:          logger,
-		vmPool:          sync.Pool{},
+		program:          program,
+		libraries:        libraries,
+		requireRegistry:  requireRegistry,
+		eventLoopTimeout: eventLoopTimeout,
+		logger:           logger,
+		vmPool:           sync.Pool{},
 	}, nil
 }
 
@@ -379,3 +379,133 @@ code: |
 
 	require.NoError(t, proc.Close(bCtx))
 }
+
+func TestProcessorAsync(t *testing.T) {
+	conf, err := javascriptProcessorConfig().ParseYAML(`
+code: |
+  (async () => {
+    let parts = [benthos.v0_msg_as_string()];
+    let id = setInterval(() => parts.push("tick"), 1);
+    await new Promise((resolve) => setTimeout(resolve, 10));
+    clearInterval(id);
+    parts.push(await Promise.resolve("done"));
+    benthos.v0_msg_set_string(parts.filter((p) => p !== "tick").join(" "));
+  })();
+`, nil)
+	require.NoError(t, err)
+
+	proc, err := newJavascriptProcessorFromConfig(conf, service.MockResources())
+	require.NoError(t, err)
+
+	bCtx, done := context.WithTimeout(context.Background(), time.Second*30)
+	defer done()
+
+	resBatches, err := proc.ProcessBatch(bCtx, service.MessageBatch{
+		service.NewMessage([]byte("first")),
+		service.NewMessage([]byte("second")),
+	})
+	require.NoError(t, err)
+	require.Len(t, resBatches, 1)
+	require.Len(t, resBatches[0], 2)
+
+	resBytes, err := resBatches[0][0].AsBytes()
+	require.NoError(t, err)
+	assert.Equal(t, "first done", string(resBytes))
+
+	resBytes, err = resBatches[0][1].AsBytes()
+	require.NoError(t, err)
+	assert.Equal(t, "second done", string(resBytes))
+
+	require.NoError(t, proc.Close(bCtx))
+}
+
+func TestProcessorAsyncErrors(t *testing.T) {
+	for _, test := range []struct {
+		name string
+		code string
+		err  string
+	}{
+		{
+			name: "rejected",
+			code: `(async () => { throw new Error("nope"); })();`,
+			err:  "nope",
+		},
+		{
+			name: "never settles",
+			code: `new Promise(() => {});`,
+			err:  "did not settle",
+		},
+		{
+			name: "timeout",
+			code: `setInterval(() => {}, 1);`,
+			err:  "exceeded the timeout",
+		},
+	} {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			conf, err := javascriptProcessorConfig().ParseYAML(fmt.Sprintf(`
+event_loop_timeout: 50ms
+code: '%v'
+`, test.code), nil)
+			require.NoError(t, err)
+
+			proc, err := newJavascriptProcessorFromConfig(conf, service.MockResources())
+			require.NoError(t, err)
+
+			_, err = proc.ProcessBatch(context.Background(), service.MessageBatch{
+				service.NewMessage([]byte("first")),
+			})
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), test.err)
+		})
+	}
+}
+
+func TestProcessorLibrariesAndESModules(t *testing.T) {
+	tmpDir := t.TempDir()
+	require.NoError(t, os.WriteFile(path.Join(tmpDir, "helpers.js"), []byte(`
+function shout(s) {
+  return s.toUpperCase();
+}
+`), 0o644))
+	require.NoError(t, os.WriteFile(path.Join(tmpDir, "suffix.js"), []byte(`
+export const suffix = "!";
+export default function addSuffix(s) {
+  return s + suffix;
+}
+`), 0o644))
+
+	conf, err := javascriptProcessorConfig().ParseYAML(fmt.Sprintf(`
+es_modules: true
+global_folders: [ "%v" ]
+libraries: [ "%v" ]
+code: |
+  import addSuffix, { suffix } from "suffix";
+  benthos.v0_msg_set_string(addSuffix(shout(benthos.v0_msg_as_string())) + suffix);
+`, tmpDir, path.Join(tmpDir, "helpers.js")), nil)
+	require.NoError(t, err)
+
+	proc, err := newJavascriptProcessorFromConfig(conf, service.MockResources())
+	require.NoError(t, err)
+
+	bCtx, done := context.WithTimeout(context.Background(), time.Second*30)
+	defer done()
+
+	resBatches, err := proc.ProcessBatch(bCtx, service.MessageBatch{
+		service.NewMessage([]byte("first")),
+		service.NewMessage([]byte("second")),
+	})
+	require.NoError(t, err)
+	require.Len(t, resBatches, 1)
+	require.Len(t, resBatches[0], 2)
+
+	resBytes, err := resBatches[0][0].AsBytes()
+	require.NoError(t, err)
+	assert.Equal(t, "FIRST!!", string(resBytes))
+
+	resBytes, err = resBatches[0][1].AsBytes()
+	require.NoError(t, err)
+	assert.Equal(t, "SECOND!!", string(resBytes))
+
+	require.NoError(t, proc.Close(bCtx))
+}
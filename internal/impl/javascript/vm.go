@@ -2,7 +2,9 @@ package javascript
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/dop251/goja"
 	"github.com/dop251/goja_nodejs/console"
@@ -14,11 +16,23 @@ type vmRunner struct {
 	vm *goja.Runtime
 	p  *goja.Program
 
-	logger *service.Logger
+	logger           *service.Logger
+	eventLoopTimeout time.Duration
 
 	runBatch      service.MessageBatch
 	targetMessage *service.Message
 	targetIndex   int
+
+	timers      map[int64]*vmTimer
+	nextTimerID int64
+}
+
+type vmTimer struct {
+	at       time.Time
+	interval time.Duration
+	repeat   bool
+	fn       goja.Callable
+	args     []goja.Value
 }
 
 func (j *javascriptProcessor) newVM() (*vmRunner, error) {
@@ -28,9 +42,11 @@ func (j *javascriptProcessor) newVM() (*vmRunner, error) {
 	console.Enable(vm)
 
 	vr := &vmRunner{
-		vm:     vm,
-		logger: j.logger,
-		p:      j.program,
+		vm:               vm,
+		logger:           j.logger,
+		eventLoopTimeout: j.eventLoopTimeout,
+		p:                j.program,
+		timers:           map[int64]*vmTimer{},
 	}
 
 	for name, fc := range vmRunnerFunctionCtors {
@@ -38,9 +54,64 @@ func (j *javascriptProcessor) newVM() (*vmRunner, error) {
 			return nil, err
 		}
 	}
+	if err := vr.setTimerFunctions(); err != nil {
+		return nil, err
+	}
+
+	for _, lib := range j.libraries {
+		if _, err := vm.RunProgram(lib); err != nil {
+			return nil, fmt.Errorf("failed to execute library: %w", err)
+		}
+	}
+	// Libraries are not able to schedule work for messages.
+	vr.reset()
 	return vr, nil
 }
 
+func (r *vmRunner) setTimerFunctions() error {
+	addTimer := func(repeat bool) func(call goja.FunctionCall) goja.Value {
+		return func(call goja.FunctionCall) goja.Value {
+			fn, ok := goja.AssertFunction(call.Argument(0))
+			if !ok {
+				panic(r.vm.NewTypeError("the first argument must be a function"))
+			}
+			interval := time.Duration(call.Argument(1).ToInteger()) * time.Millisecond
+			if interval < 0 {
+				interval = 0
+			}
+			var args []goja.Value
+			if len(call.Arguments) > 2 {
+				args = call.Arguments[2:]
+			}
+			r.nextTimerID++
+			r.timers[r.nextTimerID] = &vmTimer{
+				at:       time.Now().Add(interval),
+				interval: interval,
+				repeat:   repeat,
+				fn:       fn,
+				args:     args,
+			}
+			return r.vm.ToValue(r.nextTimerID)
+		}
+	}
+	clearTimer := func(call goja.FunctionCall) goja.Value {
+		delete(r.timers, call.Argument(0).ToInteger())
+		return goja.Undefined()
+	}
+
+	for name, fn := range map[string]func(call goja.FunctionCall) goja.Value{
+		"setTimeout":    addTimer(false),
+		"setInterval":   addTimer(true),
+		"clearTimeout":  clearTimer,
+		"clearInterval": clearTimer,
+	} {
+		if err := r.vm.Set(name, fn); err != nil {
+			return fmt.Errorf("failed to set global function %v: %w", name, err)
+		}
+	}
+	return nil
+}
+
 // The namespace within all our function definitions
 const fnCtxName = "benthos"
 
@@ -118,6 +189,63 @@ func (r *vmRunner) reset() {
 	r.runBatch = nil
 	r.targetMessage = nil
 	r.targetIndex = 0
+	for id := range r.timers {
+		delete(r.timers, id)
+	}
+}
+
+// runEventLoop runs the timers scheduled by a program until none remain, or
+// until the promise that the program evaluated to has settled.
+func (r *vmRunner) runEventLoop(ctx context.Context, result goja.Value) error {
+	var promise *goja.Promise
+	if result != nil {
+		promise, _ = result.Export().(*goja.Promise)
+	}
+
+	deadline := time.Now().Add(r.eventLoopTimeout)
+	for len(r.timers) > 0 {
+		if promise != nil && promise.State() != goja.PromiseStatePending {
+			break
+		}
+
+		var nextID int64
+		var next *vmTimer
+		for id, t := range r.timers {
+			if next == nil || t.at.Before(next.at) || (t.at.Equal(next.at) && id < nextID) {
+				nextID, next = id, t
+			}
+		}
+		if next.at.After(deadline) || time.Now().After(deadline) {
+			return fmt.Errorf("event loop exceeded the timeout of %v", r.eventLoopTimeout)
+		}
+		if wait := time.Until(next.at); wait > 0 {
+			select {
+			case <-time.After(wait):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+
+		if next.repeat {
+			next.at = next.at.Add(next.interval)
+		} else {
+			delete(r.timers, nextID)
+		}
+		if _, err := next.fn(goja.Undefined(), next.args...); err != nil {
+			return err
+		}
+	}
+
+	if promise == nil {
+		return nil
+	}
+	switch promise.State() {
+	case goja.PromiseStatePending:
+		return errors.New("program evaluated to a promise that did not settle")
+	case goja.PromiseStateRejected:
+		return fmt.Errorf("program evaluated to a promise that was rejected: %v", promise.Result())
+	}
+	return nil
 }
 
 func (r *vmRunner) Run(ctx context.Context, batch service.MessageBatch) (service.MessageBatch, error) {
@@ -130,11 +258,14 @@ func (r *vmRunner) Run(ctx context.Context, batch service.MessageBatch) (service
 		r.targetIndex = i
 		r.targetMessage = batch[i]
 
-		_, err := r.vm.RunProgram(r.p)
+		res, err := r.vm.RunProgram(r.p)
 		if err != nil {
 			// TODO: Make this more granular, error could be message specific
 			return nil, err
 		}
+		if err := r.runEventLoop(ctx, res); err != nil {
+			return nil, err
+		}
 		if newMsg := r.targetMessage; newMsg != nil {
 			newBatch = append(newBatch, newMsg)
 		}
@@ -0,0 +1,226 @@
+package jsonata
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/blues/jsonata-go"
+	lru "github.com/hashicorp/golang-lru/v2"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	jpFieldExpression  = "expression"
+	jpFieldFile        = "file"
+	jpFieldOnUndefined = "on_undefined"
+	jpFieldCacheSize   = "cache_size"
+)
+
+func jsonataProcSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Version("4.28.0").
+		Categories("Mapping").
+		Summary("Transforms messages with a [JSONata](https://jsonata.org) expression, replacing each message with the result.").
+		Description(`
+Expressions are evaluated with messages parsed as JSON as their input, and the metadata of each message is bound to the variable `+"`$metadata`"+` as an object of strings, such as `+"`$metadata.kafka_topic`"+`. This processor allows existing JSONata transforms to be reused without rewriting them, although [Bloblang](/docs/guides/bloblang/about) should be preferred for new mappings.
+
+The expression is either written inline with `+"`expression`"+` or loaded from a `+"`file`"+`. Inline expressions support [interpolation functions](/docs/configuration/interpolation#bloblang-queries), which allows the expression applied to each message to be selected dynamically, such as from metadata. Compiled expressions are cached, and so an expression is only compiled once for as long as it remains within the `+"`cache_size`"+` most recently used expressions.
+
+Messages that cannot be parsed as JSON, or where the expression fails, are flagged as having failed, allowing you to use [standard processor error handling patterns](/docs/configuration/error_handling).`).
+		Fields(
+			service.NewInterpolatedStringField(jpFieldExpression).
+				Description("An inline JSONata expression.").
+				Example(`Account.Order.Product.(Price * Quantity) ~> $sum()`).
+				Example(`${! meta("transform") }`).
+				Optional(),
+			service.NewStringField(jpFieldFile).
+				Description("The path of a file containing a JSONata expression.").
+				Example("./transforms/order.jsonata").
+				Optional(),
+			service.NewStringAnnotatedEnumField(jpFieldOnUndefined, map[string]string{
+				"error": "The message is left unchanged and flagged as having failed.",
+				"drop":  "The message is dropped.",
+				"null":  "The message is replaced with `null`.",
+			}).
+				Description("How to handle messages where the expression evaluates to undefined, which occurs when it matches nothing within the message.").
+				Default("error"),
+			service.NewIntField(jpFieldCacheSize).
+				Description("The maximum number of compiled expressions to cache when `expression` contains interpolation functions.").
+				Advanced().
+				Default(100),
+		).
+		Example("Order Totals", "Here we replace orders with the total cost of their products, and the topic that they were consumed from.", `
+pipeline:
+  processors:
+    - jsonata:
+        expression: |
+          {
+            "order": Account.Order.OrderID,
+            "total": $sum(Account.Order.Product.(Price * Quantity)),
+            "source": $metadata.kafka_topic
+          }
+`)
+}
+
+func init() {
+	err := service.RegisterProcessor(
+		"jsonata", jsonataProcSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Processor, error) {
+			return newJSONataProcFromConfig(conf, mgr)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+// compiledExpr is a compiled expression that can be evaluated concurrently.
+// Variables are bound to compiled expressions rather than to evaluations, and
+// so copies of the expression are pooled for each evaluation to use.
+type compiledExpr struct {
+	src  string
+	pool sync.Pool
+}
+
+func compileExpr(src string) (*compiledExpr, error) {
+	e, err := jsonata.Compile(src)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile expression: %w", err)
+	}
+	c := &compiledExpr{src: src}
+	c.pool.Put(e)
+	return c, nil
+}
+
+func (c *compiledExpr) eval(data any, metadata map[string]any) (any, error) {
+	e, _ := c.pool.Get().(*jsonata.Expr)
+	if e == nil {
+		var err error
+		if e, err = jsonata.Compile(c.src); err != nil {
+			return nil, err
+		}
+	}
+	defer c.pool.Put(e)
+
+	if err := e.RegisterVars(map[string]any{"metadata": metadata}); err != nil {
+		return nil, err
+	}
+	return e.Eval(data)
+}
+
+type jsonataProc struct {
+	static      *compiledExpr
+	expression  *service.InterpolatedString
+	cache       *lru.Cache[string, *compiledExpr]
+	onUndefined string
+}
+
+func newJSONataProcFromConfig(conf *service.ParsedConfig, mgr *service.Resources) (*jsonataProc, error) {
+	j := &jsonataProc{}
+
+	var err error
+	if j.onUndefined, err = conf.FieldString(jpFieldOnUndefined); err != nil {
+		return nil, err
+	}
+
+	switch {
+	case conf.Contains(jpFieldExpression) && conf.Contains(jpFieldFile):
+		return nil, errors.New("cannot specify both expression and file")
+	case conf.Contains(jpFieldFile):
+		path, err := conf.FieldString(jpFieldFile)
+		if err != nil {
+			return nil, err
+		}
+		src, err := service.ReadFile(mgr.FS(), path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read expression file: %w", err)
+		}
+		if j.static, err = compileExpr(string(src)); err != nil {
+			return nil, err
+		}
+	case conf.Contains(jpFieldExpression):
+		if j.expression, err = conf.FieldInterpolatedString(jpFieldExpression); err != nil {
+			return nil, err
+		}
+		if src, isStatic := j.expression.Static(); isStatic {
+			if j.static, err = compileExpr(src); err != nil {
+				return nil, err
+			}
+			break
+		}
+		cacheSize, err := conf.FieldInt(jpFieldCacheSize)
+		if err != nil {
+			return nil, err
+		}
+		if j.cache, err = lru.New[string, *compiledExpr](cacheSize); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, errors.New("either expression or file must be specified")
+	}
+	return j, nil
+}
+
+// exprFor returns the compiled expression to apply to a message.
+func (j *jsonataProc) exprFor(msg *service.Message) (*compiledExpr, error) {
+	if j.static != nil {
+		return j.static, nil
+	}
+
+	src, err := j.expression.TryString(msg)
+	if err != nil {
+		return nil, fmt.Errorf("expression interpolation error: %w", err)
+	}
+	if c, exists := j.cache.Get(src); exists {
+		return c, nil
+	}
+	c, err := compileExpr(src)
+	if err != nil {
+		return nil, err
+	}
+	j.cache.Add(src, c)
+	return c, nil
+}
+
+func (j *jsonataProc) Process(ctx context.Context, msg *service.Message) (service.MessageBatch, error) {
+	expr, err := j.exprFor(msg)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := msg.AsStructured()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse message as JSON: %w", err)
+	}
+
+	metadata := map[string]any{}
+	_ = msg.MetaWalk(func(k, v string) error {
+		metadata[k] = v
+		return nil
+	})
+
+	res, err := expr.eval(data, metadata)
+	if errors.Is(err, jsonata.ErrUndefined) {
+		switch j.onUndefined {
+		case "drop":
+			return nil, nil
+		case "null":
+			msg.SetStructuredMut(nil)
+			return service.MessageBatch{msg}, nil
+		}
+		return nil, errors.New("expression evaluated to undefined")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to evaluate expression: %w", err)
+	}
+
+	msg.SetStructuredMut(res)
+	return service.MessageBatch{msg}, nil
+}
+
+func (j *jsonataProc) Close(ctx context.Context) error {
+	return nil
+}
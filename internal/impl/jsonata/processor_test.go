@@ -0,0 +1,145 @@
+package jsonata
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const testOrder = `{
+  "Account": {
+    "Order": [
+      {"OrderID": "a1", "Product": [{"Price": 2, "Quantity": 3}, {"Price": 10, "Quantity": 1}]},
+      {"OrderID": "a2", "Product": [{"Price": 5, "Quantity": 2}]}
+    ]
+  }
+}`
+
+func testJSONataProc(t *testing.T, confStr string) *jsonataProc {
+	t.Helper()
+
+	conf, err := jsonataProcSpec().ParseYAML(confStr, nil)
+	require.NoError(t, err)
+
+	proc, err := newJSONataProcFromConfig(conf, service.MockResources())
+	require.NoError(t, err)
+	return proc
+}
+
+func processJSON(t *testing.T, proc *jsonataProc, msg *service.Message) string {
+	t.Helper()
+
+	res, err := proc.Process(context.Background(), msg)
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+
+	b, err := res[0].AsBytes()
+	require.NoError(t, err)
+	return string(b)
+}
+
+func TestJSONataProcessor(t *testing.T) {
+	proc := testJSONataProc(t, `
+expression: |
+  {
+    "orders": Account.Order.OrderID,
+    "total": $sum(Account.Order.Product.(Price * Quantity)),
+    "source": $metadata.topic
+  }
+`)
+
+	msg := service.NewMessage([]byte(testOrder))
+	msg.MetaSetMut("topic", "orders")
+	assert.JSONEq(t, `{"orders":["a1","a2"],"total":26,"source":"orders"}`, processJSON(t, proc, msg))
+
+	// Metadata from previous messages is not visible to later ones.
+	assert.JSONEq(t, `{"orders":["a1","a2"],"total":26}`, processJSON(t, proc, service.NewMessage([]byte(testOrder))))
+
+	_, err := proc.Process(context.Background(), service.NewMessage([]byte(`not json`)))
+	assert.ErrorContains(t, err, "failed to parse message as JSON")
+}
+
+func TestJSONataProcessorFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "total.jsonata")
+	require.NoError(t, os.WriteFile(path, []byte(`$sum(Account.Order.Product.(Price * Quantity))`), 0o644))
+
+	proc := testJSONataProc(t, `file: `+path)
+	assert.Equal(t, `26`, processJSON(t, proc, service.NewMessage([]byte(testOrder))))
+}
+
+func TestJSONataProcessorDynamic(t *testing.T) {
+	proc := testJSONataProc(t, `
+expression: '${! meta("transform") }'
+cache_size: 1
+`)
+
+	for _, test := range [][2]string{
+		{`Account.Order[0].OrderID`, `"a1"`},
+		{`$count(Account.Order)`, `2`},
+		{`Account.Order[0].OrderID`, `"a1"`},
+	} {
+		msg := service.NewMessage([]byte(testOrder))
+		msg.MetaSetMut("transform", test[0])
+		assert.Equal(t, test[1], processJSON(t, proc, msg), test[0])
+	}
+	assert.Equal(t, 1, proc.cache.Len())
+
+	msg := service.NewMessage([]byte(testOrder))
+	msg.MetaSetMut("transform", `Account.(`)
+	_, err := proc.Process(context.Background(), msg)
+	assert.ErrorContains(t, err, "failed to compile expression")
+}
+
+func TestJSONataProcessorUndefined(t *testing.T) {
+	for _, test := range []struct {
+		mode    string
+		outputs int
+		errStr  string
+	}{
+		{mode: "error", errStr: "expression evaluated to undefined"},
+		{mode: "drop", outputs: 0},
+		{mode: "null", outputs: 1},
+	} {
+		proc := testJSONataProc(t, `
+expression: Account.Nope
+on_undefined: `+test.mode+`
+`)
+
+		res, err := proc.Process(context.Background(), service.NewMessage([]byte(testOrder)))
+		if test.errStr != "" {
+			assert.EqualError(t, err, test.errStr)
+			continue
+		}
+		require.NoError(t, err, test.mode)
+		require.Len(t, res, test.outputs, test.mode)
+		if test.outputs > 0 {
+			b, err := res[0].AsBytes()
+			require.NoError(t, err)
+			assert.Equal(t, `null`, string(b))
+		}
+	}
+}
+
+func TestJSONataProcessorConfigErrors(t *testing.T) {
+	for _, confStr := range []string{
+		`on_undefined: drop`,
+		`expression: 'Account.('`,
+		`
+expression: Account
+file: ./nope.jsonata
+`,
+		`file: ./nope.jsonata`,
+	} {
+		conf, err := jsonataProcSpec().ParseYAML(confStr, nil)
+		require.NoError(t, err)
+
+		_, err = newJSONataProcFromConfig(conf, service.MockResources())
+		assert.Error(t, err, confStr)
+	}
+}
@@ -41,8 +41,11 @@ This input adds the following metadata fields to each message:
 - kafka_offset
 - kafka_timestamp_unix
 - kafka_tombstone_message
+- kafka_consumer_group
 - All record headers
 ` + "```" + `
+
+The field ` + "`kafka_consumer_group`" + ` is only added when a consumer group is configured, and allows a transactional ` + "`kafka_franz`" + ` output to commit the offsets of consumed messages within its transactions.
 `).
 		Field(service.NewStringListField("seed_brokers").
 			Description("A list of broker addresses to connect to in order to establish connections. If an item of the list contains commas it will be expanded into multiple addresses.").
@@ -261,6 +264,9 @@ func (f *franzKafkaReader) recordToMessage(record *kgo.Record) *msgWithRecord {
 	msg.MetaSetMut("kafka_offset", int(record.Offset))
 	msg.MetaSetMut("kafka_timestamp_unix", record.Timestamp.Unix())
 	msg.MetaSetMut("kafka_tombstone_message", record.Value == nil)
+	if f.consumerGroup != "" {
+		msg.MetaSetMut("kafka_consumer_group", f.consumerGroup)
+	}
 	if f.multiHeader {
 		// in multi header mode we gather headers so we can encode them as lists
 		headers := map[string][]any{}
@@ -61,10 +61,11 @@ This input adds the following metadata fields to each message:
 - kafka_lag
 - kafka_timestamp_unix
 - kafka_tombstone_message
+- kafka_consumer_group
 - All existing message headers (version 0.11+)
 `+"```"+`
 
-The field `+"`kafka_lag`"+` is the calculated difference between the high water mark offset of the partition at the time of ingestion and the current message offset.
+The field `+"`kafka_lag`"+` is the calculated difference between the high water mark offset of the partition at the time of ingestion and the current message offset. The field `+"`kafka_consumer_group`"+` is only added when a consumer group is configured, and allows a transactional `+"`kafka`"+` output to commit the offsets of consumed messages within its transactions.
 
 You can access these metadata fields using [function interpolation](/docs/configuration/interpolation#bloblang-queries).
 
@@ -374,7 +375,7 @@ func (k *kafkaReader) syncCheckpointer(topic string, partition int32) func(conte
 	}
 }
 
-func dataToPart(highestOffset int64, data *sarama.ConsumerMessage, multiHeader bool) *service.Message {
+func dataToPart(highestOffset int64, data *sarama.ConsumerMessage, multiHeader bool, consumerGroup string) *service.Message {
 	part := service.NewMessage(data.Value)
 
 	if multiHeader {
@@ -407,6 +408,9 @@ func dataToPart(highestOffset int64, data *sarama.ConsumerMessage, multiHeader b
 	part.MetaSetMut("kafka_lag", lag)
 	part.MetaSetMut("kafka_timestamp_unix", data.Timestamp.Unix())
 	part.MetaSetMut("kafka_tombstone_message", data.Value == nil)
+	if consumerGroup != "" {
+		part.MetaSetMut("kafka_consumer_group", consumerGroup)
+	}
 
 	return part
 }
@@ -78,7 +78,7 @@ func (k *kafkaReader) ConsumeClaim(sess sarama.ConsumerGroupSession, claim saram
 			}
 
 			latestOffset = data.Offset
-			part := dataToPart(claim.HighWaterMarkOffset(), data, k.multiHeader)
+			part := dataToPart(claim.HighWaterMarkOffset(), data, k.multiHeader, k.consumerGroup)
 
 			if batchPolicy.Add(part) {
 				nextTimedBatchChan = nil
@@ -78,7 +78,7 @@ partMsgLoop:
 			k.mgr.Logger().Tracef("Received message from topic %v partition %v\n", topic, partition)
 
 			latestOffset = data.Offset
-			part := dataToPart(consumer.HighWaterMarkOffset(), data, k.multiHeader)
+			part := dataToPart(consumer.HighWaterMarkOffset(), data, k.multiHeader, k.consumerGroup)
 
 			if batchPolicy.Add(part) {
 				nextTimedBatchChan = nil
@@ -3,16 +3,21 @@ package kafka
 import (
 	"context"
 	"crypto/tls"
+	"errors"
 	"fmt"
 	"math"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/dustin/go-humanize"
+	"github.com/twmb/franz-go/pkg/kerr"
 	"github.com/twmb/franz-go/pkg/kgo"
+	"github.com/twmb/franz-go/pkg/kmsg"
 	"github.com/twmb/franz-go/pkg/sasl"
 
+	"github.com/benthosdev/benthos/v4/internal/impl/confluent"
 	"github.com/benthosdev/benthos/v4/public/service"
 )
 
@@ -26,7 +31,7 @@ func franzKafkaOutputConfig() *service.ConfigSpec {
 Writes a batch of messages to Kafka brokers and waits for acknowledgement before propagating it back to the input.
 
 This output often out-performs the traditional ` + "`kafka`" + ` output as well as providing more useful logs and error messages.
-`).
+` + transactionDocs("kafka_franz")).
 		Field(service.NewStringListField("seed_brokers").
 			Description("A list of broker addresses to connect to in order to establish connections. If an item of the list contains commas it will be expanded into multiple addresses.").
 			Example([]string{"localhost:9092"}).
@@ -83,6 +88,8 @@ This output often out-performs the traditional ` + "`kafka`" + ` output as well
 			Advanced()).
 		Field(service.NewTLSToggledField("tls")).
 		Field(saslField()).
+		Field(transactionField()).
+		Field(confluent.OutputEncodingField("schema_registry")).
 		LintRule(`
 root = if this.partitioner == "manual" {
   if this.partition.or("") == "" {
@@ -107,7 +114,14 @@ func init() {
 			if batchPolicy, err = conf.FieldBatchPolicy("batching"); err != nil {
 				return
 			}
-			output, err = newFranzKafkaWriterFromConfig(conf, mgr.Logger())
+			var w *franzKafkaWriter
+			if w, err = newFranzKafkaWriterFromConfig(conf, mgr.Logger()); err != nil {
+				return
+			}
+			if w.schemaEncoder, err = confluent.OutputEncoderFromParsed(conf.Namespace("schema_registry"), mgr); err != nil {
+				return
+			}
+			output = w
 			return
 		})
 	if err != nil {
@@ -133,8 +147,11 @@ type franzKafkaWriter struct {
 	timeout          time.Duration
 	produceMaxBytes  int32
 	compressionPrefs []kgo.CompressionCodec
+	txn              txnConfig
+	schemaEncoder    *confluent.OutputEncoder
 
 	client *kgo.Client
+	txnMut sync.Mutex
 
 	log *service.Logger
 }
@@ -261,6 +278,13 @@ func newFranzKafkaWriterFromConfig(conf *service.ParsedConfig, log *service.Logg
 		return nil, err
 	}
 
+	if f.txn, err = txnConfigFromParsed(conf); err != nil {
+		return nil, err
+	}
+	if f.txn.enabled && !f.idempotentWrite {
+		return nil, errors.New("idempotent_write must be enabled in order to use transactions")
+	}
+
 	return &f, nil
 }
 
@@ -293,6 +317,12 @@ func (f *franzKafkaWriter) Connect(ctx context.Context) error {
 	if len(f.compressionPrefs) > 0 {
 		clientOpts = append(clientOpts, kgo.ProducerBatchCompression(f.compressionPrefs...))
 	}
+	if f.txn.enabled {
+		clientOpts = append(clientOpts,
+			kgo.TransactionalID(f.txn.id),
+			kgo.TransactionTimeout(f.txn.timeout),
+		)
+	}
 
 	cl, err := kgo.NewClient(clientOpts...)
 	if err != nil {
@@ -316,7 +346,11 @@ func (f *franzKafkaWriter) WriteBatch(ctx context.Context, b service.MessageBatc
 		}
 
 		record := &kgo.Record{Topic: topic}
-		if record.Value, err = msg.AsBytes(); err != nil {
+		if f.schemaEncoder != nil {
+			if record.Value, err = f.schemaEncoder.Encode(msg, topic); err != nil {
+				return
+			}
+		} else if record.Value, err = msg.AsBytes(); err != nil {
 			return
 		}
 		if f.key != nil {
@@ -345,12 +379,118 @@ func (f *franzKafkaWriter) WriteBatch(ctx context.Context, b service.MessageBatc
 		records = append(records, record)
 	}
 
+	if f.txn.enabled {
+		return f.writeTransaction(ctx, b, records)
+	}
+
 	// TODO: This is very cool and allows us to easily return granular errors,
 	// so we should honor travis by doing it.
 	err = f.client.ProduceSync(ctx, records...).FirstErr()
 	return
 }
 
+// writeTransaction produces records, and optionally commits the consumer
+// offsets of the batch, within a single transaction. Transactions are written
+// sequentially as a producer can only have one open transaction at a time.
+func (f *franzKafkaWriter) writeTransaction(ctx context.Context, b service.MessageBatch, records []*kgo.Record) error {
+	var offsets map[string][]txnPartitionOffset
+	if f.txn.commitOffsets {
+		var err error
+		if offsets, err = txnOffsetsFromBatch(b, f.txn.consumerGroup); err != nil {
+			return err
+		}
+	}
+
+	f.txnMut.Lock()
+	defer f.txnMut.Unlock()
+
+	client := f.client
+	if client == nil {
+		return service.ErrNotConnected
+	}
+
+	if err := client.BeginTransaction(); err != nil {
+		f.disconnect()
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	err := client.ProduceSync(ctx, records...).FirstErr()
+	if err == nil {
+		for group, groupOffsets := range offsets {
+			if err = f.commitTransactionOffsets(ctx, client, group, groupOffsets); err != nil {
+				break
+			}
+		}
+	}
+	if err == nil {
+		if err = client.EndTransaction(ctx, kgo.TryCommit); err == nil {
+			return nil
+		}
+		err = fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	if abortErr := client.EndTransaction(ctx, kgo.TryAbort); abortErr != nil {
+		// The producer is unable to recover from a failed abort, therefore we
+		// reconnect in order to obtain a fresh producer epoch.
+		f.log.Errorf("Failed to abort transaction: %v", abortErr)
+		f.disconnect()
+	}
+	return err
+}
+
+func (f *franzKafkaWriter) commitTransactionOffsets(ctx context.Context, client *kgo.Client, group string, offsets []txnPartitionOffset) error {
+	producerID, producerEpoch, err := client.ProducerID(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to obtain producer id: %w", err)
+	}
+
+	addReq := kmsg.NewPtrAddOffsetsToTxnRequest()
+	addReq.TransactionalID = f.txn.id
+	addReq.ProducerID = producerID
+	addReq.ProducerEpoch = producerEpoch
+	addReq.Group = group
+
+	addRes, err := addReq.RequestWith(ctx, client)
+	if err == nil {
+		err = kerr.ErrorForCode(addRes.ErrorCode)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to add consumer group %v to transaction: %w", group, err)
+	}
+
+	commitReq := kmsg.NewPtrTxnOffsetCommitRequest()
+	commitReq.TransactionalID = f.txn.id
+	commitReq.Group = group
+	commitReq.ProducerID = producerID
+	commitReq.ProducerEpoch = producerEpoch
+	for _, o := range offsets {
+		if l := len(commitReq.Topics); l == 0 || commitReq.Topics[l-1].Topic != o.topic {
+			topicReq := kmsg.NewTxnOffsetCommitRequestTopic()
+			topicReq.Topic = o.topic
+			commitReq.Topics = append(commitReq.Topics, topicReq)
+		}
+		partReq := kmsg.NewTxnOffsetCommitRequestTopicPartition()
+		partReq.Partition = o.partition
+		partReq.Offset = o.offset
+
+		topicReq := &commitReq.Topics[len(commitReq.Topics)-1]
+		topicReq.Partitions = append(topicReq.Partitions, partReq)
+	}
+
+	commitRes, err := commitReq.RequestWith(ctx, client)
+	if err != nil {
+		return fmt.Errorf("failed to commit offsets of consumer group %v: %w", group, err)
+	}
+	for _, t := range commitRes.Topics {
+		for _, p := range t.Partitions {
+			if err := kerr.ErrorForCode(p.ErrorCode); err != nil {
+				return fmt.Errorf("failed to commit offset of consumer group %v for topic %v partition %v: %w", group, t.Topic, p.Partition, err)
+			}
+		}
+	}
+	return nil
+}
+
 func (f *franzKafkaWriter) disconnect() {
 	if f.client == nil {
 		return
@@ -361,5 +501,8 @@ func (f *franzKafkaWriter) disconnect() {
 
 func (f *franzKafkaWriter) Close(ctx context.Context) error {
 	f.disconnect()
+	if f.schemaEncoder != nil {
+		return f.schemaEncoder.Close(ctx)
+	}
 	return nil
 }
@@ -14,6 +14,7 @@ import (
 	"github.com/cenkalti/backoff/v4"
 	"golang.org/x/sync/syncmap"
 
+	"github.com/benthosdev/benthos/v4/internal/impl/confluent"
 	"github.com/benthosdev/benthos/v4/public/bloblang"
 	"github.com/benthosdev/benthos/v4/public/service"
 )
@@ -43,6 +44,7 @@ const (
 	oskFieldBatching                     = "batching"
 	oskFieldMaxRetries                   = "max_retries"
 	oskFieldBackoff                      = "backoff"
+	oskFieldSchemaRegistry               = "schema_registry"
 )
 
 // OSKConfigSpec creates a new config spec for a kafka output.
@@ -72,7 +74,8 @@ If you're seeing issues writing to or reading from Kafka with this component the
 
 - I'm seeing logs that report `+"`Failed to connect to kafka: kafka: client has run out of available brokers to talk to (Is your cluster reachable?)`"+`, but the brokers are definitely reachable.
 
-Unfortunately this error message will appear for a wide range of connection problems even when the broker endpoint can be reached. Double check your authentication configuration and also ensure that you have [enabled TLS](#tlsenabled) if applicable.`+service.OutputPerformanceDocs(true, true)).
+Unfortunately this error message will appear for a wide range of connection problems even when the broker endpoint can be reached. Double check your authentication configuration and also ensure that you have [enabled TLS](#tlsenabled) if applicable.
+`+transactionDocs("kafka")+service.OutputPerformanceDocs(true, true)).
 		Fields(
 			service.NewStringListField(oskFieldAddresses).
 				Description("A list of broker addresses to connect to. If an item of the list contains commas it will be expanded into multiple addresses.").
@@ -151,6 +154,8 @@ Unfortunately this error message will appear for a wide range of connection prob
 				MaxInterval:     time.Second * 10,
 				MaxElapsedTime:  time.Second * 30,
 			}).Description("Control time intervals between retry attempts.").Advanced(),
+			transactionField(),
+			confluent.OutputEncodingField(oskFieldSchemaRegistry),
 		)
 }
 
@@ -186,6 +191,8 @@ type kafkaWriter struct {
 	staticHeaders map[string]string
 	metaFilter    *service.MetadataExcludeFilter
 	retryAsBatch  bool
+	txn           txnConfig
+	schemaEncoder *confluent.OutputEncoder
 
 	customTopicCreation bool
 	customTopicParts    int
@@ -198,6 +205,7 @@ type kafkaWriter struct {
 	producer sarama.SyncProducer
 
 	connMut    sync.RWMutex
+	txnMut     sync.Mutex
 	topicCache syncmap.Map
 }
 
@@ -286,10 +294,18 @@ func NewKafkaWriterFromParsed(conf *service.ParsedConfig, mgr *service.Resources
 		}
 	}
 
+	if k.txn, err = txnConfigFromParsed(conf); err != nil {
+		return nil, err
+	}
+
 	if k.saramConf, err = k.saramaConfigFromParsed(conf); err != nil {
 		return nil, err
 	}
 
+	if k.schemaEncoder, err = confluent.OutputEncoderFromParsed(conf.Namespace(oskFieldSchemaRegistry), mgr); err != nil {
+		return nil, err
+	}
+
 	if k.admin, err = sarama.NewClusterAdmin(k.addresses, k.saramConf); err != nil {
 		return nil, err
 	}
@@ -446,6 +462,16 @@ func (k *kafkaWriter) saramaConfigFromParsed(conf *service.ParsedConfig) (*saram
 		config.Producer.RequiredAcks = sarama.WaitForLocal
 	}
 
+	if k.txn.enabled {
+		// Transactions require an idempotent producer that waits for all
+		// replicas and has at most one request in flight per broker.
+		config.Producer.Idempotent = true
+		config.Producer.RequiredAcks = sarama.WaitForAll
+		config.Net.MaxOpenRequests = 1
+		config.Producer.Transaction.ID = k.txn.id
+		config.Producer.Transaction.Timeout = k.txn.timeout
+	}
+
 	if err := ApplySaramaSASLFromParsed(conf, k.mgr, config); err != nil {
 		return nil, err
 	}
@@ -497,7 +523,12 @@ func (k *kafkaWriter) WriteBatch(ctx context.Context, msg service.MessageBatch)
 			}
 		}
 
-		msgBytes, err := msg[i].AsBytes()
+		var msgBytes []byte
+		if k.schemaEncoder != nil {
+			msgBytes, err = k.schemaEncoder.Encode(msg[i], topic)
+		} else {
+			msgBytes, err = msg[i].AsBytes()
+		}
 		if err != nil {
 			return err
 		}
@@ -537,6 +568,10 @@ func (k *kafkaWriter) WriteBatch(ctx context.Context, msg service.MessageBatch)
 		msgs = append(msgs, nextMsg)
 	}
 
+	if k.txn.enabled {
+		return k.writeTransaction(producer, msg, msgs)
+	}
+
 	err := producer.SendMessages(msgs)
 	for err != nil {
 		if pErrs, ok := err.(sarama.ProducerErrors); !k.retryAsBatch && ok {
@@ -588,8 +623,75 @@ func (k *kafkaWriter) WriteBatch(ctx context.Context, msg service.MessageBatch)
 	return nil
 }
 
+// writeTransaction sends messages, and optionally commits the consumer offsets
+// of the batch, within a single transaction. Transactions are written
+// sequentially as a producer can only have one open transaction at a time.
+func (k *kafkaWriter) writeTransaction(producer sarama.SyncProducer, batch service.MessageBatch, msgs []*sarama.ProducerMessage) error {
+	var offsets map[string][]txnPartitionOffset
+	if k.txn.commitOffsets {
+		var err error
+		if offsets, err = txnOffsetsFromBatch(batch, k.txn.consumerGroup); err != nil {
+			return err
+		}
+	}
+
+	k.txnMut.Lock()
+	defer k.txnMut.Unlock()
+
+	if err := producer.BeginTxn(); err != nil {
+		k.resetFatalTransactionalProducer(producer)
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	err := producer.SendMessages(msgs)
+	if err == nil {
+		for group, groupOffsets := range offsets {
+			saramaOffsets := map[string][]*sarama.PartitionOffsetMetadata{}
+			for _, o := range groupOffsets {
+				saramaOffsets[o.topic] = append(saramaOffsets[o.topic], &sarama.PartitionOffsetMetadata{
+					Partition: o.partition,
+					Offset:    o.offset,
+				})
+			}
+			if err = producer.AddOffsetsToTxn(saramaOffsets, group); err != nil {
+				err = fmt.Errorf("failed to add offsets of consumer group %v to transaction: %w", group, err)
+				break
+			}
+		}
+	}
+	if err == nil {
+		if err = producer.CommitTxn(); err == nil {
+			return nil
+		}
+		err = fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	if abortErr := producer.AbortTxn(); abortErr != nil {
+		k.mgr.Logger().Errorf("Failed to abort transaction: %v", abortErr)
+	}
+	k.resetFatalTransactionalProducer(producer)
+	return err
+}
+
+// resetFatalTransactionalProducer closes a transactional producer that is no
+// longer able to recover, so that a new one is created upon reconnecting.
+func (k *kafkaWriter) resetFatalTransactionalProducer(producer sarama.SyncProducer) {
+	if producer.TxnStatus()&sarama.ProducerTxnFlagFatalError == 0 {
+		return
+	}
+
+	k.connMut.Lock()
+	defer k.connMut.Unlock()
+
+	if k.producer == producer {
+		k.mgr.Logger().Error("Transactional producer entered a fatal state, reconnecting")
+		_ = producer.Close()
+		k.producer = nil
+	}
+}
+
 // Close shuts down the Kafka writer and stops processing messages.
-func (k *kafkaWriter) Close(context.Context) error {
+func (k *kafkaWriter) Close(ctx context.Context) error {
 	k.connMut.Lock()
 	defer k.connMut.Unlock()
 
@@ -598,6 +700,11 @@ func (k *kafkaWriter) Close(context.Context) error {
 		err = k.producer.Close()
 		k.producer = nil
 	}
+	if k.schemaEncoder != nil {
+		if eErr := k.schemaEncoder.Close(ctx); err == nil {
+			err = eErr
+		}
+	}
 
 	return err
 }
@@ -0,0 +1,154 @@
+package kafka
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/benthosdev/benthos/v4/internal/value"
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	txnField              = "transaction"
+	txnFieldEnabled       = "enabled"
+	txnFieldID            = "id"
+	txnFieldTimeout       = "timeout"
+	txnFieldCommitOffsets = "commit_offsets"
+	txnFieldConsumerGroup = "consumer_group"
+)
+
+func transactionDocs(inputName string) string {
+	return `
+### Transactions
+
+When ` + "`transaction.enabled`" + ` is set to ` + "`true`" + ` each batch is written within a Kafka transaction using the configured ` + "`transaction.id`" + `, and is therefore only visible to consumers reading with an isolation level of ` + "`read_committed`" + ` once the entire batch has been written. A batch that fails to be written is aborted as a whole and retried, and batches are written one at a time regardless of ` + "`max_in_flight`" + `.
+
+When ` + "`transaction.commit_offsets`" + ` is also enabled and messages were consumed by a ` + "`" + inputName + "`" + ` input with a ` + "`consumer_group`" + `, the consumer offsets of the messages of a batch are committed within the same transaction. The offsets are derived from the metadata fields ` + "`kafka_topic`, `kafka_partition`, `kafka_offset` and `kafka_consumer_group`" + `, which allows pipelines that consume from and produce to Kafka to achieve exactly-once delivery.
+
+A ` + "`transaction.id`" + ` must be unique to each instance of a pipeline that writes concurrently, and should remain stable across restarts of the same instance so that transactions left incomplete by a previous instance are fenced off. Messages should not be modified in ways that remove the metadata above in between the input and the output, and batches should not be split or combined with messages from other consumer groups.
+`
+}
+
+func transactionField() *service.ConfigField {
+	return service.NewObjectField(txnField,
+		service.NewBoolField(txnFieldEnabled).
+			Description("Whether to write batches within transactions.").
+			Default(false),
+		service.NewStringField(txnFieldID).
+			Description("The transactional ID of the producer, which must be unique to each concurrently running instance and stable across restarts. This field corresponds to Kafka's `transactional.id`.").
+			Example("benthos-orders-0").
+			Default(""),
+		service.NewDurationField(txnFieldTimeout).
+			Description("The maximum period of time a transaction may remain open before it is aborted by the broker. This field corresponds to Kafka's `transaction.timeout.ms`.").
+			Default("1m"),
+		service.NewBoolField(txnFieldCommitOffsets).
+			Description("Whether to commit the consumer offsets of messages consumed from Kafka within each transaction.").
+			Default(true),
+		service.NewStringField(txnFieldConsumerGroup).
+			Description("An optional consumer group to commit offsets to, which overrides the `kafka_consumer_group` metadata field of messages.").
+			Default("").
+			Advanced(),
+	).
+		Description("Configures writing batches within Kafka transactions for exactly-once delivery.").
+		Advanced()
+}
+
+type txnConfig struct {
+	enabled       bool
+	id            string
+	timeout       time.Duration
+	commitOffsets bool
+	consumerGroup string
+}
+
+func txnConfigFromParsed(conf *service.ParsedConfig) (c txnConfig, err error) {
+	tConf := conf.Namespace(txnField)
+	if c.enabled, err = tConf.FieldBool(txnFieldEnabled); err != nil || !c.enabled {
+		return
+	}
+	if c.id, err = tConf.FieldString(txnFieldID); err != nil {
+		return
+	}
+	if c.id == "" {
+		err = errors.New("a transaction id must be specified when transactions are enabled")
+		return
+	}
+	if c.timeout, err = tConf.FieldDuration(txnFieldTimeout); err != nil {
+		return
+	}
+	if c.commitOffsets, err = tConf.FieldBool(txnFieldCommitOffsets); err != nil {
+		return
+	}
+	c.consumerGroup, err = tConf.FieldString(txnFieldConsumerGroup)
+	return
+}
+
+// txnPartitionOffset is the next offset to consume from a topic partition.
+type txnPartitionOffset struct {
+	topic     string
+	partition int32
+	offset    int64
+}
+
+// txnOffsetsFromBatch extracts the consumer offsets to commit for each
+// consumer group from the metadata of a batch of messages consumed from Kafka.
+// The offset committed for each partition is the offset after the highest
+// offset consumed. Messages without the required metadata are ignored.
+func txnOffsetsFromBatch(batch service.MessageBatch, groupOverride string) (map[string][]txnPartitionOffset, error) {
+	type topicPartition struct {
+		topic     string
+		partition int32
+	}
+	groups := map[string]map[topicPartition]int64{}
+
+	for i, msg := range batch {
+		group := groupOverride
+		if group == "" {
+			group, _ = msg.MetaGet("kafka_consumer_group")
+		}
+		topic, hasTopic := msg.MetaGetMut("kafka_topic")
+		partition, hasPartition := msg.MetaGetMut("kafka_partition")
+		offset, hasOffset := msg.MetaGetMut("kafka_offset")
+		if group == "" || !hasTopic || !hasPartition || !hasOffset {
+			continue
+		}
+
+		tp := topicPartition{topic: value.IToString(topic)}
+		p, err := value.IToInt(partition)
+		if err != nil {
+			return nil, fmt.Errorf("message %v: kafka_partition: %w", i, err)
+		}
+		tp.partition = int32(p)
+		o, err := value.IToInt(offset)
+		if err != nil {
+			return nil, fmt.Errorf("message %v: kafka_offset: %w", i, err)
+		}
+
+		offsets, exists := groups[group]
+		if !exists {
+			offsets = map[topicPartition]int64{}
+			groups[group] = offsets
+		}
+		if current, exists := offsets[tp]; !exists || o+1 > current {
+			offsets[tp] = o + 1
+		}
+	}
+
+	res := make(map[string][]txnPartitionOffset, len(groups))
+	for group, offsets := range groups {
+		sorted := make([]txnPartitionOffset, 0, len(offsets))
+		for tp, o := range offsets {
+			sorted = append(sorted, txnPartitionOffset{topic: tp.topic, partition: tp.partition, offset: o})
+		}
+		sort.Slice(sorted, func(i, j int) bool {
+			if sorted[i].topic != sorted[j].topic {
+				return sorted[i].topic < sorted[j].topic
+			}
+			return sorted[i].partition < sorted[j].partition
+		})
+		res[group] = sorted
+	}
+	return res, nil
+}
@@ -0,0 +1,120 @@
+package kafka
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func TestTxnOffsetsFromBatch(t *testing.T) {
+	newMsg := func(group, topic string, partition, offset int) *service.Message {
+		msg := service.NewMessage(nil)
+		if group != "" {
+			msg.MetaSetMut("kafka_consumer_group", group)
+		}
+		msg.MetaSetMut("kafka_topic", topic)
+		msg.MetaSetMut("kafka_partition", partition)
+		msg.MetaSetMut("kafka_offset", offset)
+		return msg
+	}
+
+	batch := service.MessageBatch{
+		newMsg("a", "foo", 1, 10),
+		newMsg("a", "foo", 0, 5),
+		newMsg("a", "foo", 1, 8),
+		newMsg("a", "bar", 0, 3),
+		newMsg("b", "foo", 0, 20),
+		newMsg("", "foo", 0, 100),
+		service.NewMessage(nil),
+	}
+
+	offsets, err := txnOffsetsFromBatch(batch, "")
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]txnPartitionOffset{
+		"a": {
+			{topic: "bar", partition: 0, offset: 4},
+			{topic: "foo", partition: 0, offset: 6},
+			{topic: "foo", partition: 1, offset: 11},
+		},
+		"b": {
+			{topic: "foo", partition: 0, offset: 21},
+		},
+	}, offsets)
+
+	offsets, err = txnOffsetsFromBatch(batch, "c")
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]txnPartitionOffset{
+		"c": {
+			{topic: "bar", partition: 0, offset: 4},
+			{topic: "foo", partition: 0, offset: 101},
+			{topic: "foo", partition: 1, offset: 11},
+		},
+	}, offsets)
+
+	badMsg := newMsg("a", "foo", 0, 0)
+	badMsg.MetaSetMut("kafka_offset", "nope")
+	_, err = txnOffsetsFromBatch(service.MessageBatch{badMsg}, "")
+	require.Error(t, err)
+}
+
+func TestTransactionConfig(t *testing.T) {
+	testCases := []struct {
+		name        string
+		conf        string
+		errContains string
+	}{
+		{
+			name: "franz transaction",
+			conf: `
+seed_brokers: [ foo:1234 ]
+topic: foo
+transaction:
+  enabled: true
+  id: foo-0
+`,
+		},
+		{
+			name: "franz transaction without id",
+			conf: `
+seed_brokers: [ foo:1234 ]
+topic: foo
+transaction:
+  enabled: true
+`,
+			errContains: "a transaction id must be specified",
+		},
+		{
+			name: "franz transaction without idempotence",
+			conf: `
+seed_brokers: [ foo:1234 ]
+topic: foo
+idempotent_write: false
+transaction:
+  enabled: true
+  id: foo-0
+`,
+			errContains: "idempotent_write must be enabled",
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			pConf, err := franzKafkaOutputConfig().ParseYAML(test.conf, nil)
+			require.NoError(t, err)
+
+			w, err := newFranzKafkaWriterFromConfig(pConf, nil)
+			if test.errContains == "" {
+				require.NoError(t, err)
+				assert.Equal(t, "foo-0", w.txn.id)
+				assert.True(t, w.txn.commitOffsets)
+			} else {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), test.errContains)
+			}
+		})
+	}
+}
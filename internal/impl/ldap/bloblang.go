@@ -0,0 +1,49 @@
+package ldap
+
+import (
+	"strings"
+
+	"github.com/benthosdev/benthos/v4/public/bloblang"
+)
+
+// escapeFilter escapes the characters of a value that have special meaning
+// within LDAP search filters, as described in RFC 4515.
+func escapeFilter(s string) string {
+	const hexDigits = "0123456789abcdef"
+
+	var b strings.Builder
+	b.Grow(len(s))
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '*', '(', ')', '\\', 0:
+			b.WriteByte('\\')
+			b.WriteByte(hexDigits[c>>4])
+			b.WriteByte(hexDigits[c&0xf])
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+func init() {
+	if err := bloblang.RegisterMethodV2("escape_ldap_filter",
+		bloblang.NewPluginSpec().
+			Category("String Manipulation").
+			Version("4.28.0").
+			Description("Escapes a string so that it can be safely embedded as a value within an LDAP search filter, preventing the injection of filter syntax.").
+			Example("", `root.filter = "(uid=%s)".format(this.user.escape_ldap_filter())`, [2]string{
+				`{"user":"jdoe"}`,
+				`{"filter":"(uid=jdoe)"}`,
+			}, [2]string{
+				`{"user":"*)(uid=*"}`,
+				`{"filter":"(uid=\\2a\\29\\28uid=\\2a)"}`,
+			}),
+		func(args *bloblang.ParsedParams) (bloblang.Method, error) {
+			return bloblang.StringMethod(func(s string) (any, error) {
+				return escapeFilter(s), nil
+			}), nil
+		}); err != nil {
+		panic(err)
+	}
+}
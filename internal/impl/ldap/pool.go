@@ -0,0 +1,150 @@
+package ldap
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+// ldapClient is a connection to an LDAP server.
+type ldapClient interface {
+	search(req *ldap.SearchRequest, pageSize uint32) (*ldap.SearchResult, error)
+	broken() bool
+	close()
+}
+
+type connClient struct {
+	conn *ldap.Conn
+}
+
+func (c *connClient) search(req *ldap.SearchRequest, pageSize uint32) (*ldap.SearchResult, error) {
+	if pageSize > 0 {
+		return c.conn.SearchWithPaging(req, pageSize)
+	}
+	return c.conn.Search(req)
+}
+
+func (c *connClient) broken() bool {
+	return c.conn.IsClosing()
+}
+
+func (c *connClient) close() {
+	c.conn.Close()
+}
+
+type dialConfig struct {
+	url          string
+	tlsConf      *tls.Config
+	startTLS     bool
+	bindDN       string
+	bindPassword string
+	timeout      time.Duration
+}
+
+// dial connects to an LDAP server, upgrading the connection with StartTLS and
+// binding when configured to.
+func (d dialConfig) dial(ctx context.Context) (ldapClient, error) {
+	dialer := &net.Dialer{Timeout: d.timeout}
+	if deadline, ok := ctx.Deadline(); ok {
+		dialer.Deadline = deadline
+	}
+
+	conn, err := ldap.DialURL(d.url, ldap.DialWithDialer(dialer), ldap.DialWithTLSConfig(d.tlsConf))
+	if err != nil {
+		return nil, err
+	}
+	if d.timeout > 0 {
+		conn.SetTimeout(d.timeout)
+	}
+
+	if d.startTLS {
+		if err := conn.StartTLS(d.tlsConf); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	if d.bindDN != "" {
+		if err := conn.Bind(d.bindDN, d.bindPassword); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return &connClient{conn: conn}, nil
+}
+
+// clientPool limits the number of connections open to a server at once, and
+// keeps idle connections open for reuse.
+type clientPool struct {
+	dial func(ctx context.Context) (ldapClient, error)
+	sem  chan struct{}
+	idle chan ldapClient
+}
+
+func newClientPool(size int, dial func(ctx context.Context) (ldapClient, error)) *clientPool {
+	return &clientPool{
+		dial: dial,
+		sem:  make(chan struct{}, size),
+		idle: make(chan ldapClient, size),
+	}
+}
+
+// get returns an idle connection, or a new connection when there are none,
+// waiting for a connection to be released when the pool is at capacity. When
+// fresh is true an idle connection is never returned.
+func (p *clientPool) get(ctx context.Context, fresh bool) (ldapClient, error) {
+	select {
+	case p.sem <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	for !fresh {
+		var c ldapClient
+		select {
+		case c = <-p.idle:
+		default:
+		}
+		if c == nil {
+			break
+		}
+		if !c.broken() {
+			return c, nil
+		}
+		c.close()
+	}
+
+	c, err := p.dial(ctx)
+	if err != nil {
+		<-p.sem
+		return nil, err
+	}
+	return c, nil
+}
+
+// put releases a connection back to the pool, closing it when it is broken.
+func (p *clientPool) put(c ldapClient, broken bool) {
+	if broken || c.broken() {
+		c.close()
+	} else {
+		select {
+		case p.idle <- c:
+		default:
+			c.close()
+		}
+	}
+	<-p.sem
+}
+
+func (p *clientPool) close() {
+	for {
+		select {
+		case c := <-p.idle:
+			c.close()
+		default:
+			return
+		}
+	}
+}
@@ -0,0 +1,436 @@
+package ldap
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Jeffail/gabs/v2"
+	"github.com/go-ldap/ldap/v3"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	lpFieldURL             = "url"
+	lpFieldTLS             = "tls"
+	lpFieldStartTLS        = "start_tls"
+	lpFieldBindDN          = "bind_dn"
+	lpFieldBindPassword    = "bind_password"
+	lpFieldBaseDN          = "base_dn"
+	lpFieldScope           = "scope"
+	lpFieldFilter          = "filter"
+	lpFieldAttributes      = "attributes"
+	lpFieldArrayAttributes = "array_attributes"
+	lpFieldTarget          = "target"
+	lpFieldAllEntries      = "all_entries"
+	lpFieldPageSize        = "page_size"
+	lpFieldMaxConnections  = "max_connections"
+	lpFieldTimeout         = "timeout"
+	lpFieldCache           = "cache"
+	lpFieldCacheTTL        = "cache_ttl"
+)
+
+var ldapScopes = map[string]int{
+	"base": ldap.ScopeBaseObject,
+	"one":  ldap.ScopeSingleLevel,
+	"sub":  ldap.ScopeWholeSubtree,
+}
+
+func ldapProcSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Version("4.28.0").
+		Categories("Integration").
+		Summary("Searches an LDAP directory, such as Active Directory, for entries matching a filter and adds their attributes to messages.").
+		Description(`
+Messages are parsed as JSON, and the attributes of the first entry matching `+"`filter`"+` are added as an object to `+"`target`"+`, along with the distinguished name of the entry as the field `+"`dn`"+`:
+
+`+"```json"+`
+{
+  "dn": "CN=Jane Doe,OU=Staff,DC=example,DC=com",
+  "displayName": "Jane Doe",
+  "mail": "jane.doe@example.com",
+  "memberOf": [ "CN=Admins,OU=Groups,DC=example,DC=com" ]
+}
+`+"```"+`
+
+Attributes with a single value are added as strings and attributes with multiple values as arrays of strings, and attributes listed in `+"`array_attributes`"+` are always added as arrays. When `+"`all_entries`"+` is set an array of all matching entries is added instead. When no entries match the message is left unchanged.
+
+### Filters
+
+The `+"`filter`"+` supports [interpolation functions](/docs/configuration/interpolation#bloblang-queries), and values taken from messages should be escaped with the `+"[`escape_ldap_filter` method](/docs/guides/bloblang/methods#escape_ldap_filter)"+` in order to prevent the injection of filter syntax.
+
+### Performance
+
+Connections are kept open between searches and shared across messages, up to a maximum of `+"`max_connections`"+`. Searches that match many entries can be split into pages with `+"`page_size`"+`, which is required by servers that limit the number of entries returned by each search. Search results can also be stored within a `+"[cache resource](/docs/components/caches/about)"+` set with `+"`cache`"+`, which avoids searching the directory for hot lookups such as active users, where searches that match no entries are cached as well.
+
+Messages that cannot be enriched are flagged as having failed, allowing you to use [standard processor error handling patterns](/docs/configuration/error_handling).`).
+		Fields(
+			service.NewStringField(lpFieldURL).
+				Description("The URL of the LDAP server, where the `ldaps` scheme connects with TLS.").
+				Examples("ldap://localhost:389", "ldaps://dc1.example.com:636"),
+			service.NewTLSField(lpFieldTLS).
+				Description("Custom TLS settings for `ldaps` URLs and StartTLS.").
+				Advanced(),
+			service.NewBoolField(lpFieldStartTLS).
+				Description("Whether to upgrade `ldap` connections to TLS with StartTLS.").
+				Advanced().
+				Default(false),
+			service.NewStringField(lpFieldBindDN).
+				Description("The distinguished name to bind as, where searches are anonymous when empty.").
+				Example("CN=benthos,OU=Service Accounts,DC=example,DC=com").
+				Default(""),
+			service.NewStringField(lpFieldBindPassword).
+				Description("The password to bind with.").
+				Secret().
+				Default(""),
+			service.NewInterpolatedStringField(lpFieldBaseDN).
+				Description("The distinguished name of the entry to search from.").
+				Example("DC=example,DC=com"),
+			service.NewStringAnnotatedEnumField(lpFieldScope, map[string]string{
+				"base": "Only the base entry is searched.",
+				"one":  "Only the immediate children of the base entry are searched.",
+				"sub":  "The base entry and all of its descendants are searched.",
+			}).
+				Description("The scope of searches.").
+				Default("sub"),
+			service.NewInterpolatedStringField(lpFieldFilter).
+				Description("The filter of searches.").
+				Example(`(&(objectClass=user)(sAMAccountName=${! json("user.name").escape_ldap_filter() }))`),
+			service.NewStringListField(lpFieldAttributes).
+				Description("The attributes to add to messages, where all attributes are added when empty.").
+				Example([]any{"displayName", "mail", "memberOf"}).
+				Default([]any{}),
+			service.NewStringListField(lpFieldArrayAttributes).
+				Description("Attributes that are always added as arrays, even when they have a single value.").
+				Example([]any{"memberOf"}).
+				Default([]any{}),
+			service.NewStringField(lpFieldTarget).
+				Description("The [dot path](/docs/configuration/field_paths) of the field to add entries to."),
+			service.NewBoolField(lpFieldAllEntries).
+				Description("Whether to add an array of all matching entries rather than only the first.").
+				Default(false),
+			service.NewIntField(lpFieldPageSize).
+				Description("The number of entries to request for each page of results, where results are not paged when zero.").
+				Advanced().
+				Default(0),
+			service.NewIntField(lpFieldMaxConnections).
+				Description("The maximum number of connections to open to the server.").
+				Advanced().
+				Default(4),
+			service.NewDurationField(lpFieldTimeout).
+				Description("The maximum period to wait for connections and searches.").
+				Advanced().
+				Default("10s"),
+			service.NewStringField(lpFieldCache).
+				Description("An optional [cache resource](/docs/components/caches/about) to store search results in.").
+				Optional(),
+			service.NewDurationField(lpFieldCacheTTL).
+				Description("An optional expiry period of search results stored within `cache`.").
+				Example("5m").
+				Optional(),
+		).
+		Example("Authentication Log Enrichment", "Here we add the display name and groups of users to Windows authentication events, caching the results of searches for five minutes.", `
+pipeline:
+  processors:
+    - ldap:
+        url: ldaps://dc1.example.com:636
+        bind_dn: CN=benthos,OU=Service Accounts,DC=example,DC=com
+        bind_password: ${LDAP_PASSWORD}
+        base_dn: DC=example,DC=com
+        filter: '(&(objectClass=user)(sAMAccountName=${! json("winlog.event_data.TargetUserName").escape_ldap_filter() }))'
+        attributes: [ displayName, department, memberOf ]
+        array_attributes: [ memberOf ]
+        target: user
+        cache: ldap_cache
+        cache_ttl: 5m
+
+cache_resources:
+  - label: ldap_cache
+    memory: {}
+`)
+}
+
+func init() {
+	err := service.RegisterProcessor(
+		"ldap", ldapProcSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Processor, error) {
+			return newLDAPProcFromConfig(conf, mgr)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type ldapProc struct {
+	mgr *service.Resources
+
+	pool     *clientPool
+	pageSize uint32
+	timeout  time.Duration
+
+	baseDN     *service.InterpolatedString
+	scope      int
+	filter     *service.InterpolatedString
+	attributes []string
+	arrayAttrs map[string]struct{}
+	target     string
+	allEntries bool
+	cacheName  string
+	cacheTTL   *time.Duration
+}
+
+func newLDAPProcFromConfig(conf *service.ParsedConfig, mgr *service.Resources) (*ldapProc, error) {
+	l := &ldapProc{mgr: mgr}
+
+	var dc dialConfig
+	var err error
+	if dc.url, err = conf.FieldString(lpFieldURL); err != nil {
+		return nil, err
+	}
+	u, err := url.Parse(dc.url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse url: %w", err)
+	}
+	if dc.tlsConf, err = conf.FieldTLS(lpFieldTLS); err != nil {
+		return nil, err
+	}
+	if dc.tlsConf == nil {
+		dc.tlsConf = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+	if dc.tlsConf.ServerName == "" {
+		dc.tlsConf.ServerName = u.Hostname()
+	}
+	if dc.startTLS, err = conf.FieldBool(lpFieldStartTLS); err != nil {
+		return nil, err
+	}
+	if dc.startTLS && u.Scheme == "ldaps" {
+		return nil, errors.New("start_tls cannot be used with ldaps urls")
+	}
+	if dc.bindDN, err = conf.FieldString(lpFieldBindDN); err != nil {
+		return nil, err
+	}
+	if dc.bindPassword, err = conf.FieldString(lpFieldBindPassword); err != nil {
+		return nil, err
+	}
+	if dc.timeout, err = conf.FieldDuration(lpFieldTimeout); err != nil {
+		return nil, err
+	}
+	l.timeout = dc.timeout
+
+	if l.baseDN, err = conf.FieldInterpolatedString(lpFieldBaseDN); err != nil {
+		return nil, err
+	}
+	scopeStr, err := conf.FieldString(lpFieldScope)
+	if err != nil {
+		return nil, err
+	}
+	var exists bool
+	if l.scope, exists = ldapScopes[scopeStr]; !exists {
+		return nil, fmt.Errorf("unrecognised scope: %v", scopeStr)
+	}
+	if l.filter, err = conf.FieldInterpolatedString(lpFieldFilter); err != nil {
+		return nil, err
+	}
+	if l.attributes, err = conf.FieldStringList(lpFieldAttributes); err != nil {
+		return nil, err
+	}
+	arrayAttrs, err := conf.FieldStringList(lpFieldArrayAttributes)
+	if err != nil {
+		return nil, err
+	}
+	l.arrayAttrs = map[string]struct{}{}
+	for _, a := range arrayAttrs {
+		l.arrayAttrs[strings.ToLower(a)] = struct{}{}
+	}
+	if l.target, err = conf.FieldString(lpFieldTarget); err != nil {
+		return nil, err
+	}
+	if l.allEntries, err = conf.FieldBool(lpFieldAllEntries); err != nil {
+		return nil, err
+	}
+
+	pageSize, err := conf.FieldInt(lpFieldPageSize)
+	if err != nil {
+		return nil, err
+	}
+	if pageSize < 0 {
+		return nil, errors.New("page size must not be negative")
+	}
+	l.pageSize = uint32(pageSize)
+
+	maxConns, err := conf.FieldInt(lpFieldMaxConnections)
+	if err != nil {
+		return nil, err
+	}
+	if maxConns < 1 {
+		return nil, errors.New("max connections must be at least one")
+	}
+
+	if conf.Contains(lpFieldCache) {
+		if l.cacheName, err = conf.FieldString(lpFieldCache); err != nil {
+			return nil, err
+		}
+		if !mgr.HasCache(l.cacheName) {
+			return nil, fmt.Errorf("cache named %v not found", l.cacheName)
+		}
+	}
+	if conf.Contains(lpFieldCacheTTL) {
+		ttl, err := conf.FieldDuration(lpFieldCacheTTL)
+		if err != nil {
+			return nil, err
+		}
+		l.cacheTTL = &ttl
+	}
+
+	l.pool = newClientPool(maxConns, dc.dial)
+	return l, nil
+}
+
+// entryToStructured converts an entry into an object of its attributes.
+func (l *ldapProc) entryToStructured(e *ldap.Entry) map[string]any {
+	obj := map[string]any{"dn": e.DN}
+	for _, a := range e.Attributes {
+		if _, isArray := l.arrayAttrs[strings.ToLower(a.Name)]; len(a.Values) == 1 && !isArray {
+			obj[a.Name] = a.Values[0]
+			continue
+		}
+		values := make([]any, 0, len(a.Values))
+		for _, v := range a.Values {
+			values = append(values, v)
+		}
+		obj[a.Name] = values
+	}
+	return obj
+}
+
+// search returns the entries matching a filter, retrying once with a new
+// connection when the search fails due to a broken connection, as idle
+// connections may have been closed by the server.
+func (l *ldapProc) search(ctx context.Context, baseDN, filter string) ([]any, error) {
+	req := ldap.NewSearchRequest(
+		baseDN, l.scope, ldap.NeverDerefAliases, 0, int(l.timeout.Seconds()), false,
+		filter, l.attributes, nil,
+	)
+
+	var res *ldap.SearchResult
+	for attempt := 0; ; attempt++ {
+		c, err := l.pool.get(ctx, attempt > 0)
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect: %w", err)
+		}
+		res, err = c.search(req, l.pageSize)
+		broken := ldap.IsErrorWithCode(err, ldap.ErrorNetwork)
+		l.pool.put(c, broken)
+		if err == nil {
+			break
+		}
+		if broken && attempt == 0 {
+			continue
+		}
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultNoSuchObject) {
+			return []any{}, nil
+		}
+		return nil, err
+	}
+
+	entries := make([]any, 0, len(res.Entries))
+	for _, e := range res.Entries {
+		entries = append(entries, l.entryToStructured(e))
+	}
+	return entries, nil
+}
+
+// cachedSearch returns the entries matching a filter from the cache when it is
+// configured, and otherwise searches the directory.
+func (l *ldapProc) cachedSearch(ctx context.Context, baseDN, filter string) ([]any, error) {
+	if l.cacheName == "" {
+		return l.search(ctx, baseDN, filter)
+	}
+
+	key := baseDN + "\n" + filter
+	var cached []byte
+	var err error
+	if cerr := l.mgr.AccessCache(ctx, l.cacheName, func(c service.Cache) {
+		cached, err = c.Get(ctx, key)
+	}); cerr != nil {
+		return nil, cerr
+	}
+	if err == nil {
+		var entries []any
+		if err := json.Unmarshal(cached, &entries); err != nil {
+			return nil, fmt.Errorf("failed to parse cached entries: %w", err)
+		}
+		return entries, nil
+	}
+	if !errors.Is(err, service.ErrKeyNotFound) {
+		return nil, err
+	}
+
+	entries, err := l.search(ctx, baseDN, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	// Failing to cache entries does not prevent the message from being
+	// enriched.
+	b, err := json.Marshal(entries)
+	if err == nil {
+		if cerr := l.mgr.AccessCache(ctx, l.cacheName, func(c service.Cache) {
+			err = c.Set(ctx, key, b, l.cacheTTL)
+		}); cerr != nil {
+			err = cerr
+		}
+	}
+	if err != nil {
+		l.mgr.Logger().Warnf("Failed to cache search results: %v", err)
+	}
+	return entries, nil
+}
+
+func (l *ldapProc) Process(ctx context.Context, msg *service.Message) (service.MessageBatch, error) {
+	baseDN, err := l.baseDN.TryString(msg)
+	if err != nil {
+		return nil, fmt.Errorf("base dn interpolation error: %w", err)
+	}
+	filter, err := l.filter.TryString(msg)
+	if err != nil {
+		return nil, fmt.Errorf("filter interpolation error: %w", err)
+	}
+
+	v, err := msg.AsStructuredMut()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse message as JSON: %w", err)
+	}
+
+	entries, err := l.cachedSearch(ctx, baseDN, filter)
+	if err != nil {
+		l.mgr.Logger().Debugf("Failed to search for %v: %v", filter, err)
+		return nil, fmt.Errorf("failed to search for %v: %w", filter, err)
+	}
+	if len(entries) == 0 {
+		return service.MessageBatch{msg}, nil
+	}
+
+	var value any = entries[0]
+	if l.allEntries {
+		value = entries
+	}
+	doc := gabs.Wrap(v)
+	if _, err := doc.SetP(value, l.target); err != nil {
+		return nil, fmt.Errorf("field %v: %w", l.target, err)
+	}
+	msg.SetStructuredMut(doc.Data())
+	return service.MessageBatch{msg}, nil
+}
+
+func (l *ldapProc) Close(ctx context.Context) error {
+	l.pool.close()
+	return nil
+}
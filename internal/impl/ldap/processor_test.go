@@ -0,0 +1,258 @@
+package ldap
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/bloblang"
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+// fakeDirectory serves searches from a fixed set of entries keyed by filter.
+type fakeDirectory struct {
+	mut       sync.Mutex
+	entries   map[string][]*ldap.Entry
+	searches  []*ldap.SearchRequest
+	pageSize  []uint32
+	dials     int
+	breakNext bool
+}
+
+type fakeClient struct {
+	dir      *fakeDirectory
+	isBroken bool
+}
+
+func (c *fakeClient) search(req *ldap.SearchRequest, pageSize uint32) (*ldap.SearchResult, error) {
+	c.dir.mut.Lock()
+	defer c.dir.mut.Unlock()
+
+	if c.dir.breakNext {
+		c.dir.breakNext = false
+		c.isBroken = true
+		return nil, ldap.NewError(ldap.ErrorNetwork, errors.New("connection closed"))
+	}
+	c.dir.searches = append(c.dir.searches, req)
+	c.dir.pageSize = append(c.dir.pageSize, pageSize)
+	if req.BaseDN != "dc=example,dc=com" {
+		return nil, ldap.NewError(ldap.LDAPResultNoSuchObject, errors.New("no such object"))
+	}
+
+	res := &ldap.SearchResult{}
+	for _, e := range c.dir.entries[req.Filter] {
+		if len(req.Attributes) == 0 {
+			res.Entries = append(res.Entries, e)
+			continue
+		}
+		selected := &ldap.Entry{DN: e.DN}
+		for _, a := range e.Attributes {
+			for _, name := range req.Attributes {
+				if name == a.Name {
+					selected.Attributes = append(selected.Attributes, a)
+				}
+			}
+		}
+		res.Entries = append(res.Entries, selected)
+	}
+	return res, nil
+}
+
+func (c *fakeClient) broken() bool {
+	return c.isBroken
+}
+
+func (c *fakeClient) close() {}
+
+func newFakeDirectory() *fakeDirectory {
+	return &fakeDirectory{
+		entries: map[string][]*ldap.Entry{
+			"(uid=jdoe)": {{
+				DN: "uid=jdoe,ou=people,dc=example,dc=com",
+				Attributes: []*ldap.EntryAttribute{
+					{Name: "cn", Values: []string{"Jane Doe"}},
+					{Name: "mail", Values: []string{"jdoe@example.com", "jane@example.com"}},
+					{Name: "memberOf", Values: []string{"cn=admins,ou=groups,dc=example,dc=com"}},
+				},
+			}},
+			"(ou=people)": {
+				{DN: "uid=a,ou=people,dc=example,dc=com"},
+				{DN: "uid=b,ou=people,dc=example,dc=com"},
+			},
+		},
+	}
+}
+
+func testLDAPProc(t *testing.T, dir *fakeDirectory, confStr string, opts ...service.MockResourcesOptFn) *ldapProc {
+	t.Helper()
+
+	conf, err := ldapProcSpec().ParseYAML(confStr, nil)
+	require.NoError(t, err)
+
+	proc, err := newLDAPProcFromConfig(conf, service.MockResources(opts...))
+	require.NoError(t, err)
+
+	proc.pool = newClientPool(2, func(ctx context.Context) (ldapClient, error) {
+		dir.mut.Lock()
+		dir.dials++
+		dir.mut.Unlock()
+		return &fakeClient{dir: dir}, nil
+	})
+	t.Cleanup(func() {
+		require.NoError(t, proc.Close(context.Background()))
+	})
+	return proc
+}
+
+func processJSON(t *testing.T, proc *ldapProc, doc string) string {
+	t.Helper()
+
+	res, err := proc.Process(context.Background(), service.NewMessage([]byte(doc)))
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+
+	b, err := res[0].AsBytes()
+	require.NoError(t, err)
+	return string(b)
+}
+
+func TestEscapeFilter(t *testing.T) {
+	assert.Equal(t, "jdoe", escapeFilter("jdoe"))
+	assert.Equal(t, `\2a\29\28uid=\2a`, escapeFilter("*)(uid=*"))
+	assert.Equal(t, `a\5cb\00`, escapeFilter("a\\b\x00"))
+	assert.Equal(t, "zoë", escapeFilter("zoë"))
+
+	exec, err := bloblang.Parse(`root = this.user.escape_ldap_filter()`)
+	require.NoError(t, err)
+	res, err := exec.Query(map[string]any{"user": "a*"})
+	require.NoError(t, err)
+	assert.Equal(t, `a\2a`, res)
+}
+
+func TestLDAPProcessorEnrich(t *testing.T) {
+	dir := newFakeDirectory()
+	proc := testLDAPProc(t, dir, `
+url: ldap://localhost:389
+base_dn: dc=example,dc=com
+filter: '(uid=${! json("user").escape_ldap_filter() })'
+attributes: [ cn, mail, memberOf ]
+array_attributes: [ MEMBEROF ]
+target: directory
+page_size: 100
+`)
+
+	assert.JSONEq(t, `{
+  "user": "jdoe",
+  "directory": {
+    "dn": "uid=jdoe,ou=people,dc=example,dc=com",
+    "cn": "Jane Doe",
+    "mail": ["jdoe@example.com", "jane@example.com"],
+    "memberOf": ["cn=admins,ou=groups,dc=example,dc=com"]
+  }
+}`, processJSON(t, proc, `{"user":"jdoe"}`))
+
+	// Unmatched searches leave messages unchanged.
+	assert.JSONEq(t, `{"user":"*"}`, processJSON(t, proc, `{"user":"*"}`))
+
+	require.Len(t, dir.searches, 2)
+	assert.Equal(t, `(uid=\2a)`, dir.searches[1].Filter)
+	assert.Equal(t, []string{"cn", "mail", "memberOf"}, dir.searches[0].Attributes)
+	assert.Equal(t, ldap.ScopeWholeSubtree, dir.searches[0].Scope)
+	assert.Equal(t, []uint32{100, 100}, dir.pageSize)
+
+	// Connections are reused between messages.
+	assert.Equal(t, 1, dir.dials)
+}
+
+func TestLDAPProcessorAllEntries(t *testing.T) {
+	dir := newFakeDirectory()
+	proc := testLDAPProc(t, dir, `
+url: ldap://localhost:389
+base_dn: ${! json("base") }
+scope: one
+filter: (ou=people)
+target: people
+all_entries: true
+`)
+
+	assert.JSONEq(t, `{
+  "base": "dc=example,dc=com",
+  "people": [
+    {"dn": "uid=a,ou=people,dc=example,dc=com"},
+    {"dn": "uid=b,ou=people,dc=example,dc=com"}
+  ]
+}`, processJSON(t, proc, `{"base":"dc=example,dc=com"}`))
+	assert.Equal(t, ldap.ScopeSingleLevel, dir.searches[0].Scope)
+
+	// A base entry that does not exist matches no entries.
+	assert.JSONEq(t, `{"base":"dc=nope"}`, processJSON(t, proc, `{"base":"dc=nope"}`))
+}
+
+func TestLDAPProcessorBrokenConnection(t *testing.T) {
+	dir := newFakeDirectory()
+	proc := testLDAPProc(t, dir, `
+url: ldap://localhost:389
+base_dn: dc=example,dc=com
+filter: (uid=jdoe)
+attributes: [ cn ]
+target: directory
+`)
+
+	processJSON(t, proc, `{}`)
+	assert.Equal(t, 1, dir.dials)
+
+	// A connection closed by the server is replaced and the search is retried.
+	dir.breakNext = true
+	assert.JSONEq(t, `{"directory":{"dn":"uid=jdoe,ou=people,dc=example,dc=com","cn":"Jane Doe"}}`, processJSON(t, proc, `{}`))
+	assert.Equal(t, 2, dir.dials)
+	assert.Len(t, dir.searches, 2)
+}
+
+func TestLDAPProcessorCache(t *testing.T) {
+	dir := newFakeDirectory()
+	proc := testLDAPProc(t, dir, `
+url: ldap://localhost:389
+base_dn: dc=example,dc=com
+filter: '(uid=${! json("user") })'
+attributes: [ cn ]
+target: directory
+cache: foo
+cache_ttl: 1m
+`, service.MockResourcesOptAddCache("foo"))
+
+	for i := 0; i < 3; i++ {
+		assert.JSONEq(t, `{"user":"jdoe","directory":{"dn":"uid=jdoe,ou=people,dc=example,dc=com","cn":"Jane Doe"}}`, processJSON(t, proc, `{"user":"jdoe"}`))
+		assert.JSONEq(t, `{"user":"nobody"}`, processJSON(t, proc, `{"user":"nobody"}`))
+	}
+	assert.Len(t, dir.searches, 2)
+}
+
+func TestLDAPProcessorConfigErrors(t *testing.T) {
+	for _, confStr := range []string{
+		`
+url: ldaps://localhost:636
+start_tls: true
+base_dn: dc=example,dc=com
+filter: (uid=jdoe)
+target: directory
+`,
+		`
+url: ldap://localhost:389
+base_dn: dc=example,dc=com
+filter: (uid=jdoe)
+target: directory
+cache: nope
+`,
+	} {
+		conf, err := ldapProcSpec().ParseYAML(confStr, nil)
+		require.NoError(t, err)
+
+		_, err = newLDAPProcFromConfig(conf, service.MockResources())
+		assert.Error(t, err)
+	}
+}
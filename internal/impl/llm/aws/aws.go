@@ -0,0 +1,34 @@
+package aws
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"time"
+
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+
+	baws "github.com/benthosdev/benthos/v4/internal/impl/aws"
+	"github.com/benthosdev/benthos/v4/internal/impl/llm"
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func init() {
+	llm.BedrockSignerFn = func(conf *service.ParsedConfig) (llm.BedrockSigner, string, error) {
+		sess, err := baws.GetSession(context.TODO(), conf)
+		if err != nil {
+			return nil, "", err
+		}
+
+		signer := v4.NewSigner()
+		return func(ctx context.Context, req *http.Request, body []byte) error {
+			creds, err := sess.Credentials.Retrieve(ctx)
+			if err != nil {
+				return err
+			}
+			hash := sha256.Sum256(body)
+			return signer.SignHTTP(ctx, creds, req, hex.EncodeToString(hash[:]), "bedrock", sess.Region, time.Now())
+		}, sess.Region, nil
+	}
+}
@@ -0,0 +1,139 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	rcFieldMaxRetries  = "max_retries"
+	rcFieldBackoff     = "backoff"
+	rcFieldMaxInFlight = "max_in_flight"
+	rcFieldTimeout     = "timeout"
+)
+
+// requestClientFields are the fields shared by processors that make requests
+// to model APIs.
+func requestClientFields() []*service.ConfigField {
+	return []*service.ConfigField{
+		service.NewIntField(rcFieldMaxRetries).
+			Description("The maximum number of times to retry a request that is rate limited or fails with a server error.").
+			Default(3).
+			Advanced(),
+		service.NewDurationField(rcFieldBackoff).
+			Description("The initial period to wait before retrying a request, which is doubled with each retry.").
+			Default("1s").
+			Advanced(),
+		service.NewIntField(rcFieldMaxInFlight).
+			Description("The maximum number of requests to have in flight at a given time.").
+			Default(4),
+		service.NewDurationField(rcFieldTimeout).
+			Description("The maximum period to wait for each request.").
+			Default("60s").
+			Advanced(),
+	}
+}
+
+// requestClient makes requests to model APIs, limiting the number of requests
+// in flight and retrying requests that are rate limited.
+type requestClient struct {
+	client     *http.Client
+	maxRetries int
+	backoff    time.Duration
+	sem        chan struct{}
+	log        *service.Logger
+}
+
+func requestClientFromConfig(conf *service.ParsedConfig, log *service.Logger) (*requestClient, error) {
+	c := &requestClient{log: log}
+
+	var err error
+	if c.maxRetries, err = conf.FieldInt(rcFieldMaxRetries); err != nil {
+		return nil, err
+	}
+	if c.backoff, err = conf.FieldDuration(rcFieldBackoff); err != nil {
+		return nil, err
+	}
+
+	maxInFlight, err := conf.FieldInt(rcFieldMaxInFlight)
+	if err != nil {
+		return nil, err
+	}
+	if maxInFlight < 1 {
+		return nil, fmt.Errorf("max_in_flight must be at least 1, got %v", maxInFlight)
+	}
+	c.sem = make(chan struct{}, maxInFlight)
+
+	timeout, err := conf.FieldDuration(rcFieldTimeout)
+	if err != nil {
+		return nil, err
+	}
+	c.client = &http.Client{Timeout: timeout}
+	return c, nil
+}
+
+// retryAfter returns the period to wait before retrying a response, which is
+// the Retry-After header when present and otherwise the backoff of the attempt.
+func (c *requestClient) retryAfter(res *http.Response, attempt int) time.Duration {
+	if s := res.Header.Get("Retry-After"); s != "" {
+		if secs, err := strconv.Atoi(s); err == nil && secs >= 0 {
+			return time.Duration(secs) * time.Second
+		}
+		if t, err := http.ParseTime(s); err == nil {
+			return time.Until(t)
+		}
+	}
+	return c.backoff << attempt
+}
+
+// do executes a request, returning the body of a successful response. Requests
+// are created for each attempt as bodies can only be read once.
+func (c *requestClient) do(ctx context.Context, newReq func() (*http.Request, error)) ([]byte, error) {
+	select {
+	case c.sem <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	defer func() { <-c.sem }()
+
+	for attempt := 0; ; attempt++ {
+		hReq, err := newReq()
+		if err != nil {
+			return nil, err
+		}
+
+		res, err := c.client.Do(hReq)
+		if err != nil {
+			return nil, err
+		}
+		body, err := io.ReadAll(res.Body)
+		_ = res.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		if res.StatusCode >= 200 && res.StatusCode < 300 {
+			return body, nil
+		}
+
+		err = fmt.Errorf("status code %v: %s", res.StatusCode, bytes.TrimSpace(body))
+		if (res.StatusCode != http.StatusTooManyRequests && res.StatusCode < 500) || attempt >= c.maxRetries {
+			return nil, err
+		}
+
+		wait := c.retryAfter(res, attempt)
+		c.log.Debugf("Retrying request in %v: %v", wait, err)
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// embedder translates embeddings requests for a batch of texts into the API of
+// an embeddings service.
+type embedder interface {
+	newRequest(ctx context.Context, texts []string) (*http.Request, error)
+	parseResponse(body []byte) ([][]float64, error)
+}
+
+// openAIEmbedder targets the embeddings API of OpenAI, or of any service
+// compatible with it, which includes most local embeddings servers.
+type openAIEmbedder struct {
+	url        string
+	model      string
+	apiKey     string
+	dimensions int
+}
+
+func newOpenAIEmbedder(baseURL, model, apiKey string, dimensions int) (*openAIEmbedder, error) {
+	u, err := url.JoinPath(baseURL, "embeddings")
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse url: %w", err)
+	}
+	return &openAIEmbedder{url: u, model: model, apiKey: apiKey, dimensions: dimensions}, nil
+}
+
+func (o *openAIEmbedder) newRequest(ctx context.Context, texts []string) (*http.Request, error) {
+	body := map[string]any{
+		"model": o.model,
+		"input": texts,
+	}
+	if o.dimensions > 0 {
+		body["dimensions"] = o.dimensions
+	}
+	hReq, _, err := newJSONRequest(ctx, o.url, body)
+	if err != nil {
+		return nil, err
+	}
+	if o.apiKey != "" {
+		hReq.Header.Set("Authorization", "Bearer "+o.apiKey)
+	}
+	return hReq, nil
+}
+
+func (o *openAIEmbedder) parseResponse(body []byte) ([][]float64, error) {
+	var payload struct {
+		Data []struct {
+			Index     int       `json:"index"`
+			Embedding []float64 `json:"embedding"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, err
+	}
+	vectors := make([][]float64, len(payload.Data))
+	for _, d := range payload.Data {
+		if d.Index < 0 || d.Index >= len(vectors) {
+			return nil, fmt.Errorf("response contained an embedding of unexpected index %v", d.Index)
+		}
+		vectors[d.Index] = d.Embedding
+	}
+	return vectors, nil
+}
+
+//------------------------------------------------------------------------------
+
+// cohereEmbedder targets the embed API of Cohere.
+type cohereEmbedder struct {
+	url       string
+	model     string
+	apiKey    string
+	inputType string
+}
+
+func newCohereEmbedder(baseURL, model, apiKey, inputType string) (*cohereEmbedder, error) {
+	u, err := url.JoinPath(baseURL, "embed")
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse url: %w", err)
+	}
+	return &cohereEmbedder{url: u, model: model, apiKey: apiKey, inputType: inputType}, nil
+}
+
+func (c *cohereEmbedder) newRequest(ctx context.Context, texts []string) (*http.Request, error) {
+	hReq, _, err := newJSONRequest(ctx, c.url, map[string]any{
+		"model":      c.model,
+		"texts":      texts,
+		"input_type": c.inputType,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if c.apiKey != "" {
+		hReq.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+	return hReq, nil
+}
+
+func (c *cohereEmbedder) parseResponse(body []byte) ([][]float64, error) {
+	var payload struct {
+		Embeddings [][]float64 `json:"embeddings"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, err
+	}
+	return payload.Embeddings, nil
+}
+
+//------------------------------------------------------------------------------
+
+// ollamaEmbedder targets the embed API of an Ollama server.
+type ollamaEmbedder struct {
+	url   string
+	model string
+}
+
+func newOllamaEmbedder(baseURL, model string) (*ollamaEmbedder, error) {
+	u, err := url.JoinPath(baseURL, "api/embed")
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse url: %w", err)
+	}
+	return &ollamaEmbedder{url: u, model: model}, nil
+}
+
+func (o *ollamaEmbedder) newRequest(ctx context.Context, texts []string) (*http.Request, error) {
+	hReq, _, err := newJSONRequest(ctx, o.url, map[string]any{
+		"model": o.model,
+		"input": texts,
+	})
+	return hReq, err
+}
+
+func (o *ollamaEmbedder) parseResponse(body []byte) ([][]float64, error) {
+	var payload struct {
+		Embeddings [][]float64 `json:"embeddings"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, err
+	}
+	return payload.Embeddings, nil
+}
@@ -0,0 +1,397 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/benthosdev/benthos/v4/public/bloblang"
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	epFieldProvider     = "provider"
+	epFieldModel        = "model"
+	epFieldURL          = "url"
+	epFieldAPIKey       = "api_key"
+	epFieldDimensions   = "dimensions"
+	epFieldInputType    = "input_type"
+	epFieldText         = "text"
+	epFieldBatchSize    = "batch_size"
+	epFieldChunkSize    = "chunk_size"
+	epFieldChunkOverlap = "chunk_overlap"
+	epFieldResultMap    = "result_map"
+)
+
+func embeddingsProcSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Version("4.28.0").
+		Categories("Integration").
+		Summary("Generates vector embeddings of a text resolved from each message, sending the texts of a batch to an embeddings API in as few requests as possible.").
+		Description(`
+The embedding of each message is attached to it with the `+"`result_map`"+` mapping, where `+"`this`"+` refers to an object containing the fields `+"`vector`"+`, `+"`text`"+`, `+"`index`"+` and `+"`count`"+`, and `+"`root`"+` refers to the message. By default the vector is assigned to the field `+"`embedding`"+` of the message, which can then be written to vector databases such as the `+"[`qdrant`](/docs/components/outputs/qdrant)"+`, `+"[`pinecone`](/docs/components/outputs/pinecone)"+` and `+"[`milvus`](/docs/components/outputs/milvus)"+` outputs.
+
+The following providers are supported:
+
+- `+"`openai`"+`: The embeddings API of OpenAI, or of any service compatible with it when a `+"`url`"+` is set, which includes most local embeddings servers.
+- `+"`cohere`"+`: The embed API of Cohere.
+- `+"`ollama`"+`: The embed API of an Ollama server.
+
+### Chunking
+
+Embedding models have a limited context and long documents are often better represented as several embeddings. When a `+"`chunk_size`"+` is set texts longer than it are split into chunks of at most that many characters, preferring to split at whitespace, with each chunk overlapping the previous one by `+"`chunk_overlap`"+` characters. A copy of the message is emitted for each chunk, where `+"`this.text`"+` of the `+"`result_map`"+` refers to the text of the chunk, and the following metadata fields are added to each message:
+
+- `+"`embedding_chunk_index`"+`: The index of the chunk, starting at zero.
+- `+"`embedding_chunk_count`"+`: The number of chunks of the text.
+
+Messages are flagged as having failed when their text cannot be resolved or the request containing them fails, in which case they are passed through unchanged.`).
+		Fields(
+			service.NewStringAnnotatedEnumField(epFieldProvider, map[string]string{
+				"openai": "OpenAI, or services compatible with the OpenAI embeddings API.",
+				"cohere": "Cohere.",
+				"ollama": "An Ollama server.",
+			}).
+				Description("The provider of the model."),
+			service.NewStringField(epFieldModel).
+				Description("The embeddings model to use.").
+				Examples("text-embedding-3-small", "embed-english-v3.0", "nomic-embed-text"),
+			service.NewURLField(epFieldURL).
+				Description("The base URL of the provider API. Defaults to `https://api.openai.com/v1` for `openai`, `https://api.cohere.com/v1` for `cohere` and `http://localhost:11434` for `ollama`.").
+				Optional(),
+			service.NewStringField(epFieldAPIKey).
+				Description("The API key used to authenticate with the provider.").
+				Secret().
+				Default(""),
+			service.NewIntField(epFieldDimensions).
+				Description("The number of dimensions of embeddings, for `openai` models that support shortening embeddings.").
+				Optional().
+				Advanced(),
+			service.NewStringField(epFieldInputType).
+				Description("The type of input of `cohere` requests, which should be `search_query` when embedding queries rather than documents.").
+				Default("search_document").
+				Advanced(),
+			service.NewInterpolatedStringField(epFieldText).
+				Description("The text to embed, which is interpolated from each message.").
+				Default("${! content() }").
+				Example(`${! json("body") }`),
+			service.NewIntField(epFieldBatchSize).
+				Description("The maximum number of texts to send in each request.").
+				Default(64),
+			service.NewIntField(epFieldChunkSize).
+				Description("The maximum number of characters of each text, where longer texts are split into chunks. Set to zero in order to disable chunking.").
+				Default(0),
+			service.NewIntField(epFieldChunkOverlap).
+				Description("The number of characters that each chunk overlaps the previous chunk of a text by.").
+				Default(0).
+				Advanced(),
+			service.NewBloblangField(epFieldResultMap).
+				Description("A [Bloblang mapping](/docs/guides/bloblang/about) that describes how the embedding should be mapped onto the message, where `this` refers to the embedding and `root` refers to the message.").
+				Default("root.embedding = this.vector").
+				Example(`root = {"text": this.text, "embedding": this.vector}`),
+		).
+		Fields(requestClientFields()...).
+		Example("Indexing Documents", "Here we split long documents into chunks, embed each chunk with OpenAI and upsert the chunks into Qdrant.", `
+pipeline:
+  processors:
+    - embeddings:
+        provider: openai
+        model: text-embedding-3-small
+        api_key: ${OPENAI_API_KEY}
+        text: ${! json("body") }
+        chunk_size: 2000
+        chunk_overlap: 200
+        result_map: |
+          root.chunk = this.text
+          root.embedding = this.vector
+
+output:
+  qdrant:
+    url: http://localhost:6333
+    collection: documents
+    id_mapping: root = uuid_v4()
+    vector_mapping: root = this.embedding
+    payload_mapping: root = this.without("embedding", "body")
+`)
+}
+
+func init() {
+	err := service.RegisterBatchProcessor(
+		"embeddings", embeddingsProcSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.BatchProcessor, error) {
+			return newEmbeddingsProcFromConfig(conf, mgr)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type embeddingsProc struct {
+	embedder     embedder
+	text         *service.InterpolatedString
+	batchSize    int
+	chunkSize    int
+	chunkOverlap int
+	resultMap    *bloblang.Executor
+	client       *requestClient
+}
+
+func newEmbeddingsProcFromConfig(conf *service.ParsedConfig, mgr *service.Resources) (*embeddingsProc, error) {
+	p := &embeddingsProc{}
+
+	providerStr, err := conf.FieldString(epFieldProvider)
+	if err != nil {
+		return nil, err
+	}
+	model, err := conf.FieldString(epFieldModel)
+	if err != nil {
+		return nil, err
+	}
+	var urlStr string
+	if conf.Contains(epFieldURL) {
+		if urlStr, err = conf.FieldString(epFieldURL); err != nil {
+			return nil, err
+		}
+	}
+	apiKey, err := conf.FieldString(epFieldAPIKey)
+	if err != nil {
+		return nil, err
+	}
+
+	switch providerStr {
+	case "openai":
+		if urlStr == "" {
+			urlStr = "https://api.openai.com/v1"
+		}
+		var dimensions int
+		if conf.Contains(epFieldDimensions) {
+			if dimensions, err = conf.FieldInt(epFieldDimensions); err != nil {
+				return nil, err
+			}
+		}
+		p.embedder, err = newOpenAIEmbedder(urlStr, model, apiKey, dimensions)
+	case "cohere":
+		if urlStr == "" {
+			urlStr = "https://api.cohere.com/v1"
+		}
+		var inputType string
+		if inputType, err = conf.FieldString(epFieldInputType); err != nil {
+			return nil, err
+		}
+		p.embedder, err = newCohereEmbedder(urlStr, model, apiKey, inputType)
+	case "ollama":
+		if urlStr == "" {
+			urlStr = "http://localhost:11434"
+		}
+		p.embedder, err = newOllamaEmbedder(urlStr, model)
+	default:
+		return nil, fmt.Errorf("unrecognised provider: %v", providerStr)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if p.text, err = conf.FieldInterpolatedString(epFieldText); err != nil {
+		return nil, err
+	}
+	if p.batchSize, err = conf.FieldInt(epFieldBatchSize); err != nil {
+		return nil, err
+	}
+	if p.batchSize < 1 {
+		return nil, fmt.Errorf("batch_size must be at least 1, got %v", p.batchSize)
+	}
+	if p.chunkSize, err = conf.FieldInt(epFieldChunkSize); err != nil {
+		return nil, err
+	}
+	if p.chunkOverlap, err = conf.FieldInt(epFieldChunkOverlap); err != nil {
+		return nil, err
+	}
+	if p.chunkSize < 0 || p.chunkOverlap < 0 {
+		return nil, errors.New("chunk_size and chunk_overlap must not be negative")
+	}
+	if p.chunkSize > 0 && p.chunkOverlap >= p.chunkSize {
+		return nil, fmt.Errorf("chunk_overlap must be less than chunk_size, got %v", p.chunkOverlap)
+	}
+	if p.resultMap, err = conf.FieldBloblang(epFieldResultMap); err != nil {
+		return nil, err
+	}
+	if p.client, err = requestClientFromConfig(conf, mgr.Logger()); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// chunkText splits a text into chunks of at most size characters, preferring
+// to split at whitespace within the latter half of a chunk, where each chunk
+// begins overlap characters before the end of the previous chunk.
+func chunkText(s string, size, overlap int) []string {
+	runes := []rune(s)
+	if size <= 0 || len(runes) <= size {
+		if strings.TrimSpace(s) == "" {
+			return nil
+		}
+		return []string{s}
+	}
+
+	var chunks []string
+	for start := 0; start < len(runes); {
+		end := start + size
+		if end >= len(runes) {
+			end = len(runes)
+		} else {
+			for i := end; i > start+size/2; i-- {
+				if unicode.IsSpace(runes[i]) {
+					end = i
+					break
+				}
+			}
+		}
+		if chunk := strings.TrimSpace(string(runes[start:end])); chunk != "" {
+			chunks = append(chunks, chunk)
+		}
+		if end == len(runes) {
+			break
+		}
+		next := end - overlap
+		if next <= start {
+			next = end
+		}
+		start = next
+	}
+	return chunks
+}
+
+type embeddingItem struct {
+	msgIndex int
+	text     string
+	vector   []float64
+}
+
+func (p *embeddingsProc) ProcessBatch(ctx context.Context, batch service.MessageBatch) ([]service.MessageBatch, error) {
+	// The items of each message, which are resolved before any requests are
+	// made so that texts of the batch are sent in as few requests as possible.
+	msgItems := make([][]*embeddingItem, len(batch))
+	var items []*embeddingItem
+	for i, msg := range batch {
+		text, err := batch.TryInterpolatedString(i, p.text)
+		if err != nil {
+			msg.SetError(fmt.Errorf("text interpolation error: %w", err))
+			continue
+		}
+		chunks := chunkText(text, p.chunkSize, p.chunkOverlap)
+		if len(chunks) == 0 {
+			msg.SetError(errors.New("text to embed is empty"))
+			continue
+		}
+		for _, c := range chunks {
+			item := &embeddingItem{msgIndex: i, text: c}
+			msgItems[i] = append(msgItems[i], item)
+			items = append(items, item)
+		}
+	}
+
+	msgErrs := make([]error, len(batch))
+	var errMut sync.Mutex
+	var wg sync.WaitGroup
+	for start := 0; start < len(items); start += p.batchSize {
+		end := start + p.batchSize
+		if end > len(items) {
+			end = len(items)
+		}
+		group := items[start:end]
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := p.embed(ctx, group); err != nil {
+				errMut.Lock()
+				for _, item := range group {
+					msgErrs[item.msgIndex] = err
+				}
+				errMut.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	var resBatch service.MessageBatch
+	for i, msg := range batch {
+		if msgErrs[i] != nil {
+			msg.SetError(msgErrs[i])
+		}
+		if len(msgItems[i]) == 0 || msgErrs[i] != nil {
+			resBatch = append(resBatch, msg)
+			continue
+		}
+		count := len(msgItems[i])
+		for j, item := range msgItems[i] {
+			vec := make([]any, len(item.vector))
+			for k, v := range item.vector {
+				vec[k] = v
+			}
+			embedding := service.NewMessage(nil)
+			embedding.SetStructuredMut(map[string]any{
+				"vector": vec,
+				"text":   item.text,
+				"index":  int64(j),
+				"count":  int64(count),
+			})
+
+			target := msg
+			if count > 1 {
+				target = msg.Copy()
+			}
+			if p.chunkSize > 0 {
+				target.MetaSetMut("embedding_chunk_index", int64(j))
+				target.MetaSetMut("embedding_chunk_count", int64(count))
+			}
+			res, err := target.BloblangMutateFrom(p.resultMap, embedding)
+			if err != nil {
+				target.SetError(fmt.Errorf("result mapping failed: %w", err))
+				resBatch = append(resBatch, target)
+				continue
+			}
+			if res != nil {
+				resBatch = append(resBatch, res)
+			}
+		}
+	}
+	return []service.MessageBatch{resBatch}, nil
+}
+
+// embed requests the embeddings of a group of items, setting the vector of
+// each item.
+func (p *embeddingsProc) embed(ctx context.Context, group []*embeddingItem) error {
+	texts := make([]string, len(group))
+	for i, item := range group {
+		texts[i] = item.text
+	}
+	body, err := p.client.do(ctx, func() (*http.Request, error) {
+		return p.embedder.newRequest(ctx, texts)
+	})
+	if err != nil {
+		return err
+	}
+	vectors, err := p.embedder.parseResponse(body)
+	if err != nil {
+		return err
+	}
+	if len(vectors) != len(group) {
+		return fmt.Errorf("expected %v embeddings in response, got %v", len(group), len(vectors))
+	}
+	for i, v := range vectors {
+		if len(v) == 0 {
+			return fmt.Errorf("response contained an empty embedding at index %v", i)
+		}
+		group[i].vector = v
+	}
+	return nil
+}
+
+func (p *embeddingsProc) Close(ctx context.Context) error {
+	return nil
+}
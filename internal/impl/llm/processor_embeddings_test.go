@@ -0,0 +1,212 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func testEmbeddingsProc(t *testing.T, confStr string) *embeddingsProc {
+	t.Helper()
+
+	conf, err := embeddingsProcSpec().ParseYAML(confStr, nil)
+	require.NoError(t, err)
+
+	proc, err := newEmbeddingsProcFromConfig(conf, service.MockResources())
+	require.NoError(t, err)
+	return proc
+}
+
+// fakeVector derives a vector from the length of a text.
+func fakeVector(text string) []float64 {
+	return []float64{float64(len(text)), 1}
+}
+
+func stringsOf(v any) []string {
+	var strs []string
+	for _, s := range v.([]any) {
+		strs = append(strs, s.(string))
+	}
+	return strs
+}
+
+func TestChunkText(t *testing.T) {
+	for _, test := range []struct {
+		text    string
+		size    int
+		overlap int
+		chunks  []string
+	}{
+		{text: "hello world", size: 0, chunks: []string{"hello world"}},
+		{text: "hello world", size: 20, chunks: []string{"hello world"}},
+		{text: "   ", size: 0},
+		{
+			text:   "the quick brown fox jumps over the lazy dog",
+			size:   15,
+			chunks: []string{"the quick brown", "fox jumps over", "the lazy dog"},
+		},
+		{
+			text:    "abcdefghijklmnopqrstuvwxyz",
+			size:    10,
+			overlap: 2,
+			chunks:  []string{"abcdefghij", "ijklmnopqr", "qrstuvwxyz"},
+		},
+	} {
+		assert.Equal(t, test.chunks, chunkText(test.text, test.size, test.overlap), test.text)
+	}
+}
+
+func TestEmbeddingsOpenAI(t *testing.T) {
+	ts, reqs := testServer(t, func(req recordedRequest, w http.ResponseWriter) {
+		var data []any
+		// Embeddings are returned out of order in order to test that they are
+		// matched by index.
+		texts := stringsOf(req.body["input"])
+		for i := len(texts) - 1; i >= 0; i-- {
+			data = append(data, map[string]any{"index": i, "embedding": fakeVector(texts[i])})
+		}
+		b, _ := json.Marshal(map[string]any{"data": data})
+		_, _ = w.Write(b)
+	})
+
+	proc := testEmbeddingsProc(t, `
+provider: openai
+model: text-embedding-3-small
+url: `+ts.URL+`
+api_key: foo
+text: ${! json("text") }
+dimensions: 2
+batch_size: 2
+`)
+
+	batch := service.MessageBatch{
+		service.NewMessage([]byte(`{"text":"a"}`)),
+		service.NewMessage([]byte(`{"text":"bb"}`)),
+		service.NewMessage([]byte(`not json`)),
+		service.NewMessage([]byte(`{"text":"ccc"}`)),
+	}
+	res, err := proc.ProcessBatch(context.Background(), batch)
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+	require.Len(t, res[0], 4)
+
+	for i, exp := range map[int]string{
+		0: `{"embedding":[1,1],"text":"a"}`,
+		1: `{"embedding":[2,1],"text":"bb"}`,
+		3: `{"embedding":[3,1],"text":"ccc"}`,
+	} {
+		b, err := res[0][i].AsBytes()
+		require.NoError(t, err)
+		assert.JSONEq(t, exp, string(b), i)
+	}
+
+	b, err := res[0][2].AsBytes()
+	require.NoError(t, err)
+	assert.Equal(t, "not json", string(b))
+	assert.Error(t, res[0][2].GetError())
+
+	r := reqs()
+	require.Len(t, r, 2)
+	assert.Equal(t, "/embeddings", r[0].path)
+	assert.Equal(t, "Bearer foo", r[0].header.Get("Authorization"))
+	assert.Equal(t, 2.0, r[0].body["dimensions"])
+	assert.Equal(t, "text-embedding-3-small", r[0].body["model"])
+
+	var texts []string
+	for _, req := range r {
+		texts = append(texts, stringsOf(req.body["input"])...)
+	}
+	assert.ElementsMatch(t, []string{"a", "bb", "ccc"}, texts)
+}
+
+func TestEmbeddingsCohereChunks(t *testing.T) {
+	ts, reqs := testServer(t, func(req recordedRequest, w http.ResponseWriter) {
+		var embeddings [][]float64
+		for _, text := range stringsOf(req.body["texts"]) {
+			embeddings = append(embeddings, fakeVector(text))
+		}
+		b, _ := json.Marshal(map[string]any{"embeddings": embeddings})
+		_, _ = w.Write(b)
+	})
+
+	proc := testEmbeddingsProc(t, `
+provider: cohere
+model: embed-english-v3.0
+url: `+ts.URL+`
+chunk_size: 15
+result_map: |
+  root.chunk = this.text
+  root.vector = this.vector
+  root.of = this.count
+`)
+
+	res, err := proc.ProcessBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte(`the quick brown fox jumps over`)),
+	})
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+	require.Len(t, res[0], 2)
+
+	for i, exp := range []string{
+		`{"chunk":"the quick brown","vector":[15,1],"of":2}`,
+		`{"chunk":"fox jumps over","vector":[14,1],"of":2}`,
+	} {
+		b, err := res[0][i].AsBytes()
+		require.NoError(t, err)
+		assert.JSONEq(t, exp, string(b))
+
+		v, _ := res[0][i].MetaGetMut("embedding_chunk_index")
+		assert.Equal(t, int64(i), v)
+		v, _ = res[0][i].MetaGetMut("embedding_chunk_count")
+		assert.Equal(t, int64(2), v)
+	}
+
+	r := reqs()
+	require.Len(t, r, 1)
+	assert.Equal(t, "/embed", r[0].path)
+	assert.Equal(t, "search_document", r[0].body["input_type"])
+}
+
+func TestEmbeddingsOllamaErrors(t *testing.T) {
+	ts, _ := testServer(t, func(req recordedRequest, w http.ResponseWriter) {
+		if stringsOf(req.body["input"])[0] == "bad" {
+			http.Error(w, "nope", http.StatusBadRequest)
+			return
+		}
+		_, _ = w.Write([]byte(`{"embeddings":[[0.5,0.25]]}`))
+	})
+
+	proc := testEmbeddingsProc(t, `
+provider: ollama
+model: nomic-embed-text
+url: `+ts.URL+`
+batch_size: 1
+result_map: root = this.vector
+`)
+
+	res, err := proc.ProcessBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte(`good`)),
+		service.NewMessage([]byte(`bad`)),
+		service.NewMessage([]byte(` `)),
+	})
+	require.NoError(t, err)
+	require.Len(t, res[0], 3)
+
+	b, err := res[0][0].AsBytes()
+	require.NoError(t, err)
+	assert.Equal(t, `[0.5,0.25]`, string(b))
+	assert.NoError(t, res[0][0].GetError())
+
+	b, err = res[0][1].AsBytes()
+	require.NoError(t, err)
+	assert.Equal(t, `bad`, string(b))
+	assert.ErrorContains(t, res[0][1].GetError(), "400")
+
+	assert.ErrorContains(t, res[0][2].GetError(), "empty")
+}
@@ -0,0 +1,308 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/benthosdev/benthos/v4/internal/impl/aws/config"
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	lpFieldProvider       = "provider"
+	lpFieldModel          = "model"
+	lpFieldURL            = "url"
+	lpFieldAPIKey         = "api_key"
+	lpFieldAPIVersion     = "api_version"
+	lpFieldSystemPrompt   = "system_prompt"
+	lpFieldPrompt         = "prompt"
+	lpFieldMaxTokens      = "max_tokens"
+	lpFieldTemperature    = "temperature"
+	lpFieldResponseFormat = "response_format"
+	lpFieldAWS            = "aws"
+)
+
+// BedrockSigner signs a request to the Bedrock runtime API with the body of
+// the request.
+type BedrockSigner func(ctx context.Context, req *http.Request, body []byte) error
+
+func notImportedBedrockSignerFn(conf *service.ParsedConfig) (BedrockSigner, string, error) {
+	return nil, "", errors.New("unable to configure AWS authentication as this binary does not import components/aws")
+}
+
+// BedrockSignerFn is populated with the child `aws` package when imported, and
+// returns a request signer along with the region of the session.
+var BedrockSignerFn = notImportedBedrockSignerFn
+
+// AWSField represents the aws block within the llm processor. This is exported
+// in order to make unit testing easier within the aws subpackage.
+func AWSField() *service.ConfigField {
+	return service.NewObjectField(lpFieldAWS, config.SessionFields()...).
+		Description("AWS session configuration used to sign requests when the provider is `bedrock`.").
+		Advanced()
+}
+
+func llmProcSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Version("4.28.0").
+		Categories("Integration").
+		Summary("Generates a completion from a large language model for each message, using a prompt templated from the message.").
+		Description(`
+The response of the model replaces the contents of the message, and therefore in order to enrich a message with a completion this processor should be wrapped within a `+"[`branch` processor](/docs/components/processors/branch)"+`.
+
+The following providers are supported:
+
+- `+"`openai`"+`: The chat completions API of OpenAI, or of any service compatible with it when a `+"`url`"+` is set.
+- `+"`azure_openai`"+`: The chat completions API of an Azure OpenAI resource, where the `+"`url`"+` is the endpoint of the resource and the `+"`model`"+` is the name of a deployment.
+- `+"`bedrock`"+`: The Converse API of Amazon Bedrock, where requests are signed with the credentials of the `+"`aws`"+` field.
+- `+"`ollama`"+`: The chat API of an Ollama server.
+
+### Response Format
+
+When the `+"`response_format`"+` is `+"`json`"+` the model is requested to respond with a JSON object, which is parsed into the structured contents of the message. Responses that cannot be parsed flag the message as having failed.
+
+### Metadata
+
+The following metadata fields are added to each message:
+
+- `+"`llm_model`"+`: The model that generated the response.
+- `+"`llm_finish_reason`"+`: The reason that the model stopped generating, such as when the maximum number of tokens was reached.
+- `+"`llm_prompt_tokens`"+`: The number of tokens consumed by the prompt.
+- `+"`llm_completion_tokens`"+`: The number of tokens generated by the model.
+- `+"`llm_total_tokens`"+`: The sum of the prompt and completion tokens.
+
+### Rate Limits
+
+Requests that are rate limited with a 429 status code, or fail with a 5XX status code, are retried up to `+"`max_retries`"+` times with an exponential backoff, which respects the `+"`Retry-After`"+` header when provided by the service. The number of requests made concurrently by each instance of the processor is limited by `+"`max_in_flight`"+`, which is shared across all threads of the pipeline.`).
+		Fields(
+			service.NewStringAnnotatedEnumField(lpFieldProvider, map[string]string{
+				"openai":       "OpenAI, or services compatible with the OpenAI chat completions API.",
+				"azure_openai": "Azure OpenAI deployments.",
+				"bedrock":      "Amazon Bedrock.",
+				"ollama":       "An Ollama server.",
+			}).
+				Description("The provider of the model."),
+			service.NewStringField(lpFieldModel).
+				Description("The model to use, which is the name of a deployment when the provider is `azure_openai`.").
+				Examples("gpt-4o-mini", "anthropic.claude-3-haiku-20240307-v1:0", "llama3"),
+			service.NewURLField(lpFieldURL).
+				Description("The base URL of the provider API. Defaults to `https://api.openai.com/v1` for `openai`, the regional runtime endpoint for `bedrock` and `http://localhost:11434` for `ollama`, and is required for `azure_openai`.").
+				Example("https://example.openai.azure.com").
+				Optional(),
+			service.NewStringField(lpFieldAPIKey).
+				Description("The API key used to authenticate with `openai` and `azure_openai` providers.").
+				Secret().
+				Default(""),
+			service.NewStringField(lpFieldAPIVersion).
+				Description("The API version of `azure_openai` requests.").
+				Default("2024-02-01").
+				Advanced(),
+			service.NewInterpolatedStringField(lpFieldSystemPrompt).
+				Description("An optional system prompt, which is interpolated from each message.").
+				Optional(),
+			service.NewInterpolatedStringField(lpFieldPrompt).
+				Description("The prompt, which is interpolated from each message.").
+				Default("${! content() }").
+				Example(`Summarise the following review in one sentence: ${! json("review") }`),
+			service.NewIntField(lpFieldMaxTokens).
+				Description("The maximum number of tokens to generate.").
+				Optional(),
+			service.NewFloatField(lpFieldTemperature).
+				Description("The sampling temperature of the model, where lower values produce more deterministic responses.").
+				Optional(),
+			service.NewStringAnnotatedEnumField(lpFieldResponseFormat, map[string]string{
+				"text": "The response is written to the message as is.",
+				"json": "The model is requested to respond with a JSON object, which is parsed into the message.",
+			}).
+				Description("The format of responses.").
+				Default("text"),
+			AWSField(),
+		).
+		Fields(requestClientFields()...).
+		Example("Classification", "Here we classify the sentiment of reviews with a model served by Ollama, adding the result to each review as a structured object.", `
+pipeline:
+  processors:
+    - branch:
+        processors:
+          - llm:
+              provider: ollama
+              model: llama3
+              system_prompt: 'Classify the sentiment of reviews, responding with a JSON object of the form {"sentiment":"positive|negative|neutral"}.'
+              prompt: ${! json("review") }
+              response_format: json
+        result_map: root.classification = this
+`)
+}
+
+func init() {
+	err := service.RegisterProcessor(
+		"llm", llmProcSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Processor, error) {
+			return newLLMProcFromConfig(conf, mgr)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type llmProc struct {
+	provider    provider
+	system      *service.InterpolatedString
+	prompt      *service.InterpolatedString
+	maxTokens   int
+	temperature *float64
+	jsonMode    bool
+	client      *requestClient
+}
+
+func newLLMProcFromConfig(conf *service.ParsedConfig, mgr *service.Resources) (*llmProc, error) {
+	p := &llmProc{}
+
+	providerStr, err := conf.FieldString(lpFieldProvider)
+	if err != nil {
+		return nil, err
+	}
+	model, err := conf.FieldString(lpFieldModel)
+	if err != nil {
+		return nil, err
+	}
+	var urlStr string
+	if conf.Contains(lpFieldURL) {
+		if urlStr, err = conf.FieldString(lpFieldURL); err != nil {
+			return nil, err
+		}
+	}
+	apiKey, err := conf.FieldString(lpFieldAPIKey)
+	if err != nil {
+		return nil, err
+	}
+
+	switch providerStr {
+	case "openai":
+		if urlStr == "" {
+			urlStr = "https://api.openai.com/v1"
+		}
+		p.provider, err = newOpenAIProvider(urlStr, model, apiKey)
+	case "azure_openai":
+		if urlStr == "" {
+			return nil, errors.New("a url is required for the azure_openai provider")
+		}
+		var apiVersion string
+		if apiVersion, err = conf.FieldString(lpFieldAPIVersion); err != nil {
+			return nil, err
+		}
+		p.provider, err = newAzureOpenAIProvider(urlStr, model, apiVersion, apiKey)
+	case "bedrock":
+		signer, region, serr := BedrockSignerFn(conf.Namespace(lpFieldAWS))
+		if serr != nil {
+			return nil, serr
+		}
+		if urlStr == "" {
+			if region == "" {
+				return nil, errors.New("a region or url is required for the bedrock provider")
+			}
+			urlStr = fmt.Sprintf("https://bedrock-runtime.%v.amazonaws.com", region)
+		}
+		p.provider, err = newBedrockProvider(urlStr, model, signer)
+	case "ollama":
+		if urlStr == "" {
+			urlStr = "http://localhost:11434"
+		}
+		p.provider, err = newOllamaProvider(urlStr, model)
+	default:
+		return nil, fmt.Errorf("unrecognised provider: %v", providerStr)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if conf.Contains(lpFieldSystemPrompt) {
+		if p.system, err = conf.FieldInterpolatedString(lpFieldSystemPrompt); err != nil {
+			return nil, err
+		}
+	}
+	if p.prompt, err = conf.FieldInterpolatedString(lpFieldPrompt); err != nil {
+		return nil, err
+	}
+	if conf.Contains(lpFieldMaxTokens) {
+		if p.maxTokens, err = conf.FieldInt(lpFieldMaxTokens); err != nil {
+			return nil, err
+		}
+	}
+	if conf.Contains(lpFieldTemperature) {
+		temp, err := conf.FieldFloat(lpFieldTemperature)
+		if err != nil {
+			return nil, err
+		}
+		p.temperature = &temp
+	}
+
+	responseFormat, err := conf.FieldString(lpFieldResponseFormat)
+	if err != nil {
+		return nil, err
+	}
+	p.jsonMode = responseFormat == "json"
+
+	if p.client, err = requestClientFromConfig(conf, mgr.Logger()); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (p *llmProc) complete(ctx context.Context, req chatRequest) (chatResponse, error) {
+	body, err := p.client.do(ctx, func() (*http.Request, error) {
+		return p.provider.newRequest(ctx, req)
+	})
+	if err != nil {
+		return chatResponse{}, err
+	}
+	return p.provider.parseResponse(body)
+}
+
+func (p *llmProc) Process(ctx context.Context, msg *service.Message) (service.MessageBatch, error) {
+	req := chatRequest{
+		maxTokens:   p.maxTokens,
+		temperature: p.temperature,
+		jsonMode:    p.jsonMode,
+	}
+
+	var err error
+	if p.system != nil {
+		if req.system, err = p.system.TryString(msg); err != nil {
+			return nil, fmt.Errorf("system prompt interpolation error: %w", err)
+		}
+	}
+	if req.prompt, err = p.prompt.TryString(msg); err != nil {
+		return nil, fmt.Errorf("prompt interpolation error: %w", err)
+	}
+
+	res, err := p.complete(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	if p.jsonMode {
+		var v any
+		if err := json.Unmarshal([]byte(res.content), &v); err != nil {
+			return nil, fmt.Errorf("failed to parse response as JSON: %w", err)
+		}
+		msg.SetStructuredMut(v)
+	} else {
+		msg.SetBytes([]byte(res.content))
+	}
+
+	msg.MetaSetMut("llm_model", res.model)
+	msg.MetaSetMut("llm_finish_reason", res.finishReason)
+	msg.MetaSetMut("llm_prompt_tokens", int64(res.promptTokens))
+	msg.MetaSetMut("llm_completion_tokens", int64(res.completionTokens))
+	msg.MetaSetMut("llm_total_tokens", int64(res.promptTokens+res.completionTokens))
+	return service.MessageBatch{msg}, nil
+}
+
+func (p *llmProc) Close(ctx context.Context) error {
+	return nil
+}
@@ -0,0 +1,327 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func testLLMProc(t *testing.T, confStr string) *llmProc {
+	t.Helper()
+
+	conf, err := llmProcSpec().ParseYAML(confStr, nil)
+	require.NoError(t, err)
+
+	proc, err := newLLMProcFromConfig(conf, service.MockResources())
+	require.NoError(t, err)
+	return proc
+}
+
+type recordedRequest struct {
+	path   string
+	query  string
+	header http.Header
+	body   map[string]any
+}
+
+func testServer(t *testing.T, handler func(req recordedRequest, w http.ResponseWriter)) (*httptest.Server, func() []recordedRequest) {
+	t.Helper()
+
+	var mut sync.Mutex
+	var reqs []recordedRequest
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		req := recordedRequest{path: r.URL.Path, query: r.URL.RawQuery, header: r.Header}
+		if err := json.Unmarshal(b, &req.body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		mut.Lock()
+		reqs = append(reqs, req)
+		mut.Unlock()
+		handler(req, w)
+	}))
+	t.Cleanup(ts.Close)
+	return ts, func() []recordedRequest {
+		mut.Lock()
+		defer mut.Unlock()
+		return reqs
+	}
+}
+
+func metaInt(t *testing.T, msg *service.Message, key string) int64 {
+	t.Helper()
+	v, exists := msg.MetaGetMut(key)
+	require.True(t, exists, key)
+	return v.(int64)
+}
+
+func TestLLMOpenAI(t *testing.T) {
+	ts, reqs := testServer(t, func(req recordedRequest, w http.ResponseWriter) {
+		_, _ = w.Write([]byte(`{
+  "model": "gpt-4o-mini-2024",
+  "choices": [{"message": {"role": "assistant", "content": "a summary"}, "finish_reason": "stop"}],
+  "usage": {"prompt_tokens": 12, "completion_tokens": 3, "total_tokens": 15}
+}`))
+	})
+
+	proc := testLLMProc(t, `
+provider: openai
+model: gpt-4o-mini
+url: `+ts.URL+`/v1
+api_key: foo
+system_prompt: You summarise things.
+prompt: 'Summarise: ${! json("text") }'
+max_tokens: 20
+temperature: 0.5
+`)
+
+	res, err := proc.Process(context.Background(), service.NewMessage([]byte(`{"text":"hello world"}`)))
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+
+	b, err := res[0].AsBytes()
+	require.NoError(t, err)
+	assert.Equal(t, "a summary", string(b))
+
+	v, _ := res[0].MetaGetMut("llm_model")
+	assert.Equal(t, "gpt-4o-mini-2024", v)
+	v, _ = res[0].MetaGetMut("llm_finish_reason")
+	assert.Equal(t, "stop", v)
+	assert.Equal(t, int64(12), metaInt(t, res[0], "llm_prompt_tokens"))
+	assert.Equal(t, int64(3), metaInt(t, res[0], "llm_completion_tokens"))
+	assert.Equal(t, int64(15), metaInt(t, res[0], "llm_total_tokens"))
+
+	r := reqs()
+	require.Len(t, r, 1)
+	assert.Equal(t, "/v1/chat/completions", r[0].path)
+	assert.Equal(t, "Bearer foo", r[0].header.Get("Authorization"))
+	assert.Equal(t, map[string]any{
+		"model": "gpt-4o-mini",
+		"messages": []any{
+			map[string]any{"role": "system", "content": "You summarise things."},
+			map[string]any{"role": "user", "content": "Summarise: hello world"},
+		},
+		"max_tokens":  20.0,
+		"temperature": 0.5,
+	}, r[0].body)
+}
+
+func TestLLMAzureOpenAIJSON(t *testing.T) {
+	ts, reqs := testServer(t, func(req recordedRequest, w http.ResponseWriter) {
+		_, _ = w.Write([]byte(`{
+  "model": "gpt-4o",
+  "choices": [{"message": {"role": "assistant", "content": "{\"sentiment\":\"positive\"}"}, "finish_reason": "stop"}],
+  "usage": {"prompt_tokens": 5, "completion_tokens": 4}
+}`))
+	})
+
+	proc := testLLMProc(t, `
+provider: azure_openai
+model: my-deployment
+url: `+ts.URL+`
+api_key: foo
+response_format: json
+`)
+
+	res, err := proc.Process(context.Background(), service.NewMessage([]byte(`great product`)))
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+
+	v, err := res[0].AsStructured()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"sentiment": "positive"}, v)
+	assert.Equal(t, int64(9), metaInt(t, res[0], "llm_total_tokens"))
+
+	r := reqs()
+	require.Len(t, r, 1)
+	assert.Equal(t, "/openai/deployments/my-deployment/chat/completions", r[0].path)
+	assert.Equal(t, "api-version=2024-02-01", r[0].query)
+	assert.Equal(t, "foo", r[0].header.Get("api-key"))
+	assert.Equal(t, map[string]any{"type": "json_object"}, r[0].body["response_format"])
+	_, exists := r[0].body["model"]
+	assert.False(t, exists)
+}
+
+func TestLLMOllama(t *testing.T) {
+	content := "not json"
+	ts, reqs := testServer(t, func(req recordedRequest, w http.ResponseWriter) {
+		b, _ := json.Marshal(map[string]any{
+			"model":             "llama3",
+			"message":           map[string]any{"role": "assistant", "content": content},
+			"done_reason":       "stop",
+			"prompt_eval_count": 7,
+			"eval_count":        2,
+		})
+		_, _ = w.Write(b)
+	})
+
+	proc := testLLMProc(t, `
+provider: ollama
+model: llama3
+url: `+ts.URL+`
+response_format: json
+max_tokens: 10
+`)
+
+	_, err := proc.Process(context.Background(), service.NewMessage([]byte(`hello`)))
+	require.Error(t, err)
+
+	content = `{"a":1}`
+	res, err := proc.Process(context.Background(), service.NewMessage([]byte(`hello`)))
+	require.NoError(t, err)
+	v, err := res[0].AsStructured()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"a": 1.0}, v)
+	assert.Equal(t, int64(7), metaInt(t, res[0], "llm_prompt_tokens"))
+	assert.Equal(t, int64(2), metaInt(t, res[0], "llm_completion_tokens"))
+
+	r := reqs()
+	require.Len(t, r, 2)
+	assert.Equal(t, "/api/chat", r[1].path)
+	assert.Equal(t, map[string]any{
+		"model": "llama3",
+		"messages": []any{
+			map[string]any{"role": "user", "content": "hello"},
+		},
+		"stream":  false,
+		"format":  "json",
+		"options": map[string]any{"num_predict": 10.0},
+	}, r[1].body)
+}
+
+func TestLLMBedrock(t *testing.T) {
+	ts, reqs := testServer(t, func(req recordedRequest, w http.ResponseWriter) {
+		_, _ = w.Write([]byte(`{
+  "output": {"message": {"role": "assistant", "content": [{"text": "hello "}, {"text": "there"}]}},
+  "stopReason": "end_turn",
+  "usage": {"inputTokens": 3, "outputTokens": 2, "totalTokens": 5}
+}`))
+	})
+
+	orig := BedrockSignerFn
+	t.Cleanup(func() { BedrockSignerFn = orig })
+	BedrockSignerFn = func(conf *service.ParsedConfig) (BedrockSigner, string, error) {
+		return func(ctx context.Context, req *http.Request, body []byte) error {
+			req.Header.Set("Authorization", "signed")
+			return nil
+		}, "us-east-1", nil
+	}
+
+	proc := testLLMProc(t, `
+provider: bedrock
+model: anthropic.claude-3-haiku-20240307-v1:0
+url: `+ts.URL+`
+system_prompt: Be brief.
+temperature: 0
+`)
+
+	res, err := proc.Process(context.Background(), service.NewMessage([]byte(`hi`)))
+	require.NoError(t, err)
+	b, err := res[0].AsBytes()
+	require.NoError(t, err)
+	assert.Equal(t, "hello there", string(b))
+	v, _ := res[0].MetaGetMut("llm_finish_reason")
+	assert.Equal(t, "end_turn", v)
+	assert.Equal(t, int64(5), metaInt(t, res[0], "llm_total_tokens"))
+
+	r := reqs()
+	require.Len(t, r, 1)
+	assert.Equal(t, "/model/anthropic.claude-3-haiku-20240307-v1:0/converse", r[0].path)
+	assert.Equal(t, "signed", r[0].header.Get("Authorization"))
+	assert.Equal(t, map[string]any{
+		"messages": []any{
+			map[string]any{"role": "user", "content": []any{map[string]any{"text": "hi"}}},
+		},
+		"system":          []any{map[string]any{"text": "Be brief."}},
+		"inferenceConfig": map[string]any{"temperature": 0.0},
+	}, r[0].body)
+}
+
+func TestLLMRetries(t *testing.T) {
+	var mut sync.Mutex
+	statuses := []int{http.StatusTooManyRequests, http.StatusServiceUnavailable}
+	ts, reqs := testServer(t, func(req recordedRequest, w http.ResponseWriter) {
+		mut.Lock()
+		defer mut.Unlock()
+		if len(statuses) > 0 {
+			code := statuses[0]
+			statuses = statuses[1:]
+			w.Header().Set("Retry-After", "0")
+			http.Error(w, "slow down", code)
+			return
+		}
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"content":"ok"}}]}`))
+	})
+
+	proc := testLLMProc(t, `
+provider: openai
+model: foo
+url: `+ts.URL+`
+max_retries: 2
+backoff: 1ms
+`)
+
+	res, err := proc.Process(context.Background(), service.NewMessage([]byte(`hi`)))
+	require.NoError(t, err)
+	b, err := res[0].AsBytes()
+	require.NoError(t, err)
+	assert.Equal(t, "ok", string(b))
+	assert.Len(t, reqs(), 3)
+
+	// Retries are exhausted.
+	mut.Lock()
+	statuses = []int{429, 429, 429}
+	mut.Unlock()
+	_, err = proc.Process(context.Background(), service.NewMessage([]byte(`hi`)))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "429")
+	assert.Len(t, reqs(), 6)
+
+	// Client errors are not retried.
+	mut.Lock()
+	statuses = []int{http.StatusBadRequest}
+	mut.Unlock()
+	_, err = proc.Process(context.Background(), service.NewMessage([]byte(`hi`)))
+	require.Error(t, err)
+	assert.Len(t, reqs(), 7)
+}
+
+func TestLLMConfigErrors(t *testing.T) {
+	for name, confStr := range map[string]string{
+		"azure without url": `
+provider: azure_openai
+model: foo
+`,
+		"bedrock without aws": `
+provider: bedrock
+model: foo
+`,
+		"zero max in flight": `
+provider: ollama
+model: foo
+max_in_flight: 0
+`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			conf, err := llmProcSpec().ParseYAML(confStr, nil)
+			require.NoError(t, err)
+
+			_, err = newLLMProcFromConfig(conf, service.MockResources())
+			require.Error(t, err)
+		})
+	}
+}
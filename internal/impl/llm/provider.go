@@ -0,0 +1,285 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// chatRequest is a provider agnostic completion request.
+type chatRequest struct {
+	system      string
+	prompt      string
+	maxTokens   int
+	temperature *float64
+	jsonMode    bool
+}
+
+// chatResponse is a provider agnostic completion response.
+type chatResponse struct {
+	content          string
+	model            string
+	finishReason     string
+	promptTokens     int
+	completionTokens int
+}
+
+// provider translates completion requests into the API of an LLM service.
+type provider interface {
+	newRequest(ctx context.Context, req chatRequest) (*http.Request, error)
+	parseResponse(body []byte) (chatResponse, error)
+}
+
+type chatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+func (r chatRequest) messages() []chatMessage {
+	var msgs []chatMessage
+	if r.system != "" {
+		msgs = append(msgs, chatMessage{Role: "system", Content: r.system})
+	}
+	return append(msgs, chatMessage{Role: "user", Content: r.prompt})
+}
+
+func newJSONRequest(ctx context.Context, urlStr string, body any) (*http.Request, []byte, error) {
+	b, err := json.Marshal(body)
+	if err != nil {
+		return nil, nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, urlStr, bytes.NewReader(b))
+	if err != nil {
+		return nil, nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return req, b, nil
+}
+
+//------------------------------------------------------------------------------
+
+// openAIProvider targets the chat completions API of OpenAI, and of Azure
+// OpenAI deployments, which differ only in their URLs and authentication.
+type openAIProvider struct {
+	url    string
+	model  string
+	header string
+	prefix string
+	apiKey string
+}
+
+func newOpenAIProvider(baseURL, model, apiKey string) (*openAIProvider, error) {
+	u, err := url.JoinPath(baseURL, "chat/completions")
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse url: %w", err)
+	}
+	return &openAIProvider{url: u, model: model, header: "Authorization", prefix: "Bearer ", apiKey: apiKey}, nil
+}
+
+func newAzureOpenAIProvider(baseURL, deployment, apiVersion, apiKey string) (*openAIProvider, error) {
+	u, err := url.JoinPath(baseURL, "openai/deployments", url.PathEscape(deployment), "chat/completions")
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse url: %w", err)
+	}
+	u += "?api-version=" + url.QueryEscape(apiVersion)
+	return &openAIProvider{url: u, header: "api-key", apiKey: apiKey}, nil
+}
+
+func (o *openAIProvider) newRequest(ctx context.Context, req chatRequest) (*http.Request, error) {
+	body := map[string]any{
+		"messages": req.messages(),
+	}
+	if o.model != "" {
+		body["model"] = o.model
+	}
+	if req.maxTokens > 0 {
+		body["max_tokens"] = req.maxTokens
+	}
+	if req.temperature != nil {
+		body["temperature"] = *req.temperature
+	}
+	if req.jsonMode {
+		body["response_format"] = map[string]any{"type": "json_object"}
+	}
+
+	hReq, _, err := newJSONRequest(ctx, o.url, body)
+	if err != nil {
+		return nil, err
+	}
+	if o.apiKey != "" {
+		hReq.Header.Set(o.header, o.prefix+o.apiKey)
+	}
+	return hReq, nil
+}
+
+func (o *openAIProvider) parseResponse(body []byte) (res chatResponse, err error) {
+	var payload struct {
+		Model   string `json:"model"`
+		Choices []struct {
+			Message      chatMessage `json:"message"`
+			FinishReason string      `json:"finish_reason"`
+		} `json:"choices"`
+		Usage struct {
+			PromptTokens     int `json:"prompt_tokens"`
+			CompletionTokens int `json:"completion_tokens"`
+		} `json:"usage"`
+	}
+	if err = json.Unmarshal(body, &payload); err != nil {
+		return
+	}
+	if len(payload.Choices) == 0 {
+		err = errors.New("response contained no choices")
+		return
+	}
+	res.content = payload.Choices[0].Message.Content
+	res.finishReason = payload.Choices[0].FinishReason
+	res.model = payload.Model
+	res.promptTokens = payload.Usage.PromptTokens
+	res.completionTokens = payload.Usage.CompletionTokens
+	return
+}
+
+//------------------------------------------------------------------------------
+
+// ollamaProvider targets the chat API of an Ollama server.
+type ollamaProvider struct {
+	url   string
+	model string
+}
+
+func newOllamaProvider(baseURL, model string) (*ollamaProvider, error) {
+	u, err := url.JoinPath(baseURL, "api/chat")
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse url: %w", err)
+	}
+	return &ollamaProvider{url: u, model: model}, nil
+}
+
+func (o *ollamaProvider) newRequest(ctx context.Context, req chatRequest) (*http.Request, error) {
+	options := map[string]any{}
+	if req.maxTokens > 0 {
+		options["num_predict"] = req.maxTokens
+	}
+	if req.temperature != nil {
+		options["temperature"] = *req.temperature
+	}
+	body := map[string]any{
+		"model":    o.model,
+		"messages": req.messages(),
+		"stream":   false,
+		"options":  options,
+	}
+	if req.jsonMode {
+		body["format"] = "json"
+	}
+	hReq, _, err := newJSONRequest(ctx, o.url, body)
+	return hReq, err
+}
+
+func (o *ollamaProvider) parseResponse(body []byte) (res chatResponse, err error) {
+	var payload struct {
+		Model           string      `json:"model"`
+		Message         chatMessage `json:"message"`
+		DoneReason      string      `json:"done_reason"`
+		PromptEvalCount int         `json:"prompt_eval_count"`
+		EvalCount       int         `json:"eval_count"`
+	}
+	if err = json.Unmarshal(body, &payload); err != nil {
+		return
+	}
+	res.content = payload.Message.Content
+	res.model = payload.Model
+	res.finishReason = payload.DoneReason
+	res.promptTokens = payload.PromptEvalCount
+	res.completionTokens = payload.EvalCount
+	return
+}
+
+//------------------------------------------------------------------------------
+
+// bedrockProvider targets the Converse API of Amazon Bedrock, where requests
+// are signed by a signer provided by the aws subpackage.
+type bedrockProvider struct {
+	url    string
+	model  string
+	signer BedrockSigner
+}
+
+func newBedrockProvider(baseURL, model string, signer BedrockSigner) (*bedrockProvider, error) {
+	u, err := url.JoinPath(baseURL, "model", url.PathEscape(model), "converse")
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse url: %w", err)
+	}
+	return &bedrockProvider{url: u, model: model, signer: signer}, nil
+}
+
+type bedrockText struct {
+	Text string `json:"text"`
+}
+
+func (b *bedrockProvider) newRequest(ctx context.Context, req chatRequest) (*http.Request, error) {
+	prompt := req.prompt
+	if req.jsonMode {
+		// The Converse API has no JSON mode and so the model is instructed
+		// instead.
+		prompt += "\n\nRespond with a JSON object only."
+	}
+
+	body := map[string]any{
+		"messages": []any{
+			map[string]any{"role": "user", "content": []bedrockText{{Text: prompt}}},
+		},
+	}
+	if req.system != "" {
+		body["system"] = []bedrockText{{Text: req.system}}
+	}
+	inferenceConf := map[string]any{}
+	if req.maxTokens > 0 {
+		inferenceConf["maxTokens"] = req.maxTokens
+	}
+	if req.temperature != nil {
+		inferenceConf["temperature"] = *req.temperature
+	}
+	if len(inferenceConf) > 0 {
+		body["inferenceConfig"] = inferenceConf
+	}
+
+	hReq, reqBody, err := newJSONRequest(ctx, b.url, body)
+	if err != nil {
+		return nil, err
+	}
+	if err := b.signer(ctx, hReq, reqBody); err != nil {
+		return nil, fmt.Errorf("failed to sign request: %w", err)
+	}
+	return hReq, nil
+}
+
+func (b *bedrockProvider) parseResponse(body []byte) (res chatResponse, err error) {
+	var payload struct {
+		Output struct {
+			Message struct {
+				Content []bedrockText `json:"content"`
+			} `json:"message"`
+		} `json:"output"`
+		StopReason string `json:"stopReason"`
+		Usage      struct {
+			InputTokens  int `json:"inputTokens"`
+			OutputTokens int `json:"outputTokens"`
+		} `json:"usage"`
+	}
+	if err = json.Unmarshal(body, &payload); err != nil {
+		return
+	}
+	for _, c := range payload.Output.Message.Content {
+		res.content += c.Text
+	}
+	res.model = b.model
+	res.finishReason = payload.StopReason
+	res.promptTokens = payload.Usage.InputTokens
+	res.completionTokens = payload.Usage.OutputTokens
+	return
+}
@@ -0,0 +1,547 @@
+package loki
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+	"github.com/klauspost/compress/snappy"
+
+	"github.com/benthosdev/benthos/v4/internal/impl/pure"
+	"github.com/benthosdev/benthos/v4/internal/value"
+	"github.com/benthosdev/benthos/v4/public/bloblang"
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	loFieldURL                 = "url"
+	loFieldLabels              = "labels"
+	loFieldLine                = "line"
+	loFieldTimestamp           = "timestamp"
+	loFieldStructuredMetadata  = "structured_metadata"
+	loFieldTenantID            = "tenant_id"
+	loFieldEncoding            = "encoding"
+	loFieldLimits              = "limits"
+	loFieldLimitsMaxLabels     = "max_label_names"
+	loFieldLimitsMaxNameLength = "max_label_name_length"
+	loFieldLimitsMaxValueLen   = "max_label_value_length"
+	loFieldLimitsMaxStreams    = "max_streams"
+	loFieldTimeout             = "timeout"
+	loFieldTLS                 = "tls"
+	loFieldBatching            = "batching"
+)
+
+func lokiOutputSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Services").
+		Version("4.28.0").
+		Summary("Pushes log lines to Grafana Loki.").
+		Description(`
+Messages of a batch are grouped into streams keyed by their resolved `+"`labels`"+`, and each batch is sent with a single push request per tenant. Labels that resolve to an empty string are omitted.
+
+### Label Cardinality
+
+Loki performs best with a small number of streams, and therefore this output enforces limits on the labels of each message. Messages that exceed the `+"`limits`"+`, or that have labels with invalid names, are rejected individually and can be routed elsewhere with a `+"[`fallback`](/docs/components/outputs/fallback)"+` or `+"[`reject_errored`](/docs/components/outputs/reject_errored)"+` output. When `+"`limits.max_streams`"+` is set messages that would create a stream beyond that number of distinct streams, over the lifetime of the output, are also rejected.
+
+### Retries
+
+Requests that fail with a 429 or 5XX status code are retried according to the `+"`backoff`"+` fields, honouring any `+"`Retry-After`"+` header returned by Loki. Any other failed status code fails the batch immediately.`+service.OutputPerformanceDocs(true, true)).
+		Fields(
+			service.NewStringField(loFieldURL).
+				Description("The URL of the Loki push endpoint.").
+				Example("http://localhost:3100/loki/api/v1/push"),
+			service.NewInterpolatedStringMapField(loFieldLabels).
+				Description("A map of labels to assign to each log line, the values of which are resolved for each message.").
+				Example(map[string]any{
+					"app":   "benthos",
+					"level": `${! @level.or("info") }`,
+				}).
+				Default(map[string]any{}),
+			service.NewInterpolatedStringField(loFieldLine).
+				Description("The log line of each message.").
+				Default("${! content() }"),
+			service.NewBloblangField(loFieldTimestamp).
+				Description("An optional [Bloblang mapping](/docs/guides/bloblang/about) that returns the timestamp of each log line, either as a timestamp, a string in RFC 3339 format or a unix timestamp. When omitted the current time is used.").
+				Example(`root = this.ts.ts_parse("2006-01-02 15:04:05")`).
+				Optional(),
+			service.NewInterpolatedStringMapField(loFieldStructuredMetadata).
+				Description("An optional map of structured metadata to attach to each log line, which requires Loki 3.0 or later. Unlike labels, structured metadata does not create new streams.").
+				Example(map[string]any{
+					"trace_id": `${! @trace_id }`,
+				}).
+				Advanced().
+				Default(map[string]any{}),
+			service.NewInterpolatedStringField(loFieldTenantID).
+				Description("An optional tenant ID sent with the `X-Scope-OrgID` header, used when Loki runs in multi-tenant mode. Messages of a batch that resolve different tenants are sent in separate requests.").
+				Default(""),
+			service.NewStringAnnotatedEnumField(loFieldEncoding, map[string]string{
+				"protobuf": "Snappy compressed protobuf, which is the most efficient encoding.",
+				"json":     "Uncompressed JSON.",
+			}).
+				Description("The encoding of push requests.").
+				Advanced().
+				Default("protobuf"),
+			service.NewObjectField(loFieldLimits,
+				service.NewIntField(loFieldLimitsMaxLabels).
+					Description("The maximum number of labels of a message.").
+					Default(15),
+				service.NewIntField(loFieldLimitsMaxNameLength).
+					Description("The maximum length of a label name.").
+					Default(1024),
+				service.NewIntField(loFieldLimitsMaxValueLen).
+					Description("The maximum length of a label value.").
+					Default(2048),
+				service.NewIntField(loFieldLimitsMaxStreams).
+					Description("The maximum number of distinct streams written over the lifetime of the output, where messages beyond this limit are rejected. Set to zero to disable.").
+					Default(0),
+			).
+				Description("Limits on the labels of messages, which should match those configured in Loki.").
+				Advanced(),
+			service.NewDurationField(loFieldTimeout).
+				Description("The maximum period to wait for a push request to complete.").
+				Advanced().
+				Default("10s"),
+			service.NewTLSToggledField(loFieldTLS),
+		).
+		Fields(service.NewHTTPRequestAuthSignerFields()...).
+		Fields(
+			service.NewOutputMaxInFlightField(),
+			service.NewBatchPolicyField(loFieldBatching),
+		).
+		Fields(pure.CommonRetryBackOffFields(0, "500ms", "30s", "5m")...).
+		Example("Kubernetes Logs", "Push logs to a multi-tenant Loki with labels taken from metadata.", `
+output:
+  loki:
+    url: http://loki:3100/loki/api/v1/push
+    tenant_id: ${! @namespace }
+    labels:
+      namespace: ${! @namespace }
+      pod: ${! @pod }
+      level: ${! this.level.or("info") }
+    line: ${! this.msg }
+    timestamp: root = this.ts
+    batching:
+      count: 1000
+      period: 1s
+`)
+}
+
+func init() {
+	err := service.RegisterBatchOutput("loki", lokiOutputSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (out service.BatchOutput, batchPolicy service.BatchPolicy, maxInFlight int, err error) {
+			if maxInFlight, err = conf.FieldMaxInFlight(); err != nil {
+				return
+			}
+			if batchPolicy, err = conf.FieldBatchPolicy(loFieldBatching); err != nil {
+				return
+			}
+			out, err = newLokiWriterFromParsed(conf, mgr)
+			return
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type lokiLimits struct {
+	maxLabels      int
+	maxNameLength  int
+	maxValueLength int
+	maxStreams     int
+}
+
+type lokiWriter struct {
+	log *service.Logger
+	mgr *service.Resources
+
+	url                string
+	labels             map[string]*service.InterpolatedString
+	labelNames         []string
+	line               *service.InterpolatedString
+	timestamp          *bloblang.Executor
+	structuredMetadata map[string]*service.InterpolatedString
+	tenantID           *service.InterpolatedString
+	protobuf           bool
+	limits             lokiLimits
+
+	authSigner  func(f fs.FS, req *http.Request) error
+	backoffCtor func() backoff.BackOff
+	client      *http.Client
+
+	streamsMut sync.Mutex
+	streams    map[string]struct{}
+}
+
+func newLokiWriterFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*lokiWriter, error) {
+	l := &lokiWriter{
+		log:     mgr.Logger(),
+		mgr:     mgr,
+		streams: map[string]struct{}{},
+	}
+
+	var err error
+	if l.url, err = conf.FieldString(loFieldURL); err != nil {
+		return nil, err
+	}
+	if l.labels, err = conf.FieldInterpolatedStringMap(loFieldLabels); err != nil {
+		return nil, err
+	}
+	for k := range l.labels {
+		if !validLabelName(k) {
+			return nil, fmt.Errorf("label name %q is invalid", k)
+		}
+		l.labelNames = append(l.labelNames, k)
+	}
+	sort.Strings(l.labelNames)
+
+	if l.line, err = conf.FieldInterpolatedString(loFieldLine); err != nil {
+		return nil, err
+	}
+	if conf.Contains(loFieldTimestamp) {
+		if l.timestamp, err = conf.FieldBloblang(loFieldTimestamp); err != nil {
+			return nil, err
+		}
+	}
+	if l.structuredMetadata, err = conf.FieldInterpolatedStringMap(loFieldStructuredMetadata); err != nil {
+		return nil, err
+	}
+	if l.tenantID, err = conf.FieldInterpolatedString(loFieldTenantID); err != nil {
+		return nil, err
+	}
+
+	var encoding string
+	if encoding, err = conf.FieldString(loFieldEncoding); err != nil {
+		return nil, err
+	}
+	l.protobuf = encoding == "protobuf"
+
+	limitsConf := conf.Namespace(loFieldLimits)
+	if l.limits.maxLabels, err = limitsConf.FieldInt(loFieldLimitsMaxLabels); err != nil {
+		return nil, err
+	}
+	if l.limits.maxNameLength, err = limitsConf.FieldInt(loFieldLimitsMaxNameLength); err != nil {
+		return nil, err
+	}
+	if l.limits.maxValueLength, err = limitsConf.FieldInt(loFieldLimitsMaxValueLen); err != nil {
+		return nil, err
+	}
+	if l.limits.maxStreams, err = limitsConf.FieldInt(loFieldLimitsMaxStreams); err != nil {
+		return nil, err
+	}
+
+	var timeout time.Duration
+	if timeout, err = conf.FieldDuration(loFieldTimeout); err != nil {
+		return nil, err
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	var tlsConf *tls.Config
+	var tlsEnabled bool
+	if tlsConf, tlsEnabled, err = conf.FieldTLSToggled(loFieldTLS); err != nil {
+		return nil, err
+	}
+	if tlsEnabled {
+		transport.TLSClientConfig = tlsConf
+	}
+	l.client = &http.Client{Transport: transport, Timeout: timeout}
+
+	if l.authSigner, err = conf.HTTPRequestAuthSignerFromParsed(); err != nil {
+		return nil, err
+	}
+	if l.backoffCtor, err = pure.CommonRetryBackOffCtorFromParsed(conf); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+func (l *lokiWriter) Connect(ctx context.Context) error {
+	return nil
+}
+
+// trackStream registers a stream, returning false if doing so would exceed the
+// configured stream limit.
+func (l *lokiWriter) trackStream(key string) bool {
+	if l.limits.maxStreams <= 0 {
+		return true
+	}
+	l.streamsMut.Lock()
+	defer l.streamsMut.Unlock()
+	if _, exists := l.streams[key]; exists {
+		return true
+	}
+	if len(l.streams) >= l.limits.maxStreams {
+		return false
+	}
+	l.streams[key] = struct{}{}
+	return true
+}
+
+func (l *lokiWriter) labelsFrom(batch service.MessageBatch, i int) ([]lokiLabel, error) {
+	labels := make([]lokiLabel, 0, len(l.labelNames))
+	for _, k := range l.labelNames {
+		v, err := batch.TryInterpolatedString(i, l.labels[k])
+		if err != nil {
+			return nil, fmt.Errorf("label %v interpolation error: %w", k, err)
+		}
+		if v == "" {
+			continue
+		}
+		if l.limits.maxNameLength > 0 && len(k) > l.limits.maxNameLength {
+			return nil, fmt.Errorf("label name %v exceeds the maximum length of %v", k, l.limits.maxNameLength)
+		}
+		if l.limits.maxValueLength > 0 && len(v) > l.limits.maxValueLength {
+			return nil, fmt.Errorf("value of label %v exceeds the maximum length of %v", k, l.limits.maxValueLength)
+		}
+		labels = append(labels, lokiLabel{name: k, value: v})
+	}
+	if len(labels) == 0 {
+		return nil, errors.New("at least one label must be non-empty")
+	}
+	if l.limits.maxLabels > 0 && len(labels) > l.limits.maxLabels {
+		return nil, fmt.Errorf("message has %v labels, exceeding the maximum of %v", len(labels), l.limits.maxLabels)
+	}
+	return labels, nil
+}
+
+func (l *lokiWriter) entryFrom(batch service.MessageBatch, i int) (e lokiEntry, err error) {
+	if e.line, err = batch.TryInterpolatedString(i, l.line); err != nil {
+		err = fmt.Errorf("line interpolation error: %w", err)
+		return
+	}
+
+	e.ts = time.Now()
+	if l.timestamp != nil {
+		var tsMsg *service.Message
+		if tsMsg, err = batch.BloblangQuery(i, l.timestamp); err != nil {
+			err = fmt.Errorf("timestamp mapping error: %w", err)
+			return
+		}
+		if tsMsg != nil {
+			var tsV any
+			if tsV, err = tsMsg.AsStructured(); err != nil {
+				err = fmt.Errorf("timestamp mapping error: %w", err)
+				return
+			}
+			if e.ts, err = value.IGetTimestamp(tsV); err != nil {
+				err = fmt.Errorf("timestamp mapping error: %w", err)
+				return
+			}
+		}
+	}
+
+	for k, istr := range l.structuredMetadata {
+		var v string
+		if v, err = batch.TryInterpolatedString(i, istr); err != nil {
+			err = fmt.Errorf("structured metadata %v interpolation error: %w", k, err)
+			return
+		}
+		if v != "" {
+			e.metadata = append(e.metadata, lokiLabel{name: k, value: v})
+		}
+	}
+	sortLabels(e.metadata)
+	return
+}
+
+type lokiTenantBatch struct {
+	tenant  string
+	streams []*lokiStream
+	indexes []int
+}
+
+func (l *lokiWriter) WriteBatch(ctx context.Context, batch service.MessageBatch) error {
+	var batchErr *service.BatchError
+	failed := func(i int, err error) {
+		if batchErr == nil {
+			batchErr = service.NewBatchError(batch, err)
+		}
+		batchErr.Failed(i, err)
+	}
+
+	var tenants []*lokiTenantBatch
+	tenantsByID := map[string]*lokiTenantBatch{}
+	streamsByKey := map[string]*lokiStream{}
+
+	for i := range batch {
+		tenant, err := batch.TryInterpolatedString(i, l.tenantID)
+		if err != nil {
+			failed(i, fmt.Errorf("tenant id interpolation error: %w", err))
+			continue
+		}
+		labels, err := l.labelsFrom(batch, i)
+		if err != nil {
+			failed(i, err)
+			continue
+		}
+		entry, err := l.entryFrom(batch, i)
+		if err != nil {
+			failed(i, err)
+			continue
+		}
+
+		labelsStr := labelsString(labels)
+		streamKey := tenant + "\x00" + labelsStr
+		if !l.trackStream(streamKey) {
+			failed(i, fmt.Errorf("stream %v exceeds the maximum of %v streams", labelsStr, l.limits.maxStreams))
+			continue
+		}
+
+		tb, exists := tenantsByID[tenant]
+		if !exists {
+			tb = &lokiTenantBatch{tenant: tenant}
+			tenantsByID[tenant] = tb
+			tenants = append(tenants, tb)
+		}
+		tb.indexes = append(tb.indexes, i)
+
+		s, exists := streamsByKey[streamKey]
+		if !exists {
+			s = &lokiStream{labels: labels}
+			streamsByKey[streamKey] = s
+			tb.streams = append(tb.streams, s)
+		}
+		s.entries = append(s.entries, entry)
+	}
+
+	for _, tb := range tenants {
+		for _, s := range tb.streams {
+			sort.SliceStable(s.entries, func(i, j int) bool {
+				return s.entries[i].ts.Before(s.entries[j].ts)
+			})
+		}
+		if err := l.push(ctx, tb.tenant, tb.streams); err != nil {
+			if len(tenants) == 1 && batchErr == nil {
+				return err
+			}
+			for _, i := range tb.indexes {
+				failed(i, err)
+			}
+		}
+	}
+
+	if batchErr != nil {
+		return batchErr
+	}
+	return nil
+}
+
+type lokiPushError struct {
+	status     int
+	body       []byte
+	retryAfter time.Duration
+}
+
+func (e *lokiPushError) Error() string {
+	return fmt.Sprintf("push request failed with status %v: %s", e.status, bytes.TrimSpace(e.body))
+}
+
+func (e *lokiPushError) retryable() bool {
+	return e.status == http.StatusTooManyRequests || e.status >= 500
+}
+
+func (l *lokiWriter) push(ctx context.Context, tenant string, streams []*lokiStream) error {
+	var body []byte
+	var contentType string
+	if l.protobuf {
+		body = snappy.Encode(nil, encodePushRequestProto(streams))
+		contentType = "application/x-protobuf"
+	} else {
+		var err error
+		if body, err = encodePushRequestJSON(streams); err != nil {
+			return err
+		}
+		contentType = "application/json"
+	}
+
+	boff := l.backoffCtor()
+	for {
+		err := l.pushOnce(ctx, tenant, contentType, body)
+		if err == nil {
+			return nil
+		}
+
+		var pErr *lokiPushError
+		if errors.As(err, &pErr) && !pErr.retryable() {
+			return err
+		}
+
+		wait := boff.NextBackOff()
+		if wait == backoff.Stop {
+			return err
+		}
+		if pErr != nil && pErr.retryAfter > wait {
+			wait = pErr.retryAfter
+		}
+		l.log.Warnf("Retrying push request in %v: %v", wait, err)
+
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (l *lokiWriter) pushOnce(ctx context.Context, tenant, contentType string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, l.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	if tenant != "" {
+		req.Header.Set("X-Scope-OrgID", tenant)
+	}
+	if err := l.authSigner(l.mgr.FS(), req); err != nil {
+		return err
+	}
+
+	res, err := l.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode >= 200 && res.StatusCode <= 299 {
+		_, _ = io.Copy(io.Discard, res.Body)
+		return nil
+	}
+
+	resBody, _ := io.ReadAll(io.LimitReader(res.Body, 4096))
+	return &lokiPushError{
+		status:     res.StatusCode,
+		body:       resBody,
+		retryAfter: parseRetryAfter(res.Header.Get("Retry-After")),
+	}
+}
+
+func parseRetryAfter(v string) time.Duration {
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(v); err == nil && secs > 0 {
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
+
+func (l *lokiWriter) Close(ctx context.Context) error {
+	l.client.CloseIdleConnections()
+	return nil
+}
@@ -0,0 +1,234 @@
+package loki
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/klauspost/compress/snappy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+type fakeLoki struct {
+	mut      sync.Mutex
+	tenants  []string
+	bodies   [][]byte
+	throttle int
+}
+
+func (f *fakeLoki) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mut.Lock()
+	defer f.mut.Unlock()
+
+	if f.throttle > 0 {
+		f.throttle--
+		w.Header().Set("Retry-After", "0")
+		http.Error(w, "slow down", http.StatusTooManyRequests)
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if r.Header.Get("Content-Type") == "application/x-protobuf" {
+		if body, err = snappy.Decode(nil, body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	f.tenants = append(f.tenants, r.Header.Get("X-Scope-OrgID"))
+	f.bodies = append(f.bodies, body)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func testLokiWriter(t *testing.T, confStr string) *lokiWriter {
+	t.Helper()
+
+	pConf, err := lokiOutputSpec().ParseYAML(confStr, nil)
+	require.NoError(t, err)
+
+	l, err := newLokiWriterFromParsed(pConf, service.MockResources())
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = l.Close(context.Background())
+	})
+	return l
+}
+
+func TestLokiJSONPush(t *testing.T) {
+	fake := &fakeLoki{throttle: 1}
+	ts := httptest.NewServer(fake)
+	t.Cleanup(ts.Close)
+
+	l := testLokiWriter(t, `
+url: `+ts.URL+`/loki/api/v1/push
+encoding: json
+tenant_id: ${! @tenant }
+labels:
+  app: benthos
+  level: ${! this.level.or("") }
+line: ${! this.msg }
+timestamp: root = this.ts
+backoff:
+  initial_interval: 1ms
+`)
+
+	var batch service.MessageBatch
+	for _, s := range []string{
+		`{"msg":"b","level":"info","ts":2}`,
+		`{"msg":"a","level":"info","ts":1}`,
+		`{"msg":"c","ts":3}`,
+	} {
+		m := service.NewMessage([]byte(s))
+		m.MetaSetMut("tenant", "foo")
+		batch = append(batch, m)
+	}
+	m := service.NewMessage([]byte(`{"msg":"d","ts":4}`))
+	m.MetaSetMut("tenant", "bar")
+	batch = append(batch, m)
+
+	require.NoError(t, l.WriteBatch(context.Background(), batch))
+
+	fake.mut.Lock()
+	defer fake.mut.Unlock()
+
+	assert.Equal(t, []string{"foo", "bar"}, fake.tenants)
+	require.Len(t, fake.bodies, 2)
+	assert.JSONEq(t, `{"streams":[
+  {"stream":{"app":"benthos","level":"info"},"values":[["1000000000","a"],["2000000000","b"]]},
+  {"stream":{"app":"benthos"},"values":[["3000000000","c"]]}
+]}`, string(fake.bodies[0]))
+	assert.JSONEq(t, `{"streams":[
+  {"stream":{"app":"benthos"},"values":[["4000000000","d"]]}
+]}`, string(fake.bodies[1]))
+}
+
+func TestLokiProtobufPush(t *testing.T) {
+	fake := &fakeLoki{}
+	ts := httptest.NewServer(fake)
+	t.Cleanup(ts.Close)
+
+	l := testLokiWriter(t, `
+url: `+ts.URL+`/loki/api/v1/push
+labels:
+  app: benthos
+structured_metadata:
+  trace_id: ${! @trace_id }
+`)
+
+	m := service.NewMessage([]byte(`hello world`))
+	m.MetaSetMut("trace_id", "abc")
+	require.NoError(t, l.WriteBatch(context.Background(), service.MessageBatch{m}))
+
+	fake.mut.Lock()
+	defer fake.mut.Unlock()
+
+	require.Len(t, fake.bodies, 1)
+	body := string(fake.bodies[0])
+	assert.Contains(t, body, `{app="benthos"}`)
+	assert.Contains(t, body, "hello world")
+	assert.Contains(t, body, "trace_id")
+	assert.Contains(t, body, "abc")
+}
+
+func TestLokiLimits(t *testing.T) {
+	fake := &fakeLoki{}
+	ts := httptest.NewServer(fake)
+	t.Cleanup(ts.Close)
+
+	l := testLokiWriter(t, `
+url: `+ts.URL+`/loki/api/v1/push
+encoding: json
+labels:
+  app: ${! @app }
+  host: ${! @host.or("") }
+limits:
+  max_label_names: 1
+  max_label_value_length: 5
+  max_streams: 2
+`)
+
+	var batch service.MessageBatch
+	for _, meta := range []map[string]string{
+		{"app": "a"},
+		{"app": "a", "host": "h"},
+		{"app": "toolong"},
+		{"app": "b"},
+		{"app": "c"},
+		{"app": "a"},
+	} {
+		m := service.NewMessage([]byte(`foo`))
+		for k, v := range meta {
+			m.MetaSetMut(k, v)
+		}
+		batch = append(batch, m)
+	}
+
+	index := batch.Index()
+	err := l.WriteBatch(context.Background(), batch)
+	require.Error(t, err)
+
+	var bErr *service.BatchError
+	require.True(t, errors.As(err, &bErr))
+
+	var failed []int
+	bErr.WalkMessagesIndexedBy(index, func(i int, _ *service.Message, err error) bool {
+		if err != nil {
+			failed = append(failed, i)
+		}
+		return true
+	})
+	sort.Ints(failed)
+	assert.Equal(t, []int{1, 2, 4}, failed)
+
+	fake.mut.Lock()
+	defer fake.mut.Unlock()
+
+	require.Len(t, fake.bodies, 1)
+	assert.Equal(t, 2, strings.Count(string(fake.bodies[0]), `"stream"`))
+}
+
+func TestLokiNonRetryableStatus(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "entry too far behind", http.StatusBadRequest)
+	}))
+	t.Cleanup(ts.Close)
+
+	l := testLokiWriter(t, `
+url: `+ts.URL+`/loki/api/v1/push
+labels:
+  app: benthos
+`)
+
+	start := time.Now()
+	err := l.WriteBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte(`foo`)),
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "entry too far behind")
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	assert.Equal(t, time.Duration(0), parseRetryAfter(""))
+	assert.Equal(t, 5*time.Second, parseRetryAfter("5"))
+	assert.Greater(t, parseRetryAfter(time.Now().Add(time.Minute).UTC().Format(http.TimeFormat)), 30*time.Second)
+
+	b, err := json.Marshal(labelsString([]lokiLabel{{name: "a", value: `x"y`}}))
+	require.NoError(t, err)
+	assert.Equal(t, `"{a=\"x\\\"y\"}"`, string(b))
+}
@@ -0,0 +1,164 @@
+package loki
+
+import (
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+type lokiLabel struct {
+	name  string
+	value string
+}
+
+type lokiEntry struct {
+	ts       time.Time
+	line     string
+	metadata []lokiLabel
+}
+
+type lokiStream struct {
+	labels  []lokiLabel
+	entries []lokiEntry
+}
+
+// sortLabels orders labels by name, which gives each distinct label set a
+// single canonical form.
+func sortLabels(labels []lokiLabel) {
+	sort.Slice(labels, func(i, j int) bool {
+		return labels[i].name < labels[j].name
+	})
+}
+
+// labelsString returns the label set in the selector form expected by the
+// push API, e.g. `{app="foo", env="prod"}`.
+func labelsString(labels []lokiLabel) string {
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, l := range labels {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(l.name)
+		b.WriteByte('=')
+		b.WriteString(strconv.Quote(l.value))
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+func validLabelName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i, c := range name {
+		switch {
+		case c == '_', c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z':
+		case c >= '0' && c <= '9' && i > 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+//------------------------------------------------------------------------------
+
+// The protobuf push format is encoded by hand in order to avoid depending on
+// the Loki module, the messages are defined in pkg/push/push.proto:
+//
+//	message PushRequest { repeated StreamAdapter streams = 1; }
+//	message StreamAdapter { string labels = 1; repeated EntryAdapter entries = 2; }
+//	message EntryAdapter {
+//	  google.protobuf.Timestamp timestamp = 1;
+//	  string line = 2;
+//	  repeated LabelPairAdapter structuredMetadata = 3;
+//	}
+//	message LabelPairAdapter { string name = 1; string value = 2; }
+
+func appendLabelPairProto(b []byte, l lokiLabel) []byte {
+	b = protowire.AppendTag(b, 1, protowire.BytesType)
+	b = protowire.AppendString(b, l.name)
+	b = protowire.AppendTag(b, 2, protowire.BytesType)
+	b = protowire.AppendString(b, l.value)
+	return b
+}
+
+func appendTimestampProto(b []byte, t time.Time) []byte {
+	if secs := t.Unix(); secs != 0 {
+		b = protowire.AppendTag(b, 1, protowire.VarintType)
+		b = protowire.AppendVarint(b, uint64(secs))
+	}
+	if nanos := t.Nanosecond(); nanos != 0 {
+		b = protowire.AppendTag(b, 2, protowire.VarintType)
+		b = protowire.AppendVarint(b, uint64(nanos))
+	}
+	return b
+}
+
+func appendEntryProto(b []byte, e lokiEntry) []byte {
+	b = protowire.AppendTag(b, 1, protowire.BytesType)
+	b = protowire.AppendBytes(b, appendTimestampProto(nil, e.ts))
+	b = protowire.AppendTag(b, 2, protowire.BytesType)
+	b = protowire.AppendString(b, e.line)
+	for _, l := range e.metadata {
+		b = protowire.AppendTag(b, 3, protowire.BytesType)
+		b = protowire.AppendBytes(b, appendLabelPairProto(nil, l))
+	}
+	return b
+}
+
+func appendStreamProto(b []byte, s *lokiStream) []byte {
+	b = protowire.AppendTag(b, 1, protowire.BytesType)
+	b = protowire.AppendString(b, labelsString(s.labels))
+	for _, e := range s.entries {
+		b = protowire.AppendTag(b, 2, protowire.BytesType)
+		b = protowire.AppendBytes(b, appendEntryProto(nil, e))
+	}
+	return b
+}
+
+func encodePushRequestProto(streams []*lokiStream) []byte {
+	var b []byte
+	for _, s := range streams {
+		b = protowire.AppendTag(b, 1, protowire.BytesType)
+		b = protowire.AppendBytes(b, appendStreamProto(nil, s))
+	}
+	return b
+}
+
+//------------------------------------------------------------------------------
+
+func encodePushRequestJSON(streams []*lokiStream) ([]byte, error) {
+	type jsonStream struct {
+		Stream map[string]string `json:"stream"`
+		Values [][]any           `json:"values"`
+	}
+
+	req := struct {
+		Streams []jsonStream `json:"streams"`
+	}{}
+	for _, s := range streams {
+		js := jsonStream{Stream: map[string]string{}}
+		for _, l := range s.labels {
+			js.Stream[l.name] = l.value
+		}
+		for _, e := range s.entries {
+			value := []any{strconv.FormatInt(e.ts.UnixNano(), 10), e.line}
+			if len(e.metadata) > 0 {
+				metadata := map[string]string{}
+				for _, l := range e.metadata {
+					metadata[l.name] = l.value
+				}
+				value = append(value, metadata)
+			}
+			js.Values = append(js.Values, value)
+		}
+		req.Streams = append(req.Streams, js)
+	}
+	return json.Marshal(req)
+}
@@ -0,0 +1,170 @@
+package lua
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+func (l *luaProcessor) fnGetContent(s *lua.LState) int {
+	b, err := l.msg.AsBytes()
+	if err != nil {
+		s.RaiseError("failed to read message: %v", err)
+		return 0
+	}
+	s.Push(lua.LString(b))
+	return 1
+}
+
+func (l *luaProcessor) fnSetContent(s *lua.LState) int {
+	l.msg.SetBytes([]byte(s.CheckString(1)))
+	return 0
+}
+
+func (l *luaProcessor) fnGetJSON(s *lua.LState) int {
+	v, err := l.msg.AsStructured()
+	if err != nil {
+		s.RaiseError("failed to parse message as JSON: %v", err)
+		return 0
+	}
+	s.Push(toLua(s, v))
+	return 1
+}
+
+func (l *luaProcessor) fnSetJSON(s *lua.LState) int {
+	v, err := fromLua(s.CheckAny(1))
+	if err != nil {
+		s.ArgError(1, err.Error())
+		return 0
+	}
+	l.msg.SetStructuredMut(v)
+	return 0
+}
+
+func (l *luaProcessor) fnGetMeta(s *lua.LState) int {
+	v, exists := l.msg.MetaGet(s.CheckString(1))
+	if !exists {
+		s.Push(lua.LNil)
+		return 1
+	}
+	s.Push(lua.LString(v))
+	return 1
+}
+
+func (l *luaProcessor) fnSetMeta(s *lua.LState) int {
+	key := s.CheckString(1)
+	if v := s.Get(2); v == lua.LNil {
+		l.msg.MetaDelete(key)
+	} else {
+		l.msg.MetaSetMut(key, lua.LVAsString(v))
+	}
+	return 0
+}
+
+func (l *luaProcessor) fnSetError(s *lua.LState) int {
+	l.msg.SetError(errors.New(s.CheckString(1)))
+	return 0
+}
+
+func (l *luaProcessor) fnDrop(s *lua.LState) int {
+	l.dropped = true
+	return 0
+}
+
+//------------------------------------------------------------------------------
+
+// toLua converts a structured value into a Lua value, where objects and arrays
+// become tables.
+func toLua(s *lua.LState, v any) lua.LValue {
+	switch t := v.(type) {
+	case nil:
+		return lua.LNil
+	case bool:
+		return lua.LBool(t)
+	case string:
+		return lua.LString(t)
+	case []byte:
+		return lua.LString(t)
+	case json.Number:
+		f, _ := t.Float64()
+		return lua.LNumber(f)
+	case int:
+		return lua.LNumber(t)
+	case int64:
+		return lua.LNumber(t)
+	case uint64:
+		return lua.LNumber(t)
+	case float64:
+		return lua.LNumber(t)
+	case []any:
+		tbl := s.CreateTable(len(t), 0)
+		for _, e := range t {
+			tbl.Append(toLua(s, e))
+		}
+		return tbl
+	case map[string]any:
+		tbl := s.CreateTable(0, len(t))
+		for k, e := range t {
+			tbl.RawSetString(k, toLua(s, e))
+		}
+		return tbl
+	}
+	return lua.LString(fmt.Sprintf("%v", v))
+}
+
+// fromLua converts a Lua value into a structured value. Tables with
+// consecutive integer keys starting from one become arrays, and all other
+// tables become objects.
+func fromLua(v lua.LValue) (any, error) {
+	switch t := v.(type) {
+	case *lua.LNilType:
+		return nil, nil
+	case lua.LBool:
+		return bool(t), nil
+	case lua.LString:
+		return string(t), nil
+	case lua.LNumber:
+		f := float64(t)
+		if f == math.Trunc(f) && math.Abs(f) < 1<<53 {
+			return int64(f), nil
+		}
+		return f, nil
+	case *lua.LTable:
+		if n := t.Len(); n > 0 {
+			var count int
+			t.ForEach(func(lua.LValue, lua.LValue) { count++ })
+			if count == n {
+				arr := make([]any, 0, n)
+				for i := 1; i <= n; i++ {
+					e, err := fromLua(t.RawGetInt(i))
+					if err != nil {
+						return nil, err
+					}
+					arr = append(arr, e)
+				}
+				return arr, nil
+			}
+		}
+		obj := map[string]any{}
+		var err error
+		t.ForEach(func(k, e lua.LValue) {
+			if err != nil {
+				return
+			}
+			key := lua.LVAsString(k)
+			if k.Type() != lua.LTString && k.Type() != lua.LTNumber {
+				err = fmt.Errorf("table key of type %v cannot be converted to JSON", k.Type())
+				return
+			}
+			obj[key], err = fromLua(e)
+		})
+		if err != nil {
+			return nil, err
+		}
+		return obj, nil
+	}
+	return nil, fmt.Errorf("value of type %v cannot be converted to JSON", v.Type())
+}
@@ -0,0 +1,244 @@
+package lua
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	lua "github.com/yuin/gopher-lua"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	lpFieldCode    = "code"
+	lpFieldFile    = "file"
+	lpFieldModules = "modules"
+)
+
+func luaProcessorConfig() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Categories("Mapping").
+		Version("4.28.0").
+		Summary("Executes a provided Lua code block or file for each message.").
+		Description(`
+Scripts are executed with a Lua 5.1 VM provided by the [gopher-lua](https://github.com/yuin/gopher-lua) library, with access to the standard Lua libraries and to the message being processed via the global table `+"`benthos`"+`.
+
+Each instance of this processor, of which there is one per pipeline thread, executes scripts within its own VM where global variables persist across messages. This allows scripts to maintain state, such as counters or lookup tables built on the first invocation, but the state is not shared between instances.
+
+If a script raises an error the message is flagged as having failed, allowing you to use [standard processor error handling patterns](/docs/configuration/error_handling).`).
+		Footnotes(`
+## Functions
+
+The following functions are available within the `+"`benthos`"+` table:
+
+### `+"`benthos.get_content()`"+`
+
+Returns the raw contents of the message as a string.
+
+### `+"`benthos.set_content(content)`"+`
+
+Sets the raw contents of the message to a string.
+
+### `+"`benthos.get_json()`"+`
+
+Returns the contents of the message parsed as JSON, where objects and arrays become tables.
+
+### `+"`benthos.set_json(value)`"+`
+
+Sets the contents of the message to a value serialised as JSON. Tables with consecutive integer keys starting from one are serialised as arrays, and all other tables as objects.
+
+### `+"`benthos.get_meta(key)`"+`
+
+Returns the value of a metadata key as a string, or nil if the key does not exist.
+
+### `+"`benthos.set_meta(key, value)`"+`
+
+Sets a metadata key to a string value, or removes the key if the value is nil.
+
+### `+"`benthos.set_error(message)`"+`
+
+Flags the message as having failed with an error message, without interrupting the script.
+
+### `+"`benthos.drop()`"+`
+
+Removes the message from the pipeline once the script has finished.`).
+		Field(service.NewStringField(lpFieldCode).
+			Description("An inline Lua script to execute for each message. One of `"+lpFieldCode+"` or `"+lpFieldFile+"` must be defined.").
+			Optional()).
+		Field(service.NewStringField(lpFieldFile).
+			Description("A file containing a Lua script to execute for each message. One of `"+lpFieldCode+"` or `"+lpFieldFile+"` must be defined.").
+			Optional()).
+		Field(service.NewStringMapField(lpFieldModules).
+			Description("A map of module names to Lua files that are preloaded when the VM is created, and can be loaded by scripts with `require`.").
+			Example(map[string]any{"utils": "./lua/utils.lua"}).
+			Default(map[string]any{})).
+		LintRule(fmt.Sprintf(`
+let codeLen = (this.%v | "").length()
+let fileLen = (this.%v | "").length()
+root = if $codeLen == 0 && $fileLen == 0 {
+  "either the code or file field must be specified"
+} else if $codeLen > 0 && $fileLen > 0 {
+  "cannot specify both the code and file fields"
+}`, lpFieldCode, lpFieldFile)).
+		Example(
+			"Mutate JSON",
+			"Scripts can read and write messages as JSON, which are converted to and from Lua tables.",
+			`
+pipeline:
+  processors:
+    - lua:
+        code: |
+          local doc = benthos.get_json()
+          doc.name = string.upper(doc.name)
+          doc.tags = { "processed" }
+          benthos.set_json(doc)
+`,
+		).
+		Example(
+			"Stateful counting",
+			"Global variables persist across messages, here we count the messages of each type and add the running count as metadata.",
+			`
+pipeline:
+  processors:
+    - lua:
+        code: |
+          counts = counts or {}
+          local kind = benthos.get_meta("kind") or "unknown"
+          counts[kind] = (counts[kind] or 0) + 1
+          benthos.set_meta("kind_count", tostring(counts[kind]))
+`,
+		)
+}
+
+func init() {
+	err := service.RegisterProcessor(
+		"lua", luaProcessorConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Processor, error) {
+			return newLuaProcessorFromConfig(conf, mgr)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type luaProcessor struct {
+	mut    sync.Mutex
+	state  *lua.LState
+	script *lua.LFunction
+
+	// The message being processed by the current invocation.
+	msg     *service.Message
+	dropped bool
+}
+
+func newLuaProcessorFromConfig(conf *service.ParsedConfig, mgr *service.Resources) (*luaProcessor, error) {
+	code, _ := conf.FieldString(lpFieldCode)
+	file, _ := conf.FieldString(lpFieldFile)
+	if file == "" && code == "" {
+		return nil, fmt.Errorf("either a `%v` or `%v` must be specified", lpFieldCode, lpFieldFile)
+	}
+
+	if file != "" {
+		codeBytes, err := service.ReadFile(mgr.FS(), file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open target file: %w", err)
+		}
+		code = string(codeBytes)
+	}
+
+	modules, err := conf.FieldStringMap(lpFieldModules)
+	if err != nil {
+		return nil, err
+	}
+
+	l := &luaProcessor{state: lua.NewState()}
+
+	moduleNames := make([]string, 0, len(modules))
+	for name := range modules {
+		moduleNames = append(moduleNames, name)
+	}
+	sort.Strings(moduleNames)
+
+	for _, name := range moduleNames {
+		modBytes, err := service.ReadFile(mgr.FS(), modules[name])
+		if err != nil {
+			l.state.Close()
+			return nil, fmt.Errorf("failed to open module %v: %w", name, err)
+		}
+		modFn, err := l.state.Load(bytes.NewReader(modBytes), modules[name])
+		if err != nil {
+			l.state.Close()
+			return nil, fmt.Errorf("failed to compile module %v: %w", name, err)
+		}
+		l.state.PreloadModule(name, func(s *lua.LState) int {
+			s.Push(modFn)
+			s.Call(0, 1)
+			return 1
+		})
+	}
+
+	l.state.SetGlobal("benthos", l.state.SetFuncs(l.state.NewTable(), map[string]lua.LGFunction{
+		"get_content": l.fnGetContent,
+		"set_content": l.fnSetContent,
+		"get_json":    l.fnGetJSON,
+		"set_json":    l.fnSetJSON,
+		"get_meta":    l.fnGetMeta,
+		"set_meta":    l.fnSetMeta,
+		"set_error":   l.fnSetError,
+		"drop":        l.fnDrop,
+	}))
+
+	if l.script, err = l.state.LoadString(code); err != nil {
+		l.state.Close()
+		return nil, fmt.Errorf("failed to compile lua script: %w", err)
+	}
+	return l, nil
+}
+
+func (l *luaProcessor) Process(ctx context.Context, msg *service.Message) (service.MessageBatch, error) {
+	l.mut.Lock()
+	defer l.mut.Unlock()
+
+	if l.state == nil {
+		return nil, service.ErrNotConnected
+	}
+
+	l.msg, l.dropped = msg, false
+	defer func() {
+		l.msg = nil
+	}()
+
+	l.state.SetContext(ctx)
+	defer l.state.RemoveContext()
+
+	defer l.state.SetTop(0)
+
+	l.state.Push(l.script)
+	if err := l.state.PCall(0, lua.MultRet, nil); err != nil {
+		var apiErr *lua.ApiError
+		if errors.As(err, &apiErr) {
+			err = errors.New(apiErr.Object.String())
+		}
+		return nil, err
+	}
+
+	if l.dropped {
+		return nil, nil
+	}
+	return service.MessageBatch{msg}, nil
+}
+
+func (l *luaProcessor) Close(ctx context.Context) error {
+	l.mut.Lock()
+	defer l.mut.Unlock()
+
+	if l.state != nil {
+		l.state.Close()
+		l.state = nil
+	}
+	return nil
+}
@@ -0,0 +1,152 @@
+package lua
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func TestProcessorContentAndMeta(t *testing.T) {
+	conf, err := luaProcessorConfig().ParseYAML(`
+code: |
+  local content = benthos.get_content()
+  benthos.set_content(string.upper(content))
+  benthos.set_meta("length", #content)
+  benthos.set_meta("original", benthos.get_meta("missing") or "none")
+  benthos.set_meta("remove_me", nil)
+`, nil)
+	require.NoError(t, err)
+
+	proc, err := newLuaProcessorFromConfig(conf, service.MockResources())
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, proc.Close(context.Background()))
+	})
+
+	msg := service.NewMessage([]byte("hello world"))
+	msg.MetaSetMut("remove_me", "foo")
+
+	res, err := proc.Process(context.Background(), msg)
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+
+	resBytes, err := res[0].AsBytes()
+	require.NoError(t, err)
+	assert.Equal(t, "HELLO WORLD", string(resBytes))
+
+	v, _ := res[0].MetaGet("length")
+	assert.Equal(t, "11", v)
+	v, _ = res[0].MetaGet("original")
+	assert.Equal(t, "none", v)
+	_, exists := res[0].MetaGet("remove_me")
+	assert.False(t, exists)
+}
+
+func TestProcessorJSON(t *testing.T) {
+	conf, err := luaProcessorConfig().ParseYAML(`
+code: |
+  local doc = benthos.get_json()
+  doc.name = string.upper(doc.name)
+  doc.count = doc.count + 1
+  doc.tags = { "a", "b" }
+  doc.empty = nil
+  benthos.set_json(doc)
+`, nil)
+	require.NoError(t, err)
+
+	proc, err := newLuaProcessorFromConfig(conf, service.MockResources())
+	require.NoError(t, err)
+
+	res, err := proc.Process(context.Background(), service.NewMessage([]byte(`{"name":"foo","count":1,"ratio":0.5,"empty":"x","nested":{"a":[1,2]}}`)))
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+
+	resBytes, err := res[0].AsBytes()
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"name":"FOO","count":2,"ratio":0.5,"tags":["a","b"],"nested":{"a":[1,2]}}`, string(resBytes))
+}
+
+func TestProcessorStateAndModules(t *testing.T) {
+	tmpDir := t.TempDir()
+	modPath := filepath.Join(tmpDir, "greet.lua")
+	require.NoError(t, os.WriteFile(modPath, []byte(`
+local M = {}
+function M.greet(name)
+  return "hello " .. name
+end
+return M
+`), 0o644))
+
+	conf, err := luaProcessorConfig().ParseYAML(`
+modules:
+  greet: `+modPath+`
+code: |
+  local greet = require("greet")
+  count = (count or 0) + 1
+  benthos.set_content(greet.greet(benthos.get_content()) .. " " .. count)
+`, nil)
+	require.NoError(t, err)
+
+	proc, err := newLuaProcessorFromConfig(conf, service.MockResources())
+	require.NoError(t, err)
+
+	for i, exp := range []string{"hello foo 1", "hello bar 2"} {
+		res, err := proc.Process(context.Background(), service.NewMessage([]byte([]string{"foo", "bar"}[i])))
+		require.NoError(t, err)
+		require.Len(t, res, 1)
+
+		resBytes, err := res[0].AsBytes()
+		require.NoError(t, err)
+		assert.Equal(t, exp, string(resBytes))
+	}
+}
+
+func TestProcessorErrorsAndDrop(t *testing.T) {
+	conf, err := luaProcessorConfig().ParseYAML(`
+code: |
+  local content = benthos.get_content()
+  if content == "drop" then
+    benthos.drop()
+  elseif content == "flag" then
+    benthos.set_error("flagged")
+  elseif content == "raise" then
+    error("raised")
+  end
+`, nil)
+	require.NoError(t, err)
+
+	proc, err := newLuaProcessorFromConfig(conf, service.MockResources())
+	require.NoError(t, err)
+
+	res, err := proc.Process(context.Background(), service.NewMessage([]byte("drop")))
+	require.NoError(t, err)
+	assert.Empty(t, res)
+
+	res, err = proc.Process(context.Background(), service.NewMessage([]byte("flag")))
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+	require.EqualError(t, res[0].GetError(), "flagged")
+
+	_, err = proc.Process(context.Background(), service.NewMessage([]byte("raise")))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "raised")
+
+	res, err = proc.Process(context.Background(), service.NewMessage([]byte("keep")))
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+	assert.NoError(t, res[0].GetError())
+}
+
+func TestProcessorCompileError(t *testing.T) {
+	conf, err := luaProcessorConfig().ParseYAML(`code: 'this is not lua'`, nil)
+	require.NoError(t, err)
+
+	_, err = newLuaProcessorFromConfig(conf, service.MockResources())
+	require.Error(t, err)
+}
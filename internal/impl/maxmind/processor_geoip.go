@@ -0,0 +1,472 @@
+package maxmind
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/gabs/v2"
+	"github.com/Jeffail/shutdown"
+	"github.com/oschwald/maxminddb-golang"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	gpFieldFields          = "fields"
+	gpFieldCityDatabase    = "city_database"
+	gpFieldCountryDatabase = "country_database"
+	gpFieldASNDatabase     = "asn_database"
+	gpFieldLanguage        = "language"
+	gpFieldReloadInterval  = "reload_interval"
+)
+
+func geoipProcessorConfig() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Categories("Integration").
+		Version("4.28.0").
+		Summary("Enriches messages with the city, country and autonomous system (ASN) of IP addresses, looked up from [MaxMind databases](https://www.maxmind.com/en/home).").
+		Description(`
+Messages are parsed as JSON, and for each IP address field the details found within the configured databases are added as an object to a target field. Each database is optional, but at least one must be specified. Database files are checked for changes at the interval `+"`reload_interval`"+`, and updated files are loaded without interrupting the pipeline, which allows databases to be refreshed by tools such as `+"[`geoipupdate`](https://github.com/maxmind/geoipupdate)"+` without a restart. Updated files should be moved into place atomically rather than written over.
+
+Fields that do not exist are ignored, and messages where a field does not contain a valid IP address are flagged as having failed, allowing you to use [standard processor error handling patterns](/docs/configuration/error_handling). When an address is not found within any database the target field is not set.
+
+### Enrichment
+
+The object added to each target field contains the following keys, where keys without a value are omitted:
+
+- `+"`city`"+`, `+"`postal_code`"+`, `+"`subdivisions`"+`, `+"`latitude`"+`, `+"`longitude`"+`, `+"`accuracy_radius`"+` and `+"`time_zone`"+`: From the city database.
+- `+"`country`"+`, `+"`country_code`"+`, `+"`continent`"+` and `+"`continent_code`"+`: From the city or country database.
+- `+"`asn`"+` and `+"`as_organization`"+`: From the ASN database.
+
+## Metrics
+
+This processor emits the counters `+"`geoip_lookup_hit`"+` and `+"`geoip_lookup_miss`"+` with the label `+"`database`"+` for each lookup, as well as the counter `+"`geoip_reload`"+` for each database that is reloaded.`).
+		Field(service.NewStringMapField(gpFieldFields).
+			Description("A map of [dot paths](/docs/configuration/field_paths) of IP address fields to the paths of the fields to add their details to.").
+			Example(map[string]any{
+				"client.ip": "client.geo",
+				"server_ip": "server_geo",
+			})).
+		Field(service.NewStringField(gpFieldCityDatabase).
+			Description("The path of a city database, such as GeoIP2-City or GeoLite2-City.").
+			Example("/usr/share/GeoIP/GeoLite2-City.mmdb").
+			Optional()).
+		Field(service.NewStringField(gpFieldCountryDatabase).
+			Description("The path of a country database, such as GeoIP2-Country or GeoLite2-Country.").
+			Optional()).
+		Field(service.NewStringField(gpFieldASNDatabase).
+			Description("The path of an ASN database, such as GeoLite2-ASN.").
+			Example("/usr/share/GeoIP/GeoLite2-ASN.mmdb").
+			Optional()).
+		Field(service.NewStringField(gpFieldLanguage).
+			Description("The language of the names of places, which falls back to English when a name is not available in that language.").
+			Example("de").
+			Advanced().
+			Default("en")).
+		Field(service.NewDurationField(gpFieldReloadInterval).
+			Description("The interval at which database files are checked for changes. Set to zero to disable reloading.").
+			Advanced().
+			Default("1m")).
+		LintRule(fmt.Sprintf(`root = if (this.%v | "") == "" && (this.%v | "") == "" && (this.%v | "") == "" {
+  "at least one of the city_database, country_database or asn_database fields must be specified"
+}`, gpFieldCityDatabase, gpFieldCountryDatabase, gpFieldASNDatabase)).
+		Example(
+			"Enrich access logs",
+			"Here we add the location and network operator of clients to access logs, with databases that are kept up to date by `geoipupdate`.",
+			`
+pipeline:
+  processors:
+    - geoip:
+        fields:
+          client.ip: client.geo
+        city_database: /usr/share/GeoIP/GeoLite2-City.mmdb
+        asn_database: /usr/share/GeoIP/GeoLite2-ASN.mmdb
+`,
+		)
+}
+
+func init() {
+	err := service.RegisterProcessor(
+		"geoip", geoipProcessorConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Processor, error) {
+			return newGeoIPProcessorFromConfig(conf, mgr)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type geoipNames map[string]string
+
+type geoipCityRecord struct {
+	City struct {
+		Names geoipNames `maxminddb:"names"`
+	} `maxminddb:"city"`
+	Continent struct {
+		Code  string     `maxminddb:"code"`
+		Names geoipNames `maxminddb:"names"`
+	} `maxminddb:"continent"`
+	Country struct {
+		IsoCode string     `maxminddb:"iso_code"`
+		Names   geoipNames `maxminddb:"names"`
+	} `maxminddb:"country"`
+	Location struct {
+		AccuracyRadius uint16  `maxminddb:"accuracy_radius"`
+		Latitude       float64 `maxminddb:"latitude"`
+		Longitude      float64 `maxminddb:"longitude"`
+		TimeZone       string  `maxminddb:"time_zone"`
+	} `maxminddb:"location"`
+	Postal struct {
+		Code string `maxminddb:"code"`
+	} `maxminddb:"postal"`
+	Subdivisions []struct {
+		Names geoipNames `maxminddb:"names"`
+	} `maxminddb:"subdivisions"`
+}
+
+type geoipASNRecord struct {
+	AutonomousSystemNumber       uint   `maxminddb:"autonomous_system_number"`
+	AutonomousSystemOrganization string `maxminddb:"autonomous_system_organization"`
+}
+
+func (n geoipNames) get(language string) string {
+	if v, exists := n[language]; exists {
+		return v
+	}
+	return n["en"]
+}
+
+func geoipSetIfNotEmpty(obj map[string]any, key string, value any) {
+	switch t := value.(type) {
+	case string:
+		if t == "" {
+			return
+		}
+	case []any:
+		if len(t) == 0 {
+			return
+		}
+	}
+	obj[key] = value
+}
+
+//------------------------------------------------------------------------------
+
+// geoipDatabase is a database file that is reloaded when it changes.
+type geoipDatabase struct {
+	name string
+	path string
+
+	mut     sync.RWMutex
+	reader  *maxminddb.Reader
+	modTime time.Time
+	size    int64
+
+	mHit    *service.MetricCounter
+	mMiss   *service.MetricCounter
+	mReload *service.MetricCounter
+}
+
+func (d *geoipDatabase) load() (bool, error) {
+	info, err := os.Stat(d.path)
+	if err != nil {
+		return false, err
+	}
+
+	d.mut.RLock()
+	unchanged := d.reader != nil && info.ModTime().Equal(d.modTime) && info.Size() == d.size
+	d.mut.RUnlock()
+	if unchanged {
+		return false, nil
+	}
+
+	reader, err := maxminddb.Open(d.path)
+	if err != nil {
+		return false, err
+	}
+
+	d.mut.Lock()
+	prev := d.reader
+	d.reader, d.modTime, d.size = reader, info.ModTime(), info.Size()
+	d.mut.Unlock()
+
+	if prev != nil {
+		_ = prev.Close()
+	}
+	return true, nil
+}
+
+func (d *geoipDatabase) lookup(ip net.IP, result any) (bool, error) {
+	d.mut.RLock()
+	_, found, err := d.reader.LookupNetwork(ip, result)
+	d.mut.RUnlock()
+	if err != nil {
+		return false, err
+	}
+	if found {
+		d.mHit.Incr(1, d.name)
+	} else {
+		d.mMiss.Incr(1, d.name)
+	}
+	return found, nil
+}
+
+func (d *geoipDatabase) close() {
+	d.mut.Lock()
+	if d.reader != nil {
+		_ = d.reader.Close()
+		d.reader = nil
+	}
+	d.mut.Unlock()
+}
+
+//------------------------------------------------------------------------------
+
+type geoipProcessor struct {
+	log *service.Logger
+
+	sourcePaths []string
+	fields      map[string]string
+	language    string
+
+	city, country, asn *geoipDatabase
+	databases          []*geoipDatabase
+
+	shutSig *shutdown.Signaller
+}
+
+func newGeoIPProcessorFromConfig(conf *service.ParsedConfig, mgr *service.Resources) (*geoipProcessor, error) {
+	g := &geoipProcessor{
+		log:     mgr.Logger(),
+		shutSig: shutdown.NewSignaller(),
+	}
+
+	var err error
+	if g.fields, err = conf.FieldStringMap(gpFieldFields); err != nil {
+		return nil, err
+	}
+	if len(g.fields) == 0 {
+		return nil, errors.New("at least one field must be specified")
+	}
+	for k := range g.fields {
+		g.sourcePaths = append(g.sourcePaths, k)
+	}
+	sort.Strings(g.sourcePaths)
+
+	if g.language, err = conf.FieldString(gpFieldLanguage); err != nil {
+		return nil, err
+	}
+
+	mHit := mgr.Metrics().NewCounter("geoip_lookup_hit", "database")
+	mMiss := mgr.Metrics().NewCounter("geoip_lookup_miss", "database")
+	mReload := mgr.Metrics().NewCounter("geoip_reload", "database")
+
+	for _, db := range []struct {
+		field  string
+		name   string
+		target **geoipDatabase
+	}{
+		{gpFieldCityDatabase, "city", &g.city},
+		{gpFieldCountryDatabase, "country", &g.country},
+		{gpFieldASNDatabase, "asn", &g.asn},
+	} {
+		if !conf.Contains(db.field) {
+			continue
+		}
+		path, err := conf.FieldString(db.field)
+		if err != nil {
+			return nil, err
+		}
+		if path == "" {
+			continue
+		}
+
+		d := &geoipDatabase{
+			name:    db.name,
+			path:    path,
+			mHit:    mHit,
+			mMiss:   mMiss,
+			mReload: mReload,
+		}
+		if _, err := d.load(); err != nil {
+			g.closeDatabases()
+			return nil, fmt.Errorf("failed to open %v database: %w", db.name, err)
+		}
+		*db.target = d
+		g.databases = append(g.databases, d)
+	}
+	if len(g.databases) == 0 {
+		return nil, fmt.Errorf("at least one of the %v, %v or %v fields must be specified", gpFieldCityDatabase, gpFieldCountryDatabase, gpFieldASNDatabase)
+	}
+
+	reloadInterval, err := conf.FieldDuration(gpFieldReloadInterval)
+	if err != nil {
+		g.closeDatabases()
+		return nil, err
+	}
+	if reloadInterval > 0 {
+		go g.reloadLoop(reloadInterval)
+	} else {
+		g.shutSig.TriggerHasStopped()
+	}
+	return g, nil
+}
+
+func (g *geoipProcessor) reloadLoop(interval time.Duration) {
+	defer g.shutSig.TriggerHasStopped()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			for _, d := range g.databases {
+				reloaded, err := d.load()
+				if err != nil {
+					g.log.Errorf("Failed to reload %v database: %v", d.name, err)
+					continue
+				}
+				if reloaded {
+					d.mReload.Incr(1, d.name)
+					g.log.Infof("Reloaded %v database from %v", d.name, d.path)
+				}
+			}
+		case <-g.shutSig.HardStopChan():
+			return
+		}
+	}
+}
+
+func (g *geoipProcessor) enrich(ip net.IP) (map[string]any, error) {
+	obj := map[string]any{}
+
+	var found bool
+	if g.city != nil {
+		var rec geoipCityRecord
+		ok, err := g.city.lookup(ip, &rec)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			found = true
+			geoipSetIfNotEmpty(obj, "city", rec.City.Names.get(g.language))
+			geoipSetIfNotEmpty(obj, "postal_code", rec.Postal.Code)
+
+			var subdivisions []any
+			for _, s := range rec.Subdivisions {
+				if name := s.Names.get(g.language); name != "" {
+					subdivisions = append(subdivisions, name)
+				}
+			}
+			geoipSetIfNotEmpty(obj, "subdivisions", subdivisions)
+
+			geoipSetIfNotEmpty(obj, "country", rec.Country.Names.get(g.language))
+			geoipSetIfNotEmpty(obj, "country_code", rec.Country.IsoCode)
+			geoipSetIfNotEmpty(obj, "continent", rec.Continent.Names.get(g.language))
+			geoipSetIfNotEmpty(obj, "continent_code", rec.Continent.Code)
+			if rec.Location.AccuracyRadius > 0 {
+				obj["latitude"] = rec.Location.Latitude
+				obj["longitude"] = rec.Location.Longitude
+				obj["accuracy_radius"] = int64(rec.Location.AccuracyRadius)
+			}
+			geoipSetIfNotEmpty(obj, "time_zone", rec.Location.TimeZone)
+		}
+	}
+
+	if g.country != nil {
+		var rec geoipCityRecord
+		ok, err := g.country.lookup(ip, &rec)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			found = true
+			geoipSetIfNotEmpty(obj, "country", rec.Country.Names.get(g.language))
+			geoipSetIfNotEmpty(obj, "country_code", rec.Country.IsoCode)
+			geoipSetIfNotEmpty(obj, "continent", rec.Continent.Names.get(g.language))
+			geoipSetIfNotEmpty(obj, "continent_code", rec.Continent.Code)
+		}
+	}
+
+	if g.asn != nil {
+		var rec geoipASNRecord
+		ok, err := g.asn.lookup(ip, &rec)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			found = true
+			if rec.AutonomousSystemNumber > 0 {
+				obj["asn"] = int64(rec.AutonomousSystemNumber)
+			}
+			geoipSetIfNotEmpty(obj, "as_organization", rec.AutonomousSystemOrganization)
+		}
+	}
+
+	if !found {
+		return nil, nil
+	}
+	return obj, nil
+}
+
+func (g *geoipProcessor) Process(ctx context.Context, msg *service.Message) (service.MessageBatch, error) {
+	v, err := msg.AsStructuredMut()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse message as JSON: %w", err)
+	}
+
+	doc := gabs.Wrap(v)
+	for _, path := range g.sourcePaths {
+		ipV := doc.Path(path).Data()
+		if ipV == nil {
+			continue
+		}
+		ipStr, ok := ipV.(string)
+		if !ok {
+			return nil, fmt.Errorf("field %v: expected string value, got %T", path, ipV)
+		}
+		ip := net.ParseIP(ipStr)
+		if ip == nil {
+			return nil, fmt.Errorf("field %v: value %v does not appear to be a valid v4 or v6 IP address", path, ipStr)
+		}
+
+		details, err := g.enrich(ip)
+		if err != nil {
+			return nil, fmt.Errorf("field %v: %w", path, err)
+		}
+		if details == nil {
+			continue
+		}
+		if _, err := doc.SetP(details, g.fields[path]); err != nil {
+			return nil, fmt.Errorf("field %v: %w", g.fields[path], err)
+		}
+	}
+	msg.SetStructuredMut(doc.Data())
+	return service.MessageBatch{msg}, nil
+}
+
+func (g *geoipProcessor) closeDatabases() {
+	for _, d := range g.databases {
+		d.close()
+	}
+}
+
+func (g *geoipProcessor) Close(ctx context.Context) error {
+	g.shutSig.TriggerHardStop()
+	select {
+	case <-g.shutSig.HasStoppedChan():
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	g.closeDatabases()
+	return nil
+}
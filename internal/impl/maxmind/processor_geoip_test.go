@@ -0,0 +1,124 @@
+package maxmind
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func TestGeoIPProcessorEnrich(t *testing.T) {
+	conf, err := geoipProcessorConfig().ParseYAML(`
+fields:
+  client.ip: client.geo
+  server_ip: server_geo
+  missing_ip: missing_geo
+city_database: ./testdata/GeoIP2-City-Test.mmdb
+asn_database: ./testdata/GeoLite2-ASN-Test.mmdb
+`, nil)
+	require.NoError(t, err)
+
+	proc, err := newGeoIPProcessorFromConfig(conf, service.MockResources())
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, proc.Close(context.Background()))
+	})
+
+	res, err := proc.Process(context.Background(), service.NewMessage([]byte(`{"client":{"ip":"81.2.69.192"},"server_ip":"214.0.0.0"}`)))
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+
+	v, err := res[0].AsStructured()
+	require.NoError(t, err)
+
+	client := v.(map[string]any)["client"].(map[string]any)["geo"].(map[string]any)
+	assert.Equal(t, "London", client["city"])
+	assert.Equal(t, "GB", client["country_code"])
+	assert.Equal(t, "United Kingdom", client["country"])
+	assert.Equal(t, "EU", client["continent_code"])
+	assert.Equal(t, "Europe/London", client["time_zone"])
+	assert.Equal(t, []any{"England"}, client["subdivisions"])
+
+	server := v.(map[string]any)["server_geo"].(map[string]any)
+	assert.Equal(t, "DoD Network Information Center", server["as_organization"])
+	assert.Equal(t, int64(721), server["asn"])
+
+	_, exists := v.(map[string]any)["missing_geo"]
+	assert.False(t, exists)
+
+	res, err = proc.Process(context.Background(), service.NewMessage([]byte(`{"client":{"ip":"10.0.0.1"}}`)))
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+
+	b, err := res[0].AsBytes()
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"client":{"ip":"10.0.0.1"}}`, string(b))
+
+	_, err = proc.Process(context.Background(), service.NewMessage([]byte(`{"client":{"ip":"not an ip"}}`)))
+	require.Error(t, err)
+}
+
+func TestGeoIPProcessorReload(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "city.mmdb")
+
+	copyDB := func(src string) {
+		b, err := os.ReadFile(src)
+		require.NoError(t, err)
+
+		tmpPath := filepath.Join(tmpDir, "city.mmdb.tmp")
+		require.NoError(t, os.WriteFile(tmpPath, b, 0o644))
+		require.NoError(t, os.Rename(tmpPath, dbPath))
+	}
+	copyDB("./testdata/GeoIP2-Country-Test.mmdb")
+
+	conf, err := geoipProcessorConfig().ParseYAML(`
+fields:
+  ip: geo
+city_database: `+dbPath+`
+reload_interval: 10ms
+`, nil)
+	require.NoError(t, err)
+
+	proc, err := newGeoIPProcessorFromConfig(conf, service.MockResources())
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, proc.Close(context.Background()))
+	})
+
+	city := func() any {
+		res, err := proc.Process(context.Background(), service.NewMessage([]byte(`{"ip":"81.2.69.192"}`)))
+		require.NoError(t, err)
+		require.Len(t, res, 1)
+
+		v, err := res[0].AsStructured()
+		require.NoError(t, err)
+		return v.(map[string]any)["geo"].(map[string]any)["city"]
+	}
+	assert.Nil(t, city())
+
+	copyDB("./testdata/GeoIP2-City-Test.mmdb")
+	assert.Eventually(t, func() bool {
+		return city() == "London"
+	}, time.Second*5, 10*time.Millisecond)
+}
+
+func TestGeoIPProcessorConfigErrors(t *testing.T) {
+	for _, confStr := range []string{
+		`fields: { ip: geo }`,
+		`{ fields: {}, city_database: ./testdata/GeoIP2-City-Test.mmdb }`,
+		`{ fields: { ip: geo }, city_database: ./testdata/nope.mmdb }`,
+	} {
+		conf, err := geoipProcessorConfig().ParseYAML(confStr, nil)
+		require.NoError(t, err, confStr)
+
+		_, err = newGeoIPProcessorFromConfig(conf, service.MockResources())
+		require.Error(t, err, confStr)
+	}
+}
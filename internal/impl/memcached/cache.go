@@ -3,11 +3,11 @@ package memcached
 import (
 	"context"
 	"errors"
+	"fmt"
 	"strings"
 	"sync"
 	"time"
 
-	"github.com/bradfitz/gomemcache/memcache"
 	"github.com/cenkalti/backoff/v4"
 
 	"github.com/benthosdev/benthos/v4/public/service"
@@ -22,6 +22,18 @@ func memcachedConfig() *service.ConfigSpec {
 	spec := service.NewConfigSpec().
 		Stable().
 		Summary(`Connects to a cluster of memcached services, a prefix can be specified to allow multiple cache types to share a memcached cluster under different namespaces.`).
+		Description(`
+This cache uses the meta text protocol, which requires memcached v1.6 or newer, and the requests for each server of a batch of reads are pipelined over a single connection.
+
+### Server Selection
+
+By default the server that holds a key is chosen by the CRC32 checksum of the key modulo the number of servers, which moves most keys whenever a server is added or removed. Setting ` + "`server_selection`" + ` to ` + "`consistent`" + ` instead places keys on a ring of consistent hashes with the ketama algorithm, which moves only the keys of the servers that were added or removed.
+
+When ` + "`ejection.failures`" + ` is greater than zero a server that fails that many requests in a row, due to connection errors or timeouts, is ejected from selection for the duration of ` + "`ejection.period`" + ` and its keys are held by the remaining servers in the meantime. Ejection is best combined with consistent server selection, as otherwise the ejection of a server moves most keys.
+
+### Authentication
+
+Credentials within the ` + "`auth`" + ` fields are sent using the ASCII authentication of memcached, which is enabled on servers with the ` + "`-Y`" + ` flag. SASL authentication is only supported by the binary protocol of memcached and therefore cannot be used with this cache.`).
 		Field(service.NewStringListField("addresses").
 			Description("A list of addresses of memcached servers to use.")).
 		Field(service.NewStringField("prefix").
@@ -30,6 +42,49 @@ func memcachedConfig() *service.ConfigSpec {
 		Field(service.NewDurationField("default_ttl").
 			Description("A default TTL to set for items, calculated from the moment the item is cached.").
 			Default("300s")).
+		Field(service.NewTLSToggledField("tls").
+			Version("4.28.0")).
+		Field(service.NewObjectField("auth",
+			service.NewStringField("username").
+				Description("A username to authenticate with, authentication is disabled when empty.").
+				Default(""),
+			service.NewStringField("password").
+				Description("A password to authenticate with.").
+				Default("").
+				Secret(),
+		).
+			Description("Optional credentials to authenticate with.").
+			Version("4.28.0").
+			Advanced()).
+		Field(service.NewStringAnnotatedEnumField("server_selection", map[string]string{
+			"modulo":     "Keys are placed by their CRC32 checksum modulo the number of servers, which matches the placement of keys by prior versions of this cache.",
+			"consistent": "Keys are placed on a ring of consistent hashes with the ketama algorithm.",
+		}).
+			Description("How to choose the server that holds a key.").
+			Default("modulo").
+			Version("4.28.0").
+			Advanced()).
+		Field(service.NewObjectField("ejection",
+			service.NewIntField("failures").
+				Description("The number of consecutive failed requests after which a server is ejected, where `0` disables ejection.").
+				Default(0),
+			service.NewDurationField("period").
+				Description("The period of time for which an ejected server is not selected.").
+				Default("30s"),
+		).
+			Description("Options for the automatic ejection of servers that are failing.").
+			Version("4.28.0").
+			Advanced()).
+		Field(service.NewDurationField("timeout").
+			Description("The maximum period of time to wait for a connection to be established or a request to complete.").
+			Default("500ms").
+			Version("4.28.0").
+			Advanced()).
+		Field(service.NewIntField("max_idle_conns").
+			Description("The maximum number of idle connections to keep open for each server.").
+			Default(2).
+			Version("4.28.0").
+			Advanced()).
 		Field(service.NewBackOffField("retries", false, retriesDefaults).
 			Advanced())
 
@@ -65,11 +120,80 @@ func newMemcachedFromConfig(conf *service.ParsedConfig) (*memcachedCache, error)
 		return nil, err
 	}
 
+	tlsConf, tlsEnabled, err := conf.FieldTLSToggled("tls")
+	if err != nil {
+		return nil, err
+	}
+	if !tlsEnabled {
+		tlsConf = nil
+	}
+
+	username, err := conf.FieldString("auth", "username")
+	if err != nil {
+		return nil, err
+	}
+	password, err := conf.FieldString("auth", "password")
+	if err != nil {
+		return nil, err
+	}
+
+	selection, err := conf.FieldString("server_selection")
+	if err != nil {
+		return nil, err
+	}
+	var consistent bool
+	switch selection {
+	case "modulo":
+	case "consistent":
+		consistent = true
+	default:
+		return nil, fmt.Errorf("server selection %v not recognised", selection)
+	}
+
+	ejectFailures, err := conf.FieldInt("ejection", "failures")
+	if err != nil {
+		return nil, err
+	}
+	ejectPeriod, err := conf.FieldDuration("ejection", "period")
+	if err != nil {
+		return nil, err
+	}
+
+	timeout, err := conf.FieldDuration("timeout")
+	if err != nil {
+		return nil, err
+	}
+	maxIdle, err := conf.FieldInt("max_idle_conns")
+	if err != nil {
+		return nil, err
+	}
+
 	backOff, err := conf.FieldBackOff("retries")
 	if err != nil {
 		return nil, err
 	}
-	return newMemcachedCache(addresses, prefix, ttl, backOff)
+
+	addrs := splitAddresses(addresses)
+	if len(addrs) == 0 {
+		return nil, errors.New("at least one address must be specified")
+	}
+	client := newMetaClient(
+		newServerSelector(addrs, consistent, ejectFailures, ejectPeriod),
+		tlsConf, username, password, timeout, maxIdle,
+	)
+	return newMemcachedCache(client, prefix, ttl, backOff), nil
+}
+
+func splitAddresses(inAddresses []string) []string {
+	addresses := []string{}
+	for _, addr := range inAddresses {
+		for _, splitAddr := range strings.Split(addr, ",") {
+			if splitAddr != "" {
+				addresses = append(addresses, splitAddr)
+			}
+		}
+	}
+	return addresses
 }
 
 //------------------------------------------------------------------------------
@@ -78,26 +202,18 @@ type memcachedCache struct {
 	prefix     string
 	defaultTTL time.Duration
 
-	mc       *memcache.Client
+	mc       *metaClient
 	boffPool sync.Pool
 }
 
 func newMemcachedCache(
-	inAddresses []string,
+	mc *metaClient,
 	prefix string,
 	defaultTTL time.Duration,
 	backOff *backoff.ExponentialBackOff,
-) (*memcachedCache, error) {
-	addresses := []string{}
-	for _, addr := range inAddresses {
-		for _, splitAddr := range strings.Split(addr, ",") {
-			if splitAddr != "" {
-				addresses = append(addresses, splitAddr)
-			}
-		}
-	}
+) *memcachedCache {
 	return &memcachedCache{
-		mc:         memcache.New(addresses...),
+		mc:         mc,
 		prefix:     prefix,
 		defaultTTL: defaultTTL,
 		boffPool: sync.Pool{
@@ -107,24 +223,19 @@ func newMemcachedCache(
 				return &bo
 			},
 		},
-	}, nil
+	}
 }
 
-func (m *memcachedCache) getItemFor(key string, value []byte, ttl *time.Duration) *memcache.Item {
-	var expiration int32
+func (m *memcachedCache) ttlSeconds(ttl *time.Duration) int32 {
 	if ttl != nil {
-		expiration = int32(ttl.Milliseconds() / 1000)
-	} else {
-		expiration = int32(m.defaultTTL.Milliseconds() / 1000)
-	}
-	return &memcache.Item{
-		Key:        m.prefix + key,
-		Value:      value,
-		Expiration: expiration,
+		return int32(ttl.Milliseconds() / 1000)
 	}
+	return int32(m.defaultTTL.Milliseconds() / 1000)
 }
 
-func (m *memcachedCache) Get(ctx context.Context, key string) ([]byte, error) {
+// retry attempts an operation until it succeeds or the retries are exhausted.
+// Malformed keys are never retried.
+func (m *memcachedCache) retry(ctx context.Context, fn func() error) error {
 	boff := m.boffPool.Get().(backoff.BackOff)
 	defer func() {
 		boff.Reset()
@@ -132,107 +243,93 @@ func (m *memcachedCache) Get(ctx context.Context, key string) ([]byte, error) {
 	}()
 
 	for {
-		item, err := m.mc.Get(m.prefix + key)
-		if err == nil {
-			return item.Value, nil
-		}
-		if errors.Is(err, memcache.ErrCacheMiss) {
-			return nil, service.ErrKeyNotFound
+		err := fn()
+		if err == nil || errors.Is(err, errMalformedKey) {
+			return err
 		}
 
 		wait := boff.NextBackOff()
 		if wait == backoff.Stop {
-			return nil, err
+			return err
 		}
 		select {
 		case <-time.After(wait):
 		case <-ctx.Done():
-			return nil, err
+			return err
 		}
 	}
 }
 
-func (m *memcachedCache) Set(ctx context.Context, key string, value []byte, ttl *time.Duration) error {
-	boff := m.boffPool.Get().(backoff.BackOff)
-	defer func() {
-		boff.Reset()
-		m.boffPool.Put(boff)
-	}()
+func (m *memcachedCache) Get(ctx context.Context, key string) (value []byte, err error) {
+	if err = m.retry(ctx, func() (err error) {
+		value, err = m.mc.Get(ctx, m.prefix+key)
+		return
+	}); err != nil {
+		return nil, err
+	}
+	if value == nil {
+		return nil, service.ErrKeyNotFound
+	}
+	return value, nil
+}
 
-	for {
-		err := m.mc.Set(m.getItemFor(key, value, ttl))
-		if err == nil {
-			return nil
-		}
+// GetMulti attempts to get the values of multiple keys with a single request
+// to each server that holds them.
+func (m *memcachedCache) GetMulti(ctx context.Context, keys ...string) (map[string][]byte, error) {
+	prefixedKeys := make([]string, len(keys))
+	for i, key := range keys {
+		prefixedKeys[i] = m.prefix + key
+	}
 
-		wait := boff.NextBackOff()
-		if wait == backoff.Stop {
-			return err
-		}
-		select {
-		case <-time.After(wait):
-		case <-ctx.Done():
-			return err
+	var items map[string][]byte
+	if err := m.retry(ctx, func() (err error) {
+		items, err = m.mc.GetMulti(ctx, prefixedKeys)
+		return
+	}); err != nil {
+		return nil, err
+	}
+
+	values := make(map[string][]byte, len(items))
+	for i, key := range keys {
+		if value, exists := items[prefixedKeys[i]]; exists {
+			values[key] = value
 		}
 	}
+	return values, nil
 }
 
-// AddWithTTL attempts to set the value of a key only if the key does not already exist
-// and returns an error if the key already exists or if the operation fails.
-func (m *memcachedCache) Add(ctx context.Context, key string, value []byte, ttl *time.Duration) error {
-	boff := m.boffPool.Get().(backoff.BackOff)
-	defer func() {
-		boff.Reset()
-		m.boffPool.Put(boff)
-	}()
-
-	for {
-		err := m.mc.Add(m.getItemFor(key, value, ttl))
-		if err == nil {
-			return nil
-		}
-		if errors.Is(err, memcache.ErrNotStored) {
-			return service.ErrKeyAlreadyExists
-		}
+func (m *memcachedCache) Set(ctx context.Context, key string, value []byte, ttl *time.Duration) error {
+	return m.retry(ctx, func() error {
+		return m.mc.Set(ctx, m.prefix+key, value, m.ttlSeconds(ttl))
+	})
+}
 
-		wait := boff.NextBackOff()
-		if wait == backoff.Stop {
-			return err
-		}
-		select {
-		case <-time.After(wait):
-		case <-ctx.Done():
-			return err
-		}
+// Add attempts to set the value of a key only if the key does not already
+// exist and returns an error if the key already exists or if the operation
+// fails.
+func (m *memcachedCache) Add(ctx context.Context, key string, value []byte, ttl *time.Duration) error {
+	var stored bool
+	if err := m.retry(ctx, func() (err error) {
+		stored, err = m.mc.Add(ctx, m.prefix+key, value, m.ttlSeconds(ttl))
+		return
+	}); err != nil {
+		return err
+	}
+	if !stored {
+		return service.ErrKeyAlreadyExists
 	}
+	return nil
 }
 
 // Delete attempts to remove a key.
 func (m *memcachedCache) Delete(ctx context.Context, key string) error {
-	boff := m.boffPool.Get().(backoff.BackOff)
-	defer func() {
-		boff.Reset()
-		m.boffPool.Put(boff)
-	}()
-
-	for {
-		err := m.mc.Delete(m.prefix + key)
-		if errors.Is(err, memcache.ErrCacheMiss) {
-			return nil
-		}
-
-		wait := boff.NextBackOff()
-		if wait == backoff.Stop {
-			return err
-		}
-		select {
-		case <-time.After(wait):
-		case <-ctx.Done():
-			return err
-		}
-	}
+	return m.retry(ctx, func() (err error) {
+		_, err = m.mc.Delete(ctx, m.prefix+key)
+		return
+	})
 }
 
 func (m *memcachedCache) Close(ctx context.Context) error {
+	m.mc.Close()
 	return nil
 }
@@ -58,6 +58,7 @@ cache_resources:
 		integration.CacheTestDoubleAdd(),
 		integration.CacheTestDelete(),
 		integration.CacheTestGetAndSet(50),
+		integration.CacheTestGetAndSetMulti(50),
 	)
 	suite.Run(
 		t, template,
@@ -0,0 +1,371 @@
+package memcached
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+// fakeMetaServer implements enough of the meta text protocol of memcached to
+// exercise the cache, ignoring TTLs.
+type fakeMetaServer struct {
+	ln    net.Listener
+	creds string
+
+	mut   sync.Mutex
+	items map[string][]byte
+	conns int
+}
+
+func newFakeMetaServer(t *testing.T, creds string) *fakeMetaServer {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	s := &fakeMetaServer{ln: ln, creds: creds, items: map[string][]byte{}}
+	t.Cleanup(func() {
+		_ = ln.Close()
+	})
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			s.mut.Lock()
+			s.conns++
+			s.mut.Unlock()
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *fakeMetaServer) addr() string {
+	return s.ln.Addr().String()
+}
+
+func (s *fakeMetaServer) connCount() int {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	return s.conns
+}
+
+func (s *fakeMetaServer) serve(conn net.Conn) {
+	defer conn.Close()
+
+	rw := bufio.NewReadWriter(bufio.NewReader(conn), bufio.NewWriter(conn))
+	authed := s.creds == ""
+
+	readData := func(sizeStr string) ([]byte, error) {
+		size, err := strconv.Atoi(sizeStr)
+		if err != nil {
+			return nil, err
+		}
+		data := make([]byte, size+2)
+		if _, err := io.ReadFull(rw, data); err != nil {
+			return nil, err
+		}
+		return data[:size], nil
+	}
+
+	for {
+		line, err := rw.ReadString('\n')
+		if err != nil {
+			return
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			return
+		}
+
+		s.mut.Lock()
+		switch {
+		case fields[0] == "set" && len(fields) == 5:
+			data, err := readData(fields[4])
+			if err != nil {
+				s.mut.Unlock()
+				return
+			}
+			if string(data) == s.creds {
+				authed = true
+				_, _ = rw.WriteString("STORED\r\n")
+			} else {
+				_, _ = rw.WriteString("CLIENT_ERROR authentication failure\r\n")
+			}
+		case !authed:
+			_, _ = rw.WriteString("CLIENT_ERROR unauthenticated\r\n")
+		case fields[0] == "mg":
+			if v, exists := s.items[fields[1]]; exists {
+				_, _ = fmt.Fprintf(rw, "VA %d\r\n%s\r\n", len(v), v)
+			} else {
+				_, _ = rw.WriteString("EN\r\n")
+			}
+		case fields[0] == "ms":
+			data, err := readData(fields[2])
+			if err != nil {
+				s.mut.Unlock()
+				return
+			}
+			_, exists := s.items[fields[1]]
+			if exists && strings.Contains(line, " ME") {
+				_, _ = rw.WriteString("NS\r\n")
+			} else {
+				s.items[fields[1]] = data
+				_, _ = rw.WriteString("HD\r\n")
+			}
+		case fields[0] == "md":
+			if _, exists := s.items[fields[1]]; exists {
+				delete(s.items, fields[1])
+				_, _ = rw.WriteString("HD\r\n")
+			} else {
+				_, _ = rw.WriteString("NF\r\n")
+			}
+		default:
+			_, _ = rw.WriteString("ERROR\r\n")
+		}
+		s.mut.Unlock()
+
+		if rw.Reader.Buffered() == 0 {
+			if err := rw.Flush(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func testMemcachedCache(t *testing.T, confStr string) *memcachedCache {
+	t.Helper()
+
+	conf, err := memcachedConfig().ParseYAML(confStr, nil)
+	require.NoError(t, err)
+
+	c, err := newMemcachedFromConfig(conf)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = c.Close(context.Background())
+	})
+	return c
+}
+
+func TestMemcachedCacheOperations(t *testing.T) {
+	srv := newFakeMetaServer(t, "")
+	c := testMemcachedCache(t, fmt.Sprintf(`
+addresses: [ %v ]
+prefix: foo_
+`, srv.addr()))
+
+	ctx := context.Background()
+
+	_, err := c.Get(ctx, "a")
+	assert.Equal(t, service.ErrKeyNotFound, err)
+
+	require.NoError(t, c.Set(ctx, "a", []byte("a value"), nil))
+	require.NoError(t, c.Add(ctx, "b", []byte("b value"), nil))
+	assert.Equal(t, service.ErrKeyAlreadyExists, c.Add(ctx, "a", []byte("nope"), nil))
+
+	v, err := c.Get(ctx, "a")
+	require.NoError(t, err)
+	assert.Equal(t, "a value", string(v))
+
+	values, err := c.GetMulti(ctx, "a", "b", "c")
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]byte{
+		"a": []byte("a value"),
+		"b": []byte("b value"),
+	}, values)
+
+	require.NoError(t, c.Delete(ctx, "a"))
+	require.NoError(t, c.Delete(ctx, "a"))
+
+	_, err = c.Get(ctx, "a")
+	assert.Equal(t, service.ErrKeyNotFound, err)
+
+	srv.mut.Lock()
+	assert.Equal(t, map[string][]byte{"foo_b": []byte("b value")}, srv.items)
+	srv.mut.Unlock()
+
+	assert.Equal(t, 1, srv.connCount())
+
+	assert.ErrorIs(t, c.Set(ctx, "has spaces", []byte("nope"), nil), errMalformedKey)
+}
+
+func TestMemcachedCacheGetMultiPipelined(t *testing.T) {
+	srvA, srvB := newFakeMetaServer(t, ""), newFakeMetaServer(t, "")
+	c := testMemcachedCache(t, fmt.Sprintf(`
+addresses: [ %v, %v ]
+server_selection: consistent
+`, srvA.addr(), srvB.addr()))
+
+	ctx := context.Background()
+
+	var keys []string
+	exp := map[string][]byte{}
+	for i := 0; i < 100; i++ {
+		k := fmt.Sprintf("key%v", i)
+		keys = append(keys, k)
+		if i%2 == 0 {
+			require.NoError(t, c.Set(ctx, k, []byte(k+" value"), nil))
+			exp[k] = []byte(k + " value")
+		}
+	}
+
+	values, err := c.GetMulti(ctx, keys...)
+	require.NoError(t, err)
+	assert.Equal(t, exp, values)
+
+	srvA.mut.Lock()
+	srvB.mut.Lock()
+	assert.NotEmpty(t, srvA.items)
+	assert.NotEmpty(t, srvB.items)
+	assert.Equal(t, 50, len(srvA.items)+len(srvB.items))
+	srvB.mut.Unlock()
+	srvA.mut.Unlock()
+}
+
+func TestMemcachedCacheAuth(t *testing.T) {
+	srv := newFakeMetaServer(t, "foo bar")
+	ctx := context.Background()
+
+	c := testMemcachedCache(t, fmt.Sprintf(`
+addresses: [ %v ]
+auth:
+  username: foo
+  password: bar
+`, srv.addr()))
+
+	require.NoError(t, c.Set(ctx, "a", []byte("a value"), nil))
+	v, err := c.Get(ctx, "a")
+	require.NoError(t, err)
+	assert.Equal(t, "a value", string(v))
+
+	c = testMemcachedCache(t, fmt.Sprintf(`
+addresses: [ %v ]
+auth:
+  username: foo
+  password: nope
+retries:
+  initial_interval: 1ms
+  max_elapsed_time: 10ms
+`, srv.addr()))
+
+	err = c.Set(ctx, "a", []byte("a value"), nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to authenticate")
+}
+
+func TestMemcachedCacheEjection(t *testing.T) {
+	srvA, srvB := newFakeMetaServer(t, ""), newFakeMetaServer(t, "")
+	c := testMemcachedCache(t, fmt.Sprintf(`
+addresses: [ %v, %v ]
+server_selection: consistent
+ejection:
+  failures: 1
+  period: 1h
+retries:
+  initial_interval: 1ms
+`, srvA.addr(), srvB.addr()))
+
+	ctx := context.Background()
+
+	// Find a key held by the server that we're about to stop.
+	var key string
+	for i := 0; ; i++ {
+		key = fmt.Sprintf("key%v", i)
+		if c.mc.selector.pick(key) == 1 {
+			break
+		}
+	}
+	require.NoError(t, srvB.ln.Close())
+
+	// The first attempt fails and ejects the server, and the retry is then
+	// performed on the remaining server.
+	require.NoError(t, c.Set(ctx, key, []byte("value"), nil))
+	assert.Equal(t, 0, c.mc.selector.pick(key))
+
+	srvA.mut.Lock()
+	assert.Equal(t, []byte("value"), srvA.items[key])
+	srvA.mut.Unlock()
+}
+
+func TestServerSelectorModulo(t *testing.T) {
+	s := newServerSelector([]string{"a", "b", "c"}, false, 0, 0)
+	for _, test := range []struct {
+		key    string
+		server int
+	}{
+		{key: "foo", server: 2},
+		{key: "bar", server: 2},
+		{key: "baz", server: 0},
+	} {
+		assert.Equal(t, test.server, s.pick(test.key), test.key)
+	}
+}
+
+func TestServerSelectorConsistent(t *testing.T) {
+	addrs := []string{"a:11211", "b:11211", "c:11211"}
+	before := newServerSelector(addrs, true, 0, 0)
+	after := newServerSelector(append(addrs, "d:11211"), true, 0, 0)
+
+	var moved, movedElsewhere int
+	for i := 0; i < 1000; i++ {
+		key := fmt.Sprintf("key%v", i)
+		b, a := before.pick(key), after.pick(key)
+		if a != b {
+			moved++
+			if a != 3 {
+				movedElsewhere++
+			}
+		}
+	}
+
+	// Only the keys claimed by the new server move, which should be roughly a
+	// quarter of them.
+	assert.Zero(t, movedElsewhere)
+	assert.Greater(t, moved, 150)
+	assert.Less(t, moved, 350)
+}
+
+func TestServerSelectorEjection(t *testing.T) {
+	now := time.Unix(0, 0)
+	s := newServerSelector([]string{"a", "b"}, true, 2, time.Minute)
+	s.nowFn = func() time.Time { return now }
+
+	var key string
+	for i := 0; ; i++ {
+		key = fmt.Sprintf("key%v", i)
+		if s.pick(key) == 0 {
+			break
+		}
+	}
+
+	s.markResult(0, true)
+	s.markResult(0, false)
+	s.markResult(0, true)
+	assert.Equal(t, 0, s.pick(key))
+
+	s.markResult(0, true)
+	assert.Equal(t, 1, s.pick(key))
+
+	// All servers ejected means all are selectable.
+	s.markResult(1, true)
+	s.markResult(1, true)
+	assert.Equal(t, 0, s.pick(key))
+
+	now = now.Add(time.Minute)
+	s.markResult(1, false)
+	assert.Equal(t, 0, s.pick(key))
+}
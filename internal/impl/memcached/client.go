@@ -0,0 +1,392 @@
+package memcached
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	errMalformedKey = errors.New("malformed key, keys must be between 1 and 250 bytes without spaces or control characters")
+	errClientClosed = errors.New("client closed")
+)
+
+// metaResponseError is a response from a server that indicates a failed
+// command rather than a failed server, such as CLIENT_ERROR.
+type metaResponseError struct {
+	line string
+}
+
+func (e *metaResponseError) Error() string {
+	return fmt.Sprintf("unexpected response: %v", e.line)
+}
+
+func validKey(key string) bool {
+	if key == "" || len(key) > 250 {
+		return false
+	}
+	for i := 0; i < len(key); i++ {
+		if key[i] <= ' ' || key[i] == 0x7f {
+			return false
+		}
+	}
+	return true
+}
+
+//------------------------------------------------------------------------------
+
+type metaConn struct {
+	nc net.Conn
+	rw *bufio.ReadWriter
+}
+
+func (c *metaConn) readLine() (string, error) {
+	line, err := c.rw.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(line, "\r\n"), nil
+}
+
+// readValue reads the response to an mg command with the v flag, returning
+// nil without an error when the key was not found.
+func (c *metaConn) readValue() ([]byte, error) {
+	line, err := c.readLine()
+	if err != nil {
+		return nil, err
+	}
+	if line == "EN" {
+		return nil, nil
+	}
+
+	fields := strings.Fields(line)
+	if len(fields) < 2 || fields[0] != "VA" {
+		return nil, &metaResponseError{line: line}
+	}
+	size, err := strconv.Atoi(fields[1])
+	if err != nil || size < 0 {
+		return nil, &metaResponseError{line: line}
+	}
+
+	value := make([]byte, size+2)
+	if _, err := io.ReadFull(c.rw, value); err != nil {
+		return nil, err
+	}
+	if !bytes.HasSuffix(value, []byte("\r\n")) {
+		return nil, &metaResponseError{line: line}
+	}
+	return value[:size], nil
+}
+
+// readStatus reads the response to an ms or md command, returning whether the
+// command took effect.
+func (c *metaConn) readStatus() (bool, error) {
+	line, err := c.readLine()
+	if err != nil {
+		return false, err
+	}
+	switch line {
+	case "HD":
+		return true, nil
+	case "NS", "NF":
+		return false, nil
+	}
+	return false, &metaResponseError{line: line}
+}
+
+//------------------------------------------------------------------------------
+
+// metaClient is a client for a set of memcached servers using the meta text
+// protocol, with a pool of idle connections for each server.
+type metaClient struct {
+	selector *serverSelector
+	dialer   net.Dialer
+	tlsConf  *tls.Config
+	username string
+	password string
+	timeout  time.Duration
+	maxIdle  int
+
+	poolsMut sync.Mutex
+	pools    [][]*metaConn
+	closed   bool
+}
+
+func newMetaClient(selector *serverSelector, tlsConf *tls.Config, username, password string, timeout time.Duration, maxIdle int) *metaClient {
+	return &metaClient{
+		selector: selector,
+		dialer:   net.Dialer{Timeout: timeout},
+		tlsConf:  tlsConf,
+		username: username,
+		password: password,
+		timeout:  timeout,
+		maxIdle:  maxIdle,
+		pools:    make([][]*metaConn, len(selector.addrs)),
+	}
+}
+
+func (m *metaClient) dial(ctx context.Context, server int) (*metaConn, error) {
+	addr := m.selector.addrs[server]
+
+	var nc net.Conn
+	var err error
+	if m.tlsConf != nil {
+		nc, err = (&tls.Dialer{NetDialer: &m.dialer, Config: m.tlsConf}).DialContext(ctx, "tcp", addr)
+	} else {
+		nc, err = m.dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	c := &metaConn{
+		nc: nc,
+		rw: bufio.NewReadWriter(bufio.NewReader(nc), bufio.NewWriter(nc)),
+	}
+	if m.username != "" {
+		if err := m.authenticate(c); err != nil {
+			_ = nc.Close()
+			return nil, fmt.Errorf("failed to authenticate with %v: %w", addr, err)
+		}
+	}
+	return c, nil
+}
+
+// authenticate sends credentials with the ASCII authentication of memcached,
+// which is a set command of any key where the value is the username and
+// password separated by a space.
+func (m *metaClient) authenticate(c *metaConn) error {
+	_ = c.nc.SetDeadline(time.Now().Add(m.timeout))
+
+	creds := m.username + " " + m.password
+	if _, err := fmt.Fprintf(c.rw, "set auth 0 0 %d\r\n%s\r\n", len(creds), creds); err != nil {
+		return err
+	}
+	if err := c.rw.Flush(); err != nil {
+		return err
+	}
+	line, err := c.readLine()
+	if err != nil {
+		return err
+	}
+	if line != "STORED" {
+		return &metaResponseError{line: line}
+	}
+	return nil
+}
+
+func (m *metaClient) getConn(ctx context.Context, server int) (*metaConn, error) {
+	m.poolsMut.Lock()
+	if m.closed {
+		m.poolsMut.Unlock()
+		return nil, errClientClosed
+	}
+	if idle := m.pools[server]; len(idle) > 0 {
+		c := idle[len(idle)-1]
+		m.pools[server] = idle[:len(idle)-1]
+		m.poolsMut.Unlock()
+		return c, nil
+	}
+	m.poolsMut.Unlock()
+	return m.dial(ctx, server)
+}
+
+func (m *metaClient) putConn(server int, c *metaConn) {
+	m.poolsMut.Lock()
+	defer m.poolsMut.Unlock()
+
+	if m.closed || len(m.pools[server]) >= m.maxIdle {
+		_ = c.nc.Close()
+		return
+	}
+	m.pools[server] = append(m.pools[server], c)
+}
+
+// withConn runs a closure with a connection to a server, where the connection
+// is discarded if the closure fails. Failures other than unexpected responses
+// count towards the ejection of the server.
+func (m *metaClient) withConn(ctx context.Context, server int, fn func(c *metaConn) error) error {
+	c, err := m.getConn(ctx, server)
+	if err != nil {
+		if !errors.Is(err, errClientClosed) {
+			m.selector.markResult(server, true)
+		}
+		return err
+	}
+
+	deadline := time.Now().Add(m.timeout)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+	_ = c.nc.SetDeadline(deadline)
+
+	if err = fn(c); err != nil {
+		_ = c.nc.Close()
+		var rErr *metaResponseError
+		m.selector.markResult(server, !errors.As(err, &rErr))
+		return err
+	}
+	m.selector.markResult(server, false)
+	m.putConn(server, c)
+	return nil
+}
+
+// Get returns the value of a key, or nil when the key does not exist.
+func (m *metaClient) Get(ctx context.Context, key string) (value []byte, err error) {
+	if !validKey(key) {
+		return nil, errMalformedKey
+	}
+	err = m.withConn(ctx, m.selector.pick(key), func(c *metaConn) error {
+		if _, err := fmt.Fprintf(c.rw, "mg %s v\r\n", key); err != nil {
+			return err
+		}
+		if err := c.rw.Flush(); err != nil {
+			return err
+		}
+		value, err = c.readValue()
+		return err
+	})
+	return
+}
+
+// GetMulti returns the values of keys that exist, where the commands for each
+// server are pipelined over a single connection and servers are requested
+// concurrently.
+func (m *metaClient) GetMulti(ctx context.Context, keys []string) (map[string][]byte, error) {
+	byServer := map[int][]string{}
+	for _, key := range keys {
+		if !validKey(key) {
+			return nil, errMalformedKey
+		}
+		server := m.selector.pick(key)
+		byServer[server] = append(byServer[server], key)
+	}
+
+	var wg sync.WaitGroup
+	var resMut sync.Mutex
+	values := make(map[string][]byte, len(keys))
+	var firstErr error
+
+	for server, serverKeys := range byServer {
+		wg.Add(1)
+		go func(server int, serverKeys []string) {
+			defer wg.Done()
+
+			err := m.withConn(ctx, server, func(c *metaConn) error {
+				for _, key := range serverKeys {
+					if _, err := fmt.Fprintf(c.rw, "mg %s v\r\n", key); err != nil {
+						return err
+					}
+				}
+				if err := c.rw.Flush(); err != nil {
+					return err
+				}
+				for _, key := range serverKeys {
+					value, err := c.readValue()
+					if err != nil {
+						return err
+					}
+					if value != nil {
+						resMut.Lock()
+						values[key] = value
+						resMut.Unlock()
+					}
+				}
+				return nil
+			})
+			if err != nil {
+				resMut.Lock()
+				if firstErr == nil {
+					firstErr = err
+				}
+				resMut.Unlock()
+			}
+		}(server, serverKeys)
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return values, nil
+}
+
+func (m *metaClient) store(ctx context.Context, mode, key string, value []byte, ttlSeconds int32) (stored bool, err error) {
+	if !validKey(key) {
+		return false, errMalformedKey
+	}
+	err = m.withConn(ctx, m.selector.pick(key), func(c *metaConn) error {
+		if _, err := fmt.Fprintf(c.rw, "ms %s %d T%d M%s\r\n", key, len(value), ttlSeconds, mode); err != nil {
+			return err
+		}
+		if _, err := c.rw.Write(value); err != nil {
+			return err
+		}
+		if _, err := c.rw.WriteString("\r\n"); err != nil {
+			return err
+		}
+		if err := c.rw.Flush(); err != nil {
+			return err
+		}
+		stored, err = c.readStatus()
+		return err
+	})
+	return
+}
+
+// Set sets the value of a key, where a TTL of zero never expires.
+func (m *metaClient) Set(ctx context.Context, key string, value []byte, ttlSeconds int32) error {
+	stored, err := m.store(ctx, "S", key, value, ttlSeconds)
+	if err == nil && !stored {
+		err = errors.New("item not stored")
+	}
+	return err
+}
+
+// Add sets the value of a key only if it does not already exist, returning
+// whether the value was set.
+func (m *metaClient) Add(ctx context.Context, key string, value []byte, ttlSeconds int32) (bool, error) {
+	return m.store(ctx, "E", key, value, ttlSeconds)
+}
+
+// Delete removes a key, returning whether it existed.
+func (m *metaClient) Delete(ctx context.Context, key string) (deleted bool, err error) {
+	if !validKey(key) {
+		return false, errMalformedKey
+	}
+	err = m.withConn(ctx, m.selector.pick(key), func(c *metaConn) error {
+		if _, err := fmt.Fprintf(c.rw, "md %s\r\n", key); err != nil {
+			return err
+		}
+		if err := c.rw.Flush(); err != nil {
+			return err
+		}
+		deleted, err = c.readStatus()
+		return err
+	})
+	return
+}
+
+// Close closes all idle connections, connections that are in use are closed
+// once they are returned.
+func (m *metaClient) Close() {
+	m.poolsMut.Lock()
+	defer m.poolsMut.Unlock()
+
+	m.closed = true
+	for i, idle := range m.pools {
+		for _, c := range idle {
+			_ = c.nc.Close()
+		}
+		m.pools[i] = nil
+	}
+}
@@ -0,0 +1,145 @@
+package memcached
+
+import (
+	"crypto/md5"
+	"encoding/binary"
+	"hash/crc32"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// The number of points on the ring of consistent hashes for each server, where
+// each md5 digest yields four points. This matches the ketama algorithm used
+// by other memcached clients.
+const ketamaDigestsPerServer = 40
+
+type ringPoint struct {
+	hash   uint32
+	server int
+}
+
+type serverHealth struct {
+	failures     int
+	ejectedUntil time.Time
+}
+
+// serverSelector picks the server that holds a key, and tracks consecutive
+// failures of servers in order to eject them from selection for a period.
+type serverSelector struct {
+	addrs      []string
+	consistent bool
+	ring       []ringPoint
+
+	ejectFailures int
+	ejectPeriod   time.Duration
+	nowFn         func() time.Time
+
+	healthMut sync.Mutex
+	health    []serverHealth
+}
+
+func newServerSelector(addrs []string, consistent bool, ejectFailures int, ejectPeriod time.Duration) *serverSelector {
+	s := &serverSelector{
+		addrs:         addrs,
+		consistent:    consistent,
+		ejectFailures: ejectFailures,
+		ejectPeriod:   ejectPeriod,
+		nowFn:         time.Now,
+		health:        make([]serverHealth, len(addrs)),
+	}
+	if consistent {
+		for i, addr := range addrs {
+			for j := 0; j < ketamaDigestsPerServer; j++ {
+				digest := md5.Sum([]byte(addr + "-" + strconv.Itoa(j)))
+				for k := 0; k < 4; k++ {
+					s.ring = append(s.ring, ringPoint{
+						hash:   binary.LittleEndian.Uint32(digest[k*4:]),
+						server: i,
+					})
+				}
+			}
+		}
+		sort.Slice(s.ring, func(i, j int) bool {
+			return s.ring[i].hash < s.ring[j].hash
+		})
+	}
+	return s
+}
+
+// live returns which servers are currently eligible for selection. When all
+// servers are ejected they are all considered live, as there's nothing better
+// to try.
+func (s *serverSelector) live() []bool {
+	live := make([]bool, len(s.addrs))
+	anyLive := false
+
+	now := s.nowFn()
+	s.healthMut.Lock()
+	for i, h := range s.health {
+		if live[i] = !now.Before(h.ejectedUntil); live[i] {
+			anyLive = true
+		}
+	}
+	s.healthMut.Unlock()
+
+	if !anyLive {
+		for i := range live {
+			live[i] = true
+		}
+	}
+	return live
+}
+
+// pick returns the index of the server that holds a key.
+func (s *serverSelector) pick(key string) int {
+	if len(s.addrs) == 1 {
+		return 0
+	}
+	live := s.live()
+
+	if s.consistent {
+		digest := md5.Sum([]byte(key))
+		h := binary.LittleEndian.Uint32(digest[:4])
+		start := sort.Search(len(s.ring), func(i int) bool {
+			return s.ring[i].hash >= h
+		})
+		for i := 0; i < len(s.ring); i++ {
+			p := s.ring[(start+i)%len(s.ring)]
+			if live[p.server] {
+				return p.server
+			}
+		}
+		return s.ring[start%len(s.ring)].server
+	}
+
+	liveIndexes := make([]int, 0, len(s.addrs))
+	for i, l := range live {
+		if l {
+			liveIndexes = append(liveIndexes, i)
+		}
+	}
+	return liveIndexes[crc32.ChecksumIEEE([]byte(key))%uint32(len(liveIndexes))]
+}
+
+// markResult records whether a request to a server failed, ejecting the server
+// once it has failed consecutively enough times.
+func (s *serverSelector) markResult(server int, failed bool) {
+	if s.ejectFailures <= 0 {
+		return
+	}
+
+	s.healthMut.Lock()
+	defer s.healthMut.Unlock()
+
+	h := &s.health[server]
+	if !failed {
+		h.failures = 0
+		return
+	}
+	if h.failures++; h.failures >= s.ejectFailures {
+		h.failures = 0
+		h.ejectedUntil = s.nowFn().Add(s.ejectPeriod)
+	}
+}
@@ -1,12 +1,15 @@
 package mqtt
 
 import (
+	"context"
 	"crypto/tls"
 	"errors"
 	"fmt"
+	"net"
 	"net/url"
 	"time"
 
+	"github.com/eclipse/paho.golang/paho"
 	mqtt "github.com/eclipse/paho.mqtt.golang"
 	gonanoid "github.com/matoous/go-nanoid/v2"
 
@@ -167,6 +170,59 @@ func (b *clientOptsBuilder) apply(opts *mqtt.ClientOptions) *mqtt.ClientOptions
 	return opts
 }
 
+// dial opens a network connection to the first reachable URL, which is used by
+// MQTT 5 clients that do not manage their own connections.
+func (b *clientOptsBuilder) dial(ctx context.Context) (net.Conn, error) {
+	var errs []error
+	for _, u := range b.urls {
+		conn, err := b.dialURL(ctx, u)
+		if err == nil {
+			return conn, nil
+		}
+		errs = append(errs, fmt.Errorf("%v: %w", u.Host, err))
+	}
+	if len(errs) == 0 {
+		return nil, errors.New("no urls specified")
+	}
+	return nil, errors.Join(errs...)
+}
+
+func (b *clientOptsBuilder) dialURL(ctx context.Context, u *url.URL) (net.Conn, error) {
+	ctx, done := context.WithTimeout(ctx, b.connectTimeout)
+	defer done()
+
+	netDialer := &net.Dialer{}
+	switch u.Scheme {
+	case "tcp", "mqtt":
+		return netDialer.DialContext(ctx, "tcp", u.Host)
+	case "ssl", "tls", "mqtts", "tcps":
+		tlsDialer := &tls.Dialer{NetDialer: netDialer, Config: b.tlsConf}
+		return tlsDialer.DialContext(ctx, "tcp", u.Host)
+	}
+	return nil, fmt.Errorf("url scheme %v is not supported with MQTT 5", u.Scheme)
+}
+
+func (b *clientOptsBuilder) connectPacket() *paho.Connect {
+	cp := &paho.Connect{
+		KeepAlive:    uint16(b.keepAlive),
+		ClientID:     b.clientID,
+		CleanStart:   true,
+		Username:     b.username,
+		UsernameFlag: b.username != "",
+		Password:     []byte(b.password),
+		PasswordFlag: b.password != "",
+	}
+	if b.will.Enabled {
+		cp.WillMessage = &paho.WillMessage{
+			Retain:  b.will.Retained,
+			QoS:     b.will.QoS,
+			Topic:   b.will.Topic,
+			Payload: []byte(b.will.Payload),
+		}
+	}
+	return cp
+}
+
 func willOptFromParsed(conf *service.ParsedConfig) (opt willOpt, err error) {
 	if opt.Enabled, err = conf.FieldBool(msFieldClientWillEnabled); err != nil {
 		return
@@ -3,10 +3,12 @@ package mqtt
 import (
 	"context"
 	"fmt"
+	"math"
 	"strconv"
 	"sync"
 	"time"
 
+	"github.com/eclipse/paho.golang/paho"
 	mqtt "github.com/eclipse/paho.mqtt.golang"
 
 	"github.com/benthosdev/benthos/v4/public/service"
@@ -18,6 +20,13 @@ const (
 	moFieldWriteTimeout         = "write_timeout"
 	moFieldRetained             = "retained"
 	moFieldRetainedInterpolated = "retained_interpolated"
+	moFieldProtocolVersion      = "protocol_version"
+	moFieldTopicAliasMaximum    = "topic_alias_maximum"
+	moFieldMessageExpiry        = "message_expiry"
+	moFieldUserProperties       = "user_properties"
+	moFieldContentType          = "content_type"
+	moFieldResponseTopic        = "response_topic"
+	moFieldCorrelationData      = "correlation_data"
 )
 
 func outputConfigSpec() *service.ConfigSpec {
@@ -26,7 +35,15 @@ func outputConfigSpec() *service.ConfigSpec {
 		Categories("Services").
 		Summary("Pushes messages to an MQTT broker.").
 		Description(`
-The `+"`topic`"+` field can be dynamically set using function interpolations described [here](/docs/configuration/interpolation#bloblang-queries). When sending batched messages these interpolations are performed per message part.`+service.OutputPerformanceDocs(true, false)).
+The `+"`topic`"+` field can be dynamically set using function interpolations described [here](/docs/configuration/interpolation#bloblang-queries). When sending batched messages these interpolations are performed per message part.
+
+### MQTT 5
+
+Setting `+"`protocol_version`"+` to `+"`5`"+` connects to the broker with MQTT 5, which enables the fields `+"`topic_alias_maximum`, `message_expiry`, `user_properties`, `content_type`, `response_topic` and `correlation_data`"+`.
+
+Topic aliases reduce the size of messages published to long topics by sending the topic only with the first message published to it, and a two byte alias with each message afterwards. Aliases are assigned to topics in the order they are first published to, up to the lower of `+"`topic_alias_maximum`"+` and the maximum allowed by the broker, and are reassigned each time the output reconnects.
+
+Only URLs with the schemes `+"`tcp`, `mqtt`, `ssl`, `tls`, `tcps` and `mqtts`"+` are supported with MQTT 5.`+service.OutputPerformanceDocs(true, false)).
 		Fields(ClientFields()...).
 		Fields(
 			service.NewInterpolatedStringField(moFieldTopic).
@@ -47,8 +64,58 @@ The `+"`topic`"+` field can be dynamically set using function interpolations des
 				Advanced().
 				Optional().
 				Version("3.59.0"),
+			service.NewStringEnumField(moFieldProtocolVersion, "3.1.1", "5").
+				Description("The version of the MQTT protocol to connect with.").
+				Default("3.1.1").
+				Version("4.28.0"),
+			service.NewIntField(moFieldTopicAliasMaximum).
+				Description("The maximum number of topic aliases to assign, which is further limited by the maximum allowed by the broker. Setting this to zero disables topic aliases. Requires MQTT 5.").
+				Advanced().
+				Default(0).
+				Version("4.28.0"),
+			service.NewDurationField(moFieldMessageExpiry).
+				Description("An optional period after which messages that have not yet been delivered to subscribers are discarded by the broker. Requires MQTT 5.").
+				Example("60s").
+				Optional().
+				Version("4.28.0"),
+			service.NewMetadataFilterField(moFieldUserProperties).
+				Description("Determine which (if any) metadata values should be added to messages as user properties. Requires MQTT 5.").
+				Optional().
+				Advanced().
+				Version("4.28.0"),
+			service.NewInterpolatedStringField(moFieldContentType).
+				Description("An optional content type to set for each message. Requires MQTT 5.").
+				Example("application/json").
+				Optional().
+				Advanced().
+				Version("4.28.0"),
+			service.NewInterpolatedStringField(moFieldResponseTopic).
+				Description("An optional topic that receivers of a message should publish responses to, for request and response messaging. Requires MQTT 5.").
+				Example(`${! @reply_to }`).
+				Optional().
+				Advanced().
+				Version("4.28.0"),
+			service.NewInterpolatedStringField(moFieldCorrelationData).
+				Description("Optional correlation data to set for each message, which receivers include within responses in order to identify the request. Requires MQTT 5.").
+				Example(`${! @request_id }`).
+				Optional().
+				Advanced().
+				Version("4.28.0"),
 			service.NewOutputMaxInFlightField(),
-		)
+		).
+		Example("MQTT 5 Request Messages", "Publish requests to per device topics with MQTT 5, using topic aliases to reduce the overhead of the long topics, and setting a response topic and correlation data so that responses can be matched with requests.", `
+output:
+  mqtt:
+    urls: [ tcp://localhost:1883 ]
+    topic: 'factory/${! @site }/devices/${! @device_id }/commands'
+    protocol_version: "5"
+    topic_alias_maximum: 100
+    message_expiry: 30s
+    user_properties:
+      include_prefixes: [ trace_ ]
+    response_topic: 'factory/${! @site }/responses'
+    correlation_data: '${! @request_id }'
+`)
 }
 
 func init() {
@@ -75,8 +142,77 @@ type mqttWriter struct {
 	retainedInterp *service.InterpolatedString
 	qos            uint8
 
-	client  mqtt.Client
-	connMut sync.RWMutex
+	v5 *mqtt5Opts
+
+	client   mqtt.Client
+	client5  *paho.Client
+	aliases5 *topicAliases
+	connMut  sync.RWMutex
+}
+
+type mqtt5Opts struct {
+	topicAliasMaximum uint16
+	messageExpiry     *uint32
+	userProperties    *service.MetadataFilter
+	contentType       *service.InterpolatedString
+	responseTopic     *service.InterpolatedString
+	correlationData   *service.InterpolatedString
+}
+
+func mqtt5OptsFromParsed(conf *service.ParsedConfig) (*mqtt5Opts, error) {
+	protocolVersion, err := conf.FieldString(moFieldProtocolVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	var o mqtt5Opts
+
+	tmpAliasMax, err := conf.FieldInt(moFieldTopicAliasMaximum)
+	if err != nil {
+		return nil, err
+	}
+	if tmpAliasMax < 0 || tmpAliasMax > math.MaxUint16 {
+		return nil, fmt.Errorf("%v must be between 0 and %v", moFieldTopicAliasMaximum, math.MaxUint16)
+	}
+	o.topicAliasMaximum = uint16(tmpAliasMax)
+
+	if conf.Contains(moFieldMessageExpiry) {
+		expiry, err := conf.FieldDuration(moFieldMessageExpiry)
+		if err != nil {
+			return nil, err
+		}
+		expirySecs := uint32(expiry.Seconds())
+		o.messageExpiry = &expirySecs
+	}
+	if conf.Contains(moFieldUserProperties) {
+		if o.userProperties, err = conf.FieldMetadataFilter(moFieldUserProperties); err != nil {
+			return nil, err
+		}
+	}
+	for _, f := range []struct {
+		name   string
+		target **service.InterpolatedString
+	}{
+		{moFieldContentType, &o.contentType},
+		{moFieldResponseTopic, &o.responseTopic},
+		{moFieldCorrelationData, &o.correlationData},
+	} {
+		if !conf.Contains(f.name) {
+			continue
+		}
+		if *f.target, err = conf.FieldInterpolatedString(f.name); err != nil {
+			return nil, err
+		}
+	}
+
+	if protocolVersion == "5" {
+		return &o, nil
+	}
+	if o.topicAliasMaximum > 0 || o.messageExpiry != nil || o.userProperties != nil ||
+		o.contentType != nil || o.responseTopic != nil || o.correlationData != nil {
+		return nil, fmt.Errorf("%v must be set to 5 in order to use MQTT 5 fields", moFieldProtocolVersion)
+	}
+	return nil, nil
 }
 
 func newMQTTWriterFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*mqttWriter, error) {
@@ -108,6 +244,10 @@ func newMQTTWriterFromParsed(conf *service.ParsedConfig, mgr *service.Resources)
 		return nil, err
 	}
 	m.qos = uint8(tmpQoS)
+
+	if m.v5, err = mqtt5OptsFromParsed(conf); err != nil {
+		return nil, err
+	}
 	return m, nil
 }
 
@@ -115,9 +255,12 @@ func (m *mqttWriter) Connect(ctx context.Context) error {
 	m.connMut.Lock()
 	defer m.connMut.Unlock()
 
-	if m.client != nil {
+	if m.client != nil || m.client5 != nil {
 		return nil
 	}
+	if m.v5 != nil {
+		return m.connectV5(ctx)
+	}
 
 	conf := m.clientBuilder.apply(mqtt.NewClientOptions()).
 		SetConnectionLostHandler(func(client mqtt.Client, reason error) {
@@ -139,14 +282,6 @@ func (m *mqttWriter) Connect(ctx context.Context) error {
 }
 
 func (m *mqttWriter) Write(ctx context.Context, msg *service.Message) error {
-	m.connMut.RLock()
-	client := m.client
-	m.connMut.RUnlock()
-
-	if client == nil {
-		return service.ErrNotConnected
-	}
-
 	retained := m.retained
 	if m.retainedInterp != nil {
 		retainedStr, parseErr := m.retainedInterp.TryString(msg)
@@ -167,6 +302,18 @@ func (m *mqttWriter) Write(ctx context.Context, msg *service.Message) error {
 		return err
 	}
 
+	if m.v5 != nil {
+		return m.writeV5(ctx, msg, topicStr, retained, mBytes)
+	}
+
+	m.connMut.RLock()
+	client := m.client
+	m.connMut.RUnlock()
+
+	if client == nil {
+		return service.ErrNotConnected
+	}
+
 	mtok := client.Publish(topicStr, m.qos, retained, mBytes)
 	mtok.Wait()
 	sendErr := mtok.Error()
@@ -187,5 +334,185 @@ func (m *mqttWriter) Close(context.Context) error {
 		m.client.Disconnect(0)
 		m.client = nil
 	}
+	if m.client5 != nil {
+		_ = m.client5.Disconnect(&paho.Disconnect{ReasonCode: 0})
+		m.client5 = nil
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+func (m *mqttWriter) connectV5(ctx context.Context) error {
+	conn, err := m.clientBuilder.dial(ctx)
+	if err != nil {
+		return err
+	}
+
+	var client *paho.Client
+	onLost := func(reason error) {
+		m.log.Errorf("Connection lost due to: %v", reason)
+
+		// The client might report errors whilst we hold the connection lock
+		// within Connect, and so the client is reset asynchronously.
+		go func() {
+			m.connMut.Lock()
+			if m.client5 == client {
+				m.client5 = nil
+			}
+			m.connMut.Unlock()
+		}()
+	}
+
+	client = paho.NewClient(paho.ClientConfig{
+		Conn:     conn,
+		ClientID: m.clientBuilder.clientID,
+		OnClientError: func(err error) {
+			onLost(err)
+		},
+		OnServerDisconnect: func(d *paho.Disconnect) {
+			onLost(fmt.Errorf("server disconnected with reason code %v", d.ReasonCode))
+		},
+	})
+
+	connCtx, done := context.WithTimeout(ctx, m.clientBuilder.connectTimeout)
+	defer done()
+
+	ack, err := client.Connect(connCtx, m.clientBuilder.connectPacket())
+	if err != nil {
+		_ = conn.Close()
+		return err
+	}
+	if ack.ReasonCode >= 0x80 {
+		_ = conn.Close()
+		return fmt.Errorf("connection refused with reason code %v", ack.ReasonCode)
+	}
+
+	// The broker advertises the number of aliases it accepts, which is zero
+	// (aliases are disabled) when not advertised.
+	var brokerAliasMax uint16
+	if ack.Properties != nil && ack.Properties.TopicAliasMaximum != nil {
+		brokerAliasMax = *ack.Properties.TopicAliasMaximum
+	}
+	m.aliases5 = newTopicAliases(min(m.v5.topicAliasMaximum, brokerAliasMax))
+	m.client5 = client
 	return nil
 }
+
+func (m *mqttWriter) writeV5(ctx context.Context, msg *service.Message, topic string, retained bool, mBytes []byte) error {
+	m.connMut.RLock()
+	client, aliases := m.client5, m.aliases5
+	m.connMut.RUnlock()
+
+	if client == nil {
+		return service.ErrNotConnected
+	}
+
+	props := &paho.PublishProperties{
+		MessageExpiry: m.v5.messageExpiry,
+	}
+	if m.v5.userProperties != nil {
+		_ = m.v5.userProperties.Walk(msg, func(key, value string) error {
+			props.User.Add(key, value)
+			return nil
+		})
+	}
+
+	var err error
+	if m.v5.contentType != nil {
+		if props.ContentType, err = m.v5.contentType.TryString(msg); err != nil {
+			return fmt.Errorf("content type interpolation error: %w", err)
+		}
+	}
+	if m.v5.responseTopic != nil {
+		if props.ResponseTopic, err = m.v5.responseTopic.TryString(msg); err != nil {
+			return fmt.Errorf("response topic interpolation error: %w", err)
+		}
+	}
+	if m.v5.correlationData != nil {
+		if props.CorrelationData, err = m.v5.correlationData.TryBytes(msg); err != nil {
+			return fmt.Errorf("correlation data interpolation error: %w", err)
+		}
+	}
+
+	pub := &paho.Publish{
+		QoS:        m.qos,
+		Retain:     retained,
+		Topic:      topic,
+		Payload:    mBytes,
+		Properties: props,
+	}
+
+	alias, sendTopic := aliases.get(topic)
+	if alias > 0 {
+		props.TopicAlias = &alias
+		if !sendTopic {
+			pub.Topic = ""
+		}
+	}
+
+	writeCtx, done := context.WithTimeout(ctx, m.writeTimeout)
+	defer done()
+
+	res, err := client.Publish(writeCtx, pub)
+	if err != nil {
+		return err
+	}
+	if res != nil && res.ReasonCode >= 0x80 {
+		return fmt.Errorf("publish rejected with reason code %v", res.ReasonCode)
+	}
+	if alias > 0 && sendTopic {
+		aliases.established(topic)
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+// topicAliases assigns MQTT 5 topic aliases to topics. An alias is only used in
+// place of its topic once a message that maps the alias to the topic has been
+// published, as messages published concurrently might otherwise reach the
+// broker before the mapping.
+type topicAliases struct {
+	mut     sync.Mutex
+	max     uint16
+	aliases map[string]*topicAlias
+}
+
+type topicAlias struct {
+	id          uint16
+	established bool
+}
+
+func newTopicAliases(max uint16) *topicAliases {
+	return &topicAliases{
+		max:     max,
+		aliases: map[string]*topicAlias{},
+	}
+}
+
+// get returns the alias of a topic, which is zero when the topic has no alias,
+// and whether the topic must also be sent in order to map the alias to it.
+func (t *topicAliases) get(topic string) (alias uint16, sendTopic bool) {
+	t.mut.Lock()
+	defer t.mut.Unlock()
+
+	if a, exists := t.aliases[topic]; exists {
+		return a.id, !a.established
+	}
+	if len(t.aliases) >= int(t.max) {
+		return 0, true
+	}
+	a := &topicAlias{id: uint16(len(t.aliases) + 1)}
+	t.aliases[topic] = a
+	return a.id, true
+}
+
+// established marks the alias of a topic as mapped by the broker.
+func (t *topicAliases) established(topic string) {
+	t.mut.Lock()
+	if a, exists := t.aliases[topic]; exists {
+		a.established = true
+	}
+	t.mut.Unlock()
+}
@@ -0,0 +1,80 @@
+package mqtt
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func TestMQTT5OptsFromParsed(t *testing.T) {
+	parse := func(t *testing.T, conf string) (*mqttWriter, error) {
+		t.Helper()
+		pConf, err := outputConfigSpec().ParseYAML(conf, nil)
+		require.NoError(t, err)
+		return newMQTTWriterFromParsed(pConf, service.MockResources())
+	}
+
+	w, err := parse(t, `
+urls: [ tcp://localhost:1883 ]
+topic: foo
+`)
+	require.NoError(t, err)
+	assert.Nil(t, w.v5)
+
+	_, err = parse(t, `
+urls: [ tcp://localhost:1883 ]
+topic: foo
+message_expiry: 10s
+`)
+	require.Error(t, err)
+
+	w, err = parse(t, `
+urls: [ tcp://localhost:1883 ]
+topic: foo
+protocol_version: "5"
+topic_alias_maximum: 10
+message_expiry: 90s
+user_properties:
+  include_prefixes: [ trace_ ]
+correlation_data: ${! @id }
+`)
+	require.NoError(t, err)
+	require.NotNil(t, w.v5)
+	assert.Equal(t, uint16(10), w.v5.topicAliasMaximum)
+	require.NotNil(t, w.v5.messageExpiry)
+	assert.Equal(t, uint32(90), *w.v5.messageExpiry)
+	assert.NotNil(t, w.v5.userProperties)
+	assert.NotNil(t, w.v5.correlationData)
+	assert.Nil(t, w.v5.contentType)
+}
+
+func TestTopicAliases(t *testing.T) {
+	aliases := newTopicAliases(2)
+
+	alias, sendTopic := aliases.get("foo")
+	assert.Equal(t, uint16(1), alias)
+	assert.True(t, sendTopic)
+
+	// Until the mapping is established the topic must continue to be sent.
+	alias, sendTopic = aliases.get("foo")
+	assert.Equal(t, uint16(1), alias)
+	assert.True(t, sendTopic)
+
+	aliases.established("foo")
+	alias, sendTopic = aliases.get("foo")
+	assert.Equal(t, uint16(1), alias)
+	assert.False(t, sendTopic)
+
+	alias, _ = aliases.get("bar")
+	assert.Equal(t, uint16(2), alias)
+
+	alias, sendTopic = aliases.get("baz")
+	assert.Equal(t, uint16(0), alias)
+	assert.True(t, sendTopic)
+
+	alias, _ = newTopicAliases(0).get("foo")
+	assert.Equal(t, uint16(0), alias)
+}
@@ -0,0 +1,455 @@
+package msteams
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/oauth2/clientcredentials"
+
+	"github.com/benthosdev/benthos/v4/public/bloblang"
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	toFieldWebhookURL        = "webhook_url"
+	toFieldGraph             = "graph"
+	toFieldGraphTenantID     = "tenant_id"
+	toFieldGraphClientID     = "client_id"
+	toFieldGraphClientSecret = "client_secret"
+	toFieldGraphTeamID       = "team_id"
+	toFieldGraphChannelID    = "channel_id"
+	toFieldGraphURL          = "graph_url"
+	toFieldGraphTokenURL     = "token_url"
+	toFieldTitle             = "title"
+	toFieldText              = "text"
+	toFieldCard              = "card"
+	toFieldMaxRetries        = "max_retries"
+	toFieldTimeout           = "timeout"
+)
+
+func teamsOutputSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Services", "Social").
+		Version("4.28.0").
+		Summary("Posts messages to a Microsoft Teams channel as Adaptive Cards, either with an incoming webhook or the Microsoft Graph API.").
+		Description(`
+Exactly one of `+"`webhook_url` or `graph`"+` must be set:
+
+- `+"`webhook_url`"+` posts each card to an incoming webhook, which is created either with the Incoming Webhook connector or a Workflows app of a channel.
+- `+"`graph`"+` posts each card as a channel message with the [Microsoft Graph API](https://learn.microsoft.com/en-us/graph/api/chatmessage-post), authenticated as an Azure AD application with client credentials. The application must be granted the `+"`ChannelMessage.Send.Group`"+` resource specific consent permission within each team it posts to.
+
+### Cards
+
+By default each message is sent as an [Adaptive Card](https://adaptivecards.io/) containing the `+"`title`"+`, when not empty, followed by the `+"`text`"+`. Custom cards can be created with the `+"`card`"+` field, which is a [Bloblang mapping](/docs/guides/bloblang/about) that returns an Adaptive Card object. The `+"`type`, `version` and `$schema`"+` fields of the card are added when missing.
+
+### Rate Limits
+
+When a request is throttled it is retried after the period indicated by the response, up to `+"`max_retries`"+` times, before the message is rejected.`+service.OutputPerformanceDocs(true, false)).
+		Fields(
+			service.NewStringField(toFieldWebhookURL).
+				Description("The URL of an incoming webhook to post cards to.").
+				Secret().
+				Optional(),
+			service.NewObjectField(toFieldGraph,
+				service.NewStringField(toFieldGraphTenantID).
+					Description("The ID of the Azure AD tenant of the application."),
+				service.NewStringField(toFieldGraphClientID).
+					Description("The client ID of the application."),
+				service.NewStringField(toFieldGraphClientSecret).
+					Description("A client secret of the application.").
+					Secret(),
+				service.NewInterpolatedStringField(toFieldGraphTeamID).
+					Description("The ID of the team to post messages to."),
+				service.NewInterpolatedStringField(toFieldGraphChannelID).
+					Description("The ID of the channel to post messages to.").
+					Example("19:abc123@thread.tacv2"),
+				service.NewStringField(toFieldGraphURL).
+					Description("The base URL of the Microsoft Graph API.").
+					Advanced().
+					Default("https://graph.microsoft.com/v1.0"),
+				service.NewStringField(toFieldGraphTokenURL).
+					Description("The URL to obtain access tokens from. When empty the token endpoint of the tenant is used.").
+					Advanced().
+					Default(""),
+			).
+				Description("Post cards as channel messages with the Microsoft Graph API.").
+				Optional(),
+			service.NewInterpolatedStringField(toFieldTitle).
+				Description("The title of each card, which is omitted when empty. Ignored when `card` is set.").
+				Example(`${! this.alert_name }`).
+				Default(""),
+			service.NewInterpolatedStringField(toFieldText).
+				Description("The text of each card, which may contain a subset of Markdown. Ignored when `card` is set.").
+				Default("${! content() }"),
+			service.NewBloblangField(toFieldCard).
+				Description("An optional [Bloblang mapping](/docs/guides/bloblang/about) that returns an Adaptive Card object for each message.").
+				Example(`root.body = [
+  {"type": "TextBlock", "text": this.title, "weight": "Bolder", "size": "Medium"},
+  {"type": "FactSet", "facts": this.labels.key_values().map_each(kv -> {"title": kv.key, "value": kv.value.string()})}
+]`).
+				Optional(),
+			service.NewIntField(toFieldMaxRetries).
+				Description("The maximum number of times to retry a request that was throttled.").
+				Advanced().
+				Default(3),
+			service.NewDurationField(toFieldTimeout).
+				Description("The maximum period to wait for each request to complete.").
+				Advanced().
+				Default("10s"),
+			service.NewOutputMaxInFlightField().Default(1),
+		).
+		LintRule(`root = match {
+  this.exists("webhook_url") && this.exists("graph") => [ "only one of webhook_url and graph can be set" ],
+  !this.exists("webhook_url") && !this.exists("graph") => [ "either webhook_url or graph must be set" ]
+}`).
+		Example("Webhook Alerts", "Post alerts to a channel through an incoming webhook.", `
+output:
+  msteams:
+    webhook_url: ${TEAMS_WEBHOOK_URL}
+    title: "${! this.severity.uppercase() }: ${! this.alert_name }"
+    text: ${! this.description }
+`).
+		Example("Graph API", "Post alerts to the channel of each team with the Graph API, with the labels of each alert as facts.", `
+output:
+  msteams:
+    graph:
+      tenant_id: ${AZURE_TENANT_ID}
+      client_id: ${AZURE_CLIENT_ID}
+      client_secret: ${AZURE_CLIENT_SECRET}
+      team_id: ${! @team_id }
+      channel_id: ${! @channel_id }
+    card: |
+      root.body = [
+        {"type": "TextBlock", "text": this.alert_name, "weight": "Bolder", "size": "Medium", "wrap": true},
+        {"type": "TextBlock", "text": this.description, "wrap": true},
+        {"type": "FactSet", "facts": this.labels.key_values().map_each(kv -> {"title": kv.key, "value": kv.value.string()})}
+      ]
+`)
+}
+
+func init() {
+	err := service.RegisterOutput("msteams", teamsOutputSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (out service.Output, maxInFlight int, err error) {
+			if maxInFlight, err = conf.FieldMaxInFlight(); err != nil {
+				return
+			}
+			out, err = newTeamsWriterFromParsed(conf, mgr)
+			return
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type graphConfig struct {
+	teamID    *service.InterpolatedString
+	channelID *service.InterpolatedString
+	url       string
+	client    *http.Client
+}
+
+type teamsWriter struct {
+	log *service.Logger
+
+	webhookURL string
+	graph      *graphConfig
+
+	title      *service.InterpolatedString
+	text       *service.InterpolatedString
+	card       *bloblang.Executor
+	maxRetries int
+	timeout    time.Duration
+
+	client *http.Client
+}
+
+func graphConfigFromParsed(conf *service.ParsedConfig) (*graphConfig, error) {
+	g := &graphConfig{}
+
+	tenantID, err := conf.FieldString(toFieldGraphTenantID)
+	if err != nil {
+		return nil, err
+	}
+	clientID, err := conf.FieldString(toFieldGraphClientID)
+	if err != nil {
+		return nil, err
+	}
+	clientSecret, err := conf.FieldString(toFieldGraphClientSecret)
+	if err != nil {
+		return nil, err
+	}
+	tokenURL, err := conf.FieldString(toFieldGraphTokenURL)
+	if err != nil {
+		return nil, err
+	}
+	if tokenURL == "" {
+		tokenURL = "https://login.microsoftonline.com/" + url.PathEscape(tenantID) + "/oauth2/v2.0/token"
+	}
+
+	if g.teamID, err = conf.FieldInterpolatedString(toFieldGraphTeamID); err != nil {
+		return nil, err
+	}
+	if g.channelID, err = conf.FieldInterpolatedString(toFieldGraphChannelID); err != nil {
+		return nil, err
+	}
+	if g.url, err = conf.FieldString(toFieldGraphURL); err != nil {
+		return nil, err
+	}
+	g.url = strings.TrimSuffix(g.url, "/")
+
+	ccConf := &clientcredentials.Config{
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		TokenURL:     tokenURL,
+		Scopes:       []string{"https://graph.microsoft.com/.default"},
+	}
+	g.client = ccConf.Client(context.Background())
+	return g, nil
+}
+
+func newTeamsWriterFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*teamsWriter, error) {
+	w := &teamsWriter{
+		log:    mgr.Logger(),
+		client: &http.Client{},
+	}
+
+	var err error
+	if conf.Contains(toFieldWebhookURL) {
+		if w.webhookURL, err = conf.FieldString(toFieldWebhookURL); err != nil {
+			return nil, err
+		}
+	}
+	if conf.Contains(toFieldGraph) {
+		if w.graph, err = graphConfigFromParsed(conf.Namespace(toFieldGraph)); err != nil {
+			return nil, err
+		}
+	}
+	if (w.webhookURL == "") == (w.graph == nil) {
+		return nil, fmt.Errorf("exactly one of %v and %v must be set", toFieldWebhookURL, toFieldGraph)
+	}
+
+	if w.title, err = conf.FieldInterpolatedString(toFieldTitle); err != nil {
+		return nil, err
+	}
+	if w.text, err = conf.FieldInterpolatedString(toFieldText); err != nil {
+		return nil, err
+	}
+	if conf.Contains(toFieldCard) {
+		if w.card, err = conf.FieldBloblang(toFieldCard); err != nil {
+			return nil, err
+		}
+	}
+	if w.maxRetries, err = conf.FieldInt(toFieldMaxRetries); err != nil {
+		return nil, err
+	}
+	if w.timeout, err = conf.FieldDuration(toFieldTimeout); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *teamsWriter) Connect(ctx context.Context) error {
+	return nil
+}
+
+const adaptiveCardContentType = "application/vnd.microsoft.card.adaptive"
+
+// cardFrom returns the Adaptive Card of a message.
+func (w *teamsWriter) cardFrom(msg *service.Message) (map[string]any, error) {
+	var card map[string]any
+	if w.card != nil {
+		cardMsg, err := msg.BloblangQuery(w.card)
+		if err != nil {
+			return nil, fmt.Errorf("card mapping error: %w", err)
+		}
+		if cardMsg == nil {
+			return nil, errors.New("card mapping deleted the message")
+		}
+		cardV, err := cardMsg.AsStructured()
+		if err != nil {
+			return nil, fmt.Errorf("card mapping error: %w", err)
+		}
+		var isObj bool
+		if card, isObj = cardV.(map[string]any); !isObj {
+			return nil, fmt.Errorf("card mapping must return an object, got %T", cardV)
+		}
+	} else {
+		title, err := w.title.TryString(msg)
+		if err != nil {
+			return nil, fmt.Errorf("title interpolation error: %w", err)
+		}
+		text, err := w.text.TryString(msg)
+		if err != nil {
+			return nil, fmt.Errorf("text interpolation error: %w", err)
+		}
+
+		var body []any
+		if title != "" {
+			body = append(body, map[string]any{
+				"type":   "TextBlock",
+				"text":   title,
+				"weight": "Bolder",
+				"size":   "Medium",
+				"wrap":   true,
+			})
+		}
+		body = append(body, map[string]any{
+			"type": "TextBlock",
+			"text": text,
+			"wrap": true,
+		})
+		card = map[string]any{"body": body}
+	}
+
+	for k, v := range map[string]any{
+		"type":    "AdaptiveCard",
+		"version": "1.4",
+		"$schema": "http://adaptivecards.io/schemas/adaptive-card.json",
+	} {
+		if _, exists := card[k]; !exists {
+			card[k] = v
+		}
+	}
+	return card, nil
+}
+
+func attachmentID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// requestFrom returns the URL, client and body of the request that posts the
+// card of a message.
+func (w *teamsWriter) requestFrom(msg *service.Message) (string, *http.Client, []byte, error) {
+	card, err := w.cardFrom(msg)
+	if err != nil {
+		return "", nil, nil, err
+	}
+
+	if w.graph == nil {
+		body, err := json.Marshal(map[string]any{
+			"type": "message",
+			"attachments": []any{
+				map[string]any{
+					"contentType": adaptiveCardContentType,
+					"content":     card,
+				},
+			},
+		})
+		return w.webhookURL, w.client, body, err
+	}
+
+	teamID, err := w.graph.teamID.TryString(msg)
+	if err != nil {
+		return "", nil, nil, fmt.Errorf("team_id interpolation error: %w", err)
+	}
+	channelID, err := w.graph.channelID.TryString(msg)
+	if err != nil {
+		return "", nil, nil, fmt.Errorf("channel_id interpolation error: %w", err)
+	}
+
+	// The Graph API expects cards to be attached as serialised JSON, and
+	// referenced within the body of the message by the ID of the attachment.
+	cardBytes, err := json.Marshal(card)
+	if err != nil {
+		return "", nil, nil, err
+	}
+	id := attachmentID()
+	body, err := json.Marshal(map[string]any{
+		"body": map[string]any{
+			"contentType": "html",
+			"content":     `<attachment id="` + id + `"></attachment>`,
+		},
+		"attachments": []any{
+			map[string]any{
+				"id":          id,
+				"contentType": adaptiveCardContentType,
+				"content":     string(cardBytes),
+			},
+		},
+	})
+	postURL := fmt.Sprintf("%v/teams/%v/channels/%v/messages", w.graph.url, url.PathEscape(teamID), url.PathEscape(channelID))
+	return postURL, w.graph.client, body, err
+}
+
+// errThrottled is returned when a request was throttled, along with the period
+// to wait before retrying.
+type errThrottled struct {
+	retryAfter time.Duration
+}
+
+func (e *errThrottled) Error() string {
+	return fmt.Sprintf("request throttled, retry after %v", e.retryAfter)
+}
+
+func (w *teamsWriter) post(ctx context.Context, postURL string, client *http.Client, body []byte) error {
+	ctx, done := context.WithTimeout(ctx, w.timeout)
+	defer done()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, postURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	resBytes, _ := io.ReadAll(res.Body)
+	if res.StatusCode == http.StatusTooManyRequests {
+		retryAfter := time.Second
+		if secs, err := strconv.Atoi(res.Header.Get("Retry-After")); err == nil && secs >= 0 {
+			retryAfter = time.Duration(secs) * time.Second
+		}
+		return &errThrottled{retryAfter: retryAfter}
+	}
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("request returned status code %v: %s", res.StatusCode, resBytes)
+	}
+	return nil
+}
+
+func (w *teamsWriter) Write(ctx context.Context, msg *service.Message) error {
+	postURL, client, body, err := w.requestFrom(msg)
+	if err != nil {
+		return err
+	}
+
+	for retries := 0; ; retries++ {
+		err = w.post(ctx, postURL, client, body)
+
+		var tErr *errThrottled
+		if !errors.As(err, &tErr) || retries >= w.maxRetries {
+			return err
+		}
+
+		w.log.Debugf("Request throttled, retrying after %v", tErr.retryAfter)
+		select {
+		case <-time.After(tErr.retryAfter):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (w *teamsWriter) Close(ctx context.Context) error {
+	return nil
+}
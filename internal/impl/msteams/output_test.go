@@ -0,0 +1,146 @@
+package msteams
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func testTeamsWriter(t *testing.T, conf string) *teamsWriter {
+	t.Helper()
+
+	pConf, err := teamsOutputSpec().ParseYAML(conf, nil)
+	require.NoError(t, err)
+
+	w, err := newTeamsWriterFromParsed(pConf, service.MockResources())
+	require.NoError(t, err)
+	require.NoError(t, w.Connect(context.Background()))
+	return w
+}
+
+func TestTeamsConfigValidation(t *testing.T) {
+	for _, conf := range []string{
+		`text: foo`,
+		`
+webhook_url: http://localhost/webhook
+graph:
+  tenant_id: foo
+  client_id: bar
+  client_secret: baz
+  team_id: team
+  channel_id: channel
+`,
+	} {
+		pConf, err := teamsOutputSpec().ParseYAML(conf, nil)
+		require.NoError(t, err)
+
+		_, err = newTeamsWriterFromParsed(pConf, service.MockResources())
+		require.Error(t, err, conf)
+	}
+}
+
+func TestTeamsWebhook(t *testing.T) {
+	var requests int
+	var body map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if requests++; requests == 1 {
+			rw.Header().Set("Retry-After", "0")
+			rw.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+
+		b, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		require.NoError(t, json.Unmarshal(b, &body))
+		_, _ = rw.Write([]byte("1"))
+	}))
+	t.Cleanup(srv.Close)
+
+	w := testTeamsWriter(t, `
+webhook_url: `+srv.URL+`/webhook
+title: ${! this.name }
+text: ${! this.desc }
+`)
+	require.NoError(t, w.Write(context.Background(), service.NewMessage([]byte(`{"name":"disk full","desc":"the disk is full"}`))))
+	assert.Equal(t, 2, requests)
+
+	assert.Equal(t, map[string]any{
+		"type": "message",
+		"attachments": []any{
+			map[string]any{
+				"contentType": "application/vnd.microsoft.card.adaptive",
+				"content": map[string]any{
+					"type":    "AdaptiveCard",
+					"version": "1.4",
+					"$schema": "http://adaptivecards.io/schemas/adaptive-card.json",
+					"body": []any{
+						map[string]any{"type": "TextBlock", "text": "disk full", "weight": "Bolder", "size": "Medium", "wrap": true},
+						map[string]any{"type": "TextBlock", "text": "the disk is full", "wrap": true},
+					},
+				},
+			},
+		},
+	}, body)
+}
+
+func TestTeamsGraph(t *testing.T) {
+	var body map[string]any
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", func(rw http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		assert.Equal(t, "client_credentials", r.Form.Get("grant_type"))
+		assert.Equal(t, "https://graph.microsoft.com/.default", r.Form.Get("scope"))
+
+		rw.Header().Set("Content-Type", "application/json")
+		_, _ = rw.Write([]byte(`{"access_token":"graphtoken","token_type":"Bearer","expires_in":3600}`))
+	})
+	mux.HandleFunc("/v1.0/teams/team1/channels/19:chan@thread.tacv2/messages", func(rw http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer graphtoken", r.Header.Get("Authorization"))
+
+		b, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		require.NoError(t, json.Unmarshal(b, &body))
+		rw.WriteHeader(http.StatusCreated)
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	w := testTeamsWriter(t, `
+graph:
+  tenant_id: tenant
+  client_id: foo
+  client_secret: bar
+  team_id: ${! @team }
+  channel_id: 19:chan@thread.tacv2
+  graph_url: `+srv.URL+`/v1.0
+  token_url: `+srv.URL+`/token
+card: 'root.body = [{"type": "TextBlock", "text": content().string()}]'
+`)
+
+	msg := service.NewMessage([]byte("hello world"))
+	msg.MetaSetMut("team", "team1")
+	require.NoError(t, w.Write(context.Background(), msg))
+
+	require.NotNil(t, body)
+	attachments, _ := body["attachments"].([]any)
+	require.Len(t, attachments, 1)
+
+	attachment, _ := attachments[0].(map[string]any)
+	assert.Equal(t, map[string]any{
+		"contentType": "html",
+		"content":     `<attachment id="` + attachment["id"].(string) + `"></attachment>`,
+	}, body["body"])
+
+	var card map[string]any
+	require.NoError(t, json.Unmarshal([]byte(attachment["content"].(string)), &card))
+	assert.Equal(t, "AdaptiveCard", card["type"])
+	assert.Equal(t, []any{map[string]any{"type": "TextBlock", "text": "hello world"}}, card["body"])
+}
@@ -3,6 +3,7 @@ package nats
 import (
 	"context"
 	"fmt"
+	"strconv"
 	"sync"
 
 	"github.com/nats-io/nats.go"
@@ -18,7 +19,14 @@ func natsJetStreamOutputConfig() *service.ConfigSpec {
 		Categories("Services").
 		Version("3.46.0").
 		Summary("Write messages to a NATS JetStream subject.").
-		Description(connectionNameDescription() + authDescription()).
+		Description(`
+### Deduplication
+
+When ` + "`msg_id`" + ` is set each message is published with a ` + "`Nats-Msg-Id`" + ` header, and the server discards any message with an ID that was already published to the stream within its duplicate window (two minutes by default). This makes publishing idempotent when messages are replayed, for example after a restart, as long as the replay occurs within the duplicate window of the stream. Messages identified as duplicates by the server are acknowledged as successfully delivered.
+
+The fields within ` + "`expected`" + ` can be used to implement optimistic concurrency control, where the server rejects messages that were published with expectations that do not match the current state of the stream.
+
+` + connectionNameDescription() + authDescription()).
 		Fields(connectionHeadFields()...).
 		Field(service.NewInterpolatedStringField("subject").
 			Description("A subject to write to.").
@@ -35,6 +43,30 @@ func natsJetStreamOutputConfig() *service.ConfigSpec {
 		Field(service.NewMetadataFilterField("metadata").
 			Description("Determine which (if any) metadata values should be added to messages as headers.").
 			Optional()).
+		Field(service.NewInterpolatedStringField("msg_id").
+			Description("An optional ID to publish each message with as the `Nats-Msg-Id` header, allowing the server to discard duplicate messages.").
+			Example(`${! meta("kafka_topic") }-${! meta("kafka_partition") }-${! meta("kafka_offset") }`).
+			Example(`${! json("id") }`).
+			Default("").
+			Version("4.28.0")).
+		Field(service.NewObjectField("expected",
+			service.NewInterpolatedStringField("stream").
+				Description("An optional name of the stream that the subject of each message is expected to be stored by.").
+				Default(""),
+			service.NewInterpolatedStringField("last_sequence").
+				Description("An optional sequence number that the last message of the stream is expected to have.").
+				Default(""),
+			service.NewInterpolatedStringField("last_subject_sequence").
+				Description("An optional sequence number that the last message of the stream with the same subject is expected to have.").
+				Example(`${! meta("expected_revision") }`).
+				Default(""),
+			service.NewInterpolatedStringField("last_msg_id").
+				Description("An optional ID that the last message of the stream is expected to have been published with.").
+				Default(""),
+		).
+			Description("Expectations of the state of the stream that each message is published with, where messages that do not meet their expectations are rejected by the server. Fields that resolve to an empty string are ignored.").
+			Advanced().
+			Version("4.28.0")).
 		Field(service.NewOutputMaxInFlightField().Default(1024)).
 		Fields(connectionTailFields()...).
 		Field(outputTracingDocs())
@@ -68,6 +100,12 @@ type jetStreamOutput struct {
 	subjectStr    *service.InterpolatedString
 	headers       map[string]*service.InterpolatedString
 	metaFilter    *service.MetadataFilter
+	msgID         *service.InterpolatedString
+
+	expectedStream              *service.InterpolatedString
+	expectedLastSequence        *service.InterpolatedString
+	expectedLastSubjectSequence *service.InterpolatedString
+	expectedLastMsgID           *service.InterpolatedString
 
 	log *service.Logger
 
@@ -106,6 +144,26 @@ func newJetStreamWriterFromConfig(conf *service.ParsedConfig, mgr *service.Resou
 			return nil, err
 		}
 	}
+
+	if j.msgID, err = conf.FieldInterpolatedString("msg_id"); err != nil {
+		return nil, err
+	}
+
+	if j.expectedStream, err = conf.FieldInterpolatedString("expected", "stream"); err != nil {
+		return nil, err
+	}
+
+	if j.expectedLastSequence, err = conf.FieldInterpolatedString("expected", "last_sequence"); err != nil {
+		return nil, err
+	}
+
+	if j.expectedLastSubjectSequence, err = conf.FieldInterpolatedString("expected", "last_subject_sequence"); err != nil {
+		return nil, err
+	}
+
+	if j.expectedLastMsgID, err = conf.FieldInterpolatedString("expected", "last_msg_id"); err != nil {
+		return nil, err
+	}
 	return &j, nil
 }
 
@@ -187,8 +245,72 @@ func (j *jetStreamOutput) Write(ctx context.Context, msg *service.Message) error
 		return nil
 	})
 
-	_, err = jCtx.PublishMsg(jsmsg)
-	return err
+	opts, err := j.publishOpts(msg)
+	if err != nil {
+		return err
+	}
+
+	ack, err := jCtx.PublishMsg(jsmsg, opts...)
+	if err != nil {
+		return err
+	}
+	if ack.Duplicate {
+		j.log.Debugf("Message with ID %v was discarded by the server as a duplicate", jsmsg.Header.Get(nats.MsgIdHdr))
+	}
+	return nil
+}
+
+func (j *jetStreamOutput) publishOpts(msg *service.Message) ([]nats.PubOpt, error) {
+	var opts []nats.PubOpt
+
+	msgID, err := j.msgID.TryString(msg)
+	if err != nil {
+		return nil, fmt.Errorf(`failed string interpolation on field "msg_id": %w`, err)
+	}
+	if msgID != "" {
+		opts = append(opts, nats.MsgId(msgID))
+	}
+
+	stream, err := j.expectedStream.TryString(msg)
+	if err != nil {
+		return nil, fmt.Errorf(`failed string interpolation on field "expected.stream": %w`, err)
+	}
+	if stream != "" {
+		opts = append(opts, nats.ExpectStream(stream))
+	}
+
+	lastSeqStr, err := j.expectedLastSequence.TryString(msg)
+	if err != nil {
+		return nil, fmt.Errorf(`failed string interpolation on field "expected.last_sequence": %w`, err)
+	}
+	if lastSeqStr != "" {
+		lastSeq, err := strconv.ParseUint(lastSeqStr, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf(`failed to parse field "expected.last_sequence": %w`, err)
+		}
+		opts = append(opts, nats.ExpectLastSequence(lastSeq))
+	}
+
+	lastSubjectSeqStr, err := j.expectedLastSubjectSequence.TryString(msg)
+	if err != nil {
+		return nil, fmt.Errorf(`failed string interpolation on field "expected.last_subject_sequence": %w`, err)
+	}
+	if lastSubjectSeqStr != "" {
+		lastSubjectSeq, err := strconv.ParseUint(lastSubjectSeqStr, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf(`failed to parse field "expected.last_subject_sequence": %w`, err)
+		}
+		opts = append(opts, nats.ExpectLastSequencePerSubject(lastSubjectSeq))
+	}
+
+	lastMsgID, err := j.expectedLastMsgID.TryString(msg)
+	if err != nil {
+		return nil, fmt.Errorf(`failed string interpolation on field "expected.last_msg_id": %w`, err)
+	}
+	if lastMsgID != "" {
+		opts = append(opts, nats.ExpectLastMsgId(lastMsgID))
+	}
+	return opts, nil
 }
 
 func (j *jetStreamOutput) Close(ctx context.Context) error {
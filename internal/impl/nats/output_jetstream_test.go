@@ -55,6 +55,39 @@ auth:
 		assert.Equal(t, "test auth inline user NKey Seed", e.connDetails.authConf.UserNkeySeed)
 	})
 
+	t.Run("Deduplication and expectations", func(t *testing.T) {
+		outputConfig := `
+urls: [ url1 ]
+subject: testsubject
+msg_id: ${! json("id") }
+expected:
+  stream: foo
+  last_subject_sequence: ${! meta("revision") }
+`
+
+		conf, err := spec.ParseYAML(outputConfig, env)
+		require.NoError(t, err)
+
+		e, err := newJetStreamWriterFromConfig(conf, service.MockResources())
+		require.NoError(t, err)
+
+		msg := service.NewMessage([]byte(`{"id":"abc"}`))
+		msg.MetaSet("revision", "5")
+
+		opts, err := e.publishOpts(msg)
+		require.NoError(t, err)
+		assert.Len(t, opts, 3)
+
+		msg.MetaSet("revision", "")
+		opts, err = e.publishOpts(msg)
+		require.NoError(t, err)
+		assert.Len(t, opts, 2)
+
+		msg.MetaSet("revision", "nope")
+		_, err = e.publishOpts(msg)
+		require.Error(t, err)
+	})
+
 	t.Run("Missing user_nkey_seed", func(t *testing.T) {
 		inputConfig := `
 urls: [ url1, url2 ]
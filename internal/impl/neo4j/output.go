@@ -0,0 +1,371 @@
+package neo4j
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j/config"
+
+	"github.com/benthosdev/benthos/v4/public/bloblang"
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	noFieldURL          = "url"
+	noFieldDatabase     = "database"
+	noFieldUsername     = "username"
+	noFieldPassword     = "password"
+	noFieldToken        = "bearer_token"
+	noFieldQuery        = "query"
+	noFieldArgs         = "args_mapping"
+	noFieldUnwind       = "unwind"
+	noFieldUnwindParam  = "unwind_parameter"
+	noFieldMaxRetryTime = "max_transaction_retry_time"
+	noFieldTLS          = "tls"
+	noFieldBatching     = "batching"
+)
+
+func outputSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Services").
+		Version("4.28.0").
+		Summary("Runs a parameterised Cypher statement against a Neo4j database for messages.").
+		Description(`
+The parameters of the statement are populated from each message with the `+"`args_mapping`"+`, which must result in an object.
+
+### Batching
+
+When `+"`unwind`"+` is `+"`true`"+` (the default) each batch of messages is written by running the statement once, with the parameter named by `+"`unwind_parameter`"+` set to a list of the parameter objects of all messages of the batch. Statements should therefore begin with an `+"`UNWIND`"+` clause, e.g. `+"`UNWIND $rows AS row MERGE (u:User {id: row.id})`"+`, which is usually far more efficient than running a statement per message.
+
+When `+"`unwind`"+` is `+"`false`"+` the statement is run once for each message, with the parameter object of the message, and all statements of a batch are run within a single transaction.
+
+### Retries
+
+Transactions that fail with transient errors, such as deadlocks or leader changes within a cluster, are retried by the driver until `+"`max_transaction_retry_time`"+` has elapsed.
+
+### Clusters
+
+Using the `+"`neo4j://`"+` scheme (or `+"`neo4j+s://`"+` for encrypted connections) enables routing, where the driver discovers the members of a cluster and directs writes to the current leader. Use the `+"`bolt://`"+` scheme in order to connect to a single server directly.`+service.OutputPerformanceDocs(true, true)).
+		Fields(
+			service.NewStringField(noFieldURL).
+				Description("The URL of the Neo4j server or cluster.").
+				Example("neo4j://localhost:7687").
+				Example("neo4j+s://xxxxxxxx.databases.neo4j.io").
+				Example("bolt://localhost:7687"),
+			service.NewStringField(noFieldDatabase).
+				Description("The database to write to. When empty the default database of the server is used.").
+				Default(""),
+			service.NewStringField(noFieldUsername).
+				Description("A username to authenticate with using basic authentication.").
+				Default(""),
+			service.NewStringField(noFieldPassword).
+				Description("A password to authenticate with using basic authentication.").
+				Secret().
+				Default(""),
+			service.NewStringField(noFieldToken).
+				Description("A bearer token to authenticate with, used instead of basic authentication when set.").
+				Secret().
+				Default("").
+				Advanced(),
+			service.NewStringField(noFieldQuery).
+				Description("The Cypher statement to run.").
+				Example(`UNWIND $rows AS row MERGE (u:User {id: row.id}) SET u.name = row.name`),
+			service.NewBloblangField(noFieldArgs).
+				Description("A [Bloblang mapping](/docs/guides/bloblang/about) that results in an object of the parameters of each message.").
+				Example(`root.id = this.user.id
+root.name = this.user.name`).
+				Default("root = this"),
+			service.NewBoolField(noFieldUnwind).
+				Description("Whether to run the statement once per batch with a list of the parameters of all messages, rather than once per message.").
+				Default(true),
+			service.NewStringField(noFieldUnwindParam).
+				Description("The name of the parameter that the list of message parameters is assigned to when `unwind` is `true`.").
+				Default("rows").
+				Advanced(),
+			service.NewDurationField(noFieldMaxRetryTime).
+				Description("The maximum period of time to retry transactions that fail with transient errors.").
+				Default("30s").
+				Advanced(),
+			service.NewTLSToggledField(noFieldTLS),
+			service.NewOutputMaxInFlightField(),
+			service.NewBatchPolicyField(noFieldBatching),
+		).
+		Example("Maintain a Follower Graph", "Merge users and the relationships between them from a stream of follow events.", `
+output:
+  neo4j:
+    url: neo4j://localhost:7687
+    username: neo4j
+    password: ${NEO4J_PASSWORD}
+    query: |
+      UNWIND $rows AS row
+      MERGE (a:User {id: row.follower})
+      MERGE (b:User {id: row.followee})
+      MERGE (a)-[:FOLLOWS]->(b)
+    args_mapping: |
+      root.follower = this.follower_id
+      root.followee = this.followee_id
+    batching:
+      count: 500
+      period: 1s
+`)
+}
+
+func init() {
+	err := service.RegisterBatchOutput("neo4j", outputSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (out service.BatchOutput, batchPolicy service.BatchPolicy, maxInFlight int, err error) {
+			if maxInFlight, err = conf.FieldMaxInFlight(); err != nil {
+				return
+			}
+			if batchPolicy, err = conf.FieldBatchPolicy(noFieldBatching); err != nil {
+				return
+			}
+			out, err = newOutputFromParsed(conf, mgr)
+			return
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+// statement is a Cypher statement along with its parameters.
+type statement struct {
+	query  string
+	params map[string]any
+}
+
+// writer runs a group of statements within a single write transaction.
+type writer interface {
+	write(ctx context.Context, stmts []statement) error
+	close(ctx context.Context) error
+}
+
+type neo4jOutput struct {
+	url          string
+	database     string
+	auth         neo4j.AuthToken
+	maxRetryTime time.Duration
+	tlsConf      *tls.Config
+
+	query       string
+	args        *bloblang.Executor
+	unwind      bool
+	unwindParam string
+
+	log *service.Logger
+
+	connMut sync.Mutex
+	w       writer
+}
+
+func newOutputFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*neo4jOutput, error) {
+	n := &neo4jOutput{log: mgr.Logger()}
+
+	var err error
+	if n.url, err = conf.FieldString(noFieldURL); err != nil {
+		return nil, err
+	}
+	if n.database, err = conf.FieldString(noFieldDatabase); err != nil {
+		return nil, err
+	}
+
+	var username, password, token string
+	if username, err = conf.FieldString(noFieldUsername); err != nil {
+		return nil, err
+	}
+	if password, err = conf.FieldString(noFieldPassword); err != nil {
+		return nil, err
+	}
+	if token, err = conf.FieldString(noFieldToken); err != nil {
+		return nil, err
+	}
+	switch {
+	case token != "":
+		n.auth = neo4j.BearerAuth(token)
+	case username != "":
+		n.auth = neo4j.BasicAuth(username, password, "")
+	default:
+		n.auth = neo4j.NoAuth()
+	}
+
+	if n.query, err = conf.FieldString(noFieldQuery); err != nil {
+		return nil, err
+	}
+	if n.args, err = conf.FieldBloblang(noFieldArgs); err != nil {
+		return nil, err
+	}
+	if n.unwind, err = conf.FieldBool(noFieldUnwind); err != nil {
+		return nil, err
+	}
+	if n.unwindParam, err = conf.FieldString(noFieldUnwindParam); err != nil {
+		return nil, err
+	}
+	if n.unwind && n.unwindParam == "" {
+		return nil, errors.New("an unwind_parameter must be set when unwind is enabled")
+	}
+	if n.maxRetryTime, err = conf.FieldDuration(noFieldMaxRetryTime); err != nil {
+		return nil, err
+	}
+
+	var tlsEnabled bool
+	if n.tlsConf, tlsEnabled, err = conf.FieldTLSToggled(noFieldTLS); err != nil {
+		return nil, err
+	}
+	if !tlsEnabled {
+		n.tlsConf = nil
+	}
+	return n, nil
+}
+
+func (n *neo4jOutput) Connect(ctx context.Context) error {
+	n.connMut.Lock()
+	defer n.connMut.Unlock()
+
+	if n.w != nil {
+		return nil
+	}
+
+	driver, err := neo4j.NewDriverWithContext(n.url, n.auth, func(c *config.Config) {
+		c.MaxTransactionRetryTime = n.maxRetryTime
+		if n.tlsConf != nil {
+			c.TlsConfig = n.tlsConf
+		}
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create neo4j driver: %w", err)
+	}
+	if err := driver.VerifyConnectivity(ctx); err != nil {
+		_ = driver.Close(ctx)
+		return fmt.Errorf("failed to connect to neo4j: %w", err)
+	}
+
+	n.w = &driverWriter{driver: driver, database: n.database}
+	return nil
+}
+
+// toParams converts values resulting from a mapping into types supported by
+// the driver.
+func toParams(v any) any {
+	switch t := v.(type) {
+	case json.Number:
+		if i, err := t.Int64(); err == nil {
+			return i
+		}
+		f, _ := t.Float64()
+		return f
+	case map[string]any:
+		m := make(map[string]any, len(t))
+		for k, e := range t {
+			m[k] = toParams(e)
+		}
+		return m
+	case []any:
+		s := make([]any, len(t))
+		for i, e := range t {
+			s[i] = toParams(e)
+		}
+		return s
+	}
+	return v
+}
+
+func (n *neo4jOutput) statements(batch service.MessageBatch) ([]statement, error) {
+	rows := make([]map[string]any, len(batch))
+	for i := range batch {
+		v, err := batch.BloblangQueryValue(i, n.args)
+		if err != nil {
+			return nil, fmt.Errorf("args mapping failed for message %v: %w", i, err)
+		}
+		obj, ok := v.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("expected args mapping of message %v to result in an object, got %T", i, v)
+		}
+		rows[i] = toParams(obj).(map[string]any)
+	}
+
+	if n.unwind {
+		list := make([]any, len(rows))
+		for i, r := range rows {
+			list[i] = r
+		}
+		return []statement{{
+			query:  n.query,
+			params: map[string]any{n.unwindParam: list},
+		}}, nil
+	}
+
+	stmts := make([]statement, len(rows))
+	for i, r := range rows {
+		stmts[i] = statement{query: n.query, params: r}
+	}
+	return stmts, nil
+}
+
+func (n *neo4jOutput) WriteBatch(ctx context.Context, batch service.MessageBatch) error {
+	n.connMut.Lock()
+	w := n.w
+	n.connMut.Unlock()
+
+	if w == nil {
+		return service.ErrNotConnected
+	}
+
+	stmts, err := n.statements(batch)
+	if err != nil {
+		return err
+	}
+	return w.write(ctx, stmts)
+}
+
+func (n *neo4jOutput) Close(ctx context.Context) error {
+	n.connMut.Lock()
+	defer n.connMut.Unlock()
+
+	if n.w == nil {
+		return nil
+	}
+	err := n.w.close(ctx)
+	n.w = nil
+	return err
+}
+
+//------------------------------------------------------------------------------
+
+type driverWriter struct {
+	driver   neo4j.DriverWithContext
+	database string
+}
+
+func (d *driverWriter) write(ctx context.Context, stmts []statement) error {
+	session := d.driver.NewSession(ctx, neo4j.SessionConfig{
+		AccessMode:   neo4j.AccessModeWrite,
+		DatabaseName: d.database,
+	})
+	defer session.Close(ctx)
+
+	// Managed transactions are retried by the driver when they fail with
+	// transient errors.
+	_, err := session.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+		for _, s := range stmts {
+			res, err := tx.Run(ctx, s.query, s.params)
+			if err != nil {
+				return nil, err
+			}
+			if _, err := res.Consume(ctx); err != nil {
+				return nil, err
+			}
+		}
+		return nil, nil
+	})
+	return err
+}
+
+func (d *driverWriter) close(ctx context.Context) error {
+	return d.driver.Close(ctx)
+}
@@ -0,0 +1,116 @@
+package neo4j
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+type mockWriter struct {
+	writes [][]statement
+	err    error
+}
+
+func (m *mockWriter) write(ctx context.Context, stmts []statement) error {
+	m.writes = append(m.writes, stmts)
+	return m.err
+}
+
+func (m *mockWriter) close(ctx context.Context) error {
+	return nil
+}
+
+func testOutput(t *testing.T, conf string) (*neo4jOutput, *mockWriter) {
+	t.Helper()
+
+	pConf, err := outputSpec().ParseYAML(conf, nil)
+	require.NoError(t, err)
+
+	out, err := newOutputFromParsed(pConf, service.MockResources())
+	require.NoError(t, err)
+
+	w := &mockWriter{}
+	out.w = w
+	return out, w
+}
+
+func TestNeo4jUnwind(t *testing.T) {
+	out, w := testOutput(t, `
+url: neo4j://localhost:7687
+query: UNWIND $rows AS row MERGE (u:User {id: row.id}) SET u.score = row.score
+args_mapping: |
+  root.id = this.id
+  root.score = this.score
+  root.tags = this.tags
+`)
+
+	require.NoError(t, out.WriteBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte(`{"id":1,"score":1.5,"tags":["a"]}`)),
+		service.NewMessage([]byte(`{"id":"b","score":2}`)),
+	}))
+
+	require.Len(t, w.writes, 1)
+	assert.Equal(t, []statement{{
+		query: "UNWIND $rows AS row MERGE (u:User {id: row.id}) SET u.score = row.score",
+		params: map[string]any{
+			"rows": []any{
+				map[string]any{"id": int64(1), "score": 1.5, "tags": []any{"a"}},
+				map[string]any{"id": "b", "score": int64(2), "tags": nil},
+			},
+		},
+	}}, w.writes[0])
+}
+
+func TestNeo4jPerMessage(t *testing.T) {
+	out, w := testOutput(t, `
+url: bolt://localhost:7687
+query: MERGE (u:User {id: $id})
+args_mapping: root.id = this.id
+unwind: false
+`)
+
+	require.NoError(t, out.WriteBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte(`{"id":"a"}`)),
+		service.NewMessage([]byte(`{"id":"b"}`)),
+	}))
+
+	require.Len(t, w.writes, 1)
+	assert.Equal(t, []statement{
+		{query: "MERGE (u:User {id: $id})", params: map[string]any{"id": "a"}},
+		{query: "MERGE (u:User {id: $id})", params: map[string]any{"id": "b"}},
+	}, w.writes[0])
+}
+
+func TestNeo4jErrors(t *testing.T) {
+	out, w := testOutput(t, `
+url: neo4j://localhost:7687
+query: UNWIND $rows AS row MERGE (u:User {id: row.id})
+args_mapping: root = this.id
+`)
+
+	err := out.WriteBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte(`{"id":"a"}`)),
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "expected args mapping")
+	assert.Empty(t, w.writes)
+
+	out, w = testOutput(t, `
+url: neo4j://localhost:7687
+query: UNWIND $rows AS row MERGE (u:User {id: row.id})
+`)
+	w.err = errors.New("deadlock")
+	require.EqualError(t, out.WriteBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte(`{"id":"a"}`)),
+	}), "deadlock")
+
+	require.NoError(t, out.Close(context.Background()))
+	require.ErrorIs(t, out.WriteBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte(`{"id":"a"}`)),
+	}), service.ErrNotConnected)
+}
@@ -0,0 +1,368 @@
+package ocr
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// ocrBlock is a block of text recognised within a page of a document.
+type ocrBlock struct {
+	page       int
+	text       string
+	confidence float64
+	box        [4]float64 // left, top, width, height
+}
+
+// ocrEngine recognises the blocks of text within an image or document.
+type ocrEngine interface {
+	recognise(ctx context.Context, data []byte, mimeType string) ([]ocrBlock, error)
+}
+
+// detectMIMEType returns the MIME type of an image or document, including TIFF
+// images, which are not detected by the standard library.
+func detectMIMEType(data []byte) string {
+	if bytes.HasPrefix(data, []byte("II*\x00")) || bytes.HasPrefix(data, []byte("MM\x00*")) {
+		return "image/tiff"
+	}
+	mimeType := http.DetectContentType(data)
+	if i := strings.IndexByte(mimeType, ';'); i >= 0 {
+		mimeType = mimeType[:i]
+	}
+	return mimeType
+}
+
+//------------------------------------------------------------------------------
+
+// tesseractEngine recognises text by executing the tesseract command.
+type tesseractEngine struct {
+	path      string
+	languages []string
+	psm       int
+}
+
+func (t *tesseractEngine) recognise(ctx context.Context, data []byte, mimeType string) ([]ocrBlock, error) {
+	if mimeType == "application/pdf" {
+		return nil, errors.New("tesseract does not support PDF documents")
+	}
+
+	args := []string{"stdin", "stdout", "--psm", strconv.Itoa(t.psm)}
+	if len(t.languages) > 0 {
+		args = append(args, "-l", strings.Join(t.languages, "+"))
+	}
+	args = append(args, "tsv")
+
+	cmd := exec.CommandContext(ctx, t.path, args...)
+	cmd.Stdin = bytes.NewReader(data)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%w: %v", err, msg)
+		}
+		return nil, err
+	}
+	return parseTesseractTSV(&stdout)
+}
+
+// parseTesseractTSV parses the TSV output of tesseract into blocks, where the
+// confidence of a block is the mean confidence of its words.
+func parseTesseractTSV(r io.Reader) ([]ocrBlock, error) {
+	cr := csv.NewReader(r)
+	cr.Comma = '\t'
+	cr.LazyQuotes = true
+	cr.FieldsPerRecord = -1
+
+	header, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tesseract output: %w", err)
+	}
+	cols := map[string]int{}
+	for i, name := range header {
+		cols[name] = i
+	}
+	for _, name := range []string{"level", "page_num", "block_num", "par_num", "line_num", "left", "top", "width", "height", "conf", "text"} {
+		if _, exists := cols[name]; !exists {
+			return nil, fmt.Errorf("tesseract output is missing column %v", name)
+		}
+	}
+
+	type blockKey struct{ page, block int }
+	var blocks []ocrBlock
+	var wordCounts []int
+	indexes := map[blockKey]int{}
+	lastLine := map[blockKey][2]int{}
+
+	for {
+		rec, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read tesseract output: %w", err)
+		}
+		// Rows without text omit the trailing column.
+		for len(rec) < len(header) {
+			rec = append(rec, "")
+		}
+		ints := map[string]int{}
+		for _, name := range []string{"level", "page_num", "block_num", "par_num", "line_num", "left", "top", "width", "height"} {
+			if ints[name], err = strconv.Atoi(rec[cols[name]]); err != nil {
+				return nil, fmt.Errorf("failed to parse tesseract output column %v: %w", name, err)
+			}
+		}
+
+		key := blockKey{page: ints["page_num"], block: ints["block_num"]}
+		switch ints["level"] {
+		case 2:
+			indexes[key] = len(blocks)
+			blocks = append(blocks, ocrBlock{
+				page: key.page,
+				box:  [4]float64{float64(ints["left"]), float64(ints["top"]), float64(ints["width"]), float64(ints["height"])},
+			})
+			wordCounts = append(wordCounts, 0)
+		case 5:
+			i, exists := indexes[key]
+			text := strings.TrimSpace(rec[cols["text"]])
+			if !exists || text == "" {
+				continue
+			}
+			conf, err := strconv.ParseFloat(rec[cols["conf"]], 64)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse tesseract output column conf: %w", err)
+			}
+
+			line := [2]int{ints["par_num"], ints["line_num"]}
+			if wordCounts[i] > 0 {
+				if lastLine[key] != line {
+					blocks[i].text += "\n"
+				} else {
+					blocks[i].text += " "
+				}
+			}
+			lastLine[key] = line
+			blocks[i].text += text
+			blocks[i].confidence += conf / 100
+			wordCounts[i]++
+		}
+	}
+
+	res := blocks[:0]
+	for i, b := range blocks {
+		if wordCounts[i] == 0 {
+			continue
+		}
+		b.confidence /= float64(wordCounts[i])
+		res = append(res, b)
+	}
+	return res, nil
+}
+
+//------------------------------------------------------------------------------
+
+// googleVisionEngine recognises text with the document text detection feature
+// of the Google Cloud Vision API.
+type googleVisionEngine struct {
+	client    *http.Client
+	baseURL   string
+	apiKey    string
+	languages []string
+}
+
+type gvVertex struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+}
+
+type gvBoundingPoly struct {
+	Vertices           []gvVertex `json:"vertices"`
+	NormalizedVertices []gvVertex `json:"normalizedVertices"`
+}
+
+type gvAnnotateResponse struct {
+	FullTextAnnotation *struct {
+		Pages []struct {
+			Blocks []struct {
+				BoundingBox gvBoundingPoly `json:"boundingBox"`
+				Confidence  float64        `json:"confidence"`
+				Paragraphs  []struct {
+					Words []struct {
+						Symbols []struct {
+							Text     string `json:"text"`
+							Property *struct {
+								DetectedBreak *struct {
+									Type string `json:"type"`
+								} `json:"detectedBreak"`
+							} `json:"property"`
+						} `json:"symbols"`
+					} `json:"words"`
+				} `json:"paragraphs"`
+			} `json:"blocks"`
+		} `json:"pages"`
+	} `json:"fullTextAnnotation"`
+	Context *struct {
+		PageNumber int `json:"pageNumber"`
+	} `json:"context"`
+	Error *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+func (g *googleVisionEngine) recognise(ctx context.Context, data []byte, mimeType string) ([]ocrBlock, error) {
+	content := base64.StdEncoding.EncodeToString(data)
+	features := []any{map[string]any{"type": "DOCUMENT_TEXT_DETECTION"}}
+	imageContext := map[string]any{}
+	if len(g.languages) > 0 {
+		imageContext["languageHints"] = g.languages
+	}
+
+	// Documents are annotated with the files endpoint, which supports up to
+	// five pages inline.
+	isFile := mimeType == "application/pdf" || mimeType == "image/tiff"
+
+	var path string
+	var reqBody any
+	if isFile {
+		path = "files:annotate"
+		reqBody = map[string]any{"requests": []any{map[string]any{
+			"inputConfig":  map[string]any{"content": content, "mimeType": mimeType},
+			"features":     features,
+			"imageContext": imageContext,
+		}}}
+	} else {
+		path = "images:annotate"
+		reqBody = map[string]any{"requests": []any{map[string]any{
+			"image":        map[string]any{"content": content},
+			"features":     features,
+			"imageContext": imageContext,
+		}}}
+	}
+
+	reqURL, err := url.JoinPath(g.baseURL, path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse url: %w", err)
+	}
+	if g.apiKey != "" {
+		reqURL += "?key=" + url.QueryEscape(g.apiKey)
+	}
+
+	b, err := json.Marshal(reqBody)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, reqURL, bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := g.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	resBody, err := io.ReadAll(res.Body)
+	_ = res.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return nil, fmt.Errorf("status code %v: %s", res.StatusCode, bytes.TrimSpace(resBody))
+	}
+
+	var responses []gvAnnotateResponse
+	if isFile {
+		var payload struct {
+			Responses []struct {
+				Responses []gvAnnotateResponse `json:"responses"`
+			} `json:"responses"`
+		}
+		if err := json.Unmarshal(resBody, &payload); err != nil {
+			return nil, fmt.Errorf("failed to parse response: %w", err)
+		}
+		for _, r := range payload.Responses {
+			responses = append(responses, r.Responses...)
+		}
+	} else {
+		var payload struct {
+			Responses []gvAnnotateResponse `json:"responses"`
+		}
+		if err := json.Unmarshal(resBody, &payload); err != nil {
+			return nil, fmt.Errorf("failed to parse response: %w", err)
+		}
+		responses = payload.Responses
+	}
+
+	var blocks []ocrBlock
+	for i, r := range responses {
+		if r.Error != nil {
+			return nil, fmt.Errorf("annotation error %v: %v", r.Error.Code, r.Error.Message)
+		}
+		if r.FullTextAnnotation == nil {
+			continue
+		}
+		page := i + 1
+		if r.Context != nil && r.Context.PageNumber > 0 {
+			page = r.Context.PageNumber
+		}
+		for _, p := range r.FullTextAnnotation.Pages {
+			for _, b := range p.Blocks {
+				var text strings.Builder
+				for _, para := range b.Paragraphs {
+					for _, w := range para.Words {
+						for _, s := range w.Symbols {
+							text.WriteString(s.Text)
+							if s.Property == nil || s.Property.DetectedBreak == nil {
+								continue
+							}
+							switch s.Property.DetectedBreak.Type {
+							case "SPACE", "SURE_SPACE":
+								text.WriteByte(' ')
+							case "EOL_SURE_SPACE", "LINE_BREAK":
+								text.WriteByte('\n')
+							case "HYPHEN":
+								text.WriteString("-\n")
+							}
+						}
+					}
+				}
+				blocks = append(blocks, ocrBlock{
+					page:       page,
+					text:       strings.TrimSpace(text.String()),
+					confidence: b.Confidence,
+					box:        gvBox(b.BoundingBox),
+				})
+			}
+		}
+	}
+	return blocks, nil
+}
+
+// gvBox converts a bounding polygon into a bounding box, which is normalised
+// to the dimensions of the page for documents.
+func gvBox(poly gvBoundingPoly) (box [4]float64) {
+	vertices := poly.Vertices
+	if len(vertices) == 0 {
+		vertices = poly.NormalizedVertices
+	}
+	if len(vertices) == 0 {
+		return
+	}
+	minX, minY := vertices[0].X, vertices[0].Y
+	maxX, maxY := minX, minY
+	for _, v := range vertices[1:] {
+		minX, maxX = min(minX, v.X), max(maxX, v.X)
+		minY, maxY = min(minY, v.Y), max(maxY, v.Y)
+	}
+	return [4]float64{minX, minY, maxX - minX, maxY - minY}
+}
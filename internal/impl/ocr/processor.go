@@ -0,0 +1,234 @@
+package ocr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	opFieldEngine        = "engine"
+	opFieldLanguages     = "languages"
+	opFieldMinConfidence = "min_confidence"
+	opFieldTesseract     = "tesseract"
+	opFieldTesseractPath = "path"
+	opFieldTesseractPSM  = "page_segmentation_mode"
+	opFieldGoogleVision  = "google_vision"
+	opFieldGVURL         = "url"
+	opFieldGVAPIKey      = "api_key"
+	opFieldGVTimeout     = "timeout"
+)
+
+func ocrProcSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Version("4.28.0").
+		Categories("Parsing").
+		Summary("Extracts text from images and scanned documents with optical character recognition.").
+		Description(`
+Each message is read as an image or document and replaced with an object containing the recognised text, along with each block of text and the confidence of its recognition between zero and one:
+
+`+"```json"+`
+{
+  "text": "INVOICE\n\nTotal: 42.00",
+  "confidence": 0.93,
+  "blocks": [
+    {
+      "page": 1,
+      "text": "INVOICE",
+      "confidence": 0.96,
+      "bounding_box": { "left": 120, "top": 48, "width": 310, "height": 52 }
+    },
+    {
+      "page": 1,
+      "text": "Total: 42.00",
+      "confidence": 0.9,
+      "bounding_box": { "left": 120, "top": 640, "width": 280, "height": 30 }
+    }
+  ]
+}
+`+"```"+`
+
+The following engines are supported:
+
+- `+"`tesseract`"+`: Executes the [Tesseract](https://github.com/tesseract-ocr/tesseract) command, which must be installed on the host, on images such as PNG, JPEG and TIFF. PDF documents are not supported, although the images of scanned documents can be extracted with the `+"[`pdf_extract` processor](/docs/components/processors/pdf_extract)"+`.
+- `+"`google_vision`"+`: Calls the document text detection feature of the Google Cloud Vision API, which also supports PDF and TIFF documents of up to five pages.
+
+Bounding boxes are measured in pixels, except for the blocks of PDF and TIFF documents recognised by `+"`google_vision`"+`, which are measured as fractions of the dimensions of the page.
+
+Messages that cannot be recognised are flagged as having failed, allowing you to use [standard processor error handling patterns](/docs/configuration/error_handling).`).
+		Fields(
+			service.NewStringAnnotatedEnumField(opFieldEngine, map[string]string{
+				"tesseract":     "The Tesseract command.",
+				"google_vision": "The Google Cloud Vision API.",
+			}).
+				Description("The OCR engine to use.").
+				Default("tesseract"),
+			service.NewStringListField(opFieldLanguages).
+				Description("The languages of the text, which are Tesseract language codes such as `eng` for the `tesseract` engine and BCP-47 codes such as `en` for the `google_vision` engine, where the languages are detected automatically when empty.").
+				Default([]any{}).
+				Example([]any{"eng", "deu"}),
+			service.NewFloatField(opFieldMinConfidence).
+				Description("The minimum confidence of blocks, where blocks of a lower confidence are removed from the result.").
+				Default(0.0),
+			service.NewObjectField(opFieldTesseract,
+				service.NewStringField(opFieldTesseractPath).
+					Description("The path of the tesseract command.").
+					Default("tesseract"),
+				service.NewIntField(opFieldTesseractPSM).
+					Description("The page segmentation mode of Tesseract, where `3` segments pages automatically and `6` assumes a single uniform block of text.").
+					Default(3),
+			).
+				Description("Configuration of the `tesseract` engine.").
+				Advanced(),
+			service.NewObjectField(opFieldGoogleVision,
+				service.NewURLField(opFieldGVURL).
+					Description("The base URL of the API.").
+					Default("https://vision.googleapis.com/v1"),
+				service.NewStringField(opFieldGVAPIKey).
+					Description("The API key used to authenticate with the API.").
+					Secret().
+					Default(""),
+				service.NewDurationField(opFieldGVTimeout).
+					Description("The maximum period to wait for each request.").
+					Default("60s"),
+			).
+				Description("Configuration of the `google_vision` engine.").
+				Advanced(),
+		).
+		Example("Scanned Documents", "Here we recognise the text of scanned receipts uploaded to a bucket, routing receipts that could not be recognised with confidence to a separate location for manual review.", `
+input:
+  gcp_cloud_storage:
+    bucket: receipts
+
+pipeline:
+  processors:
+    - ocr:
+        engine: google_vision
+        google_vision:
+          api_key: ${VISION_API_KEY}
+    - mapping: |
+        root = this
+        root.needs_review = this.confidence < 0.8
+`)
+}
+
+func init() {
+	err := service.RegisterProcessor(
+		"ocr", ocrProcSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Processor, error) {
+			return newOCRProcFromConfig(conf)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type ocrProc struct {
+	engine        ocrEngine
+	minConfidence float64
+}
+
+func newOCRProcFromConfig(conf *service.ParsedConfig) (*ocrProc, error) {
+	p := &ocrProc{}
+
+	engine, err := conf.FieldString(opFieldEngine)
+	if err != nil {
+		return nil, err
+	}
+	languages, err := conf.FieldStringList(opFieldLanguages)
+	if err != nil {
+		return nil, err
+	}
+	if p.minConfidence, err = conf.FieldFloat(opFieldMinConfidence); err != nil {
+		return nil, err
+	}
+
+	switch engine {
+	case "tesseract":
+		tConf := conf.Namespace(opFieldTesseract)
+		t := &tesseractEngine{languages: languages}
+		if t.path, err = tConf.FieldString(opFieldTesseractPath); err != nil {
+			return nil, err
+		}
+		if t.psm, err = tConf.FieldInt(opFieldTesseractPSM); err != nil {
+			return nil, err
+		}
+		p.engine = t
+	case "google_vision":
+		gConf := conf.Namespace(opFieldGoogleVision)
+		g := &googleVisionEngine{languages: languages}
+		if g.baseURL, err = gConf.FieldString(opFieldGVURL); err != nil {
+			return nil, err
+		}
+		if g.apiKey, err = gConf.FieldString(opFieldGVAPIKey); err != nil {
+			return nil, err
+		}
+		timeout, err := gConf.FieldDuration(opFieldGVTimeout)
+		if err != nil {
+			return nil, err
+		}
+		g.client = &http.Client{Timeout: timeout}
+		p.engine = g
+	default:
+		return nil, fmt.Errorf("unrecognised engine: %v", engine)
+	}
+	return p, nil
+}
+
+func (p *ocrProc) Process(ctx context.Context, msg *service.Message) (service.MessageBatch, error) {
+	data, err := msg.AsBytes()
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 {
+		return nil, errors.New("message is empty")
+	}
+
+	blocks, err := p.engine.recognise(ctx, data, detectMIMEType(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to recognise text: %w", err)
+	}
+
+	var texts []string
+	var totalConfidence float64
+	blockObjs := []any{}
+	for _, b := range blocks {
+		if b.text == "" || b.confidence < p.minConfidence {
+			continue
+		}
+		texts = append(texts, b.text)
+		totalConfidence += b.confidence
+		blockObjs = append(blockObjs, map[string]any{
+			"page":       int64(b.page),
+			"text":       b.text,
+			"confidence": b.confidence,
+			"bounding_box": map[string]any{
+				"left":   b.box[0],
+				"top":    b.box[1],
+				"width":  b.box[2],
+				"height": b.box[3],
+			},
+		})
+	}
+
+	var confidence float64
+	if len(texts) > 0 {
+		confidence = totalConfidence / float64(len(texts))
+	}
+
+	msg.SetStructuredMut(map[string]any{
+		"text":       strings.Join(texts, "\n\n"),
+		"confidence": confidence,
+		"blocks":     blockObjs,
+	})
+	return service.MessageBatch{msg}, nil
+}
+
+func (p *ocrProc) Close(ctx context.Context) error {
+	return nil
+}
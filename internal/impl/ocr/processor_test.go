@@ -0,0 +1,212 @@
+package ocr
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const testTSV = `level	page_num	block_num	par_num	line_num	word_num	left	top	width	height	conf	text
+1	1	0	0	0	0	0	0	800	600	-1
+2	1	1	0	0	0	10	20	300	80	-1
+3	1	1	1	0	0	10	20	300	80	-1
+4	1	1	1	1	0	10	20	300	30	-1
+5	1	1	1	1	1	10	20	100	30	96	Hello
+5	1	1	1	1	2	120	20	100	30	90	world
+4	1	1	1	2	0	10	60	300	30	-1
+5	1	1	1	2	1	10	60	100	30	84	again
+2	1	2	0	0	0	10	200	100	30	-1
+5	1	2	1	1	1	10	200	100	30	20	~~
+2	1	3	0	0	0	10	300	100	30	-1
+`
+
+// pngHeader is enough of a PNG image for its type to be detected.
+var pngHeader = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+func testOCRProc(t *testing.T, confStr string) *ocrProc {
+	t.Helper()
+
+	conf, err := ocrProcSpec().ParseYAML(confStr, nil)
+	require.NoError(t, err)
+
+	proc, err := newOCRProcFromConfig(conf)
+	require.NoError(t, err)
+	return proc
+}
+
+func TestParseTesseractTSV(t *testing.T) {
+	blocks, err := parseTesseractTSV(strings.NewReader(testTSV))
+	require.NoError(t, err)
+	require.Len(t, blocks, 2)
+
+	assert.Equal(t, 1, blocks[0].page)
+	assert.Equal(t, "Hello world\nagain", blocks[0].text)
+	assert.InDelta(t, 0.9, blocks[0].confidence, 0.0001)
+	assert.Equal(t, [4]float64{10, 20, 300, 80}, blocks[0].box)
+
+	assert.Equal(t, "~~", blocks[1].text)
+	assert.InDelta(t, 0.2, blocks[1].confidence, 0.0001)
+
+	_, err = parseTesseractTSV(strings.NewReader("level\tpage_num\n"))
+	require.Error(t, err)
+}
+
+func TestOCRTesseract(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("test requires a shell")
+	}
+
+	dir := t.TempDir()
+	tsvPath := filepath.Join(dir, "out.tsv")
+	argsPath := filepath.Join(dir, "args")
+	require.NoError(t, os.WriteFile(tsvPath, []byte(testTSV), 0o644))
+
+	script := filepath.Join(dir, "tesseract")
+	require.NoError(t, os.WriteFile(script, []byte(`#!/bin/sh
+echo "$@" > `+argsPath+`
+cat > /dev/null
+cat `+tsvPath+`
+`), 0o755))
+
+	proc := testOCRProc(t, `
+engine: tesseract
+languages: [ eng, deu ]
+min_confidence: 0.5
+tesseract:
+  path: `+script+`
+  page_segmentation_mode: 6
+`)
+
+	res, err := proc.Process(context.Background(), service.NewMessage(pngHeader))
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+
+	v, err := res[0].AsStructured()
+	require.NoError(t, err)
+	obj := v.(map[string]any)
+	assert.Equal(t, "Hello world\nagain", obj["text"])
+	assert.InDelta(t, 0.9, obj["confidence"], 0.0001)
+
+	blocks := obj["blocks"].([]any)
+	require.Len(t, blocks, 1)
+	assert.Equal(t, map[string]any{
+		"left": 10.0, "top": 20.0, "width": 300.0, "height": 80.0,
+	}, blocks[0].(map[string]any)["bounding_box"])
+
+	args, err := os.ReadFile(argsPath)
+	require.NoError(t, err)
+	assert.Equal(t, "stdin stdout --psm 6 -l eng+deu tsv\n", string(args))
+
+	_, err = proc.Process(context.Background(), service.NewMessage([]byte("%PDF-1.4\n")))
+	require.Error(t, err)
+}
+
+func TestOCRGoogleVision(t *testing.T) {
+	var paths []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path+"?"+r.URL.RawQuery)
+
+		b, _ := io.ReadAll(r.Body)
+		var body struct {
+			Requests []struct {
+				Image *struct {
+					Content string `json:"content"`
+				} `json:"image"`
+				InputConfig *struct {
+					Content  string `json:"content"`
+					MimeType string `json:"mimeType"`
+				} `json:"inputConfig"`
+				ImageContext struct {
+					LanguageHints []string `json:"languageHints"`
+				} `json:"imageContext"`
+			} `json:"requests"`
+		}
+		if err := json.Unmarshal(b, &body); err != nil || len(body.Requests) != 1 {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		assert.Equal(t, []string{"en"}, body.Requests[0].ImageContext.LanguageHints)
+
+		page := `{"fullTextAnnotation":{"pages":[{"blocks":[{
+  "boundingBox": {"vertices": [{"x":10,"y":20},{"x":110,"y":20},{"x":110,"y":50},{"x":10,"y":50}]},
+  "confidence": 0.95,
+  "paragraphs": [{"words": [
+    {"symbols": [{"text":"H"},{"text":"i","property":{"detectedBreak":{"type":"SPACE"}}}]},
+    {"symbols": [{"text":"there","property":{"detectedBreak":{"type":"LINE_BREAK"}}}]}
+  ]}]
+}]}]}%v}`
+		if body.Requests[0].InputConfig != nil {
+			assert.Equal(t, "application/pdf", body.Requests[0].InputConfig.MimeType)
+			_, _ = w.Write([]byte(`{"responses":[{"responses":[` +
+				strings.ReplaceAll(page, "%v", `,"context":{"pageNumber":1}`) + `,` +
+				strings.ReplaceAll(page, "%v", `,"context":{"pageNumber":2}`) + `]}]}`))
+			return
+		}
+
+		content, _ := base64.StdEncoding.DecodeString(body.Requests[0].Image.Content)
+		if string(content) != string(pngHeader) {
+			_, _ = w.Write([]byte(`{"responses":[{"error":{"code":3,"message":"bad image"}}]}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"responses":[` + strings.ReplaceAll(page, "%v", "") + `]}`))
+	}))
+	t.Cleanup(ts.Close)
+
+	proc := testOCRProc(t, `
+engine: google_vision
+languages: [ en ]
+google_vision:
+  url: `+ts.URL+`/v1
+  api_key: foo
+`)
+
+	res, err := proc.Process(context.Background(), service.NewMessage(pngHeader))
+	require.NoError(t, err)
+	v, err := res[0].AsStructured()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{
+		"text":       "Hi there",
+		"confidence": 0.95,
+		"blocks": []any{
+			map[string]any{
+				"page":       int64(1),
+				"text":       "Hi there",
+				"confidence": 0.95,
+				"bounding_box": map[string]any{
+					"left": 10.0, "top": 20.0, "width": 100.0, "height": 30.0,
+				},
+			},
+		},
+	}, v)
+
+	res, err = proc.Process(context.Background(), service.NewMessage([]byte("%PDF-1.4\n")))
+	require.NoError(t, err)
+	v, err = res[0].AsStructured()
+	require.NoError(t, err)
+	blocks := v.(map[string]any)["blocks"].([]any)
+	require.Len(t, blocks, 2)
+	assert.Equal(t, int64(2), blocks[1].(map[string]any)["page"])
+
+	_, err = proc.Process(context.Background(), service.NewMessage([]byte("GIF89a")))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "bad image")
+
+	assert.Equal(t, []string{
+		"/v1/images:annotate?key=foo",
+		"/v1/files:annotate?key=foo",
+		"/v1/images:annotate?key=foo",
+	}, paths)
+}
@@ -0,0 +1,385 @@
+package opa
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/shutdown"
+	"github.com/open-policy-agent/opa/bundle"
+	"github.com/open-policy-agent/opa/rego"
+
+	"github.com/benthosdev/benthos/v4/public/bloblang"
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	opFieldQuery                 = "query"
+	opFieldPolicyPaths           = "policy_paths"
+	opFieldBundleURL             = "bundle_url"
+	opFieldBundleHeaders         = "bundle_headers"
+	opFieldBundleRefreshInterval = "bundle_refresh_interval"
+	opFieldInput                 = "input"
+	opFieldAction                = "action"
+	opFieldMetadataKey           = "metadata_key"
+
+	opActionDrop     = "drop"
+	opActionFlag     = "flag"
+	opActionAnnotate = "annotate"
+)
+
+func opaProcessorConfig() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Categories("Utility").
+		Version("4.28.0").
+		Summary("Evaluates [Open Policy Agent](https://www.openpolicyagent.org/) Rego policies against messages, and drops, flags or annotates messages based on the decisions.").
+		Description(`
+Policies are loaded either from local Rego and data files with the field `+"`policy_paths`"+`, or from a [bundle](https://www.openpolicyagent.org/docs/latest/management-bundles/) downloaded from a remote server with the field `+"`bundle_url`"+`, which allows data governance rules to be managed centrally and distributed to many pipelines. When `+"`bundle_refresh_interval`"+` is set the bundle is downloaded again periodically, and policy changes take effect without restarting the pipeline. If a refresh fails the previously loaded policies remain in use.
+
+The input document of each evaluation is the contents of the message parsed as JSON, or the result of the `+"`input`"+` mapping when one is specified. The decision is the result of the `+"`query`"+`, and is acted upon according to the field `+"`action`"+`.
+
+### Decisions
+
+When the action is `+"`drop`"+` or `+"`flag`"+` the decision must be either a boolean, where `+"`true`"+` allows the message, or a collection of reasons for denying the message, such as the result of a `+"`deny`"+` rule, where an empty collection allows the message. A query that is undefined for a message denies it.
+
+When the action is `+"`annotate`"+` every message is kept, and the decision is added to the metadata key `+"`metadata_key`"+`, from which it can be read by subsequent processors. Messages for which the query is undefined are not annotated.
+
+Messages that cannot be evaluated, for example because they are not valid JSON, are flagged as having failed, allowing you to use [standard processor error handling patterns](/docs/configuration/error_handling).`).
+		Field(service.NewStringField(opFieldQuery).
+			Description("The Rego query to evaluate for each message, the result of which is the decision.").
+			Example("data.benthos.allow").
+			Example("data.governance.deny")).
+		Field(service.NewStringListField(opFieldPolicyPaths).
+			Description("A list of paths to Rego policy files, data files or directories containing them, to load policies from.").
+			Example([]string{"./policies"}).
+			Optional()).
+		Field(service.NewURLField(opFieldBundleURL).
+			Description("The URL of a remote bundle archive to download policies from.").
+			Example("https://bundles.example.com/governance/bundle.tar.gz").
+			Optional()).
+		Field(service.NewStringMapField(opFieldBundleHeaders).
+			Description("A map of headers to add to requests for the remote bundle, such as credentials.").
+			Example(map[string]any{"Authorization": "Bearer ${BUNDLE_TOKEN}"}).
+			Advanced().
+			Default(map[string]any{})).
+		Field(service.NewDurationField(opFieldBundleRefreshInterval).
+			Description("An optional interval at which the remote bundle is downloaded again in order to pick up policy changes.").
+			Example("5m").
+			Advanced().
+			Optional()).
+		Field(service.NewBloblangField(opFieldInput).
+			Description("An optional [Bloblang mapping](/docs/guides/bloblang/about) that constructs the input document of each evaluation. By default the contents of the message parsed as JSON are used.").
+			Example(`root.document = this
+root.topic = @kafka_topic`).
+			Optional()).
+		Field(service.NewStringAnnotatedEnumField(opFieldAction, map[string]string{
+			opActionDrop:     "Denied messages are removed from the pipeline.",
+			opActionFlag:     "Denied messages are flagged as having failed, with an error describing the reasons for the denial.",
+			opActionAnnotate: "All messages are kept and the decision is added to metadata.",
+		}).
+			Description("The action to take based on each decision.").
+			Default(opActionFlag)).
+		Field(service.NewStringField(opFieldMetadataKey).
+			Description("The metadata key to add decisions to when the action is `annotate`.").
+			Default("opa_decision")).
+		LintRule(fmt.Sprintf(`root = if (this.%v | []).length() == 0 && (this.%v | "") == "" {
+  "either the policy_paths or bundle_url field must be specified"
+}`, opFieldPolicyPaths, opFieldBundleURL)).
+		Example(
+			"Drop denied messages",
+			"Here we load a policy from a local file that only allows events from known tenants, and drop all other events.",
+			`
+pipeline:
+  processors:
+    - opa:
+        policy_paths: [ ./policies/tenants.rego ]
+        query: data.tenants.allow
+        action: drop
+`,
+		).
+		Example(
+			"Centrally managed governance rules",
+			"Here we download a bundle of governance rules that is refreshed every five minutes, flag messages that violate any of the rules, and route them to a dead letter queue.",
+			`
+pipeline:
+  processors:
+    - opa:
+        bundle_url: https://bundles.example.com/governance/bundle.tar.gz
+        bundle_refresh_interval: 5m
+        query: data.governance.deny
+        input: |
+          root.document = this
+          root.source = @source
+        action: flag
+
+output:
+  switch:
+    cases:
+      - check: errored()
+        output:
+          resource: dead_letter_queue
+      - output:
+          resource: main_output
+`,
+		)
+}
+
+func init() {
+	err := service.RegisterProcessor(
+		"opa", opaProcessorConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Processor, error) {
+			return newOPAProcessorFromConfig(conf, mgr)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type opaProcessor struct {
+	log *service.Logger
+
+	query         string
+	policyPaths   []string
+	bundleURL     string
+	bundleHeaders map[string]string
+	input         *bloblang.Executor
+	action        string
+	metadataKey   string
+
+	client *http.Client
+
+	preparedMut sync.RWMutex
+	prepared    rego.PreparedEvalQuery
+
+	shutSig *shutdown.Signaller
+}
+
+func newOPAProcessorFromConfig(conf *service.ParsedConfig, mgr *service.Resources) (*opaProcessor, error) {
+	o := &opaProcessor{
+		log:     mgr.Logger(),
+		client:  &http.Client{Timeout: time.Minute},
+		shutSig: shutdown.NewSignaller(),
+	}
+
+	var err error
+	if o.query, err = conf.FieldString(opFieldQuery); err != nil {
+		return nil, err
+	}
+	if conf.Contains(opFieldPolicyPaths) {
+		if o.policyPaths, err = conf.FieldStringList(opFieldPolicyPaths); err != nil {
+			return nil, err
+		}
+	}
+	if conf.Contains(opFieldBundleURL) {
+		if o.bundleURL, err = conf.FieldString(opFieldBundleURL); err != nil {
+			return nil, err
+		}
+	}
+	if len(o.policyPaths) == 0 && o.bundleURL == "" {
+		return nil, fmt.Errorf("either a `%v` or `%v` must be specified", opFieldPolicyPaths, opFieldBundleURL)
+	}
+	if o.bundleHeaders, err = conf.FieldStringMap(opFieldBundleHeaders); err != nil {
+		return nil, err
+	}
+	if conf.Contains(opFieldInput) {
+		if o.input, err = conf.FieldBloblang(opFieldInput); err != nil {
+			return nil, err
+		}
+	}
+	if o.action, err = conf.FieldString(opFieldAction); err != nil {
+		return nil, err
+	}
+	if o.metadataKey, err = conf.FieldString(opFieldMetadataKey); err != nil {
+		return nil, err
+	}
+
+	if o.prepared, err = o.prepare(context.Background()); err != nil {
+		return nil, err
+	}
+
+	if o.bundleURL != "" && conf.Contains(opFieldBundleRefreshInterval) {
+		interval, err := conf.FieldDuration(opFieldBundleRefreshInterval)
+		if err != nil {
+			return nil, err
+		}
+		if interval <= 0 {
+			return nil, errors.New("bundle refresh interval must be greater than zero")
+		}
+		go o.refreshLoop(interval)
+	} else {
+		o.shutSig.TriggerHasStopped()
+	}
+	return o, nil
+}
+
+func (o *opaProcessor) downloadBundle(ctx context.Context) (*bundle.Bundle, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, o.bundleURL, http.NoBody)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range o.bundleHeaders {
+		req.Header.Set(k, v)
+	}
+
+	res, err := o.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return nil, fmt.Errorf("unexpected status code: %v", res.StatusCode)
+	}
+
+	b, err := bundle.NewReader(res.Body).Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read bundle: %w", err)
+	}
+	return &b, nil
+}
+
+func (o *opaProcessor) prepare(ctx context.Context) (rego.PreparedEvalQuery, error) {
+	opts := []func(*rego.Rego){rego.Query(o.query)}
+	if len(o.policyPaths) > 0 {
+		opts = append(opts, rego.Load(o.policyPaths, nil))
+	}
+	if o.bundleURL != "" {
+		b, err := o.downloadBundle(ctx)
+		if err != nil {
+			return rego.PreparedEvalQuery{}, fmt.Errorf("failed to download bundle: %w", err)
+		}
+		opts = append(opts, rego.ParsedBundle(o.bundleURL, b))
+	}
+
+	prepared, err := rego.New(opts...).PrepareForEval(ctx)
+	if err != nil {
+		return rego.PreparedEvalQuery{}, fmt.Errorf("failed to prepare query: %w", err)
+	}
+	return prepared, nil
+}
+
+func (o *opaProcessor) refreshLoop(interval time.Duration) {
+	defer o.shutSig.TriggerHasStopped()
+
+	ctx, done := o.shutSig.HardStopCtx(context.Background())
+	defer done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			prepared, err := o.prepare(ctx)
+			if err != nil {
+				o.log.Errorf("Failed to refresh policy bundle: %v", err)
+				continue
+			}
+			o.preparedMut.Lock()
+			o.prepared = prepared
+			o.preparedMut.Unlock()
+			o.log.Debug("Refreshed policy bundle")
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// decisionAllows interprets a decision as either a boolean or a collection of
+// reasons for denial, returning the reasons when the message is denied.
+func decisionAllows(decision any) (allowed bool, reasons []any, err error) {
+	switch t := decision.(type) {
+	case bool:
+		return t, nil, nil
+	case []any:
+		return len(t) == 0, t, nil
+	case map[string]any:
+		keys := make([]string, 0, len(t))
+		for k := range t {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			reasons = append(reasons, map[string]any{k: t[k]})
+		}
+		return len(t) == 0, reasons, nil
+	}
+	return false, nil, fmt.Errorf("expected decision to be a boolean or collection, got %T", decision)
+}
+
+func (o *opaProcessor) Process(ctx context.Context, msg *service.Message) (service.MessageBatch, error) {
+	var input any
+	var err error
+	if o.input != nil {
+		var inputMsg *service.Message
+		if inputMsg, err = msg.BloblangQuery(o.input); err != nil {
+			return nil, fmt.Errorf("input mapping failed: %w", err)
+		}
+		if inputMsg == nil {
+			return nil, errors.New("input mapping deleted the message")
+		}
+		input, err = inputMsg.AsStructured()
+	} else {
+		input, err = msg.AsStructured()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse input: %w", err)
+	}
+
+	o.preparedMut.RLock()
+	prepared := o.prepared
+	o.preparedMut.RUnlock()
+
+	rs, err := prepared.Eval(ctx, rego.EvalInput(input))
+	if err != nil {
+		return nil, fmt.Errorf("failed to evaluate policy: %w", err)
+	}
+
+	var decision any
+	defined := len(rs) > 0 && len(rs[0].Expressions) > 0
+	if defined {
+		decision = rs[0].Expressions[0].Value
+	}
+
+	if o.action == opActionAnnotate {
+		if defined {
+			msg.MetaSetMut(o.metadataKey, decision)
+		}
+		return service.MessageBatch{msg}, nil
+	}
+
+	allowed, reasons := false, []any(nil)
+	if defined {
+		if allowed, reasons, err = decisionAllows(decision); err != nil {
+			return nil, err
+		}
+	}
+	if allowed {
+		return service.MessageBatch{msg}, nil
+	}
+	if o.action == opActionDrop {
+		return nil, nil
+	}
+
+	if !defined {
+		return nil, errors.New("denied by policy: query is undefined")
+	}
+	if len(reasons) > 0 {
+		return nil, fmt.Errorf("denied by policy: %v", reasons)
+	}
+	return nil, errors.New("denied by policy")
+}
+
+func (o *opaProcessor) Close(ctx context.Context) error {
+	o.shutSig.TriggerHardStop()
+	select {
+	case <-o.shutSig.HasStoppedChan():
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return nil
+}
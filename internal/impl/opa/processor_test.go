@@ -0,0 +1,174 @@
+package opa
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const testPolicy = `
+package tenants
+
+import rego.v1
+
+default allow := false
+
+allow if input.tenant in {"foo", "bar"}
+
+deny contains msg if {
+	not input.owner
+	msg := "document is missing an owner"
+}
+
+labels := {"tenant": input.tenant, "owned": is_string(input.owner)}
+`
+
+func testProcessor(t *testing.T, confStr string) *opaProcessor {
+	t.Helper()
+
+	policyPath := filepath.Join(t.TempDir(), "tenants.rego")
+	require.NoError(t, os.WriteFile(policyPath, []byte(testPolicy), 0o644))
+
+	conf, err := opaProcessorConfig().ParseYAML(confStr+`
+policy_paths: [ `+policyPath+` ]
+`, nil)
+	require.NoError(t, err)
+
+	proc, err := newOPAProcessorFromConfig(conf, service.MockResources())
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, proc.Close(context.Background()))
+	})
+	return proc
+}
+
+func TestProcessorDrop(t *testing.T) {
+	proc := testProcessor(t, `
+query: data.tenants.allow
+action: drop
+`)
+
+	res, err := proc.Process(context.Background(), service.NewMessage([]byte(`{"tenant":"foo"}`)))
+	require.NoError(t, err)
+	assert.Len(t, res, 1)
+
+	res, err = proc.Process(context.Background(), service.NewMessage([]byte(`{"tenant":"baz"}`)))
+	require.NoError(t, err)
+	assert.Empty(t, res)
+
+	_, err = proc.Process(context.Background(), service.NewMessage([]byte(`not json`)))
+	require.Error(t, err)
+}
+
+func TestProcessorFlag(t *testing.T) {
+	proc := testProcessor(t, `
+query: data.tenants.deny
+input: |
+  root = this.document
+`)
+
+	res, err := proc.Process(context.Background(), service.NewMessage([]byte(`{"document":{"owner":"alice"}}`)))
+	require.NoError(t, err)
+	assert.Len(t, res, 1)
+
+	_, err = proc.Process(context.Background(), service.NewMessage([]byte(`{"document":{}}`)))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "document is missing an owner")
+}
+
+func TestProcessorAnnotate(t *testing.T) {
+	proc := testProcessor(t, `
+query: data.tenants.labels
+action: annotate
+metadata_key: labels
+`)
+
+	res, err := proc.Process(context.Background(), service.NewMessage([]byte(`{"tenant":"foo","owner":"alice"}`)))
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+
+	v, exists := res[0].MetaGetMut("labels")
+	require.True(t, exists)
+	assert.Equal(t, map[string]any{"tenant": "foo", "owned": true}, v)
+}
+
+func TestProcessorRemoteBundle(t *testing.T) {
+	var version atomic.Int32
+	version.Store(1)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		allowed := `"foo"`
+		if version.Load() > 1 {
+			allowed = `"bar"`
+		}
+
+		var buf bytes.Buffer
+		gw := gzip.NewWriter(&buf)
+		tw := tar.NewWriter(gw)
+		policy := []byte(`package tenants
+
+import rego.v1
+
+allow if input.tenant == ` + allowed + `
+`)
+		require.NoError(t, tw.WriteHeader(&tar.Header{
+			Name: "/tenants/policy.rego",
+			Mode: 0o644,
+			Size: int64(len(policy)),
+		}))
+		_, err := tw.Write(policy)
+		require.NoError(t, err)
+		require.NoError(t, tw.Close())
+		require.NoError(t, gw.Close())
+
+		_, _ = w.Write(buf.Bytes())
+	}))
+	t.Cleanup(ts.Close)
+
+	conf, err := opaProcessorConfig().ParseYAML(`
+bundle_url: `+ts.URL+`
+bundle_headers:
+  Authorization: Bearer secret
+bundle_refresh_interval: 10ms
+query: data.tenants.allow
+`, nil)
+	require.NoError(t, err)
+
+	proc, err := newOPAProcessorFromConfig(conf, service.MockResources())
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, proc.Close(context.Background()))
+	})
+
+	res, err := proc.Process(context.Background(), service.NewMessage([]byte(`{"tenant":"foo"}`)))
+	require.NoError(t, err)
+	assert.Len(t, res, 1)
+
+	version.Store(2)
+	assert.Eventually(t, func() bool {
+		_, err := proc.Process(context.Background(), service.NewMessage([]byte(`{"tenant":"foo"}`)))
+		return err != nil
+	}, time.Second, 10*time.Millisecond)
+
+	res, err = proc.Process(context.Background(), service.NewMessage([]byte(`{"tenant":"bar"}`)))
+	require.NoError(t, err)
+	assert.Len(t, res, 1)
+}
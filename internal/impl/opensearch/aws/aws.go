@@ -22,7 +22,12 @@ func init() {
 			return err
 		}
 
-		signer, err := awsv2.NewSigner(tsess)
+		signingService, err := conf.FieldString(opensearch.ESOFieldAWSService)
+		if err != nil {
+			return err
+		}
+
+		signer, err := awsv2.NewSignerWithService(tsess, signingService)
 		if err != nil {
 			return err
 		}
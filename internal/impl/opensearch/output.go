@@ -25,6 +25,7 @@ const (
 	esoFieldIndex        = "index"
 	esoFieldPipeline     = "pipeline"
 	esoFieldRouting      = "routing"
+	esoFieldHeaders      = "headers"
 	esoFieldTLS          = "tls"
 	esoFieldAuth         = "basic_auth"
 	esoFieldAuthEnabled  = "enabled"
@@ -33,6 +34,7 @@ const (
 	esoFieldBatching     = "batching"
 	esoFieldAWS          = "aws"
 	ESOFieldAWSEnabled   = "enabled"
+	ESOFieldAWSService   = "service"
 )
 
 func notImportedAWSOptFn(conf *service.ParsedConfig, osconf *opensearchapi.Config) error {
@@ -51,10 +53,17 @@ func AWSField() *service.ConfigField {
 	return service.NewObjectField(esoFieldAWS,
 		append([]*service.ConfigField{
 			service.NewBoolField(ESOFieldAWSEnabled).
-				Description("Whether to connect to Amazon Elastic Service.").
+				Description("Whether to connect to Amazon OpenSearch Service.").
 				Default(false),
+			service.NewStringAnnotatedEnumField(ESOFieldAWSService, map[string]string{
+				"es":   "Amazon OpenSearch Service managed clusters.",
+				"aoss": "Amazon OpenSearch Serverless collections.",
+			}).
+				Description("The service name used when signing requests with SigV4.").
+				Version("4.28.0").
+				Default("es"),
 		}, config.SessionFields()...)...).
-		Description("Enables and customises connectivity to Amazon Elastic Service.").
+		Description("Enables and customises connectivity to Amazon OpenSearch Service and OpenSearch Serverless collections, where requests are signed with AWS SigV4.").
 		Advanced()
 }
 
@@ -105,6 +114,14 @@ func esoConfigFromParsed(pConf *service.ParsedConfig) (conf esoConfig, err error
 		}
 	}
 
+	var headers map[string]string
+	if headers, err = pConf.FieldStringMap(esoFieldHeaders); err != nil {
+		return
+	}
+	if conf.clientOpts.Client.Header, err = compatibleHeaders(headers); err != nil {
+		return
+	}
+
 	if conf.actionStr, err = pConf.FieldInterpolatedString(esoFieldAction); err != nil {
 		return
 	}
@@ -127,6 +144,34 @@ func esoConfigFromParsed(pConf *service.ParsedConfig) (conf esoConfig, err error
 	return
 }
 
+// compatibleHeaders converts custom headers into the form sent with each
+// request. Elasticsearch clients and proxies often add vendor specific
+// compatibility media types (application/vnd.elasticsearch+json) which
+// OpenSearch rejects, and therefore these are rewritten to their plain JSON
+// equivalents.
+func compatibleHeaders(headers map[string]string) (http.Header, error) {
+	if len(headers) == 0 {
+		return nil, nil
+	}
+	h := http.Header{}
+	for k, v := range headers {
+		switch http.CanonicalHeaderKey(k) {
+		case "Host", "Content-Length", "Authorization":
+			return nil, fmt.Errorf("header %v cannot be overridden", k)
+		case "Accept", "Content-Type":
+			if strings.Contains(v, "vnd.elasticsearch+") {
+				if strings.Contains(v, "x-ndjson") {
+					v = "application/x-ndjson"
+				} else {
+					v = "application/json"
+				}
+			}
+		}
+		h.Set(k, v)
+	}
+	return h, nil
+}
+
 //------------------------------------------------------------------------------
 
 // OutputSpec returns the config spec for an elasticsearch output writer.
@@ -134,9 +179,17 @@ func OutputSpec() *service.ConfigSpec {
 	return service.NewConfigSpec().
 		Stable().
 		Categories("Services").
-		Summary(`Publishes messages into an Elasticsearch index. If the index does not exist then it is created with a dynamic mapping.`).
+		Summary(`Publishes messages into an OpenSearch index. If the index does not exist then it is created with a dynamic mapping.`).
 		Description(`
-Both the `+"`id` and `index`"+` fields can be dynamically set using function interpolations described [here](/docs/configuration/interpolation#bloblang-queries). When sending batched messages these interpolations are performed per message part.`+service.OutputPerformanceDocs(true, true)).
+Both the `+"`id` and `index`"+` fields can be dynamically set using function interpolations described [here](/docs/configuration/interpolation#bloblang-queries). When sending batched messages these interpolations are performed per message part.
+
+### Amazon OpenSearch Service and Serverless
+
+Requests can be signed with AWS SigV4 by enabling the `+"`aws`"+` field. In order to write to an OpenSearch Serverless collection set `+"`aws.service`"+` to `+"`aoss`"+`. Time series collections in OpenSearch Serverless do not accept custom document IDs, in which case the `+"`id`"+` field should be left empty and the `+"`index` or `create`"+` action used.
+
+### Delivery Errors
+
+Each document of a batch is delivered with a bulk request and any documents that are rejected, or that could not be converted into a bulk request, are reported individually. This means only the failed messages of a batch are nacked, and they can be routed elsewhere with a `+"[`fallback`](/docs/components/outputs/fallback)"+` or `+"[`reject_errored`](/docs/components/outputs/reject_errored)"+` output.`+service.OutputPerformanceDocs(true, true)).
 		Fields(
 			service.NewStringListField(esoFieldURLs).
 				Description("A list of URLs to connect to. If an item of the list contains commas it will be expanded into multiple URLs.").
@@ -144,10 +197,11 @@ Both the `+"`id` and `index`"+` fields can be dynamically set using function int
 			service.NewInterpolatedStringField(esoFieldIndex).
 				Description("The index to place messages."),
 			service.NewInterpolatedStringField(esoFieldAction).
-				Description("The action to take on the document. This field must resolve to one of the following action types: `index`, `update` or `delete`."),
+				Description("The action to take on the document. This field must resolve to one of the following action types: `index`, `create`, `update` or `delete`."),
 			service.NewInterpolatedStringField(esoFieldID).
-				Description("The ID for indexed messages. Interpolation should be used in order to create a unique ID for each message.").
-				Example(`${!counter()}-${!timestamp_unix()}`),
+				Description("The ID for indexed messages. Interpolation should be used in order to create a unique ID for each message. When empty the ID is generated by OpenSearch, which is only supported by the `index` and `create` actions.").
+				Example(`${!counter()}-${!timestamp_unix()}`).
+				Default(""),
 			service.NewInterpolatedStringField(esoFieldPipeline).
 				Description("An optional pipeline id to preprocess incoming documents.").
 				Advanced().
@@ -156,6 +210,11 @@ Both the `+"`id` and `index`"+` fields can be dynamically set using function int
 				Description("The routing key to use for the document.").
 				Advanced().
 				Default(""),
+			service.NewStringMapField(esoFieldHeaders).
+				Description("Custom headers to add to each request. Elasticsearch compatibility media types in `Accept` and `Content-Type` headers are converted into their plain JSON equivalents, as these are rejected by OpenSearch.").
+				Advanced().
+				Version("4.28.0").
+				Default(map[string]any{}),
 			service.NewTLSToggledField(esoFieldTLS),
 			service.NewOutputMaxInFlightField(),
 		).
@@ -257,34 +316,6 @@ func (e *Output) WriteBatch(ctx context.Context, msg service.MessageBatch) error
 		return service.ErrNotConnected
 	}
 
-	requests := make([]*pendingBulkIndex, len(msg))
-
-	for i := 0; i < len(msg); i++ {
-		rawBytes, ierr := msg[i].AsBytes()
-		if ierr != nil {
-			e.log.Errorf("Failed to obtain message raw data: %v\n", ierr)
-			return fmt.Errorf("failed to obtain message raw data: %w", ierr)
-		}
-
-		pbi := &pendingBulkIndex{Payload: rawBytes}
-		if pbi.Action, ierr = msg.TryInterpolatedString(i, e.conf.actionStr); ierr != nil {
-			return fmt.Errorf("action interpolation error: %w", ierr)
-		}
-		if pbi.Index, ierr = msg.TryInterpolatedString(i, e.conf.indexStr); ierr != nil {
-			return fmt.Errorf("index interpolation error: %w", ierr)
-		}
-		if pbi.Pipeline, ierr = msg.TryInterpolatedString(i, e.conf.pipelineStr); ierr != nil {
-			return fmt.Errorf("pipeline interpolation error: %w", ierr)
-		}
-		if pbi.Routing, ierr = msg.TryInterpolatedString(i, e.conf.routingStr); ierr != nil {
-			return fmt.Errorf("routing interpolation error: %w", ierr)
-		}
-		if pbi.ID, ierr = msg.TryInterpolatedString(i, e.conf.idStr); ierr != nil {
-			return fmt.Errorf("id interpolation error: %w", ierr)
-		}
-		requests[i] = pbi
-	}
-
 	start := time.Now()
 	b, _ := opensearchutil.NewBulkIndexer(opensearchutil.BulkIndexerConfig{
 		Client: e.client,
@@ -292,20 +323,28 @@ func (e *Output) WriteBatch(ctx context.Context, msg service.MessageBatch) error
 
 	var bErrMut sync.Mutex
 	var bErr *service.BatchError
+	failed := func(i int, err error) {
+		bErrMut.Lock()
+		defer bErrMut.Unlock()
 
-	for i, v := range requests {
+		if bErr == nil {
+			bErr = service.NewBatchError(msg, err)
+		}
+		bErr = bErr.Failed(i, err)
+	}
+
+	for i := 0; i < len(msg); i++ {
 		i := i
-		bulkReq, err := e.buildBulkableRequest(v, func(err error) {
-			bErrMut.Lock()
-			defer bErrMut.Unlock()
 
-			if bErr == nil {
-				bErr = service.NewBatchError(msg, err)
-			}
-			bErr = bErr.Failed(i, err)
+		// Messages that cannot be converted into a bulk request are failed
+		// individually, and the remaining messages are still attempted.
+		bulkReq, err := e.bulkIndexerItemFrom(msg, i, func(err error) {
+			failed(i, err)
 		})
 		if err != nil {
-			return err
+			e.log.Errorf("Failed to prepare message for OpenSearch: %v\n", err)
+			failed(i, err)
+			continue
 		}
 		if err = b.Add(ctx, *bulkReq); err != nil {
 			return err
@@ -336,17 +375,47 @@ func (e *Output) Close(context.Context) error {
 	return nil
 }
 
+func (e *Output) bulkIndexerItemFrom(msg service.MessageBatch, i int, onError func(err error)) (*opensearchutil.BulkIndexerItem, error) {
+	rawBytes, err := msg[i].AsBytes()
+	if err != nil {
+		return nil, fmt.Errorf("failed to obtain message raw data: %w", err)
+	}
+
+	pbi := &pendingBulkIndex{Payload: rawBytes}
+	if pbi.Action, err = msg.TryInterpolatedString(i, e.conf.actionStr); err != nil {
+		return nil, fmt.Errorf("action interpolation error: %w", err)
+	}
+	if pbi.Index, err = msg.TryInterpolatedString(i, e.conf.indexStr); err != nil {
+		return nil, fmt.Errorf("index interpolation error: %w", err)
+	}
+	if pbi.Pipeline, err = msg.TryInterpolatedString(i, e.conf.pipelineStr); err != nil {
+		return nil, fmt.Errorf("pipeline interpolation error: %w", err)
+	}
+	if pbi.Routing, err = msg.TryInterpolatedString(i, e.conf.routingStr); err != nil {
+		return nil, fmt.Errorf("routing interpolation error: %w", err)
+	}
+	if pbi.ID, err = msg.TryInterpolatedString(i, e.conf.idStr); err != nil {
+		return nil, fmt.Errorf("id interpolation error: %w", err)
+	}
+	return e.buildBulkableRequest(pbi, onError)
+}
+
 // Build a bulkable request for a given pending bulk index item.
 func (e *Output) buildBulkableRequest(p *pendingBulkIndex, onError func(err error)) (r *opensearchutil.BulkIndexerItem, err error) {
+	switch p.Action {
+	case "update", "delete":
+		if p.ID == "" {
+			return nil, fmt.Errorf("opensearch action '%s' requires an id", p.Action)
+		}
+	}
+
 	switch p.Action {
 	case "update":
 		r = &opensearchutil.BulkIndexerItem{
-			Index:  p.Index,
-			Action: "update",
-			Body:   bytes.NewReader(p.Payload),
-		}
-		if p.ID != "" {
-			r.DocumentID = p.ID
+			Index:      p.Index,
+			DocumentID: p.ID,
+			Action:     "update",
+			Body:       bytes.NewReader(p.Payload),
 		}
 		if p.Routing != "" {
 			r.Routing = &p.Routing
@@ -360,10 +429,10 @@ func (e *Output) buildBulkableRequest(p *pendingBulkIndex, onError func(err erro
 		if p.Routing != "" {
 			r.Routing = &p.Routing
 		}
-	case "index":
+	case "index", "create":
 		r = &opensearchutil.BulkIndexerItem{
 			Index:  p.Index,
-			Action: "index",
+			Action: p.Action,
 			Body:   bytes.NewReader(p.Payload),
 		}
 		if p.ID != "" {
@@ -0,0 +1,61 @@
+package opensearch
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompatibleHeaders(t *testing.T) {
+	h, err := compatibleHeaders(nil)
+	require.NoError(t, err)
+	assert.Nil(t, h)
+
+	h, err = compatibleHeaders(map[string]string{
+		"accept":       "application/vnd.elasticsearch+json;compatible-with=7",
+		"Content-Type": "application/vnd.elasticsearch+x-ndjson;compatible-with=7",
+		"X-Custom":     "foo",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "application/json", h.Get("Accept"))
+	assert.Equal(t, "application/x-ndjson", h.Get("Content-Type"))
+	assert.Equal(t, "foo", h.Get("X-Custom"))
+
+	_, err = compatibleHeaders(map[string]string{
+		"authorization": "Bearer nope",
+	})
+	require.Error(t, err)
+}
+
+func TestOutputConfigHeaders(t *testing.T) {
+	pConf, err := OutputSpec().ParseYAML(`
+urls: [ http://localhost:9200 ]
+index: foo
+action: create
+headers:
+  X-Opaque-Id: benthos
+`, nil)
+	require.NoError(t, err)
+
+	conf, err := esoConfigFromParsed(pConf)
+	require.NoError(t, err)
+	assert.Equal(t, "benthos", conf.clientOpts.Client.Header.Get("X-Opaque-Id"))
+	assert.Equal(t, []string{"http://localhost:9200"}, conf.clientOpts.Client.Addresses)
+}
+
+func TestBuildBulkableRequestRequiresID(t *testing.T) {
+	o := &Output{}
+	for _, action := range []string{"update", "delete"} {
+		_, err := o.buildBulkableRequest(&pendingBulkIndex{Action: action, Index: "foo"}, nil)
+		require.Error(t, err, action)
+	}
+
+	r, err := o.buildBulkableRequest(&pendingBulkIndex{Action: "create", Index: "foo", Payload: []byte(`{}`)}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "create", r.Action)
+	assert.Empty(t, r.DocumentID)
+
+	_, err = o.buildBulkableRequest(&pendingBulkIndex{Action: "nope", Index: "foo"}, nil)
+	require.Error(t, err)
+}
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"io"
 	"io/fs"
+	"slices"
 	"sync"
 
 	"github.com/parquet-go/parquet-go"
@@ -28,14 +29,39 @@ func parquetInputConfig() *service.ConfigSpec {
 			Description(`Optionally process records in batches. This can help to speed up the consumption of exceptionally large files. When the end of the file is reached the remaining records are processed as a (potentially smaller) batch.`).
 			Default(1).
 			Advanced()).
+		Field(service.NewStringListField("columns").
+			Description("An optional list of top level columns to read, all other columns are skipped. When empty all columns are read.").
+			Example([]string{"id", "name"}).
+			Default([]any{}).
+			Advanced()).
+		Field(parquetFilterFieldSpec()).
 		Field(service.NewAutoRetryNacksToggleField()).
 		Description(`
 This input uses [https://github.com/parquet-go/parquet-go](https://github.com/parquet-go/parquet-go), which is itself experimental. Therefore changes could be made into how this processor functions outside of major version releases.
 
-By default any BYTE_ARRAY or FIXED_LEN_BYTE_ARRAY value will be extracted as a byte slice (` + "`[]byte`" + `) unless the logical type is UTF8, in which case they are extracted as a string (` + "`string`" + `).
-
-When a value extracted as a byte slice exists within a document which is later JSON serialized by default it will be base 64 encoded into strings, which is the default for arbitrary data fields. It is possible to convert these binary values to strings (or other data types) using Bloblang transformations such as ` + "`root.foo = this.foo.string()` or `root.foo = this.foo.encode(\"hex\")`" + `, etc.`).
-		Version("4.8.0")
+By default any BYTE_ARRAY or FIXED_LEN_BYTE_ARRAY value will be extracted as a byte slice (`+"`[]byte`"+`) unless the logical type is UTF8, in which case they are extracted as a string (`+"`string`"+`).
+
+When a value extracted as a byte slice exists within a document which is later JSON serialized by default it will be base 64 encoded into strings, which is the default for arbitrary data fields. It is possible to convert these binary values to strings (or other data types) using Bloblang transformations such as `+"`root.foo = this.foo.string()` or `root.foo = this.foo.encode(\"hex\")`"+`, etc.
+
+### Column Projection and Filtering
+
+The `+"`columns`"+` field can be used in order to decode only a subset of the top level columns of each file, which avoids the cost of reading and decoding columns that aren't needed.
+
+The `+"`filters`"+` field can be used in order to consume only rows that satisfy a list of simple predicates. Each row group of a file is checked against the minimum and maximum column statistics written into the file footer, and row groups that cannot contain matching rows are skipped without being read. The remaining rows are then checked individually. Columns referenced by filters do not need to be listed within `+"`columns`"+`.`).
+		Version("4.8.0").
+		Example("Projection and Filtering", "Read only the columns we need from rows where the status is active and the score is at least 10:", `
+input:
+  parquet:
+    paths: [ ./data/*.parquet ]
+    columns: [ id, name ]
+    filters:
+      - column: status
+        operator: eq
+        value: active
+      - column: score
+        operator: gte
+        value: 10
+`)
 }
 
 func init() {
@@ -78,8 +104,24 @@ func newParquetInputFromConfig(conf *service.ParsedConfig, mgr *service.Resource
 		return nil, fmt.Errorf("batch_size must be >0, got %v", batchSize)
 	}
 
+	columns, err := conf.FieldStringList("columns")
+	if err != nil {
+		return nil, err
+	}
+
+	filterConfs, err := conf.FieldObjectList("filters")
+	if err != nil {
+		return nil, err
+	}
+	filters, err := parquetFiltersFromParsed(filterConfs)
+	if err != nil {
+		return nil, err
+	}
+
 	rdr := &parquetReader{
 		batchSize:      batchSize,
+		columns:        columns,
+		filters:        filters,
 		pathsRemaining: pathsRemaining,
 		log:            mgr.Logger(),
 		mgr:            mgr,
@@ -88,13 +130,49 @@ func newParquetInputFromConfig(conf *service.ParsedConfig, mgr *service.Resource
 }
 
 type openParquetFile struct {
-	schema *parquet.Schema
-	handle fs.File
-	rdr    *parquet.GenericReader[any]
+	schema    *parquet.Schema
+	handle    fs.File
+	rowGroups []parquet.RowGroup
+	rdr       *parquet.GenericReader[any]
+
+	// Columns that were read only for the purpose of filtering and must be
+	// removed from rows.
+	dropColumns []string
+}
+
+// read fills the provided buffer with rows from the remaining row groups of the
+// file, returning io.EOF once all row groups have been consumed.
+func (p *openParquetFile) read(rows []any) (n int, err error) {
+	for n < len(rows) {
+		if p.rdr == nil {
+			if len(p.rowGroups) == 0 {
+				return n, io.EOF
+			}
+			if p.rdr, err = newRowGroupReaderWithoutPanic(p.rowGroups[0], p.schema); err != nil {
+				return n, err
+			}
+			p.rowGroups = p.rowGroups[1:]
+		}
+
+		var read int
+		read, err = readWithoutPanic(p.rdr, rows[n:])
+		n += read
+		if errors.Is(err, io.EOF) {
+			_ = p.rdr.Close()
+			p.rdr = nil
+			err = nil
+		}
+		if err != nil {
+			return n, err
+		}
+	}
+	return n, nil
 }
 
 func (p *openParquetFile) Close() error {
-	_ = p.rdr.Close()
+	if p.rdr != nil {
+		_ = p.rdr.Close()
+	}
 	return p.handle.Close()
 }
 
@@ -104,6 +182,8 @@ type parquetReader struct {
 
 	batchSize      int
 	pathsRemaining []string
+	columns        []string
+	filters        []parquetFilter
 
 	mut      sync.Mutex
 	openFile *openParquetFile
@@ -147,18 +227,36 @@ func (r *parquetReader) getOpenFile() (*openParquetFile, error) {
 
 	inFile, err := parquet.OpenFile(readAtFileHandle, fileStats.Size())
 	if err != nil {
+		_ = fileHandle.Close()
 		return nil, err
 	}
 
-	rdr, err := newReaderWithoutPanic(inFile)
-	if err != nil {
-		return nil, err
+	schema := inFile.Schema()
+	var dropColumns []string
+	if len(r.columns) > 0 {
+		readColumns := append([]string{}, r.columns...)
+		for _, f := range r.filters {
+			if !slices.Contains(readColumns, f.column) {
+				readColumns = append(readColumns, f.column)
+				dropColumns = append(dropColumns, f.column)
+			}
+		}
+		if schema, err = projectParquetSchema(schema, readColumns); err != nil {
+			_ = fileHandle.Close()
+			return nil, fmt.Errorf("file '%v': %w", path, err)
+		}
+	}
+
+	rowGroups := parquetRowGroupsMatching(inFile, r.filters)
+	if skipped := len(inFile.RowGroups()) - len(rowGroups); skipped > 0 {
+		r.log.Debugf("Skipping %v row groups of file '%v' that cannot match filters", skipped, path)
 	}
 
 	r.openFile = &openParquetFile{
-		schema: rdr.Schema(),
-		handle: fileHandle,
-		rdr:    rdr,
+		schema:      schema,
+		handle:      fileHandle,
+		rowGroups:   rowGroups,
+		dropColumns: dropColumns,
 	}
 
 	r.log.Debugf("Consuming parquet data from file '%v'", path)
@@ -179,19 +277,19 @@ func (r *parquetReader) ReadBatch(ctx context.Context) (service.MessageBatch, se
 	defer r.mut.Unlock()
 
 	rowBuf := make([]any, r.batchSize)
-	var f *openParquetFile
-	var n int
+	var resBatch service.MessageBatch
 
 	for {
-		var err error
-		if f, err = r.getOpenFile(); err != nil {
+		f, err := r.getOpenFile()
+		if err != nil {
 			if errors.Is(err, io.EOF) {
 				err = service.ErrEndOfInput
 			}
 			return nil, nil, err
 		}
 
-		if n, err = readWithoutPanic(f.rdr, rowBuf); errors.Is(err, io.EOF) {
+		var n int
+		if n, err = f.read(rowBuf); errors.Is(err, io.EOF) {
 			// If we finished this file we close the handle and forget it so
 			// that the next call moves on.
 			if closeErr := f.Close(); closeErr != nil {
@@ -200,27 +298,35 @@ func (r *parquetReader) ReadBatch(ctx context.Context) (service.MessageBatch, se
 			r.openFile = nil
 		}
 
+		for i := 0; i < n; i++ {
+			if !parquetRowMatches(r.filters, rowBuf[i]) {
+				continue
+			}
+			if obj, ok := rowBuf[i].(map[string]any); ok {
+				for _, c := range f.dropColumns {
+					delete(obj, c)
+				}
+			}
+			newMsg := service.NewMessage(nil)
+			newMsg.SetStructuredMut(rowBuf[i])
+			resBatch = append(resBatch, newMsg)
+		}
+
 		// If we got rows then break and yield them.
-		if n > 0 {
+		if len(resBatch) > 0 {
 			break
 		}
 
 		// Otherwise, unless the error is critical, we try again with the next
-		// file. If the err indicates a different issue than reaching the end
-		// then we escalate it, consumption will still continue on the next call
-		// but this gives the parent reader a chance to rate limit etc.
+		// file (or the next rows of this file when all were filtered out). If
+		// the err indicates a different issue than reaching the end then we
+		// escalate it, consumption will still continue on the next call but
+		// this gives the parent reader a chance to rate limit etc.
 		if err != nil && !errors.Is(err, io.EOF) {
 			return nil, nil, err
 		}
 	}
 
-	resBatch := make(service.MessageBatch, n)
-	for i := 0; i < n; i++ {
-		newMsg := service.NewMessage(nil)
-		newMsg.SetStructuredMut(rowBuf[i])
-		resBatch[i] = newMsg
-	}
-
 	return resBatch, func(ctx context.Context, err error) error { return nil }, nil
 }
 
@@ -0,0 +1,383 @@
+package parquet
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+	"github.com/parquet-go/parquet-go/deprecated"
+	"github.com/parquet-go/parquet-go/format"
+
+	"github.com/benthosdev/benthos/v4/internal/value"
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	pfFieldColumn   = "column"
+	pfFieldOperator = "operator"
+	pfFieldValue    = "value"
+)
+
+func parquetFilterFieldSpec() *service.ConfigField {
+	return service.NewObjectListField("filters",
+		service.NewStringField(pfFieldColumn).
+			Description("The name of a top level column to filter on."),
+		service.NewStringEnumField(pfFieldOperator, "eq", "neq", "lt", "lte", "gt", "gte", "in").
+			Description("The comparison to perform between the column and the value."),
+		service.NewAnyField(pfFieldValue).
+			Description("The value to compare against. When the operator is `in` this must be an array of values."),
+	).
+		Description("A list of predicates that rows must satisfy in order to be consumed, all of which must match. Row groups are skipped entirely when their column statistics prove that no rows within them can match, and remaining rows are then checked individually.").
+		Default([]any{}).
+		Advanced()
+}
+
+type parquetFilterOp int
+
+const (
+	parquetFilterEq parquetFilterOp = iota
+	parquetFilterNeq
+	parquetFilterLt
+	parquetFilterLte
+	parquetFilterGt
+	parquetFilterGte
+	parquetFilterIn
+)
+
+type parquetFilter struct {
+	column string
+	op     parquetFilterOp
+	value  any
+	values []any
+}
+
+func parquetFiltersFromParsed(confs []*service.ParsedConfig) ([]parquetFilter, error) {
+	filters := make([]parquetFilter, 0, len(confs))
+	for i, conf := range confs {
+		var f parquetFilter
+		var err error
+		if f.column, err = conf.FieldString(pfFieldColumn); err != nil {
+			return nil, err
+		}
+		if f.column == "" {
+			return nil, fmt.Errorf("filter %v: column must not be empty", i)
+		}
+
+		opStr, err := conf.FieldString(pfFieldOperator)
+		if err != nil {
+			return nil, err
+		}
+		switch opStr {
+		case "eq":
+			f.op = parquetFilterEq
+		case "neq":
+			f.op = parquetFilterNeq
+		case "lt":
+			f.op = parquetFilterLt
+		case "lte":
+			f.op = parquetFilterLte
+		case "gt":
+			f.op = parquetFilterGt
+		case "gte":
+			f.op = parquetFilterGte
+		case "in":
+			f.op = parquetFilterIn
+		default:
+			return nil, fmt.Errorf("filter %v: unrecognised operator: %v", i, opStr)
+		}
+
+		if f.value, err = conf.FieldAny(pfFieldValue); err != nil {
+			return nil, err
+		}
+		if f.op == parquetFilterIn {
+			var ok bool
+			if f.values, ok = f.value.([]any); !ok {
+				return nil, fmt.Errorf("filter %v: operator in requires an array value, got %T", i, f.value)
+			}
+		}
+		filters = append(filters, f)
+	}
+	return filters, nil
+}
+
+// compareParquetValues returns the ordering of two values and whether they
+// were comparable at all.
+func compareParquetValues(left, right any) (int, bool) {
+	if left == nil || right == nil {
+		return 0, false
+	}
+	switch lhs := value.RestrictForComparison(left).(type) {
+	case float64:
+		rhs, err := value.IGetNumber(value.RestrictForComparison(right))
+		if err != nil {
+			return 0, false
+		}
+		switch {
+		case lhs < rhs:
+			return -1, true
+		case lhs > rhs:
+			return 1, true
+		}
+		return 0, true
+	case string:
+		rhs, err := value.IGetString(right)
+		if err != nil {
+			return 0, false
+		}
+		return strings.Compare(lhs, rhs), true
+	case bool:
+		rhs, err := value.IGetBool(right)
+		if err != nil {
+			return 0, false
+		}
+		switch {
+		case lhs == rhs:
+			return 0, true
+		case !lhs:
+			return -1, true
+		}
+		return 1, true
+	case time.Time:
+		rhs, err := value.IGetTimestamp(right)
+		if err != nil {
+			return 0, false
+		}
+		return lhs.Compare(rhs), true
+	}
+	return 0, false
+}
+
+// matches returns whether a decoded value satisfies the filter. Values that
+// cannot be compared to the filter value never match.
+func (f parquetFilter) matches(v any) bool {
+	switch f.op {
+	case parquetFilterIn:
+		for _, fv := range f.values {
+			if c, ok := compareParquetValues(v, fv); ok && c == 0 {
+				return true
+			}
+		}
+		return false
+	case parquetFilterNeq:
+		c, ok := compareParquetValues(v, f.value)
+		return ok && c != 0
+	}
+
+	c, ok := compareParquetValues(v, f.value)
+	if !ok {
+		return false
+	}
+	switch f.op {
+	case parquetFilterEq:
+		return c == 0
+	case parquetFilterLt:
+		return c < 0
+	case parquetFilterLte:
+		return c <= 0
+	case parquetFilterGt:
+		return c > 0
+	case parquetFilterGte:
+		return c >= 0
+	}
+	return false
+}
+
+// mayMatchRange returns whether any value within the inclusive range [min,
+// max] could satisfy the filter. When in doubt this returns true.
+func (f parquetFilter) mayMatchRange(minV, maxV any) bool {
+	cMin, okMin := compareParquetValues(minV, f.value)
+	cMax, okMax := compareParquetValues(maxV, f.value)
+
+	switch f.op {
+	case parquetFilterEq:
+		return !okMin || !okMax || (cMin <= 0 && cMax >= 0)
+	case parquetFilterNeq:
+		return !okMin || !okMax || cMin != 0 || cMax != 0
+	case parquetFilterLt:
+		return !okMin || cMin < 0
+	case parquetFilterLte:
+		return !okMin || cMin <= 0
+	case parquetFilterGt:
+		return !okMax || cMax > 0
+	case parquetFilterGte:
+		return !okMax || cMax >= 0
+	case parquetFilterIn:
+		for _, fv := range f.values {
+			eq := parquetFilter{op: parquetFilterEq, value: fv}
+			if eq.mayMatchRange(minV, maxV) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+func parquetRowMatches(filters []parquetFilter, row any) bool {
+	obj, _ := row.(map[string]any)
+	for _, f := range filters {
+		if !f.matches(obj[f.column]) {
+			return false
+		}
+	}
+	return true
+}
+
+// decodeParquetStat converts a plain encoded statistics value of a column into
+// a value that can be compared against filters. Returns nil for types that
+// cannot be compared reliably, such as decimals, unsigned integers and
+// timestamps, for which the statistics are then ignored.
+func decodeParquetStat(t format.Type, e *format.SchemaElement, b []byte) any {
+	if e == nil {
+		return nil
+	}
+	switch t {
+	case format.Boolean:
+		if len(b) == 1 {
+			return b[0] != 0
+		}
+	case format.Int32:
+		if len(b) == 4 && isSignedIntParquetElement(e) {
+			return int64(int32(binary.LittleEndian.Uint32(b)))
+		}
+	case format.Int64:
+		if len(b) == 8 && isSignedIntParquetElement(e) {
+			return int64(binary.LittleEndian.Uint64(b))
+		}
+	case format.Float:
+		if len(b) == 4 {
+			return float64(math.Float32frombits(binary.LittleEndian.Uint32(b)))
+		}
+	case format.Double:
+		if len(b) == 8 {
+			return math.Float64frombits(binary.LittleEndian.Uint64(b))
+		}
+	case format.ByteArray, format.FixedLenByteArray:
+		if isStringParquetElement(e) {
+			return string(b)
+		}
+	}
+	return nil
+}
+
+// isSignedIntParquetElement returns whether an integer column holds plain
+// signed integers, rather than values with another logical type.
+func isSignedIntParquetElement(e *format.SchemaElement) bool {
+	if e.LogicalType != nil {
+		return e.LogicalType.Integer != nil && e.LogicalType.Integer.IsSigned
+	}
+	if e.ConvertedType != nil {
+		switch *e.ConvertedType {
+		case deprecated.Int8, deprecated.Int16, deprecated.Int32, deprecated.Int64:
+			return true
+		}
+		return false
+	}
+	return true
+}
+
+// isStringParquetElement returns whether a byte array column is annotated as
+// holding UTF-8 strings.
+func isStringParquetElement(e *format.SchemaElement) bool {
+	if e.LogicalType != nil {
+		return e.LogicalType.UTF8 != nil
+	}
+	return e.ConvertedType != nil && *e.ConvertedType == deprecated.UTF8
+}
+
+// topLevelParquetElements returns the schema elements of the top level columns
+// of a file, keyed by name.
+func topLevelParquetElements(meta *format.FileMetaData) map[string]*format.SchemaElement {
+	elems := map[string]*format.SchemaElement{}
+	if len(meta.Schema) == 0 {
+		return elems
+	}
+
+	// The schema is flattened depth first, and so the children of nested
+	// columns are skipped over.
+	var skip func(i int) int
+	skip = func(i int) int {
+		children := int(meta.Schema[i].NumChildren)
+		i++
+		for n := 0; n < children && i < len(meta.Schema); n++ {
+			i = skip(i)
+		}
+		return i
+	}
+
+	i := 1
+	for n := 0; n < int(meta.Schema[0].NumChildren) && i < len(meta.Schema); n++ {
+		elems[meta.Schema[i].Name] = &meta.Schema[i]
+		i = skip(i)
+	}
+	return elems
+}
+
+// parquetRowGroupsMatching returns the row groups of a file that may contain
+// rows satisfying all filters according to their column chunk statistics.
+func parquetRowGroupsMatching(f *parquet.File, filters []parquetFilter) []parquet.RowGroup {
+	rowGroups := f.RowGroups()
+	if len(filters) == 0 {
+		return rowGroups
+	}
+
+	meta := f.Metadata()
+	elems := topLevelParquetElements(meta)
+
+	var matching []parquet.RowGroup
+	for i, rg := range rowGroups {
+		if i >= len(meta.RowGroups) || rowGroupStatsMayMatch(meta.RowGroups[i], filters, elems) {
+			matching = append(matching, rg)
+		}
+	}
+	return matching
+}
+
+func rowGroupStatsMayMatch(rg format.RowGroup, filters []parquetFilter, elems map[string]*format.SchemaElement) bool {
+	for _, f := range filters {
+		for _, c := range rg.Columns {
+			md := c.MetaData
+			if len(md.PathInSchema) != 1 || md.PathInSchema[0] != f.column {
+				continue
+			}
+			if md.NumValues > 0 && md.Statistics.NullCount == md.NumValues {
+				// Null values never match a filter.
+				return false
+			}
+			if len(md.Statistics.MinValue) == 0 || len(md.Statistics.MaxValue) == 0 {
+				continue
+			}
+			minV := decodeParquetStat(md.Type, elems[f.column], md.Statistics.MinValue)
+			maxV := decodeParquetStat(md.Type, elems[f.column], md.Statistics.MaxValue)
+			if minV == nil || maxV == nil {
+				continue
+			}
+			if !f.mayMatchRange(minV, maxV) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// projectParquetSchema returns a schema containing only the named top level
+// columns of the provided schema.
+func projectParquetSchema(schema *parquet.Schema, columns []string) (*parquet.Schema, error) {
+	fields := map[string]parquet.Field{}
+	for _, f := range schema.Fields() {
+		fields[f.Name()] = f
+	}
+
+	group := parquet.Group{}
+	for _, c := range columns {
+		f, exists := fields[c]
+		if !exists {
+			return nil, fmt.Errorf("column '%v' does not exist in the file schema", c)
+		}
+		group[c] = f
+	}
+	return parquet.NewSchema(schema.Name(), group), nil
+}
@@ -3,6 +3,7 @@ package parquet
 import (
 	"bytes"
 	"context"
+	"encoding/binary"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -10,6 +11,7 @@ import (
 	"time"
 
 	"github.com/parquet-go/parquet-go"
+	"github.com/parquet-go/parquet-go/format"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
@@ -121,3 +123,199 @@ batch_count: 2
 
 	require.NoError(t, in.Close(tCtx))
 }
+
+func TestParquetColumnsAndFilters(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	buf := bytes.NewBuffer(nil)
+	pWtr := parquet.NewWriter(buf, parquet.SchemaOf(simpleData{}), parquet.MaxRowsPerRowGroup(2))
+	for i := 1; i <= 6; i++ {
+		require.NoError(t, pWtr.Write(simpleData{ID: int64(i), Value: fmt.Sprintf("foo %v", i)}))
+	}
+	require.NoError(t, pWtr.Close())
+
+	path := filepath.Join(tmpDir, "data.parquet")
+	require.NoError(t, os.WriteFile(path, buf.Bytes(), 0o655))
+
+	pFile, err := parquet.OpenFile(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+	require.Len(t, pFile.RowGroups(), 3)
+
+	filters, err := parquetFiltersFromParsed(nil)
+	require.NoError(t, err)
+	assert.Len(t, parquetRowGroupsMatching(pFile, filters), 3)
+
+	filters = []parquetFilter{{column: "ID", op: parquetFilterGte, value: 3}}
+	assert.Len(t, parquetRowGroupsMatching(pFile, filters), 2)
+
+	filters = []parquetFilter{{column: "Value", op: parquetFilterEq, value: "foo 6"}}
+	assert.Len(t, parquetRowGroupsMatching(pFile, filters), 1)
+
+	conf, err := parquetInputConfig().ParseYAML(fmt.Sprintf(`
+paths: [ "%v" ]
+batch_count: 10
+columns: [ Value ]
+filters:
+  - column: ID
+    operator: gte
+    value: 3
+  - column: Value
+    operator: neq
+    value: foo 4
+`, path), nil)
+	require.NoError(t, err)
+
+	in, err := newParquetInputFromConfig(conf, service.MockResources())
+	require.NoError(t, err)
+
+	tCtx, done := context.WithTimeout(context.Background(), time.Minute)
+	defer done()
+
+	b, _, err := in.ReadBatch(tCtx)
+	require.NoError(t, err)
+
+	var results []string
+	for _, m := range b {
+		mBytes, err := m.AsBytes()
+		require.NoError(t, err)
+		results = append(results, string(mBytes))
+	}
+	assert.Equal(t, []string{
+		`{"Value":"foo 3"}`,
+		`{"Value":"foo 5"}`,
+		`{"Value":"foo 6"}`,
+	}, results)
+
+	_, _, err = in.ReadBatch(tCtx)
+	require.ErrorIs(t, err, service.ErrEndOfInput)
+
+	require.NoError(t, in.Close(tCtx))
+}
+
+func TestParquetFilterMatching(t *testing.T) {
+	tests := []struct {
+		filter  parquetFilter
+		value   any
+		matches bool
+	}{
+		{filter: parquetFilter{op: parquetFilterEq, value: 5}, value: int32(5), matches: true},
+		{filter: parquetFilter{op: parquetFilterEq, value: "5"}, value: int64(5), matches: false},
+		{filter: parquetFilter{op: parquetFilterNeq, value: "foo"}, value: []byte("bar"), matches: true},
+		{filter: parquetFilter{op: parquetFilterNeq, value: "foo"}, value: nil, matches: false},
+		{filter: parquetFilter{op: parquetFilterLt, value: 1.5}, value: float32(1), matches: true},
+		{filter: parquetFilter{op: parquetFilterLte, value: 1}, value: uint64(2), matches: false},
+		{filter: parquetFilter{op: parquetFilterGt, value: "b"}, value: "c", matches: true},
+		{filter: parquetFilter{op: parquetFilterGte, value: true}, value: false, matches: false},
+		{filter: parquetFilter{op: parquetFilterIn, values: []any{1, 2, 3}}, value: int64(2), matches: true},
+		{filter: parquetFilter{op: parquetFilterIn, values: []any{1, 2, 3}}, value: int64(4), matches: false},
+	}
+
+	for i, test := range tests {
+		assert.Equal(t, test.matches, test.filter.matches(test.value), "test %v", i)
+	}
+}
+
+func TestParquetRowGroupStatsLogicalTypes(t *testing.T) {
+	int32Stat := func(v int32) []byte {
+		return binary.LittleEndian.AppendUint32(nil, uint32(v))
+	}
+	int32Type, byteArrayType := format.Int32, format.ByteArray
+
+	rowGroup := func(column string, t format.Type, minV, maxV []byte) format.RowGroup {
+		return format.RowGroup{
+			Columns: []format.ColumnChunk{{
+				MetaData: format.ColumnMetaData{
+					Type:         t,
+					PathInSchema: []string{column},
+					NumValues:    10,
+					Statistics: format.Statistics{
+						MinValue: minV,
+						MaxValue: maxV,
+					},
+				},
+			}},
+		}
+	}
+
+	elems := map[string]*format.SchemaElement{
+		"id": {Name: "id", Type: &int32Type},
+		"price": {
+			Name: "price",
+			Type: &int32Type,
+			LogicalType: &format.LogicalType{
+				Decimal: &format.DecimalType{Scale: 2, Precision: 9},
+			},
+		},
+		"count": {
+			Name: "count",
+			Type: &int32Type,
+			LogicalType: &format.LogicalType{
+				Integer: &format.IntType{BitWidth: 32, IsSigned: false},
+			},
+		},
+		"name": {
+			Name:        "name",
+			Type:        &byteArrayType,
+			LogicalType: &format.LogicalType{UTF8: &format.StringType{}},
+		},
+		"blob": {Name: "blob", Type: &byteArrayType},
+	}
+
+	tests := []struct {
+		name     string
+		rowGroup format.RowGroup
+		filter   parquetFilter
+		mayMatch bool
+	}{
+		{
+			name:     "plain int pruned",
+			rowGroup: rowGroup("id", format.Int32, int32Stat(10050), int32Stat(20000)),
+			filter:   parquetFilter{column: "id", op: parquetFilterLt, value: 200},
+			mayMatch: false,
+		},
+		{
+			// A min of 10050 is 100.50 and so the row group must be kept.
+			name:     "decimal not pruned",
+			rowGroup: rowGroup("price", format.Int32, int32Stat(10050), int32Stat(20000)),
+			filter:   parquetFilter{column: "price", op: parquetFilterLt, value: 200},
+			mayMatch: true,
+		},
+		{
+			name:     "unsigned not pruned",
+			rowGroup: rowGroup("count", format.Int32, int32Stat(-1), int32Stat(-1)),
+			filter:   parquetFilter{column: "count", op: parquetFilterGt, value: 5},
+			mayMatch: true,
+		},
+		{
+			name:     "string pruned",
+			rowGroup: rowGroup("name", format.ByteArray, []byte("bar"), []byte("baz")),
+			filter:   parquetFilter{column: "name", op: parquetFilterEq, value: "foo"},
+			mayMatch: false,
+		},
+		{
+			name:     "unannotated byte array not pruned",
+			rowGroup: rowGroup("blob", format.ByteArray, []byte("bar"), []byte("baz")),
+			filter:   parquetFilter{column: "blob", op: parquetFilterEq, value: "foo"},
+			mayMatch: true,
+		},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.mayMatch, rowGroupStatsMayMatch(test.rowGroup, []parquetFilter{test.filter}, elems), test.name)
+	}
+}
+
+func TestParquetFilterConfigErrors(t *testing.T) {
+	conf, err := parquetInputConfig().ParseYAML(`
+paths: [ "./foo.parquet" ]
+filters:
+  - column: ID
+    operator: in
+    value: 3
+`, nil)
+	require.NoError(t, err)
+
+	_, err = newParquetInputFromConfig(conf, service.MockResources())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "requires an array value")
+}
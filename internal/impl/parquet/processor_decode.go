@@ -78,6 +78,17 @@ func newReaderWithoutPanic(r io.ReaderAt) (pRdr *parquet.GenericReader[any], err
 	return
 }
 
+func newRowGroupReaderWithoutPanic(rg parquet.RowGroup, schema *parquet.Schema) (pRdr *parquet.GenericReader[any], err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("parquet read panic: %v", r)
+		}
+	}()
+
+	pRdr = parquet.NewGenericRowGroupReader[any](rg, schema)
+	return
+}
+
 func readWithoutPanic(pRdr *parquet.GenericReader[any], rows []any) (n int, err error) {
 	defer func() {
 		if r := recover(); r != nil {
@@ -0,0 +1,283 @@
+package pdf
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	pdfreader "github.com/ledongthuc/pdf"
+)
+
+type pdfPage struct {
+	number int
+	text   string
+	images []pdfImage
+}
+
+type pdfImage struct {
+	name   string
+	format string
+	data   []byte
+}
+
+type pdfDocument struct {
+	metadata map[string]any
+	pages    []pdfPage
+}
+
+var pdfInfoKeys = map[string]string{
+	"Title":    "title",
+	"Author":   "author",
+	"Subject":  "subject",
+	"Keywords": "keywords",
+	"Creator":  "creator",
+	"Producer": "producer",
+}
+
+var pdfInfoDateKeys = map[string]string{
+	"CreationDate": "creation_date",
+	"ModDate":      "modification_date",
+}
+
+// extractPDF parses a PDF document and extracts the text of each page, the
+// document information and, optionally, the images of each page.
+//
+// The underlying reader panics on malformed documents, and so these panics are
+// captured and returned as errors.
+func extractPDF(raw []byte, withImages bool) (doc *pdfDocument, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			doc, err = nil, fmt.Errorf("malformed document: %v", r)
+		}
+	}()
+
+	r, err := pdfreader.NewReader(bytes.NewReader(raw), int64(len(raw)))
+	if err != nil {
+		return nil, err
+	}
+
+	doc = &pdfDocument{metadata: pdfMetadata(r.Trailer().Key("Info"))}
+
+	// Image streams are read directly from the document, which isn't possible
+	// when the document is encrypted.
+	encrypted := r.Trailer().Key("Encrypt").Kind() != pdfreader.Null
+
+	fonts := map[string]*pdfreader.Font{}
+	numPages := r.NumPage()
+	for i := 1; i <= numPages; i++ {
+		p := r.Page(i)
+		if p.V.IsNull() {
+			continue
+		}
+		for _, name := range p.Fonts() {
+			if _, exists := fonts[name]; !exists {
+				f := p.Font(name)
+				fonts[name] = &f
+			}
+		}
+
+		text, err := p.GetPlainText(fonts)
+		if err != nil {
+			return nil, fmt.Errorf("page %v: %w", i, err)
+		}
+
+		page := pdfPage{number: i, text: text}
+		if withImages && !encrypted {
+			page.images = pdfPageImages(raw, p)
+		}
+		doc.pages = append(doc.pages, page)
+	}
+	return doc, nil
+}
+
+func pdfMetadata(info pdfreader.Value) map[string]any {
+	meta := map[string]any{}
+	for k, v := range pdfInfoKeys {
+		if s := info.Key(k).Text(); s != "" {
+			meta[v] = s
+		}
+	}
+	for k, v := range pdfInfoDateKeys {
+		s := info.Key(k).Text()
+		if s == "" {
+			continue
+		}
+		if t, err := parsePDFDate(s); err == nil {
+			meta[v] = t.Format(time.RFC3339)
+		} else {
+			meta[v] = s
+		}
+	}
+	return meta
+}
+
+// parsePDFDate parses dates of the form D:YYYYMMDDHHmmSSOHH'mm', where all
+// components after the year are optional.
+func parsePDFDate(s string) (time.Time, error) {
+	s = strings.TrimPrefix(s, "D:")
+	if len(s) < 4 {
+		return time.Time{}, fmt.Errorf("invalid date: %v", s)
+	}
+
+	// Default values for each optional component of the date.
+	parts := []int{0, 1, 1, 0, 0, 0}
+	widths := []int{4, 2, 2, 2, 2, 2}
+	for i, w := range widths {
+		if len(s) < w || s[0] < '0' || s[0] > '9' {
+			break
+		}
+		n, err := strconv.Atoi(s[:w])
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid date: %w", err)
+		}
+		parts[i] = n
+		s = s[w:]
+	}
+
+	loc := time.UTC
+	if s != "" && (s[0] == '+' || s[0] == '-') {
+		offset := strings.ReplaceAll(s[1:], "'", "")
+		var hours, mins int
+		var err error
+		if len(offset) >= 2 {
+			if hours, err = strconv.Atoi(offset[:2]); err != nil {
+				return time.Time{}, fmt.Errorf("invalid timezone: %w", err)
+			}
+		}
+		if len(offset) >= 4 {
+			if mins, err = strconv.Atoi(offset[2:4]); err != nil {
+				return time.Time{}, fmt.Errorf("invalid timezone: %w", err)
+			}
+		}
+		secs := hours*3600 + mins*60
+		if s[0] == '-' {
+			secs = -secs
+		}
+		loc = time.FixedZone("", secs)
+	}
+	return time.Date(parts[0], time.Month(parts[1]), parts[2], parts[3], parts[4], parts[5], 0, loc), nil
+}
+
+func pdfPageImages(raw []byte, p pdfreader.Page) []pdfImage {
+	xobjects := p.Resources().Key("XObject")
+
+	var images []pdfImage
+	for _, name := range xobjects.Keys() {
+		v := xobjects.Key(name)
+		if v.Kind() != pdfreader.Stream || v.Key("Subtype").Name() != "Image" {
+			continue
+		}
+		img, err := pdfImageFromStream(raw, v)
+		if err != nil {
+			continue
+		}
+		img.name = name
+		images = append(images, img)
+	}
+	sort.Slice(images, func(i, j int) bool {
+		return images[i].name < images[j].name
+	})
+	return images
+}
+
+// pdfStreamData returns the undecoded data of a stream. The reader doesn't
+// expose stream offsets directly, but they're included within the textual
+// representation of streams.
+func pdfStreamData(raw []byte, v pdfreader.Value) ([]byte, error) {
+	str := v.String()
+	i := strings.LastIndexByte(str, '@')
+	if i == -1 {
+		return nil, errors.New("stream offset not found")
+	}
+	offset, err := strconv.ParseInt(str[i+1:], 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	end := offset + v.Key("Length").Int64()
+	if offset < 0 || end > int64(len(raw)) || end < offset {
+		return nil, errors.New("stream exceeds document bounds")
+	}
+	return raw[offset:end], nil
+}
+
+func pdfImageFromStream(raw []byte, v pdfreader.Value) (img pdfImage, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("failed to decode image: %v", r)
+		}
+	}()
+
+	filter := v.Key("Filter")
+	if filter.Kind() == pdfreader.Array && filter.Len() == 1 {
+		filter = filter.Index(0)
+	}
+
+	switch filter.Name() {
+	case "DCTDecode":
+		img.format = "jpeg"
+		img.data, err = pdfStreamData(raw, v)
+		return
+	case "JPXDecode":
+		img.format = "jp2"
+		img.data, err = pdfStreamData(raw, v)
+		return
+	case "", "FlateDecode":
+	default:
+		return img, fmt.Errorf("unsupported image filter: %v", filter.Name())
+	}
+
+	// Otherwise the stream contains raw samples, which we support for 8 bit
+	// grey and RGB colour spaces.
+	width, height := int(v.Key("Width").Int64()), int(v.Key("Height").Int64())
+	if width <= 0 || height <= 0 {
+		return img, errors.New("invalid image dimensions")
+	}
+	if bpc := v.Key("BitsPerComponent").Int64(); bpc != 8 {
+		return img, fmt.Errorf("unsupported bits per component: %v", bpc)
+	}
+
+	samples, err := io.ReadAll(v.Reader())
+	if err != nil {
+		return img, err
+	}
+
+	var decoded image.Image
+	switch cs := v.Key("ColorSpace").Name(); cs {
+	case "DeviceGray":
+		if len(samples) < width*height {
+			return img, errors.New("image data is truncated")
+		}
+		gray := image.NewGray(image.Rect(0, 0, width, height))
+		copy(gray.Pix, samples)
+		decoded = gray
+	case "DeviceRGB":
+		if len(samples) < width*height*3 {
+			return img, errors.New("image data is truncated")
+		}
+		rgba := image.NewRGBA(image.Rect(0, 0, width, height))
+		for i := 0; i < width*height; i++ {
+			rgba.Set(i%width, i/width, color.RGBA{
+				R: samples[i*3], G: samples[i*3+1], B: samples[i*3+2], A: 0xff,
+			})
+		}
+		decoded = rgba
+	default:
+		return img, fmt.Errorf("unsupported colour space: %v", cs)
+	}
+
+	var buf bytes.Buffer
+	if err = png.Encode(&buf, decoded); err != nil {
+		return img, err
+	}
+	img.format = "png"
+	img.data = buf.Bytes()
+	return img, nil
+}
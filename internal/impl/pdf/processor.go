@@ -0,0 +1,158 @@
+package pdf
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	pepFieldSplitPages    = "split_pages"
+	pepFieldExtractImages = "extract_images"
+)
+
+func pdfExtractProcSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Categories("Parsing").
+		Version("4.28.0").
+		Summary("Extracts the text of each page and the document information of PDF documents, and optionally the images embedded within them.").
+		Description(`
+Each message is parsed as a PDF document and replaced with an object containing the document information, such as the title and author, and the text of each page:
+
+`+"```json"+`
+{
+  "metadata": {
+    "title": "Quarterly Report",
+    "author": "Jane Doe",
+    "creation_date": "2024-01-02T03:04:05+01:00"
+  },
+  "page_count": 2,
+  "pages": [
+    { "page": 1, "text": "..." },
+    { "page": 2, "text": "..." }
+  ]
+}
+`+"```"+`
+
+When `+"`split_pages`"+` is set to `+"`true`"+` a message is created for each page instead, containing the fields `+"`metadata`"+`, `+"`page_count`"+`, `+"`page`"+` and `+"`text`"+`, and with the page number added to the metadata key `+"`pdf_page`"+`.
+
+### Images
+
+When `+"`extract_images`"+` is set to `+"`true`"+` the images of each page are added as messages following the extracted text. JPEG and JPEG 2000 images are extracted as they are, and images of raw greyscale or RGB samples are encoded as PNG. Images in other formats, and images within encrypted documents, are skipped. Each image message has the following metadata fields:
+
+- pdf_page
+- pdf_image_name
+- pdf_image_format
+
+Documents that cannot be parsed are flagged as having failed, allowing you to use [standard processor error handling patterns](/docs/configuration/error_handling).`).
+		Fields(
+			service.NewBoolField(pepFieldSplitPages).
+				Description("Whether to create a message for each page rather than a single message for the document.").
+				Default(false),
+			service.NewBoolField(pepFieldExtractImages).
+				Description("Whether to add the images embedded within pages as messages.").
+				Default(false),
+		).
+		Example("Document Ingestion", "Here we read PDF documents from a bucket and split them into a message per page in order to index them in a search engine.", `
+input:
+  aws_s3:
+    bucket: documents
+    prefix: reports/
+
+pipeline:
+  processors:
+    - pdf_extract:
+        split_pages: true
+    - mapping: |
+        root = this
+        root.source = @s3_key
+`)
+}
+
+func init() {
+	err := service.RegisterProcessor(
+		"pdf_extract", pdfExtractProcSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Processor, error) {
+			return newPDFExtractProcFromConfig(conf)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type pdfExtractProc struct {
+	splitPages    bool
+	extractImages bool
+}
+
+func newPDFExtractProcFromConfig(conf *service.ParsedConfig) (*pdfExtractProc, error) {
+	p := &pdfExtractProc{}
+
+	var err error
+	if p.splitPages, err = conf.FieldBool(pepFieldSplitPages); err != nil {
+		return nil, err
+	}
+	if p.extractImages, err = conf.FieldBool(pepFieldExtractImages); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (p *pdfExtractProc) Process(ctx context.Context, msg *service.Message) (service.MessageBatch, error) {
+	raw, err := msg.AsBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	doc, err := extractPDF(raw, p.extractImages)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse PDF document: %w", err)
+	}
+
+	var batch service.MessageBatch
+	if p.splitPages {
+		for _, page := range doc.pages {
+			pageMsg := msg.Copy()
+			pageMsg.SetStructuredMut(map[string]any{
+				"metadata":   doc.metadata,
+				"page_count": int64(len(doc.pages)),
+				"page":       int64(page.number),
+				"text":       page.text,
+			})
+			pageMsg.MetaSetMut("pdf_page", int64(page.number))
+			batch = append(batch, pageMsg)
+		}
+	} else {
+		pages := make([]any, 0, len(doc.pages))
+		for _, page := range doc.pages {
+			pages = append(pages, map[string]any{
+				"page": int64(page.number),
+				"text": page.text,
+			})
+		}
+		docMsg := msg.Copy()
+		docMsg.SetStructuredMut(map[string]any{
+			"metadata":   doc.metadata,
+			"page_count": int64(len(doc.pages)),
+			"pages":      pages,
+		})
+		batch = append(batch, docMsg)
+	}
+
+	for _, page := range doc.pages {
+		for _, img := range page.images {
+			imgMsg := msg.Copy()
+			imgMsg.SetBytes(img.data)
+			imgMsg.MetaSetMut("pdf_page", int64(page.number))
+			imgMsg.MetaSetMut("pdf_image_name", img.name)
+			imgMsg.MetaSetMut("pdf_image_format", img.format)
+			batch = append(batch, imgMsg)
+		}
+	}
+	return batch, nil
+}
+
+func (p *pdfExtractProc) Close(ctx context.Context) error {
+	return nil
+}
@@ -0,0 +1,175 @@
+package pdf
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func testJPEG(t *testing.T) []byte {
+	t.Helper()
+
+	img := image.NewRGBA(image.Rect(0, 0, 4, 4))
+	for i := 0; i < 16; i++ {
+		img.Set(i%4, i/4, color.RGBA{R: 0xff, A: 0xff})
+	}
+	var buf bytes.Buffer
+	require.NoError(t, jpeg.Encode(&buf, img, nil))
+	return buf.Bytes()
+}
+
+// testPDF builds a two page document where the first page contains a JPEG
+// image and an image of raw RGB samples.
+func testPDF(jpegData []byte) []byte {
+	stream := func(dict string, data []byte) string {
+		return fmt.Sprintf("<< %s/Length %d >>\nstream\n%s\nendstream", dict, len(data), data)
+	}
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R 4 0 R] /Count 2 >>",
+		"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] /Contents 5 0 R /Resources << /Font << /F1 7 0 R >> /XObject << /Im1 8 0 R /Im2 9 0 R >> >> >>",
+		"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] /Contents 6 0 R /Resources << /Font << /F1 7 0 R >> >> >>",
+		stream("", []byte("BT /F1 12 Tf 72 720 Td (Hello page one) Tj ET")),
+		stream("", []byte("BT /F1 12 Tf 72 720 Td (Second page) Tj ET")),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>",
+		stream("/Type /XObject /Subtype /Image /Width 4 /Height 4 /ColorSpace /DeviceRGB /BitsPerComponent 8 /Filter /DCTDecode ", jpegData),
+		stream("/Type /XObject /Subtype /Image /Width 2 /Height 1 /ColorSpace /DeviceRGB /BitsPerComponent 8 ", []byte{0xff, 0, 0, 0, 0, 0xff}),
+		"<< /Title (Test Document) /Author (Jane Doe) /CreationDate (D:20240102030405+01'00') >>",
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, o := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", o)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R /Info %d 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, len(objects), xref)
+	return buf.Bytes()
+}
+
+func TestPDFExtractDocument(t *testing.T) {
+	conf, err := pdfExtractProcSpec().ParseYAML(``, nil)
+	require.NoError(t, err)
+
+	proc, err := newPDFExtractProcFromConfig(conf)
+	require.NoError(t, err)
+
+	msg := service.NewMessage(testPDF(testJPEG(t)))
+	msg.MetaSetMut("source", "report.pdf")
+
+	res, err := proc.Process(context.Background(), msg)
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+
+	v, err := res[0].AsStructured()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{
+		"metadata": map[string]any{
+			"title":         "Test Document",
+			"author":        "Jane Doe",
+			"creation_date": "2024-01-02T03:04:05+01:00",
+		},
+		"page_count": int64(2),
+		"pages": []any{
+			map[string]any{"page": int64(1), "text": "Hello page one"},
+			map[string]any{"page": int64(2), "text": "Second page"},
+		},
+	}, v)
+
+	source, _ := res[0].MetaGet("source")
+	assert.Equal(t, "report.pdf", source)
+}
+
+func TestPDFExtractPagesAndImages(t *testing.T) {
+	conf, err := pdfExtractProcSpec().ParseYAML(`
+split_pages: true
+extract_images: true
+`, nil)
+	require.NoError(t, err)
+
+	proc, err := newPDFExtractProcFromConfig(conf)
+	require.NoError(t, err)
+
+	jpegData := testJPEG(t)
+	res, err := proc.Process(context.Background(), service.NewMessage(testPDF(jpegData)))
+	require.NoError(t, err)
+	require.Len(t, res, 4)
+
+	for i, text := range []string{"Hello page one", "Second page"} {
+		v, err := res[i].AsStructured()
+		require.NoError(t, err)
+		assert.Equal(t, int64(i+1), v.(map[string]any)["page"])
+		assert.Equal(t, text, v.(map[string]any)["text"])
+
+		page, _ := res[i].MetaGetMut("pdf_page")
+		assert.Equal(t, int64(i+1), page)
+	}
+
+	b, err := res[2].AsBytes()
+	require.NoError(t, err)
+	assert.Equal(t, jpegData, b)
+	name, _ := res[2].MetaGet("pdf_image_name")
+	assert.Equal(t, "Im1", name)
+	format, _ := res[2].MetaGet("pdf_image_format")
+	assert.Equal(t, "jpeg", format)
+
+	b, err = res[3].AsBytes()
+	require.NoError(t, err)
+	format, _ = res[3].MetaGet("pdf_image_format")
+	assert.Equal(t, "png", format)
+
+	img, err := png.Decode(bytes.NewReader(b))
+	require.NoError(t, err)
+	assert.Equal(t, image.Rect(0, 0, 2, 1), img.Bounds())
+	assert.Equal(t, color.RGBAModel.Convert(color.RGBA{R: 0xff, A: 0xff}), color.RGBAModel.Convert(img.At(0, 0)))
+	assert.Equal(t, color.RGBAModel.Convert(color.RGBA{B: 0xff, A: 0xff}), color.RGBAModel.Convert(img.At(1, 0)))
+}
+
+func TestPDFExtractInvalid(t *testing.T) {
+	conf, err := pdfExtractProcSpec().ParseYAML(``, nil)
+	require.NoError(t, err)
+
+	proc, err := newPDFExtractProcFromConfig(conf)
+	require.NoError(t, err)
+
+	for _, input := range []string{"hello world", "%PDF-1.4\nnonsense"} {
+		_, err = proc.Process(context.Background(), service.NewMessage([]byte(input)))
+		require.Error(t, err, input)
+	}
+}
+
+func TestParsePDFDate(t *testing.T) {
+	for _, test := range []struct {
+		input  string
+		output string
+	}{
+		{input: "D:2024", output: "2024-01-01T00:00:00Z"},
+		{input: "D:20240102030405Z", output: "2024-01-02T03:04:05Z"},
+		{input: "D:20240102030405-05'30'", output: "2024-01-02T03:04:05-05:30"},
+		{input: "20240102", output: "2024-01-02T00:00:00Z"},
+	} {
+		d, err := parsePDFDate(test.input)
+		require.NoError(t, err, test.input)
+		assert.Equal(t, test.output, d.Format(time.RFC3339), test.input)
+	}
+
+	_, err := parsePDFDate("D:20")
+	require.Error(t, err)
+}
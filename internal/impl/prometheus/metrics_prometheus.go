@@ -217,6 +217,15 @@ func (p *promTimingHistVec) With(labelValues ...string) service.MetricsExporterT
 	}
 }
 
+type promObserverVec struct {
+	obs   prometheus.ObserverVec
+	count int
+}
+
+func (p *promObserverVec) With(labelValues ...string) service.MetricsExporterHistogram {
+	return p.obs.WithLabelValues(labelValues...)
+}
+
 type promGaugeVec struct {
 	ctr   *prometheus.GaugeVec
 	count int
@@ -248,6 +257,8 @@ type Metrics struct {
 	gauges     map[string]*promGaugeVec
 	timers     map[string]*promTimingVec
 	timersHist map[string]*promTimingHistVec
+	histograms map[string]*promObserverVec
+	summaries  map[string]*promObserverVec
 
 	mut sync.Mutex
 }
@@ -278,6 +289,8 @@ func FromParsed(conf *service.ParsedConfig, log *service.Logger) (p *Metrics, er
 		gauges:     map[string]*promGaugeVec{},
 		timers:     map[string]*promTimingVec{},
 		timersHist: map[string]*promTimingHistVec{},
+		histograms: map[string]*promObserverVec{},
+		summaries:  map[string]*promObserverVec{},
 	}
 
 	if p.useHistogramTiming, err = conf.FieldBool(pmFieldUseHistogramTiming); err != nil {
@@ -509,6 +522,62 @@ func (p *Metrics) NewGaugeCtor(path string, labelNames ...string) service.Metric
 	}
 }
 
+func (p *Metrics) NewHistogramCtor(path string, buckets []float64, labelNames ...string) service.MetricsExporterHistogramCtor {
+	return p.getObserverVec(path, p.histograms, labelNames, func() prometheus.ObserverVec {
+		return prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    path,
+			Help:    "Benthos Histogram metric",
+			Buckets: buckets,
+		}, labelNames)
+	})
+}
+
+func (p *Metrics) NewSummaryCtor(path string, objectives map[float64]float64, labelNames ...string) service.MetricsExporterHistogramCtor {
+	if len(objectives) == 0 {
+		objectives = p.summaryQuantiles
+	}
+	return p.getObserverVec(path, p.summaries, labelNames, func() prometheus.ObserverVec {
+		return prometheus.NewSummaryVec(prometheus.SummaryOpts{
+			Name:       path,
+			Help:       "Benthos Summary metric",
+			Objectives: objectives,
+		}, labelNames)
+	})
+}
+
+func (p *Metrics) getObserverVec(path string, vecs map[string]*promObserverVec, labelNames []string, ctor func() prometheus.ObserverVec) service.MetricsExporterHistogramCtor {
+	if !model.IsValidMetricName(model.LabelValue(path)) {
+		p.log.Errorf("Ignoring metric '%v' due to invalid name", path)
+		return func(labelValues ...string) service.MetricsExporterHistogram {
+			return noopStat{}
+		}
+	}
+
+	p.mut.Lock()
+	pv, exists := vecs[path]
+	if !exists {
+		obs := ctor()
+		p.reg.MustRegister(obs)
+
+		pv = &promObserverVec{
+			obs:   obs,
+			count: len(labelNames),
+		}
+		vecs[path] = pv
+	}
+	p.mut.Unlock()
+
+	if pv.count != len(labelNames) {
+		p.log.Errorf("Metrics label mismatch %v versus %v %v for name '%v', skipping metric", pv.count, len(labelNames), labelNames, path)
+		return func(labelValues ...string) service.MetricsExporterHistogram {
+			return noopStat{}
+		}
+	}
+	return func(labelValues ...string) service.MetricsExporterHistogram {
+		return pv.With(labelValues...)
+	}
+}
+
 func (p *Metrics) Close(context.Context) error {
 	if atomic.CompareAndSwapInt32(&p.running, 1, 0) {
 		close(p.closedChan)
@@ -537,3 +606,4 @@ func (n noopStat) Set(value int64)           {}
 func (n noopStat) SetFloat64(value float64)  {}
 func (n noopStat) IncrFloat64(count float64) {}
 func (n noopStat) DecrFloat64(count float64) {}
+func (n noopStat) Observe(value float64)     {}
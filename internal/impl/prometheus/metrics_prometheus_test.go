@@ -178,6 +178,29 @@ use_histogram_timing: true
 	assert.Contains(t, body, "\ntimertwo_sum{label3=\"value4\",label4=\"value5\"} 1.4e-08")
 }
 
+func TestPrometheusHistogramsAndSummaries(t *testing.T) {
+	nm, handler := getTestProm(t)
+
+	hist := nm.NewHistogramCtor("histone", []float64{10, 100}, "label1")
+	hist("value1").Observe(5)
+	hist("value1").Observe(50)
+	hist("value1").Observe(500)
+
+	sum := nm.NewSummaryCtor("sumone", map[float64]float64{0.5: 0.05})()
+	sum.Observe(1.5)
+	sum.Observe(2.5)
+
+	body := getPage(t, handler)
+
+	assert.Contains(t, body, "\nhistone_bucket{label1=\"value1\",le=\"10\"} 1")
+	assert.Contains(t, body, "\nhistone_bucket{label1=\"value1\",le=\"100\"} 2")
+	assert.Contains(t, body, "\nhistone_bucket{label1=\"value1\",le=\"+Inf\"} 3")
+	assert.Contains(t, body, "\nhistone_sum{label1=\"value1\"} 555")
+	assert.Contains(t, body, "\nsumone{quantile=\"0.5\"}")
+	assert.Contains(t, body, "\nsumone_sum 4")
+	assert.Contains(t, body, "\nsumone_count 2")
+}
+
 func TestPrometheusWithFileOutputPath(t *testing.T) {
 	fPath := t.TempDir() + "/benthos_metrics.prom"
 
@@ -0,0 +1,459 @@
+package prometheus
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+	"github.com/klauspost/compress/snappy"
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/benthosdev/benthos/v4/internal/impl/pure"
+	"github.com/benthosdev/benthos/v4/internal/value"
+	"github.com/benthosdev/benthos/v4/public/bloblang"
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	prwFieldURL         = "url"
+	prwFieldMapping     = "mapping"
+	prwFieldHeaders     = "headers"
+	prwFieldBearerToken = "bearer_token"
+	prwFieldTimeout     = "timeout"
+	prwFieldTLS         = "tls"
+	prwFieldBatching    = "batching"
+)
+
+func remoteWriteOutputSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Services").
+		Version("4.28.0").
+		Summary("Sends samples to a Prometheus remote write endpoint, such as those provided by Mimir, Thanos, Cortex or Prometheus itself.").
+		Description(`
+Each message is converted into one or more samples with the `+"`mapping`"+` field, which must result in an object, or an array of objects, of the following form:
+
+`+"```json"+`
+{
+  "name": "http_requests_total",
+  "labels": { "method": "GET", "code": "200" },
+  "value": 1027,
+  "timestamp": 1700000000000
+}
+`+"```"+`
+
+The `+"`labels` and `timestamp`"+` fields are optional. The timestamp can be either a number of milliseconds since the unix epoch, or a timestamp value or RFC 3339 string, and when omitted the current time is used. Samples of a batch that share a name and labels are sent as a single series ordered by their timestamp.
+
+Requests are sent using version 1.0 of the remote write protocol, encoded as snappy compressed protobuf. Requests that fail with a 5XX status code are retried according to the `+"`backoff`"+` fields, whereas any other failed status code fails the batch immediately.`+service.OutputPerformanceDocs(true, true)).
+		Fields(
+			service.NewStringField(prwFieldURL).
+				Description("The URL of the remote write endpoint.").
+				Example("http://localhost:9009/api/v1/push"),
+			service.NewBloblangField(prwFieldMapping).
+				Description("A [Bloblang mapping](/docs/guides/bloblang/about) that converts each message into one or more samples.").
+				Example(`root.name = "temperature_celsius"
+root.labels.room = this.room
+root.value = this.temp`).
+				Default("root = this"),
+			service.NewInterpolatedStringMapField(prwFieldHeaders).
+				Description("A map of headers to add to each request, such as `X-Scope-OrgID` for multi-tenant systems. Values are resolved using the first message of a batch.").
+				Example(map[string]any{
+					"X-Scope-OrgID": "benthos",
+				}).
+				Default(map[string]any{}),
+			service.NewStringField(prwFieldBearerToken).
+				Description("An optional bearer token to authenticate requests with.").
+				Secret().
+				Default(""),
+			service.NewDurationField(prwFieldTimeout).
+				Description("The maximum period to wait for a request to complete.").
+				Advanced().
+				Default("30s"),
+			service.NewTLSToggledField(prwFieldTLS),
+		).
+		Fields(service.NewHTTPRequestAuthSignerFields()...).
+		Fields(
+			service.NewOutputMaxInFlightField(),
+			service.NewBatchPolicyField(prwFieldBatching),
+		).
+		Fields(pure.CommonRetryBackOffFields(0, "500ms", "30s", "5m")...).
+		Example("Sensor Readings", "Convert JSON sensor readings into gauge samples and send them to Mimir.", `
+output:
+  prometheus_remote_write:
+    url: http://mimir:9009/api/v1/push
+    headers:
+      X-Scope-OrgID: sensors
+    mapping: |
+      root = this.readings.map_each(r -> {
+        "name": "sensor_" + r.kind,
+        "labels": { "sensor": this.id },
+        "value": r.value,
+        "timestamp": this.ts
+      })
+    batching:
+      count: 500
+      period: 5s
+`)
+}
+
+func init() {
+	err := service.RegisterBatchOutput("prometheus_remote_write", remoteWriteOutputSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (out service.BatchOutput, batchPolicy service.BatchPolicy, maxInFlight int, err error) {
+			if maxInFlight, err = conf.FieldMaxInFlight(); err != nil {
+				return
+			}
+			if batchPolicy, err = conf.FieldBatchPolicy(prwFieldBatching); err != nil {
+				return
+			}
+			out, err = newRemoteWriter(conf, mgr)
+			return
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type rwLabel struct {
+	name  string
+	value string
+}
+
+type rwSample struct {
+	value     float64
+	timestamp int64
+}
+
+type rwSeries struct {
+	labels  []rwLabel
+	samples []rwSample
+}
+
+// seriesKey returns a key that uniquely identifies a sorted label set.
+func seriesKey(labels []rwLabel) string {
+	var b strings.Builder
+	for _, l := range labels {
+		b.WriteString(l.name)
+		b.WriteByte(0xff)
+		b.WriteString(l.value)
+		b.WriteByte(0xfe)
+	}
+	return b.String()
+}
+
+// The protobuf write request is encoded by hand in order to avoid depending on
+// the Prometheus server module, the messages are defined in prompb:
+//
+//	message WriteRequest { repeated TimeSeries timeseries = 1; }
+//	message TimeSeries { repeated Label labels = 1; repeated Sample samples = 2; }
+//	message Label { string name = 1; string value = 2; }
+//	message Sample { double value = 1; int64 timestamp = 2; }
+func encodeWriteRequest(series []*rwSeries) []byte {
+	var b, sb, eb []byte
+	for _, s := range series {
+		sb = sb[:0]
+		for _, l := range s.labels {
+			eb = eb[:0]
+			eb = protowire.AppendTag(eb, 1, protowire.BytesType)
+			eb = protowire.AppendString(eb, l.name)
+			eb = protowire.AppendTag(eb, 2, protowire.BytesType)
+			eb = protowire.AppendString(eb, l.value)
+
+			sb = protowire.AppendTag(sb, 1, protowire.BytesType)
+			sb = protowire.AppendBytes(sb, eb)
+		}
+		for _, smp := range s.samples {
+			eb = eb[:0]
+			eb = protowire.AppendTag(eb, 1, protowire.Fixed64Type)
+			eb = protowire.AppendFixed64(eb, math.Float64bits(smp.value))
+			eb = protowire.AppendTag(eb, 2, protowire.VarintType)
+			eb = protowire.AppendVarint(eb, uint64(smp.timestamp))
+
+			sb = protowire.AppendTag(sb, 2, protowire.BytesType)
+			sb = protowire.AppendBytes(sb, eb)
+		}
+		b = protowire.AppendTag(b, 1, protowire.BytesType)
+		b = protowire.AppendBytes(b, sb)
+	}
+	return b
+}
+
+//------------------------------------------------------------------------------
+
+type remoteWriter struct {
+	log *service.Logger
+	mgr *service.Resources
+
+	url         string
+	mapping     *bloblang.Executor
+	headers     map[string]*service.InterpolatedString
+	bearerToken string
+
+	authSigner  func(f fs.FS, req *http.Request) error
+	backoffCtor func() backoff.BackOff
+	client      *http.Client
+
+	nowFn func() time.Time
+}
+
+func newRemoteWriter(conf *service.ParsedConfig, mgr *service.Resources) (*remoteWriter, error) {
+	w := &remoteWriter{
+		log:   mgr.Logger(),
+		mgr:   mgr,
+		nowFn: time.Now,
+	}
+
+	var err error
+	if w.url, err = conf.FieldString(prwFieldURL); err != nil {
+		return nil, err
+	}
+	if w.mapping, err = conf.FieldBloblang(prwFieldMapping); err != nil {
+		return nil, err
+	}
+	if w.headers, err = conf.FieldInterpolatedStringMap(prwFieldHeaders); err != nil {
+		return nil, err
+	}
+	if w.bearerToken, err = conf.FieldString(prwFieldBearerToken); err != nil {
+		return nil, err
+	}
+
+	var timeout time.Duration
+	if timeout, err = conf.FieldDuration(prwFieldTimeout); err != nil {
+		return nil, err
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	var tlsConf *tls.Config
+	var tlsEnabled bool
+	if tlsConf, tlsEnabled, err = conf.FieldTLSToggled(prwFieldTLS); err != nil {
+		return nil, err
+	}
+	if tlsEnabled {
+		transport.TLSClientConfig = tlsConf
+	}
+	w.client = &http.Client{Transport: transport, Timeout: timeout}
+
+	if w.authSigner, err = conf.HTTPRequestAuthSignerFromParsed(); err != nil {
+		return nil, err
+	}
+	if w.backoffCtor, err = pure.CommonRetryBackOffCtorFromParsed(conf); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *remoteWriter) Connect(ctx context.Context) error {
+	return nil
+}
+
+func (w *remoteWriter) samplesFrom(v any) ([]any, error) {
+	switch t := v.(type) {
+	case map[string]any:
+		return []any{t}, nil
+	case []any:
+		return t, nil
+	}
+	return nil, fmt.Errorf("expected mapping to result in an object or array, got %T", v)
+}
+
+func (w *remoteWriter) sampleFrom(v any) (labels []rwLabel, sample rwSample, err error) {
+	obj, ok := v.(map[string]any)
+	if !ok {
+		err = fmt.Errorf("expected sample to be an object, got %T", v)
+		return
+	}
+
+	name, _ := obj["name"].(string)
+	if name == "" {
+		err = errors.New("sample is missing a name")
+		return
+	}
+	labels = append(labels, rwLabel{name: "__name__", value: name})
+
+	if lObj, exists := obj["labels"]; exists && lObj != nil {
+		lMap, ok := lObj.(map[string]any)
+		if !ok {
+			err = fmt.Errorf("expected labels to be an object, got %T", lObj)
+			return
+		}
+		for k, lv := range lMap {
+			if k == "__name__" {
+				err = errors.New("label __name__ is reserved for the sample name")
+				return
+			}
+			if lv == nil {
+				continue
+			}
+			if s := value.IToString(lv); s != "" {
+				labels = append(labels, rwLabel{name: k, value: s})
+			}
+		}
+	}
+	sort.Slice(labels, func(i, j int) bool {
+		return labels[i].name < labels[j].name
+	})
+
+	if sample.value, err = value.IToFloat64(obj["value"]); err != nil {
+		err = fmt.Errorf("sample value: %w", err)
+		return
+	}
+
+	switch ts := obj["timestamp"].(type) {
+	case nil:
+		sample.timestamp = w.nowFn().UnixMilli()
+	case time.Time:
+		sample.timestamp = ts.UnixMilli()
+	case string:
+		var t time.Time
+		if t, err = time.Parse(time.RFC3339Nano, ts); err != nil {
+			err = fmt.Errorf("sample timestamp: %w", err)
+			return
+		}
+		sample.timestamp = t.UnixMilli()
+	default:
+		if sample.timestamp, err = value.IToInt(ts); err != nil {
+			err = fmt.Errorf("sample timestamp: %w", err)
+			return
+		}
+	}
+	return
+}
+
+func (w *remoteWriter) WriteBatch(ctx context.Context, batch service.MessageBatch) error {
+	var series []*rwSeries
+	seriesByKey := map[string]*rwSeries{}
+
+	for i := range batch {
+		resMsg, err := batch.BloblangQuery(i, w.mapping)
+		if err != nil {
+			return fmt.Errorf("mapping failed: %w", err)
+		}
+		if resMsg == nil {
+			continue
+		}
+		v, err := resMsg.AsStructured()
+		if err != nil {
+			return fmt.Errorf("mapping failed: %w", err)
+		}
+		samples, err := w.samplesFrom(v)
+		if err != nil {
+			return err
+		}
+		for _, sv := range samples {
+			labels, sample, err := w.sampleFrom(sv)
+			if err != nil {
+				return err
+			}
+			key := seriesKey(labels)
+			s, exists := seriesByKey[key]
+			if !exists {
+				s = &rwSeries{labels: labels}
+				seriesByKey[key] = s
+				series = append(series, s)
+			}
+			s.samples = append(s.samples, sample)
+		}
+	}
+	if len(series) == 0 {
+		return nil
+	}
+
+	for _, s := range series {
+		sort.SliceStable(s.samples, func(i, j int) bool {
+			return s.samples[i].timestamp < s.samples[j].timestamp
+		})
+	}
+
+	headers := make(map[string]string, len(w.headers))
+	for k, v := range w.headers {
+		hStr, err := batch.TryInterpolatedString(0, v)
+		if err != nil {
+			return fmt.Errorf("header '%v' interpolation error: %w", k, err)
+		}
+		headers[k] = hStr
+	}
+
+	body := snappy.Encode(nil, encodeWriteRequest(series))
+
+	boff := w.backoffCtor()
+	for {
+		err := w.send(ctx, headers, body)
+		if err == nil {
+			return nil
+		}
+
+		var sErr *remoteWriteStatusError
+		if errors.As(err, &sErr) && sErr.status < 500 {
+			return err
+		}
+
+		wait := boff.NextBackOff()
+		if wait == backoff.Stop {
+			return err
+		}
+		w.log.Warnf("Retrying remote write request in %v: %v", wait, err)
+
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+type remoteWriteStatusError struct {
+	status int
+	body   []byte
+}
+
+func (e *remoteWriteStatusError) Error() string {
+	return fmt.Sprintf("remote write request failed with status %v: %s", e.status, bytes.TrimSpace(e.body))
+}
+
+func (w *remoteWriter) send(ctx context.Context, headers map[string]string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Set("Content-Encoding", "snappy")
+	req.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+	if w.bearerToken != "" {
+		req.Header.Set("Authorization", "Bearer "+w.bearerToken)
+	}
+	if err := w.authSigner(w.mgr.FS(), req); err != nil {
+		return err
+	}
+
+	res, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode >= 200 && res.StatusCode <= 299 {
+		_, _ = io.Copy(io.Discard, res.Body)
+		return nil
+	}
+
+	resBody, _ := io.ReadAll(io.LimitReader(res.Body, 4096))
+	return &remoteWriteStatusError{status: res.StatusCode, body: resBody}
+}
+
+func (w *remoteWriter) Close(ctx context.Context) error {
+	w.client.CloseIdleConnections()
+	return nil
+}
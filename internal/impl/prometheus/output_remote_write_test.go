@@ -0,0 +1,201 @@
+package prometheus
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/klauspost/compress/snappy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+// decodeWriteRequest decodes a write request into a readable form of one line
+// per sample, e.g. `{__name__="foo", a="b"} 1 1000`.
+func decodeWriteRequest(t testing.TB, b []byte) (lines []string) {
+	t.Helper()
+
+	fields := func(b []byte, fn func(num protowire.Number, typ protowire.Type, v []byte, u uint64)) {
+		for len(b) > 0 {
+			num, typ, n := protowire.ConsumeTag(b)
+			require.GreaterOrEqual(t, n, 0)
+			b = b[n:]
+			switch typ {
+			case protowire.BytesType:
+				v, n := protowire.ConsumeBytes(b)
+				require.GreaterOrEqual(t, n, 0)
+				fn(num, typ, v, 0)
+				b = b[n:]
+			case protowire.VarintType:
+				u, n := protowire.ConsumeVarint(b)
+				require.GreaterOrEqual(t, n, 0)
+				fn(num, typ, nil, u)
+				b = b[n:]
+			case protowire.Fixed64Type:
+				u, n := protowire.ConsumeFixed64(b)
+				require.GreaterOrEqual(t, n, 0)
+				fn(num, typ, nil, u)
+				b = b[n:]
+			default:
+				t.Fatalf("unexpected wire type %v", typ)
+			}
+		}
+	}
+
+	fields(b, func(_ protowire.Number, _ protowire.Type, series []byte, _ uint64) {
+		var labels []string
+		var samples []string
+		fields(series, func(num protowire.Number, _ protowire.Type, v []byte, _ uint64) {
+			switch num {
+			case 1:
+				var name, value string
+				fields(v, func(num protowire.Number, _ protowire.Type, v []byte, _ uint64) {
+					if num == 1 {
+						name = string(v)
+					} else {
+						value = string(v)
+					}
+				})
+				labels = append(labels, fmt.Sprintf("%v=%q", name, value))
+			case 2:
+				var val float64
+				var ts int64
+				fields(v, func(num protowire.Number, _ protowire.Type, _ []byte, u uint64) {
+					if num == 1 {
+						val = math.Float64frombits(u)
+					} else {
+						ts = int64(u)
+					}
+				})
+				samples = append(samples, fmt.Sprintf("%v %v", val, ts))
+			}
+		})
+		for _, s := range samples {
+			lines = append(lines, "{"+strings.Join(labels, ", ")+"} "+s)
+		}
+	})
+	return
+}
+
+func TestRemoteWrite(t *testing.T) {
+	var mut sync.Mutex
+	var reqs [][]string
+	var tenants []string
+	failures := 1
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mut.Lock()
+		defer mut.Unlock()
+
+		if failures > 0 {
+			failures--
+			http.Error(w, "try again", http.StatusServiceUnavailable)
+			return
+		}
+
+		assert.Equal(t, "snappy", r.Header.Get("Content-Encoding"))
+		assert.Equal(t, "Bearer footoken", r.Header.Get("Authorization"))
+
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		body, err = snappy.Decode(nil, body)
+		require.NoError(t, err)
+
+		reqs = append(reqs, decodeWriteRequest(t, body))
+		tenants = append(tenants, r.Header.Get("X-Scope-OrgID"))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(ts.Close)
+
+	pConf, err := remoteWriteOutputSpec().ParseYAML(`
+url: `+ts.URL+`/api/v1/push
+bearer_token: footoken
+headers:
+  X-Scope-OrgID: ${! @tenant }
+mapping: |
+  root = this.readings.map_each(r -> {
+    "name": "temperature",
+    "labels": { "room": r.room },
+    "value": r.value,
+    "timestamp": r.ts
+  })
+backoff:
+  initial_interval: 1ms
+`, nil)
+	require.NoError(t, err)
+
+	w, err := newRemoteWriter(pConf, service.MockResources())
+	require.NoError(t, err)
+	w.nowFn = func() time.Time { return time.UnixMilli(5000) }
+
+	msgA := service.NewMessage([]byte(`{"readings":[{"room":"a","value":20.5,"ts":2000},{"room":"b","value":18}]}`))
+	msgA.MetaSetMut("tenant", "home")
+	msgB := service.NewMessage([]byte(`{"readings":[{"room":"a","value":21,"ts":1000}]}`))
+
+	require.NoError(t, w.WriteBatch(context.Background(), service.MessageBatch{msgA, msgB}))
+
+	mut.Lock()
+	defer mut.Unlock()
+
+	assert.Equal(t, []string{"home"}, tenants)
+	assert.Equal(t, [][]string{{
+		`{__name__="temperature", room="a"} 21 1000`,
+		`{__name__="temperature", room="a"} 20.5 2000`,
+		`{__name__="temperature", room="b"} 18 5000`,
+	}}, reqs)
+}
+
+func TestRemoteWriteClientError(t *testing.T) {
+	var calls int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		http.Error(w, "out of order sample", http.StatusBadRequest)
+	}))
+	t.Cleanup(ts.Close)
+
+	pConf, err := remoteWriteOutputSpec().ParseYAML(`
+url: `+ts.URL+`
+`, nil)
+	require.NoError(t, err)
+
+	w, err := newRemoteWriter(pConf, service.MockResources())
+	require.NoError(t, err)
+
+	err = w.WriteBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte(`{"name":"foo","value":1}`)),
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "out of order sample")
+	assert.Equal(t, 1, calls)
+}
+
+func TestRemoteWriteInvalidSamples(t *testing.T) {
+	pConf, err := remoteWriteOutputSpec().ParseYAML(`
+url: http://localhost:1
+`, nil)
+	require.NoError(t, err)
+
+	w, err := newRemoteWriter(pConf, service.MockResources())
+	require.NoError(t, err)
+
+	for _, s := range []string{
+		`{"value":1}`,
+		`{"name":"foo","value":"nope"}`,
+		`{"name":"foo","value":1,"labels":{"__name__":"bar"}}`,
+		`"foo"`,
+	} {
+		require.Error(t, w.WriteBatch(context.Background(), service.MessageBatch{
+			service.NewMessage([]byte(s)),
+		}), s)
+	}
+}
@@ -5,7 +5,6 @@ import (
 
 	"github.com/jhump/protoreflect/desc/protoparse"
 	"google.golang.org/protobuf/reflect/protoregistry"
-	"google.golang.org/protobuf/types/dynamicpb"
 )
 
 // RegistriesFromMap attempts to parse a map of filenames (relative to import
@@ -31,15 +30,8 @@ func RegistriesFromMap(filesMap map[string]string) (*protoregistry.Files, *proto
 		if err := files.RegisterFile(v.UnwrapFile()); err != nil {
 			return nil, nil, fmt.Errorf("failed to register file '%v': %w", v.GetName(), err)
 		}
-		for _, t := range v.GetMessageTypes() {
-			if err := types.RegisterMessage(dynamicpb.NewMessageType(t.UnwrapMessage())); err != nil {
-				return nil, nil, fmt.Errorf("failed to register type '%v': %w", t.GetName(), err)
-			}
-			for _, nt := range t.GetNestedMessageTypes() {
-				if err := types.RegisterMessage(dynamicpb.NewMessageType(nt.UnwrapMessage())); err != nil {
-					return nil, nil, fmt.Errorf("failed to register type '%v': %w", nt.GetFullyQualifiedName(), err)
-				}
-			}
+		if err := registerMessageTypes(types, v.UnwrapFile().Messages()); err != nil {
+			return nil, nil, fmt.Errorf("failed to register types of file '%v': %w", v.GetName(), err)
 		}
 	}
 	return files, types, nil
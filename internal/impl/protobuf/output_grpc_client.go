@@ -0,0 +1,504 @@
+package protobuf
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"strings"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+
+	"github.com/benthosdev/benthos/v4/internal/impl/pure"
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	gcoFieldAddress        = "address"
+	gcoFieldMethod         = "method"
+	gcoFieldImportPaths    = "import_paths"
+	gcoFieldDescriptorSets = "descriptor_sets"
+	gcoFieldDiscardUnknown = "discard_unknown"
+	gcoFieldMetadata       = "metadata"
+	gcoFieldTimeout        = "timeout"
+	gcoFieldAuthority      = "authority"
+	gcoFieldTLS            = "tls"
+	gcoFieldRetryCodes     = "retry_codes"
+	gcoFieldBatching       = "batching"
+)
+
+func grpcClientOutputSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Network").
+		Version("4.28.0").
+		Summary("Sends messages to a gRPC server by invoking a unary or client streaming method described by protobuf definitions.").
+		Description(`
+The method to invoke and its request message type are resolved from either `+"`.proto`"+` files found within `+"`import_paths`"+`, or from binary encoded `+"`FileDescriptorSet`"+` files listed in `+"`descriptor_sets`"+` (as produced by `+"`protoc --include_imports --descriptor_set_out`"+`). The contents of each message are expected to be a JSON document, which is converted into the request message following the [JSON mapping of protobuf messages](https://protobuf.dev/programming-guides/proto3/#json). Responses are discarded.
+
+### Unary and Client Streaming Methods
+
+When the method is unary each message of a batch is sent with an individual call, and messages that fail are reported individually. When the method is client streaming each batch is sent as a single stream, with the entire batch failing if the call fails. Server streaming and bidirectional streaming methods are not supported.
+
+### Deadlines and Retries
+
+Each call is given a deadline of `+"`timeout`"+`. Calls that fail with a status code listed in `+"`retry_codes`"+` are retried according to the `+"`backoff`"+` fields, any other failure is returned immediately.
+
+### Metadata
+
+The `+"`metadata`"+` field allows setting gRPC metadata of each call using interpolation functions. For client streaming methods the metadata is resolved from the first message of the batch.`+service.OutputPerformanceDocs(true, true)).
+		Fields(
+			service.NewStringField(gcoFieldAddress).
+				Description("The address of the gRPC server.").
+				Example("localhost:50051").
+				Example("dns:///api.example.com:443"),
+			service.NewStringField(gcoFieldMethod).
+				Description("The fully qualified name of the method to invoke, in the form `package.Service/Method`.").
+				Example("helloworld.Greeter/SayHello"),
+			service.NewStringListField(gcoFieldImportPaths).
+				Description("A list of directories containing .proto files, including all definitions required for the method and its messages. Each directory listed will be walked with all found .proto files imported.").
+				Default([]string{}),
+			service.NewStringListField(gcoFieldDescriptorSets).
+				Description("A list of paths to binary encoded `FileDescriptorSet` files containing the definitions required for the method and its messages.").
+				Default([]string{}),
+			service.NewBoolField(gcoFieldDiscardUnknown).
+				Description("Whether to discard fields of message documents that are unknown to the request message, rather than fail.").
+				Default(false),
+			service.NewInterpolatedStringMapField(gcoFieldMetadata).
+				Description("A map of gRPC metadata to set for each call.").
+				Example(map[string]any{
+					"authorization": "Bearer ${TOKEN}",
+					"x-request-id":  `${! @request_id }`,
+				}).
+				Default(map[string]any{}),
+			service.NewDurationField(gcoFieldTimeout).
+				Description("The deadline of each call.").
+				Default("5s"),
+			service.NewStringField(gcoFieldAuthority).
+				Description("An optional value to use as the `:authority` pseudo-header of calls, as well as the server name for TLS verification.").
+				Advanced().
+				Default(""),
+			service.NewTLSToggledField(gcoFieldTLS).
+				Description("TLS options, mutual TLS can be configured by specifying client certificates."),
+			service.NewStringListField(gcoFieldRetryCodes).
+				Description("A list of gRPC status codes that should cause a call to be retried.").
+				Example([]string{"UNAVAILABLE", "RESOURCE_EXHAUSTED", "DEADLINE_EXCEEDED"}).
+				Advanced().
+				Default([]string{"UNAVAILABLE"}),
+			service.NewOutputMaxInFlightField(),
+			service.NewBatchPolicyField(gcoFieldBatching),
+		).
+		Fields(pure.CommonRetryBackOffFields(3, "100ms", "5s", "30s")...).
+		Example("Unary Method", "Register people with a unary method of a service defined within `./protos`, with a per-call request ID taken from metadata.", `
+output:
+  grpc_client:
+    address: localhost:50051
+    method: testing.People/AddPerson
+    import_paths: [ ./protos ]
+    metadata:
+      x-request-id: ${! @request_id }
+    timeout: 2s
+`).
+		Example("Client Streaming with mTLS", "Stream batches of people to a client streaming method using mutual TLS.", `
+output:
+  grpc_client:
+    address: people.example.com:443
+    method: testing.People/AddPeople
+    descriptor_sets: [ ./people.binpb ]
+    tls:
+      enabled: true
+      client_certs:
+        - cert_file: ./client.pem
+          key_file: ./client-key.pem
+    batching:
+      count: 100
+      period: 1s
+`)
+}
+
+func init() {
+	err := service.RegisterBatchOutput("grpc_client", grpcClientOutputSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (out service.BatchOutput, batchPolicy service.BatchPolicy, maxInFlight int, err error) {
+			if maxInFlight, err = conf.FieldMaxInFlight(); err != nil {
+				return
+			}
+			if batchPolicy, err = conf.FieldBatchPolicy(gcoFieldBatching); err != nil {
+				return
+			}
+			out, err = newGRPCClientWriterFromParsed(conf, mgr)
+			return
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type grpcClientWriter struct {
+	log *service.Logger
+
+	address         string
+	fullMethod      string
+	clientStreaming bool
+	requestDesc     protoreflect.MessageDescriptor
+	responseDesc    protoreflect.MessageDescriptor
+	unmarshalOpts   protojson.UnmarshalOptions
+	metadata        map[string]*service.InterpolatedString
+	timeout         time.Duration
+	dialOpts        []grpc.DialOption
+	retryCodes      map[codes.Code]struct{}
+	backoffCtor     func() backoff.BackOff
+
+	conn *grpc.ClientConn
+}
+
+func newGRPCClientWriterFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*grpcClientWriter, error) {
+	g := &grpcClientWriter{
+		log: mgr.Logger(),
+	}
+
+	var err error
+	if g.address, err = conf.FieldString(gcoFieldAddress); err != nil {
+		return nil, err
+	}
+
+	importPaths, err := conf.FieldStringList(gcoFieldImportPaths)
+	if err != nil {
+		return nil, err
+	}
+	descriptorSets, err := conf.FieldStringList(gcoFieldDescriptorSets)
+	if err != nil {
+		return nil, err
+	}
+	files, types, err := grpcClientRegistries(mgr.FS(), importPaths, descriptorSets)
+	if err != nil {
+		return nil, err
+	}
+
+	method, err := conf.FieldString(gcoFieldMethod)
+	if err != nil {
+		return nil, err
+	}
+	if err := g.resolveMethod(files, method); err != nil {
+		return nil, err
+	}
+
+	discardUnknown, err := conf.FieldBool(gcoFieldDiscardUnknown)
+	if err != nil {
+		return nil, err
+	}
+	g.unmarshalOpts = protojson.UnmarshalOptions{
+		Resolver:       types,
+		DiscardUnknown: discardUnknown,
+	}
+
+	if g.metadata, err = conf.FieldInterpolatedStringMap(gcoFieldMetadata); err != nil {
+		return nil, err
+	}
+	if g.timeout, err = conf.FieldDuration(gcoFieldTimeout); err != nil {
+		return nil, err
+	}
+
+	authority, err := conf.FieldString(gcoFieldAuthority)
+	if err != nil {
+		return nil, err
+	}
+	if authority != "" {
+		g.dialOpts = append(g.dialOpts, grpc.WithAuthority(authority))
+	}
+
+	tlsConf, tlsEnabled, err := conf.FieldTLSToggled(gcoFieldTLS)
+	if err != nil {
+		return nil, err
+	}
+	if tlsEnabled {
+		if authority != "" && tlsConf.ServerName == "" {
+			tlsConf.ServerName = authority
+		}
+		g.dialOpts = append(g.dialOpts, grpc.WithTransportCredentials(credentials.NewTLS(tlsConf)))
+	} else {
+		g.dialOpts = append(g.dialOpts, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	}
+
+	retryCodes, err := conf.FieldStringList(gcoFieldRetryCodes)
+	if err != nil {
+		return nil, err
+	}
+	g.retryCodes = map[codes.Code]struct{}{}
+	for _, c := range retryCodes {
+		var code codes.Code
+		if err := code.UnmarshalJSON([]byte(`"` + strings.ToUpper(c) + `"`)); err != nil {
+			return nil, fmt.Errorf("invalid retry code %q: %w", c, err)
+		}
+		g.retryCodes[code] = struct{}{}
+	}
+
+	if g.backoffCtor, err = pure.CommonRetryBackOffCtorFromParsed(conf); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+// grpcClientRegistries loads protobuf definitions from both .proto files found
+// within import paths and binary encoded file descriptor sets.
+func grpcClientRegistries(f fs.FS, importPaths, descriptorSets []string) (*protoregistry.Files, *protoregistry.Types, error) {
+	if len(importPaths) == 0 && len(descriptorSets) == 0 {
+		return nil, nil, errors.New("at least one of import_paths or descriptor_sets must be specified")
+	}
+
+	files, types := &protoregistry.Files{}, &protoregistry.Types{}
+	if len(importPaths) > 0 {
+		var err error
+		if files, types, err = loadDescriptors(f, importPaths); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	for _, path := range descriptorSets {
+		setBytes, err := fs.ReadFile(f, path)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read descriptor set %v: %w", path, err)
+		}
+
+		var set descriptorpb.FileDescriptorSet
+		if err := proto.Unmarshal(setBytes, &set); err != nil {
+			return nil, nil, fmt.Errorf("failed to parse descriptor set %v: %w", path, err)
+		}
+
+		setFiles, err := protodesc.NewFiles(&set)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to resolve descriptor set %v: %w", path, err)
+		}
+
+		var rangeErr error
+		setFiles.RangeFiles(func(fd protoreflect.FileDescriptor) bool {
+			if _, err := files.FindFileByPath(fd.Path()); err == nil {
+				return true
+			}
+			if rangeErr = files.RegisterFile(fd); rangeErr != nil {
+				return false
+			}
+			rangeErr = registerMessageTypes(types, fd.Messages())
+			return rangeErr == nil
+		})
+		if rangeErr != nil {
+			return nil, nil, fmt.Errorf("failed to register descriptor set %v: %w", path, rangeErr)
+		}
+	}
+	return files, types, nil
+}
+
+func registerMessageTypes(types *protoregistry.Types, msgs protoreflect.MessageDescriptors) error {
+	for i := 0; i < msgs.Len(); i++ {
+		md := msgs.Get(i)
+		if _, err := types.FindMessageByName(md.FullName()); err == nil {
+			continue
+		}
+		if err := types.RegisterMessage(dynamicpb.NewMessageType(md)); err != nil {
+			return err
+		}
+		if err := registerMessageTypes(types, md.Messages()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (g *grpcClientWriter) resolveMethod(files *protoregistry.Files, method string) error {
+	method = strings.TrimPrefix(method, "/")
+	serviceName, methodName, ok := strings.Cut(method, "/")
+	if !ok || serviceName == "" || methodName == "" {
+		return fmt.Errorf("method %q must be in the form package.Service/Method", method)
+	}
+
+	d, err := files.FindDescriptorByName(protoreflect.FullName(serviceName))
+	if err != nil {
+		return fmt.Errorf("unable to find service '%v' definition: %w", serviceName, err)
+	}
+	sd, ok := d.(protoreflect.ServiceDescriptor)
+	if !ok {
+		return fmt.Errorf("descriptor %v was unexpected type %T", serviceName, d)
+	}
+
+	md := sd.Methods().ByName(protoreflect.Name(methodName))
+	if md == nil {
+		return fmt.Errorf("unable to find method '%v' within service '%v'", methodName, serviceName)
+	}
+	if md.IsStreamingServer() {
+		return fmt.Errorf("method '%v' is server streaming, only unary and client streaming methods are supported", method)
+	}
+
+	g.fullMethod = "/" + serviceName + "/" + methodName
+	g.clientStreaming = md.IsStreamingClient()
+	g.requestDesc = md.Input()
+	g.responseDesc = md.Output()
+	return nil
+}
+
+func (g *grpcClientWriter) Connect(ctx context.Context) error {
+	if g.conn != nil {
+		return nil
+	}
+	conn, err := grpc.DialContext(ctx, g.address, g.dialOpts...)
+	if err != nil {
+		return err
+	}
+	g.conn = conn
+	return nil
+}
+
+func (g *grpcClientWriter) requestFrom(msg *service.Message) (*dynamicpb.Message, error) {
+	msgBytes, err := msg.AsBytes()
+	if err != nil {
+		return nil, err
+	}
+	req := dynamicpb.NewMessage(g.requestDesc)
+	if err := g.unmarshalOpts.Unmarshal(msgBytes, req); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal JSON message '%v': %w", g.requestDesc.FullName(), err)
+	}
+	return req, nil
+}
+
+func (g *grpcClientWriter) metadataFrom(batch service.MessageBatch, i int) (metadata.MD, error) {
+	md := metadata.MD{}
+	for k, v := range g.metadata {
+		str, err := batch.TryInterpolatedString(i, v)
+		if err != nil {
+			return nil, fmt.Errorf("metadata %v interpolation error: %w", k, err)
+		}
+		md.Append(k, str)
+	}
+	return md, nil
+}
+
+// callWithRetries invokes a call until it either succeeds, fails with a status
+// code that isn't retryable or the backoff is exhausted.
+func (g *grpcClientWriter) callWithRetries(ctx context.Context, md metadata.MD, call func(context.Context) error) error {
+	boff := g.backoffCtor()
+	for {
+		callCtx, done := context.WithTimeout(metadata.NewOutgoingContext(ctx, md), g.timeout)
+		err := call(callCtx)
+		done()
+		if err == nil {
+			return nil
+		}
+		if _, retry := g.retryCodes[status.Code(err)]; !retry {
+			return err
+		}
+
+		wait := boff.NextBackOff()
+		if wait == backoff.Stop {
+			return err
+		}
+		g.log.Debugf("Retrying call to %v in %v: %v", g.fullMethod, wait, err)
+
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (g *grpcClientWriter) WriteBatch(ctx context.Context, batch service.MessageBatch) error {
+	conn := g.conn
+	if conn == nil {
+		return service.ErrNotConnected
+	}
+	if g.clientStreaming {
+		return g.writeStream(ctx, conn, batch)
+	}
+
+	var batchErr *service.BatchError
+	for i, msg := range batch {
+		err := g.writeUnary(ctx, conn, batch, i, msg)
+		if err == nil {
+			continue
+		}
+		if len(batch) == 1 {
+			return err
+		}
+		if batchErr == nil {
+			batchErr = service.NewBatchError(batch, err)
+		}
+		batchErr.Failed(i, err)
+	}
+	if batchErr != nil {
+		return batchErr
+	}
+	return nil
+}
+
+func (g *grpcClientWriter) writeUnary(ctx context.Context, conn *grpc.ClientConn, batch service.MessageBatch, i int, msg *service.Message) error {
+	req, err := g.requestFrom(msg)
+	if err != nil {
+		return err
+	}
+	md, err := g.metadataFrom(batch, i)
+	if err != nil {
+		return err
+	}
+	return g.callWithRetries(ctx, md, func(ctx context.Context) error {
+		return conn.Invoke(ctx, g.fullMethod, req, dynamicpb.NewMessage(g.responseDesc))
+	})
+}
+
+func (g *grpcClientWriter) writeStream(ctx context.Context, conn *grpc.ClientConn, batch service.MessageBatch) error {
+	reqs := make([]*dynamicpb.Message, 0, len(batch))
+	for _, msg := range batch {
+		req, err := g.requestFrom(msg)
+		if err != nil {
+			return err
+		}
+		reqs = append(reqs, req)
+	}
+	md, err := g.metadataFrom(batch, 0)
+	if err != nil {
+		return err
+	}
+
+	desc := &grpc.StreamDesc{
+		StreamName:    g.fullMethod[strings.LastIndex(g.fullMethod, "/")+1:],
+		ClientStreams: true,
+	}
+	return g.callWithRetries(ctx, md, func(ctx context.Context) error {
+		stream, err := conn.NewStream(ctx, desc, g.fullMethod)
+		if err != nil {
+			return err
+		}
+		for _, req := range reqs {
+			if err := stream.SendMsg(req); err != nil {
+				// The actual status of a failed send is obtained by receiving.
+				break
+			}
+		}
+		if err := stream.CloseSend(); err != nil {
+			return err
+		}
+		return stream.RecvMsg(dynamicpb.NewMessage(g.responseDesc))
+	})
+}
+
+func (g *grpcClientWriter) Close(ctx context.Context) error {
+	if g.conn == nil {
+		return nil
+	}
+	err := g.conn.Close()
+	g.conn = nil
+	return err
+}
@@ -0,0 +1,243 @@
+package protobuf
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+var grpcTestImportPaths = []string{
+	"../../../config/test/protobuf/schema",
+	"../../../config/test/protobuf/service",
+}
+
+type fakePeopleServer struct {
+	t testing.TB
+
+	personDesc protoreflect.MessageDescriptor
+	unaryDesc  protoreflect.MessageDescriptor
+	streamDesc protoreflect.MessageDescriptor
+
+	mut         sync.Mutex
+	unavailable int
+	calls       []string
+	requestIDs  []string
+	people      []string
+}
+
+func (f *fakePeopleServer) handle(_ any, stream grpc.ServerStream) error {
+	method, _ := grpc.MethodFromServerStream(stream)
+
+	f.mut.Lock()
+	defer f.mut.Unlock()
+
+	f.calls = append(f.calls, method)
+	if f.unavailable > 0 {
+		f.unavailable--
+		return status.Error(codes.Unavailable, "try again")
+	}
+
+	if md, ok := metadata.FromIncomingContext(stream.Context()); ok {
+		f.requestIDs = append(f.requestIDs, md.Get("x-request-id")...)
+	}
+
+	var count int32
+	for {
+		person := dynamicpb.NewMessage(f.personDesc)
+		if err := stream.RecvMsg(person); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return err
+		}
+		b, err := protojson.Marshal(person)
+		require.NoError(f.t, err)
+		f.people = append(f.people, string(b))
+		count++
+
+		if method == "/testing.People/AddPerson" {
+			if person.Get(f.personDesc.Fields().ByName("first_name")).String() == "reject" {
+				return status.Error(codes.InvalidArgument, "rejected")
+			}
+			res := dynamicpb.NewMessage(f.unaryDesc)
+			res.Set(f.unaryDesc.Fields().ByName("id"), protoreflect.ValueOfInt32(count))
+			return stream.SendMsg(res)
+		}
+	}
+
+	res := dynamicpb.NewMessage(f.streamDesc)
+	res.Set(f.streamDesc.Fields().ByName("count"), protoreflect.ValueOfInt32(count))
+	return stream.SendMsg(res)
+}
+
+func startFakePeopleServer(t *testing.T) (*fakePeopleServer, string) {
+	t.Helper()
+
+	files, _, err := loadDescriptors(service.MockResources().FS(), grpcTestImportPaths)
+	require.NoError(t, err)
+
+	findMsg := func(name string) protoreflect.MessageDescriptor {
+		d, err := files.FindDescriptorByName(protoreflect.FullName(name))
+		require.NoError(t, err)
+		return d.(protoreflect.MessageDescriptor)
+	}
+
+	f := &fakePeopleServer{
+		t:          t,
+		personDesc: findMsg("testing.Person"),
+		unaryDesc:  findMsg("testing.AddPersonResponse"),
+		streamDesc: findMsg("testing.AddPeopleResponse"),
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	srv := grpc.NewServer(grpc.UnknownServiceHandler(f.handle))
+	go func() {
+		_ = srv.Serve(ln)
+	}()
+	t.Cleanup(srv.Stop)
+
+	return f, ln.Addr().String()
+}
+
+func testGRPCClientWriter(t *testing.T, confStr string) *grpcClientWriter {
+	t.Helper()
+
+	pConf, err := grpcClientOutputSpec().ParseYAML(confStr, nil)
+	require.NoError(t, err)
+
+	g, err := newGRPCClientWriterFromParsed(pConf, service.MockResources())
+	require.NoError(t, err)
+	require.NoError(t, g.Connect(context.Background()))
+	t.Cleanup(func() {
+		_ = g.Close(context.Background())
+	})
+	return g
+}
+
+func TestGRPCClientUnary(t *testing.T) {
+	f, addr := startFakePeopleServer(t)
+	f.unavailable = 1
+
+	g := testGRPCClientWriter(t, `
+address: `+addr+`
+method: testing.People/AddPerson
+import_paths:
+  - ../../../config/test/protobuf/schema
+  - ../../../config/test/protobuf/service
+metadata:
+  x-request-id: ${! @id }
+backoff:
+  initial_interval: 1ms
+`)
+
+	var batch service.MessageBatch
+	for i, s := range []string{
+		`{"firstName":"john","age":10}`,
+		`{"firstName":"reject"}`,
+		`{"firstName":"daryl","unknown":true}`,
+		`{"firstName":"hall"}`,
+	} {
+		m := service.NewMessage([]byte(s))
+		m.MetaSetMut("id", string(rune('a'+i)))
+		batch = append(batch, m)
+	}
+
+	index := batch.Index()
+	err := g.WriteBatch(context.Background(), batch)
+	require.Error(t, err)
+
+	var bErr *service.BatchError
+	require.True(t, errors.As(err, &bErr))
+
+	var failed []int
+	bErr.WalkMessagesIndexedBy(index, func(i int, _ *service.Message, err error) bool {
+		if err != nil {
+			failed = append(failed, i)
+		}
+		return true
+	})
+	sort.Ints(failed)
+	assert.Equal(t, []int{1, 2}, failed)
+
+	f.mut.Lock()
+	defer f.mut.Unlock()
+
+	assert.Equal(t, []string{
+		"/testing.People/AddPerson",
+		"/testing.People/AddPerson",
+		"/testing.People/AddPerson",
+		"/testing.People/AddPerson",
+	}, f.calls)
+	assert.Equal(t, []string{"a", "b", "d"}, f.requestIDs)
+	require.Len(t, f.people, 3)
+	assert.JSONEq(t, `{"firstName":"john","age":10}`, f.people[0])
+	assert.JSONEq(t, `{"firstName":"hall"}`, f.people[2])
+}
+
+func TestGRPCClientStreaming(t *testing.T) {
+	f, addr := startFakePeopleServer(t)
+
+	g := testGRPCClientWriter(t, `
+address: `+addr+`
+method: /testing.People/AddPeople
+import_paths:
+  - ../../../config/test/protobuf/schema
+  - ../../../config/test/protobuf/service
+discard_unknown: true
+`)
+
+	require.NoError(t, g.WriteBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte(`{"firstName":"john"}`)),
+		service.NewMessage([]byte(`{"firstName":"daryl","unknown":true}`)),
+	}))
+
+	f.mut.Lock()
+	defer f.mut.Unlock()
+
+	assert.Equal(t, []string{"/testing.People/AddPeople"}, f.calls)
+	require.Len(t, f.people, 2)
+	assert.JSONEq(t, `{"firstName":"daryl"}`, f.people[1])
+}
+
+func TestGRPCClientBadMethods(t *testing.T) {
+	for _, test := range []struct {
+		method      string
+		errContains string
+	}{
+		{method: "testing.People", errContains: "must be in the form"},
+		{method: "testing.Nope/AddPerson", errContains: "unable to find service"},
+		{method: "testing.People/Nope", errContains: "unable to find method"},
+		{method: "testing.People/WatchPeople", errContains: "server streaming"},
+	} {
+		pConf, err := grpcClientOutputSpec().ParseYAML(`
+address: localhost:50051
+method: `+test.method+`
+import_paths:
+  - ../../../config/test/protobuf/schema
+  - ../../../config/test/protobuf/service
+`, nil)
+		require.NoError(t, err)
+
+		_, err = newGRPCClientWriterFromParsed(pConf, service.MockResources())
+		require.Error(t, err, test.method)
+		assert.Contains(t, err.Error(), test.errContains, test.method)
+	}
+}
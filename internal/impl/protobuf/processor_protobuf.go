@@ -2,6 +2,7 @@ package protobuf
 
 import (
 	"context"
+	"encoding/base64"
 	"errors"
 	"fmt"
 	"io/fs"
@@ -15,14 +16,28 @@ import (
 	"google.golang.org/protobuf/reflect/protoreflect"
 	"google.golang.org/protobuf/reflect/protoregistry"
 	"google.golang.org/protobuf/types/dynamicpb"
+
+	// Register well known types so that they can be resolved within Any
+	// fields.
+	_ "google.golang.org/protobuf/types/known/anypb"
+	_ "google.golang.org/protobuf/types/known/durationpb"
+	_ "google.golang.org/protobuf/types/known/emptypb"
+	_ "google.golang.org/protobuf/types/known/fieldmaskpb"
+	_ "google.golang.org/protobuf/types/known/structpb"
+	_ "google.golang.org/protobuf/types/known/timestamppb"
+	_ "google.golang.org/protobuf/types/known/wrapperspb"
 )
 
 const (
-	fieldOperator       = "operator"
-	fieldMessage        = "message"
-	fieldImportPaths    = "import_paths"
-	fieldDiscardUnknown = "discard_unknown"
-	fieldUseProtoNames  = "use_proto_names"
+	fieldOperator        = "operator"
+	fieldMessage         = "message"
+	fieldImportPaths     = "import_paths"
+	fieldDiscardUnknown  = "discard_unknown"
+	fieldUseProtoNames   = "use_proto_names"
+	fieldPreserveUnknown = "preserve_unknown"
+	fieldSchemaRegistry  = "schema_registry"
+
+	metaUnknownFields = "protobuf_unknown_fields"
 )
 
 func protobufProcessorSpec() *service.ConfigSpec {
@@ -45,11 +60,22 @@ Converts protobuf messages into a generic JSON structure. This makes it easier t
 ### `+"`from_json`"+`
 
 Attempts to create a target protobuf message from a generic JSON structure.
+
+## Any Fields
+
+Fields of the type `+"`google.protobuf.Any`"+` are unpacked into JSON with an `+"`@type`"+` field, and packed from the same form. The type URL of an Any field can refer to any message defined within the loaded schemas, including nested messages, or to any of the well known types such as `+"`google.protobuf.Timestamp`"+`.
+
+## Schema Registry
+
+When a `+"`schema_registry`"+` is configured schemas are obtained from a Confluent compatible schema registry, including any schemas they reference, and `+"`import_paths`"+` is ignored. The `+"`to_json`"+` operator obtains the schema of each message by the ID within its framing and caches it, and the `+"`from_json`"+` operator encodes messages with the latest schema of the configured subject and frames them with its ID and the message indexes of the target type.
 `).Fields(
 		service.NewStringEnumField(fieldOperator, "to_json", "from_json").
 			Description("The [operator](#operators) to execute"),
-		service.NewStringField(fieldMessage).
-			Description("The fully qualified name of the protobuf message to convert to/from."),
+		service.NewInterpolatedStringField(fieldMessage).
+			Description("The fully qualified name of the protobuf message to convert to/from. This field supports interpolation functions, allowing the message type to be selected per message. When a `schema_registry` is configured this field is optional, and when empty the message type is derived from the message indexes of the framing for `to_json`, or is the only message of the subject schema for `from_json`.").
+			Example("testing.Person").
+			Example(`${! meta("message_type") }`).
+			Default(""),
 		service.NewBoolField(fieldDiscardUnknown).
 			Description("If `true`, the `from_json` operator discards fields that are unknown to the schema.").
 			Default(false),
@@ -59,6 +85,12 @@ Attempts to create a target protobuf message from a generic JSON structure.
 		service.NewStringListField(fieldImportPaths).
 			Description("A list of directories containing .proto files, including all definitions required for parsing the target message. If left empty the current directory is used. Each directory listed will be walked with all found .proto files imported.").
 			Default([]string{}),
+		service.NewBoolField(fieldPreserveUnknown).
+			Description("If `true`, the `to_json` operator stores the raw bytes of top level fields unknown to the schema base64 encoded within the metadata key `protobuf_unknown_fields`, and the `from_json` operator appends the contents of that metadata key to the messages it creates. This allows fields unknown to the schema to survive a round trip through JSON.").
+			Advanced().
+			Default(false).
+			Version("4.28.0"),
+		schemaRegistryField(),
 	).Example(
 		"JSON to Protobuf", `
 If we have the following protobuf definition within a directory called `+"`testing/schema`"+`:
@@ -150,106 +182,62 @@ func init() {
 	}
 }
 
-type protobufOperator func(part *service.Message) error
+// typeResolver resolves the types of Any fields against the loaded types,
+// falling back to the well known types linked into the binary.
+type typeResolver struct {
+	types *protoregistry.Types
+}
 
-func newProtobufToJSONOperator(f fs.FS, msg string, importPaths []string, useProtoNames bool) (protobufOperator, error) {
-	if msg == "" {
-		return nil, errors.New("message field must not be empty")
+func (r typeResolver) FindMessageByName(name protoreflect.FullName) (protoreflect.MessageType, error) {
+	if r.types != nil {
+		if mt, err := r.types.FindMessageByName(name); err == nil {
+			return mt, nil
+		}
 	}
+	return protoregistry.GlobalTypes.FindMessageByName(name)
+}
 
-	descriptors, types, err := loadDescriptors(f, importPaths)
-	if err != nil {
-		return nil, err
+func (r typeResolver) FindMessageByURL(url string) (protoreflect.MessageType, error) {
+	if r.types != nil {
+		if mt, err := r.types.FindMessageByURL(url); err == nil {
+			return mt, nil
+		}
 	}
-
-	d, err := descriptors.FindDescriptorByName(protoreflect.FullName(msg))
+	mt, err := protoregistry.GlobalTypes.FindMessageByURL(url)
 	if err != nil {
-		return nil, fmt.Errorf("unable to find message '%v' definition within '%v'", msg, importPaths)
+		return nil, fmt.Errorf("unable to resolve Any type '%v': %w", url, err)
 	}
+	return mt, nil
+}
 
-	md, ok := d.(protoreflect.MessageDescriptor)
-	if !ok {
-		return nil, fmt.Errorf("message descriptor %v was unexpected type %T", msg, d)
-	}
-
-	return func(part *service.Message) error {
-		partBytes, err := part.AsBytes()
-		if err != nil {
-			return err
-		}
-
-		dynMsg := dynamicpb.NewMessage(md)
-		if err := proto.Unmarshal(partBytes, dynMsg); err != nil {
-			return fmt.Errorf("failed to unmarshal protobuf message '%v': %w", msg, err)
-		}
-
-		opts := protojson.MarshalOptions{
-			Resolver:      types,
-			UseProtoNames: useProtoNames,
-		}
-		data, err := opts.Marshal(dynMsg)
-		if err != nil {
-			return fmt.Errorf("failed to unmarshal JSON protobuf message '%v': %w", msg, err)
+func (r typeResolver) FindExtensionByName(field protoreflect.FullName) (protoreflect.ExtensionType, error) {
+	if r.types != nil {
+		if et, err := r.types.FindExtensionByName(field); err == nil {
+			return et, nil
 		}
-
-		part.SetBytes(data)
-		return nil
-	}, nil
+	}
+	return protoregistry.GlobalTypes.FindExtensionByName(field)
 }
 
-func newProtobufFromJSONOperator(f fs.FS, msg string, importPaths []string, discardUnknown bool) (protobufOperator, error) {
-	if msg == "" {
-		return nil, errors.New("message field must not be empty")
+func (r typeResolver) FindExtensionByNumber(message protoreflect.FullName, field protoreflect.FieldNumber) (protoreflect.ExtensionType, error) {
+	if r.types != nil {
+		if et, err := r.types.FindExtensionByNumber(message, field); err == nil {
+			return et, nil
+		}
 	}
+	return protoregistry.GlobalTypes.FindExtensionByNumber(message, field)
+}
 
-	_, types, err := loadDescriptors(f, importPaths)
+func findMessageDescriptor(files *protoregistry.Files, msg string) (protoreflect.MessageDescriptor, error) {
+	d, err := files.FindDescriptorByName(protoreflect.FullName(msg))
 	if err != nil {
 		return nil, err
 	}
-
-	types.RangeMessages(func(mt protoreflect.MessageType) bool {
-		return true
-	})
-
-	md, err := types.FindMessageByName(protoreflect.FullName(msg))
-	if err != nil {
-		return nil, fmt.Errorf("unable to find message '%v' definition within '%v'", msg, importPaths)
-	}
-
-	return func(part *service.Message) error {
-		msgBytes, err := part.AsBytes()
-		if err != nil {
-			return err
-		}
-
-		dynMsg := dynamicpb.NewMessage(md.Descriptor())
-
-		opts := protojson.UnmarshalOptions{
-			Resolver:       types,
-			DiscardUnknown: discardUnknown,
-		}
-		if err := opts.Unmarshal(msgBytes, dynMsg); err != nil {
-			return fmt.Errorf("failed to unmarshal JSON message '%v': %w", msg, err)
-		}
-
-		data, err := proto.Marshal(dynMsg)
-		if err != nil {
-			return fmt.Errorf("failed to marshal protobuf message '%v': %v", msg, err)
-		}
-
-		part.SetBytes(data)
-		return nil
-	}, nil
-}
-
-func strToProtobufOperator(f fs.FS, opStr, message string, importPaths []string, discardUnknown, useProtoNames bool) (protobufOperator, error) {
-	switch opStr {
-	case "to_json":
-		return newProtobufToJSONOperator(f, message, importPaths, useProtoNames)
-	case "from_json":
-		return newProtobufFromJSONOperator(f, message, importPaths, discardUnknown)
+	md, ok := d.(protoreflect.MessageDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("message descriptor %v was unexpected type %T", msg, d)
 	}
-	return nil, fmt.Errorf("operator not recognised: %v", opStr)
+	return md, nil
 }
 
 func loadDescriptors(f fs.FS, importPaths []string) (*protoregistry.Files, *protoregistry.Types, error) {
@@ -281,8 +269,17 @@ func loadDescriptors(f fs.FS, importPaths []string) (*protoregistry.Files, *prot
 //------------------------------------------------------------------------------
 
 type protobufProc struct {
-	operator protobufOperator
-	log      *service.Logger
+	operator        string
+	message         *service.InterpolatedString
+	staticMD        protoreflect.MessageDescriptor
+	importPaths     []string
+	files           *protoregistry.Files
+	types           *protoregistry.Types
+	registry        *schemaRegistry
+	discardUnknown  bool
+	useProtoNames   bool
+	preserveUnknown bool
+	log             *service.Logger
 }
 
 func newProtobuf(conf *service.ParsedConfig, mgr *service.Resources) (*protobufProc, error) {
@@ -290,39 +287,220 @@ func newProtobuf(conf *service.ParsedConfig, mgr *service.Resources) (*protobufP
 		log: mgr.Logger(),
 	}
 
-	operatorStr, err := conf.FieldString(fieldOperator)
-	if err != nil {
+	var err error
+	if p.operator, err = conf.FieldString(fieldOperator); err != nil {
 		return nil, err
 	}
+	if p.operator != "to_json" && p.operator != "from_json" {
+		return nil, fmt.Errorf("operator not recognised: %v", p.operator)
+	}
 
-	var message string
-	if message, err = conf.FieldString(fieldMessage); err != nil {
-		return nil, err
+	if conf.Contains(fieldSchemaRegistry) {
+		if p.registry, err = schemaRegistryFromParsed(conf.Namespace(fieldSchemaRegistry), mgr); err != nil {
+			return nil, err
+		}
 	}
 
-	var importPaths []string
-	if importPaths, err = conf.FieldStringList(fieldImportPaths); err != nil {
+	if p.message, err = conf.FieldInterpolatedString(fieldMessage); err != nil {
 		return nil, err
 	}
+	staticMessage, isStatic := p.message.Static()
+	if isStatic && staticMessage == "" {
+		if p.registry == nil {
+			return nil, errors.New("message field must not be empty")
+		}
+		p.message = nil
+	}
+	if p.registry != nil && p.operator == "from_json" {
+		if subject, isStatic := p.registry.subject.Static(); isStatic && subject == "" {
+			return nil, errors.New("a schema registry subject must be provided for the from_json operator")
+		}
+	}
 
-	var discardUnknown bool
-	if discardUnknown, err = conf.FieldBool(fieldDiscardUnknown); err != nil {
+	if p.importPaths, err = conf.FieldStringList(fieldImportPaths); err != nil {
 		return nil, err
 	}
-
-	var useProtoNames bool
-	if useProtoNames, err = conf.FieldBool(fieldUseProtoNames); err != nil {
+	if p.discardUnknown, err = conf.FieldBool(fieldDiscardUnknown); err != nil {
 		return nil, err
 	}
-
-	if p.operator, err = strToProtobufOperator(mgr.FS(), operatorStr, message, importPaths, discardUnknown, useProtoNames); err != nil {
+	if p.useProtoNames, err = conf.FieldBool(fieldUseProtoNames); err != nil {
 		return nil, err
 	}
+	if p.preserveUnknown, err = conf.FieldBool(fieldPreserveUnknown); err != nil {
+		return nil, err
+	}
+
+	if p.registry == nil {
+		if p.files, p.types, err = loadDescriptors(mgr.FS(), p.importPaths); err != nil {
+			return nil, err
+		}
+		if isStatic {
+			if p.staticMD, err = findMessageDescriptor(p.files, staticMessage); err != nil {
+				return nil, fmt.Errorf("unable to find message '%v' definition within '%v'", staticMessage, p.importPaths)
+			}
+		}
+	}
 	return p, nil
 }
 
+// messageName returns the message type name for a message, or an empty string
+// if the type should be derived from the schema registry framing.
+func (p *protobufProc) messageName(msg *service.Message) (string, error) {
+	if p.message == nil {
+		return "", nil
+	}
+	name, err := p.message.TryString(msg)
+	if err != nil {
+		return "", fmt.Errorf("message interpolation error: %w", err)
+	}
+	return name, nil
+}
+
+func (p *protobufProc) localDescriptor(msg *service.Message) (protoreflect.MessageDescriptor, error) {
+	if p.staticMD != nil {
+		return p.staticMD, nil
+	}
+	name, err := p.messageName(msg)
+	if err != nil {
+		return nil, err
+	}
+	md, err := findMessageDescriptor(p.files, name)
+	if err != nil {
+		return nil, fmt.Errorf("unable to find message '%v' definition within '%v'", name, p.importPaths)
+	}
+	return md, nil
+}
+
+func (p *protobufProc) toJSON(ctx context.Context, part *service.Message) error {
+	partBytes, err := part.AsBytes()
+	if err != nil {
+		return err
+	}
+
+	var md protoreflect.MessageDescriptor
+	types := p.types
+	if p.registry != nil {
+		id, indexes, payload, err := readWireHeader(partBytes)
+		if err != nil {
+			return err
+		}
+		schema, err := p.registry.schemaByID(ctx, id)
+		if err != nil {
+			return fmt.Errorf("failed to obtain schema %v: %w", id, err)
+		}
+		name, err := p.messageName(part)
+		if err != nil {
+			return err
+		}
+		if name != "" {
+			if md, err = findMessageDescriptor(schema.files, name); err != nil {
+				return fmt.Errorf("unable to find message '%v' definition within schema %v", name, id)
+			}
+		} else if md, err = messageByIndexes(schema.file, indexes); err != nil {
+			return fmt.Errorf("failed to resolve message type of schema %v: %w", id, err)
+		}
+		types, partBytes = schema.types, payload
+	} else if md, err = p.localDescriptor(part); err != nil {
+		return err
+	}
+
+	dynMsg := dynamicpb.NewMessage(md)
+	if err := proto.Unmarshal(partBytes, dynMsg); err != nil {
+		return fmt.Errorf("failed to unmarshal protobuf message '%v': %w", md.FullName(), err)
+	}
+
+	opts := protojson.MarshalOptions{
+		Resolver:      typeResolver{types: types},
+		UseProtoNames: p.useProtoNames,
+	}
+	data, err := opts.Marshal(dynMsg)
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal JSON protobuf message '%v': %w", md.FullName(), err)
+	}
+
+	if p.preserveUnknown {
+		if unknown := dynMsg.GetUnknown(); len(unknown) > 0 {
+			part.MetaSetMut(metaUnknownFields, base64.StdEncoding.EncodeToString(unknown))
+		}
+	}
+	part.SetBytes(data)
+	return nil
+}
+
+func (p *protobufProc) fromJSON(ctx context.Context, part *service.Message) error {
+	msgBytes, err := part.AsBytes()
+	if err != nil {
+		return err
+	}
+
+	var md protoreflect.MessageDescriptor
+	var header []byte
+	types := p.types
+	if p.registry != nil {
+		subject, err := p.registry.subject.TryString(part)
+		if err != nil {
+			return fmt.Errorf("subject interpolation error: %w", err)
+		}
+		schema, err := p.registry.schemaBySubject(ctx, subject)
+		if err != nil {
+			return fmt.Errorf("failed to obtain schema of subject %v: %w", subject, err)
+		}
+		name, err := p.messageName(part)
+		if err != nil {
+			return err
+		}
+		switch {
+		case name != "":
+			if md, err = findMessageDescriptor(schema.files, name); err != nil {
+				return fmt.Errorf("unable to find message '%v' definition within schema of subject %v", name, subject)
+			}
+		case schema.file.Messages().Len() == 1:
+			md = schema.file.Messages().Get(0)
+		default:
+			return fmt.Errorf("schema of subject %v defines %v messages, a message name must be specified", subject, schema.file.Messages().Len())
+		}
+		types, header = schema.types, appendWireHeader(nil, schema.id, md)
+	} else if md, err = p.localDescriptor(part); err != nil {
+		return err
+	}
+
+	dynMsg := dynamicpb.NewMessage(md)
+
+	opts := protojson.UnmarshalOptions{
+		Resolver:       typeResolver{types: types},
+		DiscardUnknown: p.discardUnknown,
+	}
+	if err := opts.Unmarshal(msgBytes, dynMsg); err != nil {
+		return fmt.Errorf("failed to unmarshal JSON message '%v': %w", md.FullName(), err)
+	}
+
+	if p.preserveUnknown {
+		if unknownStr, exists := part.MetaGet(metaUnknownFields); exists {
+			unknown, err := base64.StdEncoding.DecodeString(unknownStr)
+			if err != nil {
+				return fmt.Errorf("failed to decode unknown fields: %w", err)
+			}
+			dynMsg.SetUnknown(append(dynMsg.GetUnknown(), unknown...))
+		}
+	}
+
+	data, err := proto.MarshalOptions{}.MarshalAppend(header, dynMsg)
+	if err != nil {
+		return fmt.Errorf("failed to marshal protobuf message '%v': %v", md.FullName(), err)
+	}
+
+	part.SetBytes(data)
+	return nil
+}
+
 func (p *protobufProc) Process(ctx context.Context, msg *service.Message) (service.MessageBatch, error) {
-	if err := p.operator(msg); err != nil {
+	var err error
+	if p.operator == "to_json" {
+		err = p.toJSON(ctx, msg)
+	} else {
+		err = p.fromJSON(ctx, msg)
+	}
+	if err != nil {
 		p.log.Debugf("Operator failed: %v", err)
 		return nil, err
 	}
@@ -257,3 +257,119 @@ import_paths: [ %v ]
 		})
 	}
 }
+
+func TestProtobufInterpolatedMessage(t *testing.T) {
+	conf, err := protobufProcessorSpec().ParseYAML(`
+operator: from_json
+message: ${! meta("message_type") }
+import_paths: [ ../../../config/test/protobuf/schema ]
+`, nil)
+	require.NoError(t, err)
+
+	proc, err := newProtobuf(conf, service.MockResources())
+	require.NoError(t, err)
+
+	personMsg := service.NewMessage([]byte(`{"firstName":"john"}`))
+	personMsg.MetaSetMut("message_type", "testing.Person")
+
+	mailboxMsg := service.NewMessage([]byte(`{"color":"red"}`))
+	mailboxMsg.MetaSetMut("message_type", "testing.House.Mailbox")
+
+	unknownMsg := service.NewMessage([]byte(`{"color":"red"}`))
+	unknownMsg.MetaSetMut("message_type", "testing.Nope")
+
+	msgs, err := proc.Process(context.Background(), personMsg)
+	require.NoError(t, err)
+	mBytes, err := msgs[0].AsBytes()
+	require.NoError(t, err)
+	assert.Equal(t, []byte{0x0a, 0x04, 'j', 'o', 'h', 'n'}, mBytes)
+
+	msgs, err = proc.Process(context.Background(), mailboxMsg)
+	require.NoError(t, err)
+	mBytes, err = msgs[0].AsBytes()
+	require.NoError(t, err)
+	assert.Equal(t, []byte{0x0a, 0x03, 'r', 'e', 'd'}, mBytes)
+
+	_, err = proc.Process(context.Background(), unknownMsg)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unable to find message 'testing.Nope'")
+}
+
+func TestProtobufPreserveUnknown(t *testing.T) {
+	toConf, err := protobufProcessorSpec().ParseYAML(`
+operator: to_json
+message: testing.Person
+import_paths: [ ../../../config/test/protobuf/schema ]
+preserve_unknown: true
+`, nil)
+	require.NoError(t, err)
+
+	toProc, err := newProtobuf(toConf, service.MockResources())
+	require.NoError(t, err)
+
+	fromConf, err := protobufProcessorSpec().ParseYAML(`
+operator: from_json
+message: testing.Person
+import_paths: [ ../../../config/test/protobuf/schema ]
+preserve_unknown: true
+`, nil)
+	require.NoError(t, err)
+
+	fromProc, err := newProtobuf(fromConf, service.MockResources())
+	require.NoError(t, err)
+
+	// A person with the first name john and an unknown varint field 99 with
+	// the value 5.
+	input := []byte{0x0a, 0x04, 'j', 'o', 'h', 'n', 0x98, 0x06, 0x05}
+
+	msgs, err := toProc.Process(context.Background(), service.NewMessage(input))
+	require.NoError(t, err)
+	require.Len(t, msgs, 1)
+
+	mBytes, err := msgs[0].AsBytes()
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"firstName":"john"}`, string(mBytes))
+
+	unknown, exists := msgs[0].MetaGet("protobuf_unknown_fields")
+	require.True(t, exists)
+	assert.Equal(t, "mAYF", unknown)
+
+	msgs, err = fromProc.Process(context.Background(), msgs[0])
+	require.NoError(t, err)
+	require.Len(t, msgs, 1)
+
+	mBytes, err = msgs[0].AsBytes()
+	require.NoError(t, err)
+	assert.Equal(t, input, mBytes)
+}
+
+func TestProtobufAnyWellKnownType(t *testing.T) {
+	conf, err := protobufProcessorSpec().ParseYAML(`
+operator: from_json
+message: testing.Envelope
+import_paths: [ ../../../config/test/protobuf/schema ]
+`, nil)
+	require.NoError(t, err)
+
+	proc, err := newProtobuf(conf, service.MockResources())
+	require.NoError(t, err)
+
+	msgs, err := proc.Process(context.Background(), service.NewMessage([]byte(`{"id":1,"content":{"@type":"type.googleapis.com/google.protobuf.Duration","value":"1s"}}`)))
+	require.NoError(t, err)
+	require.Len(t, msgs, 1)
+
+	mBytes, err := msgs[0].AsBytes()
+	require.NoError(t, err)
+	assert.Contains(t, string(mBytes), "type.googleapis.com/google.protobuf.Duration")
+}
+
+func TestProtobufMissingMessage(t *testing.T) {
+	conf, err := protobufProcessorSpec().ParseYAML(`
+operator: to_json
+import_paths: [ ../../../config/test/protobuf/schema ]
+`, nil)
+	require.NoError(t, err)
+
+	_, err = newProtobuf(conf, service.MockResources())
+	require.EqualError(t, err, "message field must not be empty")
+}
@@ -0,0 +1,337 @@
+package protobuf
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	fieldSRURL           = "url"
+	fieldSRSubject       = "subject"
+	fieldSRRefreshPeriod = "refresh_period"
+	fieldSRTLS           = "tls"
+)
+
+func schemaRegistryField() *service.ConfigField {
+	fields := []*service.ConfigField{
+		service.NewURLField(fieldSRURL).
+			Description("The base URL of the schema registry service. Apicurio registries can be used via their Confluent compatible API.").
+			Example("http://localhost:8081").
+			Example("http://localhost:8080/apis/ccompat/v7"),
+		service.NewInterpolatedStringField(fieldSRSubject).
+			Description("The subject to obtain the latest schema from when using the `from_json` operator. This field is ignored by the `to_json` operator, which obtains schemas by the ID framed within each message.").
+			Example(`${! meta("kafka_topic") }-value`).
+			Default(""),
+		service.NewDurationField(fieldSRRefreshPeriod).
+			Description("The period after which the latest schema of a subject is obtained again.").
+			Default("10m"),
+	}
+	fields = append(fields, service.NewHTTPRequestAuthSignerFields()...)
+	fields = append(fields, service.NewTLSField(fieldSRTLS))
+
+	return service.NewObjectField(fieldSchemaRegistry, fields...).
+		Description("Obtain schemas from a Confluent compatible schema registry rather than from `import_paths`. When set, the `to_json` operator expects messages framed with a magic byte, schema ID and message indexes, and the `from_json` operator frames messages in the same way.").
+		Optional().
+		Advanced().
+		Version("4.28.0")
+}
+
+type registrySchemaInfo struct {
+	ID         int    `json:"id"`
+	Type       string `json:"schemaType"`
+	Schema     string `json:"schema"`
+	References []struct {
+		Name    string `json:"name"`
+		Subject string `json:"subject"`
+		Version int    `json:"version"`
+	} `json:"references"`
+}
+
+// registrySchema is a parsed protobuf schema obtained from a registry.
+type registrySchema struct {
+	id    int
+	file  protoreflect.FileDescriptor
+	files *protoregistry.Files
+	types *protoregistry.Types
+}
+
+type subjectSchema struct {
+	schema  *registrySchema
+	fetched time.Time
+}
+
+type schemaRegistry struct {
+	client        *http.Client
+	baseURL       *url.URL
+	reqSigner     func(f fs.FS, req *http.Request) error
+	subject       *service.InterpolatedString
+	refreshPeriod time.Duration
+	mgr           *service.Resources
+
+	mut       sync.Mutex
+	byID      map[int]*registrySchema
+	bySubject map[string]subjectSchema
+}
+
+func schemaRegistryFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*schemaRegistry, error) {
+	r := &schemaRegistry{
+		client:    http.DefaultClient,
+		mgr:       mgr,
+		byID:      map[int]*registrySchema{},
+		bySubject: map[string]subjectSchema{},
+	}
+
+	urlStr, err := conf.FieldString(fieldSRURL)
+	if err != nil {
+		return nil, err
+	}
+	if r.baseURL, err = url.Parse(urlStr); err != nil {
+		return nil, fmt.Errorf("failed to parse url: %w", err)
+	}
+	if r.subject, err = conf.FieldInterpolatedString(fieldSRSubject); err != nil {
+		return nil, err
+	}
+	if r.refreshPeriod, err = conf.FieldDuration(fieldSRRefreshPeriod); err != nil {
+		return nil, err
+	}
+	if r.reqSigner, err = conf.HTTPRequestAuthSignerFromParsed(); err != nil {
+		return nil, err
+	}
+
+	tlsConf, err := conf.FieldTLS(fieldSRTLS)
+	if err != nil {
+		return nil, err
+	}
+	if tlsConf != nil {
+		if t, ok := http.DefaultTransport.(*http.Transport); ok {
+			cloned := t.Clone()
+			cloned.TLSClientConfig = tlsConf
+			r.client = &http.Client{Transport: cloned}
+		} else {
+			r.client = &http.Client{Transport: &http.Transport{TLSClientConfig: tlsConf}}
+		}
+	}
+	return r, nil
+}
+
+func (r *schemaRegistry) get(ctx context.Context, reqPath string) (info registrySchemaInfo, err error) {
+	reqURL := *r.baseURL
+	if reqURL.Path, err = url.JoinPath(reqURL.Path, reqPath); err != nil {
+		return
+	}
+
+	var req *http.Request
+	if req, err = http.NewRequestWithContext(ctx, http.MethodGet, reqURL.String(), http.NoBody); err != nil {
+		return
+	}
+	req.Header.Add("Accept", "application/vnd.schemaregistry.v1+json")
+	if err = r.reqSigner(r.mgr.FS(), req); err != nil {
+		return
+	}
+
+	res, err := r.client.Do(req)
+	if err != nil {
+		return
+	}
+	defer res.Body.Close()
+
+	resBody, err := io.ReadAll(res.Body)
+	if err != nil {
+		return
+	}
+	if res.StatusCode != http.StatusOK {
+		err = fmt.Errorf("request for %v returned status code %v: %s", reqPath, res.StatusCode, resBody)
+		return
+	}
+	if err = json.Unmarshal(resBody, &info); err != nil {
+		err = fmt.Errorf("failed to parse response for %v: %w", reqPath, err)
+		return
+	}
+	if info.Type != "" && info.Type != "PROTOBUF" {
+		err = fmt.Errorf("schema %v is of type %v, expected PROTOBUF", reqPath, info.Type)
+	}
+	return
+}
+
+// parse obtains the references of a schema and parses them all into
+// registries, where the schema itself is registered with the path ".".
+func (r *schemaRegistry) parse(ctx context.Context, info registrySchemaInfo) (*registrySchema, error) {
+	filesMap := map[string]string{".": info.Schema}
+
+	seen := map[string]int{}
+	var walkRefs func(info registrySchemaInfo) error
+	walkRefs = func(info registrySchemaInfo) error {
+		for _, ref := range info.References {
+			if v, exists := seen[ref.Name]; exists {
+				if v != ref.Version {
+					return fmt.Errorf("duplicate reference '%v' version mismatch of %v and %v", ref.Name, v, ref.Version)
+				}
+				continue
+			}
+			seen[ref.Name] = ref.Version
+
+			refInfo, err := r.get(ctx, fmt.Sprintf("/subjects/%s/versions/%v", url.PathEscape(ref.Subject), ref.Version))
+			if err != nil {
+				return err
+			}
+			filesMap[ref.Name] = refInfo.Schema
+			if err := walkRefs(refInfo); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := walkRefs(info); err != nil {
+		return nil, err
+	}
+
+	files, types, err := RegistriesFromMap(filesMap)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse proto schema: %w", err)
+	}
+	file, err := files.FindFileByPath(".")
+	if err != nil {
+		return nil, err
+	}
+	return &registrySchema{id: info.ID, file: file, files: files, types: types}, nil
+}
+
+func (r *schemaRegistry) schemaByID(ctx context.Context, id int) (*registrySchema, error) {
+	r.mut.Lock()
+	defer r.mut.Unlock()
+
+	if s, exists := r.byID[id]; exists {
+		return s, nil
+	}
+
+	info, err := r.get(ctx, fmt.Sprintf("/schemas/ids/%v", id))
+	if err != nil {
+		return nil, err
+	}
+	info.ID = id
+
+	s, err := r.parse(ctx, info)
+	if err != nil {
+		return nil, err
+	}
+	r.byID[id] = s
+	return s, nil
+}
+
+func (r *schemaRegistry) schemaBySubject(ctx context.Context, subject string) (*registrySchema, error) {
+	r.mut.Lock()
+	defer r.mut.Unlock()
+
+	if s, exists := r.bySubject[subject]; exists && time.Since(s.fetched) < r.refreshPeriod {
+		return s.schema, nil
+	}
+
+	info, err := r.get(ctx, fmt.Sprintf("/subjects/%s/versions/latest", url.PathEscape(subject)))
+	if err != nil {
+		if s, exists := r.bySubject[subject]; exists {
+			r.mgr.Logger().Errorf("Failed to refresh schema subject '%v', continuing with previous schema: %v", subject, err)
+			return s.schema, nil
+		}
+		return nil, err
+	}
+
+	s, exists := r.byID[info.ID]
+	if !exists {
+		if s, err = r.parse(ctx, info); err != nil {
+			return nil, err
+		}
+		r.byID[info.ID] = s
+	}
+	r.bySubject[subject] = subjectSchema{schema: s, fetched: time.Now()}
+	return s, nil
+}
+
+//------------------------------------------------------------------------------
+
+// readWireHeader reads the magic byte, schema ID and message indexes that
+// prefix messages serialised for a schema registry, returning the remaining
+// payload.
+func readWireHeader(b []byte) (id int, indexes []int, payload []byte, err error) {
+	if len(b) < 5 || b[0] != 0 {
+		return 0, nil, nil, errors.New("message is not prefixed with a schema registry magic byte and schema ID")
+	}
+	id = int(binary.BigEndian.Uint32(b[1:5]))
+	b = b[5:]
+
+	arrayLen, n := binary.Varint(b)
+	if n <= 0 {
+		return 0, nil, nil, errors.New("unable to read message indexes")
+	}
+	b = b[n:]
+	if arrayLen == 0 {
+		// The first message of the schema is encoded as a single zero.
+		return id, []int{0}, b, nil
+	}
+
+	indexes = make([]int, arrayLen)
+	for i := range indexes {
+		idx, n := binary.Varint(b)
+		if n <= 0 {
+			return 0, nil, nil, errors.New("unable to read message indexes")
+		}
+		b = b[n:]
+		indexes[i] = int(idx)
+	}
+	return id, indexes, b, nil
+}
+
+// appendWireHeader appends the magic byte, schema ID and message indexes of a
+// message descriptor to a buffer.
+func appendWireHeader(b []byte, id int, md protoreflect.MessageDescriptor) []byte {
+	b = append(b, 0)
+	b = binary.BigEndian.AppendUint32(b, uint32(id))
+
+	var indexes []int
+	for d := protoreflect.Descriptor(md); ; d = d.Parent() {
+		if _, isFile := d.(protoreflect.FileDescriptor); isFile {
+			break
+		}
+		indexes = append([]int{d.Index()}, indexes...)
+	}
+	if len(indexes) == 1 && indexes[0] == 0 {
+		return append(b, 0)
+	}
+
+	b = binary.AppendVarint(b, int64(len(indexes)))
+	for _, idx := range indexes {
+		b = binary.AppendVarint(b, int64(idx))
+	}
+	return b
+}
+
+// messageByIndexes returns the message descriptor of a file at a path of
+// message indexes.
+func messageByIndexes(file protoreflect.FileDescriptor, indexes []int) (protoreflect.MessageDescriptor, error) {
+	var md protoreflect.MessageDescriptor
+	msgs := file.Messages()
+	for _, idx := range indexes {
+		if l := msgs.Len(); idx < 0 || idx >= l {
+			return nil, fmt.Errorf("message index (%v) is greater than available message definitions (%v)", idx, l)
+		}
+		md = msgs.Get(idx)
+		msgs = md.Messages()
+	}
+	if md == nil {
+		return nil, errors.New("message indexes are empty")
+	}
+	return md, nil
+}
@@ -0,0 +1,245 @@
+package protobuf
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	testRegistryPersonSchema = `
+syntax = "proto3";
+package testing;
+
+message Person {
+  string first_name = 1;
+  int32 age = 2;
+}
+`
+	testRegistryHouseSchema = `
+syntax = "proto3";
+package testing;
+
+import "person.proto";
+
+message Street {
+  string name = 1;
+}
+
+message House {
+  message Mailbox {
+    string color = 1;
+  }
+  repeated testing.Person people = 1;
+  string address = 2;
+  Mailbox mailbox = 3;
+}
+`
+)
+
+func runProtobufRegistryServer(t testing.TB) string {
+	t.Helper()
+
+	mustJSON := func(v any) []byte {
+		b, err := json.Marshal(v)
+		require.NoError(t, err)
+		return b
+	}
+
+	houseInfo := mustJSON(map[string]any{
+		"id":         2,
+		"schemaType": "PROTOBUF",
+		"schema":     testRegistryHouseSchema,
+		"references": []any{
+			map[string]any{"name": "person.proto", "subject": "person", "version": 1},
+		},
+	})
+	responses := map[string][]byte{
+		"/subjects/person/versions/1": mustJSON(map[string]any{
+			"id":         1,
+			"schemaType": "PROTOBUF",
+			"schema":     testRegistryPersonSchema,
+		}),
+		"/subjects/person/versions/latest": mustJSON(map[string]any{
+			"id":         1,
+			"schemaType": "PROTOBUF",
+			"schema":     testRegistryPersonSchema,
+		}),
+		"/subjects/house/versions/latest": houseInfo,
+		"/schemas/ids/2":                  houseInfo,
+	}
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, exists := responses[r.URL.Path]
+		if !exists {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write(b)
+	}))
+	t.Cleanup(ts.Close)
+	return ts.URL
+}
+
+func TestProtobufSchemaRegistryRoundTrip(t *testing.T) {
+	urlStr := runProtobufRegistryServer(t)
+
+	fromConf, err := protobufProcessorSpec().ParseYAML(`
+operator: from_json
+message: ${! meta("message_type") }
+schema_registry:
+  url: `+urlStr+`
+  subject: house
+`, nil)
+	require.NoError(t, err)
+
+	fromProc, err := newProtobuf(fromConf, service.MockResources())
+	require.NoError(t, err)
+
+	toConf, err := protobufProcessorSpec().ParseYAML(`
+operator: to_json
+schema_registry:
+  url: `+urlStr+`
+`, nil)
+	require.NoError(t, err)
+
+	toProc, err := newProtobuf(toConf, service.MockResources())
+	require.NoError(t, err)
+
+	tests := []struct {
+		name        string
+		messageType string
+		input       string
+		header      []byte
+	}{
+		{
+			name:        "first message",
+			messageType: "testing.Street",
+			input:       `{"name":"main"}`,
+			header:      []byte{0, 0, 0, 0, 2, 0},
+		},
+		{
+			name:        "second message with reference",
+			messageType: "testing.House",
+			input:       `{"people":[{"firstName":"john","age":10}],"address":"123"}`,
+			header:      []byte{0, 0, 0, 0, 2, 2, 2},
+		},
+		{
+			name:        "nested message",
+			messageType: "testing.House.Mailbox",
+			input:       `{"color":"red"}`,
+			header:      []byte{0, 0, 0, 0, 2, 4, 2, 0},
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			inMsg := service.NewMessage([]byte(test.input))
+			inMsg.MetaSetMut("message_type", test.messageType)
+
+			msgs, err := fromProc.Process(context.Background(), inMsg)
+			require.NoError(t, err)
+			require.Len(t, msgs, 1)
+
+			encoded, err := msgs[0].AsBytes()
+			require.NoError(t, err)
+			require.Greater(t, len(encoded), len(test.header))
+			assert.Equal(t, test.header, encoded[:len(test.header)])
+
+			msgs, err = toProc.Process(context.Background(), service.NewMessage(encoded))
+			require.NoError(t, err)
+			require.Len(t, msgs, 1)
+
+			decoded, err := msgs[0].AsBytes()
+			require.NoError(t, err)
+			assert.JSONEq(t, test.input, string(decoded))
+		})
+	}
+}
+
+func TestProtobufSchemaRegistryErrors(t *testing.T) {
+	urlStr := runProtobufRegistryServer(t)
+
+	conf, err := protobufProcessorSpec().ParseYAML(`
+operator: from_json
+schema_registry:
+  url: `+urlStr+`
+  subject: house
+`, nil)
+	require.NoError(t, err)
+
+	proc, err := newProtobuf(conf, service.MockResources())
+	require.NoError(t, err)
+
+	_, err = proc.Process(context.Background(), service.NewMessage([]byte(`{}`)))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "a message name must be specified")
+
+	conf, err = protobufProcessorSpec().ParseYAML(`
+operator: to_json
+schema_registry:
+  url: `+urlStr+`
+`, nil)
+	require.NoError(t, err)
+
+	proc, err = newProtobuf(conf, service.MockResources())
+	require.NoError(t, err)
+
+	_, err = proc.Process(context.Background(), service.NewMessage([]byte(`not framed`)))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "magic byte")
+
+	_, err = proc.Process(context.Background(), service.NewMessage([]byte{0, 0, 0, 0, 5, 0}))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to obtain schema 5")
+
+	conf, err = protobufProcessorSpec().ParseYAML(`
+operator: from_json
+schema_registry:
+  url: `+urlStr+`
+`, nil)
+	require.NoError(t, err)
+
+	_, err = newProtobuf(conf, service.MockResources())
+	require.Error(t, err)
+}
+
+func TestWireHeaderIndexes(t *testing.T) {
+	files, _, err := RegistriesFromMap(map[string]string{".": `
+syntax = "proto3";
+package testing;
+message A { message AA {} }
+message B {}
+message C {}
+message D { message DA {} message DB {} message DC { message DCA {} } }
+`})
+	require.NoError(t, err)
+
+	file, err := files.FindFileByPath(".")
+	require.NoError(t, err)
+
+	for _, indexes := range [][]int{{0}, {1}, {0, 0}, {3, 2}, {3, 2, 0}} {
+		md, err := messageByIndexes(file, indexes)
+		require.NoError(t, err)
+
+		b := appendWireHeader(nil, 7, md)
+		b = append(b, 'x')
+
+		id, readIndexes, payload, err := readWireHeader(b)
+		require.NoError(t, err)
+		assert.Equal(t, 7, id)
+		assert.Equal(t, indexes, readIndexes)
+		assert.Equal(t, []byte("x"), payload)
+	}
+
+	_, err = messageByIndexes(file, []int{4})
+	require.Error(t, err)
+}
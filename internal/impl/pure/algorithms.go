@@ -23,11 +23,34 @@ type (
 	DecompressReader func(r io.Reader) (io.Reader, error)
 )
 
+// CompressionOptions are parameters supported by some compression algorithms
+// for tuning them to the data being compressed.
+type CompressionOptions struct {
+	Level      int
+	Dictionary []byte
+
+	// WindowSize is the window size in bytes when compressing, and the maximum
+	// window size in bytes when decompressing. When zero the default of the
+	// algorithm is used.
+	WindowSize int
+}
+
 type KnownCompressionAlgorithm struct {
 	CompressFunc     CompressFunc
 	CompressWriter   CompressWriter
 	DecompressFunc   DecompressFunc
 	DecompressReader DecompressReader
+
+	// Optional functions for algorithms that support options, where the
+	// compression level given to the resulting CompressFunc is ignored in
+	// favour of the level of the options.
+	CompressFuncWithOptions   func(opts CompressionOptions) (CompressFunc, error)
+	DecompressFuncWithOptions func(opts CompressionOptions) (DecompressFunc, error)
+
+	// Optional function for algorithms that support dictionaries, which builds
+	// a dictionary of up to maxSize bytes from samples of the data to be
+	// compressed.
+	TrainDictionary func(samples [][]byte, maxSize int) ([]byte, error)
 }
 
 var knownCompressionAlgorithms = map[string]KnownCompressionAlgorithm{}
@@ -130,6 +153,39 @@ func strToDecompressFunc(str string) (DecompressFunc, error) {
 	return alg.DecompressFunc, nil
 }
 
+func strToCompressFuncWithOptions(str string, opts CompressionOptions) (CompressFunc, error) {
+	alg, err := strToCompressAlg(str)
+	if err != nil {
+		return nil, err
+	}
+	if alg.CompressFuncWithOptions == nil {
+		return nil, fmt.Errorf("compression type %v does not support dictionaries or window sizes", str)
+	}
+	return alg.CompressFuncWithOptions(opts)
+}
+
+func strToDecompressFuncWithOptions(str string, opts CompressionOptions) (DecompressFunc, error) {
+	alg, err := strToCompressAlg(str)
+	if err != nil {
+		return nil, err
+	}
+	if alg.DecompressFuncWithOptions == nil {
+		return nil, fmt.Errorf("decompression type %v does not support dictionaries or window sizes", str)
+	}
+	return alg.DecompressFuncWithOptions(opts)
+}
+
+func strToTrainDictionary(str string) (func(samples [][]byte, maxSize int) ([]byte, error), error) {
+	alg, err := strToCompressAlg(str)
+	if err != nil {
+		return nil, err
+	}
+	if alg.TrainDictionary == nil {
+		return nil, fmt.Errorf("compression type %v does not support training dictionaries", str)
+	}
+	return alg.TrainDictionary, nil
+}
+
 func strToDecompressReader(str string) (DecompressReader, error) {
 	alg, err := strToCompressAlg(str)
 	if err != nil {
@@ -0,0 +1,434 @@
+package pure
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/benthosdev/benthos/v4/internal/batch"
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	jbFieldKey           = "key"
+	jbFieldStream        = "stream"
+	jbFieldStreams       = "streams"
+	jbFieldWindow        = "window"
+	jbFieldEmitUnmatched = "emit_unmatched"
+)
+
+func joinBufferConfig() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Version("4.28.0").
+		Categories("Windowing").
+		Summary("Correlates messages from two or more streams by a key within a window of time, emitting a joined message for each match.").
+		Description(`
+This buffer is intended to be used with a `+"[`broker` input](/docs/components/inputs/broker)"+` that consumes from each of the streams to be joined, where the stream that a message belongs to is identified by the interpolated `+"[`stream`](#stream)"+` field, and the value to correlate messages by is provided by the `+"[`key`](#key)"+` field.
+
+Messages are held in memory until a message of the same key has arrived from every stream listed in `+"[`streams`](#streams)"+`, at which point a joined message is emitted that is an object containing the contents of each message keyed by the name of its stream. The metadata of the joined message is taken from the message of the first stream listed. When multiple messages of the same key arrive from a stream they are matched in the order they arrived.
+
+Messages that are not matched within the `+"[`window`](#window)"+` duration of their arrival, following the system clock, are dropped, unless `+"[`emit_unmatched`](#emit_unmatched)"+` is `+"`true`"+`, in which case they are emitted as an object containing only the contents of the unmatched message keyed by the name of its stream.
+
+Each emitted message has the following metadata fields added:
+
+- `+"`join_key`"+`: The key of the message.
+- `+"`join_status`"+`: Either `+"`matched`"+` or `+"`unmatched`"+`.
+
+## Delivery Guarantees
+
+This buffer honours the transaction model within Benthos in order to ensure that messages are not acknowledged until they are either delivered as part of a joined message or dropped after the window has passed, which means messages awaiting a match remain unacknowledged and should be considered when sizing the resources of inputs.
+
+When the input of the buffer ends, such as when a file has been consumed entirely, all messages awaiting a match are treated as unmatched.
+`).
+		Fields(
+			service.NewInterpolatedStringField(jbFieldKey).
+				Description("An interpolated string that provides the key to correlate messages by.").
+				Example(`${! json("order_id") }`),
+			service.NewInterpolatedStringField(jbFieldStream).
+				Description("An interpolated string that identifies the stream that a message belongs to, which must resolve to one of the names listed in `streams`.").
+				Example(`${! meta("kafka_topic") }`).
+				Example(`${! meta("source") }`),
+			service.NewStringListField(jbFieldStreams).
+				Description("The names of the streams to join, at least two must be listed.").
+				Example([]any{"orders", "payments"}),
+			service.NewStringField(jbFieldWindow).
+				Description("A duration string describing the period of time, following the arrival of a message, within which it can be matched.").
+				Example("30s").Example("1h"),
+			service.NewBoolField(jbFieldEmitUnmatched).
+				Description("Whether to emit messages that were not matched within the window rather than dropping them.").
+				Default(false),
+		).
+		Example("Orders and Payments", `Given a stream of orders and a stream of payments, each with an `+"`order_id`"+` field, we can correlate them and flag orders that have not been paid for within ten minutes with the following config:`,
+			`
+input:
+  broker:
+    inputs:
+      - kafka_franz:
+          seed_brokers: [ localhost:9092 ]
+          topics: [ orders, payments ]
+          consumer_group: order_payments
+
+buffer:
+  join:
+    key: ${! json("order_id") }
+    stream: ${! meta("kafka_topic") }
+    streams: [ orders, payments ]
+    window: 10m
+    emit_unmatched: true
+
+pipeline:
+  processors:
+    - mapping: |
+        root = this
+        root.paid = meta("join_status") == "matched"
+        root.unmatched_payment = this.orders == null
+`,
+		)
+}
+
+func init() {
+	err := service.RegisterBatchBuffer(
+		"join", joinBufferConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.BatchBuffer, error) {
+			key, err := conf.FieldInterpolatedString(jbFieldKey)
+			if err != nil {
+				return nil, err
+			}
+			stream, err := conf.FieldInterpolatedString(jbFieldStream)
+			if err != nil {
+				return nil, err
+			}
+			streams, err := conf.FieldStringList(jbFieldStreams)
+			if err != nil {
+				return nil, err
+			}
+			window, err := getDuration(conf, true, jbFieldWindow)
+			if err != nil {
+				return nil, err
+			}
+			emitUnmatched, err := conf.FieldBool(jbFieldEmitUnmatched)
+			if err != nil {
+				return nil, err
+			}
+			return newJoinBuffer(key, stream, streams, window, emitUnmatched, func() time.Time {
+				return time.Now().UTC()
+			}, mgr.Logger())
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type joinEntry struct {
+	key    string
+	stream string
+	*tsMessage
+}
+
+type joinBuffer struct {
+	logger *service.Logger
+
+	key, stream   *service.InterpolatedString
+	streams       []string
+	window        time.Duration
+	emitUnmatched bool
+	clock         utcNowProvider
+
+	// Messages awaiting a match, by key and then stream, in order of arrival.
+	pending map[string]map[string][]*joinEntry
+	// All messages awaiting a match in order of arrival, which includes
+	// messages that have since been matched and are skipped.
+	arrivals []*joinEntry
+	matched  map[*joinEntry]struct{}
+	ready    []readyJoin
+	joinMut  sync.Mutex
+
+	writtenChan         chan struct{}
+	endOfInputChan      chan struct{}
+	closeEndOfInputOnce sync.Once
+}
+
+type readyJoin struct {
+	msg   *service.Message
+	ackFn service.AckFunc
+}
+
+func newJoinBuffer(
+	key, stream *service.InterpolatedString,
+	streams []string,
+	window time.Duration,
+	emitUnmatched bool,
+	clock utcNowProvider,
+	logger *service.Logger,
+) (*joinBuffer, error) {
+	if len(streams) < 2 {
+		return nil, errors.New("at least two streams must be specified")
+	}
+	seen := map[string]struct{}{}
+	for _, s := range streams {
+		if _, exists := seen[s]; exists {
+			return nil, fmt.Errorf("stream %v is listed more than once", s)
+		}
+		seen[s] = struct{}{}
+	}
+	if window <= 0 {
+		return nil, errors.New("the window must be greater than zero")
+	}
+	return &joinBuffer{
+		logger:         logger,
+		key:            key,
+		stream:         stream,
+		streams:        streams,
+		window:         window,
+		emitUnmatched:  emitUnmatched,
+		clock:          clock,
+		pending:        map[string]map[string][]*joinEntry{},
+		matched:        map[*joinEntry]struct{}{},
+		writtenChan:    make(chan struct{}, 1),
+		endOfInputChan: make(chan struct{}),
+	}, nil
+}
+
+func (j *joinBuffer) isStream(name string) bool {
+	for _, s := range j.streams {
+		if s == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (j *joinBuffer) WriteBatch(ctx context.Context, msgBatch service.MessageBatch, aFn service.AckFunc) error {
+	if len(msgBatch) == 0 {
+		return aFn(ctx, nil)
+	}
+
+	keys, streams := make([]string, len(msgBatch)), make([]string, len(msgBatch))
+	for i := range msgBatch {
+		var err error
+		if keys[i], err = msgBatch.TryInterpolatedString(i, j.key); err != nil {
+			j.logger.Errorf("Join key interpolation failed for message: %v", err)
+			return err
+		}
+		if streams[i], err = msgBatch.TryInterpolatedString(i, j.stream); err != nil {
+			j.logger.Errorf("Join stream interpolation failed for message: %v", err)
+			return err
+		}
+		if !j.isStream(streams[i]) {
+			err = fmt.Errorf("message stream %v is not one of the streams being joined", streams[i])
+			j.logger.Errorf("%v", err)
+			return err
+		}
+	}
+
+	j.joinMut.Lock()
+	defer j.joinMut.Unlock()
+
+	// Expire messages before adding new ones so that late arrivals are not
+	// matched with messages beyond the window.
+	now := j.clock()
+	_ = j.expire(ctx, now, false)
+
+	aggregatedAck := batch.NewCombinedAcker(batch.AckFunc(aFn))
+	for i, msg := range msgBatch {
+		e := &joinEntry{
+			key:    keys[i],
+			stream: streams[i],
+			tsMessage: &tsMessage{
+				ts: now, m: msg, ackFn: service.AckFunc(aggregatedAck.Derive()),
+			},
+		}
+
+		byStream, exists := j.pending[e.key]
+		if !exists {
+			byStream = map[string][]*joinEntry{}
+			j.pending[e.key] = byStream
+		}
+		byStream[e.stream] = append(byStream[e.stream], e)
+		j.arrivals = append(j.arrivals, e)
+		j.tryMatch(e.key)
+	}
+
+	select {
+	case j.writtenChan <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// tryMatch emits a joined message for a key when a message is pending for
+// every stream. Must be called with the join mutex locked.
+func (j *joinBuffer) tryMatch(key string) {
+	byStream := j.pending[key]
+	for _, s := range j.streams {
+		if len(byStream[s]) == 0 {
+			return
+		}
+	}
+
+	entries := make([]*joinEntry, 0, len(j.streams))
+	for _, s := range j.streams {
+		entries = append(entries, byStream[s][0])
+		if byStream[s] = byStream[s][1:]; len(byStream[s]) == 0 {
+			delete(byStream, s)
+		}
+	}
+	if len(byStream) == 0 {
+		delete(j.pending, key)
+	}
+
+	joined := map[string]any{}
+	for _, e := range entries {
+		j.matched[e] = struct{}{}
+		joined[e.stream] = joinContents(e.m)
+	}
+
+	msg := entries[0].m.Copy()
+	msg.SetStructuredMut(joined)
+	msg.MetaSetMut("join_key", key)
+	msg.MetaSetMut("join_status", "matched")
+	j.ready = append(j.ready, readyJoin{
+		msg: msg,
+		ackFn: func(ctx context.Context, err error) error {
+			for _, e := range entries {
+				_ = e.ackFn(ctx, err)
+			}
+			return nil
+		},
+	})
+}
+
+// joinContents returns the contents of a message as a JSON document, or as a
+// string when they aren't valid JSON. Numbers are decoded as float64 values
+// rather than json.Number values.
+func joinContents(msg *service.Message) any {
+	b, _ := msg.AsBytes()
+	var v any
+	if err := json.Unmarshal(b, &v); err == nil {
+		return v
+	}
+	return string(b)
+}
+
+// expire removes messages that have been awaiting a match beyond the window,
+// or all messages when the input has ended, and returns the time until the
+// next message expires, which is zero when there are none awaiting a match.
+// Must be called with the join mutex locked.
+func (j *joinBuffer) expire(ctx context.Context, now time.Time, endOfInput bool) time.Duration {
+	for len(j.arrivals) > 0 {
+		e := j.arrivals[0]
+		if _, isMatched := j.matched[e]; isMatched {
+			delete(j.matched, e)
+			j.arrivals = j.arrivals[1:]
+			continue
+		}
+		if !endOfInput {
+			if waitFor := e.ts.Add(j.window).Sub(now); waitFor > 0 {
+				return waitFor
+			}
+		}
+		j.arrivals = j.arrivals[1:]
+
+		// Messages of a stream are matched in order of arrival, and so an
+		// expired message is always at the head of its queue.
+		byStream := j.pending[e.key]
+		if byStream[e.stream] = byStream[e.stream][1:]; len(byStream[e.stream]) == 0 {
+			delete(byStream, e.stream)
+		}
+		if len(byStream) == 0 {
+			delete(j.pending, e.key)
+		}
+
+		if !j.emitUnmatched {
+			_ = e.ackFn(ctx, nil)
+			continue
+		}
+
+		msg := e.m.Copy()
+		msg.SetStructuredMut(map[string]any{e.stream: joinContents(e.m)})
+		msg.MetaSetMut("join_key", e.key)
+		msg.MetaSetMut("join_status", "unmatched")
+		j.ready = append(j.ready, readyJoin{msg: msg, ackFn: e.ackFn})
+	}
+	return 0
+}
+
+func (j *joinBuffer) nextBatch(ctx context.Context, endOfInput bool) (service.MessageBatch, service.AckFunc, time.Duration) {
+	j.joinMut.Lock()
+	defer j.joinMut.Unlock()
+
+	waitFor := j.expire(ctx, j.clock(), endOfInput)
+	if len(j.ready) == 0 {
+		return nil, nil, waitFor
+	}
+
+	ready := j.ready
+	j.ready = nil
+
+	msgBatch := make(service.MessageBatch, 0, len(ready))
+	for _, r := range ready {
+		msgBatch = append(msgBatch, r.msg)
+	}
+	return msgBatch, func(ctx context.Context, err error) error {
+		for _, r := range ready {
+			_ = r.ackFn(ctx, err)
+		}
+		return nil
+	}, 0
+}
+
+func (j *joinBuffer) ReadBatch(ctx context.Context) (service.MessageBatch, service.AckFunc, error) {
+	for {
+		endOfInput := false
+		select {
+		case <-j.endOfInputChan:
+			endOfInput = true
+		default:
+		}
+
+		msgBatch, aFn, waitFor := j.nextBatch(ctx, endOfInput)
+		if len(msgBatch) > 0 {
+			return msgBatch, aFn, nil
+		}
+		if endOfInput {
+			return nil, nil, service.ErrEndOfBuffer
+		}
+
+		var timer *time.Timer
+		var deadlineChan <-chan time.Time
+		if waitFor > 0 {
+			timer = time.NewTimer(waitFor)
+			deadlineChan = timer.C
+		}
+
+		var err error
+		select {
+		case <-deadlineChan:
+		case <-j.writtenChan:
+		case <-j.endOfInputChan:
+		case <-ctx.Done():
+			err = ctx.Err()
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+}
+
+func (j *joinBuffer) EndOfInput() {
+	j.closeEndOfInputOnce.Do(func() {
+		close(j.endOfInputChan)
+	})
+}
+
+func (j *joinBuffer) Close(ctx context.Context) error {
+	return nil
+}
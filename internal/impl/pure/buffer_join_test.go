@@ -0,0 +1,173 @@
+package pure
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func testJoinBuffer(t *testing.T, clock utcNowProvider, emitUnmatched bool) *joinBuffer {
+	t.Helper()
+
+	key, err := service.NewInterpolatedString(`${! json("id") }`)
+	require.NoError(t, err)
+
+	stream, err := service.NewInterpolatedString(`${! meta("source") }`)
+	require.NoError(t, err)
+
+	j, err := newJoinBuffer(key, stream, []string{"orders", "payments"}, 10*time.Second, emitUnmatched, clock, nil)
+	require.NoError(t, err)
+	return j
+}
+
+func joinMsg(source, content string) *service.Message {
+	msg := service.NewMessage([]byte(content))
+	msg.MetaSetMut("source", source)
+	return msg
+}
+
+func TestJoinBufferConfigErrors(t *testing.T) {
+	key, err := service.NewInterpolatedString(`${! json("id") }`)
+	require.NoError(t, err)
+
+	for _, test := range []struct {
+		streams []string
+		window  time.Duration
+		errStr  string
+	}{
+		{streams: []string{"a"}, window: time.Second, errStr: "at least two streams must be specified"},
+		{streams: []string{"a", "b", "a"}, window: time.Second, errStr: "stream a is listed more than once"},
+		{streams: []string{"a", "b"}, window: 0, errStr: "the window must be greater than zero"},
+	} {
+		_, err := newJoinBuffer(key, key, test.streams, test.window, false, nil, nil)
+		require.EqualError(t, err, test.errStr)
+	}
+}
+
+func TestJoinBufferMatch(t *testing.T) {
+	currentTS := time.Unix(0, 0).UTC()
+	j := testJoinBuffer(t, func() time.Time {
+		return currentTS
+	}, false)
+
+	var acks []error
+	ackFn := func(ctx context.Context, err error) error {
+		acks = append(acks, err)
+		return nil
+	}
+
+	require.NoError(t, j.WriteBatch(context.Background(), service.MessageBatch{
+		joinMsg("payments", `{"id":"1","amount":5}`),
+		joinMsg("orders", `{"id":"1","item":"foo"}`),
+		joinMsg("orders", `{"id":"2","item":"bar"}`),
+	}, ackFn))
+
+	require.Error(t, j.WriteBatch(context.Background(), service.MessageBatch{
+		joinMsg("refunds", `{"id":"1"}`),
+	}, ackFn))
+
+	resBatch, aFn, err := j.ReadBatch(context.Background())
+	require.NoError(t, err)
+	require.Len(t, resBatch, 1)
+
+	v, err := resBatch[0].AsStructured()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{
+		"orders":   map[string]any{"id": "1", "item": "foo"},
+		"payments": map[string]any{"id": "1", "amount": 5.0},
+	}, v)
+
+	status, _ := resBatch[0].MetaGetMut("join_status")
+	assert.Equal(t, "matched", status)
+	key, _ := resBatch[0].MetaGetMut("join_key")
+	assert.Equal(t, "1", key)
+	source, _ := resBatch[0].MetaGetMut("source")
+	assert.Equal(t, "orders", source)
+
+	require.NoError(t, aFn(context.Background(), nil))
+	assert.Empty(t, acks)
+
+	smallWaitCtx, done := context.WithTimeout(context.Background(), time.Millisecond*50)
+	_, _, err = j.ReadBatch(smallWaitCtx)
+	done()
+	require.Error(t, err)
+
+	// The unmatched order is dropped and acknowledged once the window passes,
+	// and so a late payment is not matched with it.
+	currentTS = time.Unix(10, 0).UTC()
+	require.NoError(t, j.WriteBatch(context.Background(), service.MessageBatch{
+		joinMsg("payments", `{"id":"2","amount":3}`),
+	}, ackFn))
+	assert.Equal(t, []error{nil}, acks)
+
+	j.EndOfInput()
+	_, _, err = j.ReadBatch(context.Background())
+	require.ErrorIs(t, err, service.ErrEndOfBuffer)
+
+	assert.Equal(t, []error{nil, nil}, acks)
+	assert.Empty(t, j.pending)
+	assert.Empty(t, j.arrivals)
+}
+
+func TestJoinBufferEmitUnmatched(t *testing.T) {
+	currentTS := time.Unix(0, 0).UTC()
+	j := testJoinBuffer(t, func() time.Time {
+		return currentTS
+	}, true)
+
+	require.NoError(t, j.WriteBatch(context.Background(), service.MessageBatch{
+		joinMsg("orders", `{"id":"1","item":"foo"}`),
+		joinMsg("orders", `{"id":"1","item":"bar"}`),
+	}, noopAck))
+
+	currentTS = time.Unix(5, 0).UTC()
+	require.NoError(t, j.WriteBatch(context.Background(), service.MessageBatch{
+		joinMsg("payments", `{"id":"1","amount":5}`),
+		joinMsg("payments", `{"id":"3","amount":7}`),
+	}, noopAck))
+
+	resBatch, _, err := j.ReadBatch(context.Background())
+	require.NoError(t, err)
+	require.Len(t, resBatch, 1)
+
+	v, err := resBatch[0].AsStructured()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{
+		"orders":   map[string]any{"id": "1", "item": "foo"},
+		"payments": map[string]any{"id": "1", "amount": 5.0},
+	}, v)
+
+	currentTS = time.Unix(10, 0).UTC()
+	resBatch, _, err = j.ReadBatch(context.Background())
+	require.NoError(t, err)
+	require.Len(t, resBatch, 1)
+
+	v, err = resBatch[0].AsStructured()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{
+		"orders": map[string]any{"id": "1", "item": "bar"},
+	}, v)
+	status, _ := resBatch[0].MetaGetMut("join_status")
+	assert.Equal(t, "unmatched", status)
+
+	j.EndOfInput()
+	resBatch, _, err = j.ReadBatch(context.Background())
+	require.NoError(t, err)
+	require.Len(t, resBatch, 1)
+
+	v, err = resBatch[0].AsStructured()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{
+		"payments": map[string]any{"id": "3", "amount": 7.0},
+	}, v)
+	key, _ := resBatch[0].MetaGetMut("join_key")
+	assert.Equal(t, "3", key)
+
+	_, _, err = j.ReadBatch(context.Background())
+	require.ErrorIs(t, err, service.ErrEndOfBuffer)
+}
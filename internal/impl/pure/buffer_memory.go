@@ -178,6 +178,10 @@ func (m *memoryBuffer) ReadBatch(ctx context.Context) (service.MessageBatch, ser
 		}
 
 		for len(m.batches) > 0 && !batchReady {
+			if m.batcher.FlushBefore(m.batches[0].b) {
+				batchReady = true
+				break
+			}
 			outSize += m.batches[0].size
 			for _, msg := range m.batches[0].b {
 				batchReady = m.batcher.Add(msg.Copy())
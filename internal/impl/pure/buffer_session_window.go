@@ -0,0 +1,340 @@
+package pure
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/benthosdev/benthos/v4/internal/batch"
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	swbFieldKey         = "key"
+	swbFieldGap         = "gap"
+	swbFieldMaxDuration = "max_duration"
+	swbFieldMaxSize     = "max_size"
+)
+
+func sessionWindowBufferConfig() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Version("4.28.0").
+		Categories("Windowing").
+		Summary("Groups messages into sessions by a key, where a session is closed after a period of inactivity and flushed as a batch.").
+		Description(`
+A session is a grouping of messages that share a key and arrive without a gap of inactivity longer than the `+"[`gap`](#gap)"+` duration between them, following the system clock. Unlike the `+"[`system_window` buffer](/docs/components/buffers/system_window)"+` sessions are not of a fixed size, and so they are able to model activity such as the visits of users to a website.
+
+A session is closed and flushed as a batch once no messages of its key have arrived for the `+"`gap`"+` duration. Sessions can also be capped with a `+"[`max_duration`](#max_duration)"+`, measured from the arrival of their first message, and a `+"[`max_size`](#max_size)"+` number of messages, after which they are flushed immediately. Once a session has been flushed any further messages of its key begin a new session.
+
+When a session is flushed each of its messages have the following metadata fields added:
+
+- `+"`session_key`"+`: The key of the session.
+- `+"`session_start_timestamp`"+`: The arrival time of the first message of the session as an RFC3339 string.
+- `+"`session_end_timestamp`"+`: The arrival time of the last message of the session as an RFC3339 string.
+- `+"`session_close_reason`"+`: The reason the session was closed, one of `+"`gap`"+`, `+"`max_duration`"+`, `+"`max_size`"+` or `+"`end_of_input`"+`.
+
+## Delivery Guarantees
+
+This buffer honours the transaction model within Benthos in order to ensure that messages are not acknowledged until they are successfully delivered to outputs, which means messages of open sessions remain unacknowledged and should be considered when sizing the resources of inputs.
+
+When the input of the buffer ends, such as when a file has been consumed entirely, all open sessions are closed and flushed.
+`).
+		Fields(
+			service.NewInterpolatedStringField(swbFieldKey).
+				Description("An interpolated string that provides the key of the session that each message belongs to.").
+				Example(`${! json("user_id") }`).
+				Example(`${! meta("kafka_key") }`),
+			service.NewStringField(swbFieldGap).
+				Description("A duration string describing the period of inactivity after which a session is closed.").
+				Example("30s").Example("30m"),
+			service.NewStringField(swbFieldMaxDuration).
+				Description("An optional duration string describing the maximum length of time a session can remain open, measured from the arrival of its first message.").
+				Default("").
+				Example("1h"),
+			service.NewIntField(swbFieldMaxSize).
+				Description("An optional maximum number of messages within a session, once reached the session is closed. When set to zero sessions are not limited in size.").
+				Default(0),
+		).
+		Example("User Sessions", `Given a stream of page views of the form:
+
+`+"```json"+`
+{
+  "user_id": "f2d7b8c1",
+  "page": "/checkout",
+  "created_at": "2024-04-23T09:49:35Z"
+}
+`+"```"+`
+
+We can summarise each visit of a user, where a visit ends after thirty minutes without a page view, with the following config:`,
+			`
+buffer:
+  session_window:
+    key: ${! json("user_id") }
+    gap: 30m
+    max_duration: 24h
+
+pipeline:
+  processors:
+    - mapping: |
+        root = if batch_index() == 0 {
+          {
+            "user_id": this.user_id,
+            "started_at": meta("session_start_timestamp"),
+            "ended_at": meta("session_end_timestamp"),
+            "pages": json("page").from_all(),
+          }
+        } else { deleted() }
+`,
+		)
+}
+
+func init() {
+	err := service.RegisterBatchBuffer(
+		"session_window", sessionWindowBufferConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.BatchBuffer, error) {
+			key, err := conf.FieldInterpolatedString(swbFieldKey)
+			if err != nil {
+				return nil, err
+			}
+			gap, err := getDuration(conf, true, swbFieldGap)
+			if err != nil {
+				return nil, err
+			}
+			if gap <= 0 {
+				return nil, errors.New("the session gap must be greater than zero")
+			}
+			maxDuration, err := getDuration(conf, false, swbFieldMaxDuration)
+			if err != nil {
+				return nil, err
+			}
+			maxSize, err := conf.FieldInt(swbFieldMaxSize)
+			if err != nil {
+				return nil, err
+			}
+			if maxSize < 0 {
+				return nil, errors.New("the max_size must not be negative")
+			}
+			return newSessionWindowBuffer(key, func() time.Time {
+				return time.Now().UTC()
+			}, gap, maxDuration, maxSize, mgr.Logger())
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type windowSession struct {
+	key         string
+	start, last time.Time
+	pending     []*tsMessage
+	closeReason string
+}
+
+type sessionWindowBuffer struct {
+	logger *service.Logger
+
+	key              *service.InterpolatedString
+	clock            utcNowProvider
+	gap, maxDuration time.Duration
+	maxSize          int
+
+	sessions   map[string]*windowSession
+	closed     []*windowSession
+	sessionMut sync.Mutex
+
+	writtenChan         chan struct{}
+	endOfInputChan      chan struct{}
+	closeEndOfInputOnce sync.Once
+}
+
+func newSessionWindowBuffer(
+	key *service.InterpolatedString,
+	clock utcNowProvider,
+	gap, maxDuration time.Duration,
+	maxSize int,
+	logger *service.Logger,
+) (*sessionWindowBuffer, error) {
+	return &sessionWindowBuffer{
+		logger:         logger,
+		key:            key,
+		clock:          clock,
+		gap:            gap,
+		maxDuration:    maxDuration,
+		maxSize:        maxSize,
+		sessions:       map[string]*windowSession{},
+		writtenChan:    make(chan struct{}, 1),
+		endOfInputChan: make(chan struct{}),
+	}, nil
+}
+
+func (w *sessionWindowBuffer) WriteBatch(ctx context.Context, msgBatch service.MessageBatch, aFn service.AckFunc) error {
+	if len(msgBatch) == 0 {
+		return aFn(ctx, nil)
+	}
+
+	keys := make([]string, len(msgBatch))
+	for i := range msgBatch {
+		var err error
+		if keys[i], err = msgBatch.TryInterpolatedString(i, w.key); err != nil {
+			w.logger.Errorf("Session key interpolation failed for message: %v", err)
+			return err
+		}
+	}
+
+	w.sessionMut.Lock()
+	defer w.sessionMut.Unlock()
+
+	now := w.clock()
+	aggregatedAck := batch.NewCombinedAcker(batch.AckFunc(aFn))
+	for i, msg := range msgBatch {
+		s, exists := w.sessions[keys[i]]
+		if !exists {
+			s = &windowSession{key: keys[i], start: now}
+			w.sessions[keys[i]] = s
+		}
+		s.last = now
+		s.pending = append(s.pending, &tsMessage{
+			ts: now, m: msg, ackFn: service.AckFunc(aggregatedAck.Derive()),
+		})
+		if w.maxSize > 0 && len(s.pending) >= w.maxSize {
+			w.closeSession(s, "max_size")
+		}
+	}
+
+	select {
+	case w.writtenChan <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// closeSession removes a session from the open sessions and queues it to be
+// flushed. Must be called with the session mutex locked.
+func (w *sessionWindowBuffer) closeSession(s *windowSession, reason string) {
+	delete(w.sessions, s.key)
+	s.closeReason = reason
+	w.closed = append(w.closed, s)
+}
+
+// deadline returns the time at which a session should be closed, and the
+// reason for closing it at that time.
+func (w *sessionWindowBuffer) deadline(s *windowSession) (time.Time, string) {
+	deadline, reason := s.last.Add(w.gap), "gap"
+	if w.maxDuration > 0 {
+		if maxEnd := s.start.Add(w.maxDuration); maxEnd.Before(deadline) {
+			deadline, reason = maxEnd, "max_duration"
+		}
+	}
+	return deadline, reason
+}
+
+// nextClosed returns the next session to flush, closing the session that has
+// been open beyond its deadline for the longest time if there isn't one
+// queued already. When no sessions are due the time until the next deadline
+// is returned, or zero when there are no open sessions.
+func (w *sessionWindowBuffer) nextClosed(endOfInput bool) (*windowSession, time.Duration) {
+	w.sessionMut.Lock()
+	defer w.sessionMut.Unlock()
+
+	if len(w.closed) == 0 {
+		now := w.clock()
+
+		var next *windowSession
+		var nextDeadline time.Time
+		var nextReason string
+		for _, s := range w.sessions {
+			deadline, reason := w.deadline(s)
+			if next == nil || deadline.Before(nextDeadline) ||
+				(deadline.Equal(nextDeadline) && s.key < next.key) {
+				next, nextDeadline, nextReason = s, deadline, reason
+			}
+		}
+		if next == nil {
+			return nil, 0
+		}
+		if endOfInput {
+			nextReason = "end_of_input"
+		} else if waitFor := nextDeadline.Sub(now); waitFor > 0 {
+			return nil, waitFor
+		}
+		w.closeSession(next, nextReason)
+	}
+
+	s := w.closed[0]
+	w.closed = w.closed[1:]
+	return s, 0
+}
+
+func (w *sessionWindowBuffer) flushSession(s *windowSession) (service.MessageBatch, service.AckFunc) {
+	flushBatch := make(service.MessageBatch, 0, len(s.pending))
+	flushAcks := make([]service.AckFunc, 0, len(s.pending))
+	for _, pending := range s.pending {
+		pending.m.MetaSetMut("session_key", s.key)
+		pending.m.MetaSetMut("session_start_timestamp", s.start.Format(time.RFC3339Nano))
+		pending.m.MetaSetMut("session_end_timestamp", s.last.Format(time.RFC3339Nano))
+		pending.m.MetaSetMut("session_close_reason", s.closeReason)
+		flushBatch = append(flushBatch, pending.m)
+		flushAcks = append(flushAcks, pending.ackFn)
+	}
+	return flushBatch, func(ctx context.Context, err error) error {
+		for _, aFn := range flushAcks {
+			_ = aFn(ctx, err)
+		}
+		return nil
+	}
+}
+
+func (w *sessionWindowBuffer) ReadBatch(ctx context.Context) (service.MessageBatch, service.AckFunc, error) {
+	for {
+		endOfInput := false
+		select {
+		case <-w.endOfInputChan:
+			endOfInput = true
+		default:
+		}
+
+		s, waitFor := w.nextClosed(endOfInput)
+		if s != nil {
+			msgBatch, aFn := w.flushSession(s)
+			return msgBatch, aFn, nil
+		}
+		if endOfInput {
+			return nil, nil, service.ErrEndOfBuffer
+		}
+
+		var timer *time.Timer
+		var deadlineChan <-chan time.Time
+		if waitFor > 0 {
+			timer = time.NewTimer(waitFor)
+			deadlineChan = timer.C
+		}
+
+		var err error
+		select {
+		case <-deadlineChan:
+		case <-w.writtenChan:
+		case <-w.endOfInputChan:
+		case <-ctx.Done():
+			err = ctx.Err()
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+}
+
+func (w *sessionWindowBuffer) EndOfInput() {
+	w.closeEndOfInputOnce.Do(func() {
+		close(w.endOfInputChan)
+	})
+}
+
+func (w *sessionWindowBuffer) Close(ctx context.Context) error {
+	return nil
+}
@@ -0,0 +1,251 @@
+package pure
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func TestSessionWindowBufferConfigs(t *testing.T) {
+	tests := []struct {
+		config           string
+		lintErrContains  string
+		buildErrContains string
+	}{
+		{
+			config: `
+session_window:
+  key: ${! json("id") }
+  gap: 10s
+  max_duration: 1m
+  max_size: 10
+`,
+		},
+		{
+			config: `
+session_window:
+  key: ${! json("id") }
+`,
+			lintErrContains: "field gap is required",
+		},
+		{
+			config: `
+session_window:
+  key: ${! json("id") }
+  gap: 0s
+`,
+			buildErrContains: "gap must be greater than zero",
+		},
+		{
+			config: `
+session_window:
+  key: ${! json("id") }
+  gap: 10s
+  max_size: -1
+`,
+			buildErrContains: "max_size must not be negative",
+		},
+	}
+
+	for i, test := range tests {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			env := service.NewStreamBuilder()
+			require.NoError(t, env.SetLoggerYAML(`level: OFF`))
+			err := env.AddConsumerFunc(func(context.Context, *service.Message) error {
+				return nil
+			})
+			require.NoError(t, err)
+			_, err = env.AddProducerFunc()
+			require.NoError(t, err)
+
+			err = env.SetBufferYAML(test.config)
+			if test.lintErrContains != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), test.lintErrContains)
+				return
+			}
+			require.NoError(t, err)
+
+			strm, err := env.Build()
+			require.NoError(t, err)
+
+			cancelledCtx, done := context.WithCancel(context.Background())
+			done()
+			err = strm.Run(cancelledCtx)
+			if test.buildErrContains != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), test.buildErrContains)
+				return
+			}
+			require.EqualError(t, err, "context canceled")
+			require.NoError(t, strm.StopWithin(time.Second))
+		})
+	}
+}
+
+func testSessionWindowBuffer(t *testing.T, clock utcNowProvider, gap, maxDuration time.Duration, maxSize int) *sessionWindowBuffer {
+	t.Helper()
+
+	key, err := service.NewInterpolatedString(`${! json("user") }`)
+	require.NoError(t, err)
+
+	w, err := newSessionWindowBuffer(key, clock, gap, maxDuration, maxSize, nil)
+	require.NoError(t, err)
+	return w
+}
+
+func assertSessionBatch(t *testing.T, batch service.MessageBatch, key, reason string, contents ...string) {
+	t.Helper()
+
+	require.Len(t, batch, len(contents))
+	for i, exp := range contents {
+		msgBytes, err := batch[i].AsBytes()
+		require.NoError(t, err)
+		assert.Equal(t, exp, string(msgBytes))
+
+		v, _ := batch[i].MetaGetMut("session_key")
+		assert.Equal(t, key, v)
+		v, _ = batch[i].MetaGetMut("session_close_reason")
+		assert.Equal(t, reason, v)
+	}
+}
+
+func TestSessionWindowGap(t *testing.T) {
+	currentTS := time.Unix(0, 0).UTC()
+	w := testSessionWindowBuffer(t, func() time.Time {
+		return currentTS
+	}, 10*time.Second, 0, 0)
+
+	require.NoError(t, w.WriteBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte(`{"user":"a","id":1}`)),
+		service.NewMessage([]byte(`{"user":"b","id":2}`)),
+	}, noopAck))
+
+	currentTS = time.Unix(5, 0).UTC()
+	require.NoError(t, w.WriteBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte(`{"user":"a","id":3}`)),
+	}, noopAck))
+
+	currentTS = time.Unix(10, 500).UTC()
+	resBatch, _, err := w.ReadBatch(context.Background())
+	require.NoError(t, err)
+	assertSessionBatch(t, resBatch, "b", "gap", `{"user":"b","id":2}`)
+
+	smallWaitCtx, done := context.WithTimeout(context.Background(), time.Millisecond*50)
+	resBatch, _, err = w.ReadBatch(smallWaitCtx)
+	done()
+	require.Error(t, err)
+	assert.Empty(t, resBatch)
+
+	currentTS = time.Unix(15, 0).UTC()
+	resBatch, _, err = w.ReadBatch(context.Background())
+	require.NoError(t, err)
+	assertSessionBatch(t, resBatch, "a", "gap", `{"user":"a","id":1}`, `{"user":"a","id":3}`)
+
+	start, _ := resBatch[0].MetaGetMut("session_start_timestamp")
+	assert.Equal(t, "1970-01-01T00:00:00Z", start)
+	end, _ := resBatch[0].MetaGetMut("session_end_timestamp")
+	assert.Equal(t, "1970-01-01T00:00:05Z", end)
+
+	// A message of a flushed key begins a new session.
+	require.NoError(t, w.WriteBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte(`{"user":"a","id":4}`)),
+	}, noopAck))
+	assert.Len(t, w.sessions, 1)
+	assert.Equal(t, currentTS, w.sessions["a"].start)
+}
+
+func TestSessionWindowCaps(t *testing.T) {
+	currentTS := time.Unix(0, 0).UTC()
+	w := testSessionWindowBuffer(t, func() time.Time {
+		return currentTS
+	}, 10*time.Second, 12*time.Second, 2)
+
+	require.NoError(t, w.WriteBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte(`{"user":"a","id":1}`)),
+		service.NewMessage([]byte(`{"user":"b","id":2}`)),
+		service.NewMessage([]byte(`{"user":"a","id":3}`)),
+		service.NewMessage([]byte(`{"user":"a","id":4}`)),
+	}, noopAck))
+
+	resBatch, _, err := w.ReadBatch(context.Background())
+	require.NoError(t, err)
+	assertSessionBatch(t, resBatch, "a", "max_size", `{"user":"a","id":1}`, `{"user":"a","id":3}`)
+
+	currentTS = time.Unix(8, 0).UTC()
+	require.NoError(t, w.WriteBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte(`{"user":"b","id":5}`)),
+	}, noopAck))
+
+	// The session of b is closed by the write that fills it, and is therefore
+	// flushed before the session of a reaches its gap.
+	currentTS = time.Unix(12, 0).UTC()
+	resBatch, _, err = w.ReadBatch(context.Background())
+	require.NoError(t, err)
+	assertSessionBatch(t, resBatch, "b", "max_size", `{"user":"b","id":2}`, `{"user":"b","id":5}`)
+
+	resBatch, _, err = w.ReadBatch(context.Background())
+	require.NoError(t, err)
+	assertSessionBatch(t, resBatch, "a", "gap", `{"user":"a","id":4}`)
+
+	// A session that keeps receiving messages is closed by the max_duration
+	// before it reaches its gap.
+	currentTS = time.Unix(0, 0).UTC()
+	w = testSessionWindowBuffer(t, func() time.Time {
+		return currentTS
+	}, 10*time.Second, 12*time.Second, 10)
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, w.WriteBatch(context.Background(), service.MessageBatch{
+			service.NewMessage([]byte(`{"user":"c","id":` + strconv.Itoa(i) + `}`)),
+		}, noopAck))
+		currentTS = currentTS.Add(5 * time.Second)
+	}
+
+	resBatch, _, err = w.ReadBatch(context.Background())
+	require.NoError(t, err)
+	assertSessionBatch(t, resBatch, "c", "max_duration", `{"user":"c","id":0}`, `{"user":"c","id":1}`, `{"user":"c","id":2}`)
+}
+
+func TestSessionWindowEndOfInput(t *testing.T) {
+	currentTS := time.Unix(0, 0).UTC()
+	w := testSessionWindowBuffer(t, func() time.Time {
+		return currentTS
+	}, time.Hour, 0, 0)
+
+	var ackCalled int
+	var ackErr error
+	require.NoError(t, w.WriteBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte(`{"user":"a","id":1}`)),
+		service.NewMessage([]byte(`{"user":"b","id":2}`)),
+	}, func(ctx context.Context, err error) error {
+		ackCalled++
+		ackErr = err
+		return nil
+	}))
+
+	w.EndOfInput()
+
+	resBatch, aFnA, err := w.ReadBatch(context.Background())
+	require.NoError(t, err)
+	assertSessionBatch(t, resBatch, "a", "end_of_input", `{"user":"a","id":1}`)
+
+	resBatch, aFnB, err := w.ReadBatch(context.Background())
+	require.NoError(t, err)
+	assertSessionBatch(t, resBatch, "b", "end_of_input", `{"user":"b","id":2}`)
+
+	_, _, err = w.ReadBatch(context.Background())
+	require.ErrorIs(t, err, service.ErrEndOfBuffer)
+
+	require.NoError(t, aFnA(context.Background(), nil))
+	assert.Equal(t, 0, ackCalled)
+	require.NoError(t, aFnB(context.Background(), nil))
+	assert.Equal(t, 1, ackCalled)
+	assert.NoError(t, ackErr)
+}
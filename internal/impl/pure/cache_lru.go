@@ -221,6 +221,22 @@ type lruCache interface {
 	Get(key string) (value []byte, ok bool)
 	Add(key string, value []byte)
 	Remove(key string)
+	Keys() []string
+	Values() [][]byte
+}
+
+// lruSize returns the number of items of an LRU cache and the number of bytes
+// occupied by their keys and values, where the keys and values are obtained
+// separately and the result is therefore approximate.
+func lruSize(keys []string, values [][]byte) (items, bytes int64) {
+	items = int64(len(keys))
+	for _, k := range keys {
+		bytes += int64(len(k))
+	}
+	for _, v := range values {
+		bytes += int64(len(v))
+	}
+	return
 }
 
 type lruv2SimpleCacheAdaptor[K comparable, V any] struct {
@@ -293,6 +309,12 @@ func (ca *lruCacheAdapter) Delete(_ context.Context, key string) error {
 	return nil
 }
 
+// Size returns the approximate number of items held and the number of bytes
+// occupied by their keys and values.
+func (ca *lruCacheAdapter) Size() (items, bytes int64) {
+	return lruSize(ca.inner.Keys(), ca.inner.Values())
+}
+
 func (ca *lruCacheAdapter) Close(_ context.Context) error {
 	return nil
 }
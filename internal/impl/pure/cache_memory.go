@@ -239,6 +239,20 @@ func (m *memoryCache) Delete(_ context.Context, key string) error {
 	return nil
 }
 
+// Size returns the number of items held, including expired items that are yet
+// to be compacted, and the number of bytes occupied by their keys and values.
+func (m *memoryCache) Size() (items, bytes int64) {
+	for _, shard := range m.shards {
+		shard.RLock()
+		items += int64(len(shard.items))
+		for k, v := range shard.items {
+			bytes += int64(len(k) + len(v.value))
+		}
+		shard.RUnlock()
+	}
+	return
+}
+
 func (m *memoryCache) Close(context.Context) error {
 	return nil
 }
@@ -253,3 +253,33 @@ shards: 10
 		assert.Equal(b, value, res)
 	}
 }
+
+func TestMemoryCacheSize(t *testing.T) {
+	defConf, err := memCacheConfig().ParseYAML(`
+shards: 4
+`, nil)
+	require.NoError(t, err)
+
+	c, err := newMemCacheFromConfig(defConf)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+
+	items, bytes := c.Size()
+	assert.Equal(t, int64(0), items)
+	assert.Equal(t, int64(0), bytes)
+
+	require.NoError(t, c.Set(ctx, "foo", []byte("bar"), nil))
+	require.NoError(t, c.Set(ctx, "baz", []byte("buzz"), nil))
+	require.NoError(t, c.Set(ctx, "foo", []byte("b"), nil))
+
+	items, bytes = c.Size()
+	assert.Equal(t, int64(2), items)
+	assert.Equal(t, int64(11), bytes)
+
+	require.NoError(t, c.Delete(ctx, "baz"))
+
+	items, bytes = c.Size()
+	assert.Equal(t, int64(1), items)
+	assert.Equal(t, int64(4), bytes)
+}
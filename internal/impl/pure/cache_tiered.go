@@ -0,0 +1,448 @@
+package pure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/golang-lru/v2/expirable"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	tcFieldResource         = "resource"
+	tcFieldLocal            = "local"
+	tcFieldLocalCap         = "cap"
+	tcFieldLocalTTL         = "ttl"
+	tcFieldNegativeTTL      = "negative_ttl"
+	tcFieldWritePolicy      = "write_policy"
+	tcFieldWriteBehindQueue = "write_behind_queue"
+)
+
+func tieredCacheConfig() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Version("4.28.0").
+		Summary(`Fronts a cache resource, such as a remote `+"`redis`"+` or `+"`aws_dynamodb`"+` cache, with a bounded in-memory layer that serves repeated reads without requests to the remote cache.`).
+		Description(`
+Reads are served by the in-memory layer when it holds the key, and otherwise are read through from the cache `+"`resource`"+`, where values that are found are then held in memory for up to the duration of `+"`local.ttl`"+`. When the in-memory layer holds `+"`local.cap`"+` keys the least recently used key is evicted in order to make room for another.
+
+When `+"`negative_ttl`"+` is greater than zero keys that are not found within the cache resource are also remembered, and further reads of them fail without requests to the resource until the duration elapses. This is useful when enriching messages where many keys are not expected to exist.
+
+### Write Policies
+
+With the `+"`through`"+` write policy sets and deletes are performed on the cache resource before they are performed in memory, and any errors are returned to the caller. With the `+"`behind`"+` write policy sets and deletes are performed in memory and then queued in order to be performed on the cache resource in the background, which hides the latency of the resource at the cost of writes being lost when the resource cannot be reached, in which case errors are logged. Once `+"`write_behind_queue`"+` writes are queued further writes block until there is room.
+
+Adds are always performed on the cache resource before they are performed in memory, as only the resource can determine whether a key already exists.
+
+Values held in memory are not updated when they are modified within the cache resource by other means, and can therefore be stale for up to the duration of `+"`local.ttl`"+`.`).
+		Fields(
+			service.NewStringField(tcFieldResource).
+				Description("The cache resource to front with the in-memory layer."),
+			service.NewObjectField(tcFieldLocal,
+				service.NewIntField(tcFieldLocalCap).
+					Description("The maximum number of keys to hold in memory.").
+					Default(1024),
+				service.NewDurationField(tcFieldLocalTTL).
+					Description("The maximum period of time to hold a key in memory, which bounds how stale values served from memory can be.").
+					Default("1m"),
+			).
+				Description("Options for the in-memory layer."),
+			service.NewDurationField(tcFieldNegativeTTL).
+				Description("The period of time to remember keys that were not found within the cache resource, where `0s` disables the remembering of missing keys.").
+				Default("0s"),
+			service.NewStringAnnotatedEnumField(tcFieldWritePolicy, map[string]string{
+				"through": "Writes are performed on the cache resource before they are performed in memory.",
+				"behind":  "Writes are performed in memory and then performed on the cache resource in the background.",
+			}).
+				Description("How writes are performed on the cache resource.").
+				Default("through"),
+			service.NewIntField(tcFieldWriteBehindQueue).
+				Description("The maximum number of writes to queue when `write_policy` is `behind`.").
+				Default(1000).
+				Advanced(),
+		).
+		Example(
+			"Enrichment",
+			"Here we enrich documents with user profiles stored in Redis, where profiles that were read within the last thirty seconds are served from memory, and users without profiles are remembered for ten seconds.",
+			`
+pipeline:
+  processors:
+    - branch:
+        processors:
+          - cache:
+              resource: profiles
+              operator: get
+              key: ${! json("user_id") }
+        result_map: 'root.profile = this'
+
+cache_resources:
+  - label: profiles
+    tiered:
+      resource: remote_profiles
+      local:
+        cap: 10000
+        ttl: 30s
+      negative_ttl: 10s
+
+  - label: remote_profiles
+    redis:
+      url: redis://TODO:6379
+`)
+}
+
+func init() {
+	err := service.RegisterCache(
+		"tiered", tieredCacheConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Cache, error) {
+			return newTieredCacheFromConfig(conf, mgr)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+func newTieredCacheFromConfig(conf *service.ParsedConfig, mgr *service.Resources) (*tieredCache, error) {
+	resource, err := conf.FieldString(tcFieldResource)
+	if err != nil {
+		return nil, err
+	}
+	if !mgr.HasCache(resource) {
+		return nil, fmt.Errorf("cache resource '%v' was not found", resource)
+	}
+
+	localConf := conf.Namespace(tcFieldLocal)
+	capacity, err := localConf.FieldInt(tcFieldLocalCap)
+	if err != nil {
+		return nil, err
+	}
+	localTTL, err := localConf.FieldDuration(tcFieldLocalTTL)
+	if err != nil {
+		return nil, err
+	}
+	negativeTTL, err := conf.FieldDuration(tcFieldNegativeTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	writePolicy, err := conf.FieldString(tcFieldWritePolicy)
+	if err != nil {
+		return nil, err
+	}
+	var writeBehindQueue int
+	switch writePolicy {
+	case "through":
+	case "behind":
+		if writeBehindQueue, err = conf.FieldInt(tcFieldWriteBehindQueue); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unrecognised write policy: %v", writePolicy)
+	}
+
+	return newTieredCache(resource, mgr, mgr.Logger(), capacity, localTTL, negativeTTL, writeBehindQueue)
+}
+
+//------------------------------------------------------------------------------
+
+type tieredWrite struct {
+	key    string
+	value  []byte
+	ttl    *time.Duration
+	delete bool
+}
+
+type tieredCache struct {
+	resource string
+	mgr      cacheProvider
+	log      *service.Logger
+
+	local     *expirable.LRU[string, []byte]
+	negatives *expirable.LRU[string, struct{}]
+
+	// When nil writes are performed through to the resource.
+	writes      chan tieredWrite
+	writesMut   sync.RWMutex
+	writesDone  chan struct{}
+	writesClose sync.Once
+}
+
+func newTieredCache(
+	resource string,
+	mgr cacheProvider,
+	log *service.Logger,
+	capacity int,
+	localTTL, negativeTTL time.Duration,
+	writeBehindQueue int,
+) (*tieredCache, error) {
+	if capacity <= 0 {
+		return nil, errors.New("local cap must be larger than zero")
+	}
+	if localTTL <= 0 {
+		return nil, errors.New("local ttl must be larger than zero")
+	}
+
+	t := &tieredCache{
+		resource: resource,
+		mgr:      mgr,
+		log:      log,
+		local:    expirable.NewLRU[string, []byte](capacity, nil, localTTL),
+	}
+	if negativeTTL > 0 {
+		t.negatives = expirable.NewLRU[string, struct{}](capacity, nil, negativeTTL)
+	}
+	if writeBehindQueue > 0 {
+		t.writes = make(chan tieredWrite, writeBehindQueue)
+		t.writesDone = make(chan struct{})
+		go t.writeBehindLoop()
+	}
+	return t, nil
+}
+
+func (t *tieredCache) access(ctx context.Context, fn func(c service.Cache)) error {
+	if err := t.mgr.AccessCache(ctx, t.resource, fn); err != nil {
+		return fmt.Errorf("unable to access cache '%v': %v", t.resource, err)
+	}
+	return nil
+}
+
+// store holds a value in memory, replacing any memory of the key having been
+// missing.
+func (t *tieredCache) store(key string, value []byte) {
+	t.local.Add(key, value)
+	if t.negatives != nil {
+		t.negatives.Remove(key)
+	}
+}
+
+func (t *tieredCache) storeMissing(key string) {
+	t.local.Remove(key)
+	if t.negatives != nil {
+		t.negatives.Add(key, struct{}{})
+	}
+}
+
+// lookup returns the value of a key held in memory, and whether the key is
+// known to be missing.
+func (t *tieredCache) lookup(key string) (value []byte, found, missing bool) {
+	if value, found = t.local.Get(key); found {
+		return
+	}
+	if t.negatives != nil {
+		_, missing = t.negatives.Get(key)
+	}
+	return
+}
+
+func (t *tieredCache) Get(ctx context.Context, key string) ([]byte, error) {
+	value, found, missing := t.lookup(key)
+	if found {
+		return value, nil
+	}
+	if missing {
+		return nil, service.ErrKeyNotFound
+	}
+
+	var err error
+	if cerr := t.access(ctx, func(c service.Cache) {
+		value, err = c.Get(ctx, key)
+	}); cerr != nil {
+		return nil, cerr
+	}
+	if err != nil {
+		if errors.Is(err, service.ErrKeyNotFound) {
+			t.storeMissing(key)
+		}
+		return nil, err
+	}
+	t.store(key, value)
+	return value, nil
+}
+
+// GetMulti serves the keys held in memory and reads the remaining keys from
+// the cache resource, with a single request when the resource supports
+// batched reads.
+func (t *tieredCache) GetMulti(ctx context.Context, keys ...string) (map[string][]byte, error) {
+	values := make(map[string][]byte, len(keys))
+	var remoteKeys []string
+	for _, key := range keys {
+		value, found, missing := t.lookup(key)
+		if found {
+			values[key] = value
+		} else if !missing {
+			remoteKeys = append(remoteKeys, key)
+		}
+	}
+	if len(remoteKeys) == 0 {
+		return values, nil
+	}
+
+	var remoteValues map[string][]byte
+	var err error
+	if cerr := t.access(ctx, func(c service.Cache) {
+		if bc, ok := c.(interface {
+			GetMulti(ctx context.Context, keys ...string) (map[string][]byte, error)
+		}); ok {
+			remoteValues, err = bc.GetMulti(ctx, remoteKeys...)
+			return
+		}
+		remoteValues = make(map[string][]byte, len(remoteKeys))
+		for _, key := range remoteKeys {
+			value, gerr := c.Get(ctx, key)
+			if gerr != nil {
+				if errors.Is(gerr, service.ErrKeyNotFound) {
+					continue
+				}
+				err = gerr
+				return
+			}
+			remoteValues[key] = value
+		}
+	}); cerr != nil {
+		return nil, cerr
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for _, key := range remoteKeys {
+		if value, exists := remoteValues[key]; exists {
+			t.store(key, value)
+			values[key] = value
+		} else {
+			t.storeMissing(key)
+		}
+	}
+	return values, nil
+}
+
+func (t *tieredCache) Set(ctx context.Context, key string, value []byte, ttl *time.Duration) error {
+	if t.writes != nil {
+		t.store(key, value)
+		return t.queueWrite(ctx, tieredWrite{key: key, value: value, ttl: ttl})
+	}
+
+	var err error
+	if cerr := t.access(ctx, func(c service.Cache) {
+		err = c.Set(ctx, key, value, ttl)
+	}); cerr != nil {
+		return cerr
+	}
+	if err != nil {
+		// The value held in memory might no longer reflect the resource.
+		t.local.Remove(key)
+		return err
+	}
+	t.store(key, value)
+	return nil
+}
+
+func (t *tieredCache) Add(ctx context.Context, key string, value []byte, ttl *time.Duration) error {
+	if _, found := t.local.Get(key); found {
+		return service.ErrKeyAlreadyExists
+	}
+
+	var err error
+	if cerr := t.access(ctx, func(c service.Cache) {
+		err = c.Add(ctx, key, value, ttl)
+	}); cerr != nil {
+		return cerr
+	}
+	if err != nil {
+		return err
+	}
+	t.store(key, value)
+	return nil
+}
+
+func (t *tieredCache) Delete(ctx context.Context, key string) error {
+	if t.writes != nil {
+		t.storeMissing(key)
+		return t.queueWrite(ctx, tieredWrite{key: key, delete: true})
+	}
+
+	t.local.Remove(key)
+	var err error
+	if cerr := t.access(ctx, func(c service.Cache) {
+		err = c.Delete(ctx, key)
+	}); cerr != nil {
+		return cerr
+	}
+	if err != nil && !errors.Is(err, service.ErrKeyNotFound) {
+		return err
+	}
+	if t.negatives != nil {
+		t.negatives.Add(key, struct{}{})
+	}
+	return nil
+}
+
+// Size returns the approximate number of items held in memory and the number
+// of bytes occupied by their keys and values.
+func (t *tieredCache) Size() (items, bytes int64) {
+	return lruSize(t.local.Keys(), t.local.Values())
+}
+
+//------------------------------------------------------------------------------
+
+func (t *tieredCache) queueWrite(ctx context.Context, w tieredWrite) error {
+	t.writesMut.RLock()
+	defer t.writesMut.RUnlock()
+
+	select {
+	case <-t.writesDone:
+		return service.ErrNotConnected
+	default:
+	}
+
+	select {
+	case t.writes <- w:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return nil
+}
+
+func (t *tieredCache) writeBehindLoop() {
+	defer close(t.writesDone)
+
+	ctx := context.Background()
+	for w := range t.writes {
+		var err error
+		if cerr := t.access(ctx, func(c service.Cache) {
+			if w.delete {
+				if err = c.Delete(ctx, w.key); errors.Is(err, service.ErrKeyNotFound) {
+					err = nil
+				}
+			} else {
+				err = c.Set(ctx, w.key, w.value, w.ttl)
+			}
+		}); cerr != nil {
+			err = cerr
+		}
+		if err != nil {
+			t.log.Errorf("Failed to write key '%v' to cache '%v': %v", w.key, t.resource, err)
+		}
+	}
+}
+
+// Close waits for queued writes to be performed on the cache resource.
+func (t *tieredCache) Close(ctx context.Context) error {
+	if t.writes == nil {
+		return nil
+	}
+	t.writesClose.Do(func() {
+		t.writesMut.Lock()
+		close(t.writes)
+		t.writesMut.Unlock()
+	})
+	select {
+	case <-t.writesDone:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return nil
+}
@@ -0,0 +1,266 @@
+package pure
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+type countingCache struct {
+	service.Cache
+
+	mut   sync.Mutex
+	gets  int
+	multi int
+}
+
+func (c *countingCache) Get(ctx context.Context, key string) ([]byte, error) {
+	c.mut.Lock()
+	c.gets++
+	c.mut.Unlock()
+	return c.Cache.Get(ctx, key)
+}
+
+func (c *countingCache) GetMulti(ctx context.Context, keys ...string) (map[string][]byte, error) {
+	c.mut.Lock()
+	c.multi++
+	c.mut.Unlock()
+
+	values := map[string][]byte{}
+	for _, k := range keys {
+		if v, err := c.Cache.Get(ctx, k); err == nil {
+			values[k] = v
+		}
+	}
+	return values, nil
+}
+
+func (c *countingCache) counts() (gets, multi int) {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	return c.gets, c.multi
+}
+
+func TestTieredCacheReadThrough(t *testing.T) {
+	remote := &countingCache{Cache: newMemCache(time.Minute, 0, 1, map[string]string{
+		"foo": "foo value",
+	})}
+	p := &mockCacheProv{caches: map[string]service.Cache{"remote": remote}}
+
+	c, err := newTieredCache("remote", p, nil, 10, time.Minute, time.Minute, 0)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		val, err := c.Get(ctx, "foo")
+		require.NoError(t, err)
+		assert.Equal(t, "foo value", string(val))
+
+		_, err = c.Get(ctx, "bar")
+		assert.Equal(t, service.ErrKeyNotFound, err)
+	}
+
+	gets, _ := remote.counts()
+	assert.Equal(t, 2, gets)
+
+	// Setting a key replaces any memory of it being missing.
+	require.NoError(t, c.Set(ctx, "bar", []byte("bar value"), nil))
+	val, err := c.Get(ctx, "bar")
+	require.NoError(t, err)
+	assert.Equal(t, "bar value", string(val))
+
+	val, err = remote.Cache.Get(ctx, "bar")
+	require.NoError(t, err)
+	assert.Equal(t, "bar value", string(val))
+
+	require.NoError(t, c.Delete(ctx, "foo"))
+	_, err = c.Get(ctx, "foo")
+	assert.Equal(t, service.ErrKeyNotFound, err)
+	_, err = remote.Cache.Get(ctx, "foo")
+	assert.Equal(t, service.ErrKeyNotFound, err)
+
+	gets, _ = remote.counts()
+	assert.Equal(t, 2, gets)
+}
+
+func TestTieredCacheLocalExpiry(t *testing.T) {
+	remote := &countingCache{Cache: newMemCache(time.Minute, 0, 1, map[string]string{
+		"foo": "foo value",
+	})}
+	p := &mockCacheProv{caches: map[string]service.Cache{"remote": remote}}
+
+	c, err := newTieredCache("remote", p, nil, 10, time.Millisecond*50, 0, 0)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	_, err = c.Get(ctx, "foo")
+	require.NoError(t, err)
+	_, err = c.Get(ctx, "bar")
+	assert.Equal(t, service.ErrKeyNotFound, err)
+	_, err = c.Get(ctx, "bar")
+	assert.Equal(t, service.ErrKeyNotFound, err)
+
+	gets, _ := remote.counts()
+	assert.Equal(t, 3, gets, "missing keys should not be remembered")
+
+	require.NoError(t, remote.Cache.Set(ctx, "foo", []byte("new foo value"), nil))
+	val, err := c.Get(ctx, "foo")
+	require.NoError(t, err)
+	assert.Equal(t, "foo value", string(val))
+
+	<-time.After(time.Millisecond * 100)
+
+	val, err = c.Get(ctx, "foo")
+	require.NoError(t, err)
+	assert.Equal(t, "new foo value", string(val))
+}
+
+func TestTieredCacheAdd(t *testing.T) {
+	remote := newMemCache(time.Minute, 0, 1, map[string]string{
+		"foo": "foo value",
+	})
+	p := &mockCacheProv{caches: map[string]service.Cache{"remote": remote}}
+
+	c, err := newTieredCache("remote", p, nil, 10, time.Minute, time.Minute, 0)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	_, err = c.Get(ctx, "bar")
+	assert.Equal(t, service.ErrKeyNotFound, err)
+
+	assert.Equal(t, service.ErrKeyAlreadyExists, c.Add(ctx, "foo", []byte("nope"), nil))
+	require.NoError(t, c.Add(ctx, "bar", []byte("bar value"), nil))
+	assert.Equal(t, service.ErrKeyAlreadyExists, c.Add(ctx, "bar", []byte("nope"), nil))
+
+	val, err := c.Get(ctx, "bar")
+	require.NoError(t, err)
+	assert.Equal(t, "bar value", string(val))
+}
+
+func TestTieredCacheGetMulti(t *testing.T) {
+	remote := &countingCache{Cache: newMemCache(time.Minute, 0, 1, map[string]string{
+		"a": "a value",
+		"b": "b value",
+		"c": "c value",
+	})}
+	p := &mockCacheProv{caches: map[string]service.Cache{"remote": remote}}
+
+	c, err := newTieredCache("remote", p, nil, 10, time.Minute, time.Minute, 0)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	_, err = c.Get(ctx, "a")
+	require.NoError(t, err)
+
+	values, err := c.GetMulti(ctx, "a", "b", "c", "d")
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]byte{
+		"a": []byte("a value"),
+		"b": []byte("b value"),
+		"c": []byte("c value"),
+	}, values)
+
+	values, err = c.GetMulti(ctx, "b", "d")
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]byte{
+		"b": []byte("b value"),
+	}, values)
+
+	gets, multi := remote.counts()
+	assert.Equal(t, 1, gets)
+	assert.Equal(t, 1, multi)
+}
+
+func TestTieredCacheWriteBehind(t *testing.T) {
+	remote := newMemCache(time.Minute, 0, 1, map[string]string{
+		"foo": "foo value",
+	})
+	p := &mockCacheProv{caches: map[string]service.Cache{"remote": remote}}
+
+	c, err := newTieredCache("remote", p, nil, 10, time.Minute, time.Minute, 10)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	require.NoError(t, c.Set(ctx, "bar", []byte("bar value"), nil))
+	require.NoError(t, c.Delete(ctx, "foo"))
+
+	val, err := c.Get(ctx, "bar")
+	require.NoError(t, err)
+	assert.Equal(t, "bar value", string(val))
+
+	_, err = c.Get(ctx, "foo")
+	assert.Equal(t, service.ErrKeyNotFound, err)
+
+	require.NoError(t, c.Close(ctx))
+
+	val, err = remote.Get(ctx, "bar")
+	require.NoError(t, err)
+	assert.Equal(t, "bar value", string(val))
+
+	_, err = remote.Get(ctx, "foo")
+	assert.Equal(t, service.ErrKeyNotFound, err)
+
+	assert.Equal(t, service.ErrNotConnected, c.Set(ctx, "baz", []byte("baz value"), nil))
+}
+
+func TestTieredCacheConfig(t *testing.T) {
+	strmBuilder := service.NewStreamBuilder()
+	require.NoError(t, strmBuilder.SetYAML(`
+input:
+  generate:
+    count: 1
+    interval: ""
+    mapping: 'root = "hello world"'
+
+pipeline:
+  processors:
+    - cache:
+        resource: tiered
+        operator: set
+        key: foo
+        value: ${! content() }
+
+output:
+  drop: {}
+
+cache_resources:
+  - label: tiered
+    tiered:
+      resource: remote
+      local:
+        cap: 10
+      write_policy: behind
+
+  - label: remote
+    memory: {}
+
+logger:
+  level: NONE
+`))
+
+	strm, err := strmBuilder.Build()
+	require.NoError(t, err)
+
+	tCtx, done := context.WithTimeout(context.Background(), time.Second*30)
+	defer done()
+	require.NoError(t, strm.Run(tCtx))
+
+	strmBuilder = service.NewStreamBuilder()
+	require.NoError(t, strmBuilder.SetYAML(`
+output:
+  drop: {}
+
+cache_resources:
+  - label: tiered
+    tiered:
+      resource: nope
+`))
+	_, err = strmBuilder.Build()
+	require.ErrorContains(t, err, "cache resource 'nope' was not found")
+}
@@ -216,6 +216,12 @@ func (ca *ttlruCacheAdapter) Delete(_ context.Context, key string) error {
 	return nil
 }
 
+// Size returns the approximate number of items held and the number of bytes
+// occupied by their keys and values.
+func (ca *ttlruCacheAdapter) Size() (items, bytes int64) {
+	return lruSize(ca.inner.Keys(), ca.inner.Values())
+}
+
 func (ca *ttlruCacheAdapter) Close(_ context.Context) error {
 	return nil
 }
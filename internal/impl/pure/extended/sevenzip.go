@@ -0,0 +1,37 @@
+package extended
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/bodgit/sevenzip"
+
+	"github.com/benthosdev/benthos/v4/internal/impl/pure"
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+var _ = pure.AddKnownUnarchiveFormat("7z", func(part *service.Message, b []byte) (service.MessageBatch, error) {
+	zr, err := sevenzip.NewReader(bytes.NewReader(b), int64(len(b)))
+	if err != nil {
+		return nil, err
+	}
+
+	newParts := make(service.MessageBatch, 0, len(zr.File))
+	for _, f := range zr.File {
+		fr, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		fBytes, err := io.ReadAll(fr)
+		_ = fr.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		newPart := part.Copy()
+		newPart.SetBytes(fBytes)
+		pure.SetArchiveEntryMetadata(newPart, f.Name, f.Modified)
+		newParts = append(newParts, newPart)
+	}
+	return newParts, nil
+})
@@ -1,8 +1,10 @@
 package extended
 
 import (
+	"fmt"
 	"io"
 
+	"github.com/klauspost/compress/dict"
 	"github.com/klauspost/compress/zstd"
 
 	"github.com/benthosdev/benthos/v4/internal/impl/pure"
@@ -23,4 +25,53 @@ var _ = pure.AddKnownCompressionAlgorithm("zstd", pure.KnownCompressionAlgorithm
 		}
 		return &pure.CombinedReadCloser{Primary: ar, Source: r}, nil
 	},
+	CompressFuncWithOptions: func(opts pure.CompressionOptions) (pure.CompressFunc, error) {
+		eOpts := []zstd.EOption{zstd.WithEncoderLevel(zstd.EncoderLevelFromZstd(opts.Level))}
+		if len(opts.Dictionary) > 0 {
+			eOpts = append(eOpts, zstd.WithEncoderDict(opts.Dictionary))
+		}
+		if opts.WindowSize > 0 {
+			eOpts = append(eOpts, zstd.WithWindowSize(opts.WindowSize))
+		}
+
+		// A single encoder is shared as EncodeAll can be called concurrently.
+		enc, err := zstd.NewWriter(nil, eOpts...)
+		if err != nil {
+			return nil, err
+		}
+		return func(level int, b []byte) ([]byte, error) {
+			return enc.EncodeAll(b, nil), nil
+		}, nil
+	},
+	DecompressFuncWithOptions: func(opts pure.CompressionOptions) (pure.DecompressFunc, error) {
+		var dOpts []zstd.DOption
+		if len(opts.Dictionary) > 0 {
+			dOpts = append(dOpts, zstd.WithDecoderDicts(opts.Dictionary))
+		}
+		if opts.WindowSize > 0 {
+			dOpts = append(dOpts, zstd.WithDecoderMaxWindow(uint64(opts.WindowSize)))
+		}
+
+		// A single decoder is shared as DecodeAll can be called concurrently.
+		dec, err := zstd.NewReader(nil, dOpts...)
+		if err != nil {
+			return nil, err
+		}
+		return func(b []byte) ([]byte, error) {
+			return dec.DecodeAll(b, nil)
+		}, nil
+	},
+	TrainDictionary: func(samples [][]byte, maxSize int) (d []byte, err error) {
+		// The dictionary builder panics when the samples contain too few
+		// repetitions to build a dictionary from.
+		defer func() {
+			if r := recover(); r != nil {
+				err = fmt.Errorf("not enough samples: %v", r)
+			}
+		}()
+		return dict.BuildZstdDict(samples, dict.Options{
+			MaxDictSize: maxSize,
+			HashBytes:   6,
+		})
+	},
 })
@@ -1,11 +1,18 @@
 package extended
 
 import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/benthosdev/benthos/v4/internal/component/testutil"
+	"github.com/benthosdev/benthos/v4/internal/manager/mock"
+	"github.com/benthosdev/benthos/v4/internal/message"
 	"github.com/benthosdev/benthos/v4/public/bloblang"
 )
 
@@ -29,3 +36,76 @@ func TestZstdCompressionDecompression(t *testing.T) {
 
 	assert.Equal(t, input, decompressed)
 }
+
+func TestZstdDictionaryProcessors(t *testing.T) {
+	dir := t.TempDir()
+	for i := 0; i < 1000; i++ {
+		sample := fmt.Sprintf(`{"id":"%d","type":"page_view","user":{"name":"user%d","country":"GB"},"path":"/products/%d"}`, i, i, i*7)
+		require.NoError(t, os.WriteFile(filepath.Join(dir, fmt.Sprintf("%d.json", i)), []byte(sample), 0o644))
+	}
+	dictPath := filepath.Join(dir, "events.zdict")
+
+	conf, err := testutil.ProcessorFromYAML(fmt.Sprintf(`
+compress:
+  algorithm: zstd
+  dictionary:
+    train_from: [ '%v' ]
+    output_path: '%v'
+`, filepath.Join(dir, "*.json"), dictPath))
+	require.NoError(t, err)
+
+	comp, err := mock.NewManager().NewProcessor(conf)
+	require.NoError(t, err)
+
+	input := `{"id":"1000","type":"page_view","user":{"name":"user1000","country":"GB"},"path":"/products/7000"}`
+	msgs, res := comp.ProcessBatch(context.Background(), message.QuickBatch([][]byte{[]byte(input)}))
+	require.NoError(t, res)
+	require.Len(t, msgs, 1)
+	compressed := msgs[0].Get(0).AsBytes()
+	assert.Less(t, len(compressed), len(input)/2)
+
+	conf, err = testutil.ProcessorFromYAML(fmt.Sprintf(`
+decompress:
+  algorithm: zstd
+  dictionary:
+    path: '%v'
+`, dictPath))
+	require.NoError(t, err)
+
+	decomp, err := mock.NewManager().NewProcessor(conf)
+	require.NoError(t, err)
+
+	msgs, res = decomp.ProcessBatch(context.Background(), message.QuickBatch([][]byte{compressed}))
+	require.NoError(t, res)
+	require.Len(t, msgs, 1)
+	assert.Equal(t, input, string(msgs[0].Get(0).AsBytes()))
+
+	// Messages compressed with a dictionary cannot be decompressed without it.
+	exec, err := bloblang.Parse(`root = this.decompress(algorithm: "zstd")`)
+	require.NoError(t, err)
+
+	_, err = exec.Query(compressed)
+	require.Error(t, err)
+}
+
+func TestZstdWindowSize(t *testing.T) {
+	conf, err := testutil.ProcessorFromYAML(`
+compress:
+  algorithm: zstd
+  window_size: 3000
+`)
+	require.NoError(t, err)
+
+	_, err = mock.NewManager().NewProcessor(conf)
+	require.ErrorContains(t, err, "window size must be a power of 2")
+
+	conf, err = testutil.ProcessorFromYAML(`
+compress:
+  algorithm: gzip
+  window_size: 1024
+`)
+	require.NoError(t, err)
+
+	_, err = mock.NewManager().NewProcessor(conf)
+	require.ErrorContains(t, err, "compression type gzip does not support dictionaries or window sizes")
+}
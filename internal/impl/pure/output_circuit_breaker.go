@@ -0,0 +1,269 @@
+package pure
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	cboFieldOutput         = "output"
+	cboFieldErrorThreshold = "error_threshold"
+	cboFieldMinRequests    = "min_requests"
+	cboFieldWindow         = "window"
+	cboFieldOpenDuration   = "open_duration"
+	cboFieldHalfOpenProbes = "half_open_probes"
+)
+
+func circuitBreakerOutputSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Categories("Utility").
+		Beta().
+		Version("4.28.0").
+		Summary("Writes messages to a child output through a circuit breaker, which stops attempting writes once the rate of errors crosses a threshold and rejects messages immediately until the output recovers.").
+		Description(`
+The circuit breaker starts closed, where writes are passed to the child output. The number of writes and failed writes are counted over each `+"`window`"+`, and when at least `+"`min_requests`"+` writes have been made and the proportion of them that failed reaches `+"`error_threshold`"+` the breaker trips open.
+
+While open all writes are rejected immediately without reaching the child output. Once `+"`open_duration`"+` has passed the breaker becomes half-open, where up to `+"`half_open_probes`"+` writes are allowed through as probes. If all probes succeed the breaker closes again, and if any fail it trips open once more.
+
+### Fallback
+
+This output is most useful as a tier of a `+"[`fallback`](/docs/components/outputs/fallback)"+` output. Without a circuit breaker each message is attempted against a failing primary output before moving to the next tier, which delays every message and continues to load an output that is already struggling. With a circuit breaker the fallback moves messages to the next tier immediately whilst open, and only probes the primary periodically until it recovers.
+
+### Metrics
+
+The state of the breaker is exposed with the gauge `+"`circuit_breaker_state`"+`, where 0 is closed, 1 is half-open and 2 is open, and the number of times the breaker has tripped open is counted with `+"`circuit_breaker_trips`"+`.`).
+		Fields(
+			service.NewOutputField(cboFieldOutput).
+				Description("The child output to write messages to."),
+			service.NewFloatField(cboFieldErrorThreshold).
+				Description("The proportion of failed writes within a window, from 0 to 1, at which the breaker trips open.").
+				Default(0.5),
+			service.NewIntField(cboFieldMinRequests).
+				Description("The minimum number of writes within a window before the breaker is able to trip open.").
+				Default(10),
+			service.NewDurationField(cboFieldWindow).
+				Description("The period over which writes are counted.").
+				Default("30s"),
+			service.NewDurationField(cboFieldOpenDuration).
+				Description("The period to wait whilst open before probing the child output.").
+				Default("10s"),
+			service.NewIntField(cboFieldHalfOpenProbes).
+				Description("The number of probe writes that must succeed whilst half-open in order to close the breaker.").
+				Advanced().
+				Default(1),
+			service.NewOutputMaxInFlightField(),
+		).
+		Example("Failover", "Write messages to an HTTP endpoint, failing over to a Kafka topic without waiting on the endpoint whenever most of its requests are failing.", `
+output:
+  fallback:
+    - circuit_breaker:
+        output:
+          http_client:
+            url: http://primary:4195/post
+            retries: 0
+        error_threshold: 0.5
+        min_requests: 20
+        open_duration: 30s
+    - kafka_franz:
+        seed_brokers: [ localhost:9092 ]
+        topic: failover
+`)
+}
+
+func init() {
+	err := service.RegisterBatchOutput(
+		"circuit_breaker", circuitBreakerOutputSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (out service.BatchOutput, batchPolicy service.BatchPolicy, maxInFlight int, err error) {
+			if maxInFlight, err = conf.FieldMaxInFlight(); err != nil {
+				return
+			}
+			out, err = newCircuitBreakerOutputFromParsed(conf, mgr)
+			return
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// ErrCircuitOpen is returned by the circuit_breaker output when a write is
+// rejected without being attempted.
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+type circuitState int64
+
+const (
+	circuitClosed circuitState = iota
+	circuitHalfOpen
+	circuitOpen
+)
+
+type circuitBreakerWriter interface {
+	WriteBatch(ctx context.Context, b service.MessageBatch) error
+	Close(ctx context.Context) error
+}
+
+type circuitBreakerOutput struct {
+	output circuitBreakerWriter
+
+	errorThreshold float64
+	minRequests    int
+	window         time.Duration
+	openDuration   time.Duration
+	halfOpenProbes int
+
+	mState *service.MetricGauge
+	mTrips *service.MetricCounter
+	nowFn  func() time.Time
+
+	mut            sync.Mutex
+	state          circuitState
+	windowStart    time.Time
+	requests       int
+	failures       int
+	openedAt       time.Time
+	probesInFlight int
+	probeSuccesses int
+}
+
+func newCircuitBreakerOutputFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*circuitBreakerOutput, error) {
+	c := &circuitBreakerOutput{
+		mState: mgr.Metrics().NewGauge("circuit_breaker_state"),
+		mTrips: mgr.Metrics().NewCounter("circuit_breaker_trips"),
+		nowFn:  time.Now,
+	}
+
+	var err error
+	if c.output, err = conf.FieldOutput(cboFieldOutput); err != nil {
+		return nil, err
+	}
+	if c.errorThreshold, err = conf.FieldFloat(cboFieldErrorThreshold); err != nil {
+		return nil, err
+	}
+	if c.errorThreshold <= 0 || c.errorThreshold > 1 {
+		return nil, errors.New("error_threshold must be greater than 0 and no more than 1")
+	}
+	if c.minRequests, err = conf.FieldInt(cboFieldMinRequests); err != nil {
+		return nil, err
+	}
+	if c.window, err = conf.FieldDuration(cboFieldWindow); err != nil {
+		return nil, err
+	}
+	if c.openDuration, err = conf.FieldDuration(cboFieldOpenDuration); err != nil {
+		return nil, err
+	}
+	if c.halfOpenProbes, err = conf.FieldInt(cboFieldHalfOpenProbes); err != nil {
+		return nil, err
+	}
+	if c.halfOpenProbes < 1 {
+		return nil, errors.New("half_open_probes must be at least 1")
+	}
+
+	c.windowStart = c.nowFn()
+	c.mState.Set(int64(circuitClosed))
+	return c, nil
+}
+
+func (c *circuitBreakerOutput) Connect(ctx context.Context) error {
+	return nil
+}
+
+func (c *circuitBreakerOutput) setStateLocked(s circuitState) {
+	now := c.nowFn()
+	switch s {
+	case circuitOpen:
+		c.openedAt = now
+		c.mTrips.Incr(1)
+	case circuitHalfOpen:
+		c.probesInFlight, c.probeSuccesses = 0, 0
+	case circuitClosed:
+		c.windowStart, c.requests, c.failures = now, 0, 0
+	}
+	c.state = s
+	c.mState.Set(int64(s))
+}
+
+// allow returns whether a write should be attempted, and whether the write is
+// a half-open probe.
+func (c *circuitBreakerOutput) allow() (allowed, probe bool) {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	if c.state == circuitOpen && c.nowFn().Sub(c.openedAt) >= c.openDuration {
+		c.setStateLocked(circuitHalfOpen)
+	}
+
+	switch c.state {
+	case circuitClosed:
+		return true, false
+	case circuitHalfOpen:
+		if c.probesInFlight+c.probeSuccesses < c.halfOpenProbes {
+			c.probesInFlight++
+			return true, true
+		}
+	}
+	return false, false
+}
+
+func (c *circuitBreakerOutput) record(probe bool, failed bool) {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	if probe {
+		if c.state != circuitHalfOpen {
+			return
+		}
+		c.probesInFlight--
+		if failed {
+			c.setStateLocked(circuitOpen)
+		} else if c.probeSuccesses++; c.probeSuccesses >= c.halfOpenProbes {
+			c.setStateLocked(circuitClosed)
+		}
+		return
+	}
+
+	if c.state != circuitClosed {
+		return
+	}
+	if now := c.nowFn(); now.Sub(c.windowStart) >= c.window {
+		c.windowStart, c.requests, c.failures = now, 0, 0
+	}
+	c.requests++
+	if failed {
+		c.failures++
+	}
+	if c.requests >= c.minRequests && float64(c.failures)/float64(c.requests) >= c.errorThreshold {
+		c.setStateLocked(circuitOpen)
+	}
+}
+
+func (c *circuitBreakerOutput) WriteBatch(ctx context.Context, batch service.MessageBatch) error {
+	allowed, probe := c.allow()
+	if !allowed {
+		return ErrCircuitOpen
+	}
+
+	err := c.output.WriteBatch(ctx, batch)
+	if err != nil && ctx.Err() != nil {
+		// Cancellations are not a reflection of the health of the output.
+		if probe {
+			c.mut.Lock()
+			if c.state == circuitHalfOpen && c.probesInFlight > 0 {
+				c.probesInFlight--
+			}
+			c.mut.Unlock()
+		}
+		return err
+	}
+	c.record(probe, err != nil)
+	return err
+}
+
+func (c *circuitBreakerOutput) Close(ctx context.Context) error {
+	return c.output.Close(ctx)
+}
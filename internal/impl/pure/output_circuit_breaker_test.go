@@ -0,0 +1,111 @@
+package pure
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func testCircuitBreaker(output *fakeDeadLetterWriter, now *time.Time) *circuitBreakerOutput {
+	mgr := service.MockResources()
+	return &circuitBreakerOutput{
+		output:         output,
+		errorThreshold: 0.5,
+		minRequests:    4,
+		window:         time.Minute,
+		openDuration:   10 * time.Second,
+		halfOpenProbes: 2,
+		mState:         mgr.Metrics().NewGauge("circuit_breaker_state"),
+		mTrips:         mgr.Metrics().NewCounter("circuit_breaker_trips"),
+		nowFn: func() time.Time {
+			return *now
+		},
+		windowStart: *now,
+	}
+}
+
+func TestCircuitBreakerTripsAndRecovers(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	failing := true
+	output := &fakeDeadLetterWriter{
+		writeFn: func(b service.MessageBatch) error {
+			if failing {
+				return errors.New("nope")
+			}
+			return nil
+		},
+	}
+
+	c := testCircuitBreaker(output, &now)
+	write := func() error {
+		return c.WriteBatch(context.Background(), service.MessageBatch{
+			service.NewMessage([]byte("hello")),
+		})
+	}
+
+	// Below the minimum number of requests the breaker remains closed.
+	failing = false
+	require.NoError(t, write())
+	failing = true
+	for i := 0; i < 2; i++ {
+		require.EqualError(t, write(), "nope")
+	}
+	assert.Equal(t, circuitClosed, c.state)
+
+	// The fourth request reaches the threshold.
+	require.EqualError(t, write(), "nope")
+	assert.Equal(t, circuitOpen, c.state)
+	assert.Len(t, output.contents(), 4)
+
+	// Whilst open writes are rejected without reaching the output.
+	require.ErrorIs(t, write(), ErrCircuitOpen)
+	assert.Len(t, output.contents(), 4)
+
+	// After the open duration a failed probe trips the breaker again.
+	now = now.Add(10 * time.Second)
+	require.EqualError(t, write(), "nope")
+	assert.Equal(t, circuitOpen, c.state)
+	require.ErrorIs(t, write(), ErrCircuitOpen)
+	assert.Len(t, output.contents(), 5)
+
+	// Successful probes close the breaker.
+	now = now.Add(10 * time.Second)
+	failing = false
+	require.NoError(t, write())
+	assert.Equal(t, circuitHalfOpen, c.state)
+	require.NoError(t, write())
+	assert.Equal(t, circuitClosed, c.state)
+	require.NoError(t, write())
+	assert.Len(t, output.contents(), 8)
+}
+
+func TestCircuitBreakerWindow(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	output := &fakeDeadLetterWriter{
+		writeFn: func(b service.MessageBatch) error {
+			return errors.New("nope")
+		},
+	}
+
+	c := testCircuitBreaker(output, &now)
+	for i := 0; i < 3; i++ {
+		require.Error(t, c.WriteBatch(context.Background(), service.MessageBatch{
+			service.NewMessage([]byte("hello")),
+		}))
+	}
+
+	// Failures of a previous window are forgotten.
+	now = now.Add(time.Minute)
+	for i := 0; i < 3; i++ {
+		require.Error(t, c.WriteBatch(context.Background(), service.MessageBatch{
+			service.NewMessage([]byte("hello")),
+		}))
+	}
+	assert.Equal(t, circuitClosed, c.state)
+}
@@ -0,0 +1,188 @@
+package pure
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	dloFieldOutput = "output"
+	dloFieldDLQ    = "dlq"
+)
+
+func deadLetterOutputSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Categories("Utility").
+		Beta().
+		Version("4.28.0").
+		Summary("Attempts to write messages to a child output, retrying failed messages, and routes messages that still fail once retries are exhausted to a dead letter queue output.").
+		Description(`
+Messages that cannot be delivered to an output are normally retried indefinitely, which means a single message that can never be delivered (a poison message) can prevent a pipeline from making progress. This output retries failed messages according to the `+"`max_retries` and `backoff`"+` fields, and once these are exhausted the messages are written to the `+"`dlq`"+` output instead, allowing the pipeline to continue.
+
+When the child output reports which messages of a batch failed only those messages are retried and routed to the dead letter queue. If the `+"`dlq`"+` output also fails then the error is returned and the messages are retried from the beginning, ensuring that messages are never dropped.
+
+Setting both `+"`max_retries` and `backoff.max_elapsed_time`"+` to zero means messages are retried indefinitely and are never routed to the dead letter queue.
+
+### Metadata
+
+Messages routed to the dead letter queue have the following metadata fields added:
+
+- `+"`dlq_error`"+`: The error returned by the last attempt at writing the message.
+- `+"`dlq_attempts`"+`: The number of attempts made to write the message.
+- `+"`dlq_failed_at`"+`: The time at which the message was routed to the dead letter queue in RFC 3339 format.`).
+		Fields(
+			service.NewOutputField(dloFieldOutput).
+				Description("The child output to write messages to."),
+			service.NewOutputField(dloFieldDLQ).
+				Description("The output to route messages to once writing them to the child output has failed permanently."),
+			service.NewOutputMaxInFlightField(),
+		).
+		Fields(CommonRetryBackOffFields(3, "500ms", "10s", "0s")...).
+		Example("Poison Messages", "Write messages to Kafka, routing messages that fail three times to a file along with the reason they failed.", `
+output:
+  dead_letter:
+    output:
+      kafka_franz:
+        seed_brokers: [ localhost:9092 ]
+        topic: events
+    dlq:
+      file:
+        path: ./dlq.jsonl
+        codec: lines
+      processors:
+        - mapping: |
+            root.content = content().string()
+            root.error = @dlq_error
+            root.attempts = @dlq_attempts
+    max_retries: 3
+`)
+}
+
+func init() {
+	err := service.RegisterBatchOutput(
+		"dead_letter", deadLetterOutputSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (out service.BatchOutput, batchPolicy service.BatchPolicy, maxInFlight int, err error) {
+			if maxInFlight, err = conf.FieldMaxInFlight(); err != nil {
+				return
+			}
+			out, err = newDeadLetterOutputFromParsed(conf)
+			return
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type deadLetterWriter interface {
+	WriteBatch(ctx context.Context, b service.MessageBatch) error
+	Close(ctx context.Context) error
+}
+
+type deadLetterOutput struct {
+	output      deadLetterWriter
+	dlq         deadLetterWriter
+	backoffCtor func() backoff.BackOff
+	nowFn       func() time.Time
+}
+
+func newDeadLetterOutputFromParsed(conf *service.ParsedConfig) (*deadLetterOutput, error) {
+	d := &deadLetterOutput{nowFn: time.Now}
+
+	var err error
+	if d.output, err = conf.FieldOutput(dloFieldOutput); err != nil {
+		return nil, err
+	}
+	if d.dlq, err = conf.FieldOutput(dloFieldDLQ); err != nil {
+		return nil, err
+	}
+	if d.backoffCtor, err = CommonRetryBackOffCtorFromParsed(conf); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func (d *deadLetterOutput) Connect(ctx context.Context) error {
+	return nil
+}
+
+// failedMessages returns the messages of a batch that failed according to an
+// error, along with the error of each, which is all of them unless the error
+// identifies individual messages. The indexer must be created from the batch
+// before it was written.
+func failedMessages(batch service.MessageBatch, idx *service.Indexer, err error) (service.MessageBatch, []error) {
+	var bErr *service.BatchError
+	if len(batch) <= 1 || !errors.As(err, &bErr) {
+		errs := make([]error, len(batch))
+		for i := range errs {
+			errs[i] = err
+		}
+		return batch, errs
+	}
+
+	var failed service.MessageBatch
+	var errs []error
+	bErr.WalkMessagesIndexedBy(idx, func(_ int, m *service.Message, mErr error) bool {
+		if mErr != nil {
+			failed = append(failed, m)
+			errs = append(errs, mErr)
+		}
+		return true
+	})
+	return failed, errs
+}
+
+func (d *deadLetterOutput) WriteBatch(ctx context.Context, batch service.MessageBatch) error {
+	boff := d.backoffCtor()
+
+	pending := batch
+	for attempts := 1; ; attempts++ {
+		idx := pending.Index()
+		err := d.output.WriteBatch(ctx, pending)
+		if err == nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		var errs []error
+		if pending, errs = failedMessages(pending, idx, err); len(pending) == 0 {
+			return nil
+		}
+
+		wait := boff.NextBackOff()
+		if wait == backoff.Stop {
+			return d.writeDLQ(ctx, pending, errs, attempts)
+		}
+
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (d *deadLetterOutput) writeDLQ(ctx context.Context, batch service.MessageBatch, errs []error, attempts int) error {
+	failedAt := d.nowFn().Format(time.RFC3339Nano)
+
+	dlqBatch := make(service.MessageBatch, len(batch))
+	for i, m := range batch {
+		dlqBatch[i] = m.Copy()
+		dlqBatch[i].MetaSetMut("dlq_error", errs[i].Error())
+		dlqBatch[i].MetaSetMut("dlq_attempts", attempts)
+		dlqBatch[i].MetaSetMut("dlq_failed_at", failedAt)
+	}
+	return d.dlq.WriteBatch(ctx, dlqBatch)
+}
+
+func (d *deadLetterOutput) Close(ctx context.Context) error {
+	return errors.Join(d.output.Close(ctx), d.dlq.Close(ctx))
+}
@@ -0,0 +1,155 @@
+package pure
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+type fakeDeadLetterWriter struct {
+	mut     sync.Mutex
+	batches []service.MessageBatch
+	writeFn func(b service.MessageBatch) error
+}
+
+func (f *fakeDeadLetterWriter) WriteBatch(ctx context.Context, b service.MessageBatch) error {
+	f.mut.Lock()
+	defer f.mut.Unlock()
+
+	f.batches = append(f.batches, b)
+	if f.writeFn != nil {
+		return f.writeFn(b)
+	}
+	return nil
+}
+
+func (f *fakeDeadLetterWriter) Close(ctx context.Context) error {
+	return nil
+}
+
+func (f *fakeDeadLetterWriter) contents() [][]string {
+	f.mut.Lock()
+	defer f.mut.Unlock()
+
+	var res [][]string
+	for _, b := range f.batches {
+		var strs []string
+		for _, m := range b {
+			mBytes, _ := m.AsBytes()
+			strs = append(strs, string(mBytes))
+		}
+		res = append(res, strs)
+	}
+	return res
+}
+
+func testDeadLetterOutput(output, dlq *fakeDeadLetterWriter, maxRetries uint64) *deadLetterOutput {
+	return &deadLetterOutput{
+		output: output,
+		dlq:    dlq,
+		backoffCtor: func() backoff.BackOff {
+			return backoff.WithMaxRetries(&backoff.ZeroBackOff{}, maxRetries)
+		},
+		nowFn: func() time.Time {
+			return time.Unix(1700000000, 0).UTC()
+		},
+	}
+}
+
+func TestDeadLetterPartialFailures(t *testing.T) {
+	output := &fakeDeadLetterWriter{}
+	output.writeFn = func(b service.MessageBatch) error {
+		var bErr *service.BatchError
+		for i, m := range b {
+			if mBytes, _ := m.AsBytes(); string(mBytes) == "poison" {
+				err := errors.New("bad message")
+				if bErr == nil {
+					bErr = service.NewBatchError(b, err)
+				}
+				bErr = bErr.Failed(i, err)
+			}
+		}
+		if bErr != nil {
+			return bErr
+		}
+		return nil
+	}
+	dlq := &fakeDeadLetterWriter{}
+
+	d := testDeadLetterOutput(output, dlq, 2)
+	require.NoError(t, d.WriteBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte("foo")),
+		service.NewMessage([]byte("poison")),
+		service.NewMessage([]byte("bar")),
+	}))
+
+	assert.Equal(t, [][]string{
+		{"foo", "poison", "bar"},
+		{"poison"},
+		{"poison"},
+	}, output.contents())
+	assert.Equal(t, [][]string{{"poison"}}, dlq.contents())
+
+	m := dlq.batches[0][0]
+	v, _ := m.MetaGetMut("dlq_error")
+	assert.Equal(t, "bad message", v)
+	v, _ = m.MetaGetMut("dlq_attempts")
+	assert.Equal(t, 3, v)
+	v, _ = m.MetaGetMut("dlq_failed_at")
+	assert.Equal(t, "2023-11-14T22:13:20Z", v)
+}
+
+func TestDeadLetterRecovers(t *testing.T) {
+	var attempts int
+	output := &fakeDeadLetterWriter{}
+	output.writeFn = func(b service.MessageBatch) error {
+		if attempts++; attempts < 3 {
+			return errors.New("not yet")
+		}
+		return nil
+	}
+	dlq := &fakeDeadLetterWriter{}
+
+	d := testDeadLetterOutput(output, dlq, 5)
+	require.NoError(t, d.WriteBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte("foo")),
+		service.NewMessage([]byte("bar")),
+	}))
+
+	assert.Len(t, output.contents(), 3)
+	assert.Empty(t, dlq.contents())
+}
+
+func TestDeadLetterDLQFailure(t *testing.T) {
+	output := &fakeDeadLetterWriter{
+		writeFn: func(b service.MessageBatch) error {
+			return errors.New("nope")
+		},
+	}
+	dlq := &fakeDeadLetterWriter{
+		writeFn: func(b service.MessageBatch) error {
+			return errors.New("dlq also nope")
+		},
+	}
+
+	d := testDeadLetterOutput(output, dlq, 0)
+	err := d.WriteBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte("foo")),
+	})
+	require.EqualError(t, err, "dlq also nope")
+
+	assert.Equal(t, [][]string{{"foo"}}, output.contents())
+	assert.Equal(t, [][]string{{"foo"}}, dlq.contents())
+
+	// The original message must not be modified.
+	_, exists := output.batches[0][0].MetaGetMut("dlq_error")
+	assert.False(t, exists)
+}
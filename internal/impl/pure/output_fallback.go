@@ -41,6 +41,24 @@ output:
         path: /usr/local/benthos/everything_failed.jsonl
 `+"```"+`
 
+### Circuit Breakers
+
+By default every message is attempted against each output in turn, and so when an output is failing consistently each message is delayed by a failed attempt before moving to the next tier. Wrapping an output within a `+"[`circuit_breaker`](/docs/components/outputs/circuit_breaker)"+` output stops attempts against that output once its rate of errors crosses a threshold, moving messages straight to the next tier and only probing the output periodically until it recovers:
+
+`+"```yaml"+`
+output:
+  fallback:
+    - circuit_breaker:
+        output:
+          http_client:
+            url: http://foo:4195/post/might/become/unreachable
+            retries: 0
+        error_threshold: 0.5
+        open_duration: 30s
+    - file:
+        path: /usr/local/benthos/everything_failed.jsonl
+`+"```"+`
+
 ### Metadata
 
 When a given output fails the message routed to the following output will have a metadata value named `+"`fallback_error`"+` containing a string error message outlining the cause of the failure. The content of this string will depend on the particular output and can be used to enrich the message or provide information used to broker the data to an appropriate output using something like a `+"`switch`"+` output.
@@ -5,6 +5,9 @@ import (
 	"archive/zip"
 	"bytes"
 	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"time"
@@ -22,7 +25,9 @@ func archiveProcConfig() *service.ConfigSpec {
 		Description(`
 Some archive formats (such as tar, zip) treat each archive item (message part) as a file with a path. Since message parts only contain raw data a unique path must be generated for each part. This can be done by using function interpolations on the 'path' field as described [here](/docs/configuration/interpolation#bloblang-queries). For types that aren't file based (such as binary) the file field is ignored.
 
-The resulting archived message adopts the metadata of the _first_ message part of the batch.
+The resulting archived message adopts the metadata of the _first_ message part of the batch. For the tar and zip formats the metadata of each message can also be preserved within the archive by enabling `+"`preserve_metadata`"+`, in which case it is restored when the archive is extracted with the `+"[`unarchive` processor](/docs/components/processors/unarchive)"+`. Tar archives store metadata as PAX records prefixed with `+"`"+archivePAXMetaPrefix+"`"+`, and zip archives store metadata as a JSON object within an extra field of each file.
+
+Archives can be compressed by setting `+"`compression`"+`, such as to `+"`zstd`"+` for creating `+"`.tar.zst`"+` files.
 
 The functionality of this processor depends on being applied across messages that are batched. You can find out more about batching [in this doc](/docs/configuration/batching).`).
 		Field(service.NewStringAnnotatedEnumField("format", map[string]string{
@@ -38,6 +43,16 @@ The functionality of this processor depends on being applied across messages tha
 			Example("${!count(\"files\")}-${!timestamp_unix_nano()}.txt").
 			Example("${!meta(\"kafka_key\")}-${!json(\"id\")}.json").
 			Default("")).
+		Field(service.NewBoolField("preserve_metadata").
+			Description("Whether to store the metadata of each message within the archive, which is only supported by the `tar` and `zip` formats.").
+			Version("4.28.0").
+			Default(false)).
+		Field(service.NewStringField("compression").
+			Description("An optional compression algorithm to apply to the archive. Supported algorithms are the same as those of the `compress` processor.").
+			Example("zstd").
+			Example("gzip").
+			Version("4.28.0").
+			Optional()).
 		Example("Tar Archive", `
 If we had JSON messages in a batch each of the form:
 
@@ -52,9 +67,59 @@ pipeline:
     - archive:
         format: tar
         path: ${!json("doc.id")}.json
+`).
+		Example("Compressed Tar Archive", "Here we create zstd compressed tar archives of each batch, where the metadata of each message is preserved.", `
+pipeline:
+  processors:
+    - archive:
+        format: tar
+        path: ${!json("doc.id")}.json
+        preserve_metadata: true
+        compression: zstd
 `)
 }
 
+// archivePAXMetaPrefix is the prefix of the PAX records of tar headers that
+// contain the metadata of archived messages.
+const archivePAXMetaPrefix = "BENTHOS.metadata."
+
+// zipMetadataExtraID is the header ID of the extra field of zip files that
+// contains the metadata of archived messages.
+const zipMetadataExtraID = 0x4d42
+
+func zipMetadataExtra(meta map[string]string) ([]byte, error) {
+	mBytes, err := json.Marshal(meta)
+	if err != nil {
+		return nil, err
+	}
+	if len(mBytes) > 0xffff {
+		return nil, errors.New("metadata exceeds the maximum size of a zip extra field")
+	}
+	extra := make([]byte, 4, 4+len(mBytes))
+	binary.LittleEndian.PutUint16(extra[0:2], zipMetadataExtraID)
+	binary.LittleEndian.PutUint16(extra[2:4], uint16(len(mBytes)))
+	return append(extra, mBytes...), nil
+}
+
+func zipMetadataFromExtra(extra []byte) (map[string]string, error) {
+	for len(extra) >= 4 {
+		id := binary.LittleEndian.Uint16(extra[0:2])
+		size := int(binary.LittleEndian.Uint16(extra[2:4]))
+		if len(extra)-4 < size {
+			break
+		}
+		if id == zipMetadataExtraID {
+			var meta map[string]string
+			if err := json.Unmarshal(extra[4:4+size], &meta); err != nil {
+				return nil, err
+			}
+			return meta, nil
+		}
+		extra = extra[4+size:]
+	}
+	return nil, nil
+}
+
 func init() {
 	err := service.RegisterBatchProcessor(
 		"archive", archiveProcConfig(),
@@ -70,17 +135,24 @@ func init() {
 
 type archiveFunc func(hFunc headerFunc, msg service.MessageBatch) (*service.Message, error)
 
-type headerFunc func(index int, body *service.Message) os.FileInfo
+type headerFunc func(index int, body *service.Message) fakeInfo
 
 func tarArchive(hFunc headerFunc, msg service.MessageBatch) (*service.Message, error) {
 	buf := &bytes.Buffer{}
 	tw := tar.NewWriter(buf)
 
 	for i, part := range msg {
-		hdr, err := tar.FileInfoHeader(hFunc(i, part), "")
+		info := hFunc(i, part)
+		hdr, err := tar.FileInfoHeader(info, "")
 		if err != nil {
 			return nil, err
 		}
+		if len(info.meta) > 0 {
+			hdr.PAXRecords = make(map[string]string, len(info.meta))
+			for k, v := range info.meta {
+				hdr.PAXRecords[archivePAXMetaPrefix+k] = v
+			}
+		}
 		if err := tw.WriteHeader(hdr); err != nil {
 			return nil, err
 		}
@@ -103,11 +175,17 @@ func zipArchive(hFunc headerFunc, msg service.MessageBatch) (*service.Message, e
 	zw := zip.NewWriter(buf)
 
 	for i, part := range msg {
-		h, err := zip.FileInfoHeader(hFunc(i, part))
+		info := hFunc(i, part)
+		h, err := zip.FileInfoHeader(info)
 		if err != nil {
 			return nil, err
 		}
 		h.Method = zip.Deflate
+		if len(info.meta) > 0 {
+			if h.Extra, err = zipMetadataExtra(info.meta); err != nil {
+				return nil, err
+			}
+		}
 
 		w, err := zw.CreateHeader(h)
 		if err != nil {
@@ -202,9 +280,11 @@ func strToArchiver(str string) (archiveFunc, error) {
 //------------------------------------------------------------------------------
 
 type archive struct {
-	archive archiveFunc
-	path    *service.InterpolatedString
-	log     *service.Logger
+	archive      archiveFunc
+	path         *service.InterpolatedString
+	preserveMeta bool
+	compress     CompressFunc
+	log          *service.Logger
 }
 
 func newArchiveFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*archive, error) {
@@ -216,7 +296,26 @@ func newArchiveFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*
 	if err != nil {
 		return nil, err
 	}
-	return newArchive(mgr, formatStr, pathStr)
+	a, err := newArchive(mgr, formatStr, pathStr)
+	if err != nil {
+		return nil, err
+	}
+	if a.preserveMeta, err = conf.FieldBool("preserve_metadata"); err != nil {
+		return nil, err
+	}
+	if a.preserveMeta && formatStr != "tar" && formatStr != "zip" {
+		return nil, fmt.Errorf("preserve_metadata is not supported by the %v format", formatStr)
+	}
+	if conf.Contains("compression") {
+		algStr, err := conf.FieldString("compression")
+		if err != nil {
+			return nil, err
+		}
+		if a.compress, err = strToCompressFunc(algStr); err != nil {
+			return nil, err
+		}
+	}
+	return a, nil
 }
 
 func newArchive(nm *service.Resources, format string, path *service.InterpolatedString) (*archive, error) {
@@ -237,6 +336,7 @@ type fakeInfo struct {
 	name string
 	size int64
 	mode os.FileMode
+	meta map[string]string
 }
 
 func (f fakeInfo) Name() string {
@@ -263,18 +363,26 @@ func (f fakeInfo) Sys() any {
 	return nil
 }
 
-func (d *archive) createHeaderFunc(msg service.MessageBatch) func(int, *service.Message) os.FileInfo {
-	return func(index int, body *service.Message) os.FileInfo {
+func (d *archive) createHeaderFunc(msg service.MessageBatch) headerFunc {
+	return func(index int, body *service.Message) fakeInfo {
 		bBytes, _ := body.AsBytes()
 		name, err := msg.TryInterpolatedString(index, d.path)
 		if err != nil {
 			d.log.Errorf("Name interpolation error: %w", err)
 		}
-		return fakeInfo{
+		info := fakeInfo{
 			name: name,
 			size: int64(len(bBytes)),
 			mode: 0o666,
 		}
+		if d.preserveMeta {
+			info.meta = map[string]string{}
+			_ = body.MetaWalk(func(k, v string) error {
+				info.meta[k] = v
+				return nil
+			})
+		}
+		return info
 	}
 }
 
@@ -291,6 +399,18 @@ func (d *archive) ProcessBatch(ctx context.Context, msg service.MessageBatch) ([
 		return nil, err
 	}
 
+	if d.compress != nil {
+		aBytes, err := newPart.AsBytes()
+		if err != nil {
+			return nil, err
+		}
+		if aBytes, err = d.compress(-1, aBytes); err != nil {
+			d.log.Errorf("Failed to compress archive: %v\n", err)
+			return nil, err
+		}
+		newPart.SetBytes(aBytes)
+	}
+
 	newPart = newPart.WithContext(batch.CtxWithCollapsedCount(newPart.Context(), len(msg)))
 	return []service.MessageBatch{{newPart}}, nil
 }
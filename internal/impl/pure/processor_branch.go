@@ -15,6 +15,7 @@ import (
 	"github.com/benthosdev/benthos/v4/internal/component/interop"
 	"github.com/benthosdev/benthos/v4/internal/component/metrics"
 	"github.com/benthosdev/benthos/v4/internal/component/processor"
+	"github.com/benthosdev/benthos/v4/internal/component/ratelimit"
 	"github.com/benthosdev/benthos/v4/internal/log"
 	"github.com/benthosdev/benthos/v4/internal/message"
 	"github.com/benthosdev/benthos/v4/internal/tracing"
@@ -25,6 +26,9 @@ const (
 	branchProcFieldReqMap = "request_map"
 	branchProcFieldProcs  = "processors"
 	branchProcFieldResMap = "result_map"
+
+	branchProcFieldRateLimit        = "rate_limit"
+	branchProcFieldRateLimitMaxWait = "rate_limit_max_wait"
 )
 
 func branchProcSpec() *service.ConfigSpec {
@@ -45,7 +49,11 @@ If the `+"`request_map`"+` fails the child processors will not be executed. If t
 
 ### Conditional Branching
 
-If the root of your request map is set to `+"`deleted()`"+` then the branch processors are skipped for the given message, this allows you to conditionally branch messages.`).
+If the root of your request map is set to `+"`deleted()`"+` then the branch processors are skipped for the given message, this allows you to conditionally branch messages.
+
+### Rate Limiting
+
+When a `+"`rate_limit`"+` is set each request message waits for access to the [rate limit](/docs/components/rate_limits/about) before the branch processors are executed, which paces calls to rate limited services without the child processors returning errors. When access is not granted within `+"`rate_limit_max_wait`"+` the request is abandoned and the origin message is flagged as having failed, whilst the remaining messages of the batch are processed as normal.`).
 		Example("HTTP Request", `
 This example strips the request message into an empty body, grabs an HTTP payload, and places the result back into the original message at the path `+"`image.pull_count`"+`:`, `
 pipeline:
@@ -145,6 +153,16 @@ root.bar.id = this.user.id`,
 }`, `# Retain only the updated metadata fields which were present in the origin message
 meta = metadata().filter(v -> @.get(v.key) != null)`).
 			Default(""),
+		service.NewStringField(branchProcFieldRateLimit).
+			Description("An optional [rate limit](/docs/components/rate_limits/about) that each request message must acquire access to before the branch processors are executed.").
+			Advanced().
+			Version("4.28.0").
+			Optional(),
+		service.NewDurationField(branchProcFieldRateLimitMaxWait).
+			Description("The maximum period to wait for access to the `rate_limit` for each request message, after which the origin message is flagged as having failed. A request is abandoned as soon as the rate limit indicates that access will not be granted within the remaining period. If `0s` requests wait indefinitely.").
+			Advanced().
+			Version("4.28.0").
+			Default("0s"),
 	}
 }
 
@@ -169,6 +187,10 @@ func init() {
 type Branch struct {
 	log    log.Modular
 	tracer trace.TracerProvider
+	mgr    bundle.NewManagement
+
+	rateLimit        string
+	rateLimitMaxWait time.Duration
 
 	requestMap *mapping.Executor
 	resultMap  *mapping.Executor
@@ -188,6 +210,7 @@ func newBranchFromParsed(conf *service.ParsedConfig, mgr bundle.NewManagement) (
 	b = &Branch{
 		log:    mgr.Logger(),
 		tracer: mgr.Tracer(),
+		mgr:    mgr,
 
 		mReceived:      stats.GetCounter("processor_received"),
 		mBatchReceived: stats.GetCounter("processor_batch_received"),
@@ -220,6 +243,15 @@ func newBranchFromParsed(conf *service.ParsedConfig, mgr bundle.NewManagement) (
 		}
 	}
 
+	if b.rateLimit, _ = conf.FieldString(branchProcFieldRateLimit); b.rateLimit != "" {
+		if !mgr.ProbeRateLimit(b.rateLimit) {
+			return nil, fmt.Errorf("rate limit resource '%v' was not found", b.rateLimit)
+		}
+	}
+	if b.rateLimitMaxWait, err = conf.FieldDuration(branchProcFieldRateLimitMaxWait); err != nil {
+		return nil, err
+	}
+
 	return b, nil
 }
 
@@ -350,6 +382,47 @@ func newBranchMapError(index int, err error) branchMapError {
 
 //------------------------------------------------------------------------------
 
+var errBranchRateLimitMaxWait = errors.New("exceeded maximum wait for rate limit access")
+
+// waitForAccess blocks until the rate limit of the branch, if any, grants
+// access for a single request.
+func (b *Branch) waitForAccess(ctx context.Context) error {
+	if b.rateLimit == "" {
+		return nil
+	}
+	var deadline time.Time
+	if b.rateLimitMaxWait > 0 {
+		deadline = time.Now().Add(b.rateLimitMaxWait)
+	}
+	for {
+		var period time.Duration
+		var err error
+		if rerr := b.mgr.AccessRateLimit(ctx, b.rateLimit, func(rl ratelimit.V1) {
+			period, err = rl.Access(ctx)
+		}); rerr != nil {
+			err = rerr
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err != nil {
+			b.log.Error("Failed to access rate limit: %v", err)
+			period = time.Second
+		}
+		if period <= 0 {
+			return nil
+		}
+		if !deadline.IsZero() && time.Now().Add(period).After(deadline) {
+			return errBranchRateLimitMaxWait
+		}
+		select {
+		case <-time.After(period):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
 // createResult performs reduction and child processors to a payload. The size
 // of the payload will remain unchanged, where reduced indexes are nil. This
 // result can be overlayed onto the original message in order to complete the
@@ -368,25 +441,38 @@ func (b *Branch) createResult(ctx context.Context, parts []*message.Part, refere
 			skipped = append(skipped, i)
 			continue
 		}
+		newPart := parts[i]
 		if b.requestMap != nil {
 			_ = parts[i].SetBytes(nil)
-			newPart, err := b.requestMap.MapOnto(parts[i], i, referenceMsg)
-			if err != nil {
+			var err error
+			if newPart, err = b.requestMap.MapOnto(parts[i], i, referenceMsg); err != nil {
 				b.mError.Incr(1)
 				b.log.Debug("Failed to map request '%v': %v\n", i, err)
 
 				// Skip if message part fails mapping.
 				failed = append(failed, i)
 				mapErrs = append(mapErrs, newBranchMapError(i, fmt.Errorf("request mapping failed: %w", err)))
-			} else if newPart == nil {
+				continue
+			}
+			if newPart == nil {
 				// Skip if the message part is deleted.
 				skipped = append(skipped, i)
-			} else {
-				newParts = append(newParts, newPart)
+				continue
+			}
+		}
+		if err := b.waitForAccess(ctx); err != nil {
+			if ctx.Err() != nil {
+				return nil, mapErrs, err
 			}
-		} else {
-			newParts = append(newParts, parts[i])
+			b.mError.Incr(1)
+			b.log.Debug("Failed to access rate limit for request '%v': %v\n", i, err)
+
+			// Skip if the rate limit could not be accessed in time.
+			failed = append(failed, i)
+			mapErrs = append(mapErrs, newBranchMapError(i, fmt.Errorf("rate limit access failed: %w", err)))
+			continue
 		}
+		newParts = append(newParts, newPart)
 	}
 	parts = newParts
 
@@ -273,3 +273,56 @@ branch:
 		})
 	}
 }
+
+func TestBranchRateLimitMaxWait(t *testing.T) {
+	var accesses int
+	mgr := mock.NewManager()
+	mgr.RateLimits["foo"] = func(context.Context) (time.Duration, error) {
+		// Every other access is delayed by longer than the max wait.
+		accesses++
+		if accesses%2 == 0 {
+			return time.Hour, nil
+		}
+		return 0, nil
+	}
+
+	conf, err := testutil.ProcessorFromYAML(`
+branch:
+  rate_limit: foo
+  rate_limit_max_wait: 100ms
+  request_map: 'root = if this.id == 1 { deleted() } else { this }'
+  processors:
+    - bloblang: 'root = this.id + 10'
+  result_map: 'root.result = this'
+`)
+	require.NoError(t, err)
+
+	proc, err := mgr.NewProcessor(conf)
+	require.NoError(t, err)
+
+	outMsgs, res := proc.ProcessBatch(context.Background(), message.QuickBatch([][]byte{
+		[]byte(`{"id":0}`),
+		[]byte(`{"id":1}`),
+		[]byte(`{"id":2}`),
+		[]byte(`{"id":3}`),
+	}))
+	require.NoError(t, res)
+	require.Len(t, outMsgs, 1)
+	require.Equal(t, 4, outMsgs[0].Len())
+
+	// Deleted requests do not access the rate limit.
+	assert.Equal(t, 3, accesses)
+
+	assert.Equal(t, `{"id":0,"result":10}`, string(outMsgs[0].Get(0).AsBytes()))
+	assert.NoError(t, outMsgs[0].Get(0).ErrorGet())
+	assert.Equal(t, `{"id":1}`, string(outMsgs[0].Get(1).AsBytes()))
+	assert.NoError(t, outMsgs[0].Get(1).ErrorGet())
+	assert.Equal(t, `{"id":2}`, string(outMsgs[0].Get(2).AsBytes()))
+	assert.EqualError(t, outMsgs[0].Get(2).ErrorGet(), "rate limit access failed: exceeded maximum wait for rate limit access")
+	assert.Equal(t, `{"id":3,"result":13}`, string(outMsgs[0].Get(3).AsBytes()))
+	assert.NoError(t, outMsgs[0].Get(3).ErrorGet())
+
+	ctx, done := context.WithTimeout(context.Background(), time.Second*30)
+	defer done()
+	assert.NoError(t, proc.Close(ctx))
+}
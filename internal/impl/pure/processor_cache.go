@@ -32,7 +32,11 @@ func cacheProcSpec() *service.ConfigSpec {
 		Description(`
 For use cases where you wish to cache the result of processors consider using the `+"[`cached` processor](/docs/components/processors/cached)"+` instead.
 
-This processor will interpolate functions within the `+"`key` and `value`"+` fields individually for each message. This allows you to specify dynamic keys and values based on the contents of the message payloads and metadata. You can find a list of functions [here](/docs/configuration/interpolation#bloblang-queries).`).
+This processor will interpolate functions within the `+"`key` and `value`"+` fields individually for each message. This allows you to specify dynamic keys and values based on the contents of the message payloads and metadata. You can find a list of functions [here](/docs/configuration/interpolation#bloblang-queries).
+
+### Batching
+
+When a batch contains multiple messages the `+"`get` and `set`"+` operators are performed for the whole batch with as few requests as possible on caches that support multi-key operations, such as `+"`redis`"+` (`+"`MGET`"+`), `+"`memcached`"+` and `+"`aws_dynamodb`"+` (`+"`BatchGetItem`"+`), rather than a request per message. Other caches fall back to a request per message. Since a multi-key `+"`set`"+` succeeds or fails as a whole, a failed request flags all messages of the batch as having failed.`).
 		Footnotes(`
 ## Operators
 
@@ -184,9 +188,10 @@ type cacheProc struct {
 	value *field.Expression
 	ttl   *field.Expression
 
-	mgr       bundle.NewManagement
-	cacheName string
-	operator  cacheOperator
+	mgr           bundle.NewManagement
+	cacheName     string
+	operator      cacheOperator
+	multiOperator cacheMultiOperator
 }
 
 func newCache(conf cacheProcConfig, mgr bundle.NewManagement) (*cacheProc, error) {
@@ -199,6 +204,7 @@ func newCache(conf cacheProcConfig, mgr bundle.NewManagement) (*cacheProc, error
 	if err != nil {
 		return nil, err
 	}
+	multiOp := cacheMultiOperatorFromString(conf.Operator)
 
 	key, err := mgr.BloblEnvironment().NewField(conf.Key)
 	if err != nil {
@@ -224,9 +230,10 @@ func newCache(conf cacheProcConfig, mgr bundle.NewManagement) (*cacheProc, error
 		value: value,
 		ttl:   ttl,
 
-		mgr:       mgr,
-		cacheName: cacheName,
-		operator:  op,
+		mgr:           mgr,
+		cacheName:     cacheName,
+		operator:      op,
+		multiOperator: multiOp,
 	}, nil
 }
 
@@ -276,64 +283,166 @@ func cacheOperatorFromString(operator string) (cacheOperator, error) {
 	return nil, fmt.Errorf("operator not recognised: %v", operator)
 }
 
-//------------------------------------------------------------------------------
+type cacheOpItem struct {
+	key   string
+	value []byte
+	ttl   *time.Duration
+}
 
-func (c *cacheProc) ProcessBatch(ctx *processor.BatchProcContext, msg message.Batch) ([]message.Batch, error) {
-	_ = msg.Iter(func(index int, part *message.Part) error {
-		key, err := c.key.String(index, msg)
-		if err != nil {
-			err = fmt.Errorf("key interpolation error: %w", err)
-			ctx.OnError(err, index, nil)
-			return nil
+// cacheMultiOperator performs an operation for multiple items with as few
+// requests as possible, returning a result or an error for each item and
+// whether the results should be used.
+type cacheMultiOperator func(ctx context.Context, cache cache.V1, items []cacheOpItem) ([][]byte, []error, bool)
+
+func newCacheSetMultiOperator() cacheMultiOperator {
+	return func(ctx context.Context, c cache.V1, items []cacheOpItem) ([][]byte, []error, bool) {
+		// Items are added in order so that later items with a duplicate key
+		// take precedence, as they would when set individually.
+		kvs := make(map[string]cache.TTLItem, len(items))
+		for _, item := range items {
+			kvs[item.key] = cache.TTLItem{Value: item.value, TTL: item.ttl}
 		}
 
-		value, err := c.value.Bytes(index, msg)
-		if err != nil {
-			err = fmt.Errorf("value interpolation error: %w", err)
-			ctx.OnError(err, index, nil)
-			return nil
+		errs := make([]error, len(items))
+		if err := c.SetMulti(ctx, kvs); err != nil {
+			for i := range errs {
+				errs[i] = err
+			}
 		}
+		return nil, errs, false
+	}
+}
 
-		var ttl *time.Duration
-		ttls, err := c.ttl.String(index, msg)
-		if err != nil {
-			err = fmt.Errorf("ttl interpolation error: %w", err)
-			ctx.OnError(err, index, nil)
-			return nil
+func newCacheGetMultiOperator() cacheMultiOperator {
+	return func(ctx context.Context, c cache.V1, items []cacheOpItem) ([][]byte, []error, bool) {
+		keys := make([]string, 0, len(items))
+		seen := make(map[string]struct{}, len(items))
+		for _, item := range items {
+			if _, exists := seen[item.key]; !exists {
+				seen[item.key] = struct{}{}
+				keys = append(keys, item.key)
+			}
 		}
 
-		if ttls != "" {
-			td, err := time.ParseDuration(ttls)
+		results, errs := make([][]byte, len(items)), make([]error, len(items))
+		values, err := c.GetMulti(ctx, keys)
+
+		// When only the lookups of some keys failed the remaining messages
+		// still receive their values.
+		var keyErrs cache.KeyErrors
+		if errors.As(err, &keyErrs) {
+			err = nil
+		}
+		for i, item := range items {
 			if err != nil {
-				err = fmt.Errorf("ttl must be a duration: %w", err)
-				ctx.OnError(err, index, nil)
-				return nil
+				errs[i] = err
+				continue
+			}
+			if kerr, exists := keyErrs[item.key]; exists {
+				errs[i] = kerr
+				continue
 			}
-			ttl = &td
+			v, exists := values[item.key]
+			if !exists {
+				errs[i] = component.ErrKeyNotFound
+				continue
+			}
+			results[i] = v
 		}
+		return results, errs, true
+	}
+}
 
-		var result []byte
-		var useResult bool
+// cacheMultiOperatorFromString returns a multi-key variant of an operator, or
+// nil if the operator is only performed for each item individually.
+func cacheMultiOperatorFromString(operator string) cacheMultiOperator {
+	switch operator {
+	case "set":
+		return newCacheSetMultiOperator()
+	case "get":
+		return newCacheGetMultiOperator()
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+func (c *cacheProc) itemFor(index int, msg message.Batch) (item cacheOpItem, err error) {
+	if item.key, err = c.key.String(index, msg); err != nil {
+		err = fmt.Errorf("key interpolation error: %w", err)
+		return
+	}
+
+	if item.value, err = c.value.Bytes(index, msg); err != nil {
+		err = fmt.Errorf("value interpolation error: %w", err)
+		return
+	}
+
+	var ttls string
+	if ttls, err = c.ttl.String(index, msg); err != nil {
+		err = fmt.Errorf("ttl interpolation error: %w", err)
+		return
+	}
+
+	if ttls != "" {
+		td, terr := time.ParseDuration(ttls)
+		if terr != nil {
+			err = fmt.Errorf("ttl must be a duration: %w", terr)
+			return
+		}
+		item.ttl = &td
+	}
+	return
+}
+
+func (c *cacheProc) ProcessBatch(ctx *processor.BatchProcContext, msg message.Batch) ([]message.Batch, error) {
+	items := make([]cacheOpItem, 0, msg.Len())
+	indexes := make([]int, 0, msg.Len())
+	for i := range msg {
+		item, err := c.itemFor(i, msg)
+		if err != nil {
+			ctx.OnError(err, i, nil)
+			continue
+		}
+		items = append(items, item)
+		indexes = append(indexes, i)
+	}
+
+	results, errs := make([][]byte, len(items)), make([]error, len(items))
+	var useResults bool
+	if c.multiOperator != nil && len(items) > 1 {
 		if cerr := c.mgr.AccessCache(context.Background(), c.cacheName, func(cache cache.V1) {
-			result, useResult, err = c.operator(context.Background(), cache, key, value, ttl)
+			results, errs, useResults = c.multiOperator(context.Background(), cache, items)
 		}); cerr != nil {
-			err = cerr
+			for i := range errs {
+				errs[i] = cerr
+			}
 		}
-		if err != nil {
+	} else {
+		for i, item := range items {
+			if cerr := c.mgr.AccessCache(context.Background(), c.cacheName, func(cache cache.V1) {
+				results[i], useResults, errs[i] = c.operator(context.Background(), cache, item.key, item.value, item.ttl)
+			}); cerr != nil {
+				errs[i] = cerr
+			}
+		}
+	}
+
+	for i, item := range items {
+		index := indexes[i]
+		if err := errs[i]; err != nil {
 			if err != component.ErrKeyAlreadyExists {
-				err = fmt.Errorf("operator failed for key '%s': %v", key, err)
+				err = fmt.Errorf("operator failed for key '%s': %v", item.key, err)
 			} else {
-				err = fmt.Errorf("key already exists: %v", key)
+				err = fmt.Errorf("key already exists: %v", item.key)
 			}
 			ctx.OnError(err, index, nil)
-			return nil
+			continue
 		}
-
-		if useResult {
-			part.SetBytes(result)
+		if useResults {
+			msg.Get(index).SetBytes(results[i])
 		}
-		return nil
-	})
+	}
 
 	return []message.Batch{msg}, nil
 }
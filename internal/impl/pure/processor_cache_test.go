@@ -160,6 +160,46 @@ cache:
 	assert.Error(t, output[0].Get(2).ErrorGet())
 }
 
+func TestCacheGetBatchDuplicatesAndErrors(t *testing.T) {
+	mgr := mock.NewManager()
+	mgr.Caches["foocache"] = map[string]mock.CacheItem{
+		"1": {Value: "foo 1"},
+	}
+
+	conf, err := testutil.ProcessorFromYAML(`
+cache:
+  operator: get
+  key: ${!json("key")}
+  ttl: ${!json("ttl").or("")}
+  resource: foocache
+`)
+	require.NoError(t, err)
+
+	proc, err := mgr.NewProcessor(conf)
+	require.NoError(t, err)
+
+	output, res := proc.ProcessBatch(context.Background(), message.QuickBatch([][]byte{
+		[]byte(`{"key":"1"}`),
+		[]byte(`{"key":"2"}`),
+		[]byte(`{"key":"1","ttl":"nope"}`),
+		[]byte(`{"key":"1"}`),
+	}))
+	require.NoError(t, res)
+	require.Len(t, output, 1)
+
+	assert.Equal(t, [][]byte{
+		[]byte(`foo 1`),
+		[]byte(`{"key":"2"}`),
+		[]byte(`{"key":"1","ttl":"nope"}`),
+		[]byte(`foo 1`),
+	}, message.GetAllBytes(output[0]))
+
+	assert.NoError(t, output[0].Get(0).ErrorGet())
+	assert.EqualError(t, output[0].Get(1).ErrorGet(), "operator failed for key '2': key does not exist")
+	assert.ErrorContains(t, output[0].Get(2).ErrorGet(), "ttl must be a duration")
+	assert.NoError(t, output[0].Get(3).ErrorGet())
+}
+
 func TestCacheDelete(t *testing.T) {
 	mgr := mock.NewManager()
 	mgr.Caches["foocache"] = map[string]mock.CacheItem{
@@ -6,6 +6,8 @@ import (
 	"encoding/binary"
 	"errors"
 	"fmt"
+	"math/rand"
+	"sync"
 	"time"
 
 	"golang.org/x/sync/errgroup"
@@ -34,6 +36,16 @@ func newCachedProcessorConfigSpec() *service.ConfigSpec {
 		Field(service.NewInterpolatedStringField("ttl").
 			Description("An optional expiry period to set for each cache entry. Some caches only have a general TTL and will therefore ignore this setting.").
 			Optional()).
+		Field(service.NewFloatField("ttl_jitter").
+			Description("A ratio between 0 and 1 by which the `ttl` of each cache entry is randomly shortened, which spreads out the expiry of entries that were cached at the same time so that they are not all refreshed at once. For example, a `ttl` of `10m` with a `ttl_jitter` of `0.1` results in entries expiring after between nine and ten minutes.").
+			Default(0.0).
+			Version("4.28.0").
+			Advanced()).
+		Field(service.NewDurationField("stale_while_revalidate").
+			Description("An optional period of time after the `ttl` of a cache entry has elapsed during which the stale entry continues to be used whilst the processors are applied in the background in order to refresh it. Only one refresh is performed at a time for each key, which prevents a surge of refreshes when a popular entry expires. Requires a `ttl` to be set.").
+			Example("1m").
+			Version("4.28.0").
+			Optional()).
 		Field(service.NewProcessorListField("processors").Description("The list of processors whose result will be cached.")).
 		Example(
 			"Cached Enrichment",
@@ -103,14 +115,27 @@ type cachedProcessor struct {
 	cacheName  string
 	key        *service.InterpolatedString
 	ttl        *service.InterpolatedString
+	ttlJitter  float64
+	swr        time.Duration
 	processors []*service.OwnedProcessor
 	skipOn     *bloblang.Executor
+
+	refreshCtx    context.Context
+	refreshDone   func()
+	refreshWG     sync.WaitGroup
+	refreshMut    sync.Mutex
+	refreshing    map[string]struct{}
+	refreshClosed bool
+	nowFn         func() time.Time
 }
 
 func newCachedProcessorFromParsedConf(manager *service.Resources, conf *service.ParsedConfig) (proc *cachedProcessor, err error) {
 	proc = &cachedProcessor{
-		manager: manager,
+		manager:    manager,
+		refreshing: map[string]struct{}{},
+		nowFn:      time.Now,
 	}
+	proc.refreshCtx, proc.refreshDone = context.WithCancel(context.Background())
 
 	if proc.cacheName, err = conf.FieldString("cache"); err != nil {
 		return nil, err
@@ -130,6 +155,22 @@ func newCachedProcessorFromParsedConf(manager *service.Resources, conf *service.
 		}
 	}
 
+	if proc.ttlJitter, err = conf.FieldFloat("ttl_jitter"); err != nil {
+		return nil, err
+	}
+	if proc.ttlJitter < 0 || proc.ttlJitter > 1 {
+		return nil, fmt.Errorf("ttl_jitter must be between 0 and 1, got %v", proc.ttlJitter)
+	}
+
+	if conf.Contains("stale_while_revalidate") {
+		if proc.swr, err = conf.FieldDuration("stale_while_revalidate"); err != nil {
+			return nil, err
+		}
+		if proc.swr > 0 && proc.ttl == nil {
+			return nil, errors.New("a ttl must be set in order to use stale_while_revalidate")
+		}
+	}
+
 	if proc.processors, err = conf.FieldProcessorList("processors"); err != nil {
 		return
 	}
@@ -164,6 +205,9 @@ func (proc *cachedProcessor) Process(ctx context.Context, msg *service.Message)
 			return nil, fmt.Errorf("failed to parse ttl expression: %w", err)
 		}
 
+		if proc.ttlJitter > 0 {
+			tempTTL -= time.Duration(rand.Float64() * proc.ttlJitter * float64(tempTTL))
+		}
 		ttl = &tempTTL
 	}
 
@@ -176,11 +220,14 @@ func (proc *cachedProcessor) Process(ctx context.Context, msg *service.Message)
 
 	// Return early if we have a cached result
 	if err == nil {
-		batch, err := cachedProcResultToBatch(msg, cachedBytes)
+		batch, freshUntil, err := cachedProcResultToBatch(msg, cachedBytes)
 		if err != nil {
-			err = fmt.Errorf("failed to parse cached result, this indicates the data was not set by this processor: %w", err)
+			return nil, fmt.Errorf("failed to parse cached result, this indicates the data was not set by this processor: %w", err)
+		}
+		if proc.swr > 0 && ttl != nil && !freshUntil.IsZero() && proc.nowFn().After(freshUntil) {
+			proc.refresh(cacheKey, msg.Copy(), *ttl)
 		}
-		return batch, err
+		return batch, nil
 	}
 
 	// Or if an error occurred that wasn't ErrKeyNotFound
@@ -189,6 +236,35 @@ func (proc *cachedProcessor) Process(ctx context.Context, msg *service.Message)
 	}
 
 	// Result is not cached, so execute processors and cache the result
+	return proc.processAndCache(ctx, cacheKey, msg, ttl)
+}
+
+// refresh applies the processors to a message in the background and caches the
+// result, unless a refresh of the key is already in progress.
+func (proc *cachedProcessor) refresh(cacheKey string, msg *service.Message, ttl time.Duration) {
+	proc.refreshMut.Lock()
+	defer proc.refreshMut.Unlock()
+
+	if _, exists := proc.refreshing[cacheKey]; exists || proc.refreshClosed {
+		return
+	}
+	proc.refreshing[cacheKey] = struct{}{}
+	proc.refreshWG.Add(1)
+
+	go func() {
+		defer func() {
+			proc.refreshMut.Lock()
+			delete(proc.refreshing, cacheKey)
+			proc.refreshMut.Unlock()
+			proc.refreshWG.Done()
+		}()
+		if _, err := proc.processAndCache(proc.refreshCtx, cacheKey, msg, &ttl); err != nil {
+			proc.manager.Logger().Errorf("failed to refresh stale cache entry: %v", err)
+		}
+	}()
+}
+
+func (proc *cachedProcessor) processAndCache(ctx context.Context, cacheKey string, msg *service.Message, ttl *time.Duration) (service.MessageBatch, error) {
 	resultBatch, err := service.ExecuteProcessors(ctx, proc.processors, service.MessageBatch{msg})
 	if err != nil {
 		return nil, err
@@ -210,10 +286,19 @@ func (proc *cachedProcessor) Process(ctx context.Context, msg *service.Message)
 		return collapsedBatch, nil
 	}
 
+	// When serving stale results the entry must outlive its TTL, and so the
+	// moment at which it becomes stale is stored alongside it.
+	var freshUntil time.Time
+	if proc.swr > 0 && ttl != nil {
+		freshUntil = proc.nowFn().Add(*ttl)
+		storeTTL := *ttl + proc.swr
+		ttl = &storeTTL
+	}
+
 	// Any errors in creating a serialised batch or caching are non-fatal and
 	// should be logged but otherwise regarded as insignificant to the flowing
 	// messages.
-	result, err := cachedProcSerialiseBatch(collapsedBatch)
+	result, err := cachedProcSerialiseBatch(collapsedBatch, freshUntil)
 	if err != nil {
 		proc.manager.Logger().Errorf("failed to serialise resulting batch for caching: %w", err)
 		return collapsedBatch, nil
@@ -233,6 +318,13 @@ func (proc *cachedProcessor) Process(ctx context.Context, msg *service.Message)
 }
 
 func (proc *cachedProcessor) Close(ctx context.Context) error {
+	proc.refreshMut.Lock()
+	proc.refreshClosed = true
+	proc.refreshMut.Unlock()
+
+	proc.refreshDone()
+	proc.refreshWG.Wait()
+
 	var group errgroup.Group
 	for _, ownedProc := range proc.processors {
 		op := ownedProc
@@ -288,13 +380,26 @@ func cachedProcUint32ToBytes(n uint32) []byte {
 	return newBytes
 }
 
-func cachedProcSerialiseBatch(batch service.MessageBatch) ([]byte, error) {
+// cachedProcSerialiseBatch serialises a batch with the version 1 schema when
+// freshUntil is zero, and otherwise with the version 2 schema, which is the
+// version 1 schema preceded by the unix nanosecond timestamp at which the
+// batch becomes stale.
+func cachedProcSerialiseBatch(batch service.MessageBatch, freshUntil time.Time) ([]byte, error) {
 	var buf bytes.Buffer
 
 	// Insert schema version
 	// TODO: Increment this on any schema change
-	if _, err := buf.Write(cachedProcUint32ToBytes(1)); err != nil {
-		return nil, err
+	if freshUntil.IsZero() {
+		if _, err := buf.Write(cachedProcUint32ToBytes(1)); err != nil {
+			return nil, err
+		}
+	} else {
+		if _, err := buf.Write(cachedProcUint32ToBytes(2)); err != nil {
+			return nil, err
+		}
+		if _, err := buf.Write(binary.BigEndian.AppendUint64(nil, uint64(freshUntil.UnixNano()))); err != nil {
+			return nil, err
+		}
 	}
 
 	// Insert number of batch messages
@@ -352,13 +457,22 @@ func cachedProcV1DeserialiseBatch(msg *service.Message, data []byte) (resBatch s
 	return
 }
 
-func cachedProcResultToBatch(msg *service.Message, cachedResult []byte) (service.MessageBatch, error) {
+func cachedProcResultToBatch(msg *service.Message, cachedResult []byte) (service.MessageBatch, time.Time, error) {
 	verID, remaining, err := cachedProcExtractUint32(cachedResult)
 	if err != nil {
-		return nil, fmt.Errorf("failed to extract serialisation format version: %w", err)
-	}
-	if verID == 1 {
-		return cachedProcV1DeserialiseBatch(msg, remaining)
+		return nil, time.Time{}, fmt.Errorf("failed to extract serialisation format version: %w", err)
+	}
+	switch verID {
+	case 1:
+		batch, err := cachedProcV1DeserialiseBatch(msg, remaining)
+		return batch, time.Time{}, err
+	case 2:
+		if len(remaining) < 8 {
+			return nil, time.Time{}, errors.New("message is too small to extract timestamp")
+		}
+		freshUntil := time.Unix(0, int64(binary.BigEndian.Uint64(remaining[:8])))
+		batch, err := cachedProcV1DeserialiseBatch(msg, remaining[8:])
+		return batch, freshUntil, err
 	}
-	return nil, fmt.Errorf("invalid format version: %v", verID)
+	return nil, time.Time{}, fmt.Errorf("invalid format version: %v", verID)
 }
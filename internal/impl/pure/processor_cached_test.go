@@ -2,7 +2,10 @@ package pure
 
 import (
 	"context"
+	"fmt"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -229,3 +232,132 @@ processors:
 
 	assert.NoError(t, proc.Close(tCtx))
 }
+
+func TestCachedStaleWhileRevalidate(t *testing.T) {
+	conf, err := newCachedProcessorConfigSpec().ParseYAML(`
+key: ${! content() }
+cache: foo
+ttl: 1m
+stale_while_revalidate: 1h
+processors:
+  - mapping: 'root = content().string() + " " + count("cached_swr_test").string()'
+`, nil)
+	require.NoError(t, err)
+
+	mRes := service.MockResources(service.MockResourcesOptAddCache("foo"))
+
+	proc, err := newCachedProcessorFromParsedConf(mRes, conf)
+	require.NoError(t, err)
+
+	var nowMut sync.Mutex
+	now := time.Now()
+	proc.nowFn = func() time.Time {
+		nowMut.Lock()
+		defer nowMut.Unlock()
+		return now
+	}
+
+	tCtx := context.Background()
+	processStr := func() string {
+		t.Helper()
+		res, err := proc.Process(tCtx, service.NewMessage([]byte("keya")))
+		require.NoError(t, err)
+		require.Len(t, res, 1)
+		b, err := res[0].AsBytes()
+		require.NoError(t, err)
+		return string(b)
+	}
+
+	assert.Equal(t, "keya 1", processStr())
+	assert.Equal(t, "keya 1", processStr())
+
+	nowMut.Lock()
+	now = now.Add(time.Minute * 2)
+	nowMut.Unlock()
+
+	// The stale result is served whilst it is refreshed in the background.
+	assert.Equal(t, "keya 1", processStr())
+	assert.Eventually(t, func() bool {
+		return processStr() == "keya 2"
+	}, time.Second*5, time.Millisecond*10)
+
+	assert.NoError(t, proc.Close(tCtx))
+}
+
+func TestCachedTTLJitter(t *testing.T) {
+	conf, err := newCachedProcessorConfigSpec().ParseYAML(`
+key: ${! content() }
+cache: foo
+ttl: 10s
+ttl_jitter: 0.5
+stale_while_revalidate: 1m
+processors:
+  - mapping: 'root = content().uppercase()'
+`, nil)
+	require.NoError(t, err)
+
+	mRes := service.MockResources(service.MockResourcesOptAddCache("foo"))
+
+	proc, err := newCachedProcessorFromParsedConf(mRes, conf)
+	require.NoError(t, err)
+
+	now := time.Now()
+	proc.nowFn = func() time.Time { return now }
+
+	tCtx := context.Background()
+	for i := 0; i < 20; i++ {
+		key := fmt.Sprintf("key%v", i)
+		_, err := proc.Process(tCtx, service.NewMessage([]byte(key)))
+		require.NoError(t, err)
+
+		require.NoError(t, mRes.AccessCache(tCtx, "foo", func(c service.Cache) {
+			b, err := c.Get(tCtx, key)
+			require.NoError(t, err)
+
+			batch, freshUntil, err := cachedProcResultToBatch(service.NewMessage(nil), b)
+			require.NoError(t, err)
+			require.Len(t, batch, 1)
+
+			ttl := freshUntil.Sub(now)
+			assert.GreaterOrEqual(t, ttl, time.Second*5)
+			assert.LessOrEqual(t, ttl, time.Second*10)
+		}))
+	}
+
+	assert.NoError(t, proc.Close(tCtx))
+}
+
+func TestCachedConfigErrors(t *testing.T) {
+	mRes := service.MockResources(service.MockResourcesOptAddCache("foo"))
+
+	for _, test := range []struct {
+		conf string
+		err  string
+	}{
+		{
+			conf: `
+key: foo
+cache: foo
+ttl_jitter: 1.5
+processors: []
+`,
+			err: "ttl_jitter must be between 0 and 1",
+		},
+		{
+			conf: `
+key: foo
+cache: foo
+stale_while_revalidate: 1m
+processors: []
+`,
+			err: "a ttl must be set in order to use stale_while_revalidate",
+		},
+	} {
+		conf, err := newCachedProcessorConfigSpec().ParseYAML(test.conf, nil)
+		require.NoError(t, err)
+
+		_, err = newCachedProcessorFromParsedConf(mRes, conf)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), test.err)
+	}
+}
@@ -2,19 +2,29 @@ package pure
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	"github.com/benthosdev/benthos/v4/internal/bundle"
 	"github.com/benthosdev/benthos/v4/internal/component/interop"
 	"github.com/benthosdev/benthos/v4/internal/component/processor"
+	"github.com/benthosdev/benthos/v4/internal/filepath/ifs"
 	"github.com/benthosdev/benthos/v4/internal/log"
 	"github.com/benthosdev/benthos/v4/internal/message"
 	"github.com/benthosdev/benthos/v4/public/service"
 )
 
 const (
-	compressPFieldAlgorithm = "algorithm"
-	compressPFieldLevel     = "level"
+	compressPFieldAlgorithm  = "algorithm"
+	compressPFieldLevel      = "level"
+	compressPFieldDictionary = "dictionary"
+	compressPFieldWindowSize = "window_size"
+
+	// Dictionary fields
+	compressPFieldDictPath       = "path"
+	compressPFieldDictTrainFrom  = "train_from"
+	compressPFieldDictMaxSize    = "max_size"
+	compressPFieldDictOutputPath = "output_path"
 )
 
 func init() {
@@ -24,7 +34,11 @@ func init() {
 			Categories("Parsing").
 			Stable().
 			Summary(fmt.Sprintf("Compresses messages according to the selected algorithm. Supported compression algorithms are: %v", compAlgs)).
-			Description(`The 'level' field might not apply to all algorithms.`).
+			Description(`The 'level' field might not apply to all algorithms.
+
+### Dictionaries
+
+The `+"`zstd`"+` algorithm supports compressing messages with a dictionary, which can improve the compression ratio of small messages that are similar to each other (such as JSON documents with a common structure) considerably. A dictionary is either loaded from a file, such as one created with the `+"`zstd --train`"+` command, or trained from sample files when the processor is created. Messages compressed with a dictionary can only be decompressed with the same dictionary, and so trained dictionaries can be written to a file with `+"`output_path`"+` in order to share them with the `+"[`decompress` processor](/docs/components/processors/decompress)"+` and other consumers.`).
 			Fields(
 				service.NewStringEnumField(compressPFieldAlgorithm, compAlgs...).
 					Description("The compression algorithm to use.").
@@ -32,20 +46,62 @@ func init() {
 				service.NewIntField(compressPFieldLevel).
 					Description("The level of compression to use. May not be applicable to all algorithms.").
 					Default(-1),
-			),
+				service.NewObjectField(compressPFieldDictionary,
+					service.NewStringField(compressPFieldDictPath).
+						Description("The path of a file containing a dictionary.").
+						Example("./dictionaries/events.zdict").
+						Optional(),
+					service.NewStringListField(compressPFieldDictTrainFrom).
+						Description("A list of paths of sample files to train a dictionary from, glob patterns are supported.").
+						Example([]string{"./samples/*.json"}).
+						Optional(),
+					service.NewIntField(compressPFieldDictMaxSize).
+						Description("The maximum size in bytes of a trained dictionary.").
+						Default(112640),
+					service.NewStringField(compressPFieldDictOutputPath).
+						Description("An optional path to write a trained dictionary to.").
+						Example("./dictionaries/events.zdict").
+						Optional(),
+				).
+					Description("A dictionary to compress messages with, which is only supported by the `zstd` algorithm. Either `path` or `train_from` must be set.").
+					Version("4.28.0").
+					Advanced().
+					Optional(),
+				service.NewIntField(compressPFieldWindowSize).
+					Description("The window size in bytes to compress messages with, which must be a power of two. Larger windows can improve the compression ratio of large messages at the cost of memory. Only supported by the `zstd` algorithm, if `0` the default of the algorithm is used.").
+					Version("4.28.0").
+					Advanced().
+					Default(0),
+			).
+			Example("Dictionary Compression", "Here we compress small JSON messages with a dictionary trained from samples, and write the dictionary to a file so that consumers of the messages can decompress them.", `
+pipeline:
+  processors:
+    - compress:
+        algorithm: zstd
+        dictionary:
+          train_from: [ ./samples/*.json ]
+          output_path: ./events.zdict
+`),
 		func(conf *service.ParsedConfig, res *service.Resources) (service.BatchProcessor, error) {
 			algStr, err := conf.FieldString(compressPFieldAlgorithm)
 			if err != nil {
 				return nil, err
 			}
 
-			level, err := conf.FieldInt(compressPFieldLevel)
-			if err != nil {
+			mgr := interop.UnwrapManagement(res)
+
+			opts := CompressionOptions{}
+			if opts.Level, err = conf.FieldInt(compressPFieldLevel); err != nil {
+				return nil, err
+			}
+			if opts.Dictionary, err = compressDictionaryFromParsed(conf.Namespace(compressPFieldDictionary), algStr, mgr); err != nil {
+				return nil, err
+			}
+			if opts.WindowSize, err = conf.FieldInt(compressPFieldWindowSize); err != nil {
 				return nil, err
 			}
 
-			mgr := interop.UnwrapManagement(res)
-			p, err := newCompress(algStr, level, mgr)
+			p, err := newCompress(algStr, opts, mgr)
 			if err != nil {
 				return nil, err
 			}
@@ -62,13 +118,90 @@ type compressProc struct {
 	log   log.Modular
 }
 
-func newCompress(algStr string, level int, mgr bundle.NewManagement) (*compressProc, error) {
-	cor, err := strToCompressFunc(algStr)
+// compressDictionaryFromParsed loads or trains the dictionary of a compress
+// processor, and returns a nil dictionary when neither a path nor train_from
+// are set.
+func compressDictionaryFromParsed(conf *service.ParsedConfig, algStr string, mgr bundle.NewManagement) ([]byte, error) {
+	var patterns []string
+	if conf.Contains(compressPFieldDictTrainFrom) {
+		var err error
+		if patterns, err = conf.FieldStringList(compressPFieldDictTrainFrom); err != nil {
+			return nil, err
+		}
+	}
+
+	if conf.Contains(compressPFieldDictPath) {
+		if len(patterns) > 0 {
+			return nil, errors.New("cannot specify both a dictionary path and train_from")
+		}
+		path, err := conf.FieldString(compressPFieldDictPath)
+		if err != nil {
+			return nil, err
+		}
+		dict, err := ifs.ReadFile(mgr.FS(), path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read dictionary: %w", err)
+		}
+		return dict, nil
+	}
+	if len(patterns) == 0 {
+		return nil, nil
+	}
+
+	train, err := strToTrainDictionary(algStr)
+	if err != nil {
+		return nil, err
+	}
+	paths, err := service.Globs(mgr.FS(), patterns...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve sample paths: %w", err)
+	}
+	if len(paths) == 0 {
+		return nil, errors.New("no sample files found to train a dictionary from")
+	}
+	samples := make([][]byte, 0, len(paths))
+	for _, p := range paths {
+		sample, err := ifs.ReadFile(mgr.FS(), p)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read sample: %w", err)
+		}
+		samples = append(samples, sample)
+	}
+
+	maxSize, err := conf.FieldInt(compressPFieldDictMaxSize)
+	if err != nil {
+		return nil, err
+	}
+	dict, err := train(samples, maxSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to train dictionary: %w", err)
+	}
+
+	if conf.Contains(compressPFieldDictOutputPath) {
+		outPath, err := conf.FieldString(compressPFieldDictOutputPath)
+		if err != nil {
+			return nil, err
+		}
+		if err := ifs.WriteFile(mgr.FS(), outPath, dict, 0o644); err != nil {
+			return nil, fmt.Errorf("failed to write dictionary: %w", err)
+		}
+	}
+	return dict, nil
+}
+
+func newCompress(algStr string, opts CompressionOptions, mgr bundle.NewManagement) (*compressProc, error) {
+	var cor CompressFunc
+	var err error
+	if len(opts.Dictionary) > 0 || opts.WindowSize > 0 {
+		cor, err = strToCompressFuncWithOptions(algStr, opts)
+	} else {
+		cor, err = strToCompressFunc(algStr)
+	}
 	if err != nil {
 		return nil, err
 	}
 	return &compressProc{
-		level: level,
+		level: opts.Level,
 		comp:  cor,
 		log:   mgr.Logger(),
 	}, nil
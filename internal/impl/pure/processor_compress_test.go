@@ -314,3 +314,27 @@ compress:
 		t.Errorf("Unexpected output: %s != %s", act, exp)
 	}
 }
+
+func TestCompressDictionaryUnsupported(t *testing.T) {
+	conf, err := testutil.ProcessorFromYAML(`
+compress:
+  algorithm: gzip
+  dictionary:
+    path: ./does_not_matter.zdict
+`)
+	require.NoError(t, err)
+
+	_, err = mock.NewManager().NewProcessor(conf)
+	require.Error(t, err)
+
+	conf, err = testutil.ProcessorFromYAML(`
+compress:
+  algorithm: gzip
+  dictionary:
+    train_from: [ ./does_not_matter/*.json ]
+`)
+	require.NoError(t, err)
+
+	_, err = mock.NewManager().NewProcessor(conf)
+	require.ErrorContains(t, err, "compression type gzip does not support training dictionaries")
+}
@@ -7,13 +7,17 @@ import (
 	"github.com/benthosdev/benthos/v4/internal/bundle"
 	"github.com/benthosdev/benthos/v4/internal/component/interop"
 	"github.com/benthosdev/benthos/v4/internal/component/processor"
+	"github.com/benthosdev/benthos/v4/internal/filepath/ifs"
 	"github.com/benthosdev/benthos/v4/internal/log"
 	"github.com/benthosdev/benthos/v4/internal/message"
 	"github.com/benthosdev/benthos/v4/public/service"
 )
 
 const (
-	decompressPFieldAlgorithm = "algorithm"
+	decompressPFieldAlgorithm     = "algorithm"
+	decompressPFieldDictionary    = "dictionary"
+	decompressPFieldDictPath      = "path"
+	decompressPFieldMaxWindowSize = "max_window_size"
 )
 
 func init() {
@@ -27,6 +31,20 @@ func init() {
 				service.NewStringEnumField(decompressPFieldAlgorithm, compAlgs...).
 					Description("The decompression algorithm to use.").
 					LintRule(``),
+				service.NewObjectField(decompressPFieldDictionary,
+					service.NewStringField(decompressPFieldDictPath).
+						Description("The path of a file containing a dictionary.").
+						Example("./dictionaries/events.zdict"),
+				).
+					Description("A dictionary to decompress messages with, which must be the dictionary that they were compressed with. Only supported by the `zstd` algorithm.").
+					Version("4.28.0").
+					Advanced().
+					Optional(),
+				service.NewIntField(decompressPFieldMaxWindowSize).
+					Description("The maximum window size in bytes of messages to decompress, messages compressed with larger windows fail to decompress. This limits the memory used to decompress each message. Only supported by the `zstd` algorithm, if `0` the default of the algorithm is used.").
+					Version("4.28.0").
+					Advanced().
+					Default(0),
 			),
 		func(conf *service.ParsedConfig, res *service.Resources) (service.BatchProcessor, error) {
 			algStr, err := conf.FieldString(compressPFieldAlgorithm)
@@ -35,7 +53,22 @@ func init() {
 			}
 
 			mgr := interop.UnwrapManagement(res)
-			p, err := newDecompress(algStr, mgr)
+
+			var opts CompressionOptions
+			if conf.Contains(decompressPFieldDictionary) {
+				path, err := conf.FieldString(decompressPFieldDictionary, decompressPFieldDictPath)
+				if err != nil {
+					return nil, err
+				}
+				if opts.Dictionary, err = ifs.ReadFile(mgr.FS(), path); err != nil {
+					return nil, fmt.Errorf("failed to read dictionary: %w", err)
+				}
+			}
+			if opts.WindowSize, err = conf.FieldInt(decompressPFieldMaxWindowSize); err != nil {
+				return nil, err
+			}
+
+			p, err := newDecompress(algStr, opts, mgr)
 			if err != nil {
 				return nil, err
 			}
@@ -51,8 +84,14 @@ type decompressProc struct {
 	log    log.Modular
 }
 
-func newDecompress(algStr string, mgr bundle.NewManagement) (*decompressProc, error) {
-	dcor, err := strToDecompressFunc(algStr)
+func newDecompress(algStr string, opts CompressionOptions, mgr bundle.NewManagement) (*decompressProc, error) {
+	var dcor DecompressFunc
+	var err error
+	if len(opts.Dictionary) > 0 || opts.WindowSize > 0 {
+		dcor, err = strToDecompressFuncWithOptions(algStr, opts)
+	} else {
+		dcor, err = strToDecompressFunc(algStr)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/benthosdev/benthos/v4/internal/bloblang/field"
 	"github.com/benthosdev/benthos/v4/internal/bundle"
@@ -20,6 +21,12 @@ const (
 	dedupFieldCache          = "cache"
 	dedupFieldKey            = "key"
 	dedupFieldDropOnCacheErr = "drop_on_err"
+	dedupFieldFilter         = "filter"
+
+	dedupFilterFieldType           = "type"
+	dedupFilterFieldCapacity       = "capacity"
+	dedupFilterFieldFPRate         = "false_positive_rate"
+	dedupFilterFieldRotateInterval = "rotate_interval"
 )
 
 func dedupeProcSpec() *service.ConfigSpec {
@@ -30,6 +37,12 @@ func dedupeProcSpec() *service.ConfigSpec {
 		Description(`
 Caches must be configured as resources, for more information check out the [cache documentation here](/docs/components/caches/about).
 
+## Probabilistic Deduplication
+
+For keys of a very high cardinality, where storing an entry per key within a cache is prohibitively expensive, a `+"[`filter`](#filter)"+` can be configured instead of a cache. Keys are then added to an in-memory bloom or cuckoo filter of a bounded size, at the cost of a configurable rate of false positives, where a message is dropped despite its key not having been seen before.
+
+Filters are organised into two generations, where keys are added to the current generation and are checked against both. The current generation becomes the previous generation, and the previous generation is discarded, after the `+"`rotate_interval`"+` or once `+"`capacity`"+` keys have been added to it, and so a key is remembered for at least one generation.
+
 When using this processor with an output target that might fail you should always wrap the output within an indefinite `+"[`retry`](/docs/components/outputs/retry)"+` block. This ensures that during outages your messages aren't reprocessed after failures, which would result in messages being dropped.
 
 ## Batch Deduplication
@@ -55,17 +68,51 @@ cache_resources:
   - label: keycache
     memory:
       default_ttl: 60s
+`,
+		).
+		Example(
+			"Deduplicate high cardinality keys",
+			"The following configuration deduplicates messages by an event ID over roughly the last hour without storing each ID, accepting that one in a million messages will be dropped falsely.",
+			`
+pipeline:
+  processors:
+    - dedupe:
+        key: ${! json("event_id") }
+        filter:
+          type: bloom
+          capacity: 50000000
+          false_positive_rate: 0.000001
+          rotate_interval: 1h
 `,
 		).
 		Fields(
 			service.NewStringField(dedupFieldCache).
-				Description("The [`cache` resource](/docs/components/caches/about) to target with this processor."),
+				Description("The [`cache` resource](/docs/components/caches/about) to target with this processor. Either a cache or a `filter` must be specified.").
+				Optional(),
 			service.NewInterpolatedStringField(dedupFieldKey).
 				Description("An interpolated string yielding the key to deduplicate by for each message.").
 				Examples(`${! meta("kafka_key") }`, `${! content().hash("xxhash64") }`),
 			service.NewBoolField(dedupFieldDropOnCacheErr).
 				Description("Whether messages should be dropped when the cache returns a general error such as a network issue.").
 				Default(true),
+			service.NewObjectField(dedupFieldFilter,
+				service.NewStringEnumField(dedupFilterFieldType, "bloom", "cuckoo").
+					Description("The type of filter to use. Bloom filters are the most compact for a given false positive rate, whereas cuckoo filters offer faster lookups at low false positive rates.").
+					Default("bloom"),
+				service.NewIntField(dedupFilterFieldCapacity).
+					Description("The number of keys that a generation of the filter holds before it is rotated, which determines the memory used by the filter.").
+					Example(1000000),
+				service.NewFloatField(dedupFilterFieldFPRate).
+					Description("The target rate of false positives of the filter, where a message is dropped even though its key has not been seen.").
+					Default(0.001),
+				service.NewStringField(dedupFilterFieldRotateInterval).
+					Description("An optional interval after which a generation of the filter is rotated. When empty generations are only rotated once they reach capacity.").
+					Default("").
+					Example("1h"),
+			).
+				Description("An in-memory probabilistic filter to deduplicate keys with instead of a cache.").
+				Version("4.28.0").
+				Optional(),
 		)
 }
 
@@ -73,9 +120,19 @@ func init() {
 	err := service.RegisterBatchProcessor(
 		"dedupe", dedupeProcSpec(),
 		func(conf *service.ParsedConfig, res *service.Resources) (service.BatchProcessor, error) {
-			cache, err := conf.FieldString(dedupFieldCache)
-			if err != nil {
-				return nil, err
+			var cache string
+			var err error
+			if conf.Contains(dedupFieldCache) {
+				if cache, err = conf.FieldString(dedupFieldCache); err != nil {
+					return nil, err
+				}
+			}
+
+			var filter *rotatingFilter
+			if conf.Contains(dedupFieldFilter, dedupFilterFieldCapacity) {
+				if filter, err = dedupeFilterFromParsed(conf.Namespace(dedupFieldFilter)); err != nil {
+					return nil, err
+				}
 			}
 
 			keyStr, err := conf.FieldString(dedupFieldKey)
@@ -89,7 +146,7 @@ func init() {
 			}
 
 			mgr := interop.UnwrapManagement(res)
-			p, err := newDedupe(cache, keyStr, dropOnErr, mgr)
+			p, err := newDedupe(cache, filter, keyStr, dropOnErr, mgr)
 			if err != nil {
 				return nil, err
 			}
@@ -100,6 +157,32 @@ func init() {
 	}
 }
 
+func dedupeFilterFromParsed(conf *service.ParsedConfig) (*rotatingFilter, error) {
+	typeStr, err := conf.FieldString(dedupFilterFieldType)
+	if err != nil {
+		return nil, err
+	}
+	capacity, err := conf.FieldInt(dedupFilterFieldCapacity)
+	if err != nil {
+		return nil, err
+	}
+	fpRate, err := conf.FieldFloat(dedupFilterFieldFPRate)
+	if err != nil {
+		return nil, err
+	}
+	intervalStr, err := conf.FieldString(dedupFilterFieldRotateInterval)
+	if err != nil {
+		return nil, err
+	}
+	var interval time.Duration
+	if intervalStr != "" {
+		if interval, err = time.ParseDuration(intervalStr); err != nil {
+			return nil, fmt.Errorf("failed to parse filter rotate interval: %w", err)
+		}
+	}
+	return newRotatingFilter(typeStr, capacity, fpRate, interval, time.Now)
+}
+
 type dedupeProc struct {
 	log log.Modular
 
@@ -107,9 +190,10 @@ type dedupeProc struct {
 	key       *field.Expression
 	mgr       bundle.NewManagement
 	cacheName string
+	filter    *rotatingFilter
 }
 
-func newDedupe(cache, keyStr string, dropOnErr bool, mgr bundle.NewManagement) (*dedupeProc, error) {
+func newDedupe(cache string, filter *rotatingFilter, keyStr string, dropOnErr bool, mgr bundle.NewManagement) (*dedupeProc, error) {
 	if keyStr == "" {
 		return nil, errors.New("dedupe key must not be empty")
 	}
@@ -118,7 +202,10 @@ func newDedupe(cache, keyStr string, dropOnErr bool, mgr bundle.NewManagement) (
 		return nil, fmt.Errorf("failed to parse key expression: %v", err)
 	}
 
-	if !mgr.ProbeCache(cache) {
+	if (cache == "") == (filter == nil) {
+		return nil, errors.New("either a cache or a filter must be specified")
+	}
+	if filter == nil && !mgr.ProbeCache(cache) {
 		return nil, fmt.Errorf("cache resource '%v' was not found", cache)
 	}
 
@@ -128,6 +215,7 @@ func newDedupe(cache, keyStr string, dropOnErr bool, mgr bundle.NewManagement) (
 		key:       key,
 		mgr:       mgr,
 		cacheName: cache,
+		filter:    filter,
 	}, nil
 }
 
@@ -141,6 +229,15 @@ func (d *dedupeProc) ProcessBatch(ctx *processor.BatchProcContext, batch message
 			return nil
 		}
 
+		if d.filter != nil {
+			if d.filter.testAndAdd([]byte(key)) {
+				ctx.Span(i).LogKV("event", "dropped", "type", "deduplicated")
+				return nil
+			}
+			newBatch = append(newBatch, p)
+			return nil
+		}
+
 		if cerr := d.mgr.AccessCache(context.Background(), d.cacheName, func(cache cache.V1) {
 			err = cache.Add(context.Background(), key, []byte{'t'}, nil)
 		}); cerr != nil {
@@ -0,0 +1,272 @@
+package pure
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/bits"
+	"sync"
+	"time"
+
+	"github.com/OneOfOne/xxhash"
+)
+
+// probFilter is a probabilistic set of key hashes that may report false
+// positives but never false negatives.
+type probFilter interface {
+	contains(h uint64) bool
+	// add inserts a hash and returns false when the filter is full.
+	add(h uint64) bool
+}
+
+// mix64 derives an independent hash from another (the splitmix64 finalizer).
+func mix64(h uint64) uint64 {
+	h ^= h >> 30
+	h *= 0xbf58476d1ce4e5b9
+	h ^= h >> 27
+	h *= 0x94d049bb133111eb
+	h ^= h >> 31
+	return h
+}
+
+//------------------------------------------------------------------------------
+
+type bloomFilter struct {
+	words   []uint64
+	numBits uint64
+	numHash int
+}
+
+func newBloomFilter(capacity int, fpRate float64) *bloomFilter {
+	numBits := uint64(math.Ceil(-float64(capacity) * math.Log(fpRate) / (math.Ln2 * math.Ln2)))
+	if numBits < 64 {
+		numBits = 64
+	}
+	numHash := int(math.Round(float64(numBits) / float64(capacity) * math.Ln2))
+	if numHash < 1 {
+		numHash = 1
+	}
+	return &bloomFilter{
+		words:   make([]uint64, (numBits+63)/64),
+		numBits: numBits,
+		numHash: numHash,
+	}
+}
+
+func (b *bloomFilter) contains(h uint64) bool {
+	h2 := mix64(h) | 1
+	for i := 0; i < b.numHash; i++ {
+		bit := (h + uint64(i)*h2) % b.numBits
+		if b.words[bit/64]&(1<<(bit%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func (b *bloomFilter) add(h uint64) bool {
+	h2 := mix64(h) | 1
+	for i := 0; i < b.numHash; i++ {
+		bit := (h + uint64(i)*h2) % b.numBits
+		b.words[bit/64] |= 1 << (bit % 64)
+	}
+	return true
+}
+
+//------------------------------------------------------------------------------
+
+const (
+	cuckooBucketSize = 4
+	cuckooMaxKicks   = 500
+)
+
+type cuckooFilter struct {
+	// Fingerprints stored in buckets of cuckooBucketSize slots, where zero
+	// marks an empty slot.
+	slots      []uint32
+	bucketMask uint64
+	fpMask     uint32
+
+	// A fingerprint that could not be placed, once set the filter is full.
+	victim       uint32
+	victimBucket uint64
+	kickState    uint64
+}
+
+func newCuckooFilter(capacity int, fpRate float64) *cuckooFilter {
+	// Buckets are sized for a load factor of 95%, rounded up to a power of two
+	// so that alternate buckets can be derived with an XOR.
+	numBuckets := uint64(math.Ceil(float64(capacity) / (cuckooBucketSize * 0.95)))
+	if numBuckets < 1 {
+		numBuckets = 1
+	}
+	numBuckets = 1 << bits.Len64(numBuckets-1)
+
+	fpBits := int(math.Ceil(math.Log2(2 * cuckooBucketSize / fpRate)))
+	if fpBits < 4 {
+		fpBits = 4
+	} else if fpBits > 32 {
+		fpBits = 32
+	}
+	return &cuckooFilter{
+		slots:      make([]uint32, numBuckets*cuckooBucketSize),
+		bucketMask: numBuckets - 1,
+		fpMask:     uint32((uint64(1) << fpBits) - 1),
+	}
+}
+
+func (c *cuckooFilter) indexes(h uint64) (fp uint32, i1, i2 uint64) {
+	if fp = uint32(h>>32) & c.fpMask; fp == 0 {
+		fp = 1
+	}
+	i1 = h & c.bucketMask
+	return fp, i1, c.altIndex(i1, fp)
+}
+
+func (c *cuckooFilter) altIndex(i uint64, fp uint32) uint64 {
+	return (i ^ mix64(uint64(fp))) & c.bucketMask
+}
+
+func (c *cuckooFilter) bucketContains(i uint64, fp uint32) bool {
+	for _, s := range c.slots[i*cuckooBucketSize : (i+1)*cuckooBucketSize] {
+		if s == fp {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *cuckooFilter) bucketInsert(i uint64, fp uint32) bool {
+	bucket := c.slots[i*cuckooBucketSize : (i+1)*cuckooBucketSize]
+	for j, s := range bucket {
+		if s == 0 {
+			bucket[j] = fp
+			return true
+		}
+	}
+	return false
+}
+
+func (c *cuckooFilter) contains(h uint64) bool {
+	fp, i1, i2 := c.indexes(h)
+	if c.victim == fp && (c.victimBucket == i1 || c.victimBucket == i2) {
+		return true
+	}
+	return c.bucketContains(i1, fp) || c.bucketContains(i2, fp)
+}
+
+func (c *cuckooFilter) add(h uint64) bool {
+	if c.victim != 0 {
+		return false
+	}
+
+	fp, i1, i2 := c.indexes(h)
+	if c.bucketInsert(i1, fp) || c.bucketInsert(i2, fp) {
+		return true
+	}
+
+	i := i1
+	for n := 0; n < cuckooMaxKicks; n++ {
+		c.kickState = mix64(c.kickState + 1)
+		slot := i*cuckooBucketSize + c.kickState%cuckooBucketSize
+		fp, c.slots[slot] = c.slots[slot], fp
+
+		i = c.altIndex(i, fp)
+		if c.bucketInsert(i, fp) {
+			return true
+		}
+	}
+
+	// The evicted fingerprint is kept aside so that it isn't lost, but the
+	// filter accepts no further hashes.
+	c.victim, c.victimBucket = fp, i
+	return true
+}
+
+//------------------------------------------------------------------------------
+
+// rotatingFilter deduplicates keys with a generation of a probabilistic filter
+// that is replaced with an empty generation after an interval or once it has
+// reached capacity. The previous generation is retained and consulted, and so
+// a key is remembered for at least the length of one generation.
+type rotatingFilter struct {
+	newFilter func() probFilter
+	capacity  int
+	interval  time.Duration
+	clock     func() time.Time
+
+	mut         sync.Mutex
+	current     probFilter
+	previous    probFilter
+	added       int
+	lastRotated time.Time
+}
+
+func newRotatingFilter(typeStr string, capacity int, fpRate float64, interval time.Duration, clock func() time.Time) (*rotatingFilter, error) {
+	if capacity <= 0 {
+		return nil, errors.New("filter capacity must be greater than zero")
+	}
+	if fpRate <= 0 || fpRate >= 1 {
+		return nil, errors.New("filter false positive rate must be between zero and one")
+	}
+	if interval < 0 {
+		return nil, errors.New("filter rotate interval must not be negative")
+	}
+
+	r := &rotatingFilter{
+		capacity: capacity,
+		interval: interval,
+		clock:    clock,
+	}
+	switch typeStr {
+	case "bloom":
+		r.newFilter = func() probFilter {
+			return newBloomFilter(capacity, fpRate)
+		}
+	case "cuckoo":
+		r.newFilter = func() probFilter {
+			return newCuckooFilter(capacity, fpRate)
+		}
+	default:
+		return nil, fmt.Errorf("filter type %v not recognised", typeStr)
+	}
+	r.current = r.newFilter()
+	r.lastRotated = clock()
+	return r, nil
+}
+
+func (r *rotatingFilter) rotate(now time.Time) {
+	r.previous, r.current = r.current, r.newFilter()
+	r.added = 0
+	r.lastRotated = now
+}
+
+// testAndAdd returns true if the key has likely been seen before, otherwise it
+// is added to the filter.
+func (r *rotatingFilter) testAndAdd(key []byte) bool {
+	h := xxhash.Checksum64(key)
+
+	r.mut.Lock()
+	defer r.mut.Unlock()
+
+	now := r.clock()
+	if r.interval > 0 {
+		if elapsed := now.Sub(r.lastRotated); elapsed >= 2*r.interval {
+			// Both generations have expired.
+			r.rotate(now)
+			r.previous = nil
+		} else if elapsed >= r.interval {
+			r.rotate(now)
+		}
+	}
+
+	if r.current.contains(h) || (r.previous != nil && r.previous.contains(h)) {
+		return true
+	}
+	if r.added >= r.capacity || !r.current.add(h) {
+		r.rotate(now)
+		_ = r.current.add(h)
+	}
+	r.added++
+	return false
+}
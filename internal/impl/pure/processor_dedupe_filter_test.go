@@ -0,0 +1,81 @@
+package pure
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/OneOfOne/xxhash"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDedupeFilterFalsePositives(t *testing.T) {
+	for _, typeStr := range []string{"bloom", "cuckoo"} {
+		typeStr := typeStr
+		t.Run(typeStr, func(t *testing.T) {
+			f, err := newRotatingFilter(typeStr, 10000, 0.01, 0, time.Now)
+			require.NoError(t, err)
+
+			// New keys may be reported as seen due to false positives.
+			falsePositives := 0
+			for i := 0; i < 10000; i++ {
+				if f.testAndAdd([]byte("key" + strconv.Itoa(i))) {
+					falsePositives++
+				}
+			}
+			assert.Less(t, falsePositives, 100)
+
+			for i := 0; i < 10000; i++ {
+				require.True(t, f.testAndAdd([]byte("key"+strconv.Itoa(i))), i)
+			}
+
+			falsePositives = 0
+			for i := 10000; i < 20000; i++ {
+				if f.current.contains(xxhash.Checksum64([]byte("key" + strconv.Itoa(i)))) {
+					falsePositives++
+				}
+			}
+			assert.Less(t, falsePositives, 200)
+		})
+	}
+}
+
+func TestDedupeFilterRotation(t *testing.T) {
+	currentTS := time.Unix(0, 0)
+	f, err := newRotatingFilter("cuckoo", 100, 0.001, time.Minute, func() time.Time {
+		return currentTS
+	})
+	require.NoError(t, err)
+
+	assert.False(t, f.testAndAdd([]byte("foo")))
+
+	currentTS = time.Unix(60, 0)
+	assert.False(t, f.testAndAdd([]byte("bar")))
+	assert.True(t, f.testAndAdd([]byte("foo")), "retained by the previous generation")
+
+	currentTS = time.Unix(120, 0)
+	assert.True(t, f.testAndAdd([]byte("bar")))
+	assert.False(t, f.testAndAdd([]byte("foo")))
+
+	currentTS = time.Unix(300, 0)
+	assert.False(t, f.testAndAdd([]byte("bar")), "both generations expired")
+
+	// Generations are also rotated once they reach capacity.
+	for i := 0; i < 250; i++ {
+		f.testAndAdd([]byte("key" + strconv.Itoa(i)))
+	}
+	assert.False(t, f.testAndAdd([]byte("key0")))
+	assert.True(t, f.testAndAdd([]byte("key249")))
+}
+
+func TestDedupeFilterErrors(t *testing.T) {
+	_, err := newRotatingFilter("bloom", 0, 0.01, 0, time.Now)
+	require.Error(t, err)
+
+	_, err = newRotatingFilter("bloom", 10, 1, 0, time.Now)
+	require.Error(t, err)
+
+	_, err = newRotatingFilter("nope", 10, 0.01, 0, time.Now)
+	require.Error(t, err)
+}
@@ -107,3 +107,52 @@ dedupe:
 	require.NoError(t, err)
 	assert.Len(t, msgs, 1)
 }
+
+func TestDedupeFilter(t *testing.T) {
+	conf, err := testutil.ProcessorFromYAML(`
+dedupe:
+  key: ${! content() }
+  filter:
+    type: cuckoo
+    capacity: 1000
+`)
+	require.NoError(t, err)
+
+	proc, err := mock.NewManager().NewProcessor(conf)
+	require.NoError(t, err)
+
+	msgOut, err := proc.ProcessBatch(context.Background(), message.QuickBatch([][]byte{
+		[]byte("foo"), []byte("bar"), []byte("foo"),
+	}))
+	require.NoError(t, err)
+	require.Len(t, msgOut, 1)
+	assert.Equal(t, 2, msgOut[0].Len())
+
+	msgOut, err = proc.ProcessBatch(context.Background(), message.QuickBatch([][]byte{[]byte("bar")}))
+	require.NoError(t, err)
+	require.Empty(t, msgOut)
+}
+
+func TestDedupeCacheOrFilter(t *testing.T) {
+	for _, confStr := range []string{
+		`
+dedupe:
+  key: ${! content() }
+`,
+		`
+dedupe:
+  cache: foocache
+  key: ${! content() }
+  filter: {}
+`,
+	} {
+		conf, err := testutil.ProcessorFromYAML(confStr)
+		require.NoError(t, err)
+
+		mgr := mock.NewManager()
+		mgr.Caches["foocache"] = map[string]mock.CacheItem{}
+
+		_, err = mgr.NewProcessor(conf)
+		require.Error(t, err, confStr)
+	}
+}
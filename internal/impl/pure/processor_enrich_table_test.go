@@ -0,0 +1,143 @@
+package pure
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func testEnrichTableProc(t *testing.T, confStr string) *enrichTableProc {
+	t.Helper()
+
+	conf, err := enrichTableProcSpec().ParseYAML(confStr, nil)
+	require.NoError(t, err)
+
+	proc, err := newEnrichTableProcFromConfig(conf, service.MockResources())
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		ctx, done := context.WithTimeout(context.Background(), time.Second*10)
+		defer done()
+		require.NoError(t, proc.Close(ctx))
+	})
+	return proc
+}
+
+func TestEnrichTableProcessor(t *testing.T) {
+	proc := testEnrichTableProc(t, `
+input:
+  generate:
+    count: 4
+    interval: ""
+    mapping: |
+      let n = count("enrich_table_processor_test")
+      root.id = if $n == 4 { "u1" } else { "u%v".format($n) }
+      root.name = "user %v".format($n)
+      root.deleted = $n == 4
+      meta tier = if $n == 2 { "gold" } else { "silver" }
+row_key: ${! json("id") }
+key: ${! json("user_id") }
+result_map: |
+  root.user_name = this.name
+  meta tier = meta("tier")
+delete_check: this.deleted
+miss_policy: error
+`)
+
+	res, err := proc.Process(context.Background(), service.NewMessage([]byte(`{"user_id":"u2"}`)))
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+
+	b, err := res[0].AsBytes()
+	require.NoError(t, err)
+	assert.Equal(t, `{"user_id":"u2","user_name":"user 2"}`, string(b))
+	tier, _ := res[0].MetaGetMut("tier")
+	assert.Equal(t, "gold", tier)
+
+	// The first row is deleted by the last.
+	_, err = proc.Process(context.Background(), service.NewMessage([]byte(`{"user_id":"u1"}`)))
+	require.EqualError(t, err, "key u1 was not found within the table")
+
+	assert.Len(t, proc.table, 2)
+}
+
+func TestEnrichTableProcessorMissPolicies(t *testing.T) {
+	conf := `
+input:
+  generate:
+    count: 1
+    interval: ""
+    mapping: 'root.id = "foo"'
+row_key: ${! json("id") }
+key: ${! content() }
+`
+
+	proc := testEnrichTableProc(t, conf)
+	res, err := proc.Process(context.Background(), service.NewMessage([]byte(`bar`)))
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+	b, err := res[0].AsBytes()
+	require.NoError(t, err)
+	assert.Equal(t, "bar", string(b))
+
+	res, err = proc.Process(context.Background(), service.NewMessage([]byte(`{}`)))
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+
+	proc = testEnrichTableProc(t, conf+"\nmiss_policy: drop")
+	res, err = proc.Process(context.Background(), service.NewMessage([]byte(`bar`)))
+	require.NoError(t, err)
+	assert.Empty(t, res)
+
+	res, err = proc.Process(context.Background(), service.NewMessage([]byte(`foo`)))
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+	v, err := res[0].AsStructured()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"enrichment": map[string]any{"id": "foo"}}, v)
+}
+
+func TestEnrichTableProcessorRefresh(t *testing.T) {
+	proc := testEnrichTableProc(t, `
+input:
+  generate:
+    count: 1
+    interval: ""
+    mapping: 'root.id = "v%v".format(count("enrich_table_refresh_test"))'
+row_key: ${! json("id") }
+key: ${! content() }
+miss_policy: drop
+refresh_interval: 10ms
+`)
+
+	assert.Eventually(t, func() bool {
+		res, err := proc.Process(context.Background(), service.NewMessage([]byte(`v3`)))
+		require.NoError(t, err)
+		return len(res) == 1
+	}, time.Second*5, time.Millisecond*10)
+
+	// Each load replaces the table rather than adding to it.
+	res, err := proc.Process(context.Background(), service.NewMessage([]byte(`v1`)))
+	require.NoError(t, err)
+	assert.Empty(t, res)
+}
+
+func TestEnrichTableProcessorWaitForLoad(t *testing.T) {
+	proc := testEnrichTableProc(t, `
+input:
+  generate:
+    interval: 1h
+    mapping: 'root.id = "foo"'
+row_key: ${! json("id") }
+key: ${! content() }
+`)
+
+	ctx, done := context.WithTimeout(context.Background(), time.Millisecond*50)
+	defer done()
+	_, err := proc.Process(ctx, service.NewMessage([]byte(`foo`)))
+	require.Error(t, err)
+}
@@ -3,8 +3,14 @@ package pure
 import (
 	"bufio"
 	"context"
+	"embed"
 	"errors"
 	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"regexp"
+	"strconv"
 	"strings"
 
 	"github.com/Jeffail/gabs/v2"
@@ -26,15 +32,23 @@ const (
 	gpFieldUseDefaults        = "use_default_patterns"
 	gpFieldPatternPaths       = "pattern_paths"
 	gpFieldPatternDefinitions = "pattern_definitions"
+	gpFieldMatchedMetadata    = "matched_expression_metadata"
 )
 
+//go:embed grok_patterns
+var grokPatternLibrary embed.FS
+
 func grokProcSpec() *service.ConfigSpec {
 	return service.NewConfigSpec().
 		Categories("Parsing").
 		Stable().
 		Summary("Parses messages into a structured format by attempting to apply a list of Grok expressions, the first expression to result in at least one value replaces the original message with a JSON object containing the values.").
 		Description(`
-Type hints within patterns are respected, therefore with the pattern `+"`%{WORD:first},%{INT:second:int}`"+` and a payload of `+"`foo,1`"+` the resulting payload would be `+"`{\"first\":\"foo\",\"second\":1}`"+`.
+Type hints within patterns are respected, therefore with the pattern `+"`%{WORD:first},%{INT:second:int}`"+` and a payload of `+"`foo,1`"+` the resulting payload would be `+"`{\"first\":\"foo\",\"second\":1}`"+`. The supported types are `+"`int`"+` (or `+"`long`"+`, `+"`integer`"+`), `+"`float`"+` (or `+"`double`"+`), `+"`bool`"+` (or `+"`boolean`"+`) and `+"`string`"+`. When a captured value cannot be coerced into its type the expression is treated as not matching and the next expression is attempted.
+
+Custom patterns can be loaded from files with the field `+"`pattern_paths`"+`, where each line of a file consists of a pattern name followed by whitespace and then the pattern itself, using the same format as Logstash. Empty lines and lines beginning with `+"`#`"+` are ignored, and when a path is a directory all files within it and its subdirectories are loaded.
+
+In order to find out which expression matched a message set the field `+"`matched_expression_metadata`"+` to a metadata key, and the matching expression will be added to each message under that key.
 
 ### Performance
 
@@ -42,7 +56,30 @@ This processor currently uses the [Go RE2](https://golang.org/s/re2syntax) regul
 		Footnotes(`
 ## Default Patterns
 
-A summary of the default patterns on offer can be [found here](https://github.com/Jeffail/grok/blob/master/patterns.go#L5).`).
+A summary of the core default patterns on offer can be [found here](https://github.com/Jeffail/grok/blob/master/patterns.go#L5). In addition to these the Logstash pattern library is included, which provides patterns for parsing the logs of common software:
+
+| File | Example Patterns |
+|------|------------------|
+| `+"`aws`"+` | `+"`S3_ACCESS_LOG`"+`, `+"`ELB_ACCESS_LOG`"+`, `+"`CLOUDFRONT_ACCESS_LOG`"+` |
+| `+"`bind`"+` | `+"`BIND9`"+` |
+| `+"`exim`"+` | `+"`EXIM_MSGID`"+`, `+"`EXIM_REMOTE_HOST`"+` |
+| `+"`firewalls`"+` | `+"`NETSCREENSESSIONLOG`"+`, `+"`CISCO_TAGGED_SYSLOG`"+`, `+"`SHOREWALL`"+` |
+| `+"`haproxy`"+` | `+"`HAPROXYHTTP`"+`, `+"`HAPROXYTCP`"+` |
+| `+"`httpd`"+` | `+"`HTTPD_COMMONLOG`"+`, `+"`HTTPD_COMBINEDLOG`"+`, `+"`HTTPD_ERRORLOG`"+` |
+| `+"`java`"+` | `+"`JAVASTACKTRACEPART`"+`, `+"`CATALINALOG`"+`, `+"`TOMCATLOG`"+` |
+| `+"`junos`"+` | `+"`RT_FLOW1`"+`, `+"`RT_FLOW2`"+`, `+"`RT_FLOW3`"+` |
+| `+"`linux-syslog`"+` | `+"`SYSLOGLINE`"+`, `+"`SYSLOG5424LINE`"+`, `+"`CRONLOG`"+` |
+| `+"`maven`"+` | `+"`MAVEN_VERSION`"+` |
+| `+"`mcollective`"+` | `+"`MCOLLECTIVE`"+` |
+| `+"`mongodb`"+` | `+"`MONGO_LOG`"+`, `+"`MONGO3_LOG`"+` |
+| `+"`nagios`"+` | `+"`NAGIOSLOGLINE`"+` |
+| `+"`postgresql`"+` | `+"`POSTGRESQL`"+` |
+| `+"`rails`"+` | `+"`RAILS3`"+` |
+| `+"`redis`"+` | `+"`REDISLOG`"+`, `+"`REDISMONLOG`"+` |
+| `+"`ruby`"+` | `+"`RUBY_LOGGER`"+` |
+| `+"`squid`"+` | `+"`SQUID3`"+` |
+
+Patterns that are defined with `+"`pattern_definitions`"+` or loaded from `+"`pattern_paths`"+` take precedence over default patterns of the same name.`).
 		Example("VPC Flow Logs", `
 Grok can be used to parse unstructured logs such as VPC flow logs that look like this:
 
@@ -74,8 +111,14 @@ pipeline:
 				Description("A map of pattern definitions that can be referenced within `patterns`.").
 				Default(map[string]any{}),
 			service.NewStringListField(gpFieldPatternPaths).
-				Description("A list of paths to load Grok patterns from. This field supports wildcards, including super globs (double star).").
+				Description("A list of paths to load Grok patterns from. Directories are searched recursively for pattern files. This field supports wildcards, including super globs (double star).").
 				Default([]any{}),
+			service.NewStringField(gpFieldMatchedMetadata).
+				Description("An optional metadata key to add the expression that matched each message to.").
+				Version("4.28.0").
+				Example("grok_expression").
+				Advanced().
+				Optional(),
 			service.NewBoolField(gpFieldNamedOnly).
 				Description("Whether to only capture values from named patterns.").
 				Advanced().
@@ -98,6 +141,7 @@ type grokProcConfig struct {
 	UseDefaults        bool
 	PatternPaths       []string
 	PatternDefinitions map[string]string
+	MatchedMetadata    string
 }
 
 func init() {
@@ -126,6 +170,11 @@ func init() {
 			if g.UseDefaults, err = conf.FieldBool(gpFieldUseDefaults); err != nil {
 				return nil, err
 			}
+			if conf.Contains(gpFieldMatchedMetadata) {
+				if g.MatchedMetadata, err = conf.FieldString(gpFieldMatchedMetadata); err != nil {
+					return nil, err
+				}
+			}
 
 			mgr := interop.UnwrapManagement(res)
 			p, err := newGrok(g, mgr)
@@ -139,51 +188,162 @@ func init() {
 	}
 }
 
+type grokExpression struct {
+	expression string
+	compiled   *grok.CompiledGrok
+	types      map[string]string
+}
+
 type grokProc struct {
-	gparsers []*grok.CompiledGrok
-	log      log.Modular
+	gparsers        []grokExpression
+	matchedMetadata string
+	log             log.Modular
 }
 
 func newGrok(conf grokProcConfig, mgr bundle.NewManagement) (processor.AutoObserved, error) {
-	grokConf := grok.Config{
-		RemoveEmptyValues:   conf.RemoveEmpty,
-		NamedCapturesOnly:   conf.NamedOnly,
-		SkipDefaultPatterns: !conf.UseDefaults,
-		Patterns:            conf.PatternDefinitions,
+	patterns := map[string]string{}
+	if conf.UseDefaults {
+		if err := addGrokPatternsFromLibrary(patterns); err != nil {
+			return nil, fmt.Errorf("failed to parse default patterns: %v", err)
+		}
+	}
+	for k, v := range conf.PatternDefinitions {
+		patterns[k] = v
 	}
-
 	for _, path := range conf.PatternPaths {
-		if err := addGrokPatternsFromPath(mgr.FS(), path, grokConf.Patterns); err != nil {
+		if err := addGrokPatternsFromPath(mgr.FS(), path, patterns); err != nil {
 			return nil, fmt.Errorf("failed to parse patterns from path '%v': %v", path, err)
 		}
 	}
 
-	gcompiler, err := grok.New(grokConf)
+	// Type hints are extracted and applied by us rather than the grok library
+	// in order to support a wider range of types.
+	patternTypes := map[string]map[string]string{}
+	for k, v := range patterns {
+		var err error
+		types := map[string]string{}
+		if patterns[k], err = grokStripTypes(v, types); err != nil {
+			return nil, fmt.Errorf("pattern '%v': %v", k, err)
+		}
+		patternTypes[k] = types
+	}
+
+	gcompiler, err := grok.New(grok.Config{
+		RemoveEmptyValues:   conf.RemoveEmpty,
+		NamedCapturesOnly:   conf.NamedOnly,
+		SkipDefaultPatterns: !conf.UseDefaults,
+		Patterns:            patterns,
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to create grok compiler: %v", err)
 	}
 
-	var compiled []*grok.CompiledGrok
+	var compiled []grokExpression
 	for _, pattern := range conf.Expressions {
+		types := map[string]string{}
+		stripped, err := grokStripTypes(pattern, types)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compile Grok pattern '%v': %v", pattern, err)
+		}
+		grokCollectTypes(stripped, patterns, patternTypes, types, map[string]struct{}{})
+
 		var gcompiled *grok.CompiledGrok
-		if gcompiled, err = gcompiler.Compile(pattern); err != nil {
+		if gcompiled, err = gcompiler.Compile(stripped); err != nil {
 			return nil, fmt.Errorf("failed to compile Grok pattern '%v': %v", pattern, err)
 		}
-		compiled = append(compiled, gcompiled)
+		compiled = append(compiled, grokExpression{
+			expression: pattern,
+			compiled:   gcompiled,
+			types:      types,
+		})
 	}
 
 	g := &grokProc{
-		gparsers: compiled,
-		log:      mgr.Logger(),
+		gparsers:        compiled,
+		matchedMetadata: conf.MatchedMetadata,
+		log:             mgr.Logger(),
 	}
 	return g, nil
 }
 
+var (
+	grokRefRegexp      = regexp.MustCompile(`%\{(\w+)(?::[^:{}]+)?\}`)
+	grokTypedRefRegexp = regexp.MustCompile(`%\{(\w+):([^:{}]+):(\w+)\}`)
+)
+
+// grokStripTypes removes type hints from pattern references of the form
+// %{NAME:field:type}, adding the type of each field to a map.
+func grokStripTypes(pattern string, types map[string]string) (string, error) {
+	var err error
+	stripped := grokTypedRefRegexp.ReplaceAllStringFunc(pattern, func(ref string) string {
+		groups := grokTypedRefRegexp.FindStringSubmatch(ref)
+		switch groups[3] {
+		case "int", "long", "integer", "float", "double", "bool", "boolean", "string":
+		default:
+			err = fmt.Errorf("unsupported type '%v' for field '%v'", groups[3], groups[2])
+		}
+		types[groups[2]] = groups[3]
+		return "%{" + groups[1] + ":" + groups[2] + "}"
+	})
+	return stripped, err
+}
+
+// grokCollectTypes walks the patterns referenced by a pattern and adds the type
+// hints of each to a map.
+func grokCollectTypes(pattern string, patterns map[string]string, patternTypes map[string]map[string]string, types map[string]string, seen map[string]struct{}) {
+	for _, groups := range grokRefRegexp.FindAllStringSubmatch(pattern, -1) {
+		name := groups[1]
+		if _, exists := seen[name]; exists {
+			continue
+		}
+		seen[name] = struct{}{}
+		for k, v := range patternTypes[name] {
+			if _, exists := types[k]; !exists {
+				types[k] = v
+			}
+		}
+		grokCollectTypes(patterns[name], patterns, patternTypes, types, seen)
+	}
+}
+
+func grokCoerce(value any, kind string) (any, error) {
+	str, ok := value.(string)
+	if !ok {
+		return value, nil
+	}
+	switch kind {
+	case "int", "long", "integer":
+		return strconv.ParseInt(str, 10, 64)
+	case "float", "double":
+		return strconv.ParseFloat(str, 64)
+	case "bool", "boolean":
+		return strconv.ParseBool(str)
+	}
+	return str, nil
+}
+
+func addGrokPatternsFromLibrary(patterns map[string]string) error {
+	return fs.WalkDir(grokPatternLibrary, "grok_patterns", func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		file, err := grokPatternLibrary.Open(p)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		if err := parseGrokPatterns(file, patterns); err != nil {
+			return fmt.Errorf("%v: %w", path.Base(p), err)
+		}
+		return nil
+	})
+}
+
 func addGrokPatternsFromPath(fs ifs.FS, path string, patterns map[string]string) error {
 	if s, err := fs.Stat(path); err != nil {
 		return err
 	} else if s.IsDir() {
-		path += "/*"
+		path += "/**"
 	}
 
 	files, err := service.Globs(fs, path)
@@ -192,38 +352,75 @@ func addGrokPatternsFromPath(fs ifs.FS, path string, patterns map[string]string)
 	}
 
 	for _, f := range files {
-		file, err := fs.Open(f)
-		if err != nil {
+		if s, err := fs.Stat(f); err != nil {
 			return err
+		} else if s.IsDir() {
+			continue
 		}
 
-		scanner := bufio.NewScanner(file)
-
-		for scanner.Scan() {
-			l := scanner.Text()
-			if l != "" && l[0] != '#' {
-				names := strings.SplitN(l, " ", 2)
-				patterns[names[0]] = names[1]
-			}
+		file, err := fs.Open(f)
+		if err != nil {
+			return err
 		}
 
+		err = parseGrokPatterns(file, patterns)
 		file.Close()
+		if err != nil {
+			return fmt.Errorf("%v: %w", f, err)
+		}
 	}
 
 	return nil
 }
 
+// parseGrokPatterns reads pattern definitions in the Logstash format, where
+// each line is a pattern name followed by whitespace and then the pattern.
+func parseGrokPatterns(r io.Reader, patterns map[string]string) error {
+	scanner := bufio.NewScanner(r)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		l := strings.TrimSpace(scanner.Text())
+		if l == "" || l[0] == '#' {
+			continue
+		}
+		i := strings.IndexAny(l, " \t")
+		if i == -1 {
+			return fmt.Errorf("line %v: expected a pattern name followed by a pattern", lineNum)
+		}
+		patterns[l[:i]] = strings.TrimSpace(l[i:])
+	}
+	return scanner.Err()
+}
+
+func (g *grokProc) parse(expr grokExpression, body []byte) (map[string]any, error) {
+	values, err := expr.compiled.ParseTyped(body)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range values {
+		kind, exists := expr.types[k]
+		if !exists {
+			continue
+		}
+		if values[k], err = grokCoerce(v, kind); err != nil {
+			return nil, fmt.Errorf("field '%v': %w", k, err)
+		}
+	}
+	return values, nil
+}
+
 func (g *grokProc) Process(ctx context.Context, msg *message.Part) ([]*message.Part, error) {
 	body := msg.AsBytes()
 
 	var values map[string]any
-	for _, compiler := range g.gparsers {
+	var matched string
+	for _, expr := range g.gparsers {
 		var err error
-		if values, err = compiler.ParseTyped(body); err != nil {
+		if values, err = g.parse(expr, body); err != nil {
 			g.log.Debug("Failed to parse body: %v\n", err)
 			continue
 		}
 		if len(values) > 0 {
+			matched = expr.expression
 			break
 		}
 	}
@@ -238,6 +435,9 @@ func (g *grokProc) Process(ctx context.Context, msg *message.Part) ([]*message.P
 	}
 
 	msg.SetStructuredMut(gObj.Data())
+	if g.matchedMetadata != "" {
+		msg.MetaSetMut(g.matchedMetadata, matched)
+	}
 	return []*message.Part{msg}, nil
 }
 
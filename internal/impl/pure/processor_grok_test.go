@@ -166,3 +166,133 @@ grok:
 	require.Len(t, msgs, 1)
 	assert.Equal(t, `{"nested":{"first":10,"second":"foo","third":"bar"}}`, string(msgs[0].Get(0).AsBytes()))
 }
+
+func TestGrokTypeCoercion(t *testing.T) {
+	conf, err := testutil.ProcessorFromYAML(`
+grok:
+  expressions:
+    - "%%{WORD:enabled:bool} %%{NUMBER:ratio:double} %%{INT:count:long} %%{INT:id:string}"
+    - "%%{WORD:fallback}"
+  matched_expression_metadata: grok_expression
+`)
+	require.NoError(t, err)
+
+	gSet, err := mock.NewManager().NewProcessor(conf)
+	require.NoError(t, err)
+
+	inMsg := message.QuickBatch([][]byte{
+		[]byte(`true 0.5 10 0042`),
+		[]byte(`nope 0.5 10 0042`),
+	})
+	msgs, res := gSet.ProcessBatch(context.Background(), inMsg)
+	require.NoError(t, res)
+	require.Len(t, msgs, 1)
+	require.Equal(t, 2, msgs[0].Len())
+
+	assert.Equal(t, `{"count":10,"enabled":true,"id":"0042","ratio":0.5}`, string(msgs[0].Get(0).AsBytes()))
+	assert.Equal(t, "%{WORD:enabled:bool} %{NUMBER:ratio:double} %{INT:count:long} %{INT:id:string}", msgs[0].Get(0).MetaGetStr("grok_expression"))
+
+	assert.Equal(t, `{"fallback":"nope"}`, string(msgs[0].Get(1).AsBytes()))
+	assert.Equal(t, "%{WORD:fallback}", msgs[0].Get(1).MetaGetStr("grok_expression"))
+}
+
+func TestGrokUnsupportedType(t *testing.T) {
+	conf, err := testutil.ProcessorFromYAML(`
+grok:
+  expressions:
+    - "%%{WORD:first:date}"
+`)
+	require.NoError(t, err)
+
+	_, err = mock.NewManager().NewProcessor(conf)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unsupported type 'date'")
+}
+
+func TestGrokPatternLibrary(t *testing.T) {
+	conf, err := testutil.ProcessorFromYAML(`
+grok:
+  expressions:
+    - "%%{HTTPD_COMMONLOG}"
+`)
+	require.NoError(t, err)
+
+	gSet, err := mock.NewManager().NewProcessor(conf)
+	require.NoError(t, err)
+
+	inMsg := message.QuickBatch([][]byte{[]byte(`127.0.0.1 - frank [10/Oct/2000:13:55:36 -0700] "GET /apache_pb.gif HTTP/1.0" 200 2326`)})
+	msgs, res := gSet.ProcessBatch(context.Background(), inMsg)
+	require.NoError(t, res)
+	require.Len(t, msgs, 1)
+
+	v, err := msgs[0].Get(0).AsStructured()
+	require.NoError(t, err)
+
+	obj := gabs.Wrap(v)
+	assert.Equal(t, "127.0.0.1", obj.S("clientip").Data())
+	assert.Equal(t, "frank", obj.S("auth").Data())
+	assert.Equal(t, "GET", obj.S("verb").Data())
+	assert.Equal(t, "/apache_pb.gif", obj.S("request").Data())
+	assert.Equal(t, int64(200), obj.S("response").Data())
+	assert.Equal(t, int64(2326), obj.S("bytes").Data())
+}
+
+func TestGrokPatternLibraryDisabled(t *testing.T) {
+	conf, err := testutil.ProcessorFromYAML(`
+grok:
+  expressions:
+    - "%%{HTTPD_COMMONLOG}"
+  use_default_patterns: false
+`)
+	require.NoError(t, err)
+
+	_, err = mock.NewManager().NewProcessor(conf)
+	require.Error(t, err)
+}
+
+func TestGrokDirectoryImports(t *testing.T) {
+	tmpDir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(tmpDir, "nested"), 0o777))
+
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "base"), []byte(`
+# Base patterns
+BARWORD	bar
+`), 0o777))
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "nested", "foos"), []byte(`
+  FOOBAR   %{WORD:first} %{BARWORD:second} %{INT:third:int}
+`), 0o777))
+
+	conf, err := testutil.ProcessorFromYAML(`
+grok:
+  expressions:
+    - "%%{FOOBAR}"
+  pattern_paths: [ %v ]
+`, tmpDir)
+	require.NoError(t, err)
+
+	gSet, err := mock.NewManager().NewProcessor(conf)
+	require.NoError(t, err)
+
+	inMsg := message.QuickBatch([][]byte{[]byte(`foo bar 5`)})
+	msgs, res := gSet.ProcessBatch(context.Background(), inMsg)
+	require.NoError(t, res)
+	require.Len(t, msgs, 1)
+	assert.Equal(t, `{"first":"foo","second":"bar","third":5}`, string(msgs[0].Get(0).AsBytes()))
+}
+
+func TestGrokMalformedPatternFile(t *testing.T) {
+	tmpDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "bad"), []byte("FOO %{WORD}\nBAR\n"), 0o777))
+
+	conf, err := testutil.ProcessorFromYAML(`
+grok:
+  expressions:
+    - "%%{FOO}"
+  pattern_paths: [ %v ]
+`, tmpDir)
+	require.NoError(t, err)
+
+	_, err = mock.NewManager().NewProcessor(conf)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "line 2")
+}
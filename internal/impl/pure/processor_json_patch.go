@@ -0,0 +1,504 @@
+package pure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/Jeffail/gabs/v2"
+
+	"github.com/benthosdev/benthos/v4/internal/value"
+	"github.com/benthosdev/benthos/v4/public/bloblang"
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	jppFieldPatch         = "patch"
+	jppFieldFormat        = "format"
+	jppFieldTarget        = "target"
+	jppFieldOnTestFailure = "on_test_failure"
+
+	jppMetaFailedTest = "json_patch_failed_test"
+)
+
+func jsonPatchProcSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Categories("Mapping").
+		Version("4.28.0").
+		Summary("Applies [JSON Patch](https://datatracker.ietf.org/doc/html/rfc6902) or [JSON Merge Patch](https://datatracker.ietf.org/doc/html/rfc7386) documents to messages.").
+		Description(`
+The patch applied to each message is the result of the Bloblang mapping `+"`patch`"+`, which can extract the patch from a field of the message, parse it from metadata, or return a static patch written within the config. Patches that are strings are parsed as JSON.
+
+The operations of a JSON Patch are applied atomically, and when any operation fails the message is left unchanged and flagged as having failed, allowing you to use [standard processor error handling patterns](/docs/configuration/error_handling).
+
+### Test Operations
+
+When a `+"`test`"+` operation of a JSON Patch fails the index of the operation is added to the message as the metadata field `+"`"+jppMetaFailedTest+"`"+`, and the message is handled according to `+"`on_test_failure`"+`. This allows messages that fail tests, such as patches of change data capture streams applied to documents of an unexpected version, to be routed separately from other failures.`).
+		Fields(
+			service.NewBloblangField(jppFieldPatch).
+				Description("A [Bloblang mapping](/docs/guides/bloblang/about) that returns the patch to apply to each message.").
+				Example(`root = this.patch`).
+				Example(`root = @patch`).
+				Example(`root = [{"op":"replace","path":"/status","value":"archived"}]`),
+			service.NewStringAnnotatedEnumField(jppFieldFormat, map[string]string{
+				"json_patch":  "Patches are an array of operations as described in RFC 6902.",
+				"merge_patch": "Patches are a document that is merged into messages as described in RFC 7386, where fields that are `null` are removed.",
+			}).
+				Description("The format of patches.").
+				Default("json_patch"),
+			service.NewStringField(jppFieldTarget).
+				Description("An optional [dot path](/docs/configuration/field_paths) of the document within messages to patch, where the whole message is patched when empty.").
+				Example("after").
+				Default(""),
+			service.NewStringAnnotatedEnumField(jppFieldOnTestFailure, map[string]string{
+				"error": "The message is left unchanged and flagged as having failed.",
+				"skip":  "The message is left unchanged.",
+				"drop":  "The message is dropped.",
+			}).
+				Description("How to handle messages where a `test` operation fails.").
+				Default("error"),
+		).
+		Example("Materialising Patch Streams", "Here we consume a stream of events containing a document and a JSON Patch of changes to it, and replace each event with the patched document. Events where the patch tests for a stale version of the document are routed to a separate topic.", `
+pipeline:
+  processors:
+    - json_patch:
+        patch: 'root = this.patch'
+        target: document
+        on_test_failure: skip
+    - mapping: 'root = if @json_patch_failed_test == null { this.document } else { this }'
+
+output:
+  switch:
+    cases:
+      - check: '@json_patch_failed_test != null'
+        output:
+          kafka_franz:
+            seed_brokers: [ localhost:9092 ]
+            topic: stale_patches
+      - output:
+          kafka_franz:
+            seed_brokers: [ localhost:9092 ]
+            topic: documents
+`).
+		Example("Merge Patches From Metadata", "Here we merge a patch carried in the metadata of messages into their contents.", `
+pipeline:
+  processors:
+    - json_patch:
+        format: merge_patch
+        patch: 'root = @patch'
+`)
+}
+
+func init() {
+	err := service.RegisterProcessor(
+		"json_patch", jsonPatchProcSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Processor, error) {
+			return newJSONPatchProcFromConfig(conf)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type jsonPatchProc struct {
+	patch         *bloblang.Executor
+	mergePatch    bool
+	target        string
+	onTestFailure string
+}
+
+func newJSONPatchProcFromConfig(conf *service.ParsedConfig) (*jsonPatchProc, error) {
+	p := &jsonPatchProc{}
+
+	var err error
+	if p.patch, err = conf.FieldBloblang(jppFieldPatch); err != nil {
+		return nil, err
+	}
+	format, err := conf.FieldString(jppFieldFormat)
+	if err != nil {
+		return nil, err
+	}
+	switch format {
+	case "json_patch":
+	case "merge_patch":
+		p.mergePatch = true
+	default:
+		return nil, fmt.Errorf("unrecognised format: %v", format)
+	}
+	if p.target, err = conf.FieldString(jppFieldTarget); err != nil {
+		return nil, err
+	}
+	if p.onTestFailure, err = conf.FieldString(jppFieldOnTestFailure); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (p *jsonPatchProc) Process(ctx context.Context, msg *service.Message) (service.MessageBatch, error) {
+	patchMsg, err := msg.BloblangQuery(p.patch)
+	if err != nil {
+		return nil, fmt.Errorf("patch mapping failed: %w", err)
+	}
+	if patchMsg == nil {
+		return nil, errors.New("patch mapping failed: root was deleted")
+	}
+
+	// Patches that result from the mapping as a string are parsed as JSON.
+	patch, err := patchMsg.AsStructured()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse patch: %w", err)
+	}
+
+	v, err := msg.AsStructured()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse message as JSON: %w", err)
+	}
+
+	// Patches are applied to a copy of the message so that messages are left
+	// unchanged when an operation fails.
+	doc := gabs.Wrap(value.IClone(v))
+	target := doc.Data()
+	if p.target != "" {
+		target = doc.Path(p.target).Data()
+	}
+
+	var patched any
+	if p.mergePatch {
+		patched = applyMergePatch(target, patch)
+	} else {
+		ops, err := parseJSONPatch(patch)
+		if err != nil {
+			return nil, err
+		}
+		if patched, err = applyJSONPatch(target, ops); err != nil {
+			var tErr *jsonPatchTestError
+			if !errors.As(err, &tErr) {
+				return nil, err
+			}
+			msg.MetaSetMut(jppMetaFailedTest, tErr.index)
+			switch p.onTestFailure {
+			case "skip":
+				return service.MessageBatch{msg}, nil
+			case "drop":
+				return nil, nil
+			}
+			return nil, err
+		}
+	}
+
+	if p.target == "" {
+		msg.SetStructuredMut(patched)
+		return service.MessageBatch{msg}, nil
+	}
+	if _, err := doc.SetP(patched, p.target); err != nil {
+		return nil, fmt.Errorf("field %v: %w", p.target, err)
+	}
+	msg.SetStructuredMut(doc.Data())
+	return service.MessageBatch{msg}, nil
+}
+
+func (p *jsonPatchProc) Close(ctx context.Context) error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+// applyMergePatch merges a patch into a document as described in RFC 7386,
+// modifying the document in place.
+func applyMergePatch(doc, patch any) any {
+	patchObj, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+	docObj, ok := doc.(map[string]any)
+	if !ok {
+		docObj = map[string]any{}
+	}
+	for k, v := range patchObj {
+		if v == nil {
+			delete(docObj, k)
+			continue
+		}
+		docObj[k] = applyMergePatch(docObj[k], v)
+	}
+	return docObj
+}
+
+type jsonPatchOp struct {
+	op    string
+	path  []string
+	from  []string
+	value any
+}
+
+type jsonPatchTestError struct {
+	index int
+	path  string
+}
+
+func (e *jsonPatchTestError) Error() string {
+	return fmt.Sprintf("operation %v: test of %v failed", e.index, e.path)
+}
+
+// parsePointer parses a JSON Pointer as described in RFC 6901 into its
+// reference tokens.
+func parsePointer(p string) ([]string, error) {
+	if p == "" {
+		return nil, nil
+	}
+	if p[0] != '/' {
+		return nil, fmt.Errorf("pointer %q does not start with a slash", p)
+	}
+	tokens := strings.Split(p[1:], "/")
+	for i, t := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(t, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+// parseJSONPatch parses a JSON Patch from a structured value.
+func parseJSONPatch(patch any) ([]jsonPatchOp, error) {
+	arr, ok := patch.([]any)
+	if !ok {
+		return nil, fmt.Errorf("expected patch to be an array, got %T", patch)
+	}
+
+	ops := make([]jsonPatchOp, len(arr))
+	for i, v := range arr {
+		obj, ok := v.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("operation %v: expected object, got %T", i, v)
+		}
+		op := &ops[i]
+		op.op, _ = obj["op"].(string)
+
+		pathStr, ok := obj["path"].(string)
+		if !ok {
+			return nil, fmt.Errorf("operation %v: missing path", i)
+		}
+		var err error
+		if op.path, err = parsePointer(pathStr); err != nil {
+			return nil, fmt.Errorf("operation %v: %w", i, err)
+		}
+
+		switch op.op {
+		case "add", "replace", "test":
+			var exists bool
+			if op.value, exists = obj["value"]; !exists {
+				return nil, fmt.Errorf("operation %v: missing value", i)
+			}
+		case "move", "copy":
+			fromStr, ok := obj["from"].(string)
+			if !ok {
+				return nil, fmt.Errorf("operation %v: missing from", i)
+			}
+			if op.from, err = parsePointer(fromStr); err != nil {
+				return nil, fmt.Errorf("operation %v: %w", i, err)
+			}
+		case "remove":
+		default:
+			return nil, fmt.Errorf("operation %v: unrecognised op %q", i, op.op)
+		}
+	}
+	return ops, nil
+}
+
+func pointerString(tokens []string) string {
+	var b strings.Builder
+	for _, t := range tokens {
+		b.WriteByte('/')
+		b.WriteString(strings.ReplaceAll(strings.ReplaceAll(t, "~", "~0"), "/", "~1"))
+	}
+	return b.String()
+}
+
+// arrayIndex parses a reference token as an index of an array of length n,
+// where the index n is only valid when end is true.
+func arrayIndex(token string, n int, end bool) (int, error) {
+	if end && token == "-" {
+		return n, nil
+	}
+	if token == "" || (len(token) > 1 && token[0] == '0') || strings.TrimLeft(token, "0123456789") != "" {
+		return 0, fmt.Errorf("invalid array index %q", token)
+	}
+	i, err := strconv.Atoi(token)
+	if err != nil || i > n || (i == n && !end) {
+		return 0, fmt.Errorf("array index %v out of bounds", token)
+	}
+	return i, nil
+}
+
+func pointerGet(doc any, tokens []string) (any, error) {
+	for i, t := range tokens {
+		switch c := doc.(type) {
+		case map[string]any:
+			v, exists := c[t]
+			if !exists {
+				return nil, fmt.Errorf("path %v does not exist", pointerString(tokens[:i+1]))
+			}
+			doc = v
+		case []any:
+			idx, err := arrayIndex(t, len(c), false)
+			if err != nil {
+				return nil, fmt.Errorf("path %v: %w", pointerString(tokens[:i+1]), err)
+			}
+			doc = c[idx]
+		default:
+			return nil, fmt.Errorf("path %v does not exist", pointerString(tokens[:i+1]))
+		}
+	}
+	return doc, nil
+}
+
+// pointerUpdate replaces the container referenced by all but the last token
+// of a pointer with the result of fn, which is called with the container and
+// the last token.
+func pointerUpdate(doc any, tokens []string, fn func(container any, token string) (any, error)) (any, error) {
+	if len(tokens) == 0 {
+		return nil, errors.New("path must not be the document root")
+	}
+	parent, err := pointerGet(doc, tokens[:len(tokens)-1])
+	if err != nil {
+		return nil, err
+	}
+	updated, err := fn(parent, tokens[len(tokens)-1])
+	if err != nil {
+		return nil, fmt.Errorf("path %v: %w", pointerString(tokens), err)
+	}
+	if len(tokens) == 1 {
+		return updated, nil
+	}
+
+	grandparent, _ := pointerGet(doc, tokens[:len(tokens)-2])
+	switch c := grandparent.(type) {
+	case map[string]any:
+		c[tokens[len(tokens)-2]] = updated
+	case []any:
+		idx, _ := arrayIndex(tokens[len(tokens)-2], len(c), false)
+		c[idx] = updated
+	}
+	return doc, nil
+}
+
+func pointerAdd(doc any, tokens []string, v any) (any, error) {
+	if len(tokens) == 0 {
+		return v, nil
+	}
+	return pointerUpdate(doc, tokens, func(container any, token string) (any, error) {
+		switch c := container.(type) {
+		case map[string]any:
+			c[token] = v
+			return c, nil
+		case []any:
+			idx, err := arrayIndex(token, len(c), true)
+			if err != nil {
+				return nil, err
+			}
+			c = append(c, nil)
+			copy(c[idx+1:], c[idx:])
+			c[idx] = v
+			return c, nil
+		}
+		return nil, errors.New("parent is not an object or array")
+	})
+}
+
+func pointerReplace(doc any, tokens []string, v any) (any, error) {
+	if len(tokens) == 0 {
+		return v, nil
+	}
+	return pointerUpdate(doc, tokens, func(container any, token string) (any, error) {
+		switch c := container.(type) {
+		case map[string]any:
+			if _, exists := c[token]; !exists {
+				return nil, errors.New("does not exist")
+			}
+			c[token] = v
+			return c, nil
+		case []any:
+			idx, err := arrayIndex(token, len(c), false)
+			if err != nil {
+				return nil, err
+			}
+			c[idx] = v
+			return c, nil
+		}
+		return nil, errors.New("parent is not an object or array")
+	})
+}
+
+func pointerRemove(doc any, tokens []string) (any, error) {
+	return pointerUpdate(doc, tokens, func(container any, token string) (any, error) {
+		switch c := container.(type) {
+		case map[string]any:
+			if _, exists := c[token]; !exists {
+				return nil, errors.New("does not exist")
+			}
+			delete(c, token)
+			return c, nil
+		case []any:
+			idx, err := arrayIndex(token, len(c), false)
+			if err != nil {
+				return nil, err
+			}
+			return append(c[:idx], c[idx+1:]...), nil
+		}
+		return nil, errors.New("parent is not an object or array")
+	})
+}
+
+func isPointerPrefix(prefix, tokens []string) bool {
+	if len(prefix) >= len(tokens) {
+		return false
+	}
+	for i, t := range prefix {
+		if tokens[i] != t {
+			return false
+		}
+	}
+	return true
+}
+
+// applyJSONPatch applies the operations of a JSON Patch to a document as
+// described in RFC 6902, modifying the document in place. A
+// *jsonPatchTestError is returned when a test operation fails.
+func applyJSONPatch(doc any, ops []jsonPatchOp) (any, error) {
+	for i, op := range ops {
+		var err error
+		switch op.op {
+		case "add":
+			doc, err = pointerAdd(doc, op.path, value.IClone(op.value))
+		case "remove":
+			doc, err = pointerRemove(doc, op.path)
+		case "replace":
+			doc, err = pointerReplace(doc, op.path, value.IClone(op.value))
+		case "move":
+			if isPointerPrefix(op.from, op.path) {
+				err = errors.New("cannot move a value into one of its children")
+				break
+			}
+			var v any
+			if v, err = pointerGet(doc, op.from); err == nil && len(op.from) > 0 {
+				if doc, err = pointerRemove(doc, op.from); err == nil {
+					doc, err = pointerAdd(doc, op.path, v)
+				}
+			}
+		case "copy":
+			var v any
+			if v, err = pointerGet(doc, op.from); err == nil {
+				doc, err = pointerAdd(doc, op.path, value.IClone(v))
+			}
+		case "test":
+			var v any
+			if v, err = pointerGet(doc, op.path); err != nil || !value.ICompare(v, op.value) {
+				return nil, &jsonPatchTestError{index: i, path: pointerString(op.path)}
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("operation %v: %w", i, err)
+		}
+	}
+	return doc, nil
+}
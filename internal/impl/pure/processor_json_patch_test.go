@@ -0,0 +1,250 @@
+package pure
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func testJSONPatchProc(t *testing.T, confStr string) *jsonPatchProc {
+	t.Helper()
+
+	conf, err := jsonPatchProcSpec().ParseYAML(confStr, nil)
+	require.NoError(t, err)
+
+	proc, err := newJSONPatchProcFromConfig(conf)
+	require.NoError(t, err)
+	return proc
+}
+
+func TestApplyJSONPatch(t *testing.T) {
+	// Taken from the examples of RFC 6902.
+	for _, test := range []struct {
+		doc    string
+		patch  string
+		output string
+		errStr string
+	}{
+		{
+			doc:    `{"foo":"bar"}`,
+			patch:  `[{"op":"add","path":"/baz","value":"qux"}]`,
+			output: `{"baz":"qux","foo":"bar"}`,
+		},
+		{
+			doc:    `{"foo":["bar","baz"]}`,
+			patch:  `[{"op":"add","path":"/foo/1","value":"qux"}]`,
+			output: `{"foo":["bar","qux","baz"]}`,
+		},
+		{
+			doc:    `{"baz":"qux","foo":"bar"}`,
+			patch:  `[{"op":"remove","path":"/baz"}]`,
+			output: `{"foo":"bar"}`,
+		},
+		{
+			doc:    `{"foo":["bar","qux","baz"]}`,
+			patch:  `[{"op":"remove","path":"/foo/1"}]`,
+			output: `{"foo":["bar","baz"]}`,
+		},
+		{
+			doc:    `{"baz":"qux","foo":"bar"}`,
+			patch:  `[{"op":"replace","path":"/baz","value":"boo"}]`,
+			output: `{"baz":"boo","foo":"bar"}`,
+		},
+		{
+			doc:    `{"foo":{"bar":"baz","waldo":"fred"},"qux":{"corge":"grault"}}`,
+			patch:  `[{"op":"move","from":"/foo/waldo","path":"/qux/thud"}]`,
+			output: `{"foo":{"bar":"baz"},"qux":{"corge":"grault","thud":"fred"}}`,
+		},
+		{
+			doc:    `{"foo":["all","grass","cows","eat"]}`,
+			patch:  `[{"op":"move","from":"/foo/1","path":"/foo/3"}]`,
+			output: `{"foo":["all","cows","eat","grass"]}`,
+		},
+		{
+			doc:    `{"foo":{"bar":[1,2]}}`,
+			patch:  `[{"op":"copy","from":"/foo/bar","path":"/baz"},{"op":"add","path":"/baz/-","value":3}]`,
+			output: `{"foo":{"bar":[1,2]},"baz":[1,2,3]}`,
+		},
+		{
+			doc:    `{"baz":"qux","foo":["a",2,"c"]}`,
+			patch:  `[{"op":"test","path":"/baz","value":"qux"},{"op":"test","path":"/foo/1","value":2}]`,
+			output: `{"baz":"qux","foo":["a",2,"c"]}`,
+		},
+		{
+			doc:    `{"/":9,"~1":10}`,
+			patch:  `[{"op":"test","path":"/~01","value":10},{"op":"replace","path":"/~1","value":1}]`,
+			output: `{"/":1,"~1":10}`,
+		},
+		{
+			doc:    `{"foo":"bar"}`,
+			patch:  `[{"op":"add","path":"","value":[1]}]`,
+			output: `[1]`,
+		},
+		{
+			doc:    `{"baz":"qux"}`,
+			patch:  `[{"op":"test","path":"/baz","value":"bar"}]`,
+			errStr: "operation 0: test of /baz failed",
+		},
+		{
+			doc:    `{"foo":"bar"}`,
+			patch:  `[{"op":"add","path":"/baz/bat","value":"qux"}]`,
+			errStr: "operation 0: path /baz does not exist",
+		},
+		{
+			doc:    `{"foo":["bar"]}`,
+			patch:  `[{"op":"add","path":"/foo/01","value":"qux"}]`,
+			errStr: "invalid array index",
+		},
+		{
+			doc:    `{"foo":["bar"]}`,
+			patch:  `[{"op":"replace","path":"/foo/1","value":"qux"}]`,
+			errStr: "out of bounds",
+		},
+		{
+			doc:    `{"foo":{"bar":1}}`,
+			patch:  `[{"op":"move","from":"/foo","path":"/foo/bar/baz"}]`,
+			errStr: "cannot move a value into one of its children",
+		},
+		{
+			doc:    `{"foo":"bar"}`,
+			patch:  `[{"op":"add","path":"/baz"}]`,
+			errStr: "operation 0: missing value",
+		},
+	} {
+		var doc, patch any
+		require.NoError(t, json.Unmarshal([]byte(test.doc), &doc))
+		require.NoError(t, json.Unmarshal([]byte(test.patch), &patch))
+
+		ops, err := parseJSONPatch(patch)
+		if err == nil {
+			doc, err = applyJSONPatch(doc, ops)
+		}
+		if test.errStr != "" {
+			assert.ErrorContains(t, err, test.errStr, test.patch)
+			continue
+		}
+		require.NoError(t, err, test.patch)
+
+		b, err := json.Marshal(doc)
+		require.NoError(t, err)
+		assert.JSONEq(t, test.output, string(b), test.patch)
+	}
+}
+
+func TestApplyMergePatch(t *testing.T) {
+	// Taken from the examples of RFC 7386.
+	for _, test := range [][3]string{
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{`{"a":"b"}`, `{"a":null}`, `{}`},
+		{`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"c"}`, `{"a":["b"]}`, `{"a":["b"]}`},
+		{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{`["a","b"]`, `["c","d"]`, `["c","d"]`},
+		{`{"a":"b"}`, `["c"]`, `["c"]`},
+		{`{"e":null}`, `{"a":1}`, `{"e":null,"a":1}`},
+		{`[1,2]`, `{"a":"b","c":null}`, `{"a":"b"}`},
+		{`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+	} {
+		var doc, patch any
+		require.NoError(t, json.Unmarshal([]byte(test[0]), &doc))
+		require.NoError(t, json.Unmarshal([]byte(test[1]), &patch))
+
+		b, err := json.Marshal(applyMergePatch(doc, patch))
+		require.NoError(t, err)
+		assert.JSONEq(t, test[2], string(b), test[1])
+	}
+}
+
+func TestJSONPatchProcessor(t *testing.T) {
+	proc := testJSONPatchProc(t, `
+patch: 'root = this.patch'
+target: document
+`)
+
+	res, err := proc.Process(context.Background(), service.NewMessage([]byte(`{
+  "document": {"id":"a","tags":["x"]},
+  "patch": [{"op":"add","path":"/tags/-","value":"y"},{"op":"replace","path":"/id","value":"b"}]
+}`)))
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+
+	b, err := res[0].AsBytes()
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+  "document": {"id":"b","tags":["x","y"]},
+  "patch": [{"op":"add","path":"/tags/-","value":"y"},{"op":"replace","path":"/id","value":"b"}]
+}`, string(b))
+
+	// Messages are left unchanged when any operation fails.
+	input := `{"document":{"id":"a"},"patch":[{"op":"remove","path":"/id"},{"op":"remove","path":"/nope"}]}`
+	msg := service.NewMessage([]byte(input))
+	_, err = proc.Process(context.Background(), msg)
+	assert.ErrorContains(t, err, "operation 1: path /nope: does not exist")
+
+	b, err = msg.AsBytes()
+	require.NoError(t, err)
+	assert.JSONEq(t, input, string(b))
+}
+
+func TestJSONPatchProcessorMergePatch(t *testing.T) {
+	proc := testJSONPatchProc(t, `
+patch: 'root = @patch'
+format: merge_patch
+`)
+
+	msg := service.NewMessage([]byte(`{"title":"Hello","author":{"givenName":"John","familyName":"Doe"},"tags":["example","sample"]}`))
+	msg.MetaSetMut("patch", `{"title":"Hello!","author":{"familyName":null},"tags":["example"]}`)
+
+	res, err := proc.Process(context.Background(), msg)
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+
+	b, err := res[0].AsBytes()
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"title":"Hello!","author":{"givenName":"John"},"tags":["example"]}`, string(b))
+}
+
+func TestJSONPatchProcessorTestFailures(t *testing.T) {
+	input := `{"version":2}`
+	patch := `[{"op":"replace","path":"/version","value":3},{"op":"test","path":"/version","value":2}]`
+
+	for _, test := range []struct {
+		mode    string
+		outputs int
+		errStr  string
+	}{
+		{mode: "error", errStr: "operation 1: test of /version failed"},
+		{mode: "skip", outputs: 1},
+		{mode: "drop", outputs: 0},
+	} {
+		proc := testJSONPatchProc(t, `
+patch: 'root = `+patch+`'
+on_test_failure: `+test.mode+`
+`)
+
+		msg := service.NewMessage([]byte(input))
+		res, err := proc.Process(context.Background(), msg)
+		if test.errStr != "" {
+			assert.EqualError(t, err, test.errStr, test.mode)
+		} else {
+			require.NoError(t, err, test.mode)
+		}
+		assert.Len(t, res, test.outputs, test.mode)
+
+		failed, exists := msg.MetaGetMut("json_patch_failed_test")
+		assert.True(t, exists, test.mode)
+		assert.Equal(t, 1, failed, test.mode)
+
+		b, err := msg.AsBytes()
+		require.NoError(t, err)
+		assert.JSONEq(t, input, string(b), test.mode)
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/benthosdev/benthos/v4/internal/bundle"
 	"github.com/benthosdev/benthos/v4/internal/component/interop"
 	"github.com/benthosdev/benthos/v4/internal/component/processor"
+	"github.com/benthosdev/benthos/v4/internal/filepath"
 	"github.com/benthosdev/benthos/v4/internal/filepath/ifs"
 	"github.com/benthosdev/benthos/v4/internal/log"
 	"github.com/benthosdev/benthos/v4/internal/message"
@@ -18,8 +19,10 @@ import (
 )
 
 const (
-	jschemaPFieldSchemaPath = "schema_path"
-	jschemaPFieldSchema     = "schema"
+	jschemaPFieldSchemaPath         = "schema_path"
+	jschemaPFieldSchema             = "schema"
+	jschemaPFieldSchemaBundle       = "schema_bundle"
+	jschemaPFieldViolationsMetadata = "violations_metadata"
 )
 
 func jschemaProcSpec() *service.ConfigSpec {
@@ -27,7 +30,33 @@ func jschemaProcSpec() *service.ConfigSpec {
 		Categories("Mapping").
 		Stable().
 		Summary(`Checks messages against a provided JSONSchema definition but does not change the payload under any circumstances. If a message does not match the schema it can be caught using error handling methods outlined [here](/docs/configuration/error_handling).`).
-		Description(`Please refer to the [JSON Schema website](https://json-schema.org/) for information and tutorials regarding the syntax of the schema.`).
+		Description(`Please refer to the [JSON Schema website](https://json-schema.org/) for information and tutorials regarding the syntax of the schema.
+
+### Drafts
+
+Schemas of drafts 4, 6 and 7 are supported, as well as drafts 2019-09 and 2020-12, which must be declared with the `+"`$schema`"+` keyword of each document. Documents of drafts 2019-09 and 2020-12 are translated into their draft 7 equivalent before use, where `+"`$defs`"+`, `+"`prefixItems`"+`, `+"`dependentRequired`"+`, `+"`dependentSchemas`"+`, `+"`$anchor`"+` and references with adjacent keywords are supported. The keywords `+"`unevaluatedProperties`"+`, `+"`unevaluatedItems`"+`, `+"`minContains`"+`, `+"`maxContains`"+` and dynamic or recursive references are not supported, and schemas containing them fail to load.
+
+### References
+
+A `+"`$ref`"+` to another document is resolved by fetching it from its URL, which can be a `+"`file://`"+`, `+"`http://`"+` or `+"`https://`"+` URL. Documents listed in the field `+"`schema_bundle`"+` are loaded in advance and registered by their `+"`$id`"+`, allowing references to their identifiers to be resolved without fetching them.
+
+### Violations
+
+When the field `+"`violations_metadata`"+` is set the violations of a document that fails validation are added to the metadata key of that name as an array of objects, each containing the `+"`path`"+` of the violation within the document as a JSON pointer, the schema `+"`keyword`"+` that was violated, and a `+"`message`"+` describing the violation. This allows invalid documents to be routed with the context of each violation:
+
+`+"```yaml"+`
+pipeline:
+  processors:
+    - json_schema:
+        schema_path: file://./schemas/order.json
+        violations_metadata: violations
+    - catch:
+        - mapping: |
+            meta violations = deleted()
+            root.document = this
+            root.violations = @violations
+`+"```"+`
+`).
 		Footnotes(`
 ## Examples
 
@@ -86,6 +115,18 @@ dropped.`).
 			service.NewStringField(jschemaPFieldSchemaPath).
 				Description("The path of a schema document to apply. Use either this or the `schema` field.").
 				Optional(),
+			service.NewStringListField(jschemaPFieldSchemaBundle).
+				Description("A list of schema documents to load in advance and register by their `$id`, allowing references to them to be resolved locally. Each item is either a `file://` path, which may contain glob patterns, or an `http://` or `https://` URL.").
+				Example([]string{"file://./schemas/*.json"}).
+				Advanced().
+				Version("4.28.0").
+				Default([]string{}),
+			service.NewStringField(jschemaPFieldViolationsMetadata).
+				Description("An optional metadata key to add the violations of documents that fail validation to, as an array of objects containing the `path`, `keyword` and `message` of each violation.").
+				Example("violations").
+				Advanced().
+				Version("4.28.0").
+				Default(""),
 		)
 }
 
@@ -95,8 +136,16 @@ func init() {
 		func(conf *service.ParsedConfig, res *service.Resources) (service.BatchProcessor, error) {
 			schemaStr, _ := conf.FieldString(jschemaPFieldSchema)
 			schemaPath, _ := conf.FieldString(jschemaPFieldSchemaPath)
+			schemaBundle, err := conf.FieldStringList(jschemaPFieldSchemaBundle)
+			if err != nil {
+				return nil, err
+			}
+			violationsMeta, err := conf.FieldString(jschemaPFieldViolationsMetadata)
+			if err != nil {
+				return nil, err
+			}
 			mgr := interop.UnwrapManagement(res)
-			p, err := newJSONSchema(schemaStr, schemaPath, mgr)
+			p, err := newJSONSchema(schemaStr, schemaPath, schemaBundle, violationsMeta, mgr)
 			if err != nil {
 				return nil, err
 			}
@@ -108,36 +157,55 @@ func init() {
 }
 
 type jsonSchemaProc struct {
-	log    log.Modular
-	schema *jsonschema.Schema
+	log            log.Modular
+	schema         *jsonschema.Schema
+	violationsMeta string
 }
 
-func newJSONSchema(schemaStr, schemaPath string, mgr bundle.NewManagement) (processor.AutoObserved, error) {
-	var schema *jsonschema.Schema
-	var err error
+func newJSONSchema(schemaStr, schemaPath string, schemaBundle []string, violationsMeta string, mgr bundle.NewManagement) (processor.AutoObserved, error) {
+	httpFS := ifs.ToHTTP(mgr.FS())
 
-	// load JSONSchema definition
-	if schemaPath := schemaPath; schemaPath != "" {
-		if !(strings.HasPrefix(schemaPath, "file://") || strings.HasPrefix(schemaPath, "http://")) {
-			return nil, errors.New("invalid schema_path provided, must start with file:// or http://")
+	sl := jsonschema.NewSchemaLoader()
+	for _, b := range schemaBundle {
+		if strings.HasPrefix(b, "http://") || strings.HasPrefix(b, "https://") {
+			if err := sl.AddSchemas(jschemaTranslatingLoader{jsonschema.NewReferenceLoader(b)}); err != nil {
+				return nil, fmt.Errorf("failed to load JSON schema bundle document %v: %v", b, err)
+			}
+			continue
 		}
-
-		schema, err = jsonschema.NewSchema(jsonschema.NewReferenceLoaderFileSystem(schemaPath, ifs.ToHTTP(mgr.FS())))
+		paths, err := filepath.Globs(mgr.FS(), []string{strings.TrimPrefix(b, "file://")})
 		if err != nil {
-			return nil, fmt.Errorf("failed to load JSON schema definition: %v", err)
+			return nil, fmt.Errorf("failed to resolve schema bundle path %v: %v", b, err)
 		}
-	} else if schemaStr != "" {
-		schema, err = jsonschema.NewSchema(jsonschema.NewStringLoader(schemaStr))
-		if err != nil {
-			return nil, fmt.Errorf("failed to load JSON schema definition: %v", err)
+		for _, p := range paths {
+			if err := sl.AddSchemas(jschemaTranslatingLoader{jsonschema.NewReferenceLoaderFileSystem("file://"+p, httpFS)}); err != nil {
+				return nil, fmt.Errorf("failed to load JSON schema bundle document %v: %v", p, err)
+			}
 		}
+	}
+
+	// load JSONSchema definition
+	var rootLoader jsonschema.JSONLoader
+	if schemaPath := schemaPath; schemaPath != "" {
+		if !(strings.HasPrefix(schemaPath, "file://") || strings.HasPrefix(schemaPath, "http://") || strings.HasPrefix(schemaPath, "https://")) {
+			return nil, errors.New("invalid schema_path provided, must start with file://, http:// or https://")
+		}
+		rootLoader = jsonschema.NewReferenceLoaderFileSystem(schemaPath, httpFS)
+	} else if schemaStr != "" {
+		rootLoader = jsonschema.NewStringLoader(schemaStr)
 	} else {
 		return nil, errors.New("either schema or schema_path must be provided")
 	}
 
+	schema, err := sl.Compile(jschemaTranslatingLoader{rootLoader})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load JSON schema definition: %v", err)
+	}
+
 	return &jsonSchemaProc{
-		log:    mgr.Logger(),
-		schema: schema,
+		log:            mgr.Logger(),
+		schema:         schema,
+		violationsMeta: violationsMeta,
 	}, nil
 }
 
@@ -162,6 +230,7 @@ func (s *jsonSchemaProc) Process(ctx context.Context, part *message.Part) ([]*me
 	if !result.Valid() {
 		s.log.Debug("The document is not valid")
 		var errStr string
+		var violations []any
 		for i, desc := range result.Errors() {
 			if i > 0 {
 				errStr += "\n"
@@ -171,8 +240,16 @@ func (s *jsonSchemaProc) Process(ctx context.Context, part *message.Part) ([]*me
 				description = property.(string) + strings.TrimPrefix(description, strings.ToLower(property.(string)))
 			}
 			errStr += desc.Field() + " " + description
+			violations = append(violations, map[string]any{
+				"path":    jschemaViolationPath(desc.Context()),
+				"keyword": jschemaViolationKeyword(desc.Type()),
+				"message": desc.Description(),
+			})
 		}
-		return nil, errors.New(errStr)
+		if s.violationsMeta != "" {
+			part.MetaSetMut(s.violationsMeta, violations)
+		}
+		return nil, message.ErrorWithClass(errors.New(errStr), message.ErrorClassValidation)
 	}
 
 	s.log.Debug("The document is valid")
@@ -182,3 +259,59 @@ func (s *jsonSchemaProc) Process(ctx context.Context, part *message.Part) ([]*me
 func (s *jsonSchemaProc) Close(context.Context) error {
 	return nil
 }
+
+// jschemaViolationPath returns the location of a violation as a JSON pointer.
+func jschemaViolationPath(c *jsonschema.JsonContext) string {
+	if c == nil {
+		return ""
+	}
+	// The first segment of a context is always the root.
+	segments := strings.Split(c.String("\x00"), "\x00")[1:]
+	var path string
+	for _, seg := range segments {
+		path += "/" + jschemaEscapeToken(seg)
+	}
+	return path
+}
+
+var jschemaErrorKeywords = map[string]string{
+	"false":                           "false",
+	"required":                        "required",
+	"invalid_type":                    "type",
+	"number_any_of":                   "anyOf",
+	"number_one_of":                   "oneOf",
+	"number_all_of":                   "allOf",
+	"number_not":                      "not",
+	"missing_dependency":              "dependencies",
+	"const":                           "const",
+	"enum":                            "enum",
+	"array_no_additional_items":       "additionalItems",
+	"array_min_items":                 "minItems",
+	"array_max_items":                 "maxItems",
+	"unique":                          "uniqueItems",
+	"contains":                        "contains",
+	"array_min_properties":            "minProperties",
+	"array_max_properties":            "maxProperties",
+	"additional_property_not_allowed": "additionalProperties",
+	"invalid_property_pattern":        "patternProperties",
+	"invalid_property_name":           "propertyNames",
+	"string_gte":                      "minLength",
+	"string_lte":                      "maxLength",
+	"pattern":                         "pattern",
+	"format":                          "format",
+	"multiple_of":                     "multipleOf",
+	"number_gte":                      "minimum",
+	"number_gt":                       "exclusiveMinimum",
+	"number_lte":                      "maximum",
+	"number_lt":                       "exclusiveMaximum",
+	"condition_then":                  "then",
+	"condition_else":                  "else",
+}
+
+// jschemaViolationKeyword returns the schema keyword of a violation type.
+func jschemaViolationKeyword(errType string) string {
+	if k, exists := jschemaErrorKeywords[errType]; exists {
+		return k
+	}
+	return errType
+}
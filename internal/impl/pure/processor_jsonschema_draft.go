@@ -0,0 +1,199 @@
+package pure
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	jsonschema "github.com/xeipuuv/gojsonschema"
+)
+
+const jschemaDraft07URL = "http://json-schema.org/draft-07/schema#"
+
+// jschemaUnsupportedKeywords are keywords of drafts 2019-09 and 2020-12 that
+// have no equivalent within draft-07, and therefore cannot be translated.
+var jschemaUnsupportedKeywords = []string{
+	"unevaluatedProperties", "unevaluatedItems",
+	"$dynamicRef", "$dynamicAnchor",
+	"$recursiveRef", "$recursiveAnchor",
+	"minContains", "maxContains",
+}
+
+// jschemaTranslatingLoader wraps a loader in order to translate documents
+// declaring draft 2019-09 or 2020-12 into their draft-07 equivalent, which is
+// the latest draft supported by the validator. Documents loaded whilst
+// resolving references are translated as well.
+type jschemaTranslatingLoader struct {
+	jsonschema.JSONLoader
+}
+
+func (l jschemaTranslatingLoader) LoadJSON() (any, error) {
+	doc, err := l.JSONLoader.LoadJSON()
+	if err != nil {
+		return nil, err
+	}
+	return jschemaTranslateDraft(doc)
+}
+
+func (l jschemaTranslatingLoader) LoaderFactory() jsonschema.JSONLoaderFactory {
+	return jschemaTranslatingFactory{JSONLoaderFactory: l.JSONLoader.LoaderFactory()}
+}
+
+type jschemaTranslatingFactory struct {
+	jsonschema.JSONLoaderFactory
+}
+
+func (f jschemaTranslatingFactory) New(source string) jsonschema.JSONLoader {
+	return jschemaTranslatingLoader{JSONLoader: f.JSONLoaderFactory.New(source)}
+}
+
+//------------------------------------------------------------------------------
+
+func jschemaIsModernDraft(doc map[string]any) bool {
+	s, _ := doc["$schema"].(string)
+	s = strings.TrimSuffix(s, "#")
+	s = strings.TrimPrefix(strings.TrimPrefix(s, "https://"), "http://")
+	return s == "json-schema.org/draft/2020-12/schema" || s == "json-schema.org/draft/2019-09/schema"
+}
+
+func jschemaEscapeToken(s string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(s, "~", "~0"), "/", "~1")
+}
+
+// jschemaWalk calls fn with each schema object of a draft-07 document before
+// walking its subschemas, and therefore fn may rewrite the keywords of a schema
+// into their draft-07 form before they are walked.
+func jschemaWalk(v any, ptr string, fn func(m map[string]any, ptr string) error) error {
+	m, ok := v.(map[string]any)
+	if !ok {
+		return nil
+	}
+	if err := fn(m, ptr); err != nil {
+		return err
+	}
+	for k, child := range m {
+		childPtr := ptr + "/" + jschemaEscapeToken(k)
+		switch k {
+		case "properties", "patternProperties", "definitions", "dependencies":
+			if cm, ok := child.(map[string]any); ok {
+				for ck, cv := range cm {
+					if err := jschemaWalk(cv, childPtr+"/"+jschemaEscapeToken(ck), fn); err != nil {
+						return err
+					}
+				}
+			}
+		case "items", "allOf", "anyOf", "oneOf",
+			"additionalItems", "additionalProperties", "contains", "propertyNames", "not", "if", "then", "else":
+			if arr, ok := child.([]any); ok {
+				for i, cv := range arr {
+					if err := jschemaWalk(cv, childPtr+"/"+strconv.Itoa(i), fn); err != nil {
+						return err
+					}
+				}
+			} else if err := jschemaWalk(child, childPtr, fn); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// jschemaTranslateDraft rewrites a schema document declaring draft 2019-09 or
+// 2020-12 into draft-07, returning documents of other drafts unchanged.
+func jschemaTranslateDraft(doc any) (any, error) {
+	root, ok := doc.(map[string]any)
+	if !ok || !jschemaIsModernDraft(root) {
+		return doc, nil
+	}
+	root["$schema"] = jschemaDraft07URL
+
+	anchors := map[string]string{}
+	if err := jschemaWalk(root, "", func(m map[string]any, ptr string) error {
+		for _, k := range jschemaUnsupportedKeywords {
+			if _, exists := m[k]; exists {
+				return fmt.Errorf("keyword %v at '#%v' is not supported", k, ptr)
+			}
+		}
+		if defs, ok := m["$defs"].(map[string]any); ok {
+			if existing, ok := m["definitions"].(map[string]any); ok {
+				for k, v := range defs {
+					existing[k] = v
+				}
+			} else {
+				m["definitions"] = defs
+			}
+			delete(m, "$defs")
+		}
+		if prefix, ok := m["prefixItems"].([]any); ok {
+			if items, exists := m["items"]; exists {
+				m["additionalItems"] = items
+			}
+			m["items"] = prefix
+			delete(m, "prefixItems")
+		}
+		for _, k := range []string{"dependentRequired", "dependentSchemas"} {
+			deps, ok := m[k].(map[string]any)
+			if !ok {
+				continue
+			}
+			existing, ok := m["dependencies"].(map[string]any)
+			if !ok {
+				existing = map[string]any{}
+				m["dependencies"] = existing
+			}
+			for dk, dv := range deps {
+				existing[dk] = dv
+			}
+			delete(m, k)
+		}
+		if anchor, ok := m["$anchor"].(string); ok {
+			anchors[anchor] = ptr
+			delete(m, "$anchor")
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	if err := jschemaWalk(root, "", func(m map[string]any, ptr string) error {
+		ref, ok := m["$ref"].(string)
+		if !ok {
+			return nil
+		}
+
+		base, frag, hasFrag := strings.Cut(ref, "#")
+		if hasFrag && strings.HasPrefix(frag, "/") {
+			tokens := strings.Split(frag, "/")
+			for i, t := range tokens {
+				if t == "$defs" {
+					tokens[i] = "definitions"
+				}
+			}
+			ref = base + "#" + strings.Join(tokens, "/")
+		} else if hasFrag && frag != "" && base == "" {
+			anchorPtr, exists := anchors[frag]
+			if !exists {
+				return fmt.Errorf("reference '%v' at '#%v' does not match an anchor", ref, ptr)
+			}
+			ref = "#" + anchorPtr
+		}
+
+		// Keywords adjacent to a reference are ignored in draft-07, and so
+		// they are preserved by moving the reference into an allOf.
+		for k := range m {
+			switch k {
+			case "$ref", "$schema", "$id", "$comment", "title", "description", "definitions":
+				continue
+			}
+			allOf, _ := m["allOf"].([]any)
+			m["allOf"] = append(allOf, map[string]any{"$ref": ref})
+			delete(m, "$ref")
+			return nil
+		}
+		m["$ref"] = ref
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return root, nil
+}
@@ -399,3 +399,164 @@ json_schema:
 		t.Error("expected error from loading bad schema")
 	}
 }
+
+func TestJSONSchemaDraft202012(t *testing.T) {
+	conf, err := testutil.ProcessorFromYAML(`
+json_schema:
+  violations_metadata: violations
+  schema: |
+    {
+      "$schema": "https://json-schema.org/draft/2020-12/schema",
+      "type": "object",
+      "properties": {
+        "point": {
+          "type": "array",
+          "prefixItems": [ { "type": "number" }, { "type": "number" } ],
+          "items": false
+        },
+        "name": { "$ref": "#/$defs/name", "maxLength": 5 },
+        "nickname": { "$ref": "#nick" }
+      },
+      "dependentRequired": { "nickname": [ "name" ] },
+      "$defs": {
+        "name": { "type": "string" },
+        "nick": { "$anchor": "nick", "type": "string", "minLength": 2 }
+      }
+    }
+`)
+	require.NoError(t, err)
+
+	p, err := mock.NewManager().NewProcessor(conf)
+	require.NoError(t, err)
+
+	tests := []struct {
+		input      string
+		violations [][2]string
+	}{
+		{
+			input: `{"point":[1,2],"name":"foo","nickname":"fo"}`,
+		},
+		{
+			input: `{"point":[1,2,3],"name":"foobarbaz"}`,
+			violations: [][2]string{
+				{"/point", "additionalItems"},
+				{"/name", "maxLength"},
+			},
+		},
+		{
+			input: `{"point":["nope",2],"nickname":"f"}`,
+			violations: [][2]string{
+				{"/point/0", "type"},
+				{"/nickname", "minLength"},
+				{"", "dependencies"},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		msgs, res := p.ProcessBatch(context.Background(), message.Batch{
+			message.NewPart([]byte(test.input)),
+		})
+		require.NoError(t, res)
+		require.Len(t, msgs, 1)
+		require.Len(t, msgs[0], 1)
+
+		part := msgs[0][0]
+		assert.Equal(t, test.input, string(part.AsBytes()))
+		if len(test.violations) == 0 {
+			assert.NoError(t, part.ErrorGet(), test.input)
+			continue
+		}
+		assert.Error(t, part.ErrorGet(), test.input)
+
+		v, exists := part.MetaGetMut("violations")
+		require.True(t, exists, test.input)
+
+		var act [][2]string
+		for _, e := range v.([]any) {
+			obj := e.(map[string]any)
+			assert.NotEmpty(t, obj["message"])
+			act = append(act, [2]string{obj["path"].(string), obj["keyword"].(string)})
+		}
+		assert.ElementsMatch(t, test.violations, act, test.input)
+	}
+}
+
+func TestJSONSchemaUnsupportedKeyword(t *testing.T) {
+	conf, err := testutil.ProcessorFromYAML(`
+json_schema:
+  schema: |
+    {
+      "$schema": "https://json-schema.org/draft/2020-12/schema",
+      "type": "object",
+      "unevaluatedProperties": false
+    }
+`)
+	require.NoError(t, err)
+
+	_, err = mock.NewManager().NewProcessor(conf)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unevaluatedProperties")
+}
+
+func TestJSONSchemaBundle(t *testing.T) {
+	tmpDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "address.json"), []byte(`{
+  "$id": "https://example.com/schemas/address.json",
+  "$schema": "https://json-schema.org/draft/2020-12/schema",
+  "type": "object",
+  "properties": {
+    "street": { "$ref": "#/$defs/street" }
+  },
+  "required": [ "street" ],
+  "$defs": {
+    "street": { "type": "string" }
+  }
+}`), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "name.json"), []byte(`{
+  "$id": "https://example.com/schemas/name.json",
+  "$schema": "http://json-schema.org/draft-07/schema#",
+  "type": "string",
+  "minLength": 1
+}`), 0o644))
+
+	conf, err := testutil.ProcessorFromYAML(fmt.Sprintf(`
+json_schema:
+  schema_bundle: [ "file://%v/*.json" ]
+  violations_metadata: violations
+  schema: |
+    {
+      "$schema": "https://json-schema.org/draft/2020-12/schema",
+      "type": "object",
+      "properties": {
+        "name": { "$ref": "https://example.com/schemas/name.json" },
+        "address": { "$ref": "https://example.com/schemas/address.json" }
+      }
+    }
+`, tmpDir))
+	require.NoError(t, err)
+
+	p, err := mock.NewManager().NewProcessor(conf)
+	require.NoError(t, err)
+
+	msgs, res := p.ProcessBatch(context.Background(), message.Batch{
+		message.NewPart([]byte(`{"name":"foo","address":{"street":"bar"}}`)),
+		message.NewPart([]byte(`{"name":"","address":{"street":10}}`)),
+	})
+	require.NoError(t, res)
+	require.Len(t, msgs, 1)
+	require.Len(t, msgs[0], 2)
+
+	assert.NoError(t, msgs[0][0].ErrorGet())
+	require.Error(t, msgs[0][1].ErrorGet())
+
+	v, exists := msgs[0][1].MetaGetMut("violations")
+	require.True(t, exists)
+
+	var act []string
+	for _, e := range v.([]any) {
+		obj := e.(map[string]any)
+		act = append(act, obj["path"].(string)+" "+obj["keyword"].(string))
+	}
+	assert.ElementsMatch(t, []string{"/name minLength", "/address/street type"}, act)
+}
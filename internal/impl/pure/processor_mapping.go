@@ -39,6 +39,8 @@ root.rejected = this.invitees.filter(i -> i.mood < 0.5)
 
 Notice that we mutate the value of `+"`invitees`"+` in the resulting document by filtering out objects with a lower mood. However, even after doing so we're still able to reference the unchanged original contents of this value from the input document in order to populate a second field. Within this mapping we also have the flexibility to reference the mutable mapped document by using the keyword `+"`root` (i.e. `root.invitees`)"+` on the right-hand side instead.
 
+Values taken from the input document, such as the `+"`id`"+` field above, are shared with the new document rather than copied. Only the objects and arrays along the paths of assignments are copied, and therefore assigning large parts of the input document is cheap.
+
 Mapping documents is advantageous in situations where the result is a document with a dramatically different shape to the input document, since we are effectively rebuilding the document in its entirety and might as well keep a reference to the unchanged input document throughout. However, in situations where we are only performing minor alterations to the input document, the rest of which is unchanged, it might be more efficient to use the `+"[`mutation` processor](/docs/components/processors/mutation)"+` instead.
 
 ## Error Handling
@@ -148,7 +150,7 @@ func (m *mappingProc) ProcessBatch(ctx *processor.BatchProcContext, b message.Ba
 	for i, msg := range b {
 		newPart, err := m.exec.MapPart(i, b)
 		if err != nil {
-			ctx.OnError(err, i, msg)
+			ctx.OnError(message.ErrorWithDefaultClass(err, message.ErrorClassValidation), i, msg)
 			m.log.Errorf("%v", err)
 			newBatch = append(newBatch, msg)
 			continue
@@ -23,6 +23,11 @@ const (
 	metProcFieldName   = "name"
 	metProcFieldLabels = "labels"
 	metProcFieldValue  = "value"
+
+	metProcFieldBuckets           = "buckets"
+	metProcFieldQuantiles         = "quantiles"
+	metProcFieldQuantilesQuantile = "quantile"
+	metProcFieldQuantilesError    = "error"
 )
 
 func metProcSpec() *service.ConfigSpec {
@@ -78,7 +83,17 @@ pipeline:
 
 ### `+"`timing`"+`
 
-Equivalent to `+"`gauge`"+` where instead the metric is a timing. It is recommended that timing values are recorded in nanoseconds in order to be consistent with standard Benthos timing metrics, as in some cases these values are automatically converted into other units such as when exporting timings as histograms with Prometheus metrics.`).
+Equivalent to `+"`gauge`"+` where instead the metric is a timing. It is recommended that timing values are recorded in nanoseconds in order to be consistent with standard Benthos timing metrics, as in some cases these values are automatically converted into other units such as when exporting timings as histograms with Prometheus metrics.
+
+### `+"`histogram`"+`
+
+If the contents of `+"`value`"+` can be parsed as a number then it is observed by a histogram, where observations are counted within the `+"[`buckets`](#buckets)"+` configured. Unlike `+"`timing`"+` the value is recorded as is and is not converted into other units.
+
+### `+"`summary`"+`
+
+If the contents of `+"`value`"+` can be parsed as a number then it is observed by a summary, where the `+"[`quantiles`](#quantiles)"+` configured are calculated from the observations.
+
+Histograms and summaries are only supported by some metrics exporters, such as `+"`prometheus`"+`. Other exporters record the observations of these types as timings, where values are truncated to integers.`).
 		Example(
 			"Counter",
 			"In this example we emit a counter metric called `Foos`, which increments for every message processed, and we label the metric with some metadata about where the message came from and a field from the document that states what type it is. We also configure our metrics to emit to CloudWatch, and explicitly only allow our custom metric and some internal Benthos metrics to emit.",
@@ -120,10 +135,28 @@ pipeline:
 metrics:
   mapping: 'if this != "FooSize" { deleted() }'
   prometheus: {}
+`,
+		).
+		Example(
+			"Histogram",
+			"In this example we emit a histogram metric called `PayloadBytes`, which tracks the distribution of the sizes of messages labelled by the topic they were consumed from, within buckets ranging from one kilobyte to one megabyte.",
+			`
+pipeline:
+  processors:
+    - metric:
+        name: PayloadBytes
+        type: histogram
+        labels:
+          topic: ${! meta("kafka_topic") }
+        value: ${! content().length() }
+        buckets: [ 1024, 16384, 131072, 1048576 ]
+
+metrics:
+  prometheus: {}
 `,
 		).
 		Fields(
-			service.NewStringEnumField(metProcFieldType, "counter", "counter_by", "gauge", "timing").
+			service.NewStringEnumField(metProcFieldType, "counter", "counter_by", "gauge", "timing", "histogram", "summary").
 				Description("The metric [type](#types) to create."),
 			service.NewStringField(metProcFieldName).
 				Description("The name of the metric to create, this must be unique across all Benthos components otherwise it will overwrite those other metrics."),
@@ -137,6 +170,26 @@ metrics:
 			service.NewInterpolatedStringField(metProcFieldValue).
 				Description("For some metric types specifies a value to set, increment. Certain metrics exporters such as Prometheus support floating point values, but those that do not will cast a floating point value into an integer.").
 				Default(""),
+			service.NewFloatListField(metProcFieldBuckets).
+				Description("The upper bounds of the buckets of a `histogram` metric, where each observation is counted within the buckets with bounds greater than or equal to it. If empty the default buckets of the metrics exporter are used.").
+				Example([]any{0.005, 0.01, 0.05, 0.1, 0.5, 1, 5}).
+				Advanced().
+				Version("4.28.0").
+				Default([]any{}),
+			service.NewObjectListField(metProcFieldQuantiles,
+				service.NewFloatField(metProcFieldQuantilesQuantile).
+					Description("The quantile to calculate, between 0 and 1."),
+				service.NewFloatField(metProcFieldQuantilesError).
+					Description("The absolute error allowed for the quantile."),
+			).
+				Description("The quantile objectives of a `summary` metric. If empty the default objectives of the metrics exporter are used.").
+				Example([]any{
+					map[string]any{"quantile": 0.5, "error": 0.05},
+					map[string]any{"quantile": 0.99, "error": 0.001},
+				}).
+				Advanced().
+				Version("4.28.0").
+				Optional(),
 		)
 }
 
@@ -166,8 +219,34 @@ func init() {
 				return nil, err
 			}
 
+			buckets, err := conf.FieldFloatList(metProcFieldBuckets)
+			if err != nil {
+				return nil, err
+			}
+
+			var objectives map[float64]float64
+			if conf.Contains(metProcFieldQuantiles) {
+				quantileConfs, err := conf.FieldObjectList(metProcFieldQuantiles)
+				if err != nil {
+					return nil, err
+				}
+				objectives = map[float64]float64{}
+				for _, qConf := range quantileConfs {
+					quantile, err := qConf.FieldFloat(metProcFieldQuantilesQuantile)
+					if err != nil {
+						return nil, err
+					}
+					if quantile < 0 || quantile > 1 {
+						return nil, fmt.Errorf("quantile %v must be between 0 and 1", quantile)
+					}
+					if objectives[quantile], err = qConf.FieldFloat(metProcFieldQuantilesError); err != nil {
+						return nil, err
+					}
+				}
+			}
+
 			mgr := interop.UnwrapManagement(res)
-			p, err := newMetricProcessor(procTypeStr, procName, valueStr, labelMap, mgr)
+			p, err := newMetricProcessor(procTypeStr, procName, valueStr, labelMap, buckets, objectives, mgr)
 			if err != nil {
 				return nil, err
 			}
@@ -189,9 +268,10 @@ type metricProcessor struct {
 	mGauge   metrics.StatGauge
 	mTimer   metrics.StatTimer
 
-	mCounterVec metrics.StatCounterVec
-	mGaugeVec   metrics.StatGaugeVec
-	mTimerVec   metrics.StatTimerVec
+	mCounterVec   metrics.StatCounterVec
+	mGaugeVec     metrics.StatGaugeVec
+	mTimerVec     metrics.StatTimerVec
+	mHistogramVec metrics.StatHistogramVec
 
 	handler func(string, int, message.Batch) error
 }
@@ -228,7 +308,7 @@ func (l labels) values(index int, msg message.Batch) ([]string, error) {
 	return values, nil
 }
 
-func newMetricProcessor(typeStr, name, valueStr string, labels map[string]string, mgr bundle.NewManagement) (processor.V1, error) {
+func newMetricProcessor(typeStr, name, valueStr string, labels map[string]string, buckets []float64, objectives map[float64]float64, mgr bundle.NewManagement) (processor.V1, error) {
 	value, err := mgr.BloblEnvironment().NewField(valueStr)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse value expression: %v", err)
@@ -290,6 +370,12 @@ func newMetricProcessor(typeStr, name, valueStr string, labels map[string]string
 			m.mTimer = stats.GetTimer(name)
 		}
 		m.handler = m.handleTimer
+	case "histogram":
+		m.mHistogramVec = metrics.GetHistogramVec(stats, name, buckets, m.labels.names()...)
+		m.handler = m.handleHistogram
+	case "summary":
+		m.mHistogramVec = metrics.GetSummaryVec(stats, name, objectives, m.labels.names()...)
+		m.handler = m.handleHistogram
 	default:
 		return nil, fmt.Errorf("metric type unrecognised: %v", typeStr)
 	}
@@ -394,6 +480,19 @@ func (m *metricProcessor) handleTimer(val string, index int, msg message.Batch)
 	return nil
 }
 
+func (m *metricProcessor) handleHistogram(val string, index int, msg message.Batch) error {
+	f, err := strconv.ParseFloat(val, 64)
+	if err != nil {
+		return err
+	}
+	labelValues, err := m.labels.values(index, msg)
+	if err != nil {
+		return err
+	}
+	m.mHistogramVec.With(labelValues...).Observe(f)
+	return nil
+}
+
 func (m *metricProcessor) ProcessBatch(ctx context.Context, msg message.Batch) ([]message.Batch, error) {
 	_ = msg.Iter(func(i int, p *message.Part) error {
 		value, err := m.value.String(i, msg)
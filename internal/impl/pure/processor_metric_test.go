@@ -241,3 +241,59 @@ metric:
 
 	assert.Equal(t, expTimingAvgs, actTimingAvgs)
 }
+
+func TestMetricHistogramAndSummary(t *testing.T) {
+	for _, typeStr := range []string{"histogram", "summary"} {
+		conf, err := testutil.ProcessorFromYAML(`
+metric:
+  type: ` + typeStr + `
+  name: foo.bar
+  labels:
+    type: '${! json("type") }'
+  value: '${! json("size") }'
+  buckets: [ 10, 100 ]
+  quantiles:
+    - quantile: 0.5
+      error: 0.05
+`)
+		require.NoError(t, err)
+
+		// Local metrics do not support histograms and therefore observations
+		// are recorded as timings.
+		mockMetrics := metrics.NewLocal()
+
+		mgr := mock.NewManager()
+		mgr.M = mockMetrics
+
+		proc, err := mgr.NewProcessor(conf)
+		require.NoError(t, err, typeStr)
+
+		msg, res := proc.ProcessBatch(context.Background(), message.QuickBatch([][]byte{
+			[]byte(`{"type":"a","size":5}`),
+			[]byte(`{"type":"a","size":10.5}`),
+			[]byte(`{"type":"b","size":20}`),
+			[]byte(`{"type":"b","size":"nope"}`),
+		}))
+		assert.Len(t, msg, 1)
+		assert.NoError(t, res)
+
+		timings := mockMetrics.FlushTimings()
+		require.Len(t, timings, 2, typeStr)
+		assert.Equal(t, int64(2), timings[`foo.bar{type="a"}`].Count(), typeStr)
+		assert.Equal(t, int64(10), timings[`foo.bar{type="a"}`].Max(), typeStr)
+		assert.Equal(t, int64(1), timings[`foo.bar{type="b"}`].Count(), typeStr)
+	}
+
+	conf, err := testutil.ProcessorFromYAML(`
+metric:
+  type: summary
+  name: foo.bar
+  quantiles:
+    - quantile: 1.5
+      error: 0.05
+`)
+	require.NoError(t, err)
+
+	_, err = mock.NewManager().NewProcessor(conf)
+	require.Error(t, err)
+}
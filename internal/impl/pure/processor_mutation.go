@@ -47,7 +47,7 @@ Mutations are advantageous over a standard mapping in situations where the resul
 
 ## Error Handling
 
-Bloblang mappings can fail, in which case the error is logged and the message is flagged as having failed, allowing you to use [standard processor error handling patterns](/docs/configuration/error_handling).
+Bloblang mappings can fail, in which case the message remains unchanged, the error is logged and the message is flagged as having failed, allowing you to use [standard processor error handling patterns](/docs/configuration/error_handling).
 
 However, Bloblang itself also provides powerful ways of ensuring your mappings do not fail by specifying desired fallback behaviour, which you can read about [in this section](/docs/guides/bloblang/about#error-handling).
 			`).
@@ -152,7 +152,7 @@ func (m *mutationProc) ProcessBatch(ctx *processor.BatchProcContext, b message.B
 	for i, msg := range b {
 		newPart, err := m.exec.MapOnto(msg, i, b)
 		if err != nil {
-			ctx.OnError(err, i, msg)
+			ctx.OnError(message.ErrorWithDefaultClass(err, message.ErrorClassValidation), i, msg)
 			m.log.Errorf("%v", err)
 			newBatch = append(newBatch, msg)
 			continue
@@ -2,19 +2,17 @@ package pure
 
 import (
 	"context"
-	"fmt"
 	"sync"
 
 	"github.com/benthosdev/benthos/v4/internal/component/interop"
 	"github.com/benthosdev/benthos/v4/internal/component/processor"
 	"github.com/benthosdev/benthos/v4/internal/message"
 	"github.com/benthosdev/benthos/v4/public/service"
-
-	"github.com/google/uuid"
 )
 
 const (
 	parProcFieldCap        = "cap"
+	parProcFieldOrdered    = "ordered"
 	parProcFieldProcessors = "processors"
 )
 
@@ -27,11 +25,18 @@ func init() {
 			Description(`
 The field `+"`cap`"+`, if greater than zero, caps the maximum number of parallel processing threads.
 
+By default the messages resulting from processing are emitted in the order of the messages they originated from, where the results of messages that finish processing early are buffered until the results of all preceding messages are ready. When `+"`ordered`"+` is set to `+"`false`"+` results are instead emitted in the order in which their processing completed.
+
 The functionality of this processor depends on being applied across messages that are batched. You can find out more about batching [in this doc](/docs/configuration/batching).`).
 			Fields(
 				service.NewIntField(parProcFieldCap).
 					Description("The maximum number of messages to have processing at a given time.").
 					Default(0),
+				service.NewBoolField(parProcFieldOrdered).
+					Description("Whether the results of processing are emitted in the order of the messages they originated from, rather than the order in which their processing completed.").
+					Advanced().
+					Version("4.28.0").
+					Default(true),
 				service.NewProcessorListField(parProcFieldProcessors).
 					Description("A list of child processors to apply."),
 			),
@@ -42,6 +47,9 @@ The functionality of this processor depends on being applied across messages tha
 			if p.cap, err = conf.FieldInt(parProcFieldCap); err != nil {
 				return nil, err
 			}
+			if p.ordered, err = conf.FieldBool(parProcFieldOrdered); err != nil {
+				return nil, err
+			}
 
 			var pChildren []*service.OwnedProcessor
 			if pChildren, err = conf.FieldProcessorList(parProcFieldProcessors); err != nil {
@@ -62,47 +70,45 @@ The functionality of this processor depends on being applied across messages tha
 type parallelProc struct {
 	children []processor.V1
 	cap      int
+	ordered  bool
 }
 
 func (p *parallelProc) ProcessBatch(ctx *processor.BatchProcContext, msg message.Batch) ([]message.Batch, error) {
 	resultMsgs := make([]message.Batch, msg.Len())
-	_ = msg.Iter(func(i int, p *message.Part) error {
-		resultMsgs[i] = message.Batch{p}
-		return nil
-	})
 
 	max := p.cap
 	if max == 0 || msg.Len() < max {
 		max = msg.Len()
 	}
 
+	// The indexes of messages in the order that their processing completed,
+	// which is only tracked when results are unordered.
+	var completedMut sync.Mutex
+	var completed []int
+
 	reqChan := make(chan int)
 	wg := sync.WaitGroup{}
 	wg.Add(max)
 
 	for i := 0; i < max; i++ {
-
-		newUUID := uuid.New()
-		// fmt.Printf("THREAD NUMBER %v ID: %s\n", i, newUUID)
-		defer wg.Done()
-
 		go func() {
+			defer wg.Done()
 			for index := range reqChan {
-
-				fmt.Printf("THREAD ID: %v, msg: %v\n", newUUID, resultMsgs[index])
-
-				resMsgs, err := processor.ExecuteAll(ctx.Context(), p.children, resultMsgs[index])
+				resMsgs, err := processor.ExecuteAll(ctx.Context(), p.children, message.Batch{msg[index]})
 				if err != nil {
-					return
+					resMsgs = []message.Batch{{msg[index]}}
 				}
-				resultParts := []*message.Part{}
+				var resultParts message.Batch
 				for _, m := range resMsgs {
-					_ = m.Iter(func(i int, p *message.Part) error {
-						resultParts = append(resultParts, p)
-						return nil
-					})
+					resultParts = append(resultParts, m...)
 				}
 				resultMsgs[index] = resultParts
+
+				if !p.ordered {
+					completedMut.Lock()
+					completed = append(completed, index)
+					completedMut.Unlock()
+				}
 			}
 		}()
 	}
@@ -117,13 +123,15 @@ func (p *parallelProc) ProcessBatch(ctx *processor.BatchProcContext, msg message
 	}
 
 	resMsg := message.QuickBatch(nil)
-	for _, m := range resultMsgs {
-		_ = m.Iter(func(i int, p *message.Part) error {
-			resMsg = append(resMsg, p)
-			return nil
-		})
+	if p.ordered {
+		for _, m := range resultMsgs {
+			resMsg = append(resMsg, m...)
+		}
+	} else {
+		for _, index := range completed {
+			resMsg = append(resMsg, resultMsgs[index]...)
+		}
 	}
-
 	return []message.Batch{resMsg}, nil
 }
 
@@ -169,3 +169,38 @@ parallel:
 		t.Errorf("Wrong result: %v != %v", act, exp)
 	}
 }
+
+func TestParallelOrdering(t *testing.T) {
+	for _, test := range []struct {
+		ordered bool
+		exp     []string
+	}{
+		{ordered: true, exp: []string{"300ms", "0s", "150ms"}},
+		{ordered: false, exp: []string{"0s", "150ms", "300ms"}},
+	} {
+		conf := parseYAMLConf(t, `
+parallel:
+  ordered: %v
+  processors:
+    - sleep:
+        duration: '${! content() }'
+`, test.ordered)
+
+		h, err := mock.NewManager().NewProcessor(conf)
+		require.NoError(t, err)
+
+		msgs, res := h.ProcessBatch(context.Background(), message.QuickBatch([][]byte{
+			[]byte("300ms"),
+			[]byte("0s"),
+			[]byte("150ms"),
+		}))
+		require.NoError(t, res)
+		require.Len(t, msgs, 1)
+
+		var act []string
+		for _, b := range message.GetAllBytes(msgs[0]) {
+			act = append(act, string(b))
+		}
+		assert.Equal(t, test.exp, act, test.ordered)
+	}
+}
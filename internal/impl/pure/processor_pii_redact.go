@@ -0,0 +1,486 @@
+package pure
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	piiFieldEntities         = "entities"
+	piiFieldCustom           = "custom"
+	piiFieldCustomName       = "name"
+	piiFieldCustomPattern    = "pattern"
+	piiFieldCustomDictionary = "dictionary"
+	piiFieldStrategy         = "strategy"
+	piiFieldEntityStrategies = "entity_strategies"
+	piiFieldMaskCharacter    = "mask_character"
+	piiFieldMaskKeepLast     = "mask_keep_last"
+	piiFieldHashKey          = "hash_key"
+	piiFieldTokenCache       = "token_cache"
+	piiFieldReportMetadata   = "report_metadata"
+
+	piiStrategyMask     = "mask"
+	piiStrategyHash     = "hash"
+	piiStrategyTokenise = "tokenise"
+)
+
+func piiRedactProcSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Categories("Utility").
+		Version("4.28.0").
+		Summary("Detects personally identifiable information (PII) within messages and redacts it.").
+		Description(`
+The raw contents of each message are scanned by a set of recognisers, each of which detects an entity type, and every value detected is replaced according to the redaction strategy of its type. When the values detected by different recognisers overlap the longest value is redacted.
+
+### Recognisers
+
+The following entity types are detected by built-in recognisers, and can be selected with the field `+"`entities`"+`:
+
+- `+"`email`"+`: Email addresses.
+- `+"`phone`"+`: Phone numbers with between 7 and 15 digits, in international or common national formats.
+- `+"`credit_card`"+`: Payment card numbers of between 13 and 19 digits that pass the Luhn checksum.
+- `+"`iban`"+`: International bank account numbers that pass the ISO 13616 checksum.
+- `+"`us_ssn`"+`: United States social security numbers.
+- `+"`uk_nino`"+`: United Kingdom national insurance numbers.
+
+Additional entity types can be detected with custom recognisers, which match either a regular expression or a dictionary of words and phrases.
+
+### Strategies
+
+- `+"`mask`"+`: Replaces each character of the value with the mask character, optionally keeping the last characters of the value.
+- `+"`hash`"+`: Replaces the value with the hex encoded SHA-256 hash of the value, or an HMAC-SHA256 when a `+"`hash_key`"+` is set.
+- `+"`tokenise`"+`: Replaces the value with a deterministic token of the form `+"`<EMAIL_3f2a9c1b7d4e8a60>`"+`, where equal values produce equal tokens. When a `+"`token_cache`"+` is set the original value is stored in the cache under its token, so that it can be restored later by authorised consumers.
+
+### Report
+
+When the field `+"`report_metadata`"+` is not empty a report of the redactions is added to each message as an object metadata value under that key, where each key is an entity type and each value is the number of values of that type that were redacted.`).
+		Field(service.NewStringListField(piiFieldEntities).
+			Description("The built-in entity types to detect.").
+			Default([]any{"email", "phone", "credit_card", "iban", "us_ssn", "uk_nino"})).
+		Field(service.NewObjectListField(piiFieldCustom,
+			service.NewStringField(piiFieldCustomName).
+				Description("The name of the entity type detected by this recogniser."),
+			service.NewStringField(piiFieldCustomPattern).
+				Description("A regular expression that matches values of the entity type.").
+				Optional(),
+			service.NewStringListField(piiFieldCustomDictionary).
+				Description("A list of words and phrases that are values of the entity type, which are matched as whole words regardless of case.").
+				Optional(),
+		).
+			Description("A list of custom recognisers, each of which must specify either a `pattern` or a `dictionary`.").
+			Default([]any{})).
+		Field(service.NewStringEnumField(piiFieldStrategy, piiStrategyMask, piiStrategyHash, piiStrategyTokenise).
+			Description("The default redaction strategy for all entity types.").
+			Default(piiStrategyMask)).
+		Field(service.NewStringMapField(piiFieldEntityStrategies).
+			Description("A map of entity types to the redaction strategy to use for them, overriding the default strategy.").
+			Example(map[string]any{"email": "tokenise", "credit_card": "mask"}).
+			Default(map[string]any{})).
+		Field(service.NewStringField(piiFieldMaskCharacter).
+			Description("The character that masked values are replaced with.").
+			Advanced().
+			Default("*")).
+		Field(service.NewIntField(piiFieldMaskKeepLast).
+			Description("The number of characters at the end of masked values to leave unmasked.").
+			Advanced().
+			Default(0)).
+		Field(service.NewStringField(piiFieldHashKey).
+			Description("An optional secret key for computing hashes and tokens as HMACs, which prevents values from being recovered by hashing guesses.").
+			Secret().
+			Optional()).
+		Field(service.NewStringField(piiFieldTokenCache).
+			Description("An optional [`cache` resource](/docs/components/caches/about) to store the original values of tokenised values in, keyed by their tokens.").
+			Optional()).
+		Field(service.NewStringField(piiFieldReportMetadata).
+			Description("The metadata key to add the report of redactions to. Set to an empty string to disable the report.").
+			Default("pii_report")).
+		Example(
+			"Redact support tickets",
+			"Here we mask all built-in entity types except for email addresses, which are tokenised so that tickets from the same customer can still be correlated, and additionally mask the names of internal projects.",
+			`
+pipeline:
+  processors:
+    - pii_redact:
+        hash_key: ${PII_HASH_KEY}
+        entity_strategies:
+          email: tokenise
+        mask_keep_last: 4
+        custom:
+          - name: project
+            dictionary: [ "Project Falcon", "Bluebird" ]
+`,
+		)
+}
+
+func init() {
+	err := service.RegisterProcessor(
+		"pii_redact", piiRedactProcSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Processor, error) {
+			return newPIIRedactProcFromConfig(conf, mgr)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type piiRecogniser struct {
+	entity   string
+	re       *regexp.Regexp
+	validate func(v string) bool
+}
+
+var piiBuiltinRecognisers = map[string]piiRecogniser{
+	"email": {
+		re: regexp.MustCompile(`\b[A-Za-z0-9._%+\-]+@[A-Za-z0-9\-]+(?:\.[A-Za-z0-9\-]+)*\.[A-Za-z]{2,}\b`),
+	},
+	"phone": {
+		re: regexp.MustCompile(`(?:\+\d{1,3}[ .-]?)?(?:\(\d{1,4}\)[ .-]?|\b\d{2,4}[ .-])\d{3,4}[ .-]?\d{3,4}\b`),
+		validate: func(v string) bool {
+			n := len(piiDigits(v))
+			return n >= 7 && n <= 15
+		},
+	},
+	"credit_card": {
+		re:       regexp.MustCompile(`\b(?:\d[ -]?){12,18}\d\b`),
+		validate: piiLuhnValid,
+	},
+	"iban": {
+		re:       regexp.MustCompile(`\b[A-Z]{2}\d{2}(?:[A-Z0-9]{11,30}|(?: [A-Z0-9]{4}){2,7}(?: [A-Z0-9]{1,4})?)\b`),
+		validate: piiIBANValid,
+	},
+	"us_ssn": {
+		re: regexp.MustCompile(`\b\d{3}-\d{2}-\d{4}\b`),
+		validate: func(v string) bool {
+			area, group, serial := v[0:3], v[4:6], v[7:11]
+			return area != "000" && area != "666" && area[0] != '9' && group != "00" && serial != "0000"
+		},
+	},
+	"uk_nino": {
+		re: regexp.MustCompile(`\b[A-CEGHJ-PR-TW-Z][A-CEGHJ-NPR-TW-Z] ?\d{2} ?\d{2} ?\d{2} ?[A-D]\b`),
+	},
+}
+
+func piiDigits(v string) string {
+	var b strings.Builder
+	for _, c := range v {
+		if c >= '0' && c <= '9' {
+			b.WriteRune(c)
+		}
+	}
+	return b.String()
+}
+
+func piiLuhnValid(v string) bool {
+	digits := piiDigits(v)
+	if len(digits) < 13 || len(digits) > 19 {
+		return false
+	}
+	var sum int
+	for i := len(digits) - 1; i >= 0; i-- {
+		d := int(digits[i] - '0')
+		if (len(digits)-i)%2 == 0 {
+			if d *= 2; d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+	}
+	return sum%10 == 0
+}
+
+func piiIBANValid(v string) bool {
+	v = strings.ReplaceAll(v, " ", "")
+	if len(v) < 15 || len(v) > 34 {
+		return false
+	}
+	var numeric strings.Builder
+	for _, c := range v[4:] + v[:4] {
+		if c >= 'A' && c <= 'Z' {
+			numeric.WriteString(fmt.Sprintf("%d", c-'A'+10))
+		} else {
+			numeric.WriteRune(c)
+		}
+	}
+	n, ok := new(big.Int).SetString(numeric.String(), 10)
+	if !ok {
+		return false
+	}
+	return new(big.Int).Mod(n, big.NewInt(97)).Int64() == 1
+}
+
+//------------------------------------------------------------------------------
+
+type piiRedactProc struct {
+	mgr *service.Resources
+
+	recognisers      []piiRecogniser
+	strategy         string
+	entityStrategies map[string]string
+	maskChar         string
+	maskKeepLast     int
+	hashKey          []byte
+	tokenCache       string
+	reportMeta       string
+}
+
+func newPIIRedactProcFromConfig(conf *service.ParsedConfig, mgr *service.Resources) (*piiRedactProc, error) {
+	p := &piiRedactProc{mgr: mgr}
+
+	entities, err := conf.FieldStringList(piiFieldEntities)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range entities {
+		r, exists := piiBuiltinRecognisers[e]
+		if !exists {
+			return nil, fmt.Errorf("entity type %v is not a built-in recogniser", e)
+		}
+		r.entity = e
+		p.recognisers = append(p.recognisers, r)
+	}
+
+	customConfs, err := conf.FieldObjectList(piiFieldCustom)
+	if err != nil {
+		return nil, err
+	}
+	for i, cConf := range customConfs {
+		r, err := piiCustomRecogniserFromConfig(cConf)
+		if err != nil {
+			return nil, fmt.Errorf("custom recogniser %v: %w", i, err)
+		}
+		p.recognisers = append(p.recognisers, r)
+	}
+
+	if p.strategy, err = conf.FieldString(piiFieldStrategy); err != nil {
+		return nil, err
+	}
+	if p.entityStrategies, err = conf.FieldStringMap(piiFieldEntityStrategies); err != nil {
+		return nil, err
+	}
+	for entity, strategy := range p.entityStrategies {
+		switch strategy {
+		case piiStrategyMask, piiStrategyHash, piiStrategyTokenise:
+		default:
+			return nil, fmt.Errorf("strategy %v of entity type %v is not recognised", strategy, entity)
+		}
+	}
+
+	if p.maskChar, err = conf.FieldString(piiFieldMaskCharacter); err != nil {
+		return nil, err
+	}
+	if utf8.RuneCountInString(p.maskChar) != 1 {
+		return nil, errors.New("mask character must be a single character")
+	}
+	if p.maskKeepLast, err = conf.FieldInt(piiFieldMaskKeepLast); err != nil {
+		return nil, err
+	}
+
+	if conf.Contains(piiFieldHashKey) {
+		hashKey, err := conf.FieldString(piiFieldHashKey)
+		if err != nil {
+			return nil, err
+		}
+		p.hashKey = []byte(hashKey)
+	}
+
+	if conf.Contains(piiFieldTokenCache) {
+		if p.tokenCache, err = conf.FieldString(piiFieldTokenCache); err != nil {
+			return nil, err
+		}
+		if !mgr.HasCache(p.tokenCache) {
+			return nil, fmt.Errorf("cache named %v not found", p.tokenCache)
+		}
+	}
+
+	if p.reportMeta, err = conf.FieldString(piiFieldReportMetadata); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func piiCustomRecogniserFromConfig(conf *service.ParsedConfig) (r piiRecogniser, err error) {
+	if r.entity, err = conf.FieldString(piiFieldCustomName); err != nil {
+		return
+	}
+
+	var pattern string
+	if conf.Contains(piiFieldCustomPattern) {
+		if pattern, err = conf.FieldString(piiFieldCustomPattern); err != nil {
+			return
+		}
+	}
+
+	var dictionary []string
+	if conf.Contains(piiFieldCustomDictionary) {
+		if dictionary, err = conf.FieldStringList(piiFieldCustomDictionary); err != nil {
+			return
+		}
+	}
+
+	if (pattern == "") == (len(dictionary) == 0) {
+		err = fmt.Errorf("either a `%v` or `%v` must be specified", piiFieldCustomPattern, piiFieldCustomDictionary)
+		return
+	}
+
+	if len(dictionary) > 0 {
+		// Longer entries are listed first so that they take precedence over
+		// entries that are prefixes of them.
+		sort.SliceStable(dictionary, func(i, j int) bool {
+			return len(dictionary[i]) > len(dictionary[j])
+		})
+		quoted := make([]string, 0, len(dictionary))
+		for _, d := range dictionary {
+			quoted = append(quoted, regexp.QuoteMeta(d))
+		}
+		pattern = `(?i)\b(?:` + strings.Join(quoted, "|") + `)\b`
+	}
+
+	if r.re, err = regexp.Compile(pattern); err != nil {
+		err = fmt.Errorf("failed to compile pattern: %w", err)
+	}
+	return
+}
+
+type piiMatch struct {
+	entity     string
+	start, end int
+}
+
+// findMatches returns the values detected by all recognisers in the order
+// that they appear, where values that overlap a longer value are discarded.
+func (p *piiRedactProc) findMatches(s string) []piiMatch {
+	var matches []piiMatch
+	for _, r := range p.recognisers {
+		for _, loc := range r.re.FindAllStringIndex(s, -1) {
+			if loc[0] == loc[1] {
+				continue
+			}
+			if r.validate != nil && !r.validate(s[loc[0]:loc[1]]) {
+				continue
+			}
+			matches = append(matches, piiMatch{entity: r.entity, start: loc[0], end: loc[1]})
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		li, lj := matches[i].end-matches[i].start, matches[j].end-matches[j].start
+		if li != lj {
+			return li > lj
+		}
+		return matches[i].start < matches[j].start
+	})
+
+	var selected []piiMatch
+	for _, m := range matches {
+		overlaps := false
+		for _, s := range selected {
+			if m.start < s.end && s.start < m.end {
+				overlaps = true
+				break
+			}
+		}
+		if !overlaps {
+			selected = append(selected, m)
+		}
+	}
+
+	sort.Slice(selected, func(i, j int) bool {
+		return selected[i].start < selected[j].start
+	})
+	return selected
+}
+
+func (p *piiRedactProc) digest(v string) []byte {
+	if len(p.hashKey) > 0 {
+		h := hmac.New(sha256.New, p.hashKey)
+		_, _ = h.Write([]byte(v))
+		return h.Sum(nil)
+	}
+	h := sha256.Sum256([]byte(v))
+	return h[:]
+}
+
+func (p *piiRedactProc) redact(ctx context.Context, entity, v string) (string, error) {
+	strategy, exists := p.entityStrategies[entity]
+	if !exists {
+		strategy = p.strategy
+	}
+
+	switch strategy {
+	case piiStrategyHash:
+		return hex.EncodeToString(p.digest(v)), nil
+	case piiStrategyTokenise:
+		token := "<" + strings.ToUpper(entity) + "_" + hex.EncodeToString(p.digest(v)[:8]) + ">"
+		if p.tokenCache != "" {
+			var err error
+			if cerr := p.mgr.AccessCache(ctx, p.tokenCache, func(c service.Cache) {
+				err = c.Set(ctx, token, []byte(v), nil)
+			}); cerr != nil {
+				err = cerr
+			}
+			if err != nil {
+				return "", fmt.Errorf("failed to store token: %w", err)
+			}
+		}
+		return token, nil
+	}
+
+	runes := []rune(v)
+	keep := p.maskKeepLast
+	if keep > len(runes) {
+		keep = len(runes)
+	}
+	return strings.Repeat(p.maskChar, len(runes)-keep) + string(runes[len(runes)-keep:]), nil
+}
+
+func (p *piiRedactProc) Process(ctx context.Context, msg *service.Message) (service.MessageBatch, error) {
+	b, err := msg.AsBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	s := string(b)
+	report := map[string]any{}
+
+	matches := p.findMatches(s)
+	if len(matches) > 0 {
+		var redacted strings.Builder
+		var last int
+		for _, m := range matches {
+			replacement, err := p.redact(ctx, m.entity, s[m.start:m.end])
+			if err != nil {
+				return nil, err
+			}
+			redacted.WriteString(s[last:m.start])
+			redacted.WriteString(replacement)
+			last = m.end
+
+			count, _ := report[m.entity].(int64)
+			report[m.entity] = count + 1
+		}
+		redacted.WriteString(s[last:])
+		msg.SetBytes([]byte(redacted.String()))
+	}
+
+	if p.reportMeta != "" {
+		msg.MetaSetMut(p.reportMeta, report)
+	}
+	return service.MessageBatch{msg}, nil
+}
+
+func (p *piiRedactProc) Close(ctx context.Context) error {
+	return nil
+}
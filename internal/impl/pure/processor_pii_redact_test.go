@@ -0,0 +1,155 @@
+package pure
+
+import (
+	"context"
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func TestPIIRedactBuiltins(t *testing.T) {
+	conf, err := piiRedactProcSpec().ParseYAML(``, nil)
+	require.NoError(t, err)
+
+	proc, err := newPIIRedactProcFromConfig(conf, service.MockResources())
+	require.NoError(t, err)
+
+	for _, test := range []struct {
+		name   string
+		input  string
+		output string
+		report map[string]any
+	}{
+		{
+			name:   "email",
+			input:  "contact jane.doe+work@example.co.uk today",
+			output: "contact *************************** today",
+			report: map[string]any{"email": int64(1)},
+		},
+		{
+			name:   "phone",
+			input:  "call +44 20 7946 0958 or (555) 123-4567",
+			output: "call **************** or **************",
+			report: map[string]any{"phone": int64(2)},
+		},
+		{
+			name:   "credit card passes luhn",
+			input:  "card 4111 1111 1111 1111 end",
+			output: "card ******************* end",
+			report: map[string]any{"credit_card": int64(1)},
+		},
+		{
+			name:   "credit card fails luhn",
+			input:  "order 4111111111111112 end",
+			output: "order 4111111111111112 end",
+			report: map[string]any{},
+		},
+		{
+			name:   "iban",
+			input:  "iban GB82 WEST 1234 5698 7654 32 and DE89370400440532013000",
+			output: "iban *************************** and **********************",
+			report: map[string]any{"iban": int64(2)},
+		},
+		{
+			name:   "invalid iban",
+			input:  "iban GB82WEST12345698765433",
+			output: "iban GB82WEST12345698765433",
+			report: map[string]any{},
+		},
+		{
+			name:   "national ids",
+			input:  "ssn 123-45-6789 invalid 000-12-3456 nino AB 12 34 56 C",
+			output: "ssn *********** invalid 000-12-3456 nino *************",
+			report: map[string]any{"us_ssn": int64(1), "uk_nino": int64(1)},
+		},
+	} {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			res, err := proc.Process(context.Background(), service.NewMessage([]byte(test.input)))
+			require.NoError(t, err)
+			require.Len(t, res, 1)
+
+			b, err := res[0].AsBytes()
+			require.NoError(t, err)
+			assert.Equal(t, test.output, string(b))
+
+			report, exists := res[0].MetaGetMut("pii_report")
+			require.True(t, exists)
+			assert.Equal(t, test.report, report)
+		})
+	}
+}
+
+func TestPIIRedactStrategies(t *testing.T) {
+	conf, err := piiRedactProcSpec().ParseYAML(`
+entities: [ email, credit_card ]
+hash_key: foo
+mask_keep_last: 4
+entity_strategies:
+  email: tokenise
+  project: hash
+custom:
+  - name: project
+    dictionary: [ "Project Falcon", "Falcon" ]
+  - name: ticket
+    pattern: 'TICKET-\d+'
+token_cache: tokens
+report_metadata: redactions
+`, nil)
+	require.NoError(t, err)
+
+	mRes := service.MockResources(service.MockResourcesOptAddCache("tokens"))
+
+	proc, err := newPIIRedactProcFromConfig(conf, mRes)
+	require.NoError(t, err)
+
+	input := "jane@example.com paid with 4111111111111111 for project falcon, see TICKET-42 and jane@example.com"
+	res, err := proc.Process(context.Background(), service.NewMessage([]byte(input)))
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+
+	b, err := res[0].AsBytes()
+	require.NoError(t, err)
+
+	token := "<EMAIL_" + hex.EncodeToString(proc.digest("jane@example.com"))[:16] + ">"
+	exp := token + " paid with ************1111 for " + hex.EncodeToString(proc.digest("project falcon")) + ", see *****T-42 and " + token
+	assert.Equal(t, exp, string(b))
+
+	report, exists := res[0].MetaGetMut("redactions")
+	require.True(t, exists)
+	assert.Equal(t, map[string]any{
+		"email":       int64(2),
+		"credit_card": int64(1),
+		"project":     int64(1),
+		"ticket":      int64(1),
+	}, report)
+
+	var original []byte
+	require.NoError(t, mRes.AccessCache(context.Background(), "tokens", func(c service.Cache) {
+		original, err = c.Get(context.Background(), token)
+	}))
+	require.NoError(t, err)
+	assert.Equal(t, "jane@example.com", string(original))
+}
+
+func TestPIIRedactConfigErrors(t *testing.T) {
+	for _, confStr := range []string{
+		`entities: [ passport ]`,
+		`custom: [ { name: foo } ]`,
+		`custom: [ { name: foo, pattern: 'a', dictionary: [ b ] } ]`,
+		`custom: [ { name: foo, pattern: '(' } ]`,
+		`entity_strategies: { email: shred }`,
+		`mask_character: '##'`,
+		`token_cache: nope`,
+	} {
+		conf, err := piiRedactProcSpec().ParseYAML(confStr, nil)
+		require.NoError(t, err, confStr)
+
+		_, err = newPIIRedactProcFromConfig(conf, service.MockResources())
+		require.Error(t, err, confStr)
+	}
+}
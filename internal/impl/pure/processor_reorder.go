@@ -0,0 +1,223 @@
+package pure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/benthosdev/benthos/v4/internal/value"
+	"github.com/benthosdev/benthos/v4/public/bloblang"
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	ropFieldKey     = "key"
+	ropFieldKeyType = "key_type"
+	ropFieldOnLate  = "on_late"
+
+	ropMetaLate = "reorder_late"
+)
+
+func reorderProcSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Categories("Utility").
+		Version("4.28.0").
+		Summary("Sorts the messages of each batch by a timestamp or sequence number, and flags messages that arrive after messages with greater keys have already been emitted.").
+		Description(`
+This processor is intended for feeds where events are interleaved slightly out of order. The window within which messages are reordered is the batch, and so it should be used with a `+"[batching policy](/docs/configuration/batching)"+` where the `+"`count`"+` bounds the size of the window and the `+"`period`"+` bounds the delay added to messages.
+
+Messages are sorted by the result of the `+"`key`"+` mapping, where messages with equal keys retain the order in which they arrived. The greatest key emitted so far is remembered across batches, and messages of later batches with a lesser key are considered late, as they could not be emitted in order. Late messages are handled according to `+"`on_late`"+`, and unless they are dropped the metadata field `+"`"+ropMetaLate+"`"+` is set to `+"`true`"+` on them.
+
+Messages where the key cannot be extracted are flagged as having failed and placed at the end of the batch, allowing you to use [standard processor error handling patterns](/docs/configuration/error_handling).
+
+Since the order of batches processed by parallel pipeline threads is not guaranteed this processor should be used within a pipeline that has a single thread, or with the pipeline field `+"`ordered`"+` set to `+"`true`"+`.`).
+		Fields(
+			service.NewBloblangField(ropFieldKey).
+				Description("A [Bloblang mapping](/docs/guides/bloblang/about) that extracts the key to sort messages by.").
+				Example(`root = this.timestamp`).
+				Example(`root = @kafka_offset`),
+			service.NewStringAnnotatedEnumField(ropFieldKeyType, map[string]string{
+				"timestamp": "Keys are timestamps, which are either timestamp values, strings in RFC 3339 format, or numbers of seconds since the Unix epoch.",
+				"sequence":  "Keys are integer sequence numbers.",
+			}).
+				Description("The type of keys.").
+				Default("timestamp"),
+			service.NewStringAnnotatedEnumField(ropFieldOnLate, map[string]string{
+				"metadata": "Late messages are emitted within the sorted batch.",
+				"error":    "Late messages are emitted within the sorted batch and flagged as having failed.",
+				"drop":     "Late messages are dropped.",
+			}).
+				Description("How to handle late messages.").
+				Default("metadata"),
+		).
+		Example("Reordering Events", "Here we reorder events consumed from Kafka by their timestamps within windows of up to one thousand events or five seconds, and send late events to a separate topic.", `
+input:
+  kafka_franz:
+    seed_brokers: [ localhost:9092 ]
+    topics: [ events ]
+    consumer_group: benthos
+    batching:
+      count: 1000
+      period: 5s
+
+pipeline:
+  threads: 1
+  processors:
+    - reorder:
+        key: 'root = this.timestamp'
+
+output:
+  switch:
+    cases:
+      - check: '@reorder_late == true'
+        output:
+          kafka_franz:
+            seed_brokers: [ localhost:9092 ]
+            topic: late_events
+      - output:
+          kafka_franz:
+            seed_brokers: [ localhost:9092 ]
+            topic: ordered_events
+`)
+}
+
+func init() {
+	err := service.RegisterBatchProcessor(
+		"reorder", reorderProcSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.BatchProcessor, error) {
+			return newReorderProcFromConfig(conf)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type reorderProc struct {
+	key         *bloblang.Executor
+	isTimestamp bool
+	onLate      string
+
+	mut          sync.Mutex
+	watermark    int64
+	hasWatermark bool
+}
+
+func newReorderProcFromConfig(conf *service.ParsedConfig) (*reorderProc, error) {
+	r := &reorderProc{}
+
+	var err error
+	if r.key, err = conf.FieldBloblang(ropFieldKey); err != nil {
+		return nil, err
+	}
+	keyType, err := conf.FieldString(ropFieldKeyType)
+	if err != nil {
+		return nil, err
+	}
+	switch keyType {
+	case "timestamp":
+		r.isTimestamp = true
+	case "sequence":
+	default:
+		return nil, fmt.Errorf("unrecognised key type: %v", keyType)
+	}
+	if r.onLate, err = conf.FieldString(ropFieldOnLate); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// keyOf returns the key of a message of a batch as an integer, where
+// timestamps are converted to nanoseconds since the Unix epoch.
+func (r *reorderProc) keyOf(batch service.MessageBatch, i int) (int64, error) {
+	keyMsg, err := batch.BloblangQuery(i, r.key)
+	if err != nil {
+		return 0, fmt.Errorf("key mapping failed: %w", err)
+	}
+	if keyMsg == nil {
+		return 0, errors.New("key mapping failed: root was deleted")
+	}
+	v, err := keyMsg.AsStructured()
+	if err != nil {
+		// Timestamp strings are not valid JSON and are parsed from the raw
+		// result instead.
+		if keyBytes, _ := keyMsg.AsBytes(); len(keyBytes) > 0 {
+			v, err = string(keyBytes), nil
+		}
+	}
+	if err != nil {
+		return 0, fmt.Errorf("key mapping failed: %w", err)
+	}
+	if r.isTimestamp {
+		t, err := value.IGetTimestamp(v)
+		if err != nil {
+			return 0, fmt.Errorf("failed to parse key as a timestamp: %w", err)
+		}
+		return t.UnixNano(), nil
+	}
+	n, err := value.IGetInt(v)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse key as a sequence number: %w", err)
+	}
+	return n, nil
+}
+
+func (r *reorderProc) ProcessBatch(ctx context.Context, batch service.MessageBatch) ([]service.MessageBatch, error) {
+	type keyedMsg struct {
+		msg *service.Message
+		key int64
+	}
+
+	keyed := make([]keyedMsg, 0, len(batch))
+	var failed service.MessageBatch
+	for i, msg := range batch {
+		key, err := r.keyOf(batch, i)
+		if err != nil {
+			msg.SetError(err)
+			failed = append(failed, msg)
+			continue
+		}
+		keyed = append(keyed, keyedMsg{msg: msg, key: key})
+	}
+	sort.SliceStable(keyed, func(i, j int) bool {
+		return keyed[i].key < keyed[j].key
+	})
+
+	r.mut.Lock()
+	defer r.mut.Unlock()
+
+	res := make(service.MessageBatch, 0, len(batch))
+	for _, k := range keyed {
+		if r.hasWatermark && k.key < r.watermark {
+			switch r.onLate {
+			case "drop":
+				continue
+			case "error":
+				k.msg.SetError(fmt.Errorf("message with key %v arrived after a message with key %v", r.formatKey(k.key), r.formatKey(r.watermark)))
+			}
+			k.msg.MetaSetMut(ropMetaLate, true)
+		} else {
+			r.watermark, r.hasWatermark = k.key, true
+		}
+		res = append(res, k.msg)
+	}
+	res = append(res, failed...)
+
+	if len(res) == 0 {
+		return nil, nil
+	}
+	return []service.MessageBatch{res}, nil
+}
+
+func (r *reorderProc) formatKey(key int64) string {
+	if r.isTimestamp {
+		return time.Unix(0, key).UTC().Format(time.RFC3339Nano)
+	}
+	return fmt.Sprint(key)
+}
+
+func (r *reorderProc) Close(ctx context.Context) error {
+	return nil
+}
@@ -0,0 +1,148 @@
+package pure
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func testReorderProc(t *testing.T, confStr string) *reorderProc {
+	t.Helper()
+
+	conf, err := reorderProcSpec().ParseYAML(confStr, nil)
+	require.NoError(t, err)
+
+	proc, err := newReorderProcFromConfig(conf)
+	require.NoError(t, err)
+	return proc
+}
+
+func reorderBatch(t *testing.T, proc *reorderProc, docs ...string) (contents []string, late []bool, errs []error) {
+	t.Helper()
+
+	var batch service.MessageBatch
+	for _, d := range docs {
+		batch = append(batch, service.NewMessage([]byte(d)))
+	}
+
+	res, err := proc.ProcessBatch(context.Background(), batch)
+	require.NoError(t, err)
+	if len(res) == 0 {
+		return
+	}
+	require.Len(t, res, 1)
+
+	for _, msg := range res[0] {
+		b, err := msg.AsBytes()
+		require.NoError(t, err)
+		contents = append(contents, string(b))
+
+		_, isLate := msg.MetaGetMut("reorder_late")
+		late = append(late, isLate)
+		errs = append(errs, msg.GetError())
+	}
+	return
+}
+
+func TestReorderTimestamps(t *testing.T) {
+	proc := testReorderProc(t, `key: 'root = this.ts'`)
+
+	contents, late, _ := reorderBatch(t, proc,
+		`{"id":"c","ts":"2024-01-01T00:00:03Z"}`,
+		`{"id":"a","ts":"2024-01-01T00:00:01Z"}`,
+		`{"id":"b1","ts":1704067202}`,
+		`{"id":"b2","ts":"2024-01-01T00:00:02Z"}`,
+	)
+	assert.Equal(t, []string{
+		`{"id":"a","ts":"2024-01-01T00:00:01Z"}`,
+		`{"id":"b1","ts":1704067202}`,
+		`{"id":"b2","ts":"2024-01-01T00:00:02Z"}`,
+		`{"id":"c","ts":"2024-01-01T00:00:03Z"}`,
+	}, contents)
+	assert.Equal(t, []bool{false, false, false, false}, late)
+
+	// Messages with keys before the greatest key emitted are late, messages
+	// with an equal key are not.
+	contents, late, _ = reorderBatch(t, proc,
+		`{"id":"d","ts":"2024-01-01T00:00:04Z"}`,
+		`{"id":"late","ts":"2024-01-01T00:00:02Z"}`,
+		`{"id":"c2","ts":"2024-01-01T00:00:03Z"}`,
+	)
+	assert.Equal(t, []string{
+		`{"id":"late","ts":"2024-01-01T00:00:02Z"}`,
+		`{"id":"c2","ts":"2024-01-01T00:00:03Z"}`,
+		`{"id":"d","ts":"2024-01-01T00:00:04Z"}`,
+	}, contents)
+	assert.Equal(t, []bool{true, false, false}, late)
+}
+
+func TestReorderSequenceLateModes(t *testing.T) {
+	for _, test := range []struct {
+		mode     string
+		contents []string
+		late     []bool
+		errored  []bool
+	}{
+		{
+			mode:     "metadata",
+			contents: []string{"2", "4"},
+			late:     []bool{true, false},
+			errored:  []bool{false, false},
+		},
+		{
+			mode:     "error",
+			contents: []string{"2", "4"},
+			late:     []bool{true, false},
+			errored:  []bool{true, false},
+		},
+		{
+			mode:     "drop",
+			contents: []string{"4"},
+			late:     []bool{false},
+			errored:  []bool{false},
+		},
+	} {
+		proc := testReorderProc(t, `
+key: 'root = content().number()'
+key_type: sequence
+on_late: `+test.mode+`
+`)
+
+		contents, _, _ := reorderBatch(t, proc, "3", "1")
+		assert.Equal(t, []string{"1", "3"}, contents, test.mode)
+
+		contents, late, errs := reorderBatch(t, proc, "4", "2")
+		assert.Equal(t, test.contents, contents, test.mode)
+		assert.Equal(t, test.late, late, test.mode)
+		for i, errored := range test.errored {
+			assert.Equal(t, errored, errs[i] != nil, test.mode)
+		}
+	}
+
+	proc := testReorderProc(t, `
+key: 'root = content().number()'
+key_type: sequence
+on_late: drop
+`)
+	reorderBatch(t, proc, "5")
+	contents, _, _ := reorderBatch(t, proc, "4", "3")
+	assert.Empty(t, contents)
+}
+
+func TestReorderKeyErrors(t *testing.T) {
+	proc := testReorderProc(t, `
+key: 'root = this.seq'
+key_type: sequence
+`)
+
+	contents, _, errs := reorderBatch(t, proc, `{"seq":"nope"}`, `{"seq":2}`, `not json`, `{"seq":1}`)
+	assert.Equal(t, []string{`{"seq":1}`, `{"seq":2}`, `{"seq":"nope"}`, `not json`}, contents)
+	assert.NoError(t, errs[0])
+	assert.NoError(t, errs[1])
+	assert.ErrorContains(t, errs[2], "failed to parse key as a sequence number")
+	assert.ErrorContains(t, errs[3], "key mapping failed")
+}
@@ -3,11 +3,14 @@ package pure
 import (
 	"context"
 	"errors"
+	"fmt"
 	"sync"
 	"time"
 
 	"github.com/cenkalti/backoff/v4"
 
+	"github.com/benthosdev/benthos/v4/internal/bloblang/mapping"
+	"github.com/benthosdev/benthos/v4/internal/bundle"
 	"github.com/benthosdev/benthos/v4/internal/component/interop"
 	"github.com/benthosdev/benthos/v4/internal/component/processor"
 	"github.com/benthosdev/benthos/v4/internal/log"
@@ -19,6 +22,15 @@ const (
 	rpFieldProcessors = "processors"
 	rpFieldBackoff    = "backoff"
 	rpFieldParallel   = "parallel"
+	rpFieldPolicies   = "policies"
+
+	// Policy fields
+	rpFieldPolicyCheck           = "check"
+	rpFieldPolicyBackoff         = "backoff"
+	rpFieldPolicyInitialInterval = "initial_interval"
+	rpFieldPolicyMaxInterval     = "max_interval"
+	rpFieldPolicyMaxElapsedTime  = "max_elapsed_time"
+	rpFieldPolicyMaxRetries      = "max_retries"
 )
 
 func retryProcSpec() *service.ConfigSpec {
@@ -36,6 +48,10 @@ By default the retry backoff has a specified `+"[`max_elapsed_time`](#backoffmax
 
 In order to avoid permanent loops any error associated with messages as they first enter a retry processor will be cleared.
 
+### Retry Policies
+
+Different classes of errors can be retried differently by specifying `+"[`policies`](#policies)"+`. When an attempt fails the first errored message resulting from the child processors is tested against the `+"`check`"+` of each policy in order, and the first policy that matches determines the backoff and the maximum number of retries applied before the next attempt. When no policy matches the top level `+"`backoff`"+` is used. The backoff and number of retries of each policy are tracked separately for each message, and the `+"`max_elapsed_time`"+` of each is measured from the first attempt.
+
 :::caution Batching
 If you wish to wrap a batch-aware series of processors then take a look at the [batching section](#batching) below.
 :::
@@ -88,6 +104,27 @@ pipeline:
 output:
   # Drop everything because it's junk data, I don't want it lol
   drop: {}
+`,
+		).
+		Example("Retrying Errors Differently", `
+Here we call an HTTP API that enforces a rate limit, and therefore requests that are rejected with a 429 status code are retried for up to an hour with long intervals. Requests that are rejected with a 400 status code will never succeed and so are not retried, and all other errors are retried with the default backoff.`,
+			`
+pipeline:
+  processors:
+    - retry:
+        policies:
+          - check: '@http_status_code == 429'
+            backoff:
+              initial_interval: 1s
+              max_interval: 1m
+              max_elapsed_time: 1h
+          - check: '@http_status_code == 400'
+            max_retries: 0
+        processors:
+          - http:
+              url: 'http://example.com/enrich'
+              verb: POST
+              retries: 0
 `,
 		).
 		Fields(
@@ -97,6 +134,28 @@ output:
 			service.NewBoolField(rpFieldParallel).
 				Description("When processing batches of messages these batches are ignored and the processors apply to each message sequentially. However, when this field is set to `true` each message will be processed in parallel. Caution should be made to ensure that batch sizes do not surpass a point where this would cause resource (CPU, memory, API limits) contention.").
 				Default(false),
+			service.NewObjectListField(rpFieldPolicies,
+				service.NewBloblangField(rpFieldPolicyCheck).
+					Description("A [Bloblang query](/docs/guides/bloblang/about) executed on the first errored message resulting from an attempt that should return a boolean value indicating whether the policy applies. The error of the message can be obtained with the `error()` function.").
+					Examples(`@http_status_code == 429`, `error().contains("validation")`),
+				service.NewObjectField(rpFieldPolicyBackoff,
+					service.NewDurationField(rpFieldPolicyInitialInterval).
+						Description("The initial period to wait between retry attempts. If not set the top level `initial_interval` is used.").
+						Optional(),
+					service.NewDurationField(rpFieldPolicyMaxInterval).
+						Description("The maximum period to wait between retry attempts. If not set the top level `max_interval` is used.").
+						Optional(),
+					service.NewDurationField(rpFieldPolicyMaxElapsedTime).
+						Description("The maximum overall period of time to spend on retry attempts, where a zeroed duration results in unbounded retries. If not set the top level `max_elapsed_time` is used.").
+						Optional(),
+				).Description("Determine time intervals and cut offs for retry attempts matching this policy, where fields that are not set are inherited from the top level `backoff`."),
+				service.NewIntField(rpFieldPolicyMaxRetries).
+					Description("The maximum number of retry attempts to make for errors matching this policy, where `0` results in such errors never being retried. If not set retry attempts are only limited by the backoff.").
+					Optional(),
+			).
+				Description("An optional list of policies that determine how attempts that result in specific errors are retried. The first policy with a matching `check` is applied, and when none match the top level `backoff` is used.").
+				Version("4.28.0").
+				Optional(),
 		)
 }
 
@@ -128,6 +187,22 @@ func init() {
 				return nil, err
 			}
 
+			if conf.Contains(rpFieldPolicies) {
+				policyConfs, err := conf.FieldObjectList(rpFieldPolicies)
+				if err != nil {
+					return nil, err
+				}
+				for i, pConf := range policyConfs {
+					policy, err := retryPolicyFromParsed(pConf, p.boff, mgr)
+					if err != nil {
+						return nil, fmt.Errorf("policy %v: %w", i, err)
+					}
+					p.policies = append(p.policies, policy)
+				}
+			}
+			// The top level backoff applies when no other policy matches.
+			p.policies = append(p.policies, retryPolicy{boff: p.boff, maxRetries: -1})
+
 			return interop.NewUnwrapInternalBatchProcessor(processor.NewAutoObservedBatchedProcessor("retry", p, mgr)), nil
 		})
 	if err != nil {
@@ -135,13 +210,76 @@ func init() {
 	}
 }
 
+type retryPolicy struct {
+	check      *mapping.Executor
+	boff       *backoff.ExponentialBackOff
+	maxRetries int
+}
+
+func retryPolicyFromParsed(conf *service.ParsedConfig, boff *backoff.ExponentialBackOff, mgr bundle.NewManagement) (p retryPolicy, err error) {
+	checkStr, err := conf.FieldString(rpFieldPolicyCheck)
+	if err != nil {
+		return
+	}
+	if p.check, err = mgr.BloblEnvironment().NewMapping(checkStr); err != nil {
+		return p, fmt.Errorf("failed to parse check: %w", err)
+	}
+
+	// Inherit any backoff fields that are not set from the top level backoff.
+	pBoff := *boff
+	if conf.Contains(rpFieldPolicyBackoff, rpFieldPolicyInitialInterval) {
+		if pBoff.InitialInterval, err = conf.FieldDuration(rpFieldPolicyBackoff, rpFieldPolicyInitialInterval); err != nil {
+			return
+		}
+	}
+	if conf.Contains(rpFieldPolicyBackoff, rpFieldPolicyMaxInterval) {
+		if pBoff.MaxInterval, err = conf.FieldDuration(rpFieldPolicyBackoff, rpFieldPolicyMaxInterval); err != nil {
+			return
+		}
+	}
+	if conf.Contains(rpFieldPolicyBackoff, rpFieldPolicyMaxElapsedTime) {
+		if pBoff.MaxElapsedTime, err = conf.FieldDuration(rpFieldPolicyBackoff, rpFieldPolicyMaxElapsedTime); err != nil {
+			return
+		}
+	}
+	p.boff = &pBoff
+
+	p.maxRetries = -1
+	if conf.Contains(rpFieldPolicyMaxRetries) {
+		if p.maxRetries, err = conf.FieldInt(rpFieldPolicyMaxRetries); err != nil {
+			return
+		}
+	}
+	return
+}
+
 type retryProc struct {
 	children []processor.V1
 	boff     *backoff.ExponentialBackOff
+	policies []retryPolicy
 	parallel bool
 	log      log.Modular
 }
 
+// policyFor returns the index of the first policy that applies to an errored
+// message, where the last policy applies to all messages.
+func (r *retryProc) policyFor(p *message.Part) int {
+	for i, policy := range r.policies {
+		if policy.check == nil {
+			return i
+		}
+		matched, err := policy.check.QueryPart(0, message.Batch{p})
+		if err != nil {
+			r.log.Debug("Failed to execute retry policy check: %v", err)
+			continue
+		}
+		if matched {
+			return i
+		}
+	}
+	return len(r.policies) - 1
+}
+
 func (r *retryProc) ProcessBatch(ctx *processor.BatchProcContext, msgs message.Batch) ([]message.Batch, error) {
 	var resMsg message.Batch
 	if r.parallel {
@@ -185,9 +323,14 @@ func (r *retryProc) ProcessBatch(ctx *processor.BatchProcContext, msgs message.B
 }
 
 func (r *retryProc) dispatchMessage(ctx context.Context, p *message.Part) ([]message.Batch, error) {
-	// NOTE: We always ensure we start off with a copy of the reference backoff.
-	boff := *r.boff
-	boff.Reset()
+	// NOTE: We always ensure we start off with copies of the reference
+	// backoffs.
+	boffs := make([]backoff.ExponentialBackOff, len(r.policies))
+	retries := make([]int, len(r.policies))
+	for i, policy := range r.policies {
+		boffs[i] = *policy.boff
+		boffs[i].Reset()
+	}
 
 	// Ensure we do not start off with an error.
 	p.ErrorSet(nil)
@@ -198,23 +341,31 @@ func (r *retryProc) dispatchMessage(ctx context.Context, p *message.Part) ([]mes
 			return nil, err
 		}
 
-		hasFailed := false
+		var failed *message.Part
 
 	errorChecks:
 		for _, b := range resBatches {
 			for _, m := range b {
 				if m.ErrorGet() != nil {
-					hasFailed = true
+					failed = m
 					break errorChecks
 				}
 			}
 		}
 
-		if !hasFailed {
+		if failed == nil {
+			return resBatches, nil
+		}
+		err = failed.ErrorGet()
+
+		i := r.policyFor(failed)
+		if maxRetries := r.policies[i].maxRetries; maxRetries >= 0 && retries[i] >= maxRetries {
+			r.log.With("error", err).Debug("Error occured and maximum retries were reached.")
 			return resBatches, nil
 		}
+		retries[i]++
 
-		nextSleep := boff.NextBackOff()
+		nextSleep := boffs[i].NextBackOff()
 		if nextSleep == backoff.Stop {
 			r.log.With("error", err).Debug("Error occured and maximum wait period was reached.")
 			return resBatches, nil
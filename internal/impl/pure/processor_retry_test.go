@@ -222,3 +222,70 @@ retry:
 
 	require.NoError(t, p.Close(context.Background()))
 }
+
+func TestRetryPolicies(t *testing.T) {
+	conf, err := testutil.ProcessorFromYAML(`
+retry:
+  backoff:
+    initial_interval: 1ms
+    max_interval: 10ms
+    max_elapsed_time: 100ms
+  policies:
+    - check: '@code == "400"'
+      max_retries: 0
+    - check: 'error().contains("slow down")'
+      backoff:
+        max_elapsed_time: 0s
+      max_retries: 5
+  processors:
+    - resource: foo
+`)
+	require.NoError(t, err)
+
+	mockMgr := mock.NewManager()
+
+	calls := map[string]int{}
+	mockMgr.Processors["foo"] = func(b message.Batch) ([]message.Batch, error) {
+		content := string(b[0].AsBytes())
+		calls[content]++
+		switch content {
+		case "bad request":
+			b[0].MetaSetMut("code", "400")
+			b[0].ErrorSet(errors.New("nope"))
+		case "rate limited":
+			b[0].ErrorSet(errors.New("slow down"))
+		case "flaky":
+			if calls[content] < 3 {
+				b[0].ErrorSet(errors.New("nope"))
+			}
+		default:
+			b[0].ErrorSet(errors.New("nope"))
+		}
+		return []message.Batch{{b[0]}}, nil
+	}
+
+	p, err := mockMgr.NewProcessor(conf)
+	require.NoError(t, err)
+
+	resBatches, err := p.ProcessBatch(context.Background(), message.Batch{
+		message.NewPart([]byte("bad request")),
+		message.NewPart([]byte("rate limited")),
+		message.NewPart([]byte("flaky")),
+		message.NewPart([]byte("broken")),
+	})
+	require.NoError(t, err)
+	require.Len(t, resBatches, 1)
+	require.Len(t, resBatches[0], 4)
+
+	assert.EqualError(t, resBatches[0][0].ErrorGet(), "nope")
+	assert.EqualError(t, resBatches[0][1].ErrorGet(), "slow down")
+	assert.NoError(t, resBatches[0][2].ErrorGet())
+	assert.EqualError(t, resBatches[0][3].ErrorGet(), "nope")
+
+	assert.Equal(t, 1, calls["bad request"])
+	assert.Equal(t, 6, calls["rate limited"])
+	assert.Equal(t, 3, calls["flaky"])
+	assert.Greater(t, calls["broken"], 6)
+
+	require.NoError(t, p.Close(context.Background()))
+}
@@ -0,0 +1,192 @@
+package pure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/OneOfOne/xxhash"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	spFieldRate               = "rate"
+	spFieldKey                = "key"
+	spFieldTargetThroughput   = "target_throughput"
+	spFieldAdjustmentInterval = "adjustment_interval"
+	spFieldRateMetadata       = "rate_metadata"
+)
+
+func sampleProcSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Categories("Utility").
+		Version("4.28.0").
+		Summary("Keeps a sample of messages and drops the rest, either at random or consistently by a key.").
+		Description(`
+By default each message is kept with a probability of the `+"`rate`"+`. When a `+"`key`"+` is specified the decision is instead made consistently by a hash of the key, so that all messages of a key, such as the spans of a trace or the logs of a request, are either kept or dropped together. Consistent sampling is also stable across instances of Benthos and restarts.
+
+### Dynamic Rates
+
+When a `+"`target_throughput`"+` is specified the sampling rate is adjusted after each `+"`adjustment_interval`"+` so that the throughput of messages kept approaches the target, where the rate is calculated from the throughput of messages observed during the previous interval and never exceeds the `+"`rate`"+`. Since messages are kept when the hash of their key falls below the rate, reducing the rate only drops keys that would otherwise be kept, and so keys are kept together as long as the rate is stable.
+
+The sampling rate that applied to a message kept can be added to its metadata with the `+"`rate_metadata`"+` field, which allows downstream systems to weight sampled messages.`).
+		Fields(
+			service.NewFloatField(spFieldRate).
+				Description("The fraction of messages to keep, between 0 and 1. When a `target_throughput` is specified this is the maximum rate.").
+				Default(1.0).
+				Example(0.1),
+			service.NewInterpolatedStringField(spFieldKey).
+				Description("An optional key to sample consistently by.").
+				Optional().
+				Example(`${! json("trace_id") }`).
+				Example(`${! meta("kafka_key") }`),
+			service.NewFloatField(spFieldTargetThroughput).
+				Description("An optional target number of messages to keep per second.").
+				Optional().
+				Example(100.0),
+			service.NewDurationField(spFieldAdjustmentInterval).
+				Description("The interval after which the sampling rate is adjusted when a `target_throughput` is specified.").
+				Default("1s").
+				Advanced(),
+			service.NewStringField(spFieldRateMetadata).
+				Description("An optional metadata key to store the sampling rate of messages kept within.").
+				Default("").
+				Example("sample_rate"),
+		).
+		Example("Sampling Traces", "Here we keep a tenth of all traces, where the spans of a trace are kept or dropped together.", `
+pipeline:
+  processors:
+    - sample:
+        key: ${! json("trace_id") }
+        rate: 0.1
+        rate_metadata: sample_rate
+`).
+		Example("Controlling Log Volume", "Here we limit the volume of debug logs to roughly one hundred per second regardless of how many are produced, whilst keeping all other logs.", `
+pipeline:
+  processors:
+    - switch:
+        - check: this.level == "debug"
+          processors:
+            - sample:
+                target_throughput: 100
+`)
+}
+
+func init() {
+	err := service.RegisterProcessor(
+		"sample", sampleProcSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Processor, error) {
+			return newSampleProcFromConfig(conf, time.Now)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type sampleProc struct {
+	key          *service.InterpolatedString
+	maxRate      float64
+	target       float64
+	interval     time.Duration
+	rateMetadata string
+	clock        func() time.Time
+
+	mut         sync.Mutex
+	rate        float64
+	windowStart time.Time
+	windowCount int
+}
+
+func newSampleProcFromConfig(conf *service.ParsedConfig, clock func() time.Time) (*sampleProc, error) {
+	s := &sampleProc{clock: clock}
+
+	var err error
+	if s.maxRate, err = conf.FieldFloat(spFieldRate); err != nil {
+		return nil, err
+	}
+	if s.maxRate < 0 || s.maxRate > 1 {
+		return nil, fmt.Errorf("rate must be between 0 and 1, got %v", s.maxRate)
+	}
+	if conf.Contains(spFieldKey) {
+		if s.key, err = conf.FieldInterpolatedString(spFieldKey); err != nil {
+			return nil, err
+		}
+	}
+	if conf.Contains(spFieldTargetThroughput) {
+		if s.target, err = conf.FieldFloat(spFieldTargetThroughput); err != nil {
+			return nil, err
+		}
+		if s.target <= 0 {
+			return nil, errors.New("target throughput must be greater than zero")
+		}
+	}
+	if s.interval, err = conf.FieldDuration(spFieldAdjustmentInterval); err != nil {
+		return nil, err
+	}
+	if s.interval <= 0 {
+		return nil, errors.New("adjustment interval must be greater than zero")
+	}
+	if s.rateMetadata, err = conf.FieldString(spFieldRateMetadata); err != nil {
+		return nil, err
+	}
+
+	s.rate = s.maxRate
+	s.windowStart = clock()
+	return s, nil
+}
+
+// currentRate counts a message towards the observed throughput and returns
+// the sampling rate that applies to it.
+func (s *sampleProc) currentRate() float64 {
+	if s.target <= 0 {
+		return s.maxRate
+	}
+
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	now := s.clock()
+	if elapsed := now.Sub(s.windowStart); elapsed >= s.interval {
+		s.rate = s.maxRate
+		if observed := float64(s.windowCount) / elapsed.Seconds(); observed > 0 {
+			s.rate = math.Min(s.maxRate, s.target/observed)
+		}
+		s.windowStart = now
+		s.windowCount = 0
+	}
+	s.windowCount++
+	return s.rate
+}
+
+func (s *sampleProc) Process(ctx context.Context, msg *service.Message) (service.MessageBatch, error) {
+	rate := s.currentRate()
+
+	var v float64
+	if s.key != nil {
+		key, err := s.key.TryBytes(msg)
+		if err != nil {
+			return nil, fmt.Errorf("key interpolation error: %w", err)
+		}
+		// Map the hash onto [0, 1) with the 53 bits of precision of a float.
+		v = float64(xxhash.Checksum64(key)>>11) / (1 << 53)
+	} else {
+		v = rand.Float64()
+	}
+	if v >= rate {
+		return nil, nil
+	}
+
+	if s.rateMetadata != "" {
+		msg.MetaSetMut(s.rateMetadata, rate)
+	}
+	return service.MessageBatch{msg}, nil
+}
+
+func (s *sampleProc) Close(ctx context.Context) error {
+	return nil
+}
@@ -0,0 +1,132 @@
+package pure
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func testSampleProc(t *testing.T, confStr string, clock func() time.Time) *sampleProc {
+	t.Helper()
+
+	conf, err := sampleProcSpec().ParseYAML(confStr, nil)
+	require.NoError(t, err)
+
+	proc, err := newSampleProcFromConfig(conf, clock)
+	require.NoError(t, err)
+	return proc
+}
+
+func countSampled(t *testing.T, proc *sampleProc, n int, content func(i int) string) int {
+	t.Helper()
+
+	kept := 0
+	for i := 0; i < n; i++ {
+		res, err := proc.Process(context.Background(), service.NewMessage([]byte(content(i))))
+		require.NoError(t, err)
+		kept += len(res)
+	}
+	return kept
+}
+
+func TestSampleRandom(t *testing.T) {
+	proc := testSampleProc(t, `rate: 0.2`, time.Now)
+
+	kept := countSampled(t, proc, 10000, func(i int) string { return "foo" })
+	assert.InDelta(t, 2000, kept, 300)
+
+	proc = testSampleProc(t, `rate: 0`, time.Now)
+	assert.Equal(t, 0, countSampled(t, proc, 100, func(i int) string { return "foo" }))
+}
+
+func TestSampleConsistent(t *testing.T) {
+	proc := testSampleProc(t, `
+rate: 0.3
+key: ${! json("trace") }
+rate_metadata: sample_rate
+`, time.Now)
+
+	keptTraces := map[string]int{}
+	for i := 0; i < 10000; i++ {
+		trace := strconv.Itoa(i % 1000)
+		res, err := proc.Process(context.Background(), service.NewMessage([]byte(`{"trace":"`+trace+`"}`)))
+		require.NoError(t, err)
+		if len(res) == 1 {
+			keptTraces[trace]++
+
+			rate, _ := res[0].MetaGetMut("sample_rate")
+			assert.Equal(t, 0.3, rate)
+		}
+	}
+
+	assert.InDelta(t, 300, len(keptTraces), 60)
+	for trace, n := range keptTraces {
+		assert.Equal(t, 10, n, trace)
+	}
+
+	// Keys kept at a lower rate are a subset of those kept at a higher rate.
+	lowerProc := testSampleProc(t, `
+rate: 0.1
+key: ${! json("trace") }
+`, time.Now)
+	for i := 0; i < 1000; i++ {
+		trace := strconv.Itoa(i)
+		res, err := lowerProc.Process(context.Background(), service.NewMessage([]byte(`{"trace":"`+trace+`"}`)))
+		require.NoError(t, err)
+		if len(res) == 1 {
+			assert.Contains(t, keptTraces, trace)
+		}
+	}
+}
+
+func TestSampleTargetThroughput(t *testing.T) {
+	currentTS := time.Unix(0, 0)
+	proc := testSampleProc(t, `
+rate: 0.5
+target_throughput: 100
+rate_metadata: sample_rate
+`, func() time.Time {
+		return currentTS
+	})
+
+	// The first interval applies the maximum rate.
+	kept := countSampled(t, proc, 1000, func(i int) string { return "foo" })
+	assert.InDelta(t, 500, kept, 100)
+
+	// After observing 1000 messages per second the rate is adjusted to keep 100
+	// of them.
+	currentTS = time.Unix(1, 0)
+	res, err := proc.Process(context.Background(), service.NewMessage([]byte("foo")))
+	require.NoError(t, err)
+	assert.Equal(t, 0.1, proc.rate)
+	if len(res) == 1 {
+		rate, _ := res[0].MetaGetMut("sample_rate")
+		assert.Equal(t, 0.1, rate)
+	}
+
+	// A lower throughput is capped at the maximum rate.
+	currentTS = time.Unix(11, 0)
+	_, err = proc.Process(context.Background(), service.NewMessage([]byte("foo")))
+	require.NoError(t, err)
+	assert.Equal(t, 0.5, proc.rate)
+}
+
+func TestSampleConfigErrors(t *testing.T) {
+	for _, confStr := range []string{
+		`rate: 1.5`,
+		`target_throughput: 0`,
+		`adjustment_interval: 0s`,
+	} {
+		conf, err := sampleProcSpec().ParseYAML(confStr, nil)
+		require.NoError(t, err)
+
+		_, err = newSampleProcFromConfig(conf, time.Now)
+		require.Error(t, err, confStr)
+	}
+}
@@ -2,6 +2,7 @@ package pure
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/benthosdev/benthos/v4/internal/component/interop"
 	"github.com/benthosdev/benthos/v4/internal/component/processor"
@@ -11,8 +12,9 @@ import (
 )
 
 const (
-	splitPFieldSize     = "size"
-	splitPFieldByteSize = "byte_size"
+	splitPFieldSize        = "size"
+	splitPFieldByteSize    = "byte_size"
+	splitPFieldMaxPartSize = "max_part_size"
 )
 
 func init() {
@@ -24,7 +26,11 @@ func init() {
 			Description(`
 This processor is for breaking batches down into smaller ones. In order to break a single message out into multiple messages use the `+"[`unarchive` processor](/docs/components/processors/unarchive)"+`.
 
-If there is a remainder of messages after splitting a batch the remainder is also sent as a single batch. For example, if your target size was 10, and the processor received a batch of 95 message parts, the result would be 9 batches of 10 messages followed by a batch of 5 messages.`).
+If there is a remainder of messages after splitting a batch the remainder is also sent as a single batch. For example, if your target size was 10, and the processor received a batch of 95 message parts, the result would be 9 batches of 10 messages followed by a batch of 5 messages.
+
+### Payload Limits
+
+When splitting by `+"`byte_size`"+` a batch is ended before adding a message that would take it beyond the limit, and therefore the resulting batches only exceed `+"`byte_size`"+` when a single message does. Outputs with hard payload limits (such as the 256KB limit of AWS SQS batches) can be protected from those messages by setting `+"`max_part_size`"+`, where messages larger than it are flagged as having failed and emitted within batches of their own. These messages can then be routed elsewhere or dropped using [standard processor error handling patterns](/docs/configuration/error_handling).`).
 			Fields(
 				service.NewIntField(splitPFieldSize).
 					Description("The target number of messages.").
@@ -32,7 +38,29 @@ If there is a remainder of messages after splitting a batch the remainder is als
 				service.NewIntField(splitPFieldByteSize).
 					Description("An optional target of total message bytes.").
 					Default(0),
-			),
+				service.NewIntField(splitPFieldMaxPartSize).
+					Description("An optional maximum size in bytes of individual messages, messages larger than this are flagged as having failed and emitted in batches of their own. If `0` messages of any size are accepted.").
+					Version("4.28.0").
+					Default(0),
+			).
+			Example("SQS Payload Limits", "AWS SQS limits the total size of a batch to 256KB. Here we break batches down so that they fit within that limit, and drop messages that would not fit even on their own.", `
+pipeline:
+  processors:
+    - split:
+        size: 10
+        byte_size: 262144
+        max_part_size: 262144
+    - catch:
+        - log:
+            level: ERROR
+            message: 'Dropping oversized message: ${! error() }'
+        - mapping: 'root = deleted()'
+
+output:
+  aws_sqs:
+    url: https://sqs.us-west-2.amazonaws.com/TODO/TODO
+    max_in_flight: 16
+`),
 		func(conf *service.ParsedConfig, res *service.Resources) (service.BatchProcessor, error) {
 			mgr := interop.UnwrapManagement(res)
 			s := &splitProc{log: mgr.Logger()}
@@ -44,6 +72,9 @@ If there is a remainder of messages after splitting a batch the remainder is als
 			if s.byteSize, err = conf.FieldInt(splitPFieldByteSize); err != nil {
 				return nil, err
 			}
+			if s.maxPartSize, err = conf.FieldInt(splitPFieldMaxPartSize); err != nil {
+				return nil, err
+			}
 			return interop.NewUnwrapInternalBatchProcessor(processor.NewAutoObservedBatchedProcessor("split", s, mgr)), nil
 		})
 	if err != nil {
@@ -54,8 +85,9 @@ If there is a remainder of messages after splitting a batch the remainder is als
 type splitProc struct {
 	log log.Modular
 
-	size     int
-	byteSize int
+	size        int
+	byteSize    int
+	maxPartSize int
 }
 
 func (s *splitProc) ProcessBatch(ctx *processor.BatchProcContext, msg message.Batch) ([]message.Batch, error) {
@@ -69,6 +101,16 @@ func (s *splitProc) ProcessBatch(ctx *processor.BatchProcContext, msg message.Ba
 	byteSize := 0
 
 	_ = msg.Iter(func(i int, p *message.Part) error {
+		if partSize := len(p.AsBytes()); s.maxPartSize > 0 && partSize > s.maxPartSize {
+			ctx.OnError(fmt.Errorf("message size of %v bytes exceeds the maximum of %v bytes", partSize, s.maxPartSize), i, p)
+			if nextMsg.Len() > 0 {
+				msgs = append(msgs, nextMsg)
+				nextMsg = message.QuickBatch(nil)
+				byteSize = 0
+			}
+			msgs = append(msgs, message.Batch{p})
+			return nil
+		}
 		if (s.size > 0 && nextMsg.Len() >= s.size) ||
 			(s.byteSize > 0 && (byteSize+len(p.AsBytes())) > s.byteSize) {
 			if nextMsg.Len() > 0 {
@@ -4,6 +4,7 @@ import (
 	"context"
 	"testing"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
 	"github.com/benthosdev/benthos/v4/internal/component/processor"
@@ -179,3 +180,39 @@ split:
 		t.Errorf("Wrong contents: %v != %v", act, exp)
 	}
 }
+
+func TestSplitMaxPartSize(t *testing.T) {
+	conf, err := testutil.ProcessorFromYAML(`
+split:
+  size: 0
+  byte_size: 6
+  max_part_size: 4
+`)
+	require.NoError(t, err)
+
+	proc, err := mock.NewManager().NewProcessor(conf)
+	require.NoError(t, err)
+
+	msgs, res := proc.ProcessBatch(context.Background(), message.QuickBatch([][]byte{
+		[]byte("foo"),
+		[]byte("bar"),
+		[]byte("baz"),
+		[]byte("too large"),
+		[]byte("qux"),
+	}))
+	require.NoError(t, res)
+
+	var batches [][]string
+	var errored []bool
+	for _, m := range msgs {
+		var batch []string
+		for _, p := range m {
+			batch = append(batch, string(p.AsBytes()))
+			errored = append(errored, p.ErrorGet() != nil)
+		}
+		batches = append(batches, batch)
+	}
+	assert.Equal(t, [][]string{{"foo", "bar"}, {"baz"}, {"too large"}, {"qux"}}, batches)
+	assert.Equal(t, []bool{false, false, false, true, false}, errored)
+	assert.EqualError(t, msgs[2][0].ErrorGet(), "message size of 9 bytes exceeds the maximum of 4 bytes")
+}
@@ -0,0 +1,287 @@
+package pure
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	htemplate "html/template"
+	"io"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	ttemplate "text/template"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	trpFieldCode     = "code"
+	trpFieldFiles    = "files"
+	trpFieldTemplate = "template"
+	trpFieldHTML     = "html"
+	trpFieldStrict   = "strict"
+
+	trpInlineName = "inline"
+)
+
+func templateRenderProcSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Categories("Utility").
+		Version("4.28.0").
+		Summary("Renders a [Go template](https://pkg.go.dev/text/template) with the contents of each message, replacing the message with the result.").
+		Description(`
+Templates are executed with messages parsed as JSON as their context, where fields are accessed with the dot syntax, such as `+"`{{ .user.name }}`"+`. Messages that cannot be parsed, or that fail to render, are flagged as having failed, allowing you to use [standard processor error handling patterns](/docs/configuration/error_handling).
+
+Templates are either written inline with `+"`code`"+` or loaded from `+"`files`"+`, in which case each file defines a template named after the base name of its path. Templates can execute one another with the `+"`template`"+` action, which allows shared layouts and partials to be kept in separate files.
+
+When `+"`html`"+` is set templates are rendered with [`+"`html/template`"+`](https://pkg.go.dev/html/template), which escapes values according to the context in which they appear, and should be used when rendering HTML containing values taken from messages.
+
+### Functions
+
+Along with the [functions built into Go templates](https://pkg.go.dev/text/template#hdr-Functions) the following functions are available:
+
+- `+"`meta`"+` returns the value of a metadata key of the message as a string, or an empty string when it does not exist, such as `+"`{{ meta \"kafka_key\" }}`"+`.
+- `+"`json`"+` encodes a value as a JSON string.
+- `+"`upper`"+` and `+"`lower`"+` convert a string to upper or lower case.
+- `+"`join`"+` joins an array of values with a separator, such as `+"`{{ .tags | join \", \" }}`"+`.`).
+		Fields(
+			service.NewStringField(trpFieldCode).
+				Description("An inline template to render.").
+				Example(`Order {{ .id }} for {{ .customer.name }} has shipped.`).
+				Optional(),
+			service.NewStringListField(trpFieldFiles).
+				Description("A list of paths of template files to load, which may include glob patterns.").
+				Example([]any{"./templates/*.tmpl"}).
+				Default([]any{}),
+			service.NewStringField(trpFieldTemplate).
+				Description("The name of the template to render. Defaults to the inline template when `code` is set, and otherwise to the first file loaded in lexical order of their paths.").
+				Example("ticket.tmpl").
+				Optional(),
+			service.NewBoolField(trpFieldHTML).
+				Description("Whether to render templates as HTML, escaping values according to the context in which they appear.").
+				Default(false),
+			service.NewBoolField(trpFieldStrict).
+				Description("Whether to fail messages that are missing fields referenced by templates, rather than rendering them as `<no value>`.").
+				Default(false),
+		).
+		Example("Notification Emails", "Here we render the HTML body of an email from order events, with a layout shared between several templates, and set the subject as metadata for an output.", `
+pipeline:
+  processors:
+    - mutation: 'meta subject = "Order %v has shipped".format(this.id)'
+    - template_render:
+        files: [ ./templates/*.html ]
+        template: shipped.html
+        html: true
+        strict: true
+`).
+		Example("Support Tickets", "Here we render a plain text description of a ticket from an alert.", `
+pipeline:
+  processors:
+    - template_render:
+        code: |
+          {{ upper .severity }}: {{ .summary }}
+
+          Host: {{ .host }}
+          Labels: {{ .labels | join ", " }}
+          Source: {{ meta "kafka_topic" }}
+`)
+}
+
+func init() {
+	err := service.RegisterProcessor(
+		"template_render", templateRenderProcSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Processor, error) {
+			return newTemplateRenderProcFromConfig(conf, mgr)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+// boundTemplate is a copy of a parsed template where functions that access
+// the message being rendered are bound to the msg field.
+type boundTemplate struct {
+	msg     *service.Message
+	execute func(w io.Writer, data any) error
+}
+
+func (b *boundTemplate) funcs() map[string]any {
+	return map[string]any{
+		"meta": func(key string) string {
+			if b.msg == nil {
+				return ""
+			}
+			v, _ := b.msg.MetaGet(key)
+			return v
+		},
+		"json": func(v any) (string, error) {
+			jBytes, err := json.Marshal(v)
+			return string(jBytes), err
+		},
+		"upper": strings.ToUpper,
+		"lower": strings.ToLower,
+		"join": func(sep string, values []any) string {
+			strs := make([]string, len(values))
+			for i, v := range values {
+				strs[i] = fmt.Sprint(v)
+			}
+			return strings.Join(strs, sep)
+		},
+	}
+}
+
+type templateSource struct {
+	name string
+	code string
+}
+
+type templateRenderProc struct {
+	// bind returns a copy of the parsed templates with functions bound to a
+	// new boundTemplate.
+	bind  func() (*boundTemplate, error)
+	bound sync.Pool
+}
+
+func newTemplateRenderProcFromConfig(conf *service.ParsedConfig, mgr *service.Resources) (*templateRenderProc, error) {
+	var sources []templateSource
+	if conf.Contains(trpFieldCode) {
+		code, err := conf.FieldString(trpFieldCode)
+		if err != nil {
+			return nil, err
+		}
+		sources = append(sources, templateSource{name: trpInlineName, code: code})
+	}
+
+	files, err := conf.FieldStringList(trpFieldFiles)
+	if err != nil {
+		return nil, err
+	}
+	if files, err = service.Globs(mgr.FS(), files...); err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+	for _, f := range files {
+		code, err := service.ReadFile(mgr.FS(), f)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read template file: %w", err)
+		}
+		sources = append(sources, templateSource{name: path.Base(f), code: string(code)})
+	}
+	if len(sources) == 0 {
+		return nil, errors.New("either code or files must be specified")
+	}
+
+	name := sources[0].name
+	if conf.Contains(trpFieldTemplate) {
+		if name, err = conf.FieldString(trpFieldTemplate); err != nil {
+			return nil, err
+		}
+	}
+
+	isHTML, err := conf.FieldBool(trpFieldHTML)
+	if err != nil {
+		return nil, err
+	}
+	strict, err := conf.FieldBool(trpFieldStrict)
+	if err != nil {
+		return nil, err
+	}
+	missingKey := "missingkey=default"
+	if strict {
+		missingKey = "missingkey=error"
+	}
+
+	p := &templateRenderProc{}
+	if isHTML {
+		p.bind, err = parseHTMLTemplates(sources, name, missingKey)
+	} else {
+		p.bind, err = parseTextTemplates(sources, name, missingKey)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func parseTextTemplates(sources []templateSource, name, missingKey string) (func() (*boundTemplate, error), error) {
+	root := ttemplate.New("").Option(missingKey).Funcs((&boundTemplate{}).funcs())
+	for _, s := range sources {
+		if _, err := root.New(s.name).Parse(s.code); err != nil {
+			return nil, fmt.Errorf("failed to parse template %v: %w", s.name, err)
+		}
+	}
+	if root.Lookup(name) == nil {
+		return nil, fmt.Errorf("template %v not found", name)
+	}
+	return func() (*boundTemplate, error) {
+		t, err := root.Clone()
+		if err != nil {
+			return nil, err
+		}
+		b := &boundTemplate{}
+		t.Funcs(b.funcs())
+		b.execute = func(w io.Writer, data any) error {
+			return t.ExecuteTemplate(w, name, data)
+		}
+		return b, nil
+	}, nil
+}
+
+func parseHTMLTemplates(sources []templateSource, name, missingKey string) (func() (*boundTemplate, error), error) {
+	root := htemplate.New("").Option(missingKey).Funcs((&boundTemplate{}).funcs())
+	for _, s := range sources {
+		if _, err := root.New(s.name).Parse(s.code); err != nil {
+			return nil, fmt.Errorf("failed to parse template %v: %w", s.name, err)
+		}
+	}
+	if root.Lookup(name) == nil {
+		return nil, fmt.Errorf("template %v not found", name)
+	}
+
+	// Copies of html templates must be made before any are executed.
+	return func() (*boundTemplate, error) {
+		t, err := root.Clone()
+		if err != nil {
+			return nil, err
+		}
+		b := &boundTemplate{}
+		t.Funcs(b.funcs())
+		b.execute = func(w io.Writer, data any) error {
+			return t.ExecuteTemplate(w, name, data)
+		}
+		return b, nil
+	}, nil
+}
+
+func (p *templateRenderProc) Process(ctx context.Context, msg *service.Message) (service.MessageBatch, error) {
+	data, err := msg.AsStructured()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse message as JSON: %w", err)
+	}
+
+	b, _ := p.bound.Get().(*boundTemplate)
+	if b == nil {
+		if b, err = p.bind(); err != nil {
+			return nil, err
+		}
+	}
+
+	var buf bytes.Buffer
+	b.msg = msg
+	err = b.execute(&buf, data)
+	b.msg = nil
+	p.bound.Put(b)
+	if err != nil {
+		return nil, err
+	}
+
+	msg.SetBytes(buf.Bytes())
+	return service.MessageBatch{msg}, nil
+}
+
+func (p *templateRenderProc) Close(ctx context.Context) error {
+	return nil
+}
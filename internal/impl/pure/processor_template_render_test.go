@@ -0,0 +1,122 @@
+package pure
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func testTemplateRenderProc(t *testing.T, confStr string) *templateRenderProc {
+	t.Helper()
+
+	conf, err := templateRenderProcSpec().ParseYAML(confStr, nil)
+	require.NoError(t, err)
+
+	proc, err := newTemplateRenderProcFromConfig(conf, service.MockResources())
+	require.NoError(t, err)
+	return proc
+}
+
+func renderTemplate(t *testing.T, proc *templateRenderProc, msg *service.Message) string {
+	t.Helper()
+
+	res, err := proc.Process(context.Background(), msg)
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+
+	b, err := res[0].AsBytes()
+	require.NoError(t, err)
+	return string(b)
+}
+
+func TestTemplateRenderInline(t *testing.T) {
+	proc := testTemplateRenderProc(t, `
+code: '{{ upper .severity }}: {{ .summary }} [{{ .labels | join ", " }}] from {{ meta "topic" }} {{ json .host }}'
+`)
+
+	msg := service.NewMessage([]byte(`{"severity":"high","summary":"Disk <full>","labels":["a",2],"host":{"name":"db1"}}`))
+	msg.MetaSetMut("topic", "alerts")
+
+	assert.Equal(t, `HIGH: Disk <full> [a, 2] from alerts {"name":"db1"}`, renderTemplate(t, proc, msg))
+}
+
+func TestTemplateRenderFiles(t *testing.T) {
+	tmpDir := t.TempDir()
+	for name, content := range map[string]string{
+		"layout.html":  `<html><body>{{ block "content" . }}{{ end }}</body></html>`,
+		"shipped.html": `{{ define "content" }}<p>Hi {{ .name }}, <a href="/orders?id={{ .id }}">order {{ .id }}</a> has shipped.</p>{{ end }}{{ template "layout.html" . }}`,
+	} {
+		require.NoError(t, os.WriteFile(filepath.Join(tmpDir, name), []byte(content), 0o644))
+	}
+
+	proc := testTemplateRenderProc(t, `
+files: [ "`+filepath.Join(tmpDir, "*.html")+`" ]
+template: shipped.html
+html: true
+`)
+
+	// Renders concurrently in order to exercise the copies of templates bound
+	// to each message.
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.Equal(t,
+				`<html><body><p>Hi &lt;b&gt;Jane&lt;/b&gt;, <a href="/orders?id=a%26b">order a&amp;b</a> has shipped.</p></body></html>`,
+				renderTemplate(t, proc, service.NewMessage([]byte(`{"name":"<b>Jane</b>","id":"a&b"}`))),
+			)
+		}()
+	}
+	wg.Wait()
+
+	// Without a template name the first file is rendered.
+	tmpDir = t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "b.txt"), []byte(`B{{ .x }}`), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "a.txt"), []byte(`A {{ template "b.txt" . }}`), 0o644))
+
+	proc = testTemplateRenderProc(t, `
+files: [ "`+filepath.Join(tmpDir, "*.txt")+`" ]
+`)
+	assert.Equal(t, `A B1`, renderTemplate(t, proc, service.NewMessage([]byte(`{"x":1}`))))
+}
+
+func TestTemplateRenderStrict(t *testing.T) {
+	proc := testTemplateRenderProc(t, `code: 'Hi {{ .name }}'`)
+	assert.Equal(t, `Hi <no value>`, renderTemplate(t, proc, service.NewMessage([]byte(`{}`))))
+
+	proc = testTemplateRenderProc(t, `
+code: 'Hi {{ .name }}'
+strict: true
+`)
+	_, err := proc.Process(context.Background(), service.NewMessage([]byte(`{}`)))
+	assert.ErrorContains(t, err, `map has no entry for key "name"`)
+
+	_, err = proc.Process(context.Background(), service.NewMessage([]byte(`not json`)))
+	assert.ErrorContains(t, err, "failed to parse message as JSON")
+}
+
+func TestTemplateRenderConfigErrors(t *testing.T) {
+	for _, confStr := range []string{
+		`files: []`,
+		`code: '{{ .name '`,
+		`code: '{{ nope }}'`,
+		`
+code: 'foo'
+template: bar
+`,
+	} {
+		conf, err := templateRenderProcSpec().ParseYAML(confStr, nil)
+		require.NoError(t, err)
+
+		_, err = newTemplateRenderProcFromConfig(conf, service.MockResources())
+		assert.Error(t, err, confStr)
+	}
+}
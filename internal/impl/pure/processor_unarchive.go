@@ -11,6 +11,8 @@ import (
 	"fmt"
 	"io"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/benthosdev/benthos/v4/internal/message"
 	"github.com/benthosdev/benthos/v4/public/service"
@@ -26,11 +28,16 @@ When a message is unarchived the new messages replace the original message in th
 
 ## Metadata
 
-The metadata found on the messages handled by this processor will be copied into the resulting messages. For the unarchive formats that contain file information (tar, zip), a metadata field is also added to each message called ` + "`archive_filename`" + ` with the extracted filename.
+The metadata found on the messages handled by this processor will be copied into the resulting messages. For the unarchive formats that contain file information (tar, zip, 7z), a metadata field is also added to each message called `+"`archive_filename`"+` with the extracted filename, and a field called `+"`archive_mod_time`"+` with the modification time of the file in RFC 3339 format. The metadata of messages that were archived by the `+"[`archive` processor](/docs/components/processors/archive)"+` with `+"`preserve_metadata`"+` enabled is also restored.
+
+## Compression
+
+Archives that are compressed, such as `+"`.tar.zst`"+` or `+"`.tar.gz`"+` files, can be extracted by setting `+"`compression`"+`. Tar archives are extracted whilst they are being decompressed, and so the decompressed archive as a whole is never held in memory. When consuming large archives from files or objects the `+"[`decompress`](/docs/components/scanners/decompress) and [`tar`](/docs/components/scanners/tar) scanners"+` should be preferred, as they emit each entry as it is read rather than the processor emitting all entries at once.
 `).
 		Field(service.NewStringAnnotatedEnumField("format", map[string]string{
 			`tar`:            `Extract messages from a unix standard tape archive.`,
 			`zip`:            `Extract messages from a zip file.`,
+			`7z`:             `Extract messages from a 7z archive.`,
 			`binary`:         `Extract messages from a [binary blob format](https://github.com/benthosdev/benthos/blob/main/internal/message/message.go#L96).`,
 			`lines`:          `Extract the lines of a message each into their own message.`,
 			`json_documents`: `Attempt to parse a message as a stream of concatenated JSON documents. Each parsed document is expanded into a new message.`,
@@ -38,7 +45,24 @@ The metadata found on the messages handled by this processor will be copied into
 			`json_map`:       `Attempt to parse the message as a JSON map and for each element of the map expands its contents into a new message. A metadata field is added to each message called ` + "`archive_key`" + ` with the relevant key from the top-level map.`,
 			`csv`:            `Attempt to parse the message as a csv file (header required) and for each row in the file expands its contents into a json object in a new message.`,
 			`csv:x`:          `Attempt to parse the message as a csv file (header required) and for each row in the file expands its contents into a json object in a new message using a custom delimiter. The custom delimiter must be a single character, e.g. the format "csv:\t" would consume a tab delimited file.`,
-		}).Description("The unarchiving format to apply.").LintRule(``)) // NOTE: We disable the linter here because `csv:x` is a dynamic pattern
+		}).Description("The unarchiving format to apply.").LintRule(``)). // NOTE: We disable the linter here because `csv:x` is a dynamic pattern
+		Field(service.NewStringField("compression").
+			Description("An optional compression algorithm to decompress archives with before extracting them. Supported algorithms are the same as those of the `decompress` processor.").
+			Example("zstd").
+			Example("gzip").
+			Version("4.28.0").
+			Optional()).
+		Example("Compressed Tar Archives", "Here we extract the files of zstd compressed tar archives consumed from S3, keeping the name of each file within the archive.", `
+input:
+  aws_s3:
+    bucket: my-archives
+    prefix: exports/
+  processors:
+    - unarchive:
+        format: tar
+        compression: zstd
+    - mapping: 'meta key = meta("s3_key") + "/" + meta("archive_filename")'
+`)
 }
 
 func init() {
@@ -54,14 +78,42 @@ func init() {
 
 type unarchiveFunc func(part *service.Message) (service.MessageBatch, error)
 
+// UnarchiveFunc extracts the entries of an archive from its raw bytes, where
+// each entry is a copy of the archive message.
+type UnarchiveFunc func(part *service.Message, b []byte) (service.MessageBatch, error)
+
+var knownUnarchiveFormats = map[string]UnarchiveFunc{}
+
+var knownUnarchiveFormatsLock sync.Mutex
+
+// AddKnownUnarchiveFormat registers an unarchive format that can be selected
+// by the unarchive processor.
+func AddKnownUnarchiveFormat(name string, fn UnarchiveFunc) struct{} {
+	knownUnarchiveFormatsLock.Lock()
+	knownUnarchiveFormats[name] = fn
+	knownUnarchiveFormatsLock.Unlock()
+	return struct{}{}
+}
+
+// SetArchiveEntryMetadata adds metadata describing an entry extracted from an
+// archive to its message.
+func SetArchiveEntryMetadata(part *service.Message, name string, modTime time.Time) {
+	part.MetaSet("archive_filename", name)
+	if !modTime.IsZero() {
+		part.MetaSet("archive_mod_time", modTime.UTC().Format(time.RFC3339))
+	}
+}
+
 func tarUnarchive(part *service.Message) (service.MessageBatch, error) {
 	pBytes, err := part.AsBytes()
 	if err != nil {
 		return nil, err
 	}
+	return tarUnarchiveReader(part, bytes.NewReader(pBytes))
+}
 
-	buf := bytes.NewBuffer(pBytes)
-	tr := tar.NewReader(buf)
+func tarUnarchiveReader(part *service.Message, r io.Reader) (service.MessageBatch, error) {
+	tr := tar.NewReader(r)
 
 	var newParts []*service.Message
 
@@ -83,7 +135,12 @@ func tarUnarchive(part *service.Message) (service.MessageBatch, error) {
 
 		newPart := part.Copy()
 		newPart.SetBytes(newPartBuf.Bytes())
-		newPart.MetaSet("archive_filename", h.Name)
+		SetArchiveEntryMetadata(newPart, h.Name, h.ModTime)
+		for k, v := range h.PAXRecords {
+			if key, isMeta := strings.CutPrefix(k, archivePAXMetaPrefix); isMeta {
+				newPart.MetaSet(key, v)
+			}
+		}
 		newParts = append(newParts, newPart)
 	}
 
@@ -112,13 +169,22 @@ func zipUnarchive(part *service.Message) (service.MessageBatch, error) {
 		}
 
 		newPartBuf := bytes.Buffer{}
-		if _, err = newPartBuf.ReadFrom(fr); err != nil {
+		_, err = newPartBuf.ReadFrom(fr)
+		_ = fr.Close()
+		if err != nil {
 			return nil, err
 		}
 
 		newPart := part.Copy()
 		newPart.SetBytes(newPartBuf.Bytes())
-		newPart.MetaSet("archive_filename", f.Name)
+		SetArchiveEntryMetadata(newPart, f.Name, f.Modified)
+		meta, err := zipMetadataFromExtra(f.Extra)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse metadata of %v: %w", f.Name, err)
+		}
+		for k, v := range meta {
+			newPart.MetaSet(k, v)
+		}
 		newParts = append(newParts, newPart)
 	}
 
@@ -304,6 +370,19 @@ func strToUnarchiver(str string) (unarchiveFunc, error) {
 		return csvUnarchive(nil), nil
 	}
 
+	knownUnarchiveFormatsLock.Lock()
+	fn, exists := knownUnarchiveFormats[str]
+	knownUnarchiveFormatsLock.Unlock()
+	if exists {
+		return func(part *service.Message) (service.MessageBatch, error) {
+			pBytes, err := part.AsBytes()
+			if err != nil {
+				return nil, err
+			}
+			return fn(part, pBytes)
+		}, nil
+	}
+
 	if strings.HasPrefix(str, "csv:") {
 		by := strings.TrimPrefix(str, "csv:")
 		if by == "" {
@@ -323,8 +402,10 @@ func strToUnarchiver(str string) (unarchiveFunc, error) {
 //------------------------------------------------------------------------------
 
 type unarchiveProc struct {
-	unarchive unarchiveFunc
-	log       *service.Logger
+	unarchive  unarchiveFunc
+	isTar      bool
+	decompress DecompressReader
+	log        *service.Logger
 }
 
 func newUnarchiveFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*unarchiveProc, error) {
@@ -332,7 +413,20 @@ func newUnarchiveFromParsed(conf *service.ParsedConfig, mgr *service.Resources)
 	if err != nil {
 		return nil, err
 	}
-	return newUnarchive(mgr, formatStr)
+	u, err := newUnarchive(mgr, formatStr)
+	if err != nil {
+		return nil, err
+	}
+	if conf.Contains("compression") {
+		algStr, err := conf.FieldString("compression")
+		if err != nil {
+			return nil, err
+		}
+		if u.decompress, err = strToDecompressReader(algStr); err != nil {
+			return nil, err
+		}
+	}
+	return u, nil
 }
 
 func newUnarchive(nm *service.Resources, format string) (*unarchiveProc, error) {
@@ -342,12 +436,47 @@ func newUnarchive(nm *service.Resources, format string) (*unarchiveProc, error)
 	}
 	return &unarchiveProc{
 		unarchive: unarchiver,
+		isTar:     format == "tar",
 		log:       nm.Logger(),
 	}, nil
 }
 
+func (d *unarchiveProc) decompressAndUnarchive(msg *service.Message) (service.MessageBatch, error) {
+	pBytes, err := msg.AsBytes()
+	if err != nil {
+		return nil, err
+	}
+	rdr, err := d.decompress(bytes.NewReader(pBytes))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress archive: %w", err)
+	}
+	if c, ok := rdr.(io.Closer); ok {
+		defer c.Close()
+	}
+
+	if d.isTar {
+		return tarUnarchiveReader(msg, rdr)
+	}
+
+	dBytes, err := io.ReadAll(rdr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress archive: %w", err)
+	}
+
+	// Unarchive a copy so that the message remains unchanged on failure.
+	tmp := msg.Copy()
+	tmp.SetBytes(dBytes)
+	return d.unarchive(tmp)
+}
+
 func (d *unarchiveProc) Process(ctx context.Context, msg *service.Message) (service.MessageBatch, error) {
-	newParts, err := d.unarchive(msg)
+	var newParts service.MessageBatch
+	var err error
+	if d.decompress != nil {
+		newParts, err = d.decompressAndUnarchive(msg)
+	} else {
+		newParts, err = d.unarchive(msg)
+	}
 	if err != nil {
 		d.log.Errorf("Failed to unarchive message part: %v\n", err)
 		return nil, err
@@ -360,3 +360,93 @@ format: csv:|
 		assert.Equal(t, e, string(mBytes))
 	}
 }
+
+func TestUnarchiveArchivedMetadata(t *testing.T) {
+	for _, test := range []struct {
+		format      string
+		compression string
+	}{
+		{format: "tar"},
+		{format: "zip"},
+		{format: "tar", compression: "gzip"},
+		{format: "zip", compression: "gzip"},
+	} {
+		name := test.format + "/" + test.compression
+
+		archiveConfStr := `
+format: ` + test.format + `
+path: '${! content() }.txt'
+preserve_metadata: true
+`
+		unarchiveConfStr := `format: ` + test.format
+		if test.compression != "" {
+			archiveConfStr += `compression: ` + test.compression
+			unarchiveConfStr += `
+compression: ` + test.compression
+		}
+
+		aConf, err := archiveProcConfig().ParseYAML(archiveConfStr, nil)
+		require.NoError(t, err, name)
+
+		aProc, err := newArchiveFromParsed(aConf, service.MockResources())
+		require.NoError(t, err, name)
+
+		first, second := service.NewMessage([]byte("first")), service.NewMessage([]byte("second"))
+		first.MetaSet("id", "a")
+		first.MetaSet("topic", "foo")
+		second.MetaSet("id", "b")
+
+		batches, err := aProc.ProcessBatch(context.Background(), service.MessageBatch{first, second})
+		require.NoError(t, err, name)
+		require.Len(t, batches, 1, name)
+		require.Len(t, batches[0], 1, name)
+
+		archived := batches[0][0]
+
+		uConf, err := unarchiveProcConfig().ParseYAML(unarchiveConfStr, nil)
+		require.NoError(t, err, name)
+
+		uProc, err := newUnarchiveFromParsed(uConf, service.MockResources())
+		require.NoError(t, err, name)
+
+		msgs, err := uProc.Process(context.Background(), archived)
+		require.NoError(t, err, name)
+		require.Len(t, msgs, 2, name)
+
+		var contents []string
+		var metas []map[string]any
+		for _, m := range msgs {
+			mBytes, err := m.AsBytes()
+			require.NoError(t, err)
+			contents = append(contents, string(mBytes))
+
+			meta := map[string]any{}
+			_ = m.MetaWalkMut(func(k string, v any) error {
+				if k != "archive_mod_time" {
+					meta[k] = v
+				}
+				return nil
+			})
+			metas = append(metas, meta)
+
+			_, exists := m.MetaGet("archive_mod_time")
+			assert.True(t, exists, name)
+		}
+		assert.Equal(t, []string{"first", "second"}, contents, name)
+		assert.Equal(t, []map[string]any{
+			{"id": "a", "topic": "foo", "archive_filename": "first.txt"},
+			{"id": "b", "topic": "foo", "archive_filename": "second.txt"},
+		}, metas, name)
+	}
+}
+
+func TestArchivePreserveMetadataFormats(t *testing.T) {
+	conf, err := archiveProcConfig().ParseYAML(`
+format: lines
+preserve_metadata: true
+`, nil)
+	require.NoError(t, err)
+
+	_, err = newArchiveFromParsed(conf, service.MockResources())
+	assert.EqualError(t, err, "preserve_metadata is not supported by the lines format")
+}
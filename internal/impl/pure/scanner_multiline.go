@@ -0,0 +1,246 @@
+package pure
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	smlFieldStartPattern        = "start_pattern"
+	smlFieldContinuationPattern = "continuation_pattern"
+	smlFieldNegate              = "negate"
+	smlFieldMaxLines            = "max_lines"
+	smlFieldTimeout             = "timeout"
+	smlFieldMaxBufferSize       = "max_buffer_size"
+)
+
+func multilineScannerSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Version("4.28.0").
+		Summary("Split an input stream into lines and join consecutive lines that belong to the same event, such as the lines of a stack trace, into a single message.").
+		Description(`
+Exactly one of `+"`start_pattern`"+` or `+"`continuation_pattern`"+` must be set. When `+"`start_pattern`"+` is set each line that matches it begins a new event and all other lines are appended to the current event. When `+"`continuation_pattern`"+` is set each line that matches it is appended to the current event and all other lines begin a new event. Setting `+"`negate`"+` inverts the match of either pattern.
+
+Lines are joined with line breaks. An event is emitted once a line that begins the next event is read, once the event reaches `+"`max_lines`"+` lines, once no lines have been read for the duration of `+"`timeout`"+`, or at the end of the stream.`).
+		Fields(
+			service.NewStringField(smlFieldStartPattern).
+				Description("A regular expression that matches lines that begin an event.").
+				Example(`^\d{4}-\d{2}-\d{2}`).
+				Example(`^\[`).
+				Optional(),
+			service.NewStringField(smlFieldContinuationPattern).
+				Description("A regular expression that matches lines that continue the current event.").
+				Example(`^\s+(at|\.{3})\s|^Caused by:`).
+				Optional(),
+			service.NewBoolField(smlFieldNegate).
+				Description("Whether to invert the match of the pattern, such that lines that do not match it are treated as matching.").
+				Default(false),
+			service.NewIntField(smlFieldMaxLines).
+				Description("The maximum number of lines of an event, once reached the event is emitted and following lines begin a new event. If `0` events can have any number of lines.").
+				Default(500),
+			service.NewDurationField(smlFieldTimeout).
+				Description("The duration after which an event is emitted when no further lines have been read, which prevents the last event of a stream that has not ended from being held indefinitely. If `0s` events are only emitted once the next event begins.").
+				Default("5s"),
+			service.NewIntField(smlFieldMaxBufferSize).
+				Description("Set the maximum buffer size for storing line data, this limits the maximum size that a line can be without causing an error.").
+				Default(bufio.MaxScanTokenSize),
+		)
+}
+
+func init() {
+	err := service.RegisterBatchScannerCreator("multiline", multilineScannerSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.BatchScannerCreator, error) {
+			return multilineScannerFromParsed(conf)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+func multilineScannerFromParsed(conf *service.ParsedConfig) (m *multilineScanner, err error) {
+	m = &multilineScanner{}
+
+	var patternStr string
+	switch {
+	case conf.Contains(smlFieldStartPattern) && conf.Contains(smlFieldContinuationPattern):
+		return nil, errors.New("cannot specify both start_pattern and continuation_pattern")
+	case conf.Contains(smlFieldStartPattern):
+		m.isStart = true
+		patternStr, err = conf.FieldString(smlFieldStartPattern)
+	case conf.Contains(smlFieldContinuationPattern):
+		patternStr, err = conf.FieldString(smlFieldContinuationPattern)
+	default:
+		return nil, errors.New("either start_pattern or continuation_pattern must be specified")
+	}
+	if err != nil {
+		return
+	}
+	if m.pattern, err = regexp.Compile(patternStr); err != nil {
+		return
+	}
+	if m.negate, err = conf.FieldBool(smlFieldNegate); err != nil {
+		return
+	}
+	if m.maxLines, err = conf.FieldInt(smlFieldMaxLines); err != nil {
+		return
+	}
+	if m.timeout, err = conf.FieldDuration(smlFieldTimeout); err != nil {
+		return
+	}
+	if m.maxScanTokenSize, err = conf.FieldInt(smlFieldMaxBufferSize); err != nil {
+		return
+	}
+	return
+}
+
+type multilineScanner struct {
+	pattern          *regexp.Regexp
+	isStart          bool
+	negate           bool
+	maxLines         int
+	timeout          time.Duration
+	maxScanTokenSize int
+}
+
+func (m *multilineScanner) Create(rdr io.ReadCloser, aFn service.AckFunc, details *service.ScannerSourceDetails) (service.BatchScanner, error) {
+	scanner := bufio.NewScanner(rdr)
+	if m.maxScanTokenSize != bufio.MaxScanTokenSize {
+		scanner.Buffer([]byte{}, m.maxScanTokenSize)
+	}
+
+	s := &multilineReaderStream{
+		conf:      m,
+		r:         rdr,
+		lines:     make(chan multilineRead),
+		closeChan: make(chan struct{}),
+	}
+	go s.readLines(scanner)
+	return service.AutoAggregateBatchScannerAcks(s, aFn), nil
+}
+
+func (m *multilineScanner) Close(context.Context) error {
+	return nil
+}
+
+// beginsEvent returns whether a line begins a new event rather than continuing
+// the current one.
+func (m *multilineScanner) beginsEvent(line []byte) bool {
+	matched := m.pattern.Match(line) != m.negate
+	if m.isStart {
+		return matched
+	}
+	return !matched
+}
+
+type multilineRead struct {
+	line []byte
+	err  error
+}
+
+// multilineReaderStream reads lines in the background so that pending events
+// can be emitted after a timeout whilst waiting for the next line.
+type multilineReaderStream struct {
+	conf *multilineScanner
+	r    io.ReadCloser
+
+	lines     chan multilineRead
+	closeChan chan struct{}
+	closeOnce sync.Once
+
+	pending [][]byte
+	err     error
+}
+
+func (s *multilineReaderStream) readLines(scanner *bufio.Scanner) {
+	for scanner.Scan() {
+		lineCopy := make([]byte, len(scanner.Bytes()))
+		copy(lineCopy, scanner.Bytes())
+		select {
+		case s.lines <- multilineRead{line: lineCopy}:
+		case <-s.closeChan:
+			return
+		}
+	}
+	err := scanner.Err()
+	if err == nil {
+		err = io.EOF
+	}
+	select {
+	case s.lines <- multilineRead{err: err}:
+	case <-s.closeChan:
+	}
+}
+
+func (s *multilineReaderStream) flush() service.MessageBatch {
+	msg := service.NewMessage(bytes.Join(s.pending, []byte("\n")))
+	s.pending = nil
+	return service.MessageBatch{msg}
+}
+
+// nextRead waits for the next line to be read, returning timedOut when there
+// is a pending event and the timeout elapses first.
+func (s *multilineReaderStream) nextRead(ctx context.Context) (read multilineRead, timedOut bool, err error) {
+	var timeoutChan <-chan time.Time
+	if len(s.pending) > 0 && s.conf.timeout > 0 {
+		timer := time.NewTimer(s.conf.timeout)
+		defer timer.Stop()
+		timeoutChan = timer.C
+	}
+
+	select {
+	case <-ctx.Done():
+		err = ctx.Err()
+	case <-timeoutChan:
+		timedOut = true
+	case read = <-s.lines:
+	}
+	return
+}
+
+func (s *multilineReaderStream) NextBatch(ctx context.Context) (service.MessageBatch, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	for {
+		read, timedOut, err := s.nextRead(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if timedOut {
+			return s.flush(), nil
+		}
+		if read.err != nil {
+			s.err = read.err
+			if len(s.pending) > 0 {
+				return s.flush(), nil
+			}
+			return nil, s.err
+		}
+
+		if len(s.pending) > 0 && s.conf.beginsEvent(read.line) {
+			res := s.flush()
+			s.pending = append(s.pending, read.line)
+			return res, nil
+		}
+		s.pending = append(s.pending, read.line)
+		if s.conf.maxLines > 0 && len(s.pending) >= s.conf.maxLines {
+			return s.flush(), nil
+		}
+	}
+}
+
+func (s *multilineReaderStream) Close(ctx context.Context) error {
+	s.closeOnce.Do(func() {
+		close(s.closeChan)
+	})
+	return s.r.Close()
+}
@@ -0,0 +1,145 @@
+package pure_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/internal/component/scanner/testutil"
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func testMultilineScanner(t *testing.T, confStr string) *service.OwnedScannerCreator {
+	t.Helper()
+
+	confSpec := service.NewConfigSpec().Field(service.NewScannerField("test"))
+	pConf, err := confSpec.ParseYAML(confStr, nil)
+	require.NoError(t, err)
+
+	rdr, err := pConf.FieldScanner("test")
+	require.NoError(t, err)
+	return rdr
+}
+
+func multilineReadAll(t *testing.T, rdr *service.OwnedScannerCreator, input string) (events []string) {
+	t.Helper()
+
+	strm, err := rdr.Create(io.NopCloser(bytes.NewReader([]byte(input))), func(ctx context.Context, err error) error {
+		return nil
+	}, service.NewScannerSourceDetails())
+	require.NoError(t, err)
+
+	for {
+		m, aFn, err := strm.NextBatch(context.Background())
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		require.Len(t, m, 1)
+		require.NoError(t, aFn(context.Background(), nil))
+
+		mBytes, err := m[0].AsBytes()
+		require.NoError(t, err)
+		events = append(events, string(mBytes))
+	}
+	require.NoError(t, strm.Close(context.Background()))
+	return
+}
+
+const testStackTraces = `2024-01-01 ERROR failed to process
+java.lang.IllegalStateException: nope
+    at com.example.Foo.bar(Foo.java:10)
+    at com.example.Foo.main(Foo.java:5)
+Caused by: java.io.IOException: broken
+    ... 2 more
+2024-01-01 INFO recovered
+2024-01-01 ERROR failed again
+    at com.example.Foo.bar(Foo.java:10)`
+
+func TestMultilineScannerPatterns(t *testing.T) {
+	expected := []string{
+		`2024-01-01 ERROR failed to process
+java.lang.IllegalStateException: nope
+    at com.example.Foo.bar(Foo.java:10)
+    at com.example.Foo.main(Foo.java:5)
+Caused by: java.io.IOException: broken
+    ... 2 more`,
+		`2024-01-01 INFO recovered`,
+		`2024-01-01 ERROR failed again
+    at com.example.Foo.bar(Foo.java:10)`,
+	}
+
+	for _, confStr := range []string{
+		`
+test:
+  multiline:
+    start_pattern: '^\d{4}-\d{2}-\d{2}'
+`,
+		`
+test:
+  multiline:
+    continuation_pattern: '^\d{4}-\d{2}-\d{2}'
+    negate: true
+`,
+	} {
+		assert.Equal(t, expected, multilineReadAll(t, testMultilineScanner(t, confStr), testStackTraces), confStr)
+	}
+
+	assert.Equal(t, []string{
+		"first\n  a\n  b",
+		"  c\n  d",
+		"second",
+	}, multilineReadAll(t, testMultilineScanner(t, `
+test:
+  multiline:
+    continuation_pattern: '^\s'
+    max_lines: 3
+`), "first\n  a\n  b\n  c\n  d\nsecond"))
+}
+
+func TestMultilineScannerTimeout(t *testing.T) {
+	rdr := testMultilineScanner(t, `
+test:
+  multiline:
+    start_pattern: '^\S'
+    timeout: 50ms
+`)
+
+	pr, pw := io.Pipe()
+	strm, err := rdr.Create(pr, func(ctx context.Context, err error) error {
+		return nil
+	}, service.NewScannerSourceDetails())
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = strm.Close(context.Background())
+	})
+
+	go func() {
+		_, _ = pw.Write([]byte("first\n  continued\n"))
+	}()
+
+	tCtx, done := context.WithTimeout(context.Background(), time.Second*5)
+	defer done()
+
+	m, _, err := strm.NextBatch(tCtx)
+	require.NoError(t, err)
+	require.Len(t, m, 1)
+
+	mBytes, err := m[0].AsBytes()
+	require.NoError(t, err)
+	assert.Equal(t, "first\n  continued", string(mBytes))
+}
+
+func TestMultilineScannerSuite(t *testing.T) {
+	rdr := testMultilineScanner(t, `
+test:
+  multiline:
+    start_pattern: '^\S'
+`)
+	testutil.ScannerTestSuite(t, rdr, nil, []byte("first\n  a\nsecond\nthird\n  b\n  c"), "first\n  a", "second", "third\n  b\n  c")
+}
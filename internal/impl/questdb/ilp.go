@@ -0,0 +1,155 @@
+package questdb
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ilpColumn is a single symbol or column of a row.
+type ilpColumn struct {
+	name  string
+	value any
+}
+
+// ilpRow describes a single row to be written to a QuestDB table.
+type ilpRow struct {
+	table     string
+	symbols   []ilpColumn
+	columns   []ilpColumn
+	timestamp *time.Time
+}
+
+var (
+	nameEscaper        = strings.NewReplacer(" ", `\ `, ",", `\,`, "=", `\=`, `\`, `\\`)
+	symbolValueEscaper = strings.NewReplacer(" ", `\ `, ",", `\,`, "=", `\=`, `\`, `\\`, "\n", "\\\n", "\r", "\\\r")
+	stringValueEscaper = strings.NewReplacer(`"`, `\"`, `\`, `\\`, "\n", "\\\n", "\r", "\\\r")
+)
+
+func validateName(kind, name string) error {
+	if name == "" {
+		return fmt.Errorf("%v name must not be empty", kind)
+	}
+	if strings.ContainsAny(name, "\n\r") {
+		return fmt.Errorf("%v name %q must not contain line breaks", kind, name)
+	}
+	return nil
+}
+
+// sortedColumns converts a map into a slice of columns sorted by name, which
+// keeps encoded rows deterministic.
+func sortedColumns(m map[string]any) []ilpColumn {
+	cols := make([]ilpColumn, 0, len(m))
+	for k, v := range m {
+		cols = append(cols, ilpColumn{name: k, value: v})
+	}
+	sort.Slice(cols, func(i, j int) bool {
+		return cols[i].name < cols[j].name
+	})
+	return cols
+}
+
+func appendColumnValue(b []byte, v any) ([]byte, error) {
+	switch t := v.(type) {
+	case string:
+		b = append(b, '"')
+		b = append(b, stringValueEscaper.Replace(t)...)
+		return append(b, '"'), nil
+	case []byte:
+		return appendColumnValue(b, string(t))
+	case bool:
+		if t {
+			return append(b, 't'), nil
+		}
+		return append(b, 'f'), nil
+	case int64:
+		b = strconv.AppendInt(b, t, 10)
+		return append(b, 'i'), nil
+	case int:
+		return appendColumnValue(b, int64(t))
+	case int32:
+		return appendColumnValue(b, int64(t))
+	case uint64:
+		if t > math.MaxInt64 {
+			return nil, fmt.Errorf("unsigned integer %v overflows a long column", t)
+		}
+		return appendColumnValue(b, int64(t))
+	case uint32:
+		return appendColumnValue(b, int64(t))
+	case float32:
+		return appendColumnValue(b, float64(t))
+	case float64:
+		if math.IsInf(t, 0) {
+			return nil, errors.New("infinite floats are not supported")
+		}
+		if math.IsNaN(t) {
+			return append(b, "NaN"...), nil
+		}
+		return strconv.AppendFloat(b, t, 'g', -1, 64), nil
+	case json.Number:
+		f, err := t.Float64()
+		if err != nil {
+			return nil, err
+		}
+		return appendColumnValue(b, f)
+	case time.Time:
+		b = strconv.AppendInt(b, t.UnixMicro(), 10)
+		return append(b, 't'), nil
+	}
+	return nil, fmt.Errorf("unsupported column value type %T", v)
+}
+
+// appendRow encodes a row as a single line of InfluxDB line protocol,
+// including the trailing newline.
+func appendRow(b []byte, row ilpRow) ([]byte, error) {
+	if err := validateName("table", row.table); err != nil {
+		return nil, err
+	}
+	if len(row.columns) == 0 && len(row.symbols) == 0 {
+		return nil, errors.New("row must have at least one symbol or column")
+	}
+
+	b = append(b, nameEscaper.Replace(row.table)...)
+	for _, s := range row.symbols {
+		if err := validateName("symbol", s.name); err != nil {
+			return nil, err
+		}
+		str, ok := s.value.(string)
+		if !ok {
+			return nil, fmt.Errorf("symbol %v must be a string, got %T", s.name, s.value)
+		}
+		b = append(b, ',')
+		b = append(b, nameEscaper.Replace(s.name)...)
+		b = append(b, '=')
+		b = append(b, symbolValueEscaper.Replace(str)...)
+	}
+
+	for i, c := range row.columns {
+		if err := validateName("column", c.name); err != nil {
+			return nil, err
+		}
+		if i == 0 {
+			b = append(b, ' ')
+		} else {
+			b = append(b, ',')
+		}
+		b = append(b, nameEscaper.Replace(c.name)...)
+		b = append(b, '=')
+
+		var err error
+		if b, err = appendColumnValue(b, c.value); err != nil {
+			return nil, fmt.Errorf("column %v: %w", c.name, err)
+		}
+	}
+
+	if row.timestamp != nil {
+		b = append(b, ' ')
+		b = strconv.AppendInt(b, row.timestamp.UnixNano(), 10)
+	}
+	return append(b, '\n'), nil
+}
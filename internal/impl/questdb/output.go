@@ -0,0 +1,574 @@
+package questdb
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+
+	"github.com/benthosdev/benthos/v4/internal/impl/pure"
+	"github.com/benthosdev/benthos/v4/internal/value"
+	"github.com/benthosdev/benthos/v4/public/bloblang"
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	qdbFieldProtocol            = "protocol"
+	qdbFieldAddress             = "address"
+	qdbFieldUsername            = "username"
+	qdbFieldPassword            = "password"
+	qdbFieldToken               = "token"
+	qdbFieldTable               = "table"
+	qdbFieldSymbols             = "symbols"
+	qdbFieldColumns             = "columns"
+	qdbFieldDesignatedTimestamp = "designated_timestamp"
+	qdbFieldTimeout             = "timeout"
+	qdbFieldTLS                 = "tls"
+	qdbFieldPool                = "connection_pool"
+	qdbFieldPoolMaxIdle         = "max_idle"
+	qdbFieldPoolIdleTimeout     = "idle_timeout"
+	qdbFieldBatching            = "batching"
+)
+
+func questdbOutputSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Services").
+		Version("4.28.0").
+		Summary("Writes rows to QuestDB using the InfluxDB line protocol (ILP) over HTTP or TCP.").
+		Description(`
+Each message is converted into a row of the table resolved from `+"`table`"+`, with symbol columns resolved from the `+"`symbols`"+` map and all other columns resulting from the `+"`columns`"+` mapping. Tables and columns that do not exist are created by QuestDB automatically.
+
+### Column Types
+
+Strings are written as string columns, booleans as boolean columns, integer values created by the mapping (for example with the `+"[`int64`](/docs/guides/bloblang/methods#int64)"+` method) as long columns and all other numbers as double columns. Timestamp values (for example those created with the `+"[`ts_parse`](/docs/guides/bloblang/methods#ts_parse)"+` method) are written as timestamp columns. Columns with null values are omitted.
+
+### Designated Timestamp
+
+The designated timestamp of each row can be selected with the `+"`designated_timestamp`"+` mapping, which may result in a timestamp, a string in RFC 3339 format or a unix timestamp. When omitted, or when the mapping results in `+"`null`"+`, the timestamp is assigned by the server upon receipt.
+
+### Protocols
+
+When the `+"`protocol`"+` is `+"`http`"+` each batch is written with a single request to the `+"`/write`"+` endpoint, errors are reported by the server and requests that fail with a 5XX status code or a connection error are retried according to the `+"`backoff`"+` fields. Authentication can be configured with either `+"`username`"+` and `+"`password`"+` or a `+"`token`"+`.
+
+When the `+"`protocol`"+` is `+"`tcp`"+` rows are streamed over a connection without acknowledgement, which offers the highest throughput at the cost of the server silently dropping rows it rejects. Authentication is not supported with TCP.
+
+### Connection Pooling
+
+Connections are pooled and reused by concurrent writes, up to `+"`connection_pool.max_idle`"+` idle connections are kept open between batches and closed once they have been idle for longer than `+"`connection_pool.idle_timeout`"+`. For high rate writes it's recommended to increase `+"`max_in_flight`"+` alongside `+"`connection_pool.max_idle`"+`.`+service.OutputPerformanceDocs(true, true)).
+		Fields(
+			service.NewStringEnumField(qdbFieldProtocol, "http", "tcp").
+				Description("The protocol to write rows with.").
+				Default("http"),
+			service.NewStringField(qdbFieldAddress).
+				Description("The address of the QuestDB server, the default ports are 9000 for HTTP and 9009 for TCP.").
+				Example("localhost:9000").
+				Example("localhost:9009"),
+			service.NewStringField(qdbFieldUsername).
+				Description("A username for basic authentication with HTTP.").
+				Advanced().
+				Default(""),
+			service.NewStringField(qdbFieldPassword).
+				Description("A password for basic authentication with HTTP.").
+				Secret().
+				Advanced().
+				Default(""),
+			service.NewStringField(qdbFieldToken).
+				Description("A bearer token for authentication with HTTP.").
+				Secret().
+				Advanced().
+				Default(""),
+			service.NewInterpolatedStringField(qdbFieldTable).
+				Description("The table to write each row to.").
+				Example("trades").
+				Example(`${! @kafka_topic }`),
+			service.NewInterpolatedStringMapField(qdbFieldSymbols).
+				Description("A map of symbol columns to add to each row, symbols that resolve to an empty string are omitted.").
+				Example(map[string]any{
+					"symbol": `${! this.symbol }`,
+					"side":   `${! this.side }`,
+				}).
+				Default(map[string]any{}),
+			service.NewBloblangField(qdbFieldColumns).
+				Description("A [Bloblang mapping](/docs/guides/bloblang/about) that results in an object of the columns of each row.").
+				Example(`root.price = this.price
+root.amount = this.amount
+root.trade_id = this.id.int64()`).
+				Default("root = this"),
+			service.NewBloblangField(qdbFieldDesignatedTimestamp).
+				Description("An optional [Bloblang mapping](/docs/guides/bloblang/about) that results in the designated timestamp of each row.").
+				Example(`root = this.ts`).
+				Example(`root = this.time.ts_parse("2006-01-02 15:04:05.000")`).
+				Optional(),
+			service.NewDurationField(qdbFieldTimeout).
+				Description("The maximum period to wait for a batch to be written.").
+				Advanced().
+				Default("10s"),
+			service.NewTLSToggledField(qdbFieldTLS),
+			service.NewObjectField(qdbFieldPool,
+				service.NewIntField(qdbFieldPoolMaxIdle).
+					Description("The maximum number of idle connections to keep open.").
+					Default(4),
+				service.NewDurationField(qdbFieldPoolIdleTimeout).
+					Description("The maximum period a connection may be idle before it is closed.").
+					Default("1m"),
+			).
+				Description("Controls the reuse of connections between writes.").
+				Advanced(),
+			service.NewOutputMaxInFlightField(),
+			service.NewBatchPolicyField(qdbFieldBatching),
+		).
+		Fields(pure.CommonRetryBackOffFields(0, "500ms", "10s", "1m")...).
+		Example("Market Data", "Write trades to a table with the instrument and side as symbols, and the exchange time as the designated timestamp.", `
+output:
+  questdb:
+    address: localhost:9000
+    table: trades
+    symbols:
+      symbol: ${! this.symbol }
+      side: ${! this.side }
+    columns: |
+      root.price = this.price
+      root.amount = this.amount
+    designated_timestamp: root = this.exchange_ts.ts_parse("2006-01-02T15:04:05.999999Z07:00")
+    max_in_flight: 16
+    connection_pool:
+      max_idle: 16
+    batching:
+      count: 10000
+      period: 100ms
+`)
+}
+
+func init() {
+	err := service.RegisterBatchOutput("questdb", questdbOutputSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (out service.BatchOutput, batchPolicy service.BatchPolicy, maxInFlight int, err error) {
+			if maxInFlight, err = conf.FieldMaxInFlight(); err != nil {
+				return
+			}
+			if batchPolicy, err = conf.FieldBatchPolicy(qdbFieldBatching); err != nil {
+				return
+			}
+			out, err = newQuestDBWriterFromParsed(conf, mgr)
+			return
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type questdbWriter struct {
+	log *service.Logger
+
+	useTCP    bool
+	address   string
+	username  string
+	password  string
+	token     string
+	timeout   time.Duration
+	tlsConf   *tls.Config
+	table     *service.InterpolatedString
+	symbols   map[string]*service.InterpolatedString
+	columns   *bloblang.Executor
+	timestamp *bloblang.Executor
+
+	backoffCtor func() backoff.BackOff
+
+	httpClient *http.Client
+	writeURL   string
+	pool       *connPool
+}
+
+func newQuestDBWriterFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*questdbWriter, error) {
+	q := &questdbWriter{
+		log: mgr.Logger(),
+	}
+
+	protocol, err := conf.FieldString(qdbFieldProtocol)
+	if err != nil {
+		return nil, err
+	}
+	q.useTCP = protocol == "tcp"
+
+	if q.address, err = conf.FieldString(qdbFieldAddress); err != nil {
+		return nil, err
+	}
+	if q.username, err = conf.FieldString(qdbFieldUsername); err != nil {
+		return nil, err
+	}
+	if q.password, err = conf.FieldString(qdbFieldPassword); err != nil {
+		return nil, err
+	}
+	if q.token, err = conf.FieldString(qdbFieldToken); err != nil {
+		return nil, err
+	}
+	if q.useTCP && (q.username != "" || q.password != "" || q.token != "") {
+		return nil, errors.New("authentication is not supported with the tcp protocol")
+	}
+	if q.token != "" && (q.username != "" || q.password != "") {
+		return nil, errors.New("a token cannot be combined with a username and password")
+	}
+
+	if q.table, err = conf.FieldInterpolatedString(qdbFieldTable); err != nil {
+		return nil, err
+	}
+	if q.symbols, err = conf.FieldInterpolatedStringMap(qdbFieldSymbols); err != nil {
+		return nil, err
+	}
+	if q.columns, err = conf.FieldBloblang(qdbFieldColumns); err != nil {
+		return nil, err
+	}
+	if conf.Contains(qdbFieldDesignatedTimestamp) {
+		if q.timestamp, err = conf.FieldBloblang(qdbFieldDesignatedTimestamp); err != nil {
+			return nil, err
+		}
+	}
+	if q.timeout, err = conf.FieldDuration(qdbFieldTimeout); err != nil {
+		return nil, err
+	}
+
+	var tlsEnabled bool
+	if q.tlsConf, tlsEnabled, err = conf.FieldTLSToggled(qdbFieldTLS); err != nil {
+		return nil, err
+	}
+	if !tlsEnabled {
+		q.tlsConf = nil
+	}
+
+	poolConf := conf.Namespace(qdbFieldPool)
+	maxIdle, err := poolConf.FieldInt(qdbFieldPoolMaxIdle)
+	if err != nil {
+		return nil, err
+	}
+	idleTimeout, err := poolConf.FieldDuration(qdbFieldPoolIdleTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	if q.backoffCtor, err = pure.CommonRetryBackOffCtorFromParsed(conf); err != nil {
+		return nil, err
+	}
+
+	if q.useTCP {
+		q.pool = newConnPool(maxIdle, idleTimeout, q.dial)
+	} else {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = q.tlsConf
+		transport.MaxIdleConnsPerHost = maxIdle
+		transport.IdleConnTimeout = idleTimeout
+		q.httpClient = &http.Client{Transport: transport, Timeout: q.timeout}
+
+		scheme := "http"
+		if q.tlsConf != nil {
+			scheme = "https"
+		}
+		q.writeURL = scheme + "://" + q.address + "/write"
+	}
+	return q, nil
+}
+
+func (q *questdbWriter) dial(ctx context.Context) (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: q.timeout}
+	if q.tlsConf != nil {
+		return (&tls.Dialer{NetDialer: dialer, Config: q.tlsConf}).DialContext(ctx, "tcp", q.address)
+	}
+	return dialer.DialContext(ctx, "tcp", q.address)
+}
+
+func (q *questdbWriter) Connect(ctx context.Context) error {
+	if q.pool == nil {
+		return nil
+	}
+	conn, err := q.pool.get(ctx)
+	if err != nil {
+		return err
+	}
+	q.pool.put(conn)
+	return nil
+}
+
+func (q *questdbWriter) rowFrom(batch service.MessageBatch, i int) (row ilpRow, err error) {
+	if row.table, err = batch.TryInterpolatedString(i, q.table); err != nil {
+		return row, fmt.Errorf("table interpolation error: %w", err)
+	}
+
+	symbols := make(map[string]any, len(q.symbols))
+	for k, istr := range q.symbols {
+		v, err := batch.TryInterpolatedString(i, istr)
+		if err != nil {
+			return row, fmt.Errorf("symbol %v interpolation error: %w", k, err)
+		}
+		if v != "" {
+			symbols[k] = v
+		}
+	}
+	row.symbols = sortedColumns(symbols)
+
+	colsMsg, err := batch.BloblangQuery(i, q.columns)
+	if err != nil {
+		return row, fmt.Errorf("columns mapping failed: %w", err)
+	}
+	var colsV any
+	if colsMsg != nil {
+		if colsV, err = colsMsg.AsStructured(); err != nil {
+			return row, fmt.Errorf("columns mapping failed: %w", err)
+		}
+	}
+	colsObj, ok := colsV.(map[string]any)
+	if !ok {
+		return row, fmt.Errorf("expected columns mapping to result in an object, got %T", colsV)
+	}
+	columns := make(map[string]any, len(colsObj))
+	for k, v := range colsObj {
+		if v != nil {
+			columns[k] = v
+		}
+	}
+	row.columns = sortedColumns(columns)
+
+	if q.timestamp != nil {
+		tsMsg, err := batch.BloblangQuery(i, q.timestamp)
+		if err != nil {
+			return row, fmt.Errorf("designated timestamp mapping failed: %w", err)
+		}
+		var tsV any
+		if tsMsg != nil {
+			if tsV, err = tsMsg.AsStructured(); err != nil {
+				if tsBytes, _ := tsMsg.AsBytes(); len(tsBytes) > 0 {
+					tsV = string(tsBytes)
+					err = nil
+				}
+			}
+			if err != nil {
+				return row, fmt.Errorf("designated timestamp mapping failed: %w", err)
+			}
+		}
+		if tsV != nil {
+			t, err := value.IGetTimestamp(tsV)
+			if err != nil {
+				return row, fmt.Errorf("designated timestamp mapping failed: %w", err)
+			}
+			row.timestamp = &t
+		}
+	}
+	return row, nil
+}
+
+func (q *questdbWriter) WriteBatch(ctx context.Context, batch service.MessageBatch) error {
+	var batchErr *service.BatchError
+	var buf []byte
+	for i := range batch {
+		row, err := q.rowFrom(batch, i)
+		if err == nil {
+			var line []byte
+			if line, err = appendRow(nil, row); err == nil {
+				buf = append(buf, line...)
+				continue
+			}
+		}
+		if batchErr == nil {
+			batchErr = service.NewBatchError(batch, err)
+		}
+		batchErr.Failed(i, err)
+	}
+	if len(buf) == 0 {
+		if batchErr != nil {
+			return batchErr
+		}
+		return nil
+	}
+
+	var err error
+	if q.useTCP {
+		err = q.writeTCP(ctx, buf)
+	} else {
+		err = q.writeHTTP(ctx, buf)
+	}
+	if err != nil {
+		return err
+	}
+	if batchErr != nil {
+		return batchErr
+	}
+	return nil
+}
+
+func (q *questdbWriter) writeTCP(ctx context.Context, body []byte) error {
+	conn, err := q.pool.get(ctx)
+	if err != nil {
+		return err
+	}
+	if err := conn.SetWriteDeadline(time.Now().Add(q.timeout)); err != nil {
+		q.pool.discard(conn)
+		return err
+	}
+	if _, err := conn.Write(body); err != nil {
+		q.pool.discard(conn)
+		return err
+	}
+	q.pool.put(conn)
+	return nil
+}
+
+type httpWriteError struct {
+	status  int
+	message string
+}
+
+func (e *httpWriteError) Error() string {
+	return fmt.Sprintf("write request failed with status %v: %v", e.status, e.message)
+}
+
+func (q *questdbWriter) writeHTTP(ctx context.Context, body []byte) error {
+	boff := q.backoffCtor()
+	for {
+		err := q.writeHTTPOnce(ctx, body)
+		if err == nil {
+			return nil
+		}
+
+		var hErr *httpWriteError
+		if errors.As(err, &hErr) && hErr.status < 500 {
+			return err
+		}
+
+		wait := boff.NextBackOff()
+		if wait == backoff.Stop {
+			return err
+		}
+		q.log.Warnf("Retrying write request in %v: %v", wait, err)
+
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (q *questdbWriter) writeHTTPOnce(ctx context.Context, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, q.writeURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	if q.token != "" {
+		req.Header.Set("Authorization", "Bearer "+q.token)
+	} else if q.username != "" || q.password != "" {
+		req.SetBasicAuth(q.username, q.password)
+	}
+
+	res, err := q.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode >= 200 && res.StatusCode <= 299 {
+		_, _ = io.Copy(io.Discard, res.Body)
+		return nil
+	}
+
+	resBody, _ := io.ReadAll(io.LimitReader(res.Body, 4096))
+	hErr := &httpWriteError{status: res.StatusCode, message: string(bytes.TrimSpace(resBody))}
+
+	var errBody struct {
+		Message string `json:"message"`
+		Line    int    `json:"line"`
+	}
+	if json.Unmarshal(resBody, &errBody) == nil && errBody.Message != "" {
+		hErr.message = errBody.Message
+		if errBody.Line > 0 {
+			hErr.message = fmt.Sprintf("%v (line %v)", errBody.Message, errBody.Line)
+		}
+	}
+	return hErr
+}
+
+func (q *questdbWriter) Close(ctx context.Context) error {
+	if q.pool != nil {
+		q.pool.close()
+	}
+	if q.httpClient != nil {
+		q.httpClient.CloseIdleConnections()
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+type idleConn struct {
+	conn  net.Conn
+	since time.Time
+}
+
+// connPool keeps a bounded number of idle connections for reuse by concurrent
+// writes, dialing new connections when none are idle.
+type connPool struct {
+	maxIdle     int
+	idleTimeout time.Duration
+	dial        func(context.Context) (net.Conn, error)
+
+	mut    sync.Mutex
+	idle   []idleConn
+	closed bool
+}
+
+func newConnPool(maxIdle int, idleTimeout time.Duration, dial func(context.Context) (net.Conn, error)) *connPool {
+	return &connPool{
+		maxIdle:     maxIdle,
+		idleTimeout: idleTimeout,
+		dial:        dial,
+	}
+}
+
+func (p *connPool) get(ctx context.Context) (net.Conn, error) {
+	p.mut.Lock()
+	for len(p.idle) > 0 {
+		c := p.idle[len(p.idle)-1]
+		p.idle = p.idle[:len(p.idle)-1]
+		if p.idleTimeout > 0 && time.Since(c.since) > p.idleTimeout {
+			_ = c.conn.Close()
+			continue
+		}
+		p.mut.Unlock()
+		return c.conn, nil
+	}
+	p.mut.Unlock()
+	return p.dial(ctx)
+}
+
+func (p *connPool) put(conn net.Conn) {
+	p.mut.Lock()
+	defer p.mut.Unlock()
+	if p.closed || len(p.idle) >= p.maxIdle {
+		_ = conn.Close()
+		return
+	}
+	p.idle = append(p.idle, idleConn{conn: conn, since: time.Now()})
+}
+
+func (p *connPool) discard(conn net.Conn) {
+	_ = conn.Close()
+}
+
+func (p *connPool) close() {
+	p.mut.Lock()
+	defer p.mut.Unlock()
+	p.closed = true
+	for _, c := range p.idle {
+		_ = c.conn.Close()
+	}
+	p.idle = nil
+}
@@ -0,0 +1,296 @@
+package questdb
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func TestAppendRow(t *testing.T) {
+	ts := time.Unix(1, 500)
+	tCol := time.UnixMicro(1234)
+
+	for _, test := range []struct {
+		name   string
+		row    ilpRow
+		output string
+		errStr string
+	}{
+		{
+			name: "all types",
+			row: ilpRow{
+				table:   "trades",
+				symbols: []ilpColumn{{"side", "buy"}, {"symbol", "BTC-USD"}},
+				columns: []ilpColumn{
+					{"amount", 0.25},
+					{"at", tCol},
+					{"id", int64(10)},
+					{"note", `say "hi"`},
+					{"ok", true},
+				},
+				timestamp: &ts,
+			},
+			output: `trades,side=buy,symbol=BTC-USD amount=0.25,at=1234t,id=10i,note="say \"hi\"",ok=t 1000000500` + "\n",
+		},
+		{
+			name: "escaping",
+			row: ilpRow{
+				table:   "my table",
+				symbols: []ilpColumn{{"a,b", "c d=e"}},
+				columns: []ilpColumn{{"x y", "line\nbreak"}},
+			},
+			output: `my\ table,a\,b=c\ d\=e x\ y="line\` + "\nbreak\"\n",
+		},
+		{
+			name: "symbols only",
+			row: ilpRow{
+				table:   "t",
+				symbols: []ilpColumn{{"a", "b"}},
+			},
+			output: "t,a=b\n",
+		},
+		{
+			name:   "no columns",
+			row:    ilpRow{table: "t"},
+			errStr: "at least one",
+		},
+		{
+			name: "empty table",
+			row: ilpRow{
+				columns: []ilpColumn{{"a", 1.0}},
+			},
+			errStr: "table name must not be empty",
+		},
+		{
+			name: "unsigned overflow",
+			row: ilpRow{
+				table:   "t",
+				columns: []ilpColumn{{"a", uint64(math.MaxUint64)}},
+			},
+			errStr: "overflows",
+		},
+		{
+			name: "nested column",
+			row: ilpRow{
+				table:   "t",
+				columns: []ilpColumn{{"a", map[string]any{}}},
+			},
+			errStr: "unsupported column value type",
+		},
+	} {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			b, err := appendRow(nil, test.row)
+			if test.errStr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), test.errStr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.output, string(b))
+		})
+	}
+}
+
+func testQuestDBWriter(t *testing.T, confStr string) *questdbWriter {
+	t.Helper()
+
+	pConf, err := questdbOutputSpec().ParseYAML(confStr, nil)
+	require.NoError(t, err)
+
+	q, err := newQuestDBWriterFromParsed(pConf, service.MockResources())
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = q.Close(context.Background())
+	})
+	return q
+}
+
+func TestQuestDBHTTP(t *testing.T) {
+	var mut sync.Mutex
+	var bodies []string
+	failures := 1
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mut.Lock()
+		defer mut.Unlock()
+
+		if failures > 0 {
+			failures--
+			http.Error(w, "busy", http.StatusServiceUnavailable)
+			return
+		}
+		if r.URL.Path != "/write" || r.Header.Get("Authorization") != "Bearer footoken" {
+			http.Error(w, "bad request", http.StatusUnauthorized)
+			return
+		}
+
+		b, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		bodies = append(bodies, string(b))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(ts.Close)
+
+	q := testQuestDBWriter(t, `
+address: `+strings.TrimPrefix(ts.URL, "http://")+`
+token: footoken
+table: ${! @table }
+symbols:
+  symbol: ${! this.symbol }
+  side: ${! this.side.or("") }
+columns: |
+  root.price = this.price
+  root.amount = this.amount.int64()
+designated_timestamp: root = this.ts
+backoff:
+  initial_interval: 1ms
+`)
+	require.NoError(t, q.Connect(context.Background()))
+
+	var batch service.MessageBatch
+	for _, s := range []string{
+		`{"symbol":"ETH-USD","side":"sell","price":2615.54,"amount":3,"ts":1}`,
+		`{"symbol":"BTC-USD","price":39269.98,"amount":1,"ts":2}`,
+		`{"symbol":"BTC-USD","price":"nope","amount":"nope","ts":3}`,
+	} {
+		m := service.NewMessage([]byte(s))
+		m.MetaSetMut("table", "trades")
+		batch = append(batch, m)
+	}
+
+	index := batch.Index()
+	err := q.WriteBatch(context.Background(), batch)
+	require.Error(t, err)
+
+	var bErr *service.BatchError
+	require.True(t, errors.As(err, &bErr))
+
+	var failed []int
+	bErr.WalkMessagesIndexedBy(index, func(i int, _ *service.Message, err error) bool {
+		if err != nil {
+			failed = append(failed, i)
+		}
+		return true
+	})
+	sort.Ints(failed)
+	assert.Equal(t, []int{2}, failed)
+
+	mut.Lock()
+	defer mut.Unlock()
+
+	assert.Equal(t, []string{
+		"trades,side=sell,symbol=ETH-USD amount=3i,price=2615.54 1000000000\n" +
+			"trades,symbol=BTC-USD amount=1i,price=39269.98 2000000000\n",
+	}, bodies)
+}
+
+func TestQuestDBHTTPRejected(t *testing.T) {
+	var calls int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"code":"invalid","message":"cast error for line protocol float","line":1,"errorId":"abc"}`))
+	}))
+	t.Cleanup(ts.Close)
+
+	q := testQuestDBWriter(t, `
+address: `+strings.TrimPrefix(ts.URL, "http://")+`
+table: trades
+`)
+
+	err := q.WriteBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte(`{"price":1}`)),
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "cast error for line protocol float (line 1)")
+	assert.Equal(t, 1, calls)
+}
+
+func TestQuestDBTCPPooling(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = ln.Close()
+	})
+
+	var mut sync.Mutex
+	var conns int
+	lines := make(chan string, 10)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			mut.Lock()
+			conns++
+			mut.Unlock()
+			go func() {
+				scanner := bufio.NewScanner(conn)
+				for scanner.Scan() {
+					lines <- scanner.Text()
+				}
+			}()
+		}
+	}()
+
+	q := testQuestDBWriter(t, `
+protocol: tcp
+address: `+ln.Addr().String()+`
+table: quotes
+columns: root.bid = this.bid
+`)
+	require.NoError(t, q.Connect(context.Background()))
+
+	for _, s := range []string{`{"bid":1.5}`, `{"bid":2.5}`} {
+		require.NoError(t, q.WriteBatch(context.Background(), service.MessageBatch{
+			service.NewMessage([]byte(s)),
+		}))
+	}
+
+	for _, exp := range []string{"quotes bid=1.5", "quotes bid=2.5"} {
+		select {
+		case l := <-lines:
+			assert.Equal(t, exp, l)
+		case <-time.After(time.Second * 5):
+			t.Fatal("timed out waiting for line")
+		}
+	}
+
+	mut.Lock()
+	assert.Equal(t, 1, conns)
+	mut.Unlock()
+}
+
+func TestQuestDBTCPAuthRejected(t *testing.T) {
+	pConf, err := questdbOutputSpec().ParseYAML(`
+protocol: tcp
+address: localhost:9009
+table: foo
+token: bar
+`, nil)
+	require.NoError(t, err)
+
+	_, err = newQuestDBWriterFromParsed(pConf, service.MockResources())
+	require.Error(t, err)
+}
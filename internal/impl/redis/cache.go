@@ -20,7 +20,11 @@ func redisCacheConfig() *service.ConfigSpec {
 
 	spec := service.NewConfigSpec().
 		Stable().
-		Summary(`Use a Redis instance as a cache. The expiration can be set to zero or an empty string in order to set no expiration.`)
+		Summary(`Use a Redis instance as a cache. The expiration can be set to zero or an empty string in order to set no expiration.`).
+		Description(`
+Redis Cluster is supported by setting ` + "`kind`" + ` to ` + "`cluster`" + `, and Redis Sentinel by setting ` + "`kind`" + ` to ` + "`failover`" + `, and in both cases reads can be served by replicas with the field ` + "`read_preference`" + `.
+
+Batches of keys are read with ` + "`MGET`" + `, and when ` + "`kind`" + ` is ` + "`cluster`" + ` with an ` + "`MGET`" + ` for each hash slot of the keys. A [hash tag](https://redis.io/docs/reference/cluster-spec/#hash-tags) can be placed within the ` + "`prefix`" + `, such as ` + "`{benthos}:`" + `, in order to read all keys of a batch with a single ` + "`MGET`" + `, at the cost of all keys being held by the same shard.`)
 
 	for _, f := range clientFields() {
 		spec = spec.Field(f)
@@ -35,7 +39,21 @@ func redisCacheConfig() *service.ConfigSpec {
 			Optional().
 			Advanced()).
 		Field(service.NewBackOffField("retries", false, retriesDefaults).
-			Advanced())
+			Advanced()).
+		Field(service.NewObjectField("client_tracking",
+			service.NewBoolField("enabled").
+				Description("Whether to enable client side caching.").
+				Default(false),
+			service.NewIntField("max_items").
+				Description("The maximum number of items to hold in the local cache, where an arbitrary item is evicted in order to add an item to a full cache.").
+				Default(1000),
+			service.NewDurationField("ttl").
+				Description("The maximum period of time to hold an item in the local cache.").
+				Default("1m"),
+		).
+			Description("Client side caching holds items read from the server in a local cache, using [server assisted client tracking](https://redis.io/docs/manual/client-side-caching/) in order to remove items that are modified on the server. Invalidations are received with a dedicated connection, and so this is supported by both RESP2 and RESP3 but only with the `simple` kind and without a shared pool.").
+			Advanced().
+			Version("4.28.0"))
 
 	return spec
 }
@@ -44,19 +62,47 @@ func init() {
 	err := service.RegisterCache(
 		"redis", redisCacheConfig(),
 		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Cache, error) {
-			return newRedisCacheFromConfig(conf)
+			return newRedisCacheFromConfig(conf, mgr)
 		})
 	if err != nil {
 		panic(err)
 	}
 }
 
-func newRedisCacheFromConfig(conf *service.ParsedConfig) (*redisCache, error) {
-	client, err := getClient(conf)
+func newRedisCacheFromConfig(conf *service.ParsedConfig, mgr *service.Resources) (*redisCache, error) {
+	trackingEnabled, err := conf.FieldBool("client_tracking", "enabled")
 	if err != nil {
 		return nil, err
 	}
 
+	var client redis.UniversalClient
+	var tracking *clientTracking
+	if trackingEnabled {
+		cConf, err := clientConfigFromParsed(conf)
+		if err != nil {
+			return nil, err
+		}
+		if cConf.kind != "simple" || cConf.shared {
+			return nil, errors.New("client tracking is only supported with the simple kind and without a shared pool")
+		}
+
+		maxItems, err := conf.FieldInt("client_tracking", "max_items")
+		if err != nil {
+			return nil, err
+		}
+		if maxItems <= 0 {
+			return nil, errors.New("client tracking max_items must be larger than zero")
+		}
+
+		trackingTTL, err := conf.FieldDuration("client_tracking", "ttl")
+		if err != nil {
+			return nil, err
+		}
+		tracking = newClientTracking(cConf, mgr, trackingTTL, maxItems)
+	} else if client, err = getClient(conf, mgr); err != nil {
+		return nil, err
+	}
+
 	var prefix string
 	if conf.Contains("prefix") {
 		if prefix, err = conf.FieldString("prefix"); err != nil {
@@ -77,19 +123,40 @@ func newRedisCacheFromConfig(conf *service.ParsedConfig) (*redisCache, error) {
 	if err != nil {
 		return nil, err
 	}
-	return newRedisCache(ttl, prefix, client, backOff)
+
+	kind, err := conf.FieldString("kind")
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := newRedisCache(ttl, prefix, client, backOff)
+	if err != nil {
+		return nil, err
+	}
+	r.tracking = tracking
+	r.isCluster = kind == "cluster"
+	return r, nil
 }
 
 //------------------------------------------------------------------------------
 
 type redisCache struct {
 	client     redis.UniversalClient
+	tracking   *clientTracking
 	defaultTTL time.Duration
 	prefix     string
+	isCluster  bool
 
 	boffPool sync.Pool
 }
 
+func (r *redisCache) activeClient() redis.UniversalClient {
+	if r.tracking != nil {
+		return r.tracking.Client()
+	}
+	return r.client
+}
+
 func newRedisCache(
 	defaultTTL time.Duration,
 	prefix string,
@@ -121,9 +188,20 @@ func (r *redisCache) Get(ctx context.Context, key string) ([]byte, error) {
 		key = r.prefix + key
 	}
 
+	var epoch uint64
+	if r.tracking != nil {
+		if v, exists := r.tracking.Get(key); exists {
+			return v, nil
+		}
+		epoch = r.tracking.Epoch()
+	}
+
 	for {
-		res, err := r.client.Get(ctx, key).Result()
+		res, err := r.activeClient().Get(ctx, key).Result()
 		if err == nil {
+			if r.tracking != nil {
+				r.tracking.Store(key, []byte(res), epoch)
+			}
 			return []byte(res), nil
 		}
 
@@ -143,6 +221,120 @@ func (r *redisCache) Get(ctx context.Context, key string) ([]byte, error) {
 	}
 }
 
+func (r *redisCache) GetMulti(ctx context.Context, keys ...string) (map[string][]byte, error) {
+	boff := r.boffPool.Get().(backoff.BackOff)
+	defer func() {
+		boff.Reset()
+		r.boffPool.Put(boff)
+	}()
+
+	values := make(map[string][]byte, len(keys))
+
+	fetchKeys := make([]string, 0, len(keys))
+	for _, key := range keys {
+		if r.tracking != nil {
+			if v, exists := r.tracking.Get(r.prefix + key); exists {
+				values[key] = v
+				continue
+			}
+		}
+		fetchKeys = append(fetchKeys, key)
+	}
+	if len(fetchKeys) == 0 {
+		return values, nil
+	}
+
+	var epoch uint64
+	if r.tracking != nil {
+		epoch = r.tracking.Epoch()
+	}
+
+	prefixedKeys := make([]string, len(fetchKeys))
+	for i, key := range fetchKeys {
+		prefixedKeys[i] = r.prefix + key
+	}
+
+	for {
+		res, err := r.mget(ctx, prefixedKeys)
+		if err == nil {
+			for i, v := range res {
+				if v == nil {
+					continue
+				}
+				values[fetchKeys[i]] = v
+				if r.tracking != nil {
+					r.tracking.Store(prefixedKeys[i], v, epoch)
+				}
+			}
+			return values, nil
+		}
+
+		wait := boff.NextBackOff()
+		if wait == backoff.Stop {
+			return nil, err
+		}
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return nil, err
+		}
+	}
+}
+
+// mget returns the values of keys, where the values of keys that do not exist
+// are nil. The keys of a single MGET must belong to the same hash slot of a
+// cluster, and so for clusters keys are grouped by their hash slot and an MGET
+// is pipelined for each group. Keys can be placed within the same slot with a
+// hash tag.
+func (r *redisCache) mget(ctx context.Context, keys []string) ([][]byte, error) {
+	values := make([][]byte, len(keys))
+
+	if !r.isCluster {
+		res, err := r.activeClient().MGet(ctx, keys...).Result()
+		if err != nil {
+			return nil, err
+		}
+		for i, v := range res {
+			if str, ok := v.(string); ok {
+				values[i] = []byte(str)
+			}
+		}
+		return values, nil
+	}
+
+	var slots []uint16
+	slotIndexes := map[uint16][]int{}
+	for i, key := range keys {
+		slot := hashSlot(key)
+		if _, exists := slotIndexes[slot]; !exists {
+			slots = append(slots, slot)
+		}
+		slotIndexes[slot] = append(slotIndexes[slot], i)
+	}
+
+	cmds := make([]*redis.SliceCmd, len(slots))
+	if _, err := r.activeClient().Pipelined(ctx, func(p redis.Pipeliner) error {
+		for i, slot := range slots {
+			slotKeys := make([]string, 0, len(slotIndexes[slot]))
+			for _, index := range slotIndexes[slot] {
+				slotKeys = append(slotKeys, keys[index])
+			}
+			cmds[i] = p.MGet(ctx, slotKeys...)
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	for i, slot := range slots {
+		for j, v := range cmds[i].Val() {
+			if str, ok := v.(string); ok {
+				values[slotIndexes[slot][j]] = []byte(str)
+			}
+		}
+	}
+	return values, nil
+}
+
 func (r *redisCache) Set(ctx context.Context, key string, value []byte, ttl *time.Duration) error {
 	boff := r.boffPool.Get().(backoff.BackOff)
 	defer func() {
@@ -162,8 +354,51 @@ func (r *redisCache) Set(ctx context.Context, key string, value []byte, ttl *tim
 	}
 
 	for {
-		err := r.client.Set(ctx, key, value, t).Err()
+		err := r.activeClient().Set(ctx, key, value, t).Err()
+		if err == nil {
+			if r.tracking != nil {
+				r.tracking.Invalidate(key)
+			}
+			return nil
+		}
+
+		wait := boff.NextBackOff()
+		if wait == backoff.Stop {
+			return err
+		}
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return err
+		}
+	}
+}
+
+func (r *redisCache) SetMulti(ctx context.Context, items ...service.CacheItem) error {
+	boff := r.boffPool.Get().(backoff.BackOff)
+	defer func() {
+		boff.Reset()
+		r.boffPool.Put(boff)
+	}()
+
+	for {
+		// MSET does not support TTLs and so items are set with pipelined SETs.
+		_, err := r.activeClient().Pipelined(ctx, func(p redis.Pipeliner) error {
+			for _, item := range items {
+				t := r.defaultTTL
+				if item.TTL != nil {
+					t = *item.TTL
+				}
+				p.Set(ctx, r.prefix+item.Key, item.Value, t)
+			}
+			return nil
+		})
 		if err == nil {
+			if r.tracking != nil {
+				for _, item := range items {
+					r.tracking.Invalidate(r.prefix + item.Key)
+				}
+			}
 			return nil
 		}
 
@@ -199,11 +434,14 @@ func (r *redisCache) Add(ctx context.Context, key string, value []byte, ttl *tim
 	}
 
 	for {
-		set, err := r.client.SetNX(ctx, key, value, t).Result()
+		set, err := r.activeClient().SetNX(ctx, key, value, t).Result()
 		if err == nil {
 			if !set {
 				return service.ErrKeyAlreadyExists
 			}
+			if r.tracking != nil {
+				r.tracking.Invalidate(key)
+			}
 			return nil
 		}
 
@@ -231,8 +469,11 @@ func (r *redisCache) Delete(ctx context.Context, key string) error {
 	}
 
 	for {
-		_, err := r.client.Del(ctx, key).Result()
+		_, err := r.activeClient().Del(ctx, key).Result()
 		if err == nil {
+			if r.tracking != nil {
+				r.tracking.Invalidate(key)
+			}
 			return nil
 		}
 
@@ -249,5 +490,8 @@ func (r *redisCache) Delete(ctx context.Context, key string) error {
 }
 
 func (r *redisCache) Close(ctx context.Context) error {
+	if r.tracking != nil {
+		return r.tracking.Close()
+	}
 	return r.client.Close()
 }
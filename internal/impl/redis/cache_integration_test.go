@@ -13,6 +13,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/benthosdev/benthos/v4/public/service"
 	"github.com/benthosdev/benthos/v4/public/service/integration"
 )
 
@@ -39,7 +40,7 @@ func TestIntegrationRedisCache(t *testing.T) {
 			return cErr
 		}
 
-		r, cErr := newRedisCacheFromConfig(pConf)
+		r, cErr := newRedisCacheFromConfig(pConf, service.MockResources())
 		if cErr != nil {
 			return cErr
 		}
@@ -61,6 +62,7 @@ cache_resources:
 		integration.CacheTestDoubleAdd(),
 		integration.CacheTestDelete(),
 		integration.CacheTestGetAndSet(50),
+		integration.CacheTestGetAndSetMulti(50),
 	)
 	suite.Run(
 		t, template,
@@ -131,7 +133,7 @@ kind: cluster
 			return cErr
 		}
 
-		r, cErr := newRedisCacheFromConfig(pConf)
+		r, cErr := newRedisCacheFromConfig(pConf, service.MockResources())
 		if cErr != nil {
 			return cErr
 		}
@@ -154,6 +156,7 @@ cache_resources:
 		integration.CacheTestDoubleAdd(),
 		integration.CacheTestDelete(),
 		integration.CacheTestGetAndSet(50),
+		integration.CacheTestGetAndSetMulti(50),
 	)
 	suite.Run(
 		t, template,
@@ -243,7 +246,7 @@ master: mymaster
 			return cErr
 		}
 
-		r, cErr := newRedisCacheFromConfig(pConf)
+		r, cErr := newRedisCacheFromConfig(pConf, service.MockResources())
 		if cErr != nil {
 			return cErr
 		}
@@ -267,6 +270,7 @@ cache_resources:
 		integration.CacheTestDoubleAdd(),
 		integration.CacheTestDelete(),
 		integration.CacheTestGetAndSet(50),
+		integration.CacheTestGetAndSetMulti(50),
 	)
 	suite.Run(
 		t, template,
@@ -1,9 +1,13 @@
 package redis
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"net/url"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/redis/go-redis/v9"
 
@@ -28,7 +32,7 @@ Some cloud hosted instances of Redis (such as Azure Cache) might need some hand
 			Example("redis://localhost:6379/1").
 			Example("redis://localhost:6379/1,redis://localhost:6380/1"),
 		service.NewStringEnumField("kind", "simple", "cluster", "failover").
-			Description("Specifies a simple, cluster-aware, or failover-aware redis client.").
+			Description("Specifies a simple, cluster-aware, or failover-aware redis client. A `cluster` client discovers the topology of the cluster from the servers of the `url` and follows changes to it, and a `failover` client treats the servers of the `url` as Sentinels and follows the master that they monitor.").
 			Default("simple").
 			Advanced(),
 		service.NewStringField("master").
@@ -36,11 +40,61 @@ Some cloud hosted instances of Redis (such as Azure Cache) might need some hand
 			Default("").
 			Example("mymaster").
 			Advanced(),
+		service.NewObjectField("sentinel",
+			service.NewStringField("username").
+				Description("The username to authenticate with Sentinels, where credentials within the `url` are used to authenticate with the master.").
+				Default(""),
+			service.NewStringField("password").
+				Description("The password to authenticate with Sentinels.").
+				Default("").
+				Secret(),
+		).
+			Description("Credentials for Sentinels when `kind` is `failover`, which are only required when the Sentinels have authentication enabled.").
+			Advanced().
+			Version("4.28.0"),
+		service.NewStringAnnotatedEnumField("read_preference", map[string]string{
+			"primary": "All commands are sent to primaries.",
+			"replica": "Read-only commands are sent to replicas. Only supported when `kind` is `cluster`.",
+			"latency": "Read-only commands are sent to the primary or replica with the lowest latency. Only supported when `kind` is `cluster` or `failover`.",
+			"random":  "Read-only commands are sent to a random primary or replica. Only supported when `kind` is `cluster` or `failover`.",
+		}).
+			Description("Where read-only commands are sent, which can relieve primaries of reads at the cost of reading data that might not yet be replicated.").
+			Default("primary").
+			Advanced().
+			Version("4.28.0"),
+		service.NewStringAnnotatedEnumField("protocol", map[string]string{
+			"resp2": "Use the RESP2 protocol.",
+			"resp3": "Use the RESP3 protocol, falling back to RESP2 when the server does not support it.",
+		}).
+			Description("The version of the Redis serialization protocol to use.").
+			Default("resp3").
+			Advanced().
+			Version("4.28.0"),
+		service.NewObjectField("pool",
+			service.NewBoolField("shared").
+				Description("Whether to share a single pool of connections between all redis components with identical `url`, `kind`, `master`, `sentinel`, `read_preference`, `tls`, `protocol` and `pool` fields, rather than each component opening its own.").
+				Default(false),
+			service.NewIntField("size").
+				Description("The maximum number of connections within the pool, where `0` defaults to ten connections per CPU.").
+				Default(0),
+		).
+			Description("Options for the pool of connections to the server.").
+			Advanced().
+			Version("4.28.0"),
 		tlsField,
 	}
 }
 
-func getClient(parsedConf *service.ParsedConfig) (redis.UniversalClient, error) {
+type clientConfig struct {
+	kind   string
+	opts   *redis.UniversalOptions
+	shared bool
+
+	// Identifies the pool of a shared client.
+	poolKey string
+}
+
+func clientConfigFromParsed(parsedConf *service.ParsedConfig) (*clientConfig, error) {
 	urlStr, err := parsedConf.FieldString("url")
 	if err != nil {
 		return nil, err
@@ -64,6 +118,36 @@ func getClient(parsedConf *service.ParsedConfig) (redis.UniversalClient, error)
 		tlsConf = nil
 	}
 
+	protocolStr, err := parsedConf.FieldString("protocol")
+	if err != nil {
+		return nil, err
+	}
+
+	shared, err := parsedConf.FieldBool("pool", "shared")
+	if err != nil {
+		return nil, err
+	}
+
+	poolSize, err := parsedConf.FieldInt("pool", "size")
+	if err != nil {
+		return nil, err
+	}
+
+	sentinelUser, err := parsedConf.FieldString("sentinel", "username")
+	if err != nil {
+		return nil, err
+	}
+
+	sentinelPass, err := parsedConf.FieldString("sentinel", "password")
+	if err != nil {
+		return nil, err
+	}
+
+	readPreference, err := parsedConf.FieldString("read_preference")
+	if err != nil {
+		return nil, err
+	}
+
 	// We default to Redis DB 0 for backward compatibility
 	var redisDB int
 	var user string
@@ -92,26 +176,202 @@ func getClient(parsedConf *service.ParsedConfig) (redis.UniversalClient, error)
 		pass = rurl.Password
 	}
 
-	var client redis.UniversalClient
-	opts := &redis.UniversalOptions{
-		Addrs:     addrs,
-		DB:        redisDB,
-		Username:  user,
-		Password:  pass,
-		TLSConfig: tlsConf,
+	conf := &clientConfig{
+		kind: kind,
+		opts: &redis.UniversalOptions{
+			Addrs:            addrs,
+			DB:               redisDB,
+			Username:         user,
+			Password:         pass,
+			SentinelUsername: sentinelUser,
+			SentinelPassword: sentinelPass,
+			TLSConfig:        tlsConf,
+			PoolSize:         poolSize,
+		},
+		shared: shared,
+	}
+
+	switch protocolStr {
+	case "resp2":
+		conf.opts.Protocol = 2
+	case "resp3":
+		conf.opts.Protocol = 3
 	}
 
 	switch kind {
-	case "simple":
-		client = redis.NewClient(opts.Simple())
+	case "simple", "cluster":
+	case "failover":
+		conf.opts.MasterName = master
+	default:
+		return nil, fmt.Errorf("invalid redis kind: %s", kind)
+	}
+
+	switch readPreference {
+	case "primary":
+	case "replica":
+		if kind != "cluster" {
+			return nil, fmt.Errorf("read preference %v is not supported by redis kind %v", readPreference, kind)
+		}
+		conf.opts.ReadOnly = true
+	case "latency", "random":
+		if kind == "simple" {
+			return nil, fmt.Errorf("read preference %v is not supported by redis kind %v", readPreference, kind)
+		}
+		conf.opts.RouteByLatency = readPreference == "latency"
+		conf.opts.RouteRandomly = readPreference == "random"
+	default:
+		return nil, fmt.Errorf("invalid read preference: %s", readPreference)
+	}
+
+	if shared {
+		keyFields := map[string]any{}
+		for _, f := range []string{"url", "kind", "master", "sentinel", "read_preference", "tls", "protocol", "pool"} {
+			if keyFields[f], err = parsedConf.FieldAny(f); err != nil {
+				return nil, err
+			}
+		}
+		keyBytes, err := json.Marshal(keyFields)
+		if err != nil {
+			return nil, err
+		}
+		conf.poolKey = string(keyBytes)
+	}
+	return conf, nil
+}
+
+// newClient creates a client from the config that isn't shared, and when
+// resources are provided the latency of commands is recorded as metrics.
+func (c *clientConfig) newClient(mgr *service.Resources) redis.UniversalClient {
+	var client redis.UniversalClient
+	switch c.kind {
 	case "cluster":
-		client = redis.NewClusterClient(opts.Cluster())
+		client = redis.NewClusterClient(c.opts.Cluster())
 	case "failover":
-		opts.MasterName = master
-		client = redis.NewFailoverClient(opts.Failover())
+		// Reads can only be routed to replicas by a client that treats the
+		// master and its replicas as a cluster.
+		if c.opts.RouteByLatency || c.opts.RouteRandomly {
+			opts := c.opts.Failover()
+			opts.RouteByLatency = c.opts.RouteByLatency
+			opts.RouteRandomly = c.opts.RouteRandomly
+			client = redis.NewFailoverClusterClient(opts)
+		} else {
+			client = redis.NewFailoverClient(c.opts.Failover())
+		}
 	default:
-		err = fmt.Errorf("invalid redis kind: %s", kind)
+		client = redis.NewClient(c.opts.Simple())
+	}
+	if mgr != nil {
+		client.AddHook(newLatencyHook(mgr.Metrics()))
+	}
+	return client
+}
+
+func getClient(parsedConf *service.ParsedConfig, mgr *service.Resources) (redis.UniversalClient, error) {
+	conf, err := clientConfigFromParsed(parsedConf)
+	if err != nil {
+		return nil, err
+	}
+	if !conf.shared {
+		return conf.newClient(mgr), nil
+	}
+	return acquireSharedClient(conf, mgr), nil
+}
+
+//------------------------------------------------------------------------------
+
+type sharedPool struct {
+	client redis.UniversalClient
+	refs   int
+}
+
+var (
+	sharedPoolsMut sync.Mutex
+	sharedPools    = map[string]*sharedPool{}
+)
+
+// sharedClient is a reference to a client that is shared between components,
+// where the underlying client is closed once all references are closed.
+type sharedClient struct {
+	redis.UniversalClient
+
+	closeOnce sync.Once
+	poolKey   string
+}
+
+func acquireSharedClient(conf *clientConfig, mgr *service.Resources) redis.UniversalClient {
+	sharedPoolsMut.Lock()
+	defer sharedPoolsMut.Unlock()
+
+	pool, exists := sharedPools[conf.poolKey]
+	if !exists {
+		pool = &sharedPool{client: conf.newClient(mgr)}
+		sharedPools[conf.poolKey] = pool
 	}
+	pool.refs++
+	return &sharedClient{UniversalClient: pool.client, poolKey: conf.poolKey}
+}
+
+func (s *sharedClient) Close() (err error) {
+	s.closeOnce.Do(func() {
+		sharedPoolsMut.Lock()
+		defer sharedPoolsMut.Unlock()
+
+		pool, exists := sharedPools[s.poolKey]
+		if !exists {
+			return
+		}
+		if pool.refs--; pool.refs > 0 {
+			return
+		}
+		delete(sharedPools, s.poolKey)
+		err = pool.client.Close()
+	})
+	return
+}
+
+//------------------------------------------------------------------------------
+
+// latencyHook records the latency and errors of commands, where commands
+// executed within a pipeline are recorded as a single pipeline command.
+type latencyHook struct {
+	latency *service.MetricTimer
+	errors  *service.MetricCounter
+}
+
+func newLatencyHook(metrics *service.Metrics) *latencyHook {
+	return &latencyHook{
+		latency: metrics.NewTimer("redis_command_latency_ns", "command"),
+		errors:  metrics.NewCounter("redis_command_error", "command"),
+	}
+}
+
+func (l *latencyHook) record(command string, started time.Time, err error) {
+	l.latency.Timing(time.Since(started).Nanoseconds(), command)
+	if err != nil && err != redis.Nil {
+		l.errors.Incr(1, command)
+	}
+}
 
-	return client, err
+func (l *latencyHook) DialHook(next redis.DialHook) redis.DialHook {
+	return next
 }
+
+func (l *latencyHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		started := time.Now()
+		err := next(ctx, cmd)
+		l.record(cmd.Name(), started, err)
+		return err
+	}
+}
+
+func (l *latencyHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		started := time.Now()
+		err := next(ctx, cmds)
+		l.record("pipeline", started, err)
+		return err
+	}
+}
+
+var _ redis.Hook = &latencyHook{}
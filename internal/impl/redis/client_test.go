@@ -0,0 +1,134 @@
+package redis
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func TestClientConfigProtocol(t *testing.T) {
+	spec := service.NewConfigSpec().Fields(clientFields()...)
+
+	pConf, err := spec.ParseYAML(`url: redis://localhost:6379/2`, nil)
+	require.NoError(t, err)
+
+	conf, err := clientConfigFromParsed(pConf)
+	require.NoError(t, err)
+	assert.Equal(t, 3, conf.opts.Protocol)
+	assert.Equal(t, 2, conf.opts.DB)
+
+	pConf, err = spec.ParseYAML(`
+url: redis://localhost:6379
+protocol: resp2
+pool:
+  size: 5
+`, nil)
+	require.NoError(t, err)
+
+	conf, err = clientConfigFromParsed(pConf)
+	require.NoError(t, err)
+	assert.Equal(t, 2, conf.opts.Protocol)
+	assert.Equal(t, 5, conf.opts.PoolSize)
+}
+
+func TestClientConfigReadPreference(t *testing.T) {
+	spec := service.NewConfigSpec().Fields(clientFields()...)
+
+	pConf, err := spec.ParseYAML(`
+url: redis://localhost:6379,redis://localhost:6380
+kind: cluster
+read_preference: replica
+`, nil)
+	require.NoError(t, err)
+
+	conf, err := clientConfigFromParsed(pConf)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"localhost:6379", "localhost:6380"}, conf.opts.Addrs)
+	assert.True(t, conf.opts.ReadOnly)
+	assert.False(t, conf.opts.RouteByLatency)
+
+	pConf, err = spec.ParseYAML(`
+url: redis://:masterpass@localhost:26379
+kind: failover
+master: mymaster
+sentinel:
+  username: foo
+  password: bar
+read_preference: latency
+`, nil)
+	require.NoError(t, err)
+
+	conf, err = clientConfigFromParsed(pConf)
+	require.NoError(t, err)
+	assert.Equal(t, "masterpass", conf.opts.Password)
+	assert.Equal(t, "foo", conf.opts.SentinelUsername)
+	assert.Equal(t, "bar", conf.opts.SentinelPassword)
+	assert.True(t, conf.opts.RouteByLatency)
+	assert.False(t, conf.opts.RouteRandomly)
+
+	for _, confStr := range []string{
+		`
+url: redis://localhost:6379
+read_preference: random
+`,
+		`
+url: redis://localhost:26379
+kind: failover
+master: mymaster
+read_preference: replica
+`,
+	} {
+		pConf, err = spec.ParseYAML(confStr, nil)
+		require.NoError(t, err)
+
+		_, err = clientConfigFromParsed(pConf)
+		assert.ErrorContains(t, err, "is not supported by redis kind")
+	}
+}
+
+func TestClientSharedPool(t *testing.T) {
+	spec := service.NewConfigSpec().Fields(clientFields()...)
+
+	pConfA, err := spec.ParseYAML(`
+url: redis://localhost:6379
+pool:
+  shared: true
+`, nil)
+	require.NoError(t, err)
+
+	pConfB, err := spec.ParseYAML(`
+url: redis://localhost:6380
+pool:
+  shared: true
+`, nil)
+	require.NoError(t, err)
+
+	a1, err := getClient(pConfA, service.MockResources())
+	require.NoError(t, err)
+	a2, err := getClient(pConfA, service.MockResources())
+	require.NoError(t, err)
+	b, err := getClient(pConfB, service.MockResources())
+	require.NoError(t, err)
+
+	assert.Same(t, a1.(*sharedClient).UniversalClient, a2.(*sharedClient).UniversalClient)
+	assert.NotSame(t, a1.(*sharedClient).UniversalClient, b.(*sharedClient).UniversalClient)
+
+	key := a1.(*sharedClient).poolKey
+
+	require.NoError(t, a1.Close())
+	require.NoError(t, a1.Close())
+
+	sharedPoolsMut.Lock()
+	assert.Equal(t, 1, sharedPools[key].refs)
+	sharedPoolsMut.Unlock()
+
+	require.NoError(t, a2.Close())
+	require.NoError(t, b.Close())
+
+	sharedPoolsMut.Lock()
+	assert.Empty(t, sharedPools)
+	sharedPoolsMut.Unlock()
+}
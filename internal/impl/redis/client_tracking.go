@@ -0,0 +1,223 @@
+package redis
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const invalidationChannel = "__redis__:invalidate"
+
+type trackedItem struct {
+	value   []byte
+	expires time.Time
+}
+
+// clientTracking implements client side caching of keys read from a server
+// with tracking enabled, where the server pushes the keys that have been
+// modified to a dedicated pub/sub connection that the tracking of all other
+// connections is redirected to.
+type clientTracking struct {
+	log      *service.Logger
+	ttl      time.Duration
+	maxItems int
+
+	newClient func(onConnect func(context.Context, *redis.Conn) error) redis.UniversalClient
+
+	invalidator *redis.Client
+	invMut      sync.Mutex
+	pubsub      *redis.PubSub
+
+	// The client ID of the invalidation connection, and a generation that is
+	// incremented each time it reconnects with a new ID.
+	redirectID  atomic.Int64
+	redirectGen atomic.Int64
+
+	clientMut sync.RWMutex
+	client    redis.UniversalClient
+	clientGen int64
+
+	itemsMut sync.Mutex
+	epoch    uint64
+	items    map[string]trackedItem
+}
+
+func newClientTracking(conf *clientConfig, mgr *service.Resources, ttl time.Duration, maxItems int) *clientTracking {
+	t := &clientTracking{
+		log:      mgr.Logger(),
+		ttl:      ttl,
+		maxItems: maxItems,
+		items:    map[string]trackedItem{},
+	}
+
+	t.newClient = func(onConnect func(context.Context, *redis.Conn) error) redis.UniversalClient {
+		opts := *conf.opts
+		opts.OnConnect = onConnect
+		return (&clientConfig{kind: conf.kind, opts: &opts}).newClient(mgr)
+	}
+
+	invOpts := conf.opts.Simple()
+	invOpts.PoolSize = 1
+	invOpts.OnConnect = func(ctx context.Context, cn *redis.Conn) error {
+		id, err := cn.ClientID(ctx).Result()
+		if err != nil {
+			return err
+		}
+		if prev := t.redirectID.Swap(id); prev != 0 && prev != id {
+			// Invalidations might have been missed whilst disconnected, and
+			// the tracking of existing connections is redirected to a client
+			// that no longer exists.
+			t.log.Warn("Redis client tracking connection was re-established, flushing local cache")
+			t.redirectGen.Add(1)
+			t.flush()
+		}
+		return nil
+	}
+	t.invalidator = redis.NewClient(invOpts)
+	return t
+}
+
+// redirectTarget returns the client ID that the tracking of connections should
+// be redirected to, subscribing to invalidations if not already.
+func (t *clientTracking) redirectTarget(ctx context.Context) (int64, error) {
+	t.invMut.Lock()
+	defer t.invMut.Unlock()
+
+	if t.pubsub == nil {
+		pubsub := t.invalidator.Subscribe(ctx, invalidationChannel)
+		if _, err := pubsub.Receive(ctx); err != nil {
+			_ = pubsub.Close()
+			return 0, err
+		}
+		t.pubsub = pubsub
+		go t.loop(pubsub.Channel())
+	}
+	return t.redirectID.Load(), nil
+}
+
+func (t *clientTracking) loop(msgs <-chan *redis.Message) {
+	for msg := range msgs {
+		if len(msg.PayloadSlice) == 0 {
+			// An invalidation without keys is sent when the server flushes
+			// all keys.
+			t.flush()
+			continue
+		}
+		t.itemsMut.Lock()
+		t.epoch++
+		for _, k := range msg.PayloadSlice {
+			delete(t.items, k)
+		}
+		t.itemsMut.Unlock()
+	}
+}
+
+// Client returns a client with tracking enabled for all connections, which is
+// recreated when the invalidation connection has changed since its creation.
+func (t *clientTracking) Client() redis.UniversalClient {
+	gen := t.redirectGen.Load()
+
+	t.clientMut.RLock()
+	client, clientGen := t.client, t.clientGen
+	t.clientMut.RUnlock()
+	if client != nil && clientGen == gen {
+		return client
+	}
+
+	t.clientMut.Lock()
+	defer t.clientMut.Unlock()
+
+	if t.client != nil && t.clientGen == gen {
+		return t.client
+	}
+	if t.client != nil {
+		_ = t.client.Close()
+	}
+	t.client = t.newClient(func(ctx context.Context, cn *redis.Conn) error {
+		id, err := t.redirectTarget(ctx)
+		if err != nil {
+			return err
+		}
+		cmd := redis.NewStatusCmd(ctx, "client", "tracking", "on", "redirect", id)
+		_ = cn.Process(ctx, cmd)
+		return cmd.Err()
+	})
+	t.clientGen = gen
+	t.flush()
+	return t.client
+}
+
+// Epoch returns a value that must be provided when storing a value read from
+// the server, which prevents storing values invalidated since the read began.
+func (t *clientTracking) Epoch() uint64 {
+	t.itemsMut.Lock()
+	defer t.itemsMut.Unlock()
+	return t.epoch
+}
+
+func (t *clientTracking) Get(key string) ([]byte, bool) {
+	t.itemsMut.Lock()
+	defer t.itemsMut.Unlock()
+
+	item, exists := t.items[key]
+	if !exists {
+		return nil, false
+	}
+	if time.Now().After(item.expires) {
+		delete(t.items, key)
+		return nil, false
+	}
+	return item.value, true
+}
+
+func (t *clientTracking) Store(key string, value []byte, epoch uint64) {
+	t.itemsMut.Lock()
+	defer t.itemsMut.Unlock()
+
+	if t.epoch != epoch {
+		return
+	}
+	if _, exists := t.items[key]; !exists && len(t.items) >= t.maxItems {
+		for k := range t.items {
+			delete(t.items, k)
+			break
+		}
+	}
+	t.items[key] = trackedItem{value: value, expires: time.Now().Add(t.ttl)}
+}
+
+func (t *clientTracking) Invalidate(key string) {
+	t.itemsMut.Lock()
+	t.epoch++
+	delete(t.items, key)
+	t.itemsMut.Unlock()
+}
+
+func (t *clientTracking) flush() {
+	t.itemsMut.Lock()
+	t.epoch++
+	t.items = map[string]trackedItem{}
+	t.itemsMut.Unlock()
+}
+
+func (t *clientTracking) Close() error {
+	t.clientMut.Lock()
+	if t.client != nil {
+		_ = t.client.Close()
+		t.client = nil
+	}
+	t.clientMut.Unlock()
+
+	t.invMut.Lock()
+	if t.pubsub != nil {
+		_ = t.pubsub.Close()
+		t.pubsub = nil
+	}
+	t.invMut.Unlock()
+	return t.invalidator.Close()
+}
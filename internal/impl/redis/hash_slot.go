@@ -0,0 +1,35 @@
+package redis
+
+import "strings"
+
+// The number of hash slots that the keys of a cluster are distributed across.
+const clusterHashSlots = 16384
+
+// hashSlot returns the cluster hash slot of a key. When a key contains a hash
+// tag, which is a non-empty substring between the first `{` and the following
+// `}`, only the hash tag is hashed so that keys sharing a hash tag belong to the
+// same slot.
+func hashSlot(key string) uint16 {
+	if start := strings.IndexByte(key, '{'); start >= 0 {
+		if end := strings.IndexByte(key[start+1:], '}'); end > 0 {
+			key = key[start+1 : start+1+end]
+		}
+	}
+	return crc16(key) % clusterHashSlots
+}
+
+// crc16 implements the CRC16-CCITT (XMODEM) checksum used for hashing keys.
+func crc16(s string) uint16 {
+	var crc uint16
+	for i := 0; i < len(s); i++ {
+		crc ^= uint16(s[i]) << 8
+		for j := 0; j < 8; j++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
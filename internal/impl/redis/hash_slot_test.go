@@ -0,0 +1,26 @@
+package redis
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHashSlot(t *testing.T) {
+	assert.Equal(t, uint16(0x31C3), crc16("123456789"))
+
+	for key, slot := range map[string]uint16{
+		"":                     0,
+		"foo":                  12182,
+		"bar":                  5061,
+		"hello":                866,
+		"{user1000}.following": 3443,
+		"{user1000}.followers": 3443,
+		"user1000":             3443,
+		"foo{}{bar}":           8363,
+		"foo{{bar}}zap":        4015,
+		"foo{bar}{zap}":        5061,
+	} {
+		assert.Equal(t, slot, hashSlot(key), key)
+	}
+}
@@ -67,7 +67,7 @@ func init() {
 }
 
 func newRedisListInputFromConfig(conf *service.ParsedConfig, mgr *service.Resources) (service.Input, error) {
-	client, err := getClient(conf)
+	client, err := getClient(conf, mgr)
 	if err != nil {
 		return nil, err
 	}
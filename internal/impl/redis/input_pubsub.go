@@ -2,6 +2,7 @@ package redis
 
 import (
 	"context"
+	"errors"
 	"sync"
 
 	"github.com/redis/go-redis/v9"
@@ -12,6 +13,7 @@ import (
 const (
 	psiFieldChannels    = "channels"
 	psiFieldUsePatterns = "use_patterns"
+	psiFieldSharded     = "sharded"
 )
 
 func redisPubSubInputConfig() *service.ConfigSpec {
@@ -25,7 +27,11 @@ In order to subscribe to channels using the `+"`PSUBSCRIBE`"+` command set the f
 - `+"`h*llo`"+` subscribes to hllo and heeeello
 - `+"`h[ae]llo`"+` subscribes to hello and hallo, but not hillo
 
-Use `+"`\\`"+` to escape special characters if you want to match them verbatim.`).
+Use `+"`\\`"+` to escape special characters if you want to match them verbatim.
+
+### Sharded Pub/Sub
+
+When `+"`sharded`"+` is `+"`true`"+` channels are consumed with the `+"`SSUBSCRIBE`"+` command, which in cluster mode subscribes to the shard that owns each channel rather than receiving messages broadcast across the entire cluster. Sharded pub/sub requires Redis 7.0 or later and cannot be combined with `+"`use_patterns`"+`.`).
 		Categories("Services").
 		Fields(clientFields()...).
 		Fields(
@@ -34,6 +40,11 @@ Use `+"`\\`"+` to escape special characters if you want to match them verbatim.`
 			service.NewBoolField(psiFieldUsePatterns).
 				Description("Whether to use the PSUBSCRIBE command, allowing for glob-style patterns within target channel names.").
 				Default(false),
+			service.NewBoolField(psiFieldSharded).
+				Description("Whether to use the SSUBSCRIBE command in order to consume from sharded channels.").
+				Default(false).
+				Advanced().
+				Version("4.28.0"),
 			service.NewAutoRetryNacksToggleField(),
 		)
 }
@@ -60,12 +71,13 @@ type redisPubSubReader struct {
 
 	channels    []string
 	usePatterns bool
+	sharded     bool
 
 	log *service.Logger
 }
 
 func newRedisPubSubReader(conf *service.ParsedConfig, mgr *service.Resources) (*redisPubSubReader, error) {
-	client, err := getClient(conf)
+	client, err := getClient(conf, mgr)
 	if err != nil {
 		return nil, err
 	}
@@ -79,6 +91,12 @@ func newRedisPubSubReader(conf *service.ParsedConfig, mgr *service.Resources) (*
 	if r.usePatterns, err = conf.FieldBool(psiFieldUsePatterns); err != nil {
 		return nil, err
 	}
+	if r.sharded, err = conf.FieldBool(psiFieldSharded); err != nil {
+		return nil, err
+	}
+	if r.sharded && r.usePatterns {
+		return nil, errors.New("patterns cannot be used with sharded channels")
+	}
 	return r, nil
 }
 
@@ -94,9 +112,12 @@ func (r *redisPubSubReader) Connect(ctx context.Context) error {
 		return err
 	}
 
-	if r.usePatterns {
+	switch {
+	case r.sharded:
+		r.pubsub = r.client.SSubscribe(ctx, r.channels...)
+	case r.usePatterns:
 		r.pubsub = r.client.PSubscribe(ctx, r.channels...)
-	} else {
+	default:
 		r.pubsub = r.client.Subscribe(ctx, r.channels...)
 	}
 	return nil
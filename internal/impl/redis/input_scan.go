@@ -59,7 +59,7 @@ This input generates a message for each key value pair in the following format:
 }
 
 func newRedisScanInputFromConfig(conf *service.ParsedConfig, mgr *service.Resources) (service.Input, error) {
-	client, err := getClient(conf)
+	client, err := getClient(conf, mgr)
 	if err != nil {
 		return nil, err
 	}
@@ -128,14 +128,14 @@ func newRedisStreamsReader(conf *service.ParsedConfig, mgr *service.Resources) (
 
 	r = &redisStreamsReader{
 		clientCtor: func() (redis.UniversalClient, error) {
-			return getClient(conf)
+			return getClient(conf, mgr)
 		},
 		log:         mgr.Logger(),
 		connBackoff: connBoff,
 		closeChan:   make(chan struct{}),
 		closedChan:  make(chan struct{}),
 	}
-	if _, err = getClient(conf); err != nil {
+	if _, err = clientConfigFromParsed(conf); err != nil {
 		return
 	}
 
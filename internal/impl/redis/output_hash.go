@@ -99,11 +99,11 @@ type redisHashWriter struct {
 func newRedisHashWriter(conf *service.ParsedConfig, mgr *service.Resources) (r *redisHashWriter, err error) {
 	r = &redisHashWriter{
 		clientCtor: func() (redis.UniversalClient, error) {
-			return getClient(conf)
+			return getClient(conf, mgr)
 		},
 		log: mgr.Logger(),
 	}
-	if _, err = getClient(conf); err != nil {
+	if _, err = clientConfigFromParsed(conf); err != nil {
 		return
 	}
 
@@ -77,7 +77,7 @@ func newRedisListWriter(conf *service.ParsedConfig, mgr *service.Resources) (r *
 	r = &redisListWriter{
 		log: mgr.Logger(),
 		clientCtor: func() (redis.UniversalClient, error) {
-			return getClient(conf)
+			return getClient(conf, mgr)
 		},
 	}
 
@@ -85,7 +85,7 @@ func newRedisListWriter(conf *service.ParsedConfig, mgr *service.Resources) (r *
 		return
 	}
 
-	if _, err := getClient(conf); err != nil {
+	if _, err := clientConfigFromParsed(conf); err != nil {
 		return nil, err
 	}
 
@@ -13,6 +13,7 @@ import (
 const (
 	psoFieldChannel  = "channel"
 	psoFieldBatching = "batching"
+	psoFieldSharded  = "sharded"
 )
 
 func redisPubSubOutputConfig() *service.ConfigSpec {
@@ -20,12 +21,19 @@ func redisPubSubOutputConfig() *service.ConfigSpec {
 		Stable().
 		Summary(`Publishes messages through the Redis PubSub model. It is not possible to guarantee that messages have been received.`).
 		Description(`
-This output will interpolate functions within the channel field, you can find a list of functions [here](/docs/configuration/interpolation#bloblang-queries).`+service.OutputPerformanceDocs(true, true)).
+This output will interpolate functions within the channel field, you can find a list of functions [here](/docs/configuration/interpolation#bloblang-queries).
+
+When `+"`sharded`"+` is `+"`true`"+` messages are published with the `+"`SPUBLISH`"+` command, which in cluster mode routes each message only to the shard that owns its channel. Sharded pub/sub requires Redis 7.0 or later, and messages can only be consumed by subscribers of sharded channels.`+service.OutputPerformanceDocs(true, true)).
 		Categories("Services").
 		Fields(clientFields()...).
 		Fields(
 			service.NewInterpolatedStringField(psoFieldChannel).
 				Description("The channel to publish messages to."),
+			service.NewBoolField(psoFieldSharded).
+				Description("Whether to use the SPUBLISH command in order to publish messages to sharded channels.").
+				Default(false).
+				Advanced().
+				Version("4.28.0"),
 			service.NewOutputMaxInFlightField(),
 			service.NewBatchPolicyField(psoFieldBatching),
 		)
@@ -54,6 +62,7 @@ type redisPubSubWriter struct {
 
 	channelStr string
 	channel    *service.InterpolatedString
+	sharded    bool
 
 	clientCtor func() (redis.UniversalClient, error)
 	client     redis.UniversalClient
@@ -64,7 +73,7 @@ func newRedisPubSubWriter(conf *service.ParsedConfig, mgr *service.Resources) (r
 	r = &redisPubSubWriter{
 		log: mgr.Logger(),
 		clientCtor: func() (redis.UniversalClient, error) {
-			return getClient(conf)
+			return getClient(conf, mgr)
 		},
 	}
 
@@ -74,8 +83,11 @@ func newRedisPubSubWriter(conf *service.ParsedConfig, mgr *service.Resources) (r
 	if r.channel, err = conf.FieldInterpolatedString(psoFieldChannel); err != nil {
 		return
 	}
+	if r.sharded, err = conf.FieldBool(psoFieldSharded); err != nil {
+		return
+	}
 
-	if _, err := getClient(conf); err != nil {
+	if _, err := clientConfigFromParsed(conf); err != nil {
 		return nil, err
 	}
 	return r, nil
@@ -116,7 +128,7 @@ func (r *redisPubSubWriter) WriteBatch(ctx context.Context, batch service.Messag
 			return err
 		}
 
-		if err := client.Publish(ctx, channel, mBytes).Err(); err != nil {
+		if err := r.publish(ctx, client, channel, mBytes).Err(); err != nil {
 			_ = r.disconnect()
 			r.log.Errorf("Error from redis: %v\n", err)
 			return service.ErrNotConnected
@@ -137,7 +149,7 @@ func (r *redisPubSubWriter) WriteBatch(ctx context.Context, batch service.Messag
 			return err
 		}
 
-		_ = pipe.Publish(ctx, channel, mBytes)
+		_ = r.publish(ctx, pipe, channel, mBytes)
 	}
 
 	cmders, err := pipe.Exec(ctx)
@@ -162,6 +174,13 @@ func (r *redisPubSubWriter) WriteBatch(ctx context.Context, batch service.Messag
 	return nil
 }
 
+func (r *redisPubSubWriter) publish(ctx context.Context, client redis.Cmdable, channel string, message any) *redis.IntCmd {
+	if r.sharded {
+		return client.SPublish(ctx, channel, message)
+	}
+	return client.Publish(ctx, channel, message)
+}
+
 func (r *redisPubSubWriter) disconnect() error {
 	r.connMut.Lock()
 	defer r.connMut.Unlock()
@@ -80,7 +80,7 @@ func newRedisStreamsWriter(conf *service.ParsedConfig, mgr *service.Resources) (
 	r = &redisStreamsWriter{
 		log: mgr.Logger(),
 		clientCtor: func() (redis.UniversalClient, error) {
-			return getClient(conf)
+			return getClient(conf, mgr)
 		},
 	}
 
@@ -100,7 +100,7 @@ func newRedisStreamsWriter(conf *service.ParsedConfig, mgr *service.Resources) (
 		return
 	}
 
-	if _, err := getClient(conf); err != nil {
+	if _, err := clientConfigFromParsed(conf); err != nil {
 		return nil, err
 	}
 	return r, nil
@@ -138,7 +138,7 @@ type redisProc struct {
 }
 
 func newRedisProcFromConfig(conf *service.ParsedConfig, res *service.Resources) (*redisProc, error) {
-	client, err := getClient(conf)
+	client, err := getClient(conf, res)
 	if err != nil {
 		return nil, err
 	}
@@ -36,7 +36,7 @@ func init() {
 	err := service.RegisterRateLimit(
 		"redis", redisRatelimitConfig(),
 		func(conf *service.ParsedConfig, mgr *service.Resources) (service.RateLimit, error) {
-			return newRedisRatelimitFromConfig(conf)
+			return newRedisRatelimitFromConfig(conf, mgr)
 		})
 	if err != nil {
 		panic(err)
@@ -55,8 +55,8 @@ type redisRatelimit struct {
 	accessScript *redis.Script
 }
 
-func newRedisRatelimitFromConfig(conf *service.ParsedConfig) (*redisRatelimit, error) {
-	client, err := getClient(conf)
+func newRedisRatelimitFromConfig(conf *service.ParsedConfig, mgr *service.Resources) (*redisRatelimit, error) {
+	client, err := getClient(conf, mgr)
 	if err != nil {
 		return nil, err
 	}
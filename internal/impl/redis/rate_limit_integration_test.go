@@ -13,6 +13,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/benthosdev/benthos/v4/public/service"
 	"github.com/benthosdev/benthos/v4/public/service/integration"
 )
 
@@ -73,7 +74,7 @@ interval: 1s
 url: `+url, nil)
 	require.NoError(t, err)
 
-	rl, err := newRedisRatelimitFromConfig(conf)
+	rl, err := newRedisRatelimitFromConfig(conf, service.MockResources())
 	require.NoError(t, err)
 
 	ctx := context.Background()
@@ -101,7 +102,7 @@ interval: 100ms
 url: `+url, nil)
 	require.NoError(t, err)
 
-	rl, err := newRedisRatelimitFromConfig(conf)
+	rl, err := newRedisRatelimitFromConfig(conf, service.MockResources())
 	require.NoError(t, err)
 
 	ctx := context.Background()
@@ -4,6 +4,8 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
 )
 
 func TestRedisRateLimitConfErrors(t *testing.T) {
@@ -13,7 +15,7 @@ count: -1
 key: asdf`, nil)
 	require.NoError(t, err)
 
-	_, err = newRedisRatelimitFromConfig(conf)
+	_, err = newRedisRatelimitFromConfig(conf, service.MockResources())
 	require.Error(t, err)
 
 	_, err = redisRatelimitConfig().ParseYAML(`
@@ -22,7 +24,7 @@ interval: nope
 key: asdf`, nil)
 	require.NoError(t, err)
 
-	_, err = newRedisRatelimitFromConfig(conf)
+	_, err = newRedisRatelimitFromConfig(conf, service.MockResources())
 	require.Error(t, err)
 
 	_, err = redisRatelimitConfig().ParseYAML(`key: asdf`, nil)
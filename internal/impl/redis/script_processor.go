@@ -93,7 +93,7 @@ type redisScriptProc struct {
 }
 
 func newRedisScriptProcFromConfig(conf *service.ParsedConfig, res *service.Resources) (*redisScriptProc, error) {
-	client, err := getClient(conf)
+	client, err := getClient(conf, res)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,287 @@
+package search
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+
+	"github.com/benthosdev/benthos/v4/internal/impl/pure"
+	"github.com/benthosdev/benthos/v4/public/bloblang"
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	// Common fields of search index outputs
+	siFieldURL      = "url"
+	siFieldAPIKey   = "api_key"
+	siFieldAction   = "action"
+	siFieldDocument = "document_mapping"
+	siFieldTimeout  = "timeout"
+	siFieldTLS      = "tls"
+	siFieldBatching = "batching"
+)
+
+func documentField() *service.ConfigField {
+	return service.NewBloblangField(siFieldDocument).
+		Description("A [Bloblang mapping](/docs/guides/bloblang/about) that results in the document of each message, which must be an object.").
+		Example(`root = this.without("internal")`).
+		Default("root = this")
+}
+
+func httpFields() []*service.ConfigField {
+	return append([]*service.ConfigField{
+		service.NewDurationField(siFieldTimeout).
+			Description("The maximum period to wait for a request to complete.").
+			Advanced().
+			Default("30s"),
+		service.NewTLSToggledField(siFieldTLS),
+		service.NewOutputMaxInFlightField(),
+		service.NewBatchPolicyField(siFieldBatching),
+	}, pure.CommonRetryBackOffFields(3, "500ms", "10s", "1m")...)
+}
+
+func registerOutput(name string, spec *service.ConfigSpec, ctor func(conf *service.ParsedConfig, mgr *service.Resources) (service.BatchOutput, error)) {
+	err := service.RegisterBatchOutput(name, spec,
+		func(conf *service.ParsedConfig, mgr *service.Resources) (out service.BatchOutput, batchPolicy service.BatchPolicy, maxInFlight int, err error) {
+			if maxInFlight, err = conf.FieldMaxInFlight(); err != nil {
+				return
+			}
+			if batchPolicy, err = conf.FieldBatchPolicy(siFieldBatching); err != nil {
+				return
+			}
+			out, err = ctor(conf, mgr)
+			return
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+// documentFromMessage resolves the document of a message, which must be an
+// object.
+func documentFromMessage(batch service.MessageBatch, i int, mapping *bloblang.Executor) (map[string]any, error) {
+	resMsg, err := batch.BloblangQuery(i, mapping)
+	if err != nil {
+		return nil, fmt.Errorf("document mapping failed: %w", err)
+	}
+	var v any
+	if resMsg != nil {
+		if v, err = resMsg.AsStructured(); err != nil {
+			return nil, fmt.Errorf("document mapping failed: %w", err)
+		}
+	}
+	doc, ok := v.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("expected document mapping to result in an object, got %T", v)
+	}
+	return doc, nil
+}
+
+//------------------------------------------------------------------------------
+
+// docGroup is a group of documents written with the same request, such as
+// documents of the same collection and action.
+type docGroup struct {
+	target string
+	action string
+	docs   []map[string]any
+	index  []int
+}
+
+type groupKey struct {
+	target string
+	action string
+}
+
+// docGrouper groups the documents of a batch, tracking messages that could not
+// be written.
+type docGrouper struct {
+	batch    service.MessageBatch
+	batchErr *service.BatchError
+	groups   []*docGroup
+	byKey    map[groupKey]*docGroup
+}
+
+func newDocGrouper(batch service.MessageBatch) *docGrouper {
+	return &docGrouper{
+		batch: batch,
+		byKey: map[groupKey]*docGroup{},
+	}
+}
+
+func (g *docGrouper) add(target, action string, i int, doc map[string]any) {
+	k := groupKey{target: target, action: action}
+	dg, exists := g.byKey[k]
+	if !exists {
+		dg = &docGroup{target: target, action: action}
+		g.byKey[k] = dg
+		g.groups = append(g.groups, dg)
+	}
+	dg.docs = append(dg.docs, doc)
+	dg.index = append(dg.index, i)
+}
+
+func (g *docGrouper) fail(i int, err error) {
+	if g.batchErr == nil {
+		g.batchErr = service.NewBatchError(g.batch, err)
+	}
+	g.batchErr.Failed(i, err)
+}
+
+// write writes each group of documents, where the write function reports
+// errors of individual documents by their position within the group. When any
+// messages fail a batch error is returned that marks only those messages as
+// failed.
+func (g *docGrouper) write(ctx context.Context, fn func(ctx context.Context, dg *docGroup) (docErrs map[int]error, err error)) error {
+	if len(g.batch) == 1 && g.batchErr != nil {
+		return g.batchErr.Unwrap()
+	}
+	for _, dg := range g.groups {
+		docErrs, err := fn(ctx, dg)
+		if err != nil {
+			if len(g.groups) == 1 && g.batchErr == nil {
+				return err
+			}
+			for _, i := range dg.index {
+				g.fail(i, err)
+			}
+			continue
+		}
+		for j, dErr := range docErrs {
+			if len(g.batch) == 1 {
+				return dErr
+			}
+			g.fail(dg.index[j], dErr)
+		}
+	}
+	if g.batchErr != nil {
+		return g.batchErr
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+type statusError struct {
+	status     int
+	body       []byte
+	retryAfter time.Duration
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("request failed with status %v: %s", e.status, bytes.TrimSpace(e.body))
+}
+
+func (e *statusError) retryable() bool {
+	return e.status == http.StatusTooManyRequests || e.status >= 500
+}
+
+// apiClient sends requests to a search engine, retrying requests that fail due
+// to connection errors, rate limits or server errors.
+type apiClient struct {
+	log         *service.Logger
+	client      *http.Client
+	headers     map[string]string
+	backoffCtor func() backoff.BackOff
+}
+
+func apiClientFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*apiClient, error) {
+	c := &apiClient{
+		log:     mgr.Logger(),
+		headers: map[string]string{},
+	}
+
+	timeout, err := conf.FieldDuration(siFieldTimeout)
+	if err != nil {
+		return nil, err
+	}
+	tlsConf, tlsEnabled, err := conf.FieldTLSToggled(siFieldTLS)
+	if err != nil {
+		return nil, err
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if tlsEnabled {
+		transport.TLSClientConfig = tlsConf
+	}
+	c.client = &http.Client{Timeout: timeout, Transport: transport}
+
+	if c.backoffCtor, err = pure.CommonRetryBackOffCtorFromParsed(conf); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// do sends a request and returns the body of a successful response.
+func (c *apiClient) do(ctx context.Context, method, reqURL, contentType string, body []byte) ([]byte, error) {
+	boff := c.backoffCtor()
+	for {
+		res, err := c.doOnce(ctx, method, reqURL, contentType, body)
+		if err == nil {
+			return res, nil
+		}
+
+		var sErr *statusError
+		if errors.As(err, &sErr) && !sErr.retryable() {
+			return nil, err
+		}
+
+		wait := boff.NextBackOff()
+		if wait == backoff.Stop {
+			return nil, err
+		}
+		if sErr != nil && sErr.retryAfter > wait {
+			wait = sErr.retryAfter
+		}
+		c.log.Warnf("Retrying request in %v: %v", wait, err)
+
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+func (c *apiClient) doOnce(ctx context.Context, method, reqURL, contentType string, body []byte) ([]byte, error) {
+	var bodyReader io.Reader
+	if body != nil {
+		bodyReader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, reqURL, bodyReader)
+	if err != nil {
+		return nil, err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	for k, v := range c.headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		resBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		sErr := &statusError{status: resp.StatusCode, body: resBody}
+		if secs, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && secs > 0 {
+			sErr.retryAfter = time.Duration(secs) * time.Second
+		}
+		return nil, sErr
+	}
+	return io.ReadAll(resp.Body)
+}
+
+func (c *apiClient) close() {
+	c.client.CloseIdleConnections()
+}
@@ -0,0 +1,293 @@
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/benthosdev/benthos/v4/public/bloblang"
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	msFieldIndex        = "index"
+	msFieldPrimaryKey   = "primary_key"
+	msFieldWaitForTasks = "wait_for_tasks"
+
+	meiliActionReplace = "add_or_replace"
+	meiliActionUpdate  = "add_or_update"
+	meiliActionDelete  = "delete"
+)
+
+func meilisearchOutputSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Services").
+		Version("4.28.0").
+		Summary("Writes documents to [Meilisearch](https://www.meilisearch.com/) indexes.").
+		Description(`
+Each message is converted into a document with the `+"`document_mapping`"+`, and the `+"`action`"+` determines how the document is written:
+
+- `+"`add_or_replace`"+` adds documents, replacing any existing documents with the same primary key.
+- `+"`add_or_update`"+` adds documents, updating the fields of any existing documents with the same primary key.
+- `+"`delete`"+` deletes documents by the value of their primary key field.
+
+### Batching
+
+Documents of a batch are grouped by their index and action, and each group is sent as a single request. Documents that are missing their primary key field are marked as failed before sending without affecting the other messages of the batch.
+
+Meilisearch processes writes asynchronously as tasks. When `+"`wait_for_tasks`"+` is `+"`true`"+` (the default) messages are only acknowledged once their task has succeeded, and when a task fails all messages of its group are marked as failed. Otherwise messages are acknowledged as soon as the task is enqueued.
+
+Requests that fail due to connection errors, rate limits or server errors are retried using the backoff fields.`+service.OutputPerformanceDocs(true, true)).
+		Fields(
+			service.NewStringField(siFieldURL).
+				Description("The base URL of the Meilisearch server.").
+				Example("http://localhost:7700"),
+			service.NewStringField(siFieldAPIKey).
+				Description("An API key to authenticate with.").
+				Secret().
+				Default(""),
+			service.NewInterpolatedStringField(msFieldIndex).
+				Description("The index to write documents to.").
+				Example("movies").
+				Example(`${! meta("index") }`),
+			service.NewInterpolatedStringField(siFieldAction).
+				Description("The action to take on each document, which must be one of `add_or_replace`, `add_or_update` or `delete`.").
+				Example(`${! if meta("deleted") == "true" { "delete" } else { "add_or_replace" } }`).
+				Default(meiliActionReplace),
+			service.NewStringField(msFieldPrimaryKey).
+				Description("The primary key field of documents. When set documents without this field are rejected, and the primary key of new indexes is set to this field. When empty the primary key of the index is inferred by Meilisearch, and `id` is used to identify documents to delete.").
+				Default(""),
+			documentField(),
+			service.NewBoolField(msFieldWaitForTasks).
+				Description("Whether to wait for the tasks of writes to succeed before acknowledging messages.").
+				Default(true).
+				Advanced(),
+		).
+		Fields(httpFields()...).
+		Example("Index Products", "Keep an index of products up to date, removing products that are discontinued.", `
+output:
+  meilisearch:
+    url: http://localhost:7700
+    api_key: ${MEILI_MASTER_KEY}
+    index: products
+    primary_key: sku
+    action: ${! if this.discontinued { "delete" } else { "add_or_update" } }
+    document_mapping: root = this.without("discontinued")
+    batching:
+      count: 1000
+      period: 1s
+`)
+}
+
+func init() {
+	registerOutput("meilisearch", meilisearchOutputSpec(), func(conf *service.ParsedConfig, mgr *service.Resources) (service.BatchOutput, error) {
+		return newMeilisearchOutputFromParsed(conf, mgr)
+	})
+}
+
+type meilisearchOutput struct {
+	baseURL      string
+	index        *service.InterpolatedString
+	action       *service.InterpolatedString
+	primaryKey   string
+	document     *bloblang.Executor
+	waitForTasks bool
+
+	taskPollInterval time.Duration
+
+	client *apiClient
+}
+
+func newMeilisearchOutputFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*meilisearchOutput, error) {
+	m := &meilisearchOutput{
+		taskPollInterval: 100 * time.Millisecond,
+	}
+
+	var err error
+	if m.baseURL, err = conf.FieldString(siFieldURL); err != nil {
+		return nil, err
+	}
+	m.baseURL = strings.TrimSuffix(m.baseURL, "/")
+	if m.index, err = conf.FieldInterpolatedString(msFieldIndex); err != nil {
+		return nil, err
+	}
+	if m.action, err = conf.FieldInterpolatedString(siFieldAction); err != nil {
+		return nil, err
+	}
+	if m.primaryKey, err = conf.FieldString(msFieldPrimaryKey); err != nil {
+		return nil, err
+	}
+	if m.document, err = conf.FieldBloblang(siFieldDocument); err != nil {
+		return nil, err
+	}
+	if m.waitForTasks, err = conf.FieldBool(msFieldWaitForTasks); err != nil {
+		return nil, err
+	}
+
+	if m.client, err = apiClientFromParsed(conf, mgr); err != nil {
+		return nil, err
+	}
+	apiKey, err := conf.FieldString(siFieldAPIKey)
+	if err != nil {
+		return nil, err
+	}
+	if apiKey != "" {
+		m.client.headers["Authorization"] = "Bearer " + apiKey
+	}
+	return m, nil
+}
+
+func (m *meilisearchOutput) Connect(ctx context.Context) error {
+	return nil
+}
+
+func (m *meilisearchOutput) resolve(batch service.MessageBatch, i int) (index, action string, doc map[string]any, err error) {
+	if index, err = batch.TryInterpolatedString(i, m.index); err != nil {
+		err = fmt.Errorf("index interpolation failed: %w", err)
+		return
+	}
+	if index == "" {
+		err = errors.New("index interpolation resulted in an empty string")
+		return
+	}
+	if action, err = batch.TryInterpolatedString(i, m.action); err != nil {
+		err = fmt.Errorf("action interpolation failed: %w", err)
+		return
+	}
+	switch action {
+	case meiliActionReplace, meiliActionUpdate, meiliActionDelete:
+	default:
+		err = fmt.Errorf("action %q is not supported", action)
+		return
+	}
+	if doc, err = documentFromMessage(batch, i, m.document); err != nil {
+		return
+	}
+
+	keyField := m.primaryKey
+	if keyField == "" && action == meiliActionDelete {
+		keyField = "id"
+	}
+	if keyField != "" {
+		if _, exists := doc[keyField]; !exists {
+			err = fmt.Errorf("document is missing primary key field %v", keyField)
+		}
+	}
+	return
+}
+
+func (m *meilisearchOutput) WriteBatch(ctx context.Context, batch service.MessageBatch) error {
+	grouper := newDocGrouper(batch)
+	for i := range batch {
+		index, action, doc, err := m.resolve(batch, i)
+		if err != nil {
+			grouper.fail(i, err)
+			continue
+		}
+		grouper.add(index, action, i, doc)
+	}
+	return grouper.write(ctx, m.writeGroup)
+}
+
+// meiliTask is the subset of fields of a task relevant to awaiting it.
+type meiliTask struct {
+	TaskUID int64  `json:"taskUid"`
+	Status  string `json:"status"`
+	Error   *struct {
+		Message string `json:"message"`
+		Code    string `json:"code"`
+	} `json:"error"`
+}
+
+func (m *meilisearchOutput) writeGroup(ctx context.Context, dg *docGroup) (map[int]error, error) {
+	indexURL := fmt.Sprintf("%v/indexes/%v/documents", m.baseURL, url.PathEscape(dg.target))
+
+	var method, reqURL string
+	var body any
+	switch dg.action {
+	case meiliActionDelete:
+		keyField := m.primaryKey
+		if keyField == "" {
+			keyField = "id"
+		}
+		ids := make([]any, len(dg.docs))
+		for i, doc := range dg.docs {
+			ids[i] = doc[keyField]
+		}
+		method, reqURL, body = http.MethodPost, indexURL+"/delete-batch", ids
+	default:
+		method = http.MethodPost
+		if dg.action == meiliActionUpdate {
+			method = http.MethodPut
+		}
+		reqURL = indexURL
+		if m.primaryKey != "" {
+			reqURL += "?primaryKey=" + url.QueryEscape(m.primaryKey)
+		}
+		body = dg.docs
+	}
+
+	reqBody, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	res, err := m.client.do(ctx, method, reqURL, "application/json", reqBody)
+	if err != nil {
+		return nil, err
+	}
+
+	var task meiliTask
+	if err := json.Unmarshal(res, &task); err != nil {
+		return nil, fmt.Errorf("failed to parse task: %w", err)
+	}
+	if !m.waitForTasks {
+		return nil, nil
+	}
+	return nil, m.awaitTask(ctx, task.TaskUID)
+}
+
+// awaitTask polls a task until it has been processed, returning an error when
+// the task did not succeed.
+func (m *meilisearchOutput) awaitTask(ctx context.Context, uid int64) error {
+	taskURL := fmt.Sprintf("%v/tasks/%v", m.baseURL, uid)
+	for {
+		res, err := m.client.do(ctx, http.MethodGet, taskURL, "", nil)
+		if err != nil {
+			return fmt.Errorf("failed to get status of task %v: %w", uid, err)
+		}
+
+		var task meiliTask
+		if err := json.Unmarshal(res, &task); err != nil {
+			return fmt.Errorf("failed to parse task: %w", err)
+		}
+
+		switch task.Status {
+		case "succeeded":
+			return nil
+		case "failed":
+			if task.Error != nil {
+				return fmt.Errorf("task %v failed: %v (%v)", uid, task.Error.Message, task.Error.Code)
+			}
+			return fmt.Errorf("task %v failed", uid)
+		case "canceled":
+			return fmt.Errorf("task %v was canceled", uid)
+		}
+
+		select {
+		case <-time.After(m.taskPollInterval):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (m *meilisearchOutput) Close(ctx context.Context) error {
+	m.client.close()
+	return nil
+}
@@ -0,0 +1,111 @@
+package search
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func testMeilisearchOutput(t *testing.T, conf string) *meilisearchOutput {
+	t.Helper()
+
+	pConf, err := meilisearchOutputSpec().ParseYAML(conf, nil)
+	require.NoError(t, err)
+
+	out, err := newMeilisearchOutputFromParsed(pConf, service.MockResources())
+	require.NoError(t, err)
+
+	out.taskPollInterval = time.Millisecond
+	return out
+}
+
+func TestMeilisearchWrites(t *testing.T) {
+	var polls atomic.Int32
+	srv, reqs := testServer(t, func(r recordedRequest) (int, string) {
+		if r.method == http.MethodGet {
+			if polls.Add(1) < 2 {
+				return http.StatusOK, `{"uid":1,"status":"processing"}`
+			}
+			return http.StatusOK, `{"uid":1,"status":"succeeded"}`
+		}
+		return http.StatusAccepted, `{"taskUid":1,"status":"enqueued"}`
+	})
+
+	out := testMeilisearchOutput(t, `
+url: `+srv.URL+`
+api_key: foo
+index: products
+primary_key: sku
+action: ${! json("action") }
+document_mapping: root = this.without("action")
+`)
+
+	batch := service.MessageBatch{
+		service.NewMessage([]byte(`{"action":"add_or_update","sku":"a","price":1}`)),
+		service.NewMessage([]byte(`{"action":"add_or_update","price":2}`)),
+		service.NewMessage([]byte(`{"action":"delete","sku":"c"}`)),
+		service.NewMessage([]byte(`{"action":"upsert","sku":"d"}`)),
+	}
+	idx := batch.Index()
+	err := out.WriteBatch(context.Background(), batch)
+	assert.Equal(t, []int{1, 3}, failedIndexes(t, idx, err))
+
+	var writes []recordedRequest
+	for _, r := range reqs() {
+		if r.method != http.MethodGet {
+			writes = append(writes, r)
+		}
+	}
+	require.Len(t, writes, 2)
+
+	assert.Equal(t, http.MethodPut, writes[0].method)
+	assert.Equal(t, "/indexes/products/documents", writes[0].path)
+	assert.Equal(t, "primaryKey=sku", writes[0].query)
+	assert.Equal(t, "Bearer foo", writes[0].header.Get("Authorization"))
+	assert.JSONEq(t, `[{"sku":"a","price":1}]`, writes[0].body)
+
+	assert.Equal(t, http.MethodPost, writes[1].method)
+	assert.Equal(t, "/indexes/products/documents/delete-batch", writes[1].path)
+	assert.JSONEq(t, `["c"]`, writes[1].body)
+
+	assert.GreaterOrEqual(t, polls.Load(), int32(3))
+}
+
+func TestMeilisearchTaskFailure(t *testing.T) {
+	srv, reqs := testServer(t, func(r recordedRequest) (int, string) {
+		if r.method == http.MethodGet {
+			return http.StatusOK, `{"uid":7,"status":"failed","error":{"message":"The primary key inference failed","code":"index_primary_key_no_candidate_found"}}`
+		}
+		return http.StatusAccepted, `{"taskUid":7,"status":"enqueued"}`
+	})
+
+	out := testMeilisearchOutput(t, `
+url: `+srv.URL+`
+index: movies
+`)
+
+	err := out.WriteBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte(`{"title":"foo"}`)),
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "index_primary_key_no_candidate_found")
+
+	r := reqs()
+	require.Len(t, r, 2)
+	assert.Equal(t, http.MethodPost, r[0].method)
+	assert.Equal(t, "", r[0].query)
+	assert.Equal(t, "/tasks/7", r[1].path)
+
+	out.waitForTasks = false
+	require.NoError(t, out.WriteBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte(`{"title":"foo"}`)),
+	}))
+	assert.Len(t, reqs(), 3)
+}
@@ -0,0 +1,243 @@
+package search
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/benthosdev/benthos/v4/public/bloblang"
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	tsFieldCollection = "collection"
+)
+
+var typesenseActions = map[string]bool{
+	"create":  true,
+	"upsert":  true,
+	"update":  true,
+	"emplace": true,
+	"delete":  true,
+}
+
+func typesenseOutputSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Services").
+		Version("4.28.0").
+		Summary("Writes documents to [Typesense](https://typesense.org/) collections.").
+		Description(`
+Each message is converted into a document with the `+"`document_mapping`"+`, and the `+"`action`"+` determines how the document is written. Documents are identified by their `+"`id`"+` field, which is required when deleting documents.
+
+### Batching
+
+Documents of a batch are grouped by their collection and action. Each group of writes is sent as a single request to the import API, and each group of deletes as a single request that deletes documents by their IDs.
+
+The import API reports the result of each document individually, and so documents that are rejected, for example due to not matching the schema of the collection, are marked as failed without affecting the other messages of the batch. When the request for a group fails entirely all of its messages are marked as failed.
+
+Requests that fail due to connection errors, rate limits or server errors are retried using the backoff fields.`+service.OutputPerformanceDocs(true, true)).
+		Fields(
+			service.NewStringField(siFieldURL).
+				Description("The base URL of the Typesense server.").
+				Example("http://localhost:8108"),
+			service.NewStringField(siFieldAPIKey).
+				Description("The API key to authenticate with.").
+				Secret(),
+			service.NewInterpolatedStringField(tsFieldCollection).
+				Description("The collection to write documents to.").
+				Example("products").
+				Example(`${! meta("collection") }`),
+			service.NewInterpolatedStringField(siFieldAction).
+				Description("The action to take on each document, which must be one of `create`, `upsert`, `update`, `emplace` or `delete`.").
+				Example(`${! if meta("deleted") == "true" { "delete" } else { "upsert" } }`).
+				Default("upsert"),
+			documentField(),
+		).
+		Fields(httpFields()...).
+		Example("Change Data Capture", "Maintain a collection from a stream of changes, where deleted rows remove their documents.", `
+output:
+  typesense:
+    url: http://localhost:8108
+    api_key: ${TYPESENSE_API_KEY}
+    collection: products
+    action: ${! if this.op == "d" { "delete" } else { "upsert" } }
+    document_mapping: |
+      root = this.row
+      root.id = this.row.id.string()
+    batching:
+      count: 500
+      period: 1s
+`)
+}
+
+func init() {
+	registerOutput("typesense", typesenseOutputSpec(), func(conf *service.ParsedConfig, mgr *service.Resources) (service.BatchOutput, error) {
+		return newTypesenseOutputFromParsed(conf, mgr)
+	})
+}
+
+type typesenseOutput struct {
+	baseURL    string
+	collection *service.InterpolatedString
+	action     *service.InterpolatedString
+	document   *bloblang.Executor
+
+	client *apiClient
+}
+
+func newTypesenseOutputFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*typesenseOutput, error) {
+	t := &typesenseOutput{}
+
+	var err error
+	if t.baseURL, err = conf.FieldString(siFieldURL); err != nil {
+		return nil, err
+	}
+	t.baseURL = strings.TrimSuffix(t.baseURL, "/")
+	if t.collection, err = conf.FieldInterpolatedString(tsFieldCollection); err != nil {
+		return nil, err
+	}
+	if t.action, err = conf.FieldInterpolatedString(siFieldAction); err != nil {
+		return nil, err
+	}
+	if t.document, err = conf.FieldBloblang(siFieldDocument); err != nil {
+		return nil, err
+	}
+
+	if t.client, err = apiClientFromParsed(conf, mgr); err != nil {
+		return nil, err
+	}
+	apiKey, err := conf.FieldString(siFieldAPIKey)
+	if err != nil {
+		return nil, err
+	}
+	t.client.headers["X-TYPESENSE-API-KEY"] = apiKey
+	return t, nil
+}
+
+func (t *typesenseOutput) Connect(ctx context.Context) error {
+	return nil
+}
+
+func (t *typesenseOutput) resolve(batch service.MessageBatch, i int) (collection, action string, doc map[string]any, err error) {
+	if collection, err = batch.TryInterpolatedString(i, t.collection); err != nil {
+		err = fmt.Errorf("collection interpolation failed: %w", err)
+		return
+	}
+	if collection == "" {
+		err = errors.New("collection interpolation resulted in an empty string")
+		return
+	}
+	if action, err = batch.TryInterpolatedString(i, t.action); err != nil {
+		err = fmt.Errorf("action interpolation failed: %w", err)
+		return
+	}
+	if !typesenseActions[action] {
+		err = fmt.Errorf("action %q is not supported", action)
+		return
+	}
+	if doc, err = documentFromMessage(batch, i, t.document); err != nil {
+		return
+	}
+	if action == "delete" {
+		id, _ := doc["id"].(string)
+		if id == "" {
+			err = errors.New("documents to delete must have a string id field")
+		} else if strings.Contains(id, "`") {
+			err = fmt.Errorf("document id %q cannot be deleted as it contains a backtick", id)
+		}
+	}
+	return
+}
+
+func (t *typesenseOutput) WriteBatch(ctx context.Context, batch service.MessageBatch) error {
+	grouper := newDocGrouper(batch)
+	for i := range batch {
+		collection, action, doc, err := t.resolve(batch, i)
+		if err != nil {
+			grouper.fail(i, err)
+			continue
+		}
+		grouper.add(collection, action, i, doc)
+	}
+	return grouper.write(ctx, t.writeGroup)
+}
+
+func (t *typesenseOutput) writeGroup(ctx context.Context, dg *docGroup) (map[int]error, error) {
+	if dg.action == "delete" {
+		return nil, t.deleteDocs(ctx, dg)
+	}
+	return t.importDocs(ctx, dg)
+}
+
+// typesenseImportResult is the result of importing a single document.
+type typesenseImportResult struct {
+	Success bool   `json:"success"`
+	Error   string `json:"error"`
+}
+
+func (t *typesenseOutput) importDocs(ctx context.Context, dg *docGroup) (map[int]error, error) {
+	var body bytes.Buffer
+	enc := json.NewEncoder(&body)
+	for _, doc := range dg.docs {
+		if err := enc.Encode(doc); err != nil {
+			return nil, err
+		}
+	}
+
+	reqURL := fmt.Sprintf("%v/collections/%v/documents/import?action=%v", t.baseURL, url.PathEscape(dg.target), dg.action)
+	res, err := t.client.do(ctx, http.MethodPost, reqURL, "text/plain", body.Bytes())
+	if err != nil {
+		return nil, err
+	}
+
+	// The response contains a line with the result of each document, in the
+	// order they were sent.
+	docErrs := map[int]error{}
+
+	var n int
+	for _, line := range bytes.Split(res, []byte("\n")) {
+		if line = bytes.TrimSpace(line); len(line) == 0 {
+			continue
+		}
+		if n >= len(dg.docs) {
+			return nil, fmt.Errorf("import returned more results than the %v documents sent", len(dg.docs))
+		}
+		var r typesenseImportResult
+		if err := json.Unmarshal(line, &r); err != nil {
+			return nil, fmt.Errorf("failed to parse import result: %w", err)
+		}
+		if !r.Success {
+			docErrs[n] = fmt.Errorf("failed to import document: %v", r.Error)
+		}
+		n++
+	}
+	if n != len(dg.docs) {
+		return nil, fmt.Errorf("import returned %v results for %v documents", n, len(dg.docs))
+	}
+	return docErrs, nil
+}
+
+func (t *typesenseOutput) deleteDocs(ctx context.Context, dg *docGroup) error {
+	ids := make([]string, len(dg.docs))
+	for i, doc := range dg.docs {
+		ids[i] = "`" + doc["id"].(string) + "`"
+	}
+
+	query := url.Values{}
+	query.Set("filter_by", "id:["+strings.Join(ids, ",")+"]")
+	reqURL := fmt.Sprintf("%v/collections/%v/documents?%v", t.baseURL, url.PathEscape(dg.target), query.Encode())
+
+	_, err := t.client.do(ctx, http.MethodDelete, reqURL, "", nil)
+	return err
+}
+
+func (t *typesenseOutput) Close(ctx context.Context) error {
+	t.client.close()
+	return nil
+}
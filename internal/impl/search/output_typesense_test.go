@@ -0,0 +1,174 @@
+package search
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+type recordedRequest struct {
+	method string
+	path   string
+	query  string
+	header http.Header
+	body   string
+}
+
+// testServer records requests and responds with the result of a handler.
+func testServer(t *testing.T, fn func(r recordedRequest) (int, string)) (*httptest.Server, func() []recordedRequest) {
+	t.Helper()
+
+	var mut sync.Mutex
+	var reqs []recordedRequest
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+
+		rec := recordedRequest{
+			method: r.Method,
+			path:   r.URL.Path,
+			query:  r.URL.Query().Encode(),
+			header: r.Header,
+			body:   string(b),
+		}
+
+		mut.Lock()
+		reqs = append(reqs, rec)
+		mut.Unlock()
+
+		status, body := fn(rec)
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv, func() []recordedRequest {
+		mut.Lock()
+		defer mut.Unlock()
+		return reqs
+	}
+}
+
+// failedIndexes returns the indexes of messages that failed according to a
+// batch error, where the indexer must be created before the batch is written.
+func failedIndexes(t *testing.T, idx *service.Indexer, err error) []int {
+	t.Helper()
+
+	var bErr *service.BatchError
+	require.ErrorAs(t, err, &bErr)
+
+	var failed []int
+	bErr.WalkMessagesIndexedBy(idx, func(i int, _ *service.Message, err error) bool {
+		if err != nil {
+			failed = append(failed, i)
+		}
+		return true
+	})
+	sort.Ints(failed)
+	return failed
+}
+
+func testTypesenseOutput(t *testing.T, conf string) *typesenseOutput {
+	t.Helper()
+
+	pConf, err := typesenseOutputSpec().ParseYAML(conf, nil)
+	require.NoError(t, err)
+
+	out, err := newTypesenseOutputFromParsed(pConf, service.MockResources())
+	require.NoError(t, err)
+	return out
+}
+
+func TestTypesenseImport(t *testing.T) {
+	srv, reqs := testServer(t, func(r recordedRequest) (int, string) {
+		if r.method == http.MethodDelete {
+			return http.StatusOK, `{"num_deleted":1}`
+		}
+		var results []string
+		for _, line := range strings.Split(strings.TrimSpace(r.body), "\n") {
+			if strings.Contains(line, `"bad"`) {
+				results = append(results, `{"success":false,"error":"Field price must be a float.","document":"{}"}`)
+			} else {
+				results = append(results, `{"success":true}`)
+			}
+		}
+		return http.StatusOK, strings.Join(results, "\n")
+	})
+
+	out := testTypesenseOutput(t, `
+url: `+srv.URL+`
+api_key: foo
+collection: products
+action: ${! json("action") }
+document_mapping: root = this.doc
+`)
+
+	batch := service.MessageBatch{
+		service.NewMessage([]byte(`{"action":"upsert","doc":{"id":"a","price":1.5}}`)),
+		service.NewMessage([]byte(`{"action":"upsert","doc":{"id":"b","price":"bad"}}`)),
+		service.NewMessage([]byte(`{"action":"delete","doc":{"id":"c"}}`)),
+		service.NewMessage([]byte(`{"action":"nope","doc":{"id":"d"}}`)),
+		service.NewMessage([]byte(`{"action":"delete","doc":{"name":"e"}}`)),
+		service.NewMessage([]byte(`{"action":"upsert","doc":{"id":"f","price":2}}`)),
+	}
+	idx := batch.Index()
+	err := out.WriteBatch(context.Background(), batch)
+	assert.Equal(t, []int{1, 3, 4}, failedIndexes(t, idx, err))
+
+	r := reqs()
+	require.Len(t, r, 2)
+
+	assert.Equal(t, http.MethodPost, r[0].method)
+	assert.Equal(t, "/collections/products/documents/import", r[0].path)
+	assert.Equal(t, "action=upsert", r[0].query)
+	assert.Equal(t, "foo", r[0].header.Get("X-TYPESENSE-API-KEY"))
+	assert.Equal(t, `{"id":"a","price":1.5}
+{"id":"b","price":"bad"}
+{"id":"f","price":2}
+`, r[0].body)
+
+	assert.Equal(t, http.MethodDelete, r[1].method)
+	assert.Equal(t, "/collections/products/documents", r[1].path)
+	assert.Equal(t, "filter_by=id%3A%5B%60c%60%5D", r[1].query)
+}
+
+func TestTypesenseRequestErrors(t *testing.T) {
+	srv, _ := testServer(t, func(r recordedRequest) (int, string) {
+		if strings.Contains(r.path, "missing") {
+			return http.StatusNotFound, `{"message":"Collection not found"}`
+		}
+		return http.StatusOK, `{"success":true}`
+	})
+
+	out := testTypesenseOutput(t, `
+url: `+srv.URL+`
+api_key: foo
+collection: ${! json("coll") }
+`)
+
+	batch := service.MessageBatch{
+		service.NewMessage([]byte(`{"coll":"missing","id":"a"}`)),
+		service.NewMessage([]byte(`{"coll":"products","id":"b"}`)),
+		service.NewMessage([]byte(`{"coll":"missing","id":"c"}`)),
+	}
+	idx := batch.Index()
+	err := out.WriteBatch(context.Background(), batch)
+	assert.Equal(t, []int{0, 2}, failedIndexes(t, idx, err))
+
+	err = out.WriteBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte(`{"coll":"missing","id":"a"}`)),
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Collection not found")
+}
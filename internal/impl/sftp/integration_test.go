@@ -3,6 +3,7 @@ package sftp
 import (
 	"io/fs"
 	"os"
+	"strings"
 	"testing"
 	"time"
 
@@ -112,6 +113,25 @@ cache_resources:
 				integration.StreamTestOptVarSet("VAR2", "true"),
 			)
 		})
+
+		t.Run("atomic", func(t *testing.T) {
+			atomicTemplate := strings.Replace(template, "    max_in_flight: 1", `    max_in_flight: 1
+    atomic_upload:
+      enabled: true
+      resume: true
+    verify: checksum`, 1)
+
+			atomicSuite := integration.StreamTests(
+				integration.StreamTestOpenClose(),
+				integration.StreamTestStreamParallel(50),
+			)
+			atomicSuite.Run(
+				t, atomicTemplate,
+				integration.StreamTestOptPort(resource.GetPort("22/tcp")),
+				integration.StreamTestOptVarSet("VAR1", "all-bytes"),
+				integration.StreamTestOptVarSet("VAR2", "true"),
+			)
+		})
 	})
 }
 
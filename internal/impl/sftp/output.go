@@ -1,7 +1,9 @@
 package sftp
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
 	"errors"
 	"fmt"
 	"io"
@@ -16,9 +18,20 @@ import (
 )
 
 const (
-	soFieldAddress     = "address"
-	soFieldCredentials = "credentials"
-	soFieldPath        = "path"
+	soFieldAddress          = "address"
+	soFieldCredentials      = "credentials"
+	soFieldPath             = "path"
+	soFieldAtomic           = "atomic_upload"
+	soFieldAtomicEnabled    = "enabled"
+	soFieldAtomicTempSuffix = "temp_suffix"
+	soFieldAtomicResume     = "resume"
+	soFieldVerify           = "verify"
+)
+
+const (
+	verifyNone     = "none"
+	verifySize     = "size"
+	verifyChecksum = "checksum"
 )
 
 func sftpOutputSpec() *service.ConfigSpec {
@@ -27,7 +40,20 @@ func sftpOutputSpec() *service.ConfigSpec {
 		Categories("Network").
 		Version("3.39.0").
 		Summary(`Writes files to an SFTP server.`).
-		Description(`In order to have a different path for each object you should use function interpolations described [here](/docs/configuration/interpolation#bloblang-queries).`+service.OutputPerformanceDocs(true, false)).
+		Description(`
+In order to have a different path for each object you should use function interpolations described [here](/docs/configuration/interpolation#bloblang-queries). Any missing directories of a path are created before the file is written.
+
+### Atomic Uploads
+
+When `+"`atomic_upload.enabled`"+` is `+"`true`"+` each file is first written to a temporary path, which is the target path with `+"`atomic_upload.temp_suffix`"+` appended, and is then renamed to the target path once the upload has completed. This prevents consumers of the server from reading partially written files, as long as they ignore files with the temporary suffix.
+
+If the server supports the `+"`posix-rename@openssh.com`"+` extension then an existing file at the target path is replaced atomically, otherwise the existing file is removed before the rename.
+
+When `+"`atomic_upload.resume`"+` is also `+"`true`"+` a temporary file left behind by a failed upload of the same path, for example due to a lost connection, is resumed from its current size rather than written again from the beginning.
+
+### Verification
+
+The `+"`verify`"+` field can be used to check that a file has been uploaded completely before a message is acknowledged. With `+"`size`"+` the size of the file on the server is compared with the size of the message, and with `+"`checksum`"+` the file is read back from the server and its SHA-256 checksum compared with that of the message. When verification fails the file is removed and the write is retried.`+service.OutputPerformanceDocs(true, false)).
 		Fields(
 			service.NewStringField(soFieldAddress).
 				Description("The address of the server to connect to."),
@@ -36,8 +62,45 @@ func sftpOutputSpec() *service.ConfigSpec {
 			service.NewInternalField(codec.NewWriterDocs("codec").HasDefault("all-bytes")),
 			service.NewObjectField(soFieldCredentials, credentialsFields()...).
 				Description("The credentials to use to log into the target server."),
+			service.NewObjectField(soFieldAtomic,
+				service.NewBoolField(soFieldAtomicEnabled).
+					Description("Whether to upload files to a temporary path and rename them once complete.").
+					Default(false),
+				service.NewStringField(soFieldAtomicTempSuffix).
+					Description("The suffix to append to the path of a file whilst it is being uploaded.").
+					Default(".tmp"),
+				service.NewBoolField(soFieldAtomicResume).
+					Description("Whether to resume the upload of a temporary file left behind by a previously failed upload.").
+					Default(false),
+			).
+				Description("Allows files to be uploaded to a temporary path and renamed once complete. This cannot be used with codecs that append messages to files.").
+				Version("4.28.0").
+				Advanced(),
+			service.NewStringAnnotatedEnumField(soFieldVerify, map[string]string{
+				verifyNone:     "Files are not verified.",
+				verifySize:     "The size of each uploaded file is compared with the size of the message.",
+				verifyChecksum: "Each uploaded file is read back from the server and its SHA-256 checksum compared with that of the message.",
+			}).
+				Description("How to verify files once they have been uploaded. This cannot be used with codecs that append messages to files.").
+				Version("4.28.0").
+				Advanced().
+				Default(verifyNone),
 			service.NewOutputMaxInFlightField(),
-		)
+		).
+		Example("Atomic Uploads", "Upload files to a temporary path and rename them once complete and verified, so that partners polling the server never see half-written files.", `
+output:
+  sftp:
+    address: sftp.example.com:22
+    path: /outbound/${! meta("batch_id") }.csv
+    credentials:
+      username: benthos
+      private_key_file: ./id_rsa
+    atomic_upload:
+      enabled: true
+      temp_suffix: .part
+      resume: true
+    verify: checksum
+`)
 }
 
 func init() {
@@ -67,6 +130,11 @@ type sftpWriter struct {
 	suffixFn   codec.SuffixFn
 	appendMode bool
 
+	atomic     bool
+	tempSuffix string
+	resume     bool
+	verify     string
+
 	handleMut  sync.Mutex
 	client     *sftp.Client
 	handlePath string
@@ -97,6 +165,26 @@ func newWriterFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (s
 		return
 	}
 
+	aConf := conf.Namespace(soFieldAtomic)
+	if s.atomic, err = aConf.FieldBool(soFieldAtomicEnabled); err != nil {
+		return
+	}
+	if s.tempSuffix, err = aConf.FieldString(soFieldAtomicTempSuffix); err != nil {
+		return
+	}
+	if s.resume, err = aConf.FieldBool(soFieldAtomicResume); err != nil {
+		return
+	}
+	if s.verify, err = conf.FieldString(soFieldVerify); err != nil {
+		return
+	}
+
+	if s.appendMode && (s.atomic || s.verify != verifyNone) {
+		return nil, fmt.Errorf("atomic uploads and verification cannot be used with the codec %v", codecStr)
+	}
+	if s.atomic && s.tempSuffix == "" {
+		return nil, errors.New("the temporary suffix of atomic uploads must not be empty")
+	}
 	return s, nil
 }
 
@@ -131,6 +219,18 @@ func (s *sftpWriter) writeTo(wtr io.Writer, p *service.Message) error {
 	return nil
 }
 
+// connErr checks whether an error indicates that the connection to the server
+// has been lost, in which case the client is dropped so that the next attempt
+// reconnects.
+func (s *sftpWriter) connErr(err error) error {
+	if errors.Is(err, sftp.ErrSshFxConnectionLost) {
+		_ = s.client.Close()
+		s.client = nil
+		return service.ErrNotConnected
+	}
+	return err
+}
+
 func (s *sftpWriter) Write(ctx context.Context, msg *service.Message) error {
 	s.handleMut.Lock()
 	defer s.handleMut.Unlock()
@@ -145,8 +245,13 @@ func (s *sftpWriter) Write(ctx context.Context, msg *service.Message) error {
 	}
 
 	if s.handle != nil && path == s.handlePath {
-		// TODO: Detect underlying connection failure here and drop client.
-		return s.writeTo(s.handle, msg)
+		if err := s.writeTo(s.handle, msg); err != nil {
+			_ = s.handle.Close()
+			s.handle = nil
+			s.handlePath = ""
+			return s.connErr(err)
+		}
+		return nil
 	}
 	if s.handle != nil {
 		if err := s.handle.Close(); err != nil {
@@ -156,42 +261,140 @@ func (s *sftpWriter) Write(ctx context.Context, msg *service.Message) error {
 		s.handlePath = ""
 	}
 
-	flag := os.O_CREATE | os.O_WRONLY
-	if s.appendMode {
-		flag |= os.O_APPEND
-	} else {
-		flag |= os.O_TRUNC
+	if err := s.client.MkdirAll(filepath.Dir(path)); err != nil {
+		return s.connErr(err)
 	}
 
-	if err := s.client.MkdirAll(filepath.Dir(path)); err != nil {
-		if errors.Is(err, sftp.ErrSshFxConnectionLost) {
-			return service.ErrNotConnected
+	if !s.appendMode {
+		var buf bytes.Buffer
+		if err := s.writeTo(&buf, msg); err != nil {
+			return err
+		}
+		return s.connErr(s.uploadFile(path, buf.Bytes()))
+	}
+
+	handle, err := s.client.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND)
+	if err != nil {
+		return s.connErr(err)
+	}
+
+	if err := s.writeTo(handle, msg); err != nil {
+		_ = handle.Close()
+		return s.connErr(err)
+	}
+
+	s.handle = handle
+	s.handlePath = path
+	return nil
+}
+
+// uploadFile writes the full contents of a file, via a temporary path when
+// atomic uploads are enabled, and verifies the result.
+func (s *sftpWriter) uploadFile(path string, data []byte) error {
+	if !s.atomic {
+		if err := s.writeFile(path, data, false); err != nil {
+			return err
+		}
+		return s.verifyFile(path, data)
+	}
+
+	tmpPath := path + s.tempSuffix
+	if err := s.writeFile(tmpPath, data, s.resume); err != nil {
+		if !s.resume && !errors.Is(err, sftp.ErrSshFxConnectionLost) {
+			_ = s.client.Remove(tmpPath)
 		}
 		return err
 	}
+	if err := s.verifyFile(tmpPath, data); err != nil {
+		return err
+	}
+	if err := s.renameFile(tmpPath, path); err != nil {
+		return fmt.Errorf("failed to rename %v to %v: %w", tmpPath, path, err)
+	}
+	return nil
+}
+
+// writeFile writes data to a file, replacing its contents. When resume is true
+// and the file already contains a prefix of the data then only the remainder
+// is written.
+func (s *sftpWriter) writeFile(path string, data []byte, resume bool) error {
+	var offset int64
+	if resume {
+		if info, err := s.client.Stat(path); err == nil && info.Size() <= int64(len(data)) {
+			offset = info.Size()
+		}
+	}
+
+	flag := os.O_CREATE | os.O_WRONLY
+	if offset == 0 {
+		flag |= os.O_TRUNC
+	}
 
 	handle, err := s.client.OpenFile(path, flag)
 	if err != nil {
-		if errors.Is(err, sftp.ErrSshFxConnectionLost) {
-			return service.ErrNotConnected
-		}
 		return err
 	}
 
-	if err := s.writeTo(handle, msg); err != nil {
+	if offset > 0 {
+		s.log.Debugf("Resuming upload of file '%v' from byte %v", path, offset)
+		if _, err := handle.Seek(offset, io.SeekStart); err != nil {
+			_ = handle.Close()
+			return err
+		}
+	}
+
+	if _, err := handle.Write(data[offset:]); err != nil {
 		_ = handle.Close()
 		return err
 	}
+	return handle.Close()
+}
 
-	if s.appendMode {
-		s.handle = handle
-		s.handlePath = path
-	} else {
-		if err := handle.Close(); err != nil {
-			s.log.With("error", err).Error("Failed to close written file")
+// verifyFile checks that an uploaded file matches the data written to it,
+// removing the file when it does not so that the next attempt begins afresh.
+func (s *sftpWriter) verifyFile(path string, data []byte) error {
+	var verifyErr error
+	switch s.verify {
+	case verifySize:
+		info, err := s.client.Stat(path)
+		if err != nil {
+			return err
+		}
+		if info.Size() != int64(len(data)) {
+			verifyErr = fmt.Errorf("uploaded file %v has a size of %v bytes, expected %v", path, info.Size(), len(data))
+		}
+	case verifyChecksum:
+		handle, err := s.client.Open(path)
+		if err != nil {
+			return err
+		}
+		hasher := sha256.New()
+		_, err = io.Copy(hasher, handle)
+		_ = handle.Close()
+		if err != nil {
+			return err
+		}
+		if expected := sha256.Sum256(data); !bytes.Equal(hasher.Sum(nil), expected[:]) {
+			verifyErr = fmt.Errorf("uploaded file %v does not match the checksum of the message", path)
 		}
 	}
-	return nil
+	if verifyErr != nil {
+		_ = s.client.Remove(path)
+	}
+	return verifyErr
+}
+
+// renameFile moves a file to a new path, replacing any existing file.
+func (s *sftpWriter) renameFile(from, to string) error {
+	if _, ok := s.client.HasExtension("posix-rename@openssh.com"); ok {
+		return s.client.PosixRename(from, to)
+	}
+
+	// A standard rename fails when the target already exists.
+	if err := s.client.Remove(to); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return s.client.Rename(from, to)
 }
 
 func (s *sftpWriter) Close(ctx context.Context) error {
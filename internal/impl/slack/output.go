@@ -0,0 +1,330 @@
+package slack
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/benthosdev/benthos/v4/public/bloblang"
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	soFieldBotToken       = "bot_token"
+	soFieldChannel        = "channel"
+	soFieldText           = "text"
+	soFieldBlocks         = "blocks"
+	soFieldThreadTS       = "thread_ts"
+	soFieldReplyBroadcast = "reply_broadcast"
+	soFieldUnfurlLinks    = "unfurl_links"
+	soFieldMaxRetries     = "max_retries"
+	soFieldTimeout        = "timeout"
+	soFieldAPIURL         = "api_url"
+)
+
+func slackOutputSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Services", "Social").
+		Version("4.28.0").
+		Summary("Posts messages to a Slack channel with the Slack Web API.").
+		Description(`
+Each message is posted with the `+"[`chat.postMessage`](https://api.slack.com/methods/chat.postMessage)"+` method using a bot token, which requires the `+"`chat:write`"+` scope. The bot must be a member of the channel unless it also has the `+"`chat:write.public`"+` scope.
+
+The `+"`text`"+` of a message is always sent, and when `+"`blocks`"+` are also provided the text is used as a fallback for notifications. Setting `+"`thread_ts`"+` to the timestamp of an existing message posts the message as a reply within its thread.
+
+### Rate Limits
+
+When Slack responds that a request has been rate limited the request is retried after the period indicated by the response, up to `+"`max_retries`"+` times, before the message is rejected.`+service.OutputPerformanceDocs(true, false)).
+		Fields(
+			service.NewStringField(soFieldBotToken).
+				Description("A bot token used for authentication.").
+				Secret(),
+			service.NewInterpolatedStringField(soFieldChannel).
+				Description("The ID or name of the channel to post messages to.").
+				Example("C0123456789").
+				Example(`${! @channel }`),
+			service.NewInterpolatedStringField(soFieldText).
+				Description("The text of each message, which may contain Slack `mrkdwn` formatting.").
+				Default("${! content() }"),
+			service.NewBloblangField(soFieldBlocks).
+				Description("An optional [Bloblang mapping](/docs/guides/bloblang/about) that returns an array of [layout blocks](https://api.slack.com/reference/block-kit/blocks) for each message.").
+				Example(`root = [
+  {"type": "header", "text": {"type": "plain_text", "text": this.title}},
+  {"type": "section", "text": {"type": "mrkdwn", "text": this.body}}
+]`).
+				Optional(),
+			service.NewInterpolatedStringField(soFieldThreadTS).
+				Description("An optional timestamp of a parent message, which when set posts each message as a reply within the thread of the parent.").
+				Example(`${! @slack_thread_ts }`).
+				Default(""),
+			service.NewBoolField(soFieldReplyBroadcast).
+				Description("Whether replies within threads should also be posted to the channel.").
+				Advanced().
+				Default(false),
+			service.NewBoolField(soFieldUnfurlLinks).
+				Description("Whether to unfurl links to text based content.").
+				Advanced().
+				Default(false),
+			service.NewIntField(soFieldMaxRetries).
+				Description("The maximum number of times to retry a request that was rate limited.").
+				Advanced().
+				Default(3),
+			service.NewDurationField(soFieldTimeout).
+				Description("The maximum period to wait for each request to complete.").
+				Advanced().
+				Default("10s"),
+			service.NewStringField(soFieldAPIURL).
+				Description("The base URL of the Slack Web API.").
+				Advanced().
+				Default("https://slack.com/api"),
+			service.NewOutputMaxInFlightField().Default(1),
+		).
+		Example("Alerts", "Post alerts as formatted messages, with follow up alerts posted as replies within the thread of the original alert.", `
+output:
+  slack:
+    bot_token: ${SLACK_BOT_TOKEN}
+    channel: C0123456789
+    text: "${! this.severity.uppercase() }: ${! this.summary }"
+    blocks: |
+      root = [
+        {"type": "header", "text": {"type": "plain_text", "text": this.summary}},
+        {"type": "section", "text": {"type": "mrkdwn", "text": this.description}}
+      ]
+    thread_ts: ${! @thread_ts.or("") }
+`)
+}
+
+func init() {
+	err := service.RegisterOutput("slack", slackOutputSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (out service.Output, maxInFlight int, err error) {
+			if maxInFlight, err = conf.FieldMaxInFlight(); err != nil {
+				return
+			}
+			out, err = newSlackWriterFromParsed(conf, mgr)
+			return
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type slackWriter struct {
+	log *service.Logger
+
+	botToken       string
+	channel        *service.InterpolatedString
+	text           *service.InterpolatedString
+	blocks         *bloblang.Executor
+	threadTS       *service.InterpolatedString
+	replyBroadcast bool
+	unfurlLinks    bool
+	maxRetries     int
+	timeout        time.Duration
+	apiURL         string
+
+	client *http.Client
+}
+
+func newSlackWriterFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*slackWriter, error) {
+	s := &slackWriter{
+		log:    mgr.Logger(),
+		client: &http.Client{},
+	}
+
+	var err error
+	if s.botToken, err = conf.FieldString(soFieldBotToken); err != nil {
+		return nil, err
+	}
+	if s.channel, err = conf.FieldInterpolatedString(soFieldChannel); err != nil {
+		return nil, err
+	}
+	if s.text, err = conf.FieldInterpolatedString(soFieldText); err != nil {
+		return nil, err
+	}
+	if conf.Contains(soFieldBlocks) {
+		if s.blocks, err = conf.FieldBloblang(soFieldBlocks); err != nil {
+			return nil, err
+		}
+	}
+	if s.threadTS, err = conf.FieldInterpolatedString(soFieldThreadTS); err != nil {
+		return nil, err
+	}
+	if s.replyBroadcast, err = conf.FieldBool(soFieldReplyBroadcast); err != nil {
+		return nil, err
+	}
+	if s.unfurlLinks, err = conf.FieldBool(soFieldUnfurlLinks); err != nil {
+		return nil, err
+	}
+	if s.maxRetries, err = conf.FieldInt(soFieldMaxRetries); err != nil {
+		return nil, err
+	}
+	if s.timeout, err = conf.FieldDuration(soFieldTimeout); err != nil {
+		return nil, err
+	}
+	if s.apiURL, err = conf.FieldString(soFieldAPIURL); err != nil {
+		return nil, err
+	}
+	s.apiURL = strings.TrimSuffix(s.apiURL, "/")
+	return s, nil
+}
+
+func (s *slackWriter) Connect(ctx context.Context) error {
+	return nil
+}
+
+type slackPostMessage struct {
+	Channel        string `json:"channel"`
+	Text           string `json:"text"`
+	Blocks         any    `json:"blocks,omitempty"`
+	ThreadTS       string `json:"thread_ts,omitempty"`
+	ReplyBroadcast bool   `json:"reply_broadcast,omitempty"`
+	UnfurlLinks    bool   `json:"unfurl_links"`
+}
+
+func (s *slackWriter) postMessageFrom(msg *service.Message) (*slackPostMessage, error) {
+	p := &slackPostMessage{
+		ReplyBroadcast: s.replyBroadcast,
+		UnfurlLinks:    s.unfurlLinks,
+	}
+
+	var err error
+	if p.Channel, err = s.channel.TryString(msg); err != nil {
+		return nil, fmt.Errorf("channel interpolation error: %w", err)
+	}
+	if p.Text, err = s.text.TryString(msg); err != nil {
+		return nil, fmt.Errorf("text interpolation error: %w", err)
+	}
+	if p.ThreadTS, err = s.threadTS.TryString(msg); err != nil {
+		return nil, fmt.Errorf("thread_ts interpolation error: %w", err)
+	}
+	if p.ThreadTS == "" {
+		p.ReplyBroadcast = false
+	}
+
+	if s.blocks != nil {
+		blocksMsg, err := msg.BloblangQuery(s.blocks)
+		if err != nil {
+			return nil, fmt.Errorf("blocks mapping error: %w", err)
+		}
+		if blocksMsg != nil {
+			if p.Blocks, err = blocksMsg.AsStructured(); err != nil {
+				return nil, fmt.Errorf("blocks mapping error: %w", err)
+			}
+			if _, isArray := p.Blocks.([]any); !isArray {
+				return nil, fmt.Errorf("blocks mapping must return an array, got %T", p.Blocks)
+			}
+		}
+	}
+	return p, nil
+}
+
+type slackResponse struct {
+	OK      bool   `json:"ok"`
+	Error   string `json:"error"`
+	Warning string `json:"warning"`
+}
+
+// errRateLimited is returned when a request was rate limited, along with the
+// period to wait before retrying.
+type errRateLimited struct {
+	retryAfter time.Duration
+}
+
+func (e *errRateLimited) Error() string {
+	return fmt.Sprintf("rate limited, retry after %v", e.retryAfter)
+}
+
+func retryAfterFrom(res *http.Response) time.Duration {
+	if secs, err := strconv.Atoi(res.Header.Get("Retry-After")); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second
+	}
+	return time.Second
+}
+
+func (s *slackWriter) post(ctx context.Context, body []byte) error {
+	ctx, done := context.WithTimeout(ctx, s.timeout)
+	defer done()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.apiURL+"/chat.postMessage", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	req.Header.Set("Authorization", "Bearer "+s.botToken)
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == http.StatusTooManyRequests {
+		_, _ = io.Copy(io.Discard, res.Body)
+		return &errRateLimited{retryAfter: retryAfterFrom(res)}
+	}
+
+	resBytes, err := io.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("request returned status code %v: %s", res.StatusCode, resBytes)
+	}
+
+	var sRes slackResponse
+	if err := json.Unmarshal(resBytes, &sRes); err != nil {
+		return fmt.Errorf("failed to parse response: %w", err)
+	}
+	if !sRes.OK {
+		if sRes.Error == "ratelimited" {
+			return &errRateLimited{retryAfter: retryAfterFrom(res)}
+		}
+		return fmt.Errorf("slack API error: %v", sRes.Error)
+	}
+	if sRes.Warning != "" {
+		s.log.Debugf("Slack API warning: %v", sRes.Warning)
+	}
+	return nil
+}
+
+func (s *slackWriter) Write(ctx context.Context, msg *service.Message) error {
+	p, err := s.postMessageFrom(msg)
+	if err != nil {
+		return err
+	}
+
+	body, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+
+	for retries := 0; ; retries++ {
+		err = s.post(ctx, body)
+
+		var rlErr *errRateLimited
+		if !errors.As(err, &rlErr) || retries >= s.maxRetries {
+			return err
+		}
+
+		s.log.Debugf("Request rate limited, retrying after %v", rlErr.retryAfter)
+		select {
+		case <-time.After(rlErr.retryAfter):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (s *slackWriter) Close(ctx context.Context) error {
+	return nil
+}
@@ -0,0 +1,115 @@
+package slack
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func testSlackWriter(t *testing.T, conf string) *slackWriter {
+	t.Helper()
+
+	pConf, err := slackOutputSpec().ParseYAML(conf, nil)
+	require.NoError(t, err)
+
+	w, err := newSlackWriterFromParsed(pConf, service.MockResources())
+	require.NoError(t, err)
+	require.NoError(t, w.Connect(context.Background()))
+	return w
+}
+
+func TestSlackPostMessage(t *testing.T) {
+	var mut sync.Mutex
+	var bodies []map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/chat.postMessage", r.URL.Path)
+		assert.Equal(t, "Bearer xoxb-foo", r.Header.Get("Authorization"))
+
+		b, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+
+		var body map[string]any
+		require.NoError(t, json.Unmarshal(b, &body))
+
+		if body["channel"] == "missing" {
+			_, _ = rw.Write([]byte(`{"ok":false,"error":"channel_not_found"}`))
+			return
+		}
+
+		mut.Lock()
+		bodies = append(bodies, body)
+		mut.Unlock()
+		_, _ = rw.Write([]byte(`{"ok":true,"ts":"1700000000.000100"}`))
+	}))
+	t.Cleanup(srv.Close)
+
+	w := testSlackWriter(t, `
+bot_token: xoxb-foo
+channel: ${! @channel }
+text: ${! this.summary }
+blocks: 'root = [{"type": "section", "text": {"type": "mrkdwn", "text": this.summary}}]'
+thread_ts: ${! @thread.or("") }
+reply_broadcast: true
+api_url: `+srv.URL+`/api/
+`)
+
+	msg := service.NewMessage([]byte(`{"summary":"disk full"}`))
+	msg.MetaSetMut("channel", "C123")
+	require.NoError(t, w.Write(context.Background(), msg))
+
+	msg = service.NewMessage([]byte(`{"summary":"still full"}`))
+	msg.MetaSetMut("channel", "C123")
+	msg.MetaSetMut("thread", "1700000000.000100")
+	require.NoError(t, w.Write(context.Background(), msg))
+
+	msg = service.NewMessage([]byte(`{"summary":"nope"}`))
+	msg.MetaSetMut("channel", "missing")
+	require.EqualError(t, w.Write(context.Background(), msg), "slack API error: channel_not_found")
+
+	require.Len(t, bodies, 2)
+	assert.Equal(t, map[string]any{
+		"channel": "C123",
+		"text":    "disk full",
+		"blocks": []any{
+			map[string]any{"type": "section", "text": map[string]any{"type": "mrkdwn", "text": "disk full"}},
+		},
+		"unfurl_links": false,
+	}, bodies[0])
+	assert.Equal(t, "1700000000.000100", bodies[1]["thread_ts"])
+	assert.Equal(t, true, bodies[1]["reply_broadcast"])
+}
+
+func TestSlackRateLimited(t *testing.T) {
+	var requests int
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if requests++; requests < 3 {
+			rw.Header().Set("Retry-After", "0")
+			rw.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		_, _ = rw.Write([]byte(`{"ok":true}`))
+	}))
+	t.Cleanup(srv.Close)
+
+	w := testSlackWriter(t, `
+bot_token: xoxb-foo
+channel: C123
+api_url: `+srv.URL+`
+`)
+	require.NoError(t, w.Write(context.Background(), service.NewMessage([]byte("hello world"))))
+	assert.Equal(t, 3, requests)
+
+	requests = 0
+	w.maxRetries = 1
+	require.Error(t, w.Write(context.Background(), service.NewMessage([]byte("hello world"))))
+	assert.Equal(t, 2, requests)
+}
@@ -0,0 +1,708 @@
+package smtp
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strings"
+	"time"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	soFieldAddress     = "address"
+	soFieldTLS         = "tls"
+	soFieldStartTLS    = "starttls"
+	soFieldAuth        = "auth"
+	soFieldAuthUser    = "username"
+	soFieldAuthPass    = "password"
+	soFieldHelloName   = "hello_name"
+	soFieldFrom        = "from"
+	soFieldTo          = "to"
+	soFieldCC          = "cc"
+	soFieldBCC         = "bcc"
+	soFieldReplyTo     = "reply_to"
+	soFieldSubject     = "subject"
+	soFieldBodyText    = "body_text"
+	soFieldBodyHTML    = "body_html"
+	soFieldAttachments = "attachments"
+	soFieldAttEnabled  = "enabled"
+	soFieldAttFilename = "filename"
+	soFieldAttType     = "content_type"
+	soFieldTimeout     = "timeout"
+	soFieldBatching    = "batching"
+)
+
+func smtpOutputSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Services").
+		Version("4.28.0").
+		Summary("Sends messages as emails to an SMTP server.").
+		Description(`
+By default each message is sent as an individual email, where the headers and bodies of the email are [interpolated](/docs/configuration/interpolation#bloblang-queries) from the message. When `+"`body_html`"+` is set the email contains both a plain text and an HTML alternative, and mail clients display whichever they prefer.
+
+### Attachments
+
+When `+"`attachments.enabled`"+` is `+"`true`"+` each batch of messages is instead sent as a single email with each message of the batch attached as a file, and the headers and bodies of the email are interpolated from the first message of the batch. This can be combined with a `+"[batch policy](/docs/configuration/batching)"+` in order to send reports, where for example a `+"`period`"+` groups messages into a daily email.
+
+### TLS
+
+Setting `+"`tls.enabled`"+` to `+"`true`"+` connects to the server with implicit TLS, which is usually served on port 465. Otherwise the connection is upgraded with STARTTLS according to the `+"`starttls`"+` field, which is usually served on port 587, and the `+"`tls`"+` settings are used for the upgraded connection.`+service.OutputPerformanceDocs(true, true)).
+		Fields(
+			service.NewStringField(soFieldAddress).
+				Description("The address of the SMTP server in the form `host:port`.").
+				Example("smtp.example.com:587"),
+			service.NewTLSToggledField(soFieldTLS),
+			service.NewStringAnnotatedEnumField(soFieldStartTLS, map[string]string{
+				"opportunistic": "Upgrade the connection with STARTTLS when the server supports it.",
+				"required":      "Upgrade the connection with STARTTLS and fail when the server does not support it.",
+				"disabled":      "Never upgrade the connection.",
+			}).
+				Description("Whether to upgrade connections with STARTTLS. Ignored when `tls.enabled` is `true`.").
+				Default("opportunistic"),
+			service.NewObjectField(soFieldAuth,
+				service.NewStringField(soFieldAuthUser).
+					Description("A username to authenticate with using the PLAIN mechanism. Authentication is disabled when empty.").
+					Default(""),
+				service.NewStringField(soFieldAuthPass).
+					Description("A password to authenticate with.").
+					Secret().
+					Default(""),
+			).
+				Description("Optional authentication with the SMTP server, which is only attempted over TLS connections or with servers on localhost."),
+			service.NewStringField(soFieldHelloName).
+				Description("The hostname to identify as with the HELO or EHLO command.").
+				Advanced().
+				Default("localhost"),
+			service.NewInterpolatedStringField(soFieldFrom).
+				Description("The address that emails are sent from.").
+				Example("Benthos Alerts <alerts@example.com>"),
+			service.NewInterpolatedStringField(soFieldTo).
+				Description("A comma separated list of addresses to send emails to.").
+				Example("oncall@example.com").
+				Example(`${! this.recipients.join(",") }`),
+			service.NewInterpolatedStringField(soFieldCC).
+				Description("An optional comma separated list of addresses to copy emails to.").
+				Advanced().
+				Default(""),
+			service.NewInterpolatedStringField(soFieldBCC).
+				Description("An optional comma separated list of addresses to blind copy emails to, which are not added to the headers of emails.").
+				Advanced().
+				Default(""),
+			service.NewInterpolatedStringField(soFieldReplyTo).
+				Description("An optional address that replies should be sent to.").
+				Advanced().
+				Default(""),
+			service.NewInterpolatedStringField(soFieldSubject).
+				Description("The subject of emails.").
+				Example(`Alert: ${! this.alert_name }`),
+			service.NewInterpolatedStringField(soFieldBodyText).
+				Description("The plain text body of emails.").
+				Default("${! content() }"),
+			service.NewInterpolatedStringField(soFieldBodyHTML).
+				Description("An optional HTML body of emails, which is sent as an alternative to the plain text body.").
+				Example(`<h1>${! this.alert_name }</h1><p>${! this.description }</p>`).
+				Optional(),
+			service.NewObjectField(soFieldAttachments,
+				service.NewBoolField(soFieldAttEnabled).
+					Description("Whether to send each batch as a single email with the messages of the batch attached.").
+					Default(false),
+				service.NewInterpolatedStringField(soFieldAttFilename).
+					Description("The file name of each attachment.").
+					Example(`${! @filename }`).
+					Default(`attachment-${! batch_index() }`),
+				service.NewInterpolatedStringField(soFieldAttType).
+					Description("The content type of each attachment.").
+					Example("text/csv").
+					Default("application/octet-stream"),
+			).
+				Description("Send the messages of each batch as attachments of a single email. Check out the [attachments](#attachments) section for more details."),
+			service.NewDurationField(soFieldTimeout).
+				Description("The maximum period to wait for an email to be sent.").
+				Advanced().
+				Default("30s"),
+			service.NewOutputMaxInFlightField(),
+			service.NewBatchPolicyField(soFieldBatching),
+		).
+		Example("Alerts", "Send an email for each alert with both plain text and HTML bodies.", `
+output:
+  smtp:
+    address: smtp.example.com:587
+    auth:
+      username: alerts@example.com
+      password: ${SMTP_PASSWORD}
+    from: Benthos Alerts <alerts@example.com>
+    to: ${! this.team_email }
+    subject: "[${! this.severity.uppercase() }] ${! this.alert_name }"
+    body_text: ${! this.description }
+    body_html: <h1>${! this.alert_name }</h1><p>${! this.description }</p>
+`).
+		Example("Daily Reports", "Send a daily email with the messages consumed throughout the day attached as a CSV file.", `
+output:
+  smtp:
+    address: smtp.example.com:465
+    tls:
+      enabled: true
+    from: reports@example.com
+    to: finance@example.com
+    subject: Daily report ${! now().ts_format("2006-01-02") }
+    body_text: Please find attached the transactions from the last day.
+    attachments:
+      enabled: true
+      filename: transactions.csv
+      content_type: text/csv
+    batching:
+      count: 0
+      period: 24h
+      processors:
+        - archive:
+            format: lines
+`)
+}
+
+func init() {
+	err := service.RegisterBatchOutput("smtp", smtpOutputSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (out service.BatchOutput, batchPolicy service.BatchPolicy, maxInFlight int, err error) {
+			if maxInFlight, err = conf.FieldMaxInFlight(); err != nil {
+				return
+			}
+			if batchPolicy, err = conf.FieldBatchPolicy(soFieldBatching); err != nil {
+				return
+			}
+			out, err = newSMTPWriterFromParsed(conf, mgr)
+			return
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type smtpWriter struct {
+	log *service.Logger
+
+	address   string
+	host      string
+	tlsConf   *tls.Config
+	tlsImpl   bool
+	startTLS  string
+	username  string
+	password  string
+	helloName string
+	timeout   time.Duration
+
+	from     *service.InterpolatedString
+	to       *service.InterpolatedString
+	cc       *service.InterpolatedString
+	bcc      *service.InterpolatedString
+	replyTo  *service.InterpolatedString
+	subject  *service.InterpolatedString
+	bodyText *service.InterpolatedString
+	bodyHTML *service.InterpolatedString
+
+	attachBatch bool
+	attFilename *service.InterpolatedString
+	attType     *service.InterpolatedString
+
+	nowFn func() time.Time
+}
+
+func newSMTPWriterFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*smtpWriter, error) {
+	s := &smtpWriter{
+		log:   mgr.Logger(),
+		nowFn: time.Now,
+	}
+
+	var err error
+	if s.address, err = conf.FieldString(soFieldAddress); err != nil {
+		return nil, err
+	}
+	if s.host, _, err = net.SplitHostPort(s.address); err != nil {
+		return nil, fmt.Errorf("failed to parse address: %w", err)
+	}
+	if s.tlsConf, s.tlsImpl, err = conf.FieldTLSToggled(soFieldTLS); err != nil {
+		return nil, err
+	}
+	if s.tlsConf == nil {
+		s.tlsConf = &tls.Config{}
+	}
+	if s.tlsConf.ServerName == "" {
+		s.tlsConf = s.tlsConf.Clone()
+		s.tlsConf.ServerName = s.host
+	}
+	if s.startTLS, err = conf.FieldString(soFieldStartTLS); err != nil {
+		return nil, err
+	}
+	if s.username, err = conf.FieldString(soFieldAuth, soFieldAuthUser); err != nil {
+		return nil, err
+	}
+	if s.password, err = conf.FieldString(soFieldAuth, soFieldAuthPass); err != nil {
+		return nil, err
+	}
+	if s.helloName, err = conf.FieldString(soFieldHelloName); err != nil {
+		return nil, err
+	}
+	if s.timeout, err = conf.FieldDuration(soFieldTimeout); err != nil {
+		return nil, err
+	}
+
+	for _, f := range []struct {
+		name   string
+		target **service.InterpolatedString
+	}{
+		{soFieldFrom, &s.from},
+		{soFieldTo, &s.to},
+		{soFieldCC, &s.cc},
+		{soFieldBCC, &s.bcc},
+		{soFieldReplyTo, &s.replyTo},
+		{soFieldSubject, &s.subject},
+		{soFieldBodyText, &s.bodyText},
+	} {
+		if *f.target, err = conf.FieldInterpolatedString(f.name); err != nil {
+			return nil, err
+		}
+	}
+	if conf.Contains(soFieldBodyHTML) {
+		if s.bodyHTML, err = conf.FieldInterpolatedString(soFieldBodyHTML); err != nil {
+			return nil, err
+		}
+	}
+
+	if s.attachBatch, err = conf.FieldBool(soFieldAttachments, soFieldAttEnabled); err != nil {
+		return nil, err
+	}
+	if s.attFilename, err = conf.FieldInterpolatedString(soFieldAttachments, soFieldAttFilename); err != nil {
+		return nil, err
+	}
+	if s.attType, err = conf.FieldInterpolatedString(soFieldAttachments, soFieldAttType); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+//------------------------------------------------------------------------------
+
+type emailAttachment struct {
+	filename    string
+	contentType string
+	content     []byte
+}
+
+type email struct {
+	from     *mail.Address
+	to       []*mail.Address
+	cc       []*mail.Address
+	bcc      []*mail.Address
+	replyTo  *mail.Address
+	subject  string
+	bodyText string
+	bodyHTML string
+
+	attachments []emailAttachment
+}
+
+// recipients returns the addresses that an email must be delivered to.
+func (e *email) recipients() []string {
+	var addrs []string
+	for _, list := range [][]*mail.Address{e.to, e.cc, e.bcc} {
+		for _, a := range list {
+			addrs = append(addrs, a.Address)
+		}
+	}
+	return addrs
+}
+
+func parseAddressList(s string) ([]*mail.Address, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+	return mail.ParseAddressList(s)
+}
+
+// emailFrom builds an email with headers and bodies interpolated from the
+// message at index i of a batch.
+func (s *smtpWriter) emailFrom(batch service.MessageBatch, i int) (*email, error) {
+	var e email
+
+	from, err := batch.TryInterpolatedString(i, s.from)
+	if err != nil {
+		return nil, fmt.Errorf("from interpolation error: %w", err)
+	}
+	if e.from, err = mail.ParseAddress(from); err != nil {
+		return nil, fmt.Errorf("failed to parse from address: %w", err)
+	}
+
+	for _, f := range []struct {
+		name   string
+		interp *service.InterpolatedString
+		target *[]*mail.Address
+	}{
+		{soFieldTo, s.to, &e.to},
+		{soFieldCC, s.cc, &e.cc},
+		{soFieldBCC, s.bcc, &e.bcc},
+	} {
+		str, err := batch.TryInterpolatedString(i, f.interp)
+		if err != nil {
+			return nil, fmt.Errorf("%v interpolation error: %w", f.name, err)
+		}
+		if *f.target, err = parseAddressList(str); err != nil {
+			return nil, fmt.Errorf("failed to parse %v addresses: %w", f.name, err)
+		}
+	}
+	if len(e.to)+len(e.cc)+len(e.bcc) == 0 {
+		return nil, errors.New("email has no recipients")
+	}
+
+	replyTo, err := batch.TryInterpolatedString(i, s.replyTo)
+	if err != nil {
+		return nil, fmt.Errorf("reply_to interpolation error: %w", err)
+	}
+	if replyTo != "" {
+		if e.replyTo, err = mail.ParseAddress(replyTo); err != nil {
+			return nil, fmt.Errorf("failed to parse reply_to address: %w", err)
+		}
+	}
+
+	if e.subject, err = batch.TryInterpolatedString(i, s.subject); err != nil {
+		return nil, fmt.Errorf("subject interpolation error: %w", err)
+	}
+	if e.bodyText, err = batch.TryInterpolatedString(i, s.bodyText); err != nil {
+		return nil, fmt.Errorf("body_text interpolation error: %w", err)
+	}
+	if s.bodyHTML != nil {
+		if e.bodyHTML, err = batch.TryInterpolatedString(i, s.bodyHTML); err != nil {
+			return nil, fmt.Errorf("body_html interpolation error: %w", err)
+		}
+	}
+	return &e, nil
+}
+
+func (s *smtpWriter) attachmentsFrom(batch service.MessageBatch) ([]emailAttachment, error) {
+	atts := make([]emailAttachment, len(batch))
+	for i, msg := range batch {
+		var err error
+		if atts[i].filename, err = batch.TryInterpolatedString(i, s.attFilename); err != nil {
+			return nil, fmt.Errorf("attachment filename interpolation error: %w", err)
+		}
+		if atts[i].contentType, err = batch.TryInterpolatedString(i, s.attType); err != nil {
+			return nil, fmt.Errorf("attachment content type interpolation error: %w", err)
+		}
+		if atts[i].content, err = msg.AsBytes(); err != nil {
+			return nil, err
+		}
+	}
+	return atts, nil
+}
+
+//------------------------------------------------------------------------------
+
+func formatAddressList(addrs []*mail.Address) string {
+	strs := make([]string, len(addrs))
+	for i, a := range addrs {
+		strs[i] = a.String()
+	}
+	return strings.Join(strs, ", ")
+}
+
+func messageID(host string) string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	return fmt.Sprintf("<%v@%v>", hex.EncodeToString(b[:]), host)
+}
+
+func writeQuotedPrintable(w io.Writer, s string) error {
+	qp := quotedprintable.NewWriter(w)
+	if _, err := qp.Write([]byte(s)); err != nil {
+		return err
+	}
+	return qp.Close()
+}
+
+// base64LineWriter splits base64 encoded content into lines of 76 characters
+// as required by RFC 2045.
+type base64LineWriter struct {
+	w   io.Writer
+	col int
+}
+
+func (l *base64LineWriter) Write(p []byte) (int, error) {
+	n := 0
+	for len(p) > 0 {
+		chunk := min(len(p), 76-l.col)
+		if _, err := l.w.Write(p[:chunk]); err != nil {
+			return n, err
+		}
+		n += chunk
+		p = p[chunk:]
+		if l.col += chunk; l.col == 76 {
+			if _, err := l.w.Write([]byte("\r\n")); err != nil {
+				return n, err
+			}
+			l.col = 0
+		}
+	}
+	return n, nil
+}
+
+func writeBodies(mw *multipart.Writer, e *email) error {
+	textHeader := textproto.MIMEHeader{}
+	textHeader.Set("Content-Type", `text/plain; charset="utf-8"`)
+	textHeader.Set("Content-Transfer-Encoding", "quoted-printable")
+
+	if e.bodyHTML == "" {
+		pw, err := mw.CreatePart(textHeader)
+		if err != nil {
+			return err
+		}
+		return writeQuotedPrintable(pw, e.bodyText)
+	}
+
+	altBody := &bytes.Buffer{}
+	aw := multipart.NewWriter(altBody)
+
+	pw, err := aw.CreatePart(textHeader)
+	if err != nil {
+		return err
+	}
+	if err := writeQuotedPrintable(pw, e.bodyText); err != nil {
+		return err
+	}
+
+	htmlHeader := textproto.MIMEHeader{}
+	htmlHeader.Set("Content-Type", `text/html; charset="utf-8"`)
+	htmlHeader.Set("Content-Transfer-Encoding", "quoted-printable")
+	if pw, err = aw.CreatePart(htmlHeader); err != nil {
+		return err
+	}
+	if err := writeQuotedPrintable(pw, e.bodyHTML); err != nil {
+		return err
+	}
+	if err := aw.Close(); err != nil {
+		return err
+	}
+
+	altHeader := textproto.MIMEHeader{}
+	altHeader.Set("Content-Type", "multipart/alternative; boundary="+aw.Boundary())
+	if pw, err = mw.CreatePart(altHeader); err != nil {
+		return err
+	}
+	_, err = pw.Write(altBody.Bytes())
+	return err
+}
+
+// buildEmail serialises an email in the Internet Message Format, as a
+// multipart/mixed message containing the bodies and any attachments.
+func buildEmail(e *email, host string, now time.Time) ([]byte, error) {
+	buf := &bytes.Buffer{}
+
+	writeHeader := func(k, v string) {
+		fmt.Fprintf(buf, "%v: %v\r\n", k, v)
+	}
+	writeHeader("From", e.from.String())
+	if len(e.to) > 0 {
+		writeHeader("To", formatAddressList(e.to))
+	}
+	if len(e.cc) > 0 {
+		writeHeader("Cc", formatAddressList(e.cc))
+	}
+	if e.replyTo != nil {
+		writeHeader("Reply-To", e.replyTo.String())
+	}
+	writeHeader("Subject", mime.QEncoding.Encode("utf-8", e.subject))
+	writeHeader("Date", now.Format(time.RFC1123Z))
+	writeHeader("Message-ID", messageID(host))
+	writeHeader("MIME-Version", "1.0")
+
+	body := &bytes.Buffer{}
+	mw := multipart.NewWriter(body)
+	writeHeader("Content-Type", "multipart/mixed; boundary="+mw.Boundary())
+	buf.WriteString("\r\n")
+
+	if err := writeBodies(mw, e); err != nil {
+		return nil, err
+	}
+
+	for _, att := range e.attachments {
+		attHeader := textproto.MIMEHeader{}
+		attHeader.Set("Content-Type", mime.FormatMediaType(att.contentType, map[string]string{"name": att.filename}))
+		attHeader.Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": att.filename}))
+		attHeader.Set("Content-Transfer-Encoding", "base64")
+
+		pw, err := mw.CreatePart(attHeader)
+		if err != nil {
+			return nil, err
+		}
+		enc := base64.NewEncoder(base64.StdEncoding, &base64LineWriter{w: pw})
+		if _, err := enc.Write(att.content); err != nil {
+			return nil, err
+		}
+		if err := enc.Close(); err != nil {
+			return nil, err
+		}
+	}
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+
+	_, _ = buf.Write(body.Bytes())
+	return buf.Bytes(), nil
+}
+
+//------------------------------------------------------------------------------
+
+func (s *smtpWriter) Connect(ctx context.Context) error {
+	return nil
+}
+
+func (s *smtpWriter) dial(ctx context.Context) (*smtp.Client, error) {
+	dialer := &net.Dialer{}
+
+	var conn net.Conn
+	var err error
+	if s.tlsImpl {
+		tlsDialer := &tls.Dialer{NetDialer: dialer, Config: s.tlsConf}
+		conn, err = tlsDialer.DialContext(ctx, "tcp", s.address)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", s.address)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	client, err := smtp.NewClient(conn, s.host)
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	if err := s.initClient(client); err != nil {
+		_ = client.Close()
+		return nil, err
+	}
+	return client, nil
+}
+
+func (s *smtpWriter) initClient(client *smtp.Client) error {
+	if err := client.Hello(s.helloName); err != nil {
+		return err
+	}
+
+	if !s.tlsImpl && s.startTLS != "disabled" {
+		if ok, _ := client.Extension("STARTTLS"); ok {
+			if err := client.StartTLS(s.tlsConf); err != nil {
+				return fmt.Errorf("failed to upgrade connection with STARTTLS: %w", err)
+			}
+		} else if s.startTLS == "required" {
+			return errors.New("server does not support STARTTLS")
+		}
+	}
+
+	if s.username != "" {
+		if err := client.Auth(smtp.PlainAuth("", s.username, s.password, s.host)); err != nil {
+			return fmt.Errorf("failed to authenticate: %w", err)
+		}
+	}
+	return nil
+}
+
+func (s *smtpWriter) send(client *smtp.Client, e *email) error {
+	data, err := buildEmail(e, s.helloName, s.nowFn())
+	if err != nil {
+		return err
+	}
+
+	if err := client.Mail(e.from.Address); err != nil {
+		return err
+	}
+	for _, rcpt := range e.recipients() {
+		if err := client.Rcpt(rcpt); err != nil {
+			return fmt.Errorf("recipient %v rejected: %w", rcpt, err)
+		}
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		_ = w.Close()
+		return err
+	}
+	return w.Close()
+}
+
+func (s *smtpWriter) WriteBatch(ctx context.Context, batch service.MessageBatch) error {
+	ctx, done := context.WithTimeout(ctx, s.timeout)
+	defer done()
+
+	if s.attachBatch {
+		e, err := s.emailFrom(batch, 0)
+		if err != nil {
+			return err
+		}
+		if e.attachments, err = s.attachmentsFrom(batch); err != nil {
+			return err
+		}
+
+		client, err := s.dial(ctx)
+		if err != nil {
+			return err
+		}
+		defer client.Close()
+
+		if err := s.send(client, e); err != nil {
+			return err
+		}
+		return client.Quit()
+	}
+
+	client, err := s.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	var batchErr *service.BatchError
+	for i := range batch {
+		e, err := s.emailFrom(batch, i)
+		if err == nil {
+			if err = s.send(client, e); err != nil {
+				// Clear the state of the failed transaction so that the
+				// remaining emails can be sent over the same connection.
+				_ = client.Reset()
+			}
+		}
+		if err != nil {
+			if batchErr == nil {
+				batchErr = service.NewBatchError(batch, err)
+			}
+			batchErr = batchErr.Failed(i, err)
+		}
+	}
+	if batchErr != nil {
+		return batchErr
+	}
+	return client.Quit()
+}
+
+func (s *smtpWriter) Close(ctx context.Context) error {
+	return nil
+}
@@ -0,0 +1,313 @@
+package smtp
+
+import (
+	"context"
+	"encoding/base64"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+// fakeSMTPServer accepts emails without authentication or TLS and records the
+// envelope and data of each.
+type fakeSMTPServer struct {
+	ln net.Listener
+
+	mut    sync.Mutex
+	emails []fakeEmail
+}
+
+type fakeEmail struct {
+	from  string
+	rcpts []string
+	data  string
+}
+
+func newFakeSMTPServer(t *testing.T) *fakeSMTPServer {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = ln.Close()
+	})
+
+	f := &fakeSMTPServer{ln: ln}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go f.serve(conn)
+		}
+	}()
+	return f
+}
+
+func (f *fakeSMTPServer) serve(conn net.Conn) {
+	defer conn.Close()
+
+	tp := textproto.NewConn(conn)
+	_ = tp.PrintfLine("220 localhost ready")
+
+	var current fakeEmail
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+		cmd := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+		switch cmd {
+		case "EHLO", "HELO":
+			_ = tp.PrintfLine("250 localhost")
+		case "MAIL":
+			current = fakeEmail{from: strings.TrimSuffix(strings.TrimPrefix(line, "MAIL FROM:<"), ">")}
+			_ = tp.PrintfLine("250 OK")
+		case "RCPT":
+			rcpt := strings.TrimSuffix(strings.TrimPrefix(line, "RCPT TO:<"), ">")
+			if strings.HasPrefix(rcpt, "reject") {
+				_ = tp.PrintfLine("550 no such user")
+				continue
+			}
+			current.rcpts = append(current.rcpts, rcpt)
+			_ = tp.PrintfLine("250 OK")
+		case "DATA":
+			_ = tp.PrintfLine("354 go ahead")
+			data, err := io.ReadAll(tp.DotReader())
+			if err != nil {
+				return
+			}
+			current.data = string(data)
+			f.mut.Lock()
+			f.emails = append(f.emails, current)
+			f.mut.Unlock()
+			_ = tp.PrintfLine("250 OK")
+		case "RSET":
+			current = fakeEmail{}
+			_ = tp.PrintfLine("250 OK")
+		case "QUIT":
+			_ = tp.PrintfLine("221 bye")
+			return
+		default:
+			_ = tp.PrintfLine("502 not implemented")
+		}
+	}
+}
+
+func (f *fakeSMTPServer) received() []fakeEmail {
+	f.mut.Lock()
+	defer f.mut.Unlock()
+	return append([]fakeEmail(nil), f.emails...)
+}
+
+func testSMTPWriter(t *testing.T, conf string) *smtpWriter {
+	t.Helper()
+
+	pConf, err := smtpOutputSpec().ParseYAML(conf, nil)
+	require.NoError(t, err)
+
+	w, err := newSMTPWriterFromParsed(pConf, service.MockResources())
+	require.NoError(t, err)
+	require.NoError(t, w.Connect(context.Background()))
+	return w
+}
+
+func parseMultipart(t *testing.T, contentType string, body io.Reader) map[string]string {
+	t.Helper()
+
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(mediaType, "multipart/"), mediaType)
+
+	parts := map[string]string{}
+	mr := multipart.NewReader(body, params["boundary"])
+	for {
+		p, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+
+		partType := p.Header.Get("Content-Type")
+		if strings.HasPrefix(partType, "multipart/") {
+			for k, v := range parseMultipart(t, partType, p) {
+				parts[k] = v
+			}
+			continue
+		}
+
+		key, _, _ := mime.ParseMediaType(partType)
+		if _, params, err := mime.ParseMediaType(p.Header.Get("Content-Disposition")); err == nil {
+			key = params["filename"]
+		}
+
+		// The multipart reader decodes quoted-printable parts.
+		var content []byte
+		if p.Header.Get("Content-Transfer-Encoding") == "base64" {
+			raw, err := io.ReadAll(p)
+			require.NoError(t, err)
+			content, err = base64.StdEncoding.DecodeString(strings.ReplaceAll(string(raw), "\r\n", ""))
+			require.NoError(t, err)
+		} else {
+			content, err = io.ReadAll(p)
+			require.NoError(t, err)
+		}
+		parts[key] = string(content)
+	}
+	return parts
+}
+
+func TestSMTPBuildEmail(t *testing.T) {
+	e := &email{
+		from:     &mail.Address{Name: "Foo", Address: "foo@example.com"},
+		to:       []*mail.Address{{Address: "bar@example.com"}, {Address: "baz@example.com"}},
+		bcc:      []*mail.Address{{Address: "secret@example.com"}},
+		subject:  "héllo world",
+		bodyText: "hello world",
+		bodyHTML: "<p>hello world</p>",
+		attachments: []emailAttachment{
+			{filename: "data.csv", contentType: "text/csv", content: []byte(strings.Repeat("a,b,c\n", 50))},
+		},
+	}
+	assert.Equal(t, []string{"bar@example.com", "baz@example.com", "secret@example.com"}, e.recipients())
+
+	data, err := buildEmail(e, "localhost", time.Unix(1700000000, 0).UTC())
+	require.NoError(t, err)
+
+	msg, err := mail.ReadMessage(strings.NewReader(string(data)))
+	require.NoError(t, err)
+
+	assert.Equal(t, `"Foo" <foo@example.com>`, msg.Header.Get("From"))
+	assert.Equal(t, "<bar@example.com>, <baz@example.com>", msg.Header.Get("To"))
+	assert.Empty(t, msg.Header.Get("Bcc"))
+	assert.Equal(t, "Tue, 14 Nov 2023 22:13:20 +0000", msg.Header.Get("Date"))
+
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	require.NoError(t, err)
+	assert.Equal(t, "héllo world", subject)
+
+	parts := parseMultipart(t, msg.Header.Get("Content-Type"), msg.Body)
+	assert.Equal(t, map[string]string{
+		"text/plain": "hello world",
+		"text/html":  "<p>hello world</p>",
+		"data.csv":   strings.Repeat("a,b,c\n", 50),
+	}, parts)
+
+	for _, line := range strings.Split(string(data), "\r\n") {
+		assert.LessOrEqual(t, len(line), 998)
+	}
+}
+
+func TestSMTPWriteIndividual(t *testing.T) {
+	srv := newFakeSMTPServer(t)
+
+	w := testSMTPWriter(t, `
+address: `+srv.ln.Addr().String()+`
+from: alerts@example.com
+to: ${! this.to }
+subject: Alert ${! this.name }
+body_text: ${! this.desc }
+`)
+
+	batch := service.MessageBatch{
+		service.NewMessage([]byte(`{"to":"foo@example.com","name":"a","desc":"first"}`)),
+		service.NewMessage([]byte(`{"to":"reject@example.com","name":"b","desc":"second"}`)),
+		service.NewMessage([]byte(`{"to":"not an address","name":"c","desc":"third"}`)),
+		service.NewMessage([]byte(`{"to":"bar@example.com","name":"d","desc":"fourth"}`)),
+	}
+	idx := batch.Index()
+	err := w.WriteBatch(context.Background(), batch)
+	require.Error(t, err)
+
+	var failed []int
+	var bErr *service.BatchError
+	require.ErrorAs(t, err, &bErr)
+	bErr.WalkMessagesIndexedBy(idx, func(i int, _ *service.Message, err error) bool {
+		if err != nil {
+			failed = append(failed, i)
+		}
+		return true
+	})
+	assert.Equal(t, []int{1, 2}, failed)
+
+	emails := srv.received()
+	require.Len(t, emails, 2)
+	assert.Equal(t, "alerts@example.com", emails[0].from)
+	assert.Equal(t, []string{"foo@example.com"}, emails[0].rcpts)
+	assert.Equal(t, []string{"bar@example.com"}, emails[1].rcpts)
+
+	msg, err := mail.ReadMessage(strings.NewReader(emails[1].data))
+	require.NoError(t, err)
+	assert.Equal(t, "Alert d", msg.Header.Get("Subject"))
+	assert.Equal(t, map[string]string{
+		"text/plain": "fourth",
+	}, parseMultipart(t, msg.Header.Get("Content-Type"), msg.Body))
+}
+
+func TestSMTPWriteAttachments(t *testing.T) {
+	srv := newFakeSMTPServer(t)
+
+	w := testSMTPWriter(t, `
+address: `+srv.ln.Addr().String()+`
+from: reports@example.com
+to: foo@example.com, bar@example.com
+cc: baz@example.com
+subject: Report
+body_text: See attached
+attachments:
+  enabled: true
+  filename: ${! @name }
+  content_type: text/plain
+`)
+
+	fooMsg := service.NewMessage([]byte("foo content"))
+	fooMsg.MetaSetMut("name", "foo.txt")
+	barMsg := service.NewMessage([]byte("bar content"))
+	barMsg.MetaSetMut("name", "bar.txt")
+
+	require.NoError(t, w.WriteBatch(context.Background(), service.MessageBatch{fooMsg, barMsg}))
+
+	emails := srv.received()
+	require.Len(t, emails, 1)
+	assert.Equal(t, []string{"foo@example.com", "bar@example.com", "baz@example.com"}, emails[0].rcpts)
+
+	msg, err := mail.ReadMessage(strings.NewReader(emails[0].data))
+	require.NoError(t, err)
+	assert.Equal(t, "<baz@example.com>", msg.Header.Get("Cc"))
+	assert.Equal(t, map[string]string{
+		"text/plain": "See attached",
+		"foo.txt":    "foo content",
+		"bar.txt":    "bar content",
+	}, parseMultipart(t, msg.Header.Get("Content-Type"), msg.Body))
+}
+
+func TestSMTPStartTLSRequired(t *testing.T) {
+	srv := newFakeSMTPServer(t)
+
+	w := testSMTPWriter(t, `
+address: `+srv.ln.Addr().String()+`
+starttls: required
+from: alerts@example.com
+to: foo@example.com
+subject: nope
+`)
+
+	err := w.WriteBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte("hello world")),
+	})
+	require.ErrorContains(t, err, "STARTTLS")
+	assert.Empty(t, srv.received())
+}
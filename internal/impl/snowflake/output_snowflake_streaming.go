@@ -0,0 +1,532 @@
+package snowflake
+
+import (
+	"bytes"
+	"context"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	ssoFieldAccount        = "account"
+	ssoFieldUser           = "user"
+	ssoFieldPrivateKeyFile = "private_key_file"
+	ssoFieldPrivateKeyPass = "private_key_pass"
+	ssoFieldRole           = "role"
+	ssoFieldDatabase       = "database"
+	ssoFieldSchema         = "schema"
+	ssoFieldTable          = "table"
+	ssoFieldPipe           = "pipe"
+	ssoFieldChannelName    = "channel_name"
+	ssoFieldOffsetToken    = "offset_token"
+	ssoFieldWaitForCommit  = "wait_for_commit"
+	ssoFieldURL            = "url"
+	ssoFieldBatching       = "batching"
+
+	// The maximum size of a single append request accepted by Snowflake.
+	ssoMaxRequestBytes = 16 * 1024 * 1024
+
+	// Scoped tokens are valid for an hour, we refresh them a little earlier.
+	ssoScopedTokenLifetime = 50 * time.Minute
+)
+
+func snowflakeStreamingOutputConfig() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Services").
+		Version("4.28.0").
+		Summary("Streams messages into a Snowflake table using the Snowpipe Streaming REST API.").
+		Description(`
+Rows are appended to a channel of a pipe using the [Snowpipe Streaming high-performance architecture](https://docs.snowflake.com/en/user-guide/snowpipe-streaming/snowpipe-streaming-high-performance-overview), which makes data available to queries within seconds rather than the minutes taken by staging files with the `+"`snowflake_put`"+` output.
+
+Authentication is performed with [key pair authentication](https://docs.snowflake.com/en/user-guide/key-pair-auth) using the private key of the user.
+
+### Column Mapping
+
+Each message must be an object and is sent as a single row. When the `+"`pipe`"+` field is empty the default pipe of the table is used, which populates each column from the message field of the same name. A custom pipe can be created with a `+"`COPY`"+` transformation in order to map fields to columns differently.
+
+### Exactly Once Delivery
+
+Each batch is appended along with an offset token, and Snowflake records the offset token of the last committed batch of each channel. When the field `+"`offset_token`"+` is set it is evaluated against the last message of each batch, and it should resolve to an increasing number that identifies the position of the message within the source, such as a Kafka offset. When the channel is opened the last committed offset token is read, and any batches at or before it are acknowledged without being sent again, which prevents duplicate rows when messages are redelivered.
+
+A channel can only be written to by one writer at a time, and so each output (and each partition of the source when using offsets) should use its own channel name. Batches are sent to a channel sequentially.
+
+When `+"`wait_for_commit`"+` is set messages are only acknowledged once Snowflake reports that the batch has been committed to the table.`).
+		Fields(
+			service.NewStringField(ssoFieldAccount).
+				Description("The account identifier of the Snowflake account.").
+				Example("myorg-myaccount").
+				Example("xy12345.eu-west-1"),
+			service.NewStringField(ssoFieldUser).
+				Description("The user to authenticate as."),
+			service.NewStringField(ssoFieldPrivateKeyFile).
+				Description("The path to a file containing the private key of the user."),
+			service.NewStringField(ssoFieldPrivateKeyPass).
+				Description("An optional passphrase of the private key.").
+				Optional().
+				Secret(),
+			service.NewStringField(ssoFieldRole).
+				Description("An optional role to use, otherwise the default role of the user is used.").
+				Optional(),
+			service.NewStringField(ssoFieldDatabase).
+				Description("The database of the table."),
+			service.NewStringField(ssoFieldSchema).
+				Description("The schema of the table."),
+			service.NewStringField(ssoFieldTable).
+				Description("The table to insert rows into."),
+			service.NewStringField(ssoFieldPipe).
+				Description("The pipe to write through. When empty the default pipe of the table (`<TABLE>-STREAMING`) is used.").
+				Default(""),
+			service.NewStringField(ssoFieldChannelName).
+				Description("The name of the channel to write to.").
+				Default("benthos"),
+			service.NewInterpolatedStringField(ssoFieldOffsetToken).
+				Description("An optional offset token evaluated against the last message of each batch, used in order to skip batches that have already been committed.").
+				Example(`${! meta("kafka_offset") }`).
+				Optional(),
+			service.NewBoolField(ssoFieldWaitForCommit).
+				Description("Whether to wait for each batch to be committed to the table before acknowledging it. Requires `"+ssoFieldOffsetToken+"` to be set.").
+				Default(false).
+				Advanced(),
+			service.NewStringField(ssoFieldURL).
+				Description("An optional base URL of the Snowflake account, which is otherwise derived from the account identifier.").
+				Optional().
+				Advanced(),
+			service.NewBatchPolicyField(ssoFieldBatching),
+		).
+		Example("Exactly Once From Kafka", "Stream a partition of a Kafka topic into its own channel, using the Kafka offset in order to avoid duplicates when messages are redelivered:", `
+input:
+  kafka_franz:
+    seed_brokers: [ localhost:9092 ]
+    topics: [ events:0 ]
+    batching:
+      count: 1000
+      period: 1s
+
+output:
+  snowflake_streaming:
+    account: myorg-myaccount
+    user: BENTHOS
+    private_key_file: ./rsa_key.p8
+    database: ANALYTICS
+    schema: PUBLIC
+    table: EVENTS
+    channel_name: events_0
+    offset_token: ${! meta("kafka_offset") }
+`).
+		LintRule(`root = if this.wait_for_commit.or(false) && this.offset_token.or("") == "" { [ "wait_for_commit requires offset_token to be set" ] }`)
+}
+
+func init() {
+	err := service.RegisterBatchOutput("snowflake_streaming", snowflakeStreamingOutputConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (out service.BatchOutput, batchPolicy service.BatchPolicy, maxInFlight int, err error) {
+			if batchPolicy, err = conf.FieldBatchPolicy(ssoFieldBatching); err != nil {
+				return
+			}
+			out, err = newSnowflakeStreamingWriterFromConfig(conf, mgr)
+			maxInFlight = 1
+			return
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type streamingChannel struct {
+	continuationToken string
+	committedOffset   *string
+}
+
+type snowflakeStreamingWriter struct {
+	logger *service.Logger
+
+	account     string
+	user        string
+	role        string
+	database    string
+	schema      string
+	pipe        string
+	channelName string
+	offsetToken *service.InterpolatedString
+	waitCommit  bool
+	baseURL     string
+
+	privateKey           *rsa.PrivateKey
+	publicKeyFingerprint string
+
+	httpClient httpClientI
+	nowFn      func() time.Time
+
+	mut          sync.Mutex
+	ingestURL    string
+	scopedToken  string
+	tokenExpires time.Time
+	channel      *streamingChannel
+}
+
+func newSnowflakeStreamingWriterFromConfig(conf *service.ParsedConfig, mgr *service.Resources) (*snowflakeStreamingWriter, error) {
+	s := &snowflakeStreamingWriter{
+		logger:     mgr.Logger(),
+		httpClient: http.DefaultClient,
+		nowFn:      time.Now,
+	}
+
+	var err error
+	if s.account, err = conf.FieldString(ssoFieldAccount); err != nil {
+		return nil, err
+	}
+	if s.user, err = conf.FieldString(ssoFieldUser); err != nil {
+		return nil, err
+	}
+	if conf.Contains(ssoFieldRole) {
+		if s.role, err = conf.FieldString(ssoFieldRole); err != nil {
+			return nil, err
+		}
+	}
+	if s.database, err = conf.FieldString(ssoFieldDatabase); err != nil {
+		return nil, err
+	}
+	if s.schema, err = conf.FieldString(ssoFieldSchema); err != nil {
+		return nil, err
+	}
+
+	table, err := conf.FieldString(ssoFieldTable)
+	if err != nil {
+		return nil, err
+	}
+	if s.pipe, err = conf.FieldString(ssoFieldPipe); err != nil {
+		return nil, err
+	}
+	if s.pipe == "" {
+		s.pipe = strings.ToUpper(table) + "-STREAMING"
+	}
+	if s.channelName, err = conf.FieldString(ssoFieldChannelName); err != nil {
+		return nil, err
+	}
+
+	if conf.Contains(ssoFieldOffsetToken) {
+		if s.offsetToken, err = conf.FieldInterpolatedString(ssoFieldOffsetToken); err != nil {
+			return nil, err
+		}
+	}
+	if s.waitCommit, err = conf.FieldBool(ssoFieldWaitForCommit); err != nil {
+		return nil, err
+	}
+	if s.waitCommit && s.offsetToken == nil {
+		return nil, errors.New("wait_for_commit requires offset_token to be set")
+	}
+
+	s.baseURL = "https://" + s.account + ".snowflakecomputing.com"
+	if conf.Contains(ssoFieldURL) {
+		if s.baseURL, err = conf.FieldString(ssoFieldURL); err != nil {
+			return nil, err
+		}
+		s.baseURL = strings.TrimSuffix(s.baseURL, "/")
+	}
+
+	privateKeyFile, err := conf.FieldString(ssoFieldPrivateKeyFile)
+	if err != nil {
+		return nil, err
+	}
+	var privateKeyPass string
+	if conf.Contains(ssoFieldPrivateKeyPass) {
+		if privateKeyPass, err = conf.FieldString(ssoFieldPrivateKeyPass); err != nil {
+			return nil, err
+		}
+	}
+	if s.privateKey, err = getPrivateKey(mgr.FS(), privateKeyFile, privateKeyPass); err != nil {
+		return nil, err
+	}
+	if s.publicKeyFingerprint, err = calculatePublicKeyFingerprint(s.privateKey); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// createJWT creates a key pair JWT for the user, where the account is used
+// without any region or cloud segments.
+func (s *snowflakeStreamingWriter) createJWT() (string, error) {
+	account, _, _ := strings.Cut(s.account, ".")
+	qualifiedUsername := strings.ToUpper(account + "." + s.user)
+	now := s.nowFn().UTC()
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss": qualifiedUsername + "." + s.publicKeyFingerprint,
+		"sub": qualifiedUsername,
+		"iat": now.Unix(),
+		"exp": now.Add(defaultJWTTimeout).Unix(),
+	})
+	return token.SignedString(s.privateKey)
+}
+
+// streamingAPIError is returned for unsuccessful responses from the streaming
+// API.
+type streamingAPIError struct {
+	status int
+	body   string
+}
+
+func (e *streamingAPIError) Error() string {
+	return fmt.Sprintf("received unexpected response status %v: %v", e.status, e.body)
+}
+
+func (s *snowflakeStreamingWriter) do(ctx context.Context, method, target, token, tokenType, contentType string, body []byte) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, method, target, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("X-Snowflake-Authorization-Token-Type", tokenType)
+	req.Header.Set("Accept", "application/json")
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	res, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	resBody, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return nil, &streamingAPIError{status: res.StatusCode, body: strings.TrimSpace(string(resBody))}
+	}
+	return resBody, nil
+}
+
+// refreshScopedToken discovers the ingest host of the account and exchanges a
+// key pair JWT for a token scoped to it.
+func (s *snowflakeStreamingWriter) refreshScopedToken(ctx context.Context) error {
+	if s.scopedToken != "" && s.nowFn().Before(s.tokenExpires) {
+		return nil
+	}
+
+	jwtToken, err := s.createJWT()
+	if err != nil {
+		return fmt.Errorf("failed to create JWT: %w", err)
+	}
+
+	if s.ingestURL == "" {
+		host, err := s.do(ctx, http.MethodGet, s.baseURL+"/v2/streaming/hostname", jwtToken, "KEYPAIR_JWT", "", nil)
+		if err != nil {
+			return fmt.Errorf("failed to discover ingest host: %w", err)
+		}
+		scheme, _, _ := strings.Cut(s.baseURL, "://")
+		s.ingestURL = scheme + "://" + strings.TrimSpace(string(host))
+	}
+
+	ingestHost := strings.TrimPrefix(strings.TrimPrefix(s.ingestURL, "https://"), "http://")
+	scope := ingestHost
+	if s.role != "" {
+		scope = "session:role:" + s.role + " " + ingestHost
+	}
+	form := url.Values{
+		"grant_type": []string{"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+		"scope":      []string{scope},
+	}
+
+	token, err := s.do(ctx, http.MethodPost, s.baseURL+"/oauth/token", jwtToken, "KEYPAIR_JWT", "application/x-www-form-urlencoded", []byte(form.Encode()))
+	if err != nil {
+		return fmt.Errorf("failed to obtain scoped token: %w", err)
+	}
+	s.scopedToken = strings.TrimSpace(string(token))
+	s.tokenExpires = s.nowFn().Add(ssoScopedTokenLifetime)
+	return nil
+}
+
+func (s *snowflakeStreamingWriter) pipePath() string {
+	return fmt.Sprintf("databases/%v/schemas/%v/pipes/%v", url.PathEscape(s.database), url.PathEscape(s.schema), url.PathEscape(s.pipe))
+}
+
+func (s *snowflakeStreamingWriter) ingest(ctx context.Context, method, path, contentType string, body []byte, out any) error {
+	if err := s.refreshScopedToken(ctx); err != nil {
+		return err
+	}
+	resBody, err := s.do(ctx, method, s.ingestURL+path, s.scopedToken, "OAUTH", contentType, body)
+	if err != nil {
+		return err
+	}
+	if out == nil {
+		return nil
+	}
+	return json.Unmarshal(resBody, out)
+}
+
+type channelStatus struct {
+	StatusCode               string  `json:"channel_status_code"`
+	LastCommittedOffsetToken *string `json:"last_committed_offset_token"`
+}
+
+func (s *snowflakeStreamingWriter) openChannel(ctx context.Context) (*streamingChannel, error) {
+	var res struct {
+		NextContinuationToken string        `json:"next_continuation_token"`
+		ChannelStatus         channelStatus `json:"channel_status"`
+	}
+	path := "/v2/streaming/" + s.pipePath() + "/channels/" + url.PathEscape(s.channelName)
+	if err := s.ingest(ctx, http.MethodPut, path, "application/json", []byte(`{}`), &res); err != nil {
+		return nil, err
+	}
+	return &streamingChannel{
+		continuationToken: res.NextContinuationToken,
+		committedOffset:   res.ChannelStatus.LastCommittedOffsetToken,
+	}, nil
+}
+
+func (s *snowflakeStreamingWriter) channelStatus(ctx context.Context) (*channelStatus, error) {
+	reqBody, err := json.Marshal(map[string]any{"channel_names": []string{s.channelName}})
+	if err != nil {
+		return nil, err
+	}
+	var res struct {
+		ChannelStatuses map[string]channelStatus `json:"channel_statuses"`
+	}
+	if err := s.ingest(ctx, http.MethodPost, "/v2/streaming/"+s.pipePath()+":bulk-channel-status", "application/json", reqBody, &res); err != nil {
+		return nil, err
+	}
+	status, exists := res.ChannelStatuses[s.channelName]
+	if !exists {
+		return nil, fmt.Errorf("status of channel %v was not returned", s.channelName)
+	}
+	return &status, nil
+}
+
+func (s *snowflakeStreamingWriter) Connect(ctx context.Context) error {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	if s.channel != nil {
+		return nil
+	}
+
+	channel, err := s.openChannel(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to open channel: %w", err)
+	}
+	if channel.committedOffset != nil {
+		s.logger.Debugf("Opened channel %v with committed offset token %v", s.channelName, *channel.committedOffset)
+	}
+	s.channel = channel
+	return nil
+}
+
+// offsetAtOrBefore returns whether an offset token is at or before another,
+// comparing them numerically when possible.
+func offsetAtOrBefore(offset, committed string) bool {
+	o, oErr := strconv.ParseInt(offset, 10, 64)
+	c, cErr := strconv.ParseInt(committed, 10, 64)
+	if oErr == nil && cErr == nil {
+		return o <= c
+	}
+	return offset <= committed
+}
+
+func (s *snowflakeStreamingWriter) WriteBatch(ctx context.Context, batch service.MessageBatch) error {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	if s.channel == nil {
+		return service.ErrNotConnected
+	}
+
+	var offsetToken string
+	if s.offsetToken != nil {
+		var err error
+		if offsetToken, err = batch.TryInterpolatedString(len(batch)-1, s.offsetToken); err != nil {
+			return fmt.Errorf("offset token interpolation error: %w", err)
+		}
+		if s.channel.committedOffset != nil && offsetAtOrBefore(offsetToken, *s.channel.committedOffset) {
+			s.logger.Debugf("Skipping batch with offset token %v as it has already been committed", offsetToken)
+			return nil
+		}
+	}
+
+	var body bytes.Buffer
+	for i, msg := range batch {
+		structured, err := msg.AsStructured()
+		if err != nil {
+			return fmt.Errorf("message %v: %w", i, err)
+		}
+		if _, ok := structured.(map[string]any); !ok {
+			return fmt.Errorf("message %v: expected an object, got %T", i, structured)
+		}
+		rowBytes, err := json.Marshal(structured)
+		if err != nil {
+			return fmt.Errorf("message %v: %w", i, err)
+		}
+		body.Write(rowBytes)
+		body.WriteByte('\n')
+	}
+	if body.Len() > ssoMaxRequestBytes {
+		return fmt.Errorf("batch of %v bytes exceeds the maximum request size of %v bytes, the batching policy should be reduced", body.Len(), ssoMaxRequestBytes)
+	}
+
+	query := url.Values{"continuationToken": []string{s.channel.continuationToken}}
+	if offsetToken != "" {
+		query.Set("offsetToken", offsetToken)
+	}
+	path := "/v2/streaming/data/" + s.pipePath() + "/channels/" + url.PathEscape(s.channelName) + "/rows?" + query.Encode()
+
+	var res struct {
+		NextContinuationToken string `json:"next_continuation_token"`
+	}
+	if err := s.ingest(ctx, http.MethodPost, path, "application/x-ndjson", body.Bytes(), &res); err != nil {
+		var apiErr *streamingAPIError
+		if errors.As(err, &apiErr) && apiErr.status >= 400 && apiErr.status < 500 {
+			// The channel may have been invalidated or reopened by another
+			// writer, and so we reopen it.
+			s.channel = nil
+			return fmt.Errorf("%w: %v", service.ErrNotConnected, err)
+		}
+		return fmt.Errorf("failed to append rows: %w", err)
+	}
+	s.channel.continuationToken = res.NextContinuationToken
+
+	if !s.waitCommit {
+		return nil
+	}
+	return s.waitForCommit(ctx, offsetToken)
+}
+
+func (s *snowflakeStreamingWriter) waitForCommit(ctx context.Context, offsetToken string) error {
+	for {
+		status, err := s.channelStatus(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to read channel status: %w", err)
+		}
+		if status.LastCommittedOffsetToken != nil && offsetAtOrBefore(offsetToken, *status.LastCommittedOffsetToken) {
+			s.channel.committedOffset = status.LastCommittedOffsetToken
+			return nil
+		}
+
+		select {
+		case <-time.After(time.Second):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (s *snowflakeStreamingWriter) Close(ctx context.Context) error {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	s.channel = nil
+	return nil
+}
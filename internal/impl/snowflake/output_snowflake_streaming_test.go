@@ -0,0 +1,166 @@
+package snowflake
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+type fakeStreamingAPI struct {
+	mut          sync.Mutex
+	committed    *string
+	continuation int
+	rows         []string
+	offsets      []string
+}
+
+func newFakeStreamingAPI(t *testing.T) (*fakeStreamingAPI, *httptest.Server) {
+	t.Helper()
+
+	f := &fakeStreamingAPI{}
+	mux := http.NewServeMux()
+	var server *httptest.Server
+
+	mux.HandleFunc("/v2/streaming/hostname", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "KEYPAIR_JWT", r.Header.Get("X-Snowflake-Authorization-Token-Type"))
+		_, _ = w.Write([]byte(strings.TrimPrefix(server.URL, "http://")))
+	})
+	mux.HandleFunc("/oauth/token", func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		assert.Equal(t, "urn:ietf:params:oauth:grant-type:jwt-bearer", r.Form.Get("grant_type"))
+		_, _ = w.Write([]byte("scoped"))
+	})
+	mux.HandleFunc("/v2/streaming/databases/DB/schemas/PUBLIC/pipes/EVENTS-STREAMING/channels/foo", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPut, r.Method)
+		assert.Equal(t, "Bearer scoped", r.Header.Get("Authorization"))
+
+		f.mut.Lock()
+		defer f.mut.Unlock()
+		f.continuation++
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"next_continuation_token": fmt.Sprintf("c%v", f.continuation),
+			"channel_status": map[string]any{
+				"channel_status_code":         "SUCCESS",
+				"last_committed_offset_token": f.committed,
+			},
+		})
+	})
+	mux.HandleFunc("/v2/streaming/data/databases/DB/schemas/PUBLIC/pipes/EVENTS-STREAMING/channels/foo/rows", func(w http.ResponseWriter, r *http.Request) {
+		f.mut.Lock()
+		defer f.mut.Unlock()
+
+		if r.URL.Query().Get("continuationToken") != fmt.Sprintf("c%v", f.continuation) {
+			http.Error(w, `{"code":"STALE_CONTINUATION_TOKEN_SEQUENCER"}`, http.StatusBadRequest)
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		f.rows = append(f.rows, strings.Split(strings.TrimSpace(string(body)), "\n")...)
+
+		offset := r.URL.Query().Get("offsetToken")
+		f.offsets = append(f.offsets, offset)
+		f.committed = &offset
+
+		f.continuation++
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"next_continuation_token": fmt.Sprintf("c%v", f.continuation),
+		})
+	})
+	mux.HandleFunc("/v2/streaming/databases/DB/schemas/PUBLIC/pipes/EVENTS-STREAMING:bulk-channel-status", func(w http.ResponseWriter, r *http.Request) {
+		f.mut.Lock()
+		defer f.mut.Unlock()
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"channel_statuses": map[string]any{
+				"foo": map[string]any{
+					"channel_status_code":         "SUCCESS",
+					"last_committed_offset_token": f.committed,
+				},
+			},
+		})
+	})
+
+	server = httptest.NewServer(mux)
+	return f, server
+}
+
+func TestSnowflakeStreamingOutput(t *testing.T) {
+	ctx, done := context.WithTimeout(context.Background(), time.Minute)
+	defer done()
+
+	api, server := newFakeStreamingAPI(t)
+	t.Cleanup(server.Close)
+
+	conf, err := snowflakeStreamingOutputConfig().ParseYAML(fmt.Sprintf(`
+account: myorg-myaccount
+user: foo
+private_key_file: resources/ssh_keys/snowflake_rsa_key.pem
+database: DB
+schema: PUBLIC
+table: events
+channel_name: foo
+offset_token: ${! meta("offset") }
+wait_for_commit: true
+url: %v
+`, server.URL), nil)
+	require.NoError(t, err)
+
+	out, err := newSnowflakeStreamingWriterFromConfig(conf, service.MockResources())
+	require.NoError(t, err)
+	require.NoError(t, out.Connect(ctx))
+
+	newBatch := func(offsets ...int) (batch service.MessageBatch) {
+		for _, o := range offsets {
+			msg := service.NewMessage([]byte(fmt.Sprintf(`{"id":%v}`, o)))
+			msg.MetaSetMut("offset", fmt.Sprintf("%v", o))
+			batch = append(batch, msg)
+		}
+		return
+	}
+
+	require.NoError(t, out.WriteBatch(ctx, newBatch(1, 2)))
+	require.NoError(t, out.WriteBatch(ctx, newBatch(3)))
+
+	err = out.WriteBatch(ctx, service.MessageBatch{service.NewMessage([]byte(`"nope"`))})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "expected an object")
+
+	// Reconnecting reads the committed offset and skips redelivered batches.
+	require.NoError(t, out.Close(ctx))
+	require.NoError(t, out.Connect(ctx))
+	require.NoError(t, out.WriteBatch(ctx, newBatch(2, 3)))
+	require.NoError(t, out.WriteBatch(ctx, newBatch(4)))
+
+	// A stale continuation token results in the channel being reopened.
+	api.mut.Lock()
+	api.continuation++
+	api.mut.Unlock()
+	err = out.WriteBatch(ctx, newBatch(5))
+	require.ErrorIs(t, err, service.ErrNotConnected)
+	require.NoError(t, out.Connect(ctx))
+	require.NoError(t, out.WriteBatch(ctx, newBatch(5)))
+
+	api.mut.Lock()
+	defer api.mut.Unlock()
+	assert.Equal(t, []string{`{"id":1}`, `{"id":2}`, `{"id":3}`, `{"id":4}`, `{"id":5}`}, api.rows)
+	assert.Equal(t, []string{"2", "3", "4", "5"}, api.offsets)
+}
+
+func TestOffsetAtOrBefore(t *testing.T) {
+	assert.True(t, offsetAtOrBefore("9", "10"))
+	assert.True(t, offsetAtOrBefore("10", "10"))
+	assert.False(t, offsetAtOrBefore("11", "10"))
+	assert.True(t, offsetAtOrBefore("a", "b"))
+}
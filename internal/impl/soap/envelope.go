@@ -0,0 +1,239 @@
+package soap
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"time"
+
+	bxml "github.com/benthosdev/benthos/v4/internal/impl/xml"
+)
+
+// soapVersion describes the differences between versions of the SOAP
+// protocol that matter to clients.
+type soapVersion struct {
+	namespace   string
+	contentType string
+	// actionInContentType is true when the action of a request is sent as a
+	// parameter of the content type rather than as a separate header.
+	actionInContentType bool
+}
+
+var soapVersions = map[string]soapVersion{
+	"1.1": {
+		namespace:   "http://schemas.xmlsoap.org/soap/envelope/",
+		contentType: "text/xml; charset=utf-8",
+	},
+	"1.2": {
+		namespace:           "http://www.w3.org/2003/05/soap-envelope",
+		contentType:         "application/soap+xml; charset=utf-8",
+		actionInContentType: true,
+	},
+}
+
+const (
+	wsseNamespace = "http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-secext-1.0.xsd"
+	wsuNamespace  = "http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-utility-1.0.xsd"
+
+	wssePasswordText   = "http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-username-token-profile-1.0#PasswordText"
+	wssePasswordDigest = "http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-username-token-profile-1.0#PasswordDigest"
+	wsseBase64Binary   = "http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-soap-message-security-1.0#Base64Binary"
+
+	wsuTimeLayout = "2006-01-02T15:04:05.000Z"
+)
+
+// wsSecurity describes a WS-Security header containing a username token
+// and/or a timestamp.
+type wsSecurity struct {
+	username     string
+	password     string
+	digest       bool
+	timestamp    bool
+	timestampTTL time.Duration
+}
+
+// passwordDigest returns the digest of a password as described by the
+// WS-Security username token profile.
+func passwordDigest(nonce []byte, created, password string) string {
+	h := sha1.New()
+	_, _ = h.Write(nonce)
+	_, _ = h.Write([]byte(created))
+	_, _ = h.Write([]byte(password))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+func writeEscaped(buf *bytes.Buffer, s string) {
+	_ = xml.EscapeText(buf, []byte(s))
+}
+
+// writeHeader writes the security header element to a buffer for a request
+// created at the given time.
+func (w *wsSecurity) writeHeader(buf *bytes.Buffer, now time.Time) error {
+	created := now.UTC().Format(wsuTimeLayout)
+
+	buf.WriteString(`<wsse:Security xmlns:wsse="` + wsseNamespace + `" xmlns:wsu="` + wsuNamespace + `" soap:mustUnderstand="1">`)
+	if w.timestamp {
+		buf.WriteString(`<wsu:Timestamp wsu:Id="TS-1"><wsu:Created>` + created + `</wsu:Created><wsu:Expires>`)
+		buf.WriteString(now.Add(w.timestampTTL).UTC().Format(wsuTimeLayout))
+		buf.WriteString(`</wsu:Expires></wsu:Timestamp>`)
+	}
+	if w.username != "" {
+		buf.WriteString(`<wsse:UsernameToken wsu:Id="UsernameToken-1"><wsse:Username>`)
+		writeEscaped(buf, w.username)
+		buf.WriteString(`</wsse:Username>`)
+		if w.digest {
+			nonce := make([]byte, 16)
+			if _, err := rand.Read(nonce); err != nil {
+				return fmt.Errorf("failed to generate nonce: %w", err)
+			}
+			buf.WriteString(`<wsse:Password Type="` + wssePasswordDigest + `">`)
+			buf.WriteString(passwordDigest(nonce, created, w.password))
+			buf.WriteString(`</wsse:Password><wsse:Nonce EncodingType="` + wsseBase64Binary + `">`)
+			buf.WriteString(base64.StdEncoding.EncodeToString(nonce))
+			buf.WriteString(`</wsse:Nonce><wsu:Created>` + created + `</wsu:Created>`)
+		} else {
+			buf.WriteString(`<wsse:Password Type="` + wssePasswordText + `">`)
+			writeEscaped(buf, w.password)
+			buf.WriteString(`</wsse:Password>`)
+		}
+		buf.WriteString(`</wsse:UsernameToken>`)
+	}
+	buf.WriteString(`</wsse:Security>`)
+	return nil
+}
+
+// buildEnvelope wraps the contents of a body element within an envelope,
+// adding a security header when one is configured.
+func buildEnvelope(v soapVersion, sec *wsSecurity, body []byte, now time.Time) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	buf.WriteString(`<soap:Envelope xmlns:soap="` + v.namespace + `">`)
+	if sec != nil {
+		buf.WriteString(`<soap:Header>`)
+		if err := sec.writeHeader(&buf, now); err != nil {
+			return nil, err
+		}
+		buf.WriteString(`</soap:Header>`)
+	}
+	buf.WriteString(`<soap:Body>`)
+	buf.Write(body)
+	buf.WriteString(`</soap:Body></soap:Envelope>`)
+	return buf.Bytes(), nil
+}
+
+// escapeParams returns a copy of a structured value where all strings are
+// escaped for use as XML character data.
+func escapeParams(v any) any {
+	switch t := v.(type) {
+	case string:
+		var buf bytes.Buffer
+		writeEscaped(&buf, t)
+		return buf.String()
+	case []byte:
+		return escapeParams(string(t))
+	case map[string]any:
+		m := make(map[string]any, len(t))
+		for k, e := range t {
+			m[k] = escapeParams(e)
+		}
+		return m
+	case []any:
+		s := make([]any, len(t))
+		for i, e := range t {
+			s[i] = escapeParams(e)
+		}
+		return s
+	}
+	return v
+}
+
+var errNotEnvelope = errors.New("response is not a SOAP envelope")
+
+// textOf returns the character data of an element parsed as a structured
+// value, which is an object when the element has attributes, and an array
+// when it is repeated, in which case the first element is used.
+func textOf(v any) string {
+	switch t := v.(type) {
+	case string:
+		return t
+	case map[string]any:
+		s, _ := t["#text"].(string)
+		return s
+	case []any:
+		if len(t) > 0 {
+			return textOf(t[0])
+		}
+	}
+	return ""
+}
+
+func childOf(v any, key string) any {
+	if m, ok := v.(map[string]any); ok {
+		return m[key]
+	}
+	return nil
+}
+
+// parseFault converts the fault element of a SOAP 1.1 or 1.2 response into
+// an object with common field names.
+func parseFault(f any) map[string]any {
+	res := map[string]any{}
+	setText := func(k string, v any) {
+		if s := textOf(v); s != "" {
+			res[k] = s
+		}
+	}
+
+	if childOf(f, "faultcode") != nil || childOf(f, "faultstring") != nil {
+		setText("code", childOf(f, "faultcode"))
+		setText("reason", childOf(f, "faultstring"))
+		setText("role", childOf(f, "faultactor"))
+		if d := childOf(f, "detail"); d != nil {
+			res["detail"] = d
+		}
+		return res
+	}
+
+	code := childOf(f, "Code")
+	setText("code", childOf(code, "Value"))
+	setText("subcode", childOf(childOf(code, "Subcode"), "Value"))
+	setText("reason", childOf(childOf(f, "Reason"), "Text"))
+	setText("role", childOf(f, "Role"))
+	if d := childOf(f, "Detail"); d != nil {
+		res["detail"] = d
+	}
+	return res
+}
+
+// parseResponse parses a response envelope, returning the contents of its
+// body element, or the fault that it contains.
+func parseResponse(b []byte, cast bool) (body, fault map[string]any, err error) {
+	root, err := bxml.ToMap(b, cast)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Namespace prefixes are not included within element names.
+	env, ok := root["Envelope"].(map[string]any)
+	if !ok {
+		return nil, nil, errNotEnvelope
+	}
+	switch t := env["Body"].(type) {
+	case map[string]any:
+		body = t
+	case string:
+		// An empty body element.
+		body = map[string]any{}
+	default:
+		return nil, nil, errNotEnvelope
+	}
+
+	if f, exists := body["Fault"]; exists {
+		return nil, parseFault(f), nil
+	}
+	return body, nil, nil
+}
@@ -0,0 +1,380 @@
+package soap
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"text/template"
+	"time"
+
+	"github.com/benthosdev/benthos/v4/public/bloblang"
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	spFieldURL             = "url"
+	spFieldVersion         = "version"
+	spFieldAction          = "action"
+	spFieldParamsMapping   = "params_mapping"
+	spFieldBody            = "body"
+	spFieldWSSecurity      = "ws_security"
+	spFieldWSSUsername     = "username"
+	spFieldWSSPassword     = "password"
+	spFieldWSSPasswordType = "password_type"
+	spFieldWSSTimestamp    = "timestamp"
+	spFieldWSSTimestampTTL = "timestamp_ttl"
+	spFieldHeaders         = "headers"
+	spFieldCast            = "cast"
+	spFieldTLS             = "tls"
+	spFieldTimeout         = "timeout"
+)
+
+func soapProcSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Version("4.28.0").
+		Categories("Integration").
+		Summary("Calls a SOAP web service for each message, replacing the message with the contents of the response body.").
+		Description(`
+The contents of the request body element are created by executing `+"`body`"+` as a [Go template](https://pkg.go.dev/text/template), where parameters are accessed with the dot syntax, such as `+"`{{ .user.id }}`"+`. Parameters are the result of `+"`params_mapping`"+` when it is set, and otherwise the message parsed as JSON. All string parameters are escaped before the template is executed, so values taken from messages cannot inject markup into requests.
+
+The request body is wrapped within a SOAP 1.1 or 1.2 envelope according to `+"`version`"+`, and the headers required by that version, including the action of the request, are added.
+
+### Responses
+
+The body element of the response is converted into a JSON structure following the same rules as the `+"[`xml` processor](/docs/components/processors/xml)"+`, where namespace prefixes are removed from element names:
+
+`+"```json"+`
+{
+  "GetUserResponse": {
+    "-xmlns": "http://example.com/users",
+    "User": { "Id": "123", "Name": "Jane Doe" }
+  }
+}
+`+"```"+`
+
+The status code of the response is added to messages as the metadata field `+"`http_status_code`"+`.
+
+### Faults
+
+When the response contains a fault the message is left unchanged and flagged as having failed, allowing you to use [standard processor error handling patterns](/docs/configuration/error_handling). The fault is added to the message as the metadata field `+"`soap_fault`"+`, which is an object with the fields `+"`code`"+`, `+"`subcode`"+`, `+"`reason`"+`, `+"`role`"+` and `+"`detail`"+`, where fields missing from the fault are omitted. The fields of SOAP 1.1 faults are mapped to the same names, and can be accessed with queries such as `+"`@soap_fault.reason`"+`.
+
+### WS-Security
+
+A WS-Security header containing a username token and a timestamp can be added to requests with the `+"`ws_security`"+` field, where passwords are either sent as text or as a digest of the password, a random nonce and the creation time of the request.`).
+		Fields(
+			service.NewInterpolatedStringField(spFieldURL).
+				Description("The URL of the service endpoint.").
+				Example("https://example.com/services/UserService"),
+			service.NewStringAnnotatedEnumField(spFieldVersion, map[string]string{
+				"1.1": "Envelopes use the SOAP 1.1 namespace, and the action is sent with the `SOAPAction` header.",
+				"1.2": "Envelopes use the SOAP 1.2 namespace, and the action is sent as a parameter of the `Content-Type` header.",
+			}).
+				Description("The version of the SOAP protocol to use.").
+				Default("1.1"),
+			service.NewInterpolatedStringField(spFieldAction).
+				Description("The action of requests.").
+				Example("http://example.com/users/GetUser").
+				Default(""),
+			service.NewBloblangField(spFieldParamsMapping).
+				Description("An optional [Bloblang mapping](/docs/guides/bloblang/about) that creates the parameters of the body template from each message.").
+				Example(`root.id = this.user.id`).
+				Optional(),
+			service.NewStringField(spFieldBody).
+				Description("A Go template of the contents of the request body element.").
+				Example(`<GetUser xmlns="http://example.com/users"><Id>{{ .id }}</Id></GetUser>`),
+			service.NewObjectField(spFieldWSSecurity,
+				service.NewStringField(spFieldWSSUsername).
+					Description("The username of the username token, where the token is omitted when empty.").
+					Default(""),
+				service.NewStringField(spFieldWSSPassword).
+					Description("The password of the username token.").
+					Secret().
+					Default(""),
+				service.NewStringAnnotatedEnumField(spFieldWSSPasswordType, map[string]string{
+					"text":   "The password is sent as text.",
+					"digest": "A digest of the password is sent along with a nonce and creation time.",
+				}).
+					Description("How the password is sent.").
+					Default("text"),
+				service.NewBoolField(spFieldWSSTimestamp).
+					Description("Whether to add a timestamp to the header.").
+					Default(false),
+				service.NewDurationField(spFieldWSSTimestampTTL).
+					Description("The period after which timestamps expire.").
+					Default("5m"),
+			).
+				Description("An optional WS-Security header to add to requests.").
+				Optional(),
+			service.NewInterpolatedStringMapField(spFieldHeaders).
+				Description("A map of additional HTTP headers to add to requests.").
+				Advanced().
+				Default(map[string]any{}),
+			service.NewBoolField(spFieldCast).
+				Description("Whether to try to cast values of the response that are numbers and booleans to the right type.").
+				Advanced().
+				Default(false),
+			service.NewTLSToggledField(spFieldTLS),
+			service.NewDurationField(spFieldTimeout).
+				Description("The maximum period to wait for a response.").
+				Default("30s"),
+		).
+		Example("Enriching Orders", "Here we look up the customer of each order with a SOAP service and add their details to the order, using a username token with a password digest for authentication.", `
+pipeline:
+  processors:
+    - branch:
+        processors:
+          - soap:
+              url: https://crm.example.com/services/CustomerService
+              action: http://example.com/crm/GetCustomer
+              params_mapping: 'root.id = this.customer_id'
+              body: |
+                <GetCustomer xmlns="http://example.com/crm">
+                  <CustomerId>{{ .id }}</CustomerId>
+                </GetCustomer>
+              ws_security:
+                username: benthos
+                password: ${CRM_PASSWORD}
+                password_type: digest
+                timestamp: true
+        result_map: 'root.customer = this.GetCustomerResponse.Customer'
+`)
+}
+
+func init() {
+	err := service.RegisterProcessor(
+		"soap", soapProcSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Processor, error) {
+			return newSOAPProcFromConfig(conf, mgr)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type soapProc struct {
+	log *service.Logger
+
+	url           *service.InterpolatedString
+	version       soapVersion
+	action        *service.InterpolatedString
+	paramsMapping *bloblang.Executor
+	body          *template.Template
+	security      *wsSecurity
+	headers       map[string]*service.InterpolatedString
+	cast          bool
+
+	client *http.Client
+	now    func() time.Time
+}
+
+func newSOAPProcFromConfig(conf *service.ParsedConfig, mgr *service.Resources) (*soapProc, error) {
+	s := &soapProc{
+		log: mgr.Logger(),
+		now: time.Now,
+	}
+
+	var err error
+	if s.url, err = conf.FieldInterpolatedString(spFieldURL); err != nil {
+		return nil, err
+	}
+	versionStr, err := conf.FieldString(spFieldVersion)
+	if err != nil {
+		return nil, err
+	}
+	var exists bool
+	if s.version, exists = soapVersions[versionStr]; !exists {
+		return nil, fmt.Errorf("unrecognised version: %v", versionStr)
+	}
+	if s.action, err = conf.FieldInterpolatedString(spFieldAction); err != nil {
+		return nil, err
+	}
+	if conf.Contains(spFieldParamsMapping) {
+		if s.paramsMapping, err = conf.FieldBloblang(spFieldParamsMapping); err != nil {
+			return nil, err
+		}
+	}
+
+	bodyStr, err := conf.FieldString(spFieldBody)
+	if err != nil {
+		return nil, err
+	}
+	if s.body, err = template.New(spFieldBody).Option("missingkey=error").Parse(bodyStr); err != nil {
+		return nil, fmt.Errorf("failed to parse body template: %w", err)
+	}
+
+	// The fields of ws_security all have defaults, and so the header is only
+	// added when a username or timestamp is configured.
+	wConf := conf.Namespace(spFieldWSSecurity)
+	security := &wsSecurity{}
+	if security.username, err = wConf.FieldString(spFieldWSSUsername); err != nil {
+		return nil, err
+	}
+	if security.password, err = wConf.FieldString(spFieldWSSPassword); err != nil {
+		return nil, err
+	}
+	passwordType, err := wConf.FieldString(spFieldWSSPasswordType)
+	if err != nil {
+		return nil, err
+	}
+	switch passwordType {
+	case "text":
+	case "digest":
+		security.digest = true
+	default:
+		return nil, fmt.Errorf("unrecognised password type: %v", passwordType)
+	}
+	if security.timestamp, err = wConf.FieldBool(spFieldWSSTimestamp); err != nil {
+		return nil, err
+	}
+	if security.timestampTTL, err = wConf.FieldDuration(spFieldWSSTimestampTTL); err != nil {
+		return nil, err
+	}
+	if security.username != "" || security.timestamp {
+		s.security = security
+	} else if security.password != "" {
+		return nil, errors.New("ws_security requires a username or a timestamp")
+	}
+
+	if s.headers, err = conf.FieldInterpolatedStringMap(spFieldHeaders); err != nil {
+		return nil, err
+	}
+	if s.cast, err = conf.FieldBool(spFieldCast); err != nil {
+		return nil, err
+	}
+	tlsConf, tlsEnabled, err := conf.FieldTLSToggled(spFieldTLS)
+	if err != nil {
+		return nil, err
+	}
+	timeout, err := conf.FieldDuration(spFieldTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if tlsEnabled {
+		transport.TLSClientConfig = tlsConf
+	}
+	s.client = &http.Client{
+		Transport: transport,
+		Timeout:   timeout,
+	}
+	return s, nil
+}
+
+// requestBody executes the body template with the parameters of a message.
+func (s *soapProc) requestBody(msg *service.Message) ([]byte, error) {
+	paramsMsg := msg
+	if s.paramsMapping != nil {
+		var err error
+		if paramsMsg, err = msg.BloblangQuery(s.paramsMapping); err != nil {
+			return nil, fmt.Errorf("params mapping failed: %w", err)
+		}
+		if paramsMsg == nil {
+			return nil, errors.New("params mapping returned a deleted message")
+		}
+	}
+	params, err := paramsMsg.AsStructured()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse params: %w", err)
+	}
+
+	var buf bytes.Buffer
+	if err := s.body.Execute(&buf, escapeParams(params)); err != nil {
+		return nil, fmt.Errorf("failed to execute body template: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func (s *soapProc) newRequest(ctx context.Context, msg *service.Message) (*http.Request, error) {
+	body, err := s.requestBody(msg)
+	if err != nil {
+		return nil, err
+	}
+	envelope, err := buildEnvelope(s.version, s.security, body, s.now())
+	if err != nil {
+		return nil, err
+	}
+
+	url, err := s.url.TryString(msg)
+	if err != nil {
+		return nil, fmt.Errorf("url interpolation error: %w", err)
+	}
+	action, err := s.action.TryString(msg)
+	if err != nil {
+		return nil, fmt.Errorf("action interpolation error: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(envelope))
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range s.headers {
+		hv, err := v.TryString(msg)
+		if err != nil {
+			return nil, fmt.Errorf("header %v interpolation error: %w", k, err)
+		}
+		req.Header.Set(k, hv)
+	}
+
+	contentType := s.version.contentType
+	if s.version.actionInContentType {
+		if action != "" {
+			contentType += fmt.Sprintf("; action=%q", action)
+		}
+	} else {
+		req.Header.Set("SOAPAction", fmt.Sprintf("%q", action))
+	}
+	req.Header.Set("Content-Type", contentType)
+	return req, nil
+}
+
+func (s *soapProc) Process(ctx context.Context, msg *service.Message) (service.MessageBatch, error) {
+	req, err := s.newRequest(ctx, msg)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	resBytes, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	msg.MetaSetMut("http_status_code", res.StatusCode)
+
+	// Faults are usually returned with error status codes, and so responses
+	// are parsed regardless of their status code.
+	body, fault, err := parseResponse(resBytes, s.cast)
+	if err != nil {
+		if res.StatusCode < 200 || res.StatusCode > 299 {
+			err = fmt.Errorf("unexpected status code: %v", res.StatusCode)
+		} else {
+			err = fmt.Errorf("failed to parse response: %w", err)
+		}
+		msg.SetError(err)
+		return service.MessageBatch{msg}, nil
+	}
+	if fault != nil {
+		s.log.Debugf("Service returned fault: %v", fault)
+		msg.MetaSetMut("soap_fault", fault)
+		msg.SetError(fmt.Errorf("soap fault %v: %v", fault["code"], fault["reason"]))
+		return service.MessageBatch{msg}, nil
+	}
+
+	msg.SetStructuredMut(body)
+	return service.MessageBatch{msg}, nil
+}
+
+func (s *soapProc) Close(ctx context.Context) error {
+	s.client.CloseIdleConnections()
+	return nil
+}
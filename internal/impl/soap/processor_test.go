@@ -0,0 +1,280 @@
+package soap
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+// testRequest captures the parts of a request envelope that tests check.
+type testRequest struct {
+	Header struct {
+		Security struct {
+			Timestamp struct {
+				Created string `xml:"Created"`
+				Expires string `xml:"Expires"`
+			} `xml:"Timestamp"`
+			UsernameToken struct {
+				Username string `xml:"Username"`
+				Password struct {
+					Type  string `xml:"Type,attr"`
+					Value string `xml:",chardata"`
+				} `xml:"Password"`
+				Nonce   string `xml:"Nonce"`
+				Created string `xml:"Created"`
+			} `xml:"UsernameToken"`
+		} `xml:"Security"`
+	} `xml:"Header"`
+	Body struct {
+		GetUser struct {
+			ID   string `xml:"Id"`
+			Name string `xml:"Name"`
+		} `xml:"GetUser"`
+	} `xml:"Body"`
+}
+
+type testService struct {
+	headers  http.Header
+	request  testRequest
+	response string
+	status   int
+}
+
+func (s *testService) serve(t *testing.T) string {
+	t.Helper()
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.headers = r.Header
+		b, _ := io.ReadAll(r.Body)
+		s.request = testRequest{}
+		if err := xml.Unmarshal(b, &s.request); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "text/xml")
+		if s.status != 0 {
+			w.WriteHeader(s.status)
+		}
+		_, _ = w.Write([]byte(s.response))
+	}))
+	t.Cleanup(ts.Close)
+	return ts.URL
+}
+
+func testSOAPProc(t *testing.T, confStr string) *soapProc {
+	t.Helper()
+
+	conf, err := soapProcSpec().ParseYAML(confStr, nil)
+	require.NoError(t, err)
+
+	proc, err := newSOAPProcFromConfig(conf, service.MockResources())
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, proc.Close(context.Background()))
+	})
+	return proc
+}
+
+func processMsg(t *testing.T, proc *soapProc, doc string) *service.Message {
+	t.Helper()
+
+	res, err := proc.Process(context.Background(), service.NewMessage([]byte(doc)))
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+	return res[0]
+}
+
+func TestSOAPProcessorRequest(t *testing.T) {
+	svc := &testService{response: `<?xml version="1.0"?>
+<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/">
+  <soap:Body>
+    <m:GetUserResponse xmlns:m="http://example.com/users">
+      <m:User><m:Id>123</m:Id><m:Name>Jane &amp; Joe</m:Name></m:User>
+    </m:GetUserResponse>
+  </soap:Body>
+</soap:Envelope>`}
+
+	proc := testSOAPProc(t, `
+url: `+svc.serve(t)+`
+action: http://example.com/users/${! json("op") }
+params_mapping: 'root = this.user'
+body: '<GetUser><Id>{{ .id }}</Id><Name>{{ .name }}</Name></GetUser>'
+cast: true
+`)
+
+	msg := processMsg(t, proc, `{"op":"GetUser","user":{"id":123,"name":"<b>Jane</b> & Joe"}}`)
+	require.NoError(t, msg.GetError())
+
+	b, err := msg.AsBytes()
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+  "GetUserResponse": {
+    "-m": "http://example.com/users",
+    "User": {"Id": 123, "Name": "Jane & Joe"}
+  }
+}`, string(b))
+
+	code, _ := msg.MetaGetMut("http_status_code")
+	assert.Equal(t, 200, code)
+
+	assert.Equal(t, "text/xml; charset=utf-8", svc.headers.Get("Content-Type"))
+	assert.Equal(t, `"http://example.com/users/GetUser"`, svc.headers.Get("SOAPAction"))
+	assert.Equal(t, "123", svc.request.Body.GetUser.ID)
+	assert.Equal(t, "<b>Jane</b> & Joe", svc.request.Body.GetUser.Name)
+	assert.Empty(t, svc.request.Header.Security.UsernameToken.Username)
+}
+
+func TestSOAPProcessorWSSecurity(t *testing.T) {
+	svc := &testService{response: `<env:Envelope xmlns:env="http://www.w3.org/2003/05/soap-envelope"><env:Body/></env:Envelope>`}
+	url := svc.serve(t)
+
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	proc := testSOAPProc(t, `
+url: `+url+`
+version: "1.2"
+action: GetUser
+body: '<GetUser><Id>{{ .id }}</Id></GetUser>'
+ws_security:
+  username: benthos
+  password: s3cr&t
+  timestamp: true
+  timestamp_ttl: 1m
+`)
+	proc.now = func() time.Time { return now }
+
+	msg := processMsg(t, proc, `{"id":"a"}`)
+	require.NoError(t, msg.GetError())
+	b, err := msg.AsBytes()
+	require.NoError(t, err)
+	assert.Equal(t, `{}`, string(b))
+
+	assert.Equal(t, `application/soap+xml; charset=utf-8; action="GetUser"`, svc.headers.Get("Content-Type"))
+	assert.Empty(t, svc.headers.Get("SOAPAction"))
+
+	sec := svc.request.Header.Security
+	assert.Equal(t, "2024-03-01T12:00:00.000Z", sec.Timestamp.Created)
+	assert.Equal(t, "2024-03-01T12:01:00.000Z", sec.Timestamp.Expires)
+	assert.Equal(t, "benthos", sec.UsernameToken.Username)
+	assert.Equal(t, wssePasswordText, sec.UsernameToken.Password.Type)
+	assert.Equal(t, "s3cr&t", sec.UsernameToken.Password.Value)
+	assert.Empty(t, sec.UsernameToken.Nonce)
+
+	proc = testSOAPProc(t, `
+url: `+url+`
+body: '<GetUser><Id>{{ .id }}</Id></GetUser>'
+ws_security:
+  username: benthos
+  password: s3cr&t
+  password_type: digest
+`)
+	proc.now = func() time.Time { return now }
+
+	msg = processMsg(t, proc, `{"id":"a"}`)
+	require.NoError(t, msg.GetError())
+
+	sec = svc.request.Header.Security
+	assert.Empty(t, sec.Timestamp.Created)
+	assert.Equal(t, wssePasswordDigest, sec.UsernameToken.Password.Type)
+	assert.Equal(t, "2024-03-01T12:00:00.000Z", sec.UsernameToken.Created)
+
+	nonce, err := base64.StdEncoding.DecodeString(sec.UsernameToken.Nonce)
+	require.NoError(t, err)
+	assert.Len(t, nonce, 16)
+	assert.Equal(t, passwordDigest(nonce, sec.UsernameToken.Created, "s3cr&t"), sec.UsernameToken.Password.Value)
+}
+
+func TestSOAPProcessorFaults(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		version  string
+		response string
+		fault    map[string]any
+	}{
+		{
+			name:    "1.1",
+			version: "1.1",
+			response: `<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/"><soap:Body><soap:Fault>
+  <faultcode>soap:Client</faultcode>
+  <faultstring>Unknown user</faultstring>
+  <detail><UserFault><Id>123</Id></UserFault></detail>
+</soap:Fault></soap:Body></soap:Envelope>`,
+			fault: map[string]any{
+				"code":   "soap:Client",
+				"reason": "Unknown user",
+				"detail": map[string]any{"UserFault": map[string]any{"Id": "123"}},
+			},
+		},
+		{
+			name:    "1.2",
+			version: "1.2",
+			response: `<env:Envelope xmlns:env="http://www.w3.org/2003/05/soap-envelope"><env:Body><env:Fault>
+  <env:Code><env:Value>env:Sender</env:Value><env:Subcode><env:Value>m:UnknownUser</env:Value></env:Subcode></env:Code>
+  <env:Reason><env:Text xml:lang="en">Unknown user</env:Text></env:Reason>
+  <env:Role>http://example.com/users</env:Role>
+</env:Fault></env:Body></env:Envelope>`,
+			fault: map[string]any{
+				"code":    "env:Sender",
+				"subcode": "m:UnknownUser",
+				"reason":  "Unknown user",
+				"role":    "http://example.com/users",
+			},
+		},
+	} {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			svc := &testService{response: test.response, status: http.StatusInternalServerError}
+			proc := testSOAPProc(t, `
+url: `+svc.serve(t)+`
+version: "`+test.version+`"
+body: '<GetUser><Id>{{ .id }}</Id></GetUser>'
+`)
+
+			msg := processMsg(t, proc, `{"id":"123"}`)
+			assert.ErrorContains(t, msg.GetError(), "Unknown user")
+
+			b, err := msg.AsBytes()
+			require.NoError(t, err)
+			assert.Equal(t, `{"id":"123"}`, string(b))
+
+			fault, _ := msg.MetaGetMut("soap_fault")
+			assert.Equal(t, test.fault, fault)
+			code, _ := msg.MetaGetMut("http_status_code")
+			assert.Equal(t, 500, code)
+		})
+	}
+}
+
+func TestSOAPProcessorErrors(t *testing.T) {
+	svc := &testService{response: `Service Unavailable`, status: http.StatusServiceUnavailable}
+	proc := testSOAPProc(t, `
+url: `+svc.serve(t)+`
+body: '<GetUser><Id>{{ .id }}</Id></GetUser>'
+`)
+
+	msg := processMsg(t, proc, `{"id":"123"}`)
+	assert.EqualError(t, msg.GetError(), "unexpected status code: 503")
+
+	_, err := proc.Process(context.Background(), service.NewMessage([]byte(`{"name":"foo"}`)))
+	assert.ErrorContains(t, err, "failed to execute body template")
+
+	conf, err := soapProcSpec().ParseYAML(`
+url: http://localhost
+body: '<GetUser>'
+ws_security:
+  password: foo
+`, nil)
+	require.NoError(t, err)
+	_, err = newSOAPProcFromConfig(conf, service.MockResources())
+	assert.Error(t, err)
+}
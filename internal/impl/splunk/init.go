@@ -1,16 +0,0 @@
-package splunk
-
-import (
-	_ "embed"
-
-	"github.com/benthosdev/benthos/v4/public/service"
-)
-
-//go:embed template_output.yaml
-var outputTemplate []byte
-
-func init() {
-	if err := service.RegisterTemplateYAML(string(outputTemplate)); err != nil {
-		panic(err)
-	}
-}
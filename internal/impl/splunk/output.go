@@ -0,0 +1,595 @@
+package splunk
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofrs/uuid"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	hecoFieldURL             = "url"
+	hecoFieldToken           = "token"
+	hecoFieldGzip            = "gzip"
+	hecoFieldEventHost       = "event_host"
+	hecoFieldEventSource     = "event_source"
+	hecoFieldEventSourcetype = "event_sourcetype"
+	hecoFieldEventIndex      = "event_index"
+	hecoFieldChannel         = "channel"
+	hecoFieldAck             = "indexer_ack"
+	hecoFieldAckEnabled      = "enabled"
+	hecoFieldAckPollInterval = "poll_interval"
+	hecoFieldAckTimeout      = "timeout"
+	hecoFieldBatchingCount   = "batching_count"
+	hecoFieldBatchingPeriod  = "batching_period"
+	hecoFieldBatchingSize    = "batching_byte_size"
+	hecoFieldRateLimit       = "rate_limit"
+	hecoFieldMaxInFlight     = "max_in_flight"
+	hecoFieldSkipCertVerify  = "skip_cert_verify"
+)
+
+// HEC response codes that are handled explicitly, as documented at
+// https://docs.splunk.com/Documentation/Splunk/latest/Data/TroubleshootHTTPEventCollector
+const (
+	hecCodeSuccess            = 0
+	hecCodeServerBusy         = 9
+	hecCodeChannelMissing     = 10
+	hecCodeInvalidDataChannel = 11
+)
+
+func hecOutputSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Services").
+		Summary("Writes messages to a Splunk HTTP Event Collector.").
+		Description(`
+This output POSTs messages to a Splunk HTTP Event Collector (HEC) using token based authentication. Whether events are sent to the event or raw endpoint is determined by the path of the `+"`url`"+`, where paths ending in `+"`/raw`"+` target the raw endpoint.
+
+### Event Endpoint
+
+When writing to the event endpoint each message is expected to be a [valid event JSON](https://docs.splunk.com/Documentation/SplunkCloud/latest/Data/FormateventsforHTTPEventCollector) object containing an `+"`event`"+` field. Messages that are not in this format are wrapped as the `+"`event`"+` field of a new object. The `+"`event_host`, `event_source`, `event_sourcetype` and `event_index`"+` fields, when not empty, override the respective fields of each event.
+
+### Raw Endpoint
+
+When writing to the raw endpoint the contents of each message are sent as a line of data. The `+"`event_host`, `event_source`, `event_sourcetype` and `event_index`"+` fields are sent as query parameters, and therefore messages of a batch that resolve different values are sent in separate requests.
+
+### Indexer Acknowledgement
+
+When indexer acknowledgement is enabled for the HEC token the `+"`indexer_ack`"+` field should also be enabled, in which case a batch is only acknowledged once the acknowledgement endpoint reports that the data has been indexed. Requests are sent with a data channel identifier taken from the `+"`channel`"+` field, or generated when the output is created. When Splunk reports that a generated channel is invalid a new channel is created and the batch is retried.`+service.OutputPerformanceDocs(true, true)).
+		Fields(
+			service.NewURLField(hecoFieldURL).
+				Description("Full HTTP Event Collector (HEC) URL.").
+				Example("https://foobar.splunkcloud.com/services/collector/event").
+				Example("https://foobar.splunkcloud.com/services/collector/raw"),
+			service.NewStringField(hecoFieldToken).
+				Description("A bot token used for authentication.").
+				Secret(),
+			service.NewBoolField(hecoFieldGzip).
+				Description("Enable gzip compression").
+				Default(false),
+			service.NewInterpolatedStringField(hecoFieldEventHost).
+				Description("Set the host value to assign to the event data. Overrides existing host field if present.").
+				Default(""),
+			service.NewInterpolatedStringField(hecoFieldEventSource).
+				Description("Set the source value to assign to the event data. Overrides existing source field if present.").
+				Default(""),
+			service.NewInterpolatedStringField(hecoFieldEventSourcetype).
+				Description("Set the sourcetype value to assign to the event data. Overrides existing sourcetype field if present.").
+				Default(""),
+			service.NewInterpolatedStringField(hecoFieldEventIndex).
+				Description("Set the index value to assign to the event data. Overrides existing index field if present.").
+				Default(""),
+			service.NewStringField(hecoFieldChannel).
+				Description("A data channel identifier (a GUID) to send requests with. If left empty a channel is generated.").
+				Advanced().
+				Version("4.28.0").
+				Default(""),
+			service.NewObjectField(hecoFieldAck,
+				service.NewBoolField(hecoFieldAckEnabled).
+					Description("Whether to wait for indexer acknowledgement of each request before acknowledging a batch.").
+					Default(false),
+				service.NewDurationField(hecoFieldAckPollInterval).
+					Description("The period to wait between polls of the acknowledgement endpoint.").
+					Default("1s"),
+				service.NewDurationField(hecoFieldAckTimeout).
+					Description("The maximum period to wait for a request to be acknowledged before the batch is considered failed and is retried.").
+					Default("5m"),
+			).
+				Description("Configures waiting for indexer acknowledgement of sent data.").
+				Advanced().
+				Version("4.28.0"),
+			service.NewIntField(hecoFieldBatchingCount).
+				Description("A number of messages at which the batch should be flushed. If 0 disables count based batching.").
+				Default(100),
+			service.NewStringField(hecoFieldBatchingPeriod).
+				Description("A period in which an incomplete batch should be flushed regardless of its size.").
+				Default("30s"),
+			service.NewIntField(hecoFieldBatchingSize).
+				Description("An amount of bytes at which the batch should be flushed. If 0 disables size based batching. Splunk Cloud recommends limiting content length of HEC payload to 1 MB.").
+				Default(1000000),
+			service.NewStringField(hecoFieldRateLimit).
+				Description("An optional rate limit resource to restrict API requests with.").
+				Advanced().
+				Default(""),
+			service.NewIntField(hecoFieldMaxInFlight).
+				Description("The maximum number of parallel message batches to have in flight at any given time.").
+				Advanced().
+				Default(64),
+			service.NewBoolField(hecoFieldSkipCertVerify).
+				Description("Whether to skip server side certificate verification.").
+				Advanced().
+				Default(false),
+		).
+		Example("Indexer Acknowledgement", "Write events to an index named after the service that produced them, only acknowledging messages once they have been indexed.", `
+output:
+  splunk_hec:
+    url: https://foobar.splunkcloud.com/services/collector/event
+    token: ${SPLUNK_TOKEN}
+    gzip: true
+    event_index: ${! @service.or("main") }
+    event_sourcetype: _json
+    indexer_ack:
+      enabled: true
+`)
+}
+
+func init() {
+	err := service.RegisterBatchOutput("splunk_hec", hecOutputSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (out service.BatchOutput, batchPolicy service.BatchPolicy, maxInFlight int, err error) {
+			if maxInFlight, err = conf.FieldInt(hecoFieldMaxInFlight); err != nil {
+				return
+			}
+			if batchPolicy.Count, err = conf.FieldInt(hecoFieldBatchingCount); err != nil {
+				return
+			}
+			if batchPolicy.Period, err = conf.FieldString(hecoFieldBatchingPeriod); err != nil {
+				return
+			}
+			if batchPolicy.ByteSize, err = conf.FieldInt(hecoFieldBatchingSize); err != nil {
+				return
+			}
+			out, err = newHECWriterFromParsed(conf, mgr)
+			return
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type hecWriter struct {
+	log *service.Logger
+	mgr *service.Resources
+
+	url       string
+	ackURL    string
+	raw       bool
+	token     string
+	gzip      bool
+	rateLimit string
+
+	host       *service.InterpolatedString
+	source     *service.InterpolatedString
+	sourcetype *service.InterpolatedString
+	index      *service.InterpolatedString
+
+	ackEnabled      bool
+	ackPollInterval time.Duration
+	ackTimeout      time.Duration
+
+	client *http.Client
+
+	channelMut       sync.RWMutex
+	channel          string
+	channelGenerated bool
+}
+
+func newHECWriterFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*hecWriter, error) {
+	h := &hecWriter{
+		log: mgr.Logger(),
+		mgr: mgr,
+	}
+
+	var err error
+	if h.url, err = conf.FieldString(hecoFieldURL); err != nil {
+		return nil, err
+	}
+	u, err := url.Parse(h.url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse url: %w", err)
+	}
+	h.raw = strings.HasSuffix(strings.TrimSuffix(strings.TrimSuffix(u.Path, "/"), "/1.0"), "/raw")
+
+	if h.token, err = conf.FieldString(hecoFieldToken); err != nil {
+		return nil, err
+	}
+	if h.gzip, err = conf.FieldBool(hecoFieldGzip); err != nil {
+		return nil, err
+	}
+	if h.host, err = conf.FieldInterpolatedString(hecoFieldEventHost); err != nil {
+		return nil, err
+	}
+	if h.source, err = conf.FieldInterpolatedString(hecoFieldEventSource); err != nil {
+		return nil, err
+	}
+	if h.sourcetype, err = conf.FieldInterpolatedString(hecoFieldEventSourcetype); err != nil {
+		return nil, err
+	}
+	if h.index, err = conf.FieldInterpolatedString(hecoFieldEventIndex); err != nil {
+		return nil, err
+	}
+
+	if h.channel, err = conf.FieldString(hecoFieldChannel); err != nil {
+		return nil, err
+	}
+	if h.channel == "" {
+		if h.channel, err = newHECChannel(); err != nil {
+			return nil, err
+		}
+		h.channelGenerated = true
+	}
+
+	ackConf := conf.Namespace(hecoFieldAck)
+	if h.ackEnabled, err = ackConf.FieldBool(hecoFieldAckEnabled); err != nil {
+		return nil, err
+	}
+	if h.ackPollInterval, err = ackConf.FieldDuration(hecoFieldAckPollInterval); err != nil {
+		return nil, err
+	}
+	if h.ackTimeout, err = ackConf.FieldDuration(hecoFieldAckTimeout); err != nil {
+		return nil, err
+	}
+	if h.ackEnabled {
+		idx := strings.Index(u.Path, "/services/collector")
+		if idx < 0 {
+			return nil, errors.New("indexer acknowledgement requires a url with a /services/collector path")
+		}
+		ackURL := *u
+		ackURL.Path = u.Path[:idx] + "/services/collector/ack"
+		ackURL.RawQuery = ""
+		h.ackURL = ackURL.String()
+	}
+
+	if h.rateLimit, err = conf.FieldString(hecoFieldRateLimit); err != nil {
+		return nil, err
+	}
+	if h.rateLimit != "" && !mgr.HasRateLimit(h.rateLimit) {
+		return nil, fmt.Errorf("rate limit resource '%v' was not found", h.rateLimit)
+	}
+
+	var skipCertVerify bool
+	if skipCertVerify, err = conf.FieldBool(hecoFieldSkipCertVerify); err != nil {
+		return nil, err
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if skipCertVerify {
+		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	}
+	h.client = &http.Client{Transport: transport}
+	return h, nil
+}
+
+func newHECChannel() (string, error) {
+	id, err := uuid.NewV4()
+	if err != nil {
+		return "", fmt.Errorf("failed to generate channel: %w", err)
+	}
+	return id.String(), nil
+}
+
+func (h *hecWriter) Connect(ctx context.Context) error {
+	return nil
+}
+
+func (h *hecWriter) WriteBatch(ctx context.Context, batch service.MessageBatch) error {
+	if !h.raw {
+		var buf bytes.Buffer
+		for i := range batch {
+			event, err := h.eventFrom(batch, i)
+			if err != nil {
+				return err
+			}
+			buf.Write(event)
+			buf.WriteByte('\n')
+		}
+		return h.send(ctx, nil, buf.Bytes())
+	}
+
+	// Metadata of the raw endpoint is provided as query parameters, and
+	// therefore messages are grouped into a request per distinct set.
+	type rawGroup struct {
+		query url.Values
+		body  bytes.Buffer
+	}
+	var groups []*rawGroup
+	groupsByKey := map[string]*rawGroup{}
+	for i, m := range batch {
+		query, err := h.metadataFrom(batch, i)
+		if err != nil {
+			return err
+		}
+		key := query.Encode()
+		g, exists := groupsByKey[key]
+		if !exists {
+			g = &rawGroup{query: query}
+			groupsByKey[key] = g
+			groups = append(groups, g)
+		}
+		mBytes, err := m.AsBytes()
+		if err != nil {
+			return err
+		}
+		g.body.Write(mBytes)
+		g.body.WriteByte('\n')
+	}
+	for _, g := range groups {
+		if err := h.send(ctx, g.query, g.body.Bytes()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// metadataFrom resolves the host, source, sourcetype and index of a message,
+// omitting any that are empty.
+func (h *hecWriter) metadataFrom(batch service.MessageBatch, i int) (url.Values, error) {
+	values := url.Values{}
+	for _, f := range []struct {
+		key  string
+		istr *service.InterpolatedString
+	}{
+		{key: "host", istr: h.host},
+		{key: "source", istr: h.source},
+		{key: "sourcetype", istr: h.sourcetype},
+		{key: "index", istr: h.index},
+	} {
+		v, err := batch.TryInterpolatedString(i, f.istr)
+		if err != nil {
+			return nil, fmt.Errorf("%v interpolation error: %w", f.key, err)
+		}
+		if v != "" {
+			values.Set(f.key, v)
+		}
+	}
+	return values, nil
+}
+
+func (h *hecWriter) eventFrom(batch service.MessageBatch, i int) ([]byte, error) {
+	var event map[string]any
+	if structured, err := batch[i].AsStructuredMut(); err == nil {
+		if obj, ok := structured.(map[string]any); ok {
+			if _, exists := obj["event"]; exists {
+				event = obj
+			}
+		}
+	}
+	if event == nil {
+		mBytes, err := batch[i].AsBytes()
+		if err != nil {
+			return nil, err
+		}
+		event = map[string]any{"event": string(mBytes)}
+	}
+
+	metadata, err := h.metadataFrom(batch, i)
+	if err != nil {
+		return nil, err
+	}
+	for k := range metadata {
+		event[k] = metadata.Get(k)
+	}
+	return json.Marshal(event)
+}
+
+func (h *hecWriter) waitForAccess(ctx context.Context) error {
+	if h.rateLimit == "" {
+		return nil
+	}
+	for {
+		var period time.Duration
+		var err error
+		if rerr := h.mgr.AccessRateLimit(ctx, h.rateLimit, func(rl service.RateLimit) {
+			period, err = rl.Access(ctx)
+		}); rerr != nil {
+			err = rerr
+		}
+		if err != nil {
+			h.log.Errorf("Rate limit error: %v\n", err)
+			period = time.Second
+		}
+		if period <= 0 {
+			return nil
+		}
+		select {
+		case <-time.After(period):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (h *hecWriter) getChannel() string {
+	h.channelMut.RLock()
+	defer h.channelMut.RUnlock()
+	return h.channel
+}
+
+// rotateChannel replaces a generated channel that Splunk no longer recognises.
+// Channels provided by config are left untouched.
+func (h *hecWriter) rotateChannel(stale string) {
+	if !h.channelGenerated {
+		return
+	}
+	h.channelMut.Lock()
+	defer h.channelMut.Unlock()
+	if h.channel != stale {
+		return
+	}
+	channel, err := newHECChannel()
+	if err != nil {
+		h.log.Errorf("Failed to rotate channel: %v", err)
+		return
+	}
+	h.log.Warnf("Replacing data channel %v as it was rejected by Splunk", stale)
+	h.channel = channel
+}
+
+type hecResponse struct {
+	Text  string `json:"text"`
+	Code  int    `json:"code"`
+	AckID *int64 `json:"ackId"`
+}
+
+func (h *hecWriter) post(ctx context.Context, reqURL, channel string, body []byte, compress bool) (int, []byte, error) {
+	var contentEncoding string
+	if compress {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		if _, err := zw.Write(body); err != nil {
+			return 0, nil, err
+		}
+		if err := zw.Close(); err != nil {
+			return 0, nil, err
+		}
+		body, contentEncoding = buf.Bytes(), "gzip"
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, reqURL, bytes.NewReader(body))
+	if err != nil {
+		return 0, nil, err
+	}
+	req.Header.Set("Authorization", "Splunk "+h.token)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Splunk-Request-Channel", channel)
+	if contentEncoding != "" {
+		req.Header.Set("Content-Encoding", contentEncoding)
+	}
+
+	res, err := h.client.Do(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer res.Body.Close()
+
+	resBytes, err := io.ReadAll(res.Body)
+	if err != nil {
+		return 0, nil, err
+	}
+	return res.StatusCode, resBytes, nil
+}
+
+func (h *hecWriter) send(ctx context.Context, query url.Values, body []byte) error {
+	if err := h.waitForAccess(ctx); err != nil {
+		return err
+	}
+
+	reqURL := h.url
+	if len(query) > 0 {
+		sep := "?"
+		if strings.Contains(reqURL, "?") {
+			sep = "&"
+		}
+		reqURL += sep + query.Encode()
+	}
+
+	channel := h.getChannel()
+	status, resBytes, err := h.post(ctx, reqURL, channel, body, h.gzip)
+	if err != nil {
+		return err
+	}
+
+	var res hecResponse
+	if err := json.Unmarshal(resBytes, &res); err != nil {
+		if status != http.StatusOK {
+			return fmt.Errorf("request failed with status %v: %s", status, resBytes)
+		}
+		return fmt.Errorf("failed to parse response: %w", err)
+	}
+	if status != http.StatusOK && res.Code == hecCodeSuccess {
+		return fmt.Errorf("request failed with status %v: %s", status, resBytes)
+	}
+
+	switch res.Code {
+	case hecCodeSuccess:
+	case hecCodeChannelMissing, hecCodeInvalidDataChannel:
+		h.rotateChannel(channel)
+		return fmt.Errorf("data channel rejected (code %v): %v", res.Code, res.Text)
+	case hecCodeServerBusy:
+		return fmt.Errorf("server is busy (code %v): %v", res.Code, res.Text)
+	default:
+		return fmt.Errorf("request rejected (code %v): %v", res.Code, res.Text)
+	}
+
+	if !h.ackEnabled {
+		return nil
+	}
+	if res.AckID == nil {
+		return errors.New("indexer acknowledgement is enabled but no ackId was returned, check that it is also enabled for the token")
+	}
+	return h.waitForAck(ctx, channel, *res.AckID)
+}
+
+func (h *hecWriter) waitForAck(ctx context.Context, channel string, ackID int64) error {
+	ctx, done := context.WithTimeout(ctx, h.ackTimeout)
+	defer done()
+
+	reqURL := h.ackURL + "?channel=" + url.QueryEscape(channel)
+	reqBody, err := json.Marshal(map[string]any{"acks": []int64{ackID}})
+	if err != nil {
+		return err
+	}
+	ackKey := strconv.FormatInt(ackID, 10)
+
+	for {
+		acked, err := h.pollAck(ctx, reqURL, channel, reqBody, ackKey)
+		if err != nil {
+			h.log.Warnf("Failed to poll indexer acknowledgement %v: %v", ackID, err)
+		} else if acked {
+			return nil
+		}
+
+		select {
+		case <-time.After(h.ackPollInterval):
+		case <-ctx.Done():
+			return fmt.Errorf("timed out waiting for indexer acknowledgement %v", ackID)
+		}
+	}
+}
+
+func (h *hecWriter) pollAck(ctx context.Context, reqURL, channel string, body []byte, ackKey string) (bool, error) {
+	status, resBytes, err := h.post(ctx, reqURL, channel, body, false)
+	if err != nil {
+		return false, err
+	}
+	if status != http.StatusOK {
+		return false, fmt.Errorf("request failed with status %v: %s", status, resBytes)
+	}
+
+	var ackRes struct {
+		Acks map[string]bool `json:"acks"`
+	}
+	if err := json.Unmarshal(resBytes, &ackRes); err != nil {
+		return false, fmt.Errorf("failed to parse response: %w", err)
+	}
+	return ackRes.Acks[ackKey], nil
+}
+
+func (h *hecWriter) Close(ctx context.Context) error {
+	h.client.CloseIdleConnections()
+	return nil
+}
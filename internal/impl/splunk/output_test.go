@@ -0,0 +1,200 @@
+package splunk
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+type fakeHEC struct {
+	mut sync.Mutex
+
+	channels []string
+	queries  []string
+	lines    []string
+
+	nextAckID  int64
+	ackPolls   int
+	rejectOnce bool
+}
+
+func (f *fakeHEC) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mut.Lock()
+	defer f.mut.Unlock()
+
+	if r.Header.Get("Authorization") != "Splunk footoken" {
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write([]byte(`{"text":"Invalid authorization","code":3}`))
+		return
+	}
+
+	channel := r.Header.Get("X-Splunk-Request-Channel")
+	if r.URL.Path == "/services/collector/ack" {
+		if channel != r.URL.Query().Get("channel") {
+			http.Error(w, "channel mismatch", http.StatusBadRequest)
+			return
+		}
+		f.ackPolls++
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"acks": map[string]bool{"0": f.ackPolls > 1},
+		})
+		return
+	}
+
+	if f.rejectOnce {
+		f.rejectOnce = false
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"text":"Invalid data channel","code":11}`))
+		return
+	}
+
+	var body io.Reader = r.Body
+	if r.Header.Get("Content-Encoding") == "gzip" {
+		zr, err := gzip.NewReader(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		body = zr
+	}
+
+	f.channels = append(f.channels, channel)
+	f.queries = append(f.queries, r.URL.RawQuery)
+	scanner := bufio.NewScanner(body)
+	for scanner.Scan() {
+		f.lines = append(f.lines, scanner.Text())
+	}
+
+	ackID := f.nextAckID
+	f.nextAckID++
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"text":  "Success",
+		"code":  0,
+		"ackId": ackID,
+	})
+}
+
+func testHECWriter(t *testing.T, confStr string) *hecWriter {
+	t.Helper()
+
+	pConf, err := hecOutputSpec().ParseYAML(confStr, nil)
+	require.NoError(t, err)
+
+	w, err := newHECWriterFromParsed(pConf, service.MockResources())
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = w.Close(context.Background())
+	})
+	return w
+}
+
+func TestHECEventEndpoint(t *testing.T) {
+	fake := &fakeHEC{}
+	ts := httptest.NewServer(fake)
+	t.Cleanup(ts.Close)
+
+	w := testHECWriter(t, `
+url: `+ts.URL+`/services/collector/event
+token: footoken
+gzip: true
+event_index: ${! @index.or("") }
+event_sourcetype: _json
+indexer_ack:
+  enabled: true
+  poll_interval: 1ms
+`)
+	assert.False(t, w.raw)
+	assert.Equal(t, ts.URL+"/services/collector/ack", w.ackURL)
+
+	msgA := service.NewMessage([]byte(`{"event":{"foo":"bar"},"host":"a"}`))
+	msgA.MetaSetMut("index", "main")
+	msgB := service.NewMessage([]byte(`hello world`))
+
+	require.NoError(t, w.WriteBatch(context.Background(), service.MessageBatch{msgA, msgB}))
+
+	fake.mut.Lock()
+	defer fake.mut.Unlock()
+
+	require.Len(t, fake.lines, 2)
+	assert.JSONEq(t, `{"event":{"foo":"bar"},"host":"a","index":"main","sourcetype":"_json"}`, fake.lines[0])
+	assert.JSONEq(t, `{"event":"hello world","sourcetype":"_json"}`, fake.lines[1])
+	assert.Equal(t, []string{w.channel}, fake.channels)
+	assert.Equal(t, 2, fake.ackPolls)
+}
+
+func TestHECRawEndpoint(t *testing.T) {
+	fake := &fakeHEC{}
+	ts := httptest.NewServer(fake)
+	t.Cleanup(ts.Close)
+
+	w := testHECWriter(t, `
+url: `+ts.URL+`/services/collector/raw
+token: footoken
+event_source: ${! @source }
+`)
+	assert.True(t, w.raw)
+
+	var batch service.MessageBatch
+	for _, s := range []string{"a", "b", "a"} {
+		m := service.NewMessage([]byte("line from " + s))
+		m.MetaSetMut("source", s)
+		batch = append(batch, m)
+	}
+	require.NoError(t, w.WriteBatch(context.Background(), batch))
+
+	fake.mut.Lock()
+	defer fake.mut.Unlock()
+
+	assert.Equal(t, []string{"source=a", "source=b"}, fake.queries)
+	assert.Equal(t, []string{"line from a", "line from a", "line from b"}, fake.lines)
+}
+
+func TestHECChannelRotation(t *testing.T) {
+	fake := &fakeHEC{rejectOnce: true}
+	ts := httptest.NewServer(fake)
+	t.Cleanup(ts.Close)
+
+	w := testHECWriter(t, `
+url: `+ts.URL+`/services/collector/event
+token: footoken
+`)
+	firstChannel := w.getChannel()
+
+	batch := service.MessageBatch{service.NewMessage([]byte(`{"event":"foo"}`))}
+	require.Error(t, w.WriteBatch(context.Background(), batch))
+	require.NoError(t, w.WriteBatch(context.Background(), batch))
+
+	secondChannel := w.getChannel()
+	assert.NotEqual(t, firstChannel, secondChannel)
+
+	fake.mut.Lock()
+	defer fake.mut.Unlock()
+	assert.Equal(t, []string{secondChannel}, fake.channels)
+}
+
+func TestHECConfiguredChannelNotRotated(t *testing.T) {
+	fake := &fakeHEC{rejectOnce: true}
+	ts := httptest.NewServer(fake)
+	t.Cleanup(ts.Close)
+
+	w := testHECWriter(t, `
+url: `+ts.URL+`/services/collector/event
+token: footoken
+channel: 0aeeac95-ac74-4aa9-b30d-6c4c0ac581ba
+`)
+
+	batch := service.MessageBatch{service.NewMessage([]byte(`{"event":"foo"}`))}
+	require.Error(t, w.WriteBatch(context.Background(), batch))
+	assert.Equal(t, "0aeeac95-ac74-4aa9-b30d-6c4c0ac581ba", w.getChannel())
+}
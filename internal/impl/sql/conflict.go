@@ -0,0 +1,171 @@
+package sql
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	conflictField              = "conflict"
+	conflictFieldColumns       = "columns"
+	conflictFieldAction        = "action"
+	conflictFieldUpdateColumns = "update_columns"
+
+	conflictActionUpdate    = "update"
+	conflictActionDoNothing = "do_nothing"
+)
+
+func conflictFieldSpec() *service.ConfigField {
+	return service.NewObjectField(conflictField,
+		service.NewStringListField(conflictFieldColumns).
+			Description("The columns of a unique constraint or primary key that identify conflicting rows. Conflicts are only handled when at least one column is specified. For the `mysql` driver conflicts are detected with any unique index, but these columns are still required in order to generate the `do_nothing` action.").
+			Example([]string{"id"}),
+		service.NewStringEnumField(conflictFieldAction, conflictActionUpdate, conflictActionDoNothing).
+			Description("The action to take when an inserted row conflicts with an existing row, either updating the existing row or leaving it untouched.").
+			Default(conflictActionUpdate),
+		service.NewStringListField(conflictFieldUpdateColumns).
+			Description("The columns to update when the action is `update`. When empty all inserted columns other than the conflict columns are updated.").
+			Example([]string{"name", "updated_at"}).
+			Default([]any{}),
+	).
+		Description(`Handle rows that conflict with existing rows of the table, making inserts idempotent. The correct syntax is generated for each driver: ` + "`ON CONFLICT`" + ` for ` + "`postgres` and `sqlite`, `ON DUPLICATE KEY UPDATE` for `mysql`, and a `MERGE`" + ` statement for ` + "`mssql`" + `. Other drivers are not supported. This field cannot be combined with ` + "`suffix`" + `.`).
+		Optional().
+		Advanced()
+}
+
+type conflictConfig struct {
+	columns       []string
+	action        string
+	updateColumns []string
+}
+
+// conflictConfigFromParsed returns a nil config when no conflict columns are
+// specified, in which case conflicts are not handled.
+func conflictConfigFromParsed(conf *service.ParsedConfig, driver string, columns []string) (*conflictConfig, error) {
+	cConf := conf.Namespace(conflictField)
+
+	var c conflictConfig
+	var err error
+	if c.columns, err = cConf.FieldStringList(conflictFieldColumns); err != nil {
+		return nil, err
+	}
+	if len(c.columns) == 0 {
+		return nil, nil
+	}
+	if c.action, err = cConf.FieldString(conflictFieldAction); err != nil {
+		return nil, err
+	}
+	if c.updateColumns, err = cConf.FieldStringList(conflictFieldUpdateColumns); err != nil {
+		return nil, err
+	}
+
+	switch driver {
+	case "postgres", "sqlite", "mysql", "mssql":
+	default:
+		return nil, fmt.Errorf("conflict handling is not supported by the %v driver", driver)
+	}
+
+	if c.action == conflictActionUpdate && len(c.updateColumns) == 0 {
+		isConflictCol := map[string]struct{}{}
+		for _, col := range c.columns {
+			isConflictCol[col] = struct{}{}
+		}
+		for _, col := range columns {
+			if _, exists := isConflictCol[col]; !exists {
+				c.updateColumns = append(c.updateColumns, col)
+			}
+		}
+		if len(c.updateColumns) == 0 {
+			return nil, errors.New("there are no columns to update on conflict, use the do_nothing action instead")
+		}
+	}
+	return &c, nil
+}
+
+// insertSuffix returns the clause to append to a multi-row INSERT statement
+// for drivers that support conflict handling within an INSERT.
+func (c *conflictConfig) insertSuffix(driver string) string {
+	var sb strings.Builder
+	if driver == "mysql" {
+		sb.WriteString("ON DUPLICATE KEY UPDATE ")
+		if c.action == conflictActionDoNothing {
+			// Assigning a column to itself leaves the row untouched without
+			// also suppressing unrelated errors as INSERT IGNORE would.
+			fmt.Fprintf(&sb, "%v = %v", c.columns[0], c.columns[0])
+			return sb.String()
+		}
+		for i, col := range c.updateColumns {
+			if i > 0 {
+				sb.WriteString(", ")
+			}
+			fmt.Fprintf(&sb, "%v = VALUES(%v)", col, col)
+		}
+		return sb.String()
+	}
+
+	fmt.Fprintf(&sb, "ON CONFLICT (%v) ", strings.Join(c.columns, ", "))
+	if c.action == conflictActionDoNothing {
+		sb.WriteString("DO NOTHING")
+		return sb.String()
+	}
+	sb.WriteString("DO UPDATE SET ")
+	for i, col := range c.updateColumns {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		fmt.Fprintf(&sb, "%v = EXCLUDED.%v", col, col)
+	}
+	return sb.String()
+}
+
+// mergeSQL returns a MERGE statement that upserts rows of values for the given
+// columns, for drivers without conflict handling within an INSERT.
+func (c *conflictConfig) mergeSQL(table string, columns []string, rows [][]any) (string, []any, error) {
+	if len(rows) == 0 {
+		return "", nil, errors.New("no rows to merge")
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "MERGE INTO %v WITH (HOLDLOCK) AS target USING (VALUES ", table)
+
+	placeholders := "(" + strings.TrimSuffix(strings.Repeat("?, ", len(columns)), ", ") + ")"
+	args := make([]any, 0, len(rows)*len(columns))
+	for i, row := range rows {
+		if len(row) != len(columns) {
+			return "", nil, fmt.Errorf("number of values (%v) does not match number of columns (%v)", len(row), len(columns))
+		}
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		sb.WriteString(placeholders)
+		args = append(args, row...)
+	}
+	fmt.Fprintf(&sb, ") AS source (%v) ON ", strings.Join(columns, ", "))
+
+	for i, col := range c.columns {
+		if i > 0 {
+			sb.WriteString(" AND ")
+		}
+		fmt.Fprintf(&sb, "target.%v = source.%v", col, col)
+	}
+
+	if c.action == conflictActionUpdate {
+		sb.WriteString(" WHEN MATCHED THEN UPDATE SET ")
+		for i, col := range c.updateColumns {
+			if i > 0 {
+				sb.WriteString(", ")
+			}
+			fmt.Fprintf(&sb, "target.%v = source.%v", col, col)
+		}
+	}
+
+	sourceCols := make([]string, 0, len(columns))
+	for _, col := range columns {
+		sourceCols = append(sourceCols, "source."+col)
+	}
+	fmt.Fprintf(&sb, " WHEN NOT MATCHED THEN INSERT (%v) VALUES (%v);", strings.Join(columns, ", "), strings.Join(sourceCols, ", "))
+	return sb.String(), args, nil
+}
@@ -0,0 +1,736 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/checkpoint"
+	"github.com/Jeffail/shutdown"
+	"github.com/Masterminds/squirrel"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	kcsFieldConnector    = "connector"
+	kcsFieldConfig       = "config"
+	kcsFieldOffsetsCache = "offsets_cache"
+
+	kcsJDBCConnectorClass = "io.confluent.connect.jdbc.JdbcSourceConnector"
+)
+
+func kafkaConnectSourceInputConfig() *service.ConfigSpec {
+	spec := service.NewConfigSpec().
+		Beta().
+		Categories("Services").
+		Version("4.28.0").
+		Summary("Runs a Kafka Connect JDBC source connector configuration natively, allowing existing connector configs to be reused without a Connect cluster.").
+		Description(`
+The ` + "`config`" + ` field accepts the connector configuration exactly as it would be submitted to the Kafka Connect REST API. Rather than loading Java connector plugins the connector class is mapped onto a native Benthos implementation that mimics the behaviour of the original, including the way in which source offsets are tracked.
+
+### Supported Connectors
+
+Only the JDBC source connector below is currently supported, and configurations with any other ` + "`connector.class`" + ` are rejected.
+
+#### ` + "`" + kcsJDBCConnectorClass + "`" + `
+
+The Confluent JDBC source connector. The ` + "`connection.url`" + ` is translated into a native driver and DSN for PostgreSQL, MySQL, SQL Server, Oracle, ClickHouse and SQLite URLs, with ` + "`connection.user`" + ` and ` + "`connection.password`" + ` merged into it.
+
+Tables are selected with ` + "`table.whitelist`" + ` (or ` + "`table.include.list`" + `), or a custom ` + "`query`" + ` can be provided instead. The modes ` + "`bulk`" + `, ` + "`incrementing`" + `, ` + "`timestamp`" + ` and ` + "`timestamp+incrementing`" + ` are supported along with the properties ` + "`incrementing.column.name`" + `, ` + "`timestamp.column.name`" + `, ` + "`timestamp.delay.interval.ms`" + `, ` + "`poll.interval.ms`" + `, ` + "`batch.max.rows`" + ` and ` + "`topic.prefix`" + `. Any other properties are ignored and logged at startup.
+
+Each row is emitted as a structured message equivalent to the ` + "`JsonConverter`" + ` with ` + "`schemas.enable`" + ` set to ` + "`false`" + `, and a batch of rows is emitted for each ` + "`batch.max.rows`" + ` read.
+
+### Offsets
+
+When an ` + "`" + kcsFieldOffsetsCache + "`" + ` is configured the source offsets of each table are stored within it after messages have been delivered, using the key ` + "`<connector>/<table>`" + `, and are loaded when the input connects. Otherwise offsets are held in memory and the connector starts from the beginning of each table after a restart.
+
+### Metadata
+
+This input adds the following metadata fields to each message:
+
+` + "```text" + `
+- kafka_connect_connector
+- kafka_connect_topic
+- kafka_connect_table
+` + "```" + `
+
+The field ` + "`kafka_connect_topic`" + ` contains the topic that Kafka Connect would have written the record to, which can be used within the ` + "`topic`" + ` field of a Kafka output.`).
+		Field(service.NewStringField(kcsFieldConnector).
+			Description("The name of the connector, which is used in order to identify stored offsets.").
+			Example("jdbc-orders-source")).
+		Field(service.NewStringMapField(kcsFieldConfig).
+			Description("The Kafka Connect connector configuration.").
+			Example(map[string]any{
+				"connector.class":          kcsJDBCConnectorClass,
+				"connection.url":           "jdbc:postgresql://localhost:5432/shop",
+				"connection.user":          "benthos",
+				"connection.password":      "${DB_PASSWORD}",
+				"table.whitelist":          "orders",
+				"mode":                     "incrementing",
+				"incrementing.column.name": "id",
+				"topic.prefix":             "shop-",
+			})).
+		Field(service.NewStringField(kcsFieldOffsetsCache).
+			Description("An optional [cache resource](/docs/components/caches/about) used to persist source offsets.").
+			Optional()).
+		Field(service.NewAutoRetryNacksToggleField()).
+		LintRule(fmt.Sprintf(`
+let class = this.%v."connector.class".or("")
+root = if $class == "" {
+  "connector.class must be specified"
+} else if ![%q, "JdbcSourceConnector"].contains($class) {
+  "connector class %%v is not supported, supported classes: %v".format($class)
+}`, kcsFieldConfig, kcsJDBCConnectorClass, kcsJDBCConnectorClass))
+
+	spec = spec.Example("Migrate a JDBC Source Connector", `
+Here we take the configuration of an existing JDBC source connector and write the rows to the same topics that Kafka Connect would have, storing offsets within a Redis cache:`, `
+input:
+  kafka_connect_source:
+    connector: jdbc-orders-source
+    config:
+      connector.class: io.confluent.connect.jdbc.JdbcSourceConnector
+      connection.url: jdbc:postgresql://localhost:5432/shop
+      connection.user: benthos
+      connection.password: ${DB_PASSWORD}
+      table.whitelist: orders,customers
+      mode: timestamp+incrementing
+      incrementing.column.name: id
+      timestamp.column.name: updated_at
+      topic.prefix: shop-
+    offsets_cache: offsets
+
+output:
+  kafka_franz:
+    seed_brokers: [ localhost:9092 ]
+    topic: ${! @kafka_connect_topic }
+
+cache_resources:
+  - label: offsets
+    redis:
+      url: redis://localhost:6379
+`)
+	return spec
+}
+
+func init() {
+	err := service.RegisterBatchInput(
+		"kafka_connect_source", kafkaConnectSourceInputConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.BatchInput, error) {
+			i, err := newKafkaConnectSourceInputFromConfig(conf, mgr)
+			if err != nil {
+				return nil, err
+			}
+			return service.AutoRetryNacksBatchedToggled(conf, i)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// jdbcSourceOffset mirrors the source offset structure stored by the JDBC
+// source connector.
+type jdbcSourceOffset struct {
+	Incrementing   *int64 `json:"incrementing,omitempty"`
+	TimestampNanos *int64 `json:"timestamp_nanos,omitempty"`
+}
+
+type jdbcSourceConfig struct {
+	driver string
+	dsn    string
+
+	tables      []string
+	query       string
+	mode        string
+	incColumn   string
+	tsColumn    string
+	tsDelay     time.Duration
+	topicPrefix string
+
+	pollInterval time.Duration
+	batchMaxRows int
+}
+
+var jdbcSourceKnownProperties = map[string]struct{}{
+	"name": {}, "connector.class": {}, "tasks.max": {},
+	"connection.url": {}, "connection.user": {}, "connection.password": {},
+	"table.whitelist": {}, "table.include.list": {}, "query": {}, "mode": {},
+	"incrementing.column.name": {}, "timestamp.column.name": {},
+	"timestamp.delay.interval.ms": {}, "topic.prefix": {}, "poll.interval.ms": {},
+	"batch.max.rows": {}, "key.converter": {}, "value.converter": {},
+	"value.converter.schemas.enable": {}, "key.converter.schemas.enable": {},
+}
+
+func jdbcSourceConfigFromProps(props map[string]string) (conf jdbcSourceConfig, ignored []string, err error) {
+	for k := range props {
+		if _, exists := jdbcSourceKnownProperties[k]; !exists {
+			ignored = append(ignored, k)
+		}
+	}
+	sort.Strings(ignored)
+
+	if conf.driver, conf.dsn, err = jdbcURLToDSN(props["connection.url"], props["connection.user"], props["connection.password"]); err != nil {
+		return
+	}
+
+	tablesStr := props["table.whitelist"]
+	if tablesStr == "" {
+		tablesStr = props["table.include.list"]
+	}
+	for _, t := range strings.Split(tablesStr, ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			conf.tables = append(conf.tables, t)
+		}
+	}
+	conf.query = props["query"]
+	if conf.query == "" && len(conf.tables) == 0 {
+		err = errors.New("either table.whitelist or query must be specified")
+		return
+	}
+	if conf.query != "" && len(conf.tables) > 0 {
+		err = errors.New("table.whitelist and query cannot both be specified")
+		return
+	}
+
+	conf.mode = props["mode"]
+	if conf.mode == "" {
+		err = errors.New("property mode is required")
+		return
+	}
+	conf.incColumn = props["incrementing.column.name"]
+	conf.tsColumn = props["timestamp.column.name"]
+	switch conf.mode {
+	case "bulk":
+	case "incrementing":
+		if conf.incColumn == "" {
+			err = errors.New("mode incrementing requires incrementing.column.name")
+		}
+	case "timestamp":
+		if conf.tsColumn == "" {
+			err = errors.New("mode timestamp requires timestamp.column.name")
+		}
+	case "timestamp+incrementing":
+		if conf.incColumn == "" || conf.tsColumn == "" {
+			err = errors.New("mode timestamp+incrementing requires both incrementing.column.name and timestamp.column.name")
+		}
+	default:
+		err = fmt.Errorf("mode %v is not supported", conf.mode)
+	}
+	if err != nil {
+		return
+	}
+
+	conf.topicPrefix = props["topic.prefix"]
+
+	msProp := func(key string, def int) (time.Duration, error) {
+		v, exists := props[key]
+		if !exists {
+			return time.Duration(def) * time.Millisecond, nil
+		}
+		i, err := strconv.Atoi(v)
+		if err != nil {
+			return 0, fmt.Errorf("property %v: %w", key, err)
+		}
+		return time.Duration(i) * time.Millisecond, nil
+	}
+	if conf.pollInterval, err = msProp("poll.interval.ms", 5000); err != nil {
+		return
+	}
+	if conf.tsDelay, err = msProp("timestamp.delay.interval.ms", 0); err != nil {
+		return
+	}
+
+	conf.batchMaxRows = 100
+	if v, exists := props["batch.max.rows"]; exists {
+		if conf.batchMaxRows, err = strconv.Atoi(v); err != nil {
+			err = fmt.Errorf("property batch.max.rows: %w", err)
+			return
+		}
+		if conf.batchMaxRows <= 0 {
+			err = errors.New("property batch.max.rows must be greater than zero")
+			return
+		}
+	}
+	return
+}
+
+// jdbcURLToDSN converts a JDBC connection URL into a driver and DSN
+// understood by the drivers bundled with Benthos.
+func jdbcURLToDSN(jdbcURL, user, password string) (driver, dsn string, err error) {
+	if !strings.HasPrefix(jdbcURL, "jdbc:") {
+		return "", "", fmt.Errorf("connection.url %q is not a JDBC URL", jdbcURL)
+	}
+	rest := strings.TrimPrefix(jdbcURL, "jdbc:")
+
+	var userInfo *url.Userinfo
+	if user != "" {
+		if password != "" {
+			userInfo = url.UserPassword(user, password)
+		} else {
+			userInfo = url.User(user)
+		}
+	}
+
+	subProtocol, _, _ := strings.Cut(rest, ":")
+	switch subProtocol {
+	case "postgresql", "clickhouse":
+		var u *url.URL
+		if u, err = url.Parse(rest); err != nil {
+			return
+		}
+		if userInfo != nil {
+			u.User = userInfo
+		}
+		if subProtocol == "postgresql" {
+			driver, u.Scheme = "postgres", "postgres"
+		} else {
+			driver = "clickhouse"
+		}
+		return driver, u.String(), nil
+	case "mysql":
+		var u *url.URL
+		if u, err = url.Parse(rest); err != nil {
+			return
+		}
+		q := u.Query()
+		q.Set("parseTime", "true")
+		dsn = "tcp(" + u.Host + ")" + u.Path + "?" + q.Encode()
+		if user != "" {
+			// The MySQL DSN format does not escape credentials.
+			if password != "" {
+				user += ":" + password
+			}
+			dsn = user + "@" + dsn
+		}
+		return "mysql", dsn, nil
+	case "sqlserver":
+		// jdbc:sqlserver://host:port;databaseName=foo;prop=bar
+		hostPart, propsPart, _ := strings.Cut(strings.TrimPrefix(rest, "sqlserver://"), ";")
+		u := url.URL{Scheme: "sqlserver", Host: hostPart, User: userInfo}
+		q := url.Values{}
+		for _, kv := range strings.Split(propsPart, ";") {
+			k, v, ok := strings.Cut(kv, "=")
+			if !ok {
+				continue
+			}
+			switch strings.ToLower(k) {
+			case "databasename", "database":
+				q.Set("database", v)
+			case "user":
+				if userInfo == nil {
+					u.User = url.User(v)
+				}
+			case "password":
+			default:
+				q.Set(k, v)
+			}
+		}
+		u.RawQuery = q.Encode()
+		return "mssql", u.String(), nil
+	case "oracle":
+		// jdbc:oracle:thin:@host:port/service or jdbc:oracle:thin:@//host:port/service
+		_, target, ok := strings.Cut(rest, "@")
+		if !ok {
+			return "", "", fmt.Errorf("connection.url %q is not a supported Oracle URL", jdbcURL)
+		}
+		target = strings.TrimPrefix(target, "//")
+		hostPart, serviceName, _ := strings.Cut(target, "/")
+		if serviceName == "" {
+			// Legacy SID syntax: host:port:sid
+			if i := strings.LastIndex(hostPart, ":"); i > 0 && strings.Count(hostPart, ":") == 2 {
+				hostPart, serviceName = hostPart[:i], hostPart[i+1:]
+			}
+		}
+		u := url.URL{Scheme: "oracle", Host: hostPart, Path: "/" + serviceName, User: userInfo}
+		return "oracle", u.String(), nil
+	case "sqlite":
+		return "sqlite", "file:" + strings.TrimPrefix(rest, "sqlite:"), nil
+	}
+	return "", "", fmt.Errorf("connection.url sub-protocol %q is not supported", subProtocol)
+}
+
+//------------------------------------------------------------------------------
+
+type jdbcSourceTable struct {
+	name  string
+	topic string
+
+	offset       jdbcSourceOffset
+	checkpointer *checkpoint.Capped[jdbcSourceOffset]
+}
+
+type kafkaConnectSourceInput struct {
+	connector    string
+	conf         jdbcSourceConfig
+	offsetsCache string
+	placeholders squirrel.PlaceholderFormat
+
+	dbMut    sync.Mutex
+	db       *sql.DB
+	tables   []*jdbcSourceTable
+	tableIdx int
+	rows     *sql.Rows
+	nextPoll time.Time
+
+	mgr     *service.Resources
+	logger  *service.Logger
+	shutSig *shutdown.Signaller
+}
+
+func newKafkaConnectSourceInputFromConfig(conf *service.ParsedConfig, mgr *service.Resources) (*kafkaConnectSourceInput, error) {
+	k := &kafkaConnectSourceInput{
+		placeholders: squirrel.Question,
+		mgr:          mgr,
+		logger:       mgr.Logger(),
+		shutSig:      shutdown.NewSignaller(),
+	}
+
+	var err error
+	if k.connector, err = conf.FieldString(kcsFieldConnector); err != nil {
+		return nil, err
+	}
+
+	props, err := conf.FieldStringMap(kcsFieldConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	switch class := props["connector.class"]; class {
+	case kcsJDBCConnectorClass, "JdbcSourceConnector":
+		var ignored []string
+		if k.conf, ignored, err = jdbcSourceConfigFromProps(props); err != nil {
+			return nil, fmt.Errorf("connector %v: %w", k.connector, err)
+		}
+		if len(ignored) > 0 {
+			k.logger.Warnf("The following connector properties are not supported and will be ignored: %v", strings.Join(ignored, ", "))
+		}
+	case "":
+		return nil, errors.New("connector.class must be specified")
+	default:
+		return nil, fmt.Errorf("connector class %v is not supported, supported classes: %v", class, kcsJDBCConnectorClass)
+	}
+
+	if conf.Contains(kcsFieldOffsetsCache) {
+		if k.offsetsCache, err = conf.FieldString(kcsFieldOffsetsCache); err != nil {
+			return nil, err
+		}
+		if !mgr.HasCache(k.offsetsCache) {
+			return nil, fmt.Errorf("cache resource '%v' was not found", k.offsetsCache)
+		}
+	}
+
+	switch k.conf.driver {
+	case "postgres", "clickhouse":
+		k.placeholders = squirrel.Dollar
+	case "oracle":
+		k.placeholders = squirrel.Colon
+	}
+
+	if k.conf.query != "" {
+		k.tables = append(k.tables, &jdbcSourceTable{topic: k.conf.topicPrefix})
+	}
+	for _, t := range k.conf.tables {
+		k.tables = append(k.tables, &jdbcSourceTable{name: t, topic: k.conf.topicPrefix + t})
+	}
+	for _, t := range k.tables {
+		t.checkpointer = checkpoint.NewCapped[jdbcSourceOffset](int64(k.conf.batchMaxRows) * 1024)
+	}
+	return k, nil
+}
+
+func (k *kafkaConnectSourceInput) offsetKey(t *jdbcSourceTable) string {
+	return k.connector + "/" + t.name
+}
+
+func (k *kafkaConnectSourceInput) Connect(ctx context.Context) (err error) {
+	k.dbMut.Lock()
+	defer k.dbMut.Unlock()
+
+	if k.db != nil {
+		return nil
+	}
+
+	if k.offsetsCache != "" {
+		for _, t := range k.tables {
+			var offsetBytes []byte
+			var cErr error
+			if err = k.mgr.AccessCache(ctx, k.offsetsCache, func(c service.Cache) {
+				offsetBytes, cErr = c.Get(ctx, k.offsetKey(t))
+			}); err != nil {
+				return
+			}
+			if cErr != nil {
+				if errors.Is(cErr, service.ErrKeyNotFound) {
+					continue
+				}
+				return cErr
+			}
+			if err = json.Unmarshal(offsetBytes, &t.offset); err != nil {
+				return fmt.Errorf("failed to parse stored offset of %v: %w", k.offsetKey(t), err)
+			}
+		}
+	}
+
+	var db *sql.DB
+	if db, err = sqlOpenWithReworks(k.logger, k.conf.driver, k.conf.dsn); err != nil {
+		return
+	}
+	if err = db.PingContext(ctx); err != nil {
+		_ = db.Close()
+		return
+	}
+
+	k.db = db
+
+	go func() {
+		<-k.shutSig.HardStopChan()
+
+		k.dbMut.Lock()
+		if k.rows != nil {
+			_ = k.rows.Close()
+			k.rows = nil
+		}
+		if k.db != nil {
+			_ = k.db.Close()
+		}
+		k.dbMut.Unlock()
+
+		k.shutSig.TriggerHasStopped()
+	}()
+	return nil
+}
+
+// buildQuery returns the query and arguments for polling a table given its
+// current offset, following the semantics of the JDBC source connector.
+func (k *kafkaConnectSourceInput) buildQuery(t *jdbcSourceTable, now time.Time) (string, []any, error) {
+	var query string
+	if t.name != "" {
+		query = "SELECT * FROM " + t.name
+	} else {
+		query = k.conf.query
+	}
+
+	var args []any
+	var where, orderBy string
+
+	var lastInc int64 = -1
+	if t.offset.Incrementing != nil {
+		lastInc = *t.offset.Incrementing
+	}
+	var lastTS time.Time
+	if t.offset.TimestampNanos != nil {
+		lastTS = time.Unix(0, *t.offset.TimestampNanos).UTC()
+	} else {
+		lastTS = time.Unix(0, 0).UTC()
+	}
+	endTS := now.Add(-k.conf.tsDelay).UTC()
+
+	switch k.conf.mode {
+	case "incrementing":
+		where = k.conf.incColumn + " > ?"
+		orderBy = k.conf.incColumn + " ASC"
+		args = append(args, lastInc)
+	case "timestamp":
+		where = k.conf.tsColumn + " > ? AND " + k.conf.tsColumn + " < ?"
+		orderBy = k.conf.tsColumn + " ASC"
+		args = append(args, lastTS, endTS)
+	case "timestamp+incrementing":
+		where = k.conf.tsColumn + " < ? AND ((" + k.conf.tsColumn + " = ? AND " + k.conf.incColumn + " > ?) OR " + k.conf.tsColumn + " > ?)"
+		orderBy = k.conf.tsColumn + ", " + k.conf.incColumn + " ASC"
+		args = append(args, endTS, lastTS, lastInc, lastTS)
+	}
+	if where != "" {
+		query += " WHERE " + where + " ORDER BY " + orderBy
+	}
+
+	query, err := k.placeholders.ReplacePlaceholders(query)
+	return query, args, err
+}
+
+func jdbcOffsetInt(v any) (int64, error) {
+	switch t := v.(type) {
+	case int64:
+		return t, nil
+	case int32:
+		return int64(t), nil
+	case int:
+		return int64(t), nil
+	case uint64:
+		return int64(t), nil
+	case uint32:
+		return int64(t), nil
+	case float64:
+		return int64(t), nil
+	case string:
+		return strconv.ParseInt(t, 10, 64)
+	case []byte:
+		return strconv.ParseInt(string(t), 10, 64)
+	}
+	return 0, fmt.Errorf("unsupported incrementing column type: %T", v)
+}
+
+func jdbcOffsetTime(v any) (time.Time, error) {
+	switch t := v.(type) {
+	case time.Time:
+		return t, nil
+	case string:
+		return time.Parse(time.RFC3339Nano, t)
+	case []byte:
+		return time.Parse(time.RFC3339Nano, string(t))
+	}
+	return time.Time{}, fmt.Errorf("unsupported timestamp column type: %T", v)
+}
+
+// advanceOffset updates the offset of a table from a row read from it.
+func (k *kafkaConnectSourceInput) advanceOffset(t *jdbcSourceTable, row map[string]any) error {
+	if k.conf.incColumn != "" && k.conf.mode != "timestamp" && k.conf.mode != "bulk" {
+		i, err := jdbcOffsetInt(row[k.conf.incColumn])
+		if err != nil {
+			return err
+		}
+		t.offset.Incrementing = &i
+	}
+	if k.conf.tsColumn != "" && k.conf.mode != "incrementing" && k.conf.mode != "bulk" {
+		ts, err := jdbcOffsetTime(row[k.conf.tsColumn])
+		if err != nil {
+			return err
+		}
+		nanos := ts.UnixNano()
+		t.offset.TimestampNanos = &nanos
+	}
+	return nil
+}
+
+func (k *kafkaConnectSourceInput) ReadBatch(ctx context.Context) (service.MessageBatch, service.AckFunc, error) {
+	k.dbMut.Lock()
+	defer k.dbMut.Unlock()
+
+	if k.db == nil {
+		return nil, nil, service.ErrNotConnected
+	}
+
+	for {
+		t := k.tables[k.tableIdx]
+		if k.rows == nil {
+			if wait := time.Until(k.nextPoll); wait > 0 {
+				select {
+				case <-time.After(wait):
+				case <-ctx.Done():
+					return nil, nil, ctx.Err()
+				case <-k.shutSig.HardStopChan():
+					return nil, nil, service.ErrNotConnected
+				}
+			}
+
+			query, args, err := k.buildQuery(t, time.Now())
+			if err != nil {
+				return nil, nil, err
+			}
+			if k.rows, err = k.db.QueryContext(ctx, query, args...); err != nil {
+				return nil, nil, err
+			}
+		}
+
+		var batch service.MessageBatch
+		for len(batch) < k.conf.batchMaxRows && k.rows.Next() {
+			row, err := sqlRowToMap(k.rows)
+			if err != nil {
+				_ = k.rows.Close()
+				k.rows = nil
+				return nil, nil, err
+			}
+			if err := k.advanceOffset(t, row); err != nil {
+				_ = k.rows.Close()
+				k.rows = nil
+				return nil, nil, err
+			}
+
+			msg := service.NewMessage(nil)
+			msg.SetStructuredMut(row)
+			msg.MetaSetMut("kafka_connect_connector", k.connector)
+			msg.MetaSetMut("kafka_connect_topic", t.topic)
+			msg.MetaSetMut("kafka_connect_table", t.name)
+			batch = append(batch, msg)
+		}
+
+		if len(batch) < k.conf.batchMaxRows {
+			err := k.rows.Err()
+			_ = k.rows.Close()
+			k.rows = nil
+			if err != nil {
+				return nil, nil, err
+			}
+
+			if k.tableIdx = (k.tableIdx + 1) % len(k.tables); k.tableIdx == 0 {
+				k.nextPoll = time.Now().Add(k.conf.pollInterval)
+			}
+		}
+
+		if len(batch) == 0 {
+			continue
+		}
+
+		ackFn, err := k.offsetAckFn(ctx, t, len(batch))
+		if err != nil {
+			return nil, nil, err
+		}
+		return batch, ackFn, nil
+	}
+}
+
+func (k *kafkaConnectSourceInput) offsetAckFn(ctx context.Context, t *jdbcSourceTable, size int) (service.AckFunc, error) {
+	if k.conf.mode == "bulk" || k.offsetsCache == "" {
+		return func(ctx context.Context, err error) error {
+			return nil
+		}, nil
+	}
+
+	release, err := t.checkpointer.Track(ctx, t.offset, int64(size))
+	if err != nil {
+		return nil, err
+	}
+
+	return func(ctx context.Context, err error) error {
+		highest := release()
+		if highest == nil {
+			return nil
+		}
+		offsetBytes, err := json.Marshal(highest)
+		if err != nil {
+			return err
+		}
+		var setErr error
+		if err := k.mgr.AccessCache(ctx, k.offsetsCache, func(c service.Cache) {
+			setErr = c.Set(ctx, k.offsetKey(t), offsetBytes, nil)
+		}); err != nil {
+			return err
+		}
+		return setErr
+	}, nil
+}
+
+func (k *kafkaConnectSourceInput) Close(ctx context.Context) error {
+	k.shutSig.TriggerHardStop()
+	k.dbMut.Lock()
+	isNil := k.db == nil
+	k.dbMut.Unlock()
+	if isNil {
+		return nil
+	}
+	select {
+	case <-k.shutSig.HasStoppedChan():
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return nil
+}
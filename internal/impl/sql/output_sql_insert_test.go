@@ -4,6 +4,7 @@ import (
 	"context"
 	"testing"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
 	"github.com/benthosdev/benthos/v4/public/service"
@@ -28,3 +29,184 @@ args_mapping: 'root = [ this.id ]'
 	require.NoError(t, err)
 	require.NoError(t, insertOutput.Close(context.Background()))
 }
+
+func TestSQLInsertOutputNoConflict(t *testing.T) {
+	tests := []struct {
+		name     string
+		conf     string
+		expected string
+	}{
+		{
+			name: "plain insert",
+			conf: `
+driver: postgres
+dsn: woof
+table: quack
+columns: [ id, name ]
+args_mapping: 'root = [ this.id, this.name ]'
+`,
+			expected: "INSERT INTO quack (id,name) VALUES ($1,$2)",
+		},
+		{
+			name: "insert with suffix",
+			conf: `
+driver: postgres
+dsn: woof
+table: quack
+columns: [ id, name ]
+args_mapping: 'root = [ this.id, this.name ]'
+suffix: ON CONFLICT DO NOTHING
+`,
+			expected: "INSERT INTO quack (id,name) VALUES ($1,$2) ON CONFLICT DO NOTHING",
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			insertConfig, err := sqlInsertOutputConfig().ParseYAML(test.conf, nil)
+			require.NoError(t, err)
+
+			insertOutput, err := newSQLInsertOutputFromConfig(insertConfig, service.MockResources())
+			require.NoError(t, err)
+			assert.Nil(t, insertOutput.conflict)
+
+			sqlStr, _, err := insertOutput.builder.Values(1, "foo").ToSql()
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, sqlStr)
+		})
+	}
+}
+
+func TestSQLInsertOutputConflict(t *testing.T) {
+	tests := []struct {
+		name     string
+		driver   string
+		conflict string
+		expected string
+	}{
+		{
+			name:   "postgres update",
+			driver: "postgres",
+			conflict: `
+  columns: [ id ]
+`,
+			expected: "INSERT INTO quack (id,name,email) VALUES ($1,$2,$3) ON CONFLICT (id) DO UPDATE SET name = EXCLUDED.name, email = EXCLUDED.email",
+		},
+		{
+			name:   "sqlite do nothing",
+			driver: "sqlite",
+			conflict: `
+  columns: [ id, name ]
+  action: do_nothing
+`,
+			expected: "INSERT INTO quack (id,name,email) VALUES (?,?,?) ON CONFLICT (id, name) DO NOTHING",
+		},
+		{
+			name:   "mysql update columns",
+			driver: "mysql",
+			conflict: `
+  columns: [ id ]
+  update_columns: [ email ]
+`,
+			expected: "INSERT INTO quack (id,name,email) VALUES (?,?,?) ON DUPLICATE KEY UPDATE email = VALUES(email)",
+		},
+		{
+			name:   "mysql do nothing",
+			driver: "mysql",
+			conflict: `
+  columns: [ id ]
+  action: do_nothing
+`,
+			expected: "INSERT INTO quack (id,name,email) VALUES (?,?,?) ON DUPLICATE KEY UPDATE id = id",
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			insertConfig, err := sqlInsertOutputConfig().ParseYAML(`
+driver: `+test.driver+`
+dsn: woof
+table: quack
+columns: [ id, name, email ]
+args_mapping: 'root = [ this.id, this.name, this.email ]'
+conflict:`+test.conflict, nil)
+			require.NoError(t, err)
+
+			insertOutput, err := newSQLInsertOutputFromConfig(insertConfig, service.MockResources())
+			require.NoError(t, err)
+
+			sqlStr, _, err := insertOutput.builder.Values(1, "foo", "bar").ToSql()
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, sqlStr)
+		})
+	}
+}
+
+func TestSQLInsertOutputConflictMerge(t *testing.T) {
+	insertConfig, err := sqlInsertOutputConfig().ParseYAML(`
+driver: mssql
+dsn: woof
+table: quack
+columns: [ id, name, email ]
+args_mapping: 'root = [ this.id, this.name, this.email ]'
+conflict:
+  columns: [ id ]
+`, nil)
+	require.NoError(t, err)
+
+	insertOutput, err := newSQLInsertOutputFromConfig(insertConfig, service.MockResources())
+	require.NoError(t, err)
+
+	sqlStr, args, err := insertOutput.conflict.mergeSQL(insertOutput.table, insertOutput.columns, [][]any{
+		{1, "foo", "foo@example.com"},
+		{2, "bar", "bar@example.com"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "MERGE INTO quack WITH (HOLDLOCK) AS target USING (VALUES (?, ?, ?), (?, ?, ?)) AS source (id, name, email) ON target.id = source.id WHEN MATCHED THEN UPDATE SET target.name = source.name, target.email = source.email WHEN NOT MATCHED THEN INSERT (id, name, email) VALUES (source.id, source.name, source.email);", sqlStr)
+	assert.Equal(t, []any{1, "foo", "foo@example.com", 2, "bar", "bar@example.com"}, args)
+
+	_, _, err = insertOutput.conflict.mergeSQL(insertOutput.table, insertOutput.columns, [][]any{{1}})
+	require.Error(t, err)
+}
+
+func TestSQLInsertOutputConflictErrors(t *testing.T) {
+	for _, conf := range []string{
+		`
+driver: clickhouse
+dsn: woof
+table: quack
+columns: [ id ]
+args_mapping: 'root = [ this.id ]'
+conflict:
+  columns: [ id ]
+  action: do_nothing
+`,
+		`
+driver: postgres
+dsn: woof
+table: quack
+columns: [ id ]
+args_mapping: 'root = [ this.id ]'
+conflict:
+  columns: [ id ]
+`,
+		`
+driver: postgres
+dsn: woof
+table: quack
+columns: [ id, name ]
+args_mapping: 'root = [ this.id, this.name ]'
+suffix: ON CONFLICT DO NOTHING
+conflict:
+  columns: [ id ]
+`,
+	} {
+		insertConfig, err := sqlInsertOutputConfig().ParseYAML(conf, nil)
+		require.NoError(t, err)
+
+		_, err = newSQLInsertOutputFromConfig(insertConfig, service.MockResources())
+		require.Error(t, err, conf)
+	}
+}
@@ -21,7 +21,11 @@ func InsertProcessorConfig() *service.ConfigSpec {
 		Categories("Integration").
 		Summary("Inserts rows into an SQL database for each message, and leaves the message unchanged.").
 		Description(`
-If the insert fails to execute then the message will still remain unchanged and the error can be caught using error handling methods outlined [here](/docs/configuration/error_handling).`).
+If the insert fails to execute then the message will still remain unchanged and the error can be caught using error handling methods outlined [here](/docs/configuration/error_handling).
+
+### Transactions
+
+The rows of a batch are inserted with a single statement, and by default a message that fails its ` + "`args_mapping`" + ` is flagged as having failed whilst the rows of the remaining messages are still inserted. Setting the field ` + "`transaction`" + ` to ` + "`batch`" + ` instead rolls back the whole batch when any message fails. Setting it to ` + "`savepoint`" + ` inserts the row of each message with a separate statement within a savepoint of the batch transaction, so that a row that fails to insert, for example due to a constraint violation, only fails its own message.`).
 		Field(driverField).
 		Field(dsnField).
 		Field(service.NewStringField("table").
@@ -42,7 +46,8 @@ If the insert fails to execute then the message will still remain unchanged and
 			Description("An optional suffix to append to the insert query.").
 			Optional().
 			Advanced().
-			Example("ON CONFLICT (name) DO NOTHING"))
+			Example("ON CONFLICT (name) DO NOTHING")).
+		Field(txModeFieldSpec())
 
 	for _, f := range connFields() {
 		spec = spec.Field(f)
@@ -90,6 +95,8 @@ type sqlInsertProcessor struct {
 	dbMut   sync.RWMutex
 
 	useTxStmt   bool
+	txMode      string
+	savepoints  *savepointDialect
 	argsMapping *bloblang.Executor
 
 	logger  *service.Logger
@@ -119,6 +126,10 @@ func NewSQLInsertProcessorFromConfig(conf *service.ParsedConfig, mgr *service.Re
 		return nil, err
 	}
 
+	if s.txMode, s.savepoints, err = txModeFromParsed(conf, driverStr); err != nil {
+		return nil, err
+	}
+
 	tableStr, err := conf.FieldString("table")
 	if err != nil {
 		return nil, err
@@ -193,17 +204,24 @@ func (s *sqlInsertProcessor) ProcessBatch(ctx context.Context, batch service.Mes
 	s.dbMut.RLock()
 	defer s.dbMut.RUnlock()
 
+	if s.savepoints != nil {
+		return s.processBatchSavepoints(ctx, batch)
+	}
+
 	insertBuilder := s.builder
 
 	var tx *sql.Tx
 	var stmt *sql.Stmt
-	if s.useTxStmt {
+	if s.useTxStmt || s.txMode == txModeBatch {
 		var err error
-		if tx, err = s.db.Begin(); err != nil {
+		if tx, err = s.db.BeginTx(ctx, nil); err != nil {
 			return nil, err
 		}
+	}
+	if s.useTxStmt {
 		sqlStr, _, err := insertBuilder.ToSql()
 		if err != nil {
+			_ = tx.Rollback()
 			return nil, err
 		}
 		if stmt, err = tx.Prepare(sqlStr); err != nil {
@@ -213,41 +231,33 @@ func (s *sqlInsertProcessor) ProcessBatch(ctx context.Context, batch service.Mes
 	}
 
 	for i, msg := range batch {
-		var args []any
-		if s.argsMapping != nil {
-			resMsg, err := batch.BloblangQuery(i, s.argsMapping)
-			if err != nil {
-				s.logger.Debugf("Arguments mapping failed: %v", err)
-				msg.SetError(err)
-				continue
-			}
-
-			iargs, err := resMsg.AsStructured()
-			if err != nil {
-				s.logger.Debugf("Mapping returned non-structured result: %v", err)
-				msg.SetError(fmt.Errorf("mapping returned non-structured result: %w", err))
-				continue
-			}
-
-			var ok bool
-			if args, ok = iargs.([]any); !ok {
-				s.logger.Debugf("Mapping returned non-array result: %T", iargs)
-				msg.SetError(fmt.Errorf("mapping returned non-array result: %T", iargs))
-				continue
+		args, err := s.argsFor(batch, i)
+		if err != nil {
+			if s.txMode == txModeBatch {
+				_ = tx.Rollback()
+				return []service.MessageBatch{rolledBackBatch(batch, i, err)}, nil
 			}
+			msg.SetError(err)
+			continue
 		}
 
-		if tx == nil {
+		if stmt == nil {
 			insertBuilder = insertBuilder.Values(args...)
 		} else if _, err := stmt.Exec(args...); err != nil {
+			_ = tx.Rollback()
 			return nil, err
 		}
 	}
 
 	var err error
-	if tx == nil {
-		_, err = insertBuilder.RunWith(s.db).ExecContext(ctx)
-	} else {
+	if stmt == nil {
+		if tx == nil {
+			_, err = insertBuilder.RunWith(s.db).ExecContext(ctx)
+		} else if _, err = insertBuilder.RunWith(tx).ExecContext(ctx); err != nil {
+			_ = tx.Rollback()
+		}
+	}
+	if err == nil && tx != nil {
 		err = tx.Commit()
 	}
 	if err != nil {
@@ -257,6 +267,87 @@ func (s *sqlInsertProcessor) ProcessBatch(ctx context.Context, batch service.Mes
 	return []service.MessageBatch{batch}, nil
 }
 
+// processBatchSavepoints inserts the row of each message of a batch with a
+// separate statement executed within a savepoint, where rows that fail to
+// insert only fail their own message.
+func (s *sqlInsertProcessor) processBatchSavepoints(ctx context.Context, batch service.MessageBatch) ([]service.MessageBatch, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var stmt *sql.Stmt
+	if s.useTxStmt {
+		sqlStr, _, err := s.builder.ToSql()
+		if err != nil {
+			_ = tx.Rollback()
+			return nil, err
+		}
+		if stmt, err = tx.PrepareContext(ctx, sqlStr); err != nil {
+			_ = tx.Rollback()
+			return nil, err
+		}
+	}
+
+	for i, msg := range batch {
+		args, err := s.argsFor(batch, i)
+		if err != nil {
+			msg.SetError(err)
+			continue
+		}
+
+		msgErr, err := s.savepoints.withSavepoint(ctx, tx, func() (err error) {
+			if stmt != nil {
+				_, err = stmt.ExecContext(ctx, args...)
+			} else {
+				_, err = s.builder.Values(args...).RunWith(tx).ExecContext(ctx)
+			}
+			return
+		})
+		if err != nil {
+			_ = tx.Rollback()
+			s.logger.Debugf("Rolled back transaction: %v", err)
+			return nil, err
+		}
+		if msgErr != nil {
+			s.logger.Debugf("Failed to insert row: %v", msgErr)
+			msg.SetError(msgErr)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		s.logger.Debugf("Failed to commit transaction: %v", err)
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return []service.MessageBatch{batch}, nil
+}
+
+// argsFor returns the insert arguments of a message of a batch.
+func (s *sqlInsertProcessor) argsFor(batch service.MessageBatch, i int) ([]any, error) {
+	if s.argsMapping == nil {
+		return nil, nil
+	}
+
+	resMsg, err := batch.BloblangQuery(i, s.argsMapping)
+	if err != nil {
+		s.logger.Debugf("Arguments mapping failed: %v", err)
+		return nil, err
+	}
+
+	iargs, err := resMsg.AsStructured()
+	if err != nil {
+		s.logger.Debugf("Mapping returned non-structured result: %v", err)
+		return nil, fmt.Errorf("mapping returned non-structured result: %w", err)
+	}
+
+	args, ok := iargs.([]any)
+	if !ok {
+		s.logger.Debugf("Mapping returned non-array result: %T", iargs)
+		return nil, fmt.Errorf("mapping returned non-array result: %T", iargs)
+	}
+	return args, nil
+}
+
 func (s *sqlInsertProcessor) Close(ctx context.Context) error {
 	s.shutSig.TriggerHardStop()
 	select {
@@ -0,0 +1,126 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	txModeField = "transaction"
+
+	txModeNone      = "none"
+	txModeBatch     = "batch"
+	txModeSavepoint = "savepoint"
+
+	txSavepointName = "benthos_message"
+)
+
+func txModeFieldSpec() *service.ConfigField {
+	return service.NewStringAnnotatedEnumField(txModeField, map[string]string{
+		txModeNone:      "Statements are executed outside of an explicit transaction.",
+		txModeBatch:     "All statements of a batch are executed within a single transaction, which is rolled back if any message of the batch fails, in which case every message of the batch is flagged as having failed.",
+		txModeSavepoint: "All statements of a batch are executed within a single transaction, where the statements of each message are executed within a savepoint. When a message fails only its savepoint is rolled back and only that message is flagged as having failed, and the remaining messages are committed.",
+	}).
+		Description("Whether to execute the statements of each batch within a database transaction. The `savepoint` mode is not supported by the `clickhouse`, `trino` and `gocosmos` drivers.").
+		Advanced().
+		Version("4.28.0").
+		Default(txModeNone)
+}
+
+// txModeFromParsed returns the transaction mode of a processor, along with
+// the savepoint dialect of the driver when the mode is savepoint.
+func txModeFromParsed(conf *service.ParsedConfig, driver string) (mode string, savepoints *savepointDialect, err error) {
+	if mode, err = conf.FieldString(txModeField); err != nil {
+		return "", nil, err
+	}
+	switch mode {
+	case txModeNone:
+	case txModeBatch:
+		if driver == "trino" || driver == "gocosmos" {
+			return "", nil, fmt.Errorf("driver %v does not support transactions", driver)
+		}
+	case txModeSavepoint:
+		if savepoints, err = newSavepointDialect(driver); err != nil {
+			return "", nil, err
+		}
+	default:
+		return "", nil, fmt.Errorf("unrecognised transaction mode: %v", mode)
+	}
+	return mode, savepoints, nil
+}
+
+// savepointDialect describes the statements used to manage a savepoint within
+// a transaction for a given driver.
+type savepointDialect struct {
+	create   string
+	rollback string
+	release  string
+}
+
+func newSavepointDialect(driver string) (*savepointDialect, error) {
+	switch driver {
+	case "mysql", "postgres", "sqlite", "snowflake":
+		return &savepointDialect{
+			create:   "SAVEPOINT " + txSavepointName,
+			rollback: "ROLLBACK TO SAVEPOINT " + txSavepointName,
+			release:  "RELEASE SAVEPOINT " + txSavepointName,
+		}, nil
+	case "oracle":
+		return &savepointDialect{
+			create:   "SAVEPOINT " + txSavepointName,
+			rollback: "ROLLBACK TO SAVEPOINT " + txSavepointName,
+		}, nil
+	case "mssql":
+		return &savepointDialect{
+			create:   "SAVE TRANSACTION " + txSavepointName,
+			rollback: "ROLLBACK TRANSACTION " + txSavepointName,
+		}, nil
+	}
+	return nil, fmt.Errorf("driver %v does not support savepoints", driver)
+}
+
+// withSavepoint runs fn within a savepoint of a transaction, rolling back to
+// the savepoint when fn returns an error. The error returned by fn is returned
+// as msgErr, whereas err is only returned when the savepoint itself cannot be
+// managed, at which point the transaction is no longer usable.
+func (d *savepointDialect) withSavepoint(ctx context.Context, tx *sql.Tx, fn func() error) (msgErr, err error) {
+	if _, err = tx.ExecContext(ctx, d.create); err != nil {
+		return nil, fmt.Errorf("failed to create savepoint: %w", err)
+	}
+	if msgErr = fn(); msgErr != nil {
+		if _, err = tx.ExecContext(ctx, d.rollback); err != nil {
+			return msgErr, fmt.Errorf("failed to roll back savepoint: %w", err)
+		}
+		return msgErr, nil
+	}
+	if d.release != "" {
+		if _, err = tx.ExecContext(ctx, d.release); err != nil {
+			return nil, fmt.Errorf("failed to release savepoint: %w", err)
+		}
+	}
+	return nil, nil
+}
+
+// rolledBackBatch returns a copy of a batch where the message at index i is
+// flagged with the error that caused its transaction to be rolled back, and all
+// other messages are flagged as having been rolled back as a consequence.
+func rolledBackBatch(batch service.MessageBatch, i int, err error) service.MessageBatch {
+	failed := batch.Copy()
+	for j, msg := range failed {
+		if j == i {
+			msg.SetError(err)
+		} else {
+			msg.SetError(fmt.Errorf("transaction rolled back due to failed message at index %v: %w", i, err))
+		}
+	}
+	return failed
+}
+
+// sqlExecutor is implemented by both *sql.DB and *sql.Tx.
+type sqlExecutor interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
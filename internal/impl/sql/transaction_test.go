@@ -0,0 +1,252 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+
+	_ "modernc.org/sqlite"
+)
+
+func testTxDB(t *testing.T) (dsn string, db *sql.DB) {
+	t.Helper()
+
+	dsn = "file:" + filepath.Join(t.TempDir(), "test.db")
+
+	db, err := sql.Open("sqlite", dsn)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = db.Close()
+	})
+
+	_, err = db.Exec(`create table footable (
+  "foo" varchar(50) not null,
+  "bar" integer not null,
+  primary key ("foo")
+)`)
+	require.NoError(t, err)
+	return dsn, db
+}
+
+func testTxRows(t *testing.T, db *sql.DB) []string {
+	t.Helper()
+
+	rows, err := db.Query(`select foo from footable order by foo`)
+	require.NoError(t, err)
+	defer rows.Close()
+
+	var foos []string
+	for rows.Next() {
+		var foo string
+		require.NoError(t, rows.Scan(&foo))
+		foos = append(foos, foo)
+	}
+	require.NoError(t, rows.Err())
+	return foos
+}
+
+func testTxBatch(docs ...string) service.MessageBatch {
+	var batch service.MessageBatch
+	for _, d := range docs {
+		batch = append(batch, service.NewMessage([]byte(d)))
+	}
+	return batch
+}
+
+func TestSQLRawProcessorTransactionBatch(t *testing.T) {
+	dsn, db := testTxDB(t)
+
+	conf, err := RawProcessorConfig().ParseYAML(`
+driver: sqlite
+dsn: `+dsn+`
+query: "INSERT INTO footable (foo, bar) VALUES (?, ?) RETURNING foo;"
+args_mapping: 'root = [ this.foo, this.bar ]'
+transaction: batch
+`, nil)
+	require.NoError(t, err)
+
+	proc, err := NewSQLRawProcessorFromConfig(conf, service.MockResources())
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = proc.Close(context.Background())
+	})
+
+	res, err := proc.ProcessBatch(context.Background(), testTxBatch(
+		`{"foo":"a","bar":1}`,
+		`{"foo":"b","bar":2}`,
+	))
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+	for _, msg := range res[0] {
+		require.NoError(t, msg.GetError())
+	}
+	b, err := res[0][1].AsBytes()
+	require.NoError(t, err)
+	assert.Equal(t, `[{"foo":"b"}]`, string(b))
+
+	// The duplicate key of the second message rolls back the whole batch.
+	res, err = proc.ProcessBatch(context.Background(), testTxBatch(
+		`{"foo":"c","bar":3}`,
+		`{"foo":"a","bar":4}`,
+		`{"foo":"d","bar":5}`,
+	))
+	require.NoError(t, err)
+	require.Len(t, res[0], 3)
+	for i, msg := range res[0] {
+		assert.Error(t, msg.GetError(), i)
+	}
+	assert.ErrorContains(t, res[0][0].GetError(), "rolled back")
+
+	b, err = res[0][0].AsBytes()
+	require.NoError(t, err)
+	assert.Equal(t, `{"foo":"c","bar":3}`, string(b))
+
+	assert.Equal(t, []string{"a", "b"}, testTxRows(t, db))
+}
+
+func TestSQLRawProcessorTransactionSavepoint(t *testing.T) {
+	dsn, db := testTxDB(t)
+
+	conf, err := RawProcessorConfig().ParseYAML(`
+driver: sqlite
+dsn: `+dsn+`
+query: "INSERT INTO footable (foo, bar) VALUES (?, ?);"
+args_mapping: 'root = [ this.foo, this.bar ]'
+exec_only: true
+transaction: savepoint
+`, nil)
+	require.NoError(t, err)
+
+	proc, err := NewSQLRawProcessorFromConfig(conf, service.MockResources())
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = proc.Close(context.Background())
+	})
+
+	res, err := proc.ProcessBatch(context.Background(), testTxBatch(
+		`{"foo":"a","bar":1}`,
+		`{"foo":"a","bar":2}`,
+		`not json`,
+		`{"foo":"b","bar":3}`,
+	))
+	require.NoError(t, err)
+	require.Len(t, res[0], 4)
+	assert.NoError(t, res[0][0].GetError())
+	assert.Error(t, res[0][1].GetError())
+	assert.Error(t, res[0][2].GetError())
+	assert.NoError(t, res[0][3].GetError())
+
+	assert.Equal(t, []string{"a", "b"}, testTxRows(t, db))
+}
+
+func TestSQLInsertProcessorTransaction(t *testing.T) {
+	for _, test := range []struct {
+		mode   string
+		errs   []bool
+		stored []string
+	}{
+		{mode: "none", errs: []bool{false, true, false}, stored: []string{"a", "c"}},
+		{mode: "batch", errs: []bool{true, true, true}},
+		{mode: "savepoint", errs: []bool{false, true, false}, stored: []string{"a", "c"}},
+	} {
+		test := test
+		t.Run(test.mode, func(t *testing.T) {
+			dsn, db := testTxDB(t)
+
+			conf, err := InsertProcessorConfig().ParseYAML(`
+driver: sqlite
+dsn: `+dsn+`
+table: footable
+columns: [ foo, bar ]
+args_mapping: 'root = [ this.foo, this.bar ]'
+transaction: `+test.mode+`
+`, nil)
+			require.NoError(t, err)
+
+			proc, err := NewSQLInsertProcessorFromConfig(conf, service.MockResources())
+			require.NoError(t, err)
+			t.Cleanup(func() {
+				_ = proc.Close(context.Background())
+			})
+
+			res, err := proc.ProcessBatch(context.Background(), testTxBatch(
+				`{"foo":"a","bar":1}`,
+				`not json`,
+				`{"foo":"c","bar":3}`,
+			))
+			require.NoError(t, err)
+			require.Len(t, res[0], 3)
+			for i, msg := range res[0] {
+				assert.Equal(t, test.errs[i], msg.GetError() != nil, i)
+			}
+			assert.Equal(t, test.stored, testTxRows(t, db))
+		})
+	}
+}
+
+func TestSQLInsertProcessorTransactionSavepointConflict(t *testing.T) {
+	dsn, db := testTxDB(t)
+
+	conf, err := InsertProcessorConfig().ParseYAML(`
+driver: sqlite
+dsn: `+dsn+`
+table: footable
+columns: [ foo, bar ]
+args_mapping: 'root = [ this.foo, this.bar ]'
+transaction: savepoint
+`, nil)
+	require.NoError(t, err)
+
+	proc, err := NewSQLInsertProcessorFromConfig(conf, service.MockResources())
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = proc.Close(context.Background())
+	})
+
+	res, err := proc.ProcessBatch(context.Background(), testTxBatch(
+		`{"foo":"a","bar":1}`,
+		`{"foo":"a","bar":2}`,
+		`{"foo":"b","bar":3}`,
+	))
+	require.NoError(t, err)
+	assert.NoError(t, res[0][0].GetError())
+	assert.Error(t, res[0][1].GetError())
+	assert.NoError(t, res[0][2].GetError())
+
+	assert.Equal(t, []string{"a", "b"}, testTxRows(t, db))
+}
+
+func TestSQLTransactionModeDrivers(t *testing.T) {
+	for _, test := range []struct {
+		driver string
+		mode   string
+		errStr string
+	}{
+		{driver: "postgres", mode: "savepoint"},
+		{driver: "mssql", mode: "savepoint"},
+		{driver: "clickhouse", mode: "batch"},
+		{driver: "clickhouse", mode: "savepoint", errStr: "does not support savepoints"},
+		{driver: "trino", mode: "batch", errStr: "does not support transactions"},
+	} {
+		conf, err := RawProcessorConfig().ParseYAML(`
+driver: `+test.driver+`
+dsn: foo
+query: "SELECT 1"
+transaction: `+test.mode+`
+`, nil)
+		require.NoError(t, err)
+
+		_, _, err = txModeFromParsed(conf, test.driver)
+		if test.errStr != "" {
+			assert.ErrorContains(t, err, test.errStr, test.driver)
+		} else {
+			assert.NoError(t, err, test.driver)
+		}
+	}
+}
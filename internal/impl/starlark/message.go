@@ -0,0 +1,257 @@
+package starlark
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+
+	"go.starlark.net/starlark"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+// message exposes a message being processed to scripts.
+type message struct {
+	msg     *service.Message
+	dropped bool
+}
+
+var _ starlark.HasAttrs = (*message)(nil)
+
+var messageMethods = map[string]func(m *message, thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error){
+	"json":        (*message).json,
+	"get_meta":    (*message).getMeta,
+	"set_meta":    (*message).setMeta,
+	"delete_meta": (*message).deleteMeta,
+	"set_error":   (*message).setError,
+	"drop":        (*message).drop,
+}
+
+func (m *message) String() string        { return "message" }
+func (m *message) Type() string          { return "message" }
+func (m *message) Freeze()               {}
+func (m *message) Truth() starlark.Bool  { return starlark.True }
+func (m *message) Hash() (uint32, error) { return 0, errors.New("unhashable type: message") }
+
+func (m *message) Attr(name string) (starlark.Value, error) {
+	if name == "content" {
+		b, err := m.msg.AsBytes()
+		if err != nil {
+			return nil, err
+		}
+		return starlark.Bytes(b), nil
+	}
+	method, exists := messageMethods[name]
+	if !exists {
+		return nil, nil
+	}
+	return starlark.NewBuiltin(name, func(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+		return method(m, thread, b, args, kwargs)
+	}), nil
+}
+
+func (m *message) AttrNames() []string {
+	names := []string{"content"}
+	for k := range messageMethods {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (m *message) json(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	if err := starlark.UnpackPositionalArgs(b.Name(), args, kwargs, 0); err != nil {
+		return nil, err
+	}
+	v, err := m.msg.AsStructured()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse message as JSON: %w", err)
+	}
+	return toStarlark(v)
+}
+
+func (m *message) getMeta(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var key string
+	var def starlark.Value = starlark.None
+	if err := starlark.UnpackArgs(b.Name(), args, kwargs, "key", &key, "default?", &def); err != nil {
+		return nil, err
+	}
+	v, exists := m.msg.MetaGetMut(key)
+	if !exists {
+		return def, nil
+	}
+	return toStarlark(v)
+}
+
+func (m *message) setMeta(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var key string
+	var value starlark.Value
+	if err := starlark.UnpackArgs(b.Name(), args, kwargs, "key", &key, "value", &value); err != nil {
+		return nil, err
+	}
+	v, err := fromStarlark(value)
+	if err != nil {
+		return nil, err
+	}
+	m.msg.MetaSetMut(key, v)
+	return starlark.None, nil
+}
+
+func (m *message) deleteMeta(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var key string
+	if err := starlark.UnpackArgs(b.Name(), args, kwargs, "key", &key); err != nil {
+		return nil, err
+	}
+	m.msg.MetaDelete(key)
+	return starlark.None, nil
+}
+
+func (m *message) setError(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var errStr string
+	if err := starlark.UnpackArgs(b.Name(), args, kwargs, "message", &errStr); err != nil {
+		return nil, err
+	}
+	m.msg.SetError(errors.New(errStr))
+	return starlark.None, nil
+}
+
+func (m *message) drop(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	if err := starlark.UnpackPositionalArgs(b.Name(), args, kwargs, 0); err != nil {
+		return nil, err
+	}
+	m.dropped = true
+	return starlark.None, nil
+}
+
+//------------------------------------------------------------------------------
+
+// toStarlark converts a structured value into a Starlark value, where the keys
+// of objects are inserted into dictionaries in sorted order so that iteration
+// is deterministic.
+func toStarlark(v any) (starlark.Value, error) {
+	switch t := v.(type) {
+	case nil:
+		return starlark.None, nil
+	case bool:
+		return starlark.Bool(t), nil
+	case string:
+		return starlark.String(t), nil
+	case []byte:
+		return starlark.Bytes(t), nil
+	case json.Number:
+		if i, err := t.Int64(); err == nil {
+			return starlark.MakeInt64(i), nil
+		}
+		f, err := t.Float64()
+		if err != nil {
+			return nil, err
+		}
+		return starlark.Float(f), nil
+	case int:
+		return starlark.MakeInt(t), nil
+	case int64:
+		return starlark.MakeInt64(t), nil
+	case uint64:
+		return starlark.MakeUint64(t), nil
+	case float64:
+		return starlark.Float(t), nil
+	case []any:
+		elems := make([]starlark.Value, 0, len(t))
+		for _, e := range t {
+			se, err := toStarlark(e)
+			if err != nil {
+				return nil, err
+			}
+			elems = append(elems, se)
+		}
+		return starlark.NewList(elems), nil
+	case map[string]any:
+		keys := make([]string, 0, len(t))
+		for k := range t {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		d := starlark.NewDict(len(t))
+		for _, k := range keys {
+			se, err := toStarlark(t[k])
+			if err != nil {
+				return nil, err
+			}
+			if err := d.SetKey(starlark.String(k), se); err != nil {
+				return nil, err
+			}
+		}
+		return d, nil
+	}
+	return starlark.String(fmt.Sprintf("%v", v)), nil
+}
+
+// fromStarlark converts a Starlark value into a structured value.
+func fromStarlark(v starlark.Value) (any, error) {
+	switch t := v.(type) {
+	case starlark.NoneType:
+		return nil, nil
+	case starlark.Bool:
+		return bool(t), nil
+	case starlark.String:
+		return string(t), nil
+	case starlark.Bytes:
+		return []byte(t), nil
+	case starlark.Int:
+		if i, ok := t.Int64(); ok {
+			return i, nil
+		}
+		if u, ok := t.Uint64(); ok {
+			return u, nil
+		}
+		return float64(t.Float()), nil
+	case starlark.Float:
+		f := float64(t)
+		if math.IsInf(f, 0) || math.IsNaN(f) {
+			return nil, fmt.Errorf("float value %v cannot be converted to JSON", t)
+		}
+		return f, nil
+	case starlark.Indexable:
+		// Lists and tuples
+		arr := make([]any, 0, t.Len())
+		for i := 0; i < t.Len(); i++ {
+			e, err := fromStarlark(t.Index(i))
+			if err != nil {
+				return nil, err
+			}
+			arr = append(arr, e)
+		}
+		return arr, nil
+	case starlark.IterableMapping:
+		obj := map[string]any{}
+		for _, item := range t.Items() {
+			k, ok := item[0].(starlark.String)
+			if !ok {
+				return nil, fmt.Errorf("dict key of type %v cannot be converted to JSON", item[0].Type())
+			}
+			e, err := fromStarlark(item[1])
+			if err != nil {
+				return nil, err
+			}
+			obj[string(k)] = e
+		}
+		return obj, nil
+	case *starlark.Set:
+		arr := make([]any, 0, t.Len())
+		iter := t.Iterate()
+		defer iter.Done()
+		var e starlark.Value
+		for iter.Next(&e) {
+			ge, err := fromStarlark(e)
+			if err != nil {
+				return nil, err
+			}
+			arr = append(arr, ge)
+		}
+		return arr, nil
+	}
+	return nil, fmt.Errorf("value of type %v cannot be converted to JSON", v.Type())
+}
@@ -0,0 +1,226 @@
+package starlark
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	starjson "go.starlark.net/lib/json"
+	starmath "go.starlark.net/lib/math"
+	"go.starlark.net/starlark"
+	"go.starlark.net/syntax"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	spFieldScript   = "script"
+	spFieldFile     = "file"
+	spFieldMaxSteps = "max_steps"
+
+	processFunctionName = "process"
+)
+
+func starlarkProcessorConfig() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Categories("Mapping").
+		Version("4.28.0").
+		Summary("Executes a [Starlark](https://github.com/bazelbuild/starlark) script for each message within a deterministic and sandboxed environment.").
+		Description(`
+Scripts must define a function named `+"`process`"+` that accepts a single argument, which is the message being processed. The function is called for each message, and when it returns a value other than `+"`None`"+` the contents of the message are replaced with that value. Strings and bytes become the raw contents of the message, and all other values are serialised as JSON.
+
+Starlark is a dialect of Python designed to be embedded, and scripts executed by this processor are sandboxed: they have no access to the file system, network, clock or sources of randomness, and cannot `+"`load`"+` other modules. The only modules available are `+"`json`"+` and `+"`math`"+`. Global variables are frozen once the script has been executed, and therefore no state is carried between messages, which makes the result of a script depend only on the message it is given. Each call is limited to the number of execution steps set by the field `+"`max_steps`"+`, and fails once that limit is exceeded.
+
+These properties make this processor a safer alternative to the [`+"`javascript`"+` processor](/docs/components/processors/javascript) for executing transformations supplied by users of a multi-tenant deployment.
+
+If the script fails the message is flagged as having failed, allowing you to use [standard processor error handling patterns](/docs/configuration/error_handling). Messages can also be flagged as having failed by the script with `+"`msg.set_error`"+`.`).
+		Footnotes(`
+## Message API
+
+The message passed to the function `+"`process`"+` has the following attributes:
+
+- `+"`msg.content`"+`: The raw contents of the message as bytes.
+- `+"`msg.json()`"+`: Returns the contents of the message parsed as JSON.
+- `+"`msg.get_meta(key, default=None)`"+`: Returns the value of a metadata key, or the default if it does not exist.
+- `+"`msg.set_meta(key, value)`"+`: Sets a metadata key to a value.
+- `+"`msg.delete_meta(key)`"+`: Removes a metadata key.
+- `+"`msg.set_error(message)`"+`: Flags the message as having failed with an error message.
+- `+"`msg.drop()`"+`: Removes the message from the pipeline once the function returns.`).
+		Field(service.NewStringField(spFieldScript).
+			Description("An inline Starlark script to execute. One of `"+spFieldScript+"` or `"+spFieldFile+"` must be defined.").
+			Optional()).
+		Field(service.NewStringField(spFieldFile).
+			Description("A file containing a Starlark script to execute. One of `"+spFieldScript+"` or `"+spFieldFile+"` must be defined.").
+			Optional()).
+		Field(service.NewIntField(spFieldMaxSteps).
+			Description("The maximum number of execution steps that a single call of the function `process` may take, which bounds the amount of work that a script can perform for each message. Set to zero to disable the limit.").
+			Advanced().
+			Default(1000000)).
+		LintRule(fmt.Sprintf(`
+let scriptLen = (this.%v | "").length()
+let fileLen = (this.%v | "").length()
+root = if $scriptLen == 0 && $fileLen == 0 {
+  "either the script or file field must be specified"
+} else if $scriptLen > 0 && $fileLen > 0 {
+  "cannot specify both the script and file fields"
+}`, spFieldScript, spFieldFile)).
+		Example(
+			"Transform a document",
+			"A script that reshapes a JSON document and records the number of items it contained as metadata.",
+			`
+pipeline:
+  processors:
+    - starlark:
+        script: |
+          def process(msg):
+              doc = msg.json()
+              msg.set_meta("item_count", len(doc["items"]))
+              return {
+                  "id": doc["id"],
+                  "skus": [i["sku"] for i in doc["items"] if i["quantity"] > 0],
+              }
+`,
+		).
+		Example(
+			"Filter messages",
+			"A script that drops messages that fail a check, and flags messages that are missing a field.",
+			`
+pipeline:
+  processors:
+    - starlark:
+        script: |
+          def process(msg):
+              doc = msg.json()
+              if doc.get("type") == "heartbeat":
+                  msg.drop()
+              elif "user" not in doc:
+                  msg.set_error("document is missing a user")
+`,
+		)
+}
+
+func init() {
+	err := service.RegisterProcessor(
+		"starlark", starlarkProcessorConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Processor, error) {
+			return newStarlarkProcessorFromConfig(conf, mgr)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type starlarkProcessor struct {
+	log      *service.Logger
+	fn       starlark.Callable
+	maxSteps uint64
+}
+
+var fileOptions = &syntax.FileOptions{
+	Set:             true,
+	While:           true,
+	TopLevelControl: true,
+}
+
+func newStarlarkProcessorFromConfig(conf *service.ParsedConfig, mgr *service.Resources) (*starlarkProcessor, error) {
+	script, _ := conf.FieldString(spFieldScript)
+	file, _ := conf.FieldString(spFieldFile)
+	if file == "" && script == "" {
+		return nil, fmt.Errorf("either a `%v` or `%v` must be specified", spFieldScript, spFieldFile)
+	}
+
+	filename := "main.star"
+	if file != "" {
+		scriptBytes, err := service.ReadFile(mgr.FS(), file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open target file: %w", err)
+		}
+		filename = file
+		script = string(scriptBytes)
+	}
+
+	maxSteps, err := conf.FieldInt(spFieldMaxSteps)
+	if err != nil {
+		return nil, err
+	}
+	if maxSteps < 0 {
+		return nil, errors.New("max_steps must not be negative")
+	}
+
+	s := &starlarkProcessor{
+		log:      mgr.Logger(),
+		maxSteps: uint64(maxSteps),
+	}
+
+	thread := s.newThread()
+	globals, err := starlark.ExecFileOptions(fileOptions, thread, filename, script, starlark.StringDict{
+		"json": starjson.Module,
+		"math": starmath.Module,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute script: %w", err)
+	}
+
+	fn, ok := globals[processFunctionName].(starlark.Callable)
+	if !ok {
+		return nil, fmt.Errorf("script must define a function named %v", processFunctionName)
+	}
+	s.fn = fn
+	return s, nil
+}
+
+func (s *starlarkProcessor) newThread() *starlark.Thread {
+	thread := &starlark.Thread{
+		Name: "benthos",
+		Print: func(_ *starlark.Thread, msg string) {
+			s.log.Debug(msg)
+		},
+		Load: func(_ *starlark.Thread, module string) (starlark.StringDict, error) {
+			return nil, fmt.Errorf("cannot load module %v, modules are not supported", module)
+		},
+	}
+	if s.maxSteps > 0 {
+		thread.SetMaxExecutionSteps(s.maxSteps)
+	}
+	return thread
+}
+
+func (s *starlarkProcessor) Process(ctx context.Context, msg *service.Message) (service.MessageBatch, error) {
+	thread := s.newThread()
+	stop := context.AfterFunc(ctx, func() {
+		thread.Cancel(ctx.Err().Error())
+	})
+	defer stop()
+
+	sMsg := &message{msg: msg}
+	res, err := starlark.Call(thread, s.fn, starlark.Tuple{sMsg}, nil)
+	if err != nil {
+		var evalErr *starlark.EvalError
+		if errors.As(err, &evalErr) {
+			return nil, errors.New(evalErr.Backtrace())
+		}
+		return nil, err
+	}
+	if sMsg.dropped {
+		return nil, nil
+	}
+
+	switch t := res.(type) {
+	case starlark.NoneType:
+	case starlark.String:
+		msg.SetBytes([]byte(t))
+	case starlark.Bytes:
+		msg.SetBytes([]byte(t))
+	default:
+		v, err := fromStarlark(res)
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert result: %w", err)
+		}
+		msg.SetStructuredMut(v)
+	}
+	return service.MessageBatch{msg}, nil
+}
+
+func (s *starlarkProcessor) Close(ctx context.Context) error {
+	return nil
+}
@@ -0,0 +1,144 @@
+package starlark
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func TestProcessorTransform(t *testing.T) {
+	conf, err := starlarkProcessorConfig().ParseYAML(`
+script: |
+  def process(msg):
+      doc = msg.json()
+      msg.set_meta("item_count", len(doc["items"]))
+      msg.set_meta("source", msg.get_meta("source", "unknown"))
+      msg.delete_meta("remove_me")
+      return {
+          "id": doc["id"],
+          "skus": [i["sku"] for i in doc["items"] if i["quantity"] > 0],
+          "ratio": doc["ratio"] * 2,
+          "encoded": json.encode({"b": 2, "a": 1}),
+      }
+`, nil)
+	require.NoError(t, err)
+
+	proc, err := newStarlarkProcessorFromConfig(conf, service.MockResources())
+	require.NoError(t, err)
+
+	msg := service.NewMessage([]byte(`{"id":"foo","ratio":0.25,"items":[{"sku":"a","quantity":1},{"sku":"b","quantity":0}]}`))
+	msg.MetaSetMut("remove_me", "bar")
+
+	res, err := proc.Process(context.Background(), msg)
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+
+	resBytes, err := res[0].AsBytes()
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"id":"foo","skus":["a"],"ratio":0.5,"encoded":"{\"a\":1,\"b\":2}"}`, string(resBytes))
+
+	v, _ := res[0].MetaGet("item_count")
+	assert.Equal(t, "2", v)
+	v, _ = res[0].MetaGet("source")
+	assert.Equal(t, "unknown", v)
+	_, exists := res[0].MetaGet("remove_me")
+	assert.False(t, exists)
+}
+
+func TestProcessorRawContent(t *testing.T) {
+	conf, err := starlarkProcessorConfig().ParseYAML(`
+script: |
+  def process(msg):
+      content = str(msg.content)
+      if content == "keep":
+          return None
+      return content.upper()
+`, nil)
+	require.NoError(t, err)
+
+	proc, err := newStarlarkProcessorFromConfig(conf, service.MockResources())
+	require.NoError(t, err)
+
+	for in, exp := range map[string]string{
+		"keep":  "keep",
+		"hello": "HELLO",
+	} {
+		res, err := proc.Process(context.Background(), service.NewMessage([]byte(in)))
+		require.NoError(t, err)
+		require.Len(t, res, 1)
+
+		resBytes, err := res[0].AsBytes()
+		require.NoError(t, err)
+		assert.Equal(t, exp, string(resBytes))
+	}
+}
+
+func TestProcessorErrorsAndDrop(t *testing.T) {
+	conf, err := starlarkProcessorConfig().ParseYAML(`
+script: |
+  def process(msg):
+      content = str(msg.content)
+      if content == "drop":
+          msg.drop()
+      elif content == "flag":
+          msg.set_error("flagged")
+      elif content == "fail":
+          fail("raised")
+`, nil)
+	require.NoError(t, err)
+
+	proc, err := newStarlarkProcessorFromConfig(conf, service.MockResources())
+	require.NoError(t, err)
+
+	res, err := proc.Process(context.Background(), service.NewMessage([]byte("drop")))
+	require.NoError(t, err)
+	assert.Empty(t, res)
+
+	res, err = proc.Process(context.Background(), service.NewMessage([]byte("flag")))
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+	require.EqualError(t, res[0].GetError(), "flagged")
+
+	_, err = proc.Process(context.Background(), service.NewMessage([]byte("fail")))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "raised")
+}
+
+func TestProcessorSandbox(t *testing.T) {
+	conf, err := starlarkProcessorConfig().ParseYAML(`
+max_steps: 1000
+script: |
+  def process(msg):
+      n = 0
+      while True:
+          n += 1
+`, nil)
+	require.NoError(t, err)
+
+	proc, err := newStarlarkProcessorFromConfig(conf, service.MockResources())
+	require.NoError(t, err)
+
+	_, err = proc.Process(context.Background(), service.NewMessage([]byte("foo")))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "too many steps")
+
+	for _, script := range []string{
+		"load(\"foo.star\", \"bar\")\ndef process(msg):\n    return None",
+		"def transform(msg):\n    return None",
+		"x = [",
+	} {
+		scriptConf, err := json.Marshal(map[string]any{"script": script})
+		require.NoError(t, err)
+
+		conf, err := starlarkProcessorConfig().ParseYAML(string(scriptConf), nil)
+		require.NoError(t, err)
+
+		_, err = newStarlarkProcessorFromConfig(conf, service.MockResources())
+		require.Error(t, err, script)
+	}
+}
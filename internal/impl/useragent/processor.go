@@ -0,0 +1,304 @@
+package useragent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/gabs/v2"
+	"github.com/Jeffail/shutdown"
+	lru "github.com/hashicorp/golang-lru/v2"
+	"github.com/ua-parser/uap-go/uaparser"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	upFieldField          = "field"
+	upFieldTarget         = "target"
+	upFieldRegexesFile    = "regexes_file"
+	upFieldReloadInterval = "reload_interval"
+	upFieldCacheSize      = "cache_size"
+)
+
+func userAgentProcessorConfig() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Categories("Parsing").
+		Version("4.28.0").
+		Summary("Parses user agent strings into the browser, operating system and device that they describe.").
+		Description(`
+User agents are parsed with the regular expressions of the [uap-core](https://github.com/ua-parser/uap-core) project. By default the expressions bundled with this processor are used, and a newer copy of the file `+"`regexes.yaml`"+` can be loaded with the field `+"`regexes_file`"+`, in which case the file is checked for changes at the interval `+"`reload_interval`"+` and updated expressions are used without interrupting the pipeline.
+
+Messages are parsed as JSON, and the result of parsing the user agent within `+"`field`"+` is added as an object to `+"`target`"+` with the following structure:
+
+`+"```json"+`
+{
+  "browser": { "family": "Chrome", "major": "120", "minor": "0", "patch": "0", "version": "120.0.0" },
+  "os": { "family": "Windows", "major": "10", "minor": "", "patch": "", "version": "10" },
+  "device": { "family": "Other", "brand": "", "model": "" }
+}
+`+"```"+`
+
+Parsing user agents is expensive, and since the same user agents recur frequently within most traffic the results of recent parses are kept in a cache, the size of which is set by `+"`cache_size`"+`.
+
+Messages that do not contain `+"`field`"+` are left unchanged, and messages where the field is not a string are flagged as having failed, allowing you to use [standard processor error handling patterns](/docs/configuration/error_handling).`).
+		Field(service.NewStringField(upFieldField).
+			Description("The [dot path](/docs/configuration/field_paths) of the user agent string within messages.").
+			Example("request.headers.user_agent")).
+		Field(service.NewStringField(upFieldTarget).
+			Description("The [dot path](/docs/configuration/field_paths) of the field to add the parsed user agent to.").
+			Example("request.client")).
+		Field(service.NewStringField(upFieldRegexesFile).
+			Description("An optional path to a uap-core `regexes.yaml` file to use instead of the bundled expressions.").
+			Example("./uap-core/regexes.yaml").
+			Optional()).
+		Field(service.NewDurationField(upFieldReloadInterval).
+			Description("The interval at which the `regexes_file` is checked for changes. Set to zero to disable reloading.").
+			Advanced().
+			Default("1m")).
+		Field(service.NewIntField(upFieldCacheSize).
+			Description("The maximum number of parsed user agents to cache. Set to zero to disable the cache.").
+			Advanced().
+			Default(10000)).
+		Example(
+			"Clickstream enrichment",
+			"Here we add the browser, operating system and device of visitors to clickstream events.",
+			`
+pipeline:
+  processors:
+    - user_agent:
+        field: context.user_agent
+        target: context.client
+`,
+		)
+}
+
+func init() {
+	err := service.RegisterProcessor(
+		"user_agent", userAgentProcessorConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Processor, error) {
+			return newUserAgentProcessorFromConfig(conf, mgr)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type userAgentProcessor struct {
+	log *service.Logger
+
+	field  string
+	target string
+
+	regexesFile string
+	modTime     time.Time
+	size        int64
+
+	parserMut sync.RWMutex
+	parser    *uaparser.Parser
+	cache     *lru.Cache[string, *uaparser.Client]
+
+	shutSig *shutdown.Signaller
+}
+
+func newUserAgentProcessorFromConfig(conf *service.ParsedConfig, mgr *service.Resources) (*userAgentProcessor, error) {
+	u := &userAgentProcessor{
+		log:     mgr.Logger(),
+		shutSig: shutdown.NewSignaller(),
+	}
+
+	var err error
+	if u.field, err = conf.FieldString(upFieldField); err != nil {
+		return nil, err
+	}
+	if u.target, err = conf.FieldString(upFieldTarget); err != nil {
+		return nil, err
+	}
+
+	cacheSize, err := conf.FieldInt(upFieldCacheSize)
+	if err != nil {
+		return nil, err
+	}
+	if cacheSize < 0 {
+		return nil, errors.New("cache size must not be negative")
+	}
+	if cacheSize > 0 {
+		if u.cache, err = lru.New[string, *uaparser.Client](cacheSize); err != nil {
+			return nil, err
+		}
+	}
+
+	if conf.Contains(upFieldRegexesFile) {
+		if u.regexesFile, err = conf.FieldString(upFieldRegexesFile); err != nil {
+			return nil, err
+		}
+	}
+	if u.regexesFile == "" {
+		u.parser = uaparser.NewFromSaved()
+		u.shutSig.TriggerHasStopped()
+		return u, nil
+	}
+
+	if _, err := u.loadRegexes(); err != nil {
+		return nil, err
+	}
+
+	reloadInterval, err := conf.FieldDuration(upFieldReloadInterval)
+	if err != nil {
+		return nil, err
+	}
+	if reloadInterval > 0 {
+		go u.reloadLoop(reloadInterval)
+	} else {
+		u.shutSig.TriggerHasStopped()
+	}
+	return u, nil
+}
+
+// loadRegexes loads the regexes file when it has changed since it was last
+// loaded, and returns whether it was loaded.
+func (u *userAgentProcessor) loadRegexes() (bool, error) {
+	info, err := os.Stat(u.regexesFile)
+	if err != nil {
+		return false, err
+	}
+	if u.parser != nil && info.ModTime().Equal(u.modTime) && info.Size() == u.size {
+		return false, nil
+	}
+
+	regexBytes, err := os.ReadFile(u.regexesFile)
+	if err != nil {
+		return false, err
+	}
+	parser, err := uaparser.NewFromBytes(regexBytes)
+	if err != nil {
+		return false, fmt.Errorf("failed to parse regexes file: %w", err)
+	}
+
+	u.parserMut.Lock()
+	u.parser = parser
+	if u.cache != nil {
+		u.cache.Purge()
+	}
+	u.parserMut.Unlock()
+
+	u.modTime, u.size = info.ModTime(), info.Size()
+	return true, nil
+}
+
+func (u *userAgentProcessor) reloadLoop(interval time.Duration) {
+	defer u.shutSig.TriggerHasStopped()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			reloaded, err := u.loadRegexes()
+			if err != nil {
+				u.log.Errorf("Failed to reload regexes file: %v", err)
+				continue
+			}
+			if reloaded {
+				u.log.Infof("Reloaded regexes file %v", u.regexesFile)
+			}
+		case <-u.shutSig.HardStopChan():
+			return
+		}
+	}
+}
+
+func (u *userAgentProcessor) parse(ua string) *uaparser.Client {
+	u.parserMut.RLock()
+	defer u.parserMut.RUnlock()
+
+	if u.cache != nil {
+		if c, exists := u.cache.Get(ua); exists {
+			return c
+		}
+	}
+	c := u.parser.Parse(ua)
+	if u.cache != nil {
+		u.cache.Add(ua, c)
+	}
+	return c
+}
+
+func joinVersion(parts ...string) string {
+	var nonEmpty []string
+	for _, p := range parts {
+		if p == "" {
+			break
+		}
+		nonEmpty = append(nonEmpty, p)
+	}
+	return strings.Join(nonEmpty, ".")
+}
+
+func clientToStructured(c *uaparser.Client) map[string]any {
+	obj := map[string]any{}
+	if ua := c.UserAgent; ua != nil {
+		obj["browser"] = map[string]any{
+			"family":  ua.Family,
+			"major":   ua.Major,
+			"minor":   ua.Minor,
+			"patch":   ua.Patch,
+			"version": joinVersion(ua.Major, ua.Minor, ua.Patch),
+		}
+	}
+	if o := c.Os; o != nil {
+		obj["os"] = map[string]any{
+			"family":  o.Family,
+			"major":   o.Major,
+			"minor":   o.Minor,
+			"patch":   o.Patch,
+			"version": joinVersion(o.Major, o.Minor, o.Patch, o.PatchMinor),
+		}
+	}
+	if d := c.Device; d != nil {
+		obj["device"] = map[string]any{
+			"family": d.Family,
+			"brand":  d.Brand,
+			"model":  d.Model,
+		}
+	}
+	return obj
+}
+
+func (u *userAgentProcessor) Process(ctx context.Context, msg *service.Message) (service.MessageBatch, error) {
+	v, err := msg.AsStructuredMut()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse message as JSON: %w", err)
+	}
+
+	doc := gabs.Wrap(v)
+	uaV := doc.Path(u.field).Data()
+	if uaV == nil {
+		return service.MessageBatch{msg}, nil
+	}
+	ua, ok := uaV.(string)
+	if !ok {
+		return nil, fmt.Errorf("field %v: expected string value, got %T", u.field, uaV)
+	}
+
+	if _, err := doc.SetP(clientToStructured(u.parse(ua)), u.target); err != nil {
+		return nil, fmt.Errorf("field %v: %w", u.target, err)
+	}
+	msg.SetStructuredMut(doc.Data())
+	return service.MessageBatch{msg}, nil
+}
+
+func (u *userAgentProcessor) Close(ctx context.Context) error {
+	u.shutSig.TriggerHardStop()
+	select {
+	case <-u.shutSig.HasStoppedChan():
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return nil
+}
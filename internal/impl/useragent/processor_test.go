@@ -0,0 +1,121 @@
+package useragent
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func TestUserAgentProcessorBundled(t *testing.T) {
+	conf, err := userAgentProcessorConfig().ParseYAML(`
+field: request.ua
+target: request.client
+`, nil)
+	require.NoError(t, err)
+
+	proc, err := newUserAgentProcessorFromConfig(conf, service.MockResources())
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, proc.Close(context.Background()))
+	})
+
+	ua := "Mozilla/5.0 (iPhone; CPU iPhone OS 16_5 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/16.5 Mobile/15E148 Safari/604.1"
+	for i := 0; i < 2; i++ {
+		res, err := proc.Process(context.Background(), service.NewMessage([]byte(`{"request":{"ua":"`+ua+`"}}`)))
+		require.NoError(t, err)
+		require.Len(t, res, 1)
+
+		v, err := res[0].AsStructured()
+		require.NoError(t, err)
+
+		client := v.(map[string]any)["request"].(map[string]any)["client"].(map[string]any)
+		browser := client["browser"].(map[string]any)
+		assert.Equal(t, "Mobile Safari", browser["family"])
+		assert.Equal(t, "16.5", browser["version"])
+
+		os := client["os"].(map[string]any)
+		assert.Equal(t, "iOS", os["family"])
+		assert.Equal(t, "16", os["major"])
+
+		device := client["device"].(map[string]any)
+		assert.Equal(t, "iPhone", device["family"])
+		assert.Equal(t, "Apple", device["brand"])
+	}
+	assert.Equal(t, 1, proc.cache.Len())
+
+	res, err := proc.Process(context.Background(), service.NewMessage([]byte(`{"request":{}}`)))
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+
+	b, err := res[0].AsBytes()
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"request":{}}`, string(b))
+
+	_, err = proc.Process(context.Background(), service.NewMessage([]byte(`{"request":{"ua":10}}`)))
+	require.Error(t, err)
+}
+
+const testRegexes = `
+user_agent_parsers:
+  - regex: '(%v)/(\d+)\.(\d+)'
+os_parsers:
+  - regex: '(FooOS) (\d+)'
+device_parsers:
+  - regex: '(FooPhone)'
+    brand_replacement: 'Foo'
+    model_replacement: '$1'
+`
+
+func TestUserAgentProcessorRegexesReload(t *testing.T) {
+	tmpDir := t.TempDir()
+	regexesPath := filepath.Join(tmpDir, "regexes.yaml")
+
+	writeRegexes := func(family string) {
+		tmpPath := filepath.Join(tmpDir, "regexes.yaml.tmp")
+		require.NoError(t, os.WriteFile(tmpPath, []byte(fmt.Sprintf(testRegexes, family)), 0o644))
+		require.NoError(t, os.Rename(tmpPath, regexesPath))
+	}
+	writeRegexes("FooBrowser")
+
+	conf, err := userAgentProcessorConfig().ParseYAML(`
+field: ua
+target: client
+regexes_file: `+regexesPath+`
+reload_interval: 10ms
+`, nil)
+	require.NoError(t, err)
+
+	proc, err := newUserAgentProcessorFromConfig(conf, service.MockResources())
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, proc.Close(context.Background()))
+	})
+
+	browserFamily := func() any {
+		res, err := proc.Process(context.Background(), service.NewMessage([]byte(`{"ua":"FooBrowser/1.2 BarBrowser/3.4 (FooOS 7; FooPhone)"}`)))
+		require.NoError(t, err)
+		require.Len(t, res, 1)
+
+		v, err := res[0].AsStructured()
+		require.NoError(t, err)
+
+		client := v.(map[string]any)["client"].(map[string]any)
+		assert.Equal(t, "FooOS", client["os"].(map[string]any)["family"])
+		assert.Equal(t, "Foo", client["device"].(map[string]any)["brand"])
+		return client["browser"].(map[string]any)["family"]
+	}
+	assert.Equal(t, "FooBrowser", browserFamily())
+
+	writeRegexes("BarBrowser")
+	assert.Eventually(t, func() bool {
+		return browserFamily() == "BarBrowser"
+	}, time.Second*5, 10*time.Millisecond)
+}
@@ -0,0 +1,419 @@
+package vectordb
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+
+	"github.com/benthosdev/benthos/v4/internal/impl/pure"
+	"github.com/benthosdev/benthos/v4/public/bloblang"
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	// Common fields of vector database outputs
+	vdbFieldID       = "id_mapping"
+	vdbFieldVector   = "vector_mapping"
+	vdbFieldTimeout  = "timeout"
+	vdbFieldTLS      = "tls"
+	vdbFieldBatching = "batching"
+)
+
+func pointFields(payloadField, payloadDescription string) []*service.ConfigField {
+	return []*service.ConfigField{
+		service.NewBloblangField(vdbFieldID).
+			Description("A [Bloblang mapping](/docs/guides/bloblang/about) that results in the ID of each point.").
+			Example(`root = this.id`).
+			Example(`root = uuid_v4()`),
+		service.NewBloblangField(vdbFieldVector).
+			Description("A [Bloblang mapping](/docs/guides/bloblang/about) that results in the vector of each point as an array of numbers.").
+			Example(`root = this.embedding`),
+		service.NewBloblangField(payloadField).
+			Description(payloadDescription).
+			Example(`root = this.without("embedding")`).
+			Optional(),
+	}
+}
+
+func httpFields() []*service.ConfigField {
+	return append([]*service.ConfigField{
+		service.NewDurationField(vdbFieldTimeout).
+			Description("The maximum period to wait for a request to complete.").
+			Advanced().
+			Default("30s"),
+		service.NewTLSToggledField(vdbFieldTLS),
+		service.NewOutputMaxInFlightField(),
+		service.NewBatchPolicyField(vdbFieldBatching),
+	}, pure.CommonRetryBackOffFields(3, "500ms", "10s", "1m")...)
+}
+
+func registerOutput(name string, spec *service.ConfigSpec, ctor func(conf *service.ParsedConfig, mgr *service.Resources) (service.BatchOutput, error)) {
+	err := service.RegisterBatchOutput(name, spec,
+		func(conf *service.ParsedConfig, mgr *service.Resources) (out service.BatchOutput, batchPolicy service.BatchPolicy, maxInFlight int, err error) {
+			if maxInFlight, err = conf.FieldMaxInFlight(); err != nil {
+				return
+			}
+			if batchPolicy, err = conf.FieldBatchPolicy(vdbFieldBatching); err != nil {
+				return
+			}
+			out, err = ctor(conf, mgr)
+			return
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// point is a vector along with its ID and payload resolved from a message.
+type point struct {
+	id      any
+	vector  any
+	payload map[string]any
+}
+
+type pointMapper struct {
+	id      *bloblang.Executor
+	vector  *bloblang.Executor
+	payload *bloblang.Executor
+}
+
+func pointMapperFromParsed(conf *service.ParsedConfig, payloadField string) (p pointMapper, err error) {
+	if p.id, err = conf.FieldBloblang(vdbFieldID); err != nil {
+		return
+	}
+	if p.vector, err = conf.FieldBloblang(vdbFieldVector); err != nil {
+		return
+	}
+	if conf.Contains(payloadField) {
+		if p.payload, err = conf.FieldBloblang(payloadField); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// normaliseValue converts numbers resulting from a mapping into types that
+// encode predictably.
+func normaliseValue(v any) any {
+	switch t := v.(type) {
+	case json.Number:
+		if i, err := t.Int64(); err == nil {
+			return i
+		}
+		f, _ := t.Float64()
+		return f
+	case map[string]any:
+		m := make(map[string]any, len(t))
+		for k, e := range t {
+			m[k] = normaliseValue(e)
+		}
+		return m
+	case []any:
+		s := make([]any, len(t))
+		for i, e := range t {
+			s[i] = normaliseValue(e)
+		}
+		return s
+	}
+	return v
+}
+
+func toFloat(v any) (float64, error) {
+	switch t := v.(type) {
+	case float64:
+		return t, nil
+	case float32:
+		return float64(t), nil
+	case int64:
+		return float64(t), nil
+	case int:
+		return float64(t), nil
+	case uint64:
+		return float64(t), nil
+	case json.Number:
+		return t.Float64()
+	}
+	return 0, fmt.Errorf("expected number, got %T", v)
+}
+
+// vectorValues converts the result of a vector mapping into a dense vector.
+func vectorValues(v any) ([]float64, error) {
+	arr, ok := v.([]any)
+	if !ok {
+		if f, ok := v.([]float64); ok {
+			return f, nil
+		}
+		return nil, fmt.Errorf("expected vector to be an array of numbers, got %T", v)
+	}
+	if len(arr) == 0 {
+		return nil, errors.New("vector must not be empty")
+	}
+	vec := make([]float64, len(arr))
+	for i, e := range arr {
+		f, err := toFloat(e)
+		if err != nil {
+			return nil, fmt.Errorf("vector element %v: %w", i, err)
+		}
+		vec[i] = f
+	}
+	return vec, nil
+}
+
+// queryValue executes a mapping against a message of a batch and returns the
+// resulting value, which is nil when the mapping deletes the root. Results that
+// aren't valid JSON, such as string ids, are returned as strings.
+func queryValue(batch service.MessageBatch, i int, m *bloblang.Executor) (any, error) {
+	resMsg, err := batch.BloblangQuery(i, m)
+	if err != nil || resMsg == nil {
+		return nil, err
+	}
+	v, err := resMsg.AsStructured()
+	if err != nil {
+		if b, _ := resMsg.AsBytes(); len(b) > 0 {
+			return string(b), nil
+		}
+		return nil, err
+	}
+	return v, nil
+}
+
+// mapPoint resolves a point from a message, where the vector is left as the
+// raw result of the mapping for the output to validate.
+func (p *pointMapper) mapPoint(batch service.MessageBatch, i int) (point, error) {
+	var pt point
+
+	idV, err := queryValue(batch, i, p.id)
+	if err != nil {
+		return pt, fmt.Errorf("id mapping failed: %w", err)
+	}
+	if idV == nil {
+		return pt, errors.New("id mapping resulted in null")
+	}
+	pt.id = normaliseValue(idV)
+
+	vecV, err := queryValue(batch, i, p.vector)
+	if err != nil {
+		return pt, fmt.Errorf("vector mapping failed: %w", err)
+	}
+	pt.vector = normaliseValue(vecV)
+
+	if p.payload != nil {
+		payloadV, err := queryValue(batch, i, p.payload)
+		if err != nil {
+			return pt, fmt.Errorf("payload mapping failed: %w", err)
+		}
+		if payloadV != nil {
+			obj, ok := normaliseValue(payloadV).(map[string]any)
+			if !ok {
+				return pt, fmt.Errorf("expected payload mapping to result in an object, got %T", payloadV)
+			}
+			pt.payload = obj
+		}
+	}
+	return pt, nil
+}
+
+//------------------------------------------------------------------------------
+
+// pointGroup is a group of points written with the same request, such as
+// points of the same collection, where each point is held in the form sent to
+// the database.
+type pointGroup struct {
+	key    string
+	points []any
+	index  []int
+}
+
+// pointGrouper groups the points of a batch, tracking messages that could not
+// be converted into points.
+type pointGrouper struct {
+	batch    service.MessageBatch
+	batchErr *service.BatchError
+	groups   []*pointGroup
+	byKey    map[string]*pointGroup
+}
+
+func newPointGrouper(batch service.MessageBatch) *pointGrouper {
+	return &pointGrouper{
+		batch: batch,
+		byKey: map[string]*pointGroup{},
+	}
+}
+
+func (g *pointGrouper) add(key string, i int, pt any) {
+	pg, exists := g.byKey[key]
+	if !exists {
+		pg = &pointGroup{key: key}
+		g.byKey[key] = pg
+		g.groups = append(g.groups, pg)
+	}
+	pg.points = append(pg.points, pt)
+	pg.index = append(pg.index, i)
+}
+
+func (g *pointGrouper) fail(i int, err error) {
+	if g.batchErr == nil {
+		g.batchErr = service.NewBatchError(g.batch, err)
+	}
+	g.batchErr.Failed(i, err)
+}
+
+// write writes each group of points, and when any messages fail returns a
+// batch error that marks only those messages as failed.
+func (g *pointGrouper) write(ctx context.Context, fn func(ctx context.Context, pg *pointGroup) error) error {
+	if len(g.batch) == 1 && g.batchErr != nil {
+		return g.batchErr.Unwrap()
+	}
+	for _, pg := range g.groups {
+		if err := fn(ctx, pg); err != nil {
+			if len(g.groups) == 1 && g.batchErr == nil {
+				return err
+			}
+			for _, i := range pg.index {
+				g.fail(i, err)
+			}
+		}
+	}
+	if g.batchErr != nil {
+		return g.batchErr
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+type statusError struct {
+	status     int
+	body       []byte
+	retryAfter time.Duration
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("request failed with status %v: %s", e.status, bytes.TrimSpace(e.body))
+}
+
+func (e *statusError) retryable() bool {
+	return e.status == http.StatusTooManyRequests || e.status >= 500
+}
+
+// apiClient sends JSON requests to a vector database, retrying requests that
+// fail due to connection errors, rate limits or server errors.
+type apiClient struct {
+	log         *service.Logger
+	client      *http.Client
+	headers     map[string]string
+	backoffCtor func() backoff.BackOff
+}
+
+func apiClientFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*apiClient, error) {
+	c := &apiClient{
+		log:     mgr.Logger(),
+		headers: map[string]string{},
+	}
+
+	timeout, err := conf.FieldDuration(vdbFieldTimeout)
+	if err != nil {
+		return nil, err
+	}
+	tlsConf, tlsEnabled, err := conf.FieldTLSToggled(vdbFieldTLS)
+	if err != nil {
+		return nil, err
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if tlsEnabled {
+		transport.TLSClientConfig = tlsConf
+	}
+	c.client = &http.Client{Timeout: timeout, Transport: transport}
+
+	if c.backoffCtor, err = pure.CommonRetryBackOffCtorFromParsed(conf); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// do sends a request with a JSON body, decoding the response body into res
+// when it is not nil.
+func (c *apiClient) do(ctx context.Context, method, reqURL string, body, res any) error {
+	reqBody, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	boff := c.backoffCtor()
+	for {
+		err := c.doOnce(ctx, method, reqURL, reqBody, res)
+		if err == nil {
+			return nil
+		}
+
+		var sErr *statusError
+		if errors.As(err, &sErr) && !sErr.retryable() {
+			return err
+		}
+
+		wait := boff.NextBackOff()
+		if wait == backoff.Stop {
+			return err
+		}
+		if sErr != nil && sErr.retryAfter > wait {
+			wait = sErr.retryAfter
+		}
+		c.log.Warnf("Retrying upsert request in %v: %v", wait, err)
+
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (c *apiClient) doOnce(ctx context.Context, method, reqURL string, body []byte, res any) error {
+	req, err := http.NewRequestWithContext(ctx, method, reqURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	for k, v := range c.headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		resBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		sErr := &statusError{status: resp.StatusCode, body: resBody}
+		if secs, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && secs > 0 {
+			sErr.retryAfter = time.Duration(secs) * time.Second
+		}
+		return sErr
+	}
+
+	if res == nil {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(res); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+func (c *apiClient) close() {
+	c.client.CloseIdleConnections()
+}
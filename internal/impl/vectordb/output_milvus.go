@@ -0,0 +1,217 @@
+package vectordb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	mvFieldURL         = "url"
+	mvFieldToken       = "token"
+	mvFieldDatabase    = "database"
+	mvFieldCollection  = "collection"
+	mvFieldIDField     = "id_field"
+	mvFieldVectorField = "vector_field"
+	mvFieldPayload     = "payload_mapping"
+)
+
+func milvusOutputSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Services").
+		Version("4.28.0").
+		Summary("Upserts entities into a [Milvus](https://milvus.io/) collection.").
+		Description(`
+Each message is converted into an entity with a primary key and a vector resolved with mappings, which are assigned to the fields named by `+"`id_field`"+` and `+"`vector_field`"+` respectively. The fields of the object resulting from the optional `+"`payload_mapping`"+` are added to the entity as scalar fields, or as dynamic fields when enabled on the collection.
+
+The type of the primary key resulting from the `+"`id_mapping`"+` must match the schema of the collection, where integer keys must be mapped to numbers and `+"`VarChar`"+` keys to strings.
+
+### Batching
+
+Entities of a batch are grouped by the collection they target, and each group is upserted with a single request to the RESTful API. When the request for a group fails its messages are marked as failed whilst the remaining groups are delivered.
+
+Requests that fail due to connection errors, rate limits or server errors are retried using the backoff fields, honouring any `+"`Retry-After`"+` header of responses.`+service.OutputPerformanceDocs(true, true)).
+		Fields(
+			service.NewStringField(mvFieldURL).
+				Description("The base URL of the Milvus RESTful API.").
+				Example("http://localhost:19530").
+				Example("https://in01-xxxxxxxx.api.gcp-us-west1.zillizcloud.com"),
+			service.NewStringField(mvFieldToken).
+				Description("A token to authenticate with, which is either an API key or a username and password joined by a colon, e.g. `root:Milvus`.").
+				Secret().
+				Default(""),
+			service.NewStringField(mvFieldDatabase).
+				Description("The database of the collection. When empty the default database is used.").
+				Default(""),
+			service.NewInterpolatedStringField(mvFieldCollection).
+				Description("The collection to upsert entities into.").
+				Example("documents"),
+			service.NewStringField(mvFieldIDField).
+				Description("The name of the primary key field of the collection.").
+				Default("id"),
+			service.NewStringField(mvFieldVectorField).
+				Description("The name of the vector field of the collection.").
+				Default("vector"),
+		).
+		Fields(pointFields(mvFieldPayload, "An optional [Bloblang mapping](/docs/guides/bloblang/about) that results in an object of additional fields of each entity.")...).
+		Fields(httpFields()...).
+		Example("Embedded Documents", "Upsert documents along with their embeddings, retaining the title of each document as a scalar field.", `
+output:
+  milvus:
+    url: http://localhost:19530
+    token: root:${MILVUS_PASSWORD}
+    collection: documents
+    id_mapping: root = this.doc_id
+    vector_mapping: root = this.embedding
+    payload_mapping: root.title = this.title
+    batching:
+      count: 100
+      period: 1s
+`)
+}
+
+func init() {
+	registerOutput("milvus", milvusOutputSpec(), func(conf *service.ParsedConfig, mgr *service.Resources) (service.BatchOutput, error) {
+		return newMilvusOutputFromParsed(conf, mgr)
+	})
+}
+
+type milvusOutput struct {
+	baseURL     string
+	database    string
+	collection  *service.InterpolatedString
+	idField     string
+	vectorField string
+	mapper      pointMapper
+
+	client *apiClient
+}
+
+func newMilvusOutputFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*milvusOutput, error) {
+	m := &milvusOutput{}
+
+	var err error
+	if m.baseURL, err = conf.FieldString(mvFieldURL); err != nil {
+		return nil, err
+	}
+	m.baseURL = strings.TrimSuffix(m.baseURL, "/")
+	if m.database, err = conf.FieldString(mvFieldDatabase); err != nil {
+		return nil, err
+	}
+	if m.collection, err = conf.FieldInterpolatedString(mvFieldCollection); err != nil {
+		return nil, err
+	}
+	if m.idField, err = conf.FieldString(mvFieldIDField); err != nil {
+		return nil, err
+	}
+	if m.vectorField, err = conf.FieldString(mvFieldVectorField); err != nil {
+		return nil, err
+	}
+	if m.idField == "" || m.vectorField == "" {
+		return nil, errors.New("id_field and vector_field must not be empty")
+	}
+	if m.mapper, err = pointMapperFromParsed(conf, mvFieldPayload); err != nil {
+		return nil, err
+	}
+
+	if m.client, err = apiClientFromParsed(conf, mgr); err != nil {
+		return nil, err
+	}
+	token, err := conf.FieldString(mvFieldToken)
+	if err != nil {
+		return nil, err
+	}
+	if token != "" {
+		m.client.headers["Authorization"] = "Bearer " + token
+	}
+	return m, nil
+}
+
+func (m *milvusOutput) Connect(ctx context.Context) error {
+	return nil
+}
+
+func (m *milvusOutput) milvusEntity(batch service.MessageBatch, i int) (map[string]any, error) {
+	pt, err := m.mapper.mapPoint(batch, i)
+	if err != nil {
+		return nil, err
+	}
+
+	switch pt.id.(type) {
+	case string, int64:
+	default:
+		return nil, fmt.Errorf("expected id to be a string or an integer, got %T", pt.id)
+	}
+	vec, err := vectorValues(pt.vector)
+	if err != nil {
+		return nil, err
+	}
+
+	entity := make(map[string]any, len(pt.payload)+2)
+	for k, v := range pt.payload {
+		entity[k] = v
+	}
+	entity[m.idField] = pt.id
+	entity[m.vectorField] = vec
+	return entity, nil
+}
+
+func (m *milvusOutput) WriteBatch(ctx context.Context, batch service.MessageBatch) error {
+	grouper := newPointGrouper(batch)
+
+	for i := range batch {
+		collection, err := batch.TryInterpolatedString(i, m.collection)
+		if err != nil {
+			err = fmt.Errorf("collection interpolation failed: %w", err)
+		} else if collection == "" {
+			err = errors.New("collection interpolation resulted in an empty string")
+		}
+
+		var entity map[string]any
+		if err == nil {
+			entity, err = m.milvusEntity(batch, i)
+		}
+		if err != nil {
+			grouper.fail(i, err)
+			continue
+		}
+		grouper.add(collection, i, entity)
+	}
+	return grouper.write(ctx, m.upsert)
+}
+
+// milvusResponse is the envelope of RESTful API responses, which report
+// errors with a non-zero code rather than the status of the response.
+type milvusResponse struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (m *milvusOutput) upsert(ctx context.Context, g *pointGroup) error {
+	body := map[string]any{
+		"collectionName": g.key,
+		"data":           g.points,
+	}
+	if m.database != "" {
+		body["dbName"] = m.database
+	}
+
+	var res milvusResponse
+	if err := m.client.do(ctx, http.MethodPost, m.baseURL+"/v2/vectordb/entities/upsert", body, &res); err != nil {
+		return err
+	}
+	if res.Code != 0 {
+		return fmt.Errorf("upsert failed with code %v: %v", res.Code, res.Message)
+	}
+	return nil
+}
+
+func (m *milvusOutput) Close(ctx context.Context) error {
+	m.client.close()
+	return nil
+}
@@ -0,0 +1,95 @@
+package vectordb
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func testMilvusOutput(t *testing.T, conf string) *milvusOutput {
+	t.Helper()
+
+	pConf, err := milvusOutputSpec().ParseYAML(conf, nil)
+	require.NoError(t, err)
+
+	out, err := newMilvusOutputFromParsed(pConf, service.MockResources())
+	require.NoError(t, err)
+	return out
+}
+
+func TestMilvusUpsert(t *testing.T) {
+	srv, reqs := testServer(t, func(r recordedRequest) (int, string) {
+		return http.StatusOK, `{"code":0,"data":{"upsertCount":2}}`
+	})
+
+	out := testMilvusOutput(t, `
+url: `+srv.URL+`
+token: root:Milvus
+database: foo
+collection: docs
+id_field: pk
+vector_field: embedding
+id_mapping: root = this.id
+vector_mapping: root = this.vec
+payload_mapping: |
+  root.title = this.title
+  root.pk = "ignored"
+`)
+
+	require.NoError(t, out.WriteBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte(`{"id":1,"vec":[0.5,1],"title":"first"}`)),
+		service.NewMessage([]byte(`{"id":2,"vec":[1.5,2],"title":"second"}`)),
+	}))
+
+	r := reqs()
+	require.Len(t, r, 1)
+
+	assert.Equal(t, http.MethodPost, r[0].method)
+	assert.Equal(t, "/v2/vectordb/entities/upsert", r[0].path)
+	assert.Equal(t, "Bearer root:Milvus", r[0].header.Get("Authorization"))
+	assert.Equal(t, map[string]any{
+		"dbName":         "foo",
+		"collectionName": "docs",
+		"data": []any{
+			map[string]any{"pk": 1.0, "embedding": []any{0.5, 1.0}, "title": "first"},
+			map[string]any{"pk": 2.0, "embedding": []any{1.5, 2.0}, "title": "second"},
+		},
+	}, r[0].body)
+}
+
+func TestMilvusErrorCode(t *testing.T) {
+	srv, reqs := testServer(t, func(r recordedRequest) (int, string) {
+		if r.body["collectionName"] == "missing" {
+			return http.StatusOK, `{"code":100,"message":"collection not found[collection=missing]"}`
+		}
+		return http.StatusOK, `{"code":0,"data":{"upsertCount":1}}`
+	})
+
+	out := testMilvusOutput(t, `
+url: `+srv.URL+`
+collection: ${! json("coll") }
+id_mapping: root = this.id
+vector_mapping: root = this.vec
+`)
+
+	batch := service.MessageBatch{
+		service.NewMessage([]byte(`{"coll":"docs","id":"a","vec":[1]}`)),
+		service.NewMessage([]byte(`{"coll":"missing","id":"b","vec":[1]}`)),
+		service.NewMessage([]byte(`{"coll":"docs","id":1.5,"vec":[1]}`)),
+	}
+	idx := batch.Index()
+	err := out.WriteBatch(context.Background(), batch)
+	assert.Equal(t, []int{1, 2}, failedIndexes(t, idx, err))
+	assert.Len(t, reqs(), 2)
+
+	err = out.WriteBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte(`{"coll":"missing","id":"b","vec":[1]}`)),
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "collection not found")
+}
@@ -0,0 +1,190 @@
+package vectordb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	pcFieldHost      = "host"
+	pcFieldAPIKey    = "api_key"
+	pcFieldNamespace = "namespace"
+	pcFieldMetadata  = "metadata_mapping"
+
+	// The maximum number of vectors accepted by a single upsert request.
+	pineconeMaxVectors = 1000
+)
+
+func pineconeOutputSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Services").
+		Version("4.28.0").
+		Summary("Upserts vectors into a [Pinecone](https://www.pinecone.io/) index.").
+		Description(`
+Each message is converted into a vector with an ID, values and optional metadata resolved with mappings. Integer IDs are converted into strings.
+
+### Batching
+
+Vectors of a batch are grouped by the namespace they target, and each group is upserted with requests of up to 1000 vectors. When the requests of a group fail its messages are marked as failed whilst the remaining groups are delivered.
+
+Requests that fail due to connection errors, rate limits or server errors are retried using the backoff fields, honouring any `+"`Retry-After`"+` header of responses.`+service.OutputPerformanceDocs(true, true)).
+		Fields(
+			service.NewStringField(pcFieldHost).
+				Description("The host URL of the index, which can be found in the Pinecone console.").
+				Example("https://my-index-abcdefg.svc.us-east1-gcp.pinecone.io"),
+			service.NewStringField(pcFieldAPIKey).
+				Description("The API key to authenticate with.").
+				Secret(),
+			service.NewInterpolatedStringField(pcFieldNamespace).
+				Description("The namespace of the index to upsert vectors into, where an empty namespace targets the default namespace.").
+				Example(`${! meta("tenant") }`).
+				Default(""),
+		).
+		Fields(pointFields(pcFieldMetadata, "An optional [Bloblang mapping](/docs/guides/bloblang/about) that results in an object of the metadata of each vector.")...).
+		Fields(httpFields()...).
+		Example("Embedded Documents", "Upsert documents along with their embeddings into a namespace per tenant.", `
+output:
+  pinecone:
+    host: ${PINECONE_HOST}
+    api_key: ${PINECONE_API_KEY}
+    namespace: ${! meta("tenant") }
+    id_mapping: root = this.doc_id
+    vector_mapping: root = this.embedding
+    metadata_mapping: |
+      root.title = this.title
+      root.tags = this.tags
+    batching:
+      count: 100
+      period: 1s
+`)
+}
+
+func init() {
+	registerOutput("pinecone", pineconeOutputSpec(), func(conf *service.ParsedConfig, mgr *service.Resources) (service.BatchOutput, error) {
+		return newPineconeOutputFromParsed(conf, mgr)
+	})
+}
+
+type pineconeOutput struct {
+	host      string
+	namespace *service.InterpolatedString
+	mapper    pointMapper
+
+	client *apiClient
+}
+
+func newPineconeOutputFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*pineconeOutput, error) {
+	p := &pineconeOutput{}
+
+	var err error
+	if p.host, err = conf.FieldString(pcFieldHost); err != nil {
+		return nil, err
+	}
+	p.host = strings.TrimSuffix(p.host, "/")
+	if !strings.Contains(p.host, "://") {
+		p.host = "https://" + p.host
+	}
+	if p.namespace, err = conf.FieldInterpolatedString(pcFieldNamespace); err != nil {
+		return nil, err
+	}
+	if p.mapper, err = pointMapperFromParsed(conf, pcFieldMetadata); err != nil {
+		return nil, err
+	}
+
+	if p.client, err = apiClientFromParsed(conf, mgr); err != nil {
+		return nil, err
+	}
+	apiKey, err := conf.FieldString(pcFieldAPIKey)
+	if err != nil {
+		return nil, err
+	}
+	p.client.headers["Api-Key"] = apiKey
+	p.client.headers["X-Pinecone-API-Version"] = "2024-07"
+	return p, nil
+}
+
+func (p *pineconeOutput) Connect(ctx context.Context) error {
+	return nil
+}
+
+func (p *pineconeOutput) pineconeVector(batch service.MessageBatch, i int) (map[string]any, error) {
+	pt, err := p.mapper.mapPoint(batch, i)
+	if err != nil {
+		return nil, err
+	}
+
+	var id string
+	switch t := pt.id.(type) {
+	case string:
+		id = t
+	case int64:
+		id = strconv.FormatInt(t, 10)
+	default:
+		return nil, fmt.Errorf("expected id to be a string or an integer, got %T", pt.id)
+	}
+	if id == "" {
+		return nil, errors.New("id must not be empty")
+	}
+
+	values, err := vectorValues(pt.vector)
+	if err != nil {
+		return nil, err
+	}
+
+	obj := map[string]any{"id": id, "values": values}
+	if pt.payload != nil {
+		obj["metadata"] = pt.payload
+	}
+	return obj, nil
+}
+
+func (p *pineconeOutput) WriteBatch(ctx context.Context, batch service.MessageBatch) error {
+	grouper := newPointGrouper(batch)
+
+	for i := range batch {
+		namespace, err := batch.TryInterpolatedString(i, p.namespace)
+		if err != nil {
+			err = fmt.Errorf("namespace interpolation failed: %w", err)
+		}
+
+		var vec map[string]any
+		if err == nil {
+			vec, err = p.pineconeVector(batch, i)
+		}
+		if err != nil {
+			grouper.fail(i, err)
+			continue
+		}
+		grouper.add(namespace, i, vec)
+	}
+	return grouper.write(ctx, p.upsert)
+}
+
+func (p *pineconeOutput) upsert(ctx context.Context, g *pointGroup) error {
+	for start := 0; start < len(g.points); start += pineconeMaxVectors {
+		end := start + pineconeMaxVectors
+		if end > len(g.points) {
+			end = len(g.points)
+		}
+		body := map[string]any{
+			"vectors":   g.points[start:end],
+			"namespace": g.key,
+		}
+		if err := p.client.do(ctx, http.MethodPost, p.host+"/vectors/upsert", body, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *pineconeOutput) Close(ctx context.Context) error {
+	p.client.close()
+	return nil
+}
@@ -0,0 +1,115 @@
+package vectordb
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func testPineconeOutput(t *testing.T, conf string) *pineconeOutput {
+	t.Helper()
+
+	pConf, err := pineconeOutputSpec().ParseYAML(conf, nil)
+	require.NoError(t, err)
+
+	out, err := newPineconeOutputFromParsed(pConf, service.MockResources())
+	require.NoError(t, err)
+	return out
+}
+
+func TestPineconeUpsert(t *testing.T) {
+	srv, reqs := testServer(t, func(r recordedRequest) (int, string) {
+		return http.StatusOK, `{"upsertedCount":1}`
+	})
+
+	out := testPineconeOutput(t, `
+host: `+srv.URL+`
+api_key: foo
+namespace: ${! json("ns") }
+id_mapping: root = this.id
+vector_mapping: root = this.vec
+metadata_mapping: root.title = this.title
+`)
+
+	require.NoError(t, out.WriteBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte(`{"ns":"a","id":"doc1","vec":[0.5],"title":"first"}`)),
+		service.NewMessage([]byte(`{"ns":"","id":2,"vec":[1.5],"title":"second"}`)),
+	}))
+
+	r := reqs()
+	require.Len(t, r, 2)
+
+	assert.Equal(t, http.MethodPost, r[0].method)
+	assert.Equal(t, "/vectors/upsert", r[0].path)
+	assert.Equal(t, "foo", r[0].header.Get("Api-Key"))
+	assert.Equal(t, map[string]any{
+		"namespace": "a",
+		"vectors": []any{
+			map[string]any{"id": "doc1", "values": []any{0.5}, "metadata": map[string]any{"title": "first"}},
+		},
+	}, r[0].body)
+	assert.Equal(t, map[string]any{
+		"namespace": "",
+		"vectors": []any{
+			map[string]any{"id": "2", "values": []any{1.5}, "metadata": map[string]any{"title": "second"}},
+		},
+	}, r[1].body)
+}
+
+func TestPineconeSplitsRequests(t *testing.T) {
+	srv, reqs := testServer(t, func(r recordedRequest) (int, string) {
+		return http.StatusOK, `{}`
+	})
+
+	out := testPineconeOutput(t, `
+host: `+srv.URL+`
+api_key: foo
+id_mapping: root = this.id
+vector_mapping: root = this.vec
+`)
+
+	var batch service.MessageBatch
+	for i := 0; i < pineconeMaxVectors+1; i++ {
+		batch = append(batch, service.NewMessage([]byte(fmt.Sprintf(`{"id":%v,"vec":[1]}`, i))))
+	}
+	require.NoError(t, out.WriteBatch(context.Background(), batch))
+
+	r := reqs()
+	require.Len(t, r, 2)
+	assert.Len(t, r[0].body["vectors"], pineconeMaxVectors)
+	assert.Len(t, r[1].body["vectors"], 1)
+}
+
+func TestPineconeErrors(t *testing.T) {
+	srv, _ := testServer(t, func(r recordedRequest) (int, string) {
+		return http.StatusBadRequest, `{"code":3,"message":"Vector dimension 2 does not match the dimension of the index 1"}`
+	})
+
+	out := testPineconeOutput(t, `
+host: `+srv.URL+`
+api_key: foo
+id_mapping: root = this.id
+vector_mapping: root = this.vec
+`)
+
+	err := out.WriteBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte(`{"id":"a","vec":[1,2]}`)),
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "does not match the dimension")
+
+	batch := service.MessageBatch{
+		service.NewMessage([]byte(`{"id":"","vec":[1]}`)),
+		service.NewMessage([]byte(`{"id":true,"vec":[1]}`)),
+		service.NewMessage([]byte(`{"id":"a","vec":[]}`)),
+	}
+	idx := batch.Index()
+	err = out.WriteBatch(context.Background(), batch)
+	assert.Equal(t, []int{0, 1, 2}, failedIndexes(t, idx, err))
+}
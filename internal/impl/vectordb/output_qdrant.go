@@ -0,0 +1,210 @@
+package vectordb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	qdFieldURL        = "url"
+	qdFieldAPIKey     = "api_key"
+	qdFieldCollection = "collection"
+	qdFieldPayload    = "payload_mapping"
+	qdFieldWait       = "wait"
+)
+
+func qdrantOutputSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Services").
+		Version("4.28.0").
+		Summary("Upserts points into a [Qdrant](https://qdrant.tech/) collection.").
+		Description(`
+Each message is converted into a point with an ID, a vector and an optional payload resolved with mappings. IDs must be either unsigned integers or UUID strings.
+
+The `+"`vector_mapping`"+` may result in either an array of numbers, or an object of arrays of numbers in order to upsert [named vectors](https://qdrant.tech/documentation/concepts/vectors/#named-vectors).
+
+### Batching
+
+Points of a batch are grouped by the collection they target, and each group is upserted with a single request to the REST API. When the request for a group fails its messages are marked as failed whilst the remaining groups are delivered.
+
+Requests that fail due to connection errors, rate limits or server errors are retried using the backoff fields, honouring any `+"`Retry-After`"+` header of responses.`+service.OutputPerformanceDocs(true, true)).
+		Fields(
+			service.NewStringField(qdFieldURL).
+				Description("The base URL of the Qdrant REST API.").
+				Example("http://localhost:6333").
+				Example("https://xxxxxxxx.cloud.qdrant.io:6333"),
+			service.NewStringField(qdFieldAPIKey).
+				Description("An API key to authenticate with.").
+				Secret().
+				Default(""),
+			service.NewInterpolatedStringField(qdFieldCollection).
+				Description("The collection to upsert points into.").
+				Example("documents").
+				Example(`${! meta("tenant") }_documents`),
+		).
+		Fields(pointFields(qdFieldPayload, "An optional [Bloblang mapping](/docs/guides/bloblang/about) that results in an object of the payload of each point.")...).
+		Fields(
+			service.NewBoolField(qdFieldWait).
+				Description("Whether to wait for upserts to be applied before acknowledging messages.").
+				Default(true).
+				Advanced(),
+		).
+		Fields(httpFields()...).
+		Example("Embedded Documents", "Upsert documents along with their embeddings, retaining the text and metadata of each document as the payload of its point.", `
+output:
+  qdrant:
+    url: http://localhost:6333
+    collection: documents
+    id_mapping: root = this.doc_id
+    vector_mapping: root = this.embedding
+    payload_mapping: root = this.without("embedding", "doc_id")
+    batching:
+      count: 100
+      period: 1s
+`)
+}
+
+func init() {
+	registerOutput("qdrant", qdrantOutputSpec(), func(conf *service.ParsedConfig, mgr *service.Resources) (service.BatchOutput, error) {
+		return newQdrantOutputFromParsed(conf, mgr)
+	})
+}
+
+type qdrantOutput struct {
+	baseURL    string
+	collection *service.InterpolatedString
+	mapper     pointMapper
+	wait       bool
+
+	client *apiClient
+}
+
+func newQdrantOutputFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*qdrantOutput, error) {
+	q := &qdrantOutput{}
+
+	var err error
+	if q.baseURL, err = conf.FieldString(qdFieldURL); err != nil {
+		return nil, err
+	}
+	q.baseURL = strings.TrimSuffix(q.baseURL, "/")
+	if q.collection, err = conf.FieldInterpolatedString(qdFieldCollection); err != nil {
+		return nil, err
+	}
+	if q.mapper, err = pointMapperFromParsed(conf, qdFieldPayload); err != nil {
+		return nil, err
+	}
+	if q.wait, err = conf.FieldBool(qdFieldWait); err != nil {
+		return nil, err
+	}
+
+	if q.client, err = apiClientFromParsed(conf, mgr); err != nil {
+		return nil, err
+	}
+	apiKey, err := conf.FieldString(qdFieldAPIKey)
+	if err != nil {
+		return nil, err
+	}
+	if apiKey != "" {
+		q.client.headers["api-key"] = apiKey
+	}
+	return q, nil
+}
+
+func (q *qdrantOutput) Connect(ctx context.Context) error {
+	return nil
+}
+
+// qdrantID converts an ID into either an unsigned integer or a string.
+func qdrantID(v any) (any, error) {
+	switch t := v.(type) {
+	case int64:
+		if t < 0 {
+			return nil, fmt.Errorf("integer id must be unsigned, got %v", t)
+		}
+		return t, nil
+	case string:
+		return t, nil
+	}
+	return nil, fmt.Errorf("expected id to be an unsigned integer or a string, got %T", v)
+}
+
+// qdrantVector converts a vector into either a dense vector or an object of
+// named vectors.
+func qdrantVector(v any) (any, error) {
+	obj, ok := v.(map[string]any)
+	if !ok {
+		return vectorValues(v)
+	}
+	named := make(map[string][]float64, len(obj))
+	for k, e := range obj {
+		vec, err := vectorValues(e)
+		if err != nil {
+			return nil, fmt.Errorf("named vector %v: %w", k, err)
+		}
+		named[k] = vec
+	}
+	return named, nil
+}
+
+func (q *qdrantOutput) qdrantPoint(batch service.MessageBatch, i int) (map[string]any, error) {
+	pt, err := q.mapper.mapPoint(batch, i)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := qdrantID(pt.id)
+	if err != nil {
+		return nil, err
+	}
+	vec, err := qdrantVector(pt.vector)
+	if err != nil {
+		return nil, err
+	}
+
+	obj := map[string]any{"id": id, "vector": vec}
+	if pt.payload != nil {
+		obj["payload"] = pt.payload
+	}
+	return obj, nil
+}
+
+func (q *qdrantOutput) WriteBatch(ctx context.Context, batch service.MessageBatch) error {
+	grouper := newPointGrouper(batch)
+
+	for i := range batch {
+		collection, err := batch.TryInterpolatedString(i, q.collection)
+		if err != nil {
+			err = fmt.Errorf("collection interpolation failed: %w", err)
+		} else if collection == "" {
+			err = errors.New("collection interpolation resulted in an empty string")
+		}
+
+		var pt map[string]any
+		if err == nil {
+			pt, err = q.qdrantPoint(batch, i)
+		}
+		if err != nil {
+			grouper.fail(i, err)
+			continue
+		}
+		grouper.add(collection, i, pt)
+	}
+	return grouper.write(ctx, q.upsert)
+}
+
+func (q *qdrantOutput) upsert(ctx context.Context, g *pointGroup) error {
+	reqURL := fmt.Sprintf("%v/collections/%v/points?wait=%v", q.baseURL, url.PathEscape(g.key), q.wait)
+	return q.client.do(ctx, http.MethodPut, reqURL, map[string]any{"points": g.points}, nil)
+}
+
+func (q *qdrantOutput) Close(ctx context.Context) error {
+	q.client.close()
+	return nil
+}
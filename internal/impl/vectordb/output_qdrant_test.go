@@ -0,0 +1,200 @@
+package vectordb
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+type recordedRequest struct {
+	method string
+	path   string
+	query  string
+	header http.Header
+	body   map[string]any
+}
+
+// testServer records requests and responds with the result of a handler.
+func testServer(t *testing.T, fn func(r recordedRequest) (int, string)) (*httptest.Server, func() []recordedRequest) {
+	t.Helper()
+
+	var mut sync.Mutex
+	var reqs []recordedRequest
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+
+		rec := recordedRequest{
+			method: r.Method,
+			path:   r.URL.Path,
+			query:  r.URL.RawQuery,
+			header: r.Header,
+		}
+		require.NoError(t, json.Unmarshal(b, &rec.body))
+
+		mut.Lock()
+		reqs = append(reqs, rec)
+		mut.Unlock()
+
+		status, body := fn(rec)
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv, func() []recordedRequest {
+		mut.Lock()
+		defer mut.Unlock()
+		return reqs
+	}
+}
+
+// failedIndexes returns the indexes of messages that failed according to a
+// batch error, where the indexer must be created before the batch is written.
+func failedIndexes(t *testing.T, idx *service.Indexer, err error) []int {
+	t.Helper()
+
+	var bErr *service.BatchError
+	require.ErrorAs(t, err, &bErr)
+
+	var failed []int
+	bErr.WalkMessagesIndexedBy(idx, func(i int, _ *service.Message, err error) bool {
+		if err != nil {
+			failed = append(failed, i)
+		}
+		return true
+	})
+	sort.Ints(failed)
+	return failed
+}
+
+func testQdrantOutput(t *testing.T, conf string) *qdrantOutput {
+	t.Helper()
+
+	pConf, err := qdrantOutputSpec().ParseYAML(conf, nil)
+	require.NoError(t, err)
+
+	out, err := newQdrantOutputFromParsed(pConf, service.MockResources())
+	require.NoError(t, err)
+	return out
+}
+
+func TestQdrantUpsert(t *testing.T) {
+	srv, reqs := testServer(t, func(r recordedRequest) (int, string) {
+		return http.StatusOK, `{"status":"ok"}`
+	})
+
+	out := testQdrantOutput(t, `
+url: `+srv.URL+`
+api_key: foo
+collection: ${! json("coll") }
+id_mapping: root = this.id
+vector_mapping: root = this.vec
+payload_mapping: root = this.without("id", "vec", "coll")
+`)
+
+	require.NoError(t, out.WriteBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte(`{"coll":"a","id":1,"vec":[0.1,2],"text":"hello"}`)),
+		service.NewMessage([]byte(`{"coll":"b","id":"5c56c793-69f3-4fbf-87e6-c4bf54c28c26","vec":{"image":[1],"text":[2,3]}}`)),
+		service.NewMessage([]byte(`{"coll":"a","id":2,"vec":[0.3,4]}`)),
+	}))
+
+	r := reqs()
+	require.Len(t, r, 2)
+
+	assert.Equal(t, http.MethodPut, r[0].method)
+	assert.Equal(t, "/collections/a/points", r[0].path)
+	assert.Equal(t, "wait=true", r[0].query)
+	assert.Equal(t, "foo", r[0].header.Get("api-key"))
+	assert.Equal(t, map[string]any{
+		"points": []any{
+			map[string]any{"id": 1.0, "vector": []any{0.1, 2.0}, "payload": map[string]any{"text": "hello"}},
+			map[string]any{"id": 2.0, "vector": []any{0.3, 4.0}, "payload": map[string]any{}},
+		},
+	}, r[0].body)
+
+	assert.Equal(t, "/collections/b/points", r[1].path)
+	assert.Equal(t, map[string]any{
+		"points": []any{
+			map[string]any{
+				"id":      "5c56c793-69f3-4fbf-87e6-c4bf54c28c26",
+				"vector":  map[string]any{"image": []any{1.0}, "text": []any{2.0, 3.0}},
+				"payload": map[string]any{},
+			},
+		},
+	}, r[1].body)
+}
+
+func TestQdrantPartialFailure(t *testing.T) {
+	srv, reqs := testServer(t, func(r recordedRequest) (int, string) {
+		if r.path == "/collections/bad/points" {
+			return http.StatusNotFound, `{"status":{"error":"Not found: Collection bad doesn't exist!"}}`
+		}
+		return http.StatusOK, `{"status":"ok"}`
+	})
+
+	out := testQdrantOutput(t, `
+url: `+srv.URL+`
+collection: ${! json("coll") }
+id_mapping: root = this.id
+vector_mapping: root = this.vec
+`)
+
+	batch := service.MessageBatch{
+		service.NewMessage([]byte(`{"coll":"good","id":1,"vec":[1]}`)),
+		service.NewMessage([]byte(`{"coll":"bad","id":2,"vec":[1]}`)),
+		service.NewMessage([]byte(`{"coll":"good","id":-3,"vec":[1]}`)),
+		service.NewMessage([]byte(`{"coll":"good","id":4,"vec":["nope"]}`)),
+		service.NewMessage([]byte(`{"coll":"good","id":5,"vec":[2]}`)),
+	}
+	idx := batch.Index()
+	err := out.WriteBatch(context.Background(), batch)
+	assert.Equal(t, []int{1, 2, 3}, failedIndexes(t, idx, err))
+
+	// Not found errors are not retried.
+	assert.Len(t, reqs(), 2)
+}
+
+func TestQdrantRetries(t *testing.T) {
+	var attempts atomic.Int32
+	srv, _ := testServer(t, func(r recordedRequest) (int, string) {
+		if attempts.Add(1) < 3 {
+			return http.StatusServiceUnavailable, `unavailable`
+		}
+		return http.StatusOK, `{"status":"ok"}`
+	})
+
+	out := testQdrantOutput(t, `
+url: `+srv.URL+`
+collection: foo
+id_mapping: root = this.id
+vector_mapping: root = this.vec
+backoff:
+  initial_interval: 1ms
+  max_interval: 1ms
+`)
+
+	require.NoError(t, out.WriteBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte(`{"id":1,"vec":[1]}`)),
+	}))
+	assert.Equal(t, int32(3), attempts.Load())
+
+	attempts.Store(-10)
+	err := out.WriteBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte(`{"id":1,"vec":[1]}`)),
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "status 503")
+}
@@ -2,8 +2,10 @@ package wasm
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"sort"
 
 	"github.com/tetratelabs/wazero/api"
 )
@@ -107,3 +109,63 @@ var _ = registerModuleRunnerFunction("v0_msg_get_meta", func(r *moduleRunner) in
 		return ptrLen(contentPtr, uint64(len(metaValueBytes)))
 	}
 })
+
+var _ = registerModuleRunnerFunction("v0_msg_delete_meta", func(r *moduleRunner) interface{} {
+	return func(ctx context.Context, m api.Module, keyPtr, keySize uint32) {
+		if r.targetMessage == nil {
+			r.funcErr(errors.New("attempted to delete metadata of deleted message"))
+			return
+		}
+
+		keyBytes, err := r.readBytesOutbound(ctx, keyPtr, keySize)
+		if err != nil {
+			r.funcErr(fmt.Errorf("failed to read out-bound meta key memory: %w", err))
+			return
+		}
+
+		r.targetMessage.MetaDelete(string(keyBytes))
+	}
+})
+
+var _ = registerModuleRunnerFunction("v0_msg_meta_keys", func(r *moduleRunner) interface{} {
+	return func(ctx context.Context, m api.Module) (ptrSize uint64) {
+		if r.targetMessage == nil {
+			r.funcErr(errors.New("attempted to read meta of deleted message"))
+			return
+		}
+
+		keys := []string{}
+		_ = r.targetMessage.MetaWalkMut(func(k string, _ any) error {
+			keys = append(keys, k)
+			return nil
+		})
+		sort.Strings(keys)
+
+		keysBytes, err := json.Marshal(keys)
+		if err != nil {
+			r.funcErr(fmt.Errorf("failed to marshal meta keys: %v", err))
+			return
+		}
+
+		contentPtr, err := r.allocateBytesInbound(ctx, keysBytes)
+		if err != nil {
+			r.funcErr(fmt.Errorf("failed to allocate in-bound memory: %v", err))
+			return
+		}
+		return ptrLen(contentPtr, uint64(len(keysBytes)))
+	}
+})
+
+var _ = registerModuleRunnerFunction("v0_msg_set_error", func(r *moduleRunner) interface{} {
+	return func(ctx context.Context, m api.Module, contentPtr, contentSize uint32) {
+		contentBytes, err := r.readBytesOutbound(ctx, contentPtr, contentSize)
+		if err != nil {
+			r.funcErr(fmt.Errorf("failed to read out-bound error memory: %w", err))
+			return
+		}
+
+		// The error is attached to the message without being logged as it
+		// was raised intentionally by the module.
+		r.procErr = errors.New(string(contentBytes))
+	}
+})
@@ -1,12 +1,14 @@
 package wasm
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
 	"os"
-	"sync"
+	"time"
 
+	"github.com/dustin/go-humanize"
 	"github.com/tetratelabs/wazero"
 	"github.com/tetratelabs/wazero/api"
 	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
@@ -28,13 +30,49 @@ These examples, as well as the processor itself, is a work in progress.
 
 ### Parallelism
 
-It's not currently possible to execute a single WASM runtime across parallel threads with this processor. Therefore, in order to support parallel processing this processor implements pooling of module runtimes. Ideally your WASM module shouldn't depend on any global state, but if it does then you need to ensure the processor [is only run on a single thread](/docs/configuration/processing_pipelines).
+It's not currently possible to execute a single WASM runtime across parallel threads with this processor. Therefore, in order to support parallel processing this processor implements pooling of module runtimes, where up to ` + "`pool_size`" + ` idle module instances are retained for reuse across threads and the compiled module is shared between them. Ideally your WASM module shouldn't depend on any global state, but if it does then you need to ensure the processor [is only run on a single thread](/docs/configuration/processing_pipelines).
+
+### Resource Limits
+
+In order to run untrusted modules the memory of each module instance can be limited with ` + "`max_memory`" + `, in which case modules that declare or grow memory beyond the limit fail, and the execution time of each message can be limited with ` + "`timeout`" + `, in which case the module instance is discarded and the batch is flagged as having failed. Modules are provided with WASI preview 1 without access to the file system, network or standard streams, and with only the environment variables configured with ` + "`env`" + `. WASI preview 2 and the component model are not supported by the runtime, and so modules must be compiled as core modules targetting WASI preview 1.
+
+### Host Functions
+
+The following functions are exported to modules by the host module ` + "`benthos_wasm`" + `, where strings are passed as a pointer and length, and strings returned are packed into a ` + "`uint64`" + ` with the pointer in the high 32 bits:
+
+- ` + "`v0_msg_as_bytes`" + `: Returns the contents of the message.
+- ` + "`v0_msg_set_bytes`" + `: Sets the contents of the message.
+- ` + "`v0_msg_get_meta`" + `: Returns the value of a metadata key, or an empty string when it does not exist.
+- ` + "`v0_msg_set_meta`" + `: Sets the value of a metadata key.
+- ` + "`v0_msg_delete_meta`" + `: Removes a metadata key.
+- ` + "`v0_msg_meta_keys`" + `: Returns the metadata keys of the message as a JSON array of strings.
+- ` + "`v0_msg_set_error`" + `: Flags the message as having failed with an error message.
 `).
 		Field(service.NewStringField("module_path").
 			Description("The path of the target WASM module to execute.")).
 		Field(service.NewStringField("function").
 			Default("process").
 			Description("The name of the function exported by the target WASM module to run for each message.")).
+		Field(service.NewStringField("max_memory").
+			Description("The maximum memory of each module instance, where an empty string applies no limit beyond the 4GiB addressable by modules.").
+			Example("16MiB").
+			Default("").
+			Version("4.28.0")).
+		Field(service.NewDurationField("timeout").
+			Description("The maximum period to execute the function for each message, where modules that exceed it are interrupted.").
+			Example("100ms").
+			Optional().
+			Version("4.28.0")).
+		Field(service.NewIntField("pool_size").
+			Description("The maximum number of idle module instances to retain for reuse.").
+			Default(16).
+			Advanced().
+			Version("4.28.0")).
+		Field(service.NewStringMapField("env").
+			Description("Environment variables to provide to modules through WASI.").
+			Default(map[string]any{}).
+			Advanced().
+			Version("4.28.0")).
 		Version("4.11.0")
 }
 
@@ -51,11 +89,29 @@ func init() {
 
 //------------------------------------------------------------------------------
 
+// wazeroOptions are the resource limits and pooling behaviour of module
+// instances.
+type wazeroOptions struct {
+	maxMemoryPages uint32
+	timeout        time.Duration
+	poolSize       int
+	env            map[string]string
+}
+
+func defaultWazeroOptions() wazeroOptions {
+	return wazeroOptions{poolSize: 16}
+}
+
 type wazeroAllocProcessor struct {
 	log          *service.Logger
 	functionName string
 	wasmBinary   []byte
-	modulePool   sync.Pool
+	opts         wazeroOptions
+
+	cache         wazero.CompilationCache
+	runtimeConfig wazero.RuntimeConfig
+	moduleConfig  wazero.ModuleConfig
+	modulePool    chan *moduleRunner
 }
 
 func newWazeroAllocProcessorFromConfig(conf *service.ParsedConfig, mgr *service.Resources) (*wazeroAllocProcessor, error) {
@@ -74,32 +130,109 @@ func newWazeroAllocProcessorFromConfig(conf *service.ParsedConfig, mgr *service.
 		return nil, err
 	}
 
-	return newWazeroAllocProcessor(function, fileBytes, mgr)
+	opts := defaultWazeroOptions()
+
+	maxMemoryStr, err := conf.FieldString("max_memory")
+	if err != nil {
+		return nil, err
+	}
+	if maxMemoryStr != "" {
+		maxMemory, err := humanize.ParseBytes(maxMemoryStr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse max_memory: %w", err)
+		}
+		if opts.maxMemoryPages, err = memoryPages(maxMemory); err != nil {
+			return nil, err
+		}
+	}
+
+	if conf.Contains("timeout") {
+		if opts.timeout, err = conf.FieldDuration("timeout"); err != nil {
+			return nil, err
+		}
+	}
+
+	if opts.poolSize, err = conf.FieldInt("pool_size"); err != nil {
+		return nil, err
+	}
+
+	if opts.env, err = conf.FieldStringMap("env"); err != nil {
+		return nil, err
+	}
+
+	return newWazeroAllocProcessor(function, fileBytes, opts, mgr)
+}
+
+// wasmPageSize is the size of a page of WASM linear memory.
+const wasmPageSize = 65536
+
+// memoryPages returns the number of pages of linear memory that fit within a
+// number of bytes.
+func memoryPages(maxBytes uint64) (uint32, error) {
+	pages := maxBytes / wasmPageSize
+	if pages == 0 {
+		return 0, fmt.Errorf("max_memory must be at least %v", humanize.IBytes(wasmPageSize))
+	}
+	if pages > 65536 {
+		pages = 65536
+	}
+	return uint32(pages), nil
+}
+
+// isComponent returns true if a binary is a WASM component rather than a core
+// module, which is determined by the layer field following the version.
+func isComponent(wasmBinary []byte) bool {
+	return len(wasmBinary) >= 8 && bytes.HasPrefix(wasmBinary, []byte("\x00asm")) && (wasmBinary[6] != 0 || wasmBinary[7] != 0)
 }
 
-func newWazeroAllocProcessor(functionName string, wasmBinary []byte, mgr *service.Resources) (*wazeroAllocProcessor, error) {
+func newWazeroAllocProcessor(functionName string, wasmBinary []byte, opts wazeroOptions, mgr *service.Resources) (*wazeroAllocProcessor, error) {
+	if isComponent(wasmBinary) {
+		return nil, errors.New("the binary is a WASM component, which is not supported, modules must be compiled as core modules targetting WASI preview 1")
+	}
+
+	if opts.poolSize < 0 {
+		opts.poolSize = 0
+	}
+
 	proc := &wazeroAllocProcessor{
 		log:        mgr.Logger(),
-		modulePool: sync.Pool{},
+		modulePool: make(chan *moduleRunner, opts.poolSize),
 
 		functionName: functionName,
 		wasmBinary:   wasmBinary,
+		opts:         opts,
+	}
+
+	// Modules are compiled once and shared between the runtimes of each
+	// module instance.
+	proc.cache = wazero.NewCompilationCache()
+	proc.runtimeConfig = wazero.NewRuntimeConfig().
+		WithCompilationCache(proc.cache).
+		WithCloseOnContextDone(opts.timeout > 0)
+	if opts.maxMemoryPages > 0 {
+		proc.runtimeConfig = proc.runtimeConfig.WithMemoryLimitPages(opts.maxMemoryPages)
+	}
+
+	proc.moduleConfig = wazero.NewModuleConfig()
+	for k, v := range opts.env {
+		proc.moduleConfig = proc.moduleConfig.WithEnv(k, v)
 	}
 
 	// Ensure we can create at least one module runner.
 	modRunner, err := proc.newModule()
 	if err != nil {
+		_ = proc.cache.Close(context.Background())
 		return nil, err
 	}
 
-	proc.modulePool.Put(modRunner)
+	proc.putModule(modRunner)
 	return proc, nil
 }
 
 func (p *wazeroAllocProcessor) newModule() (mod *moduleRunner, err error) {
 	ctx := context.Background()
 
-	r := wazero.NewRuntime(ctx)
+	r := wazero.NewRuntimeWithConfig(ctx, p.runtimeConfig)
 	mod = &moduleRunner{
 		log:     p.log,
 		runtime: r,
@@ -122,11 +255,14 @@ func (p *wazeroAllocProcessor) newModule() (mod *moduleRunner, err error) {
 		return
 	}
 
-	if mod.mod, err = r.Instantiate(ctx, p.wasmBinary); err != nil {
+	if mod.mod, err = r.InstantiateWithConfig(ctx, p.wasmBinary, p.moduleConfig); err != nil {
 		return
 	}
 
-	mod.process = mod.mod.ExportedFunction(p.functionName)
+	if mod.process = mod.mod.ExportedFunction(p.functionName); mod.process == nil {
+		err = fmt.Errorf("module does not export the function %v", p.functionName)
+		return
+	}
 	mod.goMalloc = mod.mod.ExportedFunction("malloc")
 	mod.goFree = mod.mod.ExportedFunction("free")
 	mod.rustAlloc = mod.mod.ExportedFunction("allocate")
@@ -135,35 +271,53 @@ func (p *wazeroAllocProcessor) newModule() (mod *moduleRunner, err error) {
 	return mod, nil
 }
 
+// getModule returns an idle module instance from the pool, or a new module
+// instance when the pool is empty.
+func (p *wazeroAllocProcessor) getModule() (*moduleRunner, error) {
+	select {
+	case modRunner := <-p.modulePool:
+		return modRunner, nil
+	default:
+	}
+	return p.newModule()
+}
+
+// putModule returns a module instance to the pool, or closes it when the pool
+// is full.
+func (p *wazeroAllocProcessor) putModule(modRunner *moduleRunner) {
+	select {
+	case p.modulePool <- modRunner:
+	default:
+		_ = modRunner.Close(context.Background())
+	}
+}
+
 func (p *wazeroAllocProcessor) ProcessBatch(ctx context.Context, batch service.MessageBatch) ([]service.MessageBatch, error) {
-	var modRunner *moduleRunner
-	var err error
-	if modRunnerPtr := p.modulePool.Get(); modRunnerPtr != nil {
-		modRunner = modRunnerPtr.(*moduleRunner)
-	} else {
-		if modRunner, err = p.newModule(); err != nil {
-			return nil, err
-		}
+	modRunner, err := p.getModule()
+	if err != nil {
+		return nil, err
 	}
-	defer func() {
-		p.modulePool.Put(modRunner)
-	}()
 
-	res, err := modRunner.Run(ctx, batch)
+	res, err := modRunner.Run(ctx, batch, p.opts.timeout)
 	if err != nil {
+		// A module instance that was interrupted or trapped is left in an
+		// unknown state and is therefore discarded.
+		_ = modRunner.Close(context.Background())
 		return nil, err
 	}
+	p.putModule(modRunner)
 	return []service.MessageBatch{res}, nil
 }
 
 func (p *wazeroAllocProcessor) Close(ctx context.Context) error {
 	for {
-		mr := p.modulePool.Get()
-		if mr == nil {
-			return nil
-		}
-		if err := mr.(*moduleRunner).Close(ctx); err != nil {
-			return err
+		select {
+		case mr := <-p.modulePool:
+			if err := mr.Close(ctx); err != nil {
+				return err
+			}
+		default:
+			return p.cache.Close(ctx)
 		}
 	}
 }
@@ -256,7 +410,7 @@ func (r *moduleRunner) readBytesOutbound(ctx context.Context, contentPtr, conten
 	return dataCopy, nil
 }
 
-func (r *moduleRunner) Run(ctx context.Context, batch service.MessageBatch) (service.MessageBatch, error) {
+func (r *moduleRunner) Run(ctx context.Context, batch service.MessageBatch, timeout time.Duration) (service.MessageBatch, error) {
 	defer r.reset()
 
 	var newBatch service.MessageBatch
@@ -265,11 +419,24 @@ func (r *moduleRunner) Run(ctx context.Context, batch service.MessageBatch) (ser
 		r.runBatch = batch
 		r.targetIndex = i
 		r.targetMessage = batch[i]
-		_, err := r.process.Call(ctx)
-		for _, fn := range r.afterProcessing {
-			fn()
+
+		callCtx, done := ctx, func() {}
+		if timeout > 0 {
+			callCtx, done = context.WithTimeout(ctx, timeout)
+		}
+		_, err := r.process.Call(callCtx)
+		timedOut := callCtx.Err() == context.DeadlineExceeded && ctx.Err() == nil
+		done()
+
+		if err == nil {
+			for _, fn := range r.afterProcessing {
+				fn()
+			}
 		}
 		if err != nil {
+			if timedOut {
+				return nil, fmt.Errorf("module exceeded timeout of %v: %w", timeout, err)
+			}
 			return nil, err
 		}
 		newMsg := r.targetMessage
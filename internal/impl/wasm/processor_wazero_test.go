@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
 	"sync"
 	"testing"
@@ -22,7 +23,7 @@ func TestWazeroWASIGoProcessor(t *testing.T) {
 	}
 	require.NoError(t, err)
 
-	proc, err := newWazeroAllocProcessor("process", wasm, service.MockResources())
+	proc, err := newWazeroAllocProcessor("process", wasm, defaultWazeroOptions(), service.MockResources())
 	require.NoError(t, err)
 	t.Cleanup(func() {
 		require.NoError(t, proc.Close(context.Background()))
@@ -49,7 +50,7 @@ func TestWazeroWASIGoProcessorParallel(t *testing.T) {
 	}
 	require.NoError(t, err)
 
-	proc, err := newWazeroAllocProcessor("process", wasm, service.MockResources())
+	proc, err := newWazeroAllocProcessor("process", wasm, defaultWazeroOptions(), service.MockResources())
 	require.NoError(t, err)
 	t.Cleanup(func() {
 		require.NoError(t, proc.Close(context.Background()))
@@ -89,7 +90,7 @@ func TestWazeroWASIRustProcessor(t *testing.T) {
 	}
 	require.NoError(t, err)
 
-	proc, err := newWazeroAllocProcessor("process", wasm, service.MockResources())
+	proc, err := newWazeroAllocProcessor("process", wasm, defaultWazeroOptions(), service.MockResources())
 	require.NoError(t, err)
 	t.Cleanup(func() {
 		require.NoError(t, proc.Close(context.Background()))
@@ -116,7 +117,7 @@ func BenchmarkWazeroWASIGoCalls(b *testing.B) {
 	}
 	require.NoError(b, err)
 
-	proc, err := newWazeroAllocProcessor("process", wasm, service.MockResources())
+	proc, err := newWazeroAllocProcessor("process", wasm, defaultWazeroOptions(), service.MockResources())
 	require.NoError(b, err)
 	b.Cleanup(func() {
 		require.NoError(b, proc.Close(context.Background()))
@@ -146,7 +147,7 @@ func BenchmarkWazeroWASIRustCalls(b *testing.B) {
 	}
 	require.NoError(b, err)
 
-	proc, err := newWazeroAllocProcessor("process", wasm, service.MockResources())
+	proc, err := newWazeroAllocProcessor("process", wasm, defaultWazeroOptions(), service.MockResources())
 	require.NoError(b, err)
 	b.Cleanup(func() {
 		require.NoError(b, proc.Close(context.Background()))
@@ -168,3 +169,127 @@ func BenchmarkWazeroWASIRustCalls(b *testing.B) {
 		require.NoError(b, err)
 	}
 }
+
+// testModule assembles a core WASM module exporting a single function named
+// process, with an optional memory of a minimum number of pages.
+func testModule(body []byte, memPages byte) []byte {
+	mod := []byte("\x00asm\x01\x00\x00\x00")
+	mod = append(mod, 0x01, 0x04, 0x01, 0x60, 0x00, 0x00) // type () -> ()
+	mod = append(mod, 0x03, 0x02, 0x01, 0x00)             // func 0 of type 0
+	if memPages > 0 {
+		mod = append(mod, 0x05, 0x03, 0x01, 0x00, memPages)
+	}
+	mod = append(mod, 0x07, 0x0b, 0x01, 0x07)
+	mod = append(mod, "process"...)
+	mod = append(mod, 0x00, 0x00)
+	mod = append(mod, 0x0a, byte(len(body)+3), 0x01, byte(len(body)+1), 0x00)
+	return append(mod, body...)
+}
+
+var (
+	wasmNoopBody = []byte{0x0b}
+	wasmSpinBody = []byte{0x03, 0x40, 0x0c, 0x00, 0x0b, 0x0b} // loop br 0 end end
+)
+
+func TestWazeroNoop(t *testing.T) {
+	proc, err := newWazeroAllocProcessor("process", testModule(wasmNoopBody, 0), defaultWazeroOptions(), service.MockResources())
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, proc.Close(context.Background()))
+	})
+
+	outBatches, err := proc.ProcessBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte(`hello world`)),
+	})
+	require.NoError(t, err)
+	require.Len(t, outBatches, 1)
+	require.Len(t, outBatches[0], 1)
+
+	resBytes, err := outBatches[0][0].AsBytes()
+	require.NoError(t, err)
+	assert.Equal(t, "hello world", string(resBytes))
+
+	_, err = newWazeroAllocProcessor("nope", testModule(wasmNoopBody, 0), defaultWazeroOptions(), service.MockResources())
+	require.Error(t, err)
+}
+
+func TestWazeroTimeout(t *testing.T) {
+	opts := defaultWazeroOptions()
+	opts.timeout = time.Millisecond * 50
+
+	proc, err := newWazeroAllocProcessor("process", testModule(wasmSpinBody, 0), opts, service.MockResources())
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, proc.Close(context.Background()))
+	})
+
+	// Interrupted module instances are replaced for following batches.
+	for i := 0; i < 2; i++ {
+		_, err = proc.ProcessBatch(context.Background(), service.MessageBatch{
+			service.NewMessage([]byte(`hello world`)),
+		})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "timeout")
+	}
+}
+
+func TestWazeroMemoryLimit(t *testing.T) {
+	opts := defaultWazeroOptions()
+	opts.maxMemoryPages = 2
+
+	_, err := newWazeroAllocProcessor("process", testModule(wasmNoopBody, 10), opts, service.MockResources())
+	require.Error(t, err)
+
+	opts.maxMemoryPages = 16
+	proc, err := newWazeroAllocProcessor("process", testModule(wasmNoopBody, 10), opts, service.MockResources())
+	require.NoError(t, err)
+	require.NoError(t, proc.Close(context.Background()))
+}
+
+func TestWazeroComponentRejected(t *testing.T) {
+	_, err := newWazeroAllocProcessor("process", []byte("\x00asm\x0d\x00\x01\x00"), defaultWazeroOptions(), service.MockResources())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "component")
+}
+
+func TestWazeroConfig(t *testing.T) {
+	modPath := filepath.Join(t.TempDir(), "noop.wasm")
+	require.NoError(t, os.WriteFile(modPath, testModule(wasmNoopBody, 1), 0o644))
+
+	conf, err := wazeroAllocProcessorConfig().ParseYAML(`
+module_path: `+modPath+`
+max_memory: 1MiB
+timeout: 1s
+pool_size: 2
+env:
+  FOO: bar
+`, nil)
+	require.NoError(t, err)
+
+	proc, err := newWazeroAllocProcessorFromConfig(conf, service.MockResources())
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, proc.Close(context.Background()))
+	})
+
+	assert.Equal(t, uint32(16), proc.opts.maxMemoryPages)
+	assert.Equal(t, time.Second, proc.opts.timeout)
+	assert.Equal(t, 2, proc.opts.poolSize)
+	assert.Equal(t, map[string]string{"FOO": "bar"}, proc.opts.env)
+
+	// Idle module instances beyond the pool size are closed.
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := proc.ProcessBatch(context.Background(), service.MessageBatch{service.NewMessage(nil)})
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+	assert.LessOrEqual(t, len(proc.modulePool), 2)
+
+	_, err = memoryPages(100)
+	require.Error(t, err)
+}
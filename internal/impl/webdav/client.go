@@ -0,0 +1,517 @@
+package webdav
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	wdFieldURL          = "url"
+	wdFieldTLS          = "tls"
+	wdFieldAuth         = "auth"
+	wdFieldAuthType     = "type"
+	wdFieldAuthUsername = "username"
+	wdFieldAuthPassword = "password"
+	wdFieldAuthToken    = "token"
+	wdFieldTimeout      = "timeout"
+)
+
+func clientFields() []*service.ConfigField {
+	return []*service.ConfigField{
+		service.NewStringField(wdFieldURL).
+			Description("The base URL of the WebDAV server, which paths are relative to.").
+			Example("https://cloud.example.com/remote.php/dav/files/benthos").
+			Example("http://localhost:8080/dav"),
+		service.NewTLSToggledField(wdFieldTLS),
+		service.NewObjectField(wdFieldAuth,
+			service.NewStringAnnotatedEnumField(wdFieldAuthType, map[string]string{
+				"none":   "No authentication.",
+				"basic":  "HTTP basic authentication with a username and password.",
+				"digest": "HTTP digest authentication with a username and password.",
+				"bearer": "A bearer token sent with the Authorization header.",
+			}).
+				Description("The type of authentication to use.").
+				Default("none"),
+			service.NewStringField(wdFieldAuthUsername).
+				Description("A username to authenticate with.").
+				Default(""),
+			service.NewStringField(wdFieldAuthPassword).
+				Description("A password to authenticate with.").
+				Secret().
+				Default(""),
+			service.NewStringField(wdFieldAuthToken).
+				Description("A bearer token to authenticate with.").
+				Secret().
+				Default(""),
+		).
+			Description("Authentication with the WebDAV server."),
+		service.NewDurationField(wdFieldTimeout).
+			Description("The maximum period to wait for each request to complete.").
+			Advanced().
+			Default("30s"),
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type davClient struct {
+	baseURL *url.URL
+	client  *http.Client
+	timeout time.Duration
+
+	authType string
+	username string
+	password string
+	token    string
+
+	digestMut sync.Mutex
+	digest    *digestChallenge
+}
+
+func davClientFromParsed(conf *service.ParsedConfig) (*davClient, error) {
+	c := &davClient{}
+
+	urlStr, err := conf.FieldString(wdFieldURL)
+	if err != nil {
+		return nil, err
+	}
+	if c.baseURL, err = url.Parse(urlStr); err != nil {
+		return nil, fmt.Errorf("failed to parse url: %w", err)
+	}
+	c.baseURL.Path = strings.TrimSuffix(c.baseURL.Path, "/")
+
+	tlsConf, tlsEnabled, err := conf.FieldTLSToggled(wdFieldTLS)
+	if err != nil {
+		return nil, err
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if tlsEnabled {
+		transport.TLSClientConfig = tlsConf
+	}
+	c.client = &http.Client{Transport: transport}
+
+	if c.timeout, err = conf.FieldDuration(wdFieldTimeout); err != nil {
+		return nil, err
+	}
+
+	aConf := conf.Namespace(wdFieldAuth)
+	if c.authType, err = aConf.FieldString(wdFieldAuthType); err != nil {
+		return nil, err
+	}
+	if c.username, err = aConf.FieldString(wdFieldAuthUsername); err != nil {
+		return nil, err
+	}
+	if c.password, err = aConf.FieldString(wdFieldAuthPassword); err != nil {
+		return nil, err
+	}
+	if c.token, err = aConf.FieldString(wdFieldAuthToken); err != nil {
+		return nil, err
+	}
+	if c.authType == "bearer" && c.token == "" {
+		return nil, errors.New("a token must be provided for bearer authentication")
+	}
+	return c, nil
+}
+
+// resourceURL returns the URL of a path relative to the base URL.
+func (c *davClient) resourceURL(p string) *url.URL {
+	u := *c.baseURL
+	u.Path = c.baseURL.Path + "/" + strings.TrimPrefix(p, "/")
+	u.RawPath = ""
+	return &u
+}
+
+// relativePath returns the path of an href returned by the server relative to
+// the base URL.
+func (c *davClient) relativePath(href string) (string, error) {
+	u, err := url.Parse(href)
+	if err != nil {
+		return "", err
+	}
+	p := strings.TrimPrefix(u.Path, c.baseURL.Path)
+	return "/" + strings.Trim(p, "/"), nil
+}
+
+// statusError is returned when a request is answered with an unexpected
+// status code.
+type statusError struct {
+	method string
+	path   string
+	code   int
+	body   string
+}
+
+func (e *statusError) Error() string {
+	if e.body == "" {
+		return fmt.Sprintf("%v %v returned status code %v", e.method, e.path, e.code)
+	}
+	return fmt.Sprintf("%v %v returned status code %v: %v", e.method, e.path, e.code, e.body)
+}
+
+func isStatus(err error, code int) bool {
+	var sErr *statusError
+	return errors.As(err, &sErr) && sErr.code == code
+}
+
+func statusErrorFrom(res *http.Response, p string) error {
+	body, _ := io.ReadAll(io.LimitReader(res.Body, 512))
+	return &statusError{
+		method: res.Request.Method,
+		path:   p,
+		code:   res.StatusCode,
+		body:   strings.TrimSpace(string(body)),
+	}
+}
+
+func (c *davClient) newRequest(ctx context.Context, method, p string, headers map[string]string, body []byte) (*http.Request, error) {
+	var bodyRdr io.Reader
+	if body != nil {
+		bodyRdr = bytes.NewReader(body)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.resourceURL(p).String(), bodyRdr)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	switch c.authType {
+	case "basic":
+		req.SetBasicAuth(c.username, c.password)
+	case "bearer":
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	case "digest":
+		c.digestMut.Lock()
+		if c.digest != nil {
+			req.Header.Set("Authorization", c.digest.authorization(c.username, c.password, method, req.URL.RequestURI()))
+		}
+		c.digestMut.Unlock()
+	}
+	return req, nil
+}
+
+// do performs a request and returns the response, which the caller must close.
+// With digest authentication a challenge from the server is answered by
+// repeating the request once.
+func (c *davClient) do(ctx context.Context, method, p string, headers map[string]string, body []byte) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		req, err := c.newRequest(ctx, method, p, headers, body)
+		if err != nil {
+			return nil, err
+		}
+
+		res, err := c.client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		if res.StatusCode != http.StatusUnauthorized || c.authType != "digest" || attempt > 0 {
+			return res, nil
+		}
+
+		challenge, err := parseDigestChallenge(res.Header.Get("WWW-Authenticate"))
+		_, _ = io.Copy(io.Discard, res.Body)
+		res.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		c.digestMut.Lock()
+		c.digest = challenge
+		c.digestMut.Unlock()
+	}
+}
+
+type davEntry struct {
+	path         string
+	collection   bool
+	size         int64
+	lastModified time.Time
+	etag         string
+	contentType  string
+}
+
+type davMultistatus struct {
+	Responses []struct {
+		Href     string `xml:"DAV: href"`
+		Propstat []struct {
+			Status string `xml:"DAV: status"`
+			Prop   struct {
+				ResourceType struct {
+					Collection *struct{} `xml:"DAV: collection"`
+				} `xml:"DAV: resourcetype"`
+				ContentLength int64  `xml:"DAV: getcontentlength"`
+				LastModified  string `xml:"DAV: getlastmodified"`
+				ETag          string `xml:"DAV: getetag"`
+				ContentType   string `xml:"DAV: getcontenttype"`
+			} `xml:"DAV: prop"`
+		} `xml:"DAV: propstat"`
+	} `xml:"DAV: response"`
+}
+
+const propfindBody = `<?xml version="1.0" encoding="utf-8"?>
+<d:propfind xmlns:d="DAV:">
+  <d:prop>
+    <d:resourcetype/>
+    <d:getcontentlength/>
+    <d:getlastmodified/>
+    <d:getetag/>
+    <d:getcontenttype/>
+  </d:prop>
+</d:propfind>`
+
+// list returns the entry of a path followed by the entries of its members
+// when the path is a collection.
+func (c *davClient) list(ctx context.Context, p string) ([]davEntry, error) {
+	ctx, done := context.WithTimeout(ctx, c.timeout)
+	defer done()
+
+	res, err := c.do(ctx, "PROPFIND", p, map[string]string{
+		"Depth":        "1",
+		"Content-Type": `application/xml; charset="utf-8"`,
+	}, []byte(propfindBody))
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusMultiStatus {
+		return nil, statusErrorFrom(res, p)
+	}
+
+	var ms davMultistatus
+	if err := xml.NewDecoder(res.Body).Decode(&ms); err != nil {
+		return nil, fmt.Errorf("failed to parse PROPFIND response: %w", err)
+	}
+
+	entries := make([]davEntry, 0, len(ms.Responses))
+	for _, r := range ms.Responses {
+		entryPath, err := c.relativePath(r.Href)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse href %v: %w", r.Href, err)
+		}
+		e := davEntry{path: entryPath}
+		for _, ps := range r.Propstat {
+			if !strings.Contains(ps.Status, " 200 ") {
+				continue
+			}
+			e.collection = ps.Prop.ResourceType.Collection != nil
+			e.size = ps.Prop.ContentLength
+			e.etag = ps.Prop.ETag
+			e.contentType = ps.Prop.ContentType
+			if ps.Prop.LastModified != "" {
+				e.lastModified, _ = http.ParseTime(ps.Prop.LastModified)
+			}
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+// get returns the contents of a file, which the caller must close.
+func (c *davClient) get(ctx context.Context, p string) (io.ReadCloser, error) {
+	res, err := c.do(ctx, http.MethodGet, p, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode != http.StatusOK {
+		defer res.Body.Close()
+		return nil, statusErrorFrom(res, p)
+	}
+	return res.Body, nil
+}
+
+func (c *davClient) expectStatus(ctx context.Context, method, p string, headers map[string]string, body []byte, codes ...int) error {
+	ctx, done := context.WithTimeout(ctx, c.timeout)
+	defer done()
+
+	res, err := c.do(ctx, method, p, headers, body)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	for _, code := range codes {
+		if res.StatusCode == code {
+			_, _ = io.Copy(io.Discard, res.Body)
+			return nil
+		}
+	}
+	return statusErrorFrom(res, p)
+}
+
+func (c *davClient) put(ctx context.Context, p, contentType string, body []byte) error {
+	headers := map[string]string{}
+	if contentType != "" {
+		headers["Content-Type"] = contentType
+	}
+	if body == nil {
+		body = []byte{}
+	}
+	return c.expectStatus(ctx, http.MethodPut, p, headers, body, http.StatusCreated, http.StatusNoContent, http.StatusOK)
+}
+
+// mkcol creates a collection, which is not an error when the collection
+// already exists.
+func (c *davClient) mkcol(ctx context.Context, p string) error {
+	err := c.expectStatus(ctx, "MKCOL", p, nil, nil, http.StatusCreated, http.StatusOK)
+	if isStatus(err, http.StatusMethodNotAllowed) {
+		return nil
+	}
+	return err
+}
+
+func (c *davClient) delete(ctx context.Context, p string) error {
+	err := c.expectStatus(ctx, http.MethodDelete, p, nil, nil, http.StatusNoContent, http.StatusOK, http.StatusAccepted)
+	if isStatus(err, http.StatusNotFound) {
+		return nil
+	}
+	return err
+}
+
+// parentCollections returns the collections that contain a path, from the
+// outermost to the innermost.
+func parentCollections(p string) []string {
+	var parents []string
+	for dir := path.Dir(path.Clean("/" + p)); dir != "/"; dir = path.Dir(dir) {
+		parents = append([]string{dir}, parents...)
+	}
+	return parents
+}
+
+//------------------------------------------------------------------------------
+
+type digestChallenge struct {
+	realm     string
+	nonce     string
+	opaque    string
+	algorithm string
+	qop       string
+
+	nc uint32
+}
+
+func parseDigestChallenge(header string) (*digestChallenge, error) {
+	scheme, params, _ := strings.Cut(header, " ")
+	if !strings.EqualFold(scheme, "Digest") {
+		return nil, fmt.Errorf("expected a digest authentication challenge, got %q", header)
+	}
+
+	c := &digestChallenge{algorithm: "MD5"}
+	for _, param := range splitChallengeParams(params) {
+		k, v, _ := strings.Cut(param, "=")
+		v = strings.Trim(strings.TrimSpace(v), `"`)
+		switch strings.ToLower(strings.TrimSpace(k)) {
+		case "realm":
+			c.realm = v
+		case "nonce":
+			c.nonce = v
+		case "opaque":
+			c.opaque = v
+		case "algorithm":
+			c.algorithm = v
+		case "qop":
+			for _, q := range strings.Split(v, ",") {
+				if strings.TrimSpace(q) == "auth" {
+					c.qop = "auth"
+				}
+			}
+		}
+	}
+	if c.nonce == "" {
+		return nil, errors.New("digest authentication challenge is missing a nonce")
+	}
+	switch strings.ToUpper(c.algorithm) {
+	case "MD5", "MD5-SESS", "SHA-256", "SHA-256-SESS":
+	default:
+		return nil, fmt.Errorf("digest authentication algorithm %v is not supported", c.algorithm)
+	}
+	return c, nil
+}
+
+// splitChallengeParams splits the comma separated parameters of a challenge,
+// ignoring commas within quoted values.
+func splitChallengeParams(s string) []string {
+	var params []string
+	var quoted bool
+	start := 0
+	for i, r := range s {
+		switch {
+		case r == '"':
+			quoted = !quoted
+		case r == ',' && !quoted:
+			params = append(params, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(params, s[start:])
+}
+
+func (d *digestChallenge) hash(s string) string {
+	var h hash.Hash
+	if strings.HasPrefix(strings.ToUpper(d.algorithm), "SHA-256") {
+		h = sha256.New()
+	} else {
+		h = md5.New()
+	}
+	_, _ = h.Write([]byte(s))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// authorization returns the value of an Authorization header answering the
+// challenge for a request, and must be called with the challenge locked.
+func (d *digestChallenge) authorization(username, password, method, uri string) string {
+	var cnonceBytes [8]byte
+	_, _ = rand.Read(cnonceBytes[:])
+	cnonce := hex.EncodeToString(cnonceBytes[:])
+
+	d.nc++
+	nc := fmt.Sprintf("%08x", d.nc)
+
+	ha1 := d.hash(username + ":" + d.realm + ":" + password)
+	if strings.HasSuffix(strings.ToUpper(d.algorithm), "-SESS") {
+		ha1 = d.hash(ha1 + ":" + d.nonce + ":" + cnonce)
+	}
+	ha2 := d.hash(method + ":" + uri)
+
+	var response string
+	if d.qop == "" {
+		response = d.hash(ha1 + ":" + d.nonce + ":" + ha2)
+	} else {
+		response = d.hash(ha1 + ":" + d.nonce + ":" + nc + ":" + cnonce + ":" + d.qop + ":" + ha2)
+	}
+
+	parts := []string{
+		fmt.Sprintf(`username="%v"`, username),
+		fmt.Sprintf(`realm="%v"`, d.realm),
+		fmt.Sprintf(`nonce="%v"`, d.nonce),
+		fmt.Sprintf(`uri="%v"`, uri),
+		fmt.Sprintf(`algorithm=%v`, d.algorithm),
+		fmt.Sprintf(`response="%v"`, response),
+	}
+	if d.qop != "" {
+		parts = append(parts, "qop="+d.qop, "nc="+nc, fmt.Sprintf(`cnonce="%v"`, cnonce))
+	}
+	if d.opaque != "" {
+		parts = append(parts, fmt.Sprintf(`opaque="%v"`, d.opaque))
+	}
+	return "Digest " + strings.Join(parts, ", ")
+}
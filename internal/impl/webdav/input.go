@@ -0,0 +1,467 @@
+package webdav
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/benthosdev/benthos/v4/internal/codec/interop"
+	"github.com/benthosdev/benthos/v4/internal/component/scanner"
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	wiFieldPaths               = "paths"
+	wiFieldRecursive           = "recursive"
+	wiFieldDeleteOnFinish      = "delete_on_finish"
+	wiFieldWatcher             = "watcher"
+	wiFieldWatcherEnabled      = "enabled"
+	wiFieldWatcherMinimumAge   = "minimum_age"
+	wiFieldWatcherPollInterval = "poll_interval"
+	wiFieldWatcherCache        = "cache"
+)
+
+func webdavInputSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Network").
+		Version("4.28.0").
+		Summary(`Consumes files from a WebDAV server.`).
+		Description(`
+Each path is either a file, which is consumed directly, or a collection (directory), which is listed with a `+"`PROPFIND`"+` request and each file within it is consumed. When `+"`recursive`"+` is `+"`true`"+` the collections within listed collections are also listed.
+
+This input is compatible with document stores that expose WebDAV such as Nextcloud, ownCloud and SharePoint.
+
+## Metadata
+
+This input adds the following metadata fields to each message:
+
+`+"```"+`
+- webdav_path
+- webdav_last_modified
+- webdav_etag
+- webdav_content_type
+`+"```"+`
+
+You can access these metadata fields using [function interpolation](/docs/configuration/interpolation#bloblang-queries).`).
+		Fields(clientFields()...).
+		Fields(
+			service.NewStringListField(wiFieldPaths).
+				Description("A list of paths to files or collections to consume sequentially, relative to the `url`.").
+				Example([]string{"/reports"}),
+			service.NewBoolField(wiFieldRecursive).
+				Description("Whether to consume the files of collections nested within collections.").
+				Default(false),
+			service.NewAutoRetryNacksToggleField(),
+		).
+		Fields(interop.OldReaderCodecFields("to_the_end")...).
+		Fields(
+			service.NewBoolField(wiFieldDeleteOnFinish).
+				Description("Whether to delete files from the server once they are processed.").
+				Advanced().
+				Default(false),
+			service.NewObjectField(wiFieldWatcher,
+				service.NewBoolField(wiFieldWatcherEnabled).
+					Description("Whether file watching is enabled.").
+					Default(false),
+				service.NewDurationField(wiFieldWatcherMinimumAge).
+					Description("The minimum period of time since a file was last updated before attempting to consume it. Increasing this period decreases the likelihood that a file will be consumed whilst it is still being written to.").
+					Default("1s").
+					Examples("10s", "1m", "10m"),
+				service.NewDurationField(wiFieldWatcherPollInterval).
+					Description("The interval between each attempt to list the target paths for new files.").
+					Default("1s").
+					Examples("100ms", "1s"),
+				service.NewStringField(wiFieldWatcherCache).
+					Description("A [cache resource](/docs/components/caches/about) for storing the paths of files already consumed.").
+					Default(""),
+			).Description("A mode whereby the input will periodically list the target paths for new files and consume them, when all files are consumed the input will continue polling for new files."),
+		).
+		Example("Nextcloud Reports", "Consume CSV reports from a Nextcloud folder as they are uploaded, deleting each once it has been processed.", `
+input:
+  webdav:
+    url: https://cloud.example.com/remote.php/dav/files/benthos
+    auth:
+      type: basic
+      username: benthos
+      password: ${NEXTCLOUD_APP_PASSWORD}
+    paths: [ /reports ]
+    scanner:
+      csv: {}
+    delete_on_finish: true
+    watcher:
+      enabled: true
+      poll_interval: 30s
+      cache: processed_files
+
+cache_resources:
+  - label: processed_files
+    file:
+      directory: ./processed
+`)
+}
+
+func init() {
+	err := service.RegisterBatchInput("webdav", webdavInputSpec(), func(conf *service.ParsedConfig, mgr *service.Resources) (service.BatchInput, error) {
+		r, err := newWebDAVReaderFromParsed(conf, mgr)
+		if err != nil {
+			return nil, err
+		}
+		return service.AutoRetryNacksBatchedToggled(conf, r)
+	})
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type webdavReader struct {
+	log *service.Logger
+	mgr *service.Resources
+
+	// Config
+	client         *davClient
+	paths          []string
+	recursive      bool
+	scannerCtor    interop.FallbackReaderCodec
+	deleteOnFinish bool
+
+	watcherEnabled      bool
+	watcherCache        string
+	watcherPollInterval time.Duration
+	watcherMinAge       time.Duration
+
+	pathProvider pathProvider
+
+	// State
+	scannerMut   sync.Mutex
+	scanner      interop.FallbackReaderStream
+	currentEntry davEntry
+}
+
+func newWebDAVReaderFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (w *webdavReader, err error) {
+	w = &webdavReader{
+		log: mgr.Logger(),
+		mgr: mgr,
+	}
+
+	if w.client, err = davClientFromParsed(conf); err != nil {
+		return
+	}
+	if w.paths, err = conf.FieldStringList(wiFieldPaths); err != nil {
+		return
+	}
+	if w.recursive, err = conf.FieldBool(wiFieldRecursive); err != nil {
+		return
+	}
+	if w.scannerCtor, err = interop.OldReaderCodecFromParsed(conf); err != nil {
+		return
+	}
+	if w.deleteOnFinish, err = conf.FieldBool(wiFieldDeleteOnFinish); err != nil {
+		return
+	}
+
+	{
+		wConf := conf.Namespace(wiFieldWatcher)
+		if w.watcherEnabled, _ = wConf.FieldBool(wiFieldWatcherEnabled); w.watcherEnabled {
+			if w.watcherCache, err = wConf.FieldString(wiFieldWatcherCache); err != nil {
+				return
+			}
+			if w.watcherPollInterval, err = wConf.FieldDuration(wiFieldWatcherPollInterval); err != nil {
+				return
+			}
+			if w.watcherMinAge, err = wConf.FieldDuration(wiFieldWatcherMinimumAge); err != nil {
+				return
+			}
+			if !mgr.HasCache(w.watcherCache) {
+				return nil, fmt.Errorf("cache resource '%v' was not found", w.watcherCache)
+			}
+		}
+	}
+	return
+}
+
+// listFiles returns the files at the target paths, listing the contents of
+// collections.
+func (w *webdavReader) listFiles(ctx context.Context) ([]davEntry, error) {
+	var files []davEntry
+
+	var listPath func(p string) error
+	listPath = func(p string) error {
+		entries, err := w.client.list(ctx, p)
+		if err != nil {
+			return err
+		}
+		self := "/" + strings.Trim(path.Clean("/"+p), "/")
+		for _, e := range entries {
+			// The listing of a collection includes the collection itself.
+			if e.collection && e.path == self {
+				continue
+			}
+			if !e.collection {
+				files = append(files, e)
+				continue
+			}
+			if w.recursive {
+				if err := listPath(e.path); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+
+	for _, p := range w.paths {
+		if err := listPath(p); err != nil {
+			if isStatus(err, http.StatusNotFound) {
+				w.log.With("path", p).Warn("Target path does not exist")
+				continue
+			}
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+func (w *webdavReader) Connect(ctx context.Context) (err error) {
+	w.scannerMut.Lock()
+	defer w.scannerMut.Unlock()
+
+	if w.scanner != nil {
+		return nil
+	}
+
+	if w.pathProvider == nil {
+		if w.pathProvider, err = w.getFilePathProvider(ctx); err != nil {
+			return
+		}
+	}
+
+	var next davEntry
+	var body io.ReadCloser
+	for {
+		if next, err = w.pathProvider.Next(ctx); err != nil {
+			if errors.Is(err, errEndOfPaths) {
+				err = service.ErrEndOfInput
+			}
+			return
+		}
+
+		if body, err = w.client.get(ctx, next.path); err == nil {
+			break
+		}
+
+		w.log.With("path", next.path, "err", err.Error()).Warn("Unable to open previously identified file")
+		if isStatus(err, http.StatusNotFound) {
+			// If we failed to open the file because it no longer exists
+			// then we can "ack" the path as we're done with it.
+			_ = w.pathProvider.Ack(ctx, next.path, nil)
+		} else {
+			// Otherwise we "nack" it with the error as we'll want to
+			// reprocess it again later.
+			_ = w.pathProvider.Ack(ctx, next.path, err)
+			return
+		}
+	}
+
+	nextPath := next.path
+	if w.scanner, err = w.scannerCtor.Create(body, func(ctx context.Context, aErr error) (outErr error) {
+		_ = w.pathProvider.Ack(ctx, nextPath, aErr)
+		if aErr != nil || !w.deleteOnFinish {
+			return nil
+		}
+		if outErr = w.client.delete(ctx, nextPath); outErr != nil {
+			outErr = fmt.Errorf("remove %v: %w", nextPath, outErr)
+		}
+		return
+	}, scanner.SourceDetails{Name: nextPath}); err != nil {
+		_ = body.Close()
+		_ = w.pathProvider.Ack(ctx, nextPath, err)
+		return err
+	}
+	w.currentEntry = next
+
+	w.log.Debugf("Consuming from file '%v'", nextPath)
+	return
+}
+
+func (w *webdavReader) ReadBatch(ctx context.Context) (service.MessageBatch, service.AckFunc, error) {
+	w.scannerMut.Lock()
+	scanner := w.scanner
+	current := w.currentEntry
+	w.scannerMut.Unlock()
+
+	if scanner == nil {
+		return nil, nil, service.ErrNotConnected
+	}
+
+	parts, codecAckFn, err := scanner.NextBatch(ctx)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, nil, ctx.Err()
+		}
+		_ = scanner.Close(ctx)
+		w.scannerMut.Lock()
+		if w.currentEntry.path == current.path {
+			w.scanner = nil
+			w.currentEntry = davEntry{}
+		}
+		w.scannerMut.Unlock()
+		if errors.Is(err, io.EOF) {
+			err = service.ErrNotConnected
+		}
+		return nil, nil, err
+	}
+
+	for _, part := range parts {
+		part.MetaSetMut("webdav_path", current.path)
+		if !current.lastModified.IsZero() {
+			part.MetaSetMut("webdav_last_modified", current.lastModified.Format(time.RFC3339))
+		}
+		if current.etag != "" {
+			part.MetaSetMut("webdav_etag", current.etag)
+		}
+		if current.contentType != "" {
+			part.MetaSetMut("webdav_content_type", current.contentType)
+		}
+	}
+
+	return parts, func(ctx context.Context, res error) error {
+		return codecAckFn(ctx, res)
+	}, nil
+}
+
+func (w *webdavReader) Close(ctx context.Context) error {
+	w.scannerMut.Lock()
+	scanner := w.scanner
+	w.scanner = nil
+	w.scannerMut.Unlock()
+
+	if scanner != nil {
+		if err := scanner.Close(ctx); err != nil {
+			w.log.With("error", err).Warn("Failed to close consumed file")
+		}
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+var errEndOfPaths = errors.New("end of paths")
+
+type pathProvider interface {
+	Next(context.Context) (davEntry, error)
+	Ack(context.Context, string, error) error
+}
+
+type staticPathProvider struct {
+	files []davEntry
+}
+
+func (s *staticPathProvider) Next(ctx context.Context) (davEntry, error) {
+	if len(s.files) == 0 {
+		return davEntry{}, errEndOfPaths
+	}
+	next := s.files[0]
+	s.files = s.files[1:]
+	return next, nil
+}
+
+func (s *staticPathProvider) Ack(context.Context, string, error) error {
+	return nil
+}
+
+type watcherPathProvider struct {
+	mgr          *service.Resources
+	cacheName    string
+	pollInterval time.Duration
+	minAge       time.Duration
+	listFn       func(context.Context) ([]davEntry, error)
+
+	files        []davEntry
+	nextPoll     time.Time
+	followUpPoll bool
+}
+
+func (w *watcherPathProvider) Next(ctx context.Context) (davEntry, error) {
+	for len(w.files) == 0 {
+		if waitFor := time.Until(w.nextPoll); waitFor > 0 {
+			select {
+			case <-time.After(waitFor):
+			case <-ctx.Done():
+				return davEntry{}, ctx.Err()
+			}
+		}
+		w.nextPoll = time.Now().Add(w.pollInterval)
+
+		files, err := w.listFn(ctx)
+		if err != nil {
+			w.mgr.Logger().With("error", err).Warn("Failed to list files")
+			continue
+		}
+
+		if cerr := w.mgr.AccessCache(ctx, w.cacheName, func(cache service.Cache) {
+			for _, f := range files {
+				if !f.lastModified.IsZero() && time.Since(f.lastModified) < w.minAge {
+					continue
+				}
+
+				// We process it if the marker is a pending symbol (!) and we're
+				// polling for the first time, or if the path isn't found in the
+				// cache.
+				if v, err := cache.Get(ctx, f.path); errors.Is(err, service.ErrKeyNotFound) || (!w.followUpPoll && string(v) == "!") {
+					w.files = append(w.files, f)
+					if err = cache.Set(ctx, f.path, []byte("!"), nil); err != nil {
+						// Mark the file target as pending so that we do not reprocess it
+						w.mgr.Logger().With("error", err, "path", f.path).Warn("Failed to mark path as pending")
+					}
+				}
+			}
+		}); cerr != nil {
+			return davEntry{}, fmt.Errorf("error obtaining cache: %v", cerr)
+		}
+		w.followUpPoll = true
+	}
+
+	next := w.files[0]
+	w.files = w.files[1:]
+	return next, nil
+}
+
+func (w *watcherPathProvider) Ack(ctx context.Context, name string, err error) (outErr error) {
+	if cerr := w.mgr.AccessCache(ctx, w.cacheName, func(cache service.Cache) {
+		if err == nil {
+			outErr = cache.Set(ctx, name, []byte("@"), nil)
+		} else {
+			_ = cache.Delete(ctx, name)
+		}
+	}); cerr != nil {
+		outErr = cerr
+	}
+	return
+}
+
+func (w *webdavReader) getFilePathProvider(ctx context.Context) (pathProvider, error) {
+	if !w.watcherEnabled {
+		files, err := w.listFiles(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return &staticPathProvider{files: files}, nil
+	}
+
+	return &watcherPathProvider{
+		mgr:          w.mgr,
+		cacheName:    w.watcherCache,
+		pollInterval: w.watcherPollInterval,
+		minAge:       w.watcherMinAge,
+		listFn:       w.listFiles,
+	}, nil
+}
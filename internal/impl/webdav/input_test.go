@@ -0,0 +1,176 @@
+package webdav
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+
+	_ "github.com/benthosdev/benthos/v4/internal/impl/pure"
+)
+
+func putDAVFile(t *testing.T, srvURL, p, content string) {
+	t.Helper()
+
+	for _, dir := range parentCollections(p) {
+		req, err := http.NewRequest("MKCOL", srvURL+dir, http.NoBody)
+		require.NoError(t, err)
+		res, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		res.Body.Close()
+	}
+
+	req, err := http.NewRequest(http.MethodPut, srvURL+p, strings.NewReader(content))
+	require.NoError(t, err)
+	res, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	res.Body.Close()
+	require.Equal(t, http.StatusCreated, res.StatusCode)
+}
+
+func readAllWebDAV(t *testing.T, r *webdavReader) map[string]string {
+	t.Helper()
+
+	ctx, done := context.WithTimeout(context.Background(), time.Second*10)
+	defer done()
+
+	results := map[string]string{}
+	for {
+		if err := r.Connect(ctx); err != nil {
+			if errors.Is(err, service.ErrEndOfInput) {
+				return results
+			}
+			t.Fatal(err)
+		}
+
+		batch, aFn, err := r.ReadBatch(ctx)
+		if errors.Is(err, service.ErrNotConnected) {
+			continue
+		}
+		require.NoError(t, err)
+
+		for _, msg := range batch {
+			p, _ := msg.MetaGet("webdav_path")
+			mBytes, err := msg.AsBytes()
+			require.NoError(t, err)
+			results[p] += string(mBytes)
+		}
+		require.NoError(t, aFn(ctx, nil))
+	}
+}
+
+func TestWebDAVInputListing(t *testing.T) {
+	srv, _ := testDAVServer(t)
+
+	putDAVFile(t, srv.URL, "/data/a.txt", "foo")
+	putDAVFile(t, srv.URL, "/data/b.txt", "bar")
+	putDAVFile(t, srv.URL, "/data/nested/c.txt", "baz")
+
+	for _, test := range []struct {
+		name      string
+		recursive bool
+		expected  map[string]string
+	}{
+		{
+			name: "not recursive",
+			expected: map[string]string{
+				"/data/a.txt": "foo",
+				"/data/b.txt": "bar",
+			},
+		},
+		{
+			name:      "recursive",
+			recursive: true,
+			expected: map[string]string{
+				"/data/a.txt":        "foo",
+				"/data/b.txt":        "bar",
+				"/data/nested/c.txt": "baz",
+			},
+		},
+	} {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			pConf, err := webdavInputSpec().ParseYAML(fmt.Sprintf(`
+url: %v
+paths: [ /data, /missing ]
+recursive: %v
+`, srv.URL, test.recursive), nil)
+			require.NoError(t, err)
+
+			r, err := newWebDAVReaderFromParsed(pConf, service.MockResources())
+			require.NoError(t, err)
+			t.Cleanup(func() {
+				_ = r.Close(context.Background())
+			})
+
+			assert.Equal(t, test.expected, readAllWebDAV(t, r))
+		})
+	}
+}
+
+func TestWebDAVInputDeleteOnFinish(t *testing.T) {
+	srv, _ := testDAVServer(t)
+
+	putDAVFile(t, srv.URL, "/data/a.txt", "foo")
+
+	pConf, err := webdavInputSpec().ParseYAML(`
+url: `+srv.URL+`
+paths: [ /data/a.txt ]
+delete_on_finish: true
+`, nil)
+	require.NoError(t, err)
+
+	r, err := newWebDAVReaderFromParsed(pConf, service.MockResources())
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = r.Close(context.Background())
+	})
+
+	assert.Equal(t, map[string]string{"/data/a.txt": "foo"}, readAllWebDAV(t, r))
+
+	status, _ := readDAVFile(t, srv.URL, "/data/a.txt")
+	assert.Equal(t, http.StatusNotFound, status)
+}
+
+func TestWebDAVDigestAuth(t *testing.T) {
+	var authorized int
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		auth := r.Header.Get("Authorization")
+		if !strings.HasPrefix(auth, "Digest ") {
+			rw.Header().Set("WWW-Authenticate", `Digest realm="test", nonce="abc123", qop="auth", algorithm=MD5`)
+			rw.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		assert.Contains(t, auth, `username="foo"`)
+		assert.Contains(t, auth, `realm="test"`)
+		assert.Contains(t, auth, `nonce="abc123"`)
+		assert.Contains(t, auth, `uri="/a.txt"`)
+		authorized++
+		rw.WriteHeader(http.StatusCreated)
+	}))
+	t.Cleanup(srv.Close)
+
+	pConf, err := webdavOutputSpec().ParseYAML(`
+url: `+srv.URL+`
+path: /a.txt
+auth:
+  type: digest
+  username: foo
+  password: bar
+`, nil)
+	require.NoError(t, err)
+
+	w, err := newWebDAVWriterFromParsed(pConf, service.MockResources())
+	require.NoError(t, err)
+	require.NoError(t, w.Write(context.Background(), service.NewMessage([]byte("hello world"))))
+	assert.Equal(t, 1, authorized)
+}
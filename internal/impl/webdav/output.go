@@ -0,0 +1,145 @@
+package webdav
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	woFieldPath          = "path"
+	woFieldContentType   = "content_type"
+	woFieldCreateParents = "create_parents"
+)
+
+func webdavOutputSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Network").
+		Version("4.28.0").
+		Summary(`Writes files to a WebDAV server.`).
+		Description(`
+Each message is written as a file with a `+"`PUT`"+` request, replacing any existing file at the same path. In order to have a different path for each file you should use function interpolations described [here](/docs/configuration/interpolation#bloblang-queries).
+
+When `+"`create_parents`"+` is `+"`true`"+` and the server responds that the parent collection of a file does not exist, the missing collections are created with `+"`MKCOL`"+` requests and the file is written again.`+service.OutputPerformanceDocs(true, false)).
+		Fields(clientFields()...).
+		Fields(
+			service.NewInterpolatedStringField(woFieldPath).
+				Description("The path of the file to write each message to, relative to the `url`.").
+				Example(`/uploads/${! timestamp_unix_nano() }.json`).
+				Example(`/reports/${! @kafka_key }.csv`),
+			service.NewInterpolatedStringField(woFieldContentType).
+				Description("An optional content type to set for each file.").
+				Example("application/json").
+				Default(""),
+			service.NewBoolField(woFieldCreateParents).
+				Description("Whether to create the parent collections of files that do not exist.").
+				Default(true),
+			service.NewOutputMaxInFlightField(),
+		).
+		Example("Nextcloud Uploads", "Write each message as a JSON document within a folder per day of a Nextcloud account.", `
+output:
+  webdav:
+    url: https://cloud.example.com/remote.php/dav/files/benthos
+    auth:
+      type: basic
+      username: benthos
+      password: ${NEXTCLOUD_APP_PASSWORD}
+    path: /events/${! now().ts_format("2006-01-02") }/${! uuid_v4() }.json
+    content_type: application/json
+`)
+}
+
+func init() {
+	err := service.RegisterOutput(
+		"webdav", webdavOutputSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (out service.Output, maxInFlight int, err error) {
+			if maxInFlight, err = conf.FieldMaxInFlight(); err != nil {
+				return
+			}
+			out, err = newWebDAVWriterFromParsed(conf, mgr)
+			return
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type webdavWriter struct {
+	log *service.Logger
+
+	client        *davClient
+	path          *service.InterpolatedString
+	contentType   *service.InterpolatedString
+	createParents bool
+}
+
+func newWebDAVWriterFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (w *webdavWriter, err error) {
+	w = &webdavWriter{
+		log: mgr.Logger(),
+	}
+
+	if w.client, err = davClientFromParsed(conf); err != nil {
+		return
+	}
+	if w.path, err = conf.FieldInterpolatedString(woFieldPath); err != nil {
+		return
+	}
+	if w.contentType, err = conf.FieldInterpolatedString(woFieldContentType); err != nil {
+		return
+	}
+	if w.createParents, err = conf.FieldBool(woFieldCreateParents); err != nil {
+		return
+	}
+	return
+}
+
+func (w *webdavWriter) Connect(ctx context.Context) error {
+	return nil
+}
+
+// createCollections creates the parent collections of a file, where
+// collections that already exist are ignored.
+func (w *webdavWriter) createCollections(ctx context.Context, filePath string) error {
+	for _, dir := range parentCollections(filePath) {
+		if err := w.client.mkcol(ctx, dir); err != nil {
+			return fmt.Errorf("failed to create collection %v: %w", dir, err)
+		}
+	}
+	return nil
+}
+
+func (w *webdavWriter) Write(ctx context.Context, msg *service.Message) error {
+	filePath, err := w.path.TryString(msg)
+	if err != nil {
+		return fmt.Errorf("path interpolation error: %w", err)
+	}
+	contentType, err := w.contentType.TryString(msg)
+	if err != nil {
+		return fmt.Errorf("content type interpolation error: %w", err)
+	}
+
+	mBytes, err := msg.AsBytes()
+	if err != nil {
+		return err
+	}
+
+	// A conflict indicates that a parent collection of the file is missing,
+	// although some servers respond with a not found instead.
+	err = w.client.put(ctx, filePath, contentType, mBytes)
+	if !w.createParents || !(isStatus(err, http.StatusConflict) || isStatus(err, http.StatusNotFound)) {
+		return err
+	}
+	if err := w.createCollections(ctx, filePath); err != nil {
+		return err
+	}
+	return w.client.put(ctx, filePath, contentType, mBytes)
+}
+
+func (w *webdavWriter) Close(ctx context.Context) error {
+	return nil
+}
@@ -0,0 +1,138 @@
+package webdav
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/webdav"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func testDAVServer(t *testing.T) (*httptest.Server, webdav.FileSystem) {
+	t.Helper()
+
+	fs := webdav.NewMemFS()
+	srv := httptest.NewServer(&webdav.Handler{
+		FileSystem: fs,
+		LockSystem: webdav.NewMemLS(),
+	})
+	t.Cleanup(srv.Close)
+	return srv, fs
+}
+
+func readDAVFile(t *testing.T, srvURL, p string) (int, string) {
+	t.Helper()
+
+	res, err := http.Get(srvURL + p)
+	require.NoError(t, err)
+	defer res.Body.Close()
+
+	var b []byte
+	if b, err = io.ReadAll(res.Body); err != nil {
+		t.Fatal(err)
+	}
+	return res.StatusCode, string(b)
+}
+
+func TestParentCollections(t *testing.T) {
+	assert.Equal(t, []string{"/a", "/a/b"}, parentCollections("a/b/c.txt"))
+	assert.Equal(t, []string{"/a"}, parentCollections("/a/b.txt"))
+	assert.Empty(t, parentCollections("/b.txt"))
+}
+
+func TestWebDAVOutputCreateParents(t *testing.T) {
+	srv, _ := testDAVServer(t)
+
+	pConf, err := webdavOutputSpec().ParseYAML(`
+url: `+srv.URL+`/files
+path: /${! @folder }/${! @name }.txt
+`, nil)
+	require.NoError(t, err)
+
+	w, err := newWebDAVWriterFromParsed(pConf, service.MockResources())
+	require.NoError(t, err)
+	require.NoError(t, w.Connect(context.Background()))
+
+	// The base collection must already exist.
+	req, err := http.NewRequest("MKCOL", srv.URL+"/files", http.NoBody)
+	require.NoError(t, err)
+	res, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	res.Body.Close()
+
+	for _, name := range []string{"foo", "bar"} {
+		msg := service.NewMessage([]byte("hello " + name))
+		msg.MetaSetMut("folder", "a/b")
+		msg.MetaSetMut("name", name)
+		require.NoError(t, w.Write(context.Background(), msg))
+	}
+
+	status, body := readDAVFile(t, srv.URL, "/files/a/b/foo.txt")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "hello foo", body)
+
+	status, body = readDAVFile(t, srv.URL, "/files/a/b/bar.txt")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "hello bar", body)
+}
+
+func TestWebDAVOutputCreateParentsConflict(t *testing.T) {
+	fs := webdav.NewMemFS()
+	davHandler := &webdav.Handler{
+		FileSystem: fs,
+		LockSystem: webdav.NewMemLS(),
+	}
+
+	// Servers following RFC 4918 respond to writes within missing collections
+	// with a conflict rather than a not found.
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPut {
+			if _, err := fs.Stat(r.Context(), path.Dir(r.URL.Path)); err != nil {
+				http.Error(w, "parent collection missing", http.StatusConflict)
+				return
+			}
+		}
+		davHandler.ServeHTTP(w, r)
+	}))
+	t.Cleanup(srv.Close)
+
+	pConf, err := webdavOutputSpec().ParseYAML(`
+url: `+srv.URL+`
+path: /a/b/foo.txt
+`, nil)
+	require.NoError(t, err)
+
+	w, err := newWebDAVWriterFromParsed(pConf, service.MockResources())
+	require.NoError(t, err)
+
+	require.NoError(t, w.Write(context.Background(), service.NewMessage([]byte("hello world"))))
+
+	status, body := readDAVFile(t, srv.URL, "/a/b/foo.txt")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "hello world", body)
+}
+
+func TestWebDAVOutputNoCreateParents(t *testing.T) {
+	srv, _ := testDAVServer(t)
+
+	pConf, err := webdavOutputSpec().ParseYAML(`
+url: `+srv.URL+`
+path: /a/foo.txt
+create_parents: false
+`, nil)
+	require.NoError(t, err)
+
+	w, err := newWebDAVWriterFromParsed(pConf, service.MockResources())
+	require.NoError(t, err)
+
+	err = w.Write(context.Background(), service.NewMessage([]byte("hello world")))
+	require.Error(t, err)
+	assert.True(t, isStatus(err, http.StatusNotFound))
+}
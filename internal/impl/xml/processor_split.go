@@ -0,0 +1,150 @@
+package xml
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	spFieldPath   = "path"
+	spFieldFormat = "format"
+	spFieldCast   = "cast"
+)
+
+func xmlSplitFields() []*service.ConfigField {
+	return []*service.ConfigField{
+		service.NewStringField(spFieldPath).
+			Description("The path of the elements to extract, expressed as a subset of XPath. Paths must be absolute and may contain element names, wildcards (`*`), the descendant axis (`//`) and attribute predicates of the form `[@name]` or `[@name='value']`. Elements are matched by their local name and namespace prefixes are ignored.").
+			Examples("/catalog/products/product", "//record", "/export/*/item[@type='book']"),
+		service.NewStringEnumField(spFieldFormat, "xml", "json").
+			Description("The format of extracted elements. When `xml` the raw XML of each element is emitted, and when `json` each element is converted into a JSON structure following the same rules as the [`xml` processor](/docs/components/processors/xml).").
+			Default("xml"),
+		service.NewBoolField(spFieldCast).
+			Description("When the format is `json`, whether to try to cast values that are numbers and booleans to the right type.").
+			Default(false),
+	}
+}
+
+func xmlSplitProcSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Categories("Parsing").
+		Version("4.28.0").
+		Summary("Splits XML documents into a message for each element that matches a path.").
+		Description(`
+Documents are read element by element rather than being parsed in their entirety, and so only the element being extracted and its ancestors are held in memory at any given time. This makes it possible to break apart large exports that would be too expensive to process with the `+"[`xml` processor](/docs/components/processors/xml)"+`. In order to avoid holding documents within memory at all, use the `+"[`xml_split` scanner](/docs/components/scanners/xml_split)"+` with inputs that support scanners, such as `+"`file`"+` and `+"`aws_s3`"+`.
+
+Elements nested within a matching element are not matched themselves. Each resulting message has the metadata field `+"`xml_split_index`"+` set to the zero-based position of the element within the document. When a document contains no matching elements the message is removed.
+
+Documents that aren't UTF-8, as declared by their XML declaration, are converted to UTF-8. Documents that cannot be parsed are flagged as having failed, allowing you to use [standard processor error handling patterns](/docs/configuration/error_handling).`).
+		Fields(xmlSplitFields()...).
+		Example("Product Catalog", "Here we break a product catalog into a JSON message per product.", `
+pipeline:
+  processors:
+    - xml_split:
+        path: /catalog/products/product
+        format: json
+        cast: true
+    - mapping: 'root = this.product'
+`)
+}
+
+func init() {
+	err := service.RegisterProcessor(
+		"xml_split", xmlSplitProcSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Processor, error) {
+			return newXMLSplitProcFromConfig(conf)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type xmlSplitConfig struct {
+	steps  []splitStep
+	asJSON bool
+	cast   bool
+}
+
+func xmlSplitConfigFromParsed(conf *service.ParsedConfig) (c xmlSplitConfig, err error) {
+	var path string
+	if path, err = conf.FieldString(spFieldPath); err != nil {
+		return
+	}
+	if c.steps, err = parseSplitPath(path); err != nil {
+		return
+	}
+
+	var format string
+	if format, err = conf.FieldString(spFieldFormat); err != nil {
+		return
+	}
+	c.asJSON = format == "json"
+
+	c.cast, err = conf.FieldBool(spFieldCast)
+	return
+}
+
+// setMessage sets the contents of a message to an extracted element.
+func (c xmlSplitConfig) setMessage(msg *service.Message, element []byte, index int) error {
+	if c.asJSON {
+		v, err := ToMap(element, c.cast)
+		if err != nil {
+			return err
+		}
+		msg.SetStructuredMut(v)
+	} else {
+		msg.SetBytes(element)
+	}
+	msg.MetaSetMut("xml_split_index", int64(index))
+	return nil
+}
+
+type xmlSplitProc struct {
+	conf xmlSplitConfig
+}
+
+func newXMLSplitProcFromConfig(conf *service.ParsedConfig) (*xmlSplitProc, error) {
+	c, err := xmlSplitConfigFromParsed(conf)
+	if err != nil {
+		return nil, err
+	}
+	return &xmlSplitProc{conf: c}, nil
+}
+
+func (p *xmlSplitProc) Process(ctx context.Context, msg *service.Message) (service.MessageBatch, error) {
+	raw, err := msg.AsBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	splitter, err := newXMLSplitter(p.conf.steps, bytes.NewReader(raw))
+	if err != nil {
+		return nil, err
+	}
+
+	var batch service.MessageBatch
+	for {
+		element, err := splitter.next()
+		if errors.Is(err, io.EOF) {
+			return batch, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse XML document: %w", err)
+		}
+
+		part := msg.Copy()
+		if err := p.conf.setMessage(part, element, len(batch)); err != nil {
+			return nil, fmt.Errorf("failed to convert element %v: %w", len(batch), err)
+		}
+		batch = append(batch, part)
+	}
+}
+
+func (p *xmlSplitProc) Close(ctx context.Context) error {
+	return nil
+}
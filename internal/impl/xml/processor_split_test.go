@@ -0,0 +1,170 @@
+package xml
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/internal/component/scanner/testutil"
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const testSplitDoc = `<?xml version="1.0"?>
+<export xmlns:p="urn:products">
+  <!-- products -->
+  <p:products>
+    <p:product id="1" type="book"><name>Go</name><price>10</price></p:product>
+    <p:product id="2" type="film"><name>Up &amp; Away</name></p:product>
+    <p:product id="3" type="book"/>
+  </p:products>
+  <archive>
+    <product id="4"><product id="5"/></product>
+  </archive>
+</export>`
+
+func testXMLSplit(t *testing.T, confStr string, input []byte) []string {
+	t.Helper()
+
+	conf, err := xmlSplitProcSpec().ParseYAML(confStr, nil)
+	require.NoError(t, err)
+
+	proc, err := newXMLSplitProcFromConfig(conf)
+	require.NoError(t, err)
+
+	res, err := proc.Process(context.Background(), service.NewMessage(input))
+	require.NoError(t, err)
+
+	var out []string
+	for i, m := range res {
+		b, err := m.AsBytes()
+		require.NoError(t, err)
+		out = append(out, string(b))
+
+		index, _ := m.MetaGetMut("xml_split_index")
+		assert.Equal(t, int64(i), index)
+	}
+	return out
+}
+
+func TestXMLSplitPaths(t *testing.T) {
+	for _, test := range []struct {
+		path   string
+		output []string
+	}{
+		{
+			path: "/export/products/product",
+			output: []string{
+				`<p:product id="1" type="book"><name>Go</name><price>10</price></p:product>`,
+				`<p:product id="2" type="film"><name>Up &amp; Away</name></p:product>`,
+				`<p:product id="3" type="book"/>`,
+			},
+		},
+		{
+			path: "/export/p:products/p:product[@type='book']",
+			output: []string{
+				`<p:product id="1" type="book"><name>Go</name><price>10</price></p:product>`,
+				`<p:product id="3" type="book"/>`,
+			},
+		},
+		{
+			path: "//product",
+			output: []string{
+				`<p:product id="1" type="book"><name>Go</name><price>10</price></p:product>`,
+				`<p:product id="2" type="film"><name>Up &amp; Away</name></p:product>`,
+				`<p:product id="3" type="book"/>`,
+				`<product id="4"><product id="5"/></product>`,
+			},
+		},
+		{
+			path: "/export/*/product[@id=\"4\"]//product",
+			output: []string{
+				`<product id="5"/>`,
+			},
+		},
+		{
+			path: "//name",
+			output: []string{
+				`<name>Go</name>`,
+				`<name>Up &amp; Away</name>`,
+			},
+		},
+		{
+			path:   "/products/product",
+			output: nil,
+		},
+	} {
+		test := test
+		t.Run(test.path, func(t *testing.T) {
+			conf := fmt.Sprintf("path: %q", test.path)
+			assert.Equal(t, test.output, testXMLSplit(t, conf, []byte(testSplitDoc)))
+		})
+	}
+}
+
+func TestXMLSplitJSON(t *testing.T) {
+	out := testXMLSplit(t, `
+path: //product[@id]
+format: json
+cast: true
+`, []byte(`<root><product id="1"><price>10</price></product><product id="2">none</product></root>`))
+	assert.Equal(t, []string{
+		`{"product":{"-id":1,"price":10}}`,
+		`{"product":{"#text":"none","-id":2}}`,
+	}, out)
+}
+
+func TestXMLSplitCharset(t *testing.T) {
+	out := testXMLSplit(t, `path: /root/name`, []byte("<?xml version=\"1.0\" encoding=\"ISO-8859-1\"?>\n<root><name>caf\xe9</name></root>"))
+	assert.Equal(t, []string{`<name>café</name>`}, out)
+}
+
+func TestXMLSplitErrors(t *testing.T) {
+	for _, path := range []string{
+		"products/product",
+		"/products//",
+		"/products/product[1]",
+		"/products/product[@id",
+	} {
+		conf, err := xmlSplitProcSpec().ParseYAML(fmt.Sprintf("path: %q", path), nil)
+		require.NoError(t, err, path)
+
+		_, err = newXMLSplitProcFromConfig(conf)
+		require.Error(t, err, path)
+	}
+
+	conf, err := xmlSplitProcSpec().ParseYAML(`path: //product`, nil)
+	require.NoError(t, err)
+
+	proc, err := newXMLSplitProcFromConfig(conf)
+	require.NoError(t, err)
+
+	_, err = proc.Process(context.Background(), service.NewMessage([]byte(`<root><product>`)))
+	require.Error(t, err)
+}
+
+func TestXMLSplitScanner(t *testing.T) {
+	confSpec := service.NewConfigSpec().Field(service.NewScannerField("test"))
+	pConf, err := confSpec.ParseYAML(`
+test:
+  xml_split:
+    path: /root/item
+`, nil)
+	require.NoError(t, err)
+
+	rdr, err := pConf.FieldScanner("test")
+	require.NoError(t, err)
+
+	testutil.ScannerTestSuite(t, rdr, nil, []byte(`<root>
+  <item>a</item>
+  <item><b>c</b></item>
+  <other>d</other>
+  <item/>
+</root>`),
+		`<item>a</item>`,
+		`<item><b>c</b></item>`,
+		`<item/>`,
+	)
+}
@@ -0,0 +1,93 @@
+package xml
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func xmlSplitScannerSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Version("4.28.0").
+		Summary("Consumes an XML document as a stream of elements that match a path.").
+		Description("Only the element being extracted and its ancestors are held in memory at any given time, which allows documents of any size to be consumed. Each message has the metadata field `xml_split_index` set to the zero-based position of the element within the document.").
+		Fields(xmlSplitFields()...)
+}
+
+func init() {
+	err := service.RegisterBatchScannerCreator("xml_split", xmlSplitScannerSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.BatchScannerCreator, error) {
+			c, err := xmlSplitConfigFromParsed(conf)
+			if err != nil {
+				return nil, err
+			}
+			return &xmlSplitScannerCreator{conf: c}, nil
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type xmlSplitScannerCreator struct {
+	conf xmlSplitConfig
+}
+
+func (c *xmlSplitScannerCreator) Create(rdr io.ReadCloser, aFn service.AckFunc, details *service.ScannerSourceDetails) (service.BatchScanner, error) {
+	splitter, err := newXMLSplitter(c.conf.steps, rdr)
+	if err != nil {
+		_ = rdr.Close()
+		return nil, err
+	}
+	return service.AutoAggregateBatchScannerAcks(&xmlSplitScanner{
+		conf:     c.conf,
+		splitter: splitter,
+		r:        rdr,
+	}, aFn), nil
+}
+
+func (c *xmlSplitScannerCreator) Close(context.Context) error {
+	return nil
+}
+
+type xmlSplitScanner struct {
+	conf     xmlSplitConfig
+	splitter *xmlSplitter
+	r        io.ReadCloser
+	index    int
+}
+
+func (s *xmlSplitScanner) NextBatch(ctx context.Context) (service.MessageBatch, error) {
+	if s.r == nil {
+		return nil, io.EOF
+	}
+
+	element, err := s.splitter.next()
+	if err != nil {
+		_ = s.r.Close()
+		s.r = nil
+		if !errors.Is(err, io.EOF) {
+			err = fmt.Errorf("failed to parse XML document: %w", err)
+		}
+		return nil, err
+	}
+
+	index := s.index
+	s.index++
+
+	msg := service.NewMessage(nil)
+	if err := s.conf.setMessage(msg, element, index); err != nil {
+		return nil, fmt.Errorf("failed to convert element %v: %w", index, err)
+	}
+	return service.MessageBatch{msg}, nil
+}
+
+func (s *xmlSplitScanner) Close(ctx context.Context) error {
+	if s.r == nil {
+		return nil
+	}
+	return s.r.Close()
+}
@@ -0,0 +1,244 @@
+package xml
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html/charset"
+)
+
+// splitStep is a single location step of a split path, matching an element by
+// its local name and, optionally, an attribute.
+type splitStep struct {
+	descendant bool
+	name       string
+	attr       string
+	hasValue   bool
+	value      string
+}
+
+func (s splitStep) matches(start xml.StartElement) bool {
+	if s.name != "*" && s.name != start.Name.Local {
+		return false
+	}
+	if s.attr == "" {
+		return true
+	}
+	for _, a := range start.Attr {
+		if a.Name.Local == s.attr {
+			return !s.hasValue || a.Value == s.value
+		}
+	}
+	return false
+}
+
+var splitPredicateRegexp = regexp.MustCompile(`^@([\w.-]+(?::[\w.-]+)?)\s*(?:=\s*(?:'([^']*)'|"([^"]*)"))?$`)
+
+// parseSplitPath parses the subset of XPath supported by the splitter, which
+// consists of absolute paths of element names, the descendant axis (//), the
+// wildcard (*) and attribute predicates of the form [@name] or
+// [@name='value'].
+func parseSplitPath(path string) ([]splitStep, error) {
+	if !strings.HasPrefix(path, "/") {
+		return nil, fmt.Errorf("path must be absolute: %v", path)
+	}
+
+	var steps []splitStep
+	remaining := path
+	for remaining != "" {
+		var step splitStep
+		if strings.HasPrefix(remaining, "//") {
+			step.descendant = true
+			remaining = remaining[2:]
+		} else {
+			remaining = remaining[1:]
+		}
+
+		end := 0
+		for end < len(remaining) && remaining[end] != '/' {
+			if remaining[end] == '[' {
+				closing := strings.IndexByte(remaining[end:], ']')
+				if closing == -1 {
+					return nil, fmt.Errorf("unterminated predicate in path: %v", path)
+				}
+				end += closing
+			}
+			end++
+		}
+		token := remaining[:end]
+		remaining = remaining[end:]
+
+		if i := strings.IndexByte(token, '['); i != -1 {
+			pred := strings.TrimSpace(token[i+1 : len(token)-1])
+			token = token[:i]
+
+			m := splitPredicateRegexp.FindStringSubmatch(pred)
+			if m == nil {
+				return nil, fmt.Errorf("unsupported predicate [%v] in path: %v", pred, path)
+			}
+			step.attr = stripPrefix(m[1])
+			if strings.Contains(pred, "=") {
+				step.hasValue = true
+				step.value = m[2] + m[3]
+			}
+		}
+
+		step.name = stripPrefix(strings.TrimSpace(token))
+		if step.name == "" {
+			return nil, fmt.Errorf("empty step in path: %v", path)
+		}
+		steps = append(steps, step)
+	}
+	if len(steps) == 0 {
+		return nil, fmt.Errorf("path must contain at least one element: %v", path)
+	}
+	return steps, nil
+}
+
+func stripPrefix(name string) string {
+	if i := strings.IndexByte(name, ':'); i != -1 {
+		return name[i+1:]
+	}
+	return name
+}
+
+// matchSplitPath returns whether a stack of elements, ordered from the root,
+// matches a split path.
+func matchSplitPath(steps []splitStep, stack []xml.StartElement) bool {
+	if len(steps) == 0 {
+		return len(stack) == 0
+	}
+	if len(stack) == 0 {
+		return false
+	}
+
+	last := steps[len(steps)-1]
+	if !last.matches(stack[len(stack)-1]) {
+		return false
+	}
+	if !last.descendant {
+		return matchSplitPath(steps[:len(steps)-1], stack[:len(stack)-1])
+	}
+	for i := len(stack) - 1; i >= 0; i-- {
+		if matchSplitPath(steps[:len(steps)-1], stack[:i]) {
+			return true
+		}
+	}
+	return false
+}
+
+// recordingReader retains the bytes read from a reader from a given offset
+// onwards, which allows us to extract the raw bytes of elements.
+type recordingReader struct {
+	r      *bufio.Reader
+	buf    []byte
+	offset int64
+}
+
+func (r *recordingReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.buf = append(r.buf, p[:n]...)
+	return n, err
+}
+
+// ReadByte is implemented so that the decoder doesn't add its own buffering,
+// which would otherwise read far beyond the elements being extracted.
+func (r *recordingReader) ReadByte() (byte, error) {
+	b, err := r.r.ReadByte()
+	if err == nil {
+		r.buf = append(r.buf, b)
+	}
+	return b, err
+}
+
+// discard drops all retained bytes prior to an offset.
+func (r *recordingReader) discard(to int64) {
+	n := int(to - r.offset)
+	if n <= 0 {
+		return
+	}
+	r.buf = append(r.buf[:0], r.buf[n:]...)
+	r.offset = to
+}
+
+func (r *recordingReader) slice(from, to int64) []byte {
+	return bytes.Clone(r.buf[from-r.offset : to-r.offset])
+}
+
+var xmlEncodingRegexp = regexp.MustCompile(`^\s*<\?xml[^>]*encoding\s*=\s*["']([^"']+)["']`)
+
+// xmlSplitter walks the elements of an XML document and extracts the raw bytes
+// of each element that matches a path. Only the current element hierarchy and
+// the element being extracted are held in memory.
+type xmlSplitter struct {
+	steps []splitStep
+	rec   *recordingReader
+	dec   *xml.Decoder
+	stack []xml.StartElement
+}
+
+func newXMLSplitter(steps []splitStep, r io.Reader) (*xmlSplitter, error) {
+	br := bufio.NewReader(r)
+
+	// Documents that aren't UTF-8 are converted before they reach the decoder
+	// in order for the extracted elements to be UTF-8.
+	prolog, err := br.Peek(1024)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, bufio.ErrBufferFull) {
+		return nil, err
+	}
+	if m := xmlEncodingRegexp.FindSubmatch(prolog); m != nil {
+		if label := strings.ToLower(string(m[1])); label != "utf-8" && label != "utf8" {
+			cr, err := charset.NewReaderLabel(label, br)
+			if err != nil {
+				return nil, err
+			}
+			br = bufio.NewReader(cr)
+		}
+	}
+
+	rec := &recordingReader{r: br}
+	dec := xml.NewDecoder(rec)
+	dec.Strict = false
+	dec.CharsetReader = func(label string, input io.Reader) (io.Reader, error) {
+		return input, nil
+	}
+	return &xmlSplitter{steps: steps, rec: rec, dec: dec}, nil
+}
+
+// next returns the raw bytes of the next matching element, or io.EOF once the
+// document has been exhausted. Elements nested within a matching element are
+// not matched themselves.
+func (s *xmlSplitter) next() ([]byte, error) {
+	for {
+		s.rec.discard(s.dec.InputOffset())
+
+		start := s.dec.InputOffset()
+		tok, err := s.dec.Token()
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			s.stack = append(s.stack, t.Copy())
+			if !matchSplitPath(s.steps, s.stack) {
+				continue
+			}
+			s.stack = s.stack[:len(s.stack)-1]
+			if err := s.dec.Skip(); err != nil {
+				return nil, err
+			}
+			return s.rec.slice(start, s.dec.InputOffset()), nil
+		case xml.EndElement:
+			if len(s.stack) > 0 {
+				s.stack = s.stack[:len(s.stack)-1]
+			}
+		}
+	}
+}
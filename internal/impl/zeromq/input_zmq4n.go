@@ -0,0 +1,201 @@
+package zeromq
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func zmqnInputConfig() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Network").
+		Version("4.28.0").
+		Summary("Consumes messages from a ZeroMQ socket.").
+		Description(`
+This is a pure Go implementation of the ZeroMQ Message Transport Protocol (ZMTP 3.0) and is therefore available in all builds of Benthos, including static builds that are unable to link against libzmq. It supports the NULL security mechanism and is compatible with peers using libzmq or any other ZMTP 3.x implementation.
+
+The fields of this input are identical to the `+"[`zmq4` input](/docs/components/inputs/zmq4)"+` and therefore it can be used as a drop-in replacement.`).
+		Field(service.NewStringListField("urls").
+			Description("A list of URLs to connect to. If an item of the list contains commas it will be expanded into multiple URLs.").
+			Example([]string{"tcp://localhost:5555"})).
+		Field(service.NewBoolField("bind").
+			Description("Whether to bind to the specified URLs (otherwise they are connected to).").
+			Default(false)).
+		Field(service.NewStringEnumField("socket_type", "PULL", "SUB").
+			Description("The socket type to connect as.")).
+		Field(service.NewStringListField("sub_filters").
+			Description("A list of subscription topic filters to use when consuming from a SUB socket. Specifying a single sub_filter of `''` will subscribe to everything.").
+			Default([]any{})).
+		Field(service.NewIntField("high_water_mark").
+			Description("The maximum number of received messages to buffer before applying back pressure to peers. A value of zero uses a default of 1000.").
+			Default(0).
+			Advanced()).
+		Field(service.NewDurationField("poll_timeout").
+			Description("The poll timeout to use.").
+			Default("5s").
+			Advanced()).
+		Example("Consume from a PUB socket", "Subscribe to all messages published by a ZeroMQ PUB socket that begin with the topic `foo`:", `
+input:
+  zmq4n:
+    urls: [ tcp://localhost:5556 ]
+    socket_type: SUB
+    sub_filters: [ foo ]
+`)
+}
+
+func init() {
+	_ = service.RegisterBatchInput("zmq4n", zmqnInputConfig(), func(conf *service.ParsedConfig, mgr *service.Resources) (service.BatchInput, error) {
+		r, err := zmqnInputFromConfig(conf, mgr)
+		if err != nil {
+			return nil, err
+		}
+		return service.AutoRetryNacksBatched(r), nil
+	})
+}
+
+//------------------------------------------------------------------------------
+
+type zmqnInput struct {
+	log *service.Logger
+
+	urls        []string
+	socketType  string
+	hwm         int
+	bind        bool
+	subFilters  []string
+	pollTimeout time.Duration
+
+	socketMut sync.Mutex
+	socket    *zmtpSocket
+}
+
+func zmqnInputFromConfig(conf *service.ParsedConfig, mgr *service.Resources) (*zmqnInput, error) {
+	z := zmqnInput{
+		log: mgr.Logger(),
+	}
+
+	urlStrs, err := conf.FieldStringList("urls")
+	if err != nil {
+		return nil, err
+	}
+
+	for _, u := range urlStrs {
+		for _, splitU := range strings.Split(u, ",") {
+			if len(splitU) > 0 {
+				z.urls = append(z.urls, splitU)
+			}
+		}
+	}
+
+	if z.bind, err = conf.FieldBool("bind"); err != nil {
+		return nil, err
+	}
+	if z.socketType, err = conf.FieldString("socket_type"); err != nil {
+		return nil, err
+	}
+	if z.socketType != "PULL" && z.socketType != "SUB" {
+		return nil, errors.New("invalid ZMQ socket type")
+	}
+
+	if z.subFilters, err = conf.FieldStringList("sub_filters"); err != nil {
+		return nil, err
+	}
+
+	if z.socketType == "SUB" && len(z.subFilters) == 0 {
+		return nil, errors.New("must provide at least one sub filter when connecting with a SUB socket, in order to subscribe to all messages add an empty string")
+	}
+
+	if z.hwm, err = conf.FieldInt("high_water_mark"); err != nil {
+		return nil, err
+	}
+
+	if z.pollTimeout, err = conf.FieldDuration("poll_timeout"); err != nil {
+		return nil, err
+	}
+	return &z, nil
+}
+
+//------------------------------------------------------------------------------
+
+func (z *zmqnInput) Connect(ctx context.Context) (err error) {
+	z.socketMut.Lock()
+	defer z.socketMut.Unlock()
+
+	if z.socket != nil {
+		return nil
+	}
+
+	var socket *zmtpSocket
+	if socket, err = newZMTPSocket(z.socketType, z.hwm, z.subFilters); err != nil {
+		return err
+	}
+
+	defer func() {
+		if err != nil {
+			_ = socket.Close()
+		}
+	}()
+
+	for _, address := range z.urls {
+		if z.bind {
+			err = socket.Bind(address)
+		} else {
+			err = socket.Connect(address)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	z.socket = socket
+	return nil
+}
+
+func (z *zmqnInput) ReadBatch(ctx context.Context) (service.MessageBatch, service.AckFunc, error) {
+	z.socketMut.Lock()
+	socket := z.socket
+	z.socketMut.Unlock()
+
+	if socket == nil {
+		return nil, nil, service.ErrNotConnected
+	}
+
+	pollCtx, done := context.WithTimeout(ctx, z.pollTimeout)
+	defer done()
+
+	data, err := socket.Recv(pollCtx)
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil {
+			return nil, nil, context.Canceled
+		}
+		if errors.Is(err, errZMTPSocketClosed) {
+			return nil, nil, service.ErrNotConnected
+		}
+		return nil, nil, err
+	}
+
+	var batch service.MessageBatch
+	for _, d := range data {
+		batch = append(batch, service.NewMessage(d))
+	}
+
+	return batch, func(ctx context.Context, err error) error {
+		return nil
+	}, nil
+}
+
+func (z *zmqnInput) Close(ctx context.Context) error {
+	z.socketMut.Lock()
+	defer z.socketMut.Unlock()
+
+	if z.socket != nil {
+		_ = z.socket.Close()
+		z.socket = nil
+	}
+	return nil
+}
@@ -0,0 +1,182 @@
+package zeromq
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func zmqnOutputConfig() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Network").
+		Version("4.28.0").
+		Summary("Writes messages to a ZeroMQ socket.").
+		Description(`
+This is a pure Go implementation of the ZeroMQ Message Transport Protocol (ZMTP 3.0) and is therefore available in all builds of Benthos, including static builds that are unable to link against libzmq. It supports the NULL security mechanism and is compatible with peers using libzmq or any other ZMTP 3.x implementation.
+
+The fields of this output are identical to the ` + "[`zmq4` output](/docs/components/outputs/zmq4)" + ` and therefore it can be used as a drop-in replacement.
+
+A PUSH socket distributes messages across connected peers in a round-robin fashion and blocks until a peer is available, failing the write once the ` + "`poll_timeout`" + ` elapses. A PUB socket sends messages to all subscribed peers and drops messages when no peers are subscribed.`).
+		Field(service.NewStringListField("urls").
+			Description("A list of URLs to connect to. If an item of the list contains commas it will be expanded into multiple URLs.").
+			Example([]string{"tcp://localhost:5556"})).
+		Field(service.NewBoolField("bind").
+			Description("Whether to bind to the specified URLs (otherwise they are connected to).").
+			Default(true)).
+		Field(service.NewStringEnumField("socket_type", "PUSH", "PUB").
+			Description("The socket type to connect as.")).
+		Field(service.NewIntField("high_water_mark").
+			Description("The message high water mark to use.").
+			Default(0).
+			Advanced()).
+		Field(service.NewDurationField("poll_timeout").
+			Description("The poll timeout to use.").
+			Default("5s").
+			Advanced())
+}
+
+func init() {
+	_ = service.RegisterBatchOutput("zmq4n", zmqnOutputConfig(), func(conf *service.ParsedConfig, mgr *service.Resources) (service.BatchOutput, service.BatchPolicy, int, error) {
+		w, err := zmqnOutputFromConfig(conf, mgr)
+		if err != nil {
+			return nil, service.BatchPolicy{}, 1, err
+		}
+		return w, service.BatchPolicy{}, 1, nil
+	})
+}
+
+//------------------------------------------------------------------------------
+
+type zmqnOutput struct {
+	log *service.Logger
+
+	urls        []string
+	socketType  string
+	hwm         int
+	bind        bool
+	pollTimeout time.Duration
+
+	socketMut sync.Mutex
+	socket    *zmtpSocket
+}
+
+func zmqnOutputFromConfig(conf *service.ParsedConfig, mgr *service.Resources) (*zmqnOutput, error) {
+	z := zmqnOutput{
+		log: mgr.Logger(),
+	}
+
+	urlStrs, err := conf.FieldStringList("urls")
+	if err != nil {
+		return nil, err
+	}
+
+	for _, u := range urlStrs {
+		for _, splitU := range strings.Split(u, ",") {
+			if len(splitU) > 0 {
+				z.urls = append(z.urls, splitU)
+			}
+		}
+	}
+
+	if z.bind, err = conf.FieldBool("bind"); err != nil {
+		return nil, err
+	}
+	if z.socketType, err = conf.FieldString("socket_type"); err != nil {
+		return nil, err
+	}
+	if z.socketType != "PUSH" && z.socketType != "PUB" {
+		return nil, errors.New("invalid ZMQ socket type")
+	}
+
+	if z.hwm, err = conf.FieldInt("high_water_mark"); err != nil {
+		return nil, err
+	}
+
+	if z.pollTimeout, err = conf.FieldDuration("poll_timeout"); err != nil {
+		return nil, err
+	}
+	return &z, nil
+}
+
+//------------------------------------------------------------------------------
+
+func (z *zmqnOutput) Connect(_ context.Context) (err error) {
+	z.socketMut.Lock()
+	defer z.socketMut.Unlock()
+
+	if z.socket != nil {
+		return nil
+	}
+
+	var socket *zmtpSocket
+	if socket, err = newZMTPSocket(z.socketType, z.hwm, nil); err != nil {
+		return err
+	}
+
+	defer func() {
+		if err != nil {
+			_ = socket.Close()
+		}
+	}()
+
+	for _, address := range z.urls {
+		if z.bind {
+			err = socket.Bind(address)
+		} else {
+			err = socket.Connect(address)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	z.socket = socket
+	return nil
+}
+
+func (z *zmqnOutput) WriteBatch(ctx context.Context, batch service.MessageBatch) error {
+	z.socketMut.Lock()
+	socket := z.socket
+	z.socketMut.Unlock()
+
+	if socket == nil {
+		return service.ErrNotConnected
+	}
+
+	parts := make([][]byte, 0, len(batch))
+	for _, m := range batch {
+		b, err := m.AsBytes()
+		if err != nil {
+			return err
+		}
+		parts = append(parts, b)
+	}
+
+	pollCtx, done := context.WithTimeout(ctx, z.pollTimeout)
+	defer done()
+
+	err := socket.Send(pollCtx, parts)
+	if errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil {
+		return errors.New("timed out waiting for an available peer")
+	}
+	if errors.Is(err, errZMTPSocketClosed) {
+		return service.ErrNotConnected
+	}
+	return err
+}
+
+func (z *zmqnOutput) Close(ctx context.Context) error {
+	z.socketMut.Lock()
+	defer z.socketMut.Unlock()
+
+	if z.socket != nil {
+		_ = z.socket.Close()
+		z.socket = nil
+	}
+	return nil
+}
@@ -0,0 +1,627 @@
+package zeromq
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// This file contains a minimal pure Go implementation of the ZeroMQ Message
+// Transport Protocol (ZMTP 3.0, https://rfc.zeromq.org/spec/23/) using the NULL
+// security mechanism. It supports the PUSH, PULL, PUB and SUB socket types,
+// which is enough for the components in this package to be built without
+// linking against libzmq.
+
+const (
+	zmtpFlagMore    byte = 0x01
+	zmtpFlagLong    byte = 0x02
+	zmtpFlagCommand byte = 0x04
+
+	zmtpGreetingLen = 64
+	zmtpMaxFrameLen = 1 << 31
+)
+
+var errZMTPSocketClosed = errors.New("socket closed")
+
+// Socket types that are able to talk to each other.
+var zmtpCompatibleTypes = map[string][]string{
+	"PUSH": {"PULL"},
+	"PULL": {"PUSH"},
+	"PUB":  {"SUB", "XSUB"},
+	"SUB":  {"PUB", "XPUB"},
+}
+
+func zmtpGreeting() []byte {
+	g := make([]byte, zmtpGreetingLen)
+	g[0] = 0xFF
+	g[9] = 0x7F
+	g[10] = 3 // Major version
+	g[11] = 0 // Minor version
+	copy(g[12:32], "NULL")
+	return g
+}
+
+//------------------------------------------------------------------------------
+
+// zmtpConn is a single ZMTP connection to a peer that has completed the
+// greeting and handshake phases.
+type zmtpConn struct {
+	conn     net.Conn
+	r        *bufio.Reader
+	peerType string
+
+	wMut sync.Mutex
+	w    *bufio.Writer
+}
+
+func zmtpHandshake(conn net.Conn, socketType string, timeout time.Duration) (*zmtpConn, error) {
+	if timeout > 0 {
+		_ = conn.SetDeadline(time.Now().Add(timeout))
+		defer func() {
+			_ = conn.SetDeadline(time.Time{})
+		}()
+	}
+
+	z := &zmtpConn{
+		conn: conn,
+		r:    bufio.NewReader(conn),
+		w:    bufio.NewWriter(conn),
+	}
+
+	if _, err := z.w.Write(zmtpGreeting()); err != nil {
+		return nil, err
+	}
+	if err := z.w.Flush(); err != nil {
+		return nil, err
+	}
+
+	peerGreeting := make([]byte, zmtpGreetingLen)
+	if _, err := io.ReadFull(z.r, peerGreeting); err != nil {
+		return nil, fmt.Errorf("failed to read greeting: %w", err)
+	}
+	if peerGreeting[0] != 0xFF || peerGreeting[9] != 0x7F {
+		return nil, errors.New("peer greeting has an invalid signature")
+	}
+	if peerGreeting[10] < 3 {
+		return nil, fmt.Errorf("peer ZMTP version %v.%v is not supported", peerGreeting[10], peerGreeting[11])
+	}
+	if mech := string(bytes.TrimRight(peerGreeting[12:32], "\x00")); mech != "NULL" {
+		return nil, fmt.Errorf("peer security mechanism %v is not supported", mech)
+	}
+
+	if err := z.writeCommand("READY", zmtpEncodeProperties(map[string]string{
+		"Socket-Type": socketType,
+	})); err != nil {
+		return nil, err
+	}
+
+	name, body, err := z.readCommand()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read handshake: %w", err)
+	}
+	switch name {
+	case "READY":
+	case "ERROR":
+		reason := ""
+		if len(body) > 0 && int(body[0]) < len(body) {
+			reason = string(body[1 : 1+int(body[0])])
+		}
+		return nil, fmt.Errorf("peer rejected handshake: %v", reason)
+	default:
+		return nil, fmt.Errorf("unexpected handshake command: %v", name)
+	}
+
+	props, err := zmtpDecodeProperties(body)
+	if err != nil {
+		return nil, err
+	}
+	z.peerType = strings.ToUpper(props["Socket-Type"])
+
+	compatible := false
+	for _, t := range zmtpCompatibleTypes[socketType] {
+		if t == z.peerType {
+			compatible = true
+			break
+		}
+	}
+	if !compatible {
+		return nil, fmt.Errorf("peer socket type %v is incompatible with %v", z.peerType, socketType)
+	}
+	return z, nil
+}
+
+func zmtpEncodeProperties(props map[string]string) []byte {
+	var buf bytes.Buffer
+	for k, v := range props {
+		_ = buf.WriteByte(byte(len(k)))
+		_, _ = buf.WriteString(k)
+		_ = binary.Write(&buf, binary.BigEndian, uint32(len(v)))
+		_, _ = buf.WriteString(v)
+	}
+	return buf.Bytes()
+}
+
+func zmtpDecodeProperties(b []byte) (map[string]string, error) {
+	props := map[string]string{}
+	for len(b) > 0 {
+		nameLen := int(b[0])
+		if len(b) < 1+nameLen+4 {
+			return nil, errors.New("malformed command property")
+		}
+		name := string(b[1 : 1+nameLen])
+		b = b[1+nameLen:]
+
+		valueLen := int(binary.BigEndian.Uint32(b))
+		b = b[4:]
+		if len(b) < valueLen {
+			return nil, errors.New("malformed command property value")
+		}
+		props[name] = string(b[:valueLen])
+		b = b[valueLen:]
+	}
+	return props, nil
+}
+
+func (z *zmtpConn) writeFrame(flags byte, body []byte) error {
+	if len(body) > 255 {
+		flags |= zmtpFlagLong
+	}
+	if err := z.w.WriteByte(flags); err != nil {
+		return err
+	}
+	if flags&zmtpFlagLong != 0 {
+		var size [8]byte
+		binary.BigEndian.PutUint64(size[:], uint64(len(body)))
+		if _, err := z.w.Write(size[:]); err != nil {
+			return err
+		}
+	} else if err := z.w.WriteByte(byte(len(body))); err != nil {
+		return err
+	}
+	_, err := z.w.Write(body)
+	return err
+}
+
+func (z *zmtpConn) writeCommand(name string, data []byte) error {
+	body := make([]byte, 0, 1+len(name)+len(data))
+	body = append(body, byte(len(name)))
+	body = append(body, name...)
+	body = append(body, data...)
+
+	z.wMut.Lock()
+	defer z.wMut.Unlock()
+	if err := z.writeFrame(zmtpFlagCommand, body); err != nil {
+		return err
+	}
+	return z.w.Flush()
+}
+
+func (z *zmtpConn) writeMessage(parts [][]byte) error {
+	z.wMut.Lock()
+	defer z.wMut.Unlock()
+	for i, p := range parts {
+		var flags byte
+		if i < len(parts)-1 {
+			flags = zmtpFlagMore
+		}
+		if err := z.writeFrame(flags, p); err != nil {
+			return err
+		}
+	}
+	return z.w.Flush()
+}
+
+func (z *zmtpConn) readFrame() (flags byte, body []byte, err error) {
+	if flags, err = z.r.ReadByte(); err != nil {
+		return
+	}
+
+	var size uint64
+	if flags&zmtpFlagLong != 0 {
+		var sizeBytes [8]byte
+		if _, err = io.ReadFull(z.r, sizeBytes[:]); err != nil {
+			return
+		}
+		size = binary.BigEndian.Uint64(sizeBytes[:])
+	} else {
+		var b byte
+		if b, err = z.r.ReadByte(); err != nil {
+			return
+		}
+		size = uint64(b)
+	}
+	if size > zmtpMaxFrameLen {
+		err = fmt.Errorf("frame size %v exceeds maximum", size)
+		return
+	}
+
+	body = make([]byte, size)
+	_, err = io.ReadFull(z.r, body)
+	return
+}
+
+func (z *zmtpConn) readCommand() (name string, data []byte, err error) {
+	flags, body, err := z.readFrame()
+	if err != nil {
+		return "", nil, err
+	}
+	if flags&zmtpFlagCommand == 0 {
+		return "", nil, errors.New("expected command frame")
+	}
+	return zmtpParseCommand(body)
+}
+
+func zmtpParseCommand(body []byte) (name string, data []byte, err error) {
+	if len(body) == 0 || int(body[0]) > len(body)-1 {
+		return "", nil, errors.New("malformed command frame")
+	}
+	return string(body[1 : 1+int(body[0])]), body[1+int(body[0]):], nil
+}
+
+// readMessage reads the next complete multipart message from the peer. Any
+// commands received in the meantime are passed to the provided func, with
+// heartbeats being handled automatically.
+func (z *zmtpConn) readMessage(onCommand func(name string, data []byte)) ([][]byte, error) {
+	var parts [][]byte
+	for {
+		flags, body, err := z.readFrame()
+		if err != nil {
+			return nil, err
+		}
+		if flags&zmtpFlagCommand != 0 {
+			name, data, err := zmtpParseCommand(body)
+			if err != nil {
+				return nil, err
+			}
+			if name == "PING" {
+				// The PING body is a two byte TTL followed by a context that
+				// must be echoed back within the PONG.
+				var pingCtx []byte
+				if len(data) > 2 {
+					pingCtx = data[2:]
+				}
+				if err := z.writeCommand("PONG", pingCtx); err != nil {
+					return nil, err
+				}
+			} else if onCommand != nil {
+				onCommand(name, data)
+			}
+			continue
+		}
+		parts = append(parts, body)
+		if flags&zmtpFlagMore == 0 {
+			return parts, nil
+		}
+	}
+}
+
+func (z *zmtpConn) Close() error {
+	return z.conn.Close()
+}
+
+//------------------------------------------------------------------------------
+
+// zmtpEndpoint parses a ZeroMQ style endpoint into a network and address
+// understood by the net package.
+func zmtpEndpoint(endpoint string) (network, address string, err error) {
+	scheme, addr, ok := strings.Cut(endpoint, "://")
+	if !ok {
+		return "", "", fmt.Errorf("endpoint %v is missing a transport prefix", endpoint)
+	}
+	switch scheme {
+	case "tcp":
+		if strings.HasPrefix(addr, "*:") {
+			addr = strings.TrimPrefix(addr, "*")
+		}
+		return "tcp", addr, nil
+	case "ipc":
+		return "unix", addr, nil
+	}
+	return "", "", fmt.Errorf("endpoint transport %v is not supported", scheme)
+}
+
+type zmtpPeer struct {
+	conn *zmtpConn
+
+	subsMut sync.RWMutex
+	subs    map[string]int
+}
+
+func (p *zmtpPeer) subscribe(topic []byte, add bool) {
+	p.subsMut.Lock()
+	defer p.subsMut.Unlock()
+	if add {
+		p.subs[string(topic)]++
+	} else if p.subs[string(topic)] > 0 {
+		if p.subs[string(topic)]--; p.subs[string(topic)] == 0 {
+			delete(p.subs, string(topic))
+		}
+	}
+}
+
+func (p *zmtpPeer) matches(parts [][]byte) bool {
+	var topic []byte
+	if len(parts) > 0 {
+		topic = parts[0]
+	}
+	p.subsMut.RLock()
+	defer p.subsMut.RUnlock()
+	for s := range p.subs {
+		if bytes.HasPrefix(topic, []byte(s)) {
+			return true
+		}
+	}
+	return false
+}
+
+// zmtpSocket manages any number of bound or connected peers for a given
+// socket type.
+type zmtpSocket struct {
+	socketType       string
+	subFilters       []string
+	handshakeTimeout time.Duration
+	reconnectDelay   time.Duration
+
+	peersMut    sync.Mutex
+	peers       []*zmtpPeer
+	peersChange chan struct{}
+	nextPeer    int
+
+	listeners []net.Listener
+	incoming  chan [][]byte
+
+	closeOnce sync.Once
+	closed    chan struct{}
+	wg        sync.WaitGroup
+}
+
+func newZMTPSocket(socketType string, hwm int, subFilters []string) (*zmtpSocket, error) {
+	if _, exists := zmtpCompatibleTypes[socketType]; !exists {
+		return nil, fmt.Errorf("socket type %v is not supported", socketType)
+	}
+	if hwm <= 0 {
+		hwm = 1000
+	}
+	return &zmtpSocket{
+		socketType:       socketType,
+		subFilters:       subFilters,
+		handshakeTimeout: 10 * time.Second,
+		reconnectDelay:   100 * time.Millisecond,
+		peersChange:      make(chan struct{}),
+		incoming:         make(chan [][]byte, hwm),
+		closed:           make(chan struct{}),
+	}, nil
+}
+
+// Bind listens on an endpoint and accepts peers until the socket is closed.
+func (s *zmtpSocket) Bind(endpoint string) error {
+	network, address, err := zmtpEndpoint(endpoint)
+	if err != nil {
+		return err
+	}
+	l, err := net.Listen(network, address)
+	if err != nil {
+		return err
+	}
+
+	s.peersMut.Lock()
+	s.listeners = append(s.listeners, l)
+	s.peersMut.Unlock()
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				select {
+				case <-s.closed:
+					return
+				default:
+				}
+				var netErr net.Error
+				if errors.As(err, &netErr) && netErr.Timeout() {
+					continue
+				}
+				return
+			}
+			s.wg.Add(1)
+			go func() {
+				defer s.wg.Done()
+				s.serve(conn)
+			}()
+		}
+	}()
+	return nil
+}
+
+// Connect dials an endpoint in the background, reconnecting whenever the
+// connection is lost until the socket is closed.
+func (s *zmtpSocket) Connect(endpoint string) error {
+	network, address, err := zmtpEndpoint(endpoint)
+	if err != nil {
+		return err
+	}
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		for {
+			dialer := net.Dialer{Timeout: s.handshakeTimeout}
+			if conn, err := dialer.Dial(network, address); err == nil {
+				s.serve(conn)
+			}
+			select {
+			case <-time.After(s.reconnectDelay):
+			case <-s.closed:
+				return
+			}
+		}
+	}()
+	return nil
+}
+
+func (s *zmtpSocket) addPeer(p *zmtpPeer) bool {
+	s.peersMut.Lock()
+	defer s.peersMut.Unlock()
+	select {
+	case <-s.closed:
+		return false
+	default:
+	}
+	s.peers = append(s.peers, p)
+	close(s.peersChange)
+	s.peersChange = make(chan struct{})
+	return true
+}
+
+func (s *zmtpSocket) removePeer(p *zmtpPeer) {
+	s.peersMut.Lock()
+	defer s.peersMut.Unlock()
+	for i, e := range s.peers {
+		if e == p {
+			s.peers = append(s.peers[:i], s.peers[i+1:]...)
+			break
+		}
+	}
+	_ = p.conn.Close()
+}
+
+func (s *zmtpSocket) serve(conn net.Conn) {
+	zConn, err := zmtpHandshake(conn, s.socketType, s.handshakeTimeout)
+	if err != nil {
+		_ = conn.Close()
+		return
+	}
+
+	p := &zmtpPeer{conn: zConn, subs: map[string]int{}}
+	if !s.addPeer(p) {
+		_ = conn.Close()
+		return
+	}
+	defer s.removePeer(p)
+
+	if s.socketType == "SUB" {
+		for _, f := range s.subFilters {
+			if err := zConn.writeMessage([][]byte{append([]byte{1}, f...)}); err != nil {
+				return
+			}
+		}
+	}
+
+	for {
+		parts, err := zConn.readMessage(func(name string, data []byte) {
+			// ZMTP 3.1 peers may send subscriptions as commands.
+			switch name {
+			case "SUBSCRIBE":
+				p.subscribe(data, true)
+			case "CANCEL":
+				p.subscribe(data, false)
+			}
+		})
+		if err != nil {
+			return
+		}
+
+		switch s.socketType {
+		case "PUB":
+			if len(parts) == 1 && len(parts[0]) > 0 {
+				p.subscribe(parts[0][1:], parts[0][0] == 1)
+			}
+		case "PULL", "SUB":
+			select {
+			case s.incoming <- parts:
+			case <-s.closed:
+				return
+			}
+		}
+	}
+}
+
+// Send writes a multipart message to the socket. A PUSH socket writes to a
+// single peer in a round-robin fashion, blocking until a peer is available,
+// whereas a PUB socket writes to all subscribed peers and never blocks on
+// the absence of peers.
+func (s *zmtpSocket) Send(ctx context.Context, parts [][]byte) error {
+	if s.socketType == "PUB" {
+		s.peersMut.Lock()
+		peers := make([]*zmtpPeer, len(s.peers))
+		copy(peers, s.peers)
+		s.peersMut.Unlock()
+
+		for _, p := range peers {
+			if !p.matches(parts) {
+				continue
+			}
+			if err := p.conn.writeMessage(parts); err != nil {
+				s.removePeer(p)
+			}
+		}
+		return nil
+	}
+
+	for {
+		s.peersMut.Lock()
+		var p *zmtpPeer
+		if len(s.peers) > 0 {
+			s.nextPeer = (s.nextPeer + 1) % len(s.peers)
+			p = s.peers[s.nextPeer]
+		}
+		changeChan := s.peersChange
+		s.peersMut.Unlock()
+
+		if p != nil {
+			if err := p.conn.writeMessage(parts); err != nil {
+				s.removePeer(p)
+				return err
+			}
+			return nil
+		}
+
+		select {
+		case <-changeChan:
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-s.closed:
+			return errZMTPSocketClosed
+		}
+	}
+}
+
+// Recv reads the next multipart message received by the socket.
+func (s *zmtpSocket) Recv(ctx context.Context) ([][]byte, error) {
+	select {
+	case parts := <-s.incoming:
+		return parts, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-s.closed:
+		return nil, errZMTPSocketClosed
+	}
+}
+
+// Close all listeners and peer connections and waits for their goroutines to
+// exit.
+func (s *zmtpSocket) Close() error {
+	s.closeOnce.Do(func() {
+		s.peersMut.Lock()
+		close(s.closed)
+		for _, l := range s.listeners {
+			_ = l.Close()
+		}
+		for _, p := range s.peers {
+			_ = p.conn.Close()
+		}
+		s.peersMut.Unlock()
+	})
+	s.wg.Wait()
+	return nil
+}
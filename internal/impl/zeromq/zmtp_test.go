@@ -0,0 +1,169 @@
+package zeromq
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func freeTCPEndpoint(t *testing.T) string {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := l.Addr().String()
+	require.NoError(t, l.Close())
+	return "tcp://" + addr
+}
+
+func TestZMTPEndpoints(t *testing.T) {
+	for _, test := range []struct {
+		endpoint    string
+		network     string
+		address     string
+		errContains string
+	}{
+		{endpoint: "tcp://localhost:5555", network: "tcp", address: "localhost:5555"},
+		{endpoint: "tcp://*:5555", network: "tcp", address: ":5555"},
+		{endpoint: "ipc:///tmp/foo.sock", network: "unix", address: "/tmp/foo.sock"},
+		{endpoint: "localhost:5555", errContains: "missing a transport prefix"},
+		{endpoint: "inproc://foo", errContains: "not supported"},
+	} {
+		network, address, err := zmtpEndpoint(test.endpoint)
+		if test.errContains != "" {
+			require.Error(t, err, test.endpoint)
+			assert.Contains(t, err.Error(), test.errContains)
+			continue
+		}
+		require.NoError(t, err, test.endpoint)
+		assert.Equal(t, test.network, network)
+		assert.Equal(t, test.address, address)
+	}
+}
+
+func TestZMTPPushPull(t *testing.T) {
+	ctx, done := context.WithTimeout(context.Background(), time.Second*30)
+	defer done()
+
+	endpoint := freeTCPEndpoint(t)
+
+	pull, err := newZMTPSocket("PULL", 0, nil)
+	require.NoError(t, err)
+	require.NoError(t, pull.Bind(endpoint))
+	t.Cleanup(func() {
+		_ = pull.Close()
+	})
+
+	push, err := newZMTPSocket("PUSH", 0, nil)
+	require.NoError(t, err)
+	require.NoError(t, push.Connect(endpoint))
+	t.Cleanup(func() {
+		_ = push.Close()
+	})
+
+	longPart := []byte(strings.Repeat("x", 1000))
+
+	require.NoError(t, push.Send(ctx, [][]byte{[]byte("hello"), []byte("world")}))
+	require.NoError(t, push.Send(ctx, [][]byte{longPart}))
+
+	parts, err := pull.Recv(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, [][]byte{[]byte("hello"), []byte("world")}, parts)
+
+	parts, err = pull.Recv(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, [][]byte{longPart}, parts)
+}
+
+func TestZMTPPushBlocksWithoutPeers(t *testing.T) {
+	push, err := newZMTPSocket("PUSH", 0, nil)
+	require.NoError(t, err)
+	require.NoError(t, push.Connect(freeTCPEndpoint(t)))
+	t.Cleanup(func() {
+		_ = push.Close()
+	})
+
+	ctx, done := context.WithTimeout(context.Background(), time.Millisecond*100)
+	defer done()
+
+	err = push.Send(ctx, [][]byte{[]byte("hello")})
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestZMTPPubSub(t *testing.T) {
+	ctx, done := context.WithTimeout(context.Background(), time.Second*30)
+	defer done()
+
+	endpoint := freeTCPEndpoint(t)
+
+	pub, err := newZMTPSocket("PUB", 0, nil)
+	require.NoError(t, err)
+	require.NoError(t, pub.Bind(endpoint))
+	t.Cleanup(func() {
+		_ = pub.Close()
+	})
+
+	sub, err := newZMTPSocket("SUB", 0, []string{"foo"})
+	require.NoError(t, err)
+	require.NoError(t, sub.Connect(endpoint))
+	t.Cleanup(func() {
+		_ = sub.Close()
+	})
+
+	// Subscriptions are propagated asynchronously so we publish until the
+	// subscriber receives something.
+	received := make(chan [][]byte)
+	go func() {
+		parts, err := sub.Recv(ctx)
+		if err == nil {
+			received <- parts
+		}
+	}()
+
+	var parts [][]byte
+	for parts == nil {
+		require.NoError(t, pub.Send(ctx, [][]byte{[]byte("bar"), []byte("ignored")}))
+		require.NoError(t, pub.Send(ctx, [][]byte{[]byte("foobar"), []byte("kept")}))
+		select {
+		case parts = <-received:
+		case <-time.After(time.Millisecond * 50):
+		case <-ctx.Done():
+			t.Fatal("timed out")
+		}
+	}
+	assert.Equal(t, [][]byte{[]byte("foobar"), []byte("kept")}, parts)
+}
+
+func TestZMTPIncompatibleSocketTypes(t *testing.T) {
+	ctx, done := context.WithTimeout(context.Background(), time.Millisecond*500)
+	defer done()
+
+	endpoint := freeTCPEndpoint(t)
+
+	sub, err := newZMTPSocket("SUB", 0, []string{""})
+	require.NoError(t, err)
+	require.NoError(t, sub.Bind(endpoint))
+	t.Cleanup(func() {
+		_ = sub.Close()
+	})
+
+	push, err := newZMTPSocket("PUSH", 0, nil)
+	require.NoError(t, err)
+	require.NoError(t, push.Connect(endpoint))
+	t.Cleanup(func() {
+		_ = push.Close()
+	})
+
+	err = push.Send(ctx, [][]byte{[]byte("hello")})
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestZMTPUnsupportedSocketType(t *testing.T) {
+	_, err := newZMTPSocket("DEALER", 0, nil)
+	require.Error(t, err)
+}
@@ -28,6 +28,17 @@ func (c *Cache) Get(ctx context.Context, key string) ([]byte, error) {
 	return []byte(i.Value), nil
 }
 
+// GetMulti gets multiple mock cache items, omitting those that do not exist.
+func (c *Cache) GetMulti(ctx context.Context, keys []string) (map[string][]byte, error) {
+	values := map[string][]byte{}
+	for _, k := range keys {
+		if i, ok := c.Values[k]; ok {
+			values[k] = []byte(i.Value)
+		}
+	}
+	return values, nil
+}
+
 // Set a mock cache item.
 func (c *Cache) Set(ctx context.Context, key string, value []byte, ttl *time.Duration) error {
 	c.Values[key] = CacheItem{
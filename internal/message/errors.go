@@ -1,7 +1,11 @@
 package message
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
+	"net"
+	"syscall"
 )
 
 // Errors returned by the message type.
@@ -9,3 +13,142 @@ var (
 	ErrMessagePartNotExist = errors.New("target message part does not exist")
 	ErrBadMessageBytes     = errors.New("serialised message bytes were in unexpected format")
 )
+
+//------------------------------------------------------------------------------
+
+// ErrorClass describes the nature of an error encountered whilst processing a
+// message, which can be used in order to determine how the message should be
+// handled.
+type ErrorClass string
+
+// Error classes.
+const (
+	// ErrorClassTransient indicates that the error is temporary and that
+	// retrying the failed operation might succeed, e.g. a timeout.
+	ErrorClassTransient ErrorClass = "transient"
+
+	// ErrorClassPermanent indicates that retrying the failed operation is not
+	// expected to succeed.
+	ErrorClassPermanent ErrorClass = "permanent"
+
+	// ErrorClassValidation indicates that the contents of the message are
+	// invalid, e.g. they could not be parsed or failed a schema.
+	ErrorClassValidation ErrorClass = "validation"
+)
+
+type classifiedError struct {
+	err   error
+	class ErrorClass
+}
+
+func (e *classifiedError) Error() string {
+	return e.err.Error()
+}
+
+func (e *classifiedError) Unwrap() error {
+	return e.err
+}
+
+func (e *classifiedError) ErrorClass() ErrorClass {
+	return e.class
+}
+
+// ErrorWithClass wraps an error with a class.
+func ErrorWithClass(err error, class ErrorClass) error {
+	return &classifiedError{err: err, class: class}
+}
+
+// ErrorClassOf returns the class of an error and true, or false if the error
+// could not be classified. The class is determined by the first error of the
+// chain that has one, otherwise timeouts and connection failures are
+// considered transient and JSON parsing failures are considered validation
+// errors.
+func ErrorClassOf(err error) (ErrorClass, bool) {
+	if err == nil {
+		return "", false
+	}
+
+	var cErr interface{ ErrorClass() ErrorClass }
+	if errors.As(err, &cErr) {
+		return cErr.ErrorClass(), true
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.EPIPE) {
+		return ErrorClassTransient, true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return ErrorClassTransient, true
+	}
+
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &syntaxErr) || errors.As(err, &typeErr) {
+		return ErrorClassValidation, true
+	}
+	return "", false
+}
+
+// ErrorWithDefaultClass wraps an error with a class only if it could not
+// already be classified.
+func ErrorWithDefaultClass(err error, class ErrorClass) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := ErrorClassOf(err); ok {
+		return err
+	}
+	return ErrorWithClass(err, class)
+}
+
+// ProcessorError is an error attached to a message by a processor, which
+// includes details of the processor and the class of the error.
+type ProcessorError struct {
+	Err   error
+	Class ErrorClass
+
+	// The label, type name and config path of the processor.
+	Label string
+	Name  string
+	Path  []string
+}
+
+// NewProcessorError wraps an error with the details of the processor that
+// encountered it. Errors that could not be classified are considered
+// permanent. If the error is already a ProcessorError it is returned as is,
+// retaining the details of the processor it originated from.
+func NewProcessorError(err error, label, name string, path []string) error {
+	var pErr *ProcessorError
+	if errors.As(err, &pErr) {
+		return err
+	}
+	class, ok := ErrorClassOf(err)
+	if !ok {
+		class = ErrorClassPermanent
+	}
+	return &ProcessorError{
+		Err:   err,
+		Class: class,
+		Label: label,
+		Name:  name,
+		Path:  path,
+	}
+}
+
+// Error returns the message of the underlying error.
+func (e *ProcessorError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the underlying error.
+func (e *ProcessorError) Unwrap() error {
+	return e.Err
+}
+
+// ErrorClass returns the class of the error.
+func (e *ProcessorError) ErrorClass() ErrorClass {
+	return e.Class
+}
@@ -0,0 +1,52 @@
+package message
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestErrorClassOf(t *testing.T) {
+	var jsonErr error
+	var v any
+	jsonErr = json.Unmarshal([]byte(`not json`), &v)
+
+	for _, test := range []struct {
+		name  string
+		err   error
+		class ErrorClass
+	}{
+		{name: "nil"},
+		{name: "unclassified", err: errors.New("nope")},
+		{name: "deadline", err: fmt.Errorf("nope: %w", context.DeadlineExceeded), class: ErrorClassTransient},
+		{name: "json", err: jsonErr, class: ErrorClassValidation},
+		{name: "explicit", err: ErrorWithClass(errors.New("nope"), ErrorClassPermanent), class: ErrorClassPermanent},
+		{name: "outermost", err: ErrorWithClass(context.DeadlineExceeded, ErrorClassPermanent), class: ErrorClassPermanent},
+		{name: "default kept", err: ErrorWithDefaultClass(context.DeadlineExceeded, ErrorClassValidation), class: ErrorClassTransient},
+		{name: "default applied", err: ErrorWithDefaultClass(errors.New("nope"), ErrorClassValidation), class: ErrorClassValidation},
+		{name: "processor", err: NewProcessorError(errors.New("nope"), "", "", nil), class: ErrorClassPermanent},
+	} {
+		class, ok := ErrorClassOf(test.err)
+		assert.Equal(t, test.class, class, test.name)
+		assert.Equal(t, test.class != "", ok, test.name)
+	}
+}
+
+func TestProcessorError(t *testing.T) {
+	inner := errors.New("nope")
+
+	err := NewProcessorError(fmt.Errorf("failed: %w", inner), "foo", "bar", []string{"baz"})
+	assert.EqualError(t, err, "failed: nope")
+	assert.ErrorIs(t, err, inner)
+
+	var pErr *ProcessorError
+	assert.ErrorAs(t, err, &pErr)
+	assert.Equal(t, "foo", pErr.Label)
+
+	// Errors that already have a processor are unchanged.
+	assert.Equal(t, err, NewProcessorError(err, "other", "other", nil))
+}
@@ -22,6 +22,7 @@ func TestConfigParseYAML(t *testing.T) {
 			name: "basic config",
 			input: `
 threads: 123
+ordered: true
 processors:
   - label: a
     mapping: 'root = "a"'
@@ -30,6 +31,7 @@ processors:
 `,
 			validateFn: func(t testing.TB, v pipeline.Config) {
 				assert.Equal(t, 123, v.Threads)
+				assert.True(t, v.Ordered)
 				require.Len(t, v.Processors, 2)
 				assert.Equal(t, "a", v.Processors[0].Label)
 				assert.Equal(t, "mapping", v.Processors[0].Type)
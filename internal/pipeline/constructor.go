@@ -14,11 +14,14 @@ import (
 
 var threadsField = docs.FieldInt("threads", "The number of threads to execute processing pipelines across.").HasDefault(-1)
 
+var orderedField = docs.FieldBool("ordered", "Whether the results of processing are emitted in the order that messages were consumed when processing across multiple threads. Results that are ready early are held until the results of all preceding messages have been emitted, which preserves ordering at the cost of some throughput.").HasDefault(false).Advanced().AtVersion("4.28.0")
+
 func ConfigSpec() docs.FieldSpec {
 	return docs.FieldObject(
 		"pipeline", "Describes optional processing pipelines used for mutating messages.",
 	).WithChildren(
 		threadsField,
+		orderedField,
 		docs.FieldProcessor("processors", "A list of processors to apply to messages.").Array().HasDefault([]any{}),
 	)
 }
@@ -33,6 +36,7 @@ func ConfigSpec() docs.FieldSpec {
 // threads, or use a memory buffer.
 type Config struct {
 	Threads    int                `json:"threads" yaml:"threads"`
+	Ordered    bool               `json:"ordered" yaml:"ordered"`
 	Processors []processor.Config `json:"processors" yaml:"processors"`
 }
 
@@ -60,6 +64,9 @@ func New(conf Config, mgr bundle.NewManagement) (processor.Pipeline, error) {
 	if conf.Threads == 1 {
 		return NewProcessor(processors...), nil
 	}
+	if conf.Ordered {
+		return NewOrderedPool(conf.Threads, mgr.Logger(), processors...)
+	}
 	return NewPool(conf.Threads, mgr.Logger(), processors...)
 }
 
@@ -87,6 +94,12 @@ func fromMap(prov docs.Provider, val map[string]any) (conf Config, err error) {
 		conf.Threads = int(threads64)
 	}
 
+	if orderedV, exists := val["ordered"]; exists {
+		if conf.Ordered, err = value.IGetBool(orderedV); err != nil {
+			return
+		}
+	}
+
 	if procVs, ok := val["processors"].([]any); ok {
 		for _, iv := range procVs {
 			var tmpProc processor.Config
@@ -107,6 +120,10 @@ func fromYAML(prov docs.Provider, val *yaml.Node) (conf Config, err error) {
 			if err = val.Content[i+1].Decode(&conf.Threads); err != nil {
 				return
 			}
+		case "ordered":
+			if err = val.Content[i+1].Decode(&conf.Ordered); err != nil {
+				return
+			}
 		case "processors":
 			node := val.Content[i+1]
 			if node.Kind != yaml.SequenceNode {
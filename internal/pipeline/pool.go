@@ -17,9 +17,16 @@ import (
 // Pool is a pool of pipelines. Each pipeline reads from a shared transaction
 // channel. Inputs remain coupled to their outputs as they propagate the
 // response channel in the transaction.
+//
+// An ordered pool instead processes transactions across a number of workers
+// and emits the results in the order that the transactions were consumed.
 type Pool struct {
 	workers []processor.Pipeline
 
+	ordered       bool
+	threads       int
+	msgProcessors []processor.V1
+
 	log log.Modular
 
 	messagesIn  <-chan message.Transaction
@@ -48,10 +55,34 @@ func NewPool(threads int, log log.Modular, msgProcessors ...processor.V1) (*Pool
 	return p, nil
 }
 
+// NewOrderedPool creates a new processing pool that emits the results of
+// processing in the order that transactions were consumed. Results that are
+// ready early are buffered until the results of all preceding transactions
+// have been emitted.
+func NewOrderedPool(threads int, log log.Modular, msgProcessors ...processor.V1) (*Pool, error) {
+	if threads <= 0 {
+		threads = runtime.NumCPU()
+	}
+
+	return &Pool{
+		ordered:       true,
+		threads:       threads,
+		msgProcessors: msgProcessors,
+		log:           log,
+		messagesOut:   make(chan message.Transaction),
+		shutSig:       shutdown.NewSignaller(),
+	}, nil
+}
+
 //------------------------------------------------------------------------------
 
 // loop is the processing loop of this pipeline.
 func (p *Pool) loop() {
+	if p.ordered {
+		p.orderedLoop()
+		return
+	}
+
 	// Note this is currently kept open as we only have our children as a
 	// shutdown mechanism. This puts trust in individual processor pipelines, if
 	// that's not realistic we can consider adding a close now to the
@@ -126,6 +157,123 @@ func (p *Pool) loop() {
 	}
 }
 
+type pendingResult struct {
+	tran      message.Transaction
+	sorter    *message.SortGroup
+	sortBatch message.Batch
+
+	resultBatches []message.Batch
+	err           error
+	done          chan struct{}
+}
+
+// orderedLoop is the processing loop of an ordered pool.
+func (p *Pool) orderedLoop() {
+	closeNowCtx, cnDone := p.shutSig.HardStopCtx(context.Background())
+	defer cnDone()
+
+	var workersWG, acksWG sync.WaitGroup
+	defer func() {
+		workersWG.Wait()
+		for _, c := range p.msgProcessors {
+			if err := c.Close(closeNowCtx); err != nil {
+				break
+			}
+		}
+		close(p.messagesOut)
+
+		// Outputs may rely on the closure of our transaction channel in order
+		// to flush and acknowledge the final results.
+		acksDone := make(chan struct{})
+		go func() {
+			acksWG.Wait()
+			close(acksDone)
+		}()
+		select {
+		case <-acksDone:
+		case <-p.shutSig.HardStopChan():
+		}
+		p.shutSig.TriggerHasStopped()
+	}()
+
+	// Transactions are queued in the order that they're consumed, and the
+	// capacity of the queue limits the number of results that can be buffered
+	// whilst waiting for a preceding transaction to finish processing.
+	pending := make(chan *pendingResult, p.threads)
+	jobs := make(chan *pendingResult)
+
+	workersWG.Add(p.threads)
+	for i := 0; i < p.threads; i++ {
+		go func() {
+			defer workersWG.Done()
+			for r := range jobs {
+				r.resultBatches, r.err = processor.ExecuteAll(closeNowCtx, p.msgProcessors, r.sortBatch)
+				close(r.done)
+			}
+		}()
+	}
+
+	go func() {
+		defer func() {
+			close(jobs)
+			close(pending)
+		}()
+		for {
+			var tran message.Transaction
+			var open bool
+			select {
+			case tran, open = <-p.messagesIn:
+				if !open {
+					return
+				}
+			case <-p.shutSig.HardStopChan():
+				return
+			}
+
+			r := &pendingResult{tran: tran, done: make(chan struct{})}
+			r.sorter, r.sortBatch = message.NewSortGroup(tran.Payload)
+			select {
+			case pending <- r:
+			case <-p.shutSig.HardStopChan():
+				return
+			}
+			select {
+			case jobs <- r:
+			case <-p.shutSig.HardStopChan():
+				return
+			}
+		}
+	}()
+
+	for r := range pending {
+		select {
+		case <-r.done:
+		case <-p.shutSig.HardStopChan():
+			return
+		}
+
+		if len(r.resultBatches) == 0 || r.err != nil {
+			if _ = r.tran.Ack(closeNowCtx, r.err); closeNowCtx.Err() != nil {
+				return
+			}
+			continue
+		}
+
+		ackFn, sent := sendResults(p.shutSig, p.messagesOut, r.tran, r.sorter, r.sortBatch, r.resultBatches)
+		if !sent {
+			return
+		}
+
+		// Acknowledgements are awaited in the background so that the results
+		// of subsequent transactions aren't held up.
+		acksWG.Add(1)
+		go func() {
+			defer acksWG.Done()
+			ackFn(closeNowCtx)
+		}()
+	}
+}
+
 //------------------------------------------------------------------------------
 
 // Consume assigns a messages channel for the pipeline to read.
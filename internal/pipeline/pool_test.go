@@ -2,6 +2,7 @@ package pipeline_test
 
 import (
 	"context"
+	"fmt"
 	"reflect"
 	"testing"
 	"time"
@@ -10,6 +11,7 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/benthosdev/benthos/v4/internal/component/processor"
+	"github.com/benthosdev/benthos/v4/internal/component/testutil"
 	"github.com/benthosdev/benthos/v4/internal/log"
 	"github.com/benthosdev/benthos/v4/internal/manager/mock"
 	"github.com/benthosdev/benthos/v4/internal/message"
@@ -278,3 +280,69 @@ func TestPoolMultiNaturalClose(t *testing.T) {
 	close(tChan)
 	require.NoError(t, proc.WaitForClose(context.Background()))
 }
+
+func TestPoolOrdered(t *testing.T) {
+	ctx, done := context.WithTimeout(context.Background(), time.Second*30)
+	defer done()
+
+	sleepConf, err := testutil.ProcessorFromYAML(`
+sleep:
+  duration: '${! content() }'
+`)
+	require.NoError(t, err)
+
+	filterConf, err := testutil.ProcessorFromYAML(`
+mapping: 'root = if content().string() == "60ms" { deleted() } else { content() }'
+`)
+	require.NoError(t, err)
+
+	conf := pipeline.NewConfig()
+	conf.Threads = 4
+	conf.Ordered = true
+	conf.Processors = append(conf.Processors, sleepConf, filterConf)
+
+	proc, err := pipeline.New(conf, mock.NewManager())
+	require.NoError(t, err)
+
+	tChan := make(chan message.Transaction)
+	require.NoError(t, proc.Consume(tChan))
+
+	// Earlier messages sleep for longer and therefore finish processing after
+	// later ones.
+	var inputs []string
+	for i := 8; i > 0; i-- {
+		inputs = append(inputs, fmt.Sprintf("%vms", i*20))
+	}
+
+	resChans := make([]chan error, len(inputs))
+	for i := range resChans {
+		resChans[i] = make(chan error, 1)
+	}
+	go func() {
+		for i, in := range inputs {
+			select {
+			case tChan <- message.NewTransaction(message.QuickBatch([][]byte{[]byte(in)}), resChans[i]):
+			case <-ctx.Done():
+				return
+			}
+		}
+		close(tChan)
+	}()
+
+	var outputs []string
+	for tran := range proc.TransactionChan() {
+		outputs = append(outputs, string(tran.Payload.Get(0).AsBytes()))
+		require.NoError(t, tran.Ack(ctx, nil))
+	}
+	assert.Equal(t, []string{"160ms", "140ms", "120ms", "100ms", "80ms", "40ms", "20ms"}, outputs)
+
+	for i := range inputs {
+		select {
+		case res := <-resChans[i]:
+			assert.NoError(t, res, i)
+		case <-ctx.Done():
+			t.Fatal("Timed out")
+		}
+	}
+	require.NoError(t, proc.WaitForClose(ctx))
+}
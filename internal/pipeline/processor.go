@@ -77,68 +77,89 @@ func (p *Processor) loop() {
 			continue
 		}
 
-		if len(resultBatches) == 1 {
-			select {
-			case p.messagesOut <- message.NewTransactionFunc(resultBatches[0], tran.Ack):
-			case <-p.shutSig.HardStopChan():
-				return
-			}
-			continue
+		ackFn, sent := sendResults(p.shutSig, p.messagesOut, tran, sorter, sortBatch, resultBatches)
+		if !sent {
+			return
 		}
+		ackFn(closeNowCtx)
+	}
+}
 
-		var (
-			errMut     sync.Mutex
-			batchErr   *batch.Error
-			generalErr error
-			batchWG    sync.WaitGroup
-		)
-
-		for _, b := range resultBatches {
-			var wgOnce sync.Once
-			batchWG.Add(1)
-			tmpBatch := b.ShallowCopy()
-
-			select {
-			case p.messagesOut <- message.NewTransactionFunc(tmpBatch, func(ctx context.Context, err error) error {
-				if err != nil {
-					errMut.Lock()
-					defer errMut.Unlock()
-
-					if batchErr == nil {
-						batchErr = batch.NewError(sortBatch, err)
-					}
-					for _, m := range tmpBatch {
-						if bIndex := sorter.GetIndex(m); bIndex >= 0 {
-							batchErr.Failed(bIndex, err)
-						} else {
-							// We are unable to link this message with an origin
-							// and therefore we must provide a general
-							// batch-wide error instead.
-							generalErr = err
-						}
+// sendResults sends the batches resulting from processing a transaction to an
+// output channel and returns a func that blocks until they are all
+// acknowledged before acknowledging the origin transaction. Returns false if a
+// hard stop was signalled before all batches were sent.
+func sendResults(
+	shutSig *shutdown.Signaller,
+	messagesOut chan<- message.Transaction,
+	tran message.Transaction,
+	sorter *message.SortGroup,
+	sortBatch message.Batch,
+	resultBatches []message.Batch,
+) (ackFn func(ctx context.Context), sent bool) {
+	if len(resultBatches) == 1 {
+		select {
+		case messagesOut <- message.NewTransactionFunc(resultBatches[0], tran.Ack):
+		case <-shutSig.HardStopChan():
+			return nil, false
+		}
+		return func(context.Context) {}, true
+	}
+
+	var (
+		errMut     sync.Mutex
+		batchErr   *batch.Error
+		generalErr error
+		batchWG    sync.WaitGroup
+	)
+
+	for _, b := range resultBatches {
+		var wgOnce sync.Once
+		batchWG.Add(1)
+		tmpBatch := b.ShallowCopy()
+
+		select {
+		case messagesOut <- message.NewTransactionFunc(tmpBatch, func(ctx context.Context, err error) error {
+			if err != nil {
+				errMut.Lock()
+				defer errMut.Unlock()
+
+				if batchErr == nil {
+					batchErr = batch.NewError(sortBatch, err)
+				}
+				for _, m := range tmpBatch {
+					if bIndex := sorter.GetIndex(m); bIndex >= 0 {
+						batchErr.Failed(bIndex, err)
+					} else {
+						// We are unable to link this message with an origin
+						// and therefore we must provide a general batch-wide
+						// error instead.
+						generalErr = err
 					}
 				}
-
-				wgOnce.Do(func() {
-					batchWG.Done()
-				})
-				return nil
-			}):
-			case <-p.shutSig.HardStopChan():
-				return
 			}
+
+			wgOnce.Do(func() {
+				batchWG.Done()
+			})
+			return nil
+		}):
+		case <-shutSig.HardStopChan():
+			return nil, false
 		}
+	}
 
+	return func(ctx context.Context) {
 		batchWG.Wait()
 
 		if generalErr != nil {
-			_ = tran.Ack(closeNowCtx, generalErr)
+			_ = tran.Ack(ctx, generalErr)
 		} else if batchErr != nil {
-			_ = tran.Ack(closeNowCtx, batchErr)
+			_ = tran.Ack(ctx, batchErr)
 		} else {
-			_ = tran.Ack(closeNowCtx, nil)
+			_ = tran.Ack(ctx, nil)
 		}
-	}
+	}, true
 }
 
 //------------------------------------------------------------------------------
@@ -7,48 +7,81 @@ import (
 	// Import all public sub-categories.
 	_ "github.com/benthosdev/benthos/v4/public/components/amqp09"
 	_ "github.com/benthosdev/benthos/v4/public/components/amqp1"
+	_ "github.com/benthosdev/benthos/v4/public/components/asn1"
 	_ "github.com/benthosdev/benthos/v4/public/components/avro"
 	_ "github.com/benthosdev/benthos/v4/public/components/aws"
 	_ "github.com/benthosdev/benthos/v4/public/components/azure"
 	_ "github.com/benthosdev/benthos/v4/public/components/beanstalkd"
 	_ "github.com/benthosdev/benthos/v4/public/components/cassandra"
+	_ "github.com/benthosdev/benthos/v4/public/components/cel"
 	_ "github.com/benthosdev/benthos/v4/public/components/changelog"
+	_ "github.com/benthosdev/benthos/v4/public/components/clickhouse"
 	_ "github.com/benthosdev/benthos/v4/public/components/cockroachdb"
 	_ "github.com/benthosdev/benthos/v4/public/components/confluent"
 	_ "github.com/benthosdev/benthos/v4/public/components/couchbase"
 	_ "github.com/benthosdev/benthos/v4/public/components/crypto"
+	_ "github.com/benthosdev/benthos/v4/public/components/deltalake"
 	_ "github.com/benthosdev/benthos/v4/public/components/dgraph"
 	_ "github.com/benthosdev/benthos/v4/public/components/discord"
+	_ "github.com/benthosdev/benthos/v4/public/components/dns"
+	_ "github.com/benthosdev/benthos/v4/public/components/edi"
 	_ "github.com/benthosdev/benthos/v4/public/components/elasticsearch"
+	_ "github.com/benthosdev/benthos/v4/public/components/fixedwidth"
 	_ "github.com/benthosdev/benthos/v4/public/components/gcp"
+	_ "github.com/benthosdev/benthos/v4/public/components/gelf"
+	_ "github.com/benthosdev/benthos/v4/public/components/graphql"
 	_ "github.com/benthosdev/benthos/v4/public/components/hdfs"
+	_ "github.com/benthosdev/benthos/v4/public/components/hl7"
+	_ "github.com/benthosdev/benthos/v4/public/components/html"
+	_ "github.com/benthosdev/benthos/v4/public/components/iceberg"
+	_ "github.com/benthosdev/benthos/v4/public/components/imagemeta"
 	_ "github.com/benthosdev/benthos/v4/public/components/influxdb"
 	_ "github.com/benthosdev/benthos/v4/public/components/io"
 	_ "github.com/benthosdev/benthos/v4/public/components/jaeger"
 	_ "github.com/benthosdev/benthos/v4/public/components/javascript"
+	_ "github.com/benthosdev/benthos/v4/public/components/jsonata"
 	_ "github.com/benthosdev/benthos/v4/public/components/kafka"
+	_ "github.com/benthosdev/benthos/v4/public/components/ldap"
+	_ "github.com/benthosdev/benthos/v4/public/components/llm"
+	_ "github.com/benthosdev/benthos/v4/public/components/loki"
+	_ "github.com/benthosdev/benthos/v4/public/components/lua"
 	_ "github.com/benthosdev/benthos/v4/public/components/maxmind"
 	_ "github.com/benthosdev/benthos/v4/public/components/memcached"
 	_ "github.com/benthosdev/benthos/v4/public/components/mongodb"
 	_ "github.com/benthosdev/benthos/v4/public/components/mqtt"
 	_ "github.com/benthosdev/benthos/v4/public/components/msgpack"
+	_ "github.com/benthosdev/benthos/v4/public/components/msteams"
 	_ "github.com/benthosdev/benthos/v4/public/components/nanomsg"
 	_ "github.com/benthosdev/benthos/v4/public/components/nats"
+	_ "github.com/benthosdev/benthos/v4/public/components/neo4j"
 	_ "github.com/benthosdev/benthos/v4/public/components/nsq"
+	_ "github.com/benthosdev/benthos/v4/public/components/ocr"
+	_ "github.com/benthosdev/benthos/v4/public/components/opa"
 	_ "github.com/benthosdev/benthos/v4/public/components/opensearch"
 	_ "github.com/benthosdev/benthos/v4/public/components/otlp"
+	_ "github.com/benthosdev/benthos/v4/public/components/pdf"
 	_ "github.com/benthosdev/benthos/v4/public/components/prometheus"
 	_ "github.com/benthosdev/benthos/v4/public/components/pulsar"
 	_ "github.com/benthosdev/benthos/v4/public/components/pure"
 	_ "github.com/benthosdev/benthos/v4/public/components/pure/extended"
 	_ "github.com/benthosdev/benthos/v4/public/components/pusher"
+	_ "github.com/benthosdev/benthos/v4/public/components/questdb"
 	_ "github.com/benthosdev/benthos/v4/public/components/redis"
+	_ "github.com/benthosdev/benthos/v4/public/components/search"
 	_ "github.com/benthosdev/benthos/v4/public/components/sentry"
 	_ "github.com/benthosdev/benthos/v4/public/components/sftp"
+	_ "github.com/benthosdev/benthos/v4/public/components/slack"
+	_ "github.com/benthosdev/benthos/v4/public/components/smtp"
 	_ "github.com/benthosdev/benthos/v4/public/components/snowflake"
+	_ "github.com/benthosdev/benthos/v4/public/components/soap"
 	_ "github.com/benthosdev/benthos/v4/public/components/splunk"
 	_ "github.com/benthosdev/benthos/v4/public/components/sql"
+	_ "github.com/benthosdev/benthos/v4/public/components/starlark"
 	_ "github.com/benthosdev/benthos/v4/public/components/statsd"
 	_ "github.com/benthosdev/benthos/v4/public/components/twitter"
+	_ "github.com/benthosdev/benthos/v4/public/components/useragent"
+	_ "github.com/benthosdev/benthos/v4/public/components/vectordb"
 	_ "github.com/benthosdev/benthos/v4/public/components/wasm"
+	_ "github.com/benthosdev/benthos/v4/public/components/webdav"
+	_ "github.com/benthosdev/benthos/v4/public/components/zeromq"
 )
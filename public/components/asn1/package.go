@@ -0,0 +1,6 @@
+package asn1
+
+import (
+	// Bring in the internal plugin definitions.
+	_ "github.com/benthosdev/benthos/v4/internal/impl/asn1"
+)
@@ -3,7 +3,10 @@ package aws
 import (
 	// Bring in the internal plugin definitions.
 	_ "github.com/benthosdev/benthos/v4/internal/impl/aws"
+	_ "github.com/benthosdev/benthos/v4/internal/impl/deltalake/aws"
 	_ "github.com/benthosdev/benthos/v4/internal/impl/elasticsearch/aws"
+	_ "github.com/benthosdev/benthos/v4/internal/impl/iceberg/aws"
 	_ "github.com/benthosdev/benthos/v4/internal/impl/kafka/aws"
+	_ "github.com/benthosdev/benthos/v4/internal/impl/llm/aws"
 	_ "github.com/benthosdev/benthos/v4/internal/impl/opensearch/aws"
 )
@@ -3,4 +3,5 @@ package azure
 import (
 	// Bring in the internal plugin definitions.
 	_ "github.com/benthosdev/benthos/v4/internal/impl/azure"
+	_ "github.com/benthosdev/benthos/v4/internal/impl/deltalake/azure"
 )
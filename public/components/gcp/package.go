@@ -2,5 +2,6 @@ package gcp
 
 import (
 	// Bring in the internal plugin definitions.
+	_ "github.com/benthosdev/benthos/v4/internal/impl/deltalake/gcp"
 	_ "github.com/benthosdev/benthos/v4/internal/impl/gcp"
 )
@@ -0,0 +1,6 @@
+package hl7
+
+import (
+	// Bring in the internal plugin definitions.
+	_ "github.com/benthosdev/benthos/v4/internal/impl/hl7"
+)
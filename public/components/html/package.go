@@ -0,0 +1,6 @@
+package html
+
+import (
+	// Bring in the internal plugin definitions.
+	_ "github.com/benthosdev/benthos/v4/internal/impl/html"
+)
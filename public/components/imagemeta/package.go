@@ -0,0 +1,6 @@
+package imagemeta
+
+import (
+	// Bring in the internal plugin definitions.
+	_ "github.com/benthosdev/benthos/v4/internal/impl/imagemeta"
+)
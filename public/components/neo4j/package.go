@@ -0,0 +1,6 @@
+package neo4j
+
+import (
+	// Bring in the internal plugin definitions.
+	_ "github.com/benthosdev/benthos/v4/internal/impl/neo4j"
+)
@@ -55,17 +55,40 @@ type batchedCache interface {
 	SetMulti(ctx context.Context, keyValues ...CacheItem) error
 }
 
+// batchedGetCache represents a cache where the underlying implementation is
+// able to benefit from batched get requests. This interface is optional for
+// caches and when implemented will automatically be utilised where possible.
+type batchedGetCache interface {
+	// GetMulti attempts to get multiple cache items in as few requests as
+	// possible. The values of keys that exist are returned, and keys that do
+	// not exist are omitted from the result.
+	GetMulti(ctx context.Context, keys ...string) (map[string][]byte, error)
+}
+
+// sizedCache represents a cache that holds items in memory and is able to
+// report approximately how many items it holds and how many bytes their keys
+// and values occupy. This interface is optional for caches and when
+// implemented is measured periodically and exposed as metrics.
+type sizedCache interface {
+	Size() (items, bytes int64)
+}
+
 //------------------------------------------------------------------------------
 
 // Implements types.Cache.
 type airGapCache struct {
-	c  Cache
-	cm batchedCache
+	c   Cache
+	cm  batchedCache
+	cgm batchedGetCache
 }
 
 func newAirGapCache(c Cache, stats metrics.Type) cache.V1 {
 	ag := &airGapCache{c: c, cm: nil}
 	ag.cm, _ = c.(batchedCache)
+	ag.cgm, _ = c.(batchedGetCache)
+	if sc, ok := c.(sizedCache); ok {
+		return cache.MetricsForCache(&sizedAirGapCache{airGapCache: ag, sc: sc}, stats)
+	}
 	return cache.MetricsForCache(ag, stats)
 }
 
@@ -77,6 +100,32 @@ func (a *airGapCache) Get(ctx context.Context, key string) ([]byte, error) {
 	return b, err
 }
 
+func (a *airGapCache) GetMulti(ctx context.Context, keys []string) (map[string][]byte, error) {
+	if a.cgm != nil {
+		return a.cgm.GetMulti(ctx, keys...)
+	}
+	values := make(map[string][]byte, len(keys))
+	var keyErrs cache.KeyErrors
+	for _, k := range keys {
+		b, err := a.c.Get(ctx, k)
+		if err != nil {
+			if errors.Is(err, ErrKeyNotFound) || errors.Is(err, component.ErrKeyNotFound) {
+				continue
+			}
+			if keyErrs == nil {
+				keyErrs = cache.KeyErrors{}
+			}
+			keyErrs[k] = err
+			continue
+		}
+		values[k] = b
+	}
+	if keyErrs != nil {
+		return values, keyErrs
+	}
+	return values, nil
+}
+
 func (a *airGapCache) Set(ctx context.Context, key string, value []byte, ttl *time.Duration) error {
 	return a.c.Set(ctx, key, value, ttl)
 }
@@ -117,6 +166,18 @@ func (a *airGapCache) Close(ctx context.Context) error {
 	return a.c.Close(ctx)
 }
 
+// sizedAirGapCache is an airGapCache that also implements cache.Sizer, which
+// is kept separate so that only caches that are able to report their size are
+// measured.
+type sizedAirGapCache struct {
+	*airGapCache
+	sc sizedCache
+}
+
+func (s *sizedAirGapCache) Size() (items, bytes int64) {
+	return s.sc.Size()
+}
+
 //------------------------------------------------------------------------------
 
 // Implements Cache around a types.Cache.
@@ -136,6 +197,12 @@ func (r *reverseAirGapCache) Get(ctx context.Context, key string) ([]byte, error
 	return b, err
 }
 
+// GetMulti attempts to get multiple cache items with a single request, keys
+// that do not exist are omitted from the result.
+func (r *reverseAirGapCache) GetMulti(ctx context.Context, keys ...string) (map[string][]byte, error) {
+	return r.c.GetMulti(ctx, keys)
+}
+
 func (r *reverseAirGapCache) Set(ctx context.Context, key string, value []byte, ttl *time.Duration) error {
 	return r.c.Set(ctx, key, value, ttl)
 }
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/benthosdev/benthos/v4/internal/component"
 	"github.com/benthosdev/benthos/v4/internal/component/cache"
@@ -90,6 +91,19 @@ func (c *closableCacheMulti) SetMulti(ctx context.Context, keyValues ...CacheIte
 	return nil
 }
 
+func (c *closableCacheMulti) GetMulti(ctx context.Context, keys ...string) (map[string][]byte, error) {
+	if c.closableCache.err != nil {
+		return nil, c.closableCache.err
+	}
+	values := map[string][]byte{}
+	for _, k := range keys {
+		if i, ok := c.multiItems[k]; ok {
+			values[k] = i.b
+		}
+	}
+	return values, nil
+}
+
 func TestCacheAirGapShutdown(t *testing.T) {
 	rl := &closableCache{}
 	agrl := newAirGapCache(rl, metrics.Noop())
@@ -119,6 +133,88 @@ func TestCacheAirGapGet(t *testing.T) {
 	assert.EqualError(t, err, "key does not exist")
 }
 
+func TestCacheAirGapGetMulti(t *testing.T) {
+	ctx := context.Background()
+	rl := &closableCache{
+		m: map[string]testCacheItem{
+			"foo": {b: []byte("bar")},
+			"baz": {b: []byte("buz")},
+		},
+	}
+	agrl := newAirGapCache(rl, metrics.Noop())
+
+	values, err := agrl.GetMulti(ctx, []string{"foo", "not exist", "baz"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]byte{
+		"foo": []byte("bar"),
+		"baz": []byte("buz"),
+	}, values)
+
+	rl.err = errors.New("nope")
+	values, err = agrl.GetMulti(ctx, []string{"foo"})
+	assert.Empty(t, values)
+	assert.Equal(t, cache.KeyErrors{"foo": rl.err}, err)
+	assert.EqualError(t, err, "key 'foo': nope")
+}
+
+type keyErrCache struct {
+	*closableCache
+	keyErrs map[string]error
+}
+
+func (c *keyErrCache) Get(ctx context.Context, key string) ([]byte, error) {
+	if err, exists := c.keyErrs[key]; exists {
+		return nil, err
+	}
+	return c.closableCache.Get(ctx, key)
+}
+
+func TestCacheAirGapGetMultiKeyErrors(t *testing.T) {
+	ctx := context.Background()
+	rl := &keyErrCache{
+		closableCache: &closableCache{
+			m: map[string]testCacheItem{
+				"foo": {b: []byte("bar")},
+				"baz": {b: []byte("buz")},
+			},
+		},
+		keyErrs: map[string]error{
+			"baz": errors.New("nope"),
+		},
+	}
+	agrl := newAirGapCache(rl, metrics.Noop())
+
+	values, err := agrl.GetMulti(ctx, []string{"foo", "not exist", "baz"})
+	assert.Equal(t, map[string][]byte{
+		"foo": []byte("bar"),
+	}, values)
+
+	var keyErrs cache.KeyErrors
+	require.ErrorAs(t, err, &keyErrs)
+	assert.Equal(t, cache.KeyErrors{"baz": rl.keyErrs["baz"]}, keyErrs)
+}
+
+func TestCacheAirGapGetMultiPassthrough(t *testing.T) {
+	ctx := context.Background()
+	rl := &closableCacheMulti{
+		closableCache: &closableCache{
+			m: map[string]testCacheItem{
+				"foo": {b: []byte("not this")},
+			},
+		},
+		multiItems: map[string]testCacheItem{
+			"foo": {b: []byte("bar")},
+		},
+	}
+	agrl := newAirGapCache(rl, metrics.Noop())
+
+	values, err := agrl.GetMulti(ctx, []string{"foo", "not exist"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]byte{
+		"foo": []byte("bar"),
+	}, values)
+}
+
 func TestCacheAirGapSet(t *testing.T) {
 	ctx := context.Background()
 	rl := &closableCache{
@@ -326,6 +422,10 @@ func (c *closableCacheType) Set(ctx context.Context, key string, value []byte, t
 	return nil
 }
 
+func (c *closableCacheType) GetMulti(ctx context.Context, keys []string) (map[string][]byte, error) {
+	return nil, errors.New("not implemented")
+}
+
 func (c *closableCacheType) SetMulti(ctx context.Context, items map[string]cache.TTLItem) error {
 	return errors.New("not implemented")
 }
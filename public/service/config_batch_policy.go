@@ -21,6 +21,10 @@ type BatchPolicy struct {
 	Check    string
 	Period   string
 
+	// StrictByteSize flushes batches before adding messages that would take
+	// them beyond ByteSize.
+	StrictByteSize bool
+
 	// Only available when using NewBatchPolicyField.
 	procs []processor.Config
 }
@@ -28,6 +32,7 @@ type BatchPolicy struct {
 func (b BatchPolicy) toInternal() batchconfig.Config {
 	batchConf := batchconfig.NewConfig()
 	batchConf.ByteSize = b.ByteSize
+	batchConf.StrictByteSize = b.StrictByteSize
 	batchConf.Count = b.Count
 	batchConf.Check = b.Check
 	batchConf.Period = b.Period
@@ -62,6 +67,17 @@ func (b *Batcher) Add(msg *Message) bool {
 	return b.p.Add(msg.part)
 }
 
+// FlushBefore returns true if the batching policy has a strict byte size and
+// adding the messages of a batch would take the pending batch beyond it, in
+// which case Flush should be called before the messages are added.
+func (b *Batcher) FlushBefore(batch MessageBatch) bool {
+	msg := make(message.Batch, len(batch))
+	for i, m := range batch {
+		msg[i] = m.part
+	}
+	return b.p.FlushBefore(msg)
+}
+
 // UntilNext returns a duration indicating how long until the current batch
 // should be flushed due to a configured period. A boolean is also returned
 // indicating whether the batching policy has a timed factor, if this is false
@@ -150,6 +166,9 @@ func (p *ParsedConfig) FieldBatchPolicy(path ...string) (conf BatchPolicy, err e
 	if conf.ByteSize, err = p.FieldInt(append(path, "byte_size")...); err != nil {
 		return
 	}
+	if conf.StrictByteSize, err = p.FieldBool(append(path, "strict_byte_size")...); err != nil {
+		return
+	}
 	if conf.Check, err = p.FieldString(append(path, "check")...); err != nil {
 		return
 	}
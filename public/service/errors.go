@@ -29,6 +29,45 @@ var (
 	ErrEndOfBuffer = errors.New("end of buffer")
 )
 
+// ErrorClass describes the nature of an error encountered whilst processing a
+// message, which can be used in order to determine how the message should be
+// handled. The class of the error a message failed with can be obtained within
+// Bloblang with the function `error_class`.
+type ErrorClass string
+
+// Error classes.
+const (
+	// ErrorClassTransient indicates that the error is temporary and that
+	// retrying the failed operation might succeed, e.g. a timeout.
+	ErrorClassTransient = ErrorClass(message.ErrorClassTransient)
+
+	// ErrorClassPermanent indicates that retrying the failed operation is not
+	// expected to succeed.
+	ErrorClassPermanent = ErrorClass(message.ErrorClassPermanent)
+
+	// ErrorClassValidation indicates that the contents of the message are
+	// invalid, e.g. they could not be parsed or failed a schema.
+	ErrorClassValidation = ErrorClass(message.ErrorClassValidation)
+)
+
+// NewErrorWithClass wraps an error with a class. When a processor fails a
+// message with the error, or the error is set on a message with SetError, the
+// class is attached to the message. Errors that are not wrapped are classified
+// automatically, where timeouts and connection failures are transient, JSON
+// parsing failures are validation errors and all other errors are permanent.
+func NewErrorWithClass(err error, class ErrorClass) error {
+	return message.ErrorWithClass(err, message.ErrorClass(class))
+}
+
+// ErrorClassOf returns the class of an error.
+func ErrorClassOf(err error) ErrorClass {
+	class, ok := message.ErrorClassOf(err)
+	if !ok {
+		return ErrorClassPermanent
+	}
+	return ErrorClass(class)
+}
+
 // ErrBackOff is an error that plugins can optionally wrap another error with
 // which instructs upstream components to wait for a specified period of time
 // before retrying the errored call.
@@ -10,6 +10,7 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/benthosdev/benthos/v4/internal/component"
+	"github.com/benthosdev/benthos/v4/internal/component/cache"
 )
 
 // CacheTestOpenClose checks that the cache can be started, an item added, and
@@ -122,3 +123,33 @@ func CacheTestGetAndSet(n int) CacheTestDefinition {
 		},
 	)
 }
+
+// CacheTestGetAndSetMulti checks that we can set and then get n items with
+// multi-key operations, where keys that do not exist are omitted.
+func CacheTestGetAndSetMulti(n int) CacheTestDefinition {
+	return namedCacheTest(
+		"can get and set multiple keys",
+		func(t *testing.T, env *cacheTestEnvironment) {
+			c := initCache(t, env)
+			t.Cleanup(func() {
+				closeCache(t, c)
+			})
+
+			items := map[string]cache.TTLItem{}
+			keys := []string{"multimissingkey"}
+			for i := 0; i < n; i++ {
+				key := fmt.Sprintf("multikey%v", i)
+				items[key] = cache.TTLItem{Value: []byte(fmt.Sprintf("value%v", i))}
+				keys = append(keys, key)
+			}
+			require.NoError(t, c.SetMulti(env.ctx, items))
+
+			res, err := c.GetMulti(env.ctx, keys)
+			require.NoError(t, err)
+			require.Len(t, res, n)
+			for k, v := range items {
+				assert.Equal(t, string(v.Value), string(res[k]), k)
+			}
+		},
+	)
+}
@@ -267,8 +267,10 @@ func (m *Message) SetStructuredMut(i any) {
 // error to it as context. Messages marked with errors can be handled using a
 // range of methods outlined in https://www.benthos.dev/docs/configuration/error_handling.
 func (m *Message) SetError(err error) {
-	if m.onErr != nil {
+	if m.onErr != nil && err != nil {
+		// Marks the message along with the details of the processor.
 		m.onErr(err)
+		return
 	}
 	m.part.ErrorSet(err)
 }
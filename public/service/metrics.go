@@ -180,6 +180,34 @@ type MetricsExporterGauge interface {
 	// SetFloat64(value float64)
 }
 
+// MetricsExporterHistogramCtor is a constructor for a MetricsExporterHistogram
+// that must be called with a variadic list of label values exactly matching the
+// length and order of the label keys provided.
+type MetricsExporterHistogramCtor func(labelValues ...string) MetricsExporterHistogram
+
+// MetricsExporterHistogram represents a histogram or summary metric of a given
+// name and labels.
+type MetricsExporterHistogram interface {
+	// Observe adds a single observation to the distribution of the metric.
+	Observe(value float64)
+}
+
+// MetricsExporterHistograms is an optional interface that can be implemented
+// by a MetricsExporter in order to support histograms with custom buckets and
+// summaries with custom quantile objectives, such as those emitted by the
+// metric processor. The observations of these metrics are otherwise recorded as
+// timings by exporters that do not implement this interface.
+type MetricsExporterHistograms interface {
+	// NewHistogramCtor creates a histogram where observations are counted
+	// within buckets of the provided upper bounds. When no buckets are provided
+	// the defaults of the exporter should be used.
+	NewHistogramCtor(name string, buckets []float64, labelKeys ...string) MetricsExporterHistogramCtor
+
+	// NewSummaryCtor creates a summary where quantiles are calculated for the
+	// provided objectives, a map of quantiles to their absolute errors.
+	NewSummaryCtor(name string, objectives map[float64]float64, labelKeys ...string) MetricsExporterHistogramCtor
+}
+
 //------------------------------------------------------------------------------
 
 // Implements internal metrics plugin interface.
@@ -258,6 +286,14 @@ func (a *airGapTiming) Timing(val int64) {
 	a.airGapped.Timing(val)
 }
 
+type airGapHistogramTiming struct {
+	airGapped MetricsExporterTimer
+}
+
+func (a *airGapHistogramTiming) Observe(value float64) {
+	a.airGapped.Timing(int64(value))
+}
+
 type airGapCounterVec struct {
 	ctor MetricsExporterCounterCtor
 }
@@ -282,6 +318,22 @@ func (a *airGapGaugeVec) With(labelValues ...string) metrics.StatGauge {
 	return &airGapGauge{airGapped: a.ctor(labelValues...)}
 }
 
+type airGapHistogramVec struct {
+	ctor MetricsExporterHistogramCtor
+}
+
+func (a *airGapHistogramVec) With(labelValues ...string) metrics.StatHistogram {
+	return a.ctor(labelValues...)
+}
+
+type airGapHistogramTimingVec struct {
+	ctor MetricsExporterTimerCtor
+}
+
+func (a *airGapHistogramTimingVec) With(labelValues ...string) metrics.StatHistogram {
+	return &airGapHistogramTiming{a.ctor(labelValues...)}
+}
+
 func (m *airGapMetrics) GetCounter(path string) metrics.StatCounter {
 	return m.GetCounterVec(path).With()
 }
@@ -306,6 +358,20 @@ func (m *airGapMetrics) GetGaugeVec(path string, labelNames ...string) metrics.S
 	return &airGapGaugeVec{m.airGapped.NewGaugeCtor(path, labelNames...)}
 }
 
+func (m *airGapMetrics) GetHistogramVec(path string, buckets []float64, labelNames ...string) metrics.StatHistogramVec {
+	if h, ok := m.airGapped.(MetricsExporterHistograms); ok {
+		return &airGapHistogramVec{h.NewHistogramCtor(path, buckets, labelNames...)}
+	}
+	return &airGapHistogramTimingVec{m.airGapped.NewTimerCtor(path, labelNames...)}
+}
+
+func (m *airGapMetrics) GetSummaryVec(path string, objectives map[float64]float64, labelNames ...string) metrics.StatHistogramVec {
+	if h, ok := m.airGapped.(MetricsExporterHistograms); ok {
+		return &airGapHistogramVec{h.NewSummaryCtor(path, objectives, labelNames...)}
+	}
+	return &airGapHistogramTimingVec{m.airGapped.NewTimerCtor(path, labelNames...)}
+}
+
 func (m *airGapMetrics) HandlerFunc() http.HandlerFunc {
 	if hf, ok := m.airGapped.(interface {
 		HandlerFunc() http.HandlerFunc
@@ -215,3 +215,29 @@ func TestBatchProcessorAirGapOneToMany(t *testing.T) {
 	assert.Equal(t, 1, msgs[1].Len())
 	assert.Equal(t, "changed 3", string(msgs[1].Get(0).AsBytes()))
 }
+
+func TestBatchProcessorAirGapErrorClasses(t *testing.T) {
+	tCtx, done := context.WithTimeout(context.Background(), time.Second)
+	defer done()
+
+	agrp := newAirGapBatchProcessor("foo", &fnBatchProcessor{
+		fn: func(c context.Context, msgs MessageBatch) ([]MessageBatch, error) {
+			msgs[0].SetError(NewErrorWithClass(errors.New("nope"), ErrorClassValidation))
+			msgs[1].SetError(errors.New("nope"))
+			msgs[1].SetError(nil)
+			return []MessageBatch{msgs}, nil
+		},
+	}, mock.NewManager())
+
+	msg := message.QuickBatch([][]byte{[]byte("first"), []byte("second")})
+	msgs, res := agrp.ProcessBatch(tCtx, msg)
+	require.NoError(t, res)
+	require.Len(t, msgs, 1)
+	require.Equal(t, 2, msgs[0].Len())
+
+	var pErr *message.ProcessorError
+	require.ErrorAs(t, msgs[0].Get(0).ErrorGet(), &pErr)
+	assert.Equal(t, "foo", pErr.Name)
+	assert.Equal(t, ErrorClassValidation, ErrorClassOf(pErr))
+	assert.NoError(t, msgs[0].Get(1).ErrorGet())
+}
@@ -37,6 +37,7 @@ func TestStreamBuilderDefault(t *testing.T) {
     none: {}`,
 		`pipeline:
     threads: 0
+    ordered: false
     processors: []`,
 		`output:
     label: ""
@@ -408,6 +409,7 @@ type: local`))
     memory: {}`,
 		`pipeline:
     threads: 10
+    ordered: false
     processors:`,
 		`
         - label: ""
@@ -941,6 +943,7 @@ output:
     none: {}`,
 		`pipeline:
     threads: 5
+    ordered: false
     processors:`,
 		`
         - label: ""
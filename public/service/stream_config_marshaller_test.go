@@ -46,6 +46,7 @@ buffer:
   none: {}
 pipeline:
   threads: -1
+  ordered: false
   processors: []
 output:
   cat: {} # No default (required)
@@ -90,6 +91,7 @@ buffer:
   none: {}
 pipeline:
   threads: -1
+  ordered: false
   processors: []
 output:
   cat:
@@ -3,6 +3,7 @@
 package tinygo
 
 import (
+	"encoding/json"
 	"reflect"
 	"unsafe"
 )
@@ -45,6 +46,81 @@ func GetMsgAsBytes() ([]byte, error) {
 	return ptrToBytes(contentPtr, contentSize), nil
 }
 
+// _v0_msg_get_meta is a WebAssembly import which obtains the value of a
+// metadata key of the message being processed.
+//
+//go:wasm-module benthos_wasm
+//export v0_msg_get_meta
+func _v0_msg_get_meta(keyPtr, keySize uint32) (ptrSize uint64)
+
+// GetMeta returns the value of a metadata key of the message currently being
+// processed, or an empty string if the key does not exist.
+func GetMeta(key string) string {
+	keyP, keyS := bytesToPtr([]byte(key))
+	ptrSize := _v0_msg_get_meta(keyP, keyS)
+	return string(ptrToBytes(uint32(ptrSize>>32), uint32(ptrSize)))
+}
+
+// _v0_msg_set_meta is a WebAssembly import which sets the value of a metadata
+// key of the message being processed.
+//
+//go:wasm-module benthos_wasm
+//export v0_msg_set_meta
+func _v0_msg_set_meta(keyPtr, keySize, valuePtr, valueSize uint32)
+
+// SetMeta sets the value of a metadata key of the message currently being
+// processed.
+func SetMeta(key, value string) {
+	keyP, keyS := bytesToPtr([]byte(key))
+	valueP, valueS := bytesToPtr([]byte(value))
+	_v0_msg_set_meta(keyP, keyS, valueP, valueS)
+}
+
+// _v0_msg_delete_meta is a WebAssembly import which removes a metadata key of
+// the message being processed.
+//
+//go:wasm-module benthos_wasm
+//export v0_msg_delete_meta
+func _v0_msg_delete_meta(keyPtr, keySize uint32)
+
+// DeleteMeta removes a metadata key of the message currently being processed.
+func DeleteMeta(key string) {
+	keyP, keyS := bytesToPtr([]byte(key))
+	_v0_msg_delete_meta(keyP, keyS)
+}
+
+// _v0_msg_meta_keys is a WebAssembly import which obtains the metadata keys of
+// the message being processed as a JSON array.
+//
+//go:wasm-module benthos_wasm
+//export v0_msg_meta_keys
+func _v0_msg_meta_keys() (ptrSize uint64)
+
+// MetaKeys returns the metadata keys of the message currently being processed
+// in lexicographical order.
+func MetaKeys() ([]string, error) {
+	ptrSize := _v0_msg_meta_keys()
+	var keys []string
+	if err := json.Unmarshal(ptrToBytes(uint32(ptrSize>>32), uint32(ptrSize)), &keys); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// _v0_msg_set_error is a WebAssembly import which flags the message being
+// processed as having failed.
+//
+//go:wasm-module benthos_wasm
+//export v0_msg_set_error
+func _v0_msg_set_error(ptr, size uint32)
+
+// SetError flags the message currently being processed as having failed with
+// an error message.
+func SetError(msg string) {
+	msgP, msgS := bytesToPtr([]byte(msg))
+	_v0_msg_set_error(msgP, msgS)
+}
+
 // ptrToBytes returns a byte slice from WebAssembly compatible numeric types
 // representing its pointer and length.
 func ptrToBytes(ptr, size uint32) []byte {
@@ -60,6 +136,9 @@ func ptrToBytes(ptr, size uint32) []byte {
 // bytesToPtr returns a pointer and size pair for the given byte slice in a way
 // compatible with WebAssembly numeric types.
 func bytesToPtr(buf []byte) (uint32, uint32) {
+	if len(buf) == 0 {
+		return 0, 0
+	}
 	ptr := &buf[0]
 	unsafePtr := uintptr(unsafe.Pointer(ptr))
 	return uint32(unsafePtr), uint32(len(buf))